@@ -11,8 +11,9 @@ import (
 )
 
 var acpCmd = &cobra.Command{
-	Use:   "acp",
-	Short: "Run kodelet as an ACP agent",
+	Use:     "acp",
+	Aliases: []string{"stdio"},
+	Short:   "Run kodelet as an ACP agent",
 	Long: `Run kodelet as an Agent Client Protocol (ACP) agent.
 
 This mode allows kodelet to be embedded in ACP-compatible clients like
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/jingkaihe/kodelet/pkg/airgap"
+	"github.com/jingkaihe/kodelet/pkg/binaries"
+	"github.com/jingkaihe/kodelet/pkg/llm"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var airgapCmd = &cobra.Command{
+	Use:   "airgap",
+	Short: "Commands for air-gapped deployments",
+	Long:  `Commands for running kodelet inside a network without internet access.`,
+}
+
+var airgapVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check that no external endpoints are configured",
+	Long: `Check the effective configuration for endpoints outside the isolated network:
+the model provider, web access tools, tool binary downloads, telemetry, pricing
+refresh and the CA bundle.
+
+Loopback, private and link-local addresses, single-label host names and names
+under .internal, .local, .lan and .localhost count as internal, as do hosts in
+airgap.allowed_hosts.
+
+The command exits with an error when any check fails.
+
+Examples:
+  kodelet airgap verify
+  kodelet airgap verify --profile offline
+  kodelet airgap verify --format json
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		config, err := llm.GetConfigFromViperWithCmd(cmd)
+		if err != nil {
+			return errors.Wrap(err, "failed to load configuration")
+		}
+		checks := airgap.Verify(config, airgap.VerifyOptions{
+			TracingEnabled:     viper.GetBool("tracing.enabled"),
+			PricingManifestURL: viper.GetString("pricing.manifest_url"),
+		})
+		return runAirgapVerify(cmd.OutOrStdout(), checks, format)
+	},
+}
+
+func init() {
+	airgapVerifyCmd.Flags().String("format", "table", "Output format: table or json")
+	airgapCmd.AddCommand(airgapVerifyCmd)
+}
+
+func runAirgapVerify(w io.Writer, checks []airgap.Check, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(checks); err != nil {
+			return err
+		}
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Check\tResult\tDetail")
+		for _, check := range checks {
+			result := "ok"
+			if !check.Passed {
+				result = "FAIL"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", check.Name, result, check.Detail)
+		}
+		tw.Flush()
+	default:
+		return errors.Errorf("invalid format %q (expected table or json)", format)
+	}
+
+	failed := 0
+	for _, check := range checks {
+		if !check.Passed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("%d of %d airgap checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// applyAirgapSettings installs the pinned CA bundle and, in air-gapped mode,
+// stops tool binaries from being downloaded. It runs before anything opens a
+// network connection.
+func applyAirgapSettings(ctx context.Context) {
+	if caBundle := viper.GetString("airgap.ca_bundle"); caBundle != "" {
+		if err := airgap.UseCABundle(caBundle); err != nil {
+			logger.G(ctx).WithError(err).Warn("Failed to load CA bundle, using system roots")
+		}
+	}
+	if viper.GetBool("airgap.enabled") {
+		binaries.DisableDownloads()
+	}
+}
+
+// checkAirgapEndpoint rejects an endpoint outside the isolated network when
+// air-gapped mode is enabled.
+func checkAirgapEndpoint(what, endpoint string) error {
+	if !viper.GetBool("airgap.enabled") || airgap.IsInternalURL(endpoint, viper.GetStringSlice("airgap.allowed_hosts")) {
+		return nil
+	}
+	return errors.Errorf("airgap: %s %s is not internal", what, endpoint)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/airgap"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunAirgapVerify(t *testing.T) {
+	checks := []airgap.Check{
+		{Name: "airgap mode", Passed: true, Detail: "enabled"},
+		{Name: "model endpoint", Passed: false, Detail: "https://api.openai.com/v1 (openai)"},
+	}
+
+	var out bytes.Buffer
+	err := runAirgapVerify(&out, checks, "table")
+	assert.EqualError(t, err, "1 of 2 airgap checks failed")
+	assert.Contains(t, out.String(), "model endpoint")
+	assert.Contains(t, out.String(), "FAIL")
+
+	out.Reset()
+	require.NoError(t, runAirgapVerify(&out, checks[:1], "json"))
+	assert.Contains(t, out.String(), `"passed": true`)
+
+	assert.Error(t, runAirgapVerify(&out, checks, "yaml"))
+}
+
+func TestCheckAirgapEndpoint(t *testing.T) {
+	t.Cleanup(func() {
+		viper.Set("airgap.enabled", false)
+		viper.Set("airgap.allowed_hosts", nil)
+	})
+
+	assert.NoError(t, checkAirgapEndpoint("tracing endpoint", "https://collector.example.com"))
+
+	viper.Set("airgap.enabled", true)
+	assert.EqualError(t, checkAirgapEndpoint("tracing endpoint", "https://collector.example.com"), "airgap: tracing endpoint https://collector.example.com is not internal")
+	assert.NoError(t, checkAirgapEndpoint("tracing endpoint", "http://localhost:4318"))
+
+	viper.Set("airgap.allowed_hosts", []string{"example.com"})
+	assert.NoError(t, checkAirgapEndpoint("tracing endpoint", "https://collector.example.com"))
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/jingkaihe/kodelet/pkg/llm"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/markdown"
+	"github.com/jingkaihe/kodelet/pkg/presenter"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type AskConfig struct {
+	MainModel bool
+	Stats     bool
+	NoRender  bool
+}
+
+func NewAskConfig() *AskConfig {
+	return &AskConfig{}
+}
+
+var askCmd = &cobra.Command{
+	Use:   "ask [question]",
+	Short: "Answer a quick question without tools, context files or persistence",
+	Long: `Answer a single question as quickly and cheaply as possible.
+
+Unlike run, ask starts no tools, extensions or MCP servers, does not load AGENTS.md or other context files, and does not save the conversation. It uses the weak model unless --main-model is set. Input piped on stdin is appended to the question.`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sigCh
+			cancel()
+		}()
+
+		timer := newStartupTimer()
+		query, err := getQueryFromStdinOrArgs(args)
+		if err != nil {
+			presenter.Error(err, "Failed to read question")
+			os.Exit(1)
+		}
+		if strings.TrimSpace(query) == "" {
+			presenter.Error(errors.New("no question provided"), "Usage: kodelet ask \"question\"")
+			os.Exit(1)
+		}
+
+		llmConfig, err := llm.GetConfigFromViperWithCmd(cmd)
+		if err != nil {
+			presenter.Error(err, "Failed to load configuration")
+			os.Exit(1)
+		}
+		config := getAskConfigFromFlags(cmd)
+		if !cmd.Flags().Changed("log-level") {
+			logger.SetLogLevel("warn")
+		}
+		timer.Mark("config")
+
+		handler := &askMessageHandler{ConsoleMessageHandler: &llmtypes.ConsoleMessageHandler{}}
+		if !config.NoRender && markdown.IsTerminal(os.Stdout) {
+			if renderer, err := presenter.NewMarkdownRenderer(0); err == nil {
+				handler.RenderMarkdown = renderer.Render
+			}
+		}
+
+		thread, err := llm.NewThread(llmConfig)
+		if err != nil {
+			presenter.Error(err, "Failed to create LLM thread")
+			os.Exit(1)
+		}
+		defer func() { _ = llm.CloseThread(thread) }()
+		thread.EnablePersistence(ctx, false)
+		timer.Mark("thread")
+		timer.Log(ctx, "ask")
+
+		if _, err := thread.SendMessage(ctx, query, handler, askMessageOpt(config)); err != nil {
+			presenter.Error(err, "Failed to answer question")
+			os.Exit(1)
+		}
+
+		if config.Stats {
+			usage := thread.GetUsage()
+			presenter.Stats(presenter.ConvertUsageStats(&usage))
+		}
+	},
+}
+
+func init() {
+	defaults := NewAskConfig()
+	askCmd.Flags().Bool("main-model", defaults.MainModel, "Answer with the main model instead of the weak model")
+	askCmd.Flags().Bool("stats", defaults.Stats, "Print token usage and cost after the answer")
+	askCmd.Flags().Bool("no-render", defaults.NoRender, "Print the answer as raw markdown instead of rendering it")
+}
+
+func getAskConfigFromFlags(cmd *cobra.Command) *AskConfig {
+	config := NewAskConfig()
+
+	if mainModel, err := cmd.Flags().GetBool("main-model"); err == nil {
+		config.MainModel = mainModel
+	}
+	if stats, err := cmd.Flags().GetBool("stats"); err == nil {
+		config.Stats = stats
+	}
+	if noRender, err := cmd.Flags().GetBool("no-render"); err == nil {
+		config.NoRender = noRender
+	}
+
+	return config
+}
+
+// askMessageOpt answers in a single tool-less turn; the thread has no state,
+// so no context files are discovered either.
+func askMessageOpt(config *AskConfig) llmtypes.MessageOpt {
+	return llmtypes.MessageOpt{
+		UseWeakModel:       !config.MainModel,
+		NoToolUse:          true,
+		NoSaveConversation: true,
+		MaxTurns:           1,
+	}
+}
+
+// askMessageHandler prints only the answer, leaving out the model's thinking.
+type askMessageHandler struct {
+	*llmtypes.ConsoleMessageHandler
+}
+
+func (h *askMessageHandler) HandleThinking(string)      {}
+func (h *askMessageHandler) HandleThinkingStart()       {}
+func (h *askMessageHandler) HandleThinkingDelta(string) {}
+func (h *askMessageHandler) HandleThinkingBlockEnd()    {}
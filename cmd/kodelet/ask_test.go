@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAskConfigFromFlags(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().AddFlagSet(askCmd.Flags())
+
+	config := getAskConfigFromFlags(cmd)
+	assert.False(t, config.MainModel)
+	assert.False(t, config.Stats)
+	assert.False(t, config.NoRender)
+
+	require.NoError(t, cmd.Flags().Set("main-model", "true"))
+	require.NoError(t, cmd.Flags().Set("stats", "true"))
+	require.NoError(t, cmd.Flags().Set("no-render", "true"))
+
+	config = getAskConfigFromFlags(cmd)
+	assert.True(t, config.MainModel)
+	assert.True(t, config.Stats)
+	assert.True(t, config.NoRender)
+}
+
+func TestAskMessageOpt(t *testing.T) {
+	opt := askMessageOpt(NewAskConfig())
+	assert.True(t, opt.UseWeakModel)
+	assert.True(t, opt.NoToolUse)
+	assert.True(t, opt.NoSaveConversation)
+	assert.Equal(t, 1, opt.MaxTurns)
+
+	opt = askMessageOpt(&AskConfig{MainModel: true})
+	assert.False(t, opt.UseWeakModel)
+}
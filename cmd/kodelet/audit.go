@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/audit"
+	"github.com/jingkaihe/kodelet/pkg/usage"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type AuditConfig struct {
+	ConversationID string
+	Domain         string
+	Tool           string
+	Since          string
+	Until          string
+	Limit          int
+	Format         string
+}
+
+func NewAuditConfig() *AuditConfig {
+	return &AuditConfig{
+		Limit:  100,
+		Format: "table",
+	}
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show the egress audit log of network requests made by tools",
+	Long: `Show the append-only audit log of outbound network requests made by tools,
+such as web_fetch and MCP HTTP servers, with the conversation that made them.
+
+The log is stored in ~/.kodelet/audit/egress.jsonl (or under KODELET_BASE_PATH).
+
+Examples:
+  kodelet audit                                  # Newest 100 requests
+  kodelet audit --conversation-id ID             # Requests made by one conversation
+  kodelet audit --domain github.com              # Requests to github.com and its subdomains
+  kodelet audit --since 1d --limit 0             # Every request in the past day
+  kodelet audit --format json                    # JSON output
+`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runAuditCmd(cmd.OutOrStdout(), getAuditConfigFromFlags(cmd))
+	},
+}
+
+func init() {
+	defaults := NewAuditConfig()
+	auditCmd.Flags().String("conversation-id", defaults.ConversationID, "Only show requests made by this conversation")
+	auditCmd.Flags().String("domain", defaults.Domain, "Only show requests to this domain or its subdomains")
+	auditCmd.Flags().String("tool", defaults.Tool, "Only show requests made by this tool")
+	auditCmd.Flags().String("since", defaults.Since, "Show requests since this time (e.g., 2025-06-01, 1d, 1w)")
+	auditCmd.Flags().String("until", defaults.Until, "Show requests until this time (e.g., 2025-06-01)")
+	auditCmd.Flags().Int("limit", defaults.Limit, "Maximum number of newest requests to show (0 for all)")
+	auditCmd.Flags().String("format", defaults.Format, "Output format: table or json")
+}
+
+func getAuditConfigFromFlags(cmd *cobra.Command) *AuditConfig {
+	config := NewAuditConfig()
+
+	if conversationID, err := cmd.Flags().GetString("conversation-id"); err == nil {
+		config.ConversationID = conversationID
+	}
+	if domain, err := cmd.Flags().GetString("domain"); err == nil {
+		config.Domain = domain
+	}
+	if tool, err := cmd.Flags().GetString("tool"); err == nil {
+		config.Tool = tool
+	}
+	if since, err := cmd.Flags().GetString("since"); err == nil {
+		config.Since = since
+	}
+	if until, err := cmd.Flags().GetString("until"); err == nil {
+		config.Until = until
+	}
+	if limit, err := cmd.Flags().GetInt("limit"); err == nil {
+		config.Limit = limit
+	}
+	if format, err := cmd.Flags().GetString("format"); err == nil {
+		config.Format = format
+	}
+
+	return config
+}
+
+func runAuditCmd(w io.Writer, config *AuditConfig) error {
+	filter := audit.EgressFilter{
+		ConversationID: config.ConversationID,
+		Domain:         config.Domain,
+		Tool:           config.Tool,
+		Limit:          config.Limit,
+	}
+
+	var err error
+	if filter.Since, err = parseTimeSpec(config.Since); err != nil {
+		return errors.Wrap(err, "invalid since time specification")
+	}
+	if filter.Until, err = parseTimeSpec(config.Until); err != nil {
+		return errors.Wrap(err, "invalid until time specification")
+	}
+
+	log, err := audit.NewEgressLog()
+	if err != nil {
+		return err
+	}
+	entries, err := log.Read(filter)
+	if err != nil {
+		return err
+	}
+
+	switch config.Format {
+	case "json":
+		return displayAuditJSON(w, entries)
+	case "table":
+		if len(entries) == 0 {
+			fmt.Fprintln(w, "No egress requests recorded.")
+			return nil
+		}
+		displayAuditTable(w, entries)
+		return nil
+	default:
+		return errors.Errorf("invalid format %q (expected table or json)", config.Format)
+	}
+}
+
+func displayAuditTable(w io.Writer, entries []audit.EgressEntry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "Time\tConversation\tTool\tMethod\tDomain\tStatus\tSent\tReceived\tURL")
+	for _, entry := range entries {
+		status := fmt.Sprintf("%d", entry.Status)
+		if entry.Error != "" {
+			status = "error"
+		}
+		conversationID := entry.ConversationID
+		if conversationID == "" {
+			conversationID = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.Timestamp.Local().Format(time.DateTime),
+			conversationID,
+			entry.Tool,
+			entry.Method,
+			entry.Domain,
+			status,
+			usage.FormatNumber(int(entry.BytesSent)),
+			usage.FormatNumber(int(entry.BytesReceived)),
+			entry.URL,
+		)
+	}
+
+	tw.Flush()
+}
+
+func displayAuditJSON(w io.Writer, entries []audit.EgressEntry) error {
+	if entries == nil {
+		entries = []audit.EgressEntry{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunAuditCmd(t *testing.T) {
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+
+	var out bytes.Buffer
+	require.NoError(t, runAuditCmd(&out, NewAuditConfig()))
+	assert.Contains(t, out.String(), "No egress requests recorded.")
+
+	log, err := audit.NewEgressLog()
+	require.NoError(t, err)
+	now := time.Now()
+	require.NoError(t, log.Append(audit.EgressEntry{Timestamp: now.Add(-time.Minute), ConversationID: "conv-1", Tool: "web_fetch", Method: "GET", Domain: "docs.github.com", URL: "https://docs.github.com/", Status: 200, BytesReceived: 2048}))
+	require.NoError(t, log.Append(audit.EgressEntry{Timestamp: now, ConversationID: "conv-2", Tool: "mcp:remote", Method: "POST", Domain: "mcp.example", URL: "https://mcp.example/rpc", Error: "connection refused"}))
+
+	out.Reset()
+	require.NoError(t, runAuditCmd(&out, NewAuditConfig()))
+	assert.Contains(t, out.String(), "docs.github.com")
+	assert.Contains(t, out.String(), "2,048")
+	assert.Contains(t, out.String(), "error")
+
+	config := NewAuditConfig()
+	config.Domain = "github.com"
+	config.Format = "json"
+	out.Reset()
+	require.NoError(t, runAuditCmd(&out, config))
+	var entries []audit.EgressEntry
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, "conv-1", entries[0].ConversationID)
+
+	config = NewAuditConfig()
+	config.Format = "xml"
+	assert.Error(t, runAuditCmd(&out, config))
+}
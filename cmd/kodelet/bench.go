@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/bench"
+	"github.com/jingkaihe/kodelet/pkg/fragments"
+	"github.com/jingkaihe/kodelet/pkg/llm"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/tools"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench [prompt...]",
+	Short: "Compare models on the same set of prompts",
+	Long: `Run the same prompts against several models and compare their wall time,
+turns, tokens, cost and whether a validation command passed afterwards.
+
+Each --target names a model, or a configuration profile as profile:<name>.
+Prompts come from the arguments, --recipe, or a YAML suite given with --suite:
+
+  targets:
+    - model: claude-sonnet-4-6
+    - model: gpt-5.5
+    - profile: work
+  validate: go test ./...
+  max_turns: 30
+  timeout: 15m
+  cases:
+    - name: fix-flaky-test
+      prompt: Fix the flaky TestWatcher test.
+    - recipe: github/pr-review
+      args:
+        pr: "42"
+
+Every case runs on every target, one run at a time. Inside a git repository,
+each run works in its own worktree checked out at HEAD, so uncommitted changes
+are not included. Runs are not saved as conversations.
+
+Examples:
+  kodelet bench --target claude-sonnet-4-6 --target gpt-5.5 "Add a --json flag to the list command" --validate "make test"
+  kodelet bench --suite bench.yaml
+  kodelet bench --suite bench.yaml --target profile:cheap --json > results.json
+`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		suite, err := benchSuiteFromFlags(cmd, args)
+		if err != nil {
+			return err
+		}
+		if err := renderBenchRecipes(ctx, &suite); err != nil {
+			return err
+		}
+		if !cmd.Flags().Changed("log-level") {
+			logger.SetLogLevel("warn")
+		}
+
+		workingDir, err := os.Getwd()
+		if err != nil {
+			return errors.Wrap(err, "failed to get working directory")
+		}
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		runner := &bench.Runner{
+			Dir:       workingDir,
+			NewThread: benchThreadFactory(cmd),
+		}
+		if !asJSON {
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Running %d cases on %d targets.\n", len(suite.Cases), len(suite.Targets))
+			runner.OnResult = func(result bench.Result) {
+				status := "ok"
+				if !result.Succeeded() {
+					status = "failed"
+				}
+				fmt.Fprintf(out, "  %s on %s: %s in %s\n", result.Case, result.Target, status, (time.Duration(result.DurationMS) * time.Millisecond).Round(time.Second))
+			}
+		}
+
+		results := runner.Run(ctx, suite)
+		return writeBenchReport(cmd.OutOrStdout(), results, asJSON)
+	},
+}
+
+func init() {
+	addBenchFlags(benchCmd)
+}
+
+func addBenchFlags(cmd *cobra.Command) {
+	cmd.Flags().String("suite", "", "YAML file listing the targets and cases to run")
+	cmd.Flags().StringArray("target", nil, "Model to compare, or profile:<name> for a configuration profile (repeatable; replaces the suite's targets)")
+	cmd.Flags().StringP("recipe", "r", "", "Add a case rendered from this recipe")
+	cmd.Flags().StringToString("arg", map[string]string{}, "Arguments passed to --recipe (e.g., --arg pr=42)")
+	cmd.Flags().String("validate", "", "Shell command run after each case that decides whether it succeeded (overrides the suite's)")
+	cmd.Flags().Int("max-turns", 0, "Maximum number of agentic turns per run (0 for the suite's, or no limit)")
+	cmd.Flags().Duration("timeout", 0, "Maximum wall time per run (0 for the suite's, or no limit)")
+	cmd.Flags().Bool("json", false, "Print the results and summaries as JSON")
+}
+
+// benchSuiteFromFlags builds the suite of a bench run from --suite, with
+// the prompts, recipe, targets and limits given on the command line.
+func benchSuiteFromFlags(cmd *cobra.Command, args []string) (bench.Suite, error) {
+	var suite bench.Suite
+	if path, _ := cmd.Flags().GetString("suite"); path != "" {
+		loaded, err := bench.LoadSuite(path)
+		if err != nil {
+			return suite, err
+		}
+		suite = loaded
+	}
+
+	if prompt := strings.TrimSpace(strings.Join(args, " ")); prompt != "" {
+		suite.Cases = append(suite.Cases, bench.Case{Prompt: prompt})
+	}
+	if recipe, _ := cmd.Flags().GetString("recipe"); recipe != "" {
+		recipeArgs, _ := cmd.Flags().GetStringToString("arg")
+		suite.Cases = append(suite.Cases, bench.Case{Recipe: recipe, Args: recipeArgs})
+	}
+
+	if targets, _ := cmd.Flags().GetStringArray("target"); len(targets) > 0 {
+		suite.Targets = nil
+		for _, value := range targets {
+			target, err := bench.ParseTarget(value)
+			if err != nil {
+				return suite, err
+			}
+			suite.Targets = append(suite.Targets, target)
+		}
+	}
+	if cmd.Flags().Changed("validate") {
+		suite.Validate, _ = cmd.Flags().GetString("validate")
+	}
+	if cmd.Flags().Changed("max-turns") {
+		suite.MaxTurns, _ = cmd.Flags().GetInt("max-turns")
+	}
+	if cmd.Flags().Changed("timeout") {
+		suite.Timeout, _ = cmd.Flags().GetDuration("timeout")
+	}
+	return suite, suite.Check()
+}
+
+// renderBenchRecipes turns the recipe of each case into its prompt, so every
+// target receives the same text.
+func renderBenchRecipes(ctx context.Context, suite *bench.Suite) error {
+	var processor *fragments.Processor
+	for i := range suite.Cases {
+		c := &suite.Cases[i]
+		if c.Recipe == "" {
+			continue
+		}
+		if processor == nil {
+			var err error
+			if processor, err = fragments.NewFragmentProcessor(); err != nil {
+				return errors.Wrap(err, "failed to create fragment processor")
+			}
+		}
+		fragment, err := processor.LoadFragment(ctx, &fragments.Config{FragmentName: c.Recipe, Arguments: c.Args})
+		if err != nil {
+			return errors.Wrapf(err, "failed to render recipe %s of case %s", c.Recipe, c.Name)
+		}
+		if prompt := strings.TrimSpace(c.Prompt); prompt != "" {
+			c.Prompt = fragment.Content + "\n" + prompt
+		} else {
+			c.Prompt = fragment.Content
+		}
+	}
+	return nil
+}
+
+// benchThreadFactory creates the thread of each bench run from the
+// configuration of its target, with the main tools working in the run's
+// workspace. Conversations are not persisted.
+func benchThreadFactory(cmd *cobra.Command) bench.ThreadFactory {
+	return func(ctx context.Context, target bench.Target, dir string) (llmtypes.Thread, error) {
+		config, err := llm.GetConfigFromViperForModel(target.Profile, target.Model, cmd)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load configuration of %s", target.Label())
+		}
+		config.WorkingDirectory = dir
+
+		thread, err := llm.NewThread(config)
+		if err != nil {
+			return nil, err
+		}
+		thread.EnablePersistence(ctx, false)
+		thread.SetState(tools.NewBasicState(ctx,
+			tools.WithWorkingDirectory(dir),
+			tools.WithLLMConfig(config),
+			tools.WithMainTools(),
+			tools.WithSkillTool(),
+		))
+		return thread, nil
+	}
+}
+
+// benchReport is the JSON output of `kodelet bench --json`.
+type benchReport struct {
+	Results   []bench.Result  `json:"results"`
+	Summaries []bench.Summary `json:"summaries"`
+}
+
+func writeBenchReport(w io.Writer, results []bench.Result, asJSON bool) error {
+	summaries := bench.Summarize(results)
+	if asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(benchReport{Results: results, Summaries: summaries})
+	}
+	if len(results) == 0 {
+		fmt.Fprintln(w, "No runs finished.")
+		return nil
+	}
+
+	fmt.Fprintln(w)
+	bench.WriteResults(w, results)
+	fmt.Fprintln(w)
+	bench.WriteSummaries(w, summaries)
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/bench"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBenchTestCmd(t *testing.T, args ...string) *cobra.Command {
+	t.Helper()
+	cmd := &cobra.Command{}
+	addBenchFlags(cmd)
+	require.NoError(t, cmd.Flags().Parse(args))
+	return cmd
+}
+
+func TestBenchSuiteFromFlags(t *testing.T) {
+	t.Run("prompt and targets from flags", func(t *testing.T) {
+		cmd := newBenchTestCmd(t, "--target", "claude-sonnet-4-6", "--target", "profile:cheap", "--validate", "make test", "--timeout", "5m")
+		suite, err := benchSuiteFromFlags(cmd, []string{"fix", "the", "bug"})
+		require.NoError(t, err)
+
+		assert.Equal(t, []bench.Target{{Model: "claude-sonnet-4-6"}, {Profile: "cheap"}}, suite.Targets)
+		require.Len(t, suite.Cases, 1)
+		assert.Equal(t, "fix the bug", suite.Cases[0].Prompt)
+		assert.Equal(t, "case-1", suite.Cases[0].Name)
+		assert.Equal(t, "make test", suite.Validate)
+		assert.Equal(t, 5*time.Minute, suite.Timeout)
+	})
+
+	t.Run("flags override the suite file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "bench.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+targets:
+  - model: gpt-5.5
+validate: go test ./...
+max_turns: 30
+cases:
+  - name: docs
+    prompt: Update the docs
+`), 0o644))
+
+		cmd := newBenchTestCmd(t, "--suite", path, "--target", "claude-sonnet-4-6", "--max-turns", "10")
+		suite, err := benchSuiteFromFlags(cmd, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, []bench.Target{{Model: "claude-sonnet-4-6"}}, suite.Targets)
+		assert.Equal(t, "go test ./...", suite.Validate)
+		assert.Equal(t, 10, suite.MaxTurns)
+		require.Len(t, suite.Cases, 1)
+		assert.Equal(t, "docs", suite.Cases[0].Name)
+	})
+
+	t.Run("recipe case", func(t *testing.T) {
+		cmd := newBenchTestCmd(t, "--target", "gpt-5.5", "-r", "github/pr-review", "--arg", "pr=42")
+		suite, err := benchSuiteFromFlags(cmd, nil)
+		require.NoError(t, err)
+
+		require.Len(t, suite.Cases, 1)
+		assert.Equal(t, "github/pr-review", suite.Cases[0].Name)
+		assert.Equal(t, map[string]string{"pr": "42"}, suite.Cases[0].Args)
+	})
+
+	t.Run("requires targets", func(t *testing.T) {
+		_, err := benchSuiteFromFlags(newBenchTestCmd(t), []string{"hello"})
+		assert.ErrorContains(t, err, "no targets")
+	})
+}
+
+func TestWriteBenchReport(t *testing.T) {
+	results := []bench.Result{
+		{Case: "fix", Target: "claude-sonnet-4-6", Model: "claude-sonnet-4-6", DurationMS: 61500, Turns: 4, InputTokens: 12000, OutputTokens: 800, CostUSD: 0.05, Validation: bench.ValidationPassed},
+		{Case: "fix", Target: "gpt-5.5", Model: "gpt-5.5", DurationMS: 30000, Turns: 2, Validation: bench.ValidationSkipped, Error: "rate limited"},
+	}
+
+	var table bytes.Buffer
+	require.NoError(t, writeBenchReport(&table, results, false))
+	assert.Contains(t, table.String(), "rate limited")
+	assert.Contains(t, table.String(), "1/1 (100%)")
+	assert.Contains(t, table.String(), "0/1 (0%)")
+
+	var out bytes.Buffer
+	require.NoError(t, writeBenchReport(&out, results, true))
+	var report benchReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assert.Len(t, report.Results, 2)
+	require.Len(t, report.Summaries, 2)
+	assert.Equal(t, 1, report.Summaries[0].Succeeded)
+
+	var empty bytes.Buffer
+	require.NoError(t, writeBenchReport(&empty, nil, false))
+	assert.Equal(t, "No runs finished.\n", empty.String())
+}
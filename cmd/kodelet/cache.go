@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/extensions"
+	"github.com/jingkaihe/kodelet/pkg/llm"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/promptcache"
+	"github.com/jingkaihe/kodelet/pkg/toolcache"
+	"github.com/jingkaihe/kodelet/pkg/tools"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/jingkaihe/kodelet/pkg/usage"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage provider prompt caches and the tool result cache",
+	Long:  `Commands for managing the prompt caches kept by LLM providers and the tool result cache enabled by tool_cache.`,
+}
+
+var cachePrimeCmd = &cobra.Command{
+	Use:   "prime",
+	Short: "Write the system prompt and tools to the provider's prompt cache",
+	Long: `Send a minimal request carrying the system prompt, discovered contexts
+(AGENTS.md and friends) and tool definitions for the current directory, so the
+provider caches them and the first real exchange reads them at the cache read
+price.
+
+The cache stays warm for --ttl (5m or 1h); every exchange that reads it extends
+it again. Primed caches and their expiry are listed by 'kodelet usage'.
+
+Only the Anthropic provider supports priming. OpenAI caches prompt prefixes
+automatically on the first exchange.
+
+Examples:
+  kodelet cache prime                        # Prime for one hour
+  kodelet cache prime --ttl 5m               # Cheaper write, shorter life
+  kodelet cache prime --profile work         # Prime the model of a profile
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		ctx := cmd.Context()
+		ttl, _ := cmd.Flags().GetDuration("ttl")
+
+		config, err := llm.GetConfigFromViperWithCmd(cmd)
+		if err != nil {
+			return errors.Wrap(err, "failed to load configuration")
+		}
+		workingDir, err := os.Getwd()
+		if err != nil {
+			return errors.Wrap(err, "failed to get working directory")
+		}
+
+		extensionRuntime, err := extensions.NewRuntimeFromViper(ctx, workingDir)
+		if err != nil {
+			return errors.Wrap(err, "failed to initialize extensions")
+		}
+		stateOpts := []tools.BasicStateOption{tools.WithLLMConfig(config), tools.WithMainTools(), tools.WithSkillTool()}
+		if extensionRuntime != nil {
+			defer func() { _ = extensionRuntime.Close() }()
+			config.Extensions = extensionRuntime
+			stateOpts = append(stateOpts, tools.WithExtensionTools(extensionRuntime.Tools()))
+		}
+
+		thread, err := llm.NewThread(config)
+		if err != nil {
+			return errors.Wrap(err, "failed to create LLM thread")
+		}
+		defer func() { _ = llm.CloseThread(thread) }()
+		thread.SetState(tools.NewBasicState(ctx, stateOpts...))
+
+		recordPath, err := promptcache.DefaultPath()
+		if err != nil {
+			return err
+		}
+		return runCachePrime(ctx, cmd.OutOrStdout(), thread, recordPath, workingDir, ttl, time.Now())
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every cached tool result",
+	Long: `Remove the tool results cached by tool_cache, so the next calls run the
+tools again. Provider prompt caches are not affected.
+
+Examples:
+  kodelet cache clear
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		dir, err := toolcache.DefaultDir()
+		if err != nil {
+			return err
+		}
+		return runCacheClear(cmd.OutOrStdout(), dir)
+	},
+}
+
+func init() {
+	cachePrimeCmd.Flags().Duration("ttl", time.Hour, "How long the cache stays warm: 5m or 1h")
+	cacheCmd.AddCommand(cachePrimeCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+}
+
+func runCacheClear(w io.Writer, dir string) error {
+	removed, size, err := toolcache.Clear(dir)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		fmt.Fprintln(w, "The tool result cache is empty.")
+		return nil
+	}
+	fmt.Fprintf(w, "Removed %s cached tool results (%s).\n", usage.FormatNumber(removed), formatByteSize(size))
+	return nil
+}
+
+func runCachePrime(ctx context.Context, w io.Writer, thread llmtypes.Thread, recordPath, workingDir string, ttl time.Duration, now time.Time) error {
+	primer, ok := thread.(llmtypes.CachePrimer)
+	if !ok {
+		return errors.Errorf("the %s provider does not support prompt cache priming", thread.Provider())
+	}
+
+	result, err := primer.PrimePromptCache(ctx, ttl)
+	if err != nil {
+		return err
+	}
+
+	record := promptcache.Record{
+		Provider:         thread.Provider(),
+		Model:            result.Model,
+		WorkingDirectory: workingDir,
+		Tokens:           result.CacheWriteTokens + result.CacheReadTokens,
+		TTL:              result.TTL.String(),
+		PrimedAt:         now,
+		ExpiresAt:        now.Add(result.TTL),
+	}
+	if err := promptcache.Save(recordPath, record); err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to record the primed prompt cache")
+	}
+
+	if record.Tokens == 0 {
+		fmt.Fprintf(w, "Nothing was cached for %s: the prompt is shorter than the model's minimum cacheable length.\n", result.Model)
+		return nil
+	}
+	fmt.Fprintf(w, "Primed the prompt cache for %s: %s tokens written, %s already cached, cost $%.4f.\n",
+		result.Model, usage.FormatNumber(result.CacheWriteTokens), usage.FormatNumber(result.CacheReadTokens), result.Cost)
+	fmt.Fprintf(w, "The cache expires at %s unless an exchange reads it first.\n", record.ExpiresAt.Local().Format("15:04:05"))
+	return nil
+}
+
+// displayPrimedCaches lists the primed prompt caches that are still warm.
+func displayPrimedCaches(w io.Writer, records []promptcache.Record, now time.Time) {
+	active := promptcache.Active(records, now)
+	if len(active) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Primed prompt caches:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Model\tDirectory\tTokens\tTTL\tExpires")
+	for _, record := range active {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s (in %s)\n",
+			record.Model,
+			record.WorkingDirectory,
+			usage.FormatNumber(record.Tokens),
+			record.TTL,
+			record.ExpiresAt.Local().Format("15:04:05"),
+			record.Remaining(now).Round(time.Second),
+		)
+	}
+	tw.Flush()
+}
+
+// loadPrimedCaches returns the recorded primed prompt caches, logging
+// instead of failing when they cannot be read.
+func loadPrimedCaches(ctx context.Context) []promptcache.Record {
+	path, err := promptcache.DefaultPath()
+	if err == nil {
+		var records []promptcache.Record
+		if records, err = promptcache.Load(path); err == nil {
+			return records
+		}
+	}
+	logger.G(ctx).WithError(err).Debug("failed to load primed prompt caches")
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/promptcache"
+	"github.com/jingkaihe/kodelet/pkg/toolcache"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type cachePrimeThreadStub struct {
+	llmtypes.Thread
+	provider string
+	result   llmtypes.CachePrimeResult
+}
+
+func (t *cachePrimeThreadStub) Provider() string { return t.provider }
+
+type primingThreadStub struct {
+	cachePrimeThreadStub
+}
+
+func (t *primingThreadStub) PrimePromptCache(_ context.Context, ttl time.Duration) (llmtypes.CachePrimeResult, error) {
+	result := t.result
+	result.TTL = ttl
+	return result, nil
+}
+
+func TestRunCachePrime(t *testing.T) {
+	recordPath := filepath.Join(t.TempDir(), "primed.json")
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+
+	t.Run("records the primed cache", func(t *testing.T) {
+		thread := &primingThreadStub{cachePrimeThreadStub{
+			provider: "anthropic",
+			result:   llmtypes.CachePrimeResult{Model: "claude-sonnet-4-6", CacheWriteTokens: 12000, CacheReadTokens: 500, Cost: 0.05},
+		}}
+
+		var out bytes.Buffer
+		require.NoError(t, runCachePrime(context.Background(), &out, thread, recordPath, "/repo", time.Hour, now))
+		assert.Contains(t, out.String(), "Primed the prompt cache for claude-sonnet-4-6: 12,000 tokens written, 500 already cached, cost $0.0500.")
+		assert.Contains(t, out.String(), "expires at 10:00:00")
+
+		records, err := promptcache.Load(recordPath)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, "/repo", records[0].WorkingDirectory)
+		assert.Equal(t, 12500, records[0].Tokens)
+		assert.Equal(t, "1h0m0s", records[0].TTL)
+		assert.True(t, now.Add(time.Hour).Equal(records[0].ExpiresAt))
+
+		var listing bytes.Buffer
+		displayPrimedCaches(&listing, records, now.Add(15*time.Minute))
+		assert.Contains(t, listing.String(), "Primed prompt caches:")
+		assert.Contains(t, listing.String(), "claude-sonnet-4-6")
+		assert.Contains(t, listing.String(), "(in 45m0s)")
+
+		listing.Reset()
+		displayPrimedCaches(&listing, records, now.Add(2*time.Hour))
+		assert.Empty(t, listing.String(), "expired caches are not listed")
+	})
+
+	t.Run("reports prompts too short to cache", func(t *testing.T) {
+		thread := &primingThreadStub{cachePrimeThreadStub{provider: "anthropic", result: llmtypes.CachePrimeResult{Model: "claude-haiku-4-5"}}}
+
+		var out bytes.Buffer
+		require.NoError(t, runCachePrime(context.Background(), &out, thread, recordPath, "/repo", 5*time.Minute, now))
+		assert.Contains(t, out.String(), "Nothing was cached for claude-haiku-4-5")
+	})
+
+	t.Run("rejects providers without priming", func(t *testing.T) {
+		err := runCachePrime(context.Background(), &bytes.Buffer{}, &cachePrimeThreadStub{provider: "openai"}, recordPath, "/repo", time.Hour, now)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "the openai provider does not support prompt cache priming")
+	})
+}
+
+func TestRunCacheClear(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tools")
+
+	var out bytes.Buffer
+	require.NoError(t, runCacheClear(&out, dir))
+	assert.Equal(t, "The tool result cache is empty.\n", out.String())
+
+	store := toolcache.NewStore(dir, 0)
+	result := tooltypes.BaseToolResult{Result: "match"}
+	require.NoError(t, store.Put("a", toolcache.NewEntry("grep_tool", result, time.Now(), time.Hour)))
+	require.NoError(t, store.Put("b", toolcache.NewEntry("glob_tool", result, time.Now(), time.Hour)))
+
+	out.Reset()
+	require.NoError(t, runCacheClear(&out, dir))
+	assert.Contains(t, out.String(), "Removed 2 cached tool results")
+	_, ok := store.Get("a", time.Now())
+	assert.False(t, ok)
+}
@@ -9,8 +9,10 @@ import (
 
 	chatpkg "github.com/jingkaihe/kodelet/pkg/chat"
 	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/llm"
 	"github.com/jingkaihe/kodelet/pkg/logger"
 	"github.com/jingkaihe/kodelet/pkg/presenter"
+	"github.com/jingkaihe/kodelet/pkg/toolapproval"
 	"github.com/jingkaihe/kodelet/pkg/tui"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -24,6 +26,10 @@ type ChatConfig struct {
 	Follow       bool
 	NoExtensions bool
 	NoTools      bool
+	NoRender     bool
+	ReviewEdits  bool
+	ApproveTools bool
+	DeferTools   bool
 }
 
 func NewChatConfig() *ChatConfig {
@@ -49,6 +55,10 @@ var chatCmd = &cobra.Command{
 		if reasoningEffortExplicit {
 			reasoningEffort, _ = cmd.Flags().GetString("reasoning-effort")
 		}
+		if err := translateChatResumeConversation(ctx, cmd, config.ResumeConvID); err != nil {
+			presenter.Error(err, "Failed to resume conversation")
+			os.Exit(1)
+		}
 		if err := validateChatResumeConversation(ctx, config.ResumeConvID, reasoningEffort); err != nil {
 			presenter.Error(err, "Failed to resume conversation")
 			os.Exit(1)
@@ -68,6 +78,8 @@ var chatCmd = &cobra.Command{
 			ReasoningEffortExplicit: reasoningEffortExplicit,
 			CWD:                     config.CWD,
 			Theme:                   config.Theme,
+			NoRender:                config.NoRender,
+			Language:                chatLanguage(profile),
 		}); err != nil {
 			presenter.Error(err, "Chat failed")
 			os.Exit(1)
@@ -75,6 +87,15 @@ var chatCmd = &cobra.Command{
 	},
 }
 
+// chatLanguage returns the UI language configured for the chat's profile.
+func chatLanguage(profile string) string {
+	llmConfig, err := llm.GetConfigFromViperWithProfile(profile)
+	if err != nil {
+		return viper.GetString("language")
+	}
+	return llmConfig.Language
+}
+
 func applyChatRuntimeRestrictions(config *ChatConfig) {
 	if config.NoExtensions || config.NoTools {
 		viper.Set("extensions.enabled", false)
@@ -82,6 +103,15 @@ func applyChatRuntimeRestrictions(config *ChatConfig) {
 	if config.NoTools {
 		viper.Set("allowed_tools", []string{"none"})
 	}
+	if config.ReviewEdits {
+		viper.Set("review_edits", true)
+	}
+	if config.ApproveTools || config.DeferTools {
+		viper.Set("tool_approval.enabled", true)
+	}
+	if config.DeferTools {
+		viper.Set("tool_approval.mode", toolapproval.ModeDefer)
+	}
 }
 
 func init() {
@@ -92,6 +122,10 @@ func init() {
 	chatCmd.Flags().BoolP("follow", "f", defaults.Follow, "Follow the most recent conversation")
 	chatCmd.Flags().Bool("no-extensions", defaults.NoExtensions, "Disable extension runtime")
 	chatCmd.Flags().Bool("no-tools", defaults.NoTools, "Disable all tools (for simple query-response usage)")
+	chatCmd.Flags().Bool("no-render", defaults.NoRender, "Show assistant responses as raw markdown instead of rendering them")
+	chatCmd.Flags().Bool("review-edits", defaults.ReviewEdits, "Review each hunk of a file change before it is written")
+	chatCmd.Flags().Bool("approve-tools", defaults.ApproveTools, "Ask for confirmation before tool calls on the tool_approval.risks list run")
+	chatCmd.Flags().Bool("defer-tools", defaults.DeferTools, "Queue tool calls on the tool_approval.risks list and review them together when the agent finishes")
 }
 
 func getChatConfigFromFlags(ctx context.Context, cmd *cobra.Command) *ChatConfig {
@@ -126,6 +160,18 @@ func getChatConfigFromFlags(ctx context.Context, cmd *cobra.Command) *ChatConfig
 	if noTools, err := cmd.Flags().GetBool("no-tools"); err == nil {
 		config.NoTools = noTools
 	}
+	if noRender, err := cmd.Flags().GetBool("no-render"); err == nil {
+		config.NoRender = noRender
+	}
+	if reviewEdits, err := cmd.Flags().GetBool("review-edits"); err == nil {
+		config.ReviewEdits = reviewEdits
+	}
+	if approveTools, err := cmd.Flags().GetBool("approve-tools"); err == nil {
+		config.ApproveTools = approveTools
+	}
+	if deferTools, err := cmd.Flags().GetBool("defer-tools"); err == nil {
+		config.DeferTools = deferTools
+	}
 
 	return config
 }
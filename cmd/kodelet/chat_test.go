@@ -93,6 +93,44 @@ func TestChatNoToolsDisablesExtensionStartup(t *testing.T) {
 	assert.False(t, extensions.LoadConfigFromViper().Enabled)
 }
 
+func TestChatReviewEditsEnablesEditReview(t *testing.T) {
+	originalSettings := viper.AllSettings()
+	viper.Reset()
+	t.Cleanup(func() {
+		viper.Reset()
+		for key, value := range originalSettings {
+			viper.Set(key, value)
+		}
+	})
+
+	applyChatRuntimeRestrictions(&ChatConfig{})
+	assert.False(t, viper.GetBool("review_edits"))
+
+	applyChatRuntimeRestrictions(&ChatConfig{ReviewEdits: true})
+	assert.True(t, viper.GetBool("review_edits"))
+}
+
+func TestChatApproveToolsEnablesToolApproval(t *testing.T) {
+	originalSettings := viper.AllSettings()
+	viper.Reset()
+	t.Cleanup(func() {
+		viper.Reset()
+		for key, value := range originalSettings {
+			viper.Set(key, value)
+		}
+	})
+
+	applyChatRuntimeRestrictions(&ChatConfig{})
+	assert.False(t, viper.GetBool("tool_approval.enabled"))
+
+	applyChatRuntimeRestrictions(&ChatConfig{ApproveTools: true})
+	assert.True(t, viper.GetBool("tool_approval.enabled"))
+	assert.Empty(t, viper.GetString("tool_approval.mode"))
+
+	applyChatRuntimeRestrictions(&ChatConfig{DeferTools: true})
+	assert.Equal(t, "defer", viper.GetString("tool_approval.mode"))
+}
+
 func TestValidateChatResumeConversationRejectsMissingConversation(t *testing.T) {
 	setupChatConversationStore(t)
 
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/jingkaihe/kodelet/pkg/presenter"
 	"github.com/jingkaihe/kodelet/pkg/tools"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
@@ -21,16 +23,20 @@ type CommitConfig struct {
 	Prefix    string
 	NoConfirm bool
 	Save      bool
+	// ResolveConflicts resolves merge conflicts in the working tree with a
+	// restricted agent before committing.
+	ResolveConflicts bool
 }
 
 func NewCommitConfig() *CommitConfig {
 	return &CommitConfig{
-		NoSign:    false,
-		Template:  "",
-		Short:     true,
-		Prefix:    "",
-		NoConfirm: false,
-		Save:      false,
+		NoSign:           false,
+		Template:         "",
+		Short:            true,
+		Prefix:           "",
+		NoConfirm:        false,
+		Save:             false,
+		ResolveConflicts: false,
 	}
 }
 
@@ -57,50 +63,29 @@ You must stage your changes (using 'git add') before running this command.`,
 			os.Exit(1)
 		}
 
-		if !hasStagedChanges() {
-			presenter.Error(errors.New("no staged changes found"), "Please stage your changes using 'git add' first")
+		cwd, err := os.Getwd()
+		if err != nil {
+			presenter.Error(err, "Failed to get current working directory")
 			os.Exit(1)
 		}
-
-		processor, err := fragments.NewFragmentProcessor()
-		if err != nil {
-			presenter.Error(err, "Failed to create fragment processor")
+		if !checkGitConflicts(ctx, llmConfig, cwd, config.ResolveConflicts, !config.NoConfirm && stdinIsTerminal()) {
+			presenter.Error(errUnresolvedConflicts, "Please resolve the conflicts before committing")
 			os.Exit(1)
 		}
 
-		fragmentArgs := map[string]string{}
-
-		if config.Template != "" {
-			fragmentArgs["template"] = config.Template
+		if !hasStagedChanges() {
+			presenter.Error(errors.New("no staged changes found"), "Please stage your changes using 'git add' first")
+			os.Exit(1)
 		}
 
-		if config.Short {
-			fragmentArgs["short"] = "true"
-		}
+		presenter.Info("Analyzing staged changes and generating commit message...")
 
-		fragment, err := processor.LoadFragment(ctx, &fragments.Config{
-			FragmentName: "commit",
-			Arguments:    fragmentArgs,
-		})
+		commitMsg, usage, err := generateCommitMessage(ctx, s, llmConfig, config)
 		if err != nil {
-			presenter.Error(err, "Failed to load built-in commit recipe")
+			presenter.Error(err, "Failed to generate commit message")
 			os.Exit(1)
 		}
 
-		prompt := fragment.Content
-
-		presenter.Info("Analyzing staged changes and generating commit message...")
-
-		commitMsg, usage := llm.SendMessageAndGetTextWithUsage(ctx, s, prompt, llmConfig, true, llmtypes.MessageOpt{
-			UseWeakModel:       true,
-			PromptCache:        false,
-			NoToolUse:          true,
-			DisableUsageLog:    true,
-			NoSaveConversation: !config.Save,
-		})
-		commitMsg = sanitizeCommitMessage(commitMsg)
-		commitMsg = prefixCommitMessage(commitMsg, config.Prefix)
-
 		presenter.Section("Generated Commit Message")
 		presenter.Info(commitMsg)
 
@@ -134,6 +119,7 @@ func init() {
 	commitCmd.Flags().String("prefix", defaults.Prefix, "Prefix to prepend to the generated commit message")
 	commitCmd.Flags().Bool("no-confirm", defaults.NoConfirm, "Skip confirmation prompt and create commit automatically")
 	commitCmd.Flags().Bool("save", defaults.Save, "Enable conversation persistence")
+	commitCmd.Flags().Bool("resolve-conflicts", defaults.ResolveConflicts, "Resolve merge conflicts with an agent restricted to the conflicting hunks before committing")
 }
 
 func getCommitConfigFromFlags(cmd *cobra.Command) *CommitConfig {
@@ -157,10 +143,49 @@ func getCommitConfigFromFlags(cmd *cobra.Command) *CommitConfig {
 	if save, err := cmd.Flags().GetBool("save"); err == nil {
 		config.Save = save
 	}
+	if resolveConflicts, err := cmd.Flags().GetBool("resolve-conflicts"); err == nil {
+		config.ResolveConflicts = resolveConflicts
+	}
 
 	return config
 }
 
+// generateCommitMessage renders the built-in commit recipe for the staged
+// changes and asks the weak model for a commit message.
+func generateCommitMessage(ctx context.Context, state tooltypes.State, llmConfig llmtypes.Config, config *CommitConfig) (string, llmtypes.Usage, error) {
+	processor, err := fragments.NewFragmentProcessor()
+	if err != nil {
+		return "", llmtypes.Usage{}, errors.Wrap(err, "failed to create fragment processor")
+	}
+
+	fragmentArgs := map[string]string{}
+	if config.Template != "" {
+		fragmentArgs["template"] = config.Template
+	}
+	if config.Short {
+		fragmentArgs["short"] = "true"
+	}
+
+	fragment, err := processor.LoadFragment(ctx, &fragments.Config{
+		FragmentName: "commit",
+		Arguments:    fragmentArgs,
+	})
+	if err != nil {
+		return "", llmtypes.Usage{}, errors.Wrap(err, "failed to load built-in commit recipe")
+	}
+
+	commitMsg, usage := llm.SendMessageAndGetTextWithUsage(ctx, state, fragment.Content, llmConfig, true, llmtypes.MessageOpt{
+		UseWeakModel:       true,
+		PromptCache:        false,
+		NoToolUse:          true,
+		DisableUsageLog:    true,
+		NoSaveConversation: !config.Save,
+	})
+	commitMsg = sanitizeCommitMessage(commitMsg)
+	commitMsg = prefixCommitMessage(commitMsg, config.Prefix)
+	return commitMsg, usage, nil
+}
+
 func sanitizeCommitMessage(message string) string {
 	message = strings.TrimPrefix(message, "```")
 	message = strings.TrimSuffix(message, "```")
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jingkaihe/kodelet/pkg/gitconflicts"
+	"github.com/jingkaihe/kodelet/pkg/llm"
+	"github.com/jingkaihe/kodelet/pkg/markdown"
+	"github.com/jingkaihe/kodelet/pkg/presenter"
+	"github.com/jingkaihe/kodelet/pkg/tools"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+)
+
+// conflictResolverTools returns the only tools the conflict-resolution agent
+// gets. Patch mode has no direct file tools, so the agent edits with
+// apply_patch and locates the markers with grep_tool.
+func conflictResolverTools(mode llmtypes.ToolMode) []string {
+	if mode.IsPatchMode() {
+		return []string{"grep_tool", "apply_patch"}
+	}
+	return []string{"file_read", "file_edit"}
+}
+
+// conflictResolverMaxTurns bounds how long the conflict-resolution agent runs.
+const conflictResolverMaxTurns = 20
+
+// checkGitConflicts reports merge conflicts and upstream divergence in cwd and
+// optionally resolves the conflicts with a restricted agent. It returns false
+// when conflicts remain.
+func checkGitConflicts(ctx context.Context, llmConfig llmtypes.Config, cwd string, resolve, interactive bool) bool {
+	report, err := gitconflicts.Detect(ctx, cwd)
+	if err != nil || report.Empty() {
+		return true
+	}
+
+	presentGitConflicts(report)
+	if !report.HasConflicts() {
+		return true
+	}
+	if !resolvableConflicts(report) {
+		presenter.Warning("These conflicts have no conflict markers to edit; resolve them with git")
+		return false
+	}
+
+	if !resolve && interactive {
+		response := strings.ToLower(strings.TrimSpace(presenter.Prompt("Resolve the conflicting hunks with an agent?", "y/N")))
+		resolve = response == "y" || response == "yes"
+	}
+	if !resolve {
+		presenter.Info("Resolve the conflicts and stage the files, or rerun with --resolve-conflicts")
+		return false
+	}
+
+	presenter.Info("Resolving conflicting hunks...")
+	if err := resolveGitConflicts(ctx, llmConfig, cwd, report); err != nil {
+		presenter.Error(err, "Failed to resolve conflicts")
+		return false
+	}
+
+	remaining, err := gitconflicts.Detect(ctx, cwd)
+	if err != nil {
+		presenter.Error(err, "Failed to check conflicts")
+		return false
+	}
+	if remaining.HasConflicts() {
+		presentGitConflicts(remaining)
+		return false
+	}
+	presenter.Success(fmt.Sprintf("Resolved conflicts in %s", strings.Join(report.Paths(), ", ")))
+	return true
+}
+
+func presentGitConflicts(report gitconflicts.Report) {
+	presenter.Section("Git Conflicts")
+	for _, line := range report.Summary() {
+		if report.HasConflicts() {
+			presenter.Warning(line)
+		} else {
+			presenter.Info(line)
+		}
+	}
+}
+
+func resolvableConflicts(report gitconflicts.Report) bool {
+	for _, file := range report.Files {
+		if len(file.Hunks) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveGitConflicts asks an agent limited to reading and editing files to
+// resolve the conflicting hunks in report, then rejects and undoes any
+// resolution that touched anything else.
+func resolveGitConflicts(ctx context.Context, llmConfig llmtypes.Config, cwd string, report gitconflicts.Report) error {
+	snapshot, err := gitconflicts.Capture(ctx, cwd, report)
+	if err != nil {
+		return err
+	}
+
+	resolverConfig := llmConfig
+	resolverConfig.WorkingDirectory = cwd
+	resolverConfig.AllowedTools = conflictResolverTools(resolverConfig.ToolMode)
+	resolverConfig.AllowedCommands = nil
+	resolverConfig.Extensions = nil
+	state := tools.NewBasicState(ctx, tools.WithLLMConfig(resolverConfig), tools.WithMainTools())
+
+	thread, err := llm.NewThread(resolverConfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the conflict-resolution agent")
+	}
+	defer func() {
+		_ = llm.CloseThread(thread)
+	}()
+	thread.SetState(state)
+	thread.EnablePersistence(ctx, false)
+	_, sendErr := thread.SendMessage(ctx, conflictResolutionPrompt(report), &llmtypes.StringCollectorHandler{Silent: true}, llmtypes.MessageOpt{
+		PromptCache:        true,
+		MaxTurns:           conflictResolverMaxTurns,
+		NoSaveConversation: true,
+	})
+
+	// Undo edits outside the hunks even when the agent failed part way.
+	if err := snapshot.Verify(ctx); err != nil {
+		return err
+	}
+	if sendErr != nil {
+		return errors.Wrap(sendErr, "conflict-resolution agent failed")
+	}
+	return snapshot.MarkResolved(ctx)
+}
+
+func conflictResolutionPrompt(report gitconflicts.Report) string {
+	var b strings.Builder
+	b.WriteString("Resolve the git merge conflicts listed below.\n\n")
+	b.WriteString("Rules:\n")
+	b.WriteString("- Only edit the conflicting hunks, between and including the `<<<<<<<` and `>>>>>>>` markers. Any change outside them is rejected and undone.\n")
+	b.WriteString("- Replace each hunk with a version that keeps the intent of both sides, and remove every conflict marker.\n")
+	b.WriteString("- Do not edit any other file.\n")
+	b.WriteString("- If a hunk cannot be resolved safely, leave it untouched and explain why.\n")
+	if report.Operation != "" {
+		fmt.Fprintf(&b, "\nA %s is in progress.\n", report.Operation)
+	}
+	for _, file := range report.Files {
+		if len(file.Hunks) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n", file.Path)
+		for _, hunk := range file.Hunks {
+			fmt.Fprintf(&b, "\n### Lines %d-%d\n", hunk.StartLine, hunk.EndLine)
+			fmt.Fprintf(&b, "\nOurs (%s):\n```\n%s```\n", labelOrDefault(hunk.OursLabel, "ours"), hunk.Ours)
+			if hunk.Base != "" {
+				fmt.Fprintf(&b, "\nCommon ancestor:\n```\n%s```\n", hunk.Base)
+			}
+			fmt.Fprintf(&b, "\nTheirs (%s):\n```\n%s```\n", labelOrDefault(hunk.TheirsLabel, "theirs"), hunk.Theirs)
+		}
+	}
+	return b.String()
+}
+
+func labelOrDefault(label, fallback string) string {
+	if label == "" {
+		return fallback
+	}
+	return label
+}
+
+// stdinIsTerminal reports whether the user can answer prompts.
+func stdinIsTerminal() bool {
+	return markdown.IsTerminal(os.Stdin)
+}
+
+var errUnresolvedConflicts = errors.New("the working tree has unresolved merge conflicts")
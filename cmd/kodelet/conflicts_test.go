@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/gitconflicts"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConflictResolutionPrompt(t *testing.T) {
+	report := gitconflicts.Report{
+		Operation: "rebase",
+		Files: []gitconflicts.File{
+			{Path: "main.go", Unmerged: true, Hunks: []gitconflicts.Hunk{{
+				StartLine: 3, EndLine: 7, OursLabel: "HEAD", Ours: "a := 1\n", Theirs: "a := 2\n",
+			}}},
+			{Path: "logo.png", Unmerged: true},
+		},
+	}
+
+	prompt := conflictResolutionPrompt(report)
+
+	assert.Contains(t, prompt, "A rebase is in progress.")
+	assert.Contains(t, prompt, "## main.go\n\n### Lines 3-7")
+	assert.Contains(t, prompt, "Ours (HEAD):\n```\na := 1\n```")
+	assert.Contains(t, prompt, "Theirs (theirs):\n```\na := 2\n```")
+	assert.NotContains(t, prompt, "Common ancestor")
+	assert.NotContains(t, prompt, "logo.png")
+	assert.True(t, resolvableConflicts(report))
+	assert.False(t, resolvableConflicts(gitconflicts.Report{Files: report.Files[1:]}))
+}
+
+func TestConflictResolverToolsHonorToolMode(t *testing.T) {
+	assert.Equal(t, []string{"file_read", "file_edit"}, conflictResolverTools(llmtypes.ToolModeFull))
+	assert.Equal(t, []string{"grep_tool", "apply_patch"}, conflictResolverTools(llmtypes.ToolModePatch))
+}
@@ -1,11 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"html"
 	"io"
-	"maps"
 	"net/http"
 	"net/url"
 	"os"
@@ -25,6 +26,8 @@ import (
 	"github.com/jingkaihe/kodelet/pkg/types/tools"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
 )
 
 type ConversationListConfig struct {
@@ -36,6 +39,8 @@ type ConversationListConfig struct {
 	Offset     int
 	SortBy     string
 	SortOrder  string
+	Filters    []string
+	CWD        string
 	JSONOutput bool
 }
 
@@ -49,6 +54,8 @@ func NewConversationListConfig() *ConversationListConfig {
 		Offset:     0,
 		SortBy:     "updated_at",
 		SortOrder:  "desc",
+		Filters:    nil,
+		CWD:        "",
 		JSONOutput: false,
 	}
 }
@@ -90,12 +97,14 @@ func NewConversationImportConfig() *ConversationImportConfig {
 }
 
 type ConversationExportConfig struct {
+	Format        string
 	UseGist       bool
 	UsePublicGist bool
 }
 
 func NewConversationExportConfig() *ConversationExportConfig {
 	return &ConversationExportConfig{
+		Format:        "json",
 		UseGist:       false,
 		UsePublicGist: false,
 	}
@@ -113,6 +122,18 @@ func NewConversationEditConfig() *ConversationEditConfig {
 	}
 }
 
+type ConversationRedactConfig struct {
+	Tools     []string
+	NoConfirm bool
+}
+
+func NewConversationRedactConfig() *ConversationRedactConfig {
+	return &ConversationRedactConfig{
+		Tools:     []string{},
+		NoConfirm: false,
+	}
+}
+
 var conversationCmd = &cobra.Command{
 	Use:   "conversation",
 	Short: "Manage saved conversations",
@@ -125,7 +146,20 @@ var conversationCmd = &cobra.Command{
 var conversationListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all saved conversations",
-	Long:  `List saved conversations with filtering and sorting options.`,
+	Long: `List saved conversations with filtering and sorting options.
+
+The table shows the message count, total cost, provider and model, last
+activity and tags of each conversation. Tags are the profile and experiment
+arm the conversation ran with.
+
+Sort fields: cost, updated, created, messages.
+Filter keys: provider, model, profile.
+
+Examples:
+  kodelet conversation list --sort cost
+  kodelet conversation list --sort messages --sort-order asc
+  kodelet conversation list --filter provider=openai --filter model=gpt-5
+`,
 	Run: func(cmd *cobra.Command, _ []string) {
 		ctx := cmd.Context()
 		config := getConversationListConfigFromFlags(cmd)
@@ -168,7 +202,7 @@ var conversationImportCmd = &cobra.Command{
 
 var conversationExportCmd = &cobra.Command{
 	Use:   "export [conversationID] [path]",
-	Short: "Export a conversation to a file or create a gist",
+	Short: "Export a conversation as JSON, markdown, or HTML to a file or gist",
 	Args:  cobra.RangeArgs(1, 2),
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := cmd.Context()
@@ -194,6 +228,58 @@ var conversationEditCmd = &cobra.Command{
 	},
 }
 
+var conversationRedactCmd = &cobra.Command{
+	Use:   "redact [conversationID]",
+	Short: "Strip the outputs of specific tools from a stored conversation",
+	Long:  "Replace the results of the given tools with placeholders in a stored conversation, for example before sharing or exporting it. Tool calls and their inputs are kept so the conversation can still be resumed.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		config := getConversationRedactConfigFromFlags(cmd)
+		redactConversationCmd(ctx, args[0], config)
+	},
+}
+
+type ConversationSearchConfig struct {
+	ConversationID string
+	CWD            string
+	AllDirs        bool
+	Limit          int
+	JSONOutput     bool
+}
+
+func NewConversationSearchConfig() *ConversationSearchConfig {
+	return &ConversationSearchConfig{
+		ConversationID: "",
+		CWD:            "",
+		AllDirs:        false,
+		Limit:          20,
+		JSONOutput:     false,
+	}
+}
+
+var conversationSearchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Search the messages of saved conversations",
+	Long: `Search the full messages of saved conversations, including history that
+compaction removed from the model's context. Matching is case-insensitive.
+
+By default the conversations that ran in the current directory are searched,
+most recently updated first.
+
+Examples:
+  kodelet conversation search "job queue"
+  kodelet conversation search postgres --conversation <conversationID>
+  kodelet conversation search postgres --all-dirs --json
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		config := getConversationSearchConfigFromFlags(cmd)
+		searchConversationsCmd(ctx, args[0], config)
+	},
+}
+
 type ConversationStreamConfig struct {
 	IncludeHistory bool
 	HistoryOnly    bool
@@ -221,7 +307,7 @@ var conversationStreamCmd = &cobra.Command{
 var conversationForkCmd = &cobra.Command{
 	Use:   "fork [conversationID]",
 	Short: "Fork a conversation to create a copy with reset usage statistics",
-	Long:  "Fork a conversation by copying its messages and context while resetting usage statistics (tokens and costs). If no conversation ID is provided, the most recent conversation will be forked.",
+	Long:  "Fork a conversation by copying its messages, tool results and working directory into a new conversation while resetting usage statistics (tokens and costs). The original conversation is left untouched, so the fork can explore a different approach. If no conversation ID is provided, the most recent conversation will be forked.",
 	Args:  cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := cmd.Context()
@@ -237,12 +323,17 @@ func init() {
 	listDefaults := NewConversationListConfig()
 	conversationListCmd.Flags().String("start", listDefaults.StartDate, "Filter conversations after this date (format: YYYY-MM-DD)")
 	conversationListCmd.Flags().String("end", listDefaults.EndDate, "Filter conversations before this date (format: YYYY-MM-DD)")
-	conversationListCmd.Flags().String("search", listDefaults.Search, "Search term to filter conversations")
+	conversationListCmd.Flags().String("search", listDefaults.Search, "Full-text search over conversation summaries and messages")
 	conversationListCmd.Flags().String("provider", listDefaults.Provider, "Filter conversations by LLM provider (anthropic, openai)")
 	conversationListCmd.Flags().Int("limit", listDefaults.Limit, "Maximum number of conversations to display")
 	conversationListCmd.Flags().Int("offset", listDefaults.Offset, "Offset for pagination")
+	conversationListCmd.Flags().String("sort", "", "Field to sort by: cost, updated, created, messages, or relevance (default with --search)")
 	conversationListCmd.Flags().String("sort-by", listDefaults.SortBy, "Field to sort by: updated_at, created_at, or messages")
+	_ = conversationListCmd.Flags().MarkDeprecated("sort-by", "use --sort instead")
 	conversationListCmd.Flags().String("sort-order", listDefaults.SortOrder, "Sort order: asc (ascending) or desc (descending)")
+	conversationListCmd.Flags().StringArray("filter", listDefaults.Filters, "Filter conversations by key=value: provider, model, or profile (repeatable)")
+	conversationListCmd.Flags().String("cwd", listDefaults.CWD, "Only list conversations started in the project containing this directory (default with no value: current directory)")
+	conversationListCmd.Flags().Lookup("cwd").NoOptDefVal = "."
 	conversationListCmd.Flags().Bool("json", listDefaults.JSONOutput, "Output in JSON format")
 
 	deleteDefaults := NewConversationDeleteConfig()
@@ -258,6 +349,7 @@ func init() {
 	conversationImportCmd.Flags().Bool("force", importDefaults.Force, "Force overwrite existing conversation")
 
 	exportDefaults := NewConversationExportConfig()
+	conversationExportCmd.Flags().String("format", exportDefaults.Format, "Export format: json (importable record), markdown, or html")
 	conversationExportCmd.Flags().Bool("gist", exportDefaults.UseGist, "Create a private gist using gh command")
 	conversationExportCmd.Flags().Bool("public-gist", exportDefaults.UsePublicGist, "Create a public gist using gh command")
 
@@ -265,6 +357,19 @@ func init() {
 	conversationEditCmd.Flags().String("editor", editDefaults.Editor, "Editor to use for editing the conversation (default: git config core.editor, then $EDITOR, then vim)")
 	conversationEditCmd.Flags().String("edit-args", editDefaults.EditArgs, "Additional arguments to pass to the editor (e.g., '--wait' for VS Code)")
 
+	redactDefaults := NewConversationRedactConfig()
+	conversationRedactCmd.Flags().StringArray("tool", redactDefaults.Tools, "Name of a tool whose outputs are redacted (repeatable)")
+	conversationRedactCmd.Flags().Bool("no-confirm", redactDefaults.NoConfirm, "Skip confirmation prompt")
+	_ = conversationRedactCmd.MarkFlagRequired("tool")
+
+	searchDefaults := NewConversationSearchConfig()
+	conversationSearchCmd.Flags().String("conversation", searchDefaults.ConversationID, "Search only this conversation")
+	conversationSearchCmd.Flags().String("cwd", searchDefaults.CWD, "Search conversations that ran in this directory (default: current directory)")
+	conversationSearchCmd.Flags().Bool("all-dirs", searchDefaults.AllDirs, "Search conversations from every directory")
+	conversationSearchCmd.Flags().Int("limit", searchDefaults.Limit, "Maximum number of matches to show")
+	conversationSearchCmd.Flags().Bool("json", searchDefaults.JSONOutput, "Output in JSON format")
+	conversationSearchCmd.MarkFlagsMutuallyExclusive("cwd", "all-dirs")
+
 	streamDefaults := NewConversationStreamConfig()
 	conversationStreamCmd.Flags().Bool("include-history", streamDefaults.IncludeHistory, "Include historical conversation data before streaming new entries")
 	conversationStreamCmd.Flags().Bool("history-only", streamDefaults.HistoryOnly, "Output historical conversation data and exit (no live streaming)")
@@ -278,6 +383,8 @@ func init() {
 	conversationCmd.AddCommand(conversationEditCmd)
 	conversationCmd.AddCommand(conversationStreamCmd)
 	conversationCmd.AddCommand(conversationForkCmd)
+	conversationCmd.AddCommand(conversationRedactCmd)
+	conversationCmd.AddCommand(conversationSearchCmd)
 }
 
 func getConversationListConfigFromFlags(cmd *cobra.Command) *ConversationListConfig {
@@ -304,9 +411,21 @@ func getConversationListConfigFromFlags(cmd *cobra.Command) *ConversationListCon
 	if sortBy, err := cmd.Flags().GetString("sort-by"); err == nil {
 		config.SortBy = sortBy
 	}
+	if sortBy, err := cmd.Flags().GetString("sort"); err == nil && sortBy != "" {
+		config.SortBy = sortBy
+	}
+	if config.Search != "" && !cmd.Flags().Changed("sort") && !cmd.Flags().Changed("sort-by") {
+		config.SortBy = "relevance"
+	}
 	if sortOrder, err := cmd.Flags().GetString("sort-order"); err == nil {
 		config.SortOrder = sortOrder
 	}
+	if filters, err := cmd.Flags().GetStringArray("filter"); err == nil {
+		config.Filters = filters
+	}
+	if cwd, err := cmd.Flags().GetString("cwd"); err == nil {
+		config.CWD = cwd
+	}
 	if jsonOutput, err := cmd.Flags().GetBool("json"); err == nil {
 		config.JSONOutput = jsonOutput
 	}
@@ -356,6 +475,10 @@ func getConversationImportConfigFromFlags(cmd *cobra.Command) *ConversationImpor
 func getConversationExportConfigFromFlags(cmd *cobra.Command) *ConversationExportConfig {
 	config := NewConversationExportConfig()
 
+	if format, err := cmd.Flags().GetString("format"); err == nil && format != "" {
+		config.Format = format
+	}
+
 	if useGist, err := cmd.Flags().GetBool("gist"); err == nil {
 		config.UseGist = useGist
 	}
@@ -380,6 +503,41 @@ func getConversationStreamConfigFromFlags(cmd *cobra.Command) *ConversationStrea
 	return config
 }
 
+func getConversationRedactConfigFromFlags(cmd *cobra.Command) *ConversationRedactConfig {
+	config := NewConversationRedactConfig()
+
+	if tools, err := cmd.Flags().GetStringArray("tool"); err == nil {
+		config.Tools = tools
+	}
+	if noConfirm, err := cmd.Flags().GetBool("no-confirm"); err == nil {
+		config.NoConfirm = noConfirm
+	}
+
+	return config
+}
+
+func getConversationSearchConfigFromFlags(cmd *cobra.Command) *ConversationSearchConfig {
+	config := NewConversationSearchConfig()
+
+	if conversationID, err := cmd.Flags().GetString("conversation"); err == nil {
+		config.ConversationID = conversationID
+	}
+	if cwd, err := cmd.Flags().GetString("cwd"); err == nil {
+		config.CWD = cwd
+	}
+	if allDirs, err := cmd.Flags().GetBool("all-dirs"); err == nil {
+		config.AllDirs = allDirs
+	}
+	if limit, err := cmd.Flags().GetInt("limit"); err == nil {
+		config.Limit = limit
+	}
+	if jsonOutput, err := cmd.Flags().GetBool("json"); err == nil {
+		config.JSONOutput = jsonOutput
+	}
+
+	return config
+}
+
 func getConversationEditConfigFromFlags(cmd *cobra.Command) *ConversationEditConfig {
 	config := NewConversationEditConfig()
 
@@ -423,15 +581,20 @@ func NewConversationListOutput(summaries []convtypes.ConversationSummary, metada
 
 		metadata := metadataByID[summary.ID]
 		platform, apiMode := extractProviderMetadata(summary.Provider, metadata)
+		model, _ := metadata["model"].(string)
 
 		output.Conversations = append(output.Conversations, ConversationSummaryOutput{
 			ID:             summary.ID,
+			Title:          conversations.TitleFromMetadata(metadata),
+			CWD:            summary.CWD,
 			CreatedAt:      summary.CreatedAt,
 			UpdatedAt:      summary.UpdatedAt,
 			MessageCount:   summary.MessageCount,
 			Provider:       displayProviderName(summary.Provider),
 			Platform:       platform,
 			APIMode:        apiMode,
+			Model:          model,
+			Tags:           conversationTags(metadata),
 			Preview:        preview,
 			TotalCost:      summary.Usage.TotalCost(),
 			CurrentContext: summary.Usage.CurrentContextWindow,
@@ -442,6 +605,19 @@ func NewConversationListOutput(summaries []convtypes.ConversationSummary, metada
 	return output
 }
 
+// conversationTags returns the profile and experiment arm recorded in
+// conversation metadata as "profile:<name>" and "experiment:<name>/<arm>".
+func conversationTags(metadata map[string]any) []string {
+	var tags []string
+	if profile, ok := metadata["profile"].(string); ok && profile != "" {
+		tags = append(tags, "profile:"+profile)
+	}
+	if experiment, ok := conversations.ExperimentFromMetadata(metadata); ok {
+		tags = append(tags, fmt.Sprintf("experiment:%s/%s", experiment.Name, experiment.Arm))
+	}
+	return tags
+}
+
 func normalizeProviderMetadataString(value any) string {
 	strValue, ok := value.(string)
 	if !ok {
@@ -515,12 +691,13 @@ func (o *ConversationListOutput) renderJSON(w io.Writer) error {
 func (o *ConversationListOutput) renderTable(w io.Writer) error {
 	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
 
-	fmt.Fprintln(tw, "ID\tCreated\tUpdated\tMessages\tProvider\tPlatform\tAPI Mode\tCost\tContext\tSummary")
-	fmt.Fprintln(tw, "----\t-------\t-------\t--------\t--------\t--------\t--------\t----\t-------\t-------")
+	fmt.Fprintln(tw, "ID\tCreated\tLast Activity\tMessages\tProvider\tModel\tPlatform\tAPI Mode\tCost\tContext\tTags\tTitle\tSummary")
+	fmt.Fprintln(tw, "----\t-------\t-------------\t--------\t--------\t-----\t--------\t--------\t----\t-------\t----\t-----\t-------")
 
+	now := time.Now()
 	for _, summary := range o.Conversations {
 		created := summary.CreatedAt.Format(time.RFC3339)
-		updated := summary.UpdatedAt.Format(time.RFC3339)
+		lastActivity := formatLastActivity(now, summary.UpdatedAt)
 
 		// Format cost as dollars with 4 decimal places
 		costStr := fmt.Sprintf("$%.4f", summary.TotalCost)
@@ -535,6 +712,9 @@ func (o *ConversationListOutput) renderTable(w io.Writer) error {
 
 		// Truncate long previews to allow room for other columns
 		preview := summary.Preview
+		if summary.Match != "" {
+			preview = summary.Match
+		}
 		if len(preview) > 50 {
 			preview = strings.TrimSpace(preview[:47]) + "..."
 		}
@@ -547,17 +727,34 @@ func (o *ConversationListOutput) renderTable(w io.Writer) error {
 		if apiMode == "" {
 			apiMode = "-"
 		}
+		model := summary.Model
+		if model == "" {
+			model = "-"
+		}
+		tags := strings.Join(summary.Tags, ",")
+		if tags == "" {
+			tags = "-"
+		}
+		title := summary.Title
+		if title == "" {
+			title = "-"
+		} else if len([]rune(title)) > 40 {
+			title = strings.TrimSpace(string([]rune(title)[:37])) + "..."
+		}
 
-		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 			summary.ID,
 			created,
-			updated,
+			lastActivity,
 			summary.MessageCount,
 			summary.Provider,
+			model,
 			platform,
 			apiMode,
 			costStr,
 			contextStr,
+			tags,
+			title,
 			preview,
 		)
 	}
@@ -565,15 +762,38 @@ func (o *ConversationListOutput) renderTable(w io.Writer) error {
 	return tw.Flush()
 }
 
+// formatLastActivity renders how long ago a conversation was last updated,
+// falling back to the date for anything older than a week.
+func formatLastActivity(now, updated time.Time) string {
+	elapsed := now.Sub(updated)
+	switch {
+	case elapsed < time.Minute:
+		return "just now"
+	case elapsed < time.Hour:
+		return fmt.Sprintf("%dm ago", int(elapsed.Minutes()))
+	case elapsed < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(elapsed.Hours()))
+	case elapsed < 7*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(elapsed.Hours()/24))
+	default:
+		return updated.Local().Format("2006-01-02")
+	}
+}
+
 type ConversationSummaryOutput struct {
 	ID             string    `json:"id"`
+	Title          string    `json:"title,omitempty"`
+	CWD            string    `json:"cwd,omitempty"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 	MessageCount   int       `json:"message_count"`
 	Provider       string    `json:"provider"`
 	Platform       string    `json:"platform,omitempty"`
 	APIMode        string    `json:"api_mode,omitempty"`
+	Model          string    `json:"model,omitempty"`
+	Tags           []string  `json:"tags,omitempty"`
 	Preview        string    `json:"preview"`
+	Match          string    `json:"match,omitempty"`
 	TotalCost      float64   `json:"total_cost"`
 	CurrentContext int       `json:"current_context_window"`
 	MaxContext     int       `json:"max_context_window"`
@@ -588,12 +808,29 @@ func listConversationsCmd(ctx context.Context, config *ConversationListConfig) {
 	defer store.Close()
 
 	options := convtypes.QueryOptions{
-		SearchTerm: config.Search,
-		Provider:   config.Provider,
-		Limit:      config.Limit,
-		Offset:     config.Offset,
-		SortBy:     config.SortBy,
-		SortOrder:  config.SortOrder,
+		Provider:  config.Provider,
+		Limit:     config.Limit,
+		Offset:    config.Offset,
+		SortBy:    config.SortBy,
+		SortOrder: config.SortOrder,
+	}
+
+	if !conversationSortFields[config.SortBy] {
+		presenter.Error(errors.Errorf("invalid sort field %q", config.SortBy), "Sort by cost, updated, created, messages, or relevance")
+		os.Exit(1)
+	}
+	if err := applyConversationFilters(&options, config.Filters); err != nil {
+		presenter.Error(err, "Invalid filter")
+		os.Exit(1)
+	}
+	if config.CWD != "" {
+		root, err := conversations.ProjectRoot(ctx, config.CWD)
+		if err != nil {
+			presenter.Error(err, "Invalid --cwd directory")
+			os.Exit(1)
+		}
+		options.CWD = root
+		options.CWDSubdirs = true
 	}
 
 	if config.StartDate != "" {
@@ -616,13 +853,27 @@ func listConversationsCmd(ctx context.Context, config *ConversationListConfig) {
 		options.EndDate = &endDate
 	}
 
-	result, err := store.Query(ctx, options)
-	if err != nil {
-		presenter.Error(err, "Failed to list conversations")
-		os.Exit(1)
+	var summaries []convtypes.ConversationSummary
+	matches := map[string]string{}
+	if config.Search != "" {
+		result, err := store.Search(ctx, config.Search, options)
+		if err != nil {
+			presenter.Error(err, "Failed to search conversations")
+			os.Exit(1)
+		}
+		for _, hit := range result.Hits {
+			summaries = append(summaries, hit.ConversationSummary)
+			matches[hit.ID] = hit.Snippet
+		}
+	} else {
+		result, err := store.Query(ctx, options)
+		if err != nil {
+			presenter.Error(err, "Failed to list conversations")
+			os.Exit(1)
+		}
+		summaries = result.ConversationSummaries
 	}
 
-	summaries := result.ConversationSummaries
 	if len(summaries) == 0 {
 		presenter.Info("No conversations found matching your criteria.")
 		return
@@ -638,12 +889,54 @@ func listConversationsCmd(ctx context.Context, config *ConversationListConfig) {
 		format = JSONFormat
 	}
 	output := NewConversationListOutput(summaries, metadataByID, format)
+	for i := range output.Conversations {
+		output.Conversations[i].Match = strings.Join(strings.Fields(matches[output.Conversations[i].ID]), " ")
+	}
 	if err := output.Render(os.Stdout); err != nil {
 		presenter.Error(err, "Failed to render conversation list")
 		os.Exit(1)
 	}
 }
 
+// conversationSortFields are the sort fields accepted by `conversation list`,
+// including the older snake_case and camelCase spellings.
+var conversationSortFields = map[string]bool{
+	"":             true,
+	"cost":         true,
+	"updated":      true,
+	"updated_at":   true,
+	"updatedAt":    true,
+	"created":      true,
+	"created_at":   true,
+	"createdAt":    true,
+	"messages":     true,
+	"messageCount": true,
+	"relevance":    true,
+}
+
+// applyConversationFilters sets the query filters given as key=value pairs.
+func applyConversationFilters(options *convtypes.QueryOptions, filters []string) error {
+	for _, filter := range filters {
+		key, value, ok := strings.Cut(filter, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			return errors.Errorf("filter %q must have the form key=value", filter)
+		}
+		switch key {
+		case "provider":
+			options.Provider = strings.ToLower(value)
+		case "model":
+			options.Model = value
+		case "profile":
+			options.Profile = value
+		default:
+			return errors.Errorf("unknown filter key %q (expected provider, model, or profile)", key)
+		}
+	}
+	return nil
+}
+
 func deleteConversationCmd(ctx context.Context, id string, config *ConversationDeleteConfig) {
 	store, err := conversations.GetConversationStore(ctx)
 	if err != nil {
@@ -682,6 +975,8 @@ type ConversationShowOutput struct {
 	Provider  string             `json:"provider"`
 	Platform  string             `json:"platform,omitempty"`
 	APIMode   string             `json:"api_mode,omitempty"`
+	Title     string             `json:"title,omitempty"`
+	CWD       string             `json:"cwd,omitempty"`
 	Summary   string             `json:"summary,omitempty"`
 	CreatedAt time.Time          `json:"created_at"`
 	UpdatedAt time.Time          `json:"updated_at"`
@@ -720,6 +1015,8 @@ func showConversationCmd(ctx context.Context, id string, config *ConversationSho
 			Provider:  providerDisplay,
 			Platform:  platform,
 			APIMode:   apiMode,
+			Title:     conversations.TitleFromMetadata(record.Metadata),
+			CWD:       record.CWD,
 			Summary:   record.Summary,
 			CreatedAt: record.CreatedAt,
 			UpdatedAt: record.UpdatedAt,
@@ -808,7 +1105,13 @@ func displayConversationHeader(record convtypes.ConversationRecord, providerDisp
 	}
 	fmt.Printf("Created:   %s\n", record.CreatedAt.Format(time.RFC3339))
 	fmt.Printf("Updated:   %s\n", record.UpdatedAt.Format(time.RFC3339))
+	if record.CWD != "" {
+		fmt.Printf("Directory: %s\n", record.CWD)
+	}
 
+	if title := conversations.TitleFromMetadata(record.Metadata); title != "" {
+		fmt.Printf("Title:     %s\n", title)
+	}
 	if record.Summary != "" {
 		fmt.Printf("Summary:   %s\n", record.Summary)
 	}
@@ -868,6 +1171,12 @@ func renderConversationHeaderMarkdown(record convtypes.ConversationRecord, provi
 	}
 	fmt.Fprintf(&output, "- **Created:** %s\n", inlineMarkdownCode(record.CreatedAt.Format(time.RFC3339)))
 	fmt.Fprintf(&output, "- **Updated:** %s\n", inlineMarkdownCode(record.UpdatedAt.Format(time.RFC3339)))
+	if record.CWD != "" {
+		fmt.Fprintf(&output, "- **Directory:** %s\n", inlineMarkdownCode(record.CWD))
+	}
+	if title := conversations.TitleFromMetadata(record.Metadata); title != "" {
+		fmt.Fprintf(&output, "- **Title:** %s\n", sanitizeMarkdownText(title))
+	}
 	if record.Summary != "" {
 		fmt.Fprintf(&output, "- **Summary:** %s\n", sanitizeMarkdownText(record.Summary))
 	}
@@ -947,9 +1256,9 @@ func exportConversationCmd(ctx context.Context, conversationID string, path stri
 		os.Exit(1)
 	}
 
-	jsonData, err := json.MarshalIndent(record, "", "  ")
+	data, ext, err := renderConversationExport(record, config.Format)
 	if err != nil {
-		presenter.Error(err, "Failed to serialize conversation")
+		presenter.Error(err, "Failed to export conversation")
 		os.Exit(1)
 	}
 	if config.UseGist || config.UsePublicGist {
@@ -960,7 +1269,7 @@ func exportConversationCmd(ctx context.Context, conversationID string, path stri
 		}
 
 		isPrivate := config.UseGist // private if --gist, public if --public-gist
-		if err := createGist(conversationID, jsonData, isPrivate); err != nil {
+		if err := createGist(conversationID, data, ext, isPrivate); err != nil {
 			presenter.Error(err, "Failed to create gist")
 			os.Exit(1)
 		}
@@ -968,10 +1277,10 @@ func exportConversationCmd(ctx context.Context, conversationID string, path stri
 	}
 
 	if path == "" {
-		path = fmt.Sprintf("%s.json", conversationID)
+		path = conversationID + ext
 	}
 
-	if err := os.WriteFile(path, jsonData, 0o644); err != nil {
+	if err := os.WriteFile(path, data, 0o644); err != nil {
 		presenter.Error(err, "Failed to write file")
 		os.Exit(1)
 	}
@@ -979,6 +1288,67 @@ func exportConversationCmd(ctx context.Context, conversationID string, path stri
 	presenter.Success(fmt.Sprintf("Conversation %s exported to %s", conversationID, path))
 }
 
+// renderConversationExport renders record in the export format and returns
+// the file extension for it. JSON exports are the full record, which
+// `conversation import` accepts; markdown and HTML are for reading.
+func renderConversationExport(record convtypes.ConversationRecord, format string) ([]byte, string, error) {
+	switch format {
+	case "", "json":
+		data, err := json.MarshalIndent(record, "", "  ")
+		if err != nil {
+			return nil, "", errors.Wrap(err, "failed to serialize conversation")
+		}
+		return data, ".json", nil
+	case "markdown", "html":
+		platform, apiMode := extractProviderMetadata(record.Provider, record.Metadata)
+		markdown, err := llm.RenderConversationMarkdown(record.Provider, record.RawMessages, record.Metadata, record.ToolResults)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "failed to render conversation markdown")
+		}
+		markdown = renderConversationHeaderMarkdown(record, displayProviderName(record.Provider), platform, apiMode) + "\n" + markdown
+		if format == "markdown" {
+			return []byte(markdown), ".md", nil
+		}
+		page, err := renderConversationHTML(record, markdown)
+		if err != nil {
+			return nil, "", err
+		}
+		return page, ".html", nil
+	default:
+		return nil, "", errors.Errorf("unsupported export format %q: use json, markdown, or html", format)
+	}
+}
+
+// renderConversationHTML wraps the markdown export in a standalone page.
+// Raw HTML in messages and tool output is escaped rather than rendered.
+func renderConversationHTML(record convtypes.ConversationRecord, markdown string) ([]byte, error) {
+	var body bytes.Buffer
+	if err := goldmark.New(goldmark.WithExtensions(extension.GFM)).Convert([]byte(markdown), &body); err != nil {
+		return nil, errors.Wrap(err, "failed to render conversation HTML")
+	}
+
+	title := record.Summary
+	if title == "" {
+		title = "Conversation " + record.ID
+	}
+	var page bytes.Buffer
+	page.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&page, "<title>%s</title>\n", html.EscapeString(title))
+	page.WriteString("<style>\n" + conversationHTMLStyle + "</style>\n</head>\n<body>\n<main>\n")
+	page.Write(body.Bytes())
+	page.WriteString("</main>\n</body>\n</html>\n")
+	return page.Bytes(), nil
+}
+
+const conversationHTMLStyle = `body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; line-height: 1.5; color: #1f2328; }
+main { max-width: 960px; margin: 2rem auto; padding: 0 1rem; }
+pre { background: #f6f8fa; padding: 0.75rem; overflow-x: auto; border-radius: 6px; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: 0.9em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #d0d7de; padding: 0.25rem 0.5rem; }
+blockquote { margin: 0; padding-left: 1rem; border-left: 4px solid #d0d7de; color: #59636e; }
+`
+
 func readConversationData(source string) ([]byte, error) {
 	if parsedURL, err := url.Parse(source); err == nil && parsedURL.Scheme != "" {
 		return readFromURL(source)
@@ -1044,14 +1414,14 @@ func validateConversationRecord(data []byte) (*convtypes.ConversationRecord, err
 	return &record, nil
 }
 
-func createGist(conversationID string, jsonData []byte, isPrivate bool) error {
-	tmpFile, err := os.CreateTemp("", fmt.Sprintf("conversation_%s_*.json", conversationID))
+func createGist(conversationID string, data []byte, ext string, isPrivate bool) error {
+	tmpFile, err := os.CreateTemp("", fmt.Sprintf("conversation_%s_*%s", conversationID, ext))
 	if err != nil {
 		return errors.Wrap(err, "failed to create temporary file")
 	}
 	defer os.Remove(tmpFile.Name())
 
-	if _, err := tmpFile.Write(jsonData); err != nil {
+	if _, err := tmpFile.Write(data); err != nil {
 		tmpFile.Close()
 		return errors.Wrap(err, "failed to write to temporary file")
 	}
@@ -1063,7 +1433,7 @@ func createGist(conversationID string, jsonData []byte, isPrivate bool) error {
 		// Only add --public flag for public gists; private is the default
 		args = append(args, "--public")
 	}
-	args = append(args, "--filename", fmt.Sprintf("conversation_%s.json", conversationID), tmpFile.Name())
+	args = append(args, "--filename", fmt.Sprintf("conversation_%s%s", conversationID, ext), tmpFile.Name())
 	cmd := exec.Command("gh", args...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -1154,6 +1524,145 @@ func editConversationCmd(ctx context.Context, conversationID string, config *Con
 	presenter.Success(fmt.Sprintf("Conversation %s edited successfully", conversationID))
 }
 
+func redactConversationCmd(ctx context.Context, conversationID string, config *ConversationRedactConfig) {
+	store, err := conversations.GetConversationStore(ctx)
+	if err != nil {
+		presenter.Error(err, "Failed to initialize conversation store")
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	record, err := store.Load(ctx, conversationID)
+	if err != nil {
+		presenter.Error(err, "Failed to load conversation")
+		os.Exit(1)
+	}
+
+	toolNames := strings.Join(config.Tools, ", ")
+	if !config.NoConfirm {
+		response := presenter.Prompt(fmt.Sprintf("Permanently redact %s outputs from conversation %s?", toolNames, conversationID), "y", "N")
+		if response != "y" && response != "Y" {
+			presenter.Info("Redaction cancelled.")
+			return
+		}
+	}
+
+	redacted, err := llm.RedactToolResults(&record, config.Tools)
+	if err != nil {
+		presenter.Error(err, "Failed to redact conversation")
+		os.Exit(1)
+	}
+	if redacted == 0 {
+		presenter.Info(fmt.Sprintf("No %s outputs found in conversation %s", toolNames, conversationID))
+		return
+	}
+	if err := store.Save(ctx, record); err != nil {
+		presenter.Error(err, "Failed to save redacted conversation")
+		os.Exit(1)
+	}
+
+	presenter.Success(fmt.Sprintf("Redacted %d tool outputs from conversation %s", redacted, conversationID))
+}
+
+// maxSearchedConversations caps how many conversations a search loads. The
+// store's full-text index narrows them to those matching the query, most
+// relevant first.
+const maxSearchedConversations = 50
+
+func searchConversationsCmd(ctx context.Context, query string, config *ConversationSearchConfig) {
+	store, err := conversations.GetConversationStore(ctx)
+	if err != nil {
+		presenter.Error(err, "Failed to initialize conversation store")
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	matches, err := searchConversations(ctx, store, query, config)
+	if err != nil {
+		presenter.Error(err, "Failed to search conversations")
+		os.Exit(1)
+	}
+
+	if config.JSONOutput {
+		if matches == nil {
+			matches = []conversations.SearchMatch{}
+		}
+		outputJSON, err := json.MarshalIndent(map[string]any{"matches": matches}, "", "  ")
+		if err != nil {
+			presenter.Error(err, "Failed to generate JSON output")
+			os.Exit(1)
+		}
+		fmt.Println(string(outputJSON))
+		return
+	}
+
+	if len(matches) == 0 {
+		presenter.Info("No matches found")
+		return
+	}
+	for i, match := range matches {
+		if i > 0 {
+			fmt.Println()
+		}
+		role := match.Role
+		if match.Compacted {
+			role += " (compacted)"
+		}
+		fmt.Printf("%s  %s  %s\n", match.ConversationID, match.UpdatedAt.Local().Format("2006-01-02 15:04"), role)
+		fmt.Printf("  %s\n", match.Snippet)
+	}
+}
+
+// searchConversations returns up to config.Limit messages containing query,
+// from one conversation or from the conversations of a directory.
+func searchConversations(ctx context.Context, store conversations.ConversationStore, query string, config *ConversationSearchConfig) ([]conversations.SearchMatch, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errors.New("search query is empty")
+	}
+
+	ids := []string{config.ConversationID}
+	if config.ConversationID == "" {
+		options := convtypes.QueryOptions{Limit: maxSearchedConversations}
+		if !config.AllDirs {
+			cwd, err := conversations.NormalizeCWD(config.CWD)
+			if config.CWD == "" {
+				cwd, err = conversations.CurrentWorkingDirectory()
+			}
+			if err != nil {
+				return nil, err
+			}
+			options.CWD = cwd
+		}
+		result, err := store.Search(ctx, query, options)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to search conversations")
+		}
+		ids = ids[:0]
+		for _, hit := range result.Hits {
+			ids = append(ids, hit.ID)
+		}
+	}
+
+	var matches []conversations.SearchMatch
+	for _, id := range ids {
+		record, err := store.Load(ctx, id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load conversation %s", id)
+		}
+		messages, err := llm.ExtractMessages(record.Provider, record.RawMessages, record.Metadata, record.ToolResults)
+		if err != nil {
+			logger.G(ctx).WithError(err).WithField("conversation_id", id).Warn("skipping conversation with unreadable messages")
+			continue
+		}
+		matches = append(matches, conversations.SearchTranscript(record, messages, query)...)
+		if config.Limit > 0 && len(matches) >= config.Limit {
+			return matches[:config.Limit], nil
+		}
+	}
+	return matches, nil
+}
+
 func streamConversationCmd(ctx context.Context, conversationID string, config *ConversationStreamConfig) {
 	streamer, closeFunc, err := llm.NewConversationStreamer(ctx)
 	if err != nil {
@@ -1206,36 +1715,9 @@ func forkConversationCmd(ctx context.Context, conversationID string) {
 		presenter.Info(fmt.Sprintf("Forking most recent conversation: %s", conversationID))
 	}
 
-	// Load the source conversation
-	sourceRecord, err := store.Load(ctx, conversationID)
+	forkedRecord, err := store.Fork(ctx, conversationID)
 	if err != nil {
-		presenter.Error(err, fmt.Sprintf("Failed to load conversation %s", conversationID))
-		os.Exit(1)
-	}
-
-	// Create a new conversation record with a new ID
-	forkedRecord := convtypes.NewConversationRecord("")
-
-	// Copy messages and context from source
-	forkedRecord.RawMessages = sourceRecord.RawMessages
-	forkedRecord.Provider = sourceRecord.Provider
-	forkedRecord.Summary = sourceRecord.Summary
-	forkedRecord.ToolResults = sourceRecord.ToolResults
-
-	// Copy Metadata map
-	if sourceRecord.Metadata != nil {
-		forkedRecord.Metadata = make(map[string]any)
-		maps.Copy(forkedRecord.Metadata, sourceRecord.Metadata)
-	}
-
-	// Usage is already initialized to zero by NewConversationRecord
-	// Preserve context window information from source
-	forkedRecord.Usage.CurrentContextWindow = sourceRecord.Usage.CurrentContextWindow
-	forkedRecord.Usage.MaxContextWindow = sourceRecord.Usage.MaxContextWindow
-
-	// Save the forked conversation
-	if err := store.Save(ctx, forkedRecord); err != nil {
-		presenter.Error(err, "Failed to save forked conversation")
+		presenter.Error(err, fmt.Sprintf("Failed to fork conversation %s", conversationID))
 		os.Exit(1)
 	}
 
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/presenter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ConversationEncryptConfig holds the options of `kodelet conversation encrypt`.
+type ConversationEncryptConfig struct {
+	Decrypt   bool
+	NoConfirm bool
+}
+
+// NewConversationEncryptConfig creates a ConversationEncryptConfig with default values.
+func NewConversationEncryptConfig() *ConversationEncryptConfig {
+	return &ConversationEncryptConfig{
+		Decrypt:   false,
+		NoConfirm: false,
+	}
+}
+
+var conversationEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt the messages of saved conversations at rest",
+	Long: `Rewrite every saved conversation with its messages, tool results and
+compacted transcript encrypted, using the key from conversation_encryption.
+Use --decrypt to rewrite them in plaintext again.
+
+Afterwards the database file is compacted, so the plaintext of rewritten
+conversations does not linger in it.
+
+Examples:
+  export KODELET_CONVERSATION_KEY=$(openssl rand -base64 32)
+  kodelet conversation encrypt
+  kodelet conversation encrypt --decrypt
+`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		config := NewConversationEncryptConfig()
+		config.Decrypt, _ = cmd.Flags().GetBool("decrypt")
+		config.NoConfirm, _ = cmd.Flags().GetBool("no-confirm")
+		return encryptConversationsCmd(cmd.Context(), cmd.OutOrStdout(), config)
+	},
+}
+
+func init() {
+	defaults := NewConversationEncryptConfig()
+	conversationEncryptCmd.Flags().Bool("decrypt", defaults.Decrypt, "Rewrite encrypted conversations in plaintext")
+	conversationEncryptCmd.Flags().Bool("no-confirm", defaults.NoConfirm, "Skip confirmation prompt")
+	conversationCmd.AddCommand(conversationEncryptCmd)
+}
+
+// enableConversationEncryption makes the conversation stores of this process
+// encrypt what they save when conversation_encryption is enabled.
+func enableConversationEncryption(ctx context.Context) {
+	if !viper.GetBool("conversation_encryption.enabled") {
+		return
+	}
+	if err := conversations.EnableEncryption(ctx, viper.GetString("conversation_encryption.key_source")); err != nil {
+		logger.G(ctx).WithError(err).Warn("conversations will not be saved")
+	}
+}
+
+func encryptConversationsCmd(ctx context.Context, w io.Writer, config *ConversationEncryptConfig) error {
+	key, err := conversations.ReadEncryptionKey(ctx, viper.GetString("conversation_encryption.key_source"))
+	if err != nil {
+		return err
+	}
+
+	verb := "Encrypt"
+	if config.Decrypt {
+		verb = "Decrypt"
+	}
+	if !config.NoConfirm {
+		response := presenter.Prompt(verb+" every saved conversation? Back up the key first: encrypted conversations cannot be read without it.", "y", "N")
+		if response != "y" && response != "Y" {
+			presenter.Info(verb + " cancelled.")
+			return nil
+		}
+	}
+
+	count, err := conversations.MigrateEncryption(ctx, key, config.Decrypt)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%sed %d conversations.\n", verb, count)
+	if !config.Decrypt && !viper.GetBool("conversation_encryption.enabled") {
+		fmt.Fprintln(w, "Set conversation_encryption.enabled to true so new conversations are encrypted too.")
+	}
+	if config.Decrypt && viper.GetBool("conversation_encryption.enabled") {
+		fmt.Fprintln(w, "Set conversation_encryption.enabled to false, or new conversations are encrypted again.")
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/db"
+	"github.com/jingkaihe/kodelet/pkg/db/migrations"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptConversationsCmd(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	ctx := context.Background()
+	require.NoError(t, db.RunMigrations(ctx, migrations.All()))
+
+	t.Setenv(conversations.EncryptionKeyEnv, "")
+	assert.ErrorContains(t, encryptConversationsCmd(ctx, &bytes.Buffer{}, &ConversationEncryptConfig{NoConfirm: true}), "is not set")
+
+	t.Setenv(conversations.EncryptionKeyEnv, base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	var out bytes.Buffer
+	require.NoError(t, encryptConversationsCmd(ctx, &out, &ConversationEncryptConfig{NoConfirm: true}))
+	assert.Contains(t, out.String(), "Encrypted 0 conversations.")
+	assert.Contains(t, out.String(), "conversation_encryption.enabled to true")
+
+	viper.Set("conversation_encryption.enabled", true)
+	out.Reset()
+	require.NoError(t, encryptConversationsCmd(ctx, &out, &ConversationEncryptConfig{NoConfirm: true, Decrypt: true}))
+	assert.Equal(t, "Decrypted 0 conversations.\nSet conversation_encryption.enabled to false, or new conversations are encrypted again.\n", out.String())
+}
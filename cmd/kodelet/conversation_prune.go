@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/presenter"
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// ConversationPruneConfig holds the options of `kodelet conversation prune`.
+type ConversationPruneConfig struct {
+	OlderThan  string
+	MaxCount   int
+	MaxSize    string
+	DryRun     bool
+	NoConfirm  bool
+	NoVacuum   bool
+	JSONOutput bool
+}
+
+// NewConversationPruneConfig creates a ConversationPruneConfig with default values.
+func NewConversationPruneConfig() *ConversationPruneConfig {
+	return &ConversationPruneConfig{
+		OlderThan:  "",
+		MaxCount:   0,
+		MaxSize:    "",
+		DryRun:     false,
+		NoConfirm:  false,
+		NoVacuum:   false,
+		JSONOutput: false,
+	}
+}
+
+var conversationPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete old conversations to reclaim disk space",
+	Long: `Delete the conversations that fall outside a retention policy, oldest first,
+then compact the database file so the space is returned to the disk.
+
+Without flags, the conversation_retention policy from the configuration is
+applied. Conversations with an active run are never deleted.
+
+Examples:
+  kodelet conversation prune --older-than 30d --dry-run   # Preview what would be deleted
+  kodelet conversation prune --older-than 30d             # Delete conversations idle for 30 days
+  kodelet conversation prune --max-count 500 --max-size 1GB
+`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		config := getConversationPruneConfigFromFlags(cmd)
+		return pruneConversationsCmd(cmd.Context(), cmd.OutOrStdout(), config, time.Now())
+	},
+}
+
+func init() {
+	defaults := NewConversationPruneConfig()
+	conversationPruneCmd.Flags().String("older-than", defaults.OlderThan, "Delete conversations last updated longer ago than this (e.g. 30d, 2w, 12h)")
+	conversationPruneCmd.Flags().Int("max-count", defaults.MaxCount, "Keep at most this many of the most recently updated conversations")
+	conversationPruneCmd.Flags().String("max-size", defaults.MaxSize, "Keep the most recently updated conversations within this much stored data (e.g. 500MB, 2GB)")
+	conversationPruneCmd.Flags().Bool("dry-run", defaults.DryRun, "List the conversations that would be deleted without deleting them")
+	conversationPruneCmd.Flags().Bool("no-confirm", defaults.NoConfirm, "Skip confirmation prompt")
+	conversationPruneCmd.Flags().Bool("no-vacuum", defaults.NoVacuum, "Leave the database file at its size after deleting")
+	conversationPruneCmd.Flags().Bool("json", defaults.JSONOutput, "Output in JSON format")
+	conversationCmd.AddCommand(conversationPruneCmd)
+}
+
+func getConversationPruneConfigFromFlags(cmd *cobra.Command) *ConversationPruneConfig {
+	config := NewConversationPruneConfig()
+	config.OlderThan, _ = cmd.Flags().GetString("older-than")
+	config.MaxCount, _ = cmd.Flags().GetInt("max-count")
+	config.MaxSize, _ = cmd.Flags().GetString("max-size")
+	config.DryRun, _ = cmd.Flags().GetBool("dry-run")
+	config.NoConfirm, _ = cmd.Flags().GetBool("no-confirm")
+	config.NoVacuum, _ = cmd.Flags().GetBool("no-vacuum")
+	config.JSONOutput, _ = cmd.Flags().GetBool("json")
+	return config
+}
+
+// retentionPolicy returns the policy given by the flags, or the configured
+// conversation_retention policy when no flag is set.
+func (c *ConversationPruneConfig) retentionPolicy() (conversations.RetentionPolicy, error) {
+	if c.OlderThan == "" && c.MaxCount == 0 && c.MaxSize == "" {
+		return conversationRetentionPolicyFromViper()
+	}
+	return parseRetentionPolicy(c.OlderThan, c.MaxCount, c.MaxSize)
+}
+
+// conversationRetentionPolicyFromViper reads the conversation_retention settings.
+func conversationRetentionPolicyFromViper() (conversations.RetentionPolicy, error) {
+	policy, err := parseRetentionPolicy(
+		viper.GetString("conversation_retention.max_age"),
+		viper.GetInt("conversation_retention.max_count"),
+		viper.GetString("conversation_retention.max_size"),
+	)
+	return policy, errors.Wrap(err, "invalid conversation_retention")
+}
+
+func parseRetentionPolicy(maxAge string, maxCount int, maxSize string) (conversations.RetentionPolicy, error) {
+	if maxCount < 0 {
+		return conversations.RetentionPolicy{}, errors.New("max count must not be negative")
+	}
+	age, err := conversations.ParseRetentionAge(maxAge)
+	if err != nil {
+		return conversations.RetentionPolicy{}, err
+	}
+	size, err := conversations.ParseByteSize(maxSize)
+	if err != nil {
+		return conversations.RetentionPolicy{}, err
+	}
+	return conversations.RetentionPolicy{MaxAge: age, MaxCount: maxCount, MaxBytes: size}, nil
+}
+
+func pruneConversationsCmd(ctx context.Context, w io.Writer, config *ConversationPruneConfig, now time.Time) error {
+	policy, err := config.retentionPolicy()
+	if err != nil {
+		return err
+	}
+	if !policy.Enabled() {
+		return errors.New("no retention limit given; pass --older-than, --max-count or --max-size, or set conversation_retention in the configuration")
+	}
+
+	options := policy.PruneOptions(now)
+	options.DryRun = true
+	preview, err := conversations.PruneConversations(ctx, options, false)
+	if err != nil {
+		return err
+	}
+	if config.DryRun || len(preview.Conversations) == 0 {
+		return writePruneResult(w, preview, config.JSONOutput)
+	}
+
+	if !config.NoConfirm {
+		displayPrunedConversations(w, preview)
+		response := presenter.Prompt(fmt.Sprintf("Delete these %d conversations?", len(preview.Conversations)), "y", "N")
+		if response != "y" && response != "Y" {
+			presenter.Info("Prune cancelled.")
+			return nil
+		}
+	}
+
+	options.DryRun = false
+	result, err := conversations.PruneConversations(ctx, options, !config.NoVacuum)
+	if err != nil {
+		return err
+	}
+	return writePruneResult(w, result, config.JSONOutput)
+}
+
+func writePruneResult(w io.Writer, result convtypes.PruneResult, jsonOutput bool) error {
+	if jsonOutput {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	if len(result.Conversations) == 0 {
+		fmt.Fprintln(w, "No conversations to prune.")
+		return nil
+	}
+	verb := "Deleted"
+	if result.DryRun {
+		displayPrunedConversations(w, result)
+		verb = "Would delete"
+	}
+	fmt.Fprintf(w, "%s %d conversations (%s of stored data).\n", verb, len(result.Conversations), formatByteSize(result.Bytes))
+	return nil
+}
+
+func displayPrunedConversations(w io.Writer, result convtypes.PruneResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tUPDATED\tSIZE\tSUMMARY")
+	for _, conversation := range result.Conversations {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", conversation.ID, conversation.UpdatedAt.Format("2006-01-02 15:04"), formatByteSize(conversation.Bytes), truncatePostMortemText(conversation.Summary, 60))
+	}
+	tw.Flush()
+}
+
+// formatByteSize renders a byte count with a binary unit, such as 1.5 MB.
+func formatByteSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// startConversationAutoPrune runs the conversation_retention policy in the
+// background, at most once a day. Commands that manage the database
+// themselves are skipped.
+func startConversationAutoPrune(ctx context.Context, args []string) {
+	if len(args) > 1 && args[1] == "db" {
+		return
+	}
+	if len(args) > 2 && args[1] == "conversation" && args[2] == "prune" {
+		return
+	}
+	policy, err := conversationRetentionPolicyFromViper()
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("conversation retention is disabled")
+		return
+	}
+	go conversations.AutoPrune(ctx, policy)
+}
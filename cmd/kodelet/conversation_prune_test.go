@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversationPruneRetentionPolicy(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	config := NewConversationPruneConfig()
+	policy, err := config.retentionPolicy()
+	require.NoError(t, err)
+	assert.False(t, policy.Enabled())
+	assert.ErrorContains(t, pruneConversationsCmd(context.Background(), &bytes.Buffer{}, config, time.Now()), "no retention limit given")
+
+	viper.Set("conversation_retention.max_age", "90d")
+	viper.Set("conversation_retention.max_count", 1000)
+	viper.Set("conversation_retention.max_size", "2GB")
+	policy, err = config.retentionPolicy()
+	require.NoError(t, err)
+	assert.Equal(t, conversations.RetentionPolicy{MaxAge: 90 * 24 * time.Hour, MaxCount: 1000, MaxBytes: 2 << 30}, policy)
+
+	// Flags replace the configured policy as a whole.
+	config.OlderThan = "30d"
+	policy, err = config.retentionPolicy()
+	require.NoError(t, err)
+	assert.Equal(t, conversations.RetentionPolicy{MaxAge: 30 * 24 * time.Hour}, policy)
+
+	config.OlderThan = "soon"
+	_, err = config.retentionPolicy()
+	assert.Error(t, err)
+
+	viper.Set("conversation_retention.max_size", "lots")
+	_, err = NewConversationPruneConfig().retentionPolicy()
+	assert.ErrorContains(t, err, "invalid conversation_retention")
+}
+
+func TestWritePruneResult(t *testing.T) {
+	updatedAt := time.Date(2026, 9, 1, 8, 30, 0, 0, time.UTC)
+	result := convtypes.PruneResult{
+		Conversations: []convtypes.PrunedConversation{
+			{ID: "conv-1", Summary: "Fix the flaky test", UpdatedAt: updatedAt, Bytes: 3 << 20},
+		},
+		Bytes:  3 << 20,
+		DryRun: true,
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, writePruneResult(&out, result, false))
+	assert.Contains(t, out.String(), "conv-1")
+	assert.Contains(t, out.String(), "2026-09-01 08:30")
+	assert.Contains(t, out.String(), "Would delete 1 conversations (3.0 MB of stored data).")
+
+	out.Reset()
+	result.DryRun = false
+	require.NoError(t, writePruneResult(&out, result, false))
+	assert.Equal(t, "Deleted 1 conversations (3.0 MB of stored data).\n", out.String())
+
+	out.Reset()
+	require.NoError(t, writePruneResult(&out, result, true))
+	assert.Contains(t, out.String(), `"id": "conv-1"`)
+
+	out.Reset()
+	require.NoError(t, writePruneResult(&out, convtypes.PruneResult{}, false))
+	assert.Equal(t, "No conversations to prune.\n", out.String())
+}
+
+func TestFormatByteSize(t *testing.T) {
+	assert.Equal(t, "512 B", formatByteSize(512))
+	assert.Equal(t, "1.5 KB", formatByteSize(1536))
+	assert.Equal(t, "2.0 GB", formatByteSize(2<<30))
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/jingkaihe/kodelet/pkg/checkpoints"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// ConversationRollbackConfig holds the options of `kodelet conversation rollback`.
+type ConversationRollbackConfig struct {
+	Turn int
+	List bool
+}
+
+var conversationRollbackCmd = &cobra.Command{
+	Use:   "rollback <conversation-id>",
+	Short: "Revert the workspace to its state before a conversation turn",
+	Long: `Revert the files changed by a conversation to the content they had before
+the given turn, using the snapshots taken before each file edit and bash
+command. Files created from that turn onwards are removed.
+
+Turns are numbered from 1 in the order prompts were sent to the conversation
+with kodelet run, chat, ACP or the Web UI. Changes made by bash commands are
+captured inside git working trees only, and ignored files are not covered.
+
+The reverted turns are forgotten, and the agent is told which files were
+reverted with the next message of the conversation.
+
+Examples:
+  kodelet conversation rollback CONVERSATION_ID --list     # List the turns that changed files
+  kodelet conversation rollback CONVERSATION_ID --turn 3   # Revert the changes of turn 3 onwards
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config := &ConversationRollbackConfig{}
+		config.Turn, _ = cmd.Flags().GetInt("turn")
+		config.List, _ = cmd.Flags().GetBool("list")
+
+		root, err := checkpoints.DefaultDir()
+		if err != nil {
+			return err
+		}
+		return rollbackConversation(cmd.OutOrStdout(), root, args[0], config)
+	},
+}
+
+func init() {
+	conversationRollbackCmd.Flags().Int("turn", 0, "Revert the changes made from this turn onwards")
+	conversationRollbackCmd.Flags().Bool("list", false, "List the turns that changed files instead of reverting")
+	conversationCmd.AddCommand(conversationRollbackCmd)
+}
+
+func rollbackConversation(w io.Writer, root, conversationID string, config *ConversationRollbackConfig) error {
+	store, err := checkpoints.Load(root, conversationID)
+	if err != nil {
+		return err
+	}
+	if config.List {
+		displayCheckpointTurns(w, store.Steps())
+		return nil
+	}
+	if config.Turn < 1 {
+		return errors.New("--turn must be at least 1; use --list to see the turns that changed files")
+	}
+
+	restored, err := store.RollbackToCycle(config.Turn)
+	if errors.Is(err, checkpoints.ErrNothingToUndo) {
+		fmt.Fprintf(w, "No file changes recorded from turn %d onwards.\n", config.Turn)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, file := range restored {
+		if file.Deleted {
+			fmt.Fprintf(w, "Deleted %s\n", file.Path)
+		} else {
+			fmt.Fprintf(w, "Restored %s\n", file.Path)
+		}
+	}
+	return nil
+}
+
+func displayCheckpointTurns(w io.Writer, steps []checkpoints.Step) {
+	if len(steps) == 0 {
+		fmt.Fprintln(w, "No file changes recorded.")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Turn\tTime\tTool\tFiles")
+	for i, step := range steps {
+		turn := ""
+		if i == 0 || steps[i-1].Cycle != step.Cycle {
+			turn = fmt.Sprint(step.Cycle)
+		}
+		for j, file := range step.Files {
+			if j == 0 {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", turn, step.Time.Local().Format("2006-01-02 15:04:05"), step.Tool, file.Path)
+			} else {
+				fmt.Fprintf(tw, "\t\t\t%s\n", file.Path)
+			}
+		}
+	}
+	tw.Flush()
+}
+
+// openConversationCheckpoints opens the checkpoint store of a conversation and
+// begins the cycle of this run's turn, so `kodelet conversation rollback` can
+// revert it. It returns nil when checkpoints are not available.
+func openConversationCheckpoints(ctx context.Context, conversationID string) *checkpoints.Store {
+	root, err := checkpoints.DefaultDir()
+	if err == nil {
+		var store *checkpoints.Store
+		if store, err = checkpoints.Open(root, conversationID); err == nil {
+			if err = store.BeginTurn(); err == nil {
+				return store
+			}
+		}
+	}
+	logger.G(ctx).WithError(err).Warn("file checkpoints are disabled for this conversation")
+	return nil
+}
+
+// withRollbackNotice prepends the note about files reverted by a rollback or
+// `/undo` to query, so the agent does not rely on the content it last wrote.
+func withRollbackNotice(ctx context.Context, store *checkpoints.Store, query string) string {
+	if store == nil {
+		return query
+	}
+	notice, err := store.TakeUndoNotice()
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to clear undo notice")
+	}
+	if notice == "" {
+		return query
+	}
+	return notice + "\n\n" + query
+}
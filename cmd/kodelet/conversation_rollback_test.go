@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/checkpoints"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollbackConversation(t *testing.T) {
+	root := t.TempDir()
+	dir := t.TempDir()
+	edited := filepath.Join(dir, "edited.txt")
+	created := filepath.Join(dir, "created.txt")
+	require.NoError(t, os.WriteFile(edited, []byte("before\n"), 0o644))
+
+	store, err := checkpoints.Open(root, "conv-1")
+	require.NoError(t, err)
+	require.NoError(t, store.BeginTurn())
+	require.NoError(t, store.Snapshot("file_edit", []string{edited}))
+	require.NoError(t, os.WriteFile(edited, []byte("turn 1\n"), 0o644))
+	require.NoError(t, store.BeginTurn())
+	require.NoError(t, store.Snapshot("file_edit", []string{edited}))
+	require.NoError(t, os.WriteFile(edited, []byte("turn 2\n"), 0o644))
+	require.NoError(t, store.Snapshot("file_write", []string{created}))
+	require.NoError(t, os.WriteFile(created, []byte("new\n"), 0o644))
+
+	var out bytes.Buffer
+	require.NoError(t, rollbackConversation(&out, root, "conv-1", &ConversationRollbackConfig{List: true}))
+	assert.Contains(t, out.String(), "Turn")
+	assert.Contains(t, out.String(), created)
+
+	err = rollbackConversation(&out, root, "conv-1", &ConversationRollbackConfig{})
+	assert.ErrorContains(t, err, "--turn must be at least 1")
+
+	out.Reset()
+	require.NoError(t, rollbackConversation(&out, root, "conv-1", &ConversationRollbackConfig{Turn: 2}))
+	assert.Equal(t, "Deleted "+created+"\nRestored "+edited+"\n", out.String())
+	content, err := os.ReadFile(edited)
+	require.NoError(t, err)
+	assert.Equal(t, "turn 1\n", string(content))
+	assert.NoFileExists(t, created)
+
+	out.Reset()
+	require.NoError(t, rollbackConversation(&out, root, "conv-1", &ConversationRollbackConfig{Turn: 2}))
+	assert.Equal(t, "No file changes recorded from turn 2 onwards.\n", out.String())
+
+	ctx := t.Context()
+	t.Setenv("KODELET_BASE_PATH", filepath.Dir(root))
+	require.NoError(t, os.Rename(root, filepath.Join(filepath.Dir(root), "checkpoints")))
+	conversation := openConversationCheckpoints(ctx, "conv-1")
+	require.NotNil(t, conversation)
+	assert.Equal(t, 3, conversation.CurrentCycle())
+	query := withRollbackNotice(ctx, conversation, "next task")
+	assert.Contains(t, query, "from turn 2 onwards")
+	assert.Contains(t, query, "\n\nnext task")
+	assert.Equal(t, "next task", withRollbackNotice(ctx, conversation, "next task"))
+}
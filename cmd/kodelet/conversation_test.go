@@ -199,6 +199,7 @@ func TestConversationConfigDefaults(t *testing.T) {
 
 	assert.False(t, NewConversationDeleteConfig().NoConfirm)
 	assert.False(t, NewConversationImportConfig().Force)
+	assert.Equal(t, "json", NewConversationExportConfig().Format)
 	assert.False(t, NewConversationExportConfig().UseGist)
 	assert.False(t, NewConversationExportConfig().UsePublicGist)
 	assert.Equal(t, "", NewConversationEditConfig().Editor)
@@ -217,6 +218,8 @@ func TestConversationConfigFromFlags(t *testing.T) {
 	cmd.Flags().Int("offset", 0, "")
 	cmd.Flags().String("sort-by", "updated_at", "")
 	cmd.Flags().String("sort-order", "desc", "")
+	cmd.Flags().String("sort", "", "")
+	cmd.Flags().StringArray("filter", nil, "")
 	cmd.Flags().Bool("json", false, "")
 	require.NoError(t, cmd.Flags().Set("start", "2026-01-01"))
 	require.NoError(t, cmd.Flags().Set("end", "2026-01-31"))
@@ -226,6 +229,7 @@ func TestConversationConfigFromFlags(t *testing.T) {
 	require.NoError(t, cmd.Flags().Set("offset", "5"))
 	require.NoError(t, cmd.Flags().Set("sort-by", "created_at"))
 	require.NoError(t, cmd.Flags().Set("sort-order", "asc"))
+	require.NoError(t, cmd.Flags().Set("filter", "model=gpt-5"))
 	require.NoError(t, cmd.Flags().Set("json", "true"))
 	listConfig := getConversationListConfigFromFlags(cmd)
 	assert.Equal(t, "2026-01-01", listConfig.StartDate)
@@ -236,8 +240,19 @@ func TestConversationConfigFromFlags(t *testing.T) {
 	assert.Equal(t, 5, listConfig.Offset)
 	assert.Equal(t, "created_at", listConfig.SortBy)
 	assert.Equal(t, "asc", listConfig.SortOrder)
+	assert.Equal(t, []string{"model=gpt-5"}, listConfig.Filters)
 	assert.True(t, listConfig.JSONOutput)
 
+	require.NoError(t, cmd.Flags().Set("sort", "cost"))
+	assert.Equal(t, "cost", getConversationListConfigFromFlags(cmd).SortBy)
+
+	searchCmd := &cobra.Command{}
+	searchCmd.Flags().String("search", "", "")
+	searchCmd.Flags().String("sort-by", "updated_at", "")
+	searchCmd.Flags().String("sort", "", "")
+	require.NoError(t, searchCmd.Flags().Set("search", "golang"))
+	assert.Equal(t, "relevance", getConversationListConfigFromFlags(searchCmd).SortBy)
+
 	deleteCmd := &cobra.Command{}
 	deleteCmd.Flags().Bool("no-confirm", false, "")
 	require.NoError(t, deleteCmd.Flags().Set("no-confirm", "true"))
@@ -439,6 +454,7 @@ func TestConversationListOutputRenderTableAndJSON(t *testing.T) {
 	summaries := []convtypes.ConversationSummary{
 		{
 			ID:           "conv-1",
+			CWD:          "/work/project",
 			CreatedAt:    now,
 			UpdatedAt:    now.Add(time.Hour),
 			MessageCount: 3,
@@ -456,7 +472,14 @@ func TestConversationListOutputRenderTableAndJSON(t *testing.T) {
 		},
 	}
 	metadata := map[string]map[string]any{
-		"conv-1": {"platform": "Codex", "api_mode": "chat"},
+		"conv-1": {
+			"platform":   "Codex",
+			"api_mode":   "chat",
+			"model":      "gpt-5",
+			"title":      "Fix Flaky Login Test",
+			"profile":    "work",
+			"experiment": map[string]any{"name": "prompt-v2", "arm": "treatment"},
+		},
 	}
 
 	output := NewConversationListOutput(summaries, metadata, TableFormat)
@@ -465,12 +488,21 @@ func TestConversationListOutputRenderTableAndJSON(t *testing.T) {
 	assert.Equal(t, "codex", output.Conversations[0].Platform)
 	assert.Equal(t, "chat_completions", output.Conversations[0].APIMode)
 	assert.NotContains(t, output.Conversations[0].Preview, "\n")
+	assert.Equal(t, "gpt-5", output.Conversations[0].Model)
+	assert.Equal(t, []string{"profile:work", "experiment:prompt-v2/treatment"}, output.Conversations[0].Tags)
+	assert.Empty(t, output.Conversations[1].Tags)
+	assert.Equal(t, "Fix Flaky Login Test", output.Conversations[0].Title)
+	assert.Equal(t, "/work/project", output.Conversations[0].CWD)
+	assert.Empty(t, output.Conversations[1].Title)
 
 	var table bytes.Buffer
 	require.NoError(t, output.Render(&table))
 	assert.Contains(t, table.String(), "conv-1")
 	assert.Contains(t, table.String(), "$0.0300")
 	assert.Contains(t, table.String(), "1200/4000")
+	assert.Contains(t, table.String(), "Last Activity")
+	assert.Contains(t, table.String(), "profile:work,experiment:prompt-v2/treatment")
+	assert.Contains(t, table.String(), "Fix Flaky Login Test")
 	assert.Contains(t, table.String(), "...")
 
 	output.Format = JSONFormat
@@ -527,6 +559,45 @@ func TestRenderConversationHeaderMarkdown(t *testing.T) {
 	assert.Contains(t, output, "- **Context Window:** 1000 / 8000")
 }
 
+func TestRenderConversationExport(t *testing.T) {
+	record := convtypes.ConversationRecord{
+		ID:          "conv-export",
+		Provider:    "anthropic",
+		Summary:     "Fix <b>flaky</b> test",
+		RawMessages: json.RawMessage(`[{"role":"user","content":[{"type":"text","text":"Why does <script>alert(1)</script> fail?"}]},{"role":"assistant","content":[{"type":"text","text":"Because of **timing**."}]}]`),
+		ToolResults: map[string]tools.StructuredToolResult{},
+		CreatedAt:   time.Date(2026, 1, 23, 10, 0, 0, 0, time.UTC),
+		UpdatedAt:   time.Date(2026, 1, 23, 10, 30, 0, 0, time.UTC),
+		Usage:       llmtypes.Usage{InputTokens: 10, OutputTokens: 5},
+	}
+
+	data, ext, err := renderConversationExport(record, "json")
+	require.NoError(t, err)
+	assert.Equal(t, ".json", ext)
+	var decoded convtypes.ConversationRecord
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "conv-export", decoded.ID)
+
+	data, ext, err = renderConversationExport(record, "markdown")
+	require.NoError(t, err)
+	assert.Equal(t, ".md", ext)
+	assert.Contains(t, string(data), "- **ID:** `conv-export`")
+	assert.Contains(t, string(data), "## Usage")
+	assert.Contains(t, string(data), "Because of **timing**.")
+
+	data, ext, err = renderConversationExport(record, "html")
+	require.NoError(t, err)
+	assert.Equal(t, ".html", ext)
+	page := string(data)
+	assert.True(t, strings.HasPrefix(page, "<!DOCTYPE html>"))
+	assert.Contains(t, page, "<title>Fix &lt;b&gt;flaky&lt;/b&gt; test</title>")
+	assert.Contains(t, page, "<strong>timing</strong>")
+	assert.NotContains(t, page, "<script>")
+
+	_, _, err = renderConversationExport(record, "pdf")
+	assert.ErrorContains(t, err, "unsupported export format")
+}
+
 func TestDisplayProviderName(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -668,6 +739,27 @@ func TestValidateConversationRecord(t *testing.T) {
 	}
 }
 
+func TestFormatLastActivity(t *testing.T) {
+	now := time.Date(2026, 1, 23, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, "just now", formatLastActivity(now, now.Add(-30*time.Second)))
+	assert.Equal(t, "5m ago", formatLastActivity(now, now.Add(-5*time.Minute)))
+	assert.Equal(t, "3h ago", formatLastActivity(now, now.Add(-3*time.Hour)))
+	assert.Equal(t, "2d ago", formatLastActivity(now, now.Add(-50*time.Hour)))
+	old := now.Add(-30 * 24 * time.Hour)
+	assert.Equal(t, old.Local().Format("2006-01-02"), formatLastActivity(now, old))
+}
+
+func TestApplyConversationFilters(t *testing.T) {
+	var options convtypes.QueryOptions
+	require.NoError(t, applyConversationFilters(&options, []string{"provider=OpenAI", "model = gpt-5", "profile=work"}))
+	assert.Equal(t, "openai", options.Provider)
+	assert.Equal(t, "gpt-5", options.Model)
+	assert.Equal(t, "work", options.Profile)
+
+	assert.ErrorContains(t, applyConversationFilters(&options, []string{"provider"}), "key=value")
+	assert.ErrorContains(t, applyConversationFilters(&options, []string{"color=blue"}), "unknown filter key")
+}
+
 func TestConversationCommandsWithSQLiteStore(t *testing.T) {
 	ctx := setupConversationCommandStore(t)
 	record := saveConversationCommandRecord(ctx, t, "conv-cmd-1")
@@ -702,6 +794,29 @@ func TestConversationCommandsWithSQLiteStore(t *testing.T) {
 		assert.Equal(t, "conv-cmd-1", parsed.Conversations[0].ID)
 	})
 
+	t.Run("list search", func(t *testing.T) {
+		jsonOutput := captureStdout(t, func() {
+			listConversationsCmd(ctx, &ConversationListConfig{
+				Search:     "assistant hello",
+				Limit:      10,
+				SortBy:     "relevance",
+				JSONOutput: true,
+			})
+		})
+		var parsed struct {
+			Conversations []ConversationSummaryOutput `json:"conversations"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(jsonOutput), &parsed))
+		require.Len(t, parsed.Conversations, 1)
+		assert.Equal(t, "conv-cmd-1", parsed.Conversations[0].ID)
+		assert.Contains(t, parsed.Conversations[0].Match, "assistant")
+
+		tableOutput := captureStdout(t, func() {
+			listConversationsCmd(ctx, &ConversationListConfig{Search: "postgres", Limit: 10})
+		})
+		assert.NotContains(t, tableOutput, "conv-cmd-1")
+	})
+
 	t.Run("show supported formats", func(t *testing.T) {
 		rawOutput := captureStdout(t, func() {
 			showConversationCmd(ctx, record.ID, &ConversationShowConfig{Format: "raw"})
@@ -735,6 +850,34 @@ func TestConversationCommandsWithSQLiteStore(t *testing.T) {
 		assert.Contains(t, markdownOutput, "Hello from the user")
 	})
 
+	t.Run("search messages", func(t *testing.T) {
+		jsonOutput := captureStdout(t, func() {
+			searchConversationsCmd(ctx, "FROM THE USER", &ConversationSearchConfig{AllDirs: true, Limit: 10, JSONOutput: true})
+		})
+		var parsed struct {
+			Matches []conversations.SearchMatch `json:"matches"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(jsonOutput), &parsed))
+		require.Len(t, parsed.Matches, 1)
+		assert.Equal(t, "conv-cmd-1", parsed.Matches[0].ConversationID)
+		assert.Equal(t, "user", parsed.Matches[0].Role)
+		assert.Equal(t, "Hello from the user", parsed.Matches[0].Snippet)
+
+		textOutput := captureStdout(t, func() {
+			searchConversationsCmd(ctx, "hello", &ConversationSearchConfig{ConversationID: record.ID, Limit: 1})
+		})
+		assert.Contains(t, textOutput, "conv-cmd-1")
+		assert.Contains(t, textOutput, "Hello from the user")
+		assert.NotContains(t, textOutput, "Hello from the assistant")
+
+		store, err := conversations.GetConversationStore(ctx)
+		require.NoError(t, err)
+		defer store.Close()
+		matches, err := searchConversations(ctx, store, "hello", &ConversationSearchConfig{CWD: t.TempDir()})
+		require.NoError(t, err)
+		assert.Empty(t, matches)
+	})
+
 	t.Run("export import fork and delete", func(t *testing.T) {
 		exportPath := filepath.Join(t.TempDir(), "conversation.json")
 		exportOutput := captureAllStdout(t, func() {
@@ -828,7 +971,7 @@ func TestCreateGistInvokesGHWithExpectedVisibility(t *testing.T) {
 	t.Setenv("GH_ARGS_FILE", argsPath)
 
 	publicOutput := captureAllStdout(t, func() {
-		require.NoError(t, createGist("conv-gist", []byte(`{"id":"conv-gist"}`), false))
+		require.NoError(t, createGist("conv-gist", []byte(`{"id":"conv-gist"}`), ".json", false))
 	})
 	publicArgs := readGistArgs(t, argsPath)
 	assert.Contains(t, publicOutput, "https://gist.github.com/test/gist-id")
@@ -838,12 +981,12 @@ func TestCreateGistInvokesGHWithExpectedVisibility(t *testing.T) {
 	assert.Contains(t, publicArgs, "conversation_conv-gist.json")
 
 	privateOutput := captureAllStdout(t, func() {
-		require.NoError(t, createGist("conv-gist", []byte(`{"id":"conv-gist"}`), true))
+		require.NoError(t, createGist("conv-gist", []byte("# Conversation"), ".md", true))
 	})
 	privateArgs := readGistArgs(t, argsPath)
 	assert.Contains(t, privateOutput, "private gist")
 	assert.NotContains(t, privateArgs, "--public")
-	assert.Contains(t, privateArgs, "conversation_conv-gist.json")
+	assert.Contains(t, privateArgs, "conversation_conv-gist.md")
 }
 
 func readGistArgs(t *testing.T, argsPath string) string {
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/jingkaihe/kodelet/pkg/bench"
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/estimate"
+	"github.com/jingkaihe/kodelet/pkg/fragments"
+	"github.com/jingkaihe/kodelet/pkg/llm"
+	"github.com/jingkaihe/kodelet/pkg/llm/base"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/sysprompt"
+	"github.com/jingkaihe/kodelet/pkg/tools"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var estimateCmd = &cobra.Command{
+	Use:   "estimate [prompt...]",
+	Short: "Estimate the turns, tokens and cost of a run before starting it",
+	Long: `Estimate the range of turns, tokens and cost a run is likely to take with
+each model, without sending anything to a provider.
+
+With at least 3 past runs of the same recipe in this project (or, failing that,
+in any project), the range spans the middle half of those runs, priced for each
+model. Runs without --recipe are compared with past runs without a recipe.
+Otherwise the range comes from the size of the repository and of what every
+turn sends: the system prompt with the discovered context files, the tool
+definitions and the prompt.
+
+By default the configured model and weak model are estimated. Each --target
+names another model, or a configuration profile as profile:<name>.
+
+Examples:
+  kodelet estimate "Add a --json flag to the list command"
+  kodelet estimate -r github/pr-review --arg pr=42 --target claude-haiku-4-5 --target claude-opus-4-7
+  kodelet estimate --json "Fix the flaky watcher test"
+`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		if !cmd.Flags().Changed("log-level") {
+			logger.SetLogLevel("warn")
+		}
+
+		prompt := strings.TrimSpace(strings.Join(args, " "))
+		recipe, _ := cmd.Flags().GetString("recipe")
+		if prompt == "" && recipe == "" {
+			return errors.New("a prompt or --recipe is required")
+		}
+		if recipe != "" {
+			recipeArgs, _ := cmd.Flags().GetStringToString("arg")
+			rendered, err := renderEstimateRecipe(ctx, recipe, recipeArgs)
+			if err != nil {
+				return err
+			}
+			prompt = strings.TrimSpace(rendered + "\n" + prompt)
+		}
+
+		workingDir, err := os.Getwd()
+		if err != nil {
+			return errors.Wrap(err, "failed to get working directory")
+		}
+		config, err := llm.GetConfigFromViperForModel("", "", cmd)
+		if err != nil {
+			return errors.Wrap(err, "failed to load configuration")
+		}
+		targets, _ := cmd.Flags().GetStringArray("target")
+		if len(targets) == 0 {
+			targets = []string{config.Model, config.WeakModel}
+		}
+		models, err := estimateModelsFromTargets(cmd, targets)
+		if err != nil {
+			return err
+		}
+
+		projectRoot, err := conversations.ProjectRoot(ctx, workingDir)
+		if err != nil {
+			return err
+		}
+		workload, err := measureWorkload(ctx, config, projectRoot, workingDir, prompt)
+		if err != nil {
+			return err
+		}
+
+		var history estimate.History
+		store, err := conversations.GetConversationStore(ctx)
+		if err != nil {
+			logger.G(ctx).WithError(err).Warn("failed to open the conversation store, estimating without history")
+		} else {
+			defer store.Close()
+			history, err = estimate.SimilarRuns(ctx, store, estimate.HistoryOptions{ProjectRoot: projectRoot, Recipe: recipe})
+			if err != nil {
+				logger.G(ctx).WithError(err).Warn("failed to read past runs, estimating without history")
+			}
+		}
+
+		report := estimate.Estimate(workload, history.Runs, models)
+		out := cmd.OutOrStdout()
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		}
+		estimate.WriteReport(out, report)
+		if history.AllProjects {
+			cmd.Println("\nThis project has few runs of the recipe, so runs of it in every project were used.")
+		}
+		return nil
+	},
+}
+
+func init() {
+	estimateCmd.Flags().StringP("recipe", "r", "", "Estimate a run of this recipe")
+	estimateCmd.Flags().StringToString("arg", map[string]string{}, "Arguments passed to --recipe (e.g., --arg pr=42)")
+	estimateCmd.Flags().StringArray("target", nil, "Model to estimate, or profile:<name> for a configuration profile (repeatable; defaults to the configured model and weak model)")
+	estimateCmd.Flags().Bool("json", false, "Print the estimate as JSON")
+}
+
+func renderEstimateRecipe(ctx context.Context, recipe string, args map[string]string) (string, error) {
+	processor, err := fragments.NewFragmentProcessor()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create fragment processor")
+	}
+	fragment, err := processor.LoadFragment(ctx, &fragments.Config{FragmentName: recipe, Arguments: args})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to render recipe %s", recipe)
+	}
+	return fragment.Content, nil
+}
+
+// estimateModelsFromTargets prices each distinct target as configured for it.
+// Targets naming a profile are labelled with its model. Models without a known
+// price are kept without one.
+func estimateModelsFromTargets(cmd *cobra.Command, values []string) ([]estimate.Model, error) {
+	var models []estimate.Model
+	seen := make(map[string]bool)
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		target, err := bench.ParseTarget(value)
+		if err != nil {
+			return nil, err
+		}
+
+		config, err := llm.GetConfigFromViperForModel(target.Profile, target.Model, cmd)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load configuration of %s", target.Label())
+		}
+		model := estimate.Model{Name: target.Label()}
+		if target.Model == "" {
+			model.Name += " (" + config.Model + ")"
+		}
+		pricing, ok, err := llm.ModelPricing(config)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to price %s", target.Label())
+		}
+		if ok {
+			model.Pricing = &pricing
+		}
+		models = append(models, model)
+	}
+	return models, nil
+}
+
+// measureWorkload sizes the repository and what every turn of the run sends
+// before the conversation itself.
+func measureWorkload(ctx context.Context, config llmtypes.Config, projectRoot, workingDir, prompt string) (estimate.Workload, error) {
+	files, size, err := estimate.MeasureRepository(ctx, projectRoot)
+	if err != nil {
+		return estimate.Workload{}, errors.Wrap(err, "failed to measure the repository")
+	}
+	workload := estimate.Workload{RepoFiles: files, RepoBytes: size}
+
+	state := tools.NewBasicState(ctx,
+		tools.WithWorkingDirectory(workingDir),
+		tools.WithLLMConfig(config),
+		tools.WithMainTools(),
+	)
+	contexts := state.DiscoverContexts()
+	for _, content := range contexts {
+		workload.ContextTokens += base.EstimateTextTokens(content)
+	}
+
+	workload.PromptTokens = base.EstimateTextTokens(sysprompt.SystemPrompt(config.Model, config, contexts)) +
+		base.EstimateTextTokens(prompt)
+	for _, tool := range state.Tools() {
+		schema, err := json.Marshal(tooltypes.JSONSchemaForTool(tool))
+		if err != nil {
+			continue
+		}
+		workload.PromptTokens += base.EstimateTextTokens(tool.Name() + tool.Description() + string(schema))
+	}
+	return workload, nil
+}
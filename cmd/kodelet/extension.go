@@ -23,6 +23,14 @@ type ExtensionInspectConfig struct {
 	JSONOutput bool
 }
 
+type ExtensionEventsConfig struct {
+	ExtensionID string
+	Status      string
+	Limit       int
+	Requeue     bool
+	JSONOutput  bool
+}
+
 type ExtensionOutput struct {
 	ID        string `json:"id"`
 	Name      string `json:"name"`
@@ -62,11 +70,35 @@ var extensionInspectCmd = &cobra.Command{
 	},
 }
 
+var extensionEventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Show delivery status of durable extension events",
+	Long: `Show the events queued for extensions that subscribe durably, with their delivery status.
+
+Pending events are retried in order the next time the extension receives an event or starts. Events that fail extensions.event_max_attempts times are marked failed; --requeue returns them to the queue.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		config := ExtensionEventsConfig{}
+		config.ExtensionID, _ = cmd.Flags().GetString("extension")
+		config.Status, _ = cmd.Flags().GetString("status")
+		config.Limit, _ = cmd.Flags().GetInt("limit")
+		config.Requeue, _ = cmd.Flags().GetBool("requeue")
+		config.JSONOutput, _ = cmd.Flags().GetBool("json")
+		return runExtensionEvents(cmd.Context(), config)
+	},
+}
+
 func init() {
 	extensionListCmd.Flags().Bool("json", false, "Output in JSON format")
 	extensionInspectCmd.Flags().Bool("json", false, "Output in JSON format")
+	extensionEventsCmd.Flags().String("extension", "", "Only show events for this extension ID")
+	extensionEventsCmd.Flags().String("status", "", "Only show events with this status (pending, delivered, failed)")
+	extensionEventsCmd.Flags().Int("limit", 50, "Maximum number of events to show, most recent first (0 for all)")
+	extensionEventsCmd.Flags().Bool("requeue", false, "Return failed events to the queue for redelivery")
+	extensionEventsCmd.Flags().Bool("json", false, "Output in JSON format")
 	extensionCmd.AddCommand(extensionListCmd)
 	extensionCmd.AddCommand(extensionInspectCmd)
+	extensionCmd.AddCommand(extensionEventsCmd)
 	rootCmd.AddCommand(extensionCmd)
 }
 
@@ -107,6 +139,77 @@ func runExtensionInspect(_ context.Context, query string, config ExtensionInspec
 	return errors.Errorf("extension not found: %s", query)
 }
 
+func runExtensionEvents(ctx context.Context, config ExtensionEventsConfig) error {
+	switch config.Status {
+	case "", extensions.EventDeliveryPending, extensions.EventDeliveryDelivered, extensions.EventDeliveryFailed:
+	default:
+		return errors.Errorf("invalid status %q: must be pending, delivered or failed", config.Status)
+	}
+
+	queue, err := extensions.OpenEventQueue(ctx, "")
+	if err != nil {
+		return err
+	}
+	defer queue.Close()
+
+	if config.Requeue {
+		count, err := queue.Requeue(ctx, config.ExtensionID)
+		if err != nil {
+			return err
+		}
+		presenter.Success(fmt.Sprintf("Requeued %d failed event(s)", count))
+		return nil
+	}
+
+	deliveries, err := queue.List(ctx, extensions.EventDeliveryFilter{
+		ExtensionID: config.ExtensionID,
+		Status:      config.Status,
+		Limit:       config.Limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	if config.JSONOutput {
+		jsonData, err := json.MarshalIndent(map[string]any{"events": deliveries}, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "error generating JSON output")
+		}
+		_, err = fmt.Fprintln(os.Stdout, string(jsonData))
+		return err
+	}
+	if len(deliveries) == 0 {
+		presenter.Info("No extension events found")
+		return nil
+	}
+	return renderExtensionEventsTable(os.Stdout, deliveries)
+}
+
+func renderExtensionEventsTable(w io.Writer, deliveries []extensions.EventDelivery) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Created\tExtension\tEvent\tStatus\tAttempts\tLast error")
+	fmt.Fprintln(tw, "-------\t---------\t-----\t------\t--------\t----------")
+	for _, delivery := range deliveries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n",
+			delivery.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+			delivery.ExtensionID,
+			delivery.Event,
+			delivery.Status,
+			delivery.Attempts,
+			truncateExtensionEventError(delivery.LastError),
+		)
+	}
+	return tw.Flush()
+}
+
+func truncateExtensionEventError(message string) string {
+	message = strings.Join(strings.Fields(message), " ")
+	if len(message) <= 80 {
+		return message
+	}
+	return message[:77] + "..."
+}
+
 func discoverConfiguredExtensions() ([]extensions.Extension, error) {
 	discovery, err := extensions.NewDiscovery(extensions.WithConfig(extensions.LoadConfigFromViper()))
 	if err != nil {
@@ -3,10 +3,14 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/jingkaihe/kodelet/pkg/db"
+	"github.com/jingkaihe/kodelet/pkg/db/migrations"
+	"github.com/jingkaihe/kodelet/pkg/extensions"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -55,6 +59,63 @@ func TestRunExtensionListJSONEmpty(t *testing.T) {
 	assert.NotContains(t, listOutput, "No extensions found")
 }
 
+func TestRunExtensionEvents(t *testing.T) {
+	ctx := context.Background()
+	basePath := t.TempDir()
+	t.Setenv("KODELET_BASE_PATH", basePath)
+	database, err := db.Open(ctx, filepath.Join(basePath, "storage.db"))
+	require.NoError(t, err)
+	require.NoError(t, db.NewMigrationRunner(database).Run(ctx, migrations.All()))
+	require.NoError(t, database.Close())
+
+	queue, err := extensions.OpenEventQueue(ctx, "")
+	require.NoError(t, err)
+	for _, id := range []string{"evt-1", "evt-2"} {
+		require.NoError(t, queue.Enqueue(ctx, extensions.EventDelivery{
+			ID:          id,
+			ExtensionID: "audit",
+			Event:       extensions.EventTurnEnd,
+			Payload:     json.RawMessage(`{}`),
+		}))
+	}
+	require.NoError(t, queue.Drain(ctx, "audit", 1, func(_ context.Context, delivery extensions.EventDelivery) error {
+		if delivery.ID == "evt-1" {
+			return errors.New("webhook returned 503")
+		}
+		return nil
+	}))
+	require.NoError(t, queue.Close())
+
+	tableOutput := captureAllStdout(t, func() {
+		require.NoError(t, runExtensionEvents(ctx, ExtensionEventsConfig{Limit: 50}))
+	})
+	assert.Contains(t, tableOutput, "webhook returned 503")
+	assert.Contains(t, tableOutput, extensions.EventDeliveryFailed)
+	assert.Contains(t, tableOutput, extensions.EventDeliveryDelivered)
+
+	jsonOutput := captureAllStdout(t, func() {
+		require.NoError(t, runExtensionEvents(ctx, ExtensionEventsConfig{Status: extensions.EventDeliveryFailed, JSONOutput: true}))
+	})
+	var payload struct {
+		Events []extensions.EventDelivery `json:"events"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(jsonOutput), &payload))
+	require.Len(t, payload.Events, 1)
+	assert.Equal(t, "evt-1", payload.Events[0].ID)
+
+	captureAllStdout(t, func() {
+		require.NoError(t, runExtensionEvents(ctx, ExtensionEventsConfig{Requeue: true}))
+	})
+	jsonOutput = captureAllStdout(t, func() {
+		require.NoError(t, runExtensionEvents(ctx, ExtensionEventsConfig{Status: extensions.EventDeliveryPending, JSONOutput: true}))
+	})
+	require.NoError(t, json.Unmarshal([]byte(jsonOutput), &payload))
+	require.Len(t, payload.Events, 1)
+	assert.Zero(t, payload.Events[0].Attempts)
+
+	require.Error(t, runExtensionEvents(ctx, ExtensionEventsConfig{Status: "lost"}))
+}
+
 func restoreExtensionCommandViper(t *testing.T, globalDir string) {
 	t.Helper()
 	originalSettings := viper.AllSettings()
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/jingkaihe/kodelet/pkg/gitlab"
+	"github.com/jingkaihe/kodelet/pkg/osutil"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+const (
+	forgeGitHub = "github"
+	forgeGitLab = "gitlab"
+)
+
+// forgeConfig selects the code host that `kodelet pr`, `run --pr` and
+// `issue triage` work with.
+type forgeConfig struct {
+	Provider string
+	// Host is the GitLab instance, such as gitlab.example.com. When empty,
+	// glab uses the instance of the repository's remote.
+	Host string
+}
+
+// loadForgeConfig reads forge.provider and forge.host, defaulting to GitHub.
+func loadForgeConfig() (forgeConfig, error) {
+	config := forgeConfig{
+		Provider: strings.ToLower(strings.TrimSpace(viper.GetString("forge.provider"))),
+		Host:     strings.TrimSpace(viper.GetString("forge.host")),
+	}
+	if config.Provider == "" {
+		config.Provider = forgeGitHub
+	}
+	if err := validateForgeProvider(config.Provider); err != nil {
+		return config, errors.Wrap(err, "invalid forge.provider")
+	}
+	return config, nil
+}
+
+func validateForgeProvider(provider string) error {
+	switch provider {
+	case forgeGitHub, forgeGitLab:
+		return nil
+	}
+	return errors.Errorf("unsupported provider: %s, only 'github' and 'gitlab' are supported", provider)
+}
+
+// checkGitLabCLI checks that glab is installed and signed in to the host.
+func checkGitLabCLI(host string) error {
+	if !osutil.IsGLabCLIInstalled() {
+		return errors.New("GitLab CLI (glab) is not installed; see https://gitlab.com/gitlab-org/cli for installation instructions")
+	}
+	if !osutil.IsGLabCLIAuthenticated(host) {
+		login := "glab auth login"
+		if host != "" {
+			login += " --hostname " + host
+		}
+		return errors.Errorf("GitLab CLI (glab) is not authenticated; run '%s' first", login)
+	}
+	return nil
+}
+
+// newGitLabClient returns a client for the configured GitLab instance that
+// runs glab in dir, so the project is the one of the repository in dir.
+func newGitLabClient(forge forgeConfig, dir string) *gitlab.Client {
+	return gitlab.NewClient(gitlab.GLabRunner(dir), forge.Host)
+}
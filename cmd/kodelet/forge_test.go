@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadForgeConfig(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	forge, err := loadForgeConfig()
+	require.NoError(t, err)
+	assert.Equal(t, forgeConfig{Provider: forgeGitHub}, forge)
+
+	viper.Set("forge.provider", " GitLab ")
+	viper.Set("forge.host", "gitlab.example.com")
+	forge, err = loadForgeConfig()
+	require.NoError(t, err)
+	assert.Equal(t, forgeConfig{Provider: forgeGitLab, Host: "gitlab.example.com"}, forge)
+
+	viper.Set("forge.provider", "bitbucket")
+	_, err = loadForgeConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported provider: bitbucket")
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+)
+
+// githubPRDraftsDir is where pull request drafts are written, relative to the
+// working directory, when the GitHub token cannot open pull requests.
+var githubPRDraftsDir = filepath.Join(".kodelet", "pr-drafts")
+
+// githubCapability is a feature that needs a permission of the GitHub token.
+type githubCapability struct {
+	Feature string
+	// Scopes are the classic token scopes, any of which grants the feature.
+	Scopes []string
+	// Permission is the fine-grained token permission that grants it.
+	Permission string
+}
+
+var (
+	githubCapabilityPullRequests = githubCapability{
+		Feature:    "open and comment on pull requests",
+		Scopes:     []string{"repo", "public_repo"},
+		Permission: "Pull requests: write",
+	}
+	githubCapabilityIssues = githubCapability{
+		Feature:    "label and comment on issues",
+		Scopes:     []string{"repo", "public_repo"},
+		Permission: "Issues: write",
+	}
+)
+
+// githubTokenScopes are the scopes of the token gh uses. Fine-grained tokens
+// and GitHub App tokens do not report scopes, so what they allow is only
+// learnt from rejected requests.
+type githubTokenScopes struct {
+	Known  bool
+	Scopes []string
+}
+
+// detectGitHubTokenScopes reads the scopes of the gh token, which is GH_TOKEN
+// or GITHUB_TOKEN when set. Detection failures leave the scopes unknown.
+func detectGitHubTokenScopes(ctx context.Context, dir string) githubTokenScopes {
+	out, err := runGH(ctx, dir, "api", "--include", "user")
+	if err != nil {
+		return githubTokenScopes{}
+	}
+	return parseGitHubTokenScopes(out)
+}
+
+// parseGitHubTokenScopes reads the X-OAuth-Scopes header of a `gh api --include` response.
+func parseGitHubTokenScopes(response string) githubTokenScopes {
+	headers, _, _ := strings.Cut(strings.ReplaceAll(response, "\r\n", "\n"), "\n\n")
+	for _, line := range strings.Split(headers, "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "X-OAuth-Scopes") {
+			continue
+		}
+		scopes := []string{}
+		for _, scope := range strings.Split(value, ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				scopes = append(scopes, scope)
+			}
+		}
+		return githubTokenScopes{Known: true, Scopes: scopes}
+	}
+	return githubTokenScopes{}
+}
+
+// Allows reports whether the token may have the capability. Unknown scopes
+// are assumed to allow it.
+func (s githubTokenScopes) Allows(capability githubCapability) bool {
+	if !s.Known {
+		return true
+	}
+	return slices.ContainsFunc(capability.Scopes, func(scope string) bool {
+		return slices.Contains(s.Scopes, scope)
+	})
+}
+
+// renderGitHubCapabilities lists the token scope and permission each feature
+// needs and whether the token has it.
+func renderGitHubCapabilities(w io.Writer, scopes githubTokenScopes, capabilities ...githubCapability) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FEATURE\tCLASSIC TOKEN SCOPE\tFINE-GRAINED TOKEN PERMISSION\tSTATUS")
+	for _, capability := range capabilities {
+		status := "granted"
+		switch {
+		case !scopes.Known:
+			status = "unknown"
+		case !scopes.Allows(capability):
+			status = "missing"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", capability.Feature, strings.Join(capability.Scopes, " or "), capability.Permission, status)
+	}
+	tw.Flush()
+}
+
+// githubPermissionError is a request the GitHub token was not allowed to make.
+type githubPermissionError struct {
+	Capability githubCapability
+	err        error
+}
+
+func (e *githubPermissionError) Error() string {
+	return fmt.Sprintf("the GitHub token is not allowed to %s; it needs the %s scope (classic token) or %s (fine-grained token): %v",
+		e.Capability.Feature, strings.Join(e.Capability.Scopes, " or "), e.Capability.Permission, e.err)
+}
+
+func (e *githubPermissionError) Unwrap() error {
+	return e.err
+}
+
+// wrapGitHubPermissionError explains a gh failure caused by missing token
+// permissions in terms of the capability, and returns other errors as they are.
+func wrapGitHubPermissionError(err error, capability githubCapability) error {
+	if err == nil {
+		return nil
+	}
+	message := err.Error()
+	for _, marker := range []string{"HTTP 403", "Resource not accessible by", "403 Forbidden"} {
+		if strings.Contains(message, marker) {
+			return &githubPermissionError{Capability: capability, err: err}
+		}
+	}
+	return err
+}
+
+// githubPRDraftPath returns the file a pull request for branch is drafted to,
+// creating its directory. The directory ignores itself so drafts never end up
+// in commits.
+func githubPRDraftPath(cwd, branch string) (string, error) {
+	dir := filepath.Join(cwd, githubPRDraftsDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", errors.Wrap(err, "failed to create pull request draft directory")
+	}
+	gitignore := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(gitignore); os.IsNotExist(err) {
+		if err := os.WriteFile(gitignore, []byte("*\n"), 0o644); err != nil {
+			return "", errors.Wrap(err, "failed to write pull request draft .gitignore")
+		}
+	}
+	name := strings.NewReplacer("/", "-", "\\", "-").Replace(strings.TrimSpace(branch))
+	if name == "" {
+		name = "pull-request"
+	}
+	return filepath.Join(dir, name+".md"), nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jingkaihe/kodelet/pkg/fragments"
+)
+
+func TestParseGitHubTokenScopes(t *testing.T) {
+	scopes := parseGitHubTokenScopes("HTTP/2.0 200 OK\r\nContent-Type: application/json\r\nX-Oauth-Scopes: read:org, public_repo, gist\r\n\r\n{\"login\": \"octocat\"}")
+	assert.Equal(t, githubTokenScopes{Known: true, Scopes: []string{"read:org", "public_repo", "gist"}}, scopes)
+	assert.True(t, scopes.Allows(githubCapabilityPullRequests))
+
+	scopes = parseGitHubTokenScopes("HTTP/2.0 200 OK\nX-OAuth-Scopes: read:org\n\n{}")
+	assert.True(t, scopes.Known)
+	assert.False(t, scopes.Allows(githubCapabilityIssues))
+
+	// Fine-grained and GitHub App tokens report no scopes.
+	scopes = parseGitHubTokenScopes("HTTP/2.0 200 OK\nContent-Type: application/json\n\n{\"X-OAuth-Scopes\": \"repo\"}")
+	assert.False(t, scopes.Known)
+	assert.True(t, scopes.Allows(githubCapabilityIssues))
+}
+
+func TestDetectGitHubTokenScopes(t *testing.T) {
+	stubDir := t.TempDir()
+	writeGHStub(t, stubDir, `#!/bin/sh
+if [ "$1" = "api" ] && [ "$2" = "--include" ] && [ "$3" = "user" ]; then
+  printf 'HTTP/2.0 200 OK\r\nX-Oauth-Scopes: repo, workflow\r\n\r\n{}'
+  exit 0
+fi
+exit 1
+`)
+	t.Setenv("PATH", stubDir)
+	assert.Equal(t, githubTokenScopes{Known: true, Scopes: []string{"repo", "workflow"}}, detectGitHubTokenScopes(context.Background(), ""))
+
+	writeGHStub(t, stubDir, "#!/bin/sh\nexit 1\n")
+	assert.False(t, detectGitHubTokenScopes(context.Background(), "").Known)
+}
+
+func TestRenderGitHubCapabilities(t *testing.T) {
+	var out bytes.Buffer
+	renderGitHubCapabilities(&out, githubTokenScopes{Known: true, Scopes: []string{"read:org"}}, githubCapabilityPullRequests, githubCapabilityIssues)
+	assert.Contains(t, out.String(), "FINE-GRAINED TOKEN PERMISSION")
+	assert.Regexp(t, `open and comment on pull requests\s+repo or public_repo\s+Pull requests: write\s+missing`, out.String())
+	assert.Regexp(t, `label and comment on issues\s+repo or public_repo\s+Issues: write\s+missing`, out.String())
+
+	out.Reset()
+	renderGitHubCapabilities(&out, githubTokenScopes{}, githubCapabilityIssues)
+	assert.Contains(t, out.String(), "unknown")
+}
+
+func TestWrapGitHubPermissionError(t *testing.T) {
+	assert.NoError(t, wrapGitHubPermissionError(nil, githubCapabilityIssues))
+
+	other := errors.New("gh issue edit failed: could not resolve to an issue")
+	assert.Equal(t, other, wrapGitHubPermissionError(other, githubCapabilityIssues))
+
+	denied := errors.New("gh pr create failed: GraphQL: Resource not accessible by personal access token (createPullRequest)")
+	err := wrapGitHubPermissionError(denied, githubCapabilityPullRequests)
+	var permissionErr *githubPermissionError
+	require.ErrorAs(t, err, &permissionErr)
+	assert.Equal(t, githubCapabilityPullRequests, permissionErr.Capability)
+	assert.ErrorIs(t, err, denied)
+	assert.Contains(t, err.Error(), "the GitHub token is not allowed to open and comment on pull requests; it needs the repo or public_repo scope (classic token) or Pull requests: write (fine-grained token)")
+
+	err = wrapGitHubPermissionError(errors.New("gh issue comment failed: HTTP 403: Must have admin rights"), githubCapabilityIssues)
+	require.ErrorAs(t, err, &permissionErr)
+}
+
+func TestDraftRunPR(t *testing.T) {
+	dir := t.TempDir()
+	result, err := draftRunPR(dir, "kodelet/conv-1", "Fix the flaky test", "## Description\nRetry the network call.")
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(dir, ".kodelet", "pr-drafts", "kodelet-conv-1.md"), result.DraftPath)
+	content, err := os.ReadFile(result.DraftPath)
+	require.NoError(t, err)
+	assert.Equal(t, "## Description\nRetry the network call.\n", string(content))
+	gitignore, err := os.ReadFile(filepath.Join(dir, ".kodelet", "pr-drafts", ".gitignore"))
+	require.NoError(t, err)
+	assert.Equal(t, "*\n", string(gitignore))
+
+	assert.Equal(t, `gh pr create --base main --head kodelet/conv-1 --title "Fix the flaky test" --body-file `+result.DraftPath, result.createCommand("main"))
+}
+
+func TestPRFragmentDraftsToBodyFile(t *testing.T) {
+	processor, err := fragments.NewFragmentProcessor()
+	require.NoError(t, err)
+
+	fragment, err := processor.LoadFragment(context.Background(), &fragments.Config{
+		FragmentName: "github/pr",
+		Arguments:    map[string]string{"target": "main", "draft": "false", "body_file": "/repo/.kodelet/pr-drafts/feature-x.md"},
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, fragment.Content, "Do NOT create the pull request")
+	assert.Contains(t, fragment.Content, "write the pull request to the file /repo/.kodelet/pr-drafts/feature-x.md")
+	assert.NotContains(t, fragment.Content, "mcp__github_create_pull_request")
+	assert.NotContains(t, fragment.Content, "provide a link to the PR")
+}
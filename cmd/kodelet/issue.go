@@ -0,0 +1,544 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/jingkaihe/kodelet/pkg/fragments"
+	"github.com/jingkaihe/kodelet/pkg/llm"
+	"github.com/jingkaihe/kodelet/pkg/presenter"
+	"github.com/jingkaihe/kodelet/pkg/tools"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// issueTriageScanLimit caps how many open issues are fetched, both as
+	// triage candidates and as duplicate candidates.
+	issueTriageScanLimit = 500
+	// issueTriageMaxBodyBytes bounds the issue body sent to the model.
+	issueTriageMaxBodyBytes = 8000
+	// issueTriageCommentMarker identifies triage comments posted by kodelet.
+	issueTriageCommentMarker = "<!-- kodelet-issue-triage -->"
+	// issueTriageLabelColor is the hex color of a triaged label created by
+	// kodelet.
+	issueTriageLabelColor = "0E8A16"
+)
+
+var issueTriageTypes = []string{"bug", "feature", "question"}
+
+type IssueTriageConfig struct {
+	Repo         string
+	Limit        int
+	DryRun       bool
+	NoComment    bool
+	TriagedLabel string
+}
+
+func NewIssueTriageConfig() *IssueTriageConfig {
+	return &IssueTriageConfig{
+		Limit:        10,
+		TriagedLabel: "triaged",
+	}
+}
+
+func (c *IssueTriageConfig) Validate() error {
+	if c.Limit <= 0 {
+		return errors.New("limit must be greater than 0")
+	}
+	return nil
+}
+
+var issueCmd = &cobra.Command{
+	Use:   "issue",
+	Short: "Work with GitHub and GitLab issues",
+}
+
+var issueTriageCmd = &cobra.Command{
+	Use:   "triage",
+	Short: "Classify open issues, label them and post a triage summary",
+	Long: `Scan open issues of a GitHub repository or GitLab project and triage the ones that have not been triaged yet.
+
+Each issue is classified as a bug, feature or question with the weak model, which also names the affected modules and likely duplicates among the other open issues. Matching labels that already exist in the repository are applied, and a triage summary is posted as a comment.
+
+Issues carrying the triaged label (default "triaged") are skipped, and the label is added after triage when it exists in the repository. Use --dry-run to preview the results without changing any issue.
+
+GitLab is used when forge.provider is set to gitlab in the configuration.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		ctx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sigCh
+			presenter.Warning("Cancellation requested, shutting down...")
+			cancel()
+		}()
+
+		config := getIssueTriageConfigFromFlags(cmd)
+		if err := config.Validate(); err != nil {
+			presenter.Error(err, "Invalid configuration")
+			os.Exit(1)
+		}
+
+		llmConfig, err := llm.GetConfigFromViperWithCmd(cmd)
+		if err != nil {
+			presenter.Error(err, "Failed to load configuration")
+			os.Exit(1)
+		}
+
+		forge, err := loadForgeConfig()
+		if err != nil {
+			presenter.Error(err, "Failed to load configuration")
+			os.Exit(1)
+		}
+
+		if forge.Provider == forgeGitLab {
+			if err := checkGitLabCLI(forge.Host); err != nil {
+				presenter.Error(err, "GitLab CLI is not ready")
+				os.Exit(1)
+			}
+		} else if !isGhCliInstalled() {
+			presenter.Error(errors.New("GitHub CLI not installed"), "GitHub CLI (gh) is not installed. Please install it first")
+			presenter.Info("Visit https://cli.github.com/ for installation instructions")
+			os.Exit(1)
+		} else if !isGhAuthenticated() {
+			presenter.Error(errors.New("not authenticated with GitHub"), "You are not authenticated with GitHub. Please run 'gh auth login' first")
+			os.Exit(1)
+		}
+		if forge.Provider == forgeGitHub && !config.DryRun {
+			scopes := detectGitHubTokenScopes(ctx, "")
+			if !scopes.Allows(githubCapabilityIssues) {
+				presenter.Warning("The GitHub token cannot label or comment on issues; triaging as a dry run")
+				renderGitHubCapabilities(os.Stderr, scopes, githubCapabilityIssues)
+				config.DryRun = true
+			}
+		}
+
+		triager := newIssueTriager(llmConfig, forge)
+		results, usage, err := triager.Run(ctx, config)
+		if err != nil {
+			presenter.Error(err, "Failed to triage issues")
+			os.Exit(1)
+		}
+
+		renderIssueTriageResults(os.Stdout, results, config.DryRun)
+		presenter.Separator()
+		presenter.Stats(presenter.ConvertUsageStats(&usage))
+	},
+}
+
+func init() {
+	defaults := NewIssueTriageConfig()
+	issueTriageCmd.Flags().String("repo", defaults.Repo, "Repository to triage as OWNER/REPO, or a GitLab project path (defaults to the current repository)")
+	issueTriageCmd.Flags().Int("limit", defaults.Limit, "Maximum number of issues to triage in this batch")
+	issueTriageCmd.Flags().Bool("dry-run", defaults.DryRun, "Classify issues and print the results without labeling or commenting")
+	issueTriageCmd.Flags().Bool("no-comment", defaults.NoComment, "Apply labels without posting a triage comment")
+	issueTriageCmd.Flags().String("triaged-label", defaults.TriagedLabel, "Label marking triaged issues; they are skipped and the label is added after triage")
+	issueCmd.AddCommand(issueTriageCmd)
+}
+
+func getIssueTriageConfigFromFlags(cmd *cobra.Command) *IssueTriageConfig {
+	config := NewIssueTriageConfig()
+
+	if repo, err := cmd.Flags().GetString("repo"); err == nil {
+		config.Repo = strings.TrimSpace(repo)
+	}
+	if limit, err := cmd.Flags().GetInt("limit"); err == nil {
+		config.Limit = limit
+	}
+	if dryRun, err := cmd.Flags().GetBool("dry-run"); err == nil {
+		config.DryRun = dryRun
+	}
+	if noComment, err := cmd.Flags().GetBool("no-comment"); err == nil {
+		config.NoComment = noComment
+	}
+	if triagedLabel, err := cmd.Flags().GetString("triaged-label"); err == nil {
+		config.TriagedLabel = strings.TrimSpace(triagedLabel)
+	}
+
+	return config
+}
+
+type trackerLabel struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type trackerIssue struct {
+	Number int            `json:"number"`
+	Title  string         `json:"title"`
+	Body   string         `json:"body"`
+	URL    string         `json:"url"`
+	Labels []trackerLabel `json:"labels"`
+}
+
+func (i trackerIssue) hasLabel(name string) bool {
+	return slices.ContainsFunc(i.Labels, func(label trackerLabel) bool {
+		return strings.EqualFold(label.Name, name)
+	})
+}
+
+// issueTriage is the model's classification of an issue.
+type issueTriage struct {
+	Type       string   `json:"type"`
+	Modules    []string `json:"modules"`
+	Labels     []string `json:"labels"`
+	Duplicates []int    `json:"duplicates"`
+	Summary    string   `json:"summary"`
+}
+
+// issueTriageResult is the triage outcome of one issue.
+type issueTriageResult struct {
+	Issue  trackerIssue
+	Triage issueTriage
+	// AddLabels are the labels applied to the issue, including the triaged
+	// label.
+	AddLabels []string
+	Err       error
+}
+
+// issueTriager triages issues of an issue tracker with the weak model. Both
+// are replaceable so the flow can be tested without GitHub or an LLM.
+type issueTriager struct {
+	tracker  issueTracker
+	classify func(ctx context.Context, prompt string) (string, llmtypes.Usage)
+}
+
+func newIssueTriager(llmConfig llmtypes.Config, forge forgeConfig) *issueTriager {
+	var tracker issueTracker = &githubIssueTracker{
+		gh: func(ctx context.Context, args ...string) (string, error) {
+			return runGH(ctx, "", args...)
+		},
+	}
+	if forge.Provider == forgeGitLab {
+		tracker = &gitlabIssueTracker{client: newGitLabClient(forge, "")}
+	}
+	return &issueTriager{
+		tracker: tracker,
+		classify: func(ctx context.Context, prompt string) (string, llmtypes.Usage) {
+			state := tools.NewBasicState(ctx, tools.WithLLMConfig(llmConfig))
+			return llm.SendMessageAndGetTextWithUsage(ctx, state, prompt, llmConfig, true, llmtypes.MessageOpt{
+				UseWeakModel:       true,
+				NoToolUse:          true,
+				NoSaveConversation: true,
+			})
+		},
+	}
+}
+
+// Run triages up to config.Limit untriaged open issues.
+func (t *issueTriager) Run(ctx context.Context, config *IssueTriageConfig) ([]issueTriageResult, llmtypes.Usage, error) {
+	var usage llmtypes.Usage
+
+	issues, err := t.tracker.ListOpenIssues(ctx, config.Repo)
+	if err != nil {
+		return nil, usage, err
+	}
+	labels, err := t.tracker.ListLabels(ctx, config.Repo)
+	if err != nil {
+		return nil, usage, err
+	}
+
+	processor, err := fragments.NewFragmentProcessor()
+	if err != nil {
+		return nil, usage, errors.Wrap(err, "failed to create fragment processor")
+	}
+
+	selected := selectIssuesToTriage(issues, config.TriagedLabel, config.Limit)
+	if len(selected) > 0 && !config.DryRun {
+		labels = t.ensureTriagedLabel(ctx, config, labels)
+	}
+
+	var results []issueTriageResult
+	// permissionErr stops changes to further issues once the token has been
+	// rejected, as they would be rejected too.
+	var permissionErr *githubPermissionError
+	for _, issue := range selected {
+		if ctx.Err() != nil {
+			return results, usage, ctx.Err()
+		}
+		result := issueTriageResult{Issue: issue}
+
+		fragment, err := processor.LoadFragment(ctx, &fragments.Config{
+			FragmentName: "github/issue-triage",
+			Arguments:    issueTriageArguments(issue, issues, labels),
+		})
+		if err != nil {
+			return results, usage, errors.Wrap(err, "failed to load built-in issue-triage recipe")
+		}
+
+		out, messageUsage := t.classify(ctx, fragment.Content)
+		addIssueTriageUsage(&usage, messageUsage)
+
+		triage, err := parseIssueTriage(out)
+		if err != nil {
+			result.Err = err
+			results = append(results, result)
+			continue
+		}
+		result.Triage = sanitizeIssueTriage(triage, issue, issues, labels)
+		result.AddLabels = issueTriageLabels(result.Triage, issue, labels, config.TriagedLabel)
+
+		switch {
+		case config.DryRun:
+		case permissionErr != nil:
+			result.Err = permissionErr
+		default:
+			result.Err = t.apply(ctx, config, result)
+			errors.As(result.Err, &permissionErr)
+		}
+		results = append(results, result)
+	}
+	return results, usage, nil
+}
+
+// ensureTriagedLabel creates the triaged label when the repository does not
+// define it, so triaged issues can be told apart on the next run. A label
+// that cannot be created is reported and the issues are triaged without it.
+func (t *issueTriager) ensureTriagedLabel(ctx context.Context, config *IssueTriageConfig, labels []trackerLabel) []trackerLabel {
+	if config.TriagedLabel == "" {
+		return labels
+	}
+	if _, ok := findTrackerLabel(labels, config.TriagedLabel); ok {
+		return labels
+	}
+	label := trackerLabel{Name: config.TriagedLabel, Description: "Triaged by kodelet issue triage"}
+	if err := t.tracker.CreateLabel(ctx, config.Repo, label); err != nil {
+		presenter.Warning(fmt.Sprintf("Issues will not be marked as triaged: %v", err))
+		return labels
+	}
+	return append(labels, label)
+}
+
+func (t *issueTriager) apply(ctx context.Context, config *IssueTriageConfig, result issueTriageResult) error {
+	if len(result.AddLabels) > 0 {
+		if err := t.tracker.AddLabels(ctx, config.Repo, result.Issue.Number, result.AddLabels); err != nil {
+			return err
+		}
+	}
+	if !config.NoComment {
+		if err := t.tracker.Comment(ctx, config.Repo, result.Issue.Number, formatIssueTriageComment(result.Triage)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectIssuesToTriage returns up to limit issues without the triaged label,
+// oldest first so a backlog is worked through in order.
+func selectIssuesToTriage(issues []trackerIssue, triagedLabel string, limit int) []trackerIssue {
+	var selected []trackerIssue
+	for _, issue := range issues {
+		if triagedLabel != "" && issue.hasLabel(triagedLabel) {
+			continue
+		}
+		selected = append(selected, issue)
+	}
+	slices.SortFunc(selected, func(a, b trackerIssue) int {
+		return a.Number - b.Number
+	})
+	if len(selected) > limit {
+		selected = selected[:limit]
+	}
+	return selected
+}
+
+func issueTriageArguments(issue trackerIssue, openIssues []trackerIssue, labels []trackerLabel) map[string]string {
+	var labelLines []string
+	for _, label := range labels {
+		line := label.Name
+		if description := strings.TrimSpace(label.Description); description != "" {
+			line += ": " + description
+		}
+		labelLines = append(labelLines, line)
+	}
+
+	var issueLines []string
+	for _, other := range openIssues {
+		if other.Number != issue.Number {
+			issueLines = append(issueLines, fmt.Sprintf("#%d %s", other.Number, other.Title))
+		}
+	}
+
+	body := strings.TrimSpace(issue.Body)
+	if len(body) > issueTriageMaxBodyBytes {
+		body = strings.ToValidUTF8(body[:issueTriageMaxBodyBytes], "") + "\n[truncated]"
+	}
+
+	return map[string]string{
+		"number":      strconv.Itoa(issue.Number),
+		"title":       issue.Title,
+		"body":        body,
+		"labels":      strings.Join(labelLines, "\n"),
+		"open_issues": strings.Join(issueLines, "\n"),
+	}
+}
+
+// parseIssueTriage extracts the JSON classification from the model output,
+// tolerating surrounding prose or code fences.
+func parseIssueTriage(out string) (issueTriage, error) {
+	start := strings.Index(out, "{")
+	end := strings.LastIndex(out, "}")
+	if start < 0 || end < start {
+		return issueTriage{}, errors.New("model response did not contain a triage result")
+	}
+	var triage issueTriage
+	if err := json.Unmarshal([]byte(out[start:end+1]), &triage); err != nil {
+		return issueTriage{}, errors.Wrap(err, "failed to parse triage result")
+	}
+	triage.Type = strings.ToLower(strings.TrimSpace(triage.Type))
+	if !slices.Contains(issueTriageTypes, triage.Type) {
+		return issueTriage{}, errors.Errorf("unknown issue type %q", triage.Type)
+	}
+	return triage, nil
+}
+
+// sanitizeIssueTriage keeps only labels that exist in the repository and
+// duplicates that are other open issues.
+func sanitizeIssueTriage(triage issueTriage, issue trackerIssue, openIssues []trackerIssue, labels []trackerLabel) issueTriage {
+	var validLabels []string
+	for _, name := range triage.Labels {
+		if label, ok := findTrackerLabel(labels, name); ok && !slices.Contains(validLabels, label.Name) {
+			validLabels = append(validLabels, label.Name)
+		}
+	}
+	triage.Labels = validLabels
+
+	var duplicates []int
+	for _, number := range triage.Duplicates {
+		if number == issue.Number || slices.Contains(duplicates, number) {
+			continue
+		}
+		if slices.ContainsFunc(openIssues, func(other trackerIssue) bool { return other.Number == number }) {
+			duplicates = append(duplicates, number)
+		}
+	}
+	triage.Duplicates = duplicates
+
+	var modules []string
+	for _, module := range triage.Modules {
+		if module = strings.TrimSpace(module); module != "" {
+			modules = append(modules, module)
+		}
+	}
+	triage.Modules = modules
+	triage.Summary = strings.TrimSpace(triage.Summary)
+	return triage
+}
+
+// issueTriageLabels returns the labels to add to the issue: the suggested
+// labels it does not have yet, plus the triaged label when the repository
+// defines it.
+func issueTriageLabels(triage issueTriage, issue trackerIssue, labels []trackerLabel, triagedLabel string) []string {
+	var add []string
+	for _, name := range triage.Labels {
+		if !issue.hasLabel(name) {
+			add = append(add, name)
+		}
+	}
+	if triagedLabel != "" {
+		if label, ok := findTrackerLabel(labels, triagedLabel); ok && !slices.Contains(add, label.Name) {
+			add = append(add, label.Name)
+		}
+	}
+	return add
+}
+
+func findTrackerLabel(labels []trackerLabel, name string) (trackerLabel, bool) {
+	name = strings.TrimSpace(name)
+	for _, label := range labels {
+		if strings.EqualFold(label.Name, name) {
+			return label, true
+		}
+	}
+	return trackerLabel{}, false
+}
+
+func formatIssueTriageComment(triage issueTriage) string {
+	var b strings.Builder
+	b.WriteString(issueTriageCommentMarker + "\n")
+	b.WriteString("### Triage\n\n")
+	fmt.Fprintf(&b, "**Type:** %s\n", triage.Type)
+	if len(triage.Modules) > 0 {
+		fmt.Fprintf(&b, "**Affected modules:** %s\n", strings.Join(triage.Modules, ", "))
+	}
+	if len(triage.Duplicates) > 0 {
+		fmt.Fprintf(&b, "**Possible duplicates:** %s\n", formatIssueNumbers(triage.Duplicates))
+	}
+	if triage.Summary != "" {
+		fmt.Fprintf(&b, "\n%s\n", triage.Summary)
+	}
+	b.WriteString("\n<sub>Triaged automatically by `kodelet issue triage`. Labels and duplicate candidates may need a maintainer's check.</sub>")
+	return b.String()
+}
+
+func formatIssueNumbers(numbers []int) string {
+	formatted := make([]string, 0, len(numbers))
+	for _, number := range numbers {
+		formatted = append(formatted, "#"+strconv.Itoa(number))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func addIssueTriageUsage(total *llmtypes.Usage, usage llmtypes.Usage) {
+	total.InputTokens += usage.InputTokens
+	total.OutputTokens += usage.OutputTokens
+	total.CacheCreationInputTokens += usage.CacheCreationInputTokens
+	total.CacheReadInputTokens += usage.CacheReadInputTokens
+	total.InputCost += usage.InputCost
+	total.OutputCost += usage.OutputCost
+	total.CacheCreationCost += usage.CacheCreationCost
+	total.CacheReadCost += usage.CacheReadCost
+}
+
+func renderIssueTriageResults(w io.Writer, results []issueTriageResult, dryRun bool) {
+	if len(results) == 0 {
+		presenter.Info("No untriaged open issues found")
+		return
+	}
+	if dryRun {
+		presenter.Section("Issue Triage (dry run, no issues changed)")
+	} else {
+		presenter.Section("Issue Triage")
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ISSUE\tTYPE\tMODULES\tLABELS\tDUPLICATES\tSTATUS")
+	for _, result := range results {
+		status := "triaged"
+		if dryRun {
+			status = "dry run"
+		}
+		if result.Err != nil {
+			status = "error: " + result.Err.Error()
+		}
+		fmt.Fprintf(tw, "#%d\t%s\t%s\t%s\t%s\t%s\n",
+			result.Issue.Number,
+			valueOrDash(result.Triage.Type),
+			valueOrDash(strings.Join(result.Triage.Modules, ", ")),
+			valueOrDash(strings.Join(result.AddLabels, ", ")),
+			valueOrDash(formatIssueNumbers(result.Triage.Duplicates)),
+			status,
+		)
+	}
+	_ = tw.Flush()
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jingkaihe/kodelet/pkg/fragments"
+	"github.com/jingkaihe/kodelet/pkg/gitlab"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+const issueTriageTestIssues = `[
+	{"number": 7, "title": "Crash when resuming a conversation", "body": "panic in pkg/conversations", "url": "https://github.com/o/r/issues/7", "labels": []},
+	{"number": 3, "title": "Resume crashes", "body": "panic on resume", "url": "https://github.com/o/r/issues/3", "labels": [{"name": "triaged"}]},
+	{"number": 9, "title": "Support Gemini", "body": "please add a provider", "url": "https://github.com/o/r/issues/9", "labels": [{"name": "enhancement"}]}
+]`
+
+const issueTriageTestLabels = `[
+	{"name": "bug", "description": "Something is broken"},
+	{"name": "enhancement", "description": ""},
+	{"name": "area/llm", "description": "LLM providers"},
+	{"name": "Triaged", "description": ""}
+]`
+
+type fakeIssueTriageGH struct {
+	calls [][]string
+}
+
+func (f *fakeIssueTriageGH) run(_ context.Context, args ...string) (string, error) {
+	f.calls = append(f.calls, args)
+	switch args[0] + " " + args[1] {
+	case "issue list":
+		return issueTriageTestIssues, nil
+	case "label list":
+		return issueTriageTestLabels, nil
+	}
+	return "", nil
+}
+
+func TestIssueTriagerRun(t *testing.T) {
+	gh := &fakeIssueTriageGH{}
+	var prompts []string
+	triager := &issueTriager{
+		tracker: &githubIssueTracker{gh: gh.run},
+		classify: func(_ context.Context, prompt string) (string, llmtypes.Usage) {
+			prompts = append(prompts, prompt)
+			if strings.Contains(prompt, `<issue number="7">`) {
+				return "```json\n" + `{"type": "Bug", "modules": ["pkg/conversations", " "], "labels": ["BUG", "wontfix"], "duplicates": [3, 7, 42], "summary": "Resume panics."}` + "\n```", llmtypes.Usage{InputTokens: 10, InputCost: 0.01}
+			}
+			return `{"type": "feature", "modules": ["pkg/llm"], "labels": ["enhancement", "area/llm"], "duplicates": [], "summary": "New provider."}`, llmtypes.Usage{InputTokens: 5, InputCost: 0.02}
+		},
+	}
+
+	config := NewIssueTriageConfig()
+	config.Repo = "o/r"
+	results, usage, err := triager.Run(context.Background(), config)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.Equal(t, 7, results[0].Issue.Number)
+	assert.Equal(t, "bug", results[0].Triage.Type)
+	assert.Equal(t, []string{"pkg/conversations"}, results[0].Triage.Modules)
+	assert.Equal(t, []int{3}, results[0].Triage.Duplicates)
+	assert.Equal(t, []string{"bug", "Triaged"}, results[0].AddLabels)
+	assert.Equal(t, 9, results[1].Issue.Number)
+	assert.Equal(t, []string{"area/llm", "Triaged"}, results[1].AddLabels)
+	assert.Equal(t, 15, usage.InputTokens)
+	assert.InDelta(t, 0.03, usage.TotalCost(), 1e-9)
+
+	require.Len(t, prompts, 2)
+	assert.Contains(t, prompts[0], "bug: Something is broken")
+	assert.Contains(t, prompts[0], "#3 Resume crashes")
+	assert.NotContains(t, prompts[0], "#7 Crash when resuming")
+
+	assert.Contains(t, gh.calls, []string{"issue", "edit", "7", "--add-label", "bug,Triaged", "--repo", "o/r"})
+	assert.Contains(t, gh.calls, []string{"issue", "edit", "9", "--add-label", "area/llm,Triaged", "--repo", "o/r"})
+	var comments int
+	for _, call := range gh.calls {
+		if call[1] == "comment" {
+			comments++
+			assert.Contains(t, call[4], issueTriageCommentMarker)
+		}
+	}
+	assert.Equal(t, 2, comments)
+}
+
+func TestIssueTriagerRunDryRunAndLimit(t *testing.T) {
+	gh := &fakeIssueTriageGH{}
+	triager := &issueTriager{
+		tracker: &githubIssueTracker{gh: gh.run},
+		classify: func(context.Context, string) (string, llmtypes.Usage) {
+			return "I am not sure.", llmtypes.Usage{}
+		},
+	}
+
+	config := NewIssueTriageConfig()
+	config.DryRun = true
+	config.Limit = 1
+	results, _, err := triager.Run(context.Background(), config)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, 7, results[0].Issue.Number)
+	require.Error(t, results[0].Err)
+	assert.Contains(t, results[0].Err.Error(), "did not contain a triage result")
+
+	require.Len(t, gh.calls, 2)
+	assert.NotContains(t, gh.calls[0], "--repo")
+
+	var out bytes.Buffer
+	renderIssueTriageResults(&out, results, true)
+	assert.Contains(t, out.String(), "#7")
+	assert.Contains(t, out.String(), "error: model response did not contain a triage result")
+}
+
+func TestIssueTriagerRunStopsChangesWhenTokenIsRejected(t *testing.T) {
+	var edits int
+	triager := &issueTriager{
+		tracker: &githubIssueTracker{gh: func(_ context.Context, args ...string) (string, error) {
+			switch args[0] + " " + args[1] {
+			case "issue list":
+				return issueTriageTestIssues, nil
+			case "label list":
+				return issueTriageTestLabels, nil
+			}
+			edits++
+			return "", errors.New("gh issue failed: HTTP 403: Resource not accessible by integration")
+		}},
+		classify: func(context.Context, string) (string, llmtypes.Usage) {
+			return `{"type": "bug", "modules": [], "labels": ["bug"], "duplicates": [], "summary": "Broken."}`, llmtypes.Usage{}
+		},
+	}
+
+	results, _, err := triager.Run(context.Background(), NewIssueTriageConfig())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, 1, edits)
+	for _, result := range results {
+		var permissionErr *githubPermissionError
+		require.ErrorAs(t, result.Err, &permissionErr)
+		assert.Equal(t, "Issues: write", permissionErr.Capability.Permission)
+	}
+}
+
+func TestIssueTriagerRunCreatesMissingTriagedLabel(t *testing.T) {
+	var calls [][]string
+	triager := &issueTriager{
+		tracker: &githubIssueTracker{gh: func(_ context.Context, args ...string) (string, error) {
+			calls = append(calls, args)
+			switch args[0] + " " + args[1] {
+			case "issue list":
+				return `[{"number": 5, "title": "Crash", "body": "", "labels": []}]`, nil
+			case "label list":
+				return `[{"name": "bug", "description": ""}]`, nil
+			}
+			return "", nil
+		}},
+		classify: func(context.Context, string) (string, llmtypes.Usage) {
+			return `{"type": "bug", "modules": [], "labels": ["bug"], "duplicates": [], "summary": "Broken."}`, llmtypes.Usage{}
+		},
+	}
+
+	results, _, err := triager.Run(context.Background(), NewIssueTriageConfig())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, []string{"bug", "triaged"}, results[0].AddLabels)
+	assert.Equal(t, []string{"label", "create", "triaged", "--color", issueTriageLabelColor, "--description", "Triaged by kodelet issue triage"}, calls[2])
+}
+
+func TestIssueTriagerRunGitLab(t *testing.T) {
+	var calls []string
+	client := gitlab.NewClient(func(_ context.Context, args ...string) (string, error) {
+		call := strings.Join(args, " ")
+		calls = append(calls, call)
+		switch {
+		case strings.Contains(call, "/issues?state=opened"):
+			return `[{"iid": 4, "title": "Pipeline fails on tags", "description": "CI breaks", "web_url": "https://gitlab.example.com/g/p/-/issues/4", "labels": []},
+				{"iid": 2, "title": "Old", "labels": ["triaged"]}]`, nil
+		case strings.Contains(call, "/labels?"):
+			return `[{"name": "bug", "description": "Something is broken"}, {"name": "triaged"}]`, nil
+		}
+		return "{}", nil
+	}, "gitlab.example.com")
+	triager := &issueTriager{
+		tracker: &gitlabIssueTracker{client: client},
+		classify: func(_ context.Context, prompt string) (string, llmtypes.Usage) {
+			assert.Contains(t, prompt, "bug: Something is broken")
+			return `{"type": "bug", "modules": ["ci"], "labels": ["bug"], "duplicates": [], "summary": "Tag pipelines fail."}`, llmtypes.Usage{}
+		},
+	}
+
+	config := NewIssueTriageConfig()
+	config.Repo = "g/p"
+	results, _, err := triager.Run(context.Background(), config)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, 4, results[0].Issue.Number)
+	assert.Equal(t, []string{"bug", "triaged"}, results[0].AddLabels)
+
+	require.Len(t, calls, 4)
+	assert.Contains(t, calls[2], "--method PUT projects/g%2Fp/issues/4 --raw-field add_labels=bug,triaged")
+	assert.Contains(t, calls[3], "--method POST projects/g%2Fp/issues/4/notes")
+	assert.Contains(t, calls[3], issueTriageCommentMarker)
+}
+
+func TestParseIssueTriageRejectsUnknownType(t *testing.T) {
+	_, err := parseIssueTriage(`{"type": "chore"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown issue type "chore"`)
+}
+
+func TestFormatIssueTriageComment(t *testing.T) {
+	comment := formatIssueTriageComment(issueTriage{
+		Type:       "bug",
+		Modules:    []string{"pkg/tui"},
+		Duplicates: []int{4, 8},
+		Summary:    "The TUI freezes on resize.",
+	})
+
+	assert.True(t, strings.HasPrefix(comment, issueTriageCommentMarker))
+	assert.Contains(t, comment, "**Type:** bug")
+	assert.Contains(t, comment, "**Affected modules:** pkg/tui")
+	assert.Contains(t, comment, "**Possible duplicates:** #4, #8")
+	assert.Contains(t, comment, "The TUI freezes on resize.")
+}
+
+func TestGetIssueTriageConfigFromFlags(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().AddFlagSet(issueTriageCmd.Flags())
+	require.NoError(t, cmd.Flags().Set("limit", "3"))
+	require.NoError(t, cmd.Flags().Set("dry-run", "true"))
+	require.NoError(t, cmd.Flags().Set("triaged-label", " needs-review "))
+
+	config := getIssueTriageConfigFromFlags(cmd)
+	assert.Equal(t, 3, config.Limit)
+	assert.True(t, config.DryRun)
+	assert.Equal(t, "needs-review", config.TriagedLabel)
+
+	config.Limit = 0
+	assert.EqualError(t, config.Validate(), "limit must be greater than 0")
+}
+
+func TestIssueTriageFragmentContent(t *testing.T) {
+	processor, err := fragments.NewFragmentProcessor()
+	require.NoError(t, err)
+
+	fragment, err := processor.LoadFragment(context.Background(), &fragments.Config{
+		FragmentName: "github/issue-triage",
+		Arguments: issueTriageArguments(
+			trackerIssue{Number: 5, Title: "Broken {{build}}", Body: "steps"},
+			[]trackerIssue{{Number: 5, Title: "Broken {{build}}"}, {Number: 6, Title: "Other"}},
+			[]trackerLabel{{Name: "bug"}},
+		),
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, fragment.Content, "Triage GitHub issue #5")
+	assert.Contains(t, fragment.Content, "<title>Broken {{build}}</title>")
+	assert.Contains(t, fragment.Content, "#6 Other")
+	assert.Contains(t, fragment.Content, `"bug", "feature" or "question"`)
+}
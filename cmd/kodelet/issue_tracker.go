@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/jingkaihe/kodelet/pkg/gitlab"
+	"github.com/pkg/errors"
+)
+
+// issueTracker lists, labels and comments on the issues of a repository. repo
+// is empty for the repository of the working directory.
+type issueTracker interface {
+	ListOpenIssues(ctx context.Context, repo string) ([]trackerIssue, error)
+	ListLabels(ctx context.Context, repo string) ([]trackerLabel, error)
+	CreateLabel(ctx context.Context, repo string, label trackerLabel) error
+	AddLabels(ctx context.Context, repo string, number int, labels []string) error
+	Comment(ctx context.Context, repo string, number int, body string) error
+}
+
+// githubIssueTracker works with GitHub issues through gh.
+type githubIssueTracker struct {
+	gh func(ctx context.Context, args ...string) (string, error)
+}
+
+func (t *githubIssueTracker) ListOpenIssues(ctx context.Context, repo string) ([]trackerIssue, error) {
+	args := withGHRepo([]string{"issue", "list", "--state", "open", "--limit", strconv.Itoa(issueTriageScanLimit), "--json", "number,title,body,url,labels"}, repo)
+	out, err := t.gh(ctx, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list open issues")
+	}
+	var issues []trackerIssue
+	if err := json.Unmarshal([]byte(out), &issues); err != nil {
+		return nil, errors.Wrap(err, "failed to parse open issues")
+	}
+	return issues, nil
+}
+
+func (t *githubIssueTracker) ListLabels(ctx context.Context, repo string) ([]trackerLabel, error) {
+	args := withGHRepo([]string{"label", "list", "--limit", strconv.Itoa(issueTriageScanLimit), "--json", "name,description"}, repo)
+	out, err := t.gh(ctx, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list labels")
+	}
+	var labels []trackerLabel
+	if err := json.Unmarshal([]byte(out), &labels); err != nil {
+		return nil, errors.Wrap(err, "failed to parse labels")
+	}
+	return labels, nil
+}
+
+func (t *githubIssueTracker) CreateLabel(ctx context.Context, repo string, label trackerLabel) error {
+	args := withGHRepo([]string{"label", "create", label.Name, "--color", issueTriageLabelColor, "--description", label.Description}, repo)
+	if _, err := t.gh(ctx, args...); err != nil {
+		return wrapGitHubPermissionError(errors.Wrapf(err, "failed to create label %s", label.Name), githubCapabilityIssues)
+	}
+	return nil
+}
+
+func (t *githubIssueTracker) AddLabels(ctx context.Context, repo string, number int, labels []string) error {
+	args := withGHRepo([]string{"issue", "edit", strconv.Itoa(number), "--add-label", strings.Join(labels, ",")}, repo)
+	if _, err := t.gh(ctx, args...); err != nil {
+		return wrapGitHubPermissionError(errors.Wrap(err, "failed to label issue"), githubCapabilityIssues)
+	}
+	return nil
+}
+
+func (t *githubIssueTracker) Comment(ctx context.Context, repo string, number int, body string) error {
+	args := withGHRepo([]string{"issue", "comment", strconv.Itoa(number), "--body", body}, repo)
+	if _, err := t.gh(ctx, args...); err != nil {
+		return wrapGitHubPermissionError(errors.Wrap(err, "failed to comment on issue"), githubCapabilityIssues)
+	}
+	return nil
+}
+
+func withGHRepo(args []string, repo string) []string {
+	if repo == "" {
+		return args
+	}
+	return append(args, "--repo", repo)
+}
+
+// gitlabIssueTracker works with the issues of a GitLab project. Issue numbers
+// are the project-scoped IIDs shown as #123 in GitLab.
+type gitlabIssueTracker struct {
+	client *gitlab.Client
+}
+
+func (t *gitlabIssueTracker) ListOpenIssues(ctx context.Context, repo string) ([]trackerIssue, error) {
+	issues, err := t.client.ListOpenIssues(ctx, repo, issueTriageScanLimit)
+	if err != nil {
+		return nil, err
+	}
+	tracked := make([]trackerIssue, 0, len(issues))
+	for _, issue := range issues {
+		labels := make([]trackerLabel, 0, len(issue.Labels))
+		for _, name := range issue.Labels {
+			labels = append(labels, trackerLabel{Name: name})
+		}
+		tracked = append(tracked, trackerIssue{
+			Number: issue.IID,
+			Title:  issue.Title,
+			Body:   issue.Description,
+			URL:    issue.WebURL,
+			Labels: labels,
+		})
+	}
+	return tracked, nil
+}
+
+func (t *gitlabIssueTracker) ListLabels(ctx context.Context, repo string) ([]trackerLabel, error) {
+	labels, err := t.client.ListLabels(ctx, repo, issueTriageScanLimit)
+	if err != nil {
+		return nil, err
+	}
+	tracked := make([]trackerLabel, 0, len(labels))
+	for _, label := range labels {
+		tracked = append(tracked, trackerLabel{Name: label.Name, Description: label.Description})
+	}
+	return tracked, nil
+}
+
+func (t *gitlabIssueTracker) CreateLabel(ctx context.Context, repo string, label trackerLabel) error {
+	return t.client.CreateLabel(ctx, repo, label.Name, "#"+issueTriageLabelColor, label.Description)
+}
+
+func (t *gitlabIssueTracker) AddLabels(ctx context.Context, repo string, number int, labels []string) error {
+	return t.client.AddIssueLabels(ctx, repo, number, labels)
+}
+
+func (t *gitlabIssueTracker) Comment(ctx context.Context, repo string, number int, body string) error {
+	return t.client.CreateIssueNote(ctx, repo, number, body)
+}
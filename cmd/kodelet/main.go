@@ -7,9 +7,10 @@ import (
 	"strings"
 	"time"
 
-	"github.com/jingkaihe/kodelet/pkg/binaries"
+	"github.com/jingkaihe/kodelet/pkg/audit"
 	"github.com/jingkaihe/kodelet/pkg/db"
 	"github.com/jingkaihe/kodelet/pkg/db/migrations"
+	"github.com/jingkaihe/kodelet/pkg/llm"
 	"github.com/jingkaihe/kodelet/pkg/logger"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	"github.com/spf13/cobra"
@@ -29,7 +30,8 @@ func init() {
 	viper.SetDefault("thinking_budget_tokens", 4048)
 	viper.SetDefault("model", "gpt-5.5")
 	viper.SetDefault("weak_model", "gpt-5.4-mini")
-	viper.SetDefault("provider", "openai")
+	// An empty provider is inferred from the model.
+	viper.SetDefault("provider", "")
 	viper.SetDefault("openai.api_mode", "responses")
 	// Keep the configured value empty so request construction can distinguish an
 	// explicit opt-in from the upstream API default.
@@ -43,17 +45,23 @@ func init() {
 	viper.SetDefault("allowed_domains_file", "~/.kodelet/allowed_domains.txt")
 	viper.SetDefault("sysprompt", "")
 	viper.SetDefault("sysprompt_args", map[string]string{})
+	viper.SetDefault("language", "")
 	viper.SetDefault("tool_mode", "patch")
 	viper.SetDefault("enable_fs_search_tools", false)
 	viper.SetDefault("conversation_summary_mode", "llm")
 	viper.SetDefault("anthropic_api_access", "auto")
 	viper.SetDefault("compact_ratio", llmtypes.DefaultCompactRatio)
+	viper.SetDefault("compact_reinject_tokens", llmtypes.DefaultCompactReinjectTokens)
+	viper.SetDefault("compact_strategy", llmtypes.CompactStrategyAuto)
 
 	viper.SetDefault("extensions.enabled", true)
 	viper.SetDefault("extensions.global_dir", "~/.kodelet/extensions")
 	viper.SetDefault("extensions.local_dir", "./.kodelet/extensions")
 	viper.SetDefault("extensions.max_output_size", 102400)
 
+	viper.SetDefault("briefing.auto", false)
+	viper.SetDefault("briefing.max_tokens", briefingDefaultMaxTokens)
+
 	viper.SetDefault("tracing.enabled", false)
 	viper.SetDefault("tracing.sampler", "ratio")
 	viper.SetDefault("tracing.ratio", 1)
@@ -61,12 +69,14 @@ func init() {
 	viper.SetDefault("log_level", "info")
 	viper.SetDefault("log_format", "fmt")
 
-	viper.SetEnvPrefix("KODELET")
+	viper.SetEnvPrefix(llm.EnvPrefix)
 	viper.AutomaticEnv()
 
 	// Support for nested keys in environment variables
 	// e.g. KODELET_TRACING_ENABLED -> tracing.enabled
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	// Register keys without defaults so env-only settings are unmarshalled too
+	llm.BindConfigEnv(viper.GetViper())
 
 	loadConfigFiles()
 }
@@ -78,21 +88,15 @@ func loadConfigFiles() {
 		return
 	}
 
-	// Layered config: global first, then repo-level override
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath("$HOME/.kodelet")
-
-	if err := viper.ReadInConfig(); err == nil {
-		logger.G(context.TODO()).WithField("config_file", viper.ConfigFileUsed()).Debug("Using global config file")
+	// Layered config: global first, then repo-level override. Each layer may
+	// be YAML or JSON.
+	if globalConfigFile, err := llm.GlobalConfigFile(); err == nil && fileExists(globalConfigFile) {
+		readConfigFile(globalConfigFile, "global")
 	}
 
 	// Then, try to merge repo-level config which will override global settings
-	if _, err := os.Stat("kodelet-config.yaml"); err == nil {
-		viper.SetConfigFile("kodelet-config.yaml")
-		if err := viper.MergeInConfig(); err == nil {
-			logger.G(context.TODO()).WithField("config_file", "kodelet-config.yaml").Debug("Merged repo-level config file")
-		}
+	if repoConfigFile := llm.RepoConfigFile(); fileExists(repoConfigFile) {
+		mergeConfigFile(repoConfigFile, "repo-level")
 	}
 
 	if overrideConfigFile != "" {
@@ -100,6 +104,11 @@ func loadConfigFiles() {
 	}
 }
 
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
 func configFileMode() string {
 	mode := strings.ToLower(strings.TrimSpace(os.Getenv(configFileModeEnv)))
 	switch mode {
@@ -159,9 +168,18 @@ func main() {
 		if logFormat := viper.GetString("log_format"); logFormat != "" {
 			logger.SetLogFormat(logFormat)
 		}
+		// Enable tool call auditing before extensions start so that subagents
+		// they launch append to this run's audit file.
+		if viper.GetBool("audit.tool_calls") {
+			if _, err := audit.EnableToolCallLog(viper.GetString("audit.dir")); err != nil {
+				logger.G(context.TODO()).WithError(err).Warn("Failed to enable tool call audit log")
+			}
+		}
+		enableConversationEncryption(context.TODO())
+		startConversationAutoPrune(context.TODO(), os.Args)
 	})
 
-	rootCmd.PersistentFlags().String("provider", "openai", "LLM provider to use (anthropic, openai)")
+	rootCmd.PersistentFlags().String("provider", "", "LLM provider to use (anthropic, openai); inferred from --model when empty")
 	rootCmd.PersistentFlags().String("model", "gpt-5.5", "LLM model to use (overrides config)")
 	rootCmd.PersistentFlags().Int("max-tokens", 8192, "Maximum tokens for response (overrides config)")
 	rootCmd.PersistentFlags().Int("thinking-budget-tokens", 4048, "Thinking budget for non-adaptive Claude models; adaptive Claude models ignore this and use reasoning-effort instead (overrides config)")
@@ -211,13 +229,22 @@ func main() {
 	viper.BindPFlag("compact_ratio", rootCmd.PersistentFlags().Lookup("compact-ratio"))
 
 	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(askCmd)
+	rootCmd.AddCommand(benchCmd)
+	rootCmd.AddCommand(estimateCmd)
 	rootCmd.AddCommand(chatCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(commitCmd)
 	rootCmd.AddCommand(setupCmd)
 	rootCmd.AddCommand(conversationCmd)
 	rootCmd.AddCommand(usageCmd)
+	rootCmd.AddCommand(cacheCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(metricsCmd)
+	rootCmd.AddCommand(airgapCmd)
+	rootCmd.AddCommand(modelsCmd)
 	rootCmd.AddCommand(prCmd)
+	rootCmd.AddCommand(issueCmd)
 	rootCmd.AddCommand(anthropicCmd)
 	rootCmd.AddCommand(copilotLoginCmd)
 	rootCmd.AddCommand(copilotLogoutCmd)
@@ -227,6 +254,8 @@ func main() {
 	rootCmd.AddCommand(profileCmd)
 	rootCmd.AddCommand(dbCmd)
 
+	applyAirgapSettings(ctx)
+
 	// Initialize telemetry with tracing
 	tracingShutdown, err := initTracing(ctx)
 	if err != nil {
@@ -244,9 +273,6 @@ func main() {
 		}()
 	}
 
-	// Ensure required external binaries are installed
-	binaries.EnsureDepsInstalled(ctx)
-
 	// Run database migrations once at startup (skip for db commands to allow manual control)
 	skipMigrations := len(os.Args) > 1 && os.Args[1] == "db"
 	if !skipMigrations {
@@ -51,3 +51,34 @@ func TestLoadConfigFilesCanUseIsolatedOverrideConfigFile(t *testing.T) {
 	assert.Equal(t, "anthropic", viper.GetString("provider"))
 	assert.Equal(t, "default-model", viper.GetString("model"))
 }
+
+func TestLoadConfigFilesReadsJSONConfigFiles(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	viper.Reset()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(t.TempDir())
+
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".kodelet"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".kodelet", "config.json"), []byte(`{"provider":"anthropic","model":"global-model"}`), 0o644))
+	require.NoError(t, os.WriteFile("kodelet-config.json", []byte(`{"model":"repo-model"}`), 0o644))
+
+	loadConfigFiles()
+
+	assert.Equal(t, "anthropic", viper.GetString("provider"))
+	assert.Equal(t, "repo-model", viper.GetString("model"))
+}
+
+func TestLoadConfigFilesPrefersYAMLOverJSON(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	viper.Reset()
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	require.NoError(t, os.WriteFile("kodelet-config.yaml", []byte("model: yaml-model\n"), 0o644))
+	require.NoError(t, os.WriteFile("kodelet-config.json", []byte(`{"model":"json-model"}`), 0o644))
+
+	loadConfigFiles()
+
+	assert.Equal(t, "yaml-model", viper.GetString("model"))
+}
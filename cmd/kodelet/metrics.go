@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/llm/base"
+	"github.com/jingkaihe/kodelet/pkg/metrics"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var metricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "Manage anonymized run metrics for this repository",
+	Long: `Opt this repository in to anonymized run metrics and export them.
+
+Once enabled, every 'kodelet run' in the repository appends a content-free record
+to .kodelet/metrics/runs.jsonl: the turns taken, how often each tool was called,
+whether verification passed, context compactions, duration, tokens and cost.
+Records never include prompts, output, file paths or commands, and nothing is
+sent anywhere. Metrics are off until a repository opts in.`,
+}
+
+var metricsEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Start recording run metrics in this repository",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		root, err := metricsRepoRoot()
+		if err != nil {
+			return err
+		}
+		if err := metrics.Enable(root, time.Now()); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Run metrics enabled for %s.\nRecords are kept in %s and never leave this machine.\n", root, metrics.Dir)
+		return nil
+	},
+}
+
+var metricsDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Stop recording run metrics in this repository",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		purge, _ := cmd.Flags().GetBool("purge")
+		root, err := metricsRepoRoot()
+		if err != nil {
+			return err
+		}
+		if err := metrics.Disable(root, purge); err != nil {
+			return err
+		}
+		if purge {
+			fmt.Fprintf(cmd.OutOrStdout(), "Run metrics disabled for %s and recorded metrics deleted.\n", root)
+			return nil
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Run metrics disabled for %s. Recorded metrics are kept; use --purge to delete them.\n", root)
+		return nil
+	},
+}
+
+var metricsStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether this repository records run metrics",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		root, err := metricsRepoRoot()
+		if err != nil {
+			return err
+		}
+		return runMetricsStatus(cmd.OutOrStdout(), root)
+	},
+}
+
+var metricsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the recorded run metrics of this repository",
+	Long: `Write the run metrics recorded in this repository as JSON or CSV.
+
+In CSV, tool calls and compactions are name=count pairs separated by semicolons.
+
+Examples:
+  kodelet metrics export                         # JSON to stdout
+  kodelet metrics export --format csv -o runs.csv
+  kodelet metrics export --since 1w              # Runs of the past week
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		since, _ := cmd.Flags().GetString("since")
+		output, _ := cmd.Flags().GetString("output")
+		root, err := metricsRepoRoot()
+		if err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		if output != "" {
+			file, err := os.Create(output)
+			if err != nil {
+				return errors.Wrap(err, "failed to create output file")
+			}
+			defer file.Close()
+			w = file
+		}
+		return runMetricsExport(w, root, format, since)
+	},
+}
+
+func init() {
+	metricsDisableCmd.Flags().Bool("purge", false, "Also delete the recorded metrics")
+	metricsExportCmd.Flags().String("format", "json", "Output format: json or csv")
+	metricsExportCmd.Flags().String("since", "", "Only export runs since this date (e.g., 2025-06-01, 1d, 1w)")
+	metricsExportCmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout")
+	metricsCmd.AddCommand(metricsEnableCmd, metricsDisableCmd, metricsStatusCmd, metricsExportCmd)
+}
+
+func metricsRepoRoot() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get working directory")
+	}
+	return metrics.RepoRoot(cwd), nil
+}
+
+func runMetricsStatus(w io.Writer, root string) error {
+	optIn, err := metrics.Status(root)
+	if err != nil {
+		return err
+	}
+	records, err := metrics.Read(root, time.Time{})
+	if err != nil {
+		return err
+	}
+	if optIn == nil {
+		fmt.Fprintf(w, "Run metrics are disabled for %s. Enable them with 'kodelet metrics enable'.\n", root)
+	} else {
+		fmt.Fprintf(w, "Run metrics are enabled for %s since %s.\n", root, optIn.EnabledAt.Local().Format(time.DateOnly))
+	}
+	fmt.Fprintf(w, "Recorded runs: %d\n", len(records))
+	return nil
+}
+
+func runMetricsExport(w io.Writer, root, format, since string) error {
+	sinceTime, err := parseTimeSpec(since)
+	if err != nil {
+		return errors.Wrap(err, "invalid since time specification")
+	}
+	records, err := metrics.Read(root, sinceTime)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		if records == nil {
+			records = []metrics.Record{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case "csv":
+		return metrics.WriteCSV(w, records)
+	default:
+		return errors.Errorf("invalid format %q (expected json or csv)", format)
+	}
+}
+
+// recordRunMetrics records the anonymized metrics of a finished run when the
+// repository of cwd opted in. Runs without tools, such as the ones web_fetch
+// starts to summarize pages, are not agent work and are skipped.
+func recordRunMetrics(ctx context.Context, config *RunConfig, cwd string, summary *RunSummary, thread llmtypes.Thread) {
+	if config.NoTools {
+		return
+	}
+	metrics.RecordRun(ctx, cwd, newRunMetricsRecord(summary, thread, cwd))
+}
+
+// newRunMetricsRecord derives the content-free metrics of a run from its
+// summary. Tool activity and usage are counted for this run only, so a
+// resumed conversation does not count earlier runs again.
+func newRunMetricsRecord(summary *RunSummary, thread llmtypes.Thread, cwd string) metrics.Record {
+	record := metrics.Record{
+		Date:             summary.StartedAt.UTC().Format(time.DateOnly),
+		Provider:         summary.Provider,
+		Model:            summary.Model,
+		Status:           summary.Status,
+		ExitCode:         summary.ExitCode,
+		DurationMS:       summary.DurationMS,
+		ToolCalls:        map[string]int{},
+		PullRequest:      summary.PullRequestURL != "",
+		InputTokens:      summary.Usage.InputTokens,
+		OutputTokens:     summary.Usage.OutputTokens,
+		CacheWriteTokens: summary.Usage.CacheWriteTokens,
+		CacheReadTokens:  summary.Usage.CacheReadTokens,
+		CostUSD:          summary.Usage.TotalCost,
+	}
+	if summary.Verification != nil {
+		record.Verification = metrics.VerificationFailed
+		if summary.Verification.Passed {
+			record.Verification = metrics.VerificationPassed
+		}
+	}
+	if summary.PostMortem != nil {
+		record.StopReason = summary.PostMortem.Reason
+	}
+
+	if reporter, ok := thread.(interface {
+		GetStructuredToolResults() map[string]tooltypes.StructuredToolResult
+	}); ok {
+		results := map[string]tooltypes.StructuredToolResult{}
+		for id, result := range reporter.GetStructuredToolResults() {
+			if result.Timestamp.Before(summary.StartedAt) {
+				continue
+			}
+			results[id] = result
+			record.ToolCalls[result.ToolName]++
+			if !result.Success {
+				record.ToolErrors++
+			}
+		}
+		files, commands := collectRunToolActivity(results, cwd)
+		record.FilesChanged = len(files)
+		record.Commands = len(commands)
+		for _, command := range commands {
+			if command.ExitCode != 0 {
+				record.CommandFailures++
+			}
+		}
+	}
+	if counter, ok := thread.(interface{ RunStats() base.RunStats }); ok {
+		stats := counter.RunStats()
+		record.Turns = stats.Turns
+		record.Compactions = stats.Reductions
+		record.InputTokens = stats.Usage.InputTokens
+		record.OutputTokens = stats.Usage.OutputTokens
+		record.CacheWriteTokens = stats.Usage.CacheCreationInputTokens
+		record.CacheReadTokens = stats.Usage.CacheReadInputTokens
+		record.CostUSD = stats.Usage.TotalCost()
+	}
+	return record
+}
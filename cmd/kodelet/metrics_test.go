@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/llm/base"
+	"github.com/jingkaihe/kodelet/pkg/metrics"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type metricsThread struct {
+	llmtypes.Thread
+	results map[string]tooltypes.StructuredToolResult
+	stats   base.RunStats
+}
+
+func (t *metricsThread) GetStructuredToolResults() map[string]tooltypes.StructuredToolResult {
+	return t.results
+}
+
+func (t *metricsThread) RunStats() base.RunStats {
+	return t.stats
+}
+
+func TestNewRunMetricsRecord(t *testing.T) {
+	started := time.Date(2026, 10, 1, 23, 30, 0, 0, time.UTC)
+	summary := &RunSummary{
+		StartedAt:      started,
+		Provider:       "anthropic",
+		Model:          "claude-sonnet-4-6",
+		Status:         runStatusError,
+		ExitCode:       1,
+		DurationMS:     90000,
+		FinalOutput:    "secret output",
+		Usage:          RunSummaryUsage{InputTokens: 99999, TotalCost: 9},
+		Verification:   &RunSummaryVerification{Command: "go test ./...", Passed: false, Error: "exit status 1"},
+		PostMortem:     &RunPostMortem{Reason: postMortemVerificationFailed, Detail: "secret detail"},
+		PullRequestURL: "https://github.com/acme/app/pull/1",
+	}
+	thread := &metricsThread{
+		results: map[string]tooltypes.StructuredToolResult{
+			"earlier": {ToolName: "bash", Success: true, Metadata: &tooltypes.BashMetadata{Command: "ls"}, Timestamp: started.Add(-time.Hour)},
+			"edit":    {ToolName: "file_edit", Success: true, Metadata: &tooltypes.FileEditMetadata{FilePath: "/repo/main.go"}, Timestamp: started.Add(time.Minute)},
+			"test":    {ToolName: "bash", Success: false, Metadata: &tooltypes.BashMetadata{Command: "go test ./...", ExitCode: 1}, Timestamp: started.Add(2 * time.Minute)},
+			"build":   {ToolName: "bash", Success: true, Metadata: &tooltypes.BashMetadata{Command: "go build ./..."}, Timestamp: started.Add(3 * time.Minute)},
+		},
+		stats: base.RunStats{
+			Turns:      6,
+			Reductions: map[string]int{base.ReductionPrune: 1},
+			Usage:      llmtypes.Usage{InputTokens: 1200, OutputTokens: 300, InputCost: 0.1},
+		},
+	}
+
+	record := newRunMetricsRecord(summary, thread, "/repo")
+
+	assert.Equal(t, metrics.Record{
+		Date:            "2026-10-01",
+		Provider:        "anthropic",
+		Model:           "claude-sonnet-4-6",
+		Status:          runStatusError,
+		ExitCode:        1,
+		DurationMS:      90000,
+		Turns:           6,
+		ToolCalls:       map[string]int{"bash": 2, "file_edit": 1},
+		ToolErrors:      1,
+		FilesChanged:    1,
+		Commands:        2,
+		CommandFailures: 1,
+		Verification:    metrics.VerificationFailed,
+		Compactions:     map[string]int{base.ReductionPrune: 1},
+		StopReason:      postMortemVerificationFailed,
+		PullRequest:     true,
+		InputTokens:     1200,
+		OutputTokens:    300,
+		CostUSD:         0.1,
+	}, record, "only this run is counted")
+
+	data, err := json.Marshal(record)
+	require.NoError(t, err)
+	for _, content := range []string{"secret", "go test", "main.go", "/repo", "github.com"} {
+		assert.NotContains(t, string(data), content, "records never carry run content")
+	}
+}
+
+func TestRunMetricsStatusAndExport(t *testing.T) {
+	root := t.TempDir()
+
+	var out bytes.Buffer
+	require.NoError(t, runMetricsStatus(&out, root))
+	assert.Contains(t, out.String(), "Run metrics are disabled")
+
+	config := &RunConfig{}
+	summary := &RunSummary{StartedAt: time.Now(), Status: runStatusSuccess}
+	recordRunMetrics(context.Background(), config, root, summary, nil)
+	records, err := metrics.Read(root, time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, records, "nothing is recorded before opting in")
+
+	require.NoError(t, metrics.Enable(root, time.Now()))
+	recordRunMetrics(context.Background(), &RunConfig{NoTools: true}, root, summary, nil)
+	recordRunMetrics(context.Background(), config, root, summary, nil)
+
+	out.Reset()
+	require.NoError(t, runMetricsStatus(&out, root))
+	assert.Contains(t, out.String(), "Run metrics are enabled")
+	assert.Contains(t, out.String(), "Recorded runs: 1", "runs without tools are skipped")
+
+	out.Reset()
+	require.NoError(t, runMetricsExport(&out, root, "json", "1d"))
+	var exported []metrics.Record
+	require.NoError(t, json.Unmarshal(out.Bytes(), &exported))
+	require.Len(t, exported, 1)
+	assert.Equal(t, runStatusSuccess, exported[0].Status)
+
+	out.Reset()
+	require.NoError(t, runMetricsExport(&out, root, "csv", ""))
+	assert.Len(t, strings.Split(strings.TrimSpace(out.String()), "\n"), 2)
+
+	assert.Error(t, runMetricsExport(&out, root, "xml", ""))
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jingkaihe/kodelet/pkg/pricing"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "Model catalog and pricing commands",
+	Long:  `Commands for managing the model catalog used by kodelet, such as refreshing model prices.`,
+}
+
+var modelsRefreshPricingCmd = &cobra.Command{
+	Use:   "refresh-pricing",
+	Short: "Download the latest signed pricing manifest",
+	Long: `Download the pricing manifest published with kodelet releases, verify its
+signature and store it in ~/.kodelet/pricing/manifest.json (or under KODELET_BASE_PATH).
+
+Prices in the manifest, including long-context tiers and the dates they take
+effect, supersede the prices built into this binary so that cost accounting
+stays accurate between upgrades. Prices configured in openai.pricing still take
+precedence over both.
+
+Examples:
+  kodelet models refresh-pricing
+  kodelet models refresh-pricing --url https://mirror.example.com/pricing.json
+`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runRefreshPricingCmd(cmd, cmd.OutOrStdout(), pricing.RefreshOptions{
+			URL:       viper.GetString("pricing.manifest_url"),
+			PublicKey: viper.GetString("pricing.public_key"),
+		})
+	},
+}
+
+func init() {
+	modelsRefreshPricingCmd.Flags().String("url", "", "Pricing manifest URL (defaults to pricing.manifest_url or the latest release)")
+	modelsRefreshPricingCmd.Flags().String("public-key", "", "Base64 ed25519 public key the manifest is signed with (defaults to pricing.public_key or the release key)")
+	viper.BindPFlag("pricing.manifest_url", modelsRefreshPricingCmd.Flags().Lookup("url"))
+	viper.BindPFlag("pricing.public_key", modelsRefreshPricingCmd.Flags().Lookup("public-key"))
+
+	modelsCmd.AddCommand(modelsRefreshPricingCmd)
+}
+
+func runRefreshPricingCmd(cmd *cobra.Command, w io.Writer, opts pricing.RefreshOptions) error {
+	manifestURL := opts.URL
+	if manifestURL == "" {
+		manifestURL = pricing.DefaultManifestURL
+	}
+	if err := checkAirgapEndpoint("pricing manifest URL", manifestURL); err != nil {
+		return errors.Wrap(err, "cannot refresh pricing")
+	}
+	manifest, err := pricing.RefreshManifest(cmd.Context(), opts)
+	if err != nil {
+		return err
+	}
+
+	path := opts.Path
+	if path == "" {
+		path, _ = pricing.DefaultManifestPath()
+	}
+	fmt.Fprintf(w, "Updated pricing for %d models (published %s)\n", len(manifest.Models), manifest.PublishedAt.Format("2006-01-02"))
+	fmt.Fprintf(w, "Stored in %s\n", path)
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jingkaihe/kodelet/pkg/pricing"
+)
+
+func TestRunRefreshPricingCmd(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	manifest := []byte(`{"version": 1, "published_at": "2026-10-01T00:00:00Z", "models": [
+		{"provider": "anthropic", "model": "claude-sonnet-4-6", "periods": [{"effective_from": "2026-01-01T00:00:00Z", "input": 0.000003}]}]}`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pricing.json":
+			_, _ = w.Write(manifest)
+		case "/pricing.json.sig":
+			_, _ = w.Write(pricing.Sign(manifest, privateKey))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Cleanup(pricing.Reset)
+
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	var out bytes.Buffer
+
+	err = runRefreshPricingCmd(cmd, &out, pricing.RefreshOptions{
+		URL:       server.URL + "/pricing.json",
+		PublicKey: base64.StdEncoding.EncodeToString(publicKey),
+		Path:      path,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "Updated pricing for 1 models (published 2026-10-01)")
+	assert.Contains(t, out.String(), path)
+	assert.FileExists(t, path)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/jingkaihe/kodelet/pkg/extensions"
@@ -40,8 +41,8 @@ func NewPRConfig() *PRConfig {
 }
 
 func (c *PRConfig) Validate() error {
-	if c.Provider != "github" {
-		return fmt.Errorf("unsupported provider: %s, only 'github' is supported", c.Provider)
+	if err := validateForgeProvider(c.Provider); err != nil {
+		return err
 	}
 
 	if c.Target == "" {
@@ -58,7 +59,9 @@ var prCmd = &cobra.Command{
 
 This command analyzes the current branch changes compared to the target branch and generates an appropriate PR title and description.
 
-Use the --draft flag to create a draft pull request that is not ready for review.`,
+Use the --draft flag to create a draft pull request that is not ready for review.
+
+With --provider gitlab, or forge.provider set to gitlab in the configuration, a GitLab merge request is opened with glab instead.`,
 	Run: func(cmd *cobra.Command, _ []string) {
 		ctx, cancel := context.WithCancel(cmd.Context())
 		defer cancel()
@@ -77,19 +80,34 @@ Use the --draft flag to create a draft pull request that is not ready for review
 			return
 		}
 		config := getPRConfigFromFlags(cmd)
+		forge, err := loadForgeConfig()
+		if err != nil {
+			presenter.Error(err, "Failed to load configuration")
+			return
+		}
+		if !cmd.Flags().Changed("provider") {
+			config.Provider = forge.Provider
+		}
+		if err := config.Validate(); err != nil {
+			presenter.Error(err, "Invalid configuration")
+			os.Exit(1)
+		}
 
 		if !isGitRepository() {
 			presenter.Error(errors.New("not a git repository"), "Please run this command from a git repository")
 			os.Exit(1)
 		}
 
-		if !isGhCliInstalled() {
+		if config.Provider == forgeGitLab {
+			if err := checkGitLabCLI(forge.Host); err != nil {
+				presenter.Error(err, "GitLab CLI is not ready")
+				os.Exit(1)
+			}
+		} else if !isGhCliInstalled() {
 			presenter.Error(errors.New("GitHub CLI not installed"), "GitHub CLI (gh) is not installed. Please install it first")
 			presenter.Info("Visit https://cli.github.com/ for installation instructions")
 			os.Exit(1)
-		}
-
-		if !isGhAuthenticated() {
+		} else if !isGhAuthenticated() {
 			presenter.Error(errors.New("not authenticated with GitHub"), "You are not authenticated with GitHub. Please run 'gh auth login' first")
 			os.Exit(1)
 		}
@@ -114,8 +132,22 @@ Use the --draft flag to create a draft pull request that is not ready for review
 			fragmentArgs["draft"] = "false"
 		}
 
+		fragmentName := "github/pr"
+		if config.Provider == forgeGitLab {
+			fragmentName = "gitlab/mr"
+		} else if scopes := detectGitHubTokenScopes(ctx, ""); !scopes.Allows(githubCapabilityPullRequests) {
+			bodyFile, err := prDraftPath(ctx)
+			if err != nil {
+				presenter.Error(err, "Failed to prepare the pull request draft")
+				os.Exit(1)
+			}
+			presenter.Warning(fmt.Sprintf("The GitHub token cannot open pull requests; the pull request will be drafted to %s instead", bodyFile))
+			renderGitHubCapabilities(os.Stderr, scopes, githubCapabilityPullRequests)
+			fragmentArgs["body_file"] = bodyFile
+		}
+
 		fragment, err := processor.LoadFragment(ctx, &fragments.Config{
-			FragmentName: "github/pr",
+			FragmentName: fragmentName,
 			Arguments:    fragmentArgs,
 		})
 		if err != nil {
@@ -169,7 +201,7 @@ Use the --draft flag to create a draft pull request that is not ready for review
 
 func init() {
 	defaults := NewPRConfig()
-	prCmd.Flags().StringP("provider", "p", defaults.Provider, "The CVS provider to use")
+	prCmd.Flags().StringP("provider", "p", "", "The code hosting provider to use, github or gitlab (defaults to forge.provider, then github)")
 	prCmd.Flags().StringP("target", "t", defaults.Target, "The target branch to create the pull request on")
 	prCmd.Flags().String("template-file", defaults.TemplateFile, "The path to the template file for the pull request")
 	prCmd.Flags().BoolP("draft", "d", defaults.Draft, "Create the pull request as a draft")
@@ -202,6 +234,19 @@ func getPRConfigFromFlags(cmd *cobra.Command) *PRConfig {
 	return config
 }
 
+// prDraftPath returns the draft file for a pull request from the current branch.
+func prDraftPath(ctx context.Context) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get current working directory")
+	}
+	branch, err := runGit(ctx, cwd, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return githubPRDraftPath(cwd, strings.TrimSpace(branch))
+}
+
 func isGhCliInstalled() bool {
 	return osutil.IsGHCLIInstalled()
 }
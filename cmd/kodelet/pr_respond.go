@@ -0,0 +1,533 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/jingkaihe/kodelet/pkg/extensions"
+	"github.com/jingkaihe/kodelet/pkg/fragments"
+	"github.com/jingkaihe/kodelet/pkg/github"
+	"github.com/jingkaihe/kodelet/pkg/llm"
+	"github.com/jingkaihe/kodelet/pkg/presenter"
+	"github.com/jingkaihe/kodelet/pkg/tools"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// prRespondContextLines is how many lines around a commented line range are
+// shown to the agent.
+const prRespondContextLines = 3
+
+type PRRespondConfig struct {
+	PR      string
+	Resolve bool
+	DryRun  bool
+	NoSave  bool
+}
+
+func NewPRRespondConfig() *PRRespondConfig {
+	return &PRRespondConfig{}
+}
+
+var prRespondCmd = &cobra.Command{
+	Use:   "respond [number | url | branch]",
+	Short: "Address the unresolved review threads of a pull request and reply to them",
+	Long: `Address the unresolved review threads of a pull request, defaulting to the pull request of the current branch.
+
+The review threads are fetched with the GitHub GraphQL API, together with the lines of the file each thread comments on. The agent works through them on the checked out pull request branch, commits its fixes and drafts a reply to each thread. Kodelet then pushes the branch and posts each reply in its thread.
+
+Use --resolve to resolve the threads that were fixed once the fix is pushed, and --dry-run to print the replies without pushing or posting anything.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sigCh
+			presenter.Warning("Cancellation requested, shutting down...")
+			cancel()
+		}()
+
+		config := getPRRespondConfigFromFlags(cmd)
+		if len(args) > 0 {
+			config.PR = strings.TrimSpace(args[0])
+		}
+
+		llmConfig, err := llm.GetConfigFromViperWithCmd(cmd)
+		if err != nil {
+			presenter.Error(err, "Failed to load configuration")
+			os.Exit(1)
+		}
+
+		if !isGitRepository() {
+			presenter.Error(errors.New("not a git repository"), "Please run this command from a git repository")
+			os.Exit(1)
+		}
+		if !isGhCliInstalled() {
+			presenter.Error(errors.New("GitHub CLI not installed"), "GitHub CLI (gh) is not installed. Please install it first")
+			presenter.Info("Visit https://cli.github.com/ for installation instructions")
+			os.Exit(1)
+		}
+		if !isGhAuthenticated() {
+			presenter.Error(errors.New("not authenticated with GitHub"), "You are not authenticated with GitHub. Please run 'gh auth login' first")
+			os.Exit(1)
+		}
+		if !config.DryRun {
+			scopes := detectGitHubTokenScopes(ctx, "")
+			if !scopes.Allows(githubCapabilityPullRequests) {
+				presenter.Warning("The GitHub token cannot comment on pull requests; responding as a dry run")
+				renderGitHubCapabilities(os.Stderr, scopes, githubCapabilityPullRequests)
+				config.DryRun = true
+			}
+		}
+
+		extensionRuntime, err := extensions.NewRuntimeFromViper(ctx, "")
+		if err != nil {
+			presenter.Error(err, "Failed to initialize extensions")
+			os.Exit(1)
+		}
+		if extensionRuntime != nil {
+			defer func() {
+				_ = extensionRuntime.Close()
+			}()
+			llmConfig.Extensions = extensionRuntime
+		}
+
+		responder := newPRResponder(llmConfig, extensionRuntime, config.NoSave)
+		result, err := responder.Run(ctx, config)
+		if err != nil {
+			presenter.Error(err, "Failed to respond to the review threads")
+			os.Exit(1)
+		}
+
+		presenter.Separator()
+		renderPRRespondResult(os.Stdout, result, config.DryRun)
+		presenter.Separator()
+		presenter.Stats(presenter.ConvertUsageStats(&result.Usage))
+	},
+}
+
+func init() {
+	defaults := NewPRRespondConfig()
+	prRespondCmd.Flags().Bool("resolve", defaults.Resolve, "Resolve the threads the agent fixed once the fix is pushed")
+	prRespondCmd.Flags().Bool("dry-run", defaults.DryRun, "Print the replies without pushing, replying or resolving")
+	prRespondCmd.Flags().Bool("no-save", defaults.NoSave, "Disable conversation persistence")
+	prCmd.AddCommand(prRespondCmd)
+}
+
+func getPRRespondConfigFromFlags(cmd *cobra.Command) *PRRespondConfig {
+	config := NewPRRespondConfig()
+
+	if resolve, err := cmd.Flags().GetBool("resolve"); err == nil {
+		config.Resolve = resolve
+	}
+	if dryRun, err := cmd.Flags().GetBool("dry-run"); err == nil {
+		config.DryRun = dryRun
+	}
+	if noSave, err := cmd.Flags().GetBool("no-save"); err == nil {
+		config.NoSave = noSave
+	}
+
+	return config
+}
+
+// prRespondReply is the agent's answer to a review thread.
+type prRespondReply struct {
+	ThreadID string `json:"thread_id"`
+	Reply    string `json:"reply"`
+	Fixed    bool   `json:"fixed"`
+}
+
+// prRespondThreadResult is the outcome of one review thread.
+type prRespondThreadResult struct {
+	Thread github.ReviewThread
+	// Reply is nil when the agent did not answer the thread.
+	Reply    *prRespondReply
+	URL      string
+	Resolved bool
+	Err      error
+}
+
+// prRespondResult is the outcome of responding to a pull request's review.
+type prRespondResult struct {
+	PR      github.PullRequest
+	Threads []prRespondThreadResult
+	// Commit is the pushed head of the branch, empty when nothing was pushed.
+	Commit string
+	// Uncommitted is set when the agent left changes it did not commit.
+	Uncommitted bool
+	Usage       llmtypes.Usage
+}
+
+type prRespondPullRequest struct {
+	Number         int    `json:"number"`
+	Title          string `json:"title"`
+	URL            string `json:"url"`
+	HeadRefName    string `json:"headRefName"`
+	HeadRepository struct {
+		Name string `json:"name"`
+	} `json:"headRepository"`
+	HeadRepositoryOwner struct {
+		Login string `json:"login"`
+	} `json:"headRepositoryOwner"`
+	IsCrossRepository bool `json:"isCrossRepository"`
+}
+
+// prResponder responds to review threads with gh, git and an agent. All
+// three are replaceable so the flow can be tested without GitHub or an LLM.
+type prResponder struct {
+	gh    github.Runner
+	git   func(ctx context.Context, args ...string) (string, error)
+	agent func(ctx context.Context, prompt string) (string, llmtypes.Usage)
+}
+
+func newPRResponder(llmConfig llmtypes.Config, extensionRuntime *extensions.Runtime, noSave bool) *prResponder {
+	return &prResponder{
+		gh: github.GHRunner(""),
+		git: func(ctx context.Context, args ...string) (string, error) {
+			return runGit(ctx, "", args...)
+		},
+		agent: func(ctx context.Context, prompt string) (string, llmtypes.Usage) {
+			stateOpts := []tools.BasicStateOption{tools.WithLLMConfig(llmConfig), tools.WithMainTools(), tools.WithSkillTool()}
+			if extensionRuntime != nil {
+				stateOpts = append(stateOpts, tools.WithExtensionTools(extensionRuntime.Tools()))
+			}
+			state := tools.NewBasicState(ctx, stateOpts...)
+			return llm.SendMessageAndGetTextWithUsage(ctx, state, prompt, llmConfig, false, llmtypes.MessageOpt{
+				PromptCache:        true,
+				NoSaveConversation: noSave,
+			})
+		},
+	}
+}
+
+// Run has the agent address the unresolved review threads of the pull
+// request, then pushes its commits and posts its replies.
+func (r *prResponder) Run(ctx context.Context, config *PRRespondConfig) (*prRespondResult, error) {
+	info, err := r.viewPullRequest(ctx, config.PR)
+	if err != nil {
+		return nil, err
+	}
+	pr, err := github.ParsePullRequestURL(info.URL)
+	if err != nil {
+		return nil, err
+	}
+	result := &prRespondResult{PR: pr}
+
+	client := github.NewClient(r.gh)
+	threads, err := client.ReviewThreads(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	unresolved := github.Unresolved(threads)
+	if len(unresolved) == 0 {
+		return result, nil
+	}
+
+	branch, err := r.git(ctx, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if branch = strings.TrimSpace(branch); branch != info.HeadRefName {
+		return nil, errors.Errorf("the pull request branch %s is not checked out (on %s); run 'gh pr checkout %d' first", info.HeadRefName, branch, info.Number)
+	}
+	root, err := r.git(ctx, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	startHead, err := r.git(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	processor, err := fragments.NewFragmentProcessor()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create fragment processor")
+	}
+	fragment, err := processor.LoadFragment(ctx, &fragments.Config{
+		FragmentName: "github/pr-respond",
+		Arguments: map[string]string{
+			"number":  strconv.Itoa(info.Number),
+			"title":   info.Title,
+			"url":     info.URL,
+			"branch":  info.HeadRefName,
+			"threads": formatReviewThreads(unresolved, strings.TrimSpace(root)),
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load built-in pr-respond recipe")
+	}
+
+	out, usage := r.agent(ctx, fragment.Content)
+	result.Usage = usage
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+	replies, err := parsePRRespondReplies(out, unresolved)
+	if err != nil {
+		return result, err
+	}
+
+	if status, err := r.git(ctx, "status", "--porcelain"); err == nil && strings.TrimSpace(status) != "" {
+		result.Uncommitted = true
+	}
+	endHead, err := r.git(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return result, err
+	}
+	if endHead = strings.TrimSpace(endHead); endHead != strings.TrimSpace(startHead) && !config.DryRun {
+		remote, ref := r.pushTarget(ctx, info)
+		if _, err := r.git(ctx, "push", remote, "HEAD:"+ref); err != nil {
+			return result, errors.Wrap(err, "failed to push the fixes; no replies were posted")
+		}
+		result.Commit = endHead
+	}
+
+	// permissionErr stops further requests once the token has been rejected,
+	// as they would be rejected too.
+	var permissionErr *githubPermissionError
+	for _, thread := range unresolved {
+		threadResult := prRespondThreadResult{Thread: thread}
+		if reply, ok := replies[thread.ID]; ok {
+			threadResult.Reply = &reply
+		}
+		switch {
+		case threadResult.Reply == nil, config.DryRun:
+		case permissionErr != nil:
+			threadResult.Err = permissionErr
+		default:
+			threadResult.Err = r.answer(ctx, client, config, result.Commit, &threadResult)
+			errors.As(threadResult.Err, &permissionErr)
+		}
+		result.Threads = append(result.Threads, threadResult)
+	}
+	return result, nil
+}
+
+func (r *prResponder) viewPullRequest(ctx context.Context, selector string) (prRespondPullRequest, error) {
+	args := []string{"pr", "view"}
+	if selector != "" {
+		args = append(args, selector)
+	}
+	out, err := r.gh(ctx, append(args, "--json", "number,title,url,headRefName,headRepository,headRepositoryOwner,isCrossRepository")...)
+	if err != nil {
+		return prRespondPullRequest{}, errors.Wrap(err, "failed to find the pull request")
+	}
+	var info prRespondPullRequest
+	if err := json.Unmarshal([]byte(out), &info); err != nil {
+		return prRespondPullRequest{}, errors.Wrap(err, "failed to parse the pull request")
+	}
+	return info, nil
+}
+
+// pushTarget returns the remote and branch the fixes to info are pushed to:
+// the upstream of the checked out branch, which gh pr checkout sets up even
+// for pull requests from forks, or else the head repository of the pull
+// request.
+func (r *prResponder) pushTarget(ctx context.Context, info prRespondPullRequest) (remote, ref string) {
+	remote, err := r.git(ctx, "config", "--get", "branch."+info.HeadRefName+".remote")
+	if err == nil && strings.TrimSpace(remote) != "" {
+		merge, err := r.git(ctx, "config", "--get", "branch."+info.HeadRefName+".merge")
+		if err == nil && strings.TrimSpace(merge) != "" {
+			return strings.TrimSpace(remote), strings.TrimPrefix(strings.TrimSpace(merge), "refs/heads/")
+		}
+	}
+	if !info.IsCrossRepository {
+		return "origin", info.HeadRefName
+	}
+	// The URL of a pull request is https://<host>/<owner>/<repo>/pull/<number>
+	host := "github.com"
+	if parts := strings.SplitN(strings.TrimPrefix(info.URL, "https://"), "/", 2); len(parts) == 2 && parts[0] != "" {
+		host = parts[0]
+	}
+	return fmt.Sprintf("https://%s/%s/%s.git", host, info.HeadRepositoryOwner.Login, info.HeadRepository.Name), info.HeadRefName
+}
+
+// answer posts the reply in its thread and resolves the thread when asked to
+// and the fix was pushed.
+func (r *prResponder) answer(ctx context.Context, client *github.Client, config *PRRespondConfig, commit string, threadResult *prRespondThreadResult) error {
+	body := threadResult.Reply.Reply
+	if threadResult.Reply.Fixed && commit != "" {
+		body += "\n\nFixed in " + commit
+	}
+	comment, err := client.Reply(ctx, threadResult.Thread.ID, body)
+	if err != nil {
+		return wrapGitHubPermissionError(err, githubCapabilityPullRequests)
+	}
+	threadResult.URL = comment.URL
+
+	if config.Resolve && threadResult.Reply.Fixed && commit != "" {
+		if err := client.Resolve(ctx, threadResult.Thread.ID); err != nil {
+			return wrapGitHubPermissionError(err, githubCapabilityPullRequests)
+		}
+		threadResult.Resolved = true
+	}
+	return nil
+}
+
+// formatReviewThreads renders the threads for the prompt, each with its
+// comments and the lines of the file it comments on.
+func formatReviewThreads(threads []github.ReviewThread, root string) string {
+	var b strings.Builder
+	for i, thread := range threads {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		start, end := thread.Lines()
+		fmt.Fprintf(&b, "<thread id=%q path=%q lines=%q outdated=\"%t\">\n", thread.ID, thread.Path, formatLineRange(start, end), thread.IsOutdated)
+		for _, comment := range thread.Comments {
+			fmt.Fprintf(&b, "<comment author=%q>\n%s\n</comment>\n", comment.Author, strings.TrimSpace(comment.Body))
+		}
+		if snippet := reviewThreadContext(thread, root); snippet != "" {
+			fmt.Fprintf(&b, "<context>\n%s\n</context>\n", snippet)
+		} else if len(thread.Comments) > 0 && thread.Comments[0].DiffHunk != "" {
+			fmt.Fprintf(&b, "<diff_hunk>\n%s\n</diff_hunk>\n", strings.TrimSpace(thread.Comments[0].DiffHunk))
+		}
+		b.WriteString("</thread>\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// reviewThreadContext returns the lines of the working tree file around the
+// commented lines, which are marked with ">". It is empty when the lines
+// cannot be located in the file: for outdated threads, comments on removed
+// lines, comments on the whole file and files that no longer exist.
+func reviewThreadContext(thread github.ReviewThread, root string) string {
+	if thread.IsOutdated || thread.Line == 0 || strings.EqualFold(thread.DiffSide, "LEFT") {
+		return ""
+	}
+	content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(thread.Path)))
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	start, end := thread.Lines()
+	if end > len(lines) {
+		return ""
+	}
+
+	var b strings.Builder
+	for n := max(start-prRespondContextLines, 1); n <= min(end+prRespondContextLines, len(lines)); n++ {
+		marker := " "
+		if n >= start && n <= end {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s %5d | %s\n", marker, n, lines[n-1])
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func formatLineRange(start, end int) string {
+	switch {
+	case end == 0:
+		return ""
+	case start == end:
+		return strconv.Itoa(end)
+	default:
+		return fmt.Sprintf("%d-%d", start, end)
+	}
+}
+
+// parsePRRespondReplies extracts the agent's replies from its final message,
+// tolerating surrounding prose or code fences. Replies to unknown threads
+// and empty replies are dropped; the first reply to a thread wins.
+func parsePRRespondReplies(out string, threads []github.ReviewThread) (map[string]prRespondReply, error) {
+	end := strings.LastIndex(out, "]")
+	var replies []prRespondReply
+	parsed := false
+	for start := strings.Index(out, "["); start >= 0 && start < end; {
+		if err := json.Unmarshal([]byte(out[start:end+1]), &replies); err == nil {
+			parsed = true
+			break
+		}
+		next := strings.Index(out[start+1:], "[")
+		if next < 0 {
+			break
+		}
+		start += next + 1
+	}
+	if !parsed {
+		return nil, errors.New("the agent's response did not contain the replies to the review threads")
+	}
+
+	known := make(map[string]bool, len(threads))
+	for _, thread := range threads {
+		known[thread.ID] = true
+	}
+	byThread := make(map[string]prRespondReply)
+	for _, reply := range replies {
+		reply.ThreadID = strings.TrimSpace(reply.ThreadID)
+		reply.Reply = strings.TrimSpace(reply.Reply)
+		if !known[reply.ThreadID] || reply.Reply == "" {
+			continue
+		}
+		if _, seen := byThread[reply.ThreadID]; !seen {
+			byThread[reply.ThreadID] = reply
+		}
+	}
+	return byThread, nil
+}
+
+func renderPRRespondResult(w io.Writer, result *prRespondResult, dryRun bool) {
+	if len(result.Threads) == 0 {
+		presenter.Info(fmt.Sprintf("No unresolved review threads on %s", result.PR))
+		return
+	}
+	if dryRun {
+		presenter.Section(fmt.Sprintf("Review Threads of %s (dry run, nothing pushed or posted)", result.PR))
+	} else {
+		presenter.Section(fmt.Sprintf("Review Threads of %s", result.PR))
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "THREAD\tFIXED\tSTATUS")
+	for _, threadResult := range result.Threads {
+		location := threadResult.Thread.Path
+		if lines := formatLineRange(threadResult.Thread.Lines()); lines != "" {
+			location += ":" + lines
+		}
+		fixed := "-"
+		status := "no reply"
+		if threadResult.Reply != nil {
+			fixed = strconv.FormatBool(threadResult.Reply.Fixed)
+			switch {
+			case threadResult.Err != nil:
+				status = "error: " + threadResult.Err.Error()
+			case dryRun:
+				status = "dry run"
+			case threadResult.Resolved:
+				status = "replied and resolved"
+			default:
+				status = "replied"
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", location, fixed, status)
+	}
+	_ = tw.Flush()
+
+	if dryRun {
+		for _, threadResult := range result.Threads {
+			if threadResult.Reply != nil {
+				fmt.Fprintf(w, "\n%s:\n%s\n", threadResult.Thread.Path, threadResult.Reply.Reply)
+			}
+		}
+	}
+	if result.Commit != "" {
+		presenter.Info("Pushed " + result.Commit)
+	}
+	if result.Uncommitted {
+		presenter.Warning("The working tree has changes the agent did not commit; they were not pushed")
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jingkaihe/kodelet/pkg/github"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+const prRespondTestThreads = `{"data":{"repository":{"pullRequest":{"reviewThreads":{
+	"pageInfo":{"hasNextPage":false,"endCursor":""},
+	"nodes":[
+		{"id":"T1","path":"main.go","line":3,"originalLine":3,"diffSide":"RIGHT","isResolved":false,"isOutdated":false,
+			"comments":{"nodes":[{"id":"C1","author":{"login":"alice"},"body":"Check the error here"}]}},
+		{"id":"T2","path":"main.go","line":1,"originalLine":1,"diffSide":"RIGHT","isResolved":false,"isOutdated":false,
+			"comments":{"nodes":[{"id":"C2","author":{"login":"bob"},"body":"Why not a constant?"}]}},
+		{"id":"T3","path":"old.go","originalLine":5,"isResolved":true,"isOutdated":true,
+			"comments":{"nodes":[{"id":"C3","author":{"login":"alice"},"body":"Done already"}]}}
+	]}}}}}`
+
+// fakePRRespondGH answers the gh calls of a pr respond run.
+type fakePRRespondGH struct {
+	calls       [][]string
+	replyErr    error
+	pullRequest string
+}
+
+func (f *fakePRRespondGH) run(_ context.Context, args ...string) (string, error) {
+	f.calls = append(f.calls, args)
+	if args[0] == "pr" {
+		if f.pullRequest != "" {
+			return f.pullRequest, nil
+		}
+		return `{"number": 7, "title": "Add retries", "url": "https://github.com/o/r/pull/7", "headRefName": "feature"}`, nil
+	}
+	query := strings.Join(args, " ")
+	switch {
+	case strings.Contains(query, "reviewThreads"):
+		return prRespondTestThreads, nil
+	case strings.Contains(query, "addPullRequestReviewThreadReply"):
+		if f.replyErr != nil {
+			return "", f.replyErr
+		}
+		return `{"data":{"addPullRequestReviewThreadReply":{"comment":{"id":"C9","url":"https://github.com/o/r/pull/7#discussion_r9"}}}}`, nil
+	case strings.Contains(query, "resolveReviewThread"):
+		return `{"data":{"resolveReviewThread":{"thread":{"id":"T1","isResolved":true}}}}`, nil
+	}
+	return "", errors.New("unexpected gh call")
+}
+
+func (f *fakePRRespondGH) mutations(name string) [][]string {
+	var calls [][]string
+	for _, call := range f.calls {
+		if strings.Contains(strings.Join(call, " "), name) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// fakePRRespondGit is a checkout of the pull request branch whose head moves
+// when the agent commits.
+type fakePRRespondGit struct {
+	root   string
+	branch string
+	head   string
+	config map[string]string
+	pushed []string
+}
+
+func (f *fakePRRespondGit) run(_ context.Context, args ...string) (string, error) {
+	switch strings.Join(args, " ") {
+	case "rev-parse --abbrev-ref HEAD":
+		return f.branch + "\n", nil
+	case "rev-parse --show-toplevel":
+		return f.root + "\n", nil
+	case "rev-parse HEAD":
+		return f.head + "\n", nil
+	case "status --porcelain":
+		return "", nil
+	}
+	if args[0] == "push" {
+		f.pushed = append(f.pushed, strings.Join(args[1:], " "))
+		return "", nil
+	}
+	if args[0] == "config" {
+		if value, ok := f.config[args[len(args)-1]]; ok {
+			return value + "\n", nil
+		}
+		return "", errors.New("exit status 1")
+	}
+	return "", errors.Errorf("unexpected git call %v", args)
+}
+
+func newPRRespondTestRepo(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n\nfunc main() { run() }\n"), 0o644))
+	return root
+}
+
+func TestPRResponderRun(t *testing.T) {
+	gh := &fakePRRespondGH{}
+	git := &fakePRRespondGit{root: newPRRespondTestRepo(t), branch: "feature", head: "aaa"}
+	var prompt string
+	responder := &prResponder{
+		gh:  gh.run,
+		git: git.run,
+		agent: func(_ context.Context, p string) (string, llmtypes.Usage) {
+			prompt = p
+			git.head = "bbb"
+			return "All done.\n```json\n" + `[{"thread_id": "T1", "reply": "Now checked.", "fixed": true}, {"thread_id": "T2", "reply": " It is used once. ", "fixed": false}, {"thread_id": "T3", "reply": "resolved", "fixed": true}]` + "\n```", llmtypes.Usage{InputTokens: 10}
+		},
+	}
+
+	config := NewPRRespondConfig()
+	config.Resolve = true
+	result, err := responder.Run(context.Background(), config)
+	require.NoError(t, err)
+
+	assert.Contains(t, prompt, `<thread id="T1" path="main.go" lines="3" outdated="false">`)
+	assert.Contains(t, prompt, "Check the error here")
+	assert.Contains(t, prompt, ">     3 | func main() { run() }")
+	assert.NotContains(t, prompt, `id="T3"`, "resolved threads are not sent")
+
+	assert.Equal(t, []string{"origin HEAD:feature"}, git.pushed)
+	assert.Equal(t, "bbb", result.Commit)
+	assert.Equal(t, 10, result.Usage.InputTokens)
+	require.Len(t, result.Threads, 2)
+	assert.True(t, result.Threads[0].Resolved)
+	assert.Equal(t, "https://github.com/o/r/pull/7#discussion_r9", result.Threads[0].URL)
+	assert.False(t, result.Threads[1].Resolved)
+
+	replies := gh.mutations("addPullRequestReviewThreadReply")
+	require.Len(t, replies, 2)
+	assert.Contains(t, replies[0], "body=Now checked.\n\nFixed in bbb")
+	assert.Contains(t, replies[1], "body=It is used once.")
+	require.Len(t, gh.mutations("resolveReviewThread"), 1)
+	assert.Contains(t, gh.mutations("resolveReviewThread")[0], "threadId=T1")
+
+	var out bytes.Buffer
+	renderPRRespondResult(&out, result, false)
+	assert.Contains(t, out.String(), "main.go:3")
+	assert.Contains(t, out.String(), "replied and resolved")
+}
+
+func TestPRResponderRunPushTarget(t *testing.T) {
+	fork := `{"number": 7, "title": "Add retries", "url": "https://github.com/o/r/pull/7", "headRefName": "feature",
+		"headRepository": {"name": "r-fork"}, "headRepositoryOwner": {"login": "carol"}, "isCrossRepository": true}`
+
+	tests := []struct {
+		name        string
+		pullRequest string
+		config      map[string]string
+		expected    string
+	}{
+		{
+			name:        "upstream of the checked out branch",
+			pullRequest: fork,
+			config:      map[string]string{"branch.feature.remote": "carol", "branch.feature.merge": "refs/heads/feature"},
+			expected:    "carol HEAD:feature",
+		},
+		{
+			name:        "fork without an upstream",
+			pullRequest: fork,
+			expected:    "https://github.com/carol/r-fork.git HEAD:feature",
+		},
+		{
+			name:     "same repository without an upstream",
+			expected: "origin HEAD:feature",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gh := &fakePRRespondGH{pullRequest: tt.pullRequest}
+			git := &fakePRRespondGit{root: newPRRespondTestRepo(t), branch: "feature", head: "aaa", config: tt.config}
+			responder := &prResponder{
+				gh:  gh.run,
+				git: git.run,
+				agent: func(context.Context, string) (string, llmtypes.Usage) {
+					git.head = "bbb"
+					return `[{"thread_id": "T1", "reply": "Now checked.", "fixed": true}]`, llmtypes.Usage{}
+				},
+			}
+
+			_, err := responder.Run(context.Background(), NewPRRespondConfig())
+			require.NoError(t, err)
+			assert.Equal(t, []string{tt.expected}, git.pushed)
+		})
+	}
+}
+
+func TestPRResponderRunDryRun(t *testing.T) {
+	gh := &fakePRRespondGH{}
+	git := &fakePRRespondGit{root: newPRRespondTestRepo(t), branch: "feature", head: "aaa"}
+	responder := &prResponder{
+		gh:  gh.run,
+		git: git.run,
+		agent: func(context.Context, string) (string, llmtypes.Usage) {
+			git.head = "bbb"
+			return `[{"thread_id": "T1", "reply": "Now checked.", "fixed": true}]`, llmtypes.Usage{}
+		},
+	}
+
+	config := NewPRRespondConfig()
+	config.DryRun = true
+	config.Resolve = true
+	result, err := responder.Run(context.Background(), config)
+	require.NoError(t, err)
+
+	assert.Empty(t, git.pushed)
+	assert.Empty(t, result.Commit)
+	assert.Empty(t, gh.mutations("mutation"))
+	require.Len(t, result.Threads, 2)
+	assert.Nil(t, result.Threads[1].Reply, "the agent did not answer T2")
+
+	var out bytes.Buffer
+	renderPRRespondResult(&out, result, true)
+	assert.Contains(t, out.String(), "dry run")
+	assert.Contains(t, out.String(), "no reply")
+	assert.Contains(t, out.String(), "Now checked.")
+}
+
+func TestPRResponderRunRequiresPullRequestBranch(t *testing.T) {
+	gh := &fakePRRespondGH{}
+	git := &fakePRRespondGit{root: newPRRespondTestRepo(t), branch: "main", head: "aaa"}
+	responder := &prResponder{
+		gh:  gh.run,
+		git: git.run,
+		agent: func(context.Context, string) (string, llmtypes.Usage) {
+			t.Fatal("the agent must not run on the wrong branch")
+			return "", llmtypes.Usage{}
+		},
+	}
+
+	_, err := responder.Run(context.Background(), NewPRRespondConfig())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "gh pr checkout 7")
+}
+
+func TestPRResponderRunStopsRepliesWhenTokenIsRejected(t *testing.T) {
+	gh := &fakePRRespondGH{replyErr: errors.New("gh api failed: HTTP 403: Resource not accessible by integration")}
+	git := &fakePRRespondGit{root: newPRRespondTestRepo(t), branch: "feature", head: "aaa"}
+	responder := &prResponder{
+		gh:  gh.run,
+		git: git.run,
+		agent: func(context.Context, string) (string, llmtypes.Usage) {
+			return `[{"thread_id": "T1", "reply": "a"}, {"thread_id": "T2", "reply": "b"}]`, llmtypes.Usage{}
+		},
+	}
+
+	result, err := responder.Run(context.Background(), NewPRRespondConfig())
+	require.NoError(t, err)
+	require.Len(t, result.Threads, 2)
+	var permissionErr *githubPermissionError
+	require.ErrorAs(t, result.Threads[0].Err, &permissionErr)
+	require.ErrorAs(t, result.Threads[1].Err, &permissionErr)
+	assert.Len(t, gh.mutations("addPullRequestReviewThreadReply"), 1)
+}
+
+func TestParsePRRespondReplies(t *testing.T) {
+	threads := []github.ReviewThread{{ID: "T1"}, {ID: "T2"}}
+
+	replies, err := parsePRRespondReplies(`See [notes] below.
+[{"thread_id": "T1", "reply": "first"}, {"thread_id": "T1", "reply": "second"}, {"thread_id": "T2", "reply": "  "}, {"thread_id": "T9", "reply": "unknown"}]`, threads)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]prRespondReply{"T1": {ThreadID: "T1", Reply: "first"}}, replies)
+
+	_, err = parsePRRespondReplies("I could not address the review.", threads)
+	require.Error(t, err)
+}
+
+func TestReviewThreadContext(t *testing.T) {
+	root := t.TempDir()
+	var content strings.Builder
+	for _, line := range []string{"one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten"} {
+		content.WriteString(line + "\n")
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(root, "f.txt"), []byte(content.String()), 0o644))
+
+	snippet := reviewThreadContext(github.ReviewThread{Path: "f.txt", Line: 6, StartLine: 5, DiffSide: "RIGHT"}, root)
+	assert.Equal(t, strings.Join([]string{
+		"      2 | two",
+		"      3 | three",
+		"      4 | four",
+		">     5 | five",
+		">     6 | six",
+		"      7 | seven",
+		"      8 | eight",
+		"      9 | nine",
+	}, "\n"), snippet)
+
+	assert.Empty(t, reviewThreadContext(github.ReviewThread{Path: "f.txt", Line: 6, IsOutdated: true}, root))
+	assert.Empty(t, reviewThreadContext(github.ReviewThread{Path: "f.txt", Line: 6, DiffSide: "LEFT"}, root))
+	assert.Empty(t, reviewThreadContext(github.ReviewThread{Path: "missing.txt", Line: 1}, root))
+	assert.Empty(t, reviewThreadContext(github.ReviewThread{Path: "f.txt", Line: 20}, root))
+
+	formatted := formatReviewThreads([]github.ReviewThread{{
+		ID:           "T1",
+		Path:         "gone.go",
+		OriginalLine: 4,
+		IsOutdated:   true,
+		Comments:     []github.ReviewComment{{Author: "alice", Body: "Rename this", DiffHunk: "@@ -1,4 +1,4 @@\n-old\n+new"}},
+	}}, root)
+	assert.Contains(t, formatted, `<thread id="T1" path="gone.go" lines="4" outdated="true">`)
+	assert.Contains(t, formatted, "<diff_hunk>\n@@ -1,4 +1,4 @@\n-old\n+new\n</diff_hunk>")
+}
@@ -128,11 +128,19 @@ func TestPRConfigValidation(t *testing.T) {
 			wantErr: false,
 		},
 		{
-			name: "invalid provider",
+			name: "gitlab provider",
 			config: &PRConfig{
 				Provider: "gitlab",
 				Target:   "main",
 			},
+			wantErr: false,
+		},
+		{
+			name: "invalid provider",
+			config: &PRConfig{
+				Provider: "bitbucket",
+				Target:   "main",
+			},
 			wantErr: true,
 		},
 		{
@@ -73,14 +73,14 @@ var profileListCmd = &cobra.Command{
 
 		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 
-		fmt.Fprintln(tw, "NAME\tSCOPE\tSTATUS")
-		fmt.Fprintln(tw, "----\t-----\t------")
+		fmt.Fprintln(tw, "NAME\tSCOPE\tEXTENDS\tSTATUS")
+		fmt.Fprintln(tw, "----\t-----\t-------\t------")
 
 		status := ""
 		if activeProfileName == "" {
 			status = "ACTIVE"
 		}
-		fmt.Fprintf(tw, "default\t%s\t%s\n", ScopeBuiltIn, status)
+		fmt.Fprintf(tw, "default\t%s\t\t%s\n", ScopeBuiltIn, status)
 
 		if len(mergedProfiles) > 0 {
 			for name, source := range mergedProfiles {
@@ -99,7 +99,7 @@ var profileListCmd = &cobra.Command{
 					scope = ScopeRepo
 				}
 
-				fmt.Fprintf(tw, "%s\t%s\t%s\n", name, scope, status)
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, scope, profileExtends(name, globalProfiles, repoProfiles), status)
 			}
 		}
 
@@ -178,8 +178,8 @@ var profileUseCmd = &cobra.Command{
 	Use:   "use [profile-name]",
 	Short: "Switch to a different profile",
 	Long: `Switch to a different profile. 
-Without -g flag: updates ./kodelet-config.yaml
-With -g flag: updates ~/.kodelet/config.yaml
+Without -g flag: updates ./kodelet-config.yaml (or ./kodelet-config.json)
+With -g flag: updates ~/.kodelet/config.yaml (or ~/.kodelet/config.json)
 
 Use "default" to use base configuration without any profile.`,
 	Args: cobra.ExactArgs(1),
@@ -213,11 +213,18 @@ func init() {
 	profileUseCmd.Flags().BoolP("global", "g", false, "Update global config instead of repo config")
 }
 
+// profileExtends returns the parent of a profile, taking the repo definition
+// over the global one.
+func profileExtends(name string, globalProfiles, repoProfiles map[string]llmtypes.ProfileConfig) string {
+	if profile, exists := repoProfiles[name]; exists {
+		return profile.Extends()
+	}
+	return globalProfiles[name].Extends()
+}
+
 func getRepoProfileSetting() string {
 	v := viper.New()
-	v.SetConfigName("kodelet-config")
-	v.SetConfigType("yaml")
-	v.AddConfigPath(".")
+	v.SetConfigFile(llm.RepoConfigFile())
 
 	if err := v.ReadInConfig(); err != nil {
 		return ""
@@ -227,15 +234,12 @@ func getRepoProfileSetting() string {
 }
 
 func getGlobalProfileSetting() string {
-	v := viper.New()
-	v.SetConfigName("config")
-	v.SetConfigType("yaml")
-
-	homeDir, err := os.UserHomeDir()
+	configFile, err := llm.GlobalConfigFile()
 	if err != nil {
 		return ""
 	}
-	v.AddConfigPath(filepath.Join(homeDir, ".kodelet"))
+	v := viper.New()
+	v.SetConfigFile(configFile)
 
 	if err := v.ReadInConfig(); err != nil {
 		return ""
@@ -245,15 +249,12 @@ func getGlobalProfileSetting() string {
 }
 
 func getGlobalProfiles() map[string]llmtypes.ProfileConfig {
-	v := viper.New()
-	v.SetConfigName("config")
-	v.SetConfigType("yaml")
-
-	homeDir, err := os.UserHomeDir()
+	configFile, err := llm.GlobalConfigFile()
 	if err != nil {
 		return nil
 	}
-	v.AddConfigPath(filepath.Join(homeDir, ".kodelet"))
+	v := viper.New()
+	v.SetConfigFile(configFile)
 
 	if err := v.ReadInConfig(); err != nil {
 		return nil
@@ -281,9 +282,7 @@ func getGlobalProfiles() map[string]llmtypes.ProfileConfig {
 
 func getRepoProfiles() map[string]llmtypes.ProfileConfig {
 	v := viper.New()
-	v.SetConfigName("kodelet-config")
-	v.SetConfigType("yaml")
-	v.AddConfigPath(".")
+	v.SetConfigFile(llm.RepoConfigFile())
 
 	if err := v.ReadInConfig(); err != nil {
 		return nil
@@ -335,13 +334,13 @@ func getMergedProfiles() map[string]string {
 
 func getConfigFilePath(global bool) (string, error) {
 	if global {
-		homeDir, err := os.UserHomeDir()
+		configFile, err := llm.GlobalConfigFile()
 		if err != nil {
 			return "", errors.Wrap(err, "failed to get home directory")
 		}
-		return filepath.Join(homeDir, ".kodelet", "config.yaml"), nil
+		return configFile, nil
 	}
-	return "./kodelet-config.yaml", nil
+	return llm.RepoConfigFile(), nil
 }
 
 func getProfileSwitchMessage(profileName string, global bool) string {
@@ -368,7 +367,7 @@ func updateProfileInConfig(global bool, profileName string) error {
 			newConfig := map[string]any{
 				"profile": profileName,
 			}
-			return writeYAMLConfig(configPath, newConfig)
+			return writeProfileConfig(configPath, newConfig)
 		}
 		return errors.Wrap(err, "failed to read config file")
 	}
@@ -384,7 +383,24 @@ func updateProfileInConfig(global bool, profileName string) error {
 
 	config["profile"] = profileName
 
-	return writeYAMLConfig(configPath, config)
+	return writeProfileConfig(configPath, config)
+}
+
+// writeProfileConfig writes config in the format of configPath, keeping
+// JSON config files JSON.
+func writeProfileConfig(configPath string, config map[string]any) error {
+	if filepath.Ext(configPath) != ".json" {
+		return writeYAMLConfig(configPath, config)
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal config")
+	}
+	if err := os.WriteFile(configPath, append(data, '\n'), 0o644); err != nil {
+		return errors.Wrap(err, "failed to write config file")
+	}
+	logger.G(context.TODO()).WithField("file", configPath).Debug("Profile configuration updated")
+	return nil
 }
 
 func writeYAMLConfig(configPath string, config map[string]any) error {
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -87,6 +88,22 @@ func TestProfileHelpers(t *testing.T) {
 	assert.Equal(t, "Switched to profile 'fast' in global config", getProfileSwitchMessage("fast", true))
 }
 
+func TestProfileExtends(t *testing.T) {
+	globalProfiles := map[string]llmtypes.ProfileConfig{
+		"ops":    {"extends": "base"},
+		"shared": {"extends": "base"},
+	}
+	repoProfiles := map[string]llmtypes.ProfileConfig{
+		"shared": {"extends": "ops"},
+		"plain":  {"model": "plain-model"},
+	}
+
+	assert.Equal(t, "base", profileExtends("ops", globalProfiles, repoProfiles))
+	assert.Equal(t, "ops", profileExtends("shared", globalProfiles, repoProfiles))
+	assert.Empty(t, profileExtends("plain", globalProfiles, repoProfiles))
+	assert.Empty(t, profileExtends("missing", globalProfiles, repoProfiles))
+}
+
 func TestUpdateProfileInConfig(t *testing.T) {
 	t.Run("creates missing repo config", func(t *testing.T) {
 		repo := t.TempDir()
@@ -155,3 +172,20 @@ func withTempHomeAndCWD(t *testing.T, home, cwd string) {
 		require.NoError(t, os.Chdir(oldCWD))
 	})
 }
+
+func TestUpdateProfileInJSONConfig(t *testing.T) {
+	repo := t.TempDir()
+	withTempHomeAndCWD(t, t.TempDir(), repo)
+	path := filepath.Join(repo, "kodelet-config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"provider":"openai","profile":"old"}`), 0o644))
+
+	require.NoError(t, updateProfileInConfig(false, "new"))
+
+	var config map[string]any
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &config), "the config file stays JSON")
+	assert.Equal(t, "new", config["profile"])
+	assert.Equal(t, "openai", config["provider"])
+	assert.NoFileExists(t, filepath.Join(repo, "kodelet-config.yaml"))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/llm"
+	"github.com/jingkaihe/kodelet/pkg/presenter"
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+)
+
+// translateResumedConversation converts a conversation being resumed into the
+// message format of the provider requested with --provider, and saves it so
+// the usual config snapshot resume picks up the new provider. It is a no-op
+// unless --provider was given explicitly and differs from the stored one.
+func translateResumedConversation(ctx context.Context, cmd *cobra.Command, store conversations.ConversationStore, record *convtypes.ConversationRecord) error {
+	if cmd == nil || cmd.Flags().Lookup("provider") == nil || !cmd.Flags().Changed("provider") {
+		return nil
+	}
+
+	config, err := llm.GetConfigFromViperWithCmd(cmd)
+	if err != nil {
+		return err
+	}
+	fromProvider := record.Provider
+	translated, err := llm.TranslateConversation(record, config)
+	if err != nil {
+		return errors.Wrap(err, "failed to translate conversation to the requested provider")
+	}
+	if !translated {
+		return nil
+	}
+	if err := store.Save(ctx, *record); err != nil {
+		return errors.Wrap(err, "failed to save translated conversation")
+	}
+
+	presenter.Info(fmt.Sprintf(
+		"Converted conversation %s from %s to %s (%s). Reasoning and images from earlier turns were not carried over.",
+		record.ID, fromProvider, config.Provider, config.Model,
+	))
+	return nil
+}
+
+// translateChatResumeConversation opens the conversation store and applies
+// translateResumedConversation before the chat TUI loads the conversation.
+func translateChatResumeConversation(ctx context.Context, cmd *cobra.Command, conversationID string) error {
+	if conversationID == "" || !cmd.Flags().Changed("provider") {
+		return nil
+	}
+
+	store, err := conversations.GetConversationStore(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to open conversation store")
+	}
+	defer func() {
+		_ = store.Close()
+	}()
+
+	record, err := store.Load(ctx, conversationID)
+	if err != nil {
+		return errors.Wrapf(err, "conversation not found: %s", conversationID)
+	}
+	return translateResumedConversation(ctx, cmd, store, &record)
+}
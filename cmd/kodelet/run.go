@@ -6,25 +6,33 @@ import (
 	"io"
 	"os"
 	"os/signal"
+	"slices"
 	"sort"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/jingkaihe/kodelet/pkg/auth"
+	"github.com/jingkaihe/kodelet/pkg/checkpoints"
 	"github.com/jingkaihe/kodelet/pkg/conversations"
 	"github.com/jingkaihe/kodelet/pkg/extensions"
 	"github.com/jingkaihe/kodelet/pkg/fragments"
 	"github.com/jingkaihe/kodelet/pkg/goals"
 	"github.com/jingkaihe/kodelet/pkg/llm"
 	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/markdown"
 	"github.com/jingkaihe/kodelet/pkg/presenter"
+	"github.com/jingkaihe/kodelet/pkg/secrets"
 	"github.com/jingkaihe/kodelet/pkg/slashcommands"
+	"github.com/jingkaihe/kodelet/pkg/telemetry"
+	"github.com/jingkaihe/kodelet/pkg/toolapproval"
 	"github.com/jingkaihe/kodelet/pkg/tools"
 	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 type RunConfig struct {
@@ -36,6 +44,8 @@ type RunConfig struct {
 	StreamDeltas        bool              // Stream partial text and tool output in headless mode
 	Images              []string          // Image paths or URLs to include with the message
 	MaxTurns            int               // Maximum number of turns within a single SendMessage call
+	MaxCostUSD          float64           // Stop the run once it has cost this many US dollars
+	MaxTotalTokens      int               // Stop the run once it has used this many tokens
 	FragmentName        string            // Name of fragment to use
 	FragmentArgs        map[string]string // Arguments to pass to fragment
 	FragmentDirs        []string          // Additional fragment directories
@@ -50,6 +60,26 @@ type RunConfig struct {
 	ResultOnly          bool              // Only print the final agent message, no intermediate output or usage stats
 	UseWeakModel        bool              // Use weak model for SendMessage
 	Account             string            // Anthropic subscription account alias to use
+	AllowExceedLimits   bool              // Disable configured change limits for this run
+	ApproveTools        bool              // Ask for confirmation before risky tool calls run
+	DeferTools          bool              // Queue risky tool calls and review them together when the agent finishes
+	MetricsAddr         string            // Address to serve Prometheus metrics on while the run lasts
+	PR                  bool              // Branch, commit, push and open a pull request after a successful run
+	PRTarget            string            // Target branch for the pull request
+	PRDraft             bool              // Open the pull request as a draft
+	Verify              string            // Shell command that must pass before the pull request is created
+	NoRender            bool              // Print assistant markdown as raw text instead of rendering it
+	RefreshContext      bool              // Tell a resumed conversation what changed in the repository since it was last saved
+	ResolveConflicts    bool              // Resolve merge conflicts left in the working tree with a restricted agent
+	InDevContainer      bool              // Run bash and verification commands inside the repository's dev container
+	BriefFrom           string            // Conversation to distill a starting briefing from
+	BriefFiles          []string          // Files attached to the briefing verbatim
+	BriefTokens         int               // Token budget for the briefing
+	UseConversations    []string          // Conversations whose summary is injected as context
+	UseConversationMsgs int               // Recent messages of each used conversation injected with its summary
+
+	// Output is the console output profile; quiet implies ResultOnly
+	Output llmtypes.OutputProfile
 }
 
 func NewRunConfig() *RunConfig {
@@ -72,8 +102,22 @@ func NewRunConfig() *RunConfig {
 		Sysprompt:           "",
 		SyspromptArgs:       make(map[string]string),
 		ResultOnly:          false,
+		Output:              llmtypes.OutputProfileNormal,
 		UseWeakModel:        false,
 		Account:             "",
+		AllowExceedLimits:   false,
+		ApproveTools:        false,
+		DeferTools:          false,
+		MetricsAddr:         "",
+		PR:                  false,
+		PRTarget:            "main",
+		PRDraft:             false,
+		Verify:              "",
+		NoRender:            false,
+		RefreshContext:      false,
+		ResolveConflicts:    false,
+		BriefFiles:          []string{},
+		UseConversations:    []string{},
 	}
 }
 
@@ -207,6 +251,14 @@ func addRunMessageDisplay(thread llmtypes.Thread, query string, config *RunConfi
 	}
 }
 
+// addRunRecipe records the recipe the run was started with, so later runs of
+// the same recipe can be estimated from it.
+func addRunRecipe(thread llmtypes.Thread, config *RunConfig) {
+	if name := strings.TrimSpace(config.FragmentName); name != "" {
+		thread.SetMetadataValue(convtypes.RecipeNameMetadataKey, name)
+	}
+}
+
 func addRunGoalDisplay(thread llmtypes.Thread, update *goals.CommandUpdate) {
 	if thread == nil || update == nil {
 		return
@@ -219,6 +271,53 @@ func addRunGoalDisplay(thread llmtypes.Thread, update *goals.CommandUpdate) {
 	}
 }
 
+// addContextRefresh tells a resumed conversation which context and repository
+// files changed since it was last saved, so the agent does not act on stale
+// assumptions.
+func addContextRefresh(ctx context.Context, thread llmtypes.Thread, state tooltypes.State) {
+	previous, ok := conversations.ContextSnapshotFromMetadata(thread.GetMetadata())
+	if !ok {
+		presenter.Warning("Conversation has no saved context snapshot; skipping --refresh-context")
+		return
+	}
+
+	current := conversations.NewContextSnapshot(ctx, state.DiscoverContexts(), state.WorkingDirectory())
+	refresh := conversations.DiffContextSnapshots(ctx, previous, current, state.WorkingDirectory())
+	if refresh.Empty() {
+		presenter.Info("Repository unchanged since the last session")
+		return
+	}
+
+	note := conversations.RenderContextRefresh(refresh)
+	thread.AddUserMessage(ctx, note)
+	metadata := conversations.AddMessageDisplay(thread.GetMetadata(), note, refresh.Summary(), conversations.MessageDisplayKindContextRefresh, "")
+	for key, value := range metadata {
+		thread.SetMetadataValue(key, value)
+	}
+	presenter.Info(refresh.Summary())
+}
+
+// addUsedConversations injects the summary, and optionally the recent
+// messages, of each conversation in config.UseConversations. The used
+// conversations are only read.
+func addUsedConversations(ctx context.Context, thread llmtypes.Thread, config *RunConfig) error {
+	for _, conversationID := range config.UseConversations {
+		conversationContext, err := llm.LoadConversationContext(ctx, conversationID, conversations.ConversationContextOptions{Messages: config.UseConversationMsgs})
+		if err != nil {
+			return err
+		}
+		thread.AddUserMessage(ctx, conversationContext.Prompt)
+		metadata := conversations.AddMessageDisplay(thread.GetMetadata(), conversationContext.Prompt, conversationContext.Display, conversations.MessageDisplayKindConversationContext, "")
+		for key, value := range metadata {
+			thread.SetMetadataValue(key, value)
+		}
+		if !config.ResultOnly {
+			presenter.Info(conversationContext.Display)
+		}
+	}
+	return nil
+}
+
 func getQueryFromStdinOrArgs(args []string) (string, error) {
 	stat, _ := os.Stdin.Stat()
 	isPipe := (stat.Mode() & os.ModeCharDevice) == 0
@@ -262,6 +361,61 @@ func applyFragmentRestrictions(llmConfig *llmtypes.Config, fragmentMetadata *fra
 	}
 }
 
+// applyFragmentRunOptions applies the run options declared in a recipe's
+// frontmatter. Flags set explicitly on the command line win.
+func applyFragmentRunOptions(cmd *cobra.Command, config *RunConfig, llmConfig *llmtypes.Config, fragmentMetadata *fragments.Metadata) {
+	if fragmentMetadata == nil {
+		return
+	}
+
+	if fragmentMetadata.Model != "" && !cmd.Flags().Changed("model") {
+		if err := llm.SwitchModel(llmConfig, fragmentMetadata.Model); err != nil {
+			presenter.Warning(fmt.Sprintf("Invalid model %q in recipe metadata, ignoring: %v", fragmentMetadata.Model, err))
+		}
+	}
+	if fragmentMetadata.MaxTurns != 0 && !cmd.Flags().Changed("max-turns") {
+		if fragmentMetadata.MaxTurns < 0 {
+			presenter.Warning(fmt.Sprintf("Invalid max_turns %d in recipe metadata, ignoring", fragmentMetadata.MaxTurns))
+		} else {
+			config.MaxTurns = fragmentMetadata.MaxTurns
+		}
+	}
+	if fragmentMetadata.CompactRatio != 0 && !cmd.Flags().Changed("compact-ratio") {
+		if fragmentMetadata.CompactRatio < 0 || fragmentMetadata.CompactRatio > 1 {
+			presenter.Warning(fmt.Sprintf("Invalid compact_ratio %g in recipe metadata, ignoring", fragmentMetadata.CompactRatio))
+		} else {
+			llmConfig.CompactRatio = fragmentMetadata.CompactRatio
+		}
+	}
+	if fragmentMetadata.UseWeakModel && !cmd.Flags().Changed("use-weak-model") {
+		config.UseWeakModel = true
+	}
+	if fragmentMetadata.NoTools && !cmd.Flags().Changed("no-tools") {
+		config.NoTools = true
+	}
+	if len(fragmentMetadata.Images) > 0 {
+		config.Images = append(append([]string{}, fragmentMetadata.Images...), config.Images...)
+	}
+	if len(fragmentMetadata.Env) > 0 {
+		if err := secrets.ValidateEnv(fragmentMetadata.Env); err != nil {
+			presenter.Warning(fmt.Sprintf("Invalid env in recipe metadata, ignoring: %v", err))
+		} else {
+			llmConfig.ToolEnv = append(append([]llmtypes.ToolEnvVar{}, llmConfig.ToolEnv...), recipeToolEnv(fragmentMetadata.Env)...)
+		}
+	}
+}
+
+// recipeToolEnv converts a recipe's env into tool_env entries sorted by name.
+// They follow the configured entries, so the recipe wins for a shared name.
+func recipeToolEnv(env map[string]string) []llmtypes.ToolEnvVar {
+	vars := make([]llmtypes.ToolEnvVar, 0, len(env))
+	for name, value := range env {
+		vars = append(vars, llmtypes.ToolEnvVar{Name: name, Value: value})
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars
+}
+
 func applyRunToolRestrictions(llmConfig *llmtypes.Config, fragmentMetadata *fragments.Metadata, noTools bool) {
 	applyFragmentRestrictions(llmConfig, fragmentMetadata)
 	if noTools {
@@ -269,6 +423,18 @@ func applyRunToolRestrictions(llmConfig *llmtypes.Config, fragmentMetadata *frag
 	}
 }
 
+// enableToolApproval turns on approval mode, keeping a configured risk list.
+func enableToolApproval(approval *llmtypes.ToolApprovalConfig, mode string) *llmtypes.ToolApprovalConfig {
+	enabled := llmtypes.ToolApprovalConfig{Enabled: true, Mode: mode}
+	if approval != nil {
+		enabled.Risks = approval.Risks
+		if mode == "" {
+			enabled.Mode = approval.Mode
+		}
+	}
+	return &enabled
+}
+
 func createRunToolManagers(ctx context.Context, config *RunConfig, workingDir string) (*extensions.Runtime, error) {
 	var extensionRuntime *extensions.Runtime
 	if !config.NoTools && !config.NoExtensions {
@@ -356,6 +522,9 @@ func loadResumeConversationConfig(ctx context.Context, cmd *cobra.Command, conve
 	if err != nil {
 		return llmtypes.Config{}, "", errors.Wrap(err, "failed to load conversation")
 	}
+	if err := translateResumedConversation(ctx, cmd, store, &record); err != nil {
+		return llmtypes.Config{}, "", err
+	}
 
 	snapshot, hasSnapshot, err := conversations.ConfigSnapshotFromMetadata(record.Metadata)
 	if err != nil {
@@ -436,9 +605,20 @@ var runCmd = &cobra.Command{
 	Long:  `Execute a one-shot query with Kodelet and return the result.`,
 	Args:  cobra.MinimumNArgs(0),
 	Run: func(cmd *cobra.Command, args []string) {
+		// exitCode is set by failures that should still run the deferred
+		// cleanup, such as removing the dev container, before exiting.
+		exitCode := 0
+		defer func() {
+			if exitCode != 0 {
+				os.Exit(exitCode)
+			}
+		}()
+
 		ctx, cancel := context.WithCancel(cmd.Context())
 		defer cancel()
 
+		startedAt := time.Now()
+		timer := newStartupTimer()
 		config := getRunConfigFromFlags(ctx, cmd)
 
 		sigCh := make(chan os.Signal, 1)
@@ -482,11 +662,53 @@ var runCmd = &cobra.Command{
 			os.Exit(1)
 		}
 		llmConfig.WorkingDirectory = resolvedCWD
+		var prTemplates runPRTemplates
+		var prScopes githubTokenScopes
+		var prForge forgeConfig
+		if config.PR {
+			if prForge, err = loadForgeConfig(); err != nil {
+				presenter.Error(err, "Cannot create a pull request for this run")
+				os.Exit(1)
+			}
+			if err := validateRunPRPrerequisites(ctx, resolvedCWD, prForge); err != nil {
+				presenter.Error(err, "Cannot create a pull request for this run")
+				os.Exit(1)
+			}
+			prTemplates, _ = loadRunPRTemplates()
+			if prForge.Provider == forgeGitHub {
+				prScopes = detectGitHubTokenScopes(ctx, resolvedCWD)
+			}
+			if !prScopes.Allows(githubCapabilityPullRequests) {
+				presenter.Warning(fmt.Sprintf("The GitHub token cannot open pull requests; the pull request will be drafted to %s instead", githubPRDraftsDir))
+				renderGitHubCapabilities(os.Stderr, prScopes, githubCapabilityPullRequests)
+			}
+		}
+		timer.Mark("config")
+
+		if config.MetricsAddr != "" {
+			shutdownMetrics, err := telemetry.ServeMetrics(config.MetricsAddr)
+			if err != nil {
+				presenter.Error(err, "Failed to serve metrics")
+				os.Exit(1)
+			}
+			defer shutdownMetrics(context.Background())
+		}
 
 		if !config.Headless && !config.ResultOnly {
 			ctx = extensions.ContextWithUIInputBroker(ctx, extensions.NewTerminalUIInputBroker(os.Stdin, os.Stderr))
 		}
 
+		devContainer, err := startRunDevContainer(ctx, config, resolvedCWD)
+		if err != nil {
+			presenter.Error(err, "Cannot run in the dev container")
+			os.Exit(1)
+		}
+		defer func() {
+			if err := devContainer.Stop(context.WithoutCancel(ctx)); err != nil {
+				logger.G(ctx).WithError(err).Warn("failed to remove the dev container")
+			}
+		}()
+
 		extensionRuntime, err := createRunToolManagers(ctx, config, resolvedCWD)
 		if err != nil {
 			presenter.Error(err, "Failed to initialize tools")
@@ -497,6 +719,7 @@ var runCmd = &cobra.Command{
 				_ = extensionRuntime.Close()
 			}()
 		}
+		timer.Mark("extensions")
 
 		if config.FragmentName != "" {
 			processed, err := processFragment(ctx, config, args, extensionRuntime, extensions.ExtensionCallContext{
@@ -574,11 +797,25 @@ var runCmd = &cobra.Command{
 			llmConfig.AnthropicAccount = config.Account
 		}
 
+		applyFragmentRunOptions(cmd, config, &llmConfig, fragmentMetadata)
 		applyRunToolRestrictions(&llmConfig, fragmentMetadata, config.NoTools)
+		if config.AllowExceedLimits {
+			llmConfig.Limits = nil
+		}
+		if config.ApproveTools || config.DeferTools {
+			mode := ""
+			if config.DeferTools {
+				mode = toolapproval.ModeDefer
+			}
+			llmConfig.ToolApproval = enableToolApproval(llmConfig.ToolApproval, mode)
+		}
 
 		var stateOpts []tools.BasicStateOption
 		stateOpts = append(stateOpts, tools.WithWorkingDirectory(llmConfig.WorkingDirectory))
 		stateOpts = append(stateOpts, tools.WithLLMConfig(llmConfig))
+		if devContainer != nil {
+			stateOpts = append(stateOpts, tools.WithDevContainer(devContainer))
+		}
 		if !config.NoTools {
 			if extensionRuntime != nil {
 				stateOpts = append(stateOpts, tools.WithExtensionTools(extensionRuntime.Tools()))
@@ -597,7 +834,18 @@ var runCmd = &cobra.Command{
 			sessionID = convtypes.GenerateID()
 		}
 
+		summary := newRunSummary(llmConfig, sessionID, startedAt)
+		runCheckpoints := openRunCheckpoints(ctx, summary.RunID)
+		summary.checkpoints = runCheckpoints
+		var conversationCheckpoints *checkpoints.Store
+		if !config.NoSave {
+			conversationCheckpoints = openConversationCheckpoints(ctx, sessionID)
+			query = withRollbackNotice(ctx, conversationCheckpoints, query)
+		}
+		stateOpts = append(stateOpts, tools.WithCheckpoints(runCheckpoints, conversationCheckpoints))
+
 		appState := tools.NewBasicState(ctx, stateOpts...)
+		timer.Mark("tools")
 
 		if config.Headless {
 			presenter.SetQuiet(true)
@@ -614,12 +862,24 @@ var runCmd = &cobra.Command{
 			defer func() { _ = llm.CloseThread(thread) }()
 			thread.SetState(appState)
 			thread.SetConversationID(sessionID)
+			timer.Mark("thread")
 			thread.EnablePersistence(ctx, !config.NoSave)
+			if config.RefreshContext && config.ResumeConvID != "" {
+				addContextRefresh(ctx, thread, appState)
+			}
+			if err := addUsedConversations(ctx, thread, config); err != nil {
+				presenter.Error(err, "Failed to use conversation context")
+				return
+			}
+			addRunBriefing(ctx, llmConfig, thread, config, query, resolvedCWD)
+			addRunRecipe(thread, config)
 			if goalUpdate != nil {
 				addRunGoalDisplay(thread, goalUpdate)
 			} else {
 				addRunMessageDisplay(thread, query, config)
 			}
+			timer.Mark("persistence")
+			timer.Log(ctx, "run")
 
 			streamer, closeFunc, err := llm.NewConversationStreamer(ctx)
 			if err != nil {
@@ -661,6 +921,7 @@ var runCmd = &cobra.Command{
 				return
 			}
 
+			var finalOutput string
 			done := make(chan error, 1)
 			go func() {
 				var handler llmtypes.MessageHandler
@@ -676,37 +937,53 @@ var runCmd = &cobra.Command{
 				} else {
 					handler = &llmtypes.ConsoleMessageHandler{Silent: true}
 				}
-				_, err := thread.SendMessage(ctx, query, handler, llmtypes.MessageOpt{
-					PromptCache:  true,
-					Images:       config.Images,
-					MaxTurns:     config.MaxTurns,
-					CompactRatio: llmConfig.CompactRatio,
-					UseWeakModel: config.UseWeakModel,
+				output, err := thread.SendMessage(ctx, query, handler, llmtypes.MessageOpt{
+					PromptCache:    true,
+					Images:         config.Images,
+					MaxTurns:       config.MaxTurns,
+					MaxCostUSD:     config.MaxCostUSD,
+					MaxTotalTokens: config.MaxTotalTokens,
+					CompactRatio:   llmConfig.CompactRatio,
+					UseWeakModel:   config.UseWeakModel,
 				})
+				finalOutput = output
 				done <- err
 			}()
 
+			var runErr error
 			select {
 			case err := <-done:
 				if err != nil {
 					logger.G(ctx).WithError(err).Error("Error processing query")
 				}
+				runErr = err
 				// Give streaming time to catch final messages (2 polling cycles)
 				time.Sleep(2 * liveUpdateInterval)
 				cancel()
 				<-streamDone
+				summary.finish(thread, resolvedCWD, finalOutput, runErr, 0, time.Now())
+				recordRunPostMortem(ctx, llmConfig, thread, summary, query, config.MaxTurns, runErr)
 			case err := <-streamDone:
 				if err != nil && err != context.Canceled {
 					logger.G(ctx).WithError(err).Error("Error streaming updates")
+					runErr = err
 				}
+				summary.finish(nil, resolvedCWD, "", runErr, 0, time.Now())
 			}
+			saveRunSummary(ctx, resolvedCWD, summary, true)
+			recordRunMetrics(ctx, config, resolvedCWD, summary, thread)
 		} else {
 			if config.ResultOnly {
 				presenter.SetQuiet(true)
 				logger.SetLogLevel("error")
 			}
 
-			handler := &llmtypes.ConsoleMessageHandler{Silent: config.ResultOnly}
+			handler := llmtypes.NewConsoleMessageHandler(config.Output)
+			if !config.ResultOnly && !config.NoRender && markdown.IsTerminal(os.Stdout) {
+				if renderer, err := presenter.NewMarkdownRenderer(0); err == nil {
+					handler.RenderMarkdown = renderer.Render
+				}
+			}
 			thread, err := llm.NewThread(llmConfig)
 			if err != nil {
 				presenter.Error(err, "Failed to create LLM thread")
@@ -715,38 +992,120 @@ var runCmd = &cobra.Command{
 			defer func() { _ = llm.CloseThread(thread) }()
 			thread.SetState(appState)
 			thread.SetConversationID(sessionID)
+			timer.Mark("thread")
 
 			if config.ResumeConvID != "" && !config.ResultOnly {
 				presenter.Info(fmt.Sprintf("Resuming conversation: %s", config.ResumeConvID))
 			}
 
 			thread.EnablePersistence(ctx, !config.NoSave)
+			if config.RefreshContext && config.ResumeConvID != "" {
+				addContextRefresh(ctx, thread, appState)
+			}
+			if err := addUsedConversations(ctx, thread, config); err != nil {
+				presenter.Error(err, "Failed to use conversation context")
+				return
+			}
+			addRunBriefing(ctx, llmConfig, thread, config, query, resolvedCWD)
+			addRunRecipe(thread, config)
 			if goalUpdate != nil {
 				addRunGoalDisplay(thread, goalUpdate)
 			} else {
 				addRunMessageDisplay(thread, query, config)
 			}
+			timer.Mark("persistence")
+			timer.Log(ctx, "run")
 
 			finalOutput, err := thread.SendMessage(ctx, query, handler, llmtypes.MessageOpt{
-				PromptCache:  true,
-				Images:       config.Images,
-				MaxTurns:     config.MaxTurns,
-				CompactRatio: llmConfig.CompactRatio,
-				UseWeakModel: config.UseWeakModel,
+				PromptCache:    true,
+				Images:         config.Images,
+				MaxTurns:       config.MaxTurns,
+				MaxCostUSD:     config.MaxCostUSD,
+				MaxTotalTokens: config.MaxTotalTokens,
+				CompactRatio:   llmConfig.CompactRatio,
+				UseWeakModel:   config.UseWeakModel,
 			})
+			finishRun := func(runErr error, code int) {
+				summary.finish(thread, resolvedCWD, finalOutput, runErr, code, time.Now())
+				displayRunPostMortem(recordRunPostMortem(ctx, llmConfig, thread, summary, query, config.MaxTurns, runErr))
+				saveRunSummary(ctx, resolvedCWD, summary, config.ResultOnly)
+				recordRunMetrics(ctx, config, resolvedCWD, summary, thread)
+			}
 			if err != nil {
+				var budgetErr *llmtypes.BudgetExceededError
+				if errors.As(err, &budgetErr) && !config.ResultOnly {
+					usage := thread.GetUsage()
+					presenter.Stats(presenter.ConvertUsageStats(&usage))
+				}
 				presenter.Error(err, "Failed to process query")
+				finishRun(err, 1)
+				exitCode = 1
 				return
 			}
 
 			if config.ResultOnly {
 				fmt.Println(finalOutput)
-				return
+			} else {
+				usage := thread.GetUsage()
+				usageStats := presenter.ConvertUsageStats(&usage)
+				presenter.Stats(usageStats)
+				resources := appState.ResourceUsage()
+				presenter.Resources(presenter.ConvertResourceStats(&resources))
+				presenter.Todos(presenter.ConvertTodoStats(thread.GetMetadata()))
 			}
 
-			usage := thread.GetUsage()
-			usageStats := presenter.ConvertUsageStats(&usage)
-			presenter.Stats(usageStats)
+			conflictsResolved := true
+			if !config.ResultOnly || config.PR || config.ResolveConflicts {
+				conflictsResolved = checkGitConflicts(ctx, llmConfig, resolvedCWD, config.ResolveConflicts, !config.ResultOnly && stdinIsTerminal())
+			}
+
+			if config.PR {
+				if !conflictsResolved {
+					presenter.Error(errUnresolvedConflicts, "Cannot create a pull request")
+					finishRun(errUnresolvedConflicts, 1)
+					exitCode = 1
+					return
+				}
+				presenter.Section("Pull Request")
+				pr, err := createRunPR(ctx, llmConfig, runPROptions{
+					Target:         config.PRTarget,
+					Draft:          config.PRDraft,
+					Verify:         config.Verify,
+					DevContainer:   devContainer,
+					CWD:            resolvedCWD,
+					ConversationID: thread.GetConversationID(),
+					Persisted:      thread.IsPersisted(),
+					Usage:          thread.GetUsage(),
+					Summary:        summary,
+					RecipeName:     llmConfig.RecipeName,
+					Templates:      prTemplates,
+					Scopes:         prScopes,
+					Forge:          prForge,
+				})
+				if err != nil {
+					presenter.Error(err, "Failed to create pull request")
+					finishRun(err, 1)
+					exitCode = 1
+					return
+				}
+				if pr.DraftPath != "" {
+					presenter.Warning(fmt.Sprintf("Pushed %s; the pull request body was drafted to %s", pr.Branch, pr.DraftPath))
+					presenter.Info(fmt.Sprintf("Open it with a token that may: %s", pr.createCommand(config.PRTarget)))
+					summary.PullRequestDraft = pr.DraftPath
+				} else {
+					presenter.Success(fmt.Sprintf("Pull request created: %s", pr.URL))
+					summary.PullRequestURL = pr.URL
+				}
+				if pr.Pipeline != nil {
+					presenter.Info(fmt.Sprintf("CI pipeline #%d is %s: %s", pr.Pipeline.ID, pr.Pipeline.Status, pr.Pipeline.WebURL))
+				}
+			}
+
+			finishRun(nil, 0)
+
+			if config.ResultOnly {
+				return
+			}
 
 			if thread.IsPersisted() {
 				presenter.Section("Conversation Information")
@@ -768,6 +1127,8 @@ func init() {
 	runCmd.Flags().Bool("stream-deltas", defaults.StreamDeltas, "Stream partial text and tool output in headless mode (requires --headless)")
 	runCmd.Flags().StringSliceP("image", "I", defaults.Images, "Add image input (can be used multiple times)")
 	runCmd.Flags().Int("max-turns", defaults.MaxTurns, "Maximum number of agentic turns (0 for no limit)")
+	runCmd.Flags().Float64("max-cost", defaults.MaxCostUSD, "Stop the run once it has cost this many US dollars (0 for no limit)")
+	runCmd.Flags().Int("max-tokens-total", defaults.MaxTotalTokens, "Stop the run once it has used this many tokens (0 for no limit)")
 	runCmd.Flags().StringP("recipe", "r", defaults.FragmentName, "Use a fragment/recipe template")
 	runCmd.Flags().StringToString("arg", defaults.FragmentArgs, "Arguments to pass to fragment (e.g., --arg name=John --arg occupation=Engineer)")
 	runCmd.Flags().StringSlice("fragment-dirs", defaults.FragmentDirs, "Additional fragment directories (e.g., --fragment-dirs ./project-fragments --fragment-dirs ./team-fragments)")
@@ -776,8 +1137,34 @@ func init() {
 	runCmd.Flags().Bool("no-tools", defaults.NoTools, "Disable all tools (for simple query-response usage)")
 	runCmd.Flags().Bool("enable-fs-search-tools", defaults.EnableFSSearchTools, "Enable filesystem search tools (glob_tool and grep_tool)")
 	runCmd.Flags().Bool("result-only", defaults.ResultOnly, "Only print the final agent message, suppressing all intermediate output and usage statistics")
+	runCmd.Flags().Bool("quiet", false, "Only print the final answer, suppressing tool output and usage statistics (same as --result-only)")
+	runCmd.Flags().Bool("verbose", false, "Also print tool timings and the tokens used by each model response")
+	runCmd.MarkFlagsMutuallyExclusive("quiet", "verbose")
+	runCmd.MarkFlagsMutuallyExclusive("result-only", "verbose")
 	runCmd.Flags().Bool("use-weak-model", defaults.UseWeakModel, "Use weak model for processing")
+	runCmd.Flags().String("agent", "", "Run as this named agent from the agents configuration, with its model, tools and instructions")
 	runCmd.Flags().String("account", defaults.Account, "Anthropic subscription account alias to use (see 'kodelet accounts list')")
+	runCmd.Flags().Bool("allow-exceed-limits", defaults.AllowExceedLimits, "Disable the configured limits.max_files_changed and limits.max_lines_changed for this run")
+	runCmd.Flags().Bool("approve-tools", defaults.ApproveTools, "Ask for confirmation in the terminal before tool calls on the tool_approval.risks list run")
+	runCmd.MarkFlagsMutuallyExclusive("approve-tools", "headless")
+	runCmd.MarkFlagsMutuallyExclusive("approve-tools", "result-only")
+	runCmd.Flags().Bool("defer-tools", defaults.DeferTools, "Queue tool calls on the tool_approval.risks list and review them together in the terminal when the agent finishes")
+	runCmd.MarkFlagsMutuallyExclusive("defer-tools", "headless")
+	runCmd.MarkFlagsMutuallyExclusive("defer-tools", "result-only")
+	runCmd.Flags().String("metrics-addr", defaults.MetricsAddr, "Serve Prometheus metrics on /metrics at this address while the run lasts (e.g. localhost:9464)")
+	runCmd.Flags().Bool("pr", defaults.PR, "After a successful run, create a branch, commit, push and open a pull request")
+	runCmd.Flags().String("pr-target", defaults.PRTarget, "Target branch for the pull request created by --pr")
+	runCmd.Flags().Bool("pr-draft", defaults.PRDraft, "Open the pull request created by --pr as a draft")
+	runCmd.Flags().String("verify", defaults.Verify, "Shell command that must succeed before --pr creates the pull request (e.g. 'make test')")
+	runCmd.Flags().Bool("no-render", defaults.NoRender, "Print assistant responses as raw markdown instead of rendering them")
+	runCmd.Flags().Bool("refresh-context", defaults.RefreshContext, "When resuming, tell the agent which context and repository files changed since the conversation was last saved")
+	runCmd.Flags().Bool("resolve-conflicts", defaults.ResolveConflicts, "After the run, resolve merge conflicts left in the working tree with an agent restricted to the conflicting hunks")
+	runCmd.Flags().Bool("in-devcontainer", defaults.InDevContainer, "Run bash and --verify commands inside the container described by .devcontainer/devcontainer.json")
+	runCmd.Flags().String("brief-from", defaults.BriefFrom, "Start with a briefing distilled from this conversation by the weak model (a subagent can use $"+tools.ConversationIDEnv+")")
+	runCmd.Flags().StringArray("use-conversation", defaults.UseConversations, "Inject the summary of another conversation as context, without merging it (can be used multiple times)")
+	runCmd.Flags().Int("use-conversation-messages", defaults.UseConversationMsgs, "Also inject this many of the most recent messages of each --use-conversation")
+	runCmd.Flags().StringArray("brief-file", defaults.BriefFiles, "Attach a file to the briefing verbatim (can be used multiple times)")
+	runCmd.Flags().Int("brief-tokens", defaults.BriefTokens, "Token budget for the briefing (defaults to briefing.max_tokens)")
 }
 
 func getRunConfigFromFlags(ctx context.Context, cmd *cobra.Command) *RunConfig {
@@ -828,6 +1215,12 @@ func getRunConfigFromFlags(ctx context.Context, cmd *cobra.Command) *RunConfig {
 	if maxTurns, err := cmd.Flags().GetInt("max-turns"); err == nil {
 		config.MaxTurns = max(maxTurns, 0)
 	}
+	if maxCost, err := cmd.Flags().GetFloat64("max-cost"); err == nil {
+		config.MaxCostUSD = max(maxCost, 0)
+	}
+	if maxTotalTokens, err := cmd.Flags().GetInt("max-tokens-total"); err == nil {
+		config.MaxTotalTokens = max(maxTotalTokens, 0)
+	}
 	if fragmentName, err := cmd.Flags().GetString("recipe"); err == nil {
 		config.FragmentName = fragmentName
 	}
@@ -864,6 +1257,18 @@ func getRunConfigFromFlags(ctx context.Context, cmd *cobra.Command) *RunConfig {
 	if resultOnly, err := cmd.Flags().GetBool("result-only"); err == nil {
 		config.ResultOnly = resultOnly
 	}
+	if !config.Headless {
+		output, err := getOutputProfile(cmd, "run")
+		if err != nil {
+			presenter.Error(err, "Invalid output profile")
+			os.Exit(1)
+		}
+		config.Output = output
+	}
+	if config.ResultOnly {
+		config.Output = llmtypes.OutputProfileQuiet
+	}
+	config.ResultOnly = config.Output == llmtypes.OutputProfileQuiet
 
 	if useWeakModel, err := cmd.Flags().GetBool("use-weak-model"); err == nil {
 		config.UseWeakModel = useWeakModel
@@ -873,5 +1278,110 @@ func getRunConfigFromFlags(ctx context.Context, cmd *cobra.Command) *RunConfig {
 		config.Account = account
 	}
 
+	if allowExceedLimits, err := cmd.Flags().GetBool("allow-exceed-limits"); err == nil {
+		config.AllowExceedLimits = allowExceedLimits
+	}
+
+	if approveTools, err := cmd.Flags().GetBool("approve-tools"); err == nil {
+		config.ApproveTools = approveTools
+	}
+	if deferTools, err := cmd.Flags().GetBool("defer-tools"); err == nil {
+		config.DeferTools = deferTools
+	}
+	if metricsAddr, err := cmd.Flags().GetString("metrics-addr"); err == nil {
+		config.MetricsAddr = strings.TrimSpace(metricsAddr)
+	}
+
+	if pr, err := cmd.Flags().GetBool("pr"); err == nil {
+		config.PR = pr
+	}
+	if prTarget, err := cmd.Flags().GetString("pr-target"); err == nil {
+		config.PRTarget = strings.TrimSpace(prTarget)
+	}
+	if prDraft, err := cmd.Flags().GetBool("pr-draft"); err == nil {
+		config.PRDraft = prDraft
+	}
+	if verify, err := cmd.Flags().GetString("verify"); err == nil {
+		config.Verify = verify
+	}
+	if noRender, err := cmd.Flags().GetBool("no-render"); err == nil {
+		config.NoRender = noRender
+	}
+	if refreshContext, err := cmd.Flags().GetBool("refresh-context"); err == nil {
+		config.RefreshContext = refreshContext
+	}
+	if resolveConflicts, err := cmd.Flags().GetBool("resolve-conflicts"); err == nil {
+		config.ResolveConflicts = resolveConflicts
+	}
+	if inDevContainer, err := cmd.Flags().GetBool("in-devcontainer"); err == nil {
+		config.InDevContainer = inDevContainer
+	}
+	if briefFrom, err := cmd.Flags().GetString("brief-from"); err == nil {
+		config.BriefFrom = strings.TrimSpace(briefFrom)
+	}
+	if briefFiles, err := cmd.Flags().GetStringArray("brief-file"); err == nil {
+		config.BriefFiles = briefFiles
+	}
+	if useConversations, err := cmd.Flags().GetStringArray("use-conversation"); err == nil {
+		config.UseConversations = nil
+		for _, conversationID := range useConversations {
+			if conversationID = strings.TrimSpace(conversationID); conversationID != "" {
+				config.UseConversations = append(config.UseConversations, conversationID)
+			}
+		}
+	}
+	if useConversationMsgs, err := cmd.Flags().GetInt("use-conversation-messages"); err == nil {
+		config.UseConversationMsgs = min(max(useConversationMsgs, 0), conversations.MaxConversationContextMessages)
+	}
+	if briefTokens, err := cmd.Flags().GetInt("brief-tokens"); err == nil {
+		config.BriefTokens = max(briefTokens, 0)
+	}
+	if config.BriefTokens == 0 {
+		config.BriefTokens = viper.GetInt("briefing.max_tokens")
+	}
+	if config.BriefFrom == "" && config.ResumeConvID == "" && viper.GetBool("briefing.auto") {
+		config.BriefFrom = strings.TrimSpace(os.Getenv(tools.ConversationIDEnv))
+	}
+	if config.RefreshContext && config.ResumeConvID == "" && !config.Follow {
+		presenter.Error(errors.New("invalid flags"), "--refresh-context requires --resume or --follow")
+		os.Exit(1)
+	}
+	if config.ResumeConvID != "" && slices.Contains(config.UseConversations, config.ResumeConvID) {
+		presenter.Error(errors.New("invalid flags"), "--use-conversation needs a different conversation than the one being resumed")
+		os.Exit(1)
+	}
+	if (config.BriefFrom != "" || len(config.BriefFiles) > 0) && config.ResumeConvID != "" {
+		presenter.Error(errors.New("conflicting flags"), "--brief-from and --brief-file only apply to new conversations, not --resume or --follow")
+		os.Exit(1)
+	}
+	if config.PR && config.Headless {
+		presenter.Error(errors.New("conflicting flags"), "--pr cannot be used with --headless")
+		os.Exit(1)
+	}
+	if config.ResolveConflicts && config.Headless {
+		presenter.Error(errors.New("conflicting flags"), "--resolve-conflicts cannot be used with --headless")
+		os.Exit(1)
+	}
+	if (config.ApproveTools || config.DeferTools) && config.ResultOnly {
+		presenter.Error(errors.New("conflicting flags"), "--approve-tools and --defer-tools need the terminal, so they cannot be used with --quiet, --result-only or output.run: quiet")
+		os.Exit(1)
+	}
+	if config.PR && config.PRTarget == "" {
+		presenter.Error(errors.New("invalid flags"), "--pr-target cannot be empty")
+		os.Exit(1)
+	}
+
 	return config
 }
+
+// getOutputProfile resolves the console output profile of command from the
+// --quiet and --verbose flags, falling back to output.<command> in config.
+func getOutputProfile(cmd *cobra.Command, command string) (llmtypes.OutputProfile, error) {
+	if quiet, err := cmd.Flags().GetBool("quiet"); err == nil && quiet {
+		return llmtypes.OutputProfileQuiet, nil
+	}
+	if verbose, err := cmd.Flags().GetBool("verbose"); err == nil && verbose {
+		return llmtypes.OutputProfileVerbose, nil
+	}
+	return llmtypes.ParseOutputProfile(viper.GetString("output." + command))
+}
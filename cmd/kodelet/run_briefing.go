@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/llm"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/presenter"
+	"github.com/jingkaihe/kodelet/pkg/tools"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+)
+
+// briefingDisplayKind marks the briefing message in the conversation display.
+const briefingDisplayKind = "briefing"
+
+const (
+	// briefingDefaultMaxTokens is the briefing budget when briefing.max_tokens
+	// is not configured.
+	briefingDefaultMaxTokens = 2000
+	// briefingBytesPerToken approximates tokens for budgeting, matching the
+	// estimate used by the bash tool.
+	briefingBytesPerToken = 4
+	// briefingMaxTranscriptBytes caps the parent transcript sent to the weak
+	// model. The start of the conversation and its most recent part are kept.
+	briefingMaxTranscriptBytes = 200_000
+)
+
+// runBriefingGenerator sends the briefing prompt to the weak model and returns
+// its reply. It is replaced in tests.
+var runBriefingGenerator = func(ctx context.Context, llmConfig llmtypes.Config, prompt string) string {
+	state := tools.NewBasicState(ctx, tools.WithLLMConfig(llmConfig))
+	out, _ := llm.SendMessageAndGetTextWithUsage(ctx, state, prompt, llmConfig, true, llmtypes.MessageOpt{
+		UseWeakModel:       true,
+		NoToolUse:          true,
+		DisableUsageLog:    true,
+		NoSaveConversation: true,
+	})
+	return out
+}
+
+// briefingFile is a file attached to the briefing verbatim.
+type briefingFile struct {
+	Path      string
+	Content   string
+	Truncated bool
+}
+
+// addRunBriefing starts a new run with a briefing distilled from the
+// conversation in config.BriefFrom plus the files in config.BriefFiles, so a
+// subagent does not start cold. Failures are warnings: the run goes ahead
+// without the missing parts.
+func addRunBriefing(ctx context.Context, llmConfig llmtypes.Config, thread llmtypes.Thread, config *RunConfig, query, cwd string) {
+	if config.BriefFrom == "" && len(config.BriefFiles) == 0 {
+		return
+	}
+	budget := approxBriefingBytes(config.BriefTokens)
+
+	files := readBriefingFiles(ctx, cwd, config.BriefFiles, budget/2)
+	for _, file := range files {
+		budget -= len(file.Content)
+	}
+
+	var summary string
+	if config.BriefFrom != "" {
+		var err error
+		summary, err = summarizeBriefingConversation(ctx, llmConfig, config.BriefFrom, query, budget/briefingBytesPerToken)
+		if err != nil {
+			presenter.Warning(fmt.Sprintf("Skipping briefing from conversation %s: %s", config.BriefFrom, err))
+		}
+		summary = truncateBriefingText(summary, budget)
+	}
+	if summary == "" && len(files) == 0 {
+		return
+	}
+
+	briefing := renderRunBriefing(config.BriefFrom, summary, files)
+	thread.AddUserMessage(ctx, briefing)
+	display := briefingDisplayText(config.BriefFrom, summary, files)
+	metadata := conversations.AddMessageDisplay(thread.GetMetadata(), briefing, display, briefingDisplayKind, "")
+	for key, value := range metadata {
+		thread.SetMetadataValue(key, value)
+	}
+	presenter.Info(display)
+}
+
+// summarizeBriefingConversation asks the weak model for the parts of the
+// saved conversation that matter for the task, in at most maxTokens tokens.
+func summarizeBriefingConversation(ctx context.Context, llmConfig llmtypes.Config, conversationID, task string, maxTokens int) (string, error) {
+	if maxTokens <= 0 {
+		return "", errors.New("the attached files use the whole briefing budget")
+	}
+
+	store, err := conversations.GetConversationStore(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to open conversation store")
+	}
+	defer store.Close()
+
+	record, err := store.Load(ctx, conversationID)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to load conversation")
+	}
+	transcript, err := llm.RenderConversationMarkdownWithOptions(
+		record.Provider,
+		record.RawMessages,
+		record.Metadata,
+		record.ToolResults,
+		llm.ConversationMarkdownOptions{TruncateToolResults: true},
+	)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to render conversation")
+	}
+	if strings.TrimSpace(transcript) == "" {
+		return "", errors.New("conversation has no messages")
+	}
+
+	prompt := runBriefingPrompt(task, truncateBriefingTranscript(transcript), maxTokens)
+	summary := strings.TrimSpace(runBriefingGenerator(ctx, llmConfig, prompt))
+	if summary == "" {
+		return "", errors.New("the weak model returned an empty briefing")
+	}
+	logger.G(ctx).WithField("conversation_id", conversationID).Debug("generated run briefing")
+	return summary, nil
+}
+
+// runBriefingPrompt asks for a briefing of the parent conversation focused on
+// the subagent's task.
+func runBriefingPrompt(task, transcript string, maxTokens int) string {
+	var b strings.Builder
+	b.WriteString(`A coding agent is handing part of its work to a subagent. Write a briefing from the agent's conversation so the subagent does not start cold.
+
+Include only what the subagent needs for its task: the overall goal, decisions and constraints already agreed, relevant files, functions and commands, what was tried and what failed. Preserve exact paths, identifiers and error messages. Leave out anything unrelated to the task, and do not restate the task itself.
+`)
+	fmt.Fprintf(&b, "\nRespond with only the briefing as markdown, in at most %d words.\n", max(maxTokens*3/4, 1))
+	fmt.Fprintf(&b, "\n## Subagent task\n%s\n", truncateBriefingText(strings.TrimSpace(task), 4*postMortemMaxMessageChars))
+	fmt.Fprintf(&b, "\n## Agent conversation\n%s\n", transcript)
+	return b.String()
+}
+
+// readBriefingFiles reads the files attached to the briefing, relative to cwd,
+// truncating them so that together they fit in budget bytes. Unreadable files
+// are skipped with a warning.
+func readBriefingFiles(ctx context.Context, cwd string, paths []string, budget int) []briefingFile {
+	var files []briefingFile
+	for _, path := range paths {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		fullPath := path
+		if !filepath.IsAbs(fullPath) {
+			fullPath = filepath.Join(cwd, fullPath)
+		}
+		data, err := os.ReadFile(fullPath)
+		if err != nil {
+			logger.G(ctx).WithError(err).WithField("path", path).Debug("failed to read briefing file")
+			presenter.Warning(fmt.Sprintf("Skipping briefing file %s: %s", path, err))
+			continue
+		}
+		if budget <= 0 {
+			presenter.Warning(fmt.Sprintf("Skipping briefing file %s: briefing budget exhausted", path))
+			continue
+		}
+
+		content := string(data)
+		truncated := len(content) > budget
+		content = truncateBriefingText(content, budget)
+		budget -= len(content)
+		files = append(files, briefingFile{Path: path, Content: content, Truncated: truncated})
+	}
+	return files
+}
+
+// renderRunBriefing renders the briefing message added before the task.
+func renderRunBriefing(conversationID, summary string, files []briefingFile) string {
+	var b strings.Builder
+	b.WriteString("# Briefing\n\nYou were started to help with part of a larger piece of work. The context below was prepared for your task; check details against the repository before relying on them.\n")
+	if summary != "" {
+		fmt.Fprintf(&b, "\n## Context from conversation %s\n\n%s\n", conversationID, summary)
+	}
+	if len(files) > 0 {
+		b.WriteString("\n## Files\n")
+		for _, file := range files {
+			fmt.Fprintf(&b, "\n### %s\n\n```\n%s\n```\n", file.Path, strings.TrimRight(file.Content, "\n"))
+			if file.Truncated {
+				b.WriteString("(truncated to fit the briefing budget)\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+func briefingDisplayText(conversationID, summary string, files []briefingFile) string {
+	parts := []string{}
+	if summary != "" {
+		parts = append(parts, "conversation "+conversationID)
+	}
+	if len(files) == 1 {
+		parts = append(parts, "1 file")
+	} else if len(files) > 1 {
+		parts = append(parts, fmt.Sprintf("%d files", len(files)))
+	}
+	return "Briefed from " + strings.Join(parts, " and ")
+}
+
+// truncateBriefingTranscript keeps the first fifth and the most recent part
+// of an overlong transcript, where the original request and the current state
+// of the work are.
+func truncateBriefingTranscript(transcript string) string {
+	if len(transcript) <= briefingMaxTranscriptBytes {
+		return transcript
+	}
+	head := truncateBriefingText(transcript, briefingMaxTranscriptBytes/5)
+	tailStart := len(transcript) - (briefingMaxTranscriptBytes - len(head))
+	for tailStart < len(transcript) && !utf8.RuneStart(transcript[tailStart]) {
+		tailStart++
+	}
+	return head + "\n\n[... middle of the conversation omitted ...]\n\n" + transcript[tailStart:]
+}
+
+func approxBriefingBytes(tokens int) int {
+	if tokens <= 0 {
+		tokens = briefingDefaultMaxTokens
+	}
+	return tokens * briefingBytesPerToken
+}
+
+func truncateBriefingText(text string, limit int) string {
+	if limit <= 0 {
+		return ""
+	}
+	return truncatePostMortemText(text, limit)
+}
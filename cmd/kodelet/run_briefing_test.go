@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddRunBriefing(t *testing.T) {
+	ctx := setupConversationCommandStore(t)
+	saveConversationCommandRecord(ctx, t, "parent-conv")
+
+	original := runBriefingGenerator
+	t.Cleanup(func() { runBriefingGenerator = original })
+	var prompt string
+	runBriefingGenerator = func(_ context.Context, _ llmtypes.Config, p string) string {
+		prompt = p
+		return "The user wants the greeting flow fixed."
+	}
+
+	cwd := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(cwd, "NOTES.md"), []byte("Use the v2 API.\n"), 0o644))
+
+	thread := newFakeRunThread()
+	config := &RunConfig{BriefFrom: "parent-conv", BriefFiles: []string{"NOTES.md", "missing.md"}, BriefTokens: 1000}
+	addRunBriefing(ctx, llmtypes.Config{}, thread, config, "Fix the greeting test", cwd)
+
+	assert.Contains(t, prompt, "## Subagent task\nFix the greeting test")
+	assert.Contains(t, prompt, "Hello from the user")
+	assert.Contains(t, prompt, "in at most 747 words", "the file's share of the budget is left out")
+
+	require.Len(t, thread.userMessages, 1)
+	briefing := thread.userMessages[0]
+	assert.Contains(t, briefing, "## Context from conversation parent-conv\n\nThe user wants the greeting flow fixed.")
+	assert.Contains(t, briefing, "### NOTES.md\n\n```\nUse the v2 API.\n```")
+	assert.NotContains(t, briefing, "missing.md")
+
+	display, ok := conversations.LookupMessageDisplay(thread.metadata, briefing)
+	require.True(t, ok)
+	assert.Equal(t, briefingDisplayKind, display.Kind)
+	assert.Equal(t, "Briefed from conversation parent-conv and 1 file", display.Text)
+}
+
+func TestAddRunBriefingSkipsMissingConversation(t *testing.T) {
+	ctx := setupConversationCommandStore(t)
+
+	original := runBriefingGenerator
+	t.Cleanup(func() { runBriefingGenerator = original })
+	runBriefingGenerator = func(context.Context, llmtypes.Config, string) string {
+		t.Fatal("a missing conversation must not be summarised")
+		return ""
+	}
+
+	thread := newFakeRunThread()
+	addRunBriefing(ctx, llmtypes.Config{}, thread, &RunConfig{BriefFrom: "missing"}, "task", t.TempDir())
+	assert.Empty(t, thread.userMessages, "the run starts cold when there is nothing to brief")
+}
+
+func TestReadBriefingFilesBudget(t *testing.T) {
+	cwd := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(cwd, "a.txt"), []byte(strings.Repeat("a", 60)), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(cwd, "b.txt"), []byte(strings.Repeat("b", 60)), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(cwd, "c.txt"), []byte("c"), 0o644))
+
+	files := readBriefingFiles(context.Background(), cwd, []string{"a.txt", "b.txt", "c.txt"}, 100)
+	require.Len(t, files, 2)
+	assert.False(t, files[0].Truncated)
+	assert.True(t, files[1].Truncated)
+	assert.Equal(t, strings.Repeat("b", 40)+"…", files[1].Content)
+}
+
+func TestTruncateBriefingTranscript(t *testing.T) {
+	assert.Equal(t, "short", truncateBriefingTranscript("short"))
+
+	transcript := "START" + strings.Repeat("x", briefingMaxTranscriptBytes) + "END"
+	truncated := truncateBriefingTranscript(transcript)
+	assert.True(t, strings.HasPrefix(truncated, "START"))
+	assert.True(t, strings.HasSuffix(truncated, "END"))
+	assert.Contains(t, truncated, "[... middle of the conversation omitted ...]")
+	assert.Less(t, len(truncated), briefingMaxTranscriptBytes+100)
+}
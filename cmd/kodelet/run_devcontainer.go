@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/jingkaihe/kodelet/pkg/devcontainer"
+	"github.com/jingkaihe/kodelet/pkg/presenter"
+	"github.com/pkg/errors"
+)
+
+// startRunDevContainer starts the workspace's dev container for
+// --in-devcontainer. Without the flag it only points out a dev container
+// configuration the run could use, and returns nil.
+func startRunDevContainer(ctx context.Context, config *RunConfig, cwd string) (*devcontainer.Container, error) {
+	if !config.InDevContainer {
+		if path := devcontainer.Find(cwd); path != "" && !config.Headless && !config.ResultOnly {
+			rel, err := filepath.Rel(cwd, path)
+			if err != nil {
+				rel = path
+			}
+			presenter.Info(fmt.Sprintf("Found %s; pass --in-devcontainer to run bash and verification commands inside the dev container", rel))
+		}
+		return nil, nil
+	}
+
+	devConfig, err := devcontainer.Load(cwd)
+	if err != nil {
+		return nil, err
+	}
+	if !config.Headless && !config.ResultOnly {
+		presenter.Info(fmt.Sprintf("Starting dev container for %s", cwd))
+	}
+	container, err := devcontainer.Start(ctx, devConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start the dev container")
+	}
+	return container, nil
+}
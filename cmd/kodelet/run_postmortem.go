@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jingkaihe/kodelet/pkg/llm"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/presenter"
+	"github.com/jingkaihe/kodelet/pkg/tools"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+)
+
+// Reasons a run gets a post-mortem, recorded in summary.json.
+const (
+	postMortemVerificationFailed = "verification_failed"
+	postMortemBudgetExceeded     = "budget_exceeded"
+	postMortemMaxTurns           = "max_turns"
+)
+
+// postMortemMetadataKey is the conversation metadata key holding the
+// post-mortem of the run that last stopped unsuccessfully.
+const postMortemMetadataKey = "post_mortem"
+
+// Limits on the transcript sent to the weak model.
+const (
+	postMortemMaxMessages     = 20
+	postMortemMaxMessageChars = 1500
+	postMortemMaxCommands     = 20
+)
+
+// RunPostMortem explains why a run stopped before finishing its task, so a
+// person can pick the work up from where the agent left it.
+type RunPostMortem struct {
+	Reason    string   `json:"reason"`
+	Detail    string   `json:"detail,omitempty"`
+	Attempted string   `json:"attempted"`
+	Blockers  []string `json:"blockers"`
+	NextSteps []string `json:"next_steps"`
+}
+
+// runPostMortemGenerator sends the post-mortem prompt to the weak model and
+// returns its reply. It is replaced in tests.
+var runPostMortemGenerator = func(ctx context.Context, llmConfig llmtypes.Config, prompt string) string {
+	state := tools.NewBasicState(ctx, tools.WithLLMConfig(llmConfig))
+	out, _ := llm.SendMessageAndGetTextWithUsage(ctx, state, prompt, llmConfig, true, llmtypes.MessageOpt{
+		UseWeakModel:       true,
+		NoToolUse:          true,
+		DisableUsageLog:    true,
+		NoSaveConversation: true,
+	})
+	return out
+}
+
+// runPostMortemReason returns why a finished run needs a post-mortem and the
+// details of the failure, or "" when the run did not stop early.
+func runPostMortemReason(thread llmtypes.Thread, summary *RunSummary, runErr error, maxTurns int) (string, string) {
+	var budgetErr *llmtypes.BudgetExceededError
+	switch {
+	case errors.As(runErr, &budgetErr):
+		return postMortemBudgetExceeded, budgetErr.Error()
+	case summary.Verification != nil && !summary.Verification.Passed:
+		return postMortemVerificationFailed, summary.Verification.Error
+	case runErr != nil:
+		return "", ""
+	}
+	if limited, ok := thread.(interface{ MaxTurnsReached() bool }); ok && limited.MaxTurnsReached() {
+		return postMortemMaxTurns, fmt.Sprintf("stopped after the maximum of %d turns", maxTurns)
+	}
+	return "", ""
+}
+
+// recordRunPostMortem generates a post-mortem when the finished run stopped
+// before completing its task, and stores it in the run summary and the
+// conversation metadata. Failures are logged rather than returned, since the
+// run has already failed.
+func recordRunPostMortem(ctx context.Context, llmConfig llmtypes.Config, thread llmtypes.Thread, summary *RunSummary, query string, maxTurns int, runErr error) *RunPostMortem {
+	reason, detail := runPostMortemReason(thread, summary, runErr, maxTurns)
+	if reason == "" {
+		return nil
+	}
+
+	messages, err := thread.GetMessages()
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to read messages for the run post-mortem")
+	}
+
+	prompt := runPostMortemPrompt(query, reason, detail, summary, messages)
+	postMortem, err := parseRunPostMortem(runPostMortemGenerator(ctx, llmConfig, prompt))
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to generate the run post-mortem")
+		return nil
+	}
+	postMortem.Reason = reason
+	postMortem.Detail = detail
+
+	summary.PostMortem = postMortem
+	thread.SetMetadataValue(postMortemMetadataKey, postMortem)
+	if thread.IsPersisted() {
+		if err := thread.SaveConversation(ctx, false); err != nil {
+			logger.G(ctx).WithError(err).Warn("failed to save the run post-mortem to the conversation")
+		}
+	}
+	return postMortem
+}
+
+// runPostMortemPrompt asks for a JSON post-mortem of the run, giving the task,
+// why it stopped, what changed and the end of the transcript.
+func runPostMortemPrompt(query, reason, detail string, summary *RunSummary, messages []llmtypes.Message) string {
+	var b strings.Builder
+	b.WriteString(`An autonomous coding agent stopped before finishing its task. Write a post-mortem that lets a developer take over the work.
+
+Respond with only a JSON object with these fields:
+- "attempted": one or two sentences on what the agent did towards the task
+- "blockers": what stopped it or is still broken, most important first
+- "next_steps": concrete actions for the developer, most important first
+
+Base the post-mortem only on the information below. Keep each item to one sentence.
+`)
+	fmt.Fprintf(&b, "\n## Task\n%s\n", truncatePostMortemText(strings.TrimSpace(query), 4*postMortemMaxMessageChars))
+	fmt.Fprintf(&b, "\n## Why the run stopped\n%s", strings.ReplaceAll(reason, "_", " "))
+	if detail != "" {
+		fmt.Fprintf(&b, ": %s", detail)
+	}
+	b.WriteString("\n")
+
+	if summary != nil && len(summary.FilesChanged) > 0 {
+		fmt.Fprintf(&b, "\n## Files changed\n%s\n", strings.Join(summary.FilesChanged, "\n"))
+	}
+	if summary != nil && len(summary.Commands) > 0 {
+		b.WriteString("\n## Last commands run\n")
+		commands := summary.Commands[max(len(summary.Commands)-postMortemMaxCommands, 0):]
+		for _, command := range commands {
+			fmt.Fprintf(&b, "- `%s` (exit code %d)\n", truncatePostMortemText(command.Command, 200), command.ExitCode)
+		}
+	}
+	if summary != nil && summary.Verification != nil && !summary.Verification.Passed {
+		fmt.Fprintf(&b, "\n## Verification\n`%s` failed: %s\n", summary.Verification.Command, summary.Verification.Error)
+	}
+
+	if len(messages) > 0 {
+		b.WriteString("\n## End of the transcript\n")
+		for _, message := range messages[max(len(messages)-postMortemMaxMessages, 0):] {
+			content := strings.TrimSpace(message.Content)
+			if content == "" {
+				continue
+			}
+			fmt.Fprintf(&b, "\n[%s]\n%s\n", message.Role, truncatePostMortemText(content, postMortemMaxMessageChars))
+		}
+	}
+	return b.String()
+}
+
+// parseRunPostMortem extracts the JSON post-mortem from the model's reply,
+// which may wrap it in a code fence or prose.
+func parseRunPostMortem(out string) (*RunPostMortem, error) {
+	start := strings.Index(out, "{")
+	end := strings.LastIndex(out, "}")
+	if start < 0 || end < start {
+		return nil, errors.Errorf("post-mortem reply is not a JSON object: %s", truncatePostMortemText(out, 200))
+	}
+
+	var postMortem RunPostMortem
+	if err := json.Unmarshal([]byte(out[start:end+1]), &postMortem); err != nil {
+		return nil, errors.Wrap(err, "failed to parse post-mortem")
+	}
+	postMortem.Attempted = strings.TrimSpace(postMortem.Attempted)
+	if postMortem.Attempted == "" {
+		return nil, errors.New("post-mortem does not say what was attempted")
+	}
+	if postMortem.Blockers == nil {
+		postMortem.Blockers = []string{}
+	}
+	if postMortem.NextSteps == nil {
+		postMortem.NextSteps = []string{}
+	}
+	return &postMortem, nil
+}
+
+// displayRunPostMortem prints the post-mortem after the run's output.
+func displayRunPostMortem(postMortem *RunPostMortem) {
+	if postMortem == nil {
+		return
+	}
+	presenter.Section("Post-mortem")
+	presenter.Info(postMortem.Attempted)
+	for _, blocker := range postMortem.Blockers {
+		presenter.Warning(blocker)
+	}
+	for i, step := range postMortem.NextSteps {
+		presenter.Info(fmt.Sprintf("%d. %s", i+1, step))
+	}
+}
+
+func truncatePostMortemText(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "…"
+}
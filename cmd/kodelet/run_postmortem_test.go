@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type maxTurnsRunThread struct {
+	*fakeRunThread
+	reached bool
+}
+
+func (t *maxTurnsRunThread) MaxTurnsReached() bool { return t.reached }
+
+func TestRunPostMortemReason(t *testing.T) {
+	budgetErr := &llmtypes.BudgetExceededError{Limit: "cost", CostUSD: 2, MaxCostUSD: 2, Turns: 7}
+	reason, detail := runPostMortemReason(newFakeRunThread(), &RunSummary{}, budgetErr, 0)
+	assert.Equal(t, postMortemBudgetExceeded, reason)
+	assert.Contains(t, detail, "cost budget exceeded after 7 turns")
+
+	summary := &RunSummary{Verification: &RunSummaryVerification{Command: "make test", Error: "exit status 2"}}
+	reason, detail = runPostMortemReason(newFakeRunThread(), summary, errors.New("verification failed"), 0)
+	assert.Equal(t, postMortemVerificationFailed, reason)
+	assert.Equal(t, "exit status 2", detail)
+
+	reason, detail = runPostMortemReason(&maxTurnsRunThread{fakeRunThread: newFakeRunThread(), reached: true}, &RunSummary{}, nil, 30)
+	assert.Equal(t, postMortemMaxTurns, reason)
+	assert.Equal(t, "stopped after the maximum of 30 turns", detail)
+
+	reason, _ = runPostMortemReason(&maxTurnsRunThread{fakeRunThread: newFakeRunThread()}, &RunSummary{}, nil, 30)
+	assert.Empty(t, reason, "finished runs get no post-mortem")
+	reason, _ = runPostMortemReason(newFakeRunThread(), &RunSummary{}, errors.New("provider unavailable"), 0)
+	assert.Empty(t, reason, "other errors get no post-mortem")
+}
+
+func TestRunPostMortemPrompt(t *testing.T) {
+	summary := &RunSummary{
+		FilesChanged: []string{"pkg/queue/queue.go"},
+		Commands: []RunSummaryCommand{
+			{Command: "go test ./pkg/queue", ExitCode: 1},
+		},
+		Verification: &RunSummaryVerification{Command: "make test", Error: "exit status 2"},
+	}
+	messages := []llmtypes.Message{
+		{Role: "user", Content: "Fix the flaky queue test"},
+		{Role: "assistant", Content: ""},
+		{Role: "assistant", Content: strings.Repeat("x", postMortemMaxMessageChars+10)},
+	}
+
+	prompt := runPostMortemPrompt("Fix the flaky queue test", postMortemVerificationFailed, "exit status 2", summary, messages)
+	assert.Contains(t, prompt, "## Task\nFix the flaky queue test")
+	assert.Contains(t, prompt, "## Why the run stopped\nverification failed: exit status 2")
+	assert.Contains(t, prompt, "## Files changed\npkg/queue/queue.go")
+	assert.Contains(t, prompt, "- `go test ./pkg/queue` (exit code 1)")
+	assert.Contains(t, prompt, "`make test` failed: exit status 2")
+	assert.Contains(t, prompt, "[assistant]\n"+strings.Repeat("x", postMortemMaxMessageChars)+"…")
+	assert.Equal(t, 2, strings.Count(prompt, "[assistant]")+strings.Count(prompt, "[user]"), "empty messages are skipped")
+}
+
+func TestParseRunPostMortem(t *testing.T) {
+	postMortem, err := parseRunPostMortem("Here it is:\n```json\n{\"attempted\": \" Rewrote the retry loop. \", \"blockers\": [\"TestRetry still times out\"]}\n```")
+	require.NoError(t, err)
+	assert.Equal(t, "Rewrote the retry loop.", postMortem.Attempted)
+	assert.Equal(t, []string{"TestRetry still times out"}, postMortem.Blockers)
+	assert.Equal(t, []string{}, postMortem.NextSteps)
+
+	_, err = parseRunPostMortem("Error: rate limited")
+	require.Error(t, err)
+	_, err = parseRunPostMortem(`{"blockers": []}`)
+	require.Error(t, err)
+}
+
+func TestRecordRunPostMortem(t *testing.T) {
+	original := runPostMortemGenerator
+	t.Cleanup(func() { runPostMortemGenerator = original })
+
+	var prompt string
+	runPostMortemGenerator = func(_ context.Context, _ llmtypes.Config, p string) string {
+		prompt = p
+		return `{"attempted": "Rewrote the retry loop.", "blockers": ["TestRetry times out"], "next_steps": ["Run go test -run TestRetry -v"]}`
+	}
+
+	thread := &maxTurnsRunThread{fakeRunThread: newFakeRunThread(), reached: true}
+	summary := &RunSummary{}
+	postMortem := recordRunPostMortem(context.Background(), llmtypes.Config{}, thread, summary, "Fix the flaky test", 10, nil)
+	require.NotNil(t, postMortem)
+	assert.Contains(t, prompt, "Fix the flaky test")
+	assert.Equal(t, postMortemMaxTurns, postMortem.Reason)
+	assert.Equal(t, []string{"Run go test -run TestRetry -v"}, postMortem.NextSteps)
+	assert.Same(t, postMortem, summary.PostMortem)
+	assert.Same(t, postMortem, thread.metadata[postMortemMetadataKey])
+
+	runPostMortemGenerator = func(context.Context, llmtypes.Config, string) string {
+		t.Fatal("finished runs must not generate a post-mortem")
+		return ""
+	}
+	assert.Nil(t, recordRunPostMortem(context.Background(), llmtypes.Config{}, newFakeRunThread(), &RunSummary{}, "query", 10, nil))
+}
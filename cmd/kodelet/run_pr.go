@@ -0,0 +1,402 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/devcontainer"
+	"github.com/jingkaihe/kodelet/pkg/fragments"
+	"github.com/jingkaihe/kodelet/pkg/gitlab"
+	"github.com/jingkaihe/kodelet/pkg/llm"
+	"github.com/jingkaihe/kodelet/pkg/presenter"
+	"github.com/jingkaihe/kodelet/pkg/tools"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+)
+
+// runPROptions describes the branch, commit and pull request pipeline that
+// `kodelet run --pr` performs after a successful run.
+type runPROptions struct {
+	Target string
+	Draft  bool
+	Verify string
+	// DevContainer, when set, runs Verify inside the dev container.
+	DevContainer   *devcontainer.Container
+	CWD            string
+	ConversationID string
+	Persisted      bool
+	Usage          llmtypes.Usage
+	// Summary, when set, records the outcome of the Verify command.
+	Summary *RunSummary
+	// RecipeName is the recipe the run used, if any.
+	RecipeName string
+	// Templates customise the commit message and pull request body.
+	Templates runPRTemplates
+	// Scopes are the GitHub token scopes. Without pull request access the
+	// pull request is drafted to a file instead.
+	Scopes githubTokenScopes
+	// Forge selects GitHub or GitLab, where a merge request is opened.
+	Forge forgeConfig
+}
+
+// runPRResult is the outcome of the pull request pipeline: the pull request
+// URL, or the draft file written when the token cannot open pull requests.
+type runPRResult struct {
+	URL       string
+	DraftPath string
+	Branch    string
+	Title     string
+	// Pipeline is the GitLab CI pipeline started by the push, if any.
+	Pipeline *gitlab.Pipeline
+}
+
+// validateRunPRPrerequisites checks that the pipeline can run before any
+// model calls are made, so a misconfigured environment fails fast. The working
+// tree must be clean, as the pipeline commits every change in it.
+func validateRunPRPrerequisites(ctx context.Context, cwd string, forge forgeConfig) error {
+	processCWD, err := os.Getwd()
+	if err != nil {
+		return errors.Wrap(err, "failed to get current working directory")
+	}
+	if filepath.Clean(processCWD) != filepath.Clean(cwd) {
+		return errors.Errorf("--pr must be run from the conversation working directory %s", cwd)
+	}
+	if !isGitRepository() {
+		return errors.New("--pr requires a git repository")
+	}
+	status, err := runGit(ctx, cwd, "status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(status) != "" {
+		return errors.New("--pr requires a clean working tree; commit or stash the existing changes first")
+	}
+	if forge.Provider == forgeGitLab {
+		if err := checkGitLabCLI(forge.Host); err != nil {
+			return errors.Wrap(err, "--pr requires the GitLab CLI")
+		}
+	} else {
+		if !isGhCliInstalled() {
+			return errors.New("--pr requires the GitHub CLI (gh); see https://cli.github.com/")
+		}
+		if !isGhAuthenticated() {
+			return errors.New("--pr requires GitHub authentication; run 'gh auth login' first")
+		}
+	}
+	if _, err := loadRunPRTemplates(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// createRunPR verifies the run, then branches, commits, pushes and opens a pull
+// request for the working tree changes.
+func createRunPR(ctx context.Context, llmConfig llmtypes.Config, opts runPROptions) (result runPRResult, err error) {
+	if strings.TrimSpace(opts.Verify) != "" {
+		presenter.Info(fmt.Sprintf("Verifying changes: %s", opts.Verify))
+		started := time.Now()
+		err := runVerifyCommand(ctx, opts.DevContainer, opts.CWD, opts.Verify)
+		if opts.Summary != nil {
+			opts.Summary.Verification = newRunSummaryVerification(opts.Verify, err, time.Since(started))
+		}
+		if err != nil {
+			return runPRResult{}, errors.Wrap(err, "verification failed; no branch or pull request was created")
+		}
+	}
+
+	status, err := runGit(ctx, opts.CWD, "status", "--porcelain")
+	if err != nil {
+		return runPRResult{}, err
+	}
+	if strings.TrimSpace(status) == "" {
+		return runPRResult{}, errors.New("the run made no changes to commit")
+	}
+
+	original := currentRunPRCheckout(ctx, opts.CWD)
+	branch := runPRBranchName(opts.ConversationID, time.Now())
+	if _, err := runGit(ctx, opts.CWD, "checkout", "-b", branch); err != nil {
+		return runPRResult{}, err
+	}
+	pushed := false
+	defer func() {
+		if err != nil {
+			restoreRunPRCheckout(context.WithoutCancel(ctx), opts.CWD, original, branch, pushed)
+		}
+	}()
+	if _, err := runGit(ctx, opts.CWD, "add", "-A"); err != nil {
+		return runPRResult{}, err
+	}
+
+	data := newRunPRTemplateData(opts, runPRConversationSummary(ctx, opts), branch)
+
+	state := tools.NewBasicState(ctx, tools.WithLLMConfig(llmConfig))
+	commitConfig := NewCommitConfig()
+	commitMsg, _, err := generateCommitMessage(ctx, state, llmConfig, commitConfig)
+	if err != nil {
+		return runPRResult{}, err
+	}
+	data.CommitMessage = strings.TrimSpace(commitMsg)
+	if commitMsg, err = renderRunPRTemplate(opts.Templates.Commit, data, commitMsg); err != nil {
+		return runPRResult{}, err
+	}
+	if err := createCommit(commitMsg, !commitConfig.NoSign); err != nil {
+		return runPRResult{}, errors.Wrap(err, "failed to create commit")
+	}
+
+	if _, err := runGit(ctx, opts.CWD, "push", "-u", "origin", branch); err != nil {
+		return runPRResult{}, err
+	}
+	pushed = true
+	if _, err := runGit(ctx, opts.CWD, "fetch", "origin", opts.Target); err != nil {
+		return runPRResult{}, err
+	}
+
+	title, description, err := generatePRDescription(ctx, llmConfig, opts.Target)
+	if err != nil {
+		return runPRResult{}, err
+	}
+	data.Title, data.Description = title, description
+	body, err := renderRunPRTemplate(opts.Templates.Body, data, formatRunPRBody(description, opts.ConversationID, data.ConversationSummary))
+	if err != nil {
+		return runPRResult{}, err
+	}
+
+	if opts.Forge.Provider == forgeGitLab {
+		return openRunMergeRequest(ctx, newGitLabClient(opts.Forge, opts.CWD), opts, branch, title, body)
+	}
+	if !opts.Scopes.Allows(githubCapabilityPullRequests) {
+		return draftRunPR(opts.CWD, branch, title, body)
+	}
+	args := []string{"pr", "create", "--base", opts.Target, "--head", branch, "--title", title, "--body", body}
+	if opts.Draft {
+		args = append(args, "--draft")
+	}
+	url, err := runGH(ctx, opts.CWD, args...)
+	if err = wrapGitHubPermissionError(err, githubCapabilityPullRequests); err != nil {
+		var permissionErr *githubPermissionError
+		if errors.As(err, &permissionErr) {
+			presenter.Warning(err.Error())
+			return draftRunPR(opts.CWD, branch, title, body)
+		}
+		return runPRResult{}, err
+	}
+	url = strings.TrimSpace(url)
+
+	if _, err := runGH(ctx, opts.CWD, "pr", "comment", url, "--body", formatRunPRCostComment(opts.Usage)); err != nil {
+		presenter.Warning(fmt.Sprintf("Failed to post conversation cost to the pull request: %v", wrapGitHubPermissionError(err, githubCapabilityPullRequests)))
+	}
+	return runPRResult{URL: url, Branch: branch}, nil
+}
+
+// runPRCheckout is what was checked out before --pr created its branch.
+type runPRCheckout struct {
+	// Ref is the branch name, or the commit when HEAD was detached.
+	Ref string
+	// Commit is empty when the repository has no commits yet.
+	Commit string
+}
+
+func currentRunPRCheckout(ctx context.Context, cwd string) runPRCheckout {
+	commit, _ := runGit(ctx, cwd, "rev-parse", "--verify", "--quiet", "HEAD")
+	checkout := runPRCheckout{Commit: strings.TrimSpace(commit)}
+	if ref, err := runGit(ctx, cwd, "symbolic-ref", "--quiet", "--short", "HEAD"); err == nil {
+		checkout.Ref = strings.TrimSpace(ref)
+	} else {
+		checkout.Ref = checkout.Commit
+	}
+	return checkout
+}
+
+// restoreRunPRCheckout puts the user back on the checkout they started from
+// after --pr fails. An unpushed branch is deleted and its changes are left
+// uncommitted in the working tree, as they were before the run; a pushed
+// branch is kept, since it holds the only copy of the changes.
+func restoreRunPRCheckout(ctx context.Context, cwd string, original runPRCheckout, branch string, pushed bool) {
+	if original.Ref == "" || original.Commit == "" {
+		presenter.Warning(fmt.Sprintf("The changes are on branch %s", branch))
+		return
+	}
+	if !pushed {
+		if _, err := runGit(ctx, cwd, "reset", "--quiet", original.Commit); err != nil {
+			presenter.Warning(fmt.Sprintf("Failed to restore the working tree; the changes are on branch %s: %v", branch, err))
+			return
+		}
+	}
+	if _, err := runGit(ctx, cwd, "checkout", "--quiet", original.Ref); err != nil {
+		presenter.Warning(fmt.Sprintf("Failed to check out %s again; the changes are on branch %s: %v", original.Ref, branch, err))
+		return
+	}
+	if pushed {
+		presenter.Warning(fmt.Sprintf("The changes were pushed to branch %s", branch))
+		return
+	}
+	if _, err := runGit(ctx, cwd, "branch", "-D", branch); err != nil {
+		presenter.Warning(fmt.Sprintf("Failed to delete branch %s: %v", branch, err))
+	}
+}
+
+// openRunMergeRequest opens a GitLab merge request for the pushed branch,
+// posts the conversation cost on it and looks up the CI pipeline of the push.
+func openRunMergeRequest(ctx context.Context, client *gitlab.Client, opts runPROptions, branch, title, body string) (runPRResult, error) {
+	mr, err := client.CreateMergeRequest(ctx, "", gitlab.MergeRequestOptions{
+		SourceBranch: branch,
+		TargetBranch: opts.Target,
+		Title:        title,
+		Description:  body,
+		Draft:        opts.Draft,
+	})
+	if err != nil {
+		return runPRResult{}, err
+	}
+	if err := client.CreateMergeRequestNote(ctx, "", mr.IID, formatRunPRCostComment(opts.Usage)); err != nil {
+		presenter.Warning(fmt.Sprintf("Failed to post conversation cost to the merge request: %v", err))
+	}
+
+	result := runPRResult{URL: mr.WebURL, Branch: branch, Title: title}
+	if pipeline, ok, err := client.LatestPipeline(ctx, "", branch); err != nil {
+		presenter.Warning(fmt.Sprintf("Failed to look up the CI pipeline: %v", err))
+	} else if ok {
+		result.Pipeline = &pipeline
+	}
+	return result, nil
+}
+
+// draftRunPR writes the pull request body to a file, for opening the pull
+// request by hand from the pushed branch.
+func draftRunPR(cwd, branch, title, body string) (runPRResult, error) {
+	path, err := githubPRDraftPath(cwd, branch)
+	if err != nil {
+		return runPRResult{}, err
+	}
+	if err := os.WriteFile(path, []byte(body+"\n"), 0o644); err != nil {
+		return runPRResult{}, errors.Wrap(err, "failed to write pull request draft")
+	}
+	return runPRResult{DraftPath: path, Branch: branch, Title: title}, nil
+}
+
+// createCommand returns the gh command that opens a drafted pull request.
+func (r runPRResult) createCommand(target string) string {
+	return fmt.Sprintf("gh pr create --base %s --head %s --title %q --body-file %s", target, r.Branch, r.Title, r.DraftPath)
+}
+
+// runPRBranchName derives the branch name from the conversation ID, falling
+// back to a timestamp for runs without one.
+func runPRBranchName(conversationID string, now time.Time) string {
+	name := strings.ToLower(strings.TrimSpace(conversationID))
+	if name == "" {
+		name = now.UTC().Format("20060102T150405")
+	}
+	return "kodelet/" + name
+}
+
+func generatePRDescription(ctx context.Context, llmConfig llmtypes.Config, target string) (string, string, error) {
+	processor, err := fragments.NewFragmentProcessor()
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to create fragment processor")
+	}
+	fragment, err := processor.LoadFragment(ctx, &fragments.Config{
+		FragmentName: "github/pr-description",
+		Arguments:    map[string]string{"target": target},
+	})
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to load built-in pr-description recipe")
+	}
+
+	state := tools.NewBasicState(ctx, tools.WithLLMConfig(llmConfig))
+	out, _ := llm.SendMessageAndGetTextWithUsage(ctx, state, fragment.Content, llmConfig, true, llmtypes.MessageOpt{
+		UseWeakModel:       true,
+		NoToolUse:          true,
+		DisableUsageLog:    true,
+		NoSaveConversation: true,
+	})
+	title, description := splitPRDescription(sanitizeCommitMessage(out))
+	if title == "" {
+		return "", "", errors.New("failed to generate a pull request title")
+	}
+	return title, description, nil
+}
+
+// splitPRDescription splits generated output into the title on the first line
+// and the body on the remaining lines.
+func splitPRDescription(out string) (string, string) {
+	out = strings.TrimSpace(out)
+	title, body, _ := strings.Cut(out, "\n")
+	return strings.TrimSpace(strings.TrimPrefix(title, "# ")), strings.TrimSpace(body)
+}
+
+func runPRConversationSummary(ctx context.Context, opts runPROptions) string {
+	if !opts.Persisted || opts.ConversationID == "" {
+		return ""
+	}
+	store, err := conversations.GetConversationStore(ctx)
+	if err != nil {
+		return ""
+	}
+	defer func() {
+		_ = store.Close()
+	}()
+	record, err := store.Load(ctx, opts.ConversationID)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(record.Summary)
+}
+
+func formatRunPRBody(description, conversationID, summary string) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(description))
+	if conversationID == "" {
+		return b.String()
+	}
+	b.WriteString("\n\n## Conversation\n")
+	fmt.Fprintf(&b, "Created by `kodelet run` in conversation `%s`.", conversationID)
+	if summary != "" {
+		fmt.Fprintf(&b, "\n\n> %s", summary)
+	}
+	fmt.Fprintf(&b, "\n\nResume with `kodelet run --resume %s`.", conversationID)
+	return b.String()
+}
+
+func formatRunPRCostComment(usage llmtypes.Usage) string {
+	return fmt.Sprintf("Conversation cost: $%.4f (input $%.4f, output $%.4f, cache write $%.4f, cache read $%.4f; %d tokens)",
+		usage.TotalCost(), usage.InputCost, usage.OutputCost, usage.CacheCreationCost, usage.CacheReadCost, usage.TotalTokens())
+}
+
+func runVerifyCommand(ctx context.Context, container *devcontainer.Container, dir, command string) error {
+	var cmd *exec.Cmd
+	if container != nil {
+		cmd = container.Command(ctx, dir, command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	return runPRCommand(ctx, dir, "git", args...)
+}
+
+func runGH(ctx context.Context, dir string, args ...string) (string, error) {
+	return runPRCommand(ctx, dir, "gh", args...)
+}
+
+func runPRCommand(ctx context.Context, dir, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "%s %s failed: %s", name, args[0], strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// runPRTemplates are the commit message and pull request body templates that
+// `kodelet run --pr` renders instead of its own format. Nil templates keep
+// the default.
+type runPRTemplates struct {
+	Commit *template.Template
+	Body   *template.Template
+	// WebUIURL is the base URL of a shared `kodelet serve`, used to link the
+	// conversation.
+	WebUIURL string
+}
+
+// runPRTemplateData holds the variables available to pr.commit_template and
+// pr.body_template.
+type runPRTemplateData struct {
+	ConversationID      string
+	ConversationSummary string
+	// ConversationURL links the conversation in the web UI at pr.web_ui_url.
+	ConversationURL string
+	// ResumeCommand continues the conversation from the command line.
+	ResumeCommand string
+	// Cost is the total conversation cost formatted in dollars, such as $0.1234.
+	Cost       string
+	CostUSD    float64
+	Tokens     int
+	RecipeName string
+	// Verification is "passed" when the --verify command succeeded and
+	// "skipped" without one; a failed verification creates no pull request.
+	Verification  string
+	VerifyCommand string
+	Branch        string
+	Target        string
+	// CommitMessage is the generated commit message.
+	CommitMessage string
+	// Title and Description are the generated pull request title and body,
+	// set for pr.body_template only.
+	Title       string
+	Description string
+}
+
+// loadRunPRTemplates parses the pr.commit_template and pr.body_template
+// settings, so a broken template fails the run before any model calls.
+func loadRunPRTemplates() (runPRTemplates, error) {
+	templates := runPRTemplates{WebUIURL: strings.TrimRight(strings.TrimSpace(viper.GetString("pr.web_ui_url")), "/")}
+	var err error
+	if templates.Commit, err = parseRunPRTemplate("pr.commit_template"); err != nil {
+		return runPRTemplates{}, err
+	}
+	if templates.Body, err = parseRunPRTemplate("pr.body_template"); err != nil {
+		return runPRTemplates{}, err
+	}
+	return templates, nil
+}
+
+func parseRunPRTemplate(key string) (*template.Template, error) {
+	text := viper.GetString(key)
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(key).Parse(text)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", key)
+	}
+	// Unknown variables only show up when the template runs.
+	if err := tmpl.Execute(io.Discard, runPRTemplateData{}); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", key)
+	}
+	return tmpl, nil
+}
+
+// newRunPRTemplateData collects the template variables of a run.
+func newRunPRTemplateData(opts runPROptions, summary, branch string) runPRTemplateData {
+	data := runPRTemplateData{
+		ConversationID:      opts.ConversationID,
+		ConversationSummary: summary,
+		Cost:                fmt.Sprintf("$%.4f", opts.Usage.TotalCost()),
+		CostUSD:             opts.Usage.TotalCost(),
+		Tokens:              opts.Usage.TotalTokens(),
+		RecipeName:          opts.RecipeName,
+		Verification:        "skipped",
+		VerifyCommand:       strings.TrimSpace(opts.Verify),
+		Branch:              branch,
+		Target:              opts.Target,
+	}
+	if data.VerifyCommand != "" {
+		data.Verification = "passed"
+	}
+	if opts.ConversationID != "" {
+		data.ResumeCommand = "kodelet run --resume " + opts.ConversationID
+		if opts.Templates.WebUIURL != "" {
+			data.ConversationURL = opts.Templates.WebUIURL + "/c/" + opts.ConversationID
+		}
+	}
+	return data
+}
+
+// renderRunPRTemplate renders tmpl with data, or returns fallback when tmpl is nil.
+func renderRunPRTemplate(tmpl *template.Template, data runPRTemplateData, fallback string) (string, error) {
+	if tmpl == nil {
+		return fallback, nil
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", errors.Wrapf(err, "failed to render %s", tmpl.Name())
+	}
+	rendered := strings.TrimSpace(b.String())
+	if rendered == "" {
+		return "", errors.Errorf("%s rendered an empty message", tmpl.Name())
+	}
+	return rendered, nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/fragments"
+	"github.com/jingkaihe/kodelet/pkg/gitlab"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunPRBranchName(t *testing.T) {
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	assert.Equal(t, "kodelet/20231201t120000-a1b2c3", runPRBranchName("20231201T120000-a1b2c3", now))
+	assert.Equal(t, "kodelet/20240506T070809", runPRBranchName("", now))
+}
+
+func TestSplitPRDescription(t *testing.T) {
+	title, body := splitPRDescription("\n# feat: add widgets\n\n## Description\nAdds widgets.\n")
+
+	assert.Equal(t, "feat: add widgets", title)
+	assert.Equal(t, "## Description\nAdds widgets.", body)
+}
+
+func TestFormatRunPRBody(t *testing.T) {
+	body := formatRunPRBody("## Description\nAdds widgets.", "conv-1", "Added widget support")
+
+	assert.Contains(t, body, "## Description\nAdds widgets.")
+	assert.Contains(t, body, "## Conversation")
+	assert.Contains(t, body, "`conv-1`")
+	assert.Contains(t, body, "> Added widget support")
+	assert.Contains(t, body, "kodelet run --resume conv-1")
+
+	assert.Equal(t, "## Description", formatRunPRBody("## Description", "", ""))
+}
+
+func TestRunPRTemplates(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("pr.web_ui_url", "https://kodelet.example.com/")
+	viper.Set("pr.commit_template", "{{.CommitMessage}}\n\nKodelet-Conversation: {{.ConversationURL}}")
+	viper.Set("pr.body_template", "{{.Description}}\n\nRecipe: {{.RecipeName}}, verification {{.Verification}} (`{{.VerifyCommand}}`), cost {{.Cost}}\n{{.ResumeCommand}}")
+
+	templates, err := loadRunPRTemplates()
+	require.NoError(t, err)
+
+	data := newRunPRTemplateData(runPROptions{
+		ConversationID: "conv-1",
+		Target:         "main",
+		Verify:         "make test",
+		RecipeName:     "github/fix-issue",
+		Usage:          llmtypes.Usage{InputCost: 0.1, OutputCost: 0.0234},
+		Templates:      templates,
+	}, "Added widget support", "kodelet/conv-1")
+	data.CommitMessage = "feat: add widgets"
+	data.Description = "## Description\nAdds widgets."
+
+	commit, err := renderRunPRTemplate(templates.Commit, data, "unused")
+	require.NoError(t, err)
+	assert.Equal(t, "feat: add widgets\n\nKodelet-Conversation: https://kodelet.example.com/c/conv-1", commit)
+
+	body, err := renderRunPRTemplate(templates.Body, data, "unused")
+	require.NoError(t, err)
+	assert.Equal(t, "## Description\nAdds widgets.\n\nRecipe: github/fix-issue, verification passed (`make test`), cost $0.1234\nkodelet run --resume conv-1", body)
+
+	fallback, err := renderRunPRTemplate(nil, data, "default body")
+	require.NoError(t, err)
+	assert.Equal(t, "default body", fallback)
+
+	viper.Set("pr.body_template", "{{.PullRequestNumber}}")
+	_, err = loadRunPRTemplates()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid pr.body_template")
+}
+
+func TestFormatRunPRCostComment(t *testing.T) {
+	comment := formatRunPRCostComment(llmtypes.Usage{
+		InputTokens:  100,
+		OutputTokens: 50,
+		InputCost:    0.25,
+		OutputCost:   0.5,
+	})
+
+	assert.Contains(t, comment, "Conversation cost: $0.7500")
+	assert.Contains(t, comment, "150 tokens")
+}
+
+func TestPRDescriptionFragmentContent(t *testing.T) {
+	processor, err := fragments.NewFragmentProcessor()
+	require.NoError(t, err)
+
+	fragment, err := processor.LoadFragment(context.Background(), &fragments.Config{
+		FragmentName: "github/pr-description",
+		Arguments:    map[string]string{"target": "develop"},
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, fragment.Content, "target branch develop")
+	assert.Contains(t, fragment.Content, "The first line is the pull request title")
+	assert.Contains(t, fragment.Content, "## Impact")
+}
+
+func TestCreateRunPRFailsVerificationWithoutBranching(t *testing.T) {
+	dir := t.TempDir()
+	runTestGit(t, dir, "init", "-q", "-b", "main")
+	require.NoError(t, os.WriteFile(dir+"/file.txt", []byte("changed\n"), 0o644))
+
+	_, err := createRunPR(context.Background(), llmtypes.Config{}, runPROptions{
+		Target: "main",
+		Verify: "exit 3",
+		CWD:    dir,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "verification failed")
+
+	branch := runTestGit(t, dir, "symbolic-ref", "--short", "HEAD")
+	assert.Equal(t, "main\n", branch)
+}
+
+func TestCreateRunPRRequiresChanges(t *testing.T) {
+	dir := t.TempDir()
+	runTestGit(t, dir, "init", "-q", "-b", "main")
+
+	_, err := createRunPR(context.Background(), llmtypes.Config{}, runPROptions{Target: "main", CWD: dir})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no changes")
+}
+
+func TestRestoreRunPRCheckoutDeletesUnpushedBranch(t *testing.T) {
+	dir := t.TempDir()
+	runTestGit(t, dir, "init", "-q", "-b", "main")
+	runTestGit(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "init")
+	require.NoError(t, os.WriteFile(dir+"/file.txt", []byte("changed\n"), 0o644))
+
+	ctx := context.Background()
+	original := currentRunPRCheckout(ctx, dir)
+	assert.Equal(t, "main", original.Ref)
+	runTestGit(t, dir, "checkout", "-q", "-b", "kodelet/run")
+	runTestGit(t, dir, "add", "-A")
+	runTestGit(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "change")
+
+	restoreRunPRCheckout(ctx, dir, original, "kodelet/run", false)
+
+	assert.Equal(t, "main\n", runTestGit(t, dir, "symbolic-ref", "--short", "HEAD"))
+	assert.Empty(t, runTestGit(t, dir, "branch", "--list", "kodelet/run"))
+	assert.Equal(t, "?? file.txt\n", runTestGit(t, dir, "status", "--porcelain"))
+}
+
+func TestValidateRunPRPrerequisitesRequiresCleanTree(t *testing.T) {
+	dir := t.TempDir()
+	runTestGit(t, dir, "init", "-q", "-b", "main")
+	require.NoError(t, os.WriteFile(dir+"/notes.txt", []byte("unrelated\n"), 0o644))
+	t.Chdir(dir)
+
+	err := validateRunPRPrerequisites(context.Background(), dir, forgeConfig{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "clean working tree")
+}
+
+func TestOpenRunMergeRequest(t *testing.T) {
+	var calls []string
+	client := gitlab.NewClient(func(_ context.Context, args ...string) (string, error) {
+		call := strings.Join(args, " ")
+		calls = append(calls, call)
+		switch {
+		case strings.Contains(call, "/merge_requests/12/notes"):
+			return "{}", nil
+		case strings.Contains(call, "/merge_requests"):
+			return `{"iid": 12, "web_url": "https://gitlab.example.com/o/r/-/merge_requests/12"}`, nil
+		case strings.Contains(call, "/pipelines"):
+			return `[{"id": 77, "status": "pending", "web_url": "https://gitlab.example.com/o/r/-/pipelines/77"}]`, nil
+		}
+		return "", errors.New("unexpected glab call")
+	}, "gitlab.example.com")
+
+	result, err := openRunMergeRequest(context.Background(), client, runPROptions{
+		Target: "main",
+		Draft:  true,
+		Usage:  llmtypes.Usage{InputCost: 0.1},
+	}, "kodelet/conv-1", "feat: add widgets", "Adds widgets.")
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://gitlab.example.com/o/r/-/merge_requests/12", result.URL)
+	assert.Equal(t, "kodelet/conv-1", result.Branch)
+	require.NotNil(t, result.Pipeline)
+	assert.Equal(t, 77, result.Pipeline.ID)
+	assert.Equal(t, "pending", result.Pipeline.Status)
+
+	require.Len(t, calls, 3)
+	assert.Contains(t, calls[0], "--hostname gitlab.example.com --method POST projects/:id/merge_requests")
+	assert.Contains(t, calls[0], "--raw-field source_branch=kodelet/conv-1")
+	assert.Contains(t, calls[0], "--raw-field title=Draft: feat: add widgets")
+	assert.Contains(t, calls[1], "projects/:id/merge_requests/12/notes")
+	assert.Contains(t, calls[1], "$0.1000")
+	assert.Contains(t, calls[2], "pipelines?")
+}
+
+func runTestGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	return string(out)
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/audit"
+	"github.com/jingkaihe/kodelet/pkg/checkpoints"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/presenter"
+	"github.com/jingkaihe/kodelet/pkg/todos"
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/pkg/errors"
+)
+
+// runSummaryVersion is bumped on incompatible changes to summary.json.
+const runSummaryVersion = 1
+
+// runSummariesDir is where run summaries are written, relative to the working
+// directory.
+var runSummariesDir = filepath.Join(".kodelet", "runs")
+
+// Run statuses recorded in summary.json.
+const (
+	runStatusSuccess = "success"
+	runStatusError   = "error"
+)
+
+// RunSummary is the machine-readable record of one `kodelet run`, written to
+// .kodelet/runs/<run_id>/summary.json in the working directory.
+type RunSummary struct {
+	Version        int                     `json:"version"`
+	RunID          string                  `json:"run_id"`
+	ConversationID string                  `json:"conversation_id,omitempty"`
+	Status         string                  `json:"status"`
+	ExitCode       int                     `json:"exit_code"`
+	Error          string                  `json:"error,omitempty"`
+	StartedAt      time.Time               `json:"started_at"`
+	FinishedAt     time.Time               `json:"finished_at"`
+	DurationMS     int64                   `json:"duration_ms"`
+	Provider       string                  `json:"provider"`
+	Model          string                  `json:"model"`
+	FinalOutput    string                  `json:"final_output"`
+	FilesChanged   []string                `json:"files_changed"`
+	Commands       []RunSummaryCommand     `json:"commands"`
+	Usage          RunSummaryUsage         `json:"usage"`
+	Resources      RunSummaryResources     `json:"resources"`
+	Todos          *RunSummaryTodos        `json:"todos,omitempty"`
+	Verification   *RunSummaryVerification `json:"verification,omitempty"`
+	PostMortem     *RunPostMortem          `json:"post_mortem,omitempty"`
+	PullRequestURL string                  `json:"pull_request_url,omitempty"`
+	// PullRequestDraft is the file the pull request was drafted to when the
+	// GitHub token could not open it.
+	PullRequestDraft string `json:"pull_request_draft,omitempty"`
+	ToolCallLog      string `json:"tool_call_log,omitempty"`
+	// Checkpoints is the directory holding the file snapshots taken before
+	// each edit, used by `kodelet run undo`.
+	Checkpoints string `json:"checkpoints,omitempty"`
+
+	checkpoints *checkpoints.Store
+}
+
+// RunSummaryCommand is a shell command the agent ran with the bash tool.
+type RunSummaryCommand struct {
+	Command    string `json:"command"`
+	ExitCode   int    `json:"exit_code"`
+	DurationMS int64  `json:"duration_ms"`
+	WorkingDir string `json:"working_dir,omitempty"`
+}
+
+// RunSummaryUsage is the token usage and cost of the run, subagents included.
+type RunSummaryUsage struct {
+	InputTokens      int     `json:"input_tokens"`
+	OutputTokens     int     `json:"output_tokens"`
+	CacheWriteTokens int     `json:"cache_write_tokens"`
+	CacheReadTokens  int     `json:"cache_read_tokens"`
+	TotalCost        float64 `json:"total_cost"`
+}
+
+// RunSummaryResources is the CPU, memory and wall time used by the
+// subprocesses of the run's tools, background jobs included.
+type RunSummaryResources struct {
+	Processes       int   `json:"processes"`
+	WallTimeMS      int64 `json:"wall_time_ms"`
+	UserCPUTimeMS   int64 `json:"user_cpu_time_ms"`
+	SystemCPUTimeMS int64 `json:"system_cpu_time_ms"`
+	PeakMemoryBytes int64 `json:"peak_memory_bytes"`
+}
+
+func newRunSummaryResources(usage tooltypes.ResourceUsage) RunSummaryResources {
+	return RunSummaryResources{
+		Processes:       usage.Processes,
+		WallTimeMS:      usage.WallTime.Milliseconds(),
+		UserCPUTimeMS:   usage.UserCPUTime.Milliseconds(),
+		SystemCPUTimeMS: usage.SystemCPUTime.Milliseconds(),
+		PeakMemoryBytes: usage.PeakMemoryBytes,
+	}
+}
+
+// RunSummaryTodos is the agent's todo list when the run finished.
+type RunSummaryTodos struct {
+	Completed  int          `json:"completed"`
+	InProgress int          `json:"in_progress"`
+	Pending    int          `json:"pending"`
+	Cancelled  int          `json:"cancelled"`
+	Items      []todos.Item `json:"items"`
+}
+
+// newRunSummaryTodos returns nil when the agent kept no todo list.
+func newRunSummaryTodos(metadata map[string]any) *RunSummaryTodos {
+	list, ok := todos.FromMetadata(metadata)
+	if !ok {
+		return nil
+	}
+	progress := list.Progress()
+	return &RunSummaryTodos{
+		Completed:  progress.Completed,
+		InProgress: progress.InProgress,
+		Pending:    progress.Pending,
+		Cancelled:  progress.Cancelled,
+		Items:      list.Items,
+	}
+}
+
+// RunSummaryVerification is the outcome of the --verify command.
+type RunSummaryVerification struct {
+	Command    string `json:"command"`
+	Passed     bool   `json:"passed"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// newRunSummary starts the summary of a run. The run shares its ID with the
+// tool call audit log when auditing is on, so the two can be correlated.
+func newRunSummary(llmConfig llmtypes.Config, conversationID string, startedAt time.Time) *RunSummary {
+	summary := &RunSummary{
+		Version:        runSummaryVersion,
+		RunID:          convtypes.GenerateID(),
+		ConversationID: conversationID,
+		StartedAt:      startedAt.UTC(),
+		Provider:       llmConfig.Provider,
+		Model:          llmConfig.Model,
+		FilesChanged:   []string{},
+		Commands:       []RunSummaryCommand{},
+	}
+	if path := audit.ToolCallLogPath(); path != "" && !audit.IsSubagent() {
+		summary.RunID = strings.TrimSuffix(filepath.Base(path), ".jsonl")
+		summary.ToolCallLog = path
+	}
+	return summary
+}
+
+// finish records the outcome of the run and what the agent did in thread.
+func (s *RunSummary) finish(thread llmtypes.Thread, cwd, finalOutput string, runErr error, exitCode int, finishedAt time.Time) {
+	s.FinishedAt = finishedAt.UTC()
+	s.DurationMS = s.FinishedAt.Sub(s.StartedAt).Milliseconds()
+	s.FinalOutput = finalOutput
+	s.ExitCode = exitCode
+	s.Status = runStatusSuccess
+	if runErr != nil || exitCode != 0 {
+		s.Status = runStatusError
+	}
+	if runErr != nil {
+		s.Error = runErr.Error()
+	}
+	if s.checkpoints != nil && len(s.checkpoints.Steps()) > 0 {
+		s.Checkpoints = s.checkpoints.Dir()
+	}
+	if thread == nil {
+		return
+	}
+
+	s.ConversationID = thread.GetConversationID()
+	usage := thread.GetUsage()
+	s.Usage = RunSummaryUsage{
+		InputTokens:      usage.InputTokens,
+		OutputTokens:     usage.OutputTokens,
+		CacheWriteTokens: usage.CacheCreationInputTokens,
+		CacheReadTokens:  usage.CacheReadInputTokens,
+		TotalCost:        usage.TotalCost(),
+	}
+	if reporter, ok := thread.GetState().(tooltypes.ResourceUsageReporter); ok {
+		s.Resources = newRunSummaryResources(reporter.ResourceUsage())
+	}
+	s.Todos = newRunSummaryTodos(thread.GetMetadata())
+	if reporter, ok := thread.(interface {
+		GetStructuredToolResults() map[string]tooltypes.StructuredToolResult
+	}); ok {
+		s.FilesChanged, s.Commands = collectRunToolActivity(reporter.GetStructuredToolResults(), cwd)
+	}
+}
+
+// collectRunToolActivity lists the files changed by the file tools and the
+// commands run with the bash tool, in the order they ran. Paths inside cwd
+// are made relative to it.
+func collectRunToolActivity(results map[string]tooltypes.StructuredToolResult, cwd string) ([]string, []RunSummaryCommand) {
+	ordered := make([]tooltypes.StructuredToolResult, 0, len(results))
+	for _, result := range results {
+		ordered = append(ordered, result)
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Timestamp.Before(ordered[j].Timestamp)
+	})
+
+	files := []string{}
+	commands := []RunSummaryCommand{}
+	addFile := func(path string) {
+		path = runSummaryPath(path, cwd)
+		if path != "" && !slices.Contains(files, path) {
+			files = append(files, path)
+		}
+	}
+	for _, result := range ordered {
+		var bash tooltypes.BashMetadata
+		var write tooltypes.FileWriteMetadata
+		var edit tooltypes.FileEditMetadata
+		var patch tooltypes.ApplyPatchMetadata
+		switch {
+		case tooltypes.ExtractMetadata(result.Metadata, &bash):
+			commands = append(commands, RunSummaryCommand{
+				Command:    bash.Command,
+				ExitCode:   bash.ExitCode,
+				DurationMS: bash.ExecutionTime.Milliseconds(),
+				WorkingDir: bash.WorkingDir,
+			})
+		case !result.Success:
+		case tooltypes.ExtractMetadata(result.Metadata, &write):
+			addFile(write.FilePath)
+		case tooltypes.ExtractMetadata(result.Metadata, &edit):
+			addFile(edit.FilePath)
+		case tooltypes.ExtractMetadata(result.Metadata, &patch):
+			for _, change := range patch.Changes {
+				addFile(change.Path)
+				addFile(change.MovePath)
+			}
+		}
+	}
+	return files, commands
+}
+
+func runSummaryPath(path, cwd string) string {
+	path = strings.TrimSpace(path)
+	if path == "" || cwd == "" || !filepath.IsAbs(path) {
+		return path
+	}
+	if rel, err := filepath.Rel(cwd, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return rel
+	}
+	return path
+}
+
+func newRunSummaryVerification(command string, err error, duration time.Duration) *RunSummaryVerification {
+	verification := &RunSummaryVerification{
+		Command:    command,
+		Passed:     err == nil,
+		DurationMS: duration.Milliseconds(),
+	}
+	if err != nil {
+		verification.Error = err.Error()
+	}
+	return verification
+}
+
+// writeRunSummary writes summary under cwd and returns the file path. The
+// runs directory ignores itself so summaries never end up in commits.
+func writeRunSummary(cwd string, summary *RunSummary) (string, error) {
+	runsDir := filepath.Join(cwd, runSummariesDir)
+	dir := filepath.Join(runsDir, summary.RunID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", errors.Wrap(err, "failed to create run summary directory")
+	}
+	gitignore := filepath.Join(runsDir, ".gitignore")
+	if _, err := os.Stat(gitignore); os.IsNotExist(err) {
+		if err := os.WriteFile(gitignore, []byte("*\n"), 0o644); err != nil {
+			return "", errors.Wrap(err, "failed to write run summary .gitignore")
+		}
+	}
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode run summary")
+	}
+	path := filepath.Join(dir, "summary.json")
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", errors.Wrap(err, "failed to write run summary")
+	}
+	return path, nil
+}
+
+// saveRunSummary writes summary and prints its path. Quiet modes print the
+// path to stderr so stdout stays machine-readable.
+func saveRunSummary(ctx context.Context, cwd string, summary *RunSummary, quiet bool) {
+	path, err := writeRunSummary(cwd, summary)
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to write run summary")
+		presenter.Warning(fmt.Sprintf("Failed to write run summary: %v", err))
+		return
+	}
+	if quiet {
+		fmt.Fprintf(os.Stderr, "Run summary: %s\n", path)
+		return
+	}
+	presenter.Info(fmt.Sprintf("Run summary: %s", path))
+}
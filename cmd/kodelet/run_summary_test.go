@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/todos"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectRunToolActivity(t *testing.T) {
+	cwd := filepath.Join(string(filepath.Separator), "repo")
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	results := map[string]tooltypes.StructuredToolResult{
+		"call-3": {
+			ToolName:  "bash",
+			Success:   false,
+			Metadata:  &tooltypes.BashMetadata{Command: "go test ./...", ExitCode: 1, ExecutionTime: 2 * time.Second},
+			Timestamp: base.Add(3 * time.Minute),
+		},
+		"call-1": {
+			ToolName:  "file_write",
+			Success:   true,
+			Metadata:  &tooltypes.FileWriteMetadata{FilePath: filepath.Join(cwd, "main.go")},
+			Timestamp: base.Add(time.Minute),
+		},
+		"call-2": {
+			ToolName: "apply_patch",
+			Success:  true,
+			Metadata: &tooltypes.ApplyPatchMetadata{Changes: []tooltypes.ApplyPatchChange{
+				{Path: filepath.Join(cwd, "main.go"), Operation: "update"},
+				{Path: "/elsewhere/notes.txt", Operation: "add"},
+			}},
+			Timestamp: base.Add(2 * time.Minute),
+		},
+		"call-4": {
+			ToolName:  "file_edit",
+			Success:   false,
+			Metadata:  &tooltypes.FileEditMetadata{FilePath: filepath.Join(cwd, "failed.go")},
+			Timestamp: base.Add(4 * time.Minute),
+		},
+		"call-0": {
+			ToolName:  "bash",
+			Success:   true,
+			Metadata:  &tooltypes.BashMetadata{Command: "ls", WorkingDir: cwd},
+			Timestamp: base,
+		},
+	}
+
+	files, commands := collectRunToolActivity(results, cwd)
+
+	assert.Equal(t, []string{"main.go", "/elsewhere/notes.txt"}, files)
+	assert.Equal(t, []RunSummaryCommand{
+		{Command: "ls", WorkingDir: cwd},
+		{Command: "go test ./...", ExitCode: 1, DurationMS: 2000},
+	}, commands)
+}
+
+func TestWriteRunSummary(t *testing.T) {
+	cwd := t.TempDir()
+	started := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	summary := newRunSummary(llmtypes.Config{Provider: "anthropic", Model: "claude-sonnet-4-6"}, "conv-1", started)
+	summary.Verification = newRunSummaryVerification("make test", errors.New("exit status 2"), time.Second)
+	summary.finish(nil, cwd, "", errors.New("verification failed"), 1, started.Add(90*time.Second))
+
+	path, err := writeRunSummary(cwd, summary)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(cwd, ".kodelet", "runs", summary.RunID, "summary.json"), path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "error", decoded["status"])
+	assert.Equal(t, float64(1), decoded["exit_code"])
+	assert.Equal(t, float64(90000), decoded["duration_ms"])
+	assert.Equal(t, "conv-1", decoded["conversation_id"])
+	assert.Equal(t, []any{}, decoded["files_changed"])
+	assert.Equal(t, map[string]any{"processes": float64(0), "wall_time_ms": float64(0), "user_cpu_time_ms": float64(0), "system_cpu_time_ms": float64(0), "peak_memory_bytes": float64(0)}, decoded["resources"])
+	assert.Equal(t, map[string]any{"command": "make test", "passed": false, "error": "exit status 2", "duration_ms": float64(1000)}, decoded["verification"])
+
+	gitignore, err := os.ReadFile(filepath.Join(cwd, ".kodelet", "runs", ".gitignore"))
+	require.NoError(t, err)
+	assert.Equal(t, "*\n", string(gitignore))
+}
+
+func TestNewRunSummaryResources(t *testing.T) {
+	resources := newRunSummaryResources(tooltypes.ResourceUsage{
+		Processes:       3,
+		WallTime:        2 * time.Second,
+		UserCPUTime:     1500 * time.Millisecond,
+		SystemCPUTime:   250 * time.Millisecond,
+		PeakMemoryBytes: 64 << 20,
+	})
+
+	assert.Equal(t, RunSummaryResources{
+		Processes:       3,
+		WallTimeMS:      2000,
+		UserCPUTimeMS:   1500,
+		SystemCPUTimeMS: 250,
+		PeakMemoryBytes: 64 << 20,
+	}, resources)
+}
+
+func TestNewRunSummaryTodos(t *testing.T) {
+	assert.Nil(t, newRunSummaryTodos(map[string]any{}))
+
+	list, err := todos.New([]todos.Item{
+		{Content: "Write the fix", Status: todos.StatusCompleted},
+		{Content: "Run the tests", Status: todos.StatusPending},
+	}, time.Now())
+	require.NoError(t, err)
+
+	summaryTodos := newRunSummaryTodos(map[string]any{todos.MetadataKey: list})
+	require.NotNil(t, summaryTodos)
+	assert.Equal(t, 1, summaryTodos.Completed)
+	assert.Equal(t, 1, summaryTodos.Pending)
+	assert.Equal(t, list.Items, summaryTodos.Items)
+}
@@ -324,6 +324,75 @@ func TestLoadResumeConversationConfig_SnapshotLocksReasoningAndSurvivesMissingPr
 	require.ErrorContains(t, err, "locked to \"max\"")
 }
 
+func TestLoadResumeConversationConfig_ProviderFlagTranslatesConversation(t *testing.T) {
+	originalSettings := viper.AllSettings()
+	t.Setenv("KODELET_CONVERSATION_STORE_TYPE", "sqlite")
+	basePath := t.TempDir()
+	t.Setenv("KODELET_BASE_PATH", basePath)
+	defer func() {
+		viper.Reset()
+		for key, value := range originalSettings {
+			viper.Set(key, value)
+		}
+	}()
+
+	viper.Reset()
+	viper.Set("provider", "anthropic")
+	viper.Set("model", "claude-sonnet-4-6")
+
+	ctx := context.Background()
+	dbPath := filepath.Join(basePath, "storage.db")
+	sqlDB, err := db.Open(ctx, dbPath)
+	require.NoError(t, err)
+	require.NoError(t, db.NewMigrationRunner(sqlDB).Run(ctx, migrations.All()))
+	require.NoError(t, sqlDB.Close())
+
+	store, err := conversations.NewConversationStore(ctx, &conversations.Config{
+		StoreType: "sqlite",
+		BasePath:  basePath,
+	})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	metadata, err := conversations.AddConfigSnapshot(map[string]any{"model": "claude-sonnet-4-6"}, llmtypes.Config{
+		Provider:        "anthropic",
+		Model:           "claude-sonnet-4-6",
+		ReasoningEffort: "medium",
+	})
+	require.NoError(t, err)
+	conversationID := convtypes.GenerateID()
+	require.NoError(t, store.Save(ctx, convtypes.ConversationRecord{
+		ID:       conversationID,
+		Provider: "anthropic",
+		RawMessages: []byte(`[
+			{"role":"user","content":[{"type":"text","text":"list files"}]},
+			{"role":"assistant","content":[{"type":"text","text":"Listing."},{"type":"tool_use","id":"toolu_1","name":"bash","input":{"command":"ls"}}]},
+			{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":[{"type":"text","text":"main.go"}]}]}
+		]`),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Metadata:  metadata,
+	}))
+
+	cmd := &cobra.Command{Use: "run"}
+	cmd.Flags().String("provider", "", "LLM provider")
+	cmd.Flags().String("model", "", "Model")
+	require.NoError(t, cmd.Flags().Set("provider", "openai"))
+	require.NoError(t, cmd.Flags().Set("model", "gpt-5"))
+
+	config, _, err := loadResumeConversationConfig(ctx, cmd, conversationID, "")
+	require.NoError(t, err)
+	assert.Equal(t, "openai", config.Provider)
+	assert.Equal(t, "gpt-5", config.Model)
+
+	record, err := store.Load(ctx, conversationID)
+	require.NoError(t, err)
+	assert.Equal(t, "openai", record.Provider)
+	assert.Equal(t, "anthropic", record.Metadata["translated_from"])
+	assert.Contains(t, string(record.RawMessages), `"tool_call_id":"toolu_1"`)
+	assert.Contains(t, string(record.RawMessages), `"main.go"`)
+}
+
 func TestLoadResumeConversationConfig_LegacyConversationRejectsReasoningOverride(t *testing.T) {
 	originalSettings := viper.AllSettings()
 	t.Setenv("KODELET_CONVERSATION_STORE_TYPE", "sqlite")
@@ -679,6 +748,77 @@ func TestApplyFragmentRestrictions(t *testing.T) {
 	})
 }
 
+func TestApplyFragmentRunOptions(t *testing.T) {
+	newCmd := func() *cobra.Command {
+		cmd := &cobra.Command{Use: "run"}
+		defaults := NewRunConfig()
+		cmd.Flags().StringSliceP("image", "I", defaults.Images, "")
+		cmd.Flags().Int("max-turns", defaults.MaxTurns, "")
+		cmd.Flags().Float64("compact-ratio", llmtypes.DefaultCompactRatio, "")
+		cmd.Flags().Bool("use-weak-model", defaults.UseWeakModel, "")
+		cmd.Flags().Bool("no-tools", defaults.NoTools, "")
+		cmd.Flags().String("model", "", "")
+		return cmd
+	}
+	metadata := &fragments.Metadata{
+		Model:        "claude-haiku-4-5",
+		MaxTurns:     3,
+		CompactRatio: 0.6,
+		UseWeakModel: true,
+		NoTools:      true,
+		Images:       []string{"/recipes/diagram.png"},
+		Env:          map[string]string{"DEPLOY_TOKEN": "secret://vault/ci/deploy#token", "REGION": "eu-west-1"},
+	}
+
+	t.Run("applies recipe options", func(t *testing.T) {
+		config := NewRunConfig()
+		config.Images = []string{"cli.png"}
+		llmConfig := llmtypes.Config{Provider: "openai", Model: "gpt-5.5", CompactRatio: 0.8, ToolEnv: []llmtypes.ToolEnvVar{{Name: "REGION", Value: "us-east-1"}}}
+
+		applyFragmentRunOptions(newCmd(), config, &llmConfig, metadata)
+
+		assert.Equal(t, "claude-haiku-4-5", llmConfig.Model)
+		assert.Equal(t, "anthropic", llmConfig.Provider)
+		assert.Equal(t, 3, config.MaxTurns)
+		assert.Equal(t, 0.6, llmConfig.CompactRatio)
+		assert.True(t, config.UseWeakModel)
+		assert.True(t, config.NoTools)
+		assert.Equal(t, []string{"/recipes/diagram.png", "cli.png"}, config.Images)
+		assert.Equal(t, map[string]string{"DEPLOY_TOKEN": "secret://vault/ci/deploy#token", "REGION": "eu-west-1"}, llmConfig.ToolEnvMap())
+	})
+
+	t.Run("explicit flags win", func(t *testing.T) {
+		cmd := newCmd()
+		require.NoError(t, cmd.Flags().Set("max-turns", "10"))
+		require.NoError(t, cmd.Flags().Set("compact-ratio", "0.9"))
+		require.NoError(t, cmd.Flags().Set("use-weak-model", "false"))
+		require.NoError(t, cmd.Flags().Set("no-tools", "false"))
+		require.NoError(t, cmd.Flags().Set("model", "gpt-5.5"))
+		config := NewRunConfig()
+		config.MaxTurns = 10
+		llmConfig := llmtypes.Config{Provider: "openai", Model: "gpt-5.5", CompactRatio: 0.9}
+
+		applyFragmentRunOptions(cmd, config, &llmConfig, metadata)
+
+		assert.Equal(t, "gpt-5.5", llmConfig.Model)
+		assert.Equal(t, 10, config.MaxTurns)
+		assert.Equal(t, 0.9, llmConfig.CompactRatio)
+		assert.False(t, config.UseWeakModel)
+		assert.False(t, config.NoTools)
+	})
+
+	t.Run("ignores invalid values", func(t *testing.T) {
+		config := NewRunConfig()
+		llmConfig := llmtypes.Config{Provider: "openai", Model: "gpt-5.5", CompactRatio: 0.8}
+
+		applyFragmentRunOptions(newCmd(), config, &llmConfig, &fragments.Metadata{Model: "gemini-2.5-pro", MaxTurns: -1, CompactRatio: 1.5})
+
+		assert.Equal(t, "gpt-5.5", llmConfig.Model)
+		assert.Equal(t, 0, config.MaxTurns)
+		assert.Equal(t, 0.8, llmConfig.CompactRatio)
+	})
+}
+
 func TestNormalizeConversationProfile(t *testing.T) {
 	assert.Equal(t, "", normalizeConversationProfile(""))
 	assert.Equal(t, "", normalizeConversationProfile(" default "))
@@ -696,6 +836,8 @@ func TestGetRunConfigFromFlags(t *testing.T) {
 	cmd.Flags().Bool("stream-deltas", defaults.StreamDeltas, "")
 	cmd.Flags().StringSliceP("image", "I", defaults.Images, "")
 	cmd.Flags().Int("max-turns", defaults.MaxTurns, "")
+	cmd.Flags().Float64("max-cost", defaults.MaxCostUSD, "")
+	cmd.Flags().Int("max-tokens-total", defaults.MaxTotalTokens, "")
 	cmd.Flags().StringP("recipe", "r", defaults.FragmentName, "")
 	cmd.Flags().StringToString("arg", defaults.FragmentArgs, "")
 	cmd.Flags().StringSlice("fragment-dirs", defaults.FragmentDirs, "")
@@ -708,6 +850,8 @@ func TestGetRunConfigFromFlags(t *testing.T) {
 	cmd.Flags().Bool("result-only", defaults.ResultOnly, "")
 	cmd.Flags().Bool("use-weak-model", defaults.UseWeakModel, "")
 	cmd.Flags().String("account", defaults.Account, "")
+	cmd.Flags().Bool("in-devcontainer", defaults.InDevContainer, "")
+	cmd.Flags().String("metrics-addr", defaults.MetricsAddr, "")
 
 	require.NoError(t, cmd.Flags().Set("resume", "conv-1"))
 	require.NoError(t, cmd.Flags().Set("cwd", " /tmp/project "))
@@ -716,6 +860,8 @@ func TestGetRunConfigFromFlags(t *testing.T) {
 	require.NoError(t, cmd.Flags().Set("headless", "true"))
 	require.NoError(t, cmd.Flags().Set("image", "a.png,b.png"))
 	require.NoError(t, cmd.Flags().Set("max-turns", "-5"))
+	require.NoError(t, cmd.Flags().Set("max-cost", "1.5"))
+	require.NoError(t, cmd.Flags().Set("max-tokens-total", "-1"))
 	require.NoError(t, cmd.Flags().Set("recipe", "commit"))
 	require.NoError(t, cmd.Flags().Set("arg", "short=true,target=main"))
 	require.NoError(t, cmd.Flags().Set("fragment-dirs", "recipes,more-recipes"))
@@ -728,16 +874,21 @@ func TestGetRunConfigFromFlags(t *testing.T) {
 	require.NoError(t, cmd.Flags().Set("result-only", "true"))
 	require.NoError(t, cmd.Flags().Set("use-weak-model", "true"))
 	require.NoError(t, cmd.Flags().Set("account", "work"))
+	require.NoError(t, cmd.Flags().Set("in-devcontainer", "true"))
+	require.NoError(t, cmd.Flags().Set("metrics-addr", " localhost:9464 "))
 
 	config := getRunConfigFromFlags(context.Background(), cmd)
 
 	assert.Equal(t, "conv-1", config.ResumeConvID)
 	assert.Equal(t, "/tmp/project", config.CWD)
+	assert.Equal(t, "localhost:9464", config.MetricsAddr)
 	assert.False(t, config.NoSave)
 	assert.True(t, config.Headless)
 	assert.True(t, config.StreamDeltas)
 	assert.Equal(t, []string{"a.png", "b.png"}, config.Images)
 	assert.Equal(t, 0, config.MaxTurns)
+	assert.Equal(t, 1.5, config.MaxCostUSD)
+	assert.Equal(t, 0, config.MaxTotalTokens)
 	assert.Equal(t, "commit", config.FragmentName)
 	assert.Equal(t, map[string]string{"short": "true", "target": "main"}, config.FragmentArgs)
 	assert.Equal(t, []string{"recipes", "more-recipes"}, config.FragmentDirs)
@@ -750,19 +901,52 @@ func TestGetRunConfigFromFlags(t *testing.T) {
 	assert.True(t, config.ResultOnly)
 	assert.True(t, config.UseWeakModel)
 	assert.Equal(t, "work", config.Account)
+	assert.True(t, config.InDevContainer)
+}
+
+func TestGetOutputProfile(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	newCmd := func() *cobra.Command {
+		cmd := &cobra.Command{Use: "run"}
+		cmd.Flags().Bool("quiet", false, "")
+		cmd.Flags().Bool("verbose", false, "")
+		return cmd
+	}
+
+	profile, err := getOutputProfile(newCmd(), "run")
+	require.NoError(t, err)
+	assert.Equal(t, llmtypes.OutputProfileNormal, profile)
+
+	viper.Set("output.run", "verbose")
+	profile, err = getOutputProfile(newCmd(), "run")
+	require.NoError(t, err)
+	assert.Equal(t, llmtypes.OutputProfileVerbose, profile)
+
+	cmd := newCmd()
+	require.NoError(t, cmd.Flags().Set("quiet", "true"))
+	profile, err = getOutputProfile(cmd, "run")
+	require.NoError(t, err)
+	assert.Equal(t, llmtypes.OutputProfileQuiet, profile, "flags override the config default")
+
+	viper.Set("output.run", "loud")
+	_, err = getOutputProfile(newCmd(), "run")
+	require.Error(t, err)
 }
 
 type fakeRunThread struct {
-	metadata map[string]any
+	metadata     map[string]any
+	userMessages []string
 }
 
 func newFakeRunThread() *fakeRunThread {
 	return &fakeRunThread{metadata: make(map[string]any)}
 }
 
-func (f *fakeRunThread) SetState(tooltypes.State)                          {}
-func (f *fakeRunThread) GetState() tooltypes.State                         { return nil }
-func (f *fakeRunThread) AddUserMessage(context.Context, string, ...string) {}
+func (f *fakeRunThread) SetState(tooltypes.State)  {}
+func (f *fakeRunThread) GetState() tooltypes.State { return nil }
+func (f *fakeRunThread) AddUserMessage(_ context.Context, message string, _ ...string) {
+	f.userMessages = append(f.userMessages, message)
+}
 func (f *fakeRunThread) SendMessage(context.Context, string, llmtypes.MessageHandler, llmtypes.MessageOpt) (string, error) {
 	return "", nil
 }
@@ -779,6 +963,40 @@ func (f *fakeRunThread) AggregateSubagentUsage(llmtypes.Usage)        {}
 func (f *fakeRunThread) SetMetadataValue(key string, value any)       { f.metadata[key] = value }
 func (f *fakeRunThread) GetMetadata() map[string]any                  { return f.metadata }
 
+func TestAddRunRecipe(t *testing.T) {
+	thread := newFakeRunThread()
+	addRunRecipe(thread, &RunConfig{})
+	assert.NotContains(t, thread.metadata, convtypes.RecipeNameMetadataKey)
+
+	addRunRecipe(thread, &RunConfig{FragmentName: "github/pr-review"})
+	assert.Equal(t, "github/pr-review", thread.metadata[convtypes.RecipeNameMetadataKey])
+}
+
+func TestAddContextRefresh(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	state := tools.NewBasicState(ctx, tools.WithWorkingDirectory(dir))
+
+	thread := newFakeRunThread()
+	addContextRefresh(ctx, thread, state)
+	assert.Empty(t, thread.userMessages, "conversations without a snapshot are left alone")
+
+	thread.metadata = conversations.AddContextSnapshot(thread.metadata, conversations.NewContextSnapshot(ctx, state.DiscoverContexts(), dir))
+	addContextRefresh(ctx, thread, state)
+	assert.Empty(t, thread.userMessages)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "AGENTS.md"), []byte("Run make test before finishing.\n"), 0o644))
+	addContextRefresh(ctx, thread, state)
+	require.Len(t, thread.userMessages, 1)
+	assert.Contains(t, thread.userMessages[0], "Context files added:\n- "+filepath.Join(dir, "AGENTS.md"))
+
+	display, ok := conversations.LookupMessageDisplay(thread.metadata, thread.userMessages[0])
+	require.True(t, ok)
+	assert.Equal(t, conversations.MessageDisplayKindContextRefresh, display.Kind)
+	assert.Equal(t, "Repository changed since the last session: 1 context file changed", display.Text)
+}
+
 func writeRunExtensionExecutable(t *testing.T, path string) {
 	t.Helper()
 	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/jingkaihe/kodelet/pkg/checkpoints"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// RunUndoConfig holds the options of `kodelet run undo`.
+type RunUndoConfig struct {
+	Files []string
+	Step  int
+	List  bool
+}
+
+var runUndoCmd = &cobra.Command{
+	Use:   "undo <run-id>",
+	Short: "Revert the file changes made by a run",
+	Long: `Revert the files changed by a run to the content they had before the agent
+modified them, using the snapshots taken before each file edit. Files the
+run created are removed.
+
+Snapshots are stored in ~/.kodelet/checkpoints/<run-id> (or under
+KODELET_BASE_PATH). The run ID is printed in the run summary.
+
+Examples:
+  kodelet run undo RUN_ID                    # Revert every file the run changed
+  kodelet run undo RUN_ID --file main.go     # Revert only main.go
+  kodelet run undo RUN_ID --step 3           # Revert the changes from step 3 onwards
+  kodelet run undo RUN_ID --list             # List the recorded steps
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config := &RunUndoConfig{}
+		config.Files, _ = cmd.Flags().GetStringSlice("file")
+		config.Step, _ = cmd.Flags().GetInt("step")
+		config.List, _ = cmd.Flags().GetBool("list")
+
+		root, err := checkpoints.DefaultDir()
+		if err != nil {
+			return err
+		}
+		return runUndo(cmd.OutOrStdout(), root, args[0], config)
+	},
+}
+
+func init() {
+	runUndoCmd.Flags().StringSlice("file", nil, "Only revert these files (repeatable)")
+	runUndoCmd.Flags().Int("step", 0, "Revert the changes from this step onwards (default: all steps)")
+	runUndoCmd.Flags().Bool("list", false, "List the recorded steps instead of reverting")
+	runCmd.AddCommand(runUndoCmd)
+}
+
+func runUndo(w io.Writer, root, runID string, config *RunUndoConfig) error {
+	store, err := checkpoints.Load(root, runID)
+	if err != nil {
+		return err
+	}
+	if config.List {
+		displayCheckpointSteps(w, store.Steps())
+		return nil
+	}
+
+	paths := make([]string, 0, len(config.Files))
+	for _, file := range config.Files {
+		path, err := filepath.Abs(file)
+		if err != nil {
+			return errors.Wrapf(err, "failed to resolve %s", file)
+		}
+		paths = append(paths, path)
+	}
+
+	restored, err := store.Restore(checkpoints.RestoreOptions{Step: config.Step, Paths: paths})
+	if err != nil {
+		return err
+	}
+	for _, file := range restored {
+		if file.Deleted {
+			fmt.Fprintf(w, "Deleted %s\n", file.Path)
+		} else {
+			fmt.Fprintf(w, "Restored %s\n", file.Path)
+		}
+	}
+	return nil
+}
+
+func displayCheckpointSteps(w io.Writer, steps []checkpoints.Step) {
+	if len(steps) == 0 {
+		fmt.Fprintln(w, "No file changes recorded.")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Step\tTime\tTool\tFiles")
+	for _, step := range steps {
+		for i, file := range step.Files {
+			if i == 0 {
+				fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", step.Step, step.Time.Local().Format("2006-01-02 15:04:05"), step.Tool, file.Path)
+			} else {
+				fmt.Fprintf(tw, "\t\t\t%s\n", file.Path)
+			}
+		}
+	}
+	tw.Flush()
+}
+
+// openRunCheckpoints opens the checkpoint store of a run so file edits can be
+// undone with `kodelet run undo`. It returns nil when checkpoints are not
+// available; the run goes on without them.
+func openRunCheckpoints(ctx context.Context, runID string) *checkpoints.Store {
+	root, err := checkpoints.DefaultDir()
+	if err == nil {
+		var store *checkpoints.Store
+		if store, err = checkpoints.Open(root, runID); err == nil {
+			store.BeginCycle()
+			return store
+		}
+	}
+	logger.G(ctx).WithError(err).Warn("file checkpoints are disabled for this run")
+	return nil
+}
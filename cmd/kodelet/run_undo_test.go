@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/checkpoints"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunUndo(t *testing.T) {
+	root := t.TempDir()
+	dir := t.TempDir()
+	edited := filepath.Join(dir, "edited.txt")
+	created := filepath.Join(dir, "created.txt")
+	require.NoError(t, os.WriteFile(edited, []byte("before\n"), 0o644))
+
+	store, err := checkpoints.Open(root, "run-1")
+	require.NoError(t, err)
+	store.BeginCycle()
+	require.NoError(t, store.Snapshot("file_edit", []string{edited}))
+	require.NoError(t, os.WriteFile(edited, []byte("after\n"), 0o644))
+	require.NoError(t, store.Snapshot("file_write", []string{created}))
+	require.NoError(t, os.WriteFile(created, []byte("new\n"), 0o644))
+
+	var out bytes.Buffer
+	require.NoError(t, runUndo(&out, root, "run-1", &RunUndoConfig{List: true}))
+	assert.Contains(t, out.String(), "file_edit")
+	assert.Contains(t, out.String(), created)
+
+	out.Reset()
+	require.NoError(t, runUndo(&out, root, "run-1", &RunUndoConfig{Step: 2}))
+	assert.Equal(t, "Deleted "+created+"\n", out.String())
+	assert.NoFileExists(t, created)
+
+	out.Reset()
+	require.NoError(t, runUndo(&out, root, "run-1", &RunUndoConfig{Files: []string{edited}}))
+	assert.Equal(t, "Restored "+edited+"\n", out.String())
+	content, err := os.ReadFile(edited)
+	require.NoError(t, err)
+	assert.Equal(t, "before\n", string(content))
+
+	err = runUndo(&out, root, "missing", &RunUndoConfig{})
+	assert.EqualError(t, err, "no checkpoints found for missing")
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/jingkaihe/kodelet/pkg/webui"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 type ServeConfig struct {
@@ -164,12 +165,13 @@ func runServeCommand(ctx context.Context, config *ServeConfig) {
 	}).Info("Starting web UI server")
 
 	serverConfig := &webui.ServerConfig{
-		Host:         config.Host,
-		Port:         config.Port,
-		CWD:          config.CWD,
-		CompactRatio: config.CompactRatio,
-		AuthToken:    authToken,
-		CORSOrigins:  config.CORSOrigins,
+		Host:            config.Host,
+		Port:            config.Port,
+		CWD:             config.CWD,
+		CompactRatio:    config.CompactRatio,
+		AuthToken:       authToken,
+		CORSOrigins:     config.CORSOrigins,
+		AutoResumeSteer: viper.GetBool("steer.auto_resume"),
 	}
 
 	server, err := webui.NewServer(ctx, serverConfig)
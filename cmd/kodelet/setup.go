@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/jingkaihe/kodelet/pkg/binaries"
 	"github.com/jingkaihe/kodelet/pkg/logger"
 	"github.com/jingkaihe/kodelet/pkg/presenter"
 	"github.com/spf13/cobra"
@@ -112,6 +113,9 @@ var setupCmd = &cobra.Command{
 		}
 		logger.G(ctx).WithField("config_dir", configDir).Debug("Config directory created")
 
+		// Search binaries are otherwise resolved lazily on first tool use.
+		binaries.EnsureDepsInstalled(ctx)
+
 		configFile := filepath.Join(configDir, "config.yaml")
 
 		// Check if config already exists (unless override is specified)
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/logger"
+)
+
+// startupTimer records how long each cold-start phase of a command takes so
+// slow startups can be diagnosed with --log-level debug.
+type startupTimer struct {
+	start  time.Time
+	last   time.Time
+	phases []startupPhase
+}
+
+type startupPhase struct {
+	name     string
+	duration time.Duration
+}
+
+func newStartupTimer() *startupTimer {
+	now := time.Now()
+	return &startupTimer{start: now, last: now}
+}
+
+// Mark records the time elapsed since the previous mark under the given phase name.
+func (t *startupTimer) Mark(name string) {
+	if t == nil {
+		return
+	}
+	now := time.Now()
+	t.phases = append(t.phases, startupPhase{name: name, duration: now.Sub(t.last)})
+	t.last = now
+}
+
+// Total returns the time elapsed since the timer was created.
+func (t *startupTimer) Total() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return t.last.Sub(t.start)
+}
+
+// Log emits the recorded phases as a single debug log entry.
+func (t *startupTimer) Log(ctx context.Context, command string) {
+	if t == nil {
+		return
+	}
+	entry := logger.G(ctx).WithField("command", command)
+	for _, phase := range t.phases {
+		entry = entry.WithField("startup."+phase.name, phase.duration.String())
+	}
+	entry.WithField("startup.total", t.Total().String()).Debug("startup timing")
+}
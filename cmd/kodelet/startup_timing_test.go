@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartupTimerRecordsPhasesInOrder(t *testing.T) {
+	timer := newStartupTimer()
+	time.Sleep(2 * time.Millisecond)
+	timer.Mark("config")
+	timer.Mark("extensions")
+
+	require.Len(t, timer.phases, 2)
+	assert.Equal(t, "config", timer.phases[0].name)
+	assert.Equal(t, "extensions", timer.phases[1].name)
+	assert.GreaterOrEqual(t, timer.phases[0].duration, 2*time.Millisecond)
+	assert.Equal(t, timer.phases[0].duration+timer.phases[1].duration, timer.Total())
+}
+
+func TestStartupTimerNilIsSafe(t *testing.T) {
+	var timer *startupTimer
+	assert.NotPanics(t, func() {
+		timer.Mark("config")
+		timer.Log(context.Background(), "run")
+	})
+	assert.Zero(t, timer.Total())
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/exec"
 
 	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/osutil"
 	"github.com/jingkaihe/kodelet/pkg/presenter"
 	"github.com/jingkaihe/kodelet/pkg/steer"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 type SteerConfig struct {
@@ -112,7 +116,7 @@ func sendSteerCmd(ctx context.Context, conversationID, message string, isFollow
 	}
 	defer store.Close()
 
-	_, err = store.Load(ctx, conversationID)
+	record, err := store.Load(ctx, conversationID)
 	if err != nil {
 		presenter.Error(err, fmt.Sprintf("Failed to find conversation with ID: %s", conversationID))
 		presenter.Info("Use 'kodelet conversation list' to see available conversations")
@@ -145,7 +149,72 @@ func sendSteerCmd(ctx context.Context, conversationID, message string, isFollow
 		presenter.Info(fmt.Sprintf("Images: %d", len(images)))
 	}
 
-	presenter.Info("The steering will be processed when the conversation makes its next API call.")
-	presenter.Info("If the conversation is not currently running, start it with:")
-	presenter.Info(fmt.Sprintf("  kodelet run --resume %s \"continue\"", conversationID))
+	active, err := steerStore.IsRunActive(ctx, conversationID)
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to check whether the conversation is running")
+		active = true
+	}
+	switch {
+	case active:
+		presenter.Info("The steering will be processed when the conversation makes its next API call.")
+	case viper.GetBool("steer.auto_resume"):
+		pid, err := resumeIdleConversation(ctx, steerStore, conversationID, record.CWD)
+		if err != nil {
+			presenter.Error(err, "Failed to start a follow-up run; the steering is still queued")
+			os.Exit(1)
+		}
+		presenter.Success(fmt.Sprintf("Conversation was idle; started a follow-up run with the steering (pid %d)", pid))
+		presenter.Info(fmt.Sprintf("View progress with: kodelet conversation show %s", conversationID))
+	default:
+		presenter.Warning("Conversation idle — the steering will be used on next resume.")
+		presenter.Info("Resume it now with:")
+		presenter.Info(fmt.Sprintf("  kodelet run --resume %s \"continue\"", conversationID))
+		presenter.Info("Set steer.auto_resume: true to start a follow-up run automatically.")
+	}
+}
+
+// resumeIdleConversation consumes the queued steering and starts a detached
+// `kodelet run --resume` with it as the prompt. On failure the steering is
+// queued again so it is not lost.
+func resumeIdleConversation(ctx context.Context, steerStore *steer.Store, conversationID, cwd string) (int, error) {
+	messages, err := steerStore.Consume(ctx, conversationID)
+	if err != nil {
+		return 0, err
+	}
+	prompt, images := steer.FollowUpPrompt(messages)
+
+	pid, err := startFollowUpRun(conversationID, cwd, prompt, images)
+	if err != nil {
+		for _, message := range messages {
+			if _, requeueErr := steerStore.Enqueue(ctx, conversationID, message.Content, message.Images); requeueErr != nil {
+				logger.G(ctx).WithError(requeueErr).Error("failed to re-queue steering message")
+			}
+		}
+		return 0, err
+	}
+	return pid, nil
+}
+
+func startFollowUpRun(conversationID, cwd, prompt string, images []string) (int, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to locate the kodelet executable")
+	}
+
+	args := []string{"run", "--resume", conversationID}
+	for _, image := range images {
+		args = append(args, "--image", image)
+	}
+	args = append(args, "--", prompt)
+
+	cmd := exec.Command(executable, args...)
+	cmd.Dir = cwd
+	sysProcAttr := osutil.DetachSysProcAttr
+	cmd.SysProcAttr = &sysProcAttr
+	if err := cmd.Start(); err != nil {
+		return 0, errors.Wrap(err, "failed to start follow-up run")
+	}
+	pid := cmd.Process.Pid
+	_ = cmd.Process.Release()
+	return pid, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 
+	"github.com/jingkaihe/kodelet/pkg/airgap"
 	"github.com/jingkaihe/kodelet/pkg/telemetry"
 	"github.com/jingkaihe/kodelet/pkg/version"
 	"github.com/spf13/cobra"
@@ -27,6 +28,12 @@ func initTracing(ctx context.Context) (func(context.Context) error, error) {
 		SamplerRatio:   viper.GetFloat64("tracing.ratio"),
 	}
 
+	if config.Enabled {
+		if err := checkAirgapEndpoint("tracing endpoint", airgap.TracingEndpoint()); err != nil {
+			return nil, err
+		}
+	}
+
 	shutdown, err := telemetry.InitTracer(ctx, config)
 	if err != nil {
 		return nil, err
@@ -8,14 +8,17 @@ import (
 	"os"
 	"regexp"
 	"strconv"
+	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/jingkaihe/kodelet/pkg/conversations"
 	"github.com/jingkaihe/kodelet/pkg/presenter"
+	"github.com/jingkaihe/kodelet/pkg/promptcache"
 	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	"github.com/jingkaihe/kodelet/pkg/usage"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -25,6 +28,7 @@ type UsageConfig struct {
 	Format    string
 	Provider  string
 	Breakdown bool
+	GroupBy   string
 }
 
 func NewUsageConfig() *UsageConfig {
@@ -34,6 +38,7 @@ func NewUsageConfig() *UsageConfig {
 		Format:    "table",
 		Provider:  "",
 		Breakdown: false,
+		GroupBy:   usage.CostGroupByDay,
 	}
 }
 
@@ -54,6 +59,15 @@ Examples:
   kodelet usage --provider openai           # Filter by OpenAI
   kodelet usage --breakdown                  # Show breakdown by provider
   kodelet usage --breakdown --since 1w      # Provider breakdown for past week
+  kodelet usage --group-by model --since 30d   # Cost per provider and model
+  kodelet usage --group-by conversation --format csv > costs.csv
+
+--group-by model attributes each conversation to the provider and model it was
+last run with. CSV output has one record per row and no totals, with costs in
+US dollars.
+
+Prompt caches primed with 'kodelet cache prime' that are still warm are listed
+after the table with their TTL and expiry.
 `,
 	Run: func(cmd *cobra.Command, _ []string) {
 		ctx := cmd.Context()
@@ -66,9 +80,10 @@ func init() {
 	defaults := NewUsageConfig()
 	usageCmd.Flags().String("since", defaults.Since, "Show usage since this time (e.g., 2025-06-01, 1d, 1w)")
 	usageCmd.Flags().String("until", defaults.Until, "Show usage until this time (e.g., 2025-06-01)")
-	usageCmd.Flags().String("format", defaults.Format, "Output format: table or json")
+	usageCmd.Flags().String("format", defaults.Format, "Output format: table, json or csv")
 	usageCmd.Flags().String("provider", defaults.Provider, "Filter usage by LLM provider (anthropic or openai)")
 	usageCmd.Flags().Bool("breakdown", defaults.Breakdown, "Show usage breakdown by provider")
+	usageCmd.Flags().String("group-by", defaults.GroupBy, "Group usage by day, model or conversation")
 }
 
 func getUsageConfigFromFlags(cmd *cobra.Command) *UsageConfig {
@@ -89,10 +104,34 @@ func getUsageConfigFromFlags(cmd *cobra.Command) *UsageConfig {
 	if breakdown, err := cmd.Flags().GetBool("breakdown"); err == nil {
 		config.Breakdown = breakdown
 	}
+	if groupBy, err := cmd.Flags().GetString("group-by"); err == nil {
+		config.GroupBy = strings.ToLower(strings.TrimSpace(groupBy))
+	}
 
 	return config
 }
 
+// validateUsageConfig checks the output options, defaulting to daily groups.
+func validateUsageConfig(config *UsageConfig) error {
+	if config.GroupBy == "" {
+		config.GroupBy = usage.CostGroupByDay
+	}
+	switch config.GroupBy {
+	case usage.CostGroupByDay, usage.CostGroupByModel, usage.CostGroupByConversation:
+	default:
+		return errors.Errorf("unsupported --group-by %q (supported: day, model, conversation)", config.GroupBy)
+	}
+	switch config.Format {
+	case "table", "json", "csv":
+	default:
+		return errors.Errorf("unsupported --format %q (supported: table, json, csv)", config.Format)
+	}
+	if config.Breakdown && (config.GroupBy != usage.CostGroupByDay || config.Format == "csv") {
+		return errors.New("--breakdown only applies to --group-by day with table or json output")
+	}
+	return nil
+}
+
 func toUsageSummaries(summaries []convtypes.ConversationSummary) []usage.ConversationSummary {
 	result := make([]usage.ConversationSummary, len(summaries))
 	for i, s := range summaries {
@@ -151,6 +190,11 @@ func runUsageCmd(ctx context.Context, config *UsageConfig) {
 	var startTime, endTime time.Time
 	var err error
 
+	if err := validateUsageConfig(config); err != nil {
+		presenter.Error(err, "Invalid usage options")
+		os.Exit(1)
+	}
+
 	if config.Since != "" {
 		startTime, err = parseTimeSpec(config.Since)
 		if err != nil {
@@ -196,13 +240,31 @@ func runUsageCmd(ctx context.Context, config *UsageConfig) {
 	}
 
 	summaries := result.ConversationSummaries
+	primedCaches := loadPrimedCaches(ctx)
 
 	if len(summaries) == 0 {
 		presenter.Info("No conversations found in the specified time range.")
+		if config.Format == "table" {
+			displayPrimedCaches(os.Stdout, primedCaches, time.Now())
+		}
 		return
 	}
 
-	if config.Breakdown {
+	if config.Format == "csv" || config.GroupBy != usage.CostGroupByDay {
+		report := usage.CalculateCostReport(toUsageSummaries(summaries), config.GroupBy, startTime, endTime)
+		switch config.Format {
+		case "csv":
+			err = displayCostReportCSV(os.Stdout, report)
+		case "json":
+			err = displayCostReportJSON(os.Stdout, report)
+		default:
+			displayCostReportTable(os.Stdout, report)
+		}
+		if err != nil {
+			presenter.Error(err, "Failed to write usage report")
+			os.Exit(1)
+		}
+	} else if config.Breakdown {
 		dailyProviderStats := usage.CalculateDailyProviderBreakdownStats(toUsageSummaries(summaries), startTime, endTime)
 
 		if config.Format == "json" {
@@ -214,11 +276,14 @@ func runUsageCmd(ctx context.Context, config *UsageConfig) {
 		stats := usage.CalculateUsageStats(toUsageSummaries(summaries), startTime, endTime)
 
 		if config.Format == "json" {
-			displayUsageJSON(os.Stdout, stats)
+			displayUsageJSON(os.Stdout, stats, promptcache.Active(primedCaches, time.Now()))
 		} else {
 			displayUsageTable(os.Stdout, stats)
 		}
 	}
+	if config.Format == "table" {
+		displayPrimedCaches(os.Stdout, primedCaches, time.Now())
+	}
 }
 
 func displayUsageTable(w io.Writer, stats *UsageStats) {
@@ -258,8 +323,9 @@ func displayUsageTable(w io.Writer, stats *UsageStats) {
 }
 
 type UsageJSONOutput struct {
-	Daily []DailyUsageJSON `json:"daily"`
-	Total TotalUsageJSON   `json:"total"`
+	Daily        []DailyUsageJSON     `json:"daily"`
+	Total        TotalUsageJSON       `json:"total"`
+	PrimedCaches []promptcache.Record `json:"primed_caches,omitempty"`
 }
 
 type DailyUsageJSON struct {
@@ -281,9 +347,10 @@ type TotalUsageJSON struct {
 	TotalCost        float64 `json:"total_cost"`
 }
 
-func displayUsageJSON(w io.Writer, stats *UsageStats) {
+func displayUsageJSON(w io.Writer, stats *UsageStats, primedCaches []promptcache.Record) {
 	output := UsageJSONOutput{
-		Daily: make([]DailyUsageJSON, len(stats.Daily)),
+		Daily:        make([]DailyUsageJSON, len(stats.Daily)),
+		PrimedCaches: primedCaches,
 	}
 
 	for i, daily := range stats.Daily {
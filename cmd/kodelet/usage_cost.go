@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/jingkaihe/kodelet/pkg/usage"
+	"github.com/pkg/errors"
+)
+
+func displayCostReportTable(w io.Writer, report *usage.CostReport) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	var header, separator string
+	switch report.GroupBy {
+	case usage.CostGroupByModel:
+		header = "Provider\tModel\tConversations"
+		separator = "--------\t-----\t-------------"
+	case usage.CostGroupByConversation:
+		header = "Date\tConversation\tProvider\tModel"
+		separator = "----\t------------\t--------\t-----"
+	default:
+		header = "Date\tConversations"
+		separator = "----\t-------------"
+	}
+	header += "\tInput Tokens\tOutput Tokens\tCache Write\tCache Read\tTotal Cost"
+	separator += "\t------------\t-------------\t-----------\t----------\t----------"
+
+	fmt.Fprintln(tw, header)
+	fmt.Fprintln(tw, separator)
+	for _, row := range report.Rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t$%.4f\n",
+			costReportRowLabel(report.GroupBy, row),
+			usage.FormatNumber(row.Usage.InputTokens),
+			usage.FormatNumber(row.Usage.OutputTokens),
+			usage.FormatNumber(row.Usage.CacheCreationInputTokens),
+			usage.FormatNumber(row.Usage.CacheReadInputTokens),
+			row.Usage.TotalCost(),
+		)
+	}
+	fmt.Fprintln(tw, separator)
+
+	totalLabel := fmt.Sprintf("TOTAL\t%d", report.TotalConversations)
+	switch report.GroupBy {
+	case usage.CostGroupByModel:
+		totalLabel = fmt.Sprintf("TOTAL\t\t%d", report.TotalConversations)
+	case usage.CostGroupByConversation:
+		totalLabel = "TOTAL\t\t\t"
+	}
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t$%.4f\n",
+		totalLabel,
+		usage.FormatNumber(report.Total.InputTokens),
+		usage.FormatNumber(report.Total.OutputTokens),
+		usage.FormatNumber(report.Total.CacheCreationInputTokens),
+		usage.FormatNumber(report.Total.CacheReadInputTokens),
+		report.Total.TotalCost(),
+	)
+
+	tw.Flush()
+}
+
+func costReportRowLabel(groupBy string, row usage.CostReportRow) string {
+	switch groupBy {
+	case usage.CostGroupByModel:
+		return fmt.Sprintf("%s\t%s\t%d", row.Provider, costReportModel(row.Model), row.Conversations)
+	case usage.CostGroupByConversation:
+		return fmt.Sprintf("%s\t%s\t%s\t%s", row.Date.Format("2006-01-02"), row.ConversationID, row.Provider, costReportModel(row.Model))
+	default:
+		return fmt.Sprintf("%s\t%d", row.Date.Format("2006-01-02"), row.Conversations)
+	}
+}
+
+func costReportModel(model string) string {
+	if model == "" {
+		return usageReportNoGroup
+	}
+	return model
+}
+
+type CostReportJSONOutput struct {
+	GroupBy string              `json:"group_by"`
+	Rows    []CostReportRowJSON `json:"rows"`
+	Total   TotalUsageJSON      `json:"total"`
+}
+
+type CostReportRowJSON struct {
+	Date             string  `json:"date,omitempty"`
+	ConversationID   string  `json:"conversation_id,omitempty"`
+	Provider         string  `json:"provider,omitempty"`
+	Model            string  `json:"model,omitempty"`
+	Conversations    int     `json:"conversations"`
+	InputTokens      int     `json:"input_tokens"`
+	OutputTokens     int     `json:"output_tokens"`
+	CacheWriteTokens int     `json:"cache_write_tokens"`
+	CacheReadTokens  int     `json:"cache_read_tokens"`
+	InputCost        float64 `json:"input_cost"`
+	OutputCost       float64 `json:"output_cost"`
+	CacheWriteCost   float64 `json:"cache_write_cost"`
+	CacheReadCost    float64 `json:"cache_read_cost"`
+	TotalCost        float64 `json:"total_cost"`
+}
+
+func newCostReportRowJSON(groupBy string, row usage.CostReportRow) CostReportRowJSON {
+	output := CostReportRowJSON{
+		Provider:         row.Provider,
+		ConversationID:   row.ConversationID,
+		Conversations:    row.Conversations,
+		InputTokens:      row.Usage.InputTokens,
+		OutputTokens:     row.Usage.OutputTokens,
+		CacheWriteTokens: row.Usage.CacheCreationInputTokens,
+		CacheReadTokens:  row.Usage.CacheReadInputTokens,
+		InputCost:        row.Usage.InputCost,
+		OutputCost:       row.Usage.OutputCost,
+		CacheWriteCost:   row.Usage.CacheCreationCost,
+		CacheReadCost:    row.Usage.CacheReadCost,
+		TotalCost:        row.Usage.TotalCost(),
+	}
+	if groupBy != usage.CostGroupByModel {
+		output.Date = row.Date.Format("2006-01-02")
+	}
+	if groupBy != usage.CostGroupByDay {
+		output.Model = costReportModel(row.Model)
+	}
+	return output
+}
+
+func displayCostReportJSON(w io.Writer, report *usage.CostReport) error {
+	output := CostReportJSONOutput{
+		GroupBy: report.GroupBy,
+		Rows:    make([]CostReportRowJSON, len(report.Rows)),
+		Total: TotalUsageJSON{
+			Conversations:    report.TotalConversations,
+			InputTokens:      report.Total.InputTokens,
+			OutputTokens:     report.Total.OutputTokens,
+			CacheWriteTokens: report.Total.CacheCreationInputTokens,
+			CacheReadTokens:  report.Total.CacheReadInputTokens,
+			TotalCost:        report.Total.TotalCost(),
+		},
+	}
+	for i, row := range report.Rows {
+		output.Rows[i] = newCostReportRowJSON(report.GroupBy, row)
+	}
+
+	jsonData, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to generate JSON output")
+	}
+	fmt.Fprintln(w, string(jsonData))
+	return nil
+}
+
+// displayCostReportCSV writes one CSV record per report row. Costs are in US
+// dollars with full precision so spreadsheets can total them exactly.
+func displayCostReportCSV(w io.Writer, report *usage.CostReport) error {
+	var header []string
+	switch report.GroupBy {
+	case usage.CostGroupByModel:
+		header = []string{"provider", "model"}
+	case usage.CostGroupByConversation:
+		header = []string{"date", "conversation_id", "provider", "model"}
+	default:
+		header = []string{"date"}
+	}
+	header = append(header, "conversations", "input_tokens", "output_tokens", "cache_write_tokens", "cache_read_tokens",
+		"input_cost", "output_cost", "cache_write_cost", "cache_read_cost", "total_cost")
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return errors.Wrap(err, "failed to write CSV output")
+	}
+	for _, row := range report.Rows {
+		jsonRow := newCostReportRowJSON(report.GroupBy, row)
+		var record []string
+		switch report.GroupBy {
+		case usage.CostGroupByModel:
+			record = []string{jsonRow.Provider, jsonRow.Model}
+		case usage.CostGroupByConversation:
+			record = []string{jsonRow.Date, jsonRow.ConversationID, jsonRow.Provider, jsonRow.Model}
+		default:
+			record = []string{jsonRow.Date}
+		}
+		record = append(record,
+			strconv.Itoa(jsonRow.Conversations),
+			strconv.Itoa(jsonRow.InputTokens),
+			strconv.Itoa(jsonRow.OutputTokens),
+			strconv.Itoa(jsonRow.CacheWriteTokens),
+			strconv.Itoa(jsonRow.CacheReadTokens),
+			formatCSVCost(jsonRow.InputCost),
+			formatCSVCost(jsonRow.OutputCost),
+			formatCSVCost(jsonRow.CacheWriteCost),
+			formatCSVCost(jsonRow.CacheReadCost),
+			formatCSVCost(jsonRow.TotalCost),
+		)
+		if err := writer.Write(record); err != nil {
+			return errors.Wrap(err, "failed to write CSV output")
+		}
+	}
+	writer.Flush()
+	return errors.Wrap(writer.Error(), "failed to write CSV output")
+}
+
+func formatCSVCost(cost float64) string {
+	return strconv.FormatFloat(cost, 'f', -1, 64)
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/goals"
+	"github.com/jingkaihe/kodelet/pkg/presenter"
+	"github.com/jingkaihe/kodelet/pkg/todos"
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/jingkaihe/kodelet/pkg/usage"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Supported usage report groupings.
+const (
+	usageGroupByExperiment = "experiment"
+	usageGroupByModel      = "model"
+	usageGroupByProvider   = "provider"
+	usageGroupByAPIKey     = "api_key"
+)
+
+// usageReportNoGroup labels conversations without a value for the grouping.
+const usageReportNoGroup = "(none)"
+
+type UsageReportConfig struct {
+	Since      string
+	Until      string
+	Format     string
+	Provider   string
+	GroupBy    string
+	Experiment string
+}
+
+func NewUsageReportConfig() *UsageReportConfig {
+	return &UsageReportConfig{
+		Since:      "30d",
+		Until:      "",
+		Format:     "table",
+		Provider:   "",
+		GroupBy:    usageGroupByExperiment,
+		Experiment: "",
+	}
+}
+
+var usageReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Compare cost and outcomes across experiment arms, models, providers or API keys",
+	Long: `Aggregate conversation usage and outcomes by group to compare cost and success
+across experiment arms, models, providers or pooled API keys.
+
+Outcomes are the share of thread goals completed and todos completed.
+
+Grouping by api_key splits the usage of each conversation across the pooled keys
+its requests were sent with, so a conversation counts once in every key group it
+used. Conversations that used no key pool are reported under (none).
+
+Examples:
+  kodelet usage report --group-by experiment
+  kodelet usage report --group-by experiment --experiment gpt5-trial --since 1w
+  kodelet usage report --group-by model --format json
+  kodelet usage report --group-by api_key --since 1d
+`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		config := getUsageReportConfigFromFlags(cmd)
+		if err := runUsageReportCmd(cmd.Context(), os.Stdout, config); err != nil {
+			presenter.Error(err, "Failed to generate usage report")
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	defaults := NewUsageReportConfig()
+	usageReportCmd.Flags().String("since", defaults.Since, "Include conversations since this time (e.g., 2025-06-01, 1d, 1w)")
+	usageReportCmd.Flags().String("until", defaults.Until, "Include conversations until this time (e.g., 2025-06-01)")
+	usageReportCmd.Flags().String("format", defaults.Format, "Output format: table or json")
+	usageReportCmd.Flags().String("provider", defaults.Provider, "Filter conversations by LLM provider (anthropic or openai)")
+	usageReportCmd.Flags().String("group-by", defaults.GroupBy, "Group by experiment, model, provider or api_key")
+	usageReportCmd.Flags().String("experiment", defaults.Experiment, "Only include conversations in this experiment")
+	usageCmd.AddCommand(usageReportCmd)
+}
+
+func getUsageReportConfigFromFlags(cmd *cobra.Command) *UsageReportConfig {
+	config := NewUsageReportConfig()
+
+	if since, err := cmd.Flags().GetString("since"); err == nil {
+		config.Since = since
+	}
+	if until, err := cmd.Flags().GetString("until"); err == nil {
+		config.Until = until
+	}
+	if format, err := cmd.Flags().GetString("format"); err == nil {
+		config.Format = format
+	}
+	if provider, err := cmd.Flags().GetString("provider"); err == nil {
+		config.Provider = provider
+	}
+	if groupBy, err := cmd.Flags().GetString("group-by"); err == nil {
+		config.GroupBy = strings.ToLower(strings.TrimSpace(groupBy))
+	}
+	if experiment, err := cmd.Flags().GetString("experiment"); err == nil {
+		config.Experiment = strings.TrimSpace(experiment)
+	}
+
+	return config
+}
+
+func runUsageReportCmd(ctx context.Context, w io.Writer, config *UsageReportConfig) error {
+	switch config.GroupBy {
+	case usageGroupByExperiment, usageGroupByModel, usageGroupByProvider, usageGroupByAPIKey:
+	default:
+		return errors.Errorf("unsupported --group-by %q (supported: experiment, model, provider, api_key)", config.GroupBy)
+	}
+
+	options := convtypes.QueryOptions{
+		SortBy:    "updated",
+		SortOrder: "desc",
+		Provider:  config.Provider,
+	}
+	if config.Since != "" {
+		startTime, err := parseTimeSpec(config.Since)
+		if err != nil {
+			return errors.Wrap(err, "invalid since time specification")
+		}
+		startTime = startTime.Truncate(24 * time.Hour)
+		options.StartDate = &startTime
+	}
+	if config.Until != "" {
+		endTime, err := parseTimeSpec(config.Until)
+		if err != nil {
+			return errors.Wrap(err, "invalid until time specification")
+		}
+		endTime = endTime.Truncate(24 * time.Hour).Add(24*time.Hour - time.Second)
+		options.EndDate = &endTime
+	}
+
+	store, err := conversations.GetConversationStore(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize conversation store")
+	}
+	defer store.Close()
+
+	result, err := store.Query(ctx, options)
+	if err != nil {
+		return errors.Wrap(err, "failed to query conversations")
+	}
+
+	grouped := groupUsageConversations(result.ConversationSummaries, config.GroupBy, config.Experiment)
+	if len(grouped) == 0 {
+		presenter.Info("No conversations found for the report.")
+		return nil
+	}
+
+	stats := usage.CalculateGroupStats(grouped)
+	if config.Format == "json" {
+		return displayUsageReportJSON(w, config.GroupBy, stats)
+	}
+	displayUsageReportTable(w, stats)
+	return nil
+}
+
+// groupUsageConversations assigns each conversation to its report group,
+// keeping only conversations in experiment when it is set.
+func groupUsageConversations(summaries []convtypes.ConversationSummary, groupBy, experiment string) []usage.GroupedConversation {
+	grouped := make([]usage.GroupedConversation, 0, len(summaries))
+	for _, summary := range summaries {
+		assignment, inExperiment := conversations.ExperimentFromMetadata(summary.Metadata)
+		if experiment != "" && (!inExperiment || assignment.Name != experiment) {
+			continue
+		}
+
+		if groupBy == usageGroupByAPIKey {
+			grouped = append(grouped, groupByAPIKey(summary)...)
+			continue
+		}
+
+		group := ""
+		switch groupBy {
+		case usageGroupByExperiment:
+			if inExperiment {
+				group = assignment.Name + "/" + assignment.Arm
+			}
+		case usageGroupByModel:
+			group, _ = summary.Metadata["model"].(string)
+		case usageGroupByProvider:
+			group = summary.Provider
+		}
+		if strings.TrimSpace(group) == "" {
+			group = usageReportNoGroup
+		}
+
+		grouped = append(grouped, usage.GroupedConversation{
+			Summary: summary,
+			Group:   group,
+			Outcome: conversationOutcome(summary.Metadata),
+		})
+	}
+	return grouped
+}
+
+// apiKeyUsageSummary is a conversation summary reporting only the usage of
+// one pooled API key.
+type apiKeyUsageSummary struct {
+	convtypes.ConversationSummary
+	usage llmtypes.Usage
+}
+
+func (s apiKeyUsageSummary) GetUsage() llmtypes.Usage {
+	return s.usage
+}
+
+// groupByAPIKey splits a conversation into one report entry per pooled API
+// key it used.
+func groupByAPIKey(summary convtypes.ConversationSummary) []usage.GroupedConversation {
+	outcome := conversationOutcome(summary.Metadata)
+	keys := conversations.APIKeyUsageFromMetadata(summary.Metadata)
+	if len(keys) == 0 {
+		return []usage.GroupedConversation{{Summary: summary, Group: usageReportNoGroup, Outcome: outcome}}
+	}
+	grouped := make([]usage.GroupedConversation, 0, len(keys))
+	for name, key := range keys {
+		grouped = append(grouped, usage.GroupedConversation{
+			Summary: apiKeyUsageSummary{ConversationSummary: summary, usage: key.Usage},
+			Group:   name,
+			Outcome: outcome,
+		})
+	}
+	return grouped
+}
+
+func conversationOutcome(metadata map[string]any) usage.ConversationOutcome {
+	var outcome usage.ConversationOutcome
+	if goal, ok := goals.FromMetadata(metadata); ok {
+		outcome.HasGoal = true
+		outcome.GoalCompleted = goal.Status == goals.StatusComplete
+	}
+	if list, ok := todos.FromMetadata(metadata); ok {
+		progress := list.Progress()
+		outcome.Todos = progress.Completed + progress.InProgress + progress.Pending
+		outcome.TodosCompleted = progress.Completed
+	}
+	return outcome
+}
+
+func displayUsageReportTable(w io.Writer, stats []usage.GroupStats) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "Group\tConversations\tAvg Messages\tInput Tokens\tOutput Tokens\tTotal Cost\tAvg Cost\tGoals Done\tTodos Done")
+	fmt.Fprintln(tw, "-----\t-------------\t------------\t------------\t-------------\t----------\t--------\t----------\t----------")
+	for _, group := range stats {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%s\t%s\t$%.4f\t$%.4f\t%s\t%s\n",
+			group.Group,
+			group.Conversations,
+			float64(group.Messages)/float64(group.Conversations),
+			usage.FormatNumber(group.Usage.InputTokens),
+			usage.FormatNumber(group.Usage.OutputTokens),
+			group.Usage.TotalCost(),
+			group.AverageCost(),
+			formatUsageRatio(group.GoalsCompleted, group.Goals),
+			formatUsageRatio(group.TodosCompleted, group.Todos),
+		)
+	}
+
+	tw.Flush()
+}
+
+func formatUsageRatio(done, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d/%d (%.0f%%)", done, total, float64(done)*100/float64(total))
+}
+
+type UsageReportJSONOutput struct {
+	GroupBy string                 `json:"group_by"`
+	Groups  []UsageReportGroupJSON `json:"groups"`
+}
+
+type UsageReportGroupJSON struct {
+	Group            string  `json:"group"`
+	Conversations    int     `json:"conversations"`
+	Messages         int     `json:"messages"`
+	InputTokens      int     `json:"input_tokens"`
+	OutputTokens     int     `json:"output_tokens"`
+	CacheWriteTokens int     `json:"cache_write_tokens"`
+	CacheReadTokens  int     `json:"cache_read_tokens"`
+	TotalCost        float64 `json:"total_cost"`
+	AverageCost      float64 `json:"average_cost"`
+	Goals            int     `json:"goals"`
+	GoalsCompleted   int     `json:"goals_completed"`
+	Todos            int     `json:"todos"`
+	TodosCompleted   int     `json:"todos_completed"`
+}
+
+func displayUsageReportJSON(w io.Writer, groupBy string, stats []usage.GroupStats) error {
+	output := UsageReportJSONOutput{
+		GroupBy: groupBy,
+		Groups:  make([]UsageReportGroupJSON, len(stats)),
+	}
+	for i, group := range stats {
+		output.Groups[i] = UsageReportGroupJSON{
+			Group:            group.Group,
+			Conversations:    group.Conversations,
+			Messages:         group.Messages,
+			InputTokens:      group.Usage.InputTokens,
+			OutputTokens:     group.Usage.OutputTokens,
+			CacheWriteTokens: group.Usage.CacheCreationInputTokens,
+			CacheReadTokens:  group.Usage.CacheReadInputTokens,
+			TotalCost:        group.Usage.TotalCost(),
+			AverageCost:      group.AverageCost(),
+			Goals:            group.Goals,
+			GoalsCompleted:   group.GoalsCompleted,
+			Todos:            group.Todos,
+			TodosCompleted:   group.TodosCompleted,
+		}
+	}
+
+	jsonData, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to generate JSON output")
+	}
+	fmt.Fprintln(w, string(jsonData))
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/goals"
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/jingkaihe/kodelet/pkg/usage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupUsageConversations(t *testing.T) {
+	summaries := []convtypes.ConversationSummary{
+		{
+			ID:       "treatment",
+			Provider: "openai",
+			Metadata: map[string]any{
+				"experiment":      map[string]any{"name": "trial", "arm": "treatment"},
+				"model":           "gpt-5.5",
+				goals.MetadataKey: map[string]any{"objective": "ship", "status": string(goals.StatusComplete)},
+			},
+			Usage: llmtypes.Usage{InputTokens: 100},
+		},
+		{
+			ID:       "control",
+			Provider: "anthropic",
+			Metadata: map[string]any{
+				"experiment": map[string]any{"name": "trial", "arm": "control"},
+				"model":      "claude-sonnet-4-6",
+			},
+		},
+		{ID: "other", Provider: "anthropic"},
+	}
+
+	t.Run("by experiment", func(t *testing.T) {
+		grouped := groupUsageConversations(summaries, usageGroupByExperiment, "")
+		require.Len(t, grouped, 3)
+		assert.Equal(t, "trial/treatment", grouped[0].Group)
+		assert.Equal(t, "trial/control", grouped[1].Group)
+		assert.Equal(t, usageReportNoGroup, grouped[2].Group)
+		assert.True(t, grouped[0].Outcome.GoalCompleted)
+	})
+
+	t.Run("filtered to one experiment by model", func(t *testing.T) {
+		grouped := groupUsageConversations(summaries, usageGroupByModel, "trial")
+		require.Len(t, grouped, 2)
+		assert.Equal(t, "gpt-5.5", grouped[0].Group)
+		assert.Equal(t, "claude-sonnet-4-6", grouped[1].Group)
+	})
+
+	t.Run("by provider", func(t *testing.T) {
+		grouped := groupUsageConversations(summaries, usageGroupByProvider, "")
+		assert.Equal(t, "openai", grouped[0].Group)
+		assert.Equal(t, "anthropic", grouped[2].Group)
+	})
+
+	t.Run("by api key", func(t *testing.T) {
+		pooled := convtypes.ConversationSummary{
+			ID: "pooled",
+			Metadata: map[string]any{
+				"api_key_usage": map[string]any{
+					"team-a": map[string]any{"requests": 2, "usage": map[string]any{"inputTokens": 30}},
+					"team-b": map[string]any{"requests": 1, "usage": map[string]any{"inputTokens": 70}},
+				},
+			},
+			Usage: llmtypes.Usage{InputTokens: 100},
+		}
+		grouped := groupUsageConversations([]convtypes.ConversationSummary{pooled, summaries[2]}, usageGroupByAPIKey, "")
+		require.Len(t, grouped, 3)
+
+		byGroup := map[string]int{}
+		for _, entry := range grouped {
+			byGroup[entry.Group] += entry.Summary.GetUsage().InputTokens
+		}
+		assert.Equal(t, map[string]int{"team-a": 30, "team-b": 70, usageReportNoGroup: 0}, byGroup)
+	})
+}
+
+func TestDisplayUsageReport(t *testing.T) {
+	stats := usage.CalculateGroupStats([]usage.GroupedConversation{
+		{Summary: convtypes.ConversationSummary{MessageCount: 3, Usage: llmtypes.Usage{InputTokens: 1000, InputCost: 0.5}}, Group: "trial/treatment", Outcome: usage.ConversationOutcome{HasGoal: true, GoalCompleted: true}},
+	})
+
+	var table bytes.Buffer
+	displayUsageReportTable(&table, stats)
+	assert.Contains(t, table.String(), "trial/treatment")
+	assert.Contains(t, table.String(), "1/1 (100%)")
+
+	var output bytes.Buffer
+	require.NoError(t, displayUsageReportJSON(&output, usageGroupByExperiment, stats))
+	var decoded UsageReportJSONOutput
+	require.NoError(t, json.Unmarshal(output.Bytes(), &decoded))
+	assert.Equal(t, usageGroupByExperiment, decoded.GroupBy)
+	require.Len(t, decoded.Groups, 1)
+	assert.Equal(t, 1000, decoded.Groups[0].InputTokens)
+	assert.InDelta(t, 0.5, decoded.Groups[0].AverageCost, 0.0001)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"os"
 	"path/filepath"
@@ -207,6 +208,7 @@ func TestGetUsageConfigFromFlags(t *testing.T) {
 				Format:    "table",
 				Provider:  "",
 				Breakdown: false,
+				GroupBy:   "day",
 			},
 		},
 		{
@@ -220,6 +222,7 @@ func TestGetUsageConfigFromFlags(t *testing.T) {
 				Format:    "table",
 				Provider:  "",
 				Breakdown: false,
+				GroupBy:   "day",
 			},
 		},
 		{
@@ -233,6 +236,7 @@ func TestGetUsageConfigFromFlags(t *testing.T) {
 				Format:    "table",
 				Provider:  "",
 				Breakdown: false,
+				GroupBy:   "day",
 			},
 		},
 		{
@@ -246,6 +250,7 @@ func TestGetUsageConfigFromFlags(t *testing.T) {
 				Format:    "json",
 				Provider:  "",
 				Breakdown: false,
+				GroupBy:   "day",
 			},
 		},
 		{
@@ -260,6 +265,7 @@ func TestGetUsageConfigFromFlags(t *testing.T) {
 				Format:    "table",
 				Provider:  "openai",
 				Breakdown: true,
+				GroupBy:   "day",
 			},
 		},
 		{
@@ -277,6 +283,22 @@ func TestGetUsageConfigFromFlags(t *testing.T) {
 				Format:    "json",
 				Provider:  "anthropic",
 				Breakdown: true,
+				GroupBy:   "day",
+			},
+		},
+		{
+			name: "group by model as csv",
+			flags: map[string]string{
+				"group-by": "Model",
+				"format":   "csv",
+			},
+			expected: &UsageConfig{
+				Since:     "10d",
+				Until:     "",
+				Format:    "csv",
+				Provider:  "",
+				Breakdown: false,
+				GroupBy:   "model",
 			},
 		},
 	}
@@ -292,6 +314,7 @@ func TestGetUsageConfigFromFlags(t *testing.T) {
 			cmd.Flags().String("format", defaults.Format, "")
 			cmd.Flags().String("provider", defaults.Provider, "")
 			cmd.Flags().Bool("breakdown", defaults.Breakdown, "")
+			cmd.Flags().String("group-by", defaults.GroupBy, "")
 
 			// Set flag values
 			for key, value := range tt.flags {
@@ -513,7 +536,7 @@ func TestDisplayUsageJSON(t *testing.T) {
 	}
 
 	var buf bytes.Buffer
-	displayUsageJSON(&buf, stats)
+	displayUsageJSON(&buf, stats, nil)
 
 	output := buf.String()
 
@@ -603,6 +626,7 @@ func TestNewUsageConfig(t *testing.T) {
 	assert.Equal(t, "table", config.Format)
 	assert.Equal(t, "", config.Provider)
 	assert.False(t, config.Breakdown)
+	assert.Equal(t, "day", config.GroupBy)
 }
 
 func TestDateRangeFiltering(t *testing.T) {
@@ -905,6 +929,82 @@ func TestRunUsageCmdBreakdownWithTempSQLiteStore(t *testing.T) {
 	assert.Equal(t, 2, parsed.Daily[0].Total.Conversations)
 }
 
+func TestRunUsageCmdGroupByWithTempSQLiteStore(t *testing.T) {
+	ctx := context.Background()
+	basePath := setupUsageTempStore(ctx, t)
+	t.Setenv("KODELET_BASE_PATH", basePath)
+	t.Setenv("KODELET_CONVERSATION_STORE_TYPE", "sqlite")
+
+	store, err := convstore.NewConversationStore(ctx, &convstore.Config{StoreType: "sqlite", BasePath: basePath})
+	require.NoError(t, err)
+	defer func() { _ = store.Close() }()
+
+	now := time.Now().UTC()
+	saveUsageRecord(ctx, t, store, "cost-openai-1", "openai", now.Add(-24*time.Hour), llmtypes.Usage{InputTokens: 10, InputCost: 0.01})
+	saveUsageRecord(ctx, t, store, "cost-openai-2", "openai", now.Add(-2*24*time.Hour), llmtypes.Usage{InputTokens: 30, InputCost: 0.03})
+	saveUsageRecord(ctx, t, store, "cost-anthropic", "anthropic", now.Add(-24*time.Hour), llmtypes.Usage{InputTokens: 20, OutputTokens: 6, InputCost: 0.02, OutputCost: 0.005})
+
+	output := captureAllStdout(t, func() {
+		runUsageCmd(ctx, &UsageConfig{Since: "30d", Format: "json", GroupBy: "model"})
+	})
+	var parsed CostReportJSONOutput
+	require.NoError(t, json.Unmarshal([]byte(output), &parsed))
+	assert.Equal(t, "model", parsed.GroupBy)
+	require.Len(t, parsed.Rows, 2)
+	assert.Equal(t, "openai", parsed.Rows[0].Provider)
+	assert.Equal(t, "openai-model", parsed.Rows[0].Model)
+	assert.Equal(t, 2, parsed.Rows[0].Conversations)
+	assert.Equal(t, 40, parsed.Rows[0].InputTokens)
+	assert.Empty(t, parsed.Rows[0].Date)
+	assert.Equal(t, 3, parsed.Total.Conversations)
+
+	output = captureAllStdout(t, func() {
+		runUsageCmd(ctx, &UsageConfig{Since: "30d", Format: "csv", GroupBy: "conversation"})
+	})
+	records, err := csv.NewReader(strings.NewReader(output)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, []string{"date", "conversation_id", "provider", "model", "conversations"}, records[0][:5])
+	costs := map[string]string{}
+	for _, record := range records[1:] {
+		costs[record[1]] = record[len(record)-1]
+	}
+	assert.Equal(t, map[string]string{"cost-openai-1": "0.01", "cost-openai-2": "0.03", "cost-anthropic": "0.025"}, costs)
+	assert.NotContains(t, output, "TOTAL")
+}
+
+func TestDisplayCostReportTable(t *testing.T) {
+	report := &usage.CostReport{
+		GroupBy: usage.CostGroupByModel,
+		Rows: []usage.CostReportRow{
+			{Provider: "openai", Model: "gpt-5", Conversations: 2, Usage: llmtypes.Usage{InputTokens: 1200, InputCost: 0.5}},
+			{Provider: "anthropic", Conversations: 1, Usage: llmtypes.Usage{OutputTokens: 10, OutputCost: 0.25}},
+		},
+		Total:              llmtypes.Usage{InputTokens: 1200, OutputTokens: 10, InputCost: 0.5, OutputCost: 0.25},
+		TotalConversations: 3,
+	}
+
+	var buf bytes.Buffer
+	displayCostReportTable(&buf, report)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 6)
+	assert.Equal(t, []string{"Provider", "Model", "Conversations", "Input", "Tokens"}, strings.Fields(lines[0])[:5])
+	assert.Equal(t, []string{"openai", "gpt-5", "2", "1,200", "0", "0", "0", "$0.5000"}, strings.Fields(lines[2]))
+	assert.Equal(t, []string{"anthropic", usageReportNoGroup, "1", "0", "10", "0", "0", "$0.2500"}, strings.Fields(lines[3]))
+	assert.Equal(t, []string{"TOTAL", "3", "1,200", "10", "0", "0", "$0.7500"}, strings.Fields(lines[5]))
+}
+
+func TestValidateUsageConfig(t *testing.T) {
+	config := &UsageConfig{Format: "table"}
+	require.NoError(t, validateUsageConfig(config))
+	assert.Equal(t, usage.CostGroupByDay, config.GroupBy)
+
+	require.Error(t, validateUsageConfig(&UsageConfig{Format: "table", GroupBy: "week"}))
+	require.Error(t, validateUsageConfig(&UsageConfig{Format: "xml"}))
+	require.Error(t, validateUsageConfig(&UsageConfig{Format: "table", GroupBy: "model", Breakdown: true}))
+	require.Error(t, validateUsageConfig(&UsageConfig{Format: "csv", Breakdown: true}))
+}
+
 func TestRunUsageCmdNoConversationsWithTempSQLiteStore(t *testing.T) {
 	ctx := context.Background()
 	basePath := setupUsageTempStore(ctx, t)
@@ -943,7 +1043,7 @@ func saveUsageRecord(ctx context.Context, t *testing.T, store convstore.Conversa
         {"role":"user","content":[{"type":"text","text":"hello"}]},
         {"role":"assistant","content":[{"type":"text","text":"world"}]}
       ]`),
-		Metadata: map[string]any{"provider": provider},
+		Metadata: map[string]any{"provider": provider, "model": provider + "-model"},
 	}
 	require.NoError(t, store.Save(ctx, record))
 
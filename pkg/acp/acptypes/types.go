@@ -294,3 +294,86 @@ type AvailableCommandsUpdate struct {
 	SessionUpdate     string             `json:"sessionUpdate"`
 	AvailableCommands []AvailableCommand `json:"availableCommands"`
 }
+
+// Client methods the agent calls
+const (
+	MethodRequestPermission = "session/request_permission"
+)
+
+// MethodIDEContext is kodelet's extension method for IDE clients to push
+// editor state into a session. It is accepted as a request or a notification.
+const MethodIDEContext = "_kodelet/ide_context"
+
+// PermissionOptionKind hints how the client presents a permission option
+type PermissionOptionKind string
+
+// PermissionOptionKind values
+const (
+	PermissionAllowOnce  PermissionOptionKind = "allow_once"
+	PermissionRejectOnce PermissionOptionKind = "reject_once"
+)
+
+// PermissionOption is one choice offered in a permission request
+type PermissionOption struct {
+	OptionID string               `json:"optionId"`
+	Name     string               `json:"name"`
+	Kind     PermissionOptionKind `json:"kind"`
+}
+
+// PermissionToolCall describes the operation a permission request is about
+type PermissionToolCall struct {
+	ToolCallID string            `json:"toolCallId"`
+	Title      string            `json:"title,omitempty"`
+	Kind       ToolKind          `json:"kind,omitempty"`
+	Status     ToolCallStatus    `json:"status,omitempty"`
+	Content    []ToolCallContent `json:"content,omitempty"`
+}
+
+// RequestPermissionRequest asks the client to approve an operation
+type RequestPermissionRequest struct {
+	SessionID SessionID          `json:"sessionId"`
+	ToolCall  PermissionToolCall `json:"toolCall"`
+	Options   []PermissionOption `json:"options"`
+}
+
+// Permission outcomes
+const (
+	PermissionOutcomeSelected  = "selected"
+	PermissionOutcomeCancelled = "cancelled"
+)
+
+// RequestPermissionOutcome is the user's answer to a permission request
+type RequestPermissionOutcome struct {
+	Outcome  string `json:"outcome"`
+	OptionID string `json:"optionId,omitempty"`
+}
+
+// RequestPermissionResponse is the client's response to a permission request
+type RequestPermissionResponse struct {
+	Outcome RequestPermissionOutcome `json:"outcome"`
+}
+
+// IDESelection is a selected range in a file, with 1-based inclusive lines
+type IDESelection struct {
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	Text      string `json:"text,omitempty"`
+}
+
+// IDEDiagnostic is a problem the editor reports for a file
+type IDEDiagnostic struct {
+	Path     string `json:"path"`
+	Line     int    `json:"line,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	Message  string `json:"message"`
+}
+
+// IDEContextRequest carries the editor state pushed by an IDE client. Each
+// push replaces the previous one and is added to the session's next prompt.
+type IDEContextRequest struct {
+	SessionID   SessionID       `json:"sessionId"`
+	ActiveFile  string          `json:"activeFile,omitempty"`
+	Selection   *IDESelection   `json:"selection,omitempty"`
+	OpenFiles   []string        `json:"openFiles,omitempty"`
+	Diagnostics []IDEDiagnostic `json:"diagnostics,omitempty"`
+}
@@ -337,7 +337,7 @@ func ToACPToolKind(toolName string) acptypes.ToolKind {
 		return acptypes.ToolKindEdit
 	case "apply_patch":
 		return acptypes.ToolKindEdit
-	case "web_fetch":
+	case "web_fetch", "web_crawl":
 		return acptypes.ToolKindFetch
 	case "view_image":
 		return acptypes.ToolKindRead
@@ -432,6 +432,10 @@ func (g *DefaultTitleGenerator) GenerateTitle(toolName string, input string) str
 		if url, ok := params["url"].(string); ok {
 			title = fmt.Sprintf("Fetch: %s", url)
 		}
+	case "web_crawl":
+		if url, ok := params["url"].(string); ok {
+			title = fmt.Sprintf("Crawl: %s", url)
+		}
 	case "view_image":
 		if path, ok := params["path"].(string); ok {
 			title = fmt.Sprintf("Image: %s", path)
@@ -139,6 +139,9 @@ func (g *ToolContentGenerator) generateFileReadContent(structured tooltypes.Stru
 	if structured.Error != "" {
 		return g.generateTextContent(structured.Error)
 	}
+	if meta.Outline {
+		return g.generateTextContent(strings.Join(meta.Lines, "\n"))
+	}
 
 	content := strings.Join(meta.Lines, "\n")
 	contentWithLineNumbers := osutil.ContentWithLineNumber(meta.Lines, meta.Offset)
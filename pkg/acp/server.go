@@ -22,8 +22,11 @@ import (
 	"github.com/jingkaihe/kodelet/pkg/extensions"
 	"github.com/jingkaihe/kodelet/pkg/fragments"
 	"github.com/jingkaihe/kodelet/pkg/goals"
+	"github.com/jingkaihe/kodelet/pkg/llm"
 	"github.com/jingkaihe/kodelet/pkg/logger"
 	"github.com/jingkaihe/kodelet/pkg/slashcommands"
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	"github.com/jingkaihe/kodelet/pkg/version"
 	pkgerrors "github.com/pkg/errors"
 )
@@ -227,6 +230,11 @@ func (s *Server) handleRequest(data []byte) error {
 		return s.handleSessionPrompt(&req)
 	case "session/set_mode":
 		return s.handleSetMode(&req)
+	case acptypes.MethodIDEContext:
+		if err := s.applyIDEContext(req.Params); err != nil {
+			return s.sendError(req.ID, acptypes.ErrCodeInvalidParams, err.Error(), nil)
+		}
+		return s.sendResult(req.ID, map[string]any{})
 	default:
 		return s.sendError(req.ID, acptypes.ErrCodeMethodNotFound, "Method not found", nil)
 	}
@@ -257,6 +265,12 @@ func (s *Server) handleNotification(method string, data []byte) error {
 		s.activePromptsMu.Unlock()
 
 		return nil
+	case acptypes.MethodIDEContext:
+		var notif acptypes.Notification
+		if err := json.Unmarshal(data, &notif); err != nil {
+			return err
+		}
+		return s.applyIDEContext(notif.Params)
 	default:
 		logger.G(s.ctx).WithField("method", method).Warn("Unknown notification")
 		return nil
@@ -314,6 +328,11 @@ func (s *Server) handleInitialize(req *acptypes.Request) error {
 			SessionCapabilities: &acptypes.SessionCapabilities{
 				SetMode: false,
 			},
+			Meta: map[string]any{
+				"kodelet": map[string]any{
+					"ideContext": true,
+				},
+			},
 		},
 		AgentInfo: &acptypes.Implementation{
 			Name:    "kodelet",
@@ -327,6 +346,24 @@ func (s *Server) handleInitialize(req *acptypes.Request) error {
 	return s.sendResult(req.ID, result)
 }
 
+// applyIDEContext stores editor state pushed by the client for the session's
+// next prompt.
+func (s *Server) applyIDEContext(raw json.RawMessage) error {
+	if !s.initialized.Load() {
+		return pkgerrors.New("not initialized")
+	}
+	var params acptypes.IDEContextRequest
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return pkgerrors.Wrap(err, "invalid params")
+	}
+	sess, err := s.sessionManager.GetSession(params.SessionID)
+	if err != nil {
+		return err
+	}
+	sess.SetIDEContext(params)
+	return nil
+}
+
 func (s *Server) handleAuthenticate(req *acptypes.Request) error {
 	return s.sendResult(req.ID, map[string]any{})
 }
@@ -459,6 +496,7 @@ func (s *Server) handleSessionPrompt(req *acptypes.Request) error {
 	}
 
 	prompt := params.Prompt
+	var promptOpts session.PromptOptions
 	if command, args, found := parseSlashCommand(params.Prompt); found {
 		commandResult, handled, err := s.tryExtensionCommand(promptCtx, sess, params.Prompt, command, args)
 		if err != nil {
@@ -470,6 +508,39 @@ func (s *Server) handleSessionPrompt(req *acptypes.Request) error {
 				return err
 			}
 			prompt = handledPrompt
+		} else if handled, err := slashcommands.ParseUndoCommand(command, args); handled {
+			if err != nil {
+				return s.sendError(req.ID, acptypes.ErrCodeInvalidParams, err.Error(), nil)
+			}
+			return s.respondUndo(req.ID, params.SessionID, sess)
+		} else if handled, err := slashcommands.ParseForkCommand(command, args); handled {
+			if err != nil {
+				return s.sendError(req.ID, acptypes.ErrCodeInvalidParams, err.Error(), nil)
+			}
+			return s.respondFork(promptCtx, req.ID, params.SessionID)
+		} else if useConversation, handled, err := slashcommands.ParseUseConversationCommand(command, args); handled {
+			if err != nil {
+				return s.sendError(req.ID, acptypes.ErrCodeInvalidParams, err.Error(), nil)
+			}
+			if useConversation.ConversationID == string(params.SessionID) {
+				return s.sendError(req.ID, acptypes.ErrCodeInvalidParams, "/use-conversation needs a different conversation than the current one", nil)
+			}
+			conversationContext, err := llm.LoadConversationContext(promptCtx, useConversation.ConversationID, conversations.ConversationContextOptions{Messages: useConversation.Messages})
+			if err != nil {
+				return s.sendError(req.ID, acptypes.ErrCodeInvalidParams, err.Error(), nil)
+			}
+			sess.Thread.AddUserMessage(promptCtx, conversationContext.Prompt)
+			metadata := conversations.AddMessageDisplay(sess.Thread.GetMetadata(), conversationContext.Prompt, conversationContext.Display, conversations.MessageDisplayKindConversationContext, slashcommands.UseConversationCommandName)
+			for key, value := range metadata {
+				sess.Thread.SetMetadataValue(key, value)
+			}
+			prompt = replaceCommandPrompt(useConversation.MessageOrDefault(), params.Prompt)
+		} else if think, handled, err := slashcommands.ParseThinkCommand(command, args); handled {
+			if err != nil {
+				return s.sendError(req.ID, acptypes.ErrCodeInvalidParams, err.Error(), nil)
+			}
+			promptOpts.ReasoningEffort = llmtypes.EscalateReasoningEffort(sess.Thread.GetConfig())
+			prompt = replaceCommandPrompt(think.Message, params.Prompt)
 		} else if goalUpdate, handled, err := goals.ParseSlashCommand(command, args, time.Now()); handled {
 			if err != nil {
 				return s.sendError(req.ID, acptypes.ErrCodeInvalidParams, err.Error(), nil)
@@ -493,7 +564,7 @@ func (s *Server) handleSessionPrompt(req *acptypes.Request) error {
 		}
 	}
 
-	stopReason, err := sess.HandlePrompt(promptCtx, prompt, s)
+	stopReason, err := sess.HandlePromptWithOptions(promptCtx, prompt, s, promptOpts)
 	if err != nil {
 		if sess.IsCancelled() || errors.Is(err, context.Canceled) {
 			stopReason = acptypes.StopReasonCancelled
@@ -515,6 +586,46 @@ func (s *Server) handleSessionPrompt(req *acptypes.Request) error {
 	return s.sendResult(req.ID, result)
 }
 
+// respondUndo reverts the file changes of the session's last prompt and ends
+// the turn without calling the model.
+func (s *Server) respondUndo(reqID json.RawMessage, sessionID acptypes.SessionID, sess *session.Session) error {
+	response, err := sess.UndoLastTurn()
+	if err != nil {
+		return s.sendError(reqID, acptypes.ErrCodeInternalError, err.Error(), nil)
+	}
+	return s.respondText(reqID, sessionID, response)
+}
+
+// respondText sends response as the agent's reply and ends the turn.
+func (s *Server) respondText(reqID json.RawMessage, sessionID acptypes.SessionID, response string) error {
+	if err := s.SendUpdate(sessionID, map[string]any{
+		"sessionUpdate": acptypes.UpdateAgentMessageChunk,
+		"content": map[string]any{
+			"type": acptypes.ContentTypeText,
+			"text": response,
+		},
+	}); err != nil {
+		return err
+	}
+	if s.sessionStorage != nil {
+		if err := s.sessionStorage.Flush(sessionID); err != nil {
+			logger.G(s.ctx).WithError(err).Warn("Failed to flush session storage")
+		}
+	}
+	return s.sendResult(reqID, acptypes.PromptResponse{StopReason: acptypes.StopReasonEndTurn})
+}
+
+// respondFork copies the session's conversation into a new session and ends
+// the turn without calling the model. The client loads the new session to
+// continue in the fork.
+func (s *Server) respondFork(ctx context.Context, reqID json.RawMessage, sessionID acptypes.SessionID) error {
+	forkedID, err := s.sessionManager.ForkSession(ctx, sessionID)
+	if err != nil {
+		return s.sendError(reqID, acptypes.ErrCodeInternalError, err.Error(), nil)
+	}
+	return s.respondText(reqID, sessionID, fmt.Sprintf("Forked into session %s. Load it to continue in the fork; this session is unchanged.", forkedID))
+}
+
 func (s *Server) tryExtensionCommand(ctx context.Context, sess *session.Session, originalPrompt []acptypes.ContentBlock, command, args string) (*extensions.RoutedCommandResult, bool, error) {
 	if sess == nil || sess.Extensions == nil {
 		return nil, false, nil
@@ -576,7 +687,7 @@ func (s *Server) applyExtensionCommandResult(reqID json.RawMessage, sessionID ac
 			sess.Thread.SetMetadataValue(key, value)
 		}
 		if result.RecipeName != "" {
-			sess.Thread.SetMetadataValue("recipe_name", result.RecipeName)
+			sess.Thread.SetMetadataValue(convtypes.RecipeNameMetadataKey, result.RecipeName)
 		}
 		return transformExtensionCommandPrompt(result.Prompt, originalPrompt), false, nil
 	case extensions.CommandActionPass, "":
@@ -643,6 +754,21 @@ func transformGoalCommandPrompt(update goals.CommandUpdate, originalPrompt []acp
 	return newPrompt
 }
 
+// replaceCommandPrompt replaces a built-in command, such as think, with the
+// message it wraps, keeping any other prompt blocks.
+func replaceCommandPrompt(message string, originalPrompt []acptypes.ContentBlock) []acptypes.ContentBlock {
+	newPrompt := make([]acptypes.ContentBlock, 0, len(originalPrompt))
+	replaced := false
+	for _, block := range originalPrompt {
+		if !replaced && block.Type == acptypes.ContentTypeText && strings.HasPrefix(strings.TrimSpace(block.Text), "/") {
+			block.Text = message
+			replaced = true
+		}
+		newPrompt = append(newPrompt, block)
+	}
+	return newPrompt
+}
+
 func (s *Server) handleSetMode(req *acptypes.Request) error {
 	return s.sendError(req.ID, acptypes.ErrCodeMethodNotFound, "session/set_mode not supported", nil)
 }
@@ -808,9 +934,10 @@ func (s *Server) availableCommandsFromSlashCommands(availableCommands []slashcom
 		cmd := acptypes.AvailableCommand{
 			Name:        availableCommand.Name,
 			Description: availableCommand.Description,
-			Input: &acptypes.AvailableCommandInput{
-				Hint: availableCommand.Hint,
-			},
+		}
+		// Commands without a hint, such as /undo, take no input.
+		if availableCommand.Hint != "" {
+			cmd.Input = &acptypes.AvailableCommandInput{Hint: availableCommand.Hint}
 		}
 		commands = append(commands, cmd)
 	}
@@ -21,6 +21,7 @@ import (
 	"github.com/jingkaihe/kodelet/pkg/extensions"
 	"github.com/jingkaihe/kodelet/pkg/fragments"
 	"github.com/jingkaihe/kodelet/pkg/goals"
+	"github.com/jingkaihe/kodelet/pkg/slashcommands"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -919,6 +920,10 @@ func TestServer_GetAvailableCommands(t *testing.T) {
 	for _, cmd := range commands {
 		assert.NotEmpty(t, cmd.Name)
 		assert.NotEmpty(t, cmd.Description)
+		if cmd.Name == "undo" || cmd.Name == "fork" {
+			assert.Nil(t, cmd.Input)
+			continue
+		}
 		assert.NotNil(t, cmd.Input)
 		assert.NotEmpty(t, cmd.Input.Hint)
 	}
@@ -1308,6 +1313,22 @@ func TestServer_TransformSlashCommandPrompt(t *testing.T) {
 		assert.Equal(t, []string{"data:image/png;base64,base64imagedata"}, images)
 	})
 
+	t.Run("transforms think command", func(t *testing.T) {
+		think, handled, err := slashcommands.ParseThinkCommand("think", "harder why is this slow?")
+		require.NoError(t, err)
+		require.True(t, handled)
+
+		originalPrompt := []acptypes.ContentBlock{
+			{Type: acptypes.ContentTypeText, Text: "/think harder why is this slow?"},
+			{Type: acptypes.ContentTypeImage, Data: "base64imagedata", MimeType: "image/png"},
+		}
+
+		result := replaceCommandPrompt(think.Message, originalPrompt)
+		require.Len(t, result, 2)
+		assert.Equal(t, "why is this slow?", result[0].Text)
+		assert.Equal(t, acptypes.ContentTypeImage, result[1].Type)
+	})
+
 	t.Run("returns error for unknown recipe with available recipes", func(t *testing.T) {
 		if server.fragmentProcessor == nil {
 			t.Skip("fragment processor not available")
@@ -1471,3 +1492,50 @@ func writeACPServerRPCResponse(id int64, result any, rpcErr any) {
 	payload, _ := json.Marshal(response)
 	fmt.Fprintf(os.Stdout, "Content-Length: %d\r\n\r\n%s", len(payload), payload)
 }
+
+func TestServer_IDEContext(t *testing.T) {
+	workspace := t.TempDir()
+	output := bytes.NewBuffer(nil)
+	server := NewServer(
+		WithInput(bytes.NewBuffer(nil)),
+		WithOutput(output),
+		WithContext(context.Background()),
+		WithConfig(&ServerConfig{Provider: "anthropic", Model: "claude-test", NoSkills: true, NoExtensions: true}),
+	)
+	t.Cleanup(func() { server.Shutdown() })
+	server.initialized.Store(true)
+
+	sess, err := server.sessionManager.NewSession(context.Background(), acptypes.NewSessionRequest{CWD: workspace})
+	require.NoError(t, err)
+
+	request, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  acptypes.MethodIDEContext,
+		"params":  acptypes.IDEContextRequest{SessionID: sess.ID, ActiveFile: "main.go"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, server.handleMessage(request))
+	response := readJSONRPCMessage(t, output)
+	assert.Nil(t, response["error"])
+	assert.Contains(t, sess.TakeIDEContext(), "Active file: main.go")
+
+	notification, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"method":  acptypes.MethodIDEContext,
+		"params":  acptypes.IDEContextRequest{SessionID: sess.ID, OpenFiles: []string{"a.go"}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, server.handleMessage(notification))
+	assert.Contains(t, sess.TakeIDEContext(), "- a.go")
+
+	unknown, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      2,
+		"method":  acptypes.MethodIDEContext,
+		"params":  acptypes.IDEContextRequest{SessionID: "missing"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, server.handleMessage(unknown))
+	assertRPCErrorCode(t, readJSONRPCMessage(t, output), acptypes.ErrCodeInvalidParams)
+}
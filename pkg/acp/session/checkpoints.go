@@ -0,0 +1,57 @@
+package session
+
+import (
+	"context"
+
+	"github.com/jingkaihe/kodelet/pkg/checkpoints"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	pkgerrors "github.com/pkg/errors"
+)
+
+// openCheckpoints opens the checkpoint store of a session. Each prompt is one
+// cycle, so `/undo` reverts the file changes of the last prompt. It returns
+// nil when checkpoints are not available.
+func openCheckpoints(ctx context.Context, sessionID string) *checkpoints.Store {
+	root, err := checkpoints.DefaultDir()
+	if err == nil {
+		var store *checkpoints.Store
+		if store, err = checkpoints.Open(root, sessionID); err == nil {
+			return store
+		}
+	}
+	logger.G(ctx).WithField("session_id", sessionID).WithError(err).Warn("file checkpoints are disabled for this session")
+	return nil
+}
+
+// UndoLastTurn reverts the files changed by the last prompt that changed any
+// and returns a message for the user. The agent is told about the reverted
+// files with the next prompt.
+func (s *Session) UndoLastTurn() (string, error) {
+	if s.checkpoints == nil {
+		return "", pkgerrors.New("file checkpoints are not available")
+	}
+	restored, err := s.checkpoints.UndoLastCycle()
+	if pkgerrors.Is(err, checkpoints.ErrNothingToUndo) {
+		return "Nothing to undo.", nil
+	}
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "failed to undo file changes")
+	}
+	return "Reverted the file changes of the last turn:\n" + checkpoints.FormatRestored(restored), nil
+}
+
+// beginCheckpointCycle starts the checkpoint cycle of a prompt and returns the
+// note about files reverted since the last prompt, if any.
+func (s *Session) beginCheckpointCycle(ctx context.Context) string {
+	if s.checkpoints == nil {
+		return ""
+	}
+	if err := s.checkpoints.BeginTurn(); err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to record the checkpoint turn")
+	}
+	notice, err := s.checkpoints.TakeUndoNotice()
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to clear undo notice")
+	}
+	return notice
+}
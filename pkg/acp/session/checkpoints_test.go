@@ -0,0 +1,39 @@
+package session
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/checkpoints"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionUndoLastTurn(t *testing.T) {
+	store, err := checkpoints.Open(t.TempDir(), "session-1")
+	require.NoError(t, err)
+	sess := &Session{ID: "session-1", checkpoints: store}
+
+	response, err := sess.UndoLastTurn()
+	require.NoError(t, err)
+	assert.Equal(t, "Nothing to undo.", response)
+
+	path := filepath.Join(t.TempDir(), "created.txt")
+	assert.Empty(t, sess.beginCheckpointCycle(context.Background()))
+	require.NoError(t, store.Snapshot("file_write", []string{path}))
+	require.NoError(t, os.WriteFile(path, []byte("new\n"), 0o644))
+
+	response, err = sess.UndoLastTurn()
+	require.NoError(t, err)
+	assert.Equal(t, "Reverted the file changes of the last turn:\n- "+path+" (removed)", response)
+	assert.NoFileExists(t, path)
+
+	notice := sess.beginCheckpointCycle(context.Background())
+	assert.Contains(t, notice, path)
+	assert.Empty(t, sess.beginCheckpointCycle(context.Background()))
+
+	_, err = (&Session{}).UndoLastTurn()
+	assert.EqualError(t, err, "file checkpoints are not available")
+}
@@ -0,0 +1,81 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jingkaihe/kodelet/pkg/acp/acptypes"
+)
+
+// maxIDESelectionChars caps how much selected text is added to a prompt.
+const maxIDESelectionChars = 8000
+
+// maxIDEDiagnostics caps how many diagnostics are added to a prompt.
+const maxIDEDiagnostics = 50
+
+// SetIDEContext stores the editor state pushed by the client. It replaces any
+// earlier push that has not been sent yet.
+func (s *Session) SetIDEContext(ideContext acptypes.IDEContextRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ideContext = &ideContext
+}
+
+// TakeIDEContext returns the pending editor state as prompt text and clears
+// it, so each push is added to one prompt.
+func (s *Session) TakeIDEContext() string {
+	s.mu.Lock()
+	ideContext := s.ideContext
+	s.ideContext = nil
+	s.mu.Unlock()
+	if ideContext == nil {
+		return ""
+	}
+	return formatIDEContext(*ideContext)
+}
+
+// formatIDEContext renders editor state as a block the model can read ahead
+// of the user's message. It returns "" when there is nothing to report.
+func formatIDEContext(ideContext acptypes.IDEContextRequest) string {
+	var b strings.Builder
+	if ideContext.ActiveFile != "" {
+		fmt.Fprintf(&b, "Active file: %s\n", ideContext.ActiveFile)
+	}
+	if sel := ideContext.Selection; sel != nil && sel.StartLine > 0 {
+		fmt.Fprintf(&b, "Selected lines: %d-%d\n", sel.StartLine, max(sel.EndLine, sel.StartLine))
+		if text := sel.Text; text != "" {
+			if len(text) > maxIDESelectionChars {
+				text = text[:maxIDESelectionChars] + "\n... (selection truncated)"
+			}
+			fmt.Fprintf(&b, "Selected text:\n```\n%s\n```\n", text)
+		}
+	}
+	if len(ideContext.OpenFiles) > 0 {
+		b.WriteString("Open files:\n")
+		for _, path := range ideContext.OpenFiles {
+			fmt.Fprintf(&b, "- %s\n", path)
+		}
+	}
+	if len(ideContext.Diagnostics) > 0 {
+		b.WriteString("Diagnostics:\n")
+		for i, diagnostic := range ideContext.Diagnostics {
+			if i == maxIDEDiagnostics {
+				fmt.Fprintf(&b, "- ... %d more\n", len(ideContext.Diagnostics)-maxIDEDiagnostics)
+				break
+			}
+			location := diagnostic.Path
+			if diagnostic.Line > 0 {
+				location = fmt.Sprintf("%s:%d", location, diagnostic.Line)
+			}
+			severity := ""
+			if diagnostic.Severity != "" {
+				severity = " [" + diagnostic.Severity + "]"
+			}
+			fmt.Fprintf(&b, "- %s%s %s\n", location, severity, diagnostic.Message)
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "<ide_context>\n" + b.String() + "</ide_context>"
+}
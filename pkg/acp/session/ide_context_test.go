@@ -0,0 +1,57 @@
+package session
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/acp/acptypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatIDEContext(t *testing.T) {
+	assert.Empty(t, formatIDEContext(acptypes.IDEContextRequest{SessionID: "s"}))
+
+	text := formatIDEContext(acptypes.IDEContextRequest{
+		ActiveFile: "pkg/main.go",
+		Selection:  &acptypes.IDESelection{StartLine: 10, EndLine: 12, Text: "func main() {}"},
+		OpenFiles:  []string{"pkg/main.go", "README.md"},
+		Diagnostics: []acptypes.IDEDiagnostic{
+			{Path: "pkg/main.go", Line: 11, Severity: "error", Message: "undefined: foo"},
+			{Path: "README.md", Message: "spelling"},
+		},
+	})
+	assert.Equal(t, "<ide_context>\n"+
+		"Active file: pkg/main.go\n"+
+		"Selected lines: 10-12\n"+
+		"Selected text:\n```\nfunc main() {}\n```\n"+
+		"Open files:\n- pkg/main.go\n- README.md\n"+
+		"Diagnostics:\n- pkg/main.go:11 [error] undefined: foo\n- README.md spelling\n"+
+		"</ide_context>", text)
+}
+
+func TestFormatIDEContextTruncates(t *testing.T) {
+	diagnostics := make([]acptypes.IDEDiagnostic, maxIDEDiagnostics+3)
+	for i := range diagnostics {
+		diagnostics[i] = acptypes.IDEDiagnostic{Path: "a.go", Message: "problem"}
+	}
+	text := formatIDEContext(acptypes.IDEContextRequest{
+		Selection:   &acptypes.IDESelection{StartLine: 1, Text: strings.Repeat("x", maxIDESelectionChars+10)},
+		Diagnostics: diagnostics,
+	})
+	assert.Contains(t, text, "Selected lines: 1-1\n")
+	assert.Contains(t, text, "... (selection truncated)")
+	assert.Contains(t, text, "- ... 3 more\n")
+	assert.Equal(t, maxIDEDiagnostics, strings.Count(text, "- a.go problem"))
+}
+
+func TestSessionTakeIDEContext(t *testing.T) {
+	sess := &Session{ID: "s"}
+	assert.Empty(t, sess.TakeIDEContext())
+
+	sess.SetIDEContext(acptypes.IDEContextRequest{ActiveFile: "old.go"})
+	sess.SetIDEContext(acptypes.IDEContextRequest{ActiveFile: "new.go"})
+	text := sess.TakeIDEContext()
+	assert.Contains(t, text, "new.go")
+	assert.NotContains(t, text, "old.go")
+	assert.Empty(t, sess.TakeIDEContext(), "context is sent once")
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/jingkaihe/kodelet/pkg/acp/acptypes"
 	"github.com/jingkaihe/kodelet/pkg/acp/bridge"
+	"github.com/jingkaihe/kodelet/pkg/checkpoints"
 	"github.com/jingkaihe/kodelet/pkg/conversations"
 	"github.com/jingkaihe/kodelet/pkg/extensions"
 	"github.com/jingkaihe/kodelet/pkg/llm"
@@ -31,9 +32,11 @@ type Session struct {
 	maxTurns     int
 	compactRatio float64
 
-	mu         sync.Mutex
-	cancelFunc context.CancelFunc
-	cancelled  bool
+	mu          sync.Mutex
+	cancelFunc  context.CancelFunc
+	cancelled   bool
+	ideContext  *acptypes.IDEContextRequest
+	checkpoints *checkpoints.Store
 }
 
 // Cancel cancels the current prompt execution
@@ -73,8 +76,20 @@ type UpdateSender interface {
 	SendUpdate(sessionID acptypes.SessionID, update any) error
 }
 
+// PromptOptions adjusts how a single prompt is sent.
+type PromptOptions struct {
+	// ReasoningEffort overrides the session's reasoning effort for this prompt only.
+	ReasoningEffort string
+}
+
 // HandlePrompt processes a prompt and returns the stop reason
 func (s *Session) HandlePrompt(ctx context.Context, prompt []acptypes.ContentBlock, sender UpdateSender) (acptypes.StopReason, error) {
+	return s.HandlePromptWithOptions(ctx, prompt, sender, PromptOptions{})
+}
+
+// HandlePromptWithOptions processes a prompt with per-prompt options and
+// returns the stop reason
+func (s *Session) HandlePromptWithOptions(ctx context.Context, prompt []acptypes.ContentBlock, sender UpdateSender, opts PromptOptions) (acptypes.StopReason, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	s.mu.Lock()
 	s.cancelFunc = cancel
@@ -88,14 +103,24 @@ func (s *Session) HandlePrompt(ctx context.Context, prompt []acptypes.ContentBlo
 	}()
 
 	message, images := bridge.ContentBlocksToMessage(prompt)
+	if ideContext := s.TakeIDEContext(); ideContext != "" {
+		message = ideContext + "\n\n" + message
+	}
+	if notice := s.beginCheckpointCycle(ctx); notice != "" {
+		message = notice + "\n\n" + message
+	}
+	if caller, ok := sender.(ClientCaller); ok {
+		ctx = extensions.ContextWithUIInputBroker(ctx, newPermissionBroker(caller, s.ID))
+	}
 
 	handler := bridge.NewACPMessageHandler(sender, s.ID)
 
 	_, err := s.Thread.SendMessage(ctx, message, handler, llmtypes.MessageOpt{
-		PromptCache:  true,
-		Images:       images,
-		MaxTurns:     s.maxTurns,
-		CompactRatio: s.compactRatio,
+		PromptCache:     true,
+		Images:          images,
+		MaxTurns:        s.maxTurns,
+		CompactRatio:    s.compactRatio,
+		ReasoningEffort: opts.ReasoningEffort,
 	})
 
 	if s.IsCancelled() {
@@ -257,6 +282,11 @@ func (m *Manager) NewSession(ctx context.Context, req acptypes.NewSessionRequest
 		stateOpts = append(stateOpts, tools.WithExtensionTools(extensionRuntime.Tools()))
 	}
 
+	store := openCheckpoints(ctx, thread.GetConversationID())
+	if store != nil {
+		stateOpts = append(stateOpts, tools.WithCheckpoints(store))
+	}
+
 	state := tools.NewBasicState(ctx, stateOpts...)
 	thread.SetState(state)
 	thread.EnablePersistence(ctx, true)
@@ -269,6 +299,7 @@ func (m *Manager) NewSession(ctx context.Context, req acptypes.NewSessionRequest
 		CWD:          req.CWD,
 		maxTurns:     m.config.MaxTurns,
 		compactRatio: m.config.CompactRatio,
+		checkpoints:  store,
 	}
 
 	m.storeSession(ctx, session)
@@ -276,6 +307,20 @@ func (m *Manager) NewSession(ctx context.Context, req acptypes.NewSessionRequest
 	return session, nil
 }
 
+// ForkSession saves a copy of a session's conversation under a new session ID.
+// The copy can be opened with session/load; the original session is unchanged.
+func (m *Manager) ForkSession(ctx context.Context, id acptypes.SessionID) (acptypes.SessionID, error) {
+	if m.store == nil {
+		return "", pkgerrors.New("conversation store not available")
+	}
+
+	forked, err := m.store.Fork(ctx, string(id))
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "failed to fork conversation")
+	}
+	return acptypes.SessionID(forked.ID), nil
+}
+
 // LoadSession loads an existing session
 func (m *Manager) LoadSession(ctx context.Context, req acptypes.LoadSessionRequest) (*Session, error) {
 	if m.store == nil {
@@ -317,6 +362,11 @@ func (m *Manager) LoadSession(ctx context.Context, req acptypes.LoadSessionReque
 		stateOpts = append(stateOpts, tools.WithExtensionTools(extensionRuntime.Tools()))
 	}
 
+	store := openCheckpoints(ctx, string(req.SessionID))
+	if store != nil {
+		stateOpts = append(stateOpts, tools.WithCheckpoints(store))
+	}
+
 	state := tools.NewBasicState(ctx, stateOpts...)
 	thread.SetState(state)
 	thread.EnablePersistence(ctx, true)
@@ -329,6 +379,7 @@ func (m *Manager) LoadSession(ctx context.Context, req acptypes.LoadSessionReque
 		CWD:          req.CWD,
 		maxTurns:     m.config.MaxTurns,
 		compactRatio: m.config.CompactRatio,
+		checkpoints:  store,
 	}
 
 	m.storeSession(ctx, session)
@@ -0,0 +1,97 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/jingkaihe/kodelet/pkg/acp/acptypes"
+	"github.com/jingkaihe/kodelet/pkg/extensions"
+	pkgerrors "github.com/pkg/errors"
+)
+
+// ClientCaller makes JSON-RPC requests to the ACP client.
+type ClientCaller interface {
+	CallClient(ctx context.Context, method string, params any) (json.RawMessage, error)
+}
+
+// Permission option IDs offered for confirmations.
+const (
+	permissionOptionAllow  = "allow"
+	permissionOptionReject = "reject"
+)
+
+// permissionBroker asks the ACP client to approve confirmations, such as
+// exceeding change limits or extension prompts, with session/request_permission.
+// Free-form input and selection have no ACP equivalent and are reported as
+// unavailable.
+type permissionBroker struct {
+	caller    ClientCaller
+	sessionID acptypes.SessionID
+}
+
+func newPermissionBroker(caller ClientCaller, sessionID acptypes.SessionID) *permissionBroker {
+	return &permissionBroker{caller: caller, sessionID: sessionID}
+}
+
+func (b *permissionBroker) Input(context.Context, extensions.UIInputRequest) (extensions.UIInputResponse, error) {
+	return extensions.UIInputResponse{Status: extensions.UIInputStatusUnavailable, Reason: "acp input is not available"}, nil
+}
+
+func (b *permissionBroker) Select(context.Context, extensions.UISelectRequest) (extensions.UIInputResponse, error) {
+	return extensions.UIInputResponse{Status: extensions.UIInputStatusUnavailable, Reason: "acp select is not available"}, nil
+}
+
+func (b *permissionBroker) Confirm(ctx context.Context, request extensions.UIConfirmRequest) (extensions.UIInputResponse, error) {
+	id := strings.TrimSpace(request.ID)
+	if id == "" {
+		id = extensions.NewUIInputRequestID()
+	}
+	allow := request.ConfirmButtonText
+	if allow == "" {
+		allow = "Allow"
+	}
+	reject := request.CancelButtonText
+	if reject == "" {
+		reject = "Reject"
+	}
+
+	params := acptypes.RequestPermissionRequest{
+		SessionID: b.sessionID,
+		ToolCall: acptypes.PermissionToolCall{
+			ToolCallID: id,
+			Title:      request.Title,
+			Kind:       acptypes.ToolKindOther,
+			Status:     acptypes.ToolStatusPending,
+		},
+		Options: []acptypes.PermissionOption{
+			{OptionID: permissionOptionAllow, Name: allow, Kind: acptypes.PermissionAllowOnce},
+			{OptionID: permissionOptionReject, Name: reject, Kind: acptypes.PermissionRejectOnce},
+		},
+	}
+	if request.Message != "" {
+		params.ToolCall.Content = []acptypes.ToolCallContent{{
+			Type:    "content",
+			Content: acptypes.ContentBlock{Type: acptypes.ContentTypeText, Text: request.Message},
+		}}
+	}
+
+	raw, err := b.caller.CallClient(ctx, acptypes.MethodRequestPermission, params)
+	if err != nil {
+		if ctx.Err() != nil {
+			return extensions.UIInputResponse{Status: extensions.UIInputStatusDismissed, Reason: ctx.Err().Error()}, nil
+		}
+		return extensions.UIInputResponse{}, pkgerrors.Wrap(err, "failed to request permission from client")
+	}
+	var response acptypes.RequestPermissionResponse
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return extensions.UIInputResponse{}, pkgerrors.Wrap(err, "failed to decode permission response")
+	}
+	if response.Outcome.Outcome != acptypes.PermissionOutcomeSelected {
+		return extensions.UIInputResponse{Status: extensions.UIInputStatusDismissed}, nil
+	}
+	return extensions.UIInputResponse{
+		Status:    extensions.UIInputStatusSubmitted,
+		Confirmed: response.Outcome.OptionID == permissionOptionAllow,
+	}, nil
+}
@@ -0,0 +1,100 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/acp/acptypes"
+	"github.com/jingkaihe/kodelet/pkg/extensions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClientCaller struct {
+	method   string
+	params   any
+	response string
+	err      error
+}
+
+func (f *fakeClientCaller) CallClient(_ context.Context, method string, params any) (json.RawMessage, error) {
+	f.method = method
+	f.params = params
+	if f.err != nil {
+		return nil, f.err
+	}
+	return json.RawMessage(f.response), nil
+}
+
+func TestPermissionBrokerConfirm(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     extensions.UIInputResponse
+	}{
+		{
+			name:     "allowed",
+			response: `{"outcome":{"outcome":"selected","optionId":"allow"}}`,
+			want:     extensions.UIInputResponse{Status: extensions.UIInputStatusSubmitted, Confirmed: true},
+		},
+		{
+			name:     "rejected",
+			response: `{"outcome":{"outcome":"selected","optionId":"reject"}}`,
+			want:     extensions.UIInputResponse{Status: extensions.UIInputStatusSubmitted},
+		},
+		{
+			name:     "cancelled",
+			response: `{"outcome":{"outcome":"cancelled"}}`,
+			want:     extensions.UIInputResponse{Status: extensions.UIInputStatusDismissed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caller := &fakeClientCaller{response: tt.response}
+			broker := newPermissionBroker(caller, "conv-1")
+
+			response, err := broker.Confirm(context.Background(), extensions.UIConfirmRequest{
+				ID:                "req-1",
+				Title:             "Change limit exceeded",
+				Message:           "Allow more changes?",
+				ConfirmButtonText: "Continue",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, response)
+
+			assert.Equal(t, acptypes.MethodRequestPermission, caller.method)
+			params, ok := caller.params.(acptypes.RequestPermissionRequest)
+			require.True(t, ok)
+			assert.Equal(t, acptypes.SessionID("conv-1"), params.SessionID)
+			assert.Equal(t, "req-1", params.ToolCall.ToolCallID)
+			assert.Equal(t, "Change limit exceeded", params.ToolCall.Title)
+			require.Len(t, params.ToolCall.Content, 1)
+			assert.Equal(t, "Allow more changes?", params.ToolCall.Content[0].Content.Text)
+			assert.Equal(t, []acptypes.PermissionOption{
+				{OptionID: "allow", Name: "Continue", Kind: acptypes.PermissionAllowOnce},
+				{OptionID: "reject", Name: "Reject", Kind: acptypes.PermissionRejectOnce},
+			}, params.Options)
+		})
+	}
+}
+
+func TestPermissionBrokerConfirmClientError(t *testing.T) {
+	broker := newPermissionBroker(&fakeClientCaller{err: errors.New("client returned error")}, "conv-1")
+	_, err := broker.Confirm(context.Background(), extensions.UIConfirmRequest{Title: "Confirm"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to request permission from client")
+}
+
+func TestPermissionBrokerInputAndSelectUnavailable(t *testing.T) {
+	broker := newPermissionBroker(&fakeClientCaller{}, "conv-1")
+
+	response, err := broker.Input(context.Background(), extensions.UIInputRequest{Title: "Name"})
+	require.NoError(t, err)
+	assert.Equal(t, extensions.UIInputStatusUnavailable, response.Status)
+
+	response, err = broker.Select(context.Background(), extensions.UISelectRequest{Title: "Pick", Options: []string{"a"}})
+	require.NoError(t, err)
+	assert.Equal(t, extensions.UIInputStatusUnavailable, response.Status)
+}
@@ -214,6 +214,14 @@ func (f *fakeConversationStore) Query(context.Context, conversationtypes.QueryOp
 	return conversationtypes.QueryResult{}, nil
 }
 
+func (f *fakeConversationStore) Fork(context.Context, string) (conversationtypes.ConversationRecord, error) {
+	return conversationtypes.ConversationRecord{}, errors.New("not implemented")
+}
+
+func (f *fakeConversationStore) Search(context.Context, string, conversationtypes.QueryOptions) (conversationtypes.SearchResult, error) {
+	return conversationtypes.SearchResult{}, nil
+}
+
 func (f *fakeConversationStore) Close() error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
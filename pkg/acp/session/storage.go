@@ -11,6 +11,8 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/jingkaihe/kodelet/pkg/acp/acptypes"
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/conversations/sqlite"
 	"github.com/jingkaihe/kodelet/pkg/db"
 )
 
@@ -69,10 +71,12 @@ type sessionState struct {
 	pending *pendingUpdate
 }
 
-// Storage handles persistence of ACP session updates using SQLite.
+// Storage handles persistence of ACP session updates using SQLite. Updates
+// are encrypted at rest like the conversations of their sessions.
 type Storage struct {
 	dbPath string
 	db     *sqlx.DB
+	sealer *sqlite.Sealer
 
 	sessionsMu sync.Mutex
 	sessions   map[acptypes.SessionID]*sessionState
@@ -107,6 +111,12 @@ func NewStorage(ctx context.Context, opts ...StorageOption) (*Storage, error) {
 		s.dbPath = dbPath
 	}
 
+	sealer, err := conversations.NewSealer()
+	if err != nil {
+		return nil, err
+	}
+	s.sealer = sealer
+
 	sqlDB, err := db.Open(ctx, s.dbPath)
 	if err != nil {
 		return nil, err
@@ -196,6 +206,9 @@ func (s *Storage) writeUpdate(sessionID acptypes.SessionID, update any) error {
 	if err != nil {
 		return errors.Wrap(err, "failed to marshal session update entry")
 	}
+	if data, err = s.sealer.Seal(string(sessionID), data); err != nil {
+		return err
+	}
 
 	_, err = s.db.Exec(
 		"INSERT INTO acp_session_updates (session_id, update_data, created_at) VALUES (?, ?, ?)",
@@ -226,8 +239,12 @@ func (s *Storage) ReadUpdates(sessionID acptypes.SessionID) ([]StoredUpdate, err
 
 	updates := make([]StoredUpdate, 0, len(rows))
 	for _, row := range rows {
+		data, err := s.sealer.Open(string(sessionID), []byte(row.UpdateData))
+		if err != nil {
+			return nil, err
+		}
 		var update StoredUpdate
-		if err := json.Unmarshal([]byte(row.UpdateData), &update); err != nil {
+		if err := json.Unmarshal(data, &update); err != nil {
 			return nil, errors.Wrap(err, "failed to unmarshal session update")
 		}
 		updates = append(updates, update)
@@ -1,12 +1,15 @@
 package session
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"path/filepath"
 	"sync"
 	"testing"
 
 	"github.com/jingkaihe/kodelet/pkg/acp/acptypes"
+	"github.com/jingkaihe/kodelet/pkg/conversations"
 	"github.com/jingkaihe/kodelet/pkg/db"
 	"github.com/jingkaihe/kodelet/pkg/db/migrations"
 	"github.com/stretchr/testify/assert"
@@ -410,6 +413,34 @@ func TestStorage_Persistence(t *testing.T) {
 	assert.Contains(t, string(updates[0].Update), "Persisted message")
 }
 
+func TestStorage_EncryptsUpdatesAtRest(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "sessions.db")
+	setupTestDB(t, dbPath)
+	t.Setenv(conversations.EncryptionKeyEnv, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)))
+	require.NoError(t, conversations.EnableEncryption(ctx, conversations.KeySourceEnv))
+	t.Cleanup(conversations.DisableEncryption)
+
+	storage, err := NewStorage(ctx, WithDBPath(dbPath))
+	require.NoError(t, err)
+	defer storage.Close()
+
+	sessionID := acptypes.SessionID("encrypted-session")
+	require.NoError(t, storage.AppendUpdate(sessionID, map[string]any{
+		"sessionUpdate": "tool_call_update",
+		"content":       map[string]any{"type": "text", "text": "AWS_SECRET=hunter2"},
+	}))
+
+	var stored string
+	require.NoError(t, storage.db.Get(&stored, "SELECT update_data FROM acp_session_updates WHERE session_id = ?", string(sessionID)))
+	assert.NotContains(t, stored, "hunter2")
+
+	updates, err := storage.ReadUpdates(sessionID)
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	assert.Contains(t, string(updates[0].Update), "hunter2")
+}
+
 func TestStorage_MultipleSessions(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "sessions.db")
@@ -0,0 +1,166 @@
+// Package agent provides a stable facade for embedding Kodelet agents in Go
+// programs. It wires provider selection, tool state, skills, persistence and
+// message handling so callers only deal with a Config, a prompt and the
+// resulting output or event stream.
+//
+// Stability: the exported identifiers of this package follow semantic
+// versioning. Fields and functions are only added, never removed or changed
+// incompatibly, within a major version. Types re-exported from internal
+// packages (such as llmtypes.Config and tooltypes.Tool) carry the stability of
+// their own packages.
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/jingkaihe/kodelet/pkg/llm"
+	"github.com/jingkaihe/kodelet/pkg/tools"
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/pkg/errors"
+)
+
+// Config configures an embedded agent.
+type Config struct {
+	// LLM is the provider and model configuration. An empty provider is
+	// inferred from the model; programs that use the Kodelet config files can load it with
+	// llm.GetConfigFromViper.
+	LLM llmtypes.Config
+	// WorkingDirectory is where tools run and context files are discovered.
+	// It defaults to the process working directory.
+	WorkingDirectory string
+	// ConversationID resumes an existing conversation when Persist is set, or
+	// names a new one. A new ID is generated when empty.
+	ConversationID string
+	// Persist saves the conversation to the Kodelet conversation store so it
+	// can be resumed with `kodelet run --resume`.
+	Persist bool
+	// NoTools runs the agent without any tools.
+	NoTools bool
+	// Tools are registered alongside the built-in tools.
+	Tools []tooltypes.Tool
+}
+
+// RunOptions tunes a single Run or Stream call.
+type RunOptions struct {
+	// Images are local paths or HTTPS URLs attached to the prompt.
+	Images []string
+	// MaxTurns limits the number of model turns; 0 means no limit.
+	MaxTurns int
+	// UseWeakModel sends the prompt to the configured weak model.
+	UseWeakModel bool
+}
+
+// Result is the outcome of a completed Run or Stream call.
+type Result struct {
+	// Output is the final assistant text.
+	Output string
+	// ConversationID identifies the conversation the prompt was sent to.
+	ConversationID string
+	// Usage is the cumulative token usage and cost of the conversation.
+	Usage llmtypes.Usage
+}
+
+// Agent is an embedded Kodelet agent bound to a single conversation. Calls to
+// Run and Stream are serialized, so each prompt sees the previous turns.
+type Agent struct {
+	mu         sync.Mutex
+	config     Config
+	thread     llmtypes.Thread
+	persisting bool
+}
+
+// NewAgent creates an agent from config. The caller must Close the agent to
+// release provider resources.
+func NewAgent(config Config) (*Agent, error) {
+	if err := llm.ResolveProvider(&config.LLM); err != nil {
+		return nil, err
+	}
+
+	workingDir := strings.TrimSpace(config.WorkingDirectory)
+	if workingDir == "" {
+		workingDir = config.LLM.WorkingDirectory
+	}
+
+	if config.NoTools {
+		config.LLM.AllowedTools = []string{tools.NoToolsMarker}
+	}
+
+	thread, err := llm.NewThread(config.LLM)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create LLM thread")
+	}
+
+	thread.SetState(tools.NewBasicState(context.Background(),
+		tools.WithWorkingDirectory(workingDir),
+		tools.WithLLMConfig(thread.GetConfig()),
+		tools.WithExtensionTools(config.Tools),
+		tools.WithMainTools(),
+		tools.WithSkillTool(),
+	))
+
+	conversationID := strings.TrimSpace(config.ConversationID)
+	if conversationID == "" {
+		conversationID = convtypes.GenerateID()
+	}
+	thread.SetConversationID(conversationID)
+
+	return &Agent{config: config, thread: thread}, nil
+}
+
+// ConversationID returns the ID of the agent's conversation.
+func (a *Agent) ConversationID() string {
+	return a.thread.GetConversationID()
+}
+
+// Usage returns the cumulative token usage and cost of the conversation.
+func (a *Agent) Usage() llmtypes.Usage {
+	return a.thread.GetUsage()
+}
+
+// Run sends prompt to the agent, runs tools until the model finishes, and
+// returns the final output.
+func (a *Agent) Run(ctx context.Context, prompt string, opts RunOptions) (*Result, error) {
+	return a.send(ctx, prompt, opts, &llmtypes.StringCollectorHandler{Silent: true})
+}
+
+// Close releases the provider resources held by the agent.
+func (a *Agent) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return llm.CloseThread(a.thread)
+}
+
+func (a *Agent) send(ctx context.Context, prompt string, opts RunOptions, handler llmtypes.MessageHandler) (*Result, error) {
+	if strings.TrimSpace(prompt) == "" {
+		return nil, errors.New("prompt is required")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.config.Persist && !a.persisting {
+		a.thread.EnablePersistence(ctx, true)
+		a.persisting = true
+	}
+
+	output, err := a.thread.SendMessage(ctx, prompt, handler, llmtypes.MessageOpt{
+		PromptCache:        true,
+		Images:             opts.Images,
+		MaxTurns:           opts.MaxTurns,
+		CompactRatio:       a.config.LLM.CompactRatio,
+		UseWeakModel:       opts.UseWeakModel,
+		NoSaveConversation: !a.config.Persist,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to run agent")
+	}
+	return &Result{
+		Output:         output,
+		ConversationID: a.thread.GetConversationID(),
+		Usage:          a.thread.GetUsage(),
+	}, nil
+}
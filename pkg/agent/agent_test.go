@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/invopop/jsonschema"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type fakeTool struct{}
+
+func (fakeTool) GenerateSchema() *jsonschema.Schema { return &jsonschema.Schema{Type: "object"} }
+func (fakeTool) Name() string                       { return "lookup_ticket" }
+func (fakeTool) Description() string                { return "Look up a ticket" }
+func (fakeTool) ValidateInput(tooltypes.State, string) error {
+	return nil
+}
+
+func (fakeTool) Execute(context.Context, tooltypes.State, string) tooltypes.ToolResult {
+	return tooltypes.BaseToolResult{Result: "ticket"}
+}
+
+func (fakeTool) TracingKVs(string) ([]attribute.KeyValue, error) {
+	return nil, nil
+}
+
+func newTestAgent(t *testing.T, config Config) *Agent {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	config.LLM.Provider = "anthropic"
+	config.LLM.Model = "claude-sonnet-4-6"
+	a, err := NewAgent(config)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = a.Close() })
+	return a
+}
+
+func toolNames(a *Agent) []string {
+	var names []string
+	for _, tool := range a.thread.GetState().Tools() {
+		names = append(names, tool.Name())
+	}
+	return names
+}
+
+func TestNewAgentResolvesProvider(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+
+	a, err := NewAgent(Config{LLM: llmtypes.Config{Model: "claude-sonnet-4-6"}})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = a.Close() })
+	assert.Equal(t, "anthropic", a.thread.GetConfig().Provider)
+
+	_, err = NewAgent(Config{LLM: llmtypes.Config{Provider: "openai", Model: "claude-sonnet-4-6"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "conflicts with model")
+
+	_, err = NewAgent(Config{LLM: llmtypes.Config{Provider: "unknown"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported provider")
+}
+
+func TestNewAgentRegistersTools(t *testing.T) {
+	a := newTestAgent(t, Config{WorkingDirectory: t.TempDir(), Tools: []tooltypes.Tool{fakeTool{}}})
+
+	names := toolNames(a)
+	assert.Contains(t, names, "bash")
+	assert.Contains(t, names, "lookup_ticket")
+	assert.NotEmpty(t, a.ConversationID())
+}
+
+func TestNewAgentWithoutTools(t *testing.T) {
+	a := newTestAgent(t, Config{NoTools: true, Tools: []tooltypes.Tool{fakeTool{}}, ConversationID: "conv-1"})
+
+	assert.Empty(t, toolNames(a))
+	assert.Equal(t, "conv-1", a.ConversationID())
+}
+
+func TestRunRequiresPrompt(t *testing.T) {
+	a := newTestAgent(t, Config{NoTools: true})
+
+	_, err := a.Run(context.Background(), "  ", RunOptions{})
+	require.Error(t, err)
+
+	var events []Event
+	for event := range a.Stream(context.Background(), "", RunOptions{}) {
+		events = append(events, event)
+	}
+	require.Len(t, events, 1)
+	assert.Equal(t, EventTypeError, events[0].Type)
+	assert.Error(t, events[0].Err)
+}
+
+func TestStreamHandlerForwardsEvents(t *testing.T) {
+	events := make(chan Event, 16)
+	var handler llmtypes.StreamingMessageHandler = &streamHandler{ctx: context.Background(), events: events}
+
+	handler.HandleTextDelta("Hel")
+	handler.HandleToolUse("call-1", "bash", `{"command":"ls"}`)
+	handler.HandleToolResult("call-1", "bash", tooltypes.BaseToolResult{Result: "file.txt"})
+	handler.HandleText("Hello")
+	close(events)
+
+	var got []Event
+	for event := range events {
+		got = append(got, event)
+	}
+	require.Len(t, got, 4)
+	assert.Equal(t, Event{Type: EventTypeTextDelta, Content: "Hel"}, got[0])
+	assert.Equal(t, Event{Type: EventTypeToolUse, Content: `{"command":"ls"}`, ToolCallID: "call-1", ToolName: "bash"}, got[1])
+	assert.Equal(t, EventTypeToolResult, got[2].Type)
+	assert.Equal(t, "call-1", got[2].ToolCallID)
+	assert.Contains(t, got[2].Content, "file.txt")
+	require.NotNil(t, got[2].ToolResult)
+	assert.Equal(t, Event{Type: EventTypeText, Content: "Hello"}, got[3])
+}
+
+func TestStreamHandlerStopsOnCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	handler := &streamHandler{ctx: ctx, events: make(chan Event)}
+
+	handler.HandleTextDelta("dropped")
+}
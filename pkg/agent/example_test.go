@@ -0,0 +1,50 @@
+package agent_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jingkaihe/kodelet/pkg/agent"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+func ExampleAgent_Run() {
+	a, err := agent.NewAgent(agent.Config{
+		LLM: llmtypes.Config{Provider: "anthropic", Model: "claude-sonnet-4-6"},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer a.Close()
+
+	result, err := a.Run(context.Background(), "Summarize the README in this directory", agent.RunOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(result.Output)
+}
+
+func ExampleAgent_Stream() {
+	a, err := agent.NewAgent(agent.Config{
+		LLM:     llmtypes.Config{Provider: "openai", Model: "gpt-5.5"},
+		Persist: true,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer a.Close()
+
+	for event := range a.Stream(context.Background(), "List the Go packages in this repository", agent.RunOptions{MaxTurns: 10}) {
+		switch event.Type {
+		case agent.EventTypeTextDelta:
+			fmt.Print(event.Content)
+		case agent.EventTypeToolUse:
+			fmt.Printf("\n[%s] %s\n", event.ToolName, event.Content)
+		case agent.EventTypeError:
+			log.Fatal(event.Err)
+		case agent.EventTypeDone:
+			fmt.Printf("\nResume with: kodelet run --resume %s\n", event.Result.ConversationID)
+		}
+	}
+}
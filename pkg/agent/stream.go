@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// Event types emitted by Stream.
+const (
+	EventTypeTextDelta        = llmtypes.EventTypeTextDelta
+	EventTypeText             = llmtypes.EventTypeText
+	EventTypeThinkingStart    = llmtypes.EventTypeThinkingStart
+	EventTypeThinkingDelta    = llmtypes.EventTypeThinkingDelta
+	EventTypeThinkingBlockEnd = llmtypes.EventTypeThinkingBlockEnd
+	EventTypeThinking         = llmtypes.EventTypeThinking
+	EventTypeContentBlockEnd  = llmtypes.EventTypeContentBlockEnd
+	EventTypeToolUse          = llmtypes.EventTypeToolUse
+	EventTypeToolUpdate       = llmtypes.EventTypeToolUpdate
+	EventTypeToolResult       = llmtypes.EventTypeToolResult
+	EventTypeContentFilter    = llmtypes.EventTypeContentFilter
+	// EventTypeDone is the last event of a successful stream; Result is set.
+	EventTypeDone = "done"
+	// EventTypeError is the last event of a failed stream; Err is set.
+	EventTypeError = "error"
+)
+
+// Event is a single event streamed while the agent handles a prompt.
+type Event struct {
+	Type string
+	// Content is the text, thinking or tool input carried by the event.
+	Content    string
+	ToolCallID string
+	ToolName   string
+	// ToolResult is set for tool update and tool result events.
+	ToolResult *tooltypes.StructuredToolResult
+	// ContentFilter is set for content filter events.
+	ContentFilter *llmtypes.ContentFilterEvent
+	// Result is set on the EventTypeDone event.
+	Result *Result
+	// Err is set on the EventTypeError event.
+	Err error
+}
+
+// Stream sends prompt to the agent and returns a channel of events that ends
+// with an EventTypeDone or EventTypeError event before it is closed. The
+// caller must drain the channel or cancel ctx.
+func (a *Agent) Stream(ctx context.Context, prompt string, opts RunOptions) <-chan Event {
+	events := make(chan Event, 64)
+	go func() {
+		defer close(events)
+		handler := &streamHandler{ctx: ctx, events: events}
+		result, err := a.send(ctx, prompt, opts, handler)
+		if err != nil {
+			handler.emit(Event{Type: EventTypeError, Err: err})
+			return
+		}
+		handler.emit(Event{Type: EventTypeDone, Result: result})
+	}()
+	return events
+}
+
+// streamHandler forwards message handler callbacks to a Stream channel.
+type streamHandler struct {
+	ctx    context.Context
+	events chan<- Event
+}
+
+func (h *streamHandler) emit(event Event) {
+	select {
+	case h.events <- event:
+	case <-h.ctx.Done():
+	}
+}
+
+func (h *streamHandler) emitToolResult(eventType, toolCallID, toolName string, result tooltypes.ToolResult) {
+	structured := result.StructuredData()
+	h.emit(Event{
+		Type:       eventType,
+		Content:    result.AssistantFacing(),
+		ToolCallID: toolCallID,
+		ToolName:   toolName,
+		ToolResult: &structured,
+	})
+}
+
+func (h *streamHandler) HandleText(text string) {
+	h.emit(Event{Type: EventTypeText, Content: text})
+}
+
+func (h *streamHandler) HandleToolUse(toolCallID string, toolName string, input string) {
+	h.emit(Event{Type: EventTypeToolUse, Content: input, ToolCallID: toolCallID, ToolName: toolName})
+}
+
+func (h *streamHandler) HandleToolUpdate(toolCallID string, toolName string, result tooltypes.ToolResult) {
+	h.emitToolResult(EventTypeToolUpdate, toolCallID, toolName, result)
+}
+
+func (h *streamHandler) HandleToolResult(toolCallID string, toolName string, result tooltypes.ToolResult) {
+	h.emitToolResult(EventTypeToolResult, toolCallID, toolName, result)
+}
+
+func (h *streamHandler) HandleThinking(thinking string) {
+	h.emit(Event{Type: EventTypeThinking, Content: thinking})
+}
+
+func (h *streamHandler) HandleContentFilter(event llmtypes.ContentFilterEvent) {
+	h.emit(Event{Type: EventTypeContentFilter, Content: event.Message, ContentFilter: &event})
+}
+
+func (h *streamHandler) HandleDone() {}
+
+func (h *streamHandler) HandleTextDelta(delta string) {
+	h.emit(Event{Type: EventTypeTextDelta, Content: delta})
+}
+
+func (h *streamHandler) HandleThinkingStart() {
+	h.emit(Event{Type: EventTypeThinkingStart})
+}
+
+func (h *streamHandler) HandleThinkingDelta(delta string) {
+	h.emit(Event{Type: EventTypeThinkingDelta, Content: delta})
+}
+
+func (h *streamHandler) HandleThinkingBlockEnd() {
+	h.emit(Event{Type: EventTypeThinkingBlockEnd})
+}
+
+func (h *streamHandler) HandleContentBlockEnd() {
+	h.emit(Event{Type: EventTypeContentBlockEnd})
+}
@@ -0,0 +1,142 @@
+// Package airgap enforces and verifies the settings Kodelet needs to run
+// inside a network without internet access.
+//
+// In air-gapped mode the model provider must be served from an internal
+// endpoint, web access tools are removed, tool binaries are not downloaded
+// and telemetry may only be exported to internal collectors.
+package airgap
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/jingkaihe/kodelet/pkg/llm/anthropic"
+	"github.com/jingkaihe/kodelet/pkg/llm/openai"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+)
+
+// anthropicDefaultBaseURL is where the Anthropic SDK sends requests when no
+// base URL is configured.
+const anthropicDefaultBaseURL = "https://api.anthropic.com"
+
+// defaultTracingEndpoint is the OTLP/HTTP endpoint used when none is set in
+// the environment.
+const defaultTracingEndpoint = "http://localhost:4318"
+
+// internalSuffixes are DNS suffixes reserved for private networks.
+var internalSuffixes = []string{".internal", ".local", ".lan", ".localhost"}
+
+// IsInternalHost reports whether host, with or without a port, is inside the
+// isolated network: loopback, private or link-local addresses, single-label
+// names, names under a private-use suffix, or one of allowedHosts.
+func IsInternalHost(host string, allowedHosts []string) bool {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if host == "" {
+		return false
+	}
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if h, _, err := net.SplitHostPort(allowed); err == nil {
+			allowed = h
+		}
+		if allowed != "" && (host == allowed || strings.HasSuffix(host, "."+allowed)) {
+			return true
+		}
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast()
+	}
+	if host == "localhost" || !strings.Contains(host, ".") {
+		return true
+	}
+	for _, suffix := range internalSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInternalURL reports whether rawURL points at an internal host.
+func IsInternalURL(rawURL string, allowedHosts []string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	return IsInternalHost(parsed.Host, allowedHosts)
+}
+
+// ModelEndpoint returns the base URL the configured provider sends requests to.
+func ModelEndpoint(config llmtypes.Config) string {
+	switch strings.ToLower(config.Provider) {
+	case "anthropic":
+		if baseURL := anthropic.GetConfiguredBaseURL(config); baseURL != "" {
+			return baseURL
+		}
+		return anthropicDefaultBaseURL
+	default:
+		return openai.GetBaseURL(config)
+	}
+}
+
+// TracingEndpoint returns the OTLP endpoint traces are exported to, following
+// the OpenTelemetry environment variables.
+func TracingEndpoint() string {
+	for _, key := range []string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT"} {
+		if endpoint := strings.TrimSpace(os.Getenv(key)); endpoint != "" {
+			return endpoint
+		}
+	}
+	return defaultTracingEndpoint
+}
+
+// Enforce rejects a configuration whose model endpoint is outside the
+// isolated network and turns off the provider's native web search. It does
+// nothing unless air-gapped mode is enabled.
+func Enforce(config *llmtypes.Config) error {
+	if !config.AirgapEnabled() {
+		return nil
+	}
+	endpoint := ModelEndpoint(*config)
+	if !IsInternalURL(endpoint, config.Airgap.AllowedHosts) {
+		return errors.Errorf("airgap: model endpoint %s is not internal; set %s.base_url to a model server inside the network or add its host to airgap.allowed_hosts", endpoint, strings.ToLower(config.Provider))
+	}
+	if config.OpenAI == nil {
+		config.OpenAI = &llmtypes.OpenAIConfig{}
+	}
+	disabled := false
+	config.OpenAI.EnableSearch = &disabled
+	return nil
+}
+
+// UseCABundle makes the PEM certificates in path the only CAs trusted by the
+// default HTTP transport, which the model clients and tools share.
+func UseCABundle(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to read CA bundle")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return errors.Errorf("no PEM certificates found in CA bundle %s", path)
+	}
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return errors.New("default HTTP transport cannot be configured")
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	transport.TLSClientConfig.RootCAs = pool
+	return nil
+}
@@ -0,0 +1,144 @@
+package airgap
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsInternalHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"localhost:11434", true},
+		{"127.0.0.1", true},
+		{"10.1.2.3:8000", true},
+		{"192.168.1.10", true},
+		{"[::1]:8080", true},
+		{"fe80::1", true},
+		{"vllm", true},
+		{"llm.corp.internal", true},
+		{"gpu01.lan", true},
+		{"models.example.mil", true},
+		{"api.openai.com", false},
+		{"8.8.8.8", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsInternalHost(tt.host, []string{"example.mil"}))
+		})
+	}
+}
+
+func TestIsInternalURL(t *testing.T) {
+	assert.True(t, IsInternalURL("http://localhost:8000/v1", nil))
+	assert.False(t, IsInternalURL("https://api.anthropic.com", nil))
+	assert.False(t, IsInternalURL("not a url", nil))
+}
+
+func TestEnforce(t *testing.T) {
+	t.Setenv("OPENAI_API_BASE", "")
+	t.Setenv("ANTHROPIC_BASE_URL", "")
+
+	config := llmtypes.Config{Provider: "anthropic"}
+	require.NoError(t, Enforce(&config), "disabled airgap mode allows any endpoint")
+
+	config.Airgap = &llmtypes.AirgapConfig{Enabled: true}
+	err := Enforce(&config)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "model endpoint https://api.anthropic.com is not internal")
+
+	config = llmtypes.Config{
+		Provider: "openai",
+		OpenAI:   &llmtypes.OpenAIConfig{BaseURL: "http://vllm:8000/v1"},
+		Airgap:   &llmtypes.AirgapConfig{Enabled: true},
+	}
+	require.NoError(t, Enforce(&config))
+	require.NotNil(t, config.OpenAI.EnableSearch)
+	assert.False(t, *config.OpenAI.EnableSearch)
+}
+
+func writeTestCABundle(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Internal CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644))
+	return path
+}
+
+func TestUseCABundle(t *testing.T) {
+	transport := http.DefaultTransport.(*http.Transport)
+	previous := transport.TLSClientConfig
+	t.Cleanup(func() { transport.TLSClientConfig = previous })
+
+	require.NoError(t, UseCABundle(writeTestCABundle(t)))
+	require.NotNil(t, transport.TLSClientConfig)
+	assert.NotNil(t, transport.TLSClientConfig.RootCAs)
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(empty, []byte("nothing here"), 0o644))
+	assert.ErrorContains(t, UseCABundle(empty), "no PEM certificates found")
+	assert.Error(t, UseCABundle(filepath.Join(t.TempDir(), "missing.pem")))
+}
+
+func TestVerify(t *testing.T) {
+	t.Setenv("OPENAI_API_BASE", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "https://collector.example.com")
+
+	checks := Verify(llmtypes.Config{Provider: "openai"}, VerifyOptions{TracingEnabled: true})
+	failed := map[string]bool{}
+	for _, check := range checks {
+		if !check.Passed {
+			failed[check.Name] = true
+		}
+	}
+	assert.Equal(t, map[string]bool{
+		"airgap mode":           true,
+		"model endpoint":        true,
+		"web access":            true,
+		"tool binary downloads": true,
+		"telemetry":             true,
+		"pricing refresh":       true,
+	}, failed)
+
+	config := llmtypes.Config{
+		Provider: "openai",
+		OpenAI:   &llmtypes.OpenAIConfig{BaseURL: "http://10.0.0.5:8000/v1"},
+		Airgap:   &llmtypes.AirgapConfig{Enabled: true, CABundle: writeTestCABundle(t)},
+	}
+	for _, check := range Verify(config, VerifyOptions{}) {
+		assert.True(t, check.Passed, "%s: %s", check.Name, check.Detail)
+	}
+
+	config.Airgap.CABundle = filepath.Join(t.TempDir(), "missing.pem")
+	checks = Verify(config, VerifyOptions{})
+	assert.False(t, checks[len(checks)-1].Passed)
+}
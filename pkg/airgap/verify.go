@@ -0,0 +1,111 @@
+package airgap
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/jingkaihe/kodelet/pkg/pricing"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// Check is the outcome of one air-gap verification.
+type Check struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"`
+}
+
+// VerifyOptions holds the settings checked alongside the LLM configuration.
+type VerifyOptions struct {
+	TracingEnabled     bool
+	PricingManifestURL string
+}
+
+// Verify checks that config reaches no endpoint outside the isolated network.
+func Verify(config llmtypes.Config, opts VerifyOptions) []Check {
+	enabled := config.AirgapEnabled()
+	var allowedHosts []string
+	if config.Airgap != nil {
+		allowedHosts = config.Airgap.AllowedHosts
+	}
+
+	checks := []Check{{Name: "airgap mode", Passed: enabled, Detail: "enabled"}}
+	if !enabled {
+		checks[0].Detail = "airgap.enabled is not set"
+	}
+
+	endpoint := ModelEndpoint(config)
+	checks = append(checks, Check{
+		Name:   "model endpoint",
+		Passed: IsInternalURL(endpoint, allowedHosts),
+		Detail: fmt.Sprintf("%s (%s)", endpoint, config.Provider),
+	})
+
+	webTools := len(config.AllowedTools) == 0 || slices.Contains(config.AllowedTools, "web_fetch") || slices.Contains(config.AllowedTools, "web_crawl")
+	search := config.Provider == "openai" && (config.OpenAI == nil || config.OpenAI.EnableSearch == nil || *config.OpenAI.EnableSearch)
+	web := Check{Name: "web access", Passed: enabled || (!webTools && !search), Detail: "web_fetch, web_crawl and native web search are disabled"}
+	if !web.Passed {
+		web.Detail = "web_fetch, web_crawl or native web search is available"
+	}
+	checks = append(checks, web)
+
+	binaries := Check{Name: "tool binary downloads", Passed: enabled, Detail: "disabled; ripgrep and fd must be installed locally"}
+	if !enabled {
+		binaries.Detail = "ripgrep and fd are downloaded from GitHub when missing"
+	}
+	checks = append(checks, binaries)
+
+	tracing := Check{Name: "telemetry", Passed: true, Detail: "tracing is disabled"}
+	if opts.TracingEnabled {
+		tracingEndpoint := TracingEndpoint()
+		tracing.Passed = IsInternalURL(tracingEndpoint, allowedHosts)
+		tracing.Detail = "traces are exported to " + tracingEndpoint
+	}
+	checks = append(checks, tracing)
+
+	manifestURL := opts.PricingManifestURL
+	if manifestURL == "" {
+		manifestURL = pricing.DefaultManifestURL
+	}
+	pricingCheck := Check{Name: "pricing refresh", Passed: IsInternalURL(manifestURL, allowedHosts), Detail: manifestURL}
+	if !pricingCheck.Passed && enabled {
+		pricingCheck.Passed = true
+		pricingCheck.Detail = "blocked; set pricing.manifest_url to an internal mirror to refresh prices"
+	}
+	checks = append(checks, pricingCheck)
+
+	return append(checks, verifyCABundle(config))
+}
+
+func verifyCABundle(config llmtypes.Config) Check {
+	check := Check{Name: "CA bundle", Passed: true, Detail: "system roots"}
+	if config.Airgap == nil || strings.TrimSpace(config.Airgap.CABundle) == "" {
+		return check
+	}
+	path := config.Airgap.CABundle
+	data, err := os.ReadFile(path)
+	if err != nil {
+		check.Passed = false
+		check.Detail = err.Error()
+		return check
+	}
+	count := 0
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			check.Passed = false
+			check.Detail = fmt.Sprintf("%s: %s", path, err)
+			return check
+		}
+		count++
+	}
+	check.Passed = count > 0
+	check.Detail = fmt.Sprintf("%s (%d certificates)", path, count)
+	return check
+}
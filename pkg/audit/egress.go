@@ -0,0 +1,219 @@
+// Package audit records outbound network activity and tool invocations of
+// agents in append-only logs so deployments can review what an agent did.
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+const egressLogVersion = 1
+
+// EgressEntry is one outbound network request made by a tool.
+type EgressEntry struct {
+	Version        int       `json:"v"`
+	Timestamp      time.Time `json:"ts"`
+	ConversationID string    `json:"conversation_id,omitempty"`
+	Tool           string    `json:"tool"`
+	Method         string    `json:"method"`
+	Domain         string    `json:"domain"`
+	URL            string    `json:"url"`
+	Status         int       `json:"status,omitempty"`
+	BytesSent      int64     `json:"bytes_sent"`
+	BytesReceived  int64     `json:"bytes_received"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// EgressFilter selects entries when reading the egress log. Zero values match
+// everything.
+type EgressFilter struct {
+	ConversationID string
+	Domain         string
+	Tool           string
+	Since          time.Time
+	Until          time.Time
+	// Limit keeps only the newest Limit matching entries when positive.
+	Limit int
+}
+
+// EgressLog is an append-only JSONL log of outbound tool requests. Entries are
+// written with a single O_APPEND write so that concurrent writers, including
+// extension processes, never interleave lines.
+type EgressLog struct {
+	path string
+}
+
+// DefaultDir returns the audit directory under the Kodelet base directory,
+// honouring KODELET_BASE_PATH.
+func DefaultDir() (string, error) {
+	if basePath := strings.TrimSpace(os.Getenv("KODELET_BASE_PATH")); basePath != "" {
+		return filepath.Join(basePath, "audit"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get home directory")
+	}
+	return filepath.Join(homeDir, ".kodelet", "audit"), nil
+}
+
+// DefaultEgressLogPath returns the egress log path in DefaultDir.
+func DefaultEgressLogPath() (string, error) {
+	dir, err := DefaultDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "egress.jsonl"), nil
+}
+
+// NewEgressLog returns the log at DefaultEgressLogPath.
+func NewEgressLog() (*EgressLog, error) {
+	path, err := DefaultEgressLogPath()
+	if err != nil {
+		return nil, err
+	}
+	return NewEgressLogWithPath(path), nil
+}
+
+// NewEgressLogWithPath returns a log stored at path.
+func NewEgressLogWithPath(path string) *EgressLog {
+	return &EgressLog{path: path}
+}
+
+// Path returns the file the log is stored in.
+func (l *EgressLog) Path() string {
+	return l.path
+}
+
+// Append adds entry to the end of the log.
+func (l *EgressLog) Append(entry EgressEntry) error {
+	entry.Version = egressLogVersion
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	entry.Timestamp = entry.Timestamp.UTC()
+
+	return appendLine(l.path, entry)
+}
+
+// appendLine writes v as one JSON line at the end of the file at path, using a
+// single O_APPEND write so that concurrent writers never interleave lines.
+func appendLine(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode audit entry")
+	}
+	data = append(data, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return errors.Wrap(err, "failed to create audit directory")
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return errors.Wrap(err, "failed to open audit log")
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return errors.Wrap(err, "failed to write audit entry")
+	}
+	return errors.Wrap(file.Close(), "failed to close audit log")
+}
+
+// Read returns the entries matching filter in chronological order. Malformed
+// lines are skipped.
+func (l *EgressLog) Read(filter EgressFilter) ([]EgressEntry, error) {
+	file, err := os.Open(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to open egress log")
+	}
+	defer file.Close()
+
+	var entries []EgressEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry EgressEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			continue
+		}
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read egress log")
+	}
+
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
+
+func (f EgressFilter) matches(entry EgressEntry) bool {
+	if f.ConversationID != "" && entry.ConversationID != f.ConversationID {
+		return false
+	}
+	if f.Tool != "" && entry.Tool != f.Tool {
+		return false
+	}
+	if f.Domain != "" && !matchesDomain(entry.Domain, f.Domain) {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && entry.Timestamp.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// matchesDomain reports whether domain is pattern or one of its subdomains.
+func matchesDomain(domain, pattern string) bool {
+	domain = strings.ToLower(domain)
+	pattern = strings.ToLower(strings.TrimPrefix(pattern, "*."))
+	return domain == pattern || strings.HasSuffix(domain, "."+pattern)
+}
+
+// RecordEgress appends entry to the default egress log. Failures are logged
+// and never interrupt the tool making the request.
+func RecordEgress(ctx context.Context, entry EgressEntry) {
+	log, err := NewEgressLog()
+	if err == nil {
+		err = log.Append(entry)
+	}
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to record egress audit entry")
+	}
+}
+
+// redactURL drops the query string, fragment and credentials from u so that
+// secrets passed in URLs are not copied into the audit log.
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	redacted := *u
+	redacted.User = nil
+	redacted.RawQuery = ""
+	redacted.ForceQuery = false
+	redacted.Fragment = ""
+	redacted.RawFragment = ""
+	return redacted.String()
+}
@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEgressLogAppendAndRead(t *testing.T) {
+	log := NewEgressLogWithPath(filepath.Join(t.TempDir(), "audit", "egress.jsonl"))
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, log.Append(EgressEntry{Timestamp: base, ConversationID: "a", Tool: "web_fetch", Domain: "api.github.com"}))
+	require.NoError(t, log.Append(EgressEntry{Timestamp: base.Add(time.Hour), ConversationID: "b", Tool: "mcp", Domain: "example.com"}))
+	require.NoError(t, log.Append(EgressEntry{Timestamp: base.Add(2 * time.Hour), ConversationID: "a", Tool: "web_fetch", Domain: "github.com"}))
+
+	info, err := os.Stat(log.Path())
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	all, err := log.Read(EgressFilter{})
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, egressLogVersion, all[0].Version)
+
+	byConversation, err := log.Read(EgressFilter{ConversationID: "a"})
+	require.NoError(t, err)
+	assert.Len(t, byConversation, 2)
+
+	byDomain, err := log.Read(EgressFilter{Domain: "github.com"})
+	require.NoError(t, err)
+	assert.Len(t, byDomain, 2)
+
+	recent, err := log.Read(EgressFilter{Since: base.Add(30 * time.Minute), Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, recent, 1)
+	assert.Equal(t, "github.com", recent[0].Domain)
+}
+
+func TestEgressLogReadMissingFileAndMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "egress.jsonl")
+	entries, err := NewEgressLogWithPath(path).Read(EgressFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	require.NoError(t, os.WriteFile(path, []byte("not json\n{\"tool\":\"web_fetch\",\"domain\":\"example.com\"}\n"), 0o600))
+	entries, err = NewEgressLogWithPath(path).Read(EgressFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "example.com", entries[0].Domain)
+}
+
+func TestEgressTransportRecordsRequests(t *testing.T) {
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("response body"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewEgressTransport(nil, EgressSource{Tool: "test_tool", ConversationID: "conv-1"})}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL+"/upload?key=secret", strings.NewReader("payload"))
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	_, err = client.Get("http://127.0.0.1:1/unreachable")
+	require.Error(t, err)
+
+	log, err := NewEgressLog()
+	require.NoError(t, err)
+	entries, err := log.Read(EgressFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "test_tool", entries[0].Tool)
+	assert.Equal(t, "conv-1", entries[0].ConversationID)
+	assert.Equal(t, http.MethodPost, entries[0].Method)
+	assert.Equal(t, "127.0.0.1", entries[0].Domain)
+	assert.Equal(t, server.URL+"/upload", entries[0].URL)
+	assert.Equal(t, http.StatusOK, entries[0].Status)
+	assert.Equal(t, int64(len("payload")), entries[0].BytesSent)
+	assert.Zero(t, entries[0].BytesReceived, "body closed without reading")
+
+	assert.NotEmpty(t, entries[1].Error)
+	assert.Zero(t, entries[1].Status)
+}
+
+func TestMatchesDomain(t *testing.T) {
+	assert.True(t, matchesDomain("github.com", "github.com"))
+	assert.True(t, matchesDomain("api.github.com", "github.com"))
+	assert.True(t, matchesDomain("api.github.com", "*.github.com"))
+	assert.False(t, matchesDomain("notgithub.com", "github.com"))
+}
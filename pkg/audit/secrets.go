@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/logger"
+)
+
+const secretAccessLogVersion = 1
+
+// SecretSource identifies the tool call a secret is handed to.
+type SecretSource struct {
+	Tool           string
+	ConversationID string
+}
+
+// SecretAccessEntry is one secret handed to a tool subprocess. The secret
+// value is never recorded.
+type SecretAccessEntry struct {
+	Version        int       `json:"v"`
+	Timestamp      time.Time `json:"ts"`
+	ConversationID string    `json:"conversation_id,omitempty"`
+	Tool           string    `json:"tool"`
+	Name           string    `json:"name"`
+	Provider       string    `json:"provider"`
+	Reference      string    `json:"reference"`
+	Cached         bool      `json:"cached,omitempty"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// SecretAccessLog is an append-only JSONL log of secret accesses.
+type SecretAccessLog struct {
+	path string
+}
+
+// DefaultSecretAccessLogPath returns the secret access log path in
+// DefaultDir.
+func DefaultSecretAccessLogPath() (string, error) {
+	dir, err := DefaultDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "secrets.jsonl"), nil
+}
+
+// NewSecretAccessLogWithPath returns a log stored at path.
+func NewSecretAccessLogWithPath(path string) *SecretAccessLog {
+	return &SecretAccessLog{path: path}
+}
+
+// Append adds entry to the end of the log.
+func (l *SecretAccessLog) Append(entry SecretAccessEntry) error {
+	entry.Version = secretAccessLogVersion
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	entry.Timestamp = entry.Timestamp.UTC()
+
+	return appendLine(l.path, entry)
+}
+
+// RecordSecretAccess appends entry to the default secret access log.
+// Failures are logged and never interrupt the tool call.
+func RecordSecretAccess(ctx context.Context, entry SecretAccessEntry) {
+	path, err := DefaultSecretAccessLogPath()
+	if err == nil {
+		err = NewSecretAccessLogWithPath(path).Append(entry)
+	}
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to record secret access audit entry")
+	}
+}
@@ -0,0 +1,191 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	"github.com/pkg/errors"
+)
+
+const toolCallLogVersion = 1
+
+// ToolCallLogEnv carries the active tool call audit file to child processes,
+// so subagents started from bash or extensions append to their parent's run.
+const ToolCallLogEnv = "KODELET_AUDIT_LOG"
+
+// DefaultMaxToolOutputBytes is how much of each tool output is recorded when
+// no limit is configured.
+const DefaultMaxToolOutputBytes = 4096
+
+// Approval decisions recorded for a tool call.
+const (
+	ApprovalAllowed = "allowed"
+	ApprovalBlocked = "blocked"
+	ApprovalRefused = "refused"
+	// ApprovalConfirmed and ApprovalDenied record the user's answer in
+	// tool approval mode.
+	ApprovalConfirmed = "confirmed"
+	ApprovalDenied    = "denied"
+	// ApprovalDeferred records a call queued for review at the end of the
+	// turn. The call is recorded again, confirmed or denied, once reviewed.
+	ApprovalDeferred = "deferred"
+)
+
+// Statuses recorded for a tool call.
+const (
+	ToolCallSuccess = "success"
+	ToolCallError   = "error"
+	ToolCallBlocked = "blocked"
+)
+
+// parentToolCallLog is the audit file inherited from the kodelet process that
+// started this one, if any.
+var parentToolCallLog = strings.TrimSpace(os.Getenv(ToolCallLogEnv))
+
+var enableMu sync.Mutex
+
+// ToolCallEntry is one tool invocation.
+type ToolCallEntry struct {
+	Version         int             `json:"v"`
+	Timestamp       time.Time       `json:"ts"`
+	RunID           string          `json:"run_id"`
+	ConversationID  string          `json:"conversation_id,omitempty"`
+	Subagent        bool            `json:"subagent,omitempty"`
+	ToolCallID      string          `json:"tool_call_id,omitempty"`
+	Tool            string          `json:"tool"`
+	Input           json.RawMessage `json:"input,omitempty"`
+	Output          string          `json:"output,omitempty"`
+	OutputBytes     int             `json:"output_bytes"`
+	OutputTruncated bool            `json:"output_truncated,omitempty"`
+	DurationMS      int64           `json:"duration_ms"`
+	Status          string          `json:"status"`
+	ExitCode        *int            `json:"exit_code,omitempty"`
+	Error           string          `json:"error,omitempty"`
+	Approval        string          `json:"approval"`
+	ApprovalReason  string          `json:"approval_reason,omitempty"`
+}
+
+// ToolCallLog is an append-only JSONL log of the tool calls made during one
+// run, named after the run ID.
+type ToolCallLog struct {
+	path           string
+	maxOutputBytes int
+}
+
+// NewToolCallLogWithPath returns a log stored at path that keeps at most
+// maxOutputBytes of each tool output. A non-positive limit uses
+// DefaultMaxToolOutputBytes.
+func NewToolCallLogWithPath(path string, maxOutputBytes int) *ToolCallLog {
+	if maxOutputBytes <= 0 {
+		maxOutputBytes = DefaultMaxToolOutputBytes
+	}
+	return &ToolCallLog{path: path, maxOutputBytes: maxOutputBytes}
+}
+
+// Path returns the file the log is stored in.
+func (l *ToolCallLog) Path() string {
+	return l.path
+}
+
+// RunID returns the run the log belongs to.
+func (l *ToolCallLog) RunID() string {
+	return strings.TrimSuffix(filepath.Base(l.path), ".jsonl")
+}
+
+// Append adds entry to the end of the log, truncating its output.
+func (l *ToolCallLog) Append(entry ToolCallEntry) error {
+	entry.Version = toolCallLogVersion
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	entry.Timestamp = entry.Timestamp.UTC()
+	entry.RunID = l.RunID()
+	if len(entry.Input) > 0 && !json.Valid(entry.Input) {
+		entry.Input, _ = json.Marshal(string(entry.Input))
+	}
+	entry.OutputBytes = len(entry.Output)
+	if len(entry.Output) > l.maxOutputBytes {
+		entry.Output = truncateUTF8(entry.Output, l.maxOutputBytes)
+		entry.OutputTruncated = true
+	}
+
+	return appendLine(l.path, entry)
+}
+
+// EnableToolCallLog turns on tool call auditing for this process and the
+// processes it starts, creating a new run file in dir (DefaultDir when empty).
+// It is a no-op returning the existing file when auditing is already on,
+// including when it was inherited from a parent process.
+func EnableToolCallLog(dir string) (string, error) {
+	enableMu.Lock()
+	defer enableMu.Unlock()
+
+	if path := ToolCallLogPath(); path != "" {
+		return path, nil
+	}
+	dir, err := resolveDir(dir)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, convtypes.GenerateID()+".jsonl")
+	if err := os.Setenv(ToolCallLogEnv, path); err != nil {
+		return "", errors.Wrap(err, "failed to export tool call audit log")
+	}
+	return path, nil
+}
+
+// resolveDir makes dir absolute, since child processes may run elsewhere.
+func resolveDir(dir string) (string, error) {
+	dir = strings.TrimSpace(dir)
+	switch {
+	case dir == "":
+		return DefaultDir()
+	case dir == "~" || strings.HasPrefix(dir, "~/"):
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", errors.Wrap(err, "failed to get home directory")
+		}
+		return filepath.Join(homeDir, strings.TrimPrefix(dir, "~")), nil
+	}
+	absDir, err := filepath.Abs(dir)
+	return absDir, errors.Wrap(err, "failed to resolve audit directory")
+}
+
+// ToolCallLogPath returns the active tool call audit file, or "" when tool
+// call auditing is off.
+func ToolCallLogPath() string {
+	return strings.TrimSpace(os.Getenv(ToolCallLogEnv))
+}
+
+// IsSubagent reports whether this process writes to an audit file inherited
+// from a parent kodelet run.
+func IsSubagent() bool {
+	return parentToolCallLog != "" && parentToolCallLog == ToolCallLogPath()
+}
+
+// RecordToolCall appends entry to log. Failures are
+// logged and never interrupt the agent.
+func RecordToolCall(ctx context.Context, log *ToolCallLog, entry ToolCallEntry) {
+	if err := log.Append(entry); err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to record tool call audit entry")
+	}
+}
+
+// truncateUTF8 cuts s to at most limit bytes without splitting a character.
+func truncateUTF8(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit]
+}
@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolCallLogAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "20261016T120000-abc.jsonl")
+	log := NewToolCallLogWithPath(path, 2)
+
+	require.NoError(t, log.Append(ToolCallEntry{Tool: "bash", Input: []byte(`{"command":"ls"}`), Output: "héllo", Status: ToolCallSuccess}))
+	require.NoError(t, log.Append(ToolCallEntry{Tool: "file_read", Output: "ok"}))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var entry ToolCallEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, toolCallLogVersion, entry.Version)
+	assert.Equal(t, "20261016T120000-abc", entry.RunID)
+	assert.JSONEq(t, `{"command":"ls"}`, string(entry.Input))
+	assert.Equal(t, "h", entry.Output)
+	assert.Equal(t, 6, entry.OutputBytes)
+	assert.True(t, entry.OutputTruncated)
+	assert.False(t, entry.Timestamp.IsZero())
+
+	assert.NotContains(t, lines[1], `"input"`)
+	assert.NotContains(t, lines[1], `"output_truncated"`)
+}
+
+func TestEnableToolCallLog(t *testing.T) {
+	t.Setenv(ToolCallLogEnv, "")
+	t.Chdir(t.TempDir())
+
+	path, err := EnableToolCallLog("audit-runs")
+	require.NoError(t, err)
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(cwd, "audit-runs"), filepath.Dir(path))
+	assert.Equal(t, path, ToolCallLogPath())
+
+	again, err := EnableToolCallLog(t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, path, again)
+	assert.False(t, IsSubagent())
+}
+
+func TestIsSubagent(t *testing.T) {
+	original := parentToolCallLog
+	t.Cleanup(func() { parentToolCallLog = original })
+
+	parentToolCallLog = "/tmp/audit/run.jsonl"
+	t.Setenv(ToolCallLogEnv, "/tmp/audit/run.jsonl")
+	assert.True(t, IsSubagent())
+
+	t.Setenv(ToolCallLogEnv, "/tmp/audit/other.jsonl")
+	assert.False(t, IsSubagent())
+}
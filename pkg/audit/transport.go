@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// EgressSource identifies the tool and conversation behind audited requests.
+type EgressSource struct {
+	Tool           string
+	ConversationID string
+}
+
+// NewEgressTransport wraps base so that every request it sends, including
+// each redirect hop, is recorded in the egress log once its response body has
+// been consumed or closed. A nil base uses http.DefaultTransport.
+func NewEgressTransport(base http.RoundTripper, source EgressSource) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &egressTransport{base: base, source: source}
+}
+
+type egressTransport struct {
+	base   http.RoundTripper
+	source EgressSource
+}
+
+func (t *egressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := EgressEntry{
+		Timestamp:      time.Now(),
+		ConversationID: t.source.ConversationID,
+		Tool:           t.source.Tool,
+		Method:         req.Method,
+		Domain:         req.URL.Hostname(),
+		URL:            redactURL(req.URL),
+	}
+	if req.ContentLength > 0 {
+		entry.BytesSent = req.ContentLength
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		entry.Error = err.Error()
+		RecordEgress(req.Context(), entry)
+		return nil, err
+	}
+
+	entry.Status = resp.StatusCode
+	resp.Body = &countingBody{
+		ReadCloser: resp.Body,
+		record: func(received int64, readErr error) {
+			entry.BytesReceived = received
+			if readErr != nil {
+				entry.Error = readErr.Error()
+			}
+			RecordEgress(req.Context(), entry)
+		},
+	}
+	return resp, nil
+}
+
+// countingBody counts response bytes and reports them once, at EOF, on a read
+// error or when the body is closed.
+type countingBody struct {
+	io.ReadCloser
+	received int64
+	once     sync.Once
+	record   func(received int64, err error)
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.received += int64(n)
+	switch {
+	case err == io.EOF:
+		b.finish(nil)
+	case err != nil:
+		b.finish(err)
+	}
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish(nil)
+	return err
+}
+
+func (b *countingBody) finish(err error) {
+	b.once.Do(func() {
+		b.record(b.received, err)
+	})
+}
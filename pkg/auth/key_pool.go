@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+)
+
+// keyBudgetWindow is the window a key's requests_per_minute budget applies to.
+const keyBudgetWindow = time.Minute
+
+// KeyPool spreads requests across several API keys of one provider. Keys are
+// picked by smooth weighted round-robin, skipping keys that have used up
+// their per-minute budget. When every key is over budget, the next request
+// waits for the first one to free up.
+type KeyPool struct {
+	mu   sync.Mutex
+	keys []*pooledKey
+	now  func() time.Time
+}
+
+type pooledKey struct {
+	name    string
+	value   string
+	weight  int
+	current int
+	limit   int
+	sent    []time.Time
+}
+
+// NewKeyPool resolves the keys of a pool from their environment variables.
+func NewKeyPool(configs []llmtypes.APIKeyConfig) (*KeyPool, error) {
+	if len(configs) == 0 {
+		return nil, errors.New("key pool has no keys")
+	}
+	pool := &KeyPool{now: time.Now}
+	for _, config := range configs {
+		value := os.Getenv(config.EnvVar)
+		if value == "" {
+			return nil, errors.Errorf("%s environment variable is required for API key %q", config.EnvVar, config.DisplayName())
+		}
+		weight := config.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		pool.keys = append(pool.keys, &pooledKey{
+			name:   config.DisplayName(),
+			value:  value,
+			weight: weight,
+			limit:  config.RequestsPerMinute,
+		})
+	}
+	return pool, nil
+}
+
+var (
+	sharedKeyPoolsMu sync.Mutex
+	sharedKeyPools   = map[string]*KeyPool{}
+)
+
+// SharedKeyPool returns the process-wide pool for provider and configs, so
+// that subagents and parallel threads draw on the same per-key budgets.
+func SharedKeyPool(provider string, configs []llmtypes.APIKeyConfig) (*KeyPool, error) {
+	id := provider + fmt.Sprintf("%+v", configs)
+
+	sharedKeyPoolsMu.Lock()
+	defer sharedKeyPoolsMu.Unlock()
+	if pool, ok := sharedKeyPools[id]; ok {
+		return pool, nil
+	}
+	pool, err := NewKeyPool(configs)
+	if err != nil {
+		return nil, err
+	}
+	sharedKeyPools[id] = pool
+	return pool, nil
+}
+
+// Next picks the key for the next request and returns its name and value.
+func (p *KeyPool) Next(ctx context.Context) (string, string, error) {
+	for {
+		key, wait := p.pick()
+		if key != nil {
+			return key.name, key.value, nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", "", errors.Wrap(ctx.Err(), "every pooled API key is over its requests_per_minute budget")
+		case <-timer.C:
+		}
+	}
+}
+
+// pick runs one round of smooth weighted round-robin over the keys within
+// budget. When none is, it returns how long until the first frees up.
+func (p *KeyPool) pick() (*pooledKey, time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	var best *pooledKey
+	total := 0
+	wait := keyBudgetWindow
+	for _, key := range p.keys {
+		key.prune(now)
+		if key.limit > 0 && len(key.sent) >= key.limit {
+			wait = min(wait, key.sent[0].Add(keyBudgetWindow).Sub(now))
+			continue
+		}
+		key.current += key.weight
+		total += key.weight
+		if best == nil || key.current > best.current {
+			best = key
+		}
+	}
+	if best == nil {
+		return nil, max(wait, time.Millisecond)
+	}
+	best.current -= total
+	if best.limit > 0 {
+		best.sent = append(best.sent, now)
+	}
+	return best, 0
+}
+
+func (k *pooledKey) prune(now time.Time) {
+	cutoff := now.Add(-keyBudgetWindow)
+	i := 0
+	for i < len(k.sent) && !k.sent[i].After(cutoff) {
+		i++
+	}
+	k.sent = k.sent[i:]
+}
+
+// authorizer returns an authorizer that sets a pooled key on each request
+// with apply and records the key's name in the request's APIKeySelection.
+func (p *KeyPool) authorizer(apply func(*http.Request, string)) HTTPAuthorizer {
+	return AuthorizerFunc(func(req *http.Request) error {
+		name, value, err := p.Next(req.Context())
+		if err != nil {
+			return err
+		}
+		apply(req, value)
+		if selection := APIKeySelectionFromContext(req.Context()); selection != nil {
+			selection.set(name)
+		}
+		return nil
+	})
+}
+
+// AnthropicKeyPoolAuthorizer returns a request authorizer that rotates
+// Anthropic API keys from pool.
+func AnthropicKeyPoolAuthorizer(pool *KeyPool) HTTPAuthorizer {
+	return pool.authorizer(func(req *http.Request, apiKey string) {
+		req.Header.Set("X-Api-Key", apiKey)
+		req.Header.Del("Authorization")
+	})
+}
+
+// OpenAIKeyPoolAuthorizer returns a request authorizer that rotates OpenAI
+// API keys from pool.
+func OpenAIKeyPoolAuthorizer(pool *KeyPool) HTTPAuthorizer {
+	return pool.authorizer(func(req *http.Request, apiKey string) {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	})
+}
+
+type apiKeySelectionKey struct{}
+
+// APIKeySelection records which pooled API key the requests made with a
+// context were sent with, so the usage of an exchange can be attributed to
+// the key.
+type APIKeySelection struct {
+	mu   sync.Mutex
+	name string
+}
+
+// WithAPIKeySelection returns a context whose requests record the pooled key
+// they use in the returned selection.
+func WithAPIKeySelection(ctx context.Context) (context.Context, *APIKeySelection) {
+	selection := &APIKeySelection{}
+	return context.WithValue(ctx, apiKeySelectionKey{}, selection), selection
+}
+
+// APIKeySelectionFromContext returns the selection installed in ctx by
+// WithAPIKeySelection, or nil.
+func APIKeySelectionFromContext(ctx context.Context) *APIKeySelection {
+	selection, _ := ctx.Value(apiKeySelectionKey{}).(*APIKeySelection)
+	return selection
+}
+
+// Name returns the name of the key used by the last request, or "" when no
+// pooled key was used.
+func (s *APIKeySelection) Name() string {
+	if s == nil {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.name
+}
+
+func (s *APIKeySelection) set(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.name = name
+}
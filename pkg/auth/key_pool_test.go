@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKeyPool(t *testing.T, configs ...llmtypes.APIKeyConfig) *KeyPool {
+	t.Helper()
+	for _, config := range configs {
+		t.Setenv(config.EnvVar, "value-of-"+config.EnvVar)
+	}
+	pool, err := NewKeyPool(configs)
+	require.NoError(t, err)
+	return pool
+}
+
+func TestKeyPoolWeightedRoundRobin(t *testing.T) {
+	pool := newTestKeyPool(t,
+		llmtypes.APIKeyConfig{Name: "a", EnvVar: "KEY_POOL_A", Weight: 3},
+		llmtypes.APIKeyConfig{Name: "b", EnvVar: "KEY_POOL_B"},
+	)
+
+	var names []string
+	for range 8 {
+		name, value, err := pool.Next(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "value-of-KEY_POOL_"+map[string]string{"a": "A", "b": "B"}[name], value)
+		names = append(names, name)
+	}
+
+	assert.Equal(t, []string{"a", "a", "b", "a", "a", "a", "b", "a"}, names, "keys are interleaved 3:1")
+}
+
+func TestKeyPoolSkipsKeysOverBudget(t *testing.T) {
+	pool := newTestKeyPool(t,
+		llmtypes.APIKeyConfig{Name: "a", EnvVar: "KEY_POOL_A", Weight: 5, RequestsPerMinute: 1},
+		llmtypes.APIKeyConfig{Name: "b", EnvVar: "KEY_POOL_B", RequestsPerMinute: 2},
+	)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	pool.now = func() time.Time { return now }
+
+	var names []string
+	for range 3 {
+		name, _, err := pool.Next(context.Background())
+		require.NoError(t, err)
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"a", "b", "b"}, names)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, _, err := pool.Next(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "requests_per_minute budget")
+
+	now = now.Add(time.Minute)
+	name, _, err := pool.Next(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "a", name, "budgets free up after a minute")
+}
+
+func TestNewKeyPoolRequiresEnvVars(t *testing.T) {
+	t.Setenv("KEY_POOL_MISSING", "")
+
+	_, err := NewKeyPool([]llmtypes.APIKeyConfig{{Name: "missing", EnvVar: "KEY_POOL_MISSING"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "KEY_POOL_MISSING environment variable is required")
+
+	_, err = NewKeyPool(nil)
+	require.Error(t, err)
+}
+
+func TestKeyPoolAuthorizers(t *testing.T) {
+	pool := newTestKeyPool(t, llmtypes.APIKeyConfig{EnvVar: "KEY_POOL_A"})
+
+	t.Run("anthropic", func(t *testing.T) {
+		ctx, selection := WithAPIKeySelection(context.Background())
+		req := httptest.NewRequest(http.MethodPost, "https://api.anthropic.com/v1/messages", nil).WithContext(ctx)
+		req.Header.Set("Authorization", "Bearer stale")
+
+		require.NoError(t, AnthropicKeyPoolAuthorizer(pool).Authorize(req))
+		assert.Equal(t, "value-of-KEY_POOL_A", req.Header.Get("X-Api-Key"))
+		assert.Empty(t, req.Header.Get("Authorization"))
+		assert.Equal(t, "KEY_POOL_A", selection.Name(), "keys without a name are reported by env var")
+	})
+
+	t.Run("openai", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "https://api.openai.com/v1/responses", nil)
+
+		require.NoError(t, OpenAIKeyPoolAuthorizer(pool).Authorize(req))
+		assert.Equal(t, "Bearer value-of-KEY_POOL_A", req.Header.Get("Authorization"))
+		assert.Nil(t, APIKeySelectionFromContext(req.Context()))
+	})
+}
+
+func TestSharedKeyPoolReusesPool(t *testing.T) {
+	configs := []llmtypes.APIKeyConfig{{Name: "shared", EnvVar: "KEY_POOL_SHARED"}}
+	t.Setenv("KEY_POOL_SHARED", "secret")
+
+	first, err := SharedKeyPool("test-provider", configs)
+	require.NoError(t, err)
+	second, err := SharedKeyPool("test-provider", configs)
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+}
@@ -0,0 +1,164 @@
+// Package bench runs a suite of prompts against several models and compares
+// how they did: wall time, turns, tokens, cost and whether a validation
+// command passed afterwards.
+package bench
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/llm/base"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+)
+
+// Validation outcomes recorded in a Result.
+const (
+	ValidationPassed  = "passed"
+	ValidationFailed  = "failed"
+	ValidationSkipped = "skipped"
+)
+
+// Result is the outcome of one case on one target.
+type Result struct {
+	Case         string  `json:"case"`
+	Target       string  `json:"target"`
+	Provider     string  `json:"provider,omitempty"`
+	Model        string  `json:"model,omitempty"`
+	DurationMS   int64   `json:"duration_ms"`
+	Turns        int     `json:"turns"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+	// Validation is ValidationPassed or ValidationFailed after the
+	// validation command ran, or ValidationSkipped without one.
+	Validation string `json:"validation"`
+	// Error is why the agent run failed, if it did.
+	Error string `json:"error,omitempty"`
+}
+
+// Succeeded reports whether the agent finished and validation, if any,
+// passed.
+func (r Result) Succeeded() bool {
+	return r.Error == "" && r.Validation != ValidationFailed
+}
+
+// ThreadFactory creates the thread a case runs on for target, working in
+// dir. The thread must carry the state, and so the tools, of dir.
+type ThreadFactory func(ctx context.Context, target Target, dir string) (llmtypes.Thread, error)
+
+// Runner runs the cases of a suite on each of its targets.
+type Runner struct {
+	// Dir is the workspace the benchmark runs in. Inside a git repository,
+	// every run gets its own worktree checked out at HEAD, so the changes of
+	// one run do not leak into the next.
+	Dir string
+	// NewThread creates the thread of each run.
+	NewThread ThreadFactory
+	// OnResult, when set, is called as each run finishes.
+	OnResult func(Result)
+	// validate runs a validation command; tests replace it.
+	validate func(ctx context.Context, dir, command string) error
+}
+
+// Run runs every case on every target, one at a time, and returns the
+// results in case order, then target order.
+func (r *Runner) Run(ctx context.Context, suite Suite) []Result {
+	var results []Result
+	for _, c := range suite.Cases {
+		for _, target := range suite.Targets {
+			if ctx.Err() != nil {
+				return results
+			}
+			result := r.runCase(ctx, suite, c, target)
+			if r.OnResult != nil {
+				r.OnResult(result)
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+func (r *Runner) runCase(ctx context.Context, suite Suite, c Case, target Target) Result {
+	result := Result{Case: c.Name, Target: target.Label(), Validation: ValidationSkipped}
+
+	if suite.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, suite.Timeout)
+		defer cancel()
+	}
+
+	dir, cleanup, err := prepareWorkspace(ctx, r.Dir)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer cleanup()
+
+	started := time.Now()
+	thread, err := r.NewThread(ctx, target, dir)
+	if err != nil {
+		result.Error = errors.Wrap(err, "failed to create thread").Error()
+		return result
+	}
+	defer func() {
+		if closer, ok := thread.(interface{ Close() error }); ok {
+			_ = closer.Close()
+		}
+	}()
+	config := thread.GetConfig()
+	result.Provider = thread.Provider()
+	result.Model = config.Model
+
+	_, err = thread.SendMessage(ctx, c.Prompt, &llmtypes.StringCollectorHandler{Silent: true}, llmtypes.MessageOpt{
+		PromptCache:        true,
+		MaxTurns:           suite.MaxTurns,
+		CompactRatio:       config.CompactRatio,
+		NoSaveConversation: true,
+	})
+	result.DurationMS = time.Since(started).Milliseconds()
+	recordUsage(&result, thread)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	command := strings.TrimSpace(c.Validate)
+	if command == "" {
+		command = strings.TrimSpace(suite.Validate)
+	}
+	if command == "" {
+		return result
+	}
+	validate := r.validate
+	if validate == nil {
+		validate = runValidation
+	}
+	result.Validation = ValidationPassed
+	if err := validate(ctx, dir, command); err != nil {
+		result.Validation = ValidationFailed
+	}
+	return result
+}
+
+// recordUsage copies the turns and usage of the finished run into result.
+func recordUsage(result *Result, thread llmtypes.Thread) {
+	usage := thread.GetUsage()
+	if counter, ok := thread.(interface{ RunStats() base.RunStats }); ok {
+		stats := counter.RunStats()
+		result.Turns = stats.Turns
+		usage = stats.Usage
+	}
+	result.InputTokens = usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens
+	result.OutputTokens = usage.OutputTokens
+	result.CostUSD = usage.TotalCost()
+}
+
+func runValidation(ctx context.Context, dir, command string) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = dir
+	return cmd.Run()
+}
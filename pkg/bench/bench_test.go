@@ -0,0 +1,189 @@
+package bench
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/llm/base"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type benchThreadStub struct {
+	llmtypes.Thread
+	model string
+	dir   string
+	err   error
+	stats base.RunStats
+}
+
+func (t *benchThreadStub) Provider() string { return "anthropic" }
+
+func (t *benchThreadStub) GetConfig() llmtypes.Config { return llmtypes.Config{Model: t.model} }
+
+func (t *benchThreadStub) GetUsage() llmtypes.Usage { return t.stats.Usage }
+
+func (t *benchThreadStub) RunStats() base.RunStats { return t.stats }
+
+func (t *benchThreadStub) SendMessage(_ context.Context, message string, _ llmtypes.MessageHandler, _ llmtypes.MessageOpt) (string, error) {
+	if t.err != nil {
+		return "", t.err
+	}
+	// The agent "does the work" by writing the prompt into the workspace.
+	return "done", os.WriteFile(filepath.Join(t.dir, "answer.txt"), []byte(message), 0o644)
+}
+
+func initBenchRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("bench\n"), 0o644))
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	return dir
+}
+
+func TestRunnerComparesTargets(t *testing.T) {
+	repo := initBenchRepo(t)
+	suite := Suite{
+		Targets:  []Target{{Model: "claude-sonnet-4-6"}, {Model: "broken-model"}},
+		Validate: "grep -q fix answer.txt",
+		Cases: []Case{
+			{Name: "fix", Prompt: "fix it"},
+			{Name: "refactor", Prompt: "refactor it"},
+		},
+	}
+	require.NoError(t, suite.Check())
+
+	var dirs []string
+	runner := &Runner{
+		Dir: repo,
+		NewThread: func(_ context.Context, target Target, dir string) (llmtypes.Thread, error) {
+			dirs = append(dirs, dir)
+			thread := &benchThreadStub{model: target.Model, dir: dir, stats: base.RunStats{
+				Turns: 3,
+				Usage: llmtypes.Usage{InputTokens: 1000, CacheReadInputTokens: 500, OutputTokens: 200, InputCost: 0.01, OutputCost: 0.02},
+			}}
+			if target.Model == "broken-model" {
+				thread.err = errors.New("rate limited")
+			}
+			return thread, nil
+		},
+	}
+	var reported int
+	runner.OnResult = func(Result) { reported++ }
+
+	results := runner.Run(context.Background(), suite)
+	require.Len(t, results, 4)
+	assert.Equal(t, 4, reported)
+
+	fix := results[0]
+	assert.Equal(t, "fix", fix.Case)
+	assert.Equal(t, "claude-sonnet-4-6", fix.Target)
+	assert.Equal(t, "anthropic", fix.Provider)
+	assert.Equal(t, 3, fix.Turns)
+	assert.Equal(t, 1500, fix.InputTokens)
+	assert.Equal(t, 200, fix.OutputTokens)
+	assert.InDelta(t, 0.03, fix.CostUSD, 1e-9)
+	assert.Equal(t, ValidationPassed, fix.Validation)
+	assert.True(t, fix.Succeeded())
+
+	assert.Equal(t, "rate limited", results[1].Error)
+	assert.Equal(t, ValidationSkipped, results[1].Validation)
+	assert.False(t, results[1].Succeeded())
+
+	assert.Equal(t, ValidationFailed, results[2].Validation, "validation runs in the case's own workspace")
+
+	for _, dir := range dirs {
+		assert.NotEqual(t, repo, dir, "runs work in worktrees")
+		assert.NoDirExists(t, dir, "worktrees are removed after the run")
+	}
+	assert.NoFileExists(t, filepath.Join(repo, "answer.txt"))
+
+	summaries := Summarize(results)
+	require.Len(t, summaries, 2)
+	assert.Equal(t, "claude-sonnet-4-6", summaries[0].Target)
+	assert.Equal(t, 2, summaries[0].Runs)
+	assert.Equal(t, 1, summaries[0].Succeeded)
+	assert.Equal(t, 0.5, summaries[0].SuccessRate())
+	assert.Equal(t, 3.0, summaries[0].MeanTurns)
+	assert.InDelta(t, 0.06, summaries[0].CostUSD, 1e-9)
+	assert.Equal(t, 0, summaries[1].Succeeded)
+}
+
+func TestRunnerOutsideGitWorksInACopy(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main\n"), 0o644))
+	suite := Suite{Targets: []Target{{Model: "gpt-5.5"}}, Cases: []Case{{Prompt: "hello"}}}
+	require.NoError(t, suite.Check())
+
+	var runDirs []string
+	runner := &Runner{
+		Dir: dir,
+		NewThread: func(_ context.Context, target Target, runDir string) (llmtypes.Thread, error) {
+			runDirs = append(runDirs, runDir)
+			assert.FileExists(t, filepath.Join(runDir, "src", "main.go"), "the run sees the files of the working directory")
+			return &benchThreadStub{model: target.Model, dir: runDir}, nil
+		},
+	}
+	results := runner.Run(context.Background(), suite)
+	require.Len(t, results, 1)
+	assert.Equal(t, "case-1", results[0].Case)
+	assert.True(t, results[0].Succeeded())
+	require.Len(t, runDirs, 1)
+	assert.NotEqual(t, dir, runDirs[0])
+	assert.NoFileExists(t, filepath.Join(dir, "answer.txt"), "the run does not edit the working directory")
+	assert.NoDirExists(t, runDirs[0], "the copy is removed after the run")
+}
+
+func TestSuiteCheckAndTargets(t *testing.T) {
+	target, err := ParseTarget("profile:work")
+	require.NoError(t, err)
+	assert.Equal(t, Target{Profile: "work"}, target)
+	assert.Equal(t, "profile:work", target.Label())
+	target, err = ParseTarget(" gpt-5.5 ")
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-5.5", target.Label())
+	_, err = ParseTarget("profile:")
+	require.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "bench.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+targets:
+  - model: claude-sonnet-4-6
+  - profile: work
+    model: gpt-5.5
+validate: make test
+timeout: 15m
+cases:
+  - recipe: github/pr-review
+    args:
+      pr: "42"
+  - prompt: Fix the bug
+`), 0o644))
+	suite, err := LoadSuite(path)
+	require.NoError(t, err)
+	require.NoError(t, suite.Check())
+	assert.Equal(t, "work/gpt-5.5", suite.Targets[1].Label())
+	assert.Equal(t, "15m0s", suite.Timeout.String())
+	assert.Equal(t, "github/pr-review", suite.Cases[0].Name)
+	assert.Equal(t, "case-2", suite.Cases[1].Name)
+
+	suite.Targets = append(suite.Targets, Target{Model: "claude-sonnet-4-6"})
+	assert.ErrorContains(t, suite.Check(), "target claude-sonnet-4-6 is listed twice")
+	assert.ErrorContains(t, (&Suite{Targets: suite.Targets}).Check(), "no cases")
+	assert.ErrorContains(t, (&Suite{Cases: suite.Cases}).Check(), "no targets")
+}
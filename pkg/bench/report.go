@@ -0,0 +1,121 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/usage"
+)
+
+// Summary aggregates the results of one target across the cases of a suite.
+type Summary struct {
+	Target       string  `json:"target"`
+	Model        string  `json:"model,omitempty"`
+	Runs         int     `json:"runs"`
+	Succeeded    int     `json:"succeeded"`
+	MeanMS       int64   `json:"mean_duration_ms"`
+	MeanTurns    float64 `json:"mean_turns"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// SuccessRate returns the share of runs that succeeded, from 0 to 1.
+func (s Summary) SuccessRate() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.Succeeded) / float64(s.Runs)
+}
+
+// Summarize aggregates results per target, in the order targets first
+// appear.
+func Summarize(results []Result) []Summary {
+	var summaries []Summary
+	index := map[string]int{}
+	totalMS := map[string]int64{}
+	totalTurns := map[string]int{}
+	for _, result := range results {
+		i, ok := index[result.Target]
+		if !ok {
+			i = len(summaries)
+			index[result.Target] = i
+			summaries = append(summaries, Summary{Target: result.Target})
+		}
+		summary := &summaries[i]
+		if summary.Model == "" {
+			summary.Model = result.Model
+		}
+		summary.Runs++
+		if result.Succeeded() {
+			summary.Succeeded++
+		}
+		summary.InputTokens += result.InputTokens
+		summary.OutputTokens += result.OutputTokens
+		summary.CostUSD += result.CostUSD
+		totalMS[result.Target] += result.DurationMS
+		totalTurns[result.Target] += result.Turns
+	}
+	for i := range summaries {
+		summary := &summaries[i]
+		summary.MeanMS = totalMS[summary.Target] / int64(summary.Runs)
+		summary.MeanTurns = float64(totalTurns[summary.Target]) / float64(summary.Runs)
+	}
+	return summaries
+}
+
+// WriteResults writes one row per run.
+func WriteResults(w io.Writer, results []Result) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Case\tTarget\tModel\tTime\tTurns\tTokens in/out\tCost\tValidation\tError")
+	for _, result := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s/%s\t$%.4f\t%s\t%s\n",
+			result.Case,
+			result.Target,
+			result.Model,
+			formatDuration(result.DurationMS),
+			result.Turns,
+			usage.FormatNumber(result.InputTokens),
+			usage.FormatNumber(result.OutputTokens),
+			result.CostUSD,
+			result.Validation,
+			truncate(result.Error, 60),
+		)
+	}
+	tw.Flush()
+}
+
+// WriteSummaries writes one row per target.
+func WriteSummaries(w io.Writer, summaries []Summary) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Target\tModel\tSucceeded\tMean time\tMean turns\tTokens in/out\tTotal cost")
+	for _, summary := range summaries {
+		fmt.Fprintf(tw, "%s\t%s\t%d/%d (%.0f%%)\t%s\t%.1f\t%s/%s\t$%.4f\n",
+			summary.Target,
+			summary.Model,
+			summary.Succeeded,
+			summary.Runs,
+			summary.SuccessRate()*100,
+			formatDuration(summary.MeanMS),
+			summary.MeanTurns,
+			usage.FormatNumber(summary.InputTokens),
+			usage.FormatNumber(summary.OutputTokens),
+			summary.CostUSD,
+		)
+	}
+	tw.Flush()
+}
+
+func formatDuration(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).Round(100 * time.Millisecond).String()
+}
+
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
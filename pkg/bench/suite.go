@@ -0,0 +1,143 @@
+package bench
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Suite is a set of prompts run against every target of a benchmark.
+type Suite struct {
+	// Targets are the models the cases run on. Targets given on the command
+	// line replace them.
+	Targets []Target `yaml:"targets"`
+	// Validate is the shell command that decides whether a run succeeded,
+	// run in the run's workspace after the agent finishes. Cases may
+	// override it; without one, a run succeeds when the agent finishes.
+	Validate string `yaml:"validate"`
+	// MaxTurns caps the turns of each run. 0 means unlimited.
+	MaxTurns int `yaml:"max_turns"`
+	// Timeout caps the wall time of each run, validation included. 0 means
+	// unlimited.
+	Timeout time.Duration `yaml:"timeout"`
+	// Cases are the prompts of the suite.
+	Cases []Case `yaml:"cases"`
+}
+
+// Case is one prompt of a suite.
+type Case struct {
+	// Name identifies the case in the report. Defaults to the recipe name
+	// or "case-N".
+	Name string `yaml:"name"`
+	// Prompt is the message sent to the agent. With Recipe, it is appended
+	// to the rendered recipe.
+	Prompt string `yaml:"prompt"`
+	// Recipe names a recipe rendered into the prompt with Args.
+	Recipe string            `yaml:"recipe"`
+	Args   map[string]string `yaml:"args"`
+	// Validate overrides the suite's validation command for this case.
+	Validate string `yaml:"validate"`
+}
+
+// Target is a model a suite runs on: a model name, a configuration profile,
+// or a model run with the settings of a profile.
+type Target struct {
+	// Name labels the target in the report. Defaults to Profile and Model.
+	Name    string `yaml:"name" json:"name"`
+	Profile string `yaml:"profile" json:"profile,omitempty"`
+	Model   string `yaml:"model" json:"model,omitempty"`
+}
+
+// Label returns the name of the target in the report.
+func (t Target) Label() string {
+	if name := strings.TrimSpace(t.Name); name != "" {
+		return name
+	}
+	switch {
+	case t.Profile != "" && t.Model != "":
+		return t.Profile + "/" + t.Model
+	case t.Profile != "":
+		return "profile:" + t.Profile
+	default:
+		return t.Model
+	}
+}
+
+// ParseTarget parses a --target value: "profile:<name>" selects a
+// configuration profile, anything else names a model.
+func ParseTarget(value string) (Target, error) {
+	value = strings.TrimSpace(value)
+	if profile, ok := strings.CutPrefix(value, "profile:"); ok {
+		if profile = strings.TrimSpace(profile); profile == "" {
+			return Target{}, errors.Errorf("target %q names no profile", value)
+		}
+		return Target{Profile: profile}, nil
+	}
+	if value == "" {
+		return Target{}, errors.New("target must name a model or profile:<name>")
+	}
+	return Target{Model: value}, nil
+}
+
+// LoadSuite reads a suite from a YAML file.
+func LoadSuite(path string) (Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Suite{}, errors.Wrap(err, "failed to read benchmark suite")
+	}
+	var suite Suite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return Suite{}, errors.Wrapf(err, "failed to parse %s", path)
+	}
+	return suite, nil
+}
+
+// Check checks that the suite can run and fills in default case names.
+func (s *Suite) Check() error {
+	if len(s.Cases) == 0 {
+		return errors.New("benchmark suite has no cases")
+	}
+	if len(s.Targets) == 0 {
+		return errors.New("benchmark has no targets; pass --target or list targets in the suite")
+	}
+	if s.MaxTurns < 0 {
+		return errors.Errorf("max_turns must not be negative, got %d", s.MaxTurns)
+	}
+	if s.Timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+
+	seen := map[string]bool{}
+	for i := range s.Targets {
+		target := &s.Targets[i]
+		target.Profile = strings.TrimSpace(target.Profile)
+		target.Model = strings.TrimSpace(target.Model)
+		if target.Profile == "" && target.Model == "" {
+			return errors.Errorf("target %d sets neither model nor profile", i+1)
+		}
+		if seen[target.Label()] {
+			return errors.Errorf("target %s is listed twice", target.Label())
+		}
+		seen[target.Label()] = true
+	}
+
+	names := map[string]bool{}
+	for i := range s.Cases {
+		c := &s.Cases[i]
+		if strings.TrimSpace(c.Prompt) == "" && strings.TrimSpace(c.Recipe) == "" {
+			return errors.Errorf("case %d sets neither prompt nor recipe", i+1)
+		}
+		if c.Name = strings.TrimSpace(c.Name); c.Name == "" {
+			c.Name = strings.TrimSpace(c.Recipe)
+		}
+		if c.Name == "" || names[c.Name] {
+			c.Name = fmt.Sprintf("case-%d", i+1)
+		}
+		names[c.Name] = true
+	}
+	return nil
+}
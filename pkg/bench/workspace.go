@@ -0,0 +1,132 @@
+package bench
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// prepareWorkspace returns the directory a run works in and a function that
+// removes it afterwards. Inside a git repository it is a detached worktree
+// at HEAD, in the same subdirectory as dir; uncommitted changes are not
+// carried over. Elsewhere it is a copy of dir, so runs never edit dir or see
+// each other's edits.
+func prepareWorkspace(ctx context.Context, dir string) (string, func(), error) {
+	root, err := gitOutput(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return copyWorkspace(dir)
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to resolve the working directory")
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to locate the working directory in its repository")
+	}
+
+	parent, err := os.MkdirTemp("", "kodelet-bench-")
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to create benchmark workspace")
+	}
+	worktree := filepath.Join(parent, filepath.Base(root))
+	if _, err := gitOutput(ctx, root, "worktree", "add", "--detach", worktree, "HEAD"); err != nil {
+		_ = os.RemoveAll(parent)
+		return "", nil, errors.Wrap(err, "failed to create benchmark worktree")
+	}
+
+	cleanup := func() {
+		// The run's context may have expired; the worktree still has to go.
+		_, _ = gitOutput(context.Background(), root, "worktree", "remove", "--force", worktree)
+		_ = os.RemoveAll(parent)
+	}
+	return filepath.Join(worktree, rel), cleanup, nil
+}
+
+// copyWorkspace copies dir into a temporary directory for a run outside git.
+func copyWorkspace(dir string) (string, func(), error) {
+	parent, err := os.MkdirTemp("", "kodelet-bench-")
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to create benchmark workspace")
+	}
+	cleanup := func() { _ = os.RemoveAll(parent) }
+	workspace := filepath.Join(parent, filepath.Base(dir))
+	if err := copyTree(dir, workspace); err != nil {
+		cleanup()
+		return "", nil, errors.Wrap(err, "failed to copy the working directory")
+	}
+	return workspace, cleanup, nil
+}
+
+// copyTree copies the directories, regular files and symbolic links under
+// src to dst, keeping their permissions.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case entry.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case entry.Type().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			// Sockets, pipes and devices are not part of a workspace.
+			return nil
+		}
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", errors.Wrap(err, message)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
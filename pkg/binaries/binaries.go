@@ -18,6 +18,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jingkaihe/kodelet/pkg/logger"
@@ -33,6 +34,16 @@ const (
 
 var libexecDir = libexecBinDir
 
+// downloadsDisabled stops managed installs, for networks without internet
+// access.
+var downloadsDisabled atomic.Bool
+
+// DisableDownloads stops binaries from being downloaded. Only packaged,
+// previously installed or system binaries are used.
+func DisableDownloads() {
+	downloadsDisabled.Store(true)
+}
+
 // BinarySpec defines the specification for an external binary
 type BinarySpec struct {
 	Name            string
@@ -64,23 +75,40 @@ func EnsureDepsInstalled(ctx context.Context) {
 
 // BinaryPathCache provides thread-safe caching for binary paths
 type BinaryPathCache struct {
-	path string
-	err  error
-	once sync.Once
+	mu       sync.Mutex
+	resolved bool
+	path     string
+	err      error
+}
+
+// Get returns the cached path, computing it via the provided function until
+// it has been resolved once. A call whose ctx was cancelled is not cached, so
+// one abandoned request does not fail every later one.
+func (c *BinaryPathCache) Get(ctx context.Context, fn func(context.Context) (string, error)) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.resolved {
+		return c.path, c.err
+	}
+	path, err := fn(ctx)
+	if err != nil && ctx.Err() != nil {
+		return "", err
+	}
+	c.path, c.err, c.resolved = path, err, true
+	return path, err
 }
 
-// Get returns the cached path, computing it once via the provided function
-func (c *BinaryPathCache) Get(fn func() (string, error)) (string, error) {
-	c.once.Do(func() {
-		c.path, c.err = fn()
-	})
-	return c.path, c.err
+// Path returns the cached path, or an empty string if it is not resolved.
+func (c *BinaryPathCache) Path() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.path
 }
 
 // ResolveBinary resolves a binary using the following precedence:
 // 1. Packaged libexec binary (e.g. /usr/libexec/kodelet/rg)
 // 2. Managed user binary in ~/.kodelet/bin (if already installed with the expected version)
-// 3. Managed user install attempt into ~/.kodelet/bin
+// 3. Managed user install attempt into ~/.kodelet/bin, unless downloads are disabled
 // 4. System PATH lookup (including alternate names such as Debian's fdfind)
 func ResolveBinary(ctx context.Context, spec BinarySpec) (string, error) {
 	if path, ok := resolveLibexecBinary(ctx, spec); ok {
@@ -91,6 +119,13 @@ func ResolveBinary(ctx context.Context, spec BinarySpec) (string, error) {
 		return path, nil
 	}
 
+	if downloadsDisabled.Load() {
+		if systemPath, ok := resolveSystemBinary(ctx, spec); ok {
+			return systemPath, nil
+		}
+		return "", errors.Errorf("failed to resolve %s (downloads are disabled, and no packaged, managed or system binary found)", spec.Name)
+	}
+
 	path, err := EnsureBinary(ctx, spec)
 	if err == nil {
 		return path, nil
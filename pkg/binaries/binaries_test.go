@@ -323,6 +323,43 @@ func TestResolveBinaryReturnsWrappedErrorWhenAllSourcesFail(t *testing.T) {
 	assert.Contains(t, err.Error(), "failed to resolve missing-tool")
 }
 
+func TestResolveBinarySkipsDownloadWhenDisabled(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+
+	oldLibexecDir := libexecDir
+	libexecDir = filepath.Join(tmpDir, "missing-libexec")
+	t.Cleanup(func() { libexecDir = oldLibexecDir })
+
+	oldHome := os.Getenv("HOME")
+	require.NoError(t, os.Setenv("HOME", filepath.Join(tmpDir, "home")))
+	t.Cleanup(func() { _ = os.Setenv("HOME", oldHome) })
+
+	emptyPath := filepath.Join(tmpDir, "empty-path")
+	require.NoError(t, os.MkdirAll(emptyPath, 0o755))
+	setPathEnv(t, emptyPath)
+
+	DisableDownloads()
+	t.Cleanup(func() { downloadsDisabled.Store(false) })
+
+	spec := BinarySpec{
+		Name:       "missing-tool",
+		Version:    "1.0.0",
+		BinaryName: "missing-tool",
+		GetDownloadURL: func(_, _, _ string) (string, error) {
+			t.Fatal("download should not be attempted")
+			return "", nil
+		},
+		GetVersionCmd: func(binaryPath string) ([]string, func(string) string) {
+			return []string{binaryPath, "--version"}, func(output string) string { return output }
+		},
+	}
+
+	_, err := ResolveBinary(ctx, spec)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "downloads are disabled")
+}
+
 func TestFileExists(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -43,7 +43,7 @@ func FdSpec() BinarySpec {
 // It prefers packaged libexec binaries, then managed binaries, then system fd/fdfind.
 // This is cached after the first successful call.
 func EnsureFd(ctx context.Context) (string, error) {
-	return fdCache.Get(func() (string, error) {
+	return fdCache.Get(ctx, func(ctx context.Context) (string, error) {
 		return ResolveBinary(ctx, FdSpec())
 	})
 }
@@ -51,7 +51,7 @@ func EnsureFd(ctx context.Context) (string, error) {
 // GetFdPath returns the cached fd path without ensuring installation.
 // Returns empty string if fd hasn't been ensured yet.
 func GetFdPath() string {
-	return fdCache.path
+	return fdCache.Path()
 }
 
 func getFdDownloadURL(version, goos, goarch string) (string, error) {
@@ -113,17 +113,18 @@ func TestGetFdVersionCmd(t *testing.T) {
 }
 
 func TestBinaryPathCacheGetCachesResult(t *testing.T) {
+	ctx := context.Background()
 	cache := BinaryPathCache{}
 	calls := 0
 
-	path, err := cache.Get(func() (string, error) {
+	path, err := cache.Get(ctx, func(context.Context) (string, error) {
 		calls++
 		return "/tmp/fd", nil
 	})
 	require.NoError(t, err)
 	assert.Equal(t, "/tmp/fd", path)
 
-	path, err = cache.Get(func() (string, error) {
+	path, err = cache.Get(ctx, func(context.Context) (string, error) {
 		calls++
 		return "", assert.AnError
 	})
@@ -132,6 +133,24 @@ func TestBinaryPathCacheGetCachesResult(t *testing.T) {
 	assert.Equal(t, 1, calls)
 }
 
+func TestBinaryPathCacheGetRetriesAfterCancellation(t *testing.T) {
+	cache := BinaryPathCache{}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := cache.Get(cancelled, func(ctx context.Context) (string, error) {
+		return "", ctx.Err()
+	})
+	require.Error(t, err)
+
+	path, err := cache.Get(context.Background(), func(context.Context) (string, error) {
+		return "/tmp/fd", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/fd", path)
+	assert.Equal(t, "/tmp/fd", cache.Path())
+}
+
 func TestEnsureFdAndRipgrepUseLibexecAndCachePaths(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("uses Unix-style executable scripts")
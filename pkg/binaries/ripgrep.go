@@ -32,7 +32,7 @@ func RipgrepSpec() BinarySpec {
 // It prefers packaged libexec binaries, then managed binaries, then system ripgrep.
 // This is cached after the first successful call.
 func EnsureRipgrep(ctx context.Context) (string, error) {
-	return ripgrepCache.Get(func() (string, error) {
+	return ripgrepCache.Get(ctx, func(ctx context.Context) (string, error) {
 		return ResolveBinary(ctx, RipgrepSpec())
 	})
 }
@@ -40,7 +40,7 @@ func EnsureRipgrep(ctx context.Context) (string, error) {
 // GetRipgrepPath returns the cached ripgrep path without ensuring installation.
 // Returns empty string if ripgrep hasn't been ensured yet.
 func GetRipgrepPath() string {
-	return ripgrepCache.path
+	return ripgrepCache.Path()
 }
 
 func getRipgrepDownloadURL(version, goos, goarch string) (string, error) {
@@ -72,6 +72,7 @@ type ChatEvent struct {
 	UIConfirm      *UIConfirmEvent                 `json:"ui_confirm,omitempty"`
 	UISelect       *UISelectEvent                  `json:"ui_select,omitempty"`
 	UINotify       *UINotifyEvent                  `json:"ui_notify,omitempty"`
+	Collaboration  *CollaborationEvent             `json:"collaboration,omitempty"`
 	Error          string                          `json:"error,omitempty"`
 }
 
@@ -114,6 +115,34 @@ type UINotifyEvent struct {
 	Message string `json:"message"`
 }
 
+// CollaborationEvent describes who watches a conversation in the web UI and
+// which client drives it. Only the driver may send messages, steer, stop the
+// run, or answer prompts.
+type CollaborationEvent struct {
+	ConversationID string                 `json:"conversation_id"`
+	Driver         *CollaborationClient   `json:"driver,omitempty"`
+	Viewers        []CollaborationClient  `json:"viewers"`
+	Takeover       *CollaborationTakeover `json:"takeover,omitempty"`
+}
+
+// CollaborationClient is a web UI client watching a conversation.
+type CollaborationClient struct {
+	// ID is the public ID of the client. It is derived from the secret the
+	// client authenticates with, which is never broadcast.
+	ID       string    `json:"id"`
+	Name     string    `json:"name,omitempty"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// CollaborationTakeover is a viewer's pending request to become the driver.
+type CollaborationTakeover struct {
+	ClientID    string    `json:"client_id"`
+	Name        string    `json:"name,omitempty"`
+	RequestedAt time.Time `json:"requested_at"`
+	// GrantedAt is when the request is granted if the driver does not answer.
+	GrantedAt time.Time `json:"granted_at"`
+}
+
 // ChatEventSink receives streamed chat events.
 type ChatEventSink interface {
 	Send(ChatEvent) error
@@ -311,6 +340,52 @@ func runDefaultChat(
 		}
 	}
 
+	if expandSlashCommand {
+		if undo, err := IsUndoCommand(message); err != nil {
+			return sessionID, err
+		} else if undo {
+			response, err := UndoLastTurn(ctx, sessionID)
+			if err != nil {
+				return sessionID, err
+			}
+			if err := sink.Send(ChatEvent{Kind: "conversation", ConversationID: sessionID, Role: "assistant"}); err != nil {
+				logger.G(ctx).WithError(err).Debug("failed to send undo conversation event")
+			}
+			return sessionID, sink.Send(ChatEvent{Kind: "text", ConversationID: sessionID, Role: "assistant", Content: response})
+		}
+
+		if fork, err := IsForkCommand(message); err != nil {
+			return sessionID, err
+		} else if fork {
+			forkedID, response, err := ForkChat(ctx, sessionID)
+			if err != nil {
+				return sessionID, err
+			}
+			if err := sink.Send(ChatEvent{Kind: "conversation", ConversationID: sessionID, Role: "assistant"}); err != nil {
+				logger.G(ctx).WithError(err).Debug("failed to send fork conversation event")
+			}
+			return forkedID, sink.Send(ChatEvent{Kind: "text", ConversationID: sessionID, Role: "assistant", Content: response})
+		}
+	}
+
+	var conversationContext *conversationservice.ConversationContext
+	if expandSlashCommand {
+		message, conversationContext, err = TransformUseConversationCommand(ctx, message, sessionID)
+		if err != nil {
+			return sessionID, err
+		}
+		expandSlashCommand = conversationContext == nil
+	}
+
+	thinkHarder := false
+	if expandSlashCommand {
+		message, thinkHarder, err = TransformThinkCommand(message)
+		if err != nil {
+			return sessionID, err
+		}
+		expandSlashCommand = !thinkHarder
+	}
+
 	message, slashExpansion, goalUpdate, err := TransformSlashCommandIfNeeded(ctx, message, resolvedCWD, expandSlashCommand)
 	if err != nil {
 		return sessionID, err
@@ -320,7 +395,16 @@ func runDefaultChat(
 		llmConfig.RecipeName = slashExpansion.Command
 	}
 
-	appState, err := BuildState(ctx, llmConfig, sessionID, resolvedCWD, extensionRuntime)
+	var stateOpts []tools.BasicStateOption
+	if store := openChatCheckpoints(ctx, sessionID); store != nil {
+		if err := store.BeginTurn(); err != nil {
+			logger.G(ctx).WithError(err).Warn("failed to record the checkpoint turn")
+		}
+		message = withUndoNotice(ctx, store, message)
+		stateOpts = append(stateOpts, tools.WithCheckpoints(store))
+	}
+
+	appState, err := BuildState(ctx, llmConfig, sessionID, resolvedCWD, extensionRuntime, stateOpts...)
 	if err != nil {
 		return sessionID, err
 	}
@@ -339,6 +423,7 @@ func runDefaultChat(
 	if newThread {
 		thread.EnablePersistence(ctx, true)
 	}
+	AddConversationContext(ctx, thread, conversationContext)
 	if slashExpansion != nil {
 		AddSlashCommandDisplay(thread, slashExpansion)
 	}
@@ -361,10 +446,17 @@ func runDefaultChat(
 		conversationID: sessionID,
 		sink:           sink,
 	}
-	_, err = thread.SendMessage(ctx, message, handler, llmtypes.MessageOpt{
+	opt := llmtypes.MessageOpt{
 		PromptCache: true,
 		Images:      imageInputs,
-	})
+	}
+	if slashExpansion != nil {
+		ApplyFragmentMessageOpt(&opt, &slashExpansion.Metadata)
+	}
+	if thinkHarder {
+		opt.ReasoningEffort = llmtypes.EscalateReasoningEffort(llmConfig)
+	}
+	_, err = thread.SendMessage(ctx, message, handler, opt)
 	if err != nil {
 		return sessionID, errors.Wrap(err, "failed to process chat message")
 	}
@@ -586,6 +678,18 @@ func (s ServiceStoreAdapter) Query(context.Context, convtypes.QueryOptions) (con
 	return convtypes.QueryResult{}, errors.New("query not implemented")
 }
 
+func (s ServiceStoreAdapter) Fork(ctx context.Context, id string) (convtypes.ConversationRecord, error) {
+	forked, err := s.Service.ForkConversation(ctx, id)
+	if err != nil {
+		return convtypes.ConversationRecord{}, err
+	}
+	return s.Load(ctx, forked.ID)
+}
+
+func (s ServiceStoreAdapter) Search(context.Context, string, convtypes.QueryOptions) (convtypes.SearchResult, error) {
+	return convtypes.SearchResult{}, errors.New("search not implemented")
+}
+
 func (s ServiceStoreAdapter) Close() error { return nil }
 
 func (s ServiceStoreAdapter) Load(ctx context.Context, id string) (convtypes.ConversationRecord, error) {
@@ -824,6 +928,24 @@ func TransformSlashCommand(ctx context.Context, message string, cwd string) (str
 	return message, expansion, nil, err
 }
 
+// TransformThinkCommand unwraps `/think harder <message>`, returning the
+// message to send and whether it should use a raised reasoning effort.
+func TransformThinkCommand(message string) (string, bool, error) {
+	command, args, found := slashcommands.Parse(message)
+	if !found {
+		return message, false, nil
+	}
+
+	think, handled, err := slashcommands.ParseThinkCommand(command, args)
+	if !handled {
+		return message, false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return think.Message, true, nil
+}
+
 func TransformSlashCommandIfNeeded(ctx context.Context, message string, cwd string, enabled bool) (string, *slashcommands.Expansion, *goals.CommandUpdate, error) {
 	if !enabled {
 		return message, nil, nil, nil
@@ -901,6 +1023,31 @@ func ApplyFragmentRestrictions(ctx context.Context, llmConfig *llmtypes.Config,
 	if len(fragmentMetadata.AllowedCommands) > 0 {
 		llmConfig.AllowedCommands = fragmentMetadata.AllowedCommands
 	}
+
+	if fragmentMetadata.NoTools {
+		llmConfig.AllowedTools = []string{tools.NoToolsMarker}
+	}
+}
+
+// ApplyFragmentMessageOpt applies the run options declared in a recipe's
+// frontmatter to the message sent for it.
+func ApplyFragmentMessageOpt(opt *llmtypes.MessageOpt, fragmentMetadata *fragments.Metadata) {
+	if fragmentMetadata == nil {
+		return
+	}
+
+	if fragmentMetadata.MaxTurns > 0 {
+		opt.MaxTurns = fragmentMetadata.MaxTurns
+	}
+	if fragmentMetadata.CompactRatio > 0 && fragmentMetadata.CompactRatio <= 1 {
+		opt.CompactRatio = fragmentMetadata.CompactRatio
+	}
+	if fragmentMetadata.UseWeakModel {
+		opt.UseWeakModel = true
+	}
+	if len(fragmentMetadata.Images) > 0 {
+		opt.Images = append(append([]string{}, fragmentMetadata.Images...), opt.Images...)
+	}
 }
 
 func AddSlashCommandDisplay(thread llmtypes.Thread, expansion *slashcommands.Expansion) {
@@ -943,6 +1090,7 @@ func BuildState(
 	sessionID string,
 	workingDir string,
 	extensionRuntime *extensions.Runtime,
+	opts ...tools.BasicStateOption,
 ) (*tools.BasicState, error) {
 	stateOpts := []tools.BasicStateOption{
 		tools.WithWorkingDirectory(workingDir),
@@ -953,6 +1101,7 @@ func BuildState(
 	if extensionRuntime != nil {
 		stateOpts = append(stateOpts, tools.WithExtensionTools(extensionRuntime.Tools()))
 	}
+	stateOpts = append(stateOpts, opts...)
 
 	return tools.NewBasicState(ctx, stateOpts...), nil
 }
@@ -1113,6 +1262,33 @@ func (h *chatMessageHandler) HandleThinking(thinking string) {
 	h.sendEvent(event)
 }
 
+func (h *chatMessageHandler) HandleContentFilter(event llmtypes.ContentFilterEvent) {
+	message := fmt.Sprintf("The %s content policy blocked the response (%s).", event.Provider, event.Reason)
+	if event.Message != "" {
+		message += " " + event.Message
+	}
+	h.sendEvent(ChatEvent{
+		Kind:           "ui-notification",
+		ConversationID: h.conversationID,
+		Role:           "assistant",
+		UINotify:       &UINotifyEvent{Title: "Response blocked", Message: message},
+	})
+}
+
+func (h *chatMessageHandler) HandleToolStall(toolCallID string, toolName string, idle time.Duration) {
+	h.sendEvent(ChatEvent{
+		Kind:           "ui-notification",
+		ConversationID: h.conversationID,
+		Role:           "assistant",
+		ToolCallID:     toolCallID,
+		ToolName:       toolName,
+		UINotify: &UINotifyEvent{
+			Title:   "Tool call stalled",
+			Message: fmt.Sprintf("%s has reported no progress for %s.", toolName, idle.Round(time.Second)),
+		},
+	})
+}
+
 func (h *chatMessageHandler) HandleDone() {}
 
 func (h *chatMessageHandler) HandleUsage(usage llmtypes.Usage) {
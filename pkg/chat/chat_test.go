@@ -11,8 +11,11 @@ import (
 	"testing"
 
 	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/db"
+	"github.com/jingkaihe/kodelet/pkg/db/migrations"
 	"github.com/jingkaihe/kodelet/pkg/extensions"
 	"github.com/jingkaihe/kodelet/pkg/fragments"
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
 	"github.com/spf13/viper"
@@ -372,6 +375,93 @@ func TestTransformWebChatSlashCommandHandlesGoal(t *testing.T) {
 	assert.Equal(t, "Objective: find server cores and ram", goalUpdate.Display)
 }
 
+func TestTransformThinkCommand(t *testing.T) {
+	message, thinkHarder, err := TransformThinkCommand("/think harder why is this slow?")
+	require.NoError(t, err)
+	assert.True(t, thinkHarder)
+	assert.Equal(t, "why is this slow?", message)
+
+	message, thinkHarder, err = TransformThinkCommand("/goal ship it")
+	require.NoError(t, err)
+	assert.False(t, thinkHarder)
+	assert.Equal(t, "/goal ship it", message)
+
+	_, _, err = TransformThinkCommand("/think")
+	assert.EqualError(t, err, "usage: /think harder <message>")
+}
+
+func TestUndoLastTurn(t *testing.T) {
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(path, []byte("before\n"), 0o644))
+
+	undo, err := IsUndoCommand("/undo")
+	require.NoError(t, err)
+	assert.True(t, undo)
+
+	response, err := UndoLastTurn(ctx, "conversation-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Nothing to undo.", response)
+
+	store := openChatCheckpoints(ctx, "conversation-1")
+	require.NotNil(t, store)
+	store.BeginCycle()
+	require.NoError(t, store.Snapshot("file_write", []string{path}))
+	require.NoError(t, os.WriteFile(path, []byte("after\n"), 0o644))
+
+	response, err = UndoLastTurn(ctx, "conversation-1")
+	require.NoError(t, err)
+	assert.Contains(t, response, path)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "before\n", string(content))
+
+	store = openChatCheckpoints(ctx, "conversation-1")
+	message := withUndoNotice(ctx, store, "carry on")
+	assert.Contains(t, message, path)
+	assert.True(t, strings.HasSuffix(message, "\n\ncarry on"))
+	assert.Equal(t, "carry on", withUndoNotice(ctx, store, "carry on"))
+}
+
+func TestForkChat(t *testing.T) {
+	ctx := context.Background()
+	basePath := t.TempDir()
+	t.Setenv("KODELET_BASE_PATH", basePath)
+	database, err := db.Open(ctx, filepath.Join(basePath, "storage.db"))
+	require.NoError(t, err)
+	require.NoError(t, db.NewMigrationRunner(database).Run(ctx, migrations.All()))
+	require.NoError(t, database.Close())
+
+	store, err := conversations.GetConversationStore(ctx)
+	require.NoError(t, err)
+	record := convtypes.NewConversationRecord("conversation-1")
+	record.RawMessages = json.RawMessage(`[{"role":"user","content":"try the cache"}]`)
+	require.NoError(t, store.Save(ctx, record))
+	require.NoError(t, store.Close())
+
+	fork, err := IsForkCommand("/fork")
+	require.NoError(t, err)
+	assert.True(t, fork)
+	_, err = IsForkCommand("/fork now")
+	assert.EqualError(t, err, "usage: /fork")
+
+	forkedID, response, err := ForkChat(ctx, "conversation-1")
+	require.NoError(t, err)
+	assert.NotEqual(t, "conversation-1", forkedID)
+	assert.Contains(t, response, forkedID)
+
+	store, err = conversations.GetConversationStore(ctx)
+	require.NoError(t, err)
+	defer store.Close()
+	forked, err := store.Load(ctx, forkedID)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(record.RawMessages), string(forked.RawMessages))
+
+	_, _, err = ForkChat(ctx, "missing")
+	assert.Error(t, err)
+}
+
 func TestTransformWebChatSlashCommandIfNeededSkipsExtensionPrompt(t *testing.T) {
 	prompt, expansion, goalUpdate, err := TransformSlashCommandIfNeeded(context.Background(), "/tmp/path/from-extension", t.TempDir(), false)
 
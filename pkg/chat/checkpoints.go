@@ -0,0 +1,66 @@
+package chat
+
+import (
+	"context"
+
+	"github.com/jingkaihe/kodelet/pkg/checkpoints"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/slashcommands"
+	"github.com/pkg/errors"
+)
+
+// IsUndoCommand reports whether message is the built-in `/undo` command.
+func IsUndoCommand(message string) (bool, error) {
+	command, args, found := slashcommands.Parse(message)
+	if !found {
+		return false, nil
+	}
+	return slashcommands.ParseUndoCommand(command, args)
+}
+
+// openChatCheckpoints opens the checkpoint store of a conversation. Each chat
+// turn is one cycle, so `/undo` reverts the file changes of the last turn. It
+// returns nil when checkpoints are not available.
+func openChatCheckpoints(ctx context.Context, conversationID string) *checkpoints.Store {
+	root, err := checkpoints.DefaultDir()
+	if err == nil {
+		var store *checkpoints.Store
+		if store, err = checkpoints.Open(root, conversationID); err == nil {
+			return store
+		}
+	}
+	logger.G(ctx).WithError(err).Warn("file checkpoints are disabled for this conversation")
+	return nil
+}
+
+// UndoLastTurn reverts the files changed in the last chat turn of a
+// conversation that changed any, and returns a message for the user. The
+// agent is told about the reverted files with the next message.
+func UndoLastTurn(ctx context.Context, conversationID string) (string, error) {
+	store := openChatCheckpoints(ctx, conversationID)
+	if store == nil {
+		return "", errors.New("file checkpoints are not available")
+	}
+	restored, err := store.UndoLastCycle()
+	if errors.Is(err, checkpoints.ErrNothingToUndo) {
+		return "Nothing to undo.", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to undo file changes")
+	}
+
+	return "Reverted the file changes of the last turn:\n" + checkpoints.FormatRestored(restored), nil
+}
+
+// withUndoNotice prepends the note about files reverted by `/undo` to message,
+// so the agent does not rely on the content it last wrote.
+func withUndoNotice(ctx context.Context, store *checkpoints.Store, message string) string {
+	notice, err := store.TakeUndoNotice()
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to clear undo notice")
+	}
+	if notice == "" {
+		return message
+	}
+	return notice + "\n\n" + message
+}
@@ -0,0 +1,36 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+
+	conversationservice "github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/slashcommands"
+	"github.com/pkg/errors"
+)
+
+// IsForkCommand reports whether message is the built-in `/fork` command.
+func IsForkCommand(message string) (bool, error) {
+	command, args, found := slashcommands.Parse(message)
+	if !found {
+		return false, nil
+	}
+	return slashcommands.ParseForkCommand(command, args)
+}
+
+// ForkChat copies a saved conversation under a new ID and returns the new ID
+// with a message for the user. The chat continues in the copy while the
+// original is left as it was.
+func ForkChat(ctx context.Context, conversationID string) (string, string, error) {
+	store, err := conversationservice.GetConversationStore(ctx)
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to open conversation store")
+	}
+	defer store.Close()
+
+	forked, err := store.Fork(ctx, conversationID)
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to fork conversation")
+	}
+	return forked.ID, fmt.Sprintf("Forked into conversation %s. The original conversation %s is unchanged.", forked.ID, conversationID), nil
+}
@@ -0,0 +1,51 @@
+package chat
+
+import (
+	"context"
+
+	conversationservice "github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/llm"
+	"github.com/jingkaihe/kodelet/pkg/slashcommands"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+)
+
+// TransformUseConversationCommand loads the context of the conversation named
+// by `/use-conversation <id> [--messages N] [message]` and returns the message
+// to send after it. The returned context is nil for any other message.
+func TransformUseConversationCommand(ctx context.Context, message, conversationID string) (string, *conversationservice.ConversationContext, error) {
+	command, args, found := slashcommands.Parse(message)
+	if !found {
+		return message, nil, nil
+	}
+	parsed, handled, err := slashcommands.ParseUseConversationCommand(command, args)
+	if !handled {
+		return message, nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if parsed.ConversationID == conversationID {
+		return "", nil, errors.New("/use-conversation needs a different conversation than the current one")
+	}
+
+	conversationContext, err := llm.LoadConversationContext(ctx, parsed.ConversationID, conversationservice.ConversationContextOptions{Messages: parsed.Messages})
+	if err != nil {
+		return "", nil, err
+	}
+	return parsed.MessageOrDefault(), &conversationContext, nil
+}
+
+// AddConversationContext adds the context of another conversation to thread
+// as a user message, shown to the user by its short display text.
+func AddConversationContext(ctx context.Context, thread llmtypes.Thread, conversationContext *conversationservice.ConversationContext) {
+	if thread == nil || conversationContext == nil {
+		return
+	}
+
+	thread.AddUserMessage(ctx, conversationContext.Prompt)
+	metadata := conversationservice.AddMessageDisplay(thread.GetMetadata(), conversationContext.Prompt, conversationContext.Display, conversationservice.MessageDisplayKindConversationContext, slashcommands.UseConversationCommandName)
+	for key, value := range metadata {
+		thread.SetMetadataValue(key, value)
+	}
+}
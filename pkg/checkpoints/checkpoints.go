@@ -0,0 +1,477 @@
+// Package checkpoints snapshots files before agent tools modify them, so the
+// changes of a run, a step or a chat turn can be undone without git.
+//
+// Each run has its own store holding a manifest of steps, one per
+// file-mutating tool call, and a content-addressed object directory with the
+// file contents captured before each step.
+package checkpoints
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const manifestVersion = 1
+
+const (
+	manifestFileName = "checkpoint.json"
+	objectsDirName   = "objects"
+)
+
+// ErrNothingToUndo is returned when no recorded step matches an undo.
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// DefaultDir returns the checkpoints directory under the Kodelet base
+// directory, honouring KODELET_BASE_PATH.
+func DefaultDir() (string, error) {
+	if basePath := strings.TrimSpace(os.Getenv("KODELET_BASE_PATH")); basePath != "" {
+		return filepath.Join(basePath, "checkpoints"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get home directory")
+	}
+	return filepath.Join(homeDir, ".kodelet", "checkpoints"), nil
+}
+
+// FileState is the content of a file before a step modified it.
+type FileState struct {
+	Path    string `json:"path"`
+	Existed bool   `json:"existed"`
+	// Object is the SHA-256 of the content in the object directory.
+	Object string      `json:"object,omitempty"`
+	Mode   os.FileMode `json:"mode,omitempty"`
+}
+
+// Step is one file-mutating tool call.
+type Step struct {
+	Step int `json:"step"`
+	// Cycle groups the steps of one chat turn or run.
+	Cycle int         `json:"cycle"`
+	Tool  string      `json:"tool"`
+	Time  time.Time   `json:"time"`
+	Files []FileState `json:"files"`
+}
+
+type manifest struct {
+	Version int    `json:"version"`
+	ID      string `json:"id"`
+	Cycle   int    `json:"cycle"`
+	Steps   []Step `json:"steps"`
+	// UndoNotice tells the agent about files reverted by an undo. It is
+	// cleared once read.
+	UndoNotice string `json:"undo_notice,omitempty"`
+}
+
+// Restored is a file an undo put back.
+type Restored struct {
+	Path string
+	// Deleted is set when the file did not exist before and was removed.
+	Deleted bool
+}
+
+// RestoreOptions selects what an undo restores.
+type RestoreOptions struct {
+	// Step restores the content from before this step; zero or one restores
+	// the content from before the run.
+	Step int
+	// Paths limits the undo to these absolute paths when set.
+	Paths []string
+}
+
+// Store is the checkpoint store of one run. It is safe for concurrent use.
+type Store struct {
+	mu       sync.Mutex
+	dir      string
+	manifest manifest
+}
+
+// Open opens the store of run id under root, or an empty one when the run
+// has no checkpoints yet. Nothing is written until the first snapshot.
+func Open(root, id string) (*Store, error) {
+	id = strings.TrimSpace(id)
+	if id == "" || id != filepath.Base(id) || id == "." || id == ".." {
+		return nil, errors.Errorf("invalid checkpoint id %q", id)
+	}
+	s := &Store{
+		dir:      filepath.Join(root, id),
+		manifest: manifest{Version: manifestVersion, ID: id},
+	}
+	data, err := os.ReadFile(s.manifestPath())
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read checkpoint manifest")
+	}
+	if err := json.Unmarshal(data, &s.manifest); err != nil {
+		return nil, errors.Wrap(err, "failed to decode checkpoint manifest")
+	}
+	return s, nil
+}
+
+// Load opens the store of run id under root, failing when the run has no
+// checkpoints.
+func Load(root, id string) (*Store, error) {
+	s, err := Open(root, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(s.manifestPath()); err != nil {
+		return nil, errors.Errorf("no checkpoints found for %s", id)
+	}
+	return s, nil
+}
+
+// ID returns the run ID of the store.
+func (s *Store) ID() string {
+	return s.manifest.ID
+}
+
+// Dir returns the directory of the store.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Steps returns the recorded steps, oldest first.
+func (s *Store) Steps() []Step {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Step(nil), s.manifest.Steps...)
+}
+
+// BeginCycle starts a new group of steps, such as a chat turn, that UndoLastCycle
+// reverts together.
+func (s *Store) BeginCycle() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.manifest.Cycle++
+}
+
+// BeginTurn starts a new cycle for a conversation turn and saves it, so cycle
+// numbers match the turns of the conversation even when a turn changes no
+// files.
+func (s *Store) BeginTurn() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.manifest.Cycle++
+	return s.saveLocked()
+}
+
+// Snapshot records the current content of paths as a new step before tool
+// modifies them. Directories are skipped and missing files are recorded as
+// absent, so undoing the step removes them.
+func (s *Store) Snapshot(tool string, paths []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var files []FileState
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+		state, ok, err := s.captureLocked(path)
+		if err != nil {
+			return err
+		}
+		if ok {
+			files = append(files, state)
+		}
+	}
+	return s.appendStepLocked(tool, files)
+}
+
+// SnapshotContent records files, captured earlier by the caller, as a new
+// step of tool. It is used for tools such as bash whose changed files are only
+// known after they ran.
+func (s *Store) SnapshotContent(tool string, files []FileContent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := make([]FileState, 0, len(files))
+	for _, file := range files {
+		state := FileState{Path: file.Path, Existed: file.Existed, Mode: file.Mode}
+		if file.Existed {
+			object, err := s.writeObjectLocked(file.Content)
+			if err != nil {
+				return err
+			}
+			state.Object = object
+		}
+		states = append(states, state)
+	}
+	return s.appendStepLocked(tool, states)
+}
+
+func (s *Store) appendStepLocked(tool string, files []FileState) error {
+	if len(files) == 0 {
+		return nil
+	}
+	step := Step{Cycle: s.manifest.Cycle, Tool: tool, Time: time.Now().UTC(), Files: files}
+	if n := len(s.manifest.Steps); n > 0 {
+		step.Step = s.manifest.Steps[n-1].Step + 1
+	} else {
+		step.Step = 1
+	}
+	s.manifest.Steps = append(s.manifest.Steps, step)
+	return s.saveLocked()
+}
+
+func (s *Store) captureLocked(path string) (FileState, bool, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return FileState{}, false, errors.Wrapf(err, "failed to resolve %s", path)
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return FileState{Path: path}, true, nil
+	}
+	if err != nil {
+		return FileState{}, false, errors.Wrapf(err, "failed to stat %s", path)
+	}
+	if info.IsDir() {
+		return FileState{}, false, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return FileState{}, false, errors.Wrapf(err, "failed to read %s", path)
+	}
+	object, err := s.writeObjectLocked(content)
+	if err != nil {
+		return FileState{}, false, err
+	}
+	return FileState{Path: path, Existed: true, Object: object, Mode: info.Mode().Perm()}, true, nil
+}
+
+func (s *Store) writeObjectLocked(content []byte) (string, error) {
+	sum := sha256.Sum256(content)
+	object := hex.EncodeToString(sum[:])
+	path := s.objectPath(object)
+	if _, err := os.Stat(path); err == nil {
+		return object, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", errors.Wrap(err, "failed to create checkpoint object directory")
+	}
+	if err := writeFileAtomic(path, content, 0o600); err != nil {
+		return "", errors.Wrap(err, "failed to write checkpoint object")
+	}
+	return object, nil
+}
+
+// Restore puts back the content files had before opts.Step. It returns
+// ErrNothingToUndo when no recorded step matches.
+func (s *Store) Restore(opts RestoreOptions) ([]Restored, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.restoreLocked(max(opts.Step, 1), opts.Paths)
+}
+
+func (s *Store) restoreLocked(fromStep int, paths []string) ([]Restored, error) {
+	filter := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if abs, err := filepath.Abs(path); err == nil {
+			filter[filepath.Clean(abs)] = true
+		}
+	}
+
+	// The earliest snapshot of a file at or after fromStep holds its content
+	// from before that step.
+	targets := make(map[string]FileState)
+	for _, step := range s.manifest.Steps {
+		if step.Step < fromStep {
+			continue
+		}
+		for _, file := range step.Files {
+			if len(filter) > 0 && !filter[file.Path] {
+				continue
+			}
+			if _, ok := targets[file.Path]; !ok {
+				targets[file.Path] = file
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return nil, ErrNothingToUndo
+	}
+
+	ordered := make([]string, 0, len(targets))
+	for path := range targets {
+		ordered = append(ordered, path)
+	}
+	sort.Strings(ordered)
+
+	restored := make([]Restored, 0, len(ordered))
+	for _, path := range ordered {
+		file := targets[path]
+		if !file.Existed {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return restored, errors.Wrapf(err, "failed to remove %s", path)
+			}
+			restored = append(restored, Restored{Path: path, Deleted: true})
+			continue
+		}
+		content, err := os.ReadFile(s.objectPath(file.Object))
+		if err != nil {
+			return restored, errors.Wrapf(err, "failed to read checkpoint of %s", path)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return restored, errors.Wrapf(err, "failed to create directory for %s", path)
+		}
+		mode := file.Mode
+		if mode == 0 {
+			mode = 0o644
+		}
+		if err := writeFileAtomic(path, content, mode); err != nil {
+			return restored, errors.Wrapf(err, "failed to restore %s", path)
+		}
+		restored = append(restored, Restored{Path: path})
+	}
+	return restored, nil
+}
+
+// UndoLastCycle reverts every step of the most recent cycle and forgets those
+// steps, so repeated calls walk further back. The agent is told about the
+// reverted files through TakeUndoNotice.
+func (s *Store) UndoLastCycle() ([]Restored, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	steps := s.manifest.Steps
+	if len(steps) == 0 {
+		return nil, ErrNothingToUndo
+	}
+	cycle := steps[len(steps)-1].Cycle
+	first := len(steps) - 1
+	for first > 0 && steps[first-1].Cycle == cycle {
+		first--
+	}
+	return s.rollbackLocked(first, "from the last turn")
+}
+
+// RollbackToCycle reverts every step of cycle and the cycles after it, so the
+// files are back to the content they had before cycle began, and forgets
+// those steps. The agent is told about the reverted files through
+// TakeUndoNotice.
+func (s *Store) RollbackToCycle(cycle int) ([]Restored, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	first := sort.Search(len(s.manifest.Steps), func(i int) bool {
+		return s.manifest.Steps[i].Cycle >= cycle
+	})
+	if first == len(s.manifest.Steps) {
+		return nil, ErrNothingToUndo
+	}
+	return s.rollbackLocked(first, fmt.Sprintf("from turn %d onwards", cycle))
+}
+
+// CurrentCycle returns the number of the most recent cycle, or zero when no
+// cycle has begun.
+func (s *Store) CurrentCycle() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.manifest.Cycle
+}
+
+// rollbackLocked reverts the steps from index first onwards and forgets them.
+func (s *Store) rollbackLocked(first int, scope string) ([]Restored, error) {
+	steps := s.manifest.Steps
+	restored, err := s.restoreLocked(steps[first].Step, nil)
+	if err != nil {
+		return restored, err
+	}
+	s.manifest.Steps = steps[:first]
+	s.manifest.UndoNotice = undoNotice(s.manifest.UndoNotice, scope, restored)
+	return restored, s.saveLocked()
+}
+
+// TakeUndoNotice returns a note about files reverted since the agent last ran
+// and clears it.
+func (s *Store) TakeUndoNotice() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	notice := s.manifest.UndoNotice
+	if notice == "" {
+		return "", nil
+	}
+	s.manifest.UndoNotice = ""
+	return notice, s.saveLocked()
+}
+
+func undoNotice(previous, scope string, restored []Restored) string {
+	notice := "The user undid your file changes " + scope + ". These files are back to their earlier content:\n" + FormatRestored(restored)
+	if previous != "" {
+		return previous + "\n" + notice
+	}
+	return notice
+}
+
+// FormatRestored lists restored files, one per line, marking removed ones.
+func FormatRestored(restored []Restored) string {
+	lines := make([]string, 0, len(restored))
+	for _, file := range restored {
+		if file.Deleted {
+			lines = append(lines, fmt.Sprintf("- %s (removed)", file.Path))
+		} else {
+			lines = append(lines, "- "+file.Path)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (s *Store) manifestPath() string {
+	return filepath.Join(s.dir, manifestFileName)
+}
+
+func (s *Store) objectPath(object string) string {
+	return filepath.Join(s.dir, objectsDirName, object[:2], object)
+}
+
+func (s *Store) saveLocked() error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return errors.Wrap(err, "failed to create checkpoint directory")
+	}
+	data, err := json.MarshalIndent(s.manifest, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode checkpoint manifest")
+	}
+	if err := writeFileAtomic(s.manifestPath(), append(data, '\n'), 0o600); err != nil {
+		return errors.Wrap(err, "failed to write checkpoint manifest")
+	}
+	return nil
+}
+
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,167 @@
+package checkpoints
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestOpenIsLazy(t *testing.T) {
+	root := t.TempDir()
+	store, err := Open(root, "run-1")
+	require.NoError(t, err)
+	assert.Empty(t, store.Steps())
+	assert.NoDirExists(t, store.Dir())
+
+	_, err = Load(root, "run-1")
+	assert.EqualError(t, err, "no checkpoints found for run-1")
+
+	_, err = Open(root, "../escape")
+	assert.Error(t, err)
+}
+
+func TestSnapshotAndRestore(t *testing.T) {
+	root := t.TempDir()
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.txt")
+	created := filepath.Join(dir, "created.txt")
+	writeFile(t, existing, "original\n")
+
+	store, err := Open(root, "run-1")
+	require.NoError(t, err)
+	store.BeginCycle()
+
+	require.NoError(t, store.Snapshot("file_write", []string{existing}))
+	writeFile(t, existing, "first\n")
+	require.NoError(t, store.Snapshot("file_write", []string{existing, created}))
+	writeFile(t, existing, "second\n")
+	writeFile(t, created, "new\n")
+
+	loaded, err := Load(root, "run-1")
+	require.NoError(t, err)
+	steps := loaded.Steps()
+	require.Len(t, steps, 2)
+	assert.Equal(t, 2, steps[1].Step)
+	assert.Equal(t, "file_write", steps[1].Tool)
+
+	restored, err := loaded.Restore(RestoreOptions{Step: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []Restored{{Path: created, Deleted: true}, {Path: existing}}, restored)
+	assert.Equal(t, "first\n", readFile(t, existing))
+	assert.NoFileExists(t, created)
+
+	restored, err = loaded.Restore(RestoreOptions{})
+	require.NoError(t, err)
+	assert.Len(t, restored, 2)
+	assert.Equal(t, "original\n", readFile(t, existing))
+}
+
+func TestRestoreFiltersPaths(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	writeFile(t, a, "a\n")
+	writeFile(t, b, "b\n")
+
+	store, err := Open(t.TempDir(), "run-1")
+	require.NoError(t, err)
+	require.NoError(t, store.Snapshot("apply_patch", []string{a, b}))
+	writeFile(t, a, "changed a\n")
+	writeFile(t, b, "changed b\n")
+
+	restored, err := store.Restore(RestoreOptions{Paths: []string{b}})
+	require.NoError(t, err)
+	assert.Equal(t, []Restored{{Path: b}}, restored)
+	assert.Equal(t, "changed a\n", readFile(t, a))
+	assert.Equal(t, "b\n", readFile(t, b))
+
+	_, err = store.Restore(RestoreOptions{Paths: []string{filepath.Join(dir, "other.txt")}})
+	assert.ErrorIs(t, err, ErrNothingToUndo)
+}
+
+func TestUndoLastCycle(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(t.TempDir(), "file.txt")
+	writeFile(t, path, "turn 0\n")
+
+	store, err := Open(root, "conversation-1")
+	require.NoError(t, err)
+	for _, content := range []string{"turn 1\n", "turn 2\n"} {
+		store.BeginCycle()
+		require.NoError(t, store.Snapshot("file_edit", []string{path}))
+		writeFile(t, path, content)
+	}
+
+	restored, err := store.UndoLastCycle()
+	require.NoError(t, err)
+	assert.Equal(t, []Restored{{Path: path}}, restored)
+	assert.Equal(t, "turn 1\n", readFile(t, path))
+	assert.Len(t, store.Steps(), 1)
+
+	// A later turn reopens the store from disk.
+	reopened, err := Open(root, "conversation-1")
+	require.NoError(t, err)
+	notice, err := reopened.TakeUndoNotice()
+	require.NoError(t, err)
+	assert.Contains(t, notice, "undid your file changes")
+	assert.Contains(t, notice, path)
+	notice, err = reopened.TakeUndoNotice()
+	require.NoError(t, err)
+	assert.Empty(t, notice)
+
+	_, err = reopened.UndoLastCycle()
+	require.NoError(t, err)
+	assert.Equal(t, "turn 0\n", readFile(t, path))
+
+	_, err = reopened.UndoLastCycle()
+	assert.ErrorIs(t, err, ErrNothingToUndo)
+}
+
+func TestRollbackToCycle(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(t.TempDir(), "file.txt")
+	writeFile(t, path, "turn 0\n")
+
+	store, err := Open(root, "conversation-1")
+	require.NoError(t, err)
+	for _, content := range []string{"turn 1\n", "", "turn 3\n"} {
+		require.NoError(t, store.BeginTurn())
+		if content == "" {
+			continue
+		}
+		require.NoError(t, store.Snapshot("file_edit", []string{path}))
+		writeFile(t, path, content)
+	}
+
+	reopened, err := Open(root, "conversation-1")
+	require.NoError(t, err)
+	assert.Equal(t, 3, reopened.CurrentCycle())
+	assert.Equal(t, 3, reopened.Steps()[1].Cycle)
+
+	_, err = reopened.RollbackToCycle(4)
+	assert.ErrorIs(t, err, ErrNothingToUndo)
+
+	restored, err := reopened.RollbackToCycle(2)
+	require.NoError(t, err)
+	assert.Equal(t, []Restored{{Path: path}}, restored)
+	assert.Equal(t, "turn 1\n", readFile(t, path))
+	assert.Len(t, reopened.Steps(), 1)
+	notice, err := reopened.TakeUndoNotice()
+	require.NoError(t, err)
+	assert.Contains(t, notice, "from turn 2 onwards")
+}
@@ -0,0 +1,216 @@
+package checkpoints
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// maxWorkspaceBaselineBytes caps the content of changed and untracked files a
+// workspace baseline keeps in memory while a command runs.
+const maxWorkspaceBaselineBytes = 64 << 20
+
+// FileContent is the content of a file captured before a step modified it.
+type FileContent struct {
+	Path    string
+	Existed bool
+	Content []byte
+	Mode    os.FileMode
+}
+
+// Workspace is the state of a git working tree before a command that may
+// change any file in it, such as a bash command. Files that match the commit
+// it was captured at are not read; their earlier content is taken from git
+// when the command changed them. Ignored files are not tracked.
+type Workspace struct {
+	root string
+	head string
+	// files holds the changed and untracked files of the working tree.
+	files map[string]FileContent
+}
+
+// CaptureWorkspace captures the git working tree containing dir. It returns
+// nil when dir is not inside a git working tree.
+func CaptureWorkspace(ctx context.Context, dir string) (*Workspace, error) {
+	root, err := gitOutput(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil || strings.TrimSpace(string(root)) == "" {
+		return nil, nil
+	}
+	w := &Workspace{
+		root:  strings.TrimSpace(string(root)),
+		files: make(map[string]FileContent),
+	}
+	w.head = gitHead(ctx, w.root)
+
+	paths, err := w.statusPaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var total int
+	for _, path := range paths {
+		file, ok, err := readFileContent(path)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		total += len(file.Content)
+		if total > maxWorkspaceBaselineBytes {
+			return nil, errors.Errorf("too many changed files in %s to checkpoint", w.root)
+		}
+		w.files[path] = file
+	}
+	return w, nil
+}
+
+// Changes returns the content, from when w was captured, of the files that
+// changed since.
+func (w *Workspace) Changes(ctx context.Context) ([]FileContent, error) {
+	paths, err := w.statusPaths(ctx)
+	if err != nil {
+		return nil, err
+	}
+	head := gitHead(ctx, w.root)
+	if head != w.head && w.head != "" {
+		// Files committed by the command match the new commit, so they no
+		// longer show up as changed.
+		committed, err := w.gitPaths(ctx, "diff", "--name-only", "-z", "--no-renames", w.head, head, "--")
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, committed...)
+	}
+	for path := range w.files {
+		paths = append(paths, path)
+	}
+
+	var changes []FileContent
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		current, ok, err := readFileContent(path)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		before, captured := w.files[path]
+		if !captured {
+			if before, err = w.committedContent(ctx, path); err != nil {
+				return nil, err
+			}
+		}
+		if before.Existed == current.Existed && bytes.Equal(before.Content, current.Content) {
+			continue
+		}
+		changes = append(changes, before)
+	}
+	return changes, nil
+}
+
+// statusPaths lists the absolute paths of the changed and untracked files.
+func (w *Workspace) statusPaths(ctx context.Context) ([]string, error) {
+	output, err := gitOutput(ctx, w.root, "status", "--porcelain=v1", "-z", "--untracked-files=all", "--no-renames")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get git status")
+	}
+	var paths []string
+	for _, entry := range strings.Split(string(output), "\x00") {
+		// Each entry is a two letter status, a space and the path.
+		if len(entry) > 3 {
+			paths = append(paths, filepath.Join(w.root, filepath.FromSlash(entry[3:])))
+		}
+	}
+	return paths, nil
+}
+
+func (w *Workspace) gitPaths(ctx context.Context, args ...string) ([]string, error) {
+	output, err := gitOutput(ctx, w.root, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to run git %s", args[0])
+	}
+	var paths []string
+	for _, path := range strings.Split(string(output), "\x00") {
+		if path != "" {
+			paths = append(paths, filepath.Join(w.root, filepath.FromSlash(path)))
+		}
+	}
+	return paths, nil
+}
+
+// committedContent returns the content path has in the commit w was captured
+// at, or an absent file when it is not part of it.
+func (w *Workspace) committedContent(ctx context.Context, path string) (FileContent, error) {
+	file := FileContent{Path: path}
+	if w.head == "" {
+		return file, nil
+	}
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return file, errors.Wrapf(err, "failed to resolve %s", path)
+	}
+	entry, err := gitOutput(ctx, w.root, "ls-tree", "-z", w.head, "--", filepath.ToSlash(rel))
+	if err != nil {
+		return file, errors.Wrapf(err, "failed to look up %s in git", rel)
+	}
+	// An entry is "<mode> <type> <object>\t<path>".
+	fields := strings.Fields(strings.SplitN(string(entry), "\t", 2)[0])
+	if len(fields) != 3 || fields[1] != "blob" {
+		return file, nil
+	}
+	content, err := gitOutput(ctx, w.root, "cat-file", "blob", fields[2])
+	if err != nil {
+		return file, errors.Wrapf(err, "failed to read %s from git", rel)
+	}
+	file.Existed = true
+	file.Content = content
+	file.Mode = 0o644
+	if fields[0] == "100755" {
+		file.Mode = 0o755
+	}
+	return file, nil
+}
+
+// readFileContent reads path, reporting false for directories.
+func readFileContent(path string) (FileContent, bool, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return FileContent{Path: path}, true, nil
+	}
+	if err != nil {
+		return FileContent{}, false, errors.Wrapf(err, "failed to stat %s", path)
+	}
+	if info.IsDir() {
+		return FileContent{}, false, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return FileContent{}, false, errors.Wrapf(err, "failed to read %s", path)
+	}
+	return FileContent{Path: path, Existed: true, Content: content, Mode: info.Mode().Perm()}, true, nil
+}
+
+func gitHead(ctx context.Context, root string) string {
+	head, err := gitOutput(ctx, root, "rev-parse", "--verify", "-q", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(head))
+}
+
+func gitOutput(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	return cmd.Output()
+}
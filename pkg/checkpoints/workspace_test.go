@@ -0,0 +1,88 @@
+package checkpoints
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
+func TestWorkspaceChanges(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := context.Background()
+	repo := t.TempDir()
+	clean := filepath.Join(repo, "clean.txt")
+	dirty := filepath.Join(repo, "dirty.txt")
+	removed := filepath.Join(repo, "removed.txt")
+	untouched := filepath.Join(repo, "untouched.txt")
+	created := filepath.Join(repo, "sub", "created.txt")
+	writeFile(t, clean, "clean\n")
+	writeFile(t, dirty, "committed\n")
+	writeFile(t, removed, "removed\n")
+	writeFile(t, untouched, "untouched\n")
+	runGit(t, repo, "init", "-q")
+	runGit(t, repo, "add", ".")
+	runGit(t, repo, "commit", "-q", "-m", "initial")
+	writeFile(t, dirty, "work in progress\n")
+
+	workspace, err := CaptureWorkspace(ctx, repo)
+	require.NoError(t, err)
+	require.NotNil(t, workspace)
+
+	// What a shell command might do.
+	writeFile(t, clean, "changed\n")
+	writeFile(t, dirty, "more work\n")
+	require.NoError(t, os.Remove(removed))
+	require.NoError(t, os.MkdirAll(filepath.Dir(created), 0o755))
+	writeFile(t, created, "new\n")
+
+	changes, err := workspace.Changes(ctx)
+	require.NoError(t, err)
+	byPath := make(map[string]FileContent, len(changes))
+	for _, change := range changes {
+		byPath[change.Path] = change
+	}
+	require.Len(t, byPath, 4)
+	assert.Equal(t, "clean\n", string(byPath[clean].Content))
+	assert.Equal(t, "work in progress\n", string(byPath[dirty].Content))
+	assert.Equal(t, "removed\n", string(byPath[removed].Content))
+	assert.False(t, byPath[created].Existed)
+
+	store, err := Open(t.TempDir(), "conversation-1")
+	require.NoError(t, err)
+	require.NoError(t, store.SnapshotContent("bash", changes))
+	_, err = store.Restore(RestoreOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "clean\n", readFile(t, clean))
+	assert.Equal(t, "work in progress\n", readFile(t, dirty))
+	assert.Equal(t, "removed\n", readFile(t, removed))
+	assert.NoFileExists(t, created)
+}
+
+func TestCaptureWorkspaceOutsideGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_CEILING_DIRECTORIES", os.TempDir())
+	workspace, err := CaptureWorkspace(context.Background(), t.TempDir())
+	require.NoError(t, err)
+	assert.Nil(t, workspace)
+}
@@ -0,0 +1,63 @@
+package conversations
+
+import (
+	"encoding/json"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// APIKeyUsageMetadataKey stores the usage of each pooled API key a
+// conversation sent requests with.
+const APIKeyUsageMetadataKey = "api_key_usage"
+
+// APIKeyUsage is the usage attributed to one pooled API key.
+type APIKeyUsage struct {
+	Requests int            `json:"requests"`
+	Usage    llmtypes.Usage `json:"usage"`
+}
+
+// AddAPIKeyUsage adds the usage of one request sent with the key name to
+// metadata.
+func AddAPIKeyUsage(metadata map[string]any, name string, usage llmtypes.Usage) map[string]any {
+	if metadata == nil {
+		metadata = make(map[string]any)
+	}
+	keys := APIKeyUsageFromMetadata(metadata)
+	if keys == nil {
+		keys = make(map[string]APIKeyUsage)
+	}
+	entry := keys[name]
+	entry.Requests++
+	entry.Usage.InputTokens += usage.InputTokens
+	entry.Usage.OutputTokens += usage.OutputTokens
+	entry.Usage.CacheCreationInputTokens += usage.CacheCreationInputTokens
+	entry.Usage.CacheReadInputTokens += usage.CacheReadInputTokens
+	entry.Usage.InputCost += usage.InputCost
+	entry.Usage.OutputCost += usage.OutputCost
+	entry.Usage.CacheCreationCost += usage.CacheCreationCost
+	entry.Usage.CacheReadCost += usage.CacheReadCost
+	keys[name] = entry
+	metadata[APIKeyUsageMetadataKey] = keys
+	return metadata
+}
+
+// APIKeyUsageFromMetadata decodes the per-key usage stored in conversation
+// metadata. It returns nil for conversations that used no pooled key.
+func APIKeyUsageFromMetadata(metadata map[string]any) map[string]APIKeyUsage {
+	value, ok := metadata[APIKeyUsageMetadataKey]
+	if !ok || value == nil {
+		return nil
+	}
+	if keys, ok := value.(map[string]APIKeyUsage); ok {
+		return keys
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var keys map[string]APIKeyUsage
+	if err := json.Unmarshal(raw, &keys); err != nil || len(keys) == 0 {
+		return nil
+	}
+	return keys
+}
@@ -0,0 +1,37 @@
+package conversations
+
+import (
+	"encoding/json"
+	"testing"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyUsageMetadataRoundTrip(t *testing.T) {
+	metadata := AddAPIKeyUsage(nil, "team-a", llmtypes.Usage{InputTokens: 10, OutputTokens: 2, InputCost: 0.1})
+	metadata = AddAPIKeyUsage(metadata, "team-a", llmtypes.Usage{InputTokens: 5, OutputTokens: 1, InputCost: 0.05})
+	metadata = AddAPIKeyUsage(metadata, "team-b", llmtypes.Usage{InputTokens: 7})
+
+	// Metadata is persisted as JSON, so decode the stored form as well.
+	raw, err := json.Marshal(metadata)
+	require.NoError(t, err)
+	var stored map[string]any
+	require.NoError(t, json.Unmarshal(raw, &stored))
+
+	for _, m := range []map[string]any{metadata, stored} {
+		keys := APIKeyUsageFromMetadata(m)
+		require.Len(t, keys, 2)
+		assert.Equal(t, 2, keys["team-a"].Requests)
+		assert.Equal(t, 15, keys["team-a"].Usage.InputTokens)
+		assert.Equal(t, 3, keys["team-a"].Usage.OutputTokens)
+		assert.InDelta(t, 0.15, keys["team-a"].Usage.InputCost, 1e-9)
+		assert.Equal(t, 1, keys["team-b"].Requests)
+	}
+}
+
+func TestAPIKeyUsageFromMetadataIgnoresMissingAndInvalidValues(t *testing.T) {
+	assert.Nil(t, APIKeyUsageFromMetadata(nil))
+	assert.Nil(t, APIKeyUsageFromMetadata(map[string]any{APIKeyUsageMetadataKey: "not-an-object"}))
+}
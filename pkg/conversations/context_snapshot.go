@@ -0,0 +1,304 @@
+package conversations
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// ContextSnapshotMetadataKey stores what the agent could see of the
+	// working directory when the conversation was last saved.
+	ContextSnapshotMetadataKey = "context_snapshot"
+	// MessageDisplayKindContextRefresh marks the note injected when a resumed
+	// conversation's repository changed since it was last saved.
+	MessageDisplayKindContextRefresh = "context-refresh"
+
+	contextSnapshotVersion = 1
+	// maxSnapshotDirtyFiles bounds how many uncommitted files are hashed for
+	// a snapshot.
+	maxSnapshotDirtyFiles = 500
+	// maxRefreshNoteFiles bounds how many changed files the note lists.
+	maxRefreshNoteFiles = 50
+	deletedFileHash     = "deleted"
+	// snapshotGitTimeout keeps a slow repository from stalling saves.
+	snapshotGitTimeout = 5 * time.Second
+)
+
+// ContextSnapshot records the discovered context files and the git state of
+// the working directory when a conversation was saved.
+type ContextSnapshot struct {
+	Version int `json:"version"`
+	// Contexts maps each discovered context file to the SHA-256 of its content.
+	Contexts map[string]string `json:"contexts"`
+	// GitHead is the commit checked out in the working directory.
+	GitHead string `json:"gitHead,omitempty"`
+	// DirtyFiles maps uncommitted files, relative to the repository root, to
+	// the SHA-256 of their content, so edits made during the session are not
+	// reported as changed afterwards.
+	DirtyFiles map[string]string `json:"dirtyFiles,omitempty"`
+	CapturedAt time.Time         `json:"capturedAt"`
+}
+
+// ContextRefresh describes how the working directory changed between two
+// snapshots.
+type ContextRefresh struct {
+	Since            time.Time
+	AddedContexts    []string
+	RemovedContexts  []string
+	ModifiedContexts []string
+	ChangedFiles     []string
+	// FilesUnknown is set when the changed files could not be determined, for
+	// example because the earlier commit no longer exists.
+	FilesUnknown bool
+}
+
+// NewContextSnapshot captures contexts, as returned by State.DiscoverContexts,
+// and the git state of workingDir. Outside a git repository only the contexts
+// are recorded.
+func NewContextSnapshot(ctx context.Context, contexts map[string]string, workingDir string) ContextSnapshot {
+	snapshot := ContextSnapshot{
+		Version:    contextSnapshotVersion,
+		Contexts:   make(map[string]string, len(contexts)),
+		CapturedAt: time.Now().UTC(),
+	}
+	for path, content := range contexts {
+		snapshot.Contexts[path] = hashString(content)
+	}
+
+	// Saves often run as the request finishes or is cancelled.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), snapshotGitTimeout)
+	defer cancel()
+
+	root, err := gitOutput(ctx, workingDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return snapshot
+	}
+	root = strings.TrimSpace(root)
+	head, err := gitOutput(ctx, root, "rev-parse", "HEAD")
+	if err != nil {
+		return snapshot
+	}
+	snapshot.GitHead = strings.TrimSpace(head)
+
+	dirty, err := gitChangedFiles(ctx, root, snapshot.GitHead)
+	if err != nil {
+		return snapshot
+	}
+	if len(dirty) > maxSnapshotDirtyFiles {
+		dirty = dirty[:maxSnapshotDirtyFiles]
+	}
+	snapshot.DirtyFiles = make(map[string]string, len(dirty))
+	for _, path := range dirty {
+		snapshot.DirtyFiles[path] = hashFile(filepath.Join(root, path))
+	}
+	return snapshot
+}
+
+// AddContextSnapshot stores snapshot in conversation metadata.
+func AddContextSnapshot(metadata map[string]any, snapshot ContextSnapshot) map[string]any {
+	if metadata == nil {
+		metadata = make(map[string]any)
+	}
+	metadata[ContextSnapshotMetadataKey] = snapshot
+	return metadata
+}
+
+// ContextSnapshotFromMetadata decodes a persisted context snapshot. The
+// boolean is false for conversations saved before snapshots were recorded.
+func ContextSnapshotFromMetadata(metadata map[string]any) (ContextSnapshot, bool) {
+	value, ok := metadata[ContextSnapshotMetadataKey]
+	if !ok || value == nil {
+		return ContextSnapshot{}, false
+	}
+	if snapshot, ok := value.(ContextSnapshot); ok {
+		return snapshot, true
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return ContextSnapshot{}, false
+	}
+	var snapshot ContextSnapshot
+	if err := json.Unmarshal(raw, &snapshot); err != nil || snapshot.Version == 0 {
+		return ContextSnapshot{}, false
+	}
+	return snapshot, true
+}
+
+// DiffContextSnapshots compares the snapshot saved with a conversation against
+// the current one and lists the files in workingDir that changed since.
+func DiffContextSnapshots(ctx context.Context, previous, current ContextSnapshot, workingDir string) ContextRefresh {
+	refresh := ContextRefresh{Since: previous.CapturedAt}
+	for path, hash := range current.Contexts {
+		previousHash, ok := previous.Contexts[path]
+		switch {
+		case !ok:
+			refresh.AddedContexts = append(refresh.AddedContexts, path)
+		case previousHash != hash:
+			refresh.ModifiedContexts = append(refresh.ModifiedContexts, path)
+		}
+	}
+	for path := range previous.Contexts {
+		if _, ok := current.Contexts[path]; !ok {
+			refresh.RemovedContexts = append(refresh.RemovedContexts, path)
+		}
+	}
+	slices.Sort(refresh.AddedContexts)
+	slices.Sort(refresh.ModifiedContexts)
+	slices.Sort(refresh.RemovedContexts)
+
+	if previous.GitHead == "" || current.GitHead == "" {
+		return refresh
+	}
+	root, err := gitOutput(ctx, workingDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		refresh.FilesUnknown = true
+		return refresh
+	}
+	root = strings.TrimSpace(root)
+	candidates, err := gitChangedFiles(ctx, root, previous.GitHead)
+	if err != nil {
+		refresh.FilesUnknown = true
+		return refresh
+	}
+
+	candidateSet := make(map[string]struct{}, len(candidates))
+	for _, path := range candidates {
+		candidateSet[path] = struct{}{}
+		if previousHash, ok := previous.DirtyFiles[path]; ok && previousHash == hashFile(filepath.Join(root, path)) {
+			continue
+		}
+		refresh.ChangedFiles = append(refresh.ChangedFiles, path)
+	}
+	// Files edited during the session but since reverted to the earlier commit
+	// no longer show up in the diff, yet changed all the same.
+	for path := range previous.DirtyFiles {
+		if _, ok := candidateSet[path]; !ok {
+			refresh.ChangedFiles = append(refresh.ChangedFiles, path)
+		}
+	}
+	slices.Sort(refresh.ChangedFiles)
+	return refresh
+}
+
+// Empty reports whether nothing changed.
+func (r ContextRefresh) Empty() bool {
+	return len(r.AddedContexts) == 0 && len(r.RemovedContexts) == 0 && len(r.ModifiedContexts) == 0 &&
+		len(r.ChangedFiles) == 0 && !r.FilesUnknown
+}
+
+// Summary returns a one-line description for display.
+func (r ContextRefresh) Summary() string {
+	contexts := len(r.AddedContexts) + len(r.RemovedContexts) + len(r.ModifiedContexts)
+	parts := []string{}
+	if len(r.ChangedFiles) > 0 {
+		parts = append(parts, pluralize(len(r.ChangedFiles), "file"))
+	}
+	if contexts > 0 {
+		parts = append(parts, pluralize(contexts, "context file"))
+	}
+	if len(parts) == 0 {
+		return "Repository changed since the last session"
+	}
+	return "Repository changed since the last session: " + strings.Join(parts, " and ") + " changed"
+}
+
+// RenderContextRefresh renders the note telling the agent what changed since
+// the conversation was last saved.
+func RenderContextRefresh(r ContextRefresh) string {
+	var b strings.Builder
+	b.WriteString("<context_refresh>\n")
+	b.WriteString("The repository changed since the last session of this conversation")
+	if !r.Since.IsZero() {
+		fmt.Fprintf(&b, " (%s)", r.Since.UTC().Format("2006-01-02 15:04 UTC"))
+	}
+	b.WriteString(". Assumptions from earlier in the conversation about the files below may be stale; re-read them before relying on them.\n")
+
+	writeList := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s:\n", title)
+		for i, item := range items {
+			if i == maxRefreshNoteFiles {
+				fmt.Fprintf(&b, "- ... and %d more\n", len(items)-maxRefreshNoteFiles)
+				break
+			}
+			fmt.Fprintf(&b, "- %s\n", item)
+		}
+	}
+	writeList("Context files modified", r.ModifiedContexts)
+	writeList("Context files added", r.AddedContexts)
+	writeList("Context files removed", r.RemovedContexts)
+	writeList("Files changed", r.ChangedFiles)
+	if r.FilesUnknown {
+		b.WriteString("\nThe changed files could not be determined because the commit the last session was on is no longer available; check `git status` and `git log`.\n")
+	}
+	b.WriteString("</context_refresh>")
+	return b.String()
+}
+
+// gitChangedFiles lists files in root that differ from commit, including
+// untracked files, relative to root.
+func gitChangedFiles(ctx context.Context, root, commit string) ([]string, error) {
+	tracked, err := gitOutput(ctx, root, "diff", "--name-only", "--no-renames", commit, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := gitOutput(ctx, root, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(tracked+"\n"+untracked, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	slices.Sort(files)
+	return slices.Compact(files), nil
+}
+
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+func hashString(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func hashFile(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return deletedFileHash
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return deletedFileHash
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
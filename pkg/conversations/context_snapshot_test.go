@@ -0,0 +1,99 @@
+package conversations
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextSnapshotDiff(t *testing.T) {
+	ctx := context.Background()
+	repoDir := t.TempDir()
+	runGit := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0o644))
+	}
+
+	runGit("init")
+	runGit("config", "user.email", "test@example.com")
+	runGit("config", "user.name", "Test User")
+	write("main.go", "package main\n")
+	write("util.go", "package main\n")
+	write("README.md", "readme\n")
+	runGit("add", ".")
+	runGit("commit", "-m", "initial")
+
+	// Uncommitted edits made during the session.
+	write("main.go", "package main // edited in session\n")
+	write("util.go", "package main // edited in session\n")
+
+	agentsPath := filepath.Join(repoDir, "AGENTS.md")
+	previous := NewContextSnapshot(ctx, map[string]string{agentsPath: "use tabs", "/home/AGENTS.md": "be brief"}, repoDir)
+	require.NotEmpty(t, previous.GitHead)
+	assert.Len(t, previous.DirtyFiles, 2)
+
+	// Round-trip through persisted metadata.
+	raw, err := json.Marshal(AddContextSnapshot(nil, previous))
+	require.NoError(t, err)
+	var metadata map[string]any
+	require.NoError(t, json.Unmarshal(raw, &metadata))
+	previous, ok := ContextSnapshotFromMetadata(metadata)
+	require.True(t, ok)
+
+	// Changes made after the session: main.go keeps its session edit, util.go
+	// is reverted, README.md is committed and a new file appears.
+	runGit("checkout", "util.go")
+	write("README.md", "readme v2\n")
+	runGit("commit", "-am", "update readme")
+	write("new.go", "package main\n")
+
+	current := NewContextSnapshot(ctx, map[string]string{agentsPath: "use spaces", filepath.Join(repoDir, "CLAUDE.md"): "x"}, repoDir)
+	refresh := DiffContextSnapshots(ctx, previous, current, repoDir)
+
+	assert.Equal(t, []string{agentsPath}, refresh.ModifiedContexts)
+	assert.Equal(t, []string{filepath.Join(repoDir, "CLAUDE.md")}, refresh.AddedContexts)
+	assert.Equal(t, []string{"/home/AGENTS.md"}, refresh.RemovedContexts)
+	assert.Equal(t, []string{"README.md", "new.go", "util.go"}, refresh.ChangedFiles)
+	assert.False(t, refresh.Empty())
+	assert.Equal(t, "Repository changed since the last session: 3 files and 3 context files changed", refresh.Summary())
+
+	note := RenderContextRefresh(refresh)
+	assert.True(t, strings.HasPrefix(note, "<context_refresh>"))
+	assert.Contains(t, note, "Files changed:\n- README.md\n- new.go\n- util.go\n")
+	assert.Contains(t, note, "Context files modified:\n- "+agentsPath)
+	assert.NotContains(t, note, "main.go")
+
+	unchanged := DiffContextSnapshots(ctx, current, NewContextSnapshot(ctx, map[string]string{agentsPath: "use spaces", filepath.Join(repoDir, "CLAUDE.md"): "x"}, repoDir), repoDir)
+	assert.True(t, unchanged.Empty())
+}
+
+func TestContextSnapshotDiffUnknownCommit(t *testing.T) {
+	repoDir := t.TempDir()
+	cmd := exec.Command("git", "init")
+	cmd.Dir = repoDir
+	require.NoError(t, cmd.Run())
+
+	previous := ContextSnapshot{Version: 1, GitHead: "0123456789abcdef0123456789abcdef01234567"}
+	current := ContextSnapshot{Version: 1, GitHead: "fedcba9876543210fedcba9876543210fedcba98"}
+	refresh := DiffContextSnapshots(context.Background(), previous, current, repoDir)
+
+	assert.True(t, refresh.FilesUnknown)
+	assert.Contains(t, RenderContextRefresh(refresh), "could not be determined")
+}
+
+func TestContextSnapshotFromMetadataMissing(t *testing.T) {
+	_, ok := ContextSnapshotFromMetadata(map[string]any{"model": "x"})
+	assert.False(t, ok)
+}
@@ -0,0 +1,111 @@
+package conversations
+
+import (
+	"fmt"
+	"strings"
+
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MessageDisplayKindConversationContext marks the note injected with the
+	// context of another conversation by /use-conversation or
+	// `kodelet run --use-conversation`.
+	MessageDisplayKindConversationContext = "conversation-context"
+
+	// MaxConversationContextMessages bounds how many recent messages of the
+	// other conversation can be included.
+	MaxConversationContextMessages = 50
+	// conversationContextFallbackMessages is how many recent messages are
+	// included when the other conversation has no summary.
+	conversationContextFallbackMessages = 10
+	// maxConversationContextMessageBytes caps each included message.
+	maxConversationContextMessageBytes = 4000
+)
+
+// ConversationContextOptions controls which parts of another conversation are
+// injected as context.
+type ConversationContextOptions struct {
+	// Messages is how many of the most recent user and assistant messages are
+	// included alongside the summary.
+	Messages int
+}
+
+// ConversationContext is the note injecting another conversation's context,
+// with the short text shown to the user in its place.
+type ConversationContext struct {
+	Prompt  string
+	Display string
+}
+
+// RenderConversationContext renders the summary of record and its most recent
+// text messages as a note for another conversation. The note is a reference:
+// the two conversations stay separate. When record has no summary, its recent
+// messages are included instead. It fails when there is nothing to share.
+func RenderConversationContext(record convtypes.ConversationRecord, messages []StreamableMessage, opts ConversationContextOptions) (ConversationContext, error) {
+	summary := strings.TrimSpace(record.Summary)
+	count := min(max(opts.Messages, 0), MaxConversationContextMessages)
+	if summary == "" && count == 0 {
+		count = conversationContextFallbackMessages
+	}
+	recent := recentTextMessages(messages, count)
+	if summary == "" && len(recent) == 0 {
+		return ConversationContext{}, errors.Errorf("conversation %s has no summary or messages to share", record.ID)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<conversation_context id=%q>\n", record.ID)
+	b.WriteString("Context from an earlier, separate conversation")
+	if !record.UpdatedAt.IsZero() {
+		fmt.Fprintf(&b, " (last updated %s)", record.UpdatedAt.UTC().Format("2006-01-02 15:04 UTC"))
+	}
+	b.WriteString(", shared for reference. Files may have changed since; re-read them before relying on details below.\n")
+	if summary != "" {
+		fmt.Fprintf(&b, "\n## Summary\n%s\n", summary)
+	}
+	if len(recent) > 0 {
+		b.WriteString("\n## Recent messages\n")
+		for _, message := range recent {
+			fmt.Fprintf(&b, "\n### %s\n%s\n", markdownRoleHeading(message.Role, message.Kind), truncateUTF8(strings.TrimSpace(message.Content), maxConversationContextMessageBytes))
+		}
+	}
+	b.WriteString("</conversation_context>")
+
+	var parts []string
+	if summary != "" {
+		parts = append(parts, "summary")
+	}
+	if len(recent) == 1 {
+		parts = append(parts, "1 message")
+	} else if len(recent) > 1 {
+		parts = append(parts, fmt.Sprintf("%d messages", len(recent)))
+	}
+	display := fmt.Sprintf("Using context from conversation %s", record.ID)
+	if len(parts) > 0 {
+		display += " (" + strings.Join(parts, " and ") + ")"
+	}
+
+	return ConversationContext{Prompt: b.String(), Display: display}, nil
+}
+
+// recentTextMessages returns the last count user and assistant text messages.
+func recentTextMessages(messages []StreamableMessage, count int) []StreamableMessage {
+	if count <= 0 {
+		return nil
+	}
+	var text []StreamableMessage
+	for _, message := range messages {
+		if message.Kind != "text" || strings.TrimSpace(message.Content) == "" {
+			continue
+		}
+		if message.Role != "user" && message.Role != "assistant" {
+			continue
+		}
+		text = append(text, message)
+	}
+	if len(text) > count {
+		text = text[len(text)-count:]
+	}
+	return text
+}
@@ -0,0 +1,61 @@
+package conversations
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderConversationContext(t *testing.T) {
+	record := convtypes.ConversationRecord{
+		ID:        "design-1",
+		Summary:   "Designed the cache eviction policy",
+		UpdatedAt: time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC),
+	}
+	messages := []StreamableMessage{
+		{Kind: "text", Role: "user", Content: "Should we use LRU?"},
+		{Kind: "tool-use", Role: "assistant", ToolName: "bash", Input: `{"command":"ls"}`},
+		{Kind: "thinking", Role: "assistant", Content: "weighing options"},
+		{Kind: "text", Role: "assistant", Content: "LFU fits the access pattern better."},
+		{Kind: "text", Role: "user", Content: "Agreed, go with LFU."},
+	}
+
+	t.Run("summary only by default", func(t *testing.T) {
+		result, err := RenderConversationContext(record, messages, ConversationContextOptions{})
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(result.Prompt, `<conversation_context id="design-1">`))
+		assert.Contains(t, result.Prompt, "(last updated 2026-10-15 09:30 UTC)")
+		assert.Contains(t, result.Prompt, "## Summary\nDesigned the cache eviction policy")
+		assert.NotContains(t, result.Prompt, "## Recent messages")
+		assert.Equal(t, "Using context from conversation design-1 (summary)", result.Display)
+	})
+
+	t.Run("recent text messages", func(t *testing.T) {
+		result, err := RenderConversationContext(record, messages, ConversationContextOptions{Messages: 2})
+		require.NoError(t, err)
+		assert.Contains(t, result.Prompt, "### Assistant\nLFU fits the access pattern better.")
+		assert.Contains(t, result.Prompt, "### User\nAgreed, go with LFU.")
+		assert.NotContains(t, result.Prompt, "Should we use LRU?")
+		assert.NotContains(t, result.Prompt, "weighing options")
+		assert.Equal(t, "Using context from conversation design-1 (summary and 2 messages)", result.Display)
+	})
+
+	t.Run("falls back to messages without a summary", func(t *testing.T) {
+		unsummarized := record
+		unsummarized.Summary = ""
+		result, err := RenderConversationContext(unsummarized, messages, ConversationContextOptions{})
+		require.NoError(t, err)
+		assert.NotContains(t, result.Prompt, "## Summary")
+		assert.Contains(t, result.Prompt, "Should we use LRU?")
+		assert.Equal(t, "Using context from conversation design-1 (3 messages)", result.Display)
+	})
+
+	t.Run("nothing to share", func(t *testing.T) {
+		_, err := RenderConversationContext(convtypes.ConversationRecord{ID: "empty"}, nil, ConversationContextOptions{Messages: 5})
+		assert.EqualError(t, err, "conversation empty has no summary or messages to share")
+	})
+}
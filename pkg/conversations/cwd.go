@@ -65,6 +65,26 @@ func CurrentWorkingDirectory() (string, error) {
 	return NormalizeCWD(workingDir)
 }
 
+// ProjectRoot returns the canonical root of the git repository containing
+// path, or path itself when it is not inside a repository.
+func ProjectRoot(ctx context.Context, path string) (string, error) {
+	dir, err := NormalizeCWD(path)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, snapshotGitTimeout)
+	defer cancel()
+	root, err := gitOutput(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return dir, nil
+	}
+	if normalized, err := NormalizeCWD(strings.TrimSpace(root)); err == nil && normalized != "" {
+		return normalized, nil
+	}
+	return dir, nil
+}
+
 // ResolveCWD determines the effective cwd for a new or existing conversation.
 // When requireExisting is false, a missing conversation is treated as a new one.
 func ResolveCWD(
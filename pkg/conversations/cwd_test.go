@@ -3,6 +3,7 @@ package conversations
 import (
 	"context"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"testing"
 
@@ -71,6 +72,37 @@ func TestCurrentWorkingDirectory(t *testing.T) {
 	assert.Equal(t, filepath.Clean(dir), cwd)
 }
 
+func TestProjectRoot(t *testing.T) {
+	t.Run("git repository", func(t *testing.T) {
+		repoDir := t.TempDir()
+		cmd := exec.Command("git", "init")
+		cmd.Dir = repoDir
+		require.NoError(t, cmd.Run())
+		subdir := filepath.Join(repoDir, "pkg", "api")
+		require.NoError(t, os.MkdirAll(subdir, 0o755))
+
+		root, err := ProjectRoot(context.Background(), subdir)
+		require.NoError(t, err)
+		expected, err := NormalizeCWD(repoDir)
+		require.NoError(t, err)
+		assert.Equal(t, expected, root)
+	})
+
+	t.Run("plain directory", func(t *testing.T) {
+		dir := t.TempDir()
+		root, err := ProjectRoot(context.Background(), dir)
+		require.NoError(t, err)
+		expected, err := NormalizeCWD(dir)
+		require.NoError(t, err)
+		assert.Equal(t, expected, root)
+	})
+
+	t.Run("missing path", func(t *testing.T) {
+		_, err := ProjectRoot(context.Background(), filepath.Join(t.TempDir(), "missing"))
+		require.Error(t, err)
+	})
+}
+
 func TestResolveCWD(t *testing.T) {
 	ctx := context.Background()
 	requested := t.TempDir()
@@ -199,4 +231,18 @@ func (s *cwdTestStore) Query(_ context.Context, options convtypes.QueryOptions)
 	return convtypes.QueryResult{QueryOptions: options}, nil
 }
 
+func (s *cwdTestStore) Fork(_ context.Context, id string) (convtypes.ConversationRecord, error) {
+	record, ok := s.records[id]
+	if !ok {
+		return convtypes.ConversationRecord{}, errors.New("conversation not found")
+	}
+	forked := record.Fork()
+	s.records[forked.ID] = forked
+	return forked, nil
+}
+
+func (s *cwdTestStore) Search(_ context.Context, _ string, options convtypes.QueryOptions) (convtypes.SearchResult, error) {
+	return convtypes.SearchResult{QueryOptions: options}, nil
+}
+
 func (s *cwdTestStore) Close() error { return nil }
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/jingkaihe/kodelet/pkg/goals"
+	"github.com/jingkaihe/kodelet/pkg/todos"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 )
 
@@ -86,6 +87,9 @@ func ApplyDisplayToStreamableMessages(messages []StreamableMessage, metadata map
 			result = append(result, message)
 			continue
 		}
+		if todos.IsReminderText(message.Content) {
+			continue
+		}
 		if goals.IsContextText(message.Content) {
 			if display, ok := consumeDisplay(displays, consumedDisplays, message.Content); ok {
 				message.Content = display.Text
@@ -116,6 +120,9 @@ func ApplyDisplayToLLMMessages(messages []llmtypes.Message, metadata map[string]
 			result = append(result, message)
 			continue
 		}
+		if todos.IsReminderText(message.Content) {
+			continue
+		}
 		if goals.IsContextText(message.Content) {
 			if display, ok := consumeDisplay(displays, consumedDisplays, message.Content); ok {
 				message.Content = display.Text
@@ -0,0 +1,186 @@
+package conversations
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations/sqlite"
+)
+
+// EncryptionKeyEnv names the environment variable holding the base64-encoded
+// conversation encryption key.
+const EncryptionKeyEnv = "KODELET_CONVERSATION_KEY"
+
+// Sources of the conversation encryption key.
+const (
+	// KeySourceEnv reads the key from EncryptionKeyEnv.
+	KeySourceEnv = "env"
+	// KeySourceKeychain reads the key from the macOS keychain or, on Linux,
+	// the Secret Service, under service KeychainService and account
+	// KeychainAccount.
+	KeySourceKeychain = "keychain"
+)
+
+// Keychain entry that holds the conversation encryption key.
+const (
+	KeychainService = "kodelet"
+	KeychainAccount = "conversation-key"
+)
+
+// keychainTimeout bounds the keychain lookup.
+const keychainTimeout = 30 * time.Second
+
+// encryption is the process-wide encryption setting of the stores returned
+// by GetConversationStore.
+var encryption struct {
+	mu  sync.Mutex
+	key []byte
+	err error
+}
+
+// EnableEncryption makes the stores returned by GetConversationStore encrypt
+// the conversations they save with the key read from keySource. When the key
+// cannot be read, those stores fail to open rather than save conversations in
+// plaintext.
+func EnableEncryption(ctx context.Context, keySource string) error {
+	key, err := ReadEncryptionKey(ctx, keySource)
+
+	encryption.mu.Lock()
+	defer encryption.mu.Unlock()
+	encryption.key, encryption.err = key, errors.Wrap(err, "conversation encryption is enabled but its key is unavailable")
+	return encryption.err
+}
+
+// DisableEncryption undoes EnableEncryption.
+func DisableEncryption() {
+	encryption.mu.Lock()
+	defer encryption.mu.Unlock()
+	encryption.key, encryption.err = nil, nil
+}
+
+// encryptionOptions returns the store options of the process-wide encryption
+// setting.
+func encryptionOptions() ([]sqlite.StoreOption, error) {
+	encryption.mu.Lock()
+	defer encryption.mu.Unlock()
+	if encryption.err != nil {
+		return nil, encryption.err
+	}
+	if encryption.key == nil {
+		return nil, nil
+	}
+	return []sqlite.StoreOption{sqlite.WithEncryptionKey(encryption.key)}, nil
+}
+
+// NewSealer returns a sealer for data kept alongside conversations under the
+// process-wide encryption setting.
+func NewSealer() (*sqlite.Sealer, error) {
+	opts, err := encryptionOptions()
+	if err != nil {
+		return nil, err
+	}
+	return sqlite.NewSealer(opts...)
+}
+
+// MigrateEncryption rewrites the conversations of the default store
+// encrypted with key, or decrypted with it when decrypt is set, and returns
+// how many were rewritten.
+func MigrateEncryption(ctx context.Context, key []byte, decrypt bool) (int, error) {
+	config, err := DefaultConfig()
+	if err != nil {
+		return 0, err
+	}
+	store, err := sqlite.NewStore(ctx, filepath.Join(config.BasePath, "storage.db"), sqlite.WithEncryptionKey(key))
+	if err != nil {
+		return 0, err
+	}
+	defer store.Close()
+
+	if decrypt {
+		return store.Decrypt(ctx)
+	}
+	return store.Encrypt(ctx)
+}
+
+// ReadEncryptionKey reads the conversation encryption key from source, which
+// defaults to KeySourceEnv.
+func ReadEncryptionKey(ctx context.Context, source string) ([]byte, error) {
+	var encoded string
+	switch strings.TrimSpace(source) {
+	case "", KeySourceEnv:
+		encoded = os.Getenv(EncryptionKeyEnv)
+		if encoded == "" {
+			return nil, errors.Errorf("%s is not set", EncryptionKeyEnv)
+		}
+	case KeySourceKeychain:
+		var err error
+		if encoded, err = readKeychain(ctx); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("unknown key source %q, expected %s or %s", source, KeySourceEnv, KeySourceKeychain)
+	}
+	return DecodeEncryptionKey(encoded)
+}
+
+// DecodeEncryptionKey decodes a base64-encoded encryption key.
+func DecodeEncryptionKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, errors.New("conversation encryption key is not valid base64")
+	}
+	if len(key) != sqlite.EncryptionKeySize {
+		return nil, errors.Errorf("conversation encryption key must be %d bytes, got %d; generate one with `openssl rand -base64 32`", sqlite.EncryptionKeySize, len(key))
+	}
+	return key, nil
+}
+
+// runKeychain runs a keychain CLI; tests replace it.
+var runKeychain = func(ctx context.Context, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, keychainTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", errors.Errorf("%s failed: %s", name, message)
+		}
+		return "", errors.Wrapf(err, "%s failed", name)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func readKeychain(ctx context.Context) (string, error) {
+	var (
+		key string
+		err error
+	)
+	switch runtime.GOOS {
+	case "darwin":
+		key, err = runKeychain(ctx, "security", "find-generic-password", "-s", KeychainService, "-a", KeychainAccount, "-w")
+	case "linux":
+		key, err = runKeychain(ctx, "secret-tool", "lookup", "service", KeychainService, "account", KeychainAccount)
+	default:
+		return "", errors.Errorf("the keychain key source is not supported on %s", runtime.GOOS)
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the conversation encryption key from the keychain")
+	}
+	if key == "" {
+		return "", errors.Errorf("the keychain has no %s entry for service %s", KeychainAccount, KeychainService)
+	}
+	return key, nil
+}
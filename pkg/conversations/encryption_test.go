@@ -0,0 +1,76 @@
+package conversations
+
+import (
+	"context"
+	"encoding/base64"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testEncodedKey = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", sqlite.EncryptionKeySize)))
+
+func TestReadEncryptionKey(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv(EncryptionKeyEnv, testEncodedKey)
+		key, err := ReadEncryptionKey(ctx, "")
+		require.NoError(t, err)
+		assert.Len(t, key, sqlite.EncryptionKeySize)
+	})
+
+	t.Run("env unset", func(t *testing.T) {
+		t.Setenv(EncryptionKeyEnv, "")
+		_, err := ReadEncryptionKey(ctx, KeySourceEnv)
+		assert.ErrorContains(t, err, "KODELET_CONVERSATION_KEY is not set")
+	})
+
+	t.Run("keychain", func(t *testing.T) {
+		if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+			t.Skip("keychain key source is not supported on " + runtime.GOOS)
+		}
+		original := runKeychain
+		t.Cleanup(func() { runKeychain = original })
+		var command string
+		runKeychain = func(_ context.Context, name string, args ...string) (string, error) {
+			command = name + " " + strings.Join(args, " ")
+			return testEncodedKey, nil
+		}
+
+		key, err := ReadEncryptionKey(ctx, KeySourceKeychain)
+		require.NoError(t, err)
+		assert.Len(t, key, sqlite.EncryptionKeySize)
+		assert.Contains(t, command, KeychainAccount)
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		_, err := DecodeEncryptionKey("not base64!")
+		assert.ErrorContains(t, err, "not valid base64")
+		_, err = DecodeEncryptionKey(base64.StdEncoding.EncodeToString([]byte("short")))
+		assert.ErrorContains(t, err, "must be 32 bytes")
+		_, err = ReadEncryptionKey(ctx, "vault")
+		assert.ErrorContains(t, err, "unknown key source")
+	})
+}
+
+func TestEnableEncryption(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	t.Cleanup(DisableEncryption)
+
+	t.Setenv(EncryptionKeyEnv, "")
+	require.Error(t, EnableEncryption(ctx, KeySourceEnv))
+	_, err := GetConversationStore(ctx)
+	assert.ErrorContains(t, err, "key is unavailable", "stores refuse to open rather than save in plaintext")
+
+	t.Setenv(EncryptionKeyEnv, testEncodedKey)
+	require.NoError(t, EnableEncryption(ctx, KeySourceEnv))
+	store, err := GetConversationStore(ctx)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+}
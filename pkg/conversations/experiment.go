@@ -0,0 +1,45 @@
+package conversations
+
+import (
+	"encoding/json"
+	"strings"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// ExperimentMetadataKey stores the experiment arm a conversation was routed to.
+const ExperimentMetadataKey = "experiment"
+
+// AddExperiment records assignment in conversation metadata. A conversation
+// keeps the arm it was first routed to.
+func AddExperiment(metadata map[string]any, assignment *llmtypes.ExperimentAssignment) map[string]any {
+	if assignment == nil {
+		return metadata
+	}
+	if metadata == nil {
+		metadata = make(map[string]any)
+	}
+	if _, ok := ExperimentFromMetadata(metadata); ok {
+		return metadata
+	}
+	metadata[ExperimentMetadataKey] = map[string]any{"name": assignment.Name, "arm": assignment.Arm}
+	return metadata
+}
+
+// ExperimentFromMetadata decodes the experiment arm stored in conversation
+// metadata. The boolean is false for conversations outside any experiment.
+func ExperimentFromMetadata(metadata map[string]any) (llmtypes.ExperimentAssignment, bool) {
+	value, ok := metadata[ExperimentMetadataKey]
+	if !ok || value == nil {
+		return llmtypes.ExperimentAssignment{}, false
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return llmtypes.ExperimentAssignment{}, false
+	}
+	var assignment llmtypes.ExperimentAssignment
+	if err := json.Unmarshal(raw, &assignment); err != nil || strings.TrimSpace(assignment.Name) == "" {
+		return llmtypes.ExperimentAssignment{}, false
+	}
+	return assignment, true
+}
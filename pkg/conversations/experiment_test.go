@@ -0,0 +1,33 @@
+package conversations
+
+import (
+	"testing"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExperimentMetadataRoundTrip(t *testing.T) {
+	metadata := AddExperiment(nil, &llmtypes.ExperimentAssignment{Name: "trial", Arm: llmtypes.ExperimentArmTreatment})
+
+	assignment, ok := ExperimentFromMetadata(metadata)
+	require.True(t, ok)
+	assert.Equal(t, "trial", assignment.Name)
+	assert.Equal(t, llmtypes.ExperimentArmTreatment, assignment.Arm)
+
+	metadata = AddExperiment(metadata, &llmtypes.ExperimentAssignment{Name: "trial", Arm: llmtypes.ExperimentArmControl})
+	assignment, ok = ExperimentFromMetadata(metadata)
+	require.True(t, ok)
+	assert.Equal(t, llmtypes.ExperimentArmTreatment, assignment.Arm, "a conversation keeps its first arm")
+}
+
+func TestExperimentFromMetadataIgnoresMissingAndInvalidValues(t *testing.T) {
+	_, ok := ExperimentFromMetadata(nil)
+	assert.False(t, ok)
+
+	_, ok = ExperimentFromMetadata(map[string]any{ExperimentMetadataKey: "not-an-object"})
+	assert.False(t, ok)
+
+	assert.Nil(t, AddExperiment(nil, nil))
+}
@@ -34,7 +34,11 @@ func NewConversationStore(ctx context.Context, config *Config) (ConversationStor
 }
 
 func newSQLiteConversationStore(ctx context.Context, dbPath string) (ConversationStore, error) {
-	store, err := sqlite.NewStore(ctx, dbPath)
+	options, err := encryptionOptions()
+	if err != nil {
+		return nil, err
+	}
+	store, err := sqlite.NewStore(ctx, dbPath, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,162 @@
+package conversations
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+const (
+	// maxArchivedMessageBytes caps each archived message.
+	maxArchivedMessageBytes = 4 * 1024
+	// maxArchivedTranscriptBytes caps the archived transcript; the oldest
+	// messages are dropped first.
+	maxArchivedTranscriptBytes = 256 * 1024
+	// searchSnippetRadius is how many bytes of context a search match keeps on
+	// either side.
+	searchSnippetRadius = 160
+)
+
+// ArchivedMessage is a message that was removed from the context by
+// compaction.
+type ArchivedMessage struct {
+	Role        string    `json:"role"`
+	Content     string    `json:"content"`
+	CompactedAt time.Time `json:"compacted_at"`
+}
+
+// CompactedTranscript decodes the archived transcript stored in conversation
+// metadata.
+func CompactedTranscript(metadata map[string]any) []ArchivedMessage {
+	raw, ok := metadata[convtypes.CompactedTranscriptMetadataKey]
+	if !ok || raw == nil {
+		return nil
+	}
+	if transcript, ok := raw.([]ArchivedMessage); ok {
+		return transcript
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var transcript []ArchivedMessage
+	_ = json.Unmarshal(data, &transcript)
+	return transcript
+}
+
+// AppendCompactedTranscript returns the archived transcript in metadata with
+// messages appended, trimmed to the archive size limits.
+func AppendCompactedTranscript(metadata map[string]any, messages []llmtypes.Message, compactedAt time.Time) []ArchivedMessage {
+	transcript := CompactedTranscript(metadata)
+	for _, message := range messages {
+		content := strings.TrimSpace(message.Content)
+		if content == "" {
+			continue
+		}
+		transcript = append(transcript, ArchivedMessage{
+			Role:        message.Role,
+			Content:     truncateUTF8(content, maxArchivedMessageBytes),
+			CompactedAt: compactedAt,
+		})
+	}
+
+	size := 0
+	for i := len(transcript) - 1; i >= 0; i-- {
+		size += len(transcript[i].Content)
+		if size > maxArchivedTranscriptBytes {
+			return transcript[i+1:]
+		}
+	}
+	return transcript
+}
+
+// SearchMatch is a message of a saved conversation that matched a history
+// search.
+type SearchMatch struct {
+	ConversationID string    `json:"conversation_id"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Role           string    `json:"role"`
+	Snippet        string    `json:"snippet"`
+	// Compacted marks matches in history that compaction removed from the
+	// conversation's context.
+	Compacted bool `json:"compacted,omitempty"`
+}
+
+// SearchTranscript returns the messages of a conversation that contain query,
+// case-insensitively, oldest first. messages are the record's current
+// messages; the archived compacted transcript is searched before them.
+func SearchTranscript(record convtypes.ConversationRecord, messages []llmtypes.Message, query string) []SearchMatch {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+
+	var matches []SearchMatch
+	add := func(role, content string, compacted bool) {
+		snippet, ok := searchSnippet(content, query)
+		if !ok {
+			return
+		}
+		matches = append(matches, SearchMatch{
+			ConversationID: record.ID,
+			UpdatedAt:      record.UpdatedAt,
+			Role:           role,
+			Snippet:        snippet,
+			Compacted:      compacted,
+		})
+	}
+	for _, message := range CompactedTranscript(record.Metadata) {
+		add(message.Role, message.Content, true)
+	}
+	for _, message := range messages {
+		add(message.Role, message.Content, false)
+	}
+	return matches
+}
+
+// searchSnippet returns the text around the first occurrence of the lowercase
+// query in content, with whitespace collapsed.
+func searchSnippet(content, query string) (string, bool) {
+	index := strings.Index(strings.ToLower(content), query)
+	if index < 0 {
+		return "", false
+	}
+	// Lowercasing can change byte lengths for some scripts; only use the
+	// index when it still lines up with the original text.
+	if index+len(query) > len(content) {
+		index = 0
+	}
+
+	start := max(index-searchSnippetRadius, 0)
+	for start > 0 && !utf8.RuneStart(content[start]) {
+		start--
+	}
+	end := min(index+len(query)+searchSnippetRadius, len(content))
+	for end < len(content) && !utf8.RuneStart(content[end]) {
+		end++
+	}
+
+	snippet := strings.Join(strings.Fields(content[start:end]), " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(content) {
+		snippet += "…"
+	}
+	return snippet, true
+}
+
+func truncateUTF8(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "…"
+}
@@ -0,0 +1,73 @@
+package conversations
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendCompactedTranscript(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	transcript := AppendCompactedTranscript(nil, []llmtypes.Message{
+		{Role: "user", Content: "  first  "},
+		{Role: "assistant", Content: " "},
+		{Role: "assistant", Content: strings.Repeat("é", maxArchivedMessageBytes)},
+	}, now)
+	require.Len(t, transcript, 2)
+	assert.Equal(t, ArchivedMessage{Role: "user", Content: "first", CompactedAt: now}, transcript[0])
+	assert.LessOrEqual(t, len(transcript[1].Content), maxArchivedMessageBytes+len("…"))
+	assert.True(t, strings.HasSuffix(transcript[1].Content, "é…"))
+
+	// The transcript survives a JSON round trip through stored metadata.
+	data, err := json.Marshal(map[string]any{convtypes.CompactedTranscriptMetadataKey: transcript})
+	require.NoError(t, err)
+	var metadata map[string]any
+	require.NoError(t, json.Unmarshal(data, &metadata))
+	assert.Equal(t, transcript, CompactedTranscript(metadata))
+
+	var many []llmtypes.Message
+	for range maxArchivedTranscriptBytes/maxArchivedMessageBytes + 10 {
+		many = append(many, llmtypes.Message{Role: "user", Content: strings.Repeat("x", maxArchivedMessageBytes)})
+	}
+	transcript = AppendCompactedTranscript(metadata, many, now)
+	assert.Len(t, transcript, maxArchivedTranscriptBytes/maxArchivedMessageBytes)
+	assert.Equal(t, strings.Repeat("x", maxArchivedMessageBytes), transcript[0].Content)
+}
+
+func TestSearchTranscript(t *testing.T) {
+	record := convtypes.ConversationRecord{
+		ID: "conv-1",
+		Metadata: map[string]any{
+			convtypes.CompactedTranscriptMetadataKey: []ArchivedMessage{
+				{Role: "user", Content: "We agreed to use Postgres for the job queue."},
+			},
+		},
+	}
+	messages := []llmtypes.Message{
+		{Role: "assistant", Content: strings.Repeat("a ", 200) + "postgres\n\nmigration" + strings.Repeat(" b", 200)},
+		{Role: "user", Content: "unrelated"},
+	}
+
+	matches := SearchTranscript(record, messages, "  POSTGRES ")
+	require.Len(t, matches, 2)
+	assert.Equal(t, SearchMatch{
+		ConversationID: "conv-1",
+		Role:           "user",
+		Snippet:        "We agreed to use Postgres for the job queue.",
+		Compacted:      true,
+	}, matches[0])
+	assert.Equal(t, "assistant", matches[1].Role)
+	assert.False(t, matches[1].Compacted)
+	assert.True(t, strings.HasPrefix(matches[1].Snippet, "…"))
+	assert.True(t, strings.HasSuffix(matches[1].Snippet, "…"))
+	assert.Contains(t, matches[1].Snippet, "postgres migration")
+
+	assert.Empty(t, SearchTranscript(record, messages, " "))
+	assert.Empty(t, SearchTranscript(record, messages, "mysql"))
+}
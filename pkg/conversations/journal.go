@@ -0,0 +1,96 @@
+package conversations
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/jingkaihe/kodelet/pkg/types/conversations"
+	"github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// ConversationJournal is implemented by stores that keep a write-ahead
+// journal of the conversations being run, so that a conversation interrupted
+// between two saves can be recovered.
+type ConversationJournal interface {
+	AppendJournal(ctx context.Context, id string, event conversations.JournalEvent) error
+	LoadJournal(ctx context.Context, id string) ([]conversations.JournalEvent, error)
+	// JournalPosition returns the position of the last event written, so
+	// that a save clears only the events written before it started.
+	JournalPosition(ctx context.Context, id string) (int64, error)
+	ClearJournal(ctx context.Context, id string, position int64) error
+}
+
+// ReplayJournal adds to record the messages and tool results of events that
+// it does not hold yet. A message event is applied only when it continues
+// the history of record, so replaying a journal whose events were already
+// saved changes nothing. Events of another provider than record's are
+// ignored.
+func ReplayJournal(record *conversations.ConversationRecord, events []conversations.JournalEvent) (conversations.JournalReplay, error) {
+	var replay conversations.JournalReplay
+	if len(events) == 0 {
+		return replay, nil
+	}
+
+	var messages []json.RawMessage
+	if len(record.RawMessages) > 0 {
+		if err := json.Unmarshal(record.RawMessages, &messages); err != nil {
+			return replay, errors.Wrap(err, "failed to split conversation messages")
+		}
+	}
+	saved := len(messages)
+
+	var text strings.Builder
+	textIndex := -1
+	for _, event := range events {
+		if record.Provider != "" && event.Provider != "" && event.Provider != record.Provider {
+			continue
+		}
+		switch event.Kind {
+		case conversations.JournalMessage:
+			if event.Index != len(messages) || len(event.Message) == 0 {
+				continue
+			}
+			messages = append(messages, event.Message)
+		case conversations.JournalAssistantText:
+			if event.Index != textIndex {
+				text.Reset()
+				textIndex = event.Index
+			}
+			text.WriteString(event.Text)
+		case conversations.JournalToolResult:
+			if event.ToolCallID == "" {
+				continue
+			}
+			if replay.ToolOutputs == nil {
+				replay.ToolOutputs = make(map[string]conversations.JournalToolOutput)
+			}
+			replay.ToolOutputs[event.ToolCallID] = conversations.JournalToolOutput{Text: event.Text, IsError: event.IsError}
+			if event.ToolResult == nil {
+				continue
+			}
+			if record.ToolResults == nil {
+				record.ToolResults = make(map[string]tools.StructuredToolResult)
+			}
+			if _, ok := record.ToolResults[event.ToolCallID]; !ok {
+				record.ToolResults[event.ToolCallID] = *event.ToolResult
+			}
+		}
+	}
+
+	// Streamed text only stands for a message that was never finished
+	if textIndex == len(messages) && strings.TrimSpace(text.String()) != "" {
+		replay.AssistantText = text.String()
+	}
+	replay.Messages = len(messages) - saved
+	if replay.Messages > 0 {
+		rawMessages, err := json.Marshal(messages)
+		if err != nil {
+			return replay, errors.Wrap(err, "failed to marshal recovered messages")
+		}
+		record.RawMessages = rawMessages
+	}
+	return replay, nil
+}
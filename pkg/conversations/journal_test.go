@@ -0,0 +1,78 @@
+package conversations
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jingkaihe/kodelet/pkg/types/conversations"
+	"github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+func journalMessage(index int, text string) conversations.JournalEvent {
+	message, _ := json.Marshal(map[string]string{"text": text})
+	return conversations.JournalEvent{Kind: conversations.JournalMessage, Provider: "anthropic", Index: index, Message: message}
+}
+
+func TestReplayJournal(t *testing.T) {
+	record := conversations.ConversationRecord{
+		ID:          "conv-1",
+		Provider:    "anthropic",
+		RawMessages: json.RawMessage(`[{"text":"saved 0"},{"text":"saved 1"}]`),
+	}
+	events := []conversations.JournalEvent{
+		// Written before the last save, which cleared nothing yet
+		journalMessage(1, "saved 1"),
+		journalMessage(2, "user"),
+		{Kind: conversations.JournalAssistantText, Provider: "anthropic", Index: 3, Text: "Let me "},
+		journalMessage(3, "assistant with tool call"),
+		{
+			Kind:       conversations.JournalToolResult,
+			Provider:   "anthropic",
+			ToolCallID: "call-1",
+			Text:       "done",
+			ToolResult: &tools.StructuredToolResult{ToolName: "bash", Success: true},
+		},
+		{Kind: conversations.JournalAssistantText, Provider: "anthropic", Index: 4, Text: "Still "},
+		{Kind: conversations.JournalAssistantText, Provider: "anthropic", Index: 4, Text: "streaming"},
+		// A gap in the history is never applied
+		journalMessage(9, "out of order"),
+		// Neither are events of another provider
+		{Kind: conversations.JournalMessage, Provider: "openai", Index: 4, Message: json.RawMessage(`{"role":"user"}`)},
+	}
+
+	replay, err := ReplayJournal(&record, events)
+	require.NoError(t, err)
+	assert.True(t, replay.Recovered())
+	assert.Equal(t, 2, replay.Messages)
+	assert.Equal(t, "Still streaming", replay.AssistantText)
+	assert.Equal(t, map[string]conversations.JournalToolOutput{"call-1": {Text: "done"}}, replay.ToolOutputs)
+	assert.JSONEq(t, `[{"text":"saved 0"},{"text":"saved 1"},{"text":"user"},{"text":"assistant with tool call"}]`, string(record.RawMessages))
+	assert.Equal(t, "bash", record.ToolResults["call-1"].ToolName)
+
+	// Replaying the same journal again adds nothing
+	replay, err = ReplayJournal(&record, events)
+	require.NoError(t, err)
+	assert.Equal(t, 0, replay.Messages)
+}
+
+func TestReplayJournalDropsFinishedText(t *testing.T) {
+	record := conversations.ConversationRecord{ID: "conv-1", Provider: "anthropic"}
+	events := []conversations.JournalEvent{
+		journalMessage(0, "user"),
+		{Kind: conversations.JournalAssistantText, Provider: "anthropic", Index: 1, Text: "Hello"},
+		journalMessage(1, "Hello there"),
+	}
+
+	replay, err := ReplayJournal(&record, events)
+	require.NoError(t, err)
+	assert.Equal(t, 2, replay.Messages)
+	assert.Empty(t, replay.AssistantText)
+	assert.JSONEq(t, `[{"text":"user"},{"text":"Hello there"}]`, string(record.RawMessages))
+
+	replay, err = ReplayJournal(&record, nil)
+	require.NoError(t, err)
+	assert.False(t, replay.Recovered())
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/jingkaihe/kodelet/pkg/tools/renderers"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
 )
 
@@ -71,7 +72,11 @@ func RenderMarkdown(
 		case "text":
 			output.WriteString(renderTextBlockMarkdown(msg))
 		case "thinking":
-			output.WriteString("<details>\n<summary>Thinking</summary>\n\n")
+			summary := "Thinking"
+			if msg.Reasoning != nil && msg.Reasoning.Format == llmtypes.ReasoningFormatSummary {
+				summary = "Thinking (summary)"
+			}
+			fmt.Fprintf(&output, "<details>\n<summary>%s</summary>\n\n", summary)
 			output.WriteString(markdownCodeFence("text", msg.Content))
 			output.WriteString("\n\n</details>")
 		case "tool-result":
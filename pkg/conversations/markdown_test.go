@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -24,6 +25,17 @@ func TestRenderMarkdownIncludesThinkingByDefault(t *testing.T) {
 	assert.Contains(t, markdown, "Here is the summary.")
 }
 
+func TestRenderMarkdownLabelsReasoningSummaries(t *testing.T) {
+	messages := []StreamableMessage{
+		{Kind: "thinking", Role: "assistant", Content: "Plan the change", Reasoning: &llmtypes.Reasoning{Format: llmtypes.ReasoningFormatSummary}},
+	}
+
+	markdown := RenderMarkdown(messages, nil, MarkdownOptions{})
+
+	assert.Contains(t, markdown, "Thinking (summary)")
+	assert.Contains(t, markdown, "Plan the change")
+}
+
 func TestRenderMarkdownCanExcludeThinking(t *testing.T) {
 	messages := []StreamableMessage{
 		{Kind: "text", Role: "user", Content: "Summarize this"},
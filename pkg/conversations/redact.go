@@ -0,0 +1,33 @@
+package conversations
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// RedactedToolResultPlaceholder is the tool output left in place of a
+// redacted result, so the tool call keeps its paired result.
+func RedactedToolResultPlaceholder(toolName string) string {
+	return fmt.Sprintf("[redacted: %s output removed]", toolName)
+}
+
+// ToolRedactionSet matches tool names whose results are redacted.
+type ToolRedactionSet []string
+
+// NewToolRedactionSet normalizes the requested tool names.
+func NewToolRedactionSet(toolNames []string) ToolRedactionSet {
+	set := make(ToolRedactionSet, 0, len(toolNames))
+	for _, name := range toolNames {
+		name = strings.TrimSpace(name)
+		if name != "" && !slices.Contains(set, name) {
+			set = append(set, name)
+		}
+	}
+	return set
+}
+
+// Contains reports whether toolName's results are redacted.
+func (s ToolRedactionSet) Contains(toolName string) bool {
+	return slices.Contains(s, toolName)
+}
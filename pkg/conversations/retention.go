@@ -0,0 +1,154 @@
+package conversations
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/types/conversations"
+)
+
+// AutoPruneInterval is how often the background retention pass runs at most.
+const AutoPruneInterval = 24 * time.Hour
+
+// autoPruneMarker records the time of the last background retention pass
+// next to the conversation database.
+const autoPruneMarker = ".last-prune"
+
+// ConversationPruner is implemented by stores that can enforce a retention
+// policy.
+type ConversationPruner interface {
+	Prune(ctx context.Context, options conversations.PruneOptions) (conversations.PruneResult, error)
+	// Vacuum returns the space freed by pruning to the file system.
+	Vacuum(ctx context.Context) error
+}
+
+// RetentionPolicy limits how many conversations are kept. Zero values are
+// not enforced.
+type RetentionPolicy struct {
+	MaxAge   time.Duration
+	MaxCount int
+	MaxBytes int64
+}
+
+// Enabled reports whether the policy enforces any limit.
+func (p RetentionPolicy) Enabled() bool {
+	return p.MaxAge > 0 || p.MaxCount > 0 || p.MaxBytes > 0
+}
+
+// PruneOptions returns the prune options that enforce the policy at now.
+func (p RetentionPolicy) PruneOptions(now time.Time) conversations.PruneOptions {
+	options := conversations.PruneOptions{MaxCount: p.MaxCount, MaxBytes: p.MaxBytes}
+	if p.MaxAge > 0 {
+		options.Before = now.Add(-p.MaxAge)
+	}
+	return options
+}
+
+// PruneConversations applies options to the default conversation store.
+func PruneConversations(ctx context.Context, options conversations.PruneOptions, vacuum bool) (conversations.PruneResult, error) {
+	store, err := GetConversationStore(ctx)
+	if err != nil {
+		return conversations.PruneResult{}, err
+	}
+	defer store.Close()
+
+	pruner, ok := store.(ConversationPruner)
+	if !ok {
+		return conversations.PruneResult{}, errors.New("the conversation store does not support pruning")
+	}
+	result, err := pruner.Prune(ctx, options)
+	if err != nil {
+		return conversations.PruneResult{}, err
+	}
+	if vacuum && !options.DryRun && len(result.Conversations) > 0 {
+		if err := pruner.Vacuum(ctx); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// AutoPrune enforces policy on the default conversation store at most once
+// per AutoPruneInterval. It leaves the database file at its size; the freed
+// pages are reused by later conversations.
+func AutoPrune(ctx context.Context, policy RetentionPolicy) {
+	if !policy.Enabled() {
+		return
+	}
+	basePath, err := conversations.GetDefaultBasePath()
+	if err != nil {
+		return
+	}
+	marker := filepath.Join(basePath, autoPruneMarker)
+	if info, err := os.Stat(marker); err == nil && time.Since(info.ModTime()) < AutoPruneInterval {
+		return
+	}
+
+	result, err := PruneConversations(ctx, policy.PruneOptions(time.Now()), false)
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to prune conversations")
+		return
+	}
+	if err := os.WriteFile(marker, []byte(time.Now().UTC().Format(time.RFC3339)+"\n"), 0o644); err != nil {
+		logger.G(ctx).WithError(err).Debug("failed to record the conversation prune time")
+	}
+	if len(result.Conversations) > 0 {
+		logger.G(ctx).
+			WithField("conversations", len(result.Conversations)).
+			WithField("bytes", result.Bytes).
+			Info("pruned conversations outside the retention policy")
+	}
+}
+
+// ParseRetentionAge parses an age such as 30d, 2w or 12h. Days and weeks are
+// accepted on top of time.ParseDuration units.
+func ParseRetentionAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if number, found := strings.CutSuffix(value, suffix); found {
+			amount, err := strconv.ParseFloat(number, 64)
+			if err != nil || amount < 0 {
+				return 0, errors.Errorf("invalid age %q (expected a value such as 30d, 2w or 12h)", value)
+			}
+			return time.Duration(amount * float64(unit)), nil
+		}
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, errors.Errorf("invalid age %q (expected a value such as 30d, 2w or 12h)", value)
+	}
+	return age, nil
+}
+
+// ParseByteSize parses a size such as 500MB or 2GB. Units are powers of
+// 1024, and a plain number is a count of bytes.
+func ParseByteSize(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	split := strings.IndexFunc(value, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' })
+	number, unit := value, ""
+	if split >= 0 {
+		number, unit = value[:split], strings.ToUpper(strings.TrimSpace(value[split:]))
+	}
+	exponents := map[string]float64{"": 0, "B": 0, "K": 1, "KB": 1, "KIB": 1, "M": 2, "MB": 2, "MIB": 2, "G": 3, "GB": 3, "GIB": 3, "T": 4, "TB": 4, "TIB": 4}
+	exponent, ok := exponents[unit]
+	amount, err := strconv.ParseFloat(number, 64)
+	if !ok || err != nil || amount < 0 {
+		return 0, errors.Errorf("invalid size %q (expected a value such as 500MB or 2GB)", value)
+	}
+	return int64(amount * math.Pow(1024, exponent)), nil
+}
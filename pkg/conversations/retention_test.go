@@ -0,0 +1,74 @@
+package conversations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetentionAge(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"30d", 30 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"1.5d", 36 * time.Hour},
+		{"12h", 12 * time.Hour},
+		{" 90m ", 90 * time.Minute},
+	}
+	for _, tt := range tests {
+		age, err := ParseRetentionAge(tt.value)
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.expected, age, tt.value)
+	}
+
+	for _, value := range []string{"d", "30x", "-1d", "-2h", "thirty days"} {
+		_, err := ParseRetentionAge(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int64
+	}{
+		{"", 0},
+		{"512", 512},
+		{"512B", 512},
+		{"1K", 1024},
+		{"500MB", 500 << 20},
+		{"1.5 GiB", 3 << 29},
+		{"2gb", 2 << 30},
+		{"1TB", 1 << 40},
+	}
+	for _, tt := range tests {
+		size, err := ParseByteSize(tt.value)
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.expected, size, tt.value)
+	}
+
+	for _, value := range []string{"MB", "10XB", "-5MB", "1.2.3G"} {
+		_, err := ParseByteSize(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestRetentionPolicy(t *testing.T) {
+	assert.False(t, RetentionPolicy{}.Enabled())
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	policy := RetentionPolicy{MaxAge: 48 * time.Hour, MaxCount: 10, MaxBytes: 1 << 20}
+	assert.True(t, policy.Enabled())
+
+	options := policy.PruneOptions(now)
+	assert.Equal(t, now.Add(-48*time.Hour), options.Before)
+	assert.Equal(t, 10, options.MaxCount)
+	assert.Equal(t, int64(1<<20), options.MaxBytes)
+
+	assert.True(t, RetentionPolicy{MaxCount: 5}.PruneOptions(now).Before.IsZero())
+}
@@ -6,7 +6,6 @@ package conversations
 import (
 	"context"
 	"encoding/json"
-	"maps"
 	"time"
 
 	"github.com/pkg/errors"
@@ -240,29 +239,9 @@ func (s *ConversationService) GetToolResult(ctx context.Context, conversationID,
 func (s *ConversationService) ForkConversation(ctx context.Context, id string) (*GetConversationResponse, error) {
 	logger.G(ctx).WithField("id", id).Debug("Forking conversation")
 
-	sourceRecord, err := s.store.Load(ctx, id)
+	forkedRecord, err := s.store.Fork(ctx, id)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to load conversation")
-	}
-
-	forkedRecord := conversations.NewConversationRecord("")
-	forkedRecord.RawMessages = sourceRecord.RawMessages
-	forkedRecord.CWD = sourceRecord.CWD
-	forkedRecord.Provider = sourceRecord.Provider
-	forkedRecord.Summary = sourceRecord.Summary
-	forkedRecord.Usage.CurrentContextWindow = sourceRecord.Usage.CurrentContextWindow
-	forkedRecord.Usage.MaxContextWindow = sourceRecord.Usage.MaxContextWindow
-
-	if sourceRecord.ToolResults != nil {
-		forkedRecord.ToolResults = maps.Clone(sourceRecord.ToolResults)
-	}
-
-	if sourceRecord.Metadata != nil {
-		forkedRecord.Metadata = maps.Clone(sourceRecord.Metadata)
-	}
-
-	if err := s.store.Save(ctx, forkedRecord); err != nil {
-		return nil, errors.Wrap(err, "failed to save forked conversation")
+		return nil, errors.Wrap(err, "failed to fork conversation")
 	}
 
 	logger.G(ctx).WithFields(map[string]any{
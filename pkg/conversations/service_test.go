@@ -3,6 +3,7 @@ package conversations
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -76,6 +77,10 @@ func (m *mockConversationStore) Query(ctx context.Context, options conversations
 	}, nil
 }
 
+func (m *mockConversationStore) Search(_ context.Context, _ string, options conversations.QueryOptions) (conversations.SearchResult, error) {
+	return conversations.SearchResult{QueryOptions: options}, nil
+}
+
 func (m *mockConversationStore) Delete(ctx context.Context, id string) error {
 	if m.deleteFunc != nil {
 		return m.deleteFunc(ctx, id)
@@ -84,6 +89,18 @@ func (m *mockConversationStore) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (m *mockConversationStore) Fork(ctx context.Context, id string) (conversations.ConversationRecord, error) {
+	source, err := m.Load(ctx, id)
+	if err != nil {
+		return conversations.ConversationRecord{}, fmt.Errorf("failed to load conversation: %w", err)
+	}
+	forked := source.Fork()
+	if err := m.Save(ctx, forked); err != nil {
+		return conversations.ConversationRecord{}, fmt.Errorf("failed to save forked conversation: %w", err)
+	}
+	return forked, nil
+}
+
 func (m *mockConversationStore) Close() error {
 	if m.closeFunc != nil {
 		return m.closeFunc()
@@ -0,0 +1,251 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"maps"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/jingkaihe/kodelet/pkg/db/migrations"
+	"github.com/jingkaihe/kodelet/pkg/types/conversations"
+	"github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// consolidateAfter is the number of appends a conversation collects before
+// its next save writes the record in full again.
+const consolidateAfter = 20
+
+// savedConversation is what this store last wrote for a conversation. It
+// lets the next save append only the messages and tool results that are new,
+// instead of rewriting the whole record.
+type savedConversation struct {
+	updatedAt    time.Time
+	summary      string
+	metadata     [sha256.Size]byte
+	messageCount int
+	messages     [sha256.Size]byte
+	toolResults  map[string][sha256.Size]byte
+	appends      int
+}
+
+// conversationAppend is what a save adds to the conversation saved before it
+type conversationAppend struct {
+	messages        []json.RawMessage
+	toolResults     map[string]tools.StructuredToolResult
+	metadataChanged bool
+}
+
+// digestConversation records the digests of what saving record writes. It
+// also returns the messages of record, split out of its raw JSON array.
+func digestConversation(record conversations.ConversationRecord) (*savedConversation, []json.RawMessage, error) {
+	var messages []json.RawMessage
+	if err := json.Unmarshal(record.RawMessages, &messages); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to split conversation messages")
+	}
+	metadata, err := json.Marshal(record.Metadata)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to marshal conversation metadata")
+	}
+
+	saved := &savedConversation{
+		updatedAt:    record.UpdatedAt,
+		summary:      record.Summary,
+		metadata:     sha256.Sum256(metadata),
+		messageCount: len(messages),
+		messages:     digestMessages(messages),
+		toolResults:  make(map[string][sha256.Size]byte, len(record.ToolResults)),
+	}
+	for id, result := range record.ToolResults {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to marshal tool result %s", id)
+		}
+		saved.toolResults[id] = sha256.Sum256(data)
+	}
+	return saved, messages, nil
+}
+
+func digestMessages(messages []json.RawMessage) [sha256.Size]byte {
+	hash := sha256.New()
+	for _, message := range messages {
+		hash.Write(message)
+		hash.Write([]byte{0})
+	}
+	var digest [sha256.Size]byte
+	copy(digest[:], hash.Sum(nil))
+	return digest
+}
+
+// appendTo returns what next adds to the conversation previously saved as
+// s. It returns false when next changes what was saved, for example after
+// a compaction or a new summary, and has to be written in full.
+func (s *savedConversation) appendTo(next *savedConversation, messages []json.RawMessage, record conversations.ConversationRecord) (conversationAppend, bool) {
+	if next.summary != s.summary || next.messageCount < s.messageCount || digestMessages(messages[:s.messageCount]) != s.messages {
+		return conversationAppend{}, false
+	}
+
+	appended := conversationAppend{
+		messages:        messages[s.messageCount:],
+		toolResults:     make(map[string]tools.StructuredToolResult),
+		metadataChanged: next.metadata != s.metadata,
+	}
+	for id := range s.toolResults {
+		if _, ok := next.toolResults[id]; !ok {
+			return conversationAppend{}, false
+		}
+	}
+	for id, digest := range next.toolResults {
+		if previous, ok := s.toolResults[id]; !ok || previous != digest {
+			appended.toolResults[id] = record.ToolResults[id]
+		}
+	}
+	return appended, true
+}
+
+// saveAppend writes the messages and tool results appended to a conversation
+// as one conversation_appends row, and updates the rest of its record in
+// place. It returns false without writing anything when another store saved
+// the conversation since previous.
+func (s *Store) saveAppend(ctx context.Context, record conversations.ConversationRecord, previous *savedConversation, appended conversationAppend) (bool, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	dbRecord := fromConversationRecord(record)
+	if dbRecord.Metadata.Data, err = s.sealMetadata(record.ID, dbRecord.Metadata.Data); err != nil {
+		return false, err
+	}
+	query := `UPDATE conversations SET cwd = ?, provider = ?, usage = ?, updated_at = ?`
+	args := []any{dbRecord.CWD, dbRecord.Provider, dbRecord.Usage, dbRecord.UpdatedAt}
+	if appended.metadataChanged {
+		// Setting metadata re-indexes the conversation for search, so it is
+		// only written when it changed.
+		query += `, metadata = ?`
+		args = append(args, dbRecord.Metadata)
+	}
+	query += ` WHERE id = ? AND updated_at = ?`
+	args = append(args, record.ID, previous.updatedAt)
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to update conversation record")
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+		return false, nil
+	}
+
+	if len(appended.messages) > 0 || len(appended.toolResults) > 0 {
+		if err := s.insertAppend(ctx, tx, record, appended); err != nil {
+			return false, err
+		}
+	}
+	if len(appended.messages) > 0 || appended.metadataChanged {
+		if err := indexAppends(ctx, tx, record.ID); err != nil {
+			return false, err
+		}
+	}
+
+	if err := saveSummary(ctx, tx, record); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, errors.Wrap(err, "failed to commit conversation append")
+	}
+	return true, nil
+}
+
+func (s *Store) insertAppend(ctx context.Context, tx *sqlx.Tx, record conversations.ConversationRecord, appended conversationAppend) error {
+	messages, err := json.Marshal(appended.messages)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal appended messages")
+	}
+	toolResults, err := json.Marshal(appended.toolResults)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal appended tool results")
+	}
+	if messages, err = s.seal(record.ID, messages); err != nil {
+		return err
+	}
+	if toolResults, err = s.seal(record.ID, toolResults); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO conversation_appends (conversation_id, raw_messages, tool_results, created_at)
+		VALUES (?, ?, ?, ?)`, record.ID, messages, toolResults, record.UpdatedAt)
+	return errors.Wrap(err, "failed to append to conversation")
+}
+
+// indexAppends re-indexes the conversation id for search together with the
+// messages appended to it. Encrypted messages are not indexed.
+func indexAppends(ctx context.Context, tx *sqlx.Tx, id string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM conversation_search
+		WHERE rowid = (SELECT rowid FROM conversations WHERE id = ?)`, id); err != nil {
+		return errors.Wrap(err, "failed to index appended messages")
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO conversation_search (rowid, id, summary, content)
+		SELECT c.rowid, c.id, COALESCE(c.summary, ''), `+migrations.ConversationSearchContent("c")+` || ' ' || COALESCE((
+			SELECT group_concat(content, ' ') FROM (
+				SELECT `+migrations.MessageSearchContent("a.raw_messages")+` AS content
+				FROM conversation_appends a WHERE a.conversation_id = c.id ORDER BY a.seq
+			)
+		), '')
+		FROM conversations c WHERE c.id = ?`, id)
+	return errors.Wrap(err, "failed to index appended messages")
+}
+
+// applyAppends adds the messages and tool results appended to record since
+// it was last written in full.
+func (s *Store) applyAppends(ctx context.Context, tx *sqlx.Tx, record *conversations.ConversationRecord) error {
+	var appends []dbConversationAppend
+	err := tx.SelectContext(ctx, &appends, `SELECT raw_messages, tool_results
+		FROM conversation_appends WHERE conversation_id = ? ORDER BY seq`, record.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to load conversation appends")
+	}
+	if len(appends) == 0 {
+		return nil
+	}
+
+	var messages []json.RawMessage
+	if err := json.Unmarshal(record.RawMessages, &messages); err != nil {
+		return errors.Wrap(err, "failed to split conversation messages")
+	}
+	if record.ToolResults == nil {
+		record.ToolResults = make(map[string]tools.StructuredToolResult)
+	}
+	for _, appended := range appends {
+		rawMessages, err := s.open(record.ID, appended.RawMessages)
+		if err != nil {
+			return err
+		}
+		var appendedMessages []json.RawMessage
+		if err := json.Unmarshal(rawMessages, &appendedMessages); err != nil {
+			return errors.Wrap(err, "failed to unmarshal appended messages")
+		}
+		messages = append(messages, appendedMessages...)
+
+		toolResults, err := s.open(record.ID, appended.ToolResults)
+		if err != nil {
+			return err
+		}
+		var appendedResults map[string]tools.StructuredToolResult
+		if err := json.Unmarshal(toolResults, &appendedResults); err != nil {
+			return errors.Wrap(err, "failed to unmarshal appended tool results")
+		}
+		maps.Copy(record.ToolResults, appendedResults)
+	}
+
+	rawMessages, err := json.Marshal(messages)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal conversation messages")
+	}
+	record.RawMessages = rawMessages
+	return nil
+}
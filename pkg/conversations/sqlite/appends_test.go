@@ -0,0 +1,181 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	conversations "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	"github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAppendsTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "appends.db")
+	setupTestDB(t, dbPath)
+	store, err := NewStore(context.Background(), dbPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store, dbPath
+}
+
+func appendsTestRecord(messages int, toolResults ...string) conversations.ConversationRecord {
+	raw := make([]string, messages)
+	for i := range raw {
+		raw[i] = fmt.Sprintf(`{"role":"user","content":[{"type":"text","text":"message %d"}]}`, i+1)
+	}
+	record := conversations.ConversationRecord{
+		ID:          "conv-appends",
+		RawMessages: json.RawMessage("[" + strings.Join(raw, ",") + "]"),
+		Provider:    "anthropic",
+		Summary:     "Appending conversation",
+		Metadata:    map[string]any{"model": "claude-sonnet-4-6"},
+		CreatedAt:   time.Now(),
+		ToolResults: map[string]tools.StructuredToolResult{},
+	}
+	for _, id := range toolResults {
+		record.ToolResults[id] = tools.StructuredToolResult{ToolName: "bash", Success: true}
+	}
+	return record
+}
+
+func countAppends(t *testing.T, store *Store) int {
+	t.Helper()
+	var count int
+	require.NoError(t, store.db.Get(&count, "SELECT COUNT(*) FROM conversation_appends WHERE conversation_id = ?", "conv-appends"))
+	return count
+}
+
+func storedMessageCount(t *testing.T, store *Store) int {
+	t.Helper()
+	var count int
+	require.NoError(t, store.db.Get(&count, "SELECT json_array_length(raw_messages) FROM conversations WHERE id = ?", "conv-appends"))
+	return count
+}
+
+func loadedMessageCount(t *testing.T, store *Store) int {
+	t.Helper()
+	loaded, err := store.Load(context.Background(), "conv-appends")
+	require.NoError(t, err)
+	var messages []json.RawMessage
+	require.NoError(t, json.Unmarshal(loaded.RawMessages, &messages))
+	return len(messages)
+}
+
+func TestStore_SaveAppendsNewMessages(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newAppendsTestStore(t)
+
+	require.NoError(t, store.Save(ctx, appendsTestRecord(1, "call-1")))
+	require.NoError(t, store.Save(ctx, appendsTestRecord(3, "call-1", "call-2")))
+	require.NoError(t, store.Save(ctx, appendsTestRecord(3, "call-1", "call-2")))
+
+	assert.Equal(t, 1, countAppends(t, store), "a save that adds nothing appends no row")
+	assert.Equal(t, 1, storedMessageCount(t, store), "the record itself is not rewritten")
+
+	loaded, err := store.Load(ctx, "conv-appends")
+	require.NoError(t, err)
+	assert.JSONEq(t, string(appendsTestRecord(3).RawMessages), string(loaded.RawMessages))
+	assert.Len(t, loaded.ToolResults, 2)
+
+	result, err := store.Query(ctx, conversations.QueryOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.ConversationSummaries, 1)
+	assert.Equal(t, 3, result.ConversationSummaries[0].MessageCount)
+
+	found, err := store.Query(ctx, conversations.QueryOptions{ContentSearch: "message 3"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, found.Total, "content search covers appended messages")
+
+	hits, err := store.Search(ctx, `"message 3"`, conversations.QueryOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, hits.Total, "full-text search covers appended messages")
+
+	record := appendsTestRecord(4, "call-1", "call-2")
+	record.Metadata["model"] = "claude-opus-4-6"
+	require.NoError(t, store.Save(ctx, record))
+	assert.Equal(t, 2, countAppends(t, store))
+	for _, query := range []string{`"message 3"`, `"message 4"`} {
+		hits, err := store.Search(ctx, query, conversations.QueryOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, hits.Total, "appended messages stay indexed when the metadata changes: %s", query)
+	}
+}
+
+func TestStore_SaveRewritesChangedConversations(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("rewritten history", func(t *testing.T) {
+		store, _ := newAppendsTestStore(t)
+		require.NoError(t, store.Save(ctx, appendsTestRecord(2)))
+		require.NoError(t, store.Save(ctx, appendsTestRecord(4)))
+		require.Equal(t, 1, countAppends(t, store))
+
+		compacted := appendsTestRecord(1)
+		compacted.RawMessages = json.RawMessage(`[{"role":"user","content":[{"type":"text","text":"summary of the conversation"}]}]`)
+		require.NoError(t, store.Save(ctx, compacted))
+
+		assert.Equal(t, 0, countAppends(t, store))
+		assert.Equal(t, 1, loadedMessageCount(t, store))
+	})
+
+	t.Run("new summary", func(t *testing.T) {
+		store, _ := newAppendsTestStore(t)
+		require.NoError(t, store.Save(ctx, appendsTestRecord(1)))
+		record := appendsTestRecord(2)
+		record.Summary = "Generated title"
+		require.NoError(t, store.Save(ctx, record))
+
+		assert.Equal(t, 0, countAppends(t, store))
+		assert.Equal(t, 2, storedMessageCount(t, store))
+	})
+
+	t.Run("saved by another store", func(t *testing.T) {
+		store, dbPath := newAppendsTestStore(t)
+		other, err := NewStore(ctx, dbPath)
+		require.NoError(t, err)
+		defer other.Close()
+
+		require.NoError(t, store.Save(ctx, appendsTestRecord(1)))
+		require.NoError(t, other.Save(ctx, appendsTestRecord(2)))
+		require.NoError(t, store.Save(ctx, appendsTestRecord(3)))
+
+		assert.Equal(t, 0, countAppends(t, store))
+		assert.Equal(t, 3, storedMessageCount(t, store))
+	})
+
+	t.Run("consolidated periodically", func(t *testing.T) {
+		store, _ := newAppendsTestStore(t)
+		for messages := 1; messages <= consolidateAfter+1; messages++ {
+			require.NoError(t, store.Save(ctx, appendsTestRecord(messages)))
+		}
+		assert.Equal(t, consolidateAfter, countAppends(t, store))
+
+		require.NoError(t, store.Save(ctx, appendsTestRecord(consolidateAfter+2)))
+		assert.Equal(t, 0, countAppends(t, store))
+		assert.Equal(t, consolidateAfter+2, storedMessageCount(t, store))
+		assert.Equal(t, consolidateAfter+2, loadedMessageCount(t, store))
+	})
+}
+
+func TestStore_DeleteRemovesAppends(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newAppendsTestStore(t)
+
+	require.NoError(t, store.Save(ctx, appendsTestRecord(1)))
+	require.NoError(t, store.Save(ctx, appendsTestRecord(2)))
+	require.Equal(t, 1, countAppends(t, store))
+
+	require.NoError(t, store.Delete(ctx, "conv-appends"))
+	assert.Equal(t, 0, countAppends(t, store))
+
+	require.NoError(t, store.Save(ctx, appendsTestRecord(3)))
+	assert.Equal(t, 0, countAppends(t, store), "a deleted conversation is written in full when saved again")
+	assert.Equal(t, 3, loadedMessageCount(t, store))
+}
@@ -0,0 +1,292 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"maps"
+
+	"github.com/pkg/errors"
+
+	"github.com/jingkaihe/kodelet/pkg/types/conversations"
+)
+
+// EncryptionKeySize is the size of the AES-256 key conversations are
+// encrypted with.
+const EncryptionKeySize = 32
+
+// sealedPrefix marks a column value encrypted by the store. Values without it
+// are plaintext, so a database can hold both while it is being migrated.
+var sealedPrefix = []byte("kodelet:aes-256-gcm:v1:")
+
+// StoreOption configures a Store.
+type StoreOption func(*Store) error
+
+// WithEncryptionKey encrypts the messages, tool results and compacted
+// transcript of the conversations the store saves with AES-256-GCM under key.
+// Encrypted values are decrypted transparently on load.
+func WithEncryptionKey(key []byte) StoreOption {
+	return func(s *Store) error {
+		if len(key) != EncryptionKeySize {
+			return errors.Errorf("conversation encryption key must be %d bytes, got %d", EncryptionKeySize, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return errors.Wrap(err, "failed to create conversation cipher")
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return errors.Wrap(err, "failed to create conversation cipher")
+		}
+		s.aead = aead
+		s.encrypt = true
+		return nil
+	}
+}
+
+// Sealer encrypts values kept alongside conversations, such as the session
+// updates of ACP clients, the way a store opened with the same options
+// encrypts conversations.
+type Sealer struct {
+	store *Store
+}
+
+// NewSealer returns a sealer configured by opts.
+func NewSealer(opts ...StoreOption) (*Sealer, error) {
+	store := &Store{}
+	for _, opt := range opts {
+		if err := opt(store); err != nil {
+			return nil, err
+		}
+	}
+	return &Sealer{store: store}, nil
+}
+
+// Seal encrypts value for the conversation id when the sealer has a key,
+// and returns it unchanged otherwise.
+func (s *Sealer) Seal(id string, value []byte) ([]byte, error) {
+	return s.store.seal(id, value)
+}
+
+// Open decrypts a value sealed for the conversation id. Plaintext values are
+// returned unchanged.
+func (s *Sealer) Open(id string, value []byte) ([]byte, error) {
+	return s.store.open(id, value)
+}
+
+// isSealed reports whether value was encrypted by a store.
+func isSealed(value []byte) bool {
+	return bytes.HasPrefix(value, sealedPrefix)
+}
+
+// seal encrypts value for the conversation id when the store encrypts
+// conversations, and returns it unchanged otherwise. The conversation ID is
+// bound to the ciphertext, so it cannot be moved to another conversation.
+func (s *Store) seal(id string, value []byte) ([]byte, error) {
+	if !s.encrypt || value == nil {
+		return value, nil
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+	sealed := s.aead.Seal(nonce, nonce, value, []byte(id))
+
+	encoded := make([]byte, len(sealedPrefix)+base64.StdEncoding.EncodedLen(len(sealed)))
+	copy(encoded, sealedPrefix)
+	base64.StdEncoding.Encode(encoded[len(sealedPrefix):], sealed)
+	return encoded, nil
+}
+
+// open decrypts a value sealed for the conversation id. Plaintext values are
+// returned unchanged.
+func (s *Store) open(id string, value []byte) ([]byte, error) {
+	if !isSealed(value) {
+		return value, nil
+	}
+	if s.aead == nil {
+		return nil, errors.Errorf("conversation %s is encrypted; configure conversation_encryption to read it", id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(string(value[len(sealedPrefix):]))
+	if err != nil || len(sealed) < s.aead.NonceSize() {
+		return nil, errors.Errorf("conversation %s has a malformed encrypted value", id)
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return nil, errors.Errorf("failed to decrypt conversation %s: wrong encryption key or corrupted data", id)
+	}
+	return plaintext, nil
+}
+
+// sealMetadata returns metadata with its compacted transcript, the messages
+// compaction removed from the conversation, encrypted.
+func (s *Store) sealMetadata(id string, metadata map[string]any) (map[string]any, error) {
+	transcript, ok := metadata[conversations.CompactedTranscriptMetadataKey]
+	if !s.encrypt || !ok || transcript == nil {
+		return metadata, nil
+	}
+	data, err := json.Marshal(transcript)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal compacted transcript")
+	}
+	sealed, err := s.seal(id, data)
+	if err != nil {
+		return nil, err
+	}
+	metadata = maps.Clone(metadata)
+	metadata[conversations.CompactedTranscriptMetadataKey] = string(sealed)
+	return metadata, nil
+}
+
+// openMetadata decrypts the compacted transcript of metadata in place.
+func (s *Store) openMetadata(id string, metadata map[string]any) error {
+	sealed, ok := metadata[conversations.CompactedTranscriptMetadataKey].(string)
+	if !ok || !isSealed([]byte(sealed)) {
+		return nil
+	}
+	data, err := s.open(id, []byte(sealed))
+	if err != nil {
+		return err
+	}
+	var transcript any
+	if err := json.Unmarshal(data, &transcript); err != nil {
+		return errors.Wrap(err, "failed to unmarshal compacted transcript")
+	}
+	metadata[conversations.CompactedTranscriptMetadataKey] = transcript
+	return nil
+}
+
+// sealRecord returns the columns of dbRecord that are encrypted at rest:
+// the raw messages and tool results, with the metadata of dbRecord sealed in
+// place.
+func (s *Store) sealRecord(dbRecord *dbConversationRecord) (rawMessages, toolResults []byte, err error) {
+	if rawMessages, err = s.seal(dbRecord.ID, dbRecord.RawMessages); err != nil {
+		return nil, nil, err
+	}
+	results, err := dbRecord.ToolResults.Value()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to marshal tool results")
+	}
+	if toolResults, err = s.seal(dbRecord.ID, results.([]byte)); err != nil {
+		return nil, nil, err
+	}
+	if dbRecord.Metadata.Data, err = s.sealMetadata(dbRecord.ID, dbRecord.Metadata.Data); err != nil {
+		return nil, nil, err
+	}
+	return rawMessages, toolResults, nil
+}
+
+// openRecord decrypts a record read with its sealed columns.
+func (s *Store) openRecord(stored *dbStoredConversationRecord) (conversations.ConversationRecord, error) {
+	dbRecord := stored.dbConversationRecord
+	rawMessages, err := s.open(dbRecord.ID, stored.SealedRawMessages)
+	if err != nil {
+		return conversations.ConversationRecord{}, err
+	}
+	dbRecord.RawMessages = rawMessages
+
+	toolResults, err := s.open(dbRecord.ID, stored.SealedToolResults)
+	if err != nil {
+		return conversations.ConversationRecord{}, err
+	}
+	if toolResults != nil {
+		if err := dbRecord.ToolResults.Scan(toolResults); err != nil {
+			return conversations.ConversationRecord{}, errors.Wrap(err, "failed to unmarshal tool results")
+		}
+	}
+	if err := s.openMetadata(dbRecord.ID, dbRecord.Metadata.Data); err != nil {
+		return conversations.ConversationRecord{}, err
+	}
+	return dbRecord.ToConversationRecord(), nil
+}
+
+// Encrypt rewrites every stored conversation encrypted under the key of the
+// store, and returns how many were rewritten. Conversations that are already
+// encrypted are decrypted and encrypted again.
+func (s *Store) Encrypt(ctx context.Context) (int, error) {
+	if s.aead == nil {
+		return 0, errors.New("no conversation encryption key is configured")
+	}
+	return s.rewriteAll(ctx, true)
+}
+
+// Decrypt rewrites every stored conversation in plaintext, and returns how
+// many were rewritten. Encrypted conversations need the key of the store.
+func (s *Store) Decrypt(ctx context.Context) (int, error) {
+	return s.rewriteAll(ctx, false)
+}
+
+func (s *Store) rewriteAll(ctx context.Context, encrypt bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.encrypt
+	s.encrypt = encrypt
+	defer func() { s.encrypt = previous }()
+
+	var ids []string
+	if err := s.db.SelectContext(ctx, &ids, "SELECT id FROM conversations ORDER BY id"); err != nil {
+		return 0, errors.Wrap(err, "failed to list conversations")
+	}
+	for i, id := range ids {
+		record, err := s.Load(ctx, id)
+		if err != nil {
+			return i, err
+		}
+		if err := s.saveRecord(ctx, record); err != nil {
+			return i, errors.Wrapf(err, "failed to rewrite conversation %s", id)
+		}
+		delete(s.saved, id)
+	}
+	if err := s.resealJournals(ctx); err != nil {
+		return len(ids), err
+	}
+	if err := s.resealSessionUpdates(ctx); err != nil {
+		return len(ids), err
+	}
+
+	// Drop the plaintext that deleted index entries and pages still hold
+	if _, err := s.db.ExecContext(ctx, "INSERT INTO conversation_search (conversation_search) VALUES ('optimize')"); err != nil {
+		return len(ids), errors.Wrap(err, "failed to optimize the conversation search index")
+	}
+	if err := s.Vacuum(ctx); err != nil {
+		return len(ids), err
+	}
+	if _, err := s.db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return len(ids), errors.Wrap(err, "failed to checkpoint the conversation database")
+	}
+	return len(ids), nil
+}
+
+// resealSessionUpdates rewrites the stored updates of ACP sessions, whose IDs
+// are those of their conversations, under the current encryption setting.
+func (s *Store) resealSessionUpdates(ctx context.Context) error {
+	var rows []dbSessionUpdate
+	if err := s.db.SelectContext(ctx, &rows, "SELECT id, session_id, update_data FROM acp_session_updates ORDER BY id"); err != nil {
+		return errors.Wrap(err, "failed to list ACP session updates")
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+	for _, row := range rows {
+		data, err := s.open(row.SessionID, row.UpdateData)
+		if err != nil {
+			return err
+		}
+		if data, err = s.seal(row.SessionID, data); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE acp_session_updates SET update_data = ? WHERE id = ?", string(data), row.ID); err != nil {
+			return errors.Wrapf(err, "failed to rewrite the updates of ACP session %s", row.SessionID)
+		}
+	}
+	return errors.Wrap(tx.Commit(), "failed to commit ACP session updates")
+}
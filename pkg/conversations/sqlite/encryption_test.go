@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	conversations "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	"github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testEncryptionKey = bytes.Repeat([]byte{7}, EncryptionKeySize)
+
+func newEncryptionTestStore(t *testing.T, dbPath string, options ...StoreOption) *Store {
+	t.Helper()
+	store, err := NewStore(context.Background(), dbPath, options...)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func encryptionTestRecord(messages int) conversations.ConversationRecord {
+	record := appendsTestRecord(messages, "call-1")
+	record.ToolResults["call-1"] = tools.StructuredToolResult{ToolName: "bash", Success: true, Error: "AWS_SECRET=hunter2"}
+	record.Metadata[conversations.CompactedTranscriptMetadataKey] = []any{map[string]any{"role": "user", "content": "token ghp_secret"}}
+	return record
+}
+
+// storedColumns returns every stored value that may hold conversation content.
+func storedColumns(t *testing.T, store *Store) string {
+	t.Helper()
+	var values []string
+	require.NoError(t, store.db.Select(&values, `
+		SELECT CAST(raw_messages AS TEXT) || CAST(tool_results AS TEXT) || metadata FROM conversations
+		UNION ALL SELECT CAST(raw_messages AS TEXT) || CAST(tool_results AS TEXT) FROM conversation_appends
+		UNION ALL SELECT content FROM conversation_search
+		UNION ALL SELECT update_data FROM acp_session_updates`))
+	var joined bytes.Buffer
+	for _, value := range values {
+		joined.WriteString(value)
+	}
+	return joined.String()
+}
+
+func TestStore_EncryptsConversationsAtRest(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "encrypted.db")
+	setupTestDB(t, dbPath)
+	store := newEncryptionTestStore(t, dbPath, WithEncryptionKey(testEncryptionKey))
+
+	require.NoError(t, store.Save(ctx, encryptionTestRecord(1)))
+	require.NoError(t, store.Save(ctx, encryptionTestRecord(2)))
+	require.Equal(t, 1, countAppends(t, store))
+
+	stored := storedColumns(t, store)
+	for _, secret := range []string{"message 1", "message 2", "hunter2", "ghp_secret"} {
+		assert.NotContains(t, stored, secret)
+	}
+
+	loaded, err := store.Load(ctx, "conv-appends")
+	require.NoError(t, err)
+	assert.JSONEq(t, string(encryptionTestRecord(2).RawMessages), string(loaded.RawMessages))
+	assert.Equal(t, "AWS_SECRET=hunter2", loaded.ToolResults["call-1"].Error)
+	assert.Equal(t, encryptionTestRecord(2).Metadata[conversations.CompactedTranscriptMetadataKey], loaded.Metadata[conversations.CompactedTranscriptMetadataKey])
+
+	result, err := store.Query(ctx, conversations.QueryOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.ConversationSummaries, 1)
+	assert.Equal(t, "message 1", result.ConversationSummaries[0].FirstMessage, "summaries stay readable for listing")
+
+	t.Run("without the key", func(t *testing.T) {
+		_, err := newEncryptionTestStore(t, dbPath).Load(ctx, "conv-appends")
+		assert.ErrorContains(t, err, "is encrypted")
+	})
+
+	t.Run("with another key", func(t *testing.T) {
+		other := newEncryptionTestStore(t, dbPath, WithEncryptionKey(bytes.Repeat([]byte{8}, EncryptionKeySize)))
+		_, err := other.Load(ctx, "conv-appends")
+		assert.ErrorContains(t, err, "wrong encryption key")
+	})
+}
+
+func TestStore_EncryptAndDecryptExistingConversations(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "migrate.db")
+	setupTestDB(t, dbPath)
+
+	plain := newEncryptionTestStore(t, dbPath)
+	require.NoError(t, plain.Save(ctx, encryptionTestRecord(1)))
+	require.NoError(t, plain.Save(ctx, encryptionTestRecord(2)))
+	_, err := plain.db.Exec(`INSERT INTO acp_session_updates (session_id, update_data) VALUES (?, ?)`,
+		"conv-appends", `{"sessionId":"conv-appends","update":{"text":"ACP_TOKEN=swordfish"}}`)
+	require.NoError(t, err)
+	require.Contains(t, storedColumns(t, plain), "hunter2")
+
+	keyed := newEncryptionTestStore(t, dbPath, WithEncryptionKey(testEncryptionKey))
+	loaded, err := keyed.Load(ctx, "conv-appends")
+	require.NoError(t, err, "plaintext conversations stay readable with a key")
+	assert.JSONEq(t, string(encryptionTestRecord(2).RawMessages), string(loaded.RawMessages))
+
+	count, err := keyed.Encrypt(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.NotContains(t, storedColumns(t, keyed), "hunter2")
+	assert.NotContains(t, storedColumns(t, keyed), "swordfish", "ACP session updates are encrypted too")
+	assert.Equal(t, 0, countAppends(t, keyed))
+
+	sealer, err := NewSealer(WithEncryptionKey(testEncryptionKey))
+	require.NoError(t, err)
+	var update []byte
+	require.NoError(t, keyed.db.Get(&update, "SELECT update_data FROM acp_session_updates"))
+	update, err = sealer.Open("conv-appends", update)
+	require.NoError(t, err)
+	assert.Contains(t, string(update), "swordfish")
+
+	count, err = keyed.Decrypt(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Contains(t, storedColumns(t, keyed), "hunter2")
+	assert.Contains(t, storedColumns(t, keyed), "swordfish")
+
+	loaded, err = plain.Load(ctx, "conv-appends")
+	require.NoError(t, err)
+	assert.JSONEq(t, string(encryptionTestRecord(2).RawMessages), string(loaded.RawMessages))
+
+	_, err = plain.Encrypt(ctx)
+	assert.ErrorContains(t, err, "no conversation encryption key")
+}
+
+func TestWithEncryptionKeyRejectsShortKeys(t *testing.T) {
+	_, err := NewStore(context.Background(), filepath.Join(t.TempDir(), "short.db"), WithEncryptionKey([]byte("short")))
+	assert.ErrorContains(t, err, "must be 32 bytes")
+}
@@ -0,0 +1,107 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/jingkaihe/kodelet/pkg/types/conversations"
+)
+
+// AppendJournal adds event to the write-ahead journal of the conversation
+// id. Each event is committed on its own, so it survives the process being
+// killed right after.
+func (s *Store) AppendJournal(ctx context.Context, id string, event conversations.JournalEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal journal event")
+	}
+	if data, err = s.seal(id, data); err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO conversation_journal (conversation_id, event, created_at)
+		VALUES (?, ?, ?)`, id, data, event.CreatedAt)
+	return errors.Wrap(err, "failed to append to conversation journal")
+}
+
+// LoadJournal returns the journal of the conversation id in the order its
+// events were written.
+func (s *Store) LoadJournal(ctx context.Context, id string) ([]conversations.JournalEvent, error) {
+	var rows []dbJournalEvent
+	err := s.db.SelectContext(ctx, &rows, `SELECT seq, event
+		FROM conversation_journal WHERE conversation_id = ? ORDER BY seq`, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load conversation journal")
+	}
+
+	events := make([]conversations.JournalEvent, 0, len(rows))
+	for _, row := range rows {
+		data, err := s.open(id, row.Event)
+		if err != nil {
+			return nil, err
+		}
+		var event conversations.JournalEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse journal event %d", row.Seq)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// JournalPosition returns the position of the last event in the journal of
+// the conversation id, or zero when the journal is empty.
+func (s *Store) JournalPosition(ctx context.Context, id string) (int64, error) {
+	var position int64
+	err := s.db.GetContext(ctx, &position, `SELECT COALESCE(MAX(seq), 0)
+		FROM conversation_journal WHERE conversation_id = ?`, id)
+	return position, errors.Wrap(err, "failed to read conversation journal position")
+}
+
+// ClearJournal removes the events of the journal of the conversation id up
+// to and including position, once what they record has been saved. Events
+// written after position are kept.
+func (s *Store) ClearJournal(ctx context.Context, id string, position int64) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM conversation_journal WHERE conversation_id = ? AND seq <= ?", id, position)
+	return errors.Wrap(err, "failed to clear conversation journal")
+}
+
+// resealJournals rewrites the journal events of every conversation under
+// the current encryption setting of the store.
+func (s *Store) resealJournals(ctx context.Context) error {
+	var rows []dbJournalEvent
+	if err := s.db.SelectContext(ctx, &rows, "SELECT seq, conversation_id, event FROM conversation_journal ORDER BY seq"); err != nil {
+		return errors.Wrap(err, "failed to list conversation journals")
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+	for _, row := range rows {
+		if err := s.resealJournalEvent(ctx, tx, row); err != nil {
+			return err
+		}
+	}
+	return errors.Wrap(tx.Commit(), "failed to commit conversation journals")
+}
+
+func (s *Store) resealJournalEvent(ctx context.Context, tx *sqlx.Tx, row dbJournalEvent) error {
+	data, err := s.open(row.ConversationID, row.Event)
+	if err != nil {
+		return err
+	}
+	if data, err = s.seal(row.ConversationID, data); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE conversation_journal SET event = ? WHERE seq = ?", data, row.Seq)
+	return errors.Wrapf(err, "failed to rewrite the journal of conversation %s", row.ConversationID)
+}
@@ -0,0 +1,109 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	conversations "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	"github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func journalTestEvents() []conversations.JournalEvent {
+	return []conversations.JournalEvent{
+		{Kind: conversations.JournalMessage, Provider: "anthropic", Index: 0, Message: json.RawMessage(`{"role":"user","content":"deploy it"}`)},
+		{Kind: conversations.JournalAssistantText, Provider: "anthropic", Index: 1, Text: "Deploying"},
+		{
+			Kind:       conversations.JournalToolResult,
+			Provider:   "anthropic",
+			ToolCallID: "call-1",
+			Text:       "AWS_SECRET=hunter2",
+			ToolResult: &tools.StructuredToolResult{ToolName: "bash", Success: true},
+		},
+	}
+}
+
+func TestStore_Journal(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newAppendsTestStore(t)
+
+	for _, event := range journalTestEvents() {
+		require.NoError(t, store.AppendJournal(ctx, "conv-journal", event))
+	}
+	require.NoError(t, store.AppendJournal(ctx, "conv-other", journalTestEvents()[0]))
+
+	events, err := store.LoadJournal(ctx, "conv-journal")
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, conversations.JournalMessage, events[0].Kind)
+	assert.JSONEq(t, `{"role":"user","content":"deploy it"}`, string(events[0].Message))
+	assert.Equal(t, "Deploying", events[1].Text)
+	assert.Equal(t, "call-1", events[2].ToolCallID)
+	require.NotNil(t, events[2].ToolResult)
+	assert.Equal(t, "bash", events[2].ToolResult.ToolName)
+	assert.False(t, events[0].CreatedAt.IsZero())
+
+	position, err := store.JournalPosition(ctx, "conv-journal")
+	require.NoError(t, err)
+	require.NoError(t, store.AppendJournal(ctx, "conv-journal", journalTestEvents()[1]))
+	require.NoError(t, store.ClearJournal(ctx, "conv-journal", position))
+	events, err = store.LoadJournal(ctx, "conv-journal")
+	require.NoError(t, err)
+	require.Len(t, events, 1, "events written after position are kept")
+	assert.Equal(t, "Deploying", events[0].Text)
+
+	position, err = store.JournalPosition(ctx, "conv-empty")
+	require.NoError(t, err)
+	assert.Zero(t, position)
+
+	events, err = store.LoadJournal(ctx, "conv-other")
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestStore_DeleteRemovesJournal(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newAppendsTestStore(t)
+
+	record := appendsTestRecord(1)
+	require.NoError(t, store.Save(ctx, record))
+	require.NoError(t, store.AppendJournal(ctx, record.ID, journalTestEvents()[0]))
+
+	require.NoError(t, store.Delete(ctx, record.ID))
+	events, err := store.LoadJournal(ctx, record.ID)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestStore_EncryptsJournal(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "encrypted.db")
+	setupTestDB(t, dbPath)
+	store := newEncryptionTestStore(t, dbPath, WithEncryptionKey(testEncryptionKey))
+
+	for _, event := range journalTestEvents() {
+		require.NoError(t, store.AppendJournal(ctx, "conv-journal", event))
+	}
+	var stored []string
+	require.NoError(t, store.db.Select(&stored, "SELECT event FROM conversation_journal"))
+	for _, event := range stored {
+		assert.NotContains(t, event, "hunter2")
+		assert.NotContains(t, event, "deploy it")
+	}
+
+	events, err := store.LoadJournal(ctx, "conv-journal")
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, "AWS_SECRET=hunter2", events[2].Text)
+
+	// Decrypting the database rewrites the journal in plaintext
+	_, err = store.Decrypt(ctx)
+	require.NoError(t, err)
+	plaintext := newEncryptionTestStore(t, dbPath)
+	events, err = plaintext.LoadJournal(ctx, "conv-journal")
+	require.NoError(t, err)
+	assert.Len(t, events, 3)
+}
@@ -68,6 +68,12 @@ type dbConversationSummary struct {
 	UpdatedAt    time.Time                 `db:"updated_at"`
 }
 
+// dbSearchHit represents a conversation_summaries row matched by a full-text search
+type dbSearchHit struct {
+	dbConversationSummary
+	Snippet string `db:"snippet"`
+}
+
 // ToConversationRecord converts database record to domain model
 func (dbr *dbConversationRecord) ToConversationRecord() conversations.ConversationRecord {
 	record := conversations.ConversationRecord{
@@ -159,3 +165,35 @@ func fromConversationSummary(summary conversations.ConversationSummary) *dbConve
 
 	return dbSummary
 }
+
+// dbStoredConversationRecord is a conversations row as stored, with the
+// columns that may be encrypted at rest read as they are
+type dbStoredConversationRecord struct {
+	dbConversationRecord
+	SealedRawMessages []byte `db:"sealed_raw_messages"`
+	SealedToolResults []byte `db:"sealed_tool_results"`
+}
+
+// dbConversationAppend represents a conversation_appends row: the messages
+// and tool results one incremental save added to a conversation, which may
+// be encrypted at rest
+type dbConversationAppend struct {
+	RawMessages []byte `db:"raw_messages"`
+	ToolResults []byte `db:"tool_results"`
+}
+
+// dbSessionUpdate represents an acp_session_updates row: one update of an ACP
+// session, which may be encrypted at rest
+type dbSessionUpdate struct {
+	ID         int64  `db:"id"`
+	SessionID  string `db:"session_id"`
+	UpdateData []byte `db:"update_data"`
+}
+
+// dbJournalEvent represents a conversation_journal row: one event of the
+// write-ahead journal of a conversation, which may be encrypted at rest
+type dbJournalEvent struct {
+	Seq            int64  `db:"seq"`
+	ConversationID string `db:"conversation_id"`
+	Event          []byte `db:"event"`
+}
@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"github.com/jingkaihe/kodelet/pkg/types/conversations"
+)
+
+// pruneBatchSize keeps the IDs of one delete statement well below SQLite's
+// bound parameter limit.
+const pruneBatchSize = 500
+
+// activeRunWindow mirrors the heartbeat staleness of the steer package: a run
+// that has not heartbeated for this long was left behind by a dead process.
+const activeRunWindow = time.Minute
+
+// pruneCandidate is a conversation considered by a retention pass
+type pruneCandidate struct {
+	ID        string    `db:"id"`
+	Summary   *string   `db:"summary"`
+	UpdatedAt time.Time `db:"updated_at"`
+	Bytes     int64     `db:"bytes"`
+	Active    bool      `db:"active"`
+}
+
+// Prune removes the conversations that fall outside the retention limits of
+// options, oldest first. Conversations with an active run are never removed,
+// but still count towards MaxCount and MaxBytes.
+func (s *Store) Prune(ctx context.Context, options conversations.PruneOptions) (conversations.PruneResult, error) {
+	result := conversations.PruneResult{Conversations: []conversations.PrunedConversation{}, DryRun: options.DryRun}
+	if options.Before.IsZero() && options.MaxCount <= 0 && options.MaxBytes <= 0 {
+		return result, nil
+	}
+
+	var candidates []pruneCandidate
+	query := `SELECT c.id, s.summary, c.updated_at,
+		LENGTH(CAST(c.raw_messages AS BLOB)) +
+			COALESCE(LENGTH(CAST(c.tool_results AS BLOB)), 0) +
+			COALESCE(LENGTH(CAST(c.metadata AS BLOB)), 0) +
+			COALESCE((
+				SELECT SUM(LENGTH(CAST(a.raw_messages AS BLOB)) + LENGTH(CAST(a.tool_results AS BLOB)))
+				FROM conversation_appends a WHERE a.conversation_id = c.id
+			), 0) AS bytes,
+		EXISTS (
+			SELECT 1 FROM conversation_runs r
+			WHERE r.conversation_id = c.id AND r.heartbeat_at > ?
+		) AS active
+		FROM conversations c
+		LEFT JOIN conversation_summaries s ON s.id = c.id
+		ORDER BY c.updated_at DESC, c.id`
+	if err := s.db.SelectContext(ctx, &candidates, query, time.Now().Add(-activeRunWindow)); err != nil {
+		return result, errors.Wrap(err, "failed to list conversations for pruning")
+	}
+
+	var keptBytes int64
+	var ids []string
+	for i, candidate := range candidates {
+		keptBytes += candidate.Bytes
+		expired := (!options.Before.IsZero() && candidate.UpdatedAt.Before(options.Before)) ||
+			(options.MaxCount > 0 && i >= options.MaxCount) ||
+			(options.MaxBytes > 0 && keptBytes > options.MaxBytes)
+		if !expired || candidate.Active {
+			continue
+		}
+		keptBytes -= candidate.Bytes
+
+		pruned := conversations.PrunedConversation{ID: candidate.ID, UpdatedAt: candidate.UpdatedAt, Bytes: candidate.Bytes}
+		if candidate.Summary != nil {
+			pruned.Summary = *candidate.Summary
+		}
+		result.Conversations = append(result.Conversations, pruned)
+		result.Bytes += candidate.Bytes
+		ids = append(ids, candidate.ID)
+	}
+
+	if options.DryRun || len(ids) == 0 {
+		return result, nil
+	}
+	if err := s.deleteConversations(ctx, ids); err != nil {
+		return conversations.PruneResult{}, err
+	}
+	return result, nil
+}
+
+// deleteConversations removes conversations together with their appends,
+// journals, queued steering messages and ACP session updates.
+func (s *Store) deleteConversations(ctx context.Context, ids []string) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		"DELETE FROM conversations WHERE id IN (?)",
+		"DELETE FROM conversation_summaries WHERE id IN (?)",
+		"DELETE FROM conversation_appends WHERE conversation_id IN (?)",
+		"DELETE FROM conversation_journal WHERE conversation_id IN (?)",
+		"DELETE FROM steering_messages WHERE conversation_id IN (?)",
+		"DELETE FROM acp_session_updates WHERE session_id IN (?)",
+	}
+	for batch := range slices.Chunk(ids, pruneBatchSize) {
+		for _, statement := range statements {
+			query, args, err := sqlx.In(statement, batch)
+			if err != nil {
+				return errors.Wrap(err, "failed to build prune query")
+			}
+			if _, err := tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+				return errors.Wrap(err, "failed to prune conversations")
+			}
+		}
+	}
+	s.forget(ids...)
+	return tx.Commit()
+}
+
+// Vacuum rebuilds the database file so the space of deleted conversations is
+// returned to the file system.
+func (s *Store) Vacuum(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "VACUUM")
+	return errors.Wrap(err, "failed to vacuum the conversation database")
+}
@@ -0,0 +1,131 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	conversations "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_Prune(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	newStore := func(t *testing.T) *Store {
+		t.Helper()
+		dbPath := filepath.Join(t.TempDir(), "test_conversations.db")
+		setupTestDB(t, dbPath)
+		store, err := NewStore(ctx, dbPath)
+		require.NoError(t, err)
+		t.Cleanup(func() { store.Close() })
+
+		// conv-0 is the newest and conv-3 the oldest, each a day older.
+		for i := range 4 {
+			id := fmt.Sprintf("conv-%d", i)
+			require.NoError(t, store.Save(ctx, conversations.ConversationRecord{
+				ID:          id,
+				RawMessages: json.RawMessage(`[{"role":"user","content":"hello"}]`),
+				Provider:    "anthropic",
+				Summary:     "Summary " + id,
+				CreatedAt:   now,
+			}))
+			_, err := store.db.ExecContext(ctx, "UPDATE conversations SET updated_at = ? WHERE id = ?", now.Add(-time.Duration(i)*24*time.Hour), id)
+			require.NoError(t, err)
+		}
+		return store
+	}
+
+	remaining := func(t *testing.T, store *Store) []string {
+		t.Helper()
+		var ids []string
+		require.NoError(t, store.db.SelectContext(ctx, &ids, "SELECT id FROM conversations ORDER BY id"))
+		return ids
+	}
+
+	prunedIDs := func(result conversations.PruneResult) []string {
+		ids := []string{}
+		for _, conversation := range result.Conversations {
+			ids = append(ids, conversation.ID)
+		}
+		return ids
+	}
+
+	t.Run("no limits", func(t *testing.T) {
+		store := newStore(t)
+		result, err := store.Prune(ctx, conversations.PruneOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, result.Conversations)
+		assert.Len(t, remaining(t, store), 4)
+	})
+
+	t.Run("dry run keeps everything", func(t *testing.T) {
+		store := newStore(t)
+		result, err := store.Prune(ctx, conversations.PruneOptions{Before: now.Add(-36 * time.Hour), DryRun: true})
+		require.NoError(t, err)
+		assert.True(t, result.DryRun)
+		assert.Equal(t, []string{"conv-2", "conv-3"}, prunedIDs(result))
+		assert.Equal(t, "Summary conv-2", result.Conversations[0].Summary)
+		assert.Positive(t, result.Bytes)
+		assert.Len(t, remaining(t, store), 4)
+	})
+
+	t.Run("max age", func(t *testing.T) {
+		store := newStore(t)
+		_, err := store.db.ExecContext(ctx, "INSERT INTO steering_messages (conversation_id, content, created_at) VALUES (?, ?, ?)", "conv-3", "steer", now)
+		require.NoError(t, err)
+
+		result, err := store.Prune(ctx, conversations.PruneOptions{Before: now.Add(-36 * time.Hour)})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"conv-2", "conv-3"}, prunedIDs(result))
+		assert.Equal(t, []string{"conv-0", "conv-1"}, remaining(t, store))
+
+		var summaries, steering int
+		require.NoError(t, store.db.GetContext(ctx, &summaries, "SELECT COUNT(*) FROM conversation_summaries"))
+		require.NoError(t, store.db.GetContext(ctx, &steering, "SELECT COUNT(*) FROM steering_messages"))
+		assert.Equal(t, 2, summaries)
+		assert.Zero(t, steering)
+	})
+
+	t.Run("max count", func(t *testing.T) {
+		store := newStore(t)
+		result, err := store.Prune(ctx, conversations.PruneOptions{MaxCount: 1})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"conv-1", "conv-2", "conv-3"}, prunedIDs(result))
+		assert.Equal(t, []string{"conv-0"}, remaining(t, store))
+	})
+
+	t.Run("max bytes", func(t *testing.T) {
+		store := newStore(t)
+		preview, err := store.Prune(ctx, conversations.PruneOptions{MaxCount: 3, DryRun: true})
+		require.NoError(t, err)
+		require.Len(t, preview.Conversations, 1)
+		perConversation := preview.Conversations[0].Bytes
+
+		result, err := store.Prune(ctx, conversations.PruneOptions{MaxBytes: 2*perConversation + 1})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"conv-2", "conv-3"}, prunedIDs(result))
+		assert.Equal(t, 2*perConversation, result.Bytes)
+		require.NoError(t, store.Vacuum(ctx))
+	})
+
+	t.Run("active runs are kept", func(t *testing.T) {
+		store := newStore(t)
+		_, err := store.db.ExecContext(ctx,
+			"INSERT INTO conversation_runs (conversation_id, owner, hostname, pid, started_at, heartbeat_at) VALUES (?, ?, ?, ?, ?, ?), (?, ?, ?, ?, ?, ?)",
+			"conv-3", "live", "host", 1, now, time.Now(),
+			"conv-2", "dead", "host", 2, now, now.Add(-time.Hour),
+		)
+		require.NoError(t, err)
+
+		result, err := store.Prune(ctx, conversations.PruneOptions{MaxCount: 1})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"conv-1", "conv-2"}, prunedIDs(result))
+		assert.Equal(t, []string{"conv-0", "conv-3"}, remaining(t, store))
+	})
+}
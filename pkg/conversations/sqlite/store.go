@@ -5,13 +5,19 @@ package sqlite
 
 import (
 	"context"
+	"crypto/cipher"
+	"database/sql"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 
 	"github.com/jingkaihe/kodelet/pkg/db"
+	"github.com/jingkaihe/kodelet/pkg/steer"
 	"github.com/jingkaihe/kodelet/pkg/types/conversations"
 )
 
@@ -19,36 +25,97 @@ import (
 type Store struct {
 	dbPath string
 	db     *sqlx.DB
+
+	// aead encrypts conversations at rest when the store has a key, and
+	// encrypt says whether saves use it.
+	aead    cipher.AEAD
+	encrypt bool
+
+	mu    sync.Mutex
+	saved map[string]*savedConversation
 }
 
 // NewStore creates a new SQLite-based conversation store.
 // Note: Migrations should be run via db.RunMigrations() at CLI startup before calling this.
-func NewStore(ctx context.Context, dbPath string) (*Store, error) {
+func NewStore(ctx context.Context, dbPath string, options ...StoreOption) (*Store, error) {
+	store := &Store{
+		dbPath: dbPath,
+		saved:  make(map[string]*savedConversation),
+	}
+	for _, option := range options {
+		if err := option(store); err != nil {
+			return nil, err
+		}
+	}
+
 	sqlDB, err := db.Open(ctx, dbPath)
 	if err != nil {
 		return nil, err
 	}
-
-	return &Store{
-		dbPath: dbPath,
-		db:     sqlDB,
-	}, nil
+	store.db = sqlDB
+	return store, nil
 }
 
-// Save persists a conversation record to the database using UPSERT to preserve created_at timestamps
+// Save persists a conversation record to the database using UPSERT to preserve created_at timestamps.
+// When this store saved the conversation before and record only adds to it,
+// the new messages and tool results are appended instead of rewriting the
+// record; every consolidateAfter appends it is written in full again.
 func (s *Store) Save(ctx context.Context, record conversations.ConversationRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Ensure UpdatedAt is set to current time for saves
+	record.UpdatedAt = time.Now()
+
+	saved, messages, err := digestConversation(record)
+	if err != nil {
+		// Records whose messages are not a JSON array are always written in full
+		delete(s.saved, record.ID)
+		return s.saveRecord(ctx, record)
+	}
+
+	if previous, ok := s.saved[record.ID]; ok && previous.appends < consolidateAfter {
+		if appended, ok := previous.appendTo(saved, messages, record); ok {
+			written, err := s.saveAppend(ctx, record, previous, appended)
+			if err != nil {
+				delete(s.saved, record.ID)
+				return err
+			}
+			if written {
+				saved.appends = previous.appends
+				if len(appended.messages) > 0 || len(appended.toolResults) > 0 {
+					saved.appends++
+				}
+				s.saved[record.ID] = saved
+				return nil
+			}
+		}
+	}
+
+	if err := s.saveRecord(ctx, record); err != nil {
+		delete(s.saved, record.ID)
+		return err
+	}
+	s.saved[record.ID] = saved
+	return nil
+}
+
+// saveRecord writes record in full, folding in and removing the appends of
+// the conversation.
+func (s *Store) saveRecord(ctx context.Context, record conversations.ConversationRecord) error {
 	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return errors.Wrap(err, "failed to begin transaction")
 	}
 	defer tx.Rollback()
 
-	// Ensure UpdatedAt is set to current time for saves
-	record.UpdatedAt = time.Now()
-
-	// Convert to database models
+	// Convert to database models, encrypting what is stored encrypted
 	dbRecord := fromConversationRecord(record)
-	dbSummary := fromConversationSummary(record.ToSummary())
+	stored := dbStoredConversationRecord{dbConversationRecord: *dbRecord}
+	stored.SealedRawMessages, stored.SealedToolResults, err = s.sealRecord(&stored.dbConversationRecord)
+	if err != nil {
+		return err
+	}
 
 	// Insert or update conversation record with UPSERT to preserve created_at
 	conversationQuery := `
@@ -56,8 +123,8 @@ func (s *Store) Save(ctx context.Context, record conversations.ConversationRecor
 			id, cwd, raw_messages, provider, usage,
 			summary, created_at, updated_at, metadata, tool_results
 		) VALUES (
-			:id, :cwd, :raw_messages, :provider, :usage,
-			:summary, :created_at, :updated_at, :metadata, :tool_results
+			:id, :cwd, :sealed_raw_messages, :provider, :usage,
+			:summary, :created_at, :updated_at, :metadata, :sealed_tool_results
 		)
 		ON CONFLICT(id) DO UPDATE SET
 			cwd = excluded.cwd,
@@ -69,12 +136,24 @@ func (s *Store) Save(ctx context.Context, record conversations.ConversationRecor
 			metadata = excluded.metadata,
 			tool_results = excluded.tool_results
 	`
-	_, err = tx.NamedExecContext(ctx, conversationQuery, dbRecord)
+	_, err = tx.NamedExecContext(ctx, conversationQuery, stored)
 	if err != nil {
 		return errors.Wrap(err, "failed to save conversation record")
 	}
 
-	// Insert or update conversation summary with UPSERT to preserve created_at
+	_, err = tx.ExecContext(ctx, "DELETE FROM conversation_appends WHERE conversation_id = ?", record.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to consolidate conversation appends")
+	}
+
+	if err := saveSummary(ctx, tx, record); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// saveSummary inserts or updates the summary of record with UPSERT to preserve created_at
+func saveSummary(ctx context.Context, tx *sqlx.Tx, record conversations.ConversationRecord) error {
 	summaryQuery := `
 		INSERT INTO conversation_summaries (
 			id, cwd, message_count, first_message, summary, provider, metadata, usage, created_at, updated_at
@@ -91,22 +170,25 @@ func (s *Store) Save(ctx context.Context, record conversations.ConversationRecor
 			usage = excluded.usage,
 			updated_at = excluded.updated_at
 	`
-	_, err = tx.NamedExecContext(ctx, summaryQuery, dbSummary)
-	if err != nil {
-		return errors.Wrap(err, "failed to save conversation summary")
-	}
-
-	return tx.Commit()
+	_, err := tx.NamedExecContext(ctx, summaryQuery, fromConversationSummary(record.ToSummary()))
+	return errors.Wrap(err, "failed to save conversation summary")
 }
 
 // Load retrieves a conversation record by ID
 func (s *Store) Load(ctx context.Context, id string) (conversations.ConversationRecord, error) {
-	var dbRecord dbConversationRecord
+	// Read the record and its appends from the same snapshot, so a
+	// concurrent consolidation cannot drop messages
+	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return conversations.ConversationRecord{}, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
 
-	query := `SELECT id, cwd, raw_messages, provider, usage,
-		summary, created_at, updated_at, metadata, tool_results
+	var stored dbStoredConversationRecord
+	query := `SELECT id, cwd, raw_messages AS sealed_raw_messages, provider, usage,
+		summary, created_at, updated_at, metadata, tool_results AS sealed_tool_results
 		FROM conversations WHERE id = ?`
-	err := s.db.GetContext(ctx, &dbRecord, query, id)
+	err = tx.GetContext(ctx, &stored, query, id)
 	if err != nil {
 		if err.Error() == "sql: no rows in result set" {
 			return conversations.ConversationRecord{}, errors.Errorf("conversation not found: %s", id)
@@ -114,7 +196,14 @@ func (s *Store) Load(ctx context.Context, id string) (conversations.Conversation
 		return conversations.ConversationRecord{}, errors.Wrap(err, "failed to load conversation record")
 	}
 
-	return dbRecord.ToConversationRecord(), nil
+	record, err := s.openRecord(&stored)
+	if err != nil {
+		return conversations.ConversationRecord{}, err
+	}
+	if err := s.applyAppends(ctx, tx, &record); err != nil {
+		return conversations.ConversationRecord{}, err
+	}
+	return record, nil
 }
 
 // Delete removes a conversation and its associated data
@@ -136,51 +225,45 @@ func (s *Store) Delete(ctx context.Context, id string) error {
 		return errors.Wrap(err, "failed to delete conversation summary")
 	}
 
-	return tx.Commit()
-}
-
-// Query performs advanced queries with filtering, sorting, and pagination
-func (s *Store) Query(ctx context.Context, options conversations.QueryOptions) (conversations.QueryResult, error) {
-	// Build WHERE conditions
-	conditions := []string{}
-	args := map[string]any{}
-
-	if options.StartDate != nil {
-		conditions = append(conditions, "created_at >= :start_date")
-		args["start_date"] = *options.StartDate
+	_, err = tx.ExecContext(ctx, "DELETE FROM conversation_appends WHERE conversation_id = ?", id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete conversation appends")
 	}
 
-	if options.EndDate != nil {
-		conditions = append(conditions, "created_at <= :end_date")
-		args["end_date"] = *options.EndDate
+	_, err = tx.ExecContext(ctx, "DELETE FROM conversation_journal WHERE conversation_id = ?", id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete conversation journal")
 	}
 
-	if options.SearchTerm != "" {
-		searchPattern := "%" + strings.ToLower(options.SearchTerm) + "%"
-		conditions = append(conditions, "(LOWER(first_message) LIKE :search_term OR LOWER(summary) LIKE :search_term)")
-		args["search_term"] = searchPattern
-	}
+	s.forget(id)
+	return tx.Commit()
+}
 
-	if options.Provider != "" {
-		conditions = append(conditions, "provider = :provider")
-		args["provider"] = options.Provider
+// Fork saves a copy of a conversation under a new ID and returns it
+func (s *Store) Fork(ctx context.Context, id string) (conversations.ConversationRecord, error) {
+	source, err := s.Load(ctx, id)
+	if err != nil {
+		return conversations.ConversationRecord{}, errors.Wrap(err, "failed to load conversation")
 	}
 
-	if options.CWD != "" {
-		conditions = append(conditions, "cwd = :cwd")
-		args["cwd"] = options.CWD
+	forked := source.Fork()
+	if err := s.Save(ctx, forked); err != nil {
+		return conversations.ConversationRecord{}, errors.Wrap(err, "failed to save forked conversation")
 	}
+	return forked, nil
+}
 
-	// Build ORDER BY clause
-	sortBy := "updated_at"
-	switch options.SortBy {
-	case "createdAt":
-		sortBy = "created_at"
-	case "updatedAt":
-		sortBy = "updated_at"
-	case "messageCount":
-		sortBy = "message_count"
-	}
+// totalCostExpr sums the cost fields of the usage JSON column, matching
+// llmtypes.Usage.TotalCost.
+const totalCostExpr = `(COALESCE(json_extract(usage, '$.inputCost'), 0) +
+	COALESCE(json_extract(usage, '$.outputCost'), 0) +
+	COALESCE(json_extract(usage, '$.cacheCreationCost'), 0) +
+	COALESCE(json_extract(usage, '$.cacheReadCost'), 0))`
+
+// Query performs advanced queries with filtering, sorting, and pagination
+func (s *Store) Query(ctx context.Context, options conversations.QueryOptions) (conversations.QueryResult, error) {
+	conditions, args := queryConditions(options)
+	sortBy := querySortColumn(options.SortBy)
 
 	sortOrder := "DESC"
 	if options.SortOrder == "asc" {
@@ -258,6 +341,206 @@ func (s *Store) Query(ctx context.Context, options conversations.QueryOptions) (
 	}, nil
 }
 
+// Search performs a full-text search over conversation summaries and message
+// content. Every word of query must match; quoted phrases match as a whole.
+// Hits are ordered by relevance unless options.SortBy names another field.
+func (s *Store) Search(ctx context.Context, query string, options conversations.QueryOptions) (conversations.SearchResult, error) {
+	match := ftsMatchQuery(query)
+	if match == "" {
+		return conversations.SearchResult{}, errors.New("search query must contain at least one word")
+	}
+
+	conditions, args := queryConditions(options)
+	args["match"] = match
+
+	from := `conversation_summaries JOIN (
+		SELECT id AS hit_id, rank, snippet(conversation_search, -1, '', '', '…', 12) AS snippet
+		FROM conversation_search WHERE conversation_search MATCH :match
+	) hits ON hits.hit_id = conversation_summaries.id`
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	sortOrder := "DESC"
+	if options.SortOrder == "asc" {
+		sortOrder = "ASC"
+	}
+	orderBy := "rank, updated_at DESC"
+	if options.SortBy != "" && options.SortBy != "relevance" {
+		orderBy = querySortColumn(options.SortBy) + " " + sortOrder
+	}
+
+	searchQuery := `SELECT id, cwd, message_count, first_message, summary, provider,
+		metadata, usage, created_at, updated_at, snippet FROM ` + from + where + " ORDER BY " + orderBy
+	if options.Limit > 0 {
+		searchQuery += " LIMIT :limit"
+		args["limit"] = options.Limit
+
+		if options.Offset > 0 {
+			searchQuery += " OFFSET :offset"
+			args["offset"] = options.Offset
+		}
+	}
+
+	finalQuery, argsSlice, err := sqlx.Named(searchQuery, args)
+	if err != nil {
+		return conversations.SearchResult{}, errors.Wrap(err, "failed to build named search query")
+	}
+
+	var dbHits []dbSearchHit
+	if err := s.db.SelectContext(ctx, &dbHits, s.db.Rebind(finalQuery), argsSlice...); err != nil {
+		return conversations.SearchResult{}, errors.Wrap(err, "failed to execute search query")
+	}
+
+	hits := make([]conversations.SearchHit, len(dbHits))
+	for i, dbHit := range dbHits {
+		hits[i] = conversations.SearchHit{
+			ConversationSummary: dbHit.ToConversationSummary(),
+			Snippet:             dbHit.Snippet,
+		}
+	}
+
+	delete(args, "limit")
+	delete(args, "offset")
+	countQuery, countArgs, err := sqlx.Named("SELECT COUNT(*) FROM "+from+where, args)
+	if err != nil {
+		return conversations.SearchResult{}, errors.Wrap(err, "failed to build named search count query")
+	}
+
+	var total int
+	if err := s.db.GetContext(ctx, &total, s.db.Rebind(countQuery), countArgs...); err != nil {
+		return conversations.SearchResult{}, errors.Wrap(err, "failed to get search total count")
+	}
+
+	return conversations.SearchResult{
+		Hits:         hits,
+		Total:        total,
+		QueryOptions: options,
+	}, nil
+}
+
+// ftsMatchQuery turns user input into an FTS5 MATCH expression. Each word or
+// double-quoted phrase becomes a quoted string so FTS5 operators and syntax
+// characters in the input are matched literally.
+func ftsMatchQuery(input string) string {
+	var terms []string
+	addTerm := func(term string) {
+		if strings.IndexFunc(term, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0 {
+			return
+		}
+		terms = append(terms, `"`+term+`"`)
+	}
+
+	for i, part := range strings.Split(input, `"`) {
+		if i%2 == 1 {
+			addTerm(strings.Join(strings.Fields(part), " "))
+			continue
+		}
+		for _, word := range strings.Fields(part) {
+			addTerm(word)
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// queryConditions builds the WHERE conditions and named arguments for the
+// filters in options
+func queryConditions(options conversations.QueryOptions) ([]string, map[string]any) {
+	conditions := []string{}
+	args := map[string]any{}
+
+	if options.StartDate != nil {
+		conditions = append(conditions, "created_at >= :start_date")
+		args["start_date"] = *options.StartDate
+	}
+
+	if options.EndDate != nil {
+		conditions = append(conditions, "created_at <= :end_date")
+		args["end_date"] = *options.EndDate
+	}
+
+	if options.SearchTerm != "" {
+		searchPattern := "%" + strings.ToLower(options.SearchTerm) + "%"
+		conditions = append(conditions, "(LOWER(first_message) LIKE :search_term OR LOWER(summary) LIKE :search_term)")
+		args["search_term"] = searchPattern
+	}
+
+	if options.ContentSearch != "" {
+		conditions = append(conditions, `(id IN (SELECT id FROM conversations
+			WHERE LOWER(raw_messages) LIKE :content_search OR LOWER(metadata) LIKE :content_search)
+			OR id IN (SELECT conversation_id FROM conversation_appends
+			WHERE LOWER(raw_messages) LIKE :content_search))`)
+		args["content_search"] = "%" + strings.ToLower(options.ContentSearch) + "%"
+	}
+
+	if options.Provider != "" {
+		conditions = append(conditions, "provider = :provider")
+		args["provider"] = options.Provider
+	}
+
+	if options.CWD != "" {
+		if options.CWDSubdirs {
+			conditions = append(conditions, `(cwd = :cwd OR cwd LIKE :cwd_prefix ESCAPE '\')`)
+			args["cwd_prefix"] = escapeLikePattern(strings.TrimSuffix(options.CWD, string(filepath.Separator))+string(filepath.Separator)) + "%"
+		} else {
+			conditions = append(conditions, "cwd = :cwd")
+		}
+		args["cwd"] = options.CWD
+	}
+
+	if options.Model != "" {
+		conditions = append(conditions, "json_extract(metadata, '$.model') = :model")
+		args["model"] = options.Model
+	}
+
+	if options.Profile != "" {
+		conditions = append(conditions, "json_extract(metadata, '$.profile') = :profile")
+		args["profile"] = options.Profile
+	}
+
+	return conditions, args
+}
+
+// escapeLikePattern escapes the LIKE wildcards in value, using a backslash as
+// the escape character.
+func escapeLikePattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// querySortColumn maps a sort field to its conversation_summaries column
+func querySortColumn(field string) string {
+	sortBy := "updated_at"
+	switch field {
+	case "createdAt", "created_at", "created":
+		sortBy = "created_at"
+	case "updatedAt", "updated_at", "updated":
+		sortBy = "updated_at"
+	case "messageCount", "message_count", "messages":
+		sortBy = "message_count"
+	case "cost":
+		sortBy = totalCostExpr
+	}
+
+	return sortBy
+}
+
+// TrackRun records that the conversation is running, for steering submitted
+// from elsewhere, in the database of the store.
+func (s *Store) TrackRun(ctx context.Context, id string) func() {
+	return steer.NewSteerStoreWithDB(s.db).TrackRun(ctx, id)
+}
+
+// forget drops what this store remembers about saving the conversations
+// ids, so their next save writes them in full.
+func (s *Store) forget(ids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.saved, id)
+	}
+}
+
 // Close closes the database connection
 func (s *Store) Close() error {
 	if s == nil || s.db == nil {
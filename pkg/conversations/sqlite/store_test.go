@@ -11,6 +11,7 @@ import (
 
 	"github.com/jingkaihe/kodelet/pkg/db"
 	"github.com/jingkaihe/kodelet/pkg/db/migrations"
+	"github.com/jingkaihe/kodelet/pkg/steer"
 	conversations "github.com/jingkaihe/kodelet/pkg/types/conversations"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	"github.com/jingkaihe/kodelet/pkg/types/tools"
@@ -183,6 +184,14 @@ func TestStore_Query(t *testing.T) {
 	assert.Len(t, result.ConversationSummaries, 1)
 	assert.Equal(t, "conv-2", result.ConversationSummaries[0].ID)
 
+	// Test content search over the stored messages
+	result, err = store.Query(ctx, conversations.QueryOptions{
+		ContentSearch: "ANOTHER",
+	})
+	require.NoError(t, err)
+	require.Len(t, result.ConversationSummaries, 1)
+	assert.Equal(t, "conv-3", result.ConversationSummaries[0].ID)
+
 	// Test sorting by update time (default)
 	result, err = store.Query(ctx, conversations.QueryOptions{})
 	require.NoError(t, err)
@@ -238,6 +247,278 @@ func TestStore_Query(t *testing.T) {
 	assert.Equal(t, "conv-2", result.ConversationSummaries[0].ID)
 }
 
+func TestStore_QueryByCostModelAndProfile(t *testing.T) {
+	ctx := context.Background()
+
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test_conversations.db")
+	setupTestDB(t, dbPath)
+
+	store, err := NewStore(ctx, dbPath)
+	require.NoError(t, err)
+	defer store.Close()
+
+	records := []conversations.ConversationRecord{
+		{
+			ID:          "cheap",
+			RawMessages: json.RawMessage(`[{"role": "user", "content": [{"type": "text", "text": "Hi"}]}]`),
+			Provider:    "anthropic",
+			Usage:       llmtypes.Usage{InputCost: 0.01},
+			Metadata:    map[string]any{"model": "claude-sonnet-4-6"},
+			ToolResults: map[string]tools.StructuredToolResult{},
+		},
+		{
+			ID:          "expensive",
+			RawMessages: json.RawMessage(`[{"role": "user", "content": [{"type": "text", "text": "Hi"}]}]`),
+			Provider:    "openai",
+			Usage:       llmtypes.Usage{InputCost: 0.5, OutputCost: 1, CacheReadCost: 0.1},
+			Metadata:    map[string]any{"model": "gpt-5", "profile": "work"},
+			ToolResults: map[string]tools.StructuredToolResult{},
+		},
+		{
+			ID:          "middle",
+			RawMessages: json.RawMessage(`[{"role": "user", "content": [{"type": "text", "text": "Hi"}]}]`),
+			Provider:    "openai",
+			Usage:       llmtypes.Usage{OutputCost: 0.2},
+			Metadata:    map[string]any{"model": "gpt-5"},
+			ToolResults: map[string]tools.StructuredToolResult{},
+		},
+	}
+	for _, record := range records {
+		require.NoError(t, store.Save(ctx, record))
+	}
+
+	result, err := store.Query(ctx, conversations.QueryOptions{SortBy: "cost", SortOrder: "desc"})
+	require.NoError(t, err)
+	require.Len(t, result.ConversationSummaries, 3)
+	assert.Equal(t, "expensive", result.ConversationSummaries[0].ID)
+	assert.Equal(t, "middle", result.ConversationSummaries[1].ID)
+	assert.Equal(t, "cheap", result.ConversationSummaries[2].ID)
+
+	result, err = store.Query(ctx, conversations.QueryOptions{Model: "gpt-5", SortBy: "cost", SortOrder: "asc"})
+	require.NoError(t, err)
+	require.Len(t, result.ConversationSummaries, 2)
+	assert.Equal(t, 2, result.Total)
+	assert.Equal(t, "middle", result.ConversationSummaries[0].ID)
+
+	result, err = store.Query(ctx, conversations.QueryOptions{Profile: "work"})
+	require.NoError(t, err)
+	require.Len(t, result.ConversationSummaries, 1)
+	assert.Equal(t, "expensive", result.ConversationSummaries[0].ID)
+}
+
+func TestStore_QueryByCWDSubdirs(t *testing.T) {
+	ctx := context.Background()
+
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test_conversations.db")
+	setupTestDB(t, dbPath)
+
+	store, err := NewStore(ctx, dbPath)
+	require.NoError(t, err)
+	defer store.Close()
+
+	for id, cwd := range map[string]string{
+		"root":    "/src/my_repo",
+		"nested":  "/src/my_repo/cmd/tool",
+		"sibling": "/src/my_repo-old",
+		"escaped": "/src/myXrepo/cmd",
+		"other":   "/src/other",
+	} {
+		require.NoError(t, store.Save(ctx, conversations.ConversationRecord{
+			ID:          id,
+			RawMessages: json.RawMessage(`[]`),
+			Provider:    "anthropic",
+			CWD:         cwd,
+			Metadata:    map[string]any{},
+			ToolResults: map[string]tools.StructuredToolResult{},
+		}))
+	}
+
+	ids := func(result *conversations.QueryResult) []string {
+		var ids []string
+		for _, summary := range result.ConversationSummaries {
+			ids = append(ids, summary.ID)
+		}
+		return ids
+	}
+
+	result, err := store.Query(ctx, conversations.QueryOptions{CWD: "/src/my_repo"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"root"}, ids(&result))
+
+	result, err = store.Query(ctx, conversations.QueryOptions{CWD: "/src/my_repo", CWDSubdirs: true})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"root", "nested"}, ids(&result))
+}
+
+func TestStore_Fork(t *testing.T) {
+	ctx := context.Background()
+
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test_conversations.db")
+	setupTestDB(t, dbPath)
+
+	store, err := NewStore(ctx, dbPath)
+	require.NoError(t, err)
+	defer store.Close()
+
+	source := conversations.ConversationRecord{
+		ID:          "source",
+		CWD:         "/repo",
+		RawMessages: json.RawMessage(`[{"role": "user", "content": [{"type": "text", "text": "Try the retry approach"}]}]`),
+		Provider:    "anthropic",
+		Usage: llmtypes.Usage{
+			InputTokens:          100,
+			OutputTokens:         50,
+			InputCost:            0.5,
+			CurrentContextWindow: 150,
+			MaxContextWindow:     200000,
+		},
+		Summary:     "Retry design",
+		CreatedAt:   time.Now().Add(-time.Hour),
+		UpdatedAt:   time.Now().Add(-time.Hour),
+		Metadata:    map[string]any{"profile": "work"},
+		ToolResults: map[string]tools.StructuredToolResult{"call-1": {ToolName: "bash", Success: true}},
+	}
+	require.NoError(t, store.Save(ctx, source))
+
+	forked, err := store.Fork(ctx, "source")
+	require.NoError(t, err)
+	assert.NotEqual(t, "source", forked.ID)
+
+	loaded, err := store.Load(ctx, forked.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "/repo", loaded.CWD)
+	assert.JSONEq(t, string(source.RawMessages), string(loaded.RawMessages))
+	assert.Equal(t, source.Summary, loaded.Summary)
+	assert.Equal(t, source.Metadata, loaded.Metadata)
+	assert.Equal(t, "bash", loaded.ToolResults["call-1"].ToolName)
+	assert.Zero(t, loaded.Usage.InputTokens)
+	assert.Zero(t, loaded.Usage.TotalCost())
+	assert.Equal(t, 150, loaded.Usage.CurrentContextWindow)
+	assert.Equal(t, 200000, loaded.Usage.MaxContextWindow)
+
+	original, err := store.Load(ctx, "source")
+	require.NoError(t, err)
+	assert.Equal(t, 100, original.Usage.InputTokens, "the original conversation is left untouched")
+
+	_, err = store.Fork(ctx, "missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "conversation not found")
+}
+
+func TestStore_Search(t *testing.T) {
+	ctx := context.Background()
+
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test_conversations.db")
+	setupTestDB(t, dbPath)
+
+	store, err := NewStore(ctx, dbPath)
+	require.NoError(t, err)
+	defer store.Close()
+
+	records := []conversations.ConversationRecord{
+		{
+			ID:          "queue",
+			RawMessages: json.RawMessage(`[{"role": "user", "content": [{"type": "text", "text": "Move the background jobs to a Postgres queue"}]}]`),
+			Provider:    "anthropic",
+			Summary:     "Job queue design",
+			CWD:         "/repo",
+			Metadata:    map[string]any{},
+			ToolResults: map[string]tools.StructuredToolResult{},
+		},
+		{
+			ID:          "cache",
+			RawMessages: json.RawMessage(`[{"role": "user", "content": [{"type": "text", "text": "Add a Redis cache"}]}, {"role": "assistant", "content": [{"type": "text", "text": "The cache sits in front of Postgres"}]}]`),
+			Provider:    "openai",
+			Summary:     "Caching",
+			CWD:         "/other",
+			Metadata:    map[string]any{},
+			ToolResults: map[string]tools.StructuredToolResult{},
+		},
+		{
+			ID:          "compacted",
+			RawMessages: json.RawMessage(`[{"role": "user", "content": [{"type": "text", "text": "Summary of earlier work"}]}]`),
+			Provider:    "anthropic",
+			CWD:         "/repo",
+			Metadata: map[string]any{
+				conversations.CompactedTranscriptMetadataKey: []map[string]any{
+					{"role": "user", "content": "we agreed to vendor the sqlite driver"},
+				},
+			},
+			ToolResults: map[string]tools.StructuredToolResult{},
+		},
+	}
+	for _, record := range records {
+		require.NoError(t, store.Save(ctx, record))
+	}
+
+	result, err := store.Search(ctx, "postgres", conversations.QueryOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Total)
+	require.Len(t, result.Hits, 2)
+	assert.ElementsMatch(t, []string{"queue", "cache"}, []string{result.Hits[0].ID, result.Hits[1].ID})
+	for _, hit := range result.Hits {
+		assert.Contains(t, hit.Snippet, "Postgres")
+	}
+
+	result, err = store.Search(ctx, "postgres", conversations.QueryOptions{CWD: "/repo"})
+	require.NoError(t, err)
+	require.Len(t, result.Hits, 1)
+	assert.Equal(t, "queue", result.Hits[0].ID)
+	assert.Equal(t, "Job queue design", result.Hits[0].Summary)
+
+	result, err = store.Search(ctx, "design", conversations.QueryOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.Hits, 1)
+	assert.Equal(t, "queue", result.Hits[0].ID, "summaries are searched")
+
+	result, err = store.Search(ctx, "vendor sqlite", conversations.QueryOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.Hits, 1)
+	assert.Equal(t, "compacted", result.Hits[0].ID, "compacted transcripts are searched")
+
+	result, err = store.Search(ctx, `"postgres queue"`, conversations.QueryOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.Hits, 1)
+	assert.Equal(t, "queue", result.Hits[0].ID)
+
+	result, err = store.Search(ctx, "redis OR queue", conversations.QueryOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, result.Hits, "operators are matched literally")
+
+	result, err = store.Search(ctx, "postgres", conversations.QueryOptions{SortBy: "messageCount", SortOrder: "desc", Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Total)
+	require.Len(t, result.Hits, 1)
+	assert.Equal(t, "cache", result.Hits[0].ID)
+
+	record := records[1]
+	record.RawMessages = json.RawMessage(`[{"role": "user", "content": [{"type": "text", "text": "Add a Redis cache"}]}]`)
+	require.NoError(t, store.Save(ctx, record))
+	result, err = store.Search(ctx, "postgres", conversations.QueryOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.Hits, 1, "the index follows updates")
+
+	require.NoError(t, store.Delete(ctx, "queue"))
+	result, err = store.Search(ctx, "postgres", conversations.QueryOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, result.Hits, "the index follows deletes")
+
+	_, err = store.Search(ctx, ` "" * `, conversations.QueryOptions{})
+	require.Error(t, err)
+}
+
+func TestFTSMatchQuery(t *testing.T) {
+	assert.Equal(t, `"job" "queue"`, ftsMatchQuery("  job queue "))
+	assert.Equal(t, `"job queue" "redis"`, ftsMatchQuery(`"job   queue" redis`))
+	assert.Equal(t, `"job" "queue"`, ftsMatchQuery(`job "queue`))
+	assert.Equal(t, `"NEAR(a" "b)"`, ftsMatchQuery("NEAR(a b) -"))
+	assert.Empty(t, ftsMatchQuery(`* ""`))
+}
+
 func TestStore_DefaultSorting(t *testing.T) {
 	ctx := context.Background()
 
@@ -468,7 +749,7 @@ func TestStore_Migrations(t *testing.T) {
 	assert.Equal(t, len(migrations.All()), count)
 
 	// Verify all tables exist
-	tables := []string{"schema_migrations", "conversations", "conversation_summaries", "acp_session_updates"}
+	tables := []string{"schema_migrations", "conversations", "conversation_summaries", "acp_session_updates", "conversation_search", "extension_events"}
 	for _, table := range tables {
 		var exists bool
 		err = store.db.QueryRow(`
@@ -1063,3 +1344,24 @@ func TestStore_ErrorScenarios(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestStore_TrackRun(t *testing.T) {
+	ctx := context.Background()
+	store, dbPath := newAppendsTestStore(t)
+	steerStore, err := steer.NewSteerStore(ctx, steer.WithDBPath(dbPath))
+	require.NoError(t, err)
+	defer steerStore.Close()
+
+	release := store.TrackRun(ctx, "conv-running")
+	active, err := steerStore.IsRunActive(ctx, "conv-running")
+	require.NoError(t, err)
+	assert.True(t, active)
+
+	release()
+	active, err = steerStore.IsRunActive(ctx, "conv-running")
+	require.NoError(t, err)
+	assert.False(t, active)
+
+	// The store stays usable once the run has ended
+	require.NoError(t, store.Save(ctx, appendsTestRecord(1)))
+}
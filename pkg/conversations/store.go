@@ -13,14 +13,27 @@ type ConversationStore interface {
 	Save(ctx context.Context, record conversations.ConversationRecord) error
 	Load(ctx context.Context, id string) (conversations.ConversationRecord, error)
 	Delete(ctx context.Context, id string) error
+	// Fork saves a copy of the conversation under a new ID, leaving the
+	// original untouched, and returns the copy.
+	Fork(ctx context.Context, id string) (conversations.ConversationRecord, error)
 
 	// Advanced query operations
 	Query(ctx context.Context, options conversations.QueryOptions) (conversations.QueryResult, error)
+	// Search performs a full-text search over conversation summaries and
+	// message content. options filter and paginate the hits like Query.
+	Search(ctx context.Context, query string, options conversations.QueryOptions) (conversations.SearchResult, error)
 
 	// Lifecycle methods
 	Close() error // Close doesn't need context
 }
 
+// ConversationRunTracker is implemented by stores that share their database
+// with steering, so that runs are recorded without opening another
+// connection. The returned function ends the run.
+type ConversationRunTracker interface {
+	TrackRun(ctx context.Context, id string) func()
+}
+
 // Config holds configuration for the conversation store
 type Config struct {
 	StoreType string // "sqlite"
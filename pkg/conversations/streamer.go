@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/jingkaihe/kodelet/pkg/logger"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	"github.com/jingkaihe/kodelet/pkg/types/tools"
 	"github.com/pkg/errors"
 )
@@ -25,6 +26,9 @@ type StreamEntry struct {
 	Role           string `json:"role"`                      // "user", "assistant", "system"
 	ToolCallID     string `json:"tool_call_id,omitempty"`    // For matching tool calls to results
 	ConversationID string `json:"conversation_id,omitempty"` // ID of the conversation this entry belongs to
+	// ReasoningFormat tells whether thinking content is the full reasoning
+	// ("thinking") or a provider summary of it ("summary").
+	ReasoningFormat string `json:"reasoning_format,omitempty"`
 }
 
 // StreamOpts contains options for streaming conversation data
@@ -36,6 +40,11 @@ type StreamOpts struct {
 	Ready          chan<- struct{}
 	// LiveExcludedKinds accepts either a kind (all roles) or "role:kind".
 	LiveExcludedKinds map[string]bool
+	// Output receives every streamed entry. Entries are written to stdout as
+	// JSON lines when it is nil.
+	Output func(StreamEntry) error
+	// Done stops live streaming after one final check for updates.
+	Done <-chan struct{}
 }
 
 // StreamableMessage contains parsed message data for streaming
@@ -47,6 +56,8 @@ type StreamableMessage struct {
 	ToolName   string // For tool use/result
 	ToolCallID string // For matching tool results
 	Input      string // For tool use (JSON string)
+	// Reasoning is the provider-neutral form of thinking messages.
+	Reasoning *llmtypes.Reasoning
 }
 
 // ConversationStreamer handles streaming conversation data in structured JSON format
@@ -73,6 +84,7 @@ type streamState struct {
 	lastUpdateTime    time.Time
 	streamedEntries   int
 	liveExcludedKinds map[string]bool
+	output            func(StreamEntry) error
 }
 
 // StreamLiveUpdates watches for conversation updates and streams entries based on options
@@ -85,7 +97,7 @@ func (cs *ConversationStreamer) StreamLiveUpdates(
 
 	includeHistory := streamOpts.IncludeHistory || streamOpts.HistoryOnly
 
-	state, err := cs.initializeStream(ctx, conversationID, includeHistory, streamOpts.New)
+	state, err := cs.initializeStream(ctx, conversationID, includeHistory, streamOpts.New, streamOpts.Output)
 	if err != nil {
 		return err
 	}
@@ -105,6 +117,9 @@ func (cs *ConversationStreamer) StreamLiveUpdates(
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
+		case <-streamOpts.Done:
+			cs.processLiveUpdate(ctx, conversationID, state)
+			return nil
 		case <-ticker.C:
 			cs.processLiveUpdate(ctx, conversationID, state)
 		}
@@ -117,11 +132,16 @@ func (cs *ConversationStreamer) initializeStream(
 	conversationID string,
 	includeHistory bool,
 	isNew bool,
+	output func(StreamEntry) error,
 ) (*streamState, error) {
+	if output == nil {
+		output = cs.outputStreamEntry
+	}
 	if isNew {
 		return &streamState{
 			lastUpdateTime:  time.Now(),
 			streamedEntries: 0,
+			output:          output,
 		}, nil
 	}
 	response, err := cs.service.GetConversation(ctx, conversationID)
@@ -142,12 +162,13 @@ func (cs *ConversationStreamer) initializeStream(
 	state := &streamState{
 		lastUpdateTime:  response.UpdatedAt,
 		streamedEntries: len(messages),
+		output:          output,
 	}
 
 	if includeHistory {
 		for _, msg := range messages {
 			entry := cs.convertToStreamEntry(msg, conversationID)
-			if err := cs.outputStreamEntry(entry); err != nil {
+			if err := output(entry); err != nil {
 				return nil, errors.Wrap(err, "failed to output stream entry")
 			}
 		}
@@ -171,7 +192,7 @@ func (cs *ConversationStreamer) processLiveUpdate(ctx context.Context, conversat
 		return
 	}
 
-	newlyStreamed, totalEntries, err := cs.streamNewMessagesSince(ctx, response, state.streamedEntries, conversationID, state.liveExcludedKinds)
+	newlyStreamed, totalEntries, err := cs.streamNewMessagesSince(ctx, response, state.streamedEntries, conversationID, state.liveExcludedKinds, state.output)
 	if err != nil {
 		logger.G(ctx).WithError(err).Error("Failed to stream new messages")
 		return
@@ -187,7 +208,10 @@ func (cs *ConversationStreamer) processLiveUpdate(ctx context.Context, conversat
 }
 
 // streamNewMessagesSince streams only the new messages since the last streamed count
-func (cs *ConversationStreamer) streamNewMessagesSince(ctx context.Context, response *GetConversationResponse, alreadyStreamed int, conversationID string, excludedKinds map[string]bool) (int, int, error) {
+func (cs *ConversationStreamer) streamNewMessagesSince(ctx context.Context, response *GetConversationResponse, alreadyStreamed int, conversationID string, excludedKinds map[string]bool, output func(StreamEntry) error) (int, int, error) {
+	if output == nil {
+		output = cs.outputStreamEntry
+	}
 	parser, exists := cs.messageParsers[response.Provider]
 	if !exists {
 		return 0, 0, errors.Errorf("no message parser registered for provider: %s", response.Provider)
@@ -217,7 +241,7 @@ func (cs *ConversationStreamer) streamNewMessagesSince(ctx context.Context, resp
 				continue
 			}
 			entry := cs.convertToStreamEntry(msg, conversationID)
-			if err := cs.outputStreamEntry(entry); err != nil {
+			if err := output(entry); err != nil {
 				return newlyStreamed, totalEntries, errors.Wrap(err, "failed to output stream entry")
 			}
 			newlyStreamed++
@@ -241,6 +265,9 @@ func (cs *ConversationStreamer) convertToStreamEntry(msg StreamableMessage, conv
 		if entry.Content == "" {
 			entry.Content = renderOpenAIResponsesRawItemMarkdown(msg.RawItem)
 		}
+		if msg.Reasoning != nil {
+			entry.ReasoningFormat = msg.Reasoning.Format
+		}
 	case "tool-use":
 		entry.ToolName = msg.ToolName
 		entry.Input = msg.Input
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	"github.com/jingkaihe/kodelet/pkg/types/tools"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -77,16 +78,18 @@ func TestConvertToStreamEntry(t *testing.T) {
 		{
 			name: "thinking message",
 			input: StreamableMessage{
-				Kind:    "thinking",
-				Role:    "assistant",
-				Content: "Let me think about this",
+				Kind:      "thinking",
+				Role:      "assistant",
+				Content:   "Let me think about this",
+				Reasoning: &llmtypes.Reasoning{Provider: "openai", Format: llmtypes.ReasoningFormatSummary},
 			},
 			conversationID: "test-conv-456",
 			expected: StreamEntry{
-				Kind:           "thinking",
-				Role:           "assistant",
-				Content:        "Let me think about this",
-				ConversationID: "test-conv-456",
+				Kind:            "thinking",
+				Role:            "assistant",
+				Content:         "Let me think about this",
+				ReasoningFormat: "summary",
+				ConversationID:  "test-conv-456",
 			},
 		},
 		{
@@ -288,25 +291,25 @@ func TestStreamNewMessagesSince(t *testing.T) {
 	ctx := context.Background()
 
 	// Test streaming from message 0 (should get all 5)
-	count, total, err := streamer.streamNewMessagesSince(ctx, service.conversation, 0, "test-conv", nil)
+	count, total, err := streamer.streamNewMessagesSince(ctx, service.conversation, 0, "test-conv", nil, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, 5, count)
 	assert.Equal(t, 5, total)
 
 	// Test streaming from message 3 (should get 2 new messages)
-	count, total, err = streamer.streamNewMessagesSince(ctx, service.conversation, 3, "test-conv", nil)
+	count, total, err = streamer.streamNewMessagesSince(ctx, service.conversation, 3, "test-conv", nil, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, 2, count)
 	assert.Equal(t, 5, total)
 
 	// Test streaming from message 5 (should get 0 new messages)
-	count, total, err = streamer.streamNewMessagesSince(ctx, service.conversation, 5, "test-conv", nil)
+	count, total, err = streamer.streamNewMessagesSince(ctx, service.conversation, 5, "test-conv", nil, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, 0, count)
 	assert.Equal(t, 5, total)
 
 	// Test streaming from message 10 (beyond available, should recover by rebasing and streaming all)
-	count, total, err = streamer.streamNewMessagesSince(ctx, service.conversation, 10, "test-conv", nil)
+	count, total, err = streamer.streamNewMessagesSince(ctx, service.conversation, 10, "test-conv", nil, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, 5, count)
 	assert.Equal(t, 5, total)
@@ -389,7 +392,7 @@ func TestStreamNewMessagesSinceExcludesSelectedLiveKinds(t *testing.T) {
 		"assistant:thinking": true,
 		"tool-use":           true,
 		"tool-result":        true,
-	})
+	}, nil)
 	require.NoError(t, err)
 	assert.Equal(t, 1, count)
 	assert.Equal(t, 5, total)
@@ -526,3 +529,54 @@ func TestStreamLiveUpdates_HistoryOnly(t *testing.T) {
 		t.Fatal("streamer did not signal readiness")
 	}
 }
+
+// sequenceConversationService returns its responses in order, repeating the last one.
+type sequenceConversationService struct {
+	mockConversationService
+	responses []*GetConversationResponse
+}
+
+func (m *sequenceConversationService) GetConversation(_ context.Context, _ string) (*GetConversationResponse, error) {
+	response := m.responses[0]
+	if len(m.responses) > 1 {
+		m.responses = m.responses[1:]
+	}
+	return response, nil
+}
+
+func TestStreamLiveUpdates_OutputAndDone(t *testing.T) {
+	now := time.Now()
+	service := &sequenceConversationService{responses: []*GetConversationResponse{
+		{ID: "conv-1", Provider: "test-provider", RawMessages: json.RawMessage(`1`), UpdatedAt: now},
+		{ID: "conv-1", Provider: "test-provider", RawMessages: json.RawMessage(`2`), UpdatedAt: now.Add(time.Second)},
+	}}
+
+	streamer := NewConversationStreamer(service)
+	streamer.RegisterMessageParser("test-provider", func(raw json.RawMessage, _ map[string]any, _ map[string]tools.StructuredToolResult) ([]StreamableMessage, error) {
+		messages := []StreamableMessage{{Kind: "text", Role: "user", Content: "list files"}}
+		if string(raw) == "2" {
+			messages = append(messages, StreamableMessage{Kind: "tool-use", Role: "assistant", ToolName: "bash", ToolCallID: "call-1", Input: `{"command":"ls"}`})
+		}
+		return messages, nil
+	})
+
+	done := make(chan struct{})
+	close(done)
+	var entries []StreamEntry
+	err := streamer.StreamLiveUpdates(context.Background(), "conv-1", StreamOpts{
+		Interval:       time.Hour,
+		IncludeHistory: true,
+		Done:           done,
+		Output: func(entry StreamEntry) error {
+			entries = append(entries, entry)
+			return nil
+		},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "list files", entries[0].Content)
+	assert.Equal(t, "tool-use", entries[1].Kind)
+	assert.Equal(t, "call-1", entries[1].ToolCallID)
+	assert.Equal(t, "conv-1", entries[1].ConversationID)
+}
@@ -0,0 +1,14 @@
+package conversations
+
+import "strings"
+
+// TitleMetadataKey stores the short, human-friendly title of a conversation.
+// It is derived once at the first exchange and, unlike the summary, does not
+// change as the conversation grows.
+const TitleMetadataKey = "title"
+
+// TitleFromMetadata returns the conversation title recorded in metadata.
+func TitleFromMetadata(metadata map[string]any) string {
+	title, _ := metadata[TitleMetadataKey].(string)
+	return strings.TrimSpace(title)
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/jingkaihe/kodelet/pkg/db"
+	"github.com/pkg/errors"
+)
+
+// Migration20261016120000CreateConversationRuns creates the table of active
+// conversation runs used to tell whether queued steering will be consumed.
+func Migration20261016120000CreateConversationRuns() db.Migration {
+	return db.Migration{
+		Version:     20261016120000,
+		Description: "Create conversation runs table",
+		Up: func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`
+				CREATE TABLE IF NOT EXISTS conversation_runs (
+					conversation_id TEXT PRIMARY KEY,
+					owner TEXT NOT NULL,
+					hostname TEXT NOT NULL,
+					pid INTEGER NOT NULL,
+					started_at DATETIME NOT NULL,
+					heartbeat_at DATETIME NOT NULL
+				)
+			`); err != nil {
+				return errors.Wrap(err, "failed to create conversation_runs table")
+			}
+			return nil
+		},
+		Down: func(tx *sql.Tx) error {
+			_, err := tx.Exec("DROP TABLE IF EXISTS conversation_runs")
+			return errors.Wrap(err, "failed to drop conversation_runs table")
+		},
+	}
+}
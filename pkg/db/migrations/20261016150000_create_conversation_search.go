@@ -0,0 +1,92 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jingkaihe/kodelet/pkg/db"
+	"github.com/pkg/errors"
+)
+
+// MessageSearchContent returns the SQL expression for the searchable text of
+// the raw messages held by the SQL expression rawMessages: their string
+// values, which covers every provider's message format. Identifiers,
+// signatures and inline image data are left out.
+func MessageSearchContent(rawMessages string) string {
+	return fmt.Sprintf(`COALESCE((
+		SELECT group_concat(value, ' ')
+		FROM json_tree(CASE WHEN json_valid(%[1]s) THEN %[1]s ELSE '[]' END)
+		WHERE type = 'text'
+			AND key NOT IN ('type', 'role', 'id', 'tool_use_id', 'tool_call_id', 'call_id',
+				'signature', 'encrypted_content', 'data', 'media_type')
+			AND value NOT LIKE 'data:%%'
+	), '')`, rawMessages)
+}
+
+// ConversationSearchContent returns the SQL expression for the searchable
+// text of the conversations row alias: its raw messages and its archived
+// compacted transcript.
+func ConversationSearchContent(alias string) string {
+	return MessageSearchContent(alias+".raw_messages") + fmt.Sprintf(` || ' ' || COALESCE((
+		SELECT group_concat(value, ' ')
+		FROM json_tree(CASE WHEN json_valid(%[1]s.metadata) THEN %[1]s.metadata ELSE '{}' END, '$.compacted_transcript')
+		WHERE type = 'text' AND key = 'content'
+	), '')`, alias)
+}
+
+// Migration20261016150000CreateConversationSearch creates the full-text
+// index over conversation summaries and message content. Triggers keep it in
+// sync with the conversations table, and existing conversations are indexed.
+func Migration20261016150000CreateConversationSearch() db.Migration {
+	return db.Migration{
+		Version:     20261016150000,
+		Description: "Create conversation full-text search index",
+		Up: func(tx *sql.Tx) error {
+			statements := []string{
+				`CREATE VIRTUAL TABLE IF NOT EXISTS conversation_search USING fts5(
+					id UNINDEXED,
+					summary,
+					content,
+					tokenize = 'porter unicode61'
+				)`,
+				fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS conversation_search_insert
+					AFTER INSERT ON conversations BEGIN
+						INSERT INTO conversation_search (rowid, id, summary, content)
+						VALUES (new.rowid, new.id, COALESCE(new.summary, ''), %s);
+					END`, ConversationSearchContent("new")),
+				fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS conversation_search_update
+					AFTER UPDATE OF raw_messages, summary, metadata ON conversations BEGIN
+						DELETE FROM conversation_search WHERE rowid = old.rowid;
+						INSERT INTO conversation_search (rowid, id, summary, content)
+						VALUES (new.rowid, new.id, COALESCE(new.summary, ''), %s);
+					END`, ConversationSearchContent("new")),
+				`CREATE TRIGGER IF NOT EXISTS conversation_search_delete
+					AFTER DELETE ON conversations BEGIN
+						DELETE FROM conversation_search WHERE rowid = old.rowid;
+					END`,
+				fmt.Sprintf(`INSERT INTO conversation_search (rowid, id, summary, content)
+					SELECT rowid, id, COALESCE(summary, ''), %s FROM conversations`,
+					ConversationSearchContent("conversations")),
+			}
+			for _, statement := range statements {
+				if _, err := tx.Exec(statement); err != nil {
+					return errors.Wrap(err, "failed to create conversation search index")
+				}
+			}
+			return nil
+		},
+		Down: func(tx *sql.Tx) error {
+			for _, statement := range []string{
+				"DROP TRIGGER IF EXISTS conversation_search_insert",
+				"DROP TRIGGER IF EXISTS conversation_search_update",
+				"DROP TRIGGER IF EXISTS conversation_search_delete",
+				"DROP TABLE IF EXISTS conversation_search",
+			} {
+				if _, err := tx.Exec(statement); err != nil {
+					return errors.Wrap(err, "failed to drop conversation search index")
+				}
+			}
+			return nil
+		},
+	}
+}
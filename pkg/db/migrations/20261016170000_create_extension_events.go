@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/jingkaihe/kodelet/pkg/db"
+	"github.com/pkg/errors"
+)
+
+// Migration20261016170000CreateExtensionEvents creates the queue of durable
+// extension events awaiting or completing delivery.
+func Migration20261016170000CreateExtensionEvents() db.Migration {
+	return db.Migration{
+		Version:     20261016170000,
+		Description: "Create extension events queue",
+		Up: func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`
+				CREATE TABLE IF NOT EXISTS extension_events (
+					seq INTEGER PRIMARY KEY AUTOINCREMENT,
+					id TEXT NOT NULL UNIQUE,
+					extension_id TEXT NOT NULL,
+					event TEXT NOT NULL,
+					payload TEXT NOT NULL,
+					call_context TEXT NOT NULL,
+					status TEXT NOT NULL,
+					attempts INTEGER NOT NULL DEFAULT 0,
+					last_error TEXT NOT NULL DEFAULT '',
+					created_at DATETIME NOT NULL,
+					updated_at DATETIME NOT NULL,
+					delivered_at DATETIME
+				)
+			`); err != nil {
+				return errors.Wrap(err, "failed to create extension_events table")
+			}
+
+			if _, err := tx.Exec(`
+				CREATE INDEX IF NOT EXISTS idx_extension_events_extension_status
+				ON extension_events(extension_id, status, seq)
+			`); err != nil {
+				return errors.Wrap(err, "failed to create extension events index")
+			}
+
+			return nil
+		},
+		Down: func(tx *sql.Tx) error {
+			_, err := tx.Exec("DROP TABLE IF EXISTS extension_events")
+			return errors.Wrap(err, "failed to drop extension_events table")
+		},
+	}
+}
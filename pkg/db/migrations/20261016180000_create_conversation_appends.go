@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/jingkaihe/kodelet/pkg/db"
+	"github.com/pkg/errors"
+)
+
+// Migration20261016180000CreateConversationAppends creates the log of
+// messages and tool results appended to a conversation since its record was
+// last written in full.
+func Migration20261016180000CreateConversationAppends() db.Migration {
+	return db.Migration{
+		Version:     20261016180000,
+		Description: "Create conversation appends table",
+		Up: func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`
+				CREATE TABLE IF NOT EXISTS conversation_appends (
+					seq INTEGER PRIMARY KEY AUTOINCREMENT,
+					conversation_id TEXT NOT NULL,
+					raw_messages TEXT NOT NULL,
+					tool_results TEXT NOT NULL,
+					created_at DATETIME NOT NULL
+				)
+			`); err != nil {
+				return errors.Wrap(err, "failed to create conversation_appends table")
+			}
+
+			if _, err := tx.Exec(`
+				CREATE INDEX IF NOT EXISTS idx_conversation_appends_conversation_id
+				ON conversation_appends(conversation_id, seq)
+			`); err != nil {
+				return errors.Wrap(err, "failed to create conversation appends index")
+			}
+
+			return nil
+		},
+		Down: func(tx *sql.Tx) error {
+			_, err := tx.Exec("DROP TABLE IF EXISTS conversation_appends")
+			return errors.Wrap(err, "failed to drop conversation_appends table")
+		},
+	}
+}
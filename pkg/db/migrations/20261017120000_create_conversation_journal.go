@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/jingkaihe/kodelet/pkg/db"
+	"github.com/pkg/errors"
+)
+
+// Migration20261017120000CreateConversationJournal creates the write-ahead
+// journal of the changes made to a conversation since it was last saved.
+func Migration20261017120000CreateConversationJournal() db.Migration {
+	return db.Migration{
+		Version:     20261017120000,
+		Description: "Create conversation journal table",
+		Up: func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`
+				CREATE TABLE IF NOT EXISTS conversation_journal (
+					seq INTEGER PRIMARY KEY AUTOINCREMENT,
+					conversation_id TEXT NOT NULL,
+					event TEXT NOT NULL,
+					created_at DATETIME NOT NULL
+				)
+			`); err != nil {
+				return errors.Wrap(err, "failed to create conversation_journal table")
+			}
+
+			if _, err := tx.Exec(`
+				CREATE INDEX IF NOT EXISTS idx_conversation_journal_conversation_id
+				ON conversation_journal(conversation_id, seq)
+			`); err != nil {
+				return errors.Wrap(err, "failed to create conversation journal index")
+			}
+
+			return nil
+		},
+		Down: func(tx *sql.Tx) error {
+			_, err := tx.Exec("DROP TABLE IF EXISTS conversation_journal")
+			return errors.Wrap(err, "failed to drop conversation_journal table")
+		},
+	}
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"database/sql"
+
+	"github.com/jingkaihe/kodelet/pkg/db"
+	"github.com/pkg/errors"
+)
+
+// Migration20261017150000CreateRateLimits creates the provider rate limit
+// budgets shared by every kodelet process.
+func Migration20261017150000CreateRateLimits() db.Migration {
+	return db.Migration{
+		Version:     20261017150000,
+		Description: "Create provider rate limits table",
+		Up: func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`
+				CREATE TABLE IF NOT EXISTS rate_limits (
+					id TEXT PRIMARY KEY,
+					requests REAL NOT NULL,
+					tokens REAL NOT NULL,
+					updated_at INTEGER NOT NULL,
+					paused_until INTEGER NOT NULL DEFAULT 0,
+					failures INTEGER NOT NULL DEFAULT 0
+				)
+			`); err != nil {
+				return errors.Wrap(err, "failed to create rate_limits table")
+			}
+			return nil
+		},
+		Down: func(tx *sql.Tx) error {
+			_, err := tx.Exec("DROP TABLE IF EXISTS rate_limits")
+			return errors.Wrap(err, "failed to drop rate_limits table")
+		},
+	}
+}
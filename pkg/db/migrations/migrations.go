@@ -18,5 +18,11 @@ func All() []db.Migration {
 		Migration20260226120000AddMetadataToSummaries(),
 		Migration20260331120000AddCWDToConversations(),
 		Migration20260719170000CreateSteeringMessages(),
+		Migration20261016120000CreateConversationRuns(),
+		Migration20261016150000CreateConversationSearch(),
+		Migration20261016170000CreateExtensionEvents(),
+		Migration20261016180000CreateConversationAppends(),
+		Migration20261017120000CreateConversationJournal(),
+		Migration20261017150000CreateRateLimits(),
 	}
 }
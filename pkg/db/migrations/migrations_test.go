@@ -16,7 +16,7 @@ import (
 
 func TestAll(t *testing.T) {
 	migrations := All()
-	require.Len(t, migrations, 8)
+	require.Len(t, migrations, 14)
 
 	versions := make([]int64, 0, len(migrations))
 	for _, migration := range migrations {
@@ -34,6 +34,12 @@ func TestAll(t *testing.T) {
 		20260226120000,
 		20260331120000,
 		20260719170000,
+		20261016120000,
+		20261016150000,
+		20261016170000,
+		20261016180000,
+		20261017120000,
+		20261017150000,
 	}, versions)
 }
 
@@ -49,6 +55,12 @@ func TestMigrationsCreateExpectedSchema(t *testing.T) {
 	assertTableExists(t, database.DB, "conversation_summaries")
 	assertTableExists(t, database.DB, "acp_session_updates")
 	assertTableExists(t, database.DB, "steering_messages")
+	assertTableExists(t, database.DB, "conversation_runs")
+	assertTableExists(t, database.DB, "conversation_search")
+	assertTableExists(t, database.DB, "extension_events")
+	assertTableExists(t, database.DB, "conversation_appends")
+	assertTableExists(t, database.DB, "conversation_journal")
+	assertTableExists(t, database.DB, "rate_limits")
 	assertColumnExists(t, database.DB, "conversations", "background_processes")
 	assertColumnExists(t, database.DB, "conversations", "cwd")
 	assertColumnExists(t, database.DB, "conversation_summaries", "provider")
@@ -59,6 +71,9 @@ func TestMigrationsCreateExpectedSchema(t *testing.T) {
 	assertIndexExists(t, database.DB, "idx_acp_session_updates_session_id")
 	assertIndexExists(t, database.DB, "idx_conversations_cwd_updated_at")
 	assertIndexExists(t, database.DB, "idx_steering_messages_conversation_id")
+	assertIndexExists(t, database.DB, "idx_extension_events_extension_status")
+	assertIndexExists(t, database.DB, "idx_conversation_appends_conversation_id")
+	assertIndexExists(t, database.DB, "idx_conversation_journal_conversation_id")
 
 	versions, err := runner.GetAppliedVersions(ctx)
 	require.NoError(t, err)
@@ -71,6 +86,12 @@ func TestMigrationsCreateExpectedSchema(t *testing.T) {
 		20260226120000,
 		20260331120000,
 		20260719170000,
+		20261016120000,
+		20261016150000,
+		20261016170000,
+		20261016180000,
+		20261017120000,
+		20261017150000,
 	}, versions)
 }
 
@@ -111,6 +132,52 @@ func TestProviderMetadataAndCWDBackfillMigrations(t *testing.T) {
 	assert.Equal(t, "/tmp/project", cwd)
 }
 
+func TestConversationSearchMigrationIndexesConversations(t *testing.T) {
+	ctx := context.Background()
+	database := openMigrationsTestDB(t)
+	runner := db.NewMigrationRunner(database)
+	all := All()
+
+	insert := func(id, rawMessages, metadata string) {
+		t.Helper()
+		now := time.Now().UTC().Format(time.RFC3339Nano)
+		_, err := database.ExecContext(ctx, `
+			INSERT INTO conversations (id, raw_messages, provider, usage, summary, created_at, updated_at, metadata, tool_results)
+			VALUES (?, ?, 'anthropic', '{}', 'summary of '||?, ?, ?, ?, '{}')
+			ON CONFLICT(id) DO UPDATE SET raw_messages = excluded.raw_messages, metadata = excluded.metadata
+		`, id, rawMessages, id, now, now, metadata)
+		require.NoError(t, err)
+	}
+	search := func(query string) []string {
+		t.Helper()
+		var ids []string
+		require.NoError(t, sqlx.SelectContext(ctx, database, &ids,
+			`SELECT id FROM conversation_search WHERE conversation_search MATCH ? ORDER BY id`, query))
+		return ids
+	}
+
+	require.NoError(t, runner.Run(ctx, all[:len(all)-1]))
+	insert("conv-old", `[{"role":"user","content":[{"type":"text","text":"connection refused on port 5432"}]}]`, `{}`)
+
+	require.NoError(t, runner.Run(ctx, all))
+	assert.Equal(t, []string{"conv-old"}, search(`"connection refused"`))
+
+	insert("conv-new", `[{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"bash","input":{"command":"go test ./pkg/queue"}}]}]`,
+		`{"compacted_transcript":[{"role":"user","content":"we debugged the flaky queue test"}]}`)
+	insert("conv-bad", `not json`, `not json`)
+	assert.Equal(t, []string{"conv-new"}, search(`"pkg/queue"`))
+	assert.Equal(t, []string{"conv-new"}, search(`debug`))
+	assert.Empty(t, search(`toolu_1`))
+	assert.Equal(t, []string{"conv-bad", "conv-new", "conv-old"}, search(`summary`))
+
+	insert("conv-old", `[{"role":"user","content":"nothing to see"}]`, `{}`)
+	assert.Empty(t, search(`"connection refused"`))
+
+	_, err := database.ExecContext(ctx, `DELETE FROM conversations WHERE id = ?`, "conv-new")
+	require.NoError(t, err)
+	assert.Empty(t, search(`debug`))
+}
+
 func TestColumnMigrationsAreIdempotentWhenColumnsAlreadyExist(t *testing.T) {
 	ctx := context.Background()
 	database := openMigrationsTestDB(t)
@@ -202,6 +269,12 @@ func TestMigrationFunctionsReturnTransactionErrors(t *testing.T) {
 		{"cwd down", Migration20260331120000AddCWDToConversations().Down},
 		{"steering messages up", Migration20260719170000CreateSteeringMessages().Up},
 		{"steering messages down", Migration20260719170000CreateSteeringMessages().Down},
+		{"conversation runs up", Migration20261016120000CreateConversationRuns().Up},
+		{"conversation runs down", Migration20261016120000CreateConversationRuns().Down},
+		{"conversation search up", Migration20261016150000CreateConversationSearch().Up},
+		{"conversation search down", Migration20261016150000CreateConversationSearch().Down},
+		{"extension events up", Migration20261016170000CreateExtensionEvents().Up},
+		{"extension events down", Migration20261016170000CreateExtensionEvents().Down},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			err := tt.run(closedTx(t))
@@ -217,6 +290,30 @@ func TestMigrationsDownFunctions(t *testing.T) {
 	runner := db.NewMigrationRunner(database)
 	require.NoError(t, runner.Run(ctx, All()))
 
+	// Rate limits rollback drops its table.
+	require.NoError(t, runner.Rollback(ctx, All()))
+	assertTableMissing(t, database.DB, "rate_limits")
+
+	// Conversation journal rollback drops its table.
+	require.NoError(t, runner.Rollback(ctx, All()))
+	assertTableMissing(t, database.DB, "conversation_journal")
+
+	// Conversation appends rollback drops its log table.
+	require.NoError(t, runner.Rollback(ctx, All()))
+	assertTableMissing(t, database.DB, "conversation_appends")
+
+	// Extension events rollback drops the queue table.
+	require.NoError(t, runner.Rollback(ctx, All()))
+	assertTableMissing(t, database.DB, "extension_events")
+
+	// Conversation search rollback drops the index and its triggers.
+	require.NoError(t, runner.Rollback(ctx, All()))
+	assertTableMissing(t, database.DB, "conversation_search")
+
+	// Conversation runs rollback drops its table.
+	require.NoError(t, runner.Rollback(ctx, All()))
+	assertTableMissing(t, database.DB, "conversation_runs")
+
 	// Steering rollback drops its queue table.
 	require.NoError(t, runner.Rollback(ctx, All()))
 	assertTableMissing(t, database.DB, "steering_messages")
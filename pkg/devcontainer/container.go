@@ -0,0 +1,259 @@
+package devcontainer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+// workspaceLabel marks containers started by kodelet with the host workspace
+// they mount.
+const workspaceLabel = "dev.kodelet.workspace"
+
+// runDocker runs a docker CLI command and returns its trimmed stdout. It is a
+// variable so tests can fake the docker CLI.
+var runDocker = func(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrapf(err, "docker %s failed: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Container is a running dev container with the workspace mounted.
+type Container struct {
+	// Name is the docker container name.
+	Name   string
+	config *Config
+	// created records that this process started the container, so Stop
+	// removes it. A container that was already running is left alone.
+	created bool
+}
+
+// ContainerName returns the deterministic container name for a workspace, so
+// concurrent runs in the same workspace share one container.
+func ContainerName(workspaceDir string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(workspaceDir)))
+	return "kodelet-devcontainer-" + hex.EncodeToString(sum[:6])
+}
+
+// Start reuses the workspace's running dev container or starts a new one,
+// building the image first when the configuration uses a Dockerfile.
+// postCreateCommand runs once, in a newly created container.
+func Start(ctx context.Context, config *Config) (*Container, error) {
+	container := &Container{Name: ContainerName(config.WorkspaceDir), config: config}
+	log := logger.G(ctx).WithField("container", container.Name)
+
+	state, err := runDocker(ctx, "inspect", "--format", "{{.State.Running}}", container.Name)
+	switch {
+	case err == nil && state == "true":
+		log.Debug("reusing running dev container")
+		return container, nil
+	case err == nil:
+		log.Debug("removing stopped dev container")
+		if _, err := runDocker(ctx, "rm", "-f", container.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	image, err := container.image(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		"run", "--detach", "--init",
+		"--name", container.Name,
+		"--label", workspaceLabel + "=" + config.WorkspaceDir,
+		"--mount", "type=bind,source=" + config.WorkspaceDir + ",target=" + config.WorkspaceFolder,
+		"--workdir", config.WorkspaceFolder,
+	}
+	if config.ContainerUser != "" {
+		args = append(args, "--user", config.ContainerUser)
+	}
+	args = append(args, envArgs(config.ContainerEnv)...)
+	args = append(args, config.RunArgs...)
+	args = append(args, "--entrypoint", "sleep", image, "infinity")
+	if _, err := runDocker(ctx, args...); err != nil {
+		return nil, errors.Wrap(err, "failed to start dev container")
+	}
+	container.created = true
+	log.WithField("image", image).Info("started dev container")
+
+	for _, command := range config.PostCreateCommands() {
+		log.WithField("command", command).Info("running dev container postCreateCommand")
+		if _, err := runDocker(ctx, append(container.execArgs(config.WorkspaceFolder), "bash", "-c", command)...); err != nil {
+			_ = container.Stop(context.WithoutCancel(ctx))
+			return nil, errors.Wrap(err, "dev container postCreateCommand failed")
+		}
+	}
+	return container, nil
+}
+
+// image returns the configured image, building it from the Dockerfile when
+// there is none.
+func (c *Container) image(ctx context.Context) (string, error) {
+	if c.config.Image != "" {
+		return c.config.Image, nil
+	}
+
+	configDir := filepath.Dir(c.config.Path)
+	buildContext := c.config.Build.Context
+	if buildContext == "" {
+		buildContext = "."
+	}
+	tag := c.Name + ":latest"
+	args := []string{"build", "--tag", tag, "--file", filepath.Join(configDir, c.config.Build.Dockerfile)}
+	for _, name := range sortedKeys(c.config.Build.Args) {
+		args = append(args, "--build-arg", name+"="+c.config.Build.Args[name])
+	}
+	args = append(args, filepath.Join(configDir, buildContext))
+
+	logger.G(ctx).WithField("dockerfile", c.config.Build.Dockerfile).Info("building dev container image")
+	if _, err := runDocker(ctx, args...); err != nil {
+		return "", errors.Wrap(err, "failed to build dev container image")
+	}
+	return tag, nil
+}
+
+// Stop removes the container if this process started it.
+func (c *Container) Stop(ctx context.Context) error {
+	if c == nil || !c.created {
+		return nil
+	}
+	c.created = false
+	_, err := runDocker(ctx, "rm", "--force", c.Name)
+	return err
+}
+
+// WorkspaceDir returns the host directory mounted into the container.
+func (c *Container) WorkspaceDir() string {
+	return c.config.WorkspaceDir
+}
+
+// WorkspaceFolder returns where the workspace is mounted in the container.
+func (c *Container) WorkspaceFolder() string {
+	return c.config.WorkspaceFolder
+}
+
+// ContainerPath maps a host path inside the workspace to its path in the
+// container. Paths outside the workspace are not visible in the container.
+func (c *Container) ContainerPath(hostPath string) (string, bool) {
+	rel, err := filepath.Rel(c.config.WorkspaceDir, hostPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return path.Join(c.config.WorkspaceFolder, filepath.ToSlash(rel)), true
+}
+
+// HostPath maps a container path inside the workspace folder back to the
+// host.
+func (c *Container) HostPath(containerPath string) (string, bool) {
+	folder := path.Clean(c.config.WorkspaceFolder)
+	containerPath = path.Clean(containerPath)
+	if containerPath != folder && !strings.HasPrefix(containerPath, folder+"/") {
+		return "", false
+	}
+	return filepath.Join(c.config.WorkspaceDir, filepath.FromSlash(strings.TrimPrefix(containerPath, folder))), true
+}
+
+// Command returns a docker exec command running script with bash in the
+// container. hostDir is mapped into the container and falls back to the
+// workspace folder when it lies outside the workspace. The variables named in
+// passEnv are copied from the environment of the docker command, so their
+// values do not appear on its command line.
+//
+// Killing docker exec leaves what it started running in the container, so the
+// script runs there in its own process group under timeout, which outlives
+// ctx's deadline by execKillGrace. Cancelling ctx kills that group.
+func (c *Container) Command(ctx context.Context, hostDir, script string, passEnv ...string) *exec.Cmd {
+	dir, ok := c.ContainerPath(hostDir)
+	if !ok {
+		dir = c.config.WorkspaceFolder
+	}
+	limit := time.Duration(0)
+	if deadline, ok := ctx.Deadline(); ok {
+		limit = max(time.Until(deadline), 0) + execKillGrace
+	}
+	pidFile := execPIDFile()
+
+	seconds := strconv.FormatInt(int64(math.Ceil(limit.Seconds())), 10)
+	args := append(c.execArgs(dir, passEnv...), "bash", "-c", execScript, pidFile, script, seconds)
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Cancel = func() error {
+		c.killExec(pidFile)
+		return cmd.Process.Kill()
+	}
+	return cmd
+}
+
+// execScript starts the script ($1) under timeout, which leads its own
+// process group, and records the group in the PID file ($0) while it runs.
+const execScript = `timeout -k 5 "$2" bash -c "$1" & pid=$!; echo "$pid" >"$0"; wait "$pid"; status=$?; rm -f "$0"; exit "$status"`
+
+// execKillGrace is how long the in-container timeout waits past the caller's
+// deadline, so the caller times out and kills the command first.
+const execKillGrace = 5 * time.Second
+
+// execArgs returns the docker exec arguments up to the container name.
+func (c *Container) execArgs(dir string, passEnv ...string) []string {
+	args := []string{"exec", "--workdir", dir}
+	if user := c.config.User(); user != "" {
+		args = append(args, "--user", user)
+	}
+	args = append(args, envArgs(c.config.RemoteEnv)...)
+	for _, name := range passEnv {
+		args = append(args, "--env", name)
+	}
+	return append(args, c.Name)
+}
+
+// killExec terminates the process group of a command started by Command,
+// escalating to SIGKILL when it outlives the grace period.
+func (c *Container) killExec(pidFile string) {
+	ctx, cancel := context.WithTimeout(context.Background(), execKillGrace+5*time.Second)
+	defer cancel()
+	script := `pid=$(cat "$0" 2>/dev/null) || exit 0; kill -TERM "-$pid" 2>/dev/null || exit 0; sleep 2; kill -KILL "-$pid" 2>/dev/null; rm -f "$0"`
+	if _, err := runDocker(ctx, "exec", c.Name, "sh", "-c", script, pidFile); err != nil {
+		logger.G(ctx).WithError(err).WithField("container", c.Name).Warn("failed to stop the command in the dev container")
+	}
+}
+
+func execPIDFile() string {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	return "/tmp/kodelet-exec-" + hex.EncodeToString(id[:]) + ".pid"
+}
+
+func envArgs(env map[string]string) []string {
+	var args []string
+	for _, name := range sortedKeys(env) {
+		args = append(args, "--env", name+"="+env[name])
+	}
+	return args
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
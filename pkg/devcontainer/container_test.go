@@ -0,0 +1,140 @@
+package devcontainer
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fakeDocker(t *testing.T, respond func(args []string) (string, error)) *[]string {
+	t.Helper()
+	var calls []string
+	original := runDocker
+	runDocker = func(_ context.Context, args ...string) (string, error) {
+		calls = append(calls, strings.Join(args, " "))
+		return respond(args)
+	}
+	t.Cleanup(func() { runDocker = original })
+	return &calls
+}
+
+func testConfig(t *testing.T) *Config {
+	dir := t.TempDir()
+	config := &Config{
+		Image:             "golang:1.25",
+		WorkspaceFolder:   "/workspaces/app",
+		RemoteUser:        "dev",
+		RemoteEnv:         map[string]string{"CI": "1"},
+		PostCreateCommand: "go mod download",
+		Path:              filepath.Join(dir, ".devcontainer", "devcontainer.json"),
+		WorkspaceDir:      dir,
+	}
+	return config
+}
+
+func TestStartCreatesContainerAndStopRemovesIt(t *testing.T) {
+	config := testConfig(t)
+	name := ContainerName(config.WorkspaceDir)
+	calls := fakeDocker(t, func(args []string) (string, error) {
+		if args[0] == "inspect" {
+			return "", errors.New("no such container")
+		}
+		return "", nil
+	})
+
+	container, err := Start(context.Background(), config)
+	require.NoError(t, err)
+	require.Len(t, *calls, 3)
+	assert.Equal(t, "run --detach --init --name "+name+" --label dev.kodelet.workspace="+config.WorkspaceDir+
+		" --mount type=bind,source="+config.WorkspaceDir+",target=/workspaces/app --workdir /workspaces/app --entrypoint sleep golang:1.25 infinity", (*calls)[1])
+	assert.Equal(t, "exec --workdir /workspaces/app --user dev --env CI=1 "+name+" bash -c go mod download", (*calls)[2])
+
+	require.NoError(t, container.Stop(context.Background()))
+	require.NoError(t, container.Stop(context.Background()))
+	assert.Equal(t, []string{"rm --force " + name}, (*calls)[3:])
+}
+
+func TestStartReusesRunningContainer(t *testing.T) {
+	config := testConfig(t)
+	calls := fakeDocker(t, func(args []string) (string, error) {
+		return "true", nil
+	})
+
+	container, err := Start(context.Background(), config)
+	require.NoError(t, err)
+	require.NoError(t, container.Stop(context.Background()))
+	assert.Len(t, *calls, 1, "a container that was already running is neither set up nor removed")
+}
+
+func TestStartBuildsDockerfileImage(t *testing.T) {
+	config := testConfig(t)
+	config.Image = ""
+	config.PostCreateCommand = nil
+	config.Build.Dockerfile = "Dockerfile"
+	config.Build.Context = ".."
+	config.Build.Args = map[string]string{"GO_VERSION": "1.25"}
+	calls := fakeDocker(t, func(args []string) (string, error) {
+		if args[0] == "inspect" {
+			return "false", nil
+		}
+		return "", nil
+	})
+
+	_, err := Start(context.Background(), config)
+	require.NoError(t, err)
+	name := ContainerName(config.WorkspaceDir)
+	require.Len(t, *calls, 4)
+	assert.Equal(t, "rm -f "+name, (*calls)[1])
+	assert.Equal(t, "build --tag "+name+":latest --file "+filepath.Join(config.WorkspaceDir, ".devcontainer", "Dockerfile")+
+		" --build-arg GO_VERSION=1.25 "+config.WorkspaceDir, (*calls)[2])
+	assert.Contains(t, (*calls)[3], name+":latest infinity")
+}
+
+func TestPathMappingAndCommand(t *testing.T) {
+	config := testConfig(t)
+	container := &Container{Name: "box", config: config}
+
+	inside, ok := container.ContainerPath(filepath.Join(config.WorkspaceDir, "pkg", "api"))
+	assert.True(t, ok)
+	assert.Equal(t, "/workspaces/app/pkg/api", inside)
+	_, ok = container.ContainerPath(filepath.Dir(config.WorkspaceDir))
+	assert.False(t, ok)
+
+	host, ok := container.HostPath("/workspaces/app/pkg/api")
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(config.WorkspaceDir, "pkg", "api"), host)
+	_, ok = container.HostPath("/workspaces/application")
+	assert.False(t, ok)
+
+	cmd := container.Command(context.Background(), "/elsewhere", "make test")
+	assert.Equal(t, []string{"docker", "exec", "--workdir", "/workspaces/app", "--user", "dev", "--env", "CI=1", "box", "bash", "-c", execScript}, cmd.Args[:12])
+	assert.Regexp(t, `^/tmp/kodelet-exec-[0-9a-f]{16}\.pid$`, cmd.Args[12])
+	assert.Equal(t, []string{"make test", "0"}, cmd.Args[13:], "no deadline runs without a time limit")
+	assert.NotNil(t, cmd.Cancel)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cmd = container.Command(ctx, "/elsewhere", "make test")
+	assert.Equal(t, "35", cmd.Args[14], "the container limit outlives the deadline")
+
+	cmd = container.Command(context.Background(), "/elsewhere", "make deploy", "DEPLOY_TOKEN")
+	assert.Equal(t, []string{"--env", "DEPLOY_TOKEN", "box"}, cmd.Args[8:11], "passed variables are named without their value")
+}
+
+func TestKillExecStopsTheProcessGroupInTheContainer(t *testing.T) {
+	calls := fakeDocker(t, func([]string) (string, error) { return "", nil })
+	container := &Container{Name: "box", config: testConfig(t)}
+
+	container.killExec("/tmp/kodelet-exec-1.pid")
+
+	require.Len(t, *calls, 1)
+	assert.True(t, strings.HasPrefix((*calls)[0], "exec box sh -c "))
+	assert.Contains(t, (*calls)[0], `kill -TERM "-$pid"`)
+	assert.True(t, strings.HasSuffix((*calls)[0], " /tmp/kodelet-exec-1.pid"))
+}
@@ -0,0 +1,196 @@
+// Package devcontainer runs commands inside a repository's dev container.
+// It reads the subset of devcontainer.json needed to start the container with
+// docker, keeps the workspace bind mounted at the configured workspace folder,
+// and maps paths between the host and the container.
+package devcontainer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ConfigPaths are the locations searched for a dev container configuration,
+// relative to the workspace root, in priority order.
+var ConfigPaths = []string{
+	filepath.Join(".devcontainer", "devcontainer.json"),
+	".devcontainer.json",
+}
+
+// Config is the part of devcontainer.json that kodelet understands.
+type Config struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+	Build struct {
+		Dockerfile string            `json:"dockerfile"`
+		Context    string            `json:"context"`
+		Args       map[string]string `json:"args"`
+	} `json:"build"`
+	WorkspaceFolder   string            `json:"workspaceFolder"`
+	RemoteUser        string            `json:"remoteUser"`
+	ContainerUser     string            `json:"containerUser"`
+	ContainerEnv      map[string]string `json:"containerEnv"`
+	RemoteEnv         map[string]string `json:"remoteEnv"`
+	RunArgs           []string          `json:"runArgs"`
+	PostCreateCommand any               `json:"postCreateCommand"`
+
+	// Path is the devcontainer.json the config was read from.
+	Path string `json:"-"`
+	// WorkspaceDir is the host directory mounted into the container.
+	WorkspaceDir string `json:"-"`
+}
+
+// Find returns the dev container configuration for the workspace at dir, or
+// an empty string when the repository has none.
+func Find(dir string) string {
+	for _, rel := range ConfigPaths {
+		path := filepath.Join(dir, rel)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// Load reads the dev container configuration for the workspace at dir.
+func Load(dir string) (*Config, error) {
+	path := Find(dir)
+	if path == "" {
+		return nil, errors.Errorf("no dev container configuration found in %s (looked for %s)", dir, strings.Join(ConfigPaths, ", "))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+
+	config := &Config{}
+	if err := json.Unmarshal(stripJSONC(data), config); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", path)
+	}
+	config.Path = path
+	config.WorkspaceDir = dir
+
+	if config.Image == "" && config.Build.Dockerfile == "" {
+		return nil, errors.Errorf("%s must set image or build.dockerfile; docker compose based dev containers are not supported", path)
+	}
+	if config.WorkspaceFolder == "" {
+		config.WorkspaceFolder = "/workspaces/" + filepath.Base(dir)
+	}
+	return config, nil
+}
+
+// User returns the user commands run as, or an empty string for the image
+// default.
+func (c *Config) User() string {
+	if c.RemoteUser != "" {
+		return c.RemoteUser
+	}
+	return c.ContainerUser
+}
+
+// PostCreateCommands returns postCreateCommand as shell commands. The string
+// and array forms become one command, and the object form one command per
+// entry.
+func (c *Config) PostCreateCommands() []string {
+	switch command := c.PostCreateCommand.(type) {
+	case string:
+		if strings.TrimSpace(command) != "" {
+			return []string{command}
+		}
+	case []any:
+		if args := stringArgs(command); len(args) > 0 {
+			return []string{shellJoin(args)}
+		}
+	case map[string]any:
+		names := make([]string, 0, len(command))
+		for name := range command {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		var commands []string
+		for _, name := range names {
+			commands = append(commands, (&Config{PostCreateCommand: command[name]}).PostCreateCommands()...)
+		}
+		return commands
+	}
+	return nil
+}
+
+// stripJSONC removes comments and trailing commas, which devcontainer.json
+// allows, so the result parses as JSON.
+func stripJSONC(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString, escaped := false, false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			out = append(out, c)
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i+1 < len(data) && (data[i] != '*' || data[i+1] != '/') {
+				i++
+			}
+			i++
+		case c == ']' || c == '}':
+			last := len(out) - 1
+			for last >= 0 && isJSONSpace(out[last]) {
+				last--
+			}
+			if last >= 0 && out[last] == ',' {
+				out = append(out[:last], out[last+1:]...)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func stringArgs(values []any) []string {
+	args := make([]string, 0, len(values))
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			args = append(args, s)
+		}
+	}
+	return args
+}
+
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,52 @@
+package devcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadParsesJSONC(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".devcontainer"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".devcontainer", "devcontainer.json"), []byte(`{
+	// The toolchain image
+	"name": "kodelet",
+	"image": "mcr.microsoft.com/devcontainers/go:1", /* pinned */
+	"remoteUser": "vscode",
+	"containerEnv": {"GOFLAGS": "-mod=mod", "DOCS": "https://example.com/a//b",},
+	"postCreateCommand": ["go", "mod", "download"],
+}`), 0o644))
+
+	config, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "mcr.microsoft.com/devcontainers/go:1", config.Image)
+	assert.Equal(t, "vscode", config.User())
+	assert.Equal(t, map[string]string{"GOFLAGS": "-mod=mod", "DOCS": "https://example.com/a//b"}, config.ContainerEnv)
+	assert.Equal(t, "/workspaces/"+filepath.Base(dir), config.WorkspaceFolder)
+	assert.Equal(t, []string{"'go' 'mod' 'download'"}, config.PostCreateCommands())
+	assert.Equal(t, filepath.Join(dir, ".devcontainer", "devcontainer.json"), config.Path)
+}
+
+func TestLoadRequiresImageOrDockerfile(t *testing.T) {
+	dir := t.TempDir()
+	_, err := Load(dir)
+	assert.ErrorContains(t, err, "no dev container configuration found")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".devcontainer.json"), []byte(`{"dockerComposeFile": "compose.yml"}`), 0o644))
+	assert.Equal(t, filepath.Join(dir, ".devcontainer.json"), Find(dir))
+	_, err = Load(dir)
+	assert.ErrorContains(t, err, "docker compose based dev containers are not supported")
+}
+
+func TestPostCreateCommands(t *testing.T) {
+	assert.Nil(t, (&Config{}).PostCreateCommands())
+	assert.Equal(t, []string{"make deps"}, (&Config{PostCreateCommand: "make deps"}).PostCreateCommands())
+	assert.Equal(t, []string{"npm ci", "'echo' 'it'\\''s ready'"}, (&Config{PostCreateCommand: map[string]any{
+		"b": []any{"echo", "it's ready"},
+		"a": "npm ci",
+	}}).PostCreateCommands())
+}
@@ -85,6 +85,16 @@ func FromApplyPatchChange(change tooltypes.ApplyPatchChange) FileDiff {
 	return file
 }
 
+// FromFileEditMetadata returns the diff of a file_edit result. It has no
+// lines when the result predates recording the diff of the whole file.
+func FromFileEditMetadata(meta tooltypes.FileEditMetadata) FileDiff {
+	return FromApplyPatchChange(tooltypes.ApplyPatchChange{
+		Path:        meta.FilePath,
+		Operation:   tooltypes.ApplyPatchOperationUpdate,
+		UnifiedDiff: meta.UnifiedDiff,
+	})
+}
+
 func (f FileDiff) DisplayPath() string {
 	if strings.TrimSpace(f.MovePath) != "" {
 		return fmt.Sprintf("%s → %s", f.Path, f.MovePath)
@@ -184,6 +194,52 @@ func RenderedText(lines []RenderedLine) string {
 	return strings.TrimSuffix(strings.Join(parts, "\n"), "\n")
 }
 
+// SideBySideRow is one row of a side-by-side diff. Left is the old line and
+// Right the new one; either is nil when the row only exists on one side.
+type SideBySideRow struct {
+	Left  *Line
+	Right *Line
+}
+
+// SideBySide pairs the lines of a diff into rows, matching each run of
+// removed lines with the added lines that follow it. Header and meta lines
+// are dropped.
+func SideBySide(lines []Line) []SideBySideRow {
+	var rows []SideBySideRow
+	var removed, added []Line
+	flush := func() {
+		for i := 0; i < max(len(removed), len(added)); i++ {
+			row := SideBySideRow{}
+			if i < len(removed) {
+				row.Left = &removed[i]
+			}
+			if i < len(added) {
+				row.Right = &added[i]
+			}
+			rows = append(rows, row)
+		}
+		removed, added = nil, nil
+	}
+
+	for i := range lines {
+		line := lines[i]
+		switch line.Kind {
+		case LineRemoved:
+			if len(added) > 0 {
+				flush()
+			}
+			removed = append(removed, line)
+		case LineAdded:
+			added = append(added, line)
+		case LineContext:
+			flush()
+			rows = append(rows, SideBySideRow{Left: &line, Right: &line})
+		}
+	}
+	flush()
+	return rows
+}
+
 func linesForChange(change tooltypes.ApplyPatchChange) []Line {
 	if strings.TrimSpace(change.UnifiedDiff) != "" {
 		return parseUnifiedDiff(change.UnifiedDiff)
@@ -52,3 +52,26 @@ func TestRenderFileBodyWidthWrapsLongLinesWithGutterContinuation(t *testing.T) {
 		assert.LessOrEqual(t, displayWidth(line.Text), 12)
 	}
 }
+
+func TestSideBySidePairsRemovedAndAddedRuns(t *testing.T) {
+	rows := SideBySide([]Line{
+		{Kind: LineHeader, Content: "@@ -1,4 +1,4 @@"},
+		{Kind: LineContext, OldLine: 1, NewLine: 1, Content: "same"},
+		{Kind: LineRemoved, OldLine: 2, Content: "old"},
+		{Kind: LineRemoved, OldLine: 3, Content: "gone"},
+		{Kind: LineAdded, NewLine: 2, Content: "new"},
+		{Kind: LineContext, OldLine: 4, NewLine: 3, Content: "tail"},
+		{Kind: LineAdded, NewLine: 4, Content: "extra"},
+	})
+
+	require.Len(t, rows, 5)
+	assert.Equal(t, "same", rows[0].Left.Content)
+	assert.Equal(t, "same", rows[0].Right.Content)
+	assert.Equal(t, "old", rows[1].Left.Content)
+	assert.Equal(t, "new", rows[1].Right.Content)
+	assert.Equal(t, "gone", rows[2].Left.Content)
+	assert.Nil(t, rows[2].Right)
+	assert.Equal(t, 4, rows[3].Left.OldLine)
+	assert.Nil(t, rows[4].Left)
+	assert.Equal(t, "extra", rows[4].Right.Content)
+}
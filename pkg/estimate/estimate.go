@@ -0,0 +1,214 @@
+// Package estimate predicts the turns, tokens and cost of a run before it
+// starts, from past runs of the same kind when there are enough of them and
+// otherwise from the size of the repository and of the prompt sent on every
+// turn.
+package estimate
+
+import (
+	"math"
+	"sort"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// MinSimilarRuns is the number of past runs needed to estimate from history
+// rather than from the heuristic.
+const MinSimilarRuns = 3
+
+// Assumptions of the heuristic about each turn after the first.
+const (
+	// outputTokensPerTurn is what the model writes per turn, tool calls
+	// included.
+	outputTokensPerTurn = 800
+	// toolTokensPerTurn is the tool output the agent reads per turn.
+	toolTokensPerTurn = 2500
+	// compactionRatio is the share of the context window a conversation
+	// grows to before it is compacted.
+	compactionRatio = 0.8
+)
+
+// Quantiles of the past runs reported as the low and high ends of a range.
+const (
+	lowQuantile  = 0.25
+	highQuantile = 0.75
+)
+
+// Basis of an estimate.
+const (
+	BasisHistory   = "history"
+	BasisHeuristic = "heuristic"
+)
+
+// Workload describes the run to estimate.
+type Workload struct {
+	RepoFiles int   `json:"repo_files"`
+	RepoBytes int64 `json:"repo_bytes"`
+	// ContextTokens is the size of the discovered context files.
+	ContextTokens int `json:"context_tokens"`
+	// PromptTokens is the size of what every turn sends before the
+	// conversation itself: the system prompt with the context files, the
+	// tool definitions and the prompt.
+	PromptTokens int `json:"prompt_tokens"`
+}
+
+// PastRun is the usage of a past conversation similar to the run.
+type PastRun struct {
+	Turns int
+	Usage llmtypes.Usage
+}
+
+// Model is a model to estimate the run with.
+type Model struct {
+	Name string
+	// Pricing is nil when the price of the model is not known.
+	Pricing *llmtypes.ModelPricing
+}
+
+// Range is the low and high ends of an estimate.
+type Range[T int | float64] struct {
+	Low  T `json:"low"`
+	High T `json:"high"`
+}
+
+// ModelEstimate is the estimate of the run with one model.
+type ModelEstimate struct {
+	Model  string          `json:"model"`
+	Turns  Range[int]      `json:"turns"`
+	Tokens Range[int]      `json:"tokens"`
+	Cost   *Range[float64] `json:"cost_usd,omitempty"`
+}
+
+// Report is the estimate of a run with each model.
+type Report struct {
+	Workload
+	Basis       string          `json:"basis"`
+	SimilarRuns int             `json:"similar_runs"`
+	Estimates   []ModelEstimate `json:"estimates"`
+}
+
+// Estimate estimates the run with each model. With at least MinSimilarRuns
+// past runs, the ranges span the middle half of those runs, priced for each
+// model; otherwise they come from the heuristic.
+func Estimate(workload Workload, history []PastRun, models []Model) Report {
+	report := Report{Workload: workload, Basis: BasisHeuristic, SimilarRuns: len(history)}
+	if len(history) >= MinSimilarRuns {
+		report.Basis = BasisHistory
+	}
+
+	for _, model := range models {
+		if report.Basis == BasisHistory {
+			report.Estimates = append(report.Estimates, estimateRuns(model, history, lowQuantile, highQuantile))
+		} else {
+			report.Estimates = append(report.Estimates, estimateRuns(model, heuristicRuns(workload, model), 0, 1))
+		}
+	}
+	return report
+}
+
+// estimateRuns spans the low and high quantiles of runs.
+func estimateRuns(model Model, runs []PastRun, low, high float64) ModelEstimate {
+	turns := make([]float64, 0, len(runs))
+	tokens := make([]float64, 0, len(runs))
+	costs := make([]float64, 0, len(runs))
+	for _, run := range runs {
+		turns = append(turns, float64(run.Turns))
+		tokens = append(tokens, float64(run.Usage.TotalTokens()))
+		if model.Pricing != nil {
+			costs = append(costs, Cost(run.Usage, *model.Pricing))
+		}
+	}
+
+	estimate := ModelEstimate{
+		Model:  model.Name,
+		Turns:  Range[int]{Low: int(math.Round(quantile(turns, low))), High: int(math.Round(quantile(turns, high)))},
+		Tokens: Range[int]{Low: int(quantile(tokens, low)), High: int(quantile(tokens, high))},
+	}
+	if model.Pricing != nil {
+		estimate.Cost = &Range[float64]{Low: quantile(costs, low), High: quantile(costs, high)}
+	}
+	return estimate
+}
+
+// Cost prices usage with pricing. Models without separate cache rates are
+// charged their input rate for cached tokens.
+func Cost(usage llmtypes.Usage, pricing llmtypes.ModelPricing) float64 {
+	cacheWrite := pricing.CacheWriteInput
+	if cacheWrite == 0 {
+		cacheWrite = pricing.Input
+	}
+	cacheRead := pricing.CachedInput
+	if cacheRead == 0 {
+		cacheRead = pricing.Input
+	}
+	return float64(usage.InputTokens)*pricing.Input +
+		float64(usage.OutputTokens)*pricing.Output +
+		float64(usage.CacheCreationInputTokens)*cacheWrite +
+		float64(usage.CacheReadInputTokens)*cacheRead
+}
+
+// heuristicRuns returns the shortest and the longest run the heuristic
+// expects. Larger repositories take more turns to explore.
+func heuristicRuns(workload Workload, model Model) []PastRun {
+	low, high := heuristicTurns(workload.RepoFiles)
+	contextWindow := 0
+	if model.Pricing != nil {
+		contextWindow = model.Pricing.ContextWindow
+	}
+	return []PastRun{
+		{Turns: low, Usage: simulateUsage(low, workload.PromptTokens, contextWindow)},
+		{Turns: high, Usage: simulateUsage(high, workload.PromptTokens, contextWindow)},
+	}
+}
+
+func heuristicTurns(repoFiles int) (int, int) {
+	switch {
+	case repoFiles <= 100:
+		return 3, 10
+	case repoFiles <= 1000:
+		return 5, 20
+	case repoFiles <= 10000:
+		return 8, 30
+	default:
+		return 10, 40
+	}
+}
+
+// simulateUsage returns the usage of a run of turns that sends promptTokens
+// on every turn and grows by one turn of output and tool results per turn.
+// Each turn reads the previous turn's context from the prompt cache and
+// writes what is new. Compaction keeps the context below the window.
+func simulateUsage(turns, promptTokens, contextWindow int) llmtypes.Usage {
+	const growth = outputTokensPerTurn + toolTokensPerTurn
+	limit := math.MaxInt
+	if contextWindow > 0 {
+		limit = max(int(float64(contextWindow)*compactionRatio), promptTokens+growth)
+	}
+
+	var usage llmtypes.Usage
+	previous := 0
+	for turn := range turns {
+		context := min(promptTokens+turn*growth, limit)
+		cached := 0
+		if turn > 0 {
+			cached = max(min(previous, context-growth), 0)
+		}
+		usage.CacheReadInputTokens += cached
+		usage.CacheCreationInputTokens += context - cached
+		usage.OutputTokens += outputTokensPerTurn
+		previous = context
+	}
+	return usage
+}
+
+// quantile returns the q-quantile of values by linear interpolation.
+func quantile(values []float64, q float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
@@ -0,0 +1,153 @@
+package estimate
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPricing = llmtypes.ModelPricing{
+	Input:           0.000003,
+	CachedInput:     0.0000003,
+	CacheWriteInput: 0.00000375,
+	Output:          0.000015,
+	ContextWindow:   200000,
+}
+
+func TestCost(t *testing.T) {
+	usage := llmtypes.Usage{InputTokens: 1000, OutputTokens: 100, CacheCreationInputTokens: 2000, CacheReadInputTokens: 10000}
+	assert.InDelta(t, 0.003+0.0015+0.0075+0.003, Cost(usage, testPricing), 1e-9)
+
+	// Without cache rates, cached tokens are charged as input.
+	assert.InDelta(t, 0.039+0.0015, Cost(usage, llmtypes.ModelPricing{Input: 0.000003, Output: 0.000015}), 1e-9)
+}
+
+func TestEstimateFromHistory(t *testing.T) {
+	history := []PastRun{
+		{Turns: 2, Usage: llmtypes.Usage{InputTokens: 1000}},
+		{Turns: 4, Usage: llmtypes.Usage{InputTokens: 2000}},
+		{Turns: 6, Usage: llmtypes.Usage{InputTokens: 3000}},
+		{Turns: 8, Usage: llmtypes.Usage{InputTokens: 4000}},
+		{Turns: 10, Usage: llmtypes.Usage{InputTokens: 5000}},
+	}
+	report := Estimate(Workload{RepoFiles: 10}, history, []Model{{Name: "priced", Pricing: &testPricing}, {Name: "unpriced"}})
+
+	assert.Equal(t, BasisHistory, report.Basis)
+	assert.Equal(t, 5, report.SimilarRuns)
+	require.Len(t, report.Estimates, 2)
+	assert.Equal(t, Range[int]{Low: 4, High: 8}, report.Estimates[0].Turns)
+	assert.Equal(t, Range[int]{Low: 2000, High: 4000}, report.Estimates[0].Tokens)
+	require.NotNil(t, report.Estimates[0].Cost)
+	assert.InDelta(t, 0.006, report.Estimates[0].Cost.Low, 1e-9)
+	assert.InDelta(t, 0.012, report.Estimates[0].Cost.High, 1e-9)
+	assert.Nil(t, report.Estimates[1].Cost)
+}
+
+func TestEstimateFromHeuristic(t *testing.T) {
+	history := []PastRun{{Turns: 50, Usage: llmtypes.Usage{InputTokens: 1}}}
+	small := Estimate(Workload{RepoFiles: 50, PromptTokens: 10000}, history, []Model{{Name: "m", Pricing: &testPricing}})
+	large := Estimate(Workload{RepoFiles: 50000, PromptTokens: 10000}, nil, []Model{{Name: "m", Pricing: &testPricing}})
+
+	assert.Equal(t, BasisHeuristic, small.Basis)
+	assert.Equal(t, 1, small.SimilarRuns)
+	assert.Equal(t, Range[int]{Low: 3, High: 10}, small.Estimates[0].Turns)
+	assert.Equal(t, Range[int]{Low: 10, High: 40}, large.Estimates[0].Turns)
+	assert.Less(t, small.Estimates[0].Tokens.Low, small.Estimates[0].Tokens.High)
+	assert.Less(t, small.Estimates[0].Cost.High, large.Estimates[0].Cost.High)
+}
+
+func TestSimulateUsage(t *testing.T) {
+	usage := simulateUsage(3, 1000, 0)
+	// Turns send 1000, 4300 and 7600 tokens, each caching what the previous
+	// one sent.
+	assert.Equal(t, 1000+4300, usage.CacheReadInputTokens)
+	assert.Equal(t, 1000+3300+3300, usage.CacheCreationInputTokens)
+	assert.Equal(t, 3*outputTokensPerTurn, usage.OutputTokens)
+
+	compacted := simulateUsage(40, 1000, 20000)
+	assert.LessOrEqual(t, compacted.TotalTokens(), 40*(16000+outputTokensPerTurn))
+}
+
+func TestQuantile(t *testing.T) {
+	values := []float64{40, 10, 30, 20}
+	assert.Equal(t, 10.0, quantile(values, 0))
+	assert.Equal(t, 40.0, quantile(values, 1))
+	assert.Equal(t, 25.0, quantile(values, 0.5))
+	assert.Equal(t, 0.0, quantile(nil, 0.5))
+}
+
+type fakeQuerier struct {
+	summaries []convtypes.ConversationSummary
+	queries   []convtypes.QueryOptions
+}
+
+func (f *fakeQuerier) Query(_ context.Context, options convtypes.QueryOptions) (convtypes.QueryResult, error) {
+	f.queries = append(f.queries, options)
+	var result convtypes.QueryResult
+	for _, summary := range f.summaries {
+		if options.CWD == "" || summary.CWD == options.CWD {
+			result.ConversationSummaries = append(result.ConversationSummaries, summary)
+		}
+	}
+	return result, nil
+}
+
+func summary(cwd, recipe string, messages, tokens int) convtypes.ConversationSummary {
+	s := convtypes.ConversationSummary{CWD: cwd, MessageCount: messages, Usage: llmtypes.Usage{InputTokens: tokens}}
+	if recipe != "" {
+		s.Metadata = map[string]any{convtypes.RecipeNameMetadataKey: recipe}
+	}
+	return s
+}
+
+func TestSimilarRuns(t *testing.T) {
+	store := &fakeQuerier{summaries: []convtypes.ConversationSummary{
+		summary("/repo", "", 6, 100),
+		summary("/repo", "", 0, 0),
+		summary("/repo", "review", 4, 200),
+		summary("/other", "review", 2, 300),
+		summary("/other", "review", 8, 400),
+	}}
+
+	history, err := SimilarRuns(context.Background(), store, HistoryOptions{ProjectRoot: "/repo"})
+	require.NoError(t, err)
+	assert.Equal(t, []PastRun{{Turns: 3, Usage: llmtypes.Usage{InputTokens: 100}}}, history.Runs)
+	assert.False(t, history.AllProjects)
+	assert.True(t, store.queries[0].CWDSubdirs)
+
+	history, err = SimilarRuns(context.Background(), store, HistoryOptions{ProjectRoot: "/repo", Recipe: "review"})
+	require.NoError(t, err)
+	assert.True(t, history.AllProjects)
+	assert.Len(t, history.Runs, 3)
+}
+
+func TestMeasureRepository(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".cache"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".cache", "blob"), []byte("ignored"), 0o644))
+
+	files, size, err := MeasureRepository(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, 1, files)
+	assert.Equal(t, int64(13), size)
+}
+
+func TestWriteReport(t *testing.T) {
+	report := Estimate(Workload{RepoFiles: 1200, RepoBytes: 5000000, ContextTokens: 800, PromptTokens: 9000}, nil,
+		[]Model{{Name: "priced", Pricing: &testPricing}, {Name: "unpriced"}})
+
+	var out bytes.Buffer
+	WriteReport(&out, report)
+	assert.Contains(t, out.String(), "Repository: 1,200 files, 5,000,000 bytes.")
+	assert.Contains(t, out.String(), "0 similar past runs, 3 needed")
+	assert.Contains(t, out.String(), "priced    8 - 30")
+	assert.Regexp(t, `unpriced\s+8 - 30\s+[\d,]+ - [\d,]+\s+unknown`, out.String())
+}
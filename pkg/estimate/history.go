@@ -0,0 +1,87 @@
+package estimate
+
+import (
+	"context"
+
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	"github.com/pkg/errors"
+)
+
+const (
+	// historyScanLimit bounds the past conversations searched for similar
+	// runs.
+	historyScanLimit = 500
+	// maxSimilarRuns is how many of the most recent similar runs are used.
+	maxSimilarRuns = 20
+)
+
+// ConversationQuerier queries saved conversations.
+type ConversationQuerier interface {
+	Query(ctx context.Context, options convtypes.QueryOptions) (convtypes.QueryResult, error)
+}
+
+// HistoryOptions selects the past runs similar to a run.
+type HistoryOptions struct {
+	// ProjectRoot limits the runs to a project. Empty means every project.
+	ProjectRoot string
+	// Recipe is the recipe the run uses. Runs without a recipe are only
+	// similar to other runs without one.
+	Recipe string
+}
+
+// History is the past runs similar to a run.
+type History struct {
+	Runs []PastRun
+	// AllProjects is set when the project had too few runs of the recipe and
+	// runs of it in every project are used instead.
+	AllProjects bool
+}
+
+// SimilarRuns returns the most recent past runs of the same recipe in the
+// project. When the project has fewer than MinSimilarRuns runs of a recipe,
+// runs of it in every project are used instead.
+func SimilarRuns(ctx context.Context, store ConversationQuerier, opts HistoryOptions) (History, error) {
+	runs, err := similarRuns(ctx, store, opts.ProjectRoot, opts.Recipe)
+	if err != nil {
+		return History{}, err
+	}
+	if len(runs) >= MinSimilarRuns || opts.Recipe == "" || opts.ProjectRoot == "" {
+		return History{Runs: runs}, nil
+	}
+
+	everywhere, err := similarRuns(ctx, store, "", opts.Recipe)
+	if err != nil {
+		return History{}, err
+	}
+	if len(everywhere) > len(runs) {
+		return History{Runs: everywhere, AllProjects: true}, nil
+	}
+	return History{Runs: runs}, nil
+}
+
+func similarRuns(ctx context.Context, store ConversationQuerier, projectRoot, recipe string) ([]PastRun, error) {
+	result, err := store.Query(ctx, convtypes.QueryOptions{
+		CWD:        projectRoot,
+		CWDSubdirs: projectRoot != "",
+		SortBy:     "updated",
+		SortOrder:  "desc",
+		Limit:      historyScanLimit,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query past conversations")
+	}
+
+	var runs []PastRun
+	for _, summary := range result.ConversationSummaries {
+		name, _ := summary.Metadata[convtypes.RecipeNameMetadataKey].(string)
+		if name != recipe || summary.Usage.TotalTokens() == 0 {
+			continue
+		}
+		// A turn is a user message and the assistant's reply.
+		runs = append(runs, PastRun{Turns: max(summary.MessageCount/2, 1), Usage: summary.Usage})
+		if len(runs) == maxSimilarRuns {
+			break
+		}
+	}
+	return runs, nil
+}
@@ -0,0 +1,43 @@
+package estimate
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/jingkaihe/kodelet/pkg/usage"
+)
+
+// WriteReport writes one row per model, after a line describing what the
+// estimate is based on.
+func WriteReport(w io.Writer, report Report) {
+	fmt.Fprintf(w, "Repository: %s files, %s bytes. Context files: ~%s tokens. Sent every turn: ~%s tokens.\n",
+		usage.FormatNumber(report.RepoFiles),
+		usage.FormatNumber(int(report.RepoBytes)),
+		usage.FormatNumber(report.ContextTokens),
+		usage.FormatNumber(report.PromptTokens),
+	)
+	if report.Basis == BasisHistory {
+		fmt.Fprintf(w, "Based on %d similar past runs.\n\n", report.SimilarRuns)
+	} else {
+		fmt.Fprintf(w, "Based on the repository and prompt size (%d similar past runs, %d needed to use history).\n\n", report.SimilarRuns, MinSimilarRuns)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Model\tTurns\tTokens\tCost")
+	for _, estimate := range report.Estimates {
+		cost := "unknown"
+		if estimate.Cost != nil {
+			cost = usage.FormatCost(estimate.Cost.Low) + " - " + usage.FormatCost(estimate.Cost.High)
+		}
+		fmt.Fprintf(tw, "%s\t%d - %d\t%s - %s\t%s\n",
+			estimate.Model,
+			estimate.Turns.Low,
+			estimate.Turns.High,
+			usage.FormatNumber(estimate.Tokens.Low),
+			usage.FormatNumber(estimate.Tokens.High),
+			cost,
+		)
+	}
+	tw.Flush()
+}
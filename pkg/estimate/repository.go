@@ -0,0 +1,64 @@
+package estimate
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// maxWalkedFiles bounds the files counted outside a git repository.
+const maxWalkedFiles = 100000
+
+// MeasureRepository returns the number and total size of the files in dir.
+// In a git repository these are the files git does not ignore; elsewhere,
+// hidden directories are skipped.
+func MeasureRepository(ctx context.Context, dir string) (int, int64, error) {
+	cmd := exec.CommandContext(ctx, "git", "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	cmd.Dir = dir
+	if output, err := cmd.Output(); err == nil {
+		var files int
+		var size int64
+		for _, name := range bytes.Split(output, []byte{0}) {
+			if len(name) == 0 {
+				continue
+			}
+			info, err := os.Lstat(filepath.Join(dir, string(name)))
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			files++
+			size += info.Size()
+		}
+		return files, size, nil
+	}
+
+	var files int
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if path != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			files++
+			size += info.Size()
+		}
+		if files >= maxWalkedFiles {
+			return filepath.SkipAll
+		}
+		return ctx.Err()
+	})
+	return files, size, err
+}
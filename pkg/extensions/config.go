@@ -8,6 +8,7 @@ import (
 
 	"github.com/go-viper/mapstructure/v2"
 	"github.com/jingkaihe/kodelet/pkg/logger"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	"github.com/spf13/viper"
 )
 
@@ -25,19 +26,42 @@ type Config struct {
 	Allow         []string              `mapstructure:"allow" json:"allow" yaml:"allow"`
 	Deny          []string              `mapstructure:"deny" json:"deny" yaml:"deny"`
 	Tools         map[string]ToolConfig `mapstructure:"tools" json:"tools" yaml:"tools"`
+
+	// EventTimeout applies to event handlers that do not declare timeoutInSec.
+	EventTimeout time.Duration `mapstructure:"event_timeout" json:"event_timeout" yaml:"event_timeout"`
+	// MaxEventTimeout caps every event handler timeout, including handlers
+	// declaring no timeout, so a stuck extension cannot stall the agent loop.
+	// It is off by default so handlers keep the timeout they declare.
+	MaxEventTimeout time.Duration `mapstructure:"max_event_timeout" json:"max_event_timeout" yaml:"max_event_timeout"`
+	// MaxMessageSize caps a single JSON-RPC message read from an extension.
+	// Larger messages fail the call and restart the extension.
+	MaxMessageSize int `mapstructure:"max_message_size" json:"max_message_size" yaml:"max_message_size"`
+	// ScrubEnv starts extensions with a minimal environment instead of
+	// inheriting Kodelet's, keeping API keys and tokens out of reach.
+	ScrubEnv bool `mapstructure:"scrub_env" json:"scrub_env" yaml:"scrub_env"`
+	// EnvAllow lists extra environment variables passed through when ScrubEnv
+	// is set.
+	EnvAllow []string `mapstructure:"env_allow" json:"env_allow" yaml:"env_allow"`
+	// EventMaxAttempts is how many times a durable event is delivered before
+	// it is marked failed. Zero retries until the event is handled.
+	EventMaxAttempts int `mapstructure:"event_max_attempts" json:"event_max_attempts" yaml:"event_max_attempts"`
 }
 
 // DefaultConfig returns the default extension runtime configuration.
 func DefaultConfig() Config {
 	return Config{
-		Enabled:       true,
-		GlobalDir:     "~/.kodelet/extensions",
-		LocalDir:      "./.kodelet/extensions",
-		MaxOutputSize: 102400,
+		Enabled:          true,
+		GlobalDir:        "~/.kodelet/extensions",
+		LocalDir:         "./.kodelet/extensions",
+		MaxOutputSize:    102400,
+		EventTimeout:     30 * time.Second,
+		MaxMessageSize:   8 << 20,
+		EventMaxAttempts: 5,
 	}
 }
 
-// LoadConfigFromViper loads extension configuration from viper.
+// LoadConfigFromViper loads extension configuration from viper, including
+// the extensions settings of the active profile and the profiles it extends.
 func LoadConfigFromViper() Config {
 	config := DefaultConfig()
 	if viper.IsSet("extensions") {
@@ -45,12 +69,61 @@ func LoadConfigFromViper() Config {
 			logger.G(context.Background()).WithError(err).Warn("failed to load extensions config, using defaults")
 		}
 	}
+
+	for _, settings := range activeProfileExtensionSettings() {
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook:       extensionConfigDecodeHook(),
+			WeaklyTypedInput: true,
+			Result:           &config,
+		})
+		if err == nil {
+			err = decoder.Decode(settings)
+		}
+		if err != nil {
+			logger.G(context.Background()).WithError(err).Warn("failed to load profile extensions config")
+		}
+	}
 	return config
 }
 
+// activeProfileExtensionSettings returns the extensions settings of the
+// active profile's inheritance chain, ancestors first.
+func activeProfileExtensionSettings() []any {
+	profileName := viper.GetString("profile")
+	if profileName == "" || profileName == "default" {
+		return nil
+	}
+
+	profiles := make(map[string]llmtypes.ProfileConfig)
+	for name, raw := range viper.GetStringMap("profiles") {
+		if profile, ok := raw.(map[string]any); ok {
+			profiles[name] = llmtypes.ProfileConfig(profile)
+		}
+	}
+	if _, ok := profiles[profileName]; !ok {
+		return nil
+	}
+
+	chain, err := llmtypes.ProfileChain(profiles, profileName)
+	if err != nil {
+		logger.G(context.Background()).WithError(err).Warn("failed to resolve profile for extensions config")
+		return nil
+	}
+
+	var settings []any
+	for _, profile := range chain {
+		if value, ok := profile["extensions"]; ok && value != nil {
+			settings = append(settings, value)
+		}
+	}
+	return settings
+}
+
 func extensionConfigDecodeHook() mapstructure.DecodeHookFunc {
 	return mapstructure.ComposeDecodeHookFunc(
+		llmtypes.JSONStringHook,
 		mapstructure.StringToSliceHookFunc(","),
+		mapstructure.StringToTimeDurationHookFunc(),
 	)
 }
 
@@ -55,6 +55,33 @@ func TestLoadConfigFromViperLoadsExtensionConfig(t *testing.T) {
 	assert.Equal(t, &requireFalse, config.Tools["get_weather"].Enabled)
 }
 
+func TestLoadConfigFromViperAppliesActiveProfileExtensions(t *testing.T) {
+	originalSettings := viper.AllSettings()
+	defer restoreViperSettings(originalSettings)
+	viper.Reset()
+	viper.Set("extensions", map[string]any{
+		"local_dir": "./local-ext",
+		"deny":      []string{"org@repo/blocked"},
+	})
+	viper.Set("profile", "prod-ops")
+	viper.Set("profiles", map[string]any{
+		"ops": map[string]any{
+			"extensions": map[string]any{"allow": []string{"./ops-ext"}},
+		},
+		"prod-ops": map[string]any{
+			"extends":    "ops",
+			"extensions": map[string]any{"enabled": false},
+		},
+	})
+
+	config := LoadConfigFromViper()
+
+	assert.False(t, config.Enabled)
+	assert.Equal(t, "./local-ext", config.LocalDir)
+	assert.Equal(t, []string{"./ops-ext"}, config.Allow)
+	assert.Equal(t, []string{"org@repo/blocked"}, config.Deny)
+}
+
 func TestExtensionConfigHasNoTimeoutConfigSurface(t *testing.T) {
 	typeOfConfig := reflect.TypeOf(Config{})
 	_, hasTimeout := typeOfConfig.FieldByName("Timeout")
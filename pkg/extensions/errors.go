@@ -0,0 +1,81 @@
+package extensions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// EventErrorKind classifies how an extension event handler misbehaved.
+type EventErrorKind string
+
+const (
+	// EventErrorTimeout means the handler did not answer within its timeout.
+	EventErrorTimeout EventErrorKind = "timeout"
+	// EventErrorOversized means the extension sent a message larger than
+	// extensions.max_message_size.
+	EventErrorOversized EventErrorKind = "oversized"
+	// EventErrorDisabled means the extension was disabled after repeated
+	// failures.
+	EventErrorDisabled EventErrorKind = "disabled"
+	// EventErrorHandler means the handler returned a JSON-RPC error.
+	EventErrorHandler EventErrorKind = "handler"
+	// EventErrorProcess means the extension process exited or broke the
+	// protocol.
+	EventErrorProcess EventErrorKind = "process"
+)
+
+// errMessageTooLarge is returned when an extension sends a JSON-RPC message
+// over the configured size limit.
+var errMessageTooLarge = errors.New("extension rpc message exceeds max_message_size")
+
+// errExtensionDisabled is returned for calls to an extension disabled after
+// repeated failures.
+var errExtensionDisabled = errors.New("disabled after repeated failures")
+
+// EventError reports an extension event handler that failed, timed out or
+// broke the protocol. Failed handlers are skipped; the event carries on with
+// the remaining handlers.
+type EventError struct {
+	Extension string
+	Event     string
+	Kind      EventErrorKind
+	Timeout   time.Duration
+	Err       error
+}
+
+func (e *EventError) Error() string {
+	if e.Kind == EventErrorTimeout {
+		return fmt.Sprintf("extension %s %s handler timed out after %s", e.Extension, e.Event, e.Timeout)
+	}
+	return fmt.Sprintf("extension %s %s handler failed (%s): %v", e.Extension, e.Event, e.Kind, e.Err)
+}
+
+func (e *EventError) Unwrap() error {
+	return e.Err
+}
+
+func newEventError(handler eventHandler, eventName string, timeout time.Duration, err error) *EventError {
+	eventErr := &EventError{
+		Event:   eventName,
+		Kind:    EventErrorProcess,
+		Timeout: timeout,
+		Err:     err,
+	}
+	if handler.process != nil {
+		eventErr.Extension = handler.process.Extension.ID
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		eventErr.Kind = EventErrorTimeout
+	case errors.Is(err, errMessageTooLarge):
+		eventErr.Kind = EventErrorOversized
+	case errors.Is(err, errExtensionDisabled):
+		eventErr.Kind = EventErrorDisabled
+	case isExtensionRPCError(err):
+		eventErr.Kind = EventErrorHandler
+	}
+	return eventErr
+}
@@ -0,0 +1,39 @@
+package extensions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEventErrorClassifiesFailures(t *testing.T) {
+	handler := eventHandler{process: &Process{Extension: Extension{ID: "guard"}}}
+
+	tests := []struct {
+		name    string
+		err     error
+		kind    EventErrorKind
+		message string
+	}{
+		{name: "timeout", err: context.DeadlineExceeded, kind: EventErrorTimeout, message: "extension guard user.message handler timed out after 5s"},
+		{name: "oversized", err: errors.Wrap(errMessageTooLarge, "too big"), kind: EventErrorOversized},
+		{name: "disabled", err: errors.Wrap(errExtensionDisabled, "extension guard"), kind: EventErrorDisabled},
+		{name: "handler", err: errors.New("extension rpc error -32000: boom"), kind: EventErrorHandler, message: "extension guard user.message handler failed (handler): extension rpc error -32000: boom"},
+		{name: "process", err: errors.New("failed to read rpc header: EOF"), kind: EventErrorProcess},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventErr := newEventError(handler, EventUserMessage, 5*time.Second, tt.err)
+
+			assert.Equal(t, tt.kind, eventErr.Kind)
+			assert.Equal(t, "guard", eventErr.Extension)
+			assert.ErrorIs(t, eventErr, tt.err)
+			if tt.message != "" {
+				assert.Equal(t, tt.message, eventErr.Error())
+			}
+		})
+	}
+}
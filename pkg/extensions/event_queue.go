@@ -0,0 +1,340 @@
+package extensions
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/db"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Delivery states of a durable extension event.
+const (
+	EventDeliveryPending   = "pending"
+	EventDeliveryDelivered = "delivered"
+	EventDeliveryFailed    = "failed"
+)
+
+// deliveredEventRetention is how long delivered events stay in the queue for
+// `kodelet extension events` before they are pruned.
+const deliveredEventRetention = 30 * 24 * time.Hour
+
+// errEventDeferred is returned by a Drain delivery function when the
+// extension cannot take events right now. The event stays pending without
+// using up an attempt.
+var errEventDeferred = errors.New("extension unavailable; delivery deferred")
+
+// durableEvents are the observational events an extension may subscribe to
+// durably. Events whose result changes what the agent does are always
+// delivered synchronously and never queued.
+var durableEvents = map[string]struct{}{
+	EventSessionStart:      {},
+	EventResourcesDiscover: {},
+	EventAgentStart:        {},
+	EventTurnStart:         {},
+	EventTurnEnd:           {},
+	EventContextCompacted:  {},
+	EventSessionEnd:        {},
+}
+
+// EventDelivery is a durable extension event and its delivery status.
+type EventDelivery struct {
+	ID          string               `json:"id" db:"id"`
+	ExtensionID string               `json:"extension_id" db:"extension_id"`
+	Event       string               `json:"event" db:"event"`
+	Payload     json.RawMessage      `json:"payload" db:"-"`
+	Context     ExtensionCallContext `json:"context" db:"-"`
+	Status      string               `json:"status" db:"status"`
+	Attempts    int                  `json:"attempts" db:"attempts"`
+	LastError   string               `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt   time.Time            `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at" db:"updated_at"`
+	DeliveredAt *time.Time           `json:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+type dbEventDelivery struct {
+	EventDelivery
+	Seq            int64  `db:"seq"`
+	RawPayload     string `db:"payload"`
+	RawCallContext string `db:"call_context"`
+}
+
+// EventDeliveryFilter narrows the events returned by EventQueue.List.
+type EventDeliveryFilter struct {
+	ExtensionID string
+	Status      string
+	Limit       int
+}
+
+// EventQueue persists durable extension events in Kodelet's shared SQLite
+// database, so events an extension failed to handle are retried in order
+// instead of being dropped.
+type EventQueue struct {
+	db *sqlx.DB
+}
+
+// OpenEventQueue opens the event queue in the database at dbPath, or the
+// default database when dbPath is empty. Database migrations must be applied
+// before the queue is used.
+func OpenEventQueue(ctx context.Context, dbPath string) (*EventQueue, error) {
+	if strings.TrimSpace(dbPath) == "" {
+		defaultPath, err := db.DefaultDBPath()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to resolve extension event queue path")
+		}
+		dbPath = defaultPath
+	}
+
+	database, err := db.Open(ctx, dbPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open extension event queue")
+	}
+	return &EventQueue{db: database}, nil
+}
+
+// Close releases the queue's database connection.
+func (q *EventQueue) Close() error {
+	if q == nil || q.db == nil {
+		return nil
+	}
+	return q.db.Close()
+}
+
+// Enqueue records a pending event for an extension.
+func (q *EventQueue) Enqueue(ctx context.Context, delivery EventDelivery) error {
+	callContext, err := json.Marshal(delivery.Context)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal extension event context")
+	}
+	now := time.Now().UTC()
+	if _, err := q.db.ExecContext(ctx, `
+		INSERT INTO extension_events (id, extension_id, event, payload, call_context, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, delivery.ID, delivery.ExtensionID, delivery.Event, string(delivery.Payload), string(callContext), EventDeliveryPending, now, now); err != nil {
+		return errors.Wrap(err, "failed to enqueue extension event")
+	}
+	return nil
+}
+
+// Drain delivers an extension's pending events oldest first. It stops at the
+// first event that fails and still has attempts left, so later events are
+// never handled before earlier ones. An event that fails maxAttempts times is
+// marked failed and skipped. Extensions receive the event's original ID on
+// every attempt, so they can ignore an event they have already handled.
+func (q *EventQueue) Drain(ctx context.Context, extensionID string, maxAttempts int, deliver func(context.Context, EventDelivery) error) error {
+	pending, err := q.List(ctx, EventDeliveryFilter{ExtensionID: extensionID, Status: EventDeliveryPending})
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range pending {
+		deliverErr := deliver(ctx, delivery)
+		if errors.Is(deliverErr, errEventDeferred) {
+			return nil
+		}
+		if deliverErr == nil {
+			if err := q.markDelivered(ctx, delivery.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		status := EventDeliveryPending
+		if maxAttempts > 0 && delivery.Attempts+1 >= maxAttempts {
+			status = EventDeliveryFailed
+		}
+		if err := q.markAttemptFailed(ctx, delivery.ID, status, deliverErr); err != nil {
+			return err
+		}
+		if status == EventDeliveryPending {
+			return nil
+		}
+	}
+	return nil
+}
+
+// List returns queued events oldest first.
+func (q *EventQueue) List(ctx context.Context, filter EventDeliveryFilter) ([]EventDelivery, error) {
+	var conditions []string
+	var args []any
+	if filter.ExtensionID != "" {
+		conditions = append(conditions, "extension_id = ?")
+		args = append(args, filter.ExtensionID)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query := `SELECT seq, id, extension_id, event, payload, call_context, status, attempts,
+		last_error, created_at, updated_at, delivered_at FROM extension_events` + where + " ORDER BY seq ASC"
+	if filter.Limit > 0 {
+		// Keep the most recent events, still listed oldest first.
+		query = `SELECT * FROM (SELECT seq, id, extension_id, event, payload, call_context, status, attempts,
+			last_error, created_at, updated_at, delivered_at FROM extension_events` + where + ` ORDER BY seq DESC LIMIT ?)
+			ORDER BY seq ASC`
+		args = append(args, filter.Limit)
+	}
+
+	var rows []dbEventDelivery
+	if err := q.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, errors.Wrap(err, "failed to list extension events")
+	}
+
+	deliveries := make([]EventDelivery, 0, len(rows))
+	for _, row := range rows {
+		delivery := row.EventDelivery
+		delivery.Payload = json.RawMessage(row.RawPayload)
+		if err := json.Unmarshal([]byte(row.RawCallContext), &delivery.Context); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse context of extension event %s", delivery.ID)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// Requeue returns failed events to the queue with their attempts reset, so
+// they are redelivered the next time their extension runs. It returns the
+// number of events requeued.
+func (q *EventQueue) Requeue(ctx context.Context, extensionID string) (int, error) {
+	query := "UPDATE extension_events SET status = ?, attempts = 0, updated_at = ? WHERE status = ?"
+	args := []any{EventDeliveryPending, time.Now().UTC(), EventDeliveryFailed}
+	if extensionID != "" {
+		query += " AND extension_id = ?"
+		args = append(args, extensionID)
+	}
+	result, err := q.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to requeue extension events")
+	}
+	count, err := result.RowsAffected()
+	return int(count), errors.Wrap(err, "failed to count requeued extension events")
+}
+
+// Prune removes events delivered before the given time.
+func (q *EventQueue) Prune(ctx context.Context, before time.Time) error {
+	_, err := q.db.ExecContext(ctx, "DELETE FROM extension_events WHERE status = ? AND delivered_at < ?", EventDeliveryDelivered, before.UTC())
+	return errors.Wrap(err, "failed to prune delivered extension events")
+}
+
+func (q *EventQueue) markDelivered(ctx context.Context, id string) error {
+	now := time.Now().UTC()
+	_, err := q.db.ExecContext(ctx, `
+		UPDATE extension_events
+		SET status = ?, attempts = attempts + 1, last_error = '', updated_at = ?, delivered_at = ?
+		WHERE id = ?
+	`, EventDeliveryDelivered, now, now, id)
+	return errors.Wrap(err, "failed to mark extension event delivered")
+}
+
+func (q *EventQueue) markAttemptFailed(ctx context.Context, id, status string, deliverErr error) error {
+	_, err := q.db.ExecContext(ctx, `
+		UPDATE extension_events
+		SET status = ?, attempts = attempts + 1, last_error = ?, updated_at = ?
+		WHERE id = ?
+	`, status, deliverErr.Error(), time.Now().UTC(), id)
+	return errors.Wrap(err, "failed to record extension event failure")
+}
+
+// openEventQueue opens the event queue when an extension subscribed to an
+// event durably, and redelivers events left pending by earlier sessions. If
+// the queue cannot be opened, durable events are delivered like any other.
+func (r *Runtime) openEventQueue(ctx context.Context) {
+	var durable []*Process
+	seen := map[*Process]struct{}{}
+	for eventName := range durableEvents {
+		for _, handler := range r.eventHandlers(eventName) {
+			if _, ok := seen[handler.process]; ok || !handler.sub.Durable || handler.process == nil {
+				continue
+			}
+			seen[handler.process] = struct{}{}
+			durable = append(durable, handler.process)
+		}
+	}
+	if len(durable) == 0 {
+		return
+	}
+
+	queue, err := OpenEventQueue(ctx, "")
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("extension event queue unavailable; durable events are delivered once")
+		return
+	}
+	if err := queue.Prune(ctx, time.Now().Add(-deliveredEventRetention)); err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to prune extension event queue")
+	}
+	r.eventQueue = queue
+
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+	for _, proc := range durable {
+		r.drainEventQueue(ctx, proc)
+	}
+}
+
+// dispatchDurableEvent queues an event for a durable subscription and
+// delivers the extension's pending events in order. It reports false when the
+// event should be delivered directly instead.
+func (r *Runtime) dispatchDurableEvent(ctx context.Context, handler eventHandler, eventName string, payload any, callContext ExtensionCallContext) bool {
+	if r.eventQueue == nil || !handler.sub.Durable || handler.process == nil {
+		return false
+	}
+	if _, ok := durableEvents[eventName]; !ok {
+		return false
+	}
+
+	rawPayload, err := json.Marshal(payload)
+	if err != nil {
+		logger.G(ctx).WithError(err).WithField("event", eventName).Warn("failed to marshal durable extension event")
+		return false
+	}
+
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+	delivery := EventDelivery{
+		ID:          nextEventID(),
+		ExtensionID: handler.process.Extension.ID,
+		Event:       eventName,
+		Payload:     rawPayload,
+		Context:     callContext,
+	}
+	if err := r.eventQueue.Enqueue(ctx, delivery); err != nil {
+		logger.G(ctx).WithError(err).WithField("extension", delivery.ExtensionID).WithField("event", eventName).Warn("failed to queue durable extension event")
+		return false
+	}
+	r.drainEventQueue(ctx, handler.process)
+	return true
+}
+
+// drainEventQueue delivers a process's pending events. The caller holds
+// queueMu.
+func (r *Runtime) drainEventQueue(ctx context.Context, proc *Process) {
+	err := r.eventQueue.Drain(ctx, proc.Extension.ID, r.config.EventMaxAttempts, func(ctx context.Context, delivery EventDelivery) error {
+		handler := eventHandler{process: proc, sub: Subscription{Event: delivery.Event}}
+		for _, candidate := range r.eventHandlers(delivery.Event) {
+			if candidate.process == proc {
+				handler = candidate
+				break
+			}
+		}
+		_, err := r.dispatchEventIDToHandler(ctx, handler, delivery.ID, delivery.Event, delivery.Payload, delivery.Context)
+		if errors.Is(err, errExtensionDisabled) {
+			return errEventDeferred
+		}
+		if err != nil {
+			logger.G(ctx).WithError(err).WithField("extension", delivery.ExtensionID).WithField("event", delivery.Event).Warn("durable extension event delivery failed; will retry")
+		}
+		return err
+	})
+	if err != nil {
+		logger.G(ctx).WithError(err).WithField("extension", proc.Extension.ID).Warn("failed to deliver queued extension events")
+	}
+}
@@ -0,0 +1,189 @@
+package extensions
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/db"
+	"github.com/jingkaihe/kodelet/pkg/db/migrations"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func migrateEventQueueDB(t *testing.T, dbPath string) {
+	t.Helper()
+	ctx := context.Background()
+	database, err := db.Open(ctx, dbPath)
+	require.NoError(t, err)
+	require.NoError(t, db.NewMigrationRunner(database).Run(ctx, migrations.All()))
+	require.NoError(t, database.Close())
+}
+
+func openTestEventQueue(t *testing.T) *EventQueue {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "storage.db")
+	migrateEventQueueDB(t, dbPath)
+	queue, err := OpenEventQueue(context.Background(), dbPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = queue.Close() })
+	return queue
+}
+
+func enqueueTestEvents(t *testing.T, queue *EventQueue, extensionID string, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		require.NoError(t, queue.Enqueue(context.Background(), EventDelivery{
+			ID:          id,
+			ExtensionID: extensionID,
+			Event:       EventTurnEnd,
+			Payload:     json.RawMessage(`{"turnNumber":1}`),
+			Context:     ExtensionCallContext{ConversationID: "conv-1", InvokedBy: "main"},
+		}))
+	}
+}
+
+func TestEventQueueDrainDeliversInOrderAndStopsAtFailure(t *testing.T) {
+	ctx := context.Background()
+	queue := openTestEventQueue(t)
+	enqueueTestEvents(t, queue, "audit", "evt-1", "evt-2", "evt-3")
+	enqueueTestEvents(t, queue, "other", "evt-other")
+
+	var delivered []string
+	failing := "evt-2"
+	deliver := func(_ context.Context, delivery EventDelivery) error {
+		if delivery.ID == failing {
+			return errors.New("webhook returned 503")
+		}
+		assert.Equal(t, "conv-1", delivery.Context.ConversationID)
+		assert.JSONEq(t, `{"turnNumber":1}`, string(delivery.Payload))
+		delivered = append(delivered, delivery.ID)
+		return nil
+	}
+
+	require.NoError(t, queue.Drain(ctx, "audit", 5, deliver))
+	assert.Equal(t, []string{"evt-1"}, delivered, "later events wait for the failed one")
+
+	pending, err := queue.List(ctx, EventDeliveryFilter{ExtensionID: "audit", Status: EventDeliveryPending})
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, "evt-2", pending[0].ID)
+	assert.Equal(t, 1, pending[0].Attempts)
+	assert.Equal(t, "webhook returned 503", pending[0].LastError)
+
+	failing = ""
+	require.NoError(t, queue.Drain(ctx, "audit", 5, deliver))
+	assert.Equal(t, []string{"evt-1", "evt-2", "evt-3"}, delivered)
+
+	all, err := queue.List(ctx, EventDeliveryFilter{ExtensionID: "audit"})
+	require.NoError(t, err)
+	for _, delivery := range all {
+		assert.Equal(t, EventDeliveryDelivered, delivery.Status)
+		assert.NotNil(t, delivery.DeliveredAt)
+		assert.Empty(t, delivery.LastError)
+	}
+
+	other, err := queue.List(ctx, EventDeliveryFilter{ExtensionID: "other", Status: EventDeliveryPending})
+	require.NoError(t, err)
+	assert.Len(t, other, 1, "other extensions' events are untouched")
+}
+
+func TestEventQueueDrainMarksExhaustedEventsFailed(t *testing.T) {
+	ctx := context.Background()
+	queue := openTestEventQueue(t)
+	enqueueTestEvents(t, queue, "audit", "evt-1", "evt-2")
+
+	var delivered []string
+	deliver := func(_ context.Context, delivery EventDelivery) error {
+		if delivery.ID == "evt-1" {
+			return errors.New("bad payload")
+		}
+		delivered = append(delivered, delivery.ID)
+		return nil
+	}
+
+	require.NoError(t, queue.Drain(ctx, "audit", 2, deliver))
+	assert.Empty(t, delivered)
+	require.NoError(t, queue.Drain(ctx, "audit", 2, deliver))
+	assert.Equal(t, []string{"evt-2"}, delivered, "the queue moves on once an event has failed for good")
+
+	failed, err := queue.List(ctx, EventDeliveryFilter{Status: EventDeliveryFailed})
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.Equal(t, "evt-1", failed[0].ID)
+	assert.Equal(t, 2, failed[0].Attempts)
+
+	count, err := queue.Requeue(ctx, "audit")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	pending, err := queue.List(ctx, EventDeliveryFilter{Status: EventDeliveryPending})
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Zero(t, pending[0].Attempts)
+}
+
+func TestEventQueueDrainDeferredKeepsAttempts(t *testing.T) {
+	ctx := context.Background()
+	queue := openTestEventQueue(t)
+	enqueueTestEvents(t, queue, "audit", "evt-1")
+
+	require.NoError(t, queue.Drain(ctx, "audit", 1, func(context.Context, EventDelivery) error {
+		return errEventDeferred
+	}))
+
+	pending, err := queue.List(ctx, EventDeliveryFilter{Status: EventDeliveryPending})
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Zero(t, pending[0].Attempts)
+}
+
+func TestEventQueueListLimitAndPrune(t *testing.T) {
+	ctx := context.Background()
+	queue := openTestEventQueue(t)
+	enqueueTestEvents(t, queue, "audit", "evt-1", "evt-2", "evt-3")
+
+	latest, err := queue.List(ctx, EventDeliveryFilter{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, latest, 2)
+	assert.Equal(t, "evt-2", latest[0].ID)
+	assert.Equal(t, "evt-3", latest[1].ID)
+
+	require.NoError(t, queue.Drain(ctx, "audit", 5, func(context.Context, EventDelivery) error { return nil }))
+	require.NoError(t, queue.Prune(ctx, time.Now().Add(time.Minute)))
+	all, err := queue.List(ctx, EventDeliveryFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, all)
+}
+
+func TestRuntimeQueuesDurableEvents(t *testing.T) {
+	rootDir := t.TempDir()
+	basePath := t.TempDir()
+	t.Setenv("KODELET_BASE_PATH", basePath)
+	migrateEventQueueDB(t, filepath.Join(basePath, "storage.db"))
+	extDir := filepath.Join(rootDir, "events")
+	writeExecutable(t, filepath.Join(extDir, "kodelet-extension-events"), helperExtensionScript(t))
+
+	runtime, err := NewRuntime(
+		context.Background(),
+		WithConfig(DefaultConfig()),
+		WithWorkingDir(rootDir),
+		WithRoots(Root{Dir: rootDir, Kind: SourceKindLocalStandalone}),
+	)
+	require.NoError(t, err)
+	require.NotNil(t, runtime.eventQueue)
+	require.NoError(t, runtime.Close())
+
+	queue, err := OpenEventQueue(context.Background(), "")
+	require.NoError(t, err)
+	defer queue.Close()
+	deliveries, err := queue.List(context.Background(), EventDeliveryFilter{})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1, "only the durable session.start subscription is queued")
+	assert.Equal(t, EventSessionStart, deliveries[0].Event)
+	assert.Equal(t, "events", deliveries[0].ExtensionID)
+	assert.Equal(t, EventDeliveryDelivered, deliveries[0].Status)
+	assert.Equal(t, rootDir, deliveries[0].Context.CWD)
+}
@@ -36,6 +36,8 @@ const (
 	EventToolResult = "tool.result"
 	// EventTurnEnd is dispatched after one assistant turn completes.
 	EventTurnEnd = "turn.end"
+	// EventContextCompacted is dispatched after compaction replaces the conversation history with a summary.
+	EventContextCompacted = "context.compacted"
 	// EventAgentEnd is dispatched when an agent loop has completed.
 	EventAgentEnd = "agent.end"
 	// EventSessionEnd is dispatched when the extension runtime shuts down.
@@ -85,6 +87,11 @@ type turnEndPayload struct {
 	TurnNumber int    `json:"turnNumber"`
 }
 
+type contextCompactedPayload struct {
+	Summary  string             `json:"summary"`
+	Messages []llmtypes.Message `json:"messages"`
+}
+
 type agentEndPayload struct {
 	Messages []llmtypes.Message `json:"messages"`
 }
@@ -339,6 +346,17 @@ func (r *Runtime) DispatchTurnEnd(ctx context.Context, callContext ExtensionCall
 	r.dispatchObservationalEvent(ctx, EventTurnEnd, turnEndPayload{Response: response, TurnNumber: turnNumber}, callContext)
 }
 
+// DispatchContextCompacted runs context.compacted subscriptions with the
+// summary that replaced the history and the messages it replaced.
+func (r *Runtime) DispatchContextCompacted(ctx context.Context, callContext ExtensionCallContext, summary string, messages []llmtypes.Message) {
+	r.dispatchObservationalEvent(ctx, EventContextCompacted, contextCompactedPayload{Summary: summary, Messages: messages}, callContext)
+}
+
+// HasEventHandlers reports whether any extension subscribes to eventName.
+func (r *Runtime) HasEventHandlers(eventName string) bool {
+	return r != nil && len(r.eventHandlers(eventName)) > 0
+}
+
 // DispatchAgentEnd runs agent.end subscriptions and returns accumulated follow-up messages.
 func (r *Runtime) DispatchAgentEnd(ctx context.Context, callContext ExtensionCallContext, messages []llmtypes.Message) []string {
 	if r == nil {
@@ -370,6 +388,9 @@ func (r *Runtime) dispatchObservationalEvent(ctx context.Context, eventName stri
 		return
 	}
 	for _, handler := range r.eventHandlers(eventName) {
+		if r.dispatchDurableEvent(ctx, handler, eventName, payload, callContext) {
+			continue
+		}
 		if _, err := r.dispatchEventToHandler(ctx, handler, eventName, payload, callContext); err != nil {
 			logger.G(ctx).WithError(err).WithField("extension", handler.process.Extension.ID).WithField("event", eventName).Warn("extension event handler failed")
 		}
@@ -427,12 +448,53 @@ func applyToolListPatch(allowedTools []string, patch *ToolListPatch) []string {
 }
 
 func (r *Runtime) dispatchEventToHandler(ctx context.Context, handler eventHandler, eventName string, payload any, callContext ExtensionCallContext) (*EventResult, error) {
-	ctx, cancel := contextWithOptionalDuration(ctx, eventTimeout(handler))
+	return r.dispatchEventIDToHandler(ctx, handler, nextEventID(), eventName, payload, callContext)
+}
+
+func (r *Runtime) dispatchEventIDToHandler(ctx context.Context, handler eventHandler, eventID, eventName string, payload any, callContext ExtensionCallContext) (*EventResult, error) {
+	if handler.process == nil {
+		return &EventResult{}, nil
+	}
+	timeout := r.handlerTimeout(handler)
+	handlerCtx, cancel := contextWithOptionalDuration(ctx, timeout)
 	defer cancel()
-	if handler.process != nil {
-		return handler.process.HandleEvent(ctx, nextEventID(), eventName, payload, callContext)
+	result, err := handler.process.HandleEvent(handlerCtx, eventID, eventName, payload, callContext)
+	if err != nil {
+		eventErr := newEventError(handler, eventName, timeout, err)
+		reportEventError(ctx, eventErr)
+		return nil, eventErr
+	}
+	return result, nil
+}
+
+// reportEventError surfaces a misbehaving handler to interactive hosts.
+func reportEventError(ctx context.Context, eventErr *EventError) {
+	sink, ok := DiagnosticSinkFromContext(ctx)
+	if !ok {
+		return
+	}
+	sink.ReportDiagnostic(ctx, Diagnostic{
+		Level:     DiagnosticLevelWarning,
+		Extension: eventErr.Extension,
+		Message:   eventErr.Error(),
+		Fields: map[string]any{
+			"event": eventErr.Event,
+			"kind":  string(eventErr.Kind),
+		},
+	})
+}
+
+// handlerTimeout applies extensions.event_timeout and
+// extensions.max_event_timeout to the handler's declared timeout.
+func (r *Runtime) handlerTimeout(handler eventHandler) time.Duration {
+	timeout := eventTimeout(handler)
+	if handler.sub.TimeoutInSec == nil && r.config.EventTimeout > 0 {
+		timeout = r.config.EventTimeout
+	}
+	if limit := r.config.MaxEventTimeout; limit > 0 && (timeout <= 0 || timeout > limit) {
+		timeout = limit
 	}
-	return &EventResult{}, nil
+	return timeout
 }
 
 func eventTimeout(handler eventHandler) time.Duration {
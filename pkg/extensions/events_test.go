@@ -104,6 +104,23 @@ func TestEventTimeoutUsesSpecificAndDefaultTimeouts(t *testing.T) {
 	assert.Equal(t, 30*time.Second, eventTimeout(eventHandler{}))
 }
 
+func TestHandlerTimeoutAppliesConfiguredDefaultAndCap(t *testing.T) {
+	sdkTimeoutInSec := 3.0
+	sdkLongTimeoutInSec := 600.0
+	sdkNoTimeoutInSec := 0.0
+	runtime := EmptyRuntime()
+	runtime.config.EventTimeout = 10 * time.Second
+	runtime.config.MaxEventTimeout = time.Minute
+
+	assert.Equal(t, 3*time.Second, runtime.handlerTimeout(eventHandler{sub: Subscription{TimeoutInSec: &sdkTimeoutInSec}}))
+	assert.Equal(t, 10*time.Second, runtime.handlerTimeout(eventHandler{}))
+	assert.Equal(t, time.Minute, runtime.handlerTimeout(eventHandler{sub: Subscription{TimeoutInSec: &sdkLongTimeoutInSec}}))
+	assert.Equal(t, time.Minute, runtime.handlerTimeout(eventHandler{sub: Subscription{TimeoutInSec: &sdkNoTimeoutInSec}}))
+
+	runtime.config.MaxEventTimeout = 0
+	assert.Zero(t, runtime.handlerTimeout(eventHandler{sub: Subscription{TimeoutInSec: &sdkNoTimeoutInSec}}))
+}
+
 func TestNilRuntimeDispatchersReturnDefaults(t *testing.T) {
 	var runtime *Runtime
 	toolResult := tooltypes.StructuredToolResult{ToolName: "tool", Success: true}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -60,6 +61,9 @@ func (p *Process) start(ctx context.Context) error {
 	cmd := exec.CommandContext(processCtx, p.Extension.ExecPath)
 	cmd.Dir = p.Extension.Dir
 	cmd.Env = extensionProcessEnv(p.workspaceCWD)
+	if p.config.ScrubEnv {
+		cmd.Env = scrubbedEnv(cmd.Env, p.config.EnvAllow)
+	}
 	// Keep extension diagnostics on the host's configured log sink so a
 	// full-screen UI can redirect them without replacing the process stderr.
 	cmd.Stderr = newExtensionStderrWriter(processCtx, p.Extension.ID, logger.G(processCtx).Logger.Out)
@@ -80,6 +84,7 @@ func (p *Process) start(ctx context.Context) error {
 
 	p.cmd = cmd
 	p.client = newRPCClient(stdout, stdin)
+	p.client.maxMessageSize = p.config.MaxMessageSize
 	p.stdin = stdin
 	p.stdout = stdout
 	p.closed = false
@@ -108,12 +113,37 @@ func extensionProcessEnv(workspaceCWD string) []string {
 	return append(env, entry)
 }
 
+// scrubbedEnvKeys are the variables extensions keep when the environment is
+// scrubbed: enough to locate executables, the home directory and locale.
+var scrubbedEnvKeys = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "TERM", "TMPDIR", "TZ", "LANG",
+	workspaceCWDEnvKey,
+}
+
+// scrubbedEnvPrefixes are variable prefixes kept when the environment is
+// scrubbed.
+var scrubbedEnvPrefixes = []string{"LC_", "XDG_"}
+
+func scrubbedEnv(env []string, allow []string) []string {
+	keep := append(slices.Clone(scrubbedEnvKeys), allow...)
+	scrubbed := make([]string, 0, len(keep))
+	for _, entry := range env {
+		key, _, _ := strings.Cut(entry, "=")
+		if slices.Contains(keep, key) || slices.ContainsFunc(scrubbedEnvPrefixes, func(prefix string) bool {
+			return strings.HasPrefix(key, prefix)
+		}) {
+			scrubbed = append(scrubbed, entry)
+		}
+	}
+	return scrubbed
+}
+
 func (p *Process) ensureRunning(ctx context.Context) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	if p.disabled {
-		return errors.Errorf("extension %s disabled after repeated failures", p.Extension.ID)
+		return errors.Wrapf(errExtensionDisabled, "extension %s", p.Extension.ID)
 	}
 	if p.shutdown {
 		return errors.Errorf("extension %s is shut down", p.Extension.ID)
@@ -175,6 +205,31 @@ func (p *Process) recordFailureLocked() {
 	}
 }
 
+// States of an extension process reported by Process.State.
+const (
+	ProcessRunning  = "running"
+	ProcessStopped  = "stopped"
+	ProcessDisabled = "disabled"
+	ProcessShutDown = "shut down"
+)
+
+// State reports whether the process is running. A stopped process is
+// restarted on its next call; a disabled one failed too often and is not.
+func (p *Process) State() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case p.disabled:
+		return ProcessDisabled
+	case p.shutdown:
+		return ProcessShutDown
+	case p.closed:
+		return ProcessStopped
+	default:
+		return ProcessRunning
+	}
+}
+
 // Initialize initializes the extension process and returns its registrations.
 func (p *Process) Initialize(ctx context.Context, cwd string) (*InitializeResult, error) {
 	p.mu.Lock()
@@ -215,6 +270,7 @@ func (p *Process) initialize(ctx context.Context, cwd string) (*InitializeResult
 				"tool.update",
 				"tool.result",
 				"turn.end",
+				"context.compacted",
 				"agent.end",
 				"session.end",
 			},
@@ -464,7 +520,13 @@ func shouldRestartAfterCallError(err error) bool {
 	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
 		return true
 	}
-	return !strings.Contains(err.Error(), "extension rpc error")
+	return !isExtensionRPCError(err)
+}
+
+// isExtensionRPCError reports whether err is an error response from the
+// extension rather than a failure of the process or transport.
+func isExtensionRPCError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "extension rpc error")
 }
 
 func (p *Process) closeForRestart() {
@@ -21,6 +21,28 @@ func TestExtensionDataDirUsesKodeletBasePathAndSanitizedID(t *testing.T) {
 	assert.Contains(t, dataDir, "org@repo_weather")
 }
 
+func TestScrubbedEnvKeepsBaselineAndAllowedVariables(t *testing.T) {
+	env := []string{
+		"PATH=/usr/bin",
+		"HOME=/home/dev",
+		"LC_ALL=C.UTF-8",
+		"ANTHROPIC_API_KEY=secret",
+		"GITHUB_TOKEN=secret",
+		"WEATHER_API_KEY=allowed",
+		workspaceCWDEnvKey + "=/work",
+	}
+
+	scrubbed := scrubbedEnv(env, []string{"WEATHER_API_KEY"})
+
+	assert.Equal(t, []string{
+		"PATH=/usr/bin",
+		"HOME=/home/dev",
+		"LC_ALL=C.UTF-8",
+		"WEATHER_API_KEY=allowed",
+		workspaceCWDEnvKey + "=/work",
+	}, scrubbed)
+}
+
 func TestProcessEnsureRunningDisabledAndShutdownBranches(t *testing.T) {
 	t.Run("disabled after repeated failures", func(t *testing.T) {
 		process := &Process{Extension: Extension{ID: "weather"}}
@@ -68,6 +90,13 @@ func TestProcessRPCClientNilWhenClosedDisabledOrShutdown(t *testing.T) {
 	assert.Same(t, client, (&Process{client: client}).rpcClient())
 }
 
+func TestProcessState(t *testing.T) {
+	assert.Equal(t, ProcessRunning, (&Process{}).State())
+	assert.Equal(t, ProcessStopped, (&Process{closed: true}).State())
+	assert.Equal(t, ProcessShutDown, (&Process{closed: true, shutdown: true}).State())
+	assert.Equal(t, ProcessDisabled, (&Process{closed: true, disabled: true}).State())
+}
+
 func TestProcessContextIgnoresCallerCancellation(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
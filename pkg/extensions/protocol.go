@@ -82,6 +82,9 @@ type Subscription struct {
 	Event        string   `json:"event"`
 	Priority     int      `json:"priority,omitempty"`
 	TimeoutInSec *float64 `json:"timeoutInSec,omitempty"`
+	// Durable queues observational events in the database until the
+	// extension handles them, retrying failed deliveries in order.
+	Durable bool `json:"durable,omitempty"`
 }
 
 type initializeParams struct {
@@ -151,6 +154,19 @@ type ToolExecutionResult struct {
 	Content string         `json:"content"`
 	Data    map[string]any `json:"data,omitempty"`
 	Error   string         `json:"error,omitempty"`
+	// Images are passed back to the model with the result, such as the
+	// screenshots of a computer-use loop.
+	Images []ToolResultImage `json:"images,omitempty"`
+}
+
+// ToolResultImage is an image returned by an extension tool.
+type ToolResultImage struct {
+	// Data is the base64-encoded image, optionally as a data URL. The format
+	// is detected from the image itself.
+	Data string `json:"data"`
+	// Detail set to "original" keeps the full resolution on models that
+	// support it. Other images are downscaled like view_image.
+	Detail string `json:"detail,omitempty"`
 }
 
 // CommandInvocation describes the user prompt that invoked an extension command.
@@ -199,14 +215,16 @@ type ToolListPatch struct {
 }
 
 type rpcClient struct {
-	reader   *bufio.Reader
-	writer   io.Writer
-	writeMu  sync.Mutex
-	stateMu  sync.Mutex
-	readOnce sync.Once
-	nextID   int64
-	pending  map[int64]*rpcPendingCall
-	terminal error
+	// maxMessageSize caps incoming message payloads; zero means unlimited.
+	maxMessageSize int
+	reader         *bufio.Reader
+	writer         io.Writer
+	writeMu        sync.Mutex
+	stateMu        sync.Mutex
+	readOnce       sync.Once
+	nextID         int64
+	pending        map[int64]*rpcPendingCall
+	terminal       error
 }
 
 type rpcPendingCall struct {
@@ -302,7 +320,7 @@ func (c *rpcClient) removePending(id int64, expected *rpcPendingCall) bool {
 
 func (c *rpcClient) readLoop() {
 	for {
-		msg, err := readIncomingMessage(c.reader)
+		msg, err := readIncomingMessage(c.reader, c.maxMessageSize)
 		if err != nil {
 			c.fail(err)
 			return
@@ -465,15 +483,15 @@ func writeFrame(writer io.Writer, payload []byte) error {
 }
 
 func readResponse(reader *bufio.Reader) (rpcResponse, error) {
-	msg, err := readIncomingMessage(reader)
+	msg, err := readIncomingMessage(reader, 0)
 	if err != nil {
 		return rpcResponse{}, err
 	}
 	return incomingResponse(msg)
 }
 
-func readIncomingMessage(reader *bufio.Reader) (rpcIncomingMessage, error) {
-	payload, err := readFrame(reader)
+func readIncomingMessage(reader *bufio.Reader, maxSize int) (rpcIncomingMessage, error) {
+	payload, err := readLimitedFrame(reader, maxSize)
 	if err != nil {
 		return rpcIncomingMessage{}, err
 	}
@@ -499,6 +517,12 @@ func incomingResponse(msg rpcIncomingMessage) (rpcResponse, error) {
 }
 
 func readFrame(reader *bufio.Reader) ([]byte, error) {
+	return readLimitedFrame(reader, 0)
+}
+
+// readLimitedFrame reads one frame, refusing payloads over maxSize bytes
+// before allocating them. A maxSize of zero means unlimited.
+func readLimitedFrame(reader *bufio.Reader, maxSize int) ([]byte, error) {
 	contentLength := -1
 	for {
 		line, err := reader.ReadString('\n')
@@ -524,6 +548,9 @@ func readFrame(reader *bufio.Reader) ([]byte, error) {
 	if contentLength < 0 {
 		return nil, errors.New("missing Content-Length header")
 	}
+	if maxSize > 0 && contentLength > maxSize {
+		return nil, errors.Wrapf(errMessageTooLarge, "%d bytes over the %d byte limit", contentLength, maxSize)
+	}
 	payload := make([]byte, contentLength)
 	if _, err := io.ReadFull(reader, payload); err != nil {
 		return nil, errors.Wrap(err, "failed to read rpc payload")
@@ -240,6 +240,13 @@ func TestReadFrameValidationErrors(t *testing.T) {
 	}
 }
 
+func TestReadLimitedFrameRejectsOversizedPayload(t *testing.T) {
+	_, err := readLimitedFrame(bufio.NewReader(strings.NewReader("Content-Length: 1048576\r\n\r\n{}")), 1024)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, errMessageTooLarge)
+}
+
 func TestReadResponseRejectsInvalidJSON(t *testing.T) {
 	var inbound bytes.Buffer
 	require.NoError(t, writeFrame(&inbound, []byte("not-json")))
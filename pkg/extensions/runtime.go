@@ -22,6 +22,11 @@ type Runtime struct {
 	commands            []Command
 	subs                []Subscription
 	eventHandlersByName map[string][]eventHandler
+
+	// eventQueue holds durable events; it is nil when no extension
+	// subscribes durably or the database is unavailable.
+	eventQueue *EventQueue
+	queueMu    sync.Mutex
 }
 
 // Command is an extension command registration bound to its process.
@@ -72,31 +77,68 @@ func (r *Runtime) initialize(ctx context.Context, discovery *Discovery) error {
 	if err != nil {
 		return err
 	}
-	for _, ext := range extensions {
-		proc, err := StartProcess(ctx, ext, r.config, r.workingDir)
-		if err != nil {
-			logger.G(ctx).WithError(err).WithField("extension", ext.ID).Warn("failed to start extension; disabling for this process")
+	started := r.startProcesses(ctx, extensions)
+	for _, entry := range started {
+		if entry.proc == nil {
 			continue
 		}
-		initCtx, cancel := context.WithTimeout(ctx, extensionInitializeTimeout)
-		result, err := proc.Initialize(initCtx, r.workingDir)
-		cancel()
-		if err != nil {
-			_ = proc.Close()
-			logger.G(ctx).WithError(err).WithField("extension", ext.ID).Warn("failed to initialize extension; disabling for this process")
+		r.processes = append(r.processes, entry.proc)
+	}
+	for _, entry := range started {
+		if entry.proc == nil {
 			continue
 		}
-		r.processes = append(r.processes, proc)
-		if err := r.register(ctx, proc, result); err != nil {
+		if err := r.register(ctx, entry.proc, entry.result); err != nil {
 			return err
 		}
 	}
+	r.openEventQueue(ctx)
 	callContext := ExtensionCallContext{CWD: r.workingDir, InvokedBy: "main"}
 	r.DispatchSessionStart(ctx, callContext)
 	r.DispatchResourcesDiscover(ctx, callContext)
 	return nil
 }
 
+type startedProcess struct {
+	proc   *Process
+	result *InitializeResult
+}
+
+// startProcesses launches and initializes extensions concurrently so that slow
+// handshakes do not serialize startup. Results keep discovery order, which keeps
+// registration conflicts deterministic.
+func (r *Runtime) startProcesses(ctx context.Context, extensions []Extension) []startedProcess {
+	started := make([]startedProcess, len(extensions))
+	var wg sync.WaitGroup
+	for i, ext := range extensions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			startedAt := time.Now()
+			proc, err := StartProcess(ctx, ext, r.config, r.workingDir)
+			if err != nil {
+				logger.G(ctx).WithError(err).WithField("extension", ext.ID).Warn("failed to start extension; disabling for this process")
+				return
+			}
+			initCtx, cancel := context.WithTimeout(ctx, extensionInitializeTimeout)
+			result, err := proc.Initialize(initCtx, r.workingDir)
+			cancel()
+			if err != nil {
+				_ = proc.Close()
+				logger.G(ctx).WithError(err).WithField("extension", ext.ID).Warn("failed to initialize extension; disabling for this process")
+				return
+			}
+			logger.G(ctx).
+				WithField("extension", ext.ID).
+				WithField("duration", time.Since(startedAt)).
+				Debug("extension initialized")
+			started[i] = startedProcess{proc: proc, result: result}
+		}()
+	}
+	wg.Wait()
+	return started
+}
+
 func (r *Runtime) register(_ context.Context, proc *Process, result *InitializeResult) error {
 	if result == nil {
 		return nil
@@ -234,6 +276,23 @@ func (r *Runtime) Subscriptions() []Subscription {
 	return append([]Subscription(nil), r.subs...)
 }
 
+// ProcessStatus is the state of an extension process.
+type ProcessStatus struct {
+	ExtensionID string `json:"id"`
+	State       string `json:"state"`
+}
+
+// Statuses returns the state of each extension process in discovery order.
+func (r *Runtime) Statuses() []ProcessStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	statuses := make([]ProcessStatus, 0, len(r.processes))
+	for _, proc := range r.processes {
+		statuses = append(statuses, ProcessStatus{ExtensionID: proc.Extension.ID, State: proc.State()})
+	}
+	return statuses
+}
+
 // Close terminates all extension processes.
 func (r *Runtime) Close() error {
 	if r == nil {
@@ -249,5 +308,9 @@ func (r *Runtime) Close() error {
 		}
 	}
 	r.processes = nil
+	if err := r.eventQueue.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	r.eventQueue = nil
 	return firstErr
 }
@@ -348,6 +348,32 @@ func TestRuntimeDispatchesSessionLifecycleEvents(t *testing.T) {
 	assert.Contains(t, string(data), EventSessionEnd+"\n")
 }
 
+func TestRuntimeDispatchesContextCompacted(t *testing.T) {
+	rootDir := t.TempDir()
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	extDir := filepath.Join(rootDir, "events")
+	writeExecutable(t, filepath.Join(extDir, "kodelet-extension-events"), helperExtensionScript(t))
+
+	runtime, err := NewRuntime(
+		context.Background(),
+		WithConfig(DefaultConfig()),
+		WithWorkingDir(rootDir),
+		WithRoots(Root{Dir: rootDir, Kind: SourceKindLocalStandalone}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, runtime.Close()) })
+
+	assert.True(t, runtime.HasEventHandlers(EventContextCompacted))
+	assert.False(t, (*Runtime)(nil).HasEventHandlers(EventContextCompacted))
+
+	messages := []llmtypes.Message{{Role: "user", Content: "fix the bug"}, {Role: "assistant", Content: "fixed"}}
+	runtime.DispatchContextCompacted(context.Background(), ExtensionCallContext{ConversationID: "conv-compact", CWD: rootDir}, "the bug was fixed", messages)
+
+	data, err := os.ReadFile(filepath.Join(rootDir, "events.log"))
+	require.NoError(t, err)
+	assert.Equal(t, EventContextCompacted+" \"the bug was fixed\" 2\n", string(data))
+}
+
 func TestRuntimeTryCommandRoutesExtensionCommand(t *testing.T) {
 	rootDir := t.TempDir()
 	t.Setenv("KODELET_BASE_PATH", t.TempDir())
@@ -455,6 +481,26 @@ func TestExtensionProcessInheritsParentEnvironment(t *testing.T) {
 	assert.Equal(t, "parent-secret", result.GetResult())
 }
 
+func TestRuntimeSkipsExtensionsThatFailToStart(t *testing.T) {
+	rootDir := t.TempDir()
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	writeExecutable(t, filepath.Join(rootDir, "broken", "kodelet-extension-broken"), "#!/bin/sh\nexit 1\n")
+	writeExecutable(t, filepath.Join(rootDir, "weather", "kodelet-extension-weather"), helperExtensionScript(t))
+
+	runtime, err := NewRuntime(
+		context.Background(),
+		WithConfig(DefaultConfig()),
+		WithWorkingDir(rootDir),
+		WithRoots(Root{Dir: rootDir, Kind: SourceKindLocalStandalone}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, runtime.Close()) })
+
+	tools := runtime.Tools()
+	require.Len(t, tools, 1)
+	assert.Equal(t, "get_weather", tools[0].Name())
+}
+
 func TestRuntimeRejectsDuplicateToolRegistrations(t *testing.T) {
 	rootDir := t.TempDir()
 	t.Setenv("KODELET_BASE_PATH", t.TempDir())
@@ -662,7 +708,7 @@ func runExtensionHelperProcess() {
 					Kind:        "recipe",
 				}},
 				Subscriptions: []Subscription{
-					{Event: EventSessionStart, Priority: 10},
+					{Event: EventSessionStart, Priority: 10, Durable: true},
 					{Event: EventResourcesDiscover, Priority: 10},
 					{Event: EventToolCall, Priority: 10},
 					{Event: EventToolUpdate, Priority: 10},
@@ -670,6 +716,7 @@ func runExtensionHelperProcess() {
 					{Event: EventUserMessage, Priority: 10},
 					{Event: EventAgentInit, Priority: 10},
 					{Event: EventTurnEnd, Priority: 10},
+					{Event: EventContextCompacted, Priority: 10},
 					{Event: EventAgentEnd, Priority: 10},
 					{Event: EventSessionEnd, Priority: 10},
 				},
@@ -822,6 +869,14 @@ func handleHelperEvent(params eventParams) EventResult {
 		}
 	case EventTurnEnd:
 		return EventResult{}
+	case EventContextCompacted:
+		payload, _ := json.Marshal(params.Payload)
+		var event contextCompactedPayload
+		_ = json.Unmarshal(payload, &event)
+		if path := filepath.Join(params.Context.CWD, "events.log"); params.Context.CWD != "" {
+			_ = os.WriteFile(path, fmt.Appendf(nil, "%s %q %d\n", params.Event, event.Summary, len(event.Messages)), 0o644)
+		}
+		return EventResult{}
 	case EventAgentEnd:
 		return EventResult{FollowUpMessages: []string{"inspect tests", "update docs"}}
 	default:
@@ -2,18 +2,28 @@ package extensions
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/invopop/jsonschema"
 	"github.com/jingkaihe/kodelet/pkg/tools"
 	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/jingkaihe/kodelet/pkg/vision"
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel/attribute"
 )
 
-var _ tooltypes.StreamingTool = &Tool{}
+var (
+	_ tooltypes.StreamingTool        = &Tool{}
+	_ tooltypes.MultiModalToolResult = &ToolResult{}
+)
+
+// maxToolResultImages caps the images passed back to the model from one
+// extension tool call.
+const maxToolResultImages = 10
 
 // Tool is a tool registered by an extension.
 type Tool struct {
@@ -116,7 +126,9 @@ func (t *Tool) execute(ctx context.Context, parameters string, onUpdate tooltype
 	if err != nil {
 		return &ToolResult{toolName: t.name, extensionID: t.extensionID, executionTime: executionTime, err: err.Error()}
 	}
-	return t.resultFromExecution(*result, executionTime)
+	toolResult := t.resultFromExecution(*result, executionTime)
+	toolResult.addImages(result.Images, toolCtx.Model)
+	return toolResult
 }
 
 func (t *Tool) resultFromExecution(result ToolExecutionResult, executionTime time.Duration) *ToolResult {
@@ -134,6 +146,47 @@ func (t *Tool) resultFromExecution(result ToolExecutionResult, executionTime tim
 	}
 }
 
+// addImages processes the images returned by the tool like view_image does.
+// Images that cannot be used are left out with a note in the result, so the
+// model knows they are missing.
+func (r *ToolResult) addImages(images []ToolResultImage, model string) {
+	var notes []string
+	for i, image := range images {
+		if i >= maxToolResultImages {
+			notes = append(notes, fmt.Sprintf("[%d more images omitted: at most %d images are passed back per tool call]", len(images)-i, maxToolResultImages))
+			break
+		}
+		processed, err := decodeToolResultImage(image, model)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("[Image %d omitted: %v]", i+1, err))
+			continue
+		}
+		r.images = append(r.images, tooltypes.ToolResultContentPart{
+			Type:     tooltypes.ToolResultContentPartTypeImage,
+			ImageURL: processed.ImageURL,
+			MimeType: processed.MimeType,
+			Detail:   processed.Detail,
+		})
+	}
+	if len(notes) > 0 {
+		r.result = strings.TrimSpace(r.result + "\n\n" + strings.Join(notes, "\n"))
+	}
+}
+
+func decodeToolResultImage(image ToolResultImage, model string) (*vision.Result, error) {
+	payload := strings.TrimSpace(image.Data)
+	if strings.HasPrefix(payload, "data:") {
+		if _, encoded, ok := strings.Cut(payload, ";base64,"); ok {
+			payload = encoded
+		}
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid base64 image data")
+	}
+	return vision.MakeImageResult(data, image.Detail, model)
+}
+
 // TracingKVs returns tracing attributes for the tool.
 func (t *Tool) TracingKVs(_ string) ([]attribute.KeyValue, error) {
 	return []attribute.KeyValue{
@@ -151,6 +204,7 @@ type ToolResult struct {
 	result        string
 	err           string
 	data          map[string]any
+	images        []tooltypes.ToolResultContentPart
 }
 
 // AssistantFacing returns the result for the assistant.
@@ -178,6 +232,7 @@ func (r *ToolResult) StructuredData() tooltypes.StructuredToolResult {
 			ToolName:      r.toolName,
 			Output:        r.result,
 			Data:          r.data,
+			Images:        len(r.images),
 			ExecutionTime: r.executionTime,
 		},
 	}
@@ -187,6 +242,19 @@ func (r *ToolResult) StructuredData() tooltypes.StructuredToolResult {
 	return result
 }
 
+// ContentParts returns the result text followed by the images returned by the
+// tool, or nil when it returned none.
+func (r *ToolResult) ContentParts() []tooltypes.ToolResultContentPart {
+	if len(r.images) == 0 {
+		return nil
+	}
+	parts := []tooltypes.ToolResultContentPart{{
+		Type: tooltypes.ToolResultContentPartTypeText,
+		Text: r.AssistantFacing(),
+	}}
+	return append(parts, r.images...)
+}
+
 func (r *ToolResult) String() string {
 	if r.IsError() {
 		return fmt.Sprintf("extension tool %s failed: %s", r.toolName, r.err)
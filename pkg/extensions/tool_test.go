@@ -1,8 +1,12 @@
 package extensions
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"image"
+	"image/png"
 	"path/filepath"
 	"testing"
 
@@ -140,6 +144,47 @@ func TestToolResultAssistantFacingStringAndStructuredData(t *testing.T) {
 	assert.Equal(t, "weather", metadata.ExtensionID)
 }
 
+func TestToolResultAddImages(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 3))))
+	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	result := &ToolResult{toolName: "screenshot", extensionID: "desktop", result: "captured"}
+	assert.Nil(t, result.ContentParts(), "results without images stay text only")
+
+	result.addImages([]ToolResultImage{
+		{Data: encoded},
+		{Data: "data:image/png;base64," + encoded},
+		{Data: "not base64!"},
+		{Data: base64.StdEncoding.EncodeToString([]byte("plain text"))},
+	}, "claude-sonnet-4-6")
+
+	parts := result.ContentParts()
+	require.Len(t, parts, 3)
+	assert.Equal(t, tooltypes.ToolResultContentPartTypeText, parts[0].Type)
+	assert.Contains(t, parts[0].Text, "captured")
+	for _, part := range parts[1:] {
+		assert.Equal(t, tooltypes.ToolResultContentPartTypeImage, part.Type)
+		assert.Equal(t, "image/png", part.MimeType)
+		assert.Equal(t, "data:image/png;base64,"+encoded, part.ImageURL)
+	}
+	assert.Contains(t, result.GetResult(), "[Image 3 omitted: invalid base64 image data")
+	assert.Contains(t, result.GetResult(), "[Image 4 omitted: unable to decode image")
+
+	var metadata tooltypes.ExtensionToolMetadata
+	require.True(t, tooltypes.ExtractMetadata(result.StructuredData().Metadata, &metadata))
+	assert.Equal(t, 2, metadata.Images)
+
+	many := make([]ToolResultImage, maxToolResultImages+2)
+	for i := range many {
+		many[i] = ToolResultImage{Data: encoded}
+	}
+	result = &ToolResult{toolName: "screenshot"}
+	result.addImages(many, "")
+	assert.Len(t, result.images, maxToolResultImages)
+	assert.Equal(t, "[2 more images omitted: at most 10 images are passed back per tool call]", result.GetResult())
+}
+
 func TestShouldRestartAfterCallError(t *testing.T) {
 	assert.False(t, shouldRestartAfterCallError(nil))
 	assert.True(t, shouldRestartAfterCallError(context.DeadlineExceeded))
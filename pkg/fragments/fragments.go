@@ -40,6 +40,18 @@ type Metadata struct {
 	AllowedTools    []string                `yaml:"allowed_tools,omitempty"`
 	AllowedCommands []string                `yaml:"allowed_commands,omitempty"`
 	Arguments       map[string]ArgumentMeta `yaml:"arguments,omitempty"` // Argument definitions with descriptions
+
+	// Run options applied when the recipe is executed. Flags given explicitly
+	// on the command line take precedence.
+	Model        string   `yaml:"model,omitempty"`
+	MaxTurns     int      `yaml:"max_turns,omitempty"`
+	CompactRatio float64  `yaml:"compact_ratio,omitempty"`
+	UseWeakModel bool     `yaml:"use_weak_model,omitempty"`
+	NoTools      bool     `yaml:"no_tools,omitempty"`
+	Images       []string `yaml:"images,omitempty"`
+	// Env sets environment variables, which may be secret:// references,
+	// for the bash commands of the run.
+	Env map[string]string `yaml:"env,omitempty"`
 }
 
 // Fragment represents a fragment with its metadata and content
@@ -293,6 +305,27 @@ func (fp *Processor) parseFrontmatter(content string) (Metadata, string, error)
 			}
 		}
 
+		if model, ok := metaData["model"].(string); ok {
+			metadata.Model = strings.TrimSpace(model)
+		}
+		if maxTurns, ok := metaData["max_turns"].(int); ok {
+			metadata.MaxTurns = maxTurns
+		}
+		switch ratio := metaData["compact_ratio"].(type) {
+		case float64:
+			metadata.CompactRatio = ratio
+		case int:
+			metadata.CompactRatio = float64(ratio)
+		}
+		if useWeakModel, ok := metaData["use_weak_model"].(bool); ok {
+			metadata.UseWeakModel = useWeakModel
+		}
+		if noTools, ok := metaData["no_tools"].(bool); ok {
+			metadata.NoTools = noTools
+		}
+		if images := metaData["images"]; images != nil {
+			metadata.Images = fp.parseStringArrayField(images)
+		}
 	}
 
 	bodyContent := fp.extractBodyContent(content)
@@ -329,6 +362,22 @@ func (fp *Processor) parseStringArrayField(field any) []string {
 	}
 }
 
+// resolveImagePaths makes relative image paths declared in a recipe relative
+// to the recipe's directory. URLs and absolute paths are kept as they are.
+func resolveImagePaths(images []string, dir string) []string {
+	resolved := make([]string, 0, len(images))
+	for _, image := range images {
+		if image == "" {
+			continue
+		}
+		if !strings.Contains(image, "://") && !strings.HasPrefix(image, "data:") && !filepath.IsAbs(image) {
+			image = filepath.Join(dir, image)
+		}
+		resolved = append(resolved, image)
+	}
+	return resolved
+}
+
 func (fp *Processor) extractBodyContent(content string) string {
 	if !strings.HasPrefix(content, "---") {
 		return content
@@ -395,6 +444,8 @@ func (fp *Processor) LoadFragment(ctx context.Context, config *Config) (*Fragmen
 	displayPath := fragmentPath
 	if strings.HasPrefix(fragmentPath, "embed:") {
 		displayPath = "builtin:" + strings.TrimPrefix(fragmentPath, "embed:")
+	} else {
+		metadata.Images = resolveImagePaths(metadata.Images, filepath.Dir(fragmentPath))
 	}
 
 	return &Fragment{
@@ -451,7 +451,7 @@ Unique content`
 	fragments, err := processor.ListFragmentsWithMetadata()
 	require.NoError(t, err)
 
-	assert.Len(t, fragments, 6)
+	assert.Len(t, fragments, 11)
 
 	var withMeta, withoutMeta, unique *Fragment
 	for _, f := range fragments {
@@ -535,6 +535,37 @@ Test content here.`
 	assert.Equal(t, []string{"git *", "cat *"}, metadata2.Metadata.AllowedCommands)
 }
 
+func TestFragmentProcessor_ParseRunOptions(t *testing.T) {
+	dir := t.TempDir()
+	fragmentContent := `---
+name: Quick Summary
+model: claude-haiku-4-5
+max_turns: 3
+compact_ratio: 0.6
+use_weak_model: true
+no_tools: true
+images:
+  - diagram.png
+  - https://example.com/screenshot.png
+---
+
+Summarize the diagram.`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "summary.md"), []byte(fragmentContent), 0o644))
+
+	processor, err := NewFragmentProcessor(WithFragmentDirs(dir))
+	require.NoError(t, err)
+
+	fragment, err := processor.LoadFragment(context.Background(), &Config{FragmentName: "summary"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "claude-haiku-4-5", fragment.Metadata.Model)
+	assert.Equal(t, 3, fragment.Metadata.MaxTurns)
+	assert.Equal(t, 0.6, fragment.Metadata.CompactRatio)
+	assert.True(t, fragment.Metadata.UseWeakModel)
+	assert.True(t, fragment.Metadata.NoTools)
+	assert.Equal(t, []string{filepath.Join(dir, "diagram.png"), "https://example.com/screenshot.png"}, fragment.Metadata.Images)
+}
+
 func TestFragmentProcessor_Subdirectories(t *testing.T) {
 	tempDir, err := os.MkdirTemp("", "kodelet-fragments-subdir-test")
 	require.NoError(t, err)
@@ -930,3 +961,43 @@ Regular PR instructions here.
 	assert.Contains(t, result3.Content, "Target branch: develop")
 	assert.NotContains(t, result3.Content, "DRAFT")
 }
+
+func TestFragmentProcessor_InitRecipeProposesConfig(t *testing.T) {
+	processor, err := NewFragmentProcessor(WithFragmentDirs(t.TempDir()))
+	require.NoError(t, err)
+
+	result, err := processor.LoadFragment(context.Background(), &Config{FragmentName: "init"})
+	require.NoError(t, err)
+	assert.Contains(t, result.Content, "## Recommend Kodelet Configuration")
+	assert.Contains(t, result.Content, "kodelet-config.proposed.yaml")
+	assert.NotContains(t, result.Content, "{{")
+
+	result, err = processor.LoadFragment(context.Background(), &Config{
+		FragmentName: "init",
+		Arguments:    map[string]string{"config": "false"},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, result.Content, "AGENTS.md")
+	assert.NotContains(t, result.Content, "kodelet-config.proposed.yaml")
+}
+
+func TestFragmentProcessor_ReleaseNotesRecipe(t *testing.T) {
+	processor, err := NewFragmentProcessor(WithFragmentDirs(t.TempDir()))
+	require.NoError(t, err)
+
+	result, err := processor.LoadFragment(context.Background(), &Config{FragmentName: "release-notes"})
+	require.NoError(t, err)
+	assert.Contains(t, result.Metadata.AllowedTools, "git_log")
+	assert.Contains(t, result.Content, "since the latest tag before `HEAD`")
+	assert.Contains(t, result.Content, "add them to `RELEASE.md`")
+
+	result, err = processor.LoadFragment(context.Background(), &Config{
+		FragmentName: "release-notes",
+		Arguments:    map[string]string{"from": "v1.0.0", "to": "v1.1.0", "output": "CHANGELOG.md", "version": "1.1.0"},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, result.Content, "between `v1.0.0` and `v1.1.0`")
+	assert.Contains(t, result.Content, "`from` set to `v1.0.0`")
+	assert.Contains(t, result.Content, "headed with the version `1.1.0`")
+	assert.Contains(t, result.Content, "`CHANGELOG.md`")
+}
@@ -0,0 +1,528 @@
+// Package gitconflicts detects merge conflicts and divergence from the
+// upstream branch in a working tree, and checks that a conflict resolution
+// only touched the conflicting hunks.
+package gitconflicts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxScannedFiles bounds how many changed files are scanned for leftover
+	// conflict markers.
+	maxScannedFiles = 500
+	// maxScannedFileSize skips large files, which are rarely hand-merged text.
+	maxScannedFileSize = 1 << 20
+
+	markerOurs   = "<<<<<<<"
+	markerBase   = "|||||||"
+	markerSplit  = "======="
+	markerTheirs = ">>>>>>>"
+)
+
+// Hunk is one conflict region delimited by conflict markers.
+type Hunk struct {
+	// StartLine and EndLine are the 1-based lines of the opening and closing
+	// markers.
+	StartLine   int
+	EndLine     int
+	OursLabel   string
+	TheirsLabel string
+	Ours        string
+	Base        string
+	Theirs      string
+
+	start, end int // byte offsets of the region, markers included
+}
+
+// File is a file with conflicts in the working tree.
+type File struct {
+	Path string
+	// Unmerged is set when git records the file as unmerged. Files that are
+	// not unmerged but still contain markers were usually staged by mistake.
+	Unmerged bool
+	Hunks    []Hunk
+}
+
+// Report describes the conflict state of a working tree.
+type Report struct {
+	// Operation is the git operation in progress, such as "merge" or
+	// "rebase", or "" when none is.
+	Operation string
+	Branch    string
+	Upstream  string
+	Ahead     int
+	Behind    int
+	Files     []File
+	// UpstreamConflicts lists files that would conflict when the upstream
+	// branch is merged into HEAD.
+	UpstreamConflicts []string
+	// UpstreamOverlaps lists uncommitted files that the upstream branch also
+	// changed since the branches diverged.
+	UpstreamOverlaps []string
+}
+
+// HasConflicts reports whether the working tree contains conflicts.
+func (r Report) HasConflicts() bool {
+	return len(r.Files) > 0
+}
+
+// Empty reports whether there is nothing worth telling the user about.
+func (r Report) Empty() bool {
+	return !r.HasConflicts() && r.Operation == "" && r.Behind == 0 &&
+		len(r.UpstreamConflicts) == 0 && len(r.UpstreamOverlaps) == 0
+}
+
+// Paths returns the paths of the conflicted files.
+func (r Report) Paths() []string {
+	paths := make([]string, 0, len(r.Files))
+	for _, file := range r.Files {
+		paths = append(paths, file.Path)
+	}
+	return paths
+}
+
+// Summary renders the report as indented lines for display.
+func (r Report) Summary() []string {
+	var lines []string
+	if r.Operation != "" {
+		lines = append(lines, fmt.Sprintf("A %s is in progress", r.Operation))
+	}
+	for _, file := range r.Files {
+		state := "unmerged"
+		if !file.Unmerged {
+			state = "conflict markers left in file"
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s (%s)", file.Path, pluralize(len(file.Hunks), "conflicting hunk"), state))
+		for _, hunk := range file.Hunks {
+			lines = append(lines, fmt.Sprintf("  lines %d-%d: %s vs %s", hunk.StartLine, hunk.EndLine, labelOr(hunk.OursLabel, "ours"), labelOr(hunk.TheirsLabel, "theirs")))
+		}
+	}
+	if r.Upstream != "" && (r.Ahead > 0 || r.Behind > 0) {
+		lines = append(lines, fmt.Sprintf("%s is %d ahead and %d behind %s", labelOr(r.Branch, "HEAD"), r.Ahead, r.Behind, r.Upstream))
+	}
+	if len(r.UpstreamConflicts) > 0 {
+		lines = append(lines, fmt.Sprintf("Merging %s would conflict in: %s", r.Upstream, strings.Join(r.UpstreamConflicts, ", ")))
+	}
+	if len(r.UpstreamOverlaps) > 0 {
+		lines = append(lines, fmt.Sprintf("Uncommitted changes overlap files changed on %s: %s", r.Upstream, strings.Join(r.UpstreamOverlaps, ", ")))
+	}
+	return lines
+}
+
+// Detect inspects the git working tree containing dir. It only uses local
+// refs and never fetches.
+func Detect(ctx context.Context, dir string) (Report, error) {
+	var report Report
+	root, err := gitOutput(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return report, errors.Wrap(err, "not a git repository")
+	}
+	root = strings.TrimSpace(root)
+
+	report.Operation = operationInProgress(ctx, root)
+	if branch, err := gitOutput(ctx, root, "symbolic-ref", "--short", "-q", "HEAD"); err == nil {
+		report.Branch = strings.TrimSpace(branch)
+	}
+
+	unmerged, err := gitLines(ctx, root, "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return report, err
+	}
+	unmergedSet := make(map[string]bool, len(unmerged))
+	for _, path := range unmerged {
+		unmergedSet[path] = true
+	}
+
+	changed, err := changedFiles(ctx, root)
+	if err != nil {
+		return report, err
+	}
+	candidates := slices.Compact(slices.Sorted(slices.Values(append(unmerged, changed...))))
+	if len(candidates) > maxScannedFiles {
+		candidates = candidates[:maxScannedFiles]
+	}
+	for _, path := range candidates {
+		hunks := scanFile(filepath.Join(root, path))
+		if len(hunks) == 0 && !unmergedSet[path] {
+			continue
+		}
+		report.Files = append(report.Files, File{Path: path, Unmerged: unmergedSet[path], Hunks: hunks})
+	}
+
+	upstream, err := gitOutput(ctx, root, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	if err != nil {
+		return report, nil
+	}
+	report.Upstream = strings.TrimSpace(upstream)
+	counts, err := gitOutput(ctx, root, "rev-list", "--left-right", "--count", "HEAD...@{upstream}")
+	if err != nil {
+		return report, nil
+	}
+	if fields := strings.Fields(counts); len(fields) == 2 {
+		report.Ahead, _ = strconv.Atoi(fields[0])
+		report.Behind, _ = strconv.Atoi(fields[1])
+	}
+	if report.Behind == 0 {
+		return report, nil
+	}
+
+	if report.Ahead > 0 {
+		report.UpstreamConflicts = mergeTreeConflicts(ctx, root)
+	}
+	upstreamChanged, err := gitLines(ctx, root, "diff", "--name-only", "HEAD...@{upstream}")
+	if err == nil {
+		for _, path := range changed {
+			if slices.Contains(upstreamChanged, path) {
+				report.UpstreamOverlaps = append(report.UpstreamOverlaps, path)
+			}
+		}
+	}
+	return report, nil
+}
+
+// ParseHunks finds the conflict regions in content. Incomplete regions are
+// ignored.
+func ParseHunks(content string) []Hunk {
+	var hunks []Hunk
+	var current *Hunk
+	section := ""
+	var ours, base, theirs strings.Builder
+	offset := 0
+
+	for lineNumber, line := range strings.SplitAfter(content, "\n") {
+		lineStart := offset
+		offset += len(line)
+		text := strings.TrimRight(line, "\r\n")
+
+		switch {
+		case isMarker(text, markerOurs):
+			current = &Hunk{StartLine: lineNumber + 1, OursLabel: markerLabel(text), start: lineStart}
+			section = "ours"
+			ours.Reset()
+			base.Reset()
+			theirs.Reset()
+		case current == nil:
+		case isMarker(text, markerBase) && section == "ours":
+			section = "base"
+		case text == markerSplit && (section == "ours" || section == "base"):
+			section = "theirs"
+		case isMarker(text, markerTheirs) && section == "theirs":
+			current.EndLine = lineNumber + 1
+			current.TheirsLabel = markerLabel(text)
+			current.Ours = ours.String()
+			current.Base = base.String()
+			current.Theirs = theirs.String()
+			current.end = offset
+			hunks = append(hunks, *current)
+			current = nil
+		case section == "ours":
+			ours.WriteString(line)
+		case section == "base":
+			base.WriteString(line)
+		case section == "theirs":
+			theirs.WriteString(line)
+		}
+	}
+	return hunks
+}
+
+// Snapshot records a working tree before a conflict resolution, so the
+// resolution can be checked and undone.
+type Snapshot struct {
+	root      string
+	conflicts map[string]string // conflicted path -> original content
+	unmerged  []string
+	others    map[string]string // other changed path -> content hash
+}
+
+// Capture snapshots the files in report that contain conflict hunks and the
+// hashes of every other changed file in the working tree containing dir.
+// Conflicts without hunks, such as modify/delete conflicts, are left alone.
+func Capture(ctx context.Context, dir string, report Report) (*Snapshot, error) {
+	root, err := gitOutput(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, errors.Wrap(err, "not a git repository")
+	}
+	snapshot := &Snapshot{
+		root:      strings.TrimSpace(root),
+		conflicts: make(map[string]string, len(report.Files)),
+	}
+	for _, file := range report.Files {
+		if len(file.Hunks) == 0 {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(snapshot.root, file.Path))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s", file.Path)
+		}
+		snapshot.conflicts[file.Path] = string(content)
+		if file.Unmerged {
+			snapshot.unmerged = append(snapshot.unmerged, file.Path)
+		}
+	}
+	snapshot.others, err = snapshot.otherFileHashes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Verify checks that the conflicted files no longer contain conflict markers,
+// that nothing outside their conflicting hunks changed, and that no other
+// file changed. Conflicted files that fail the check are restored.
+func (s *Snapshot) Verify(ctx context.Context) error {
+	var problems []string
+	for _, path := range slices.Sorted(maps.Keys(s.conflicts)) {
+		original := s.conflicts[path]
+		content, err := os.ReadFile(filepath.Join(s.root, path))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		problem := ""
+		switch {
+		case len(ParseHunks(string(content))) > 0 || hasMarkerLine(string(content)):
+			problem = "conflict markers remain"
+		case !preservesOutsideHunks(original, string(content)):
+			problem = "changes were made outside the conflicting hunks"
+		}
+		if problem == "" {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("%s: %s", path, problem))
+		if err := os.WriteFile(filepath.Join(s.root, path), []byte(original), 0o644); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: failed to restore: %v", path, err))
+		}
+	}
+
+	others, err := s.otherFileHashes(ctx)
+	if err != nil {
+		return err
+	}
+	for path, hash := range others {
+		if s.others[path] != hash {
+			problems = append(problems, fmt.Sprintf("%s: changed outside the conflicted files", path))
+		}
+	}
+	for path := range s.others {
+		if _, ok := others[path]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: changed outside the conflicted files", path))
+		}
+	}
+
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return errors.Errorf("conflict resolution rejected:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// MarkResolved stages the files that git recorded as unmerged.
+func (s *Snapshot) MarkResolved(ctx context.Context) error {
+	if len(s.unmerged) == 0 {
+		return nil
+	}
+	_, err := gitOutput(ctx, s.root, append([]string{"add", "--"}, s.unmerged...)...)
+	return errors.Wrap(err, "failed to mark conflicts resolved")
+}
+
+func (s *Snapshot) otherFileHashes(ctx context.Context) (map[string]string, error) {
+	changed, err := changedFiles(ctx, s.root)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]string, len(changed))
+	for _, path := range changed {
+		if _, ok := s.conflicts[path]; ok {
+			continue
+		}
+		hashes[path] = hashFile(filepath.Join(s.root, path))
+	}
+	return hashes, nil
+}
+
+// preservesOutsideHunks reports whether updated keeps, in order, every part
+// of original that lies outside its conflict regions.
+func preservesOutsideHunks(original, updated string) bool {
+	hunks := ParseHunks(original)
+	segments := make([]string, 0, len(hunks)+1)
+	previous := 0
+	for _, hunk := range hunks {
+		segments = append(segments, original[previous:hunk.start])
+		previous = hunk.end
+	}
+	segments = append(segments, original[previous:])
+
+	if !strings.HasPrefix(updated, segments[0]) {
+		return false
+	}
+	position := len(segments[0])
+	last := segments[len(segments)-1]
+	if len(segments) == 1 {
+		return updated == original
+	}
+	for _, segment := range segments[1 : len(segments)-1] {
+		index := strings.Index(updated[position:], segment)
+		if index < 0 {
+			return false
+		}
+		position += index + len(segment)
+	}
+	return strings.HasSuffix(updated, last) && len(updated)-len(last) >= position
+}
+
+func operationInProgress(ctx context.Context, root string) string {
+	operations := []struct {
+		path string
+		name string
+	}{
+		{"rebase-merge", "rebase"},
+		{"rebase-apply", "rebase"},
+		{"MERGE_HEAD", "merge"},
+		{"CHERRY_PICK_HEAD", "cherry-pick"},
+		{"REVERT_HEAD", "revert"},
+	}
+	for _, operation := range operations {
+		path, err := gitOutput(ctx, root, "rev-parse", "--git-path", operation.path)
+		if err != nil {
+			continue
+		}
+		path = strings.TrimSpace(path)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(root, path)
+		}
+		if _, err := os.Stat(path); err == nil {
+			return operation.name
+		}
+	}
+	return ""
+}
+
+// mergeTreeConflicts lists the files that would conflict when merging the
+// upstream branch into HEAD, or nil when git cannot tell.
+func mergeTreeConflicts(ctx context.Context, root string) []string {
+	cmd := exec.CommandContext(ctx, "git", "merge-tree", "--write-tree", "--name-only", "--no-messages", "HEAD", "@{upstream}")
+	cmd.Dir = root
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if err == nil || !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		return nil
+	}
+	// The first line is the tree that would be written.
+	_, names, _ := strings.Cut(string(output), "\n")
+	return splitLines(names)
+}
+
+// changedFiles lists tracked files that differ from HEAD and untracked files,
+// relative to root.
+func changedFiles(ctx context.Context, root string) ([]string, error) {
+	tracked, err := gitLines(ctx, root, "diff", "--name-only", "--no-renames", "HEAD", "--")
+	if err != nil {
+		// A repository without commits has nothing to diff against.
+		tracked, err = gitLines(ctx, root, "diff", "--name-only", "--cached")
+		if err != nil {
+			return nil, err
+		}
+	}
+	untracked, err := gitLines(ctx, root, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	return slices.Compact(slices.Sorted(slices.Values(append(tracked, untracked...)))), nil
+}
+
+func scanFile(path string) []Hunk {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxScannedFileSize {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return ParseHunks(string(content))
+}
+
+func hasMarkerLine(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if isMarker(line, markerOurs) || isMarker(line, markerTheirs) {
+			return true
+		}
+	}
+	return false
+}
+
+func isMarker(line, marker string) bool {
+	return line == marker || strings.HasPrefix(line, marker+" ")
+}
+
+func markerLabel(line string) string {
+	return strings.TrimSpace(line[len(markerOurs):])
+}
+
+func labelOr(label, fallback string) string {
+	if label == "" {
+		return fallback
+	}
+	return label
+}
+
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "git %s failed: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
+
+func gitLines(ctx context.Context, dir string, args ...string) ([]string, error) {
+	output, err := gitOutput(ctx, dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(output), nil
+}
+
+func splitLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func hashFile(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "deleted"
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
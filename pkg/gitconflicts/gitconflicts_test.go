@@ -0,0 +1,214 @@
+package gitconflicts
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRepo struct {
+	t   *testing.T
+	dir string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	repo := &testRepo{t: t, dir: t.TempDir()}
+	repo.git("init", "-b", "main")
+	repo.git("config", "user.email", "test@example.com")
+	repo.git("config", "user.name", "Test User")
+	return repo
+}
+
+func (r *testRepo) git(args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(r.t, err, string(output))
+	return string(output)
+}
+
+func (r *testRepo) write(name, content string) {
+	r.t.Helper()
+	require.NoError(r.t, os.WriteFile(filepath.Join(r.dir, name), []byte(content), 0o644))
+}
+
+func (r *testRepo) read(name string) string {
+	r.t.Helper()
+	content, err := os.ReadFile(filepath.Join(r.dir, name))
+	require.NoError(r.t, err)
+	return string(content)
+}
+
+// conflictedRepo leaves main in the middle of a merge that conflicts in
+// main.go.
+func conflictedRepo(t *testing.T) *testRepo {
+	repo := newTestRepo(t)
+	repo.write("main.go", "package main\n\nconst greeting = \"hello\"\n\nfunc main() {}\n")
+	repo.write("notes.txt", "notes\n")
+	repo.git("add", ".")
+	repo.git("commit", "-m", "initial")
+
+	repo.git("checkout", "-b", "feature")
+	repo.write("main.go", "package main\n\nconst greeting = \"hi\"\n\nfunc main() {}\n")
+	repo.git("commit", "-am", "feature greeting")
+
+	repo.git("checkout", "main")
+	repo.write("main.go", "package main\n\nconst greeting = \"hey\"\n\nfunc main() {}\n")
+	repo.git("commit", "-am", "main greeting")
+
+	cmd := exec.Command("git", "merge", "feature")
+	cmd.Dir = repo.dir
+	require.Error(t, cmd.Run())
+	return repo
+}
+
+func TestDetectMergeConflict(t *testing.T) {
+	repo := conflictedRepo(t)
+
+	report, err := Detect(context.Background(), repo.dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, "merge", report.Operation)
+	assert.Equal(t, "main", report.Branch)
+	require.Len(t, report.Files, 1)
+	file := report.Files[0]
+	assert.Equal(t, "main.go", file.Path)
+	assert.True(t, file.Unmerged)
+	require.Len(t, file.Hunks, 1)
+	assert.Equal(t, 3, file.Hunks[0].StartLine)
+	assert.Equal(t, 7, file.Hunks[0].EndLine)
+	assert.Equal(t, "const greeting = \"hey\"\n", file.Hunks[0].Ours)
+	assert.Equal(t, "const greeting = \"hi\"\n", file.Hunks[0].Theirs)
+	assert.Equal(t, []string{
+		"A merge is in progress",
+		"main.go: 1 conflicting hunk (unmerged)",
+		"  lines 3-7: HEAD vs feature",
+	}, report.Summary())
+}
+
+func TestDetectStagedConflictMarkers(t *testing.T) {
+	repo := conflictedRepo(t)
+	repo.git("add", "main.go")
+
+	report, err := Detect(context.Background(), repo.dir)
+	require.NoError(t, err)
+
+	require.Len(t, report.Files, 1)
+	assert.False(t, report.Files[0].Unmerged)
+	assert.Contains(t, report.Summary()[1], "conflict markers left in file")
+}
+
+func TestDetectUpstreamDivergence(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("main.go", "package main\n\nconst greeting = \"hello\"\n")
+	repo.write("README.md", "readme\n")
+	repo.git("add", ".")
+	repo.git("commit", "-m", "initial")
+
+	repo.git("checkout", "-b", "upstream")
+	repo.write("main.go", "package main\n\nconst greeting = \"hi\"\n")
+	repo.write("README.md", "readme v2\n")
+	repo.git("commit", "-am", "upstream change")
+
+	repo.git("checkout", "-b", "work", "main")
+	repo.git("branch", "--set-upstream-to=upstream")
+	repo.write("main.go", "package main\n\nconst greeting = \"hey\"\n")
+	repo.git("commit", "-am", "local change")
+	repo.write("README.md", "readme local\n")
+
+	report, err := Detect(context.Background(), repo.dir)
+	require.NoError(t, err)
+
+	assert.False(t, report.HasConflicts())
+	assert.False(t, report.Empty())
+	assert.Equal(t, "upstream", report.Upstream)
+	assert.Equal(t, 1, report.Ahead)
+	assert.Equal(t, 1, report.Behind)
+	assert.Equal(t, []string{"main.go"}, report.UpstreamConflicts)
+	assert.Equal(t, []string{"README.md"}, report.UpstreamOverlaps)
+	assert.Contains(t, report.Summary(), "work is 1 ahead and 1 behind upstream")
+}
+
+func TestDetectCleanTree(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("main.go", "package main\n")
+	repo.git("add", ".")
+	repo.git("commit", "-m", "initial")
+
+	report, err := Detect(context.Background(), repo.dir)
+	require.NoError(t, err)
+	assert.True(t, report.Empty())
+}
+
+func TestParseHunksDiff3(t *testing.T) {
+	content := "a\n<<<<<<< HEAD\nours\n||||||| base\norig\n=======\ntheirs\n>>>>>>> branch\nb\n<<<<<<< unterminated\n"
+
+	hunks := ParseHunks(content)
+
+	require.Len(t, hunks, 1)
+	assert.Equal(t, "HEAD", hunks[0].OursLabel)
+	assert.Equal(t, "branch", hunks[0].TheirsLabel)
+	assert.Equal(t, "ours\n", hunks[0].Ours)
+	assert.Equal(t, "orig\n", hunks[0].Base)
+	assert.Equal(t, "theirs\n", hunks[0].Theirs)
+}
+
+func TestSnapshotVerify(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("accepts a resolution confined to the hunks", func(t *testing.T) {
+		repo := conflictedRepo(t)
+		report, err := Detect(ctx, repo.dir)
+		require.NoError(t, err)
+		snapshot, err := Capture(ctx, repo.dir, report)
+		require.NoError(t, err)
+
+		repo.write("main.go", "package main\n\nconst greeting = \"hey there\"\n\nfunc main() {}\n")
+		require.NoError(t, snapshot.Verify(ctx))
+		require.NoError(t, snapshot.MarkResolved(ctx))
+
+		report, err = Detect(ctx, repo.dir)
+		require.NoError(t, err)
+		assert.False(t, report.HasConflicts())
+	})
+
+	t.Run("rejects and undoes edits outside the hunks", func(t *testing.T) {
+		repo := conflictedRepo(t)
+		report, err := Detect(ctx, repo.dir)
+		require.NoError(t, err)
+		snapshot, err := Capture(ctx, repo.dir, report)
+		require.NoError(t, err)
+		original := repo.read("main.go")
+
+		repo.write("main.go", "package app\n\nconst greeting = \"hey\"\n\nfunc main() {}\n")
+		repo.write("notes.txt", "rewritten\n")
+		err = snapshot.Verify(ctx)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "main.go: changes were made outside the conflicting hunks")
+		assert.Contains(t, err.Error(), "notes.txt: changed outside the conflicted files")
+		assert.Equal(t, original, repo.read("main.go"))
+	})
+
+	t.Run("rejects leftover markers", func(t *testing.T) {
+		repo := conflictedRepo(t)
+		report, err := Detect(ctx, repo.dir)
+		require.NoError(t, err)
+		snapshot, err := Capture(ctx, repo.dir, report)
+		require.NoError(t, err)
+
+		repo.write("main.go", strings.Replace(repo.read("main.go"), "=======\n", "", 1))
+		err = snapshot.Verify(ctx)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "conflict markers remain")
+	})
+}
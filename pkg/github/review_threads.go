@@ -0,0 +1,335 @@
+// Package github reads and answers pull request review threads through the
+// GitHub GraphQL API. Requests go through the GitHub CLI (gh), so they use the
+// same authentication as kodelet's other GitHub commands.
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxThreadPages bounds how many pages of review threads ReviewThreads reads,
+// which is 5000 threads at 100 a page.
+const maxThreadPages = 50
+
+const reviewThreadsQuery = `query($owner: String!, $name: String!, $number: Int!, $after: String) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      reviewThreads(first: 100, after: $after) {
+        pageInfo { hasNextPage endCursor }
+        nodes {
+          id
+          path
+          line
+          startLine
+          originalLine
+          originalStartLine
+          diffSide
+          isResolved
+          isOutdated
+          comments(first: 100) {
+            nodes { ` + reviewCommentFields + ` }
+          }
+        }
+      }
+    }
+  }
+}`
+
+const replyMutation = `mutation($threadId: ID!, $body: String!) {
+  addPullRequestReviewThreadReply(input: {pullRequestReviewThreadId: $threadId, body: $body}) {
+    comment { ` + reviewCommentFields + ` }
+  }
+}`
+
+const resolveMutation = `mutation($threadId: ID!) {
+  resolveReviewThread(input: {threadId: $threadId}) {
+    thread { id isResolved }
+  }
+}`
+
+const reviewCommentFields = `id databaseId author { login } body url createdAt diffHunk`
+
+// Runner runs gh with args and returns its standard output.
+type Runner func(ctx context.Context, args ...string) (string, error)
+
+// GHRunner returns a Runner that executes gh in dir, or in the current
+// directory when dir is empty.
+func GHRunner(dir string) Runner {
+	return func(ctx context.Context, args ...string) (string, error) {
+		cmd := exec.CommandContext(ctx, "gh", args...)
+		cmd.Dir = dir
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", errors.Wrapf(err, "gh %s failed: %s", strings.Join(args[:min(len(args), 2)], " "), strings.TrimSpace(stderr.String()))
+		}
+		return string(out), nil
+	}
+}
+
+// PullRequest identifies a pull request.
+type PullRequest struct {
+	Owner  string
+	Repo   string
+	Number int
+}
+
+func (pr PullRequest) String() string {
+	return pr.Owner + "/" + pr.Repo + "#" + strconv.Itoa(pr.Number)
+}
+
+// ParsePullRequestURL parses a URL such as
+// https://github.com/owner/repo/pull/123.
+func ParsePullRequestURL(rawURL string) (PullRequest, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return PullRequest{}, errors.Wrapf(err, "invalid pull request URL %q", rawURL)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 4 || parts[2] != "pull" {
+		return PullRequest{}, errors.Errorf("invalid pull request URL %q: expected https://<host>/<owner>/<repo>/pull/<number>", rawURL)
+	}
+	number, err := strconv.Atoi(parts[3])
+	if err != nil || number <= 0 {
+		return PullRequest{}, errors.Errorf("invalid pull request number in URL %q", rawURL)
+	}
+	return PullRequest{Owner: parts[0], Repo: parts[1], Number: number}, nil
+}
+
+// ReviewComment is a comment in a review thread.
+type ReviewComment struct {
+	ID         string    `json:"id"`
+	DatabaseID int64     `json:"databaseId"`
+	Author     string    `json:"author"`
+	Body       string    `json:"body"`
+	URL        string    `json:"url"`
+	CreatedAt  time.Time `json:"createdAt"`
+	// DiffHunk is the part of the diff the comment was made on.
+	DiffHunk string `json:"diffHunk"`
+}
+
+// ReviewThread is a conversation on a line range of a pull request's diff.
+type ReviewThread struct {
+	ID   string
+	Path string
+	// Line and StartLine are the commented lines in the current version of
+	// the file. They are zero when the thread is outdated and its lines no
+	// longer exist; OriginalLine and OriginalStartLine are then the lines in
+	// the commit the thread was started on. StartLine is zero for single
+	// line comments.
+	Line              int
+	StartLine         int
+	OriginalLine      int
+	OriginalStartLine int
+	// DiffSide is LEFT for comments on removed lines and RIGHT otherwise.
+	DiffSide   string
+	IsResolved bool
+	IsOutdated bool
+	Comments   []ReviewComment
+}
+
+// Lines returns the first and last commented line, preferring the current
+// version of the file. Both are zero for comments on the whole file.
+func (t ReviewThread) Lines() (int, int) {
+	end, start := t.Line, t.StartLine
+	if end == 0 {
+		end, start = t.OriginalLine, t.OriginalStartLine
+	}
+	if start == 0 || start > end {
+		start = end
+	}
+	return start, end
+}
+
+// Unresolved returns the threads that have not been resolved.
+func Unresolved(threads []ReviewThread) []ReviewThread {
+	var unresolved []ReviewThread
+	for _, thread := range threads {
+		if !thread.IsResolved {
+			unresolved = append(unresolved, thread)
+		}
+	}
+	return unresolved
+}
+
+// Client reads and answers review threads.
+type Client struct {
+	gh Runner
+}
+
+// NewClient returns a client that sends its requests with gh.
+func NewClient(gh Runner) *Client {
+	return &Client{gh: gh}
+}
+
+// ReviewThreads returns all review threads of the pull request, resolved or
+// not, in the order GitHub lists them.
+func (c *Client) ReviewThreads(ctx context.Context, pr PullRequest) ([]ReviewThread, error) {
+	var threads []ReviewThread
+	cursor := ""
+	for range maxThreadPages {
+		args := []string{"-f", "owner=" + pr.Owner, "-f", "name=" + pr.Repo, "-F", "number=" + strconv.Itoa(pr.Number)}
+		if cursor != "" {
+			args = append(args, "-f", "after="+cursor)
+		}
+		var data struct {
+			Repository *struct {
+				PullRequest *struct {
+					ReviewThreads struct {
+						PageInfo struct {
+							HasNextPage bool   `json:"hasNextPage"`
+							EndCursor   string `json:"endCursor"`
+						} `json:"pageInfo"`
+						Nodes []reviewThreadNode `json:"nodes"`
+					} `json:"reviewThreads"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}
+		if err := c.graphql(ctx, reviewThreadsQuery, args, &data); err != nil {
+			return nil, errors.Wrapf(err, "failed to list review threads of %s", pr)
+		}
+		if data.Repository == nil || data.Repository.PullRequest == nil {
+			return nil, errors.Errorf("pull request %s not found", pr)
+		}
+		page := data.Repository.PullRequest.ReviewThreads
+		for _, node := range page.Nodes {
+			threads = append(threads, node.thread())
+		}
+		if !page.PageInfo.HasNextPage || page.PageInfo.EndCursor == "" {
+			return threads, nil
+		}
+		cursor = page.PageInfo.EndCursor
+	}
+	return threads, nil
+}
+
+// Reply posts body as a reply in the thread and returns the new comment.
+func (c *Client) Reply(ctx context.Context, threadID, body string) (ReviewComment, error) {
+	var data struct {
+		AddPullRequestReviewThreadReply struct {
+			Comment reviewCommentNode `json:"comment"`
+		} `json:"addPullRequestReviewThreadReply"`
+	}
+	if err := c.graphql(ctx, replyMutation, []string{"-f", "threadId=" + threadID, "-f", "body=" + body}, &data); err != nil {
+		return ReviewComment{}, errors.Wrapf(err, "failed to reply to review thread %s", threadID)
+	}
+	return data.AddPullRequestReviewThreadReply.Comment.comment(), nil
+}
+
+// Resolve marks the thread as resolved.
+func (c *Client) Resolve(ctx context.Context, threadID string) error {
+	var data struct {
+		ResolveReviewThread struct {
+			Thread struct {
+				IsResolved bool `json:"isResolved"`
+			} `json:"thread"`
+		} `json:"resolveReviewThread"`
+	}
+	if err := c.graphql(ctx, resolveMutation, []string{"-f", "threadId=" + threadID}, &data); err != nil {
+		return errors.Wrapf(err, "failed to resolve review thread %s", threadID)
+	}
+	if !data.ResolveReviewThread.Thread.IsResolved {
+		return errors.Errorf("review thread %s was not resolved", threadID)
+	}
+	return nil
+}
+
+// graphql runs query with the variables in args, which are gh api -f and -F
+// flags, and decodes the data of the response into out.
+func (c *Client) graphql(ctx context.Context, query string, args []string, out any) error {
+	stdout, err := c.gh(ctx, append([]string{"api", "graphql", "-f", "query=" + query}, args...)...)
+	if err != nil {
+		return err
+	}
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &response); err != nil {
+		return errors.Wrap(err, "failed to parse GraphQL response")
+	}
+	if len(response.Errors) > 0 {
+		messages := make([]string, 0, len(response.Errors))
+		for _, e := range response.Errors {
+			messages = append(messages, e.Message)
+		}
+		return errors.New(strings.Join(messages, "; "))
+	}
+	if len(response.Data) == 0 || string(response.Data) == "null" {
+		return errors.New("GraphQL response has no data")
+	}
+	return errors.Wrap(json.Unmarshal(response.Data, out), "failed to parse GraphQL data")
+}
+
+type reviewThreadNode struct {
+	ID                string `json:"id"`
+	Path              string `json:"path"`
+	Line              int    `json:"line"`
+	StartLine         int    `json:"startLine"`
+	OriginalLine      int    `json:"originalLine"`
+	OriginalStartLine int    `json:"originalStartLine"`
+	DiffSide          string `json:"diffSide"`
+	IsResolved        bool   `json:"isResolved"`
+	IsOutdated        bool   `json:"isOutdated"`
+	Comments          struct {
+		Nodes []reviewCommentNode `json:"nodes"`
+	} `json:"comments"`
+}
+
+func (n reviewThreadNode) thread() ReviewThread {
+	thread := ReviewThread{
+		ID:                n.ID,
+		Path:              n.Path,
+		Line:              n.Line,
+		StartLine:         n.StartLine,
+		OriginalLine:      n.OriginalLine,
+		OriginalStartLine: n.OriginalStartLine,
+		DiffSide:          n.DiffSide,
+		IsResolved:        n.IsResolved,
+		IsOutdated:        n.IsOutdated,
+	}
+	for _, comment := range n.Comments.Nodes {
+		thread.Comments = append(thread.Comments, comment.comment())
+	}
+	return thread
+}
+
+type reviewCommentNode struct {
+	ID         string `json:"id"`
+	DatabaseID int64  `json:"databaseId"`
+	Author     *struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Body      string    `json:"body"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"createdAt"`
+	DiffHunk  string    `json:"diffHunk"`
+}
+
+func (n reviewCommentNode) comment() ReviewComment {
+	// Comments of deleted accounts have no author.
+	author := "ghost"
+	if n.Author != nil && n.Author.Login != "" {
+		author = n.Author.Login
+	}
+	return ReviewComment{
+		ID:         n.ID,
+		DatabaseID: n.DatabaseID,
+		Author:     author,
+		Body:       n.Body,
+		URL:        n.URL,
+		CreatedAt:  n.CreatedAt,
+		DiffHunk:   n.DiffHunk,
+	}
+}
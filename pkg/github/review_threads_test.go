@@ -0,0 +1,137 @@
+package github
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGH answers gh calls with the queued responses and records the
+// arguments of each call.
+type fakeGH struct {
+	responses []string
+	calls     [][]string
+}
+
+func (f *fakeGH) run(_ context.Context, args ...string) (string, error) {
+	f.calls = append(f.calls, args)
+	if len(f.responses) == 0 {
+		return "", errors.New("unexpected gh call")
+	}
+	response := f.responses[0]
+	f.responses = f.responses[1:]
+	return response, nil
+}
+
+// variable returns the value of a -f or -F variable of a recorded call.
+func variable(args []string, name string) (string, bool) {
+	for i := 1; i < len(args); i++ {
+		if args[i-1] != "-f" && args[i-1] != "-F" {
+			continue
+		}
+		if key, value, ok := strings.Cut(args[i], "="); ok && key == name {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func TestParsePullRequestURL(t *testing.T) {
+	pr, err := ParsePullRequestURL("https://github.com/jingkaihe/kodelet/pull/42/files")
+	require.NoError(t, err)
+	assert.Equal(t, PullRequest{Owner: "jingkaihe", Repo: "kodelet", Number: 42}, pr)
+	assert.Equal(t, "jingkaihe/kodelet#42", pr.String())
+
+	for _, invalid := range []string{"https://github.com/jingkaihe/kodelet/issues/42", "https://github.com/jingkaihe/kodelet/pull/abc", "https://github.com/jingkaihe"} {
+		_, err := ParsePullRequestURL(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestReviewThreadLines(t *testing.T) {
+	start, end := ReviewThread{Line: 12, StartLine: 10, OriginalLine: 8}.Lines()
+	assert.Equal(t, []int{10, 12}, []int{start, end})
+
+	start, end = ReviewThread{OriginalLine: 8, IsOutdated: true}.Lines()
+	assert.Equal(t, []int{8, 8}, []int{start, end}, "outdated threads fall back to the original line")
+
+	start, end = ReviewThread{}.Lines()
+	assert.Equal(t, []int{0, 0}, []int{start, end})
+}
+
+func TestClientReviewThreads(t *testing.T) {
+	gh := &fakeGH{responses: []string{
+		`{"data":{"repository":{"pullRequest":{"reviewThreads":{
+			"pageInfo":{"hasNextPage":true,"endCursor":"cursor-1"},
+			"nodes":[{"id":"T1","path":"pkg/a.go","line":12,"startLine":10,"originalLine":12,"diffSide":"RIGHT","isResolved":false,"isOutdated":false,
+				"comments":{"nodes":[{"id":"C1","databaseId":101,"author":{"login":"alice"},"body":"Handle the error","url":"https://github.com/o/r/pull/7#discussion_r101","createdAt":"2026-10-01T10:00:00Z","diffHunk":"@@ -1 +1 @@"}]}}]}}}}}`,
+		`{"data":{"repository":{"pullRequest":{"reviewThreads":{
+			"pageInfo":{"hasNextPage":false,"endCursor":"cursor-2"},
+			"nodes":[{"id":"T2","path":"pkg/b.go","line":null,"originalLine":3,"isResolved":true,"isOutdated":true,
+				"comments":{"nodes":[{"id":"C2","databaseId":102,"author":null,"body":"Nit","url":"u","createdAt":"2026-10-01T11:00:00Z"}]}}]}}}}}`,
+	}}
+	client := NewClient(gh.run)
+
+	threads, err := client.ReviewThreads(context.Background(), PullRequest{Owner: "o", Repo: "r", Number: 7})
+	require.NoError(t, err)
+	require.Len(t, threads, 2)
+
+	assert.Equal(t, "T1", threads[0].ID)
+	assert.Equal(t, "pkg/a.go", threads[0].Path)
+	assert.Equal(t, 12, threads[0].Line)
+	assert.Equal(t, 10, threads[0].StartLine)
+	require.Len(t, threads[0].Comments, 1)
+	assert.Equal(t, "alice", threads[0].Comments[0].Author)
+	assert.Equal(t, int64(101), threads[0].Comments[0].DatabaseID)
+	assert.Equal(t, "ghost", threads[1].Comments[0].Author)
+	assert.True(t, threads[1].IsOutdated)
+
+	require.Len(t, gh.calls, 2)
+	assert.Equal(t, []string{"api", "graphql"}, gh.calls[0][:2])
+	number, _ := variable(gh.calls[0], "number")
+	assert.Equal(t, "7", number)
+	_, hasCursor := variable(gh.calls[0], "after")
+	assert.False(t, hasCursor)
+	cursor, _ := variable(gh.calls[1], "after")
+	assert.Equal(t, "cursor-1", cursor)
+
+	unresolved := Unresolved(threads)
+	require.Len(t, unresolved, 1)
+	assert.Equal(t, "T1", unresolved[0].ID)
+}
+
+func TestClientReviewThreadsErrors(t *testing.T) {
+	gh := &fakeGH{responses: []string{`{"data":{"repository":{"pullRequest":null}},"errors":[{"message":"Could not resolve to a PullRequest with the number of 7."}]}`}}
+	_, err := NewClient(gh.run).ReviewThreads(context.Background(), PullRequest{Owner: "o", Repo: "r", Number: 7})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Could not resolve to a PullRequest")
+
+	gh = &fakeGH{responses: []string{`{"data":{"repository":null}}`}}
+	_, err = NewClient(gh.run).ReviewThreads(context.Background(), PullRequest{Owner: "o", Repo: "r", Number: 7})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "pull request o/r#7 not found")
+}
+
+func TestClientReplyAndResolve(t *testing.T) {
+	gh := &fakeGH{responses: []string{
+		`{"data":{"addPullRequestReviewThreadReply":{"comment":{"id":"C9","databaseId":109,"author":{"login":"kodelet-bot"},"body":"Done","url":"https://github.com/o/r/pull/7#discussion_r109"}}}}`,
+		`{"data":{"resolveReviewThread":{"thread":{"id":"T1","isResolved":true}}}}`,
+	}}
+	client := NewClient(gh.run)
+
+	comment, err := client.Reply(context.Background(), "T1", "Done")
+	require.NoError(t, err)
+	assert.Equal(t, "https://github.com/o/r/pull/7#discussion_r109", comment.URL)
+	threadID, _ := variable(gh.calls[0], "threadId")
+	assert.Equal(t, "T1", threadID)
+	body, _ := variable(gh.calls[0], "body")
+	assert.Equal(t, "Done", body)
+
+	require.NoError(t, client.Resolve(context.Background(), "T1"))
+	threadID, _ = variable(gh.calls[1], "threadId")
+	assert.Equal(t, "T1", threadID)
+}
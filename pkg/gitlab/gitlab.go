@@ -0,0 +1,233 @@
+// Package gitlab works with merge requests, issues and pipelines of GitLab
+// projects through the GitLab REST API. Requests go through the GitLab CLI
+// (glab), so they use its authentication, including for self-hosted
+// instances.
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/url"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// pageSize is the number of items requested per page.
+	pageSize = 100
+	// maxPages bounds how many pages a list reads.
+	maxPages = 20
+	// draftPrefix marks a merge request as a draft.
+	draftPrefix = "Draft: "
+)
+
+// Runner runs glab with args and returns its standard output.
+type Runner func(ctx context.Context, args ...string) (string, error)
+
+// GLabRunner returns a Runner that executes glab in dir, or in the current
+// directory when dir is empty.
+func GLabRunner(dir string) Runner {
+	return func(ctx context.Context, args ...string) (string, error) {
+		cmd := exec.CommandContext(ctx, "glab", args...)
+		cmd.Dir = dir
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", errors.Wrapf(err, "glab %s failed: %s", strings.Join(args[:min(len(args), 2)], " "), strings.TrimSpace(stderr.String()))
+		}
+		return string(out), nil
+	}
+}
+
+// MergeRequest is a GitLab merge request.
+type MergeRequest struct {
+	IID    int    `json:"iid"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+	WebURL string `json:"web_url"`
+}
+
+// Issue is a GitLab issue.
+type Issue struct {
+	IID         int      `json:"iid"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	WebURL      string   `json:"web_url"`
+	Labels      []string `json:"labels"`
+}
+
+// Label is a label of a GitLab project.
+type Label struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Pipeline is a CI pipeline run.
+type Pipeline struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	Ref    string `json:"ref"`
+	SHA    string `json:"sha"`
+	WebURL string `json:"web_url"`
+}
+
+// MergeRequestOptions describes a merge request to open.
+type MergeRequestOptions struct {
+	SourceBranch string
+	TargetBranch string
+	Title        string
+	Description  string
+	Draft        bool
+}
+
+// Client talks to the GitLab REST API of one instance.
+type Client struct {
+	glab Runner
+	host string
+}
+
+// NewClient returns a client that sends its requests with glab. host selects
+// a self-hosted instance such as gitlab.example.com; when empty, glab uses
+// the instance of the current repository's remote.
+func NewClient(glab Runner, host string) *Client {
+	return &Client{glab: glab, host: strings.TrimSpace(host)}
+}
+
+// CreateMergeRequest opens a merge request in project, which is a path such
+// as group/project or empty for the project of the current repository.
+func (c *Client) CreateMergeRequest(ctx context.Context, project string, opts MergeRequestOptions) (MergeRequest, error) {
+	title := opts.Title
+	if opts.Draft && !strings.HasPrefix(title, draftPrefix) {
+		title = draftPrefix + title
+	}
+	var mr MergeRequest
+	err := c.request(ctx, "POST", projectPath(project)+"/merge_requests", map[string]string{
+		"source_branch": opts.SourceBranch,
+		"target_branch": opts.TargetBranch,
+		"title":         title,
+		"description":   opts.Description,
+	}, &mr)
+	if err != nil {
+		return MergeRequest{}, errors.Wrap(err, "failed to create merge request")
+	}
+	return mr, nil
+}
+
+// CreateMergeRequestNote posts a comment on the merge request.
+func (c *Client) CreateMergeRequestNote(ctx context.Context, project string, iid int, body string) error {
+	endpoint := projectPath(project) + "/merge_requests/" + strconv.Itoa(iid) + "/notes"
+	return errors.Wrapf(c.request(ctx, "POST", endpoint, map[string]string{"body": body}, nil), "failed to comment on merge request !%d", iid)
+}
+
+// ListOpenIssues returns up to limit open issues of the project.
+func (c *Client) ListOpenIssues(ctx context.Context, project string, limit int) ([]Issue, error) {
+	issues, err := list[Issue](ctx, c, projectPath(project)+"/issues?state=opened", limit)
+	return issues, errors.Wrap(err, "failed to list open issues")
+}
+
+// ListLabels returns up to limit labels of the project.
+func (c *Client) ListLabels(ctx context.Context, project string, limit int) ([]Label, error) {
+	labels, err := list[Label](ctx, c, projectPath(project)+"/labels", limit)
+	return labels, errors.Wrap(err, "failed to list labels")
+}
+
+// CreateLabel creates a project label. color is a hex color such as #0E8A16.
+func (c *Client) CreateLabel(ctx context.Context, project, name, color, description string) error {
+	fields := map[string]string{"name": name, "color": color}
+	if description != "" {
+		fields["description"] = description
+	}
+	return errors.Wrapf(c.request(ctx, "POST", projectPath(project)+"/labels", fields, nil), "failed to create label %s", name)
+}
+
+// AddIssueLabels adds labels to the issue, keeping its other labels.
+func (c *Client) AddIssueLabels(ctx context.Context, project string, iid int, labels []string) error {
+	endpoint := projectPath(project) + "/issues/" + strconv.Itoa(iid)
+	return errors.Wrapf(c.request(ctx, "PUT", endpoint, map[string]string{"add_labels": strings.Join(labels, ",")}, nil), "failed to label issue #%d", iid)
+}
+
+// CreateIssueNote posts a comment on the issue.
+func (c *Client) CreateIssueNote(ctx context.Context, project string, iid int, body string) error {
+	endpoint := projectPath(project) + "/issues/" + strconv.Itoa(iid) + "/notes"
+	return errors.Wrapf(c.request(ctx, "POST", endpoint, map[string]string{"body": body}, nil), "failed to comment on issue #%d", iid)
+}
+
+// LatestPipeline returns the most recent pipeline of ref. It returns false
+// when ref has no pipeline, for example because CI is not configured.
+func (c *Client) LatestPipeline(ctx context.Context, project, ref string) (Pipeline, bool, error) {
+	endpoint := projectPath(project) + "/pipelines?" + url.Values{
+		"ref":      {ref},
+		"order_by": {"id"},
+		"sort":     {"desc"},
+		"per_page": {"1"},
+	}.Encode()
+	var pipelines []Pipeline
+	if err := c.request(ctx, "GET", endpoint, nil, &pipelines); err != nil {
+		return Pipeline{}, false, errors.Wrapf(err, "failed to get the pipeline of %s", ref)
+	}
+	if len(pipelines) == 0 {
+		return Pipeline{}, false, nil
+	}
+	return pipelines[0], true, nil
+}
+
+// list reads the pages of a list endpoint until limit items are read or the
+// list ends.
+func list[T any](ctx context.Context, c *Client, endpoint string, limit int) ([]T, error) {
+	separator := "?"
+	if strings.Contains(endpoint, "?") {
+		separator = "&"
+	}
+	var items []T
+	for page := 1; page <= maxPages && len(items) < limit; page++ {
+		var batch []T
+		if err := c.request(ctx, "GET", endpoint+separator+"per_page="+strconv.Itoa(pageSize)+"&page="+strconv.Itoa(page), nil, &batch); err != nil {
+			return nil, err
+		}
+		items = append(items, batch...)
+		if len(batch) < pageSize {
+			break
+		}
+	}
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+// request calls the endpoint with fields as the request parameters and
+// decodes the JSON response into out unless it is nil.
+func (c *Client) request(ctx context.Context, method, endpoint string, fields map[string]string, out any) error {
+	args := []string{"api"}
+	if c.host != "" {
+		args = append(args, "--hostname", c.host)
+	}
+	args = append(args, "--method", method, endpoint)
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		args = append(args, "--raw-field", key+"="+fields[key])
+	}
+	stdout, err := c.glab(ctx, args...)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return errors.Wrap(json.Unmarshal([]byte(stdout), out), "failed to parse GitLab response")
+}
+
+// projectPath returns the API path of project. glab fills in the :id
+// placeholder with the project of the current repository.
+func projectPath(project string) string {
+	project = strings.Trim(strings.TrimSpace(project), "/")
+	if project == "" {
+		return "projects/:id"
+	}
+	return "projects/" + url.PathEscape(project)
+}
@@ -0,0 +1,128 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGLab answers glab calls with respond and records their arguments.
+type fakeGLab struct {
+	calls   [][]string
+	respond func(args []string) (string, error)
+}
+
+func (f *fakeGLab) run(_ context.Context, args ...string) (string, error) {
+	f.calls = append(f.calls, args)
+	if f.respond == nil {
+		return "{}", nil
+	}
+	return f.respond(args)
+}
+
+func TestCreateMergeRequest(t *testing.T) {
+	glab := &fakeGLab{respond: func([]string) (string, error) {
+		return `{"iid": 12, "title": "Draft: Add retries", "state": "opened", "web_url": "https://gitlab.example.com/group/sub/project/-/merge_requests/12"}`, nil
+	}}
+	client := NewClient(glab.run, "gitlab.example.com")
+
+	mr, err := client.CreateMergeRequest(context.Background(), "group/sub/project", MergeRequestOptions{
+		SourceBranch: "kodelet/abc",
+		TargetBranch: "main",
+		Title:        "Add retries",
+		Description:  "Retries failed requests.",
+		Draft:        true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 12, mr.IID)
+	assert.Equal(t, "https://gitlab.example.com/group/sub/project/-/merge_requests/12", mr.WebURL)
+
+	require.Len(t, glab.calls, 1)
+	assert.Equal(t, []string{
+		"api", "--hostname", "gitlab.example.com", "--method", "POST", "projects/group%2Fsub%2Fproject/merge_requests",
+		"--raw-field", "description=Retries failed requests.",
+		"--raw-field", "source_branch=kodelet/abc",
+		"--raw-field", "target_branch=main",
+		"--raw-field", "title=Draft: Add retries",
+	}, glab.calls[0])
+}
+
+func TestNotesAndLabels(t *testing.T) {
+	glab := &fakeGLab{}
+	client := NewClient(glab.run, "")
+
+	require.NoError(t, client.CreateMergeRequestNote(context.Background(), "", 12, "Cost: $0.10"))
+	require.NoError(t, client.AddIssueLabels(context.Background(), "", 4, []string{"bug", "triaged"}))
+	require.NoError(t, client.CreateIssueNote(context.Background(), "", 4, "Triaged"))
+	require.NoError(t, client.CreateLabel(context.Background(), "", "triaged", "#0E8A16", ""))
+
+	assert.Equal(t, []string{"api", "--method", "POST", "projects/:id/merge_requests/12/notes", "--raw-field", "body=Cost: $0.10"}, glab.calls[0])
+	assert.Equal(t, []string{"api", "--method", "PUT", "projects/:id/issues/4", "--raw-field", "add_labels=bug,triaged"}, glab.calls[1])
+	assert.Equal(t, []string{"api", "--method", "POST", "projects/:id/issues/4/notes", "--raw-field", "body=Triaged"}, glab.calls[2])
+	assert.Equal(t, []string{"api", "--method", "POST", "projects/:id/labels", "--raw-field", "color=#0E8A16", "--raw-field", "name=triaged"}, glab.calls[3])
+
+	glab.respond = func([]string) (string, error) {
+		return "", errors.New("glab api failed: 403 Forbidden")
+	}
+	err := client.CreateIssueNote(context.Background(), "", 4, "Triaged")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to comment on issue #4")
+	assert.Contains(t, err.Error(), "403 Forbidden")
+}
+
+func TestListOpenIssuesPaginates(t *testing.T) {
+	page := func(start, count int) string {
+		issues := make([]string, 0, count)
+		for i := range count {
+			issues = append(issues, fmt.Sprintf(`{"iid": %d, "title": "Issue %d", "labels": ["bug"]}`, start+i, start+i))
+		}
+		return "[" + strings.Join(issues, ",") + "]"
+	}
+	glab := &fakeGLab{respond: func(args []string) (string, error) {
+		endpoint := args[len(args)-1]
+		switch {
+		case strings.HasSuffix(endpoint, "&page=1"):
+			return page(1, pageSize), nil
+		case strings.HasSuffix(endpoint, "&page=2"):
+			return page(pageSize+1, 5), nil
+		}
+		return "[]", nil
+	}}
+	client := NewClient(glab.run, "")
+
+	issues, err := client.ListOpenIssues(context.Background(), "group/project", 500)
+	require.NoError(t, err)
+	assert.Len(t, issues, pageSize+5)
+	assert.Equal(t, []string{"bug"}, issues[0].Labels)
+	require.Len(t, glab.calls, 2)
+	assert.Equal(t, "projects/group%2Fproject/issues?state=opened&per_page=100&page=1", glab.calls[0][len(glab.calls[0])-1])
+
+	glab.calls = nil
+	issues, err = client.ListOpenIssues(context.Background(), "group/project", 10)
+	require.NoError(t, err)
+	assert.Len(t, issues, 10)
+	assert.Len(t, glab.calls, 1)
+}
+
+func TestLatestPipeline(t *testing.T) {
+	glab := &fakeGLab{respond: func([]string) (string, error) {
+		return `[{"id": 77, "status": "running", "ref": "kodelet/abc", "sha": "abc123", "web_url": "https://gitlab.com/o/r/-/pipelines/77"}]`, nil
+	}}
+	client := NewClient(glab.run, "")
+
+	pipeline, ok, err := client.LatestPipeline(context.Background(), "", "kodelet/abc")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, Pipeline{ID: 77, Status: "running", Ref: "kodelet/abc", SHA: "abc123", WebURL: "https://gitlab.com/o/r/-/pipelines/77"}, pipeline)
+	assert.Equal(t, "projects/:id/pipelines?order_by=id&per_page=1&ref=kodelet%2Fabc&sort=desc", glab.calls[0][len(glab.calls[0])-1])
+
+	glab.respond = func([]string) (string, error) { return "[]", nil }
+	_, ok, err = client.LatestPipeline(context.Background(), "", "main")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
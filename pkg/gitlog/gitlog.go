@@ -0,0 +1,284 @@
+// Package gitlog reads the commits in a revision range and groups them by the
+// area of the repository they touch, for drafting release notes.
+package gitlog
+
+import (
+	"context"
+	"os/exec"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxCommits bounds how many commits Log reads when Options.MaxCommits
+// is not set.
+const DefaultMaxCommits = 500
+
+const (
+	recordSeparator = "\x1e"
+	fieldSeparator  = "\x1f"
+	logFormat       = recordSeparator + "%H" + fieldSeparator + "%an" + fieldSeparator + "%aI" + fieldSeparator + "%s" + fieldSeparator + "%b" + fieldSeparator
+)
+
+// nestedAreaRoots are top-level directories whose subdirectories are areas
+// of their own, such as pkg/llm or cmd/kodelet.
+var nestedAreaRoots = []string{"pkg", "cmd", "internal", "src", "lib", "apps", "packages", "services"}
+
+var (
+	conventionalPattern = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^)]+)\))?!?:\s`)
+	pullRequestPattern  = regexp.MustCompile(`(?i)(?:\(#(\d+)\)|pull request #(\d+))`)
+	issuePattern        = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?)\s+#(\d+)`)
+	githubRemotePattern = regexp.MustCompile(`^(?:https?://|ssh://git@|git@)github\.com[:/]([^/]+)/([^/]+?)(?:\.git)?/?$`)
+)
+
+// Options selects the commits to read.
+type Options struct {
+	// Dir is the working tree to read the history of.
+	Dir string
+	// From is the exclusive start of the range. Empty means the latest tag
+	// before To, or the whole history when there is none.
+	From string
+	// To is the inclusive end of the range. Empty means HEAD.
+	To string
+	// MaxCommits caps the number of commits read. Zero means
+	// DefaultMaxCommits.
+	MaxCommits int
+}
+
+// Commit is one non-merge commit in the range.
+type Commit struct {
+	Hash    string    `json:"hash"`
+	Subject string    `json:"subject"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+	// Type is the conventional commit type, such as feat or fix, when the
+	// subject follows that format.
+	Type   string   `json:"type,omitempty"`
+	Area   string   `json:"area"`
+	Files  []string `json:"files,omitempty"`
+	PRs    []int    `json:"prs,omitempty"`
+	Issues []int    `json:"issues,omitempty"`
+}
+
+// Area is the group of commits touching one area of the repository.
+type Area struct {
+	Name    string   `json:"name"`
+	Commits []Commit `json:"commits"`
+}
+
+// Log is the grouped history of a revision range.
+type Log struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to"`
+	// RepoURL is the GitHub repository the origin remote points at, used to
+	// link pull requests and issues. It is empty for other hosts.
+	RepoURL   string `json:"repo_url,omitempty"`
+	Areas     []Area `json:"areas"`
+	Total     int    `json:"total"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// PullRequestURL returns the link to pull request number, or "" when the
+// repository is not on GitHub.
+func (l Log) PullRequestURL(number int) string {
+	if l.RepoURL == "" {
+		return ""
+	}
+	return l.RepoURL + "/pull/" + strconv.Itoa(number)
+}
+
+// IssueURL returns the link to issue number, or "" when the repository is not
+// on GitHub.
+func (l Log) IssueURL(number int) string {
+	if l.RepoURL == "" {
+		return ""
+	}
+	return l.RepoURL + "/issues/" + strconv.Itoa(number)
+}
+
+// Read returns the non-merge commits between opts.From and opts.To grouped by
+// area. Areas are ordered by commit count and commits by date, newest first.
+func Read(ctx context.Context, opts Options) (Log, error) {
+	to := strings.TrimSpace(opts.To)
+	if to == "" {
+		to = "HEAD"
+	}
+	from := strings.TrimSpace(opts.From)
+	if from == "" {
+		from = LatestTag(ctx, opts.Dir, to)
+	}
+	maxCommits := opts.MaxCommits
+	if maxCommits <= 0 {
+		maxCommits = DefaultMaxCommits
+	}
+
+	revRange := to
+	if from != "" {
+		revRange = from + ".." + to
+	}
+	output, err := gitOutput(ctx, opts.Dir, "log", "--no-merges", "--name-only",
+		"--max-count="+strconv.Itoa(maxCommits+1), "--format="+logFormat, revRange, "--")
+	if err != nil {
+		return Log{}, err
+	}
+
+	commits := parseLog(output)
+	log := Log{From: from, To: to, RepoURL: repoURL(ctx, opts.Dir)}
+	if len(commits) > maxCommits {
+		commits = commits[:maxCommits]
+		log.Truncated = true
+	}
+	log.Total = len(commits)
+	log.Areas = groupByArea(commits)
+	return log, nil
+}
+
+// LatestTag returns the most recent tag reachable from the parent of rev, so
+// a release commit that is already tagged still reports the previous release.
+// It returns "" when there is no such tag.
+func LatestTag(ctx context.Context, dir, rev string) string {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	output, err := gitOutput(ctx, dir, "describe", "--tags", "--abbrev=0", rev+"^")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(output)
+}
+
+func parseLog(output string) []Commit {
+	var commits []Commit
+	for _, record := range strings.Split(output, recordSeparator) {
+		fields := strings.SplitN(record, fieldSeparator, 6)
+		if len(fields) < 6 {
+			continue
+		}
+		commit := Commit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Subject: strings.TrimSpace(fields[3]),
+		}
+		commit.Date, _ = time.Parse(time.RFC3339, fields[2])
+		for _, line := range strings.Split(fields[5], "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				commit.Files = append(commit.Files, line)
+			}
+		}
+
+		scope := ""
+		if match := conventionalPattern.FindStringSubmatch(commit.Subject); match != nil {
+			commit.Type = strings.ToLower(match[1])
+			scope = match[2]
+		}
+		commit.Area = commitArea(scope, commit.Files)
+
+		message := commit.Subject + "\n" + fields[4]
+		for _, match := range pullRequestPattern.FindAllStringSubmatch(message, -1) {
+			commit.PRs = appendNumber(commit.PRs, match[1]+match[2])
+		}
+		for _, match := range issuePattern.FindAllStringSubmatch(message, -1) {
+			if number, err := strconv.Atoi(match[1]); err == nil && !slices.Contains(commit.PRs, number) {
+				commit.Issues = appendNumber(commit.Issues, match[1])
+			}
+		}
+		commits = append(commits, commit)
+	}
+	return commits
+}
+
+func appendNumber(numbers []int, value string) []int {
+	number, err := strconv.Atoi(value)
+	if err != nil || slices.Contains(numbers, number) {
+		return numbers
+	}
+	return append(numbers, number)
+}
+
+// commitArea is the conventional commit scope when there is one, otherwise
+// the area most of the commit's files belong to.
+func commitArea(scope string, files []string) string {
+	if scope = strings.TrimSpace(scope); scope != "" {
+		return scope
+	}
+	counts := map[string]int{}
+	best := ""
+	for _, file := range files {
+		area := FileArea(file)
+		counts[area]++
+		if best == "" || counts[area] > counts[best] || (counts[area] == counts[best] && area < best) {
+			best = area
+		}
+	}
+	if best == "" {
+		return "other"
+	}
+	return best
+}
+
+// FileArea returns the area a repository path belongs to: its top-level
+// directory, or the first two directories under pkg, cmd and similar roots.
+// Files at the repository root belong to "root".
+func FileArea(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(parts) == 1:
+		return "root"
+	case len(parts) > 2 && slices.Contains(nestedAreaRoots, parts[0]):
+		return parts[0] + "/" + parts[1]
+	default:
+		return parts[0]
+	}
+}
+
+func groupByArea(commits []Commit) []Area {
+	index := map[string]int{}
+	var areas []Area
+	for _, commit := range commits {
+		i, ok := index[commit.Area]
+		if !ok {
+			i = len(areas)
+			index[commit.Area] = i
+			areas = append(areas, Area{Name: commit.Area})
+		}
+		areas[i].Commits = append(areas[i].Commits, commit)
+	}
+	sort.SliceStable(areas, func(i, j int) bool {
+		if len(areas[i].Commits) != len(areas[j].Commits) {
+			return len(areas[i].Commits) > len(areas[j].Commits)
+		}
+		return areas[i].Name < areas[j].Name
+	})
+	return areas
+}
+
+// repoURL returns the https URL of the GitHub repository behind the origin
+// remote, or "" when origin is missing or not on GitHub.
+func repoURL(ctx context.Context, dir string) string {
+	output, err := gitOutput(ctx, dir, "remote", "get-url", "origin")
+	if err != nil {
+		return ""
+	}
+	match := githubRemotePattern.FindStringSubmatch(strings.TrimSpace(output))
+	if match == nil {
+		return ""
+	}
+	return "https://github.com/" + match[1] + "/" + match[2]
+}
+
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "git %s failed: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
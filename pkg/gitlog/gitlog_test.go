@@ -0,0 +1,138 @@
+package gitlog
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRepo struct {
+	t   *testing.T
+	dir string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	repo := &testRepo{t: t, dir: t.TempDir()}
+	repo.git("init", "-b", "main")
+	repo.git("config", "user.email", "test@example.com")
+	repo.git("config", "user.name", "Test User")
+	return repo
+}
+
+func (r *testRepo) git(args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(r.t, err, string(output))
+	return string(output)
+}
+
+func (r *testRepo) commit(message string, files ...string) {
+	r.t.Helper()
+	for _, name := range files {
+		path := filepath.Join(r.dir, name)
+		require.NoError(r.t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(r.t, os.WriteFile(path, []byte(message), 0o644))
+	}
+	r.git("add", ".")
+	r.git("commit", "-m", message)
+}
+
+func TestReadGroupsCommitsSinceLatestTag(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("initial", "README.md")
+	repo.git("tag", "v0.1.0")
+	repo.commit("Add streaming to the OpenAI thread (#12)", "pkg/llm/openai/openai.go", "pkg/llm/base/base.go", "pkg/llm/openai/openai_test.go")
+	repo.commit("fix(tui): restore the cursor on exit\n\nFixes #7", "pkg/tui/model.go")
+	repo.commit("Tidy the OpenAI retry loop", "pkg/llm/openai/retry.go")
+	repo.commit("Document release notes", "docs/MANUAL.md")
+	repo.git("remote", "add", "origin", "git@github.com:acme/widget.git")
+
+	log, err := Read(context.Background(), Options{Dir: repo.dir})
+	require.NoError(t, err)
+
+	assert.Equal(t, "v0.1.0", log.From)
+	assert.Equal(t, "HEAD", log.To)
+	assert.Equal(t, "https://github.com/acme/widget", log.RepoURL)
+	assert.Equal(t, 4, log.Total)
+	assert.False(t, log.Truncated)
+
+	require.Len(t, log.Areas, 3)
+	assert.Equal(t, "pkg/llm", log.Areas[0].Name)
+	require.Len(t, log.Areas[0].Commits, 2)
+	assert.Equal(t, "Tidy the OpenAI retry loop", log.Areas[0].Commits[0].Subject)
+	assert.Equal(t, []int{12}, log.Areas[0].Commits[1].PRs)
+	assert.Equal(t, "docs", log.Areas[1].Name)
+	assert.Equal(t, "tui", log.Areas[2].Name)
+
+	fix := log.Areas[2].Commits[0]
+	assert.Equal(t, "fix", fix.Type)
+	assert.Equal(t, []int{7}, fix.Issues)
+	assert.Equal(t, "Test User", fix.Author)
+	assert.Equal(t, []string{"pkg/tui/model.go"}, fix.Files)
+	assert.False(t, fix.Date.IsZero())
+
+	assert.Equal(t, "https://github.com/acme/widget/pull/12", log.PullRequestURL(12))
+	assert.Equal(t, "https://github.com/acme/widget/issues/7", log.IssueURL(7))
+}
+
+func TestReadExplicitRangeAndLimit(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("one", "a.txt")
+	repo.git("tag", "v1")
+	repo.commit("two", "b.txt")
+	repo.commit("three", "c.txt")
+	repo.git("tag", "v2")
+	repo.commit("four", "d.txt")
+
+	log, err := Read(context.Background(), Options{Dir: repo.dir, From: "v1", To: "v2"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, log.Total)
+	assert.Empty(t, log.RepoURL)
+	assert.Empty(t, log.PullRequestURL(1))
+
+	log, err = Read(context.Background(), Options{Dir: repo.dir, From: "v1", MaxCommits: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 1, log.Total)
+	assert.True(t, log.Truncated)
+	assert.Equal(t, "four", log.Areas[0].Commits[0].Subject)
+
+	assert.Equal(t, "v1", LatestTag(context.Background(), repo.dir, "v2"))
+
+	_, err = Read(context.Background(), Options{Dir: repo.dir, From: "missing"})
+	assert.Error(t, err)
+}
+
+func TestReadWholeHistoryWithoutTags(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.commit("one", "a.txt")
+	repo.commit("two", "b.txt")
+
+	log, err := Read(context.Background(), Options{Dir: repo.dir})
+	require.NoError(t, err)
+	assert.Empty(t, log.From)
+	assert.Equal(t, 2, log.Total)
+	require.Len(t, log.Areas, 1)
+	assert.Equal(t, "root", log.Areas[0].Name)
+}
+
+func TestFileArea(t *testing.T) {
+	tests := map[string]string{
+		"README.md":                "root",
+		"docs/MANUAL.md":           "docs",
+		"pkg/llm/openai/openai.go": "pkg/llm",
+		"pkg/doc.go":               "pkg",
+		"cmd/kodelet/main.go":      "cmd/kodelet",
+		"sdk/python/client.py":     "sdk",
+	}
+	for path, area := range tests {
+		assert.Equal(t, area, FileArea(path), path)
+	}
+}
@@ -0,0 +1,67 @@
+// Package i18n resolves the configured output language and holds the
+// translations of the chat UI's fixed strings.
+package i18n
+
+import "strings"
+
+// English is the default language.
+const English = "en"
+
+type language struct {
+	name   string
+	native string
+}
+
+var languages = map[string]language{
+	"en": {name: "English", native: "English"},
+	"ja": {name: "Japanese", native: "日本語"},
+	"zh": {name: "Chinese", native: "中文"},
+	"ko": {name: "Korean", native: "한국어"},
+	"es": {name: "Spanish", native: "Español"},
+	"fr": {name: "French", native: "Français"},
+	"de": {name: "German", native: "Deutsch"},
+	"pt": {name: "Portuguese", native: "Português"},
+	"it": {name: "Italian", native: "Italiano"},
+	"ru": {name: "Russian", native: "Русский"},
+}
+
+// Normalize returns the lower-case base language code for a configured
+// language such as "ja", "ja-JP", "zh_CN" or "Japanese". Languages that are
+// not recognized are returned trimmed, and empty means English.
+func Normalize(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return English
+	}
+
+	lower := strings.ToLower(value)
+	base, _, _ := strings.Cut(strings.ReplaceAll(lower, "_", "-"), "-")
+	if _, ok := languages[base]; ok {
+		return base
+	}
+	for code, lang := range languages {
+		if lower == strings.ToLower(lang.name) || lower == strings.ToLower(lang.native) {
+			return code
+		}
+	}
+	return value
+}
+
+// IsEnglish reports whether the configured language is English.
+func IsEnglish(value string) bool {
+	return Normalize(value) == English
+}
+
+// DisplayName describes the configured language for the system prompt, for
+// example "Japanese (日本語)". Unrecognized languages are described as given.
+func DisplayName(value string) string {
+	code := Normalize(value)
+	lang, ok := languages[code]
+	if !ok {
+		return code
+	}
+	if lang.name == lang.native {
+		return lang.name
+	}
+	return lang.name + " (" + lang.native + ")"
+}
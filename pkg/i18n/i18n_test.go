@@ -0,0 +1,45 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"":         English,
+		"  ":       English,
+		"ja":       "ja",
+		"JA-jp":    "ja",
+		"zh_CN":    "zh",
+		"Japanese": "ja",
+		"Deutsch":  "de",
+		"Klingon":  "Klingon",
+	}
+	for input, want := range tests {
+		assert.Equal(t, want, Normalize(input), input)
+	}
+}
+
+func TestDisplayName(t *testing.T) {
+	assert.Equal(t, "Japanese (日本語)", DisplayName("ja"))
+	assert.Equal(t, "English", DisplayName(""))
+	assert.Equal(t, "Klingon", DisplayName(" Klingon "))
+	assert.True(t, IsEnglish("en-GB"))
+	assert.False(t, IsEnglish("fr"))
+}
+
+func TestT(t *testing.T) {
+	assert.Equal(t, "作業中…", T("ja", Working))
+	assert.Equal(t, "Working…", T("", Working))
+	assert.Equal(t, "Working…", T("pt", Working))
+}
+
+func TestCatalogComplete(t *testing.T) {
+	for language, messages := range catalog {
+		for msg := range catalog[English] {
+			assert.NotEmpty(t, messages[msg], "%s is missing %s", language, msg)
+		}
+	}
+}
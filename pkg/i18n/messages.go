@@ -0,0 +1,193 @@
+package i18n
+
+// Message identifies a translatable UI string.
+type Message string
+
+const (
+	InputPlaceholder        Message = "input_placeholder"
+	Working                 Message = "working"
+	ShortcutsTitle          Message = "shortcuts_title"
+	ShortcutsClose          Message = "shortcuts_close"
+	ShortcutSend            Message = "shortcut_send"
+	ShortcutNewline         Message = "shortcut_newline"
+	ShortcutQueue           Message = "shortcut_queue"
+	ShortcutEditor          Message = "shortcut_editor"
+	ShortcutFilePath        Message = "shortcut_file_path"
+	ShortcutSearchHistory   Message = "shortcut_search_history"
+	ShortcutProfile         Message = "shortcut_profile"
+	ShortcutReasoningEffort Message = "shortcut_reasoning_effort"
+	ShortcutDetails         Message = "shortcut_details"
+	ShortcutScroll          Message = "shortcut_scroll"
+	ShortcutDismiss         Message = "shortcut_dismiss"
+	ShortcutQuit            Message = "shortcut_quit"
+	Required                Message = "required"
+	ConfirmHint             Message = "confirm_hint"
+	NoOptions               Message = "no_options"
+	EditReviewHint          Message = "edit_review_hint"
+)
+
+var catalog = map[string]map[Message]string{
+	"en": {
+		InputPlaceholder:        "Ask kodelet...",
+		Working:                 "Working…",
+		ShortcutsTitle:          "Shortcuts",
+		ShortcutsClose:          "Press Esc, Enter, ?, or q to close.",
+		ShortcutSend:            "Send message",
+		ShortcutNewline:         "Insert newline",
+		ShortcutQueue:           "Queue message for after the current turn",
+		ShortcutEditor:          "Edit draft in $EDITOR",
+		ShortcutFilePath:        "Complete a file path",
+		ShortcutSearchHistory:   "Search previous sent messages",
+		ShortcutProfile:         "Change profile before starting",
+		ShortcutReasoningEffort: "Change reasoning effort before starting",
+		ShortcutDetails:         "Toggle thought/tool details",
+		ShortcutScroll:          "Scroll transcript",
+		ShortcutDismiss:         "Cancel or dismiss",
+		ShortcutQuit:            "Cancel run or quit",
+		Required:                "Required",
+		ConfirmHint:             "Press Enter/Y to confirm or Esc/N to cancel.",
+		NoOptions:               "No options available.",
+		EditReviewHint:          "←/→ hunk · Y accept · N reject · Space toggle · ↑/↓ scroll · A accept all",
+	},
+	"ja": {
+		InputPlaceholder:        "kodelet に質問...",
+		Working:                 "作業中…",
+		ShortcutsTitle:          "ショートカット",
+		ShortcutsClose:          "Esc、Enter、?、q で閉じます。",
+		ShortcutSend:            "メッセージを送信",
+		ShortcutNewline:         "改行を挿入",
+		ShortcutQueue:           "現在のターンの後に送るメッセージを予約",
+		ShortcutEditor:          "$EDITOR で下書きを編集",
+		ShortcutFilePath:        "ファイルパスを補完",
+		ShortcutSearchHistory:   "送信済みメッセージを検索",
+		ShortcutProfile:         "開始前にプロファイルを変更",
+		ShortcutReasoningEffort: "開始前に推論レベルを変更",
+		ShortcutDetails:         "思考/ツールの詳細を切り替え",
+		ShortcutScroll:          "履歴をスクロール",
+		ShortcutDismiss:         "キャンセルまたは閉じる",
+		ShortcutQuit:            "実行をキャンセルまたは終了",
+		Required:                "必須",
+		ConfirmHint:             "Enter/Y で確定、Esc/N でキャンセル。",
+		NoOptions:               "選択肢がありません。",
+		EditReviewHint:          "←/→ ハンク移動 · Y 採用 · N 却下 · Space 切替 · ↑/↓ スクロール · A すべて採用",
+	},
+	"zh": {
+		InputPlaceholder:        "向 kodelet 提问...",
+		Working:                 "处理中…",
+		ShortcutsTitle:          "快捷键",
+		ShortcutsClose:          "按 Esc、Enter、? 或 q 关闭。",
+		ShortcutSend:            "发送消息",
+		ShortcutNewline:         "插入换行",
+		ShortcutQueue:           "排队到当前轮次之后发送",
+		ShortcutEditor:          "在 $EDITOR 中编辑草稿",
+		ShortcutFilePath:        "补全文件路径",
+		ShortcutSearchHistory:   "搜索已发送的消息",
+		ShortcutProfile:         "开始前切换配置",
+		ShortcutReasoningEffort: "开始前调整推理强度",
+		ShortcutDetails:         "显示/隐藏思考与工具详情",
+		ShortcutScroll:          "滚动对话记录",
+		ShortcutDismiss:         "取消或关闭",
+		ShortcutQuit:            "取消运行或退出",
+		Required:                "必填",
+		ConfirmHint:             "按 Enter/Y 确认，按 Esc/N 取消。",
+		NoOptions:               "没有可用选项。",
+		EditReviewHint:          "←/→ 切换块 · Y 接受 · N 拒绝 · 空格 切换 · ↑/↓ 滚动 · A 全部接受",
+	},
+	"ko": {
+		InputPlaceholder:        "kodelet에게 질문하기...",
+		Working:                 "작업 중…",
+		ShortcutsTitle:          "단축키",
+		ShortcutsClose:          "Esc, Enter, ?, q 키로 닫습니다.",
+		ShortcutSend:            "메시지 보내기",
+		ShortcutNewline:         "줄바꿈 삽입",
+		ShortcutQueue:           "현재 턴 이후에 보낼 메시지 예약",
+		ShortcutEditor:          "$EDITOR에서 초안 편집",
+		ShortcutFilePath:        "파일 경로 자동 완성",
+		ShortcutSearchHistory:   "보낸 메시지 검색",
+		ShortcutProfile:         "시작 전에 프로필 변경",
+		ShortcutReasoningEffort: "시작 전에 추론 수준 변경",
+		ShortcutDetails:         "생각/도구 세부 정보 전환",
+		ShortcutScroll:          "대화 기록 스크롤",
+		ShortcutDismiss:         "취소 또는 닫기",
+		ShortcutQuit:            "실행 취소 또는 종료",
+		Required:                "필수",
+		ConfirmHint:             "Enter/Y로 확인, Esc/N으로 취소합니다.",
+		NoOptions:               "사용 가능한 옵션이 없습니다.",
+		EditReviewHint:          "←/→ 블록 이동 · Y 수락 · N 거부 · Space 전환 · ↑/↓ 스크롤 · A 모두 수락",
+	},
+	"es": {
+		InputPlaceholder:        "Pregunta a kodelet...",
+		Working:                 "Trabajando…",
+		ShortcutsTitle:          "Atajos",
+		ShortcutsClose:          "Pulsa Esc, Enter, ? o q para cerrar.",
+		ShortcutSend:            "Enviar mensaje",
+		ShortcutNewline:         "Insertar salto de línea",
+		ShortcutQueue:           "Poner el mensaje en cola tras el turno actual",
+		ShortcutEditor:          "Editar el borrador en $EDITOR",
+		ShortcutFilePath:        "Completar una ruta de archivo",
+		ShortcutSearchHistory:   "Buscar mensajes enviados",
+		ShortcutProfile:         "Cambiar de perfil antes de empezar",
+		ShortcutReasoningEffort: "Cambiar el esfuerzo de razonamiento antes de empezar",
+		ShortcutDetails:         "Mostrar u ocultar detalles de razonamiento y herramientas",
+		ShortcutScroll:          "Desplazar la conversación",
+		ShortcutDismiss:         "Cancelar o cerrar",
+		ShortcutQuit:            "Cancelar la ejecución o salir",
+		Required:                "Obligatorio",
+		ConfirmHint:             "Pulsa Enter/Y para confirmar o Esc/N para cancelar.",
+		NoOptions:               "No hay opciones disponibles.",
+		EditReviewHint:          "←/→ bloque · Y aceptar · N rechazar · Espacio alternar · ↑/↓ desplazar · A aceptar todo",
+	},
+	"fr": {
+		InputPlaceholder:        "Demandez à kodelet...",
+		Working:                 "En cours…",
+		ShortcutsTitle:          "Raccourcis",
+		ShortcutsClose:          "Appuyez sur Échap, Entrée, ? ou q pour fermer.",
+		ShortcutSend:            "Envoyer le message",
+		ShortcutNewline:         "Insérer un saut de ligne",
+		ShortcutQueue:           "Mettre le message en file après le tour en cours",
+		ShortcutEditor:          "Modifier le brouillon dans $EDITOR",
+		ShortcutFilePath:        "Compléter un chemin de fichier",
+		ShortcutSearchHistory:   "Rechercher dans les messages envoyés",
+		ShortcutProfile:         "Changer de profil avant de commencer",
+		ShortcutReasoningEffort: "Changer l'effort de raisonnement avant de commencer",
+		ShortcutDetails:         "Afficher/masquer les détails de réflexion et d'outils",
+		ShortcutScroll:          "Faire défiler la conversation",
+		ShortcutDismiss:         "Annuler ou fermer",
+		ShortcutQuit:            "Annuler l'exécution ou quitter",
+		Required:                "Obligatoire",
+		ConfirmHint:             "Appuyez sur Entrée/Y pour confirmer ou Échap/N pour annuler.",
+		NoOptions:               "Aucune option disponible.",
+		EditReviewHint:          "←/→ bloc · Y accepter · N refuser · Espace basculer · ↑/↓ défiler · A tout accepter",
+	},
+	"de": {
+		InputPlaceholder:        "Frag kodelet...",
+		Working:                 "Arbeite…",
+		ShortcutsTitle:          "Tastenkürzel",
+		ShortcutsClose:          "Esc, Enter, ? oder q zum Schließen drücken.",
+		ShortcutSend:            "Nachricht senden",
+		ShortcutNewline:         "Zeilenumbruch einfügen",
+		ShortcutQueue:           "Nachricht nach dem aktuellen Zug einreihen",
+		ShortcutEditor:          "Entwurf in $EDITOR bearbeiten",
+		ShortcutFilePath:        "Dateipfad vervollständigen",
+		ShortcutSearchHistory:   "Gesendete Nachrichten durchsuchen",
+		ShortcutProfile:         "Profil vor dem Start wechseln",
+		ShortcutReasoningEffort: "Denkaufwand vor dem Start ändern",
+		ShortcutDetails:         "Gedanken-/Tool-Details ein- oder ausblenden",
+		ShortcutScroll:          "Verlauf scrollen",
+		ShortcutDismiss:         "Abbrechen oder schließen",
+		ShortcutQuit:            "Lauf abbrechen oder beenden",
+		Required:                "Erforderlich",
+		ConfirmHint:             "Enter/Y zum Bestätigen, Esc/N zum Abbrechen.",
+		NoOptions:               "Keine Optionen verfügbar.",
+		EditReviewHint:          "←/→ Block · Y annehmen · N ablehnen · Leertaste umschalten · ↑/↓ scrollen · A alle annehmen",
+	},
+}
+
+// T returns msg in the configured language, falling back to English when the
+// language or the string has no translation.
+func T(language string, msg Message) string {
+	if text, ok := catalog[Normalize(language)][msg]; ok {
+		return text
+	}
+	return catalog[English][msg]
+}
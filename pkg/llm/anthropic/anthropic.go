@@ -6,6 +6,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"slices"
 	"strings"
@@ -14,12 +15,16 @@ import (
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/vertex"
+	"golang.org/x/oauth2/google"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/jingkaihe/kodelet/pkg/auth"
 	"github.com/jingkaihe/kodelet/pkg/conversations"
 	"github.com/jingkaihe/kodelet/pkg/goals"
 	"github.com/jingkaihe/kodelet/pkg/llm/base"
+	"github.com/jingkaihe/kodelet/pkg/llm/chaos"
+	"github.com/jingkaihe/kodelet/pkg/llm/ratelimit"
 	"github.com/jingkaihe/kodelet/pkg/logger"
 	"github.com/jingkaihe/kodelet/pkg/steer"
 	"github.com/jingkaihe/kodelet/pkg/sysprompt"
@@ -77,6 +82,19 @@ func resolveClientBaseURL(config llmtypes.Config, useCopilot bool) string {
 	return ""
 }
 
+// newAPIKeyAuthorizer returns the authorizer for API key authentication: the
+// shared key pool when anthropic.api_keys is set, otherwise ANTHROPIC_API_KEY.
+func newAPIKeyAuthorizer(config llmtypes.Config) (auth.HTTPAuthorizer, error) {
+	if config.Anthropic == nil || len(config.Anthropic.APIKeys) == 0 {
+		return auth.AnthropicAPIKeyAuthorizerFromEnv(), nil
+	}
+	pool, err := auth.SharedKeyPool("anthropic", config.Anthropic.APIKeys)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to set up the anthropic API key pool")
+	}
+	return auth.AnthropicKeyPoolAuthorizer(pool), nil
+}
+
 // NewAnthropicThread creates a new thread with Anthropic's Claude API
 func NewAnthropicThread(config llmtypes.Config) (*Thread, error) {
 	if err := llmtypes.NormalizeReasoningConfig(&config); err != nil {
@@ -97,6 +115,20 @@ func NewAnthropicThread(config llmtypes.Config) (*Thread, error) {
 	}
 
 	opts := []option.RequestOption{option.WithoutEnvironmentDefaults()}
+	if middleware := ratelimit.Middleware(ratelimit.Shared("anthropic", config.RateLimit)); middleware != nil {
+		opts = append(opts, option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+			return middleware(req, next)
+		}))
+	}
+	if middleware := chaos.Middleware("anthropic"); middleware != nil {
+		opts = append(opts, option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+			return middleware(req, next)
+		}))
+	}
+	retryCounter := telemetry.RetryCountMiddleware("anthropic")
+	opts = append(opts, option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		return retryCounter(req, next)
+	}))
 
 	logger := logger.G(context.Background())
 	var client anthropic.Client
@@ -115,6 +147,31 @@ func NewAnthropicThread(config llmtypes.Config) (*Thread, error) {
 		client = anthropic.NewClient(opts...)
 		useSubscription = false
 		useCopilot = true
+	} else if isVertexPlatform(config) {
+		credentials, err := google.FindDefaultCredentials(context.Background(), googleCloudPlatformScope)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load Google application default credentials")
+		}
+		settings, err := resolveVertexSettings(config, credentials.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+
+		logger.WithField("project", settings.ProjectID).WithField("region", settings.Region).Debug("using Claude on Vertex AI")
+		opts = append(opts,
+			option.WithMiddleware(vertexModelMiddleware),
+			vertex.WithCredentials(context.Background(), settings.Region, settings.ProjectID, credentials),
+		)
+		if baseURL := GetConfiguredBaseURL(config); baseURL != "" {
+			opts = append(opts, option.WithBaseURL(baseURL))
+		}
+		client = anthropic.NewClient(opts...)
+		useSubscription = false
+
+		// Vertex AI model names are mapped back to Anthropic IDs so model
+		// capabilities are recognised; requests map them to Vertex names again.
+		config.Model = canonicalModelID(config.Model)
+		config.WeakModel = canonicalModelID(config.WeakModel)
 	} else {
 		if baseURL := resolveClientBaseURL(config, false); baseURL != "" {
 			opts = append(opts, option.WithBaseURL(baseURL))
@@ -125,7 +182,11 @@ func NewAnthropicThread(config llmtypes.Config) (*Thread, error) {
 		case llmtypes.AnthropicAPIAccessAPIKey:
 			// Force API key usage
 			logger.Debug("using API key authentication (forced by configuration)")
-			apiKeyOpts := auth.AnthropicRequestOptionsWithAuthorizer(auth.AnthropicAPIKeyAuthorizerFromEnv())
+			apiKeyAuthorizer, err := newAPIKeyAuthorizer(config)
+			if err != nil {
+				return nil, err
+			}
+			apiKeyOpts := auth.AnthropicRequestOptionsWithAuthorizer(apiKeyAuthorizer)
 			client = anthropic.NewClient(append(opts, apiKeyOpts...)...)
 			useSubscription = false
 
@@ -155,7 +216,11 @@ func NewAnthropicThread(config llmtypes.Config) (*Thread, error) {
 			if antCredsExists {
 				if _, err := auth.AnthropicAccessToken(context.Background(), config.AnthropicAccount); err != nil {
 					logger.WithError(err).Error("failed to get anthropic access token, falling back to use API key")
-					apiKeyOpts := auth.AnthropicRequestOptionsWithAuthorizer(auth.AnthropicAPIKeyAuthorizerFromEnv())
+					apiKeyAuthorizer, err := newAPIKeyAuthorizer(config)
+					if err != nil {
+						return nil, err
+					}
+					apiKeyOpts := auth.AnthropicRequestOptionsWithAuthorizer(apiKeyAuthorizer)
 					client = anthropic.NewClient(append(opts, apiKeyOpts...)...)
 					useSubscription = false
 				} else {
@@ -170,7 +235,11 @@ func NewAnthropicThread(config llmtypes.Config) (*Thread, error) {
 				}
 			} else {
 				logger.Debug("no anthropic credentials found, falling back to use API key")
-				apiKeyOpts := auth.AnthropicRequestOptionsWithAuthorizer(auth.AnthropicAPIKeyAuthorizerFromEnv())
+				apiKeyAuthorizer, err := newAPIKeyAuthorizer(config)
+				if err != nil {
+					return nil, err
+				}
+				apiKeyOpts := auth.AnthropicRequestOptionsWithAuthorizer(apiKeyAuthorizer)
 				client = anthropic.NewClient(append(opts, apiKeyOpts...)...)
 				useSubscription = false
 			}
@@ -189,14 +258,23 @@ func NewAnthropicThread(config llmtypes.Config) (*Thread, error) {
 		useCopilot:      useCopilot,
 	}
 
+	// Subscription and Copilot responses report how full the rate limit windows are
+	if useSubscription || useCopilot {
+		baseThread.EnableQuotaTracking()
+	}
+
 	// Set the LoadConversation callback for provider-specific loading
 	baseThread.LoadConversation = thread.loadConversation
+	baseThread.PruneToolResults = thread.pruneToolResults
+	baseThread.CurrentMessages = thread.GetMessages
 
 	return thread, nil
 }
 
 // AddUserMessage adds a user message with optional images to the thread
 func (t *Thread) AddUserMessage(ctx context.Context, message string, imagePaths ...string) {
+	defer t.journalMessagesFrom(ctx, len(t.messages))
+
 	if goals.IsContextText(message) {
 		if imageBlocks := t.userImageContentBlocks(ctx, imagePaths); len(imageBlocks) > 0 {
 			t.messages = append(t.messages, anthropic.NewUserMessage(imageBlocks...))
@@ -211,6 +289,17 @@ func (t *Thread) AddUserMessage(ctx context.Context, message string, imagePaths
 	t.messages = append(t.messages, anthropic.NewUserMessage(contentBlocks...))
 }
 
+// journalMessagesFrom journals the messages added to the history from index
+// from on.
+func (t *Thread) journalMessagesFrom(ctx context.Context, from int) {
+	if t.Thread == nil {
+		return
+	}
+	for i := from; i < len(t.messages); i++ {
+		t.JournalMessage(ctx, i, t.messages[i])
+	}
+}
+
 func (t *Thread) userImageContentBlocks(ctx context.Context, imagePaths []string) []anthropic.ContentBlockParamUnion {
 	contentBlocks := []anthropic.ContentBlockParamUnion{}
 
@@ -335,17 +424,28 @@ func (t *Thread) SendMessage(
 		copy(originalMessages, t.messages)
 	}
 
+	defer t.TrackRun(ctx, opt)()
+	t.StartJournal(t.Provider(), opt)
+	defer t.StopJournal()
+
 	message, err = base.ProcessUserMessage(ctx, t, message)
 	if err != nil {
 		return "", err
 	}
 
-	t.AddUserMessage(ctx, message, opt.Images...)
-
 	model, maxTokens := t.getModelAndTokens(opt)
 
+	if len(opt.Images) > 0 && !t.modelFeatures(model).Vision() {
+		logger.G(ctx).WithField("model", model).Warn("model does not support image input, dropping attached images")
+		opt.Images = nil
+	}
+
+	t.AddUserMessage(ctx, message, opt.Images...)
+
 	turnCount := 0
 	maxTurns := max(opt.MaxTurns, 0)
+	budget := t.StartBudget(opt)
+	var budgetErr error
 	base.DispatchAgentStart(ctx, t)
 
 OUTER:
@@ -363,6 +463,10 @@ OUTER:
 					WithField("turn_count", turnCount).
 					WithField("max_turns", maxTurns).
 					Warn("reached maximum turn limit, stopping interaction")
+				t.MarkMaxTurnsReached()
+				break OUTER
+			}
+			if budgetErr = budget.Check(ctx, turnCount); budgetErr != nil {
 				break OUTER
 			}
 
@@ -376,12 +480,22 @@ OUTER:
 			if t.State != nil {
 				contexts = t.State.DiscoverContexts()
 			}
-			systemPrompt := base.ProcessSystemPrompt(ctx, t, sysprompt.SystemPrompt(model, t.Config, contexts))
+			turnModel, turnMaxTokens := model, maxTokens
+			useWeakModel, err := base.ApplyQuotaPolicy(ctx, t)
+			if err != nil {
+				return "", err
+			}
+			if useWeakModel {
+				weakOpt := opt
+				weakOpt.UseWeakModel = true
+				turnModel, turnMaxTokens = t.getModelAndTokens(weakOpt)
+			}
+			systemPrompt := base.ProcessSystemPrompt(ctx, t, sysprompt.SystemPrompt(turnModel, t.Config, contexts))
 
 			exchangeOpt := opt.WithTurnInitiator(turnCount)
 
 			var exchangeOutput string
-			exchangeOutput, toolsUsed, err := t.processMessageExchange(ctx, handler, model, maxTokens, systemPrompt, exchangeOpt)
+			exchangeOutput, toolsUsed, err := t.processMessageExchange(ctx, handler, turnModel, turnMaxTokens, systemPrompt, exchangeOpt)
 			if err != nil {
 				logger.G(ctx).WithError(err).Error("error processing message exchange")
 				// xxx: based on the observation, the anthropic sdk swallows context cancellation, and return empty message
@@ -406,9 +520,15 @@ OUTER:
 			finalOutput = exchangeOutput
 
 			base.TriggerTurnEnd(ctx, t, finalOutput, turnCount)
+			if toolsUsed {
+				base.HandleTodoDiscipline(ctx, t, t.tools(opt))
+			}
 
 			// If no tools were used, check for queued continuations before stopping
 			if !toolsUsed {
+				if base.ReviewDeferredTools(ctx, t, handler) {
+					continue OUTER
+				}
 				if base.HandleAgentStopFollowUps(ctx, t, handler) {
 					continue OUTER
 				}
@@ -424,6 +544,8 @@ OUTER:
 		}
 	}
 
+	base.DiscardDeferredTools(ctx, t, handler)
+
 	if opt.NoSaveConversation {
 		t.messages = originalMessages
 	}
@@ -435,7 +557,7 @@ OUTER:
 	}
 
 	handler.HandleDone()
-	return finalOutput, nil
+	return finalOutput, budgetErr
 }
 
 func isMessageToolUse(msg anthropic.MessageParam) bool {
@@ -595,9 +717,21 @@ func (t *Thread) processMessageExchange(
 	}
 	systemPromptBlocks = append(systemPromptBlocks, anthropic.TextBlockParam{Text: systemPrompt})
 
-	if err := t.validateThinkingConfigForModel(model); err != nil {
+	effort := opt.ResolvedReasoningEffort(t.Config.ReasoningEffort)
+	if err := t.validateThinkingConfigForEffort(model, effort); err != nil {
 		return "", false, err
 	}
+	thinkingConfig, useThinking := t.thinkingConfigForEffort(model, effort)
+	if useThinking && thinkingConfig.OfEnabled != nil {
+		maxTokens += int(thinkingConfig.OfEnabled.BudgetTokens) - t.Config.ThinkingBudgetTokens
+	}
+
+	features := t.modelFeatures(model)
+	maxTokens = features.ClampMaxTokens(maxTokens)
+	if useThinking && thinkingConfig.OfEnabled != nil && thinkingConfig.OfEnabled.BudgetTokens >= int64(maxTokens) {
+		// The thinking budget must stay below max_tokens.
+		thinkingConfig.OfEnabled.BudgetTokens = int64(maxTokens) / 2
+	}
 
 	// Prepare message parameters
 	messageParams := anthropic.MessageNewParams{
@@ -605,12 +739,19 @@ func (t *Thread) processMessageExchange(
 		System:    systemPromptBlocks,
 		Messages:  t.messages,
 		Model:     model,
-		Tools:     toAnthropicTools(t.tools(opt), t.useSubscription),
 	}
-	if thinkingConfig, ok := t.thinkingConfigForModel(model); ok {
+	if features.Tools() {
+		messageParams.Tools = toAnthropicTools(t.tools(opt), t.useSubscription)
+		if len(messageParams.Tools) > 0 && !features.ParallelToolCalls() {
+			messageParams.ToolChoice = anthropic.ToolChoiceUnionParam{
+				OfAuto: &anthropic.ToolChoiceAutoParam{DisableParallelToolUse: anthropic.Bool(true)},
+			}
+		}
+	}
+	if useThinking {
 		messageParams.Thinking = thinkingConfig
 	}
-	if outputConfig, ok := t.outputConfigForModel(model); ok {
+	if outputConfig, ok := t.outputConfigForEffort(model, effort); ok {
 		messageParams.OutputConfig = outputConfig
 	}
 
@@ -633,7 +774,8 @@ func (t *Thread) processMessageExchange(
 	// Check if handler supports streaming for skipping post-stream calls
 	_, isStreamingHandler := handler.(llmtypes.StreamingMessageHandler)
 
-	response, err := t.NewMessage(ctx, messageParams, handler, opt)
+	requestCtx, keySelection := auth.WithAPIKeySelection(ctx)
+	response, err := t.NewMessage(requestCtx, messageParams, handler, opt)
 	if err != nil {
 		if t.Persisted && t.Store != nil && !opt.NoSaveConversation {
 			t.SaveConversation(ctx, false)
@@ -647,13 +789,27 @@ func (t *Thread) processMessageExchange(
 		attribute.Int("output_tokens", int(response.Usage.OutputTokens)),
 	)
 
-	// Add the assistant response to history
-	t.messages = append(t.messages, response.ToParam())
+	// Add the assistant response to history. A refusal may have no content,
+	// which the API would reject on the next request.
+	refused := response.StopReason == anthropic.StopReasonRefusal
+	if !refused || len(response.Content) > 0 {
+		t.messages = append(t.messages, response.ToParam())
+		t.journalMessagesFrom(ctx, len(t.messages)-1)
+	}
 
+	usageBefore := t.GetUsage()
 	t.updateUsage(response, model)
+	t.RecordAPIKeyUsage(keySelection.Name(), usageBefore)
+	t.RecordExchangeMetrics(string(model), usageBefore)
 	if usageHandler, ok := handler.(llmtypes.UsageMessageHandler); ok {
 		usageHandler.HandleUsage(t.GetUsage())
 	}
+	if refused {
+		base.ReportContentFilter(ctx, handler, llmtypes.ContentFilterEvent{
+			Provider: t.Provider(),
+			Reason:   llmtypes.ContentFilterReasonRefusal,
+		})
+	}
 
 	// Process the response content blocks - first pass: handle text/thinking, collect tool blocks
 	var toolBlocks []struct {
@@ -703,13 +859,14 @@ func (t *Thread) processMessageExchange(
 	// Add all tool results as a single user message (required by Anthropic API)
 	if len(toolResultBlocks) > 0 {
 		t.messages = append(t.messages, anthropic.NewUserMessage(toolResultBlocks...))
+		t.journalMessagesFrom(ctx, len(t.messages)-1)
 	}
 
 	toolUseCount := len(toolResults)
 
 	// Log structured LLM usage after all content processing is complete
 	if !opt.DisableUsageLog {
-		usage.LogLLMUsage(ctx, t.GetUsage(), model, apiStartTime, int(response.Usage.OutputTokens))
+		usage.LogLLMUsageWithResources(t.UsageLogContext(ctx), t.GetUsage(), t.GetResourceUsage(), model, apiStartTime, int(response.Usage.OutputTokens))
 	}
 
 	if t.Persisted && t.Store != nil && !opt.NoSaveConversation {
@@ -789,6 +946,7 @@ func (t *Thread) processPendingSteer(ctx context.Context, messageParams *anthrop
 			contentBlocks := t.pendingSteerContentBlocks(ctx, steerMsg)
 			userMessage := anthropic.NewUserMessage(contentBlocks...)
 			t.messages = append(t.messages, userMessage)
+			t.journalMessagesFrom(ctx, len(t.messages)-1)
 			messageParams.Messages = append(messageParams.Messages, userMessage)
 			if userHandler, ok := handler.(llmtypes.UserMessageHandler); ok {
 				userHandler.HandleUserMessage(steerMsg.Content, steerMsg.Images)
@@ -842,9 +1000,9 @@ func (t *Thread) getModelAndTokens(opt llmtypes.MessageOpt) (anthropic.Model, in
 	return model, maxTokens
 }
 
-func (t *Thread) shouldUtiliseThinking(model anthropic.Model) bool {
+func (t *Thread) shouldUtiliseThinking(model anthropic.Model, effort string) bool {
 	if t.isAdaptiveThinkingModel(model) {
-		return !t.adaptiveThinkingDisabled(model)
+		return !adaptiveThinkingDisabled(effort)
 	}
 	if !isThinkingModel(model) {
 		return false
@@ -855,16 +1013,16 @@ func (t *Thread) shouldUtiliseThinking(model anthropic.Model) bool {
 	return true
 }
 
-func (t *Thread) adaptiveThinkingDisabled(model anthropic.Model) bool {
-	if !t.isAdaptiveThinkingModel(model) {
-		return false
-	}
-
-	return strings.EqualFold(strings.TrimSpace(t.Config.ReasoningEffort), "none")
+func adaptiveThinkingDisabled(effort string) bool {
+	return strings.EqualFold(strings.TrimSpace(effort), "none")
 }
 
 func (t *Thread) validateThinkingConfigForModel(model anthropic.Model) error {
-	if isAlwaysOnAdaptiveThinkingModel(model) && t.adaptiveThinkingDisabled(model) {
+	return t.validateThinkingConfigForEffort(model, t.Config.ReasoningEffort)
+}
+
+func (t *Thread) validateThinkingConfigForEffort(model anthropic.Model, effort string) error {
+	if isAlwaysOnAdaptiveThinkingModel(model) && t.isAdaptiveThinkingModel(model) && adaptiveThinkingDisabled(effort) {
 		return errors.Errorf("%s does not support disabling adaptive thinking with reasoning_effort=none", model)
 	}
 
@@ -872,7 +1030,14 @@ func (t *Thread) validateThinkingConfigForModel(model anthropic.Model) error {
 }
 
 func (t *Thread) thinkingConfigForModel(model anthropic.Model) (anthropic.ThinkingConfigParamUnion, bool) {
-	if !t.shouldUtiliseThinking(model) {
+	return t.thinkingConfigForEffort(model, t.Config.ReasoningEffort)
+}
+
+// thinkingConfigForEffort returns the thinking configuration for a request at
+// effort. Models with a fixed thinking budget get their budget doubled for
+// every level effort is above the configured reasoning effort.
+func (t *Thread) thinkingConfigForEffort(model anthropic.Model, effort string) (anthropic.ThinkingConfigParamUnion, bool) {
+	if !t.shouldUtiliseThinking(model, effort) {
 		return anthropic.ThinkingConfigParamUnion{}, false
 	}
 
@@ -888,14 +1053,18 @@ func (t *Thread) thinkingConfigForModel(model anthropic.Model) (anthropic.Thinki
 	return anthropic.ThinkingConfigParamUnion{
 		OfEnabled: &anthropic.ThinkingConfigEnabledParam{
 			Type:         "enabled",
-			BudgetTokens: int64(t.Config.ThinkingBudgetTokens),
+			BudgetTokens: int64(t.Config.ThinkingBudgetTokens) << llmtypes.ReasoningEffortIncrease(t.Config.ReasoningEffort, effort),
 			Display:      anthropic.ThinkingConfigEnabledDisplaySummarized,
 		},
 	}, true
 }
 
 func (t *Thread) outputConfigForModel(model anthropic.Model) (anthropic.OutputConfigParam, bool) {
-	effort, ok := t.anthropicReasoningEffortForModel(model, t.Config.ReasoningEffort)
+	return t.outputConfigForEffort(model, t.Config.ReasoningEffort)
+}
+
+func (t *Thread) outputConfigForEffort(model anthropic.Model, configured string) (anthropic.OutputConfigParam, bool) {
+	effort, ok := t.anthropicReasoningEffortForModel(model, configured)
 	if !ok {
 		return anthropic.OutputConfigParam{}, false
 	}
@@ -1069,6 +1238,9 @@ func (t *Thread) NewMessage(ctx context.Context, params anthropic.MessageNewPara
 	if t.useCopilot {
 		requestOpts = append(requestOpts, auth.CopilotAnthropicRequestOptions(opt)...)
 	}
+	if tracker := t.QuotaTracker(); tracker != nil {
+		requestOpts = append(requestOpts, option.WithMiddleware(quotaMiddleware(tracker)))
+	}
 
 	stream := t.client.Messages.NewStreaming(ctx, params, requestOpts...)
 	defer stream.Close()
@@ -1115,6 +1287,12 @@ func (t *Thread) NewMessage(ctx context.Context, params anthropic.MessageNewPara
 			return nil, stream.Err()
 		}
 
+		if delta, ok := event.AsAny().(anthropic.ContentBlockDeltaEvent); ok {
+			if text, ok := delta.Delta.AsAny().(anthropic.TextDelta); ok {
+				t.JournalAssistantText(ctx, len(params.Messages), text.Text)
+			}
+		}
+
 		if streamHandler, ok := handler.(llmtypes.StreamingMessageHandler); ok {
 			switch eventVariant := event.AsAny().(type) {
 			case anthropic.ContentBlockStartEvent:
@@ -1202,7 +1380,8 @@ func (t *Thread) updateUsage(response *anthropic.Message, model anthropic.Model)
 	t.Usage.CacheReadInputTokens += int(response.Usage.CacheReadInputTokens)
 
 	// Calculate costs based on model pricing
-	pricing := getModelPricing(model)
+	promptTokens := response.Usage.InputTokens + response.Usage.CacheCreationInputTokens + response.Usage.CacheReadInputTokens
+	pricing := getModelPricing(model).forPromptTokens(int(promptTokens))
 
 	// Calculate individual costs and show usage regardless of subscription.
 	t.Usage.InputCost += float64(response.Usage.InputTokens) * pricing.Input
@@ -1299,7 +1478,7 @@ func (t *Thread) SwapContext(_ context.Context, summary string) error {
 
 // CompactContext performs comprehensive context compacting by creating a detailed summary
 func (t *Thread) CompactContext(ctx context.Context) error {
-	return base.CompactContextWithSummary(ctx, t.runUtilityPrompt, t.SwapContext)
+	return t.CompactContextWithSummary(ctx, t.runUtilityPrompt, t.SwapContext)
 }
 
 // GetMessages returns the current messages in the thread
@@ -8,6 +8,8 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -23,6 +25,7 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/jingkaihe/kodelet/pkg/llm/base"
+	kodeletpricing "github.com/jingkaihe/kodelet/pkg/pricing"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
 )
@@ -308,6 +311,38 @@ func TestAnthropicProcessMessageExchangeUsesManualPromptCaching(t *testing.T) {
 	assert.NotContains(t, capturedRequest.Messages[0].Content[0].Text, "<goal_context>")
 }
 
+func TestProcessMessageExchangeReportsRefusal(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("event: message_start\n" +
+			`data: {"type":"message_start","message":{"id":"msg_test","type":"message","role":"assistant","model":"claude-sonnet-4-6","content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":1,"output_tokens":0,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}` + "\n\n" +
+			"event: message_delta\n" +
+			`data: {"type":"message_delta","delta":{"stop_reason":"refusal","stop_sequence":null},"usage":{"input_tokens":1,"output_tokens":0}}` + "\n\n" +
+			"event: message_stop\n" +
+			`data: {"type":"message_stop"}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	thread := &Thread{
+		Thread: base.NewThread(llmtypes.Config{Provider: "anthropic", Model: "claude-sonnet-4-6"}, "conv-test"),
+		client: anthropic.NewClient(
+			option.WithBaseURL(server.URL),
+			option.WithAPIKey("test-key"),
+		),
+		messages: []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("hello"))},
+	}
+
+	handler := &llmtypes.StringCollectorHandler{Silent: true}
+	output, toolsUsed, err := thread.processMessageExchange(context.Background(), handler, "claude-sonnet-4-6", 256, "system", llmtypes.MessageOpt{DisableUsageLog: true, NoToolUse: true})
+	require.NoError(t, err)
+	assert.Empty(t, output)
+	assert.False(t, toolsUsed)
+
+	require.NotNil(t, handler.ContentFilter())
+	assert.Equal(t, llmtypes.ContentFilterEvent{Provider: "anthropic", Reason: llmtypes.ContentFilterReasonRefusal}, *handler.ContentFilter())
+	assert.Len(t, thread.messages, 1, "an empty refusal must not be added to the history")
+}
+
 func TestAnthropicToolResultBlockUsesMultimodalPartsWhenAvailable(t *testing.T) {
 	result := fakeAnthropicMultiModalToolResult{
 		BaseToolResult: tooltypes.BaseToolResult{Result: "fallback"},
@@ -416,6 +451,36 @@ func TestCacheCreationCostFallsBackToLegacyAggregateTokens(t *testing.T) {
 	assert.Equal(t, 300*pricing.PromptCachingWrite5m, cacheCreationCost(usage, pricing))
 }
 
+func TestGetModelPricingPrefersPricingManifest(t *testing.T) {
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	kodeletpricing.Reset()
+	t.Cleanup(kodeletpricing.Reset)
+
+	path, err := kodeletpricing.DefaultManifestPath()
+	require.NoError(t, err)
+	require.NoError(t, kodeletpricing.SaveManifest(path, []byte(`{"version": 1, "models": [
+		{"provider": "anthropic", "model": "claude-sonnet-4-6", "periods": [{
+			"effective_from": "2020-01-01T00:00:00Z",
+			"input": 0.000002, "cached_input": 0.0000002, "cache_write_input": 0.0000025, "output": 0.00001,
+			"long_context_threshold": 200000, "long_context_input": 0.000004, "long_context_output": 0.000015,
+			"context_window": 1000000}]}]}`)))
+
+	pricing := getModelPricing(anthropic.ModelClaudeSonnet4_6)
+	assert.Equal(t, 0.000002, pricing.Input)
+	assert.Equal(t, 0.0000025, pricing.PromptCachingWrite5m)
+	assert.Equal(t, 0.000004, pricing.PromptCachingWrite1h, "one-hour writes default to twice the input rate")
+	assert.Equal(t, 0.0000002, pricing.PromptCachingRead)
+
+	assert.Equal(t, 0.000002, pricing.forPromptTokens(200_000).Input)
+	longContext := pricing.forPromptTokens(200_001)
+	assert.Equal(t, 0.000004, longContext.Input)
+	assert.Equal(t, 0.000015, longContext.Output)
+	assert.Equal(t, 0.000008, longContext.PromptCachingWrite1h)
+	assert.Equal(t, 0.0000002, longContext.PromptCachingRead, "unset long-context rates keep the standard rate")
+
+	assert.Equal(t, ModelPricingMap[anthropic.ModelClaudeHaiku4_5], getModelPricing(anthropic.ModelClaudeHaiku4_5))
+}
+
 func TestNewAnthropicThreadCopilotUsesConfiguredBaseURL(t *testing.T) {
 	t.Setenv("HOME", t.TempDir())
 	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
@@ -1098,6 +1163,38 @@ func TestThinkingConfigForModel(t *testing.T) {
 	})
 }
 
+func TestThinkingConfigForEffort(t *testing.T) {
+	thread, err := NewAnthropicThread(llmtypes.Config{ThinkingBudgetTokens: 4096, ReasoningEffort: "medium"})
+	require.NoError(t, err)
+
+	t.Run("raised effort doubles the budget per level", func(t *testing.T) {
+		config, ok := thread.thinkingConfigForEffort(anthropic.ModelClaudeSonnet4_5, "xhigh")
+		require.True(t, ok)
+		assert.EqualValues(t, 16384, *config.GetBudgetTokens())
+	})
+
+	t.Run("lower effort keeps the configured budget", func(t *testing.T) {
+		config, ok := thread.thinkingConfigForEffort(anthropic.ModelClaudeSonnet4_5, "low")
+		require.True(t, ok)
+		assert.EqualValues(t, 4096, *config.GetBudgetTokens())
+	})
+
+	t.Run("adaptive models send the raised effort", func(t *testing.T) {
+		config, ok := thread.outputConfigForEffort(anthropic.ModelClaudeOpus4_7, "high")
+		require.True(t, ok)
+		assert.Equal(t, anthropic.OutputConfigEffortHigh, config.Effort)
+	})
+
+	t.Run("raised effort re-enables adaptive thinking", func(t *testing.T) {
+		disabledThread, err := NewAnthropicThread(llmtypes.Config{ReasoningEffort: "none"})
+		require.NoError(t, err)
+
+		_, ok := disabledThread.thinkingConfigForEffort(anthropic.ModelClaudeOpus4_7, "low")
+		assert.True(t, ok)
+		assert.NoError(t, disabledThread.validateThinkingConfigForEffort(anthropic.ModelClaudeFable5, "low"))
+	})
+}
+
 func TestValidateThinkingConfigForModel(t *testing.T) {
 	thread, err := NewAnthropicThread(llmtypes.Config{ReasoningEffort: "none"})
 	require.NoError(t, err)
@@ -1724,3 +1821,34 @@ func (t testTool) Execute(_ context.Context, _ tooltypes.State, _ string) toolty
 func (t testTool) TracingKVs(_ string) ([]attribute.KeyValue, error) {
 	return nil, nil
 }
+
+func TestPruneToolResults(t *testing.T) {
+	toolResult := func(id, text string) anthropic.MessageParam {
+		return anthropic.NewUserMessage(anthropic.NewToolResultBlock(id, text, false))
+	}
+	thread := &Thread{
+		Thread: base.NewThread(llmtypes.Config{}, "conv-test"),
+		messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock("hello")),
+			toolResult("tool-1", strings.Repeat("a", 400)),
+			toolResult("tool-2", strings.Repeat("b", 800)),
+			toolResult("tool-3", "recent"),
+		},
+	}
+	snapshot := slices.Clone(thread.messages)
+
+	assert.Equal(t, 300, thread.pruneToolResults(1, false))
+	assert.Equal(t, strings.Repeat("a", 400), thread.messages[1].Content[0].OfToolResult.Content[0].OfText.Text)
+
+	assert.Equal(t, 300, thread.pruneToolResults(1, true))
+	for _, msg := range thread.messages[1:3] {
+		result := msg.Content[0].OfToolResult
+		require.NotNil(t, result)
+		assert.Equal(t, base.PrunedToolResultText, result.Content[0].OfText.Text)
+	}
+	assert.Equal(t, "tool-2", thread.messages[2].Content[0].OfToolResult.ToolUseID)
+	assert.Equal(t, "recent", thread.messages[3].Content[0].OfToolResult.Content[0].OfText.Text)
+	assert.Equal(t, strings.Repeat("a", 400), snapshot[1].Content[0].OfToolResult.Content[0].OfText.Text, "snapshots must not change")
+
+	assert.Zero(t, thread.pruneToolResults(1, true), "pruned results are not counted again")
+}
@@ -0,0 +1,78 @@
+package anthropic
+
+import (
+	"context"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/jingkaihe/kodelet/pkg/auth"
+	"github.com/jingkaihe/kodelet/pkg/llm/base"
+	"github.com/jingkaihe/kodelet/pkg/sysprompt"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+)
+
+// cachePrimeMessage is the throwaway user turn sent after the cached prefix.
+const cachePrimeMessage = "Reply with OK."
+
+// PrimePromptCache writes the tools and system prompt of the next exchange to
+// the prompt cache with the given TTL, 5m or 1h, so that exchange reads them
+// at the cache read price. The request asks for a single output token and is
+// not added to the conversation.
+func (t *Thread) PrimePromptCache(ctx context.Context, ttl time.Duration) (llmtypes.CachePrimeResult, error) {
+	var cacheTTL anthropic.CacheControlEphemeralTTL
+	switch ttl {
+	case 5 * time.Minute:
+		cacheTTL = anthropic.CacheControlEphemeralTTLTTL5m
+	case time.Hour:
+		cacheTTL = anthropic.CacheControlEphemeralTTLTTL1h
+	default:
+		return llmtypes.CachePrimeResult{}, errors.Errorf("unsupported prompt cache TTL %s (supported: 5m, 1h)", ttl)
+	}
+
+	model, _ := t.getModelAndTokens(llmtypes.MessageOpt{})
+	var contexts map[string]string
+	if t.State != nil {
+		contexts = t.State.DiscoverContexts()
+	}
+	systemPrompt := base.ProcessSystemPrompt(ctx, t, sysprompt.SystemPrompt(model, t.Config, contexts))
+
+	// Mirror the prefix processMessageExchange sends: tools, then the system
+	// blocks, each ending with a cache breakpoint.
+	systemPromptBlocks := []anthropic.TextBlockParam{}
+	if t.useSubscription {
+		systemPromptBlocks = append(systemPromptBlocks, auth.AnthropicSystemPrompt()...)
+	}
+	systemPromptBlocks = append(systemPromptBlocks, anthropic.TextBlockParam{Text: systemPrompt})
+
+	params := anthropic.MessageNewParams{
+		MaxTokens: 1,
+		System:    systemPromptBlocks,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock(cachePrimeMessage))},
+		Model:     model,
+		Tools:     toAnthropicTools(t.tools(llmtypes.MessageOpt{}), t.useSubscription),
+	}
+	cacheAnthropicToolDefinitions(params.Tools)
+	cacheAnthropicSystemPrompt(params.System)
+	if len(params.Tools) > 0 {
+		if cacheControl := params.Tools[len(params.Tools)-1].GetCacheControl(); cacheControl != nil {
+			cacheControl.TTL = cacheTTL
+		}
+	}
+	params.System[len(params.System)-1].CacheControl.TTL = cacheTTL
+
+	response, err := t.NewMessage(ctx, params, &llmtypes.StringCollectorHandler{Silent: true}, llmtypes.MessageOpt{})
+	if err != nil {
+		return llmtypes.CachePrimeResult{}, errors.Wrap(err, "failed to prime the prompt cache")
+	}
+	t.updateUsage(response, model)
+
+	usage := t.GetUsage()
+	return llmtypes.CachePrimeResult{
+		Model:            model,
+		CacheWriteTokens: int(response.Usage.CacheCreationInputTokens),
+		CacheReadTokens:  int(response.Usage.CacheReadInputTokens),
+		Cost:             usage.TotalCost(),
+		TTL:              ttl,
+	}, nil
+}
@@ -0,0 +1,64 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/jingkaihe/kodelet/pkg/llm/base"
+	"github.com/jingkaihe/kodelet/pkg/tools"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrimePromptCache(t *testing.T) {
+	var captured map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &captured))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("event: message_start\n" +
+			`data: {"type":"message_start","message":{"id":"msg_test","type":"message","role":"assistant","model":"claude-sonnet-4-6","content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":5,"output_tokens":0,"cache_creation_input_tokens":4000,"cache_read_input_tokens":0}}}` + "\n\n" +
+			"event: message_delta\n" +
+			`data: {"type":"message_delta","delta":{"stop_reason":"max_tokens","stop_sequence":null},"usage":{"output_tokens":1}}` + "\n\n" +
+			"event: message_stop\n" +
+			`data: {"type":"message_stop"}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	config := llmtypes.Config{Provider: "anthropic", Model: "claude-sonnet-4-6"}
+	thread := &Thread{
+		Thread: base.NewThread(config, "conv-test"),
+		client: anthropic.NewClient(option.WithBaseURL(server.URL), option.WithAPIKey("test-key")),
+	}
+	thread.SetState(tools.NewBasicState(context.Background(), tools.WithLLMConfig(config), tools.WithMainTools()))
+
+	result, err := thread.PrimePromptCache(context.Background(), time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "claude-sonnet-4-6", result.Model)
+	assert.Equal(t, 4000, result.CacheWriteTokens)
+	assert.Equal(t, time.Hour, result.TTL)
+	assert.Positive(t, result.Cost)
+
+	assert.EqualValues(t, 1, captured["max_tokens"])
+	system := captured["system"].([]any)
+	lastSystem := system[len(system)-1].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "ephemeral", "ttl": "1h"}, lastSystem["cache_control"])
+	toolDefs := captured["tools"].([]any)
+	require.NotEmpty(t, toolDefs)
+	assert.Equal(t, map[string]any{"type": "ephemeral", "ttl": "1h"}, toolDefs[len(toolDefs)-1].(map[string]any)["cache_control"])
+	assert.Empty(t, thread.messages, "priming does not add to the conversation")
+
+	_, err = thread.PrimePromptCache(context.Background(), 10*time.Minute)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "supported: 5m, 1h")
+}
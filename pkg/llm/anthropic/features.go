@@ -0,0 +1,35 @@
+package anthropic
+
+import (
+	"github.com/anthropics/anthropic-sdk-go"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// ModelFeaturesMap holds the output limits of Claude models. Every Claude
+// model accepts tools, images and parallel tool calls.
+var ModelFeaturesMap = llmtypes.CustomFeatures{
+	string(modelClaude35Haiku):                      {MaxOutputTokens: 8_192},
+	string(modelClaude35Haiku20241022):              {MaxOutputTokens: 8_192},
+	string(anthropic.ModelClaudeHaiku4_5):           {MaxOutputTokens: 64_000},
+	string(anthropic.ModelClaudeHaiku4_5_20251001):  {MaxOutputTokens: 64_000},
+	string(anthropic.ModelClaudeSonnet4_5):          {MaxOutputTokens: 64_000},
+	string(anthropic.ModelClaudeSonnet4_5_20250929): {MaxOutputTokens: 64_000},
+	string(anthropic.ModelClaudeSonnet4_6):          {MaxOutputTokens: 64_000},
+	string(anthropic.ModelClaudeOpus4_5):            {MaxOutputTokens: 64_000},
+	string(anthropic.ModelClaudeOpus4_5_20251101):   {MaxOutputTokens: 64_000},
+	string(anthropic.ModelClaudeOpus4_6):            {MaxOutputTokens: 128_000},
+}
+
+// modelFeatures returns what model accepts, from ModelFeaturesMap and the
+// model_features configuration. Vertex AI model names resolve to their
+// Anthropic IDs.
+func (t *Thread) modelFeatures(model anthropic.Model) llmtypes.ModelFeatures {
+	canonical := string(canonicalModelID(model))
+	features, _ := ModelFeaturesMap.Lookup(canonical)
+	override, ok := t.Config.ModelFeatures.Lookup(string(model))
+	if !ok {
+		override, _ = t.Config.ModelFeatures.Lookup(canonical)
+	}
+	return features.Merge(override)
+}
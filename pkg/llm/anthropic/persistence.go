@@ -72,6 +72,7 @@ func (t *Thread) SaveConversation(ctx context.Context, summarise bool) error {
 	if !t.Persisted || t.Store == nil {
 		return nil
 	}
+	journalPosition := t.JournalPosition(ctx)
 
 	// Clean up orphaned messages before saving
 	t.cleanupOrphanedMessages()
@@ -88,7 +89,8 @@ func (t *Thread) SaveConversation(ctx context.Context, summarise bool) error {
 		return errors.Wrap(err, "failed to parse conversation messages for summary")
 	}
 	metadata := t.GetMetadata()
-	summary := base.FirstUserMessageFallback(conversations.ApplyDisplayToStreamableMessages(conversationsFromAnthropic(messages), metadata))
+	displayMessages := conversations.ApplyDisplayToStreamableMessages(conversationsFromAnthropic(messages), metadata)
+	summary := base.FirstUserMessageFallback(displayMessages)
 
 	if summarise {
 		if t.Config.ConversationSummaryMode.UsesLLM() {
@@ -101,6 +103,9 @@ func (t *Thread) SaveConversation(ctx context.Context, summarise bool) error {
 		}
 	}
 	t.summary = summary
+	if summarise {
+		t.RecordConversationTitle(ctx, metadata, displayMessages, toolResults, t.runUtilityPrompt)
+	}
 
 	// Create a new conversation record
 	if profile := strings.TrimSpace(t.Config.Profile); profile != "" {
@@ -115,6 +120,10 @@ func (t *Thread) SaveConversation(ctx context.Context, summarise bool) error {
 	if err != nil {
 		return errors.Wrap(err, "failed to persist conversation config snapshot")
 	}
+	metadata = conversations.AddExperiment(metadata, t.Config.ExperimentAssignment)
+	if snapshot, ok := t.ContextSnapshot(ctx, summarise); ok {
+		metadata = conversations.AddContextSnapshot(metadata, snapshot)
+	}
 
 	record := convtypes.ConversationRecord{
 		ID:          t.ConversationID,
@@ -130,7 +139,11 @@ func (t *Thread) SaveConversation(ctx context.Context, summarise bool) error {
 	}
 
 	// Save the record
-	return t.Store.Save(ctx, record)
+	if err := t.Store.Save(ctx, record); err != nil {
+		return err
+	}
+	t.ClearJournal(ctx, journalPosition)
+	return nil
 }
 
 // loadConversation loads a conversation from the store into the thread.
@@ -141,11 +154,12 @@ func (t *Thread) loadConversation(ctx context.Context) {
 		return
 	}
 
-	// Try to load the conversation
+	// Try to load the conversation. A conversation killed before its first
+	// save may only exist in its journal.
 	record, err := t.Store.Load(ctx, t.ConversationID)
 	if err != nil {
-		// Log error but don't return - caller expects void return
-		return
+		record = convtypes.NewConversationRecord(t.ConversationID)
+		record.Provider = "anthropic"
 	}
 
 	// Check if this is an Anthropic model conversation
@@ -153,12 +167,19 @@ func (t *Thread) loadConversation(ctx context.Context) {
 		return
 	}
 
-	// Reset current messages
-	messages, err := DeserializeMessages(record.RawMessages)
-	if err != nil {
+	replay := t.ReplayJournal(ctx, &record)
+	if err != nil && replay.Messages == 0 {
 		return
 	}
-	t.messages = messages
+
+	// Reset current messages
+	messages := []anthropic.MessageParam{}
+	if len(record.RawMessages) > 0 {
+		if messages, err = DeserializeMessages(record.RawMessages); err != nil {
+			return
+		}
+	}
+	t.messages = recoverJournaledTurn(messages, replay)
 
 	t.cleanupOrphanedMessages()
 	// Restore usage statistics
@@ -169,6 +190,33 @@ func (t *Thread) loadConversation(ctx context.Context) {
 	t.SetStructuredToolResults(record.ToolResults)
 }
 
+// recoverJournaledTurn adds the text replay recovered as an assistant message,
+// or answers the tool_use blocks of a trailing assistant message.
+func recoverJournaledTurn(messages []anthropic.MessageParam, replay convtypes.JournalReplay) []anthropic.MessageParam {
+	if !replay.Recovered() {
+		return messages
+	}
+	if replay.AssistantText != "" {
+		return append(messages, anthropic.NewAssistantMessage(anthropic.NewTextBlock(replay.AssistantText)))
+	}
+	if len(messages) == 0 || messages[len(messages)-1].Role != anthropic.MessageParamRoleAssistant {
+		return messages
+	}
+
+	var results []anthropic.ContentBlockParamUnion
+	for _, block := range messages[len(messages)-1].Content {
+		if block.OfToolUse == nil {
+			continue
+		}
+		output := base.RecoveredToolOutput(replay, block.OfToolUse.ID)
+		results = append(results, anthropic.NewToolResultBlock(block.OfToolUse.ID, output.Text, output.IsError))
+	}
+	if len(results) == 0 {
+		return messages
+	}
+	return append(messages, anthropic.NewUserMessage(results...))
+}
+
 // DeserializeMessages deserializes a JSON byte array into Anthropic message parameters
 func DeserializeMessages(b []byte) ([]anthropic.MessageParam, error) {
 	var messages []anthropic.MessageParam
@@ -181,12 +229,37 @@ func DeserializeMessages(b []byte) ([]anthropic.MessageParam, error) {
 
 // StreamableMessage contains parsed message data for streaming
 type StreamableMessage struct {
-	Kind       string // "text", "tool-use", "tool-result", "thinking"
-	Role       string // "user", "assistant", "system"
-	Content    string // Text content
-	ToolName   string // For tool use/result
-	ToolCallID string // For matching tool results
-	Input      string // For tool use (JSON string)
+	Kind       string         // "text", "tool-use", "tool-result", "thinking"
+	Role       string         // "user", "assistant", "system"
+	Content    string         // Text content
+	ToolName   string         // For tool use/result
+	ToolCallID string         // For matching tool results
+	Input      string         // For tool use (JSON string)
+	Reasoning  *llm.Reasoning // For thinking
+}
+
+// ReasoningFromBlock converts a thinking or redacted thinking block into the
+// provider-neutral transcript form. The block itself, including its
+// signature, is kept as the opaque payload.
+func ReasoningFromBlock(block anthropic.ContentBlockParamUnion) (llm.Reasoning, bool) {
+	switch {
+	case block.OfThinking != nil:
+		opaque, _ := json.Marshal(block.OfThinking)
+		return llm.Reasoning{
+			Provider: "anthropic",
+			Format:   llm.ReasoningFormatThinking,
+			Text:     block.OfThinking.Thinking,
+			Opaque:   opaque,
+		}, true
+	case block.OfRedactedThinking != nil:
+		opaque, _ := json.Marshal(block.OfRedactedThinking)
+		return llm.Reasoning{
+			Provider: "anthropic",
+			Format:   llm.ReasoningFormatRedacted,
+			Opaque:   opaque,
+		}, true
+	}
+	return llm.Reasoning{}, false
 }
 
 // StreamMessages parses raw messages into streamable format for conversation streaming
@@ -256,11 +329,12 @@ func StreamMessages(rawMessages json.RawMessage, toolResults map[string]tooltype
 				})
 			}
 
-			if thinkingBlock := contentBlock.OfThinking; thinkingBlock != nil && thinkingBlock.Thinking != "" {
+			if reasoning, ok := ReasoningFromBlock(contentBlock); ok && reasoning.DisplayText() != "" {
 				streamable = append(streamable, StreamableMessage{
-					Kind:    "thinking",
-					Role:    string(msg.Role),
-					Content: thinkingBlock.Thinking,
+					Kind:      "thinking",
+					Role:      string(msg.Role),
+					Content:   reasoning.DisplayText(),
+					Reasoning: &reasoning,
 				})
 			}
 		}
@@ -325,10 +399,10 @@ func ExtractMessages(rawMessages json.RawMessage, toolResults map[string]tooltyp
 				}
 			}
 			// Handle thinking blocks
-			if thinkingBlock := contentBlock.OfThinking; thinkingBlock != nil {
+			if reasoning, ok := ReasoningFromBlock(contentBlock); ok && reasoning.TranscriptText() != "" {
 				messages = append(messages, llm.Message{
 					Role:    "assistant",
-					Content: fmt.Sprintf("💭 Thinking: %s", strings.TrimLeft(thinkingBlock.Thinking, "\n")),
+					Content: reasoning.TranscriptText(),
 				})
 			}
 		}
@@ -356,3 +430,49 @@ func anthropicImageDisplayString(imageBlock *anthropic.ImageBlockParam) string {
 
 	return ""
 }
+
+// RedactToolResults replaces the results of the given tools with a
+// placeholder, keeping each tool_result block so tool calls stay paired. It
+// returns the rewritten messages and the IDs of the redacted tool calls.
+func RedactToolResults(rawMessages json.RawMessage, toolNames conversations.ToolRedactionSet) (json.RawMessage, []string, error) {
+	messages, err := DeserializeMessages(rawMessages)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	redactedTools := make(map[string]string)
+	for _, msg := range messages {
+		for _, contentBlock := range msg.Content {
+			if toolUse := contentBlock.OfToolUse; toolUse != nil && toolNames.Contains(toolUse.Name) {
+				redactedTools[toolUse.ID] = toolUse.Name
+			}
+		}
+	}
+
+	var redactedIDs []string
+	for i := range messages {
+		for j := range messages[i].Content {
+			toolResult := messages[i].Content[j].OfToolResult
+			if toolResult == nil {
+				continue
+			}
+			toolName, ok := redactedTools[toolResult.ToolUseID]
+			if !ok {
+				continue
+			}
+			toolResult.Content = []anthropic.ToolResultBlockParamContentUnion{{
+				OfText: &anthropic.TextBlockParam{Text: conversations.RedactedToolResultPlaceholder(toolName)},
+			}}
+			redactedIDs = append(redactedIDs, toolResult.ToolUseID)
+		}
+	}
+	if len(redactedIDs) == 0 {
+		return rawMessages, nil, nil
+	}
+
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to marshal redacted messages")
+	}
+	return data, redactedIDs, nil
+}
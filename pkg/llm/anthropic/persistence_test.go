@@ -56,6 +56,14 @@ func (m *MockConversationStore) Query(_ context.Context, _ convtypes.QueryOption
 	return convtypes.QueryResult{}, nil
 }
 
+func (m *MockConversationStore) Fork(_ context.Context, _ string) (convtypes.ConversationRecord, error) {
+	return convtypes.ConversationRecord{}, nil
+}
+
+func (m *MockConversationStore) Search(_ context.Context, _ string, _ convtypes.QueryOptions) (convtypes.SearchResult, error) {
+	return convtypes.SearchResult{}, nil
+}
+
 func (m *MockConversationStore) Close() error {
 	return nil
 }
@@ -1487,3 +1495,130 @@ func TestStreamMessages_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestReasoningFromBlock(t *testing.T) {
+	reasoning, ok := ReasoningFromBlock(anthropic.NewThinkingBlock("sig-123", "Consider the edge cases."))
+	require.True(t, ok)
+	assert.Equal(t, "anthropic", reasoning.Provider)
+	assert.Equal(t, llmtypes.ReasoningFormatThinking, reasoning.Format)
+	assert.Equal(t, "Consider the edge cases.", reasoning.Text)
+	assert.Contains(t, string(reasoning.Opaque), "sig-123", "the signature is kept for replay")
+
+	reasoning, ok = ReasoningFromBlock(anthropic.NewRedactedThinkingBlock("opaque-data"))
+	require.True(t, ok)
+	assert.Equal(t, llmtypes.ReasoningFormatRedacted, reasoning.Format)
+	assert.Empty(t, reasoning.TranscriptText())
+	assert.Contains(t, string(reasoning.Opaque), "opaque-data")
+
+	_, ok = ReasoningFromBlock(anthropic.NewTextBlock("hello"))
+	assert.False(t, ok)
+}
+
+// journalingMockStore is a MockConversationStore that keeps a journal.
+type journalingMockStore struct {
+	MockConversationStore
+	journal []convtypes.JournalEvent
+	cleared int64
+	// duringSave runs while a conversation is being saved.
+	duringSave func()
+}
+
+func (m *journalingMockStore) Save(ctx context.Context, record convtypes.ConversationRecord) error {
+	if m.duringSave != nil {
+		m.duringSave()
+	}
+	return m.MockConversationStore.Save(ctx, record)
+}
+
+func (m *journalingMockStore) AppendJournal(_ context.Context, _ string, event convtypes.JournalEvent) error {
+	m.journal = append(m.journal, event)
+	return nil
+}
+
+func (m *journalingMockStore) LoadJournal(_ context.Context, _ string) ([]convtypes.JournalEvent, error) {
+	return m.journal, nil
+}
+
+func (m *journalingMockStore) JournalPosition(_ context.Context, _ string) (int64, error) {
+	return m.cleared + int64(len(m.journal)), nil
+}
+
+func (m *journalingMockStore) ClearJournal(_ context.Context, _ string, position int64) error {
+	n := min(position-m.cleared, int64(len(m.journal)))
+	m.journal = m.journal[n:]
+	m.cleared += n
+	return nil
+}
+
+func journalMessageEvent(t *testing.T, index int, message anthropic.MessageParam) convtypes.JournalEvent {
+	t.Helper()
+	raw, err := json.Marshal(message)
+	require.NoError(t, err)
+	return convtypes.JournalEvent{Kind: convtypes.JournalMessage, Provider: "anthropic", Index: index, Message: raw}
+}
+
+func TestLoadConversationRecoversInterruptedToolCalls(t *testing.T) {
+	ctx := context.Background()
+	rawMessages, err := json.Marshal([]anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("deploy it"))})
+	require.NoError(t, err)
+	store := &journalingMockStore{}
+	store.LoadedRecord = &convtypes.ConversationRecord{ID: "conv-1", Provider: "anthropic", RawMessages: rawMessages}
+	store.journal = []convtypes.JournalEvent{
+		journalMessageEvent(t, 1, anthropic.NewAssistantMessage(
+			anthropic.NewToolUseBlock("call-1", map[string]string{"command": "make deploy"}, "bash"),
+			anthropic.NewToolUseBlock("call-2", map[string]string{"command": "make smoke"}, "bash"),
+		)),
+		{
+			Kind:       convtypes.JournalToolResult,
+			Provider:   "anthropic",
+			ToolCallID: "call-1",
+			Text:       "deployed",
+			ToolResult: &tooltypes.StructuredToolResult{ToolName: "bash", Success: true},
+		},
+	}
+
+	thread, err := NewAnthropicThread(llmtypes.Config{Model: anthropic.ModelClaudeSonnet4_6})
+	require.NoError(t, err)
+	thread.ConversationID = "conv-1"
+	thread.Store = store
+	thread.Persisted = true
+	thread.loadConversation(ctx)
+
+	require.Len(t, thread.messages, 3)
+	results := thread.messages[2].Content
+	require.Len(t, results, 2)
+	require.NotNil(t, results[0].OfToolResult)
+	assert.Equal(t, "call-1", results[0].OfToolResult.ToolUseID)
+	assert.Equal(t, "deployed", results[0].OfToolResult.Content[0].OfText.Text)
+	assert.False(t, results[0].OfToolResult.IsError.Value)
+	assert.Equal(t, "call-2", results[1].OfToolResult.ToolUseID)
+	assert.True(t, results[1].OfToolResult.IsError.Value)
+	assert.Contains(t, thread.GetStructuredToolResults(), "call-1")
+
+	// Saving the recovered conversation clears its journal, except for what
+	// is journaled while the save runs
+	lateResult := convtypes.JournalEvent{Kind: convtypes.JournalToolResult, Provider: "anthropic", ToolCallID: "call-3", Text: "late"}
+	store.duringSave = func() { store.journal = append(store.journal, lateResult) }
+	thread.SetState(tools.NewBasicState(ctx))
+	require.NoError(t, thread.SaveConversation(ctx, false))
+	assert.Equal(t, []convtypes.JournalEvent{lateResult}, store.journal)
+}
+
+func TestLoadConversationRecoversUnsavedConversation(t *testing.T) {
+	store := &journalingMockStore{}
+	store.journal = []convtypes.JournalEvent{
+		journalMessageEvent(t, 0, anthropic.NewUserMessage(anthropic.NewTextBlock("explain the build"))),
+		{Kind: convtypes.JournalAssistantText, Provider: "anthropic", Index: 1, Text: "The build runs "},
+		{Kind: convtypes.JournalAssistantText, Provider: "anthropic", Index: 1, Text: "in two stages"},
+	}
+
+	thread, err := NewAnthropicThread(llmtypes.Config{Model: anthropic.ModelClaudeSonnet4_6})
+	require.NoError(t, err)
+	thread.Store = store
+	thread.Persisted = true
+	thread.loadConversation(context.Background())
+
+	require.Len(t, thread.messages, 2)
+	assert.Equal(t, anthropic.MessageParamRoleAssistant, thread.messages[1].Role)
+	assert.Equal(t, "The build runs in two stages", thread.messages[1].Content[0].OfText.Text)
+}
@@ -0,0 +1,119 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/pkg/errors"
+
+	llm "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// ToPortableMessages converts stored Anthropic messages into the
+// provider-neutral form. Thinking blocks are dropped, because their
+// signatures are only valid for Anthropic, and images become a placeholder.
+func ToPortableMessages(rawMessages []byte) ([]llm.PortableMessage, error) {
+	messages, err := DeserializeMessages(rawMessages)
+	if err != nil {
+		return nil, err
+	}
+
+	var portable []llm.PortableMessage
+	for _, msg := range messages {
+		var text []string
+		var toolCalls []llm.PortableToolCall
+		for _, block := range msg.Content {
+			switch {
+			case block.OfText != nil && block.OfText.Text != "":
+				text = append(text, block.OfText.Text)
+			case block.OfImage != nil:
+				text = append(text, llm.PortableImagePlaceholder)
+			case block.OfToolUse != nil:
+				input, _ := json.Marshal(block.OfToolUse.Input)
+				toolCalls = append(toolCalls, llm.PortableToolCall{
+					ID:    block.OfToolUse.ID,
+					Name:  block.OfToolUse.Name,
+					Input: string(input),
+				})
+			case block.OfToolResult != nil:
+				portable = append(portable, llm.PortableMessage{
+					Role:       "tool",
+					Content:    toolResultText(block.OfToolResult),
+					ToolCallID: block.OfToolResult.ToolUseID,
+				})
+			}
+		}
+		if len(text) == 0 && len(toolCalls) == 0 {
+			continue
+		}
+		portable = append(portable, llm.PortableMessage{
+			Role:      string(msg.Role),
+			Content:   strings.Join(text, "\n\n"),
+			ToolCalls: toolCalls,
+		})
+	}
+	return portable, nil
+}
+
+func toolResultText(block *anthropic.ToolResultBlockParam) string {
+	var parts []string
+	for _, content := range block.Content {
+		switch {
+		case content.OfText != nil:
+			parts = append(parts, content.OfText.Text)
+		case content.OfImage != nil:
+			parts = append(parts, llm.PortableImagePlaceholder)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// FromPortableMessages converts provider-neutral messages into stored
+// Anthropic messages. Tool results and user text that follow each other are
+// sent in one user message, as the Messages API expects.
+func FromPortableMessages(portable []llm.PortableMessage) (json.RawMessage, error) {
+	var messages []anthropic.MessageParam
+	appendBlocks := func(role anthropic.MessageParamRole, blocks ...anthropic.ContentBlockParamUnion) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(messages); n > 0 && messages[n-1].Role == role {
+			messages[n-1].Content = append(messages[n-1].Content, blocks...)
+			return
+		}
+		messages = append(messages, anthropic.MessageParam{Role: role, Content: blocks})
+	}
+
+	for _, msg := range portable {
+		switch msg.Role {
+		case "user":
+			if msg.Content != "" {
+				appendBlocks(anthropic.MessageParamRoleUser, anthropic.NewTextBlock(msg.Content))
+			}
+		case "tool":
+			appendBlocks(anthropic.MessageParamRoleUser, anthropic.NewToolResultBlock(msg.ToolCallID, msg.Content, false))
+		case "assistant":
+			var blocks []anthropic.ContentBlockParamUnion
+			if msg.Content != "" {
+				blocks = append(blocks, anthropic.NewTextBlock(msg.Content))
+			}
+			for _, call := range msg.ToolCalls {
+				var input any = map[string]any{}
+				if strings.TrimSpace(call.Input) != "" {
+					if err := json.Unmarshal([]byte(call.Input), &input); err != nil {
+						return nil, errors.Wrapf(err, "invalid input of tool call %s", call.ID)
+					}
+				}
+				blocks = append(blocks, anthropic.NewToolUseBlock(call.ID, input, call.Name))
+			}
+			appendBlocks(anthropic.MessageParamRoleAssistant, blocks...)
+		}
+	}
+
+	raw, err := json.Marshal(messages)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal anthropic messages")
+	}
+	return raw, nil
+}
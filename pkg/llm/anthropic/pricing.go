@@ -2,8 +2,12 @@ package anthropic
 
 import (
 	"strings"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
+
+	"github.com/jingkaihe/kodelet/pkg/pricing"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 )
 
 const (
@@ -20,6 +24,41 @@ type ModelPricing struct {
 	PromptCachingWrite1h float64
 	PromptCachingRead    float64
 	ContextWindow        int
+
+	// Long-context rates replace the standard rates for a whole request once
+	// its prompt exceeds LongContextThreshold tokens. Zero rates keep the
+	// standard rate.
+	LongContextThreshold            int
+	LongContextInput                float64
+	LongContextOutput               float64
+	LongContextPromptCachingWrite5m float64
+	LongContextPromptCachingWrite1h float64
+	LongContextPromptCachingRead    float64
+}
+
+// forPromptTokens returns the pricing that applies to a request whose prompt,
+// including cached tokens, has promptTokens tokens.
+func (p ModelPricing) forPromptTokens(promptTokens int) ModelPricing {
+	if p.LongContextThreshold <= 0 || promptTokens <= p.LongContextThreshold {
+		return p
+	}
+
+	if p.LongContextInput > 0 {
+		p.Input = p.LongContextInput
+	}
+	if p.LongContextOutput > 0 {
+		p.Output = p.LongContextOutput
+	}
+	if p.LongContextPromptCachingWrite5m > 0 {
+		p.PromptCachingWrite5m = p.LongContextPromptCachingWrite5m
+	}
+	if p.LongContextPromptCachingWrite1h > 0 {
+		p.PromptCachingWrite1h = p.LongContextPromptCachingWrite1h
+	}
+	if p.LongContextPromptCachingRead > 0 {
+		p.PromptCachingRead = p.LongContextPromptCachingRead
+	}
+	return p
 }
 
 // ModelPricingMap maps model names to their pricing information
@@ -140,6 +179,11 @@ var ModelPricingMap = map[anthropic.Model]ModelPricing{
 
 // getModelPricing returns the pricing information for a given model
 func getModelPricing(model anthropic.Model) ModelPricing {
+	model = canonicalModelID(model)
+	// Prices from a refreshed pricing manifest supersede the built-in table
+	if manifestPricing, ok := pricing.Current().Lookup("anthropic", string(model), "", time.Now()); ok {
+		return fromManifestPricing(manifestPricing)
+	}
 	// First try exact match
 	if pricing, ok := ModelPricingMap[model]; ok {
 		return pricing
@@ -171,3 +215,41 @@ func getModelPricing(model anthropic.Model) ModelPricing {
 	// Default to Claude Sonnet 4.6 pricing if no match
 	return ModelPricingMap[anthropic.ModelClaudeSonnet4_6]
 }
+
+// fromManifestPricing converts pricing manifest rates. One-hour cache writes
+// default to twice the input rate, as Anthropic prices them.
+func fromManifestPricing(p llmtypes.ModelPricing) ModelPricing {
+	result := ModelPricing{
+		Input:                           p.Input,
+		Output:                          p.Output,
+		PromptCachingWrite5m:            p.CacheWriteInput,
+		PromptCachingWrite1h:            p.CacheWrite1hInput,
+		PromptCachingRead:               p.CachedInput,
+		ContextWindow:                   p.ContextWindow,
+		LongContextThreshold:            p.LongContextThreshold,
+		LongContextInput:                p.LongContextInput,
+		LongContextOutput:               p.LongContextOutput,
+		LongContextPromptCachingWrite5m: p.LongContextCacheWriteInput,
+		LongContextPromptCachingWrite1h: 2 * p.LongContextInput,
+		LongContextPromptCachingRead:    p.LongContextCachedInput,
+	}
+	if result.PromptCachingWrite1h == 0 {
+		result.PromptCachingWrite1h = 2 * p.Input
+	}
+	return result
+}
+
+// PricingForModel returns the per-token pricing of model, falling back to its
+// model family like the costs of a thread. Cache writes use the five-minute
+// rate.
+func PricingForModel(model string) llmtypes.ModelPricing {
+	p := getModelPricing(anthropic.Model(model))
+	return llmtypes.ModelPricing{
+		Input:             p.Input,
+		CachedInput:       p.PromptCachingRead,
+		CacheWriteInput:   p.PromptCachingWrite5m,
+		CacheWrite1hInput: p.PromptCachingWrite1h,
+		Output:            p.Output,
+		ContextWindow:     p.ContextWindow,
+	}
+}
@@ -0,0 +1,62 @@
+package anthropic
+
+import (
+	"slices"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/jingkaihe/kodelet/pkg/llm/base"
+)
+
+// pruneToolResults estimates the tokens held by all but the keepRecent most
+// recent tool results and, when apply is set, replaces their content with a
+// placeholder. Tool use and result pairing is preserved.
+func (t *Thread) pruneToolResults(keepRecent int, apply bool) int {
+	type location struct{ message, block int }
+	var results []location
+	for i, msg := range t.messages {
+		for j, block := range msg.Content {
+			if block.OfToolResult != nil {
+				results = append(results, location{i, j})
+			}
+		}
+	}
+
+	tokens := 0
+	for _, loc := range results[:base.PrunableCount(len(results), keepRecent)] {
+		result := t.messages[loc.message].Content[loc.block].OfToolResult
+		if isPrunedToolResult(result) {
+			continue
+		}
+		tokens += toolResultTokens(result)
+		if !apply {
+			continue
+		}
+		// Copy before writing: saved message snapshots share content slices.
+		content := slices.Clone(t.messages[loc.message].Content)
+		content[loc.block].OfToolResult = &anthropic.ToolResultBlockParam{
+			ToolUseID: result.ToolUseID,
+			IsError:   result.IsError,
+			Content: []anthropic.ToolResultBlockParamContentUnion{{
+				OfText: &anthropic.TextBlockParam{Text: base.PrunedToolResultText},
+			}},
+		}
+		t.messages[loc.message].Content = content
+	}
+	return tokens
+}
+
+func isPrunedToolResult(result *anthropic.ToolResultBlockParam) bool {
+	return len(result.Content) == 1 && result.Content[0].OfText != nil && result.Content[0].OfText.Text == base.PrunedToolResultText
+}
+
+func toolResultTokens(result *anthropic.ToolResultBlockParam) int {
+	tokens := 0
+	for _, part := range result.Content {
+		if part.OfText != nil {
+			tokens += base.EstimateTextTokens(part.OfText.Text)
+		} else {
+			tokens += base.EstimatedNonTextTokens
+		}
+	}
+	return tokens
+}
@@ -3,12 +3,12 @@ package anthropic
 import (
 	"context"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/jingkaihe/kodelet/pkg/auth"
+	"github.com/jingkaihe/kodelet/pkg/quota"
 	"github.com/pkg/errors"
 )
 
@@ -65,26 +65,26 @@ func GetRateLimitStats(ctx context.Context, accountAlias string) (*RateLimitStat
 		return nil, errors.Wrap(err, "failed to make API request")
 	}
 
-	stats.Status5h = capturedHeaders.Get("Anthropic-Ratelimit-Unified-5h-Status")
-	stats.Status7d = capturedHeaders.Get("Anthropic-Ratelimit-Unified-7d-Status")
-
-	if v := capturedHeaders.Get("Anthropic-Ratelimit-Unified-5h-Utilization"); v != "" {
-		stats.Utilization5h, _ = strconv.ParseFloat(v, 64)
-	}
-	if v := capturedHeaders.Get("Anthropic-Ratelimit-Unified-7d-Utilization"); v != "" {
-		stats.Utilization7d, _ = strconv.ParseFloat(v, 64)
-	}
-
-	if v := capturedHeaders.Get("Anthropic-Ratelimit-Unified-5h-Reset"); v != "" {
-		if ts, err := strconv.ParseInt(v, 10, 64); err == nil {
-			stats.Reset5h = time.Unix(ts, 0)
-		}
-	}
-	if v := capturedHeaders.Get("Anthropic-Ratelimit-Unified-7d-Reset"); v != "" {
-		if ts, err := strconv.ParseInt(v, 10, 64); err == nil {
-			stats.Reset7d = time.Unix(ts, 0)
+	for _, window := range quota.ParseHeaders(capturedHeaders) {
+		switch window.Name {
+		case "5h":
+			stats.Status5h, stats.Utilization5h, stats.Reset5h = window.Status, window.Utilization, window.ResetsAt
+		case "7d":
+			stats.Status7d, stats.Utilization7d, stats.Reset7d = window.Status, window.Utilization, window.ResetsAt
 		}
 	}
 
 	return &stats, nil
 }
+
+// quotaMiddleware records the rate limit windows of every response, including
+// 429s, in tracker.
+func quotaMiddleware(tracker *quota.Tracker) option.Middleware {
+	return func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		resp, err := next(req)
+		if resp != nil {
+			tracker.Observe(resp.Header)
+		}
+		return resp, err
+	}
+}
@@ -0,0 +1,115 @@
+package anthropic
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/pkg/errors"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+const (
+	// platformVertex serves Claude from Google Cloud Vertex AI.
+	platformVertex      = "vertex"
+	defaultVertexRegion = "global"
+	// googleCloudPlatformScope is the OAuth scope Vertex AI requests need.
+	googleCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// vertexModelDateSuffix matches the release date Anthropic appends to
+// snapshot model IDs, such as -20250929 in claude-sonnet-4-5-20250929.
+var vertexModelDateSuffix = regexp.MustCompile(`-(\d{8})$`)
+
+// vertexSettings locate the Vertex AI publisher endpoint Claude is called on.
+type vertexSettings struct {
+	ProjectID string
+	Region    string
+}
+
+func isVertexPlatform(config llmtypes.Config) bool {
+	return config.Anthropic != nil && strings.EqualFold(strings.TrimSpace(config.Anthropic.Platform), platformVertex)
+}
+
+// resolveVertexSettings reads the Vertex AI project and region from the
+// configuration, then the environment variables used by other Claude on
+// Vertex clients, then the project of the credentials.
+func resolveVertexSettings(config llmtypes.Config, credentialsProjectID string) (vertexSettings, error) {
+	var settings vertexSettings
+	if config.Anthropic != nil && config.Anthropic.Vertex != nil {
+		settings.ProjectID = strings.TrimSpace(config.Anthropic.Vertex.ProjectID)
+		settings.Region = strings.TrimSpace(config.Anthropic.Vertex.Region)
+	}
+	for _, candidate := range []string{os.Getenv("ANTHROPIC_VERTEX_PROJECT_ID"), os.Getenv("GOOGLE_CLOUD_PROJECT"), credentialsProjectID} {
+		if settings.ProjectID != "" {
+			break
+		}
+		settings.ProjectID = strings.TrimSpace(candidate)
+	}
+	if settings.Region == "" {
+		settings.Region = strings.TrimSpace(os.Getenv("CLOUD_ML_REGION"))
+	}
+	if settings.Region == "" {
+		settings.Region = defaultVertexRegion
+	}
+	if settings.ProjectID == "" {
+		return settings, errors.New("no Google Cloud project for Vertex AI; set anthropic.vertex.project_id or ANTHROPIC_VERTEX_PROJECT_ID")
+	}
+	return settings, nil
+}
+
+// vertexModelID returns the Vertex AI name of an Anthropic model ID, which
+// separates the release date with @ instead of -.
+func vertexModelID(model string) string {
+	if strings.Contains(model, "@") {
+		return model
+	}
+	return vertexModelDateSuffix.ReplaceAllString(model, "@$1")
+}
+
+// canonicalModelID returns the Anthropic ID of a Vertex AI model name, so
+// pricing, context windows and thinking support are looked up the same way
+// for both platforms.
+func canonicalModelID(model anthropic.Model) anthropic.Model {
+	return anthropic.Model(strings.Replace(string(model), "@", "-", 1))
+}
+
+// vertexModelMiddleware renames the model of Messages API requests to its
+// Vertex AI name, before the Vertex AI middleware of the SDK moves it from
+// the body into the path.
+func vertexModelMiddleware(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	if req.Body == nil || req.Method != http.MethodPost {
+		return next(req)
+	}
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read request body")
+	}
+	req.Body.Close()
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, errors.Wrap(err, "failed to decode request body")
+	}
+	var model string
+	if err := json.Unmarshal(body["model"], &model); err == nil && model != "" {
+		body["model"], _ = json.Marshal(vertexModelID(model))
+		if data, err = json.Marshal(body); err != nil {
+			return nil, errors.Wrap(err, "failed to encode request body")
+		}
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.ContentLength = int64(len(data))
+	return next(req)
+}
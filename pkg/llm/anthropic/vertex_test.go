@@ -0,0 +1,104 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/vertex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+func TestVertexModelIDs(t *testing.T) {
+	assert.Equal(t, "claude-sonnet-4-5@20250929", vertexModelID("claude-sonnet-4-5-20250929"))
+	assert.Equal(t, "claude-sonnet-4-5@20250929", vertexModelID("claude-sonnet-4-5@20250929"))
+	assert.Equal(t, "claude-opus-4-7", vertexModelID("claude-opus-4-7"))
+
+	assert.Equal(t, anthropic.ModelClaudeSonnet4_5_20250929, canonicalModelID("claude-sonnet-4-5@20250929"))
+	assert.Equal(t, anthropic.ModelClaudeOpus4_7, canonicalModelID(anthropic.ModelClaudeOpus4_7))
+	assert.Equal(t, ModelPricingMap[anthropic.ModelClaudeOpus4_5_20251101], getModelPricing("claude-opus-4-5@20251101"))
+}
+
+func TestResolveVertexSettings(t *testing.T) {
+	t.Setenv("ANTHROPIC_VERTEX_PROJECT_ID", "")
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+	t.Setenv("CLOUD_ML_REGION", "")
+
+	_, err := resolveVertexSettings(llmtypes.Config{}, "")
+	require.Error(t, err)
+
+	settings, err := resolveVertexSettings(llmtypes.Config{}, "credentials-project")
+	require.NoError(t, err)
+	assert.Equal(t, vertexSettings{ProjectID: "credentials-project", Region: "global"}, settings)
+
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "env-project")
+	t.Setenv("CLOUD_ML_REGION", "europe-west1")
+	settings, err = resolveVertexSettings(llmtypes.Config{}, "credentials-project")
+	require.NoError(t, err)
+	assert.Equal(t, vertexSettings{ProjectID: "env-project", Region: "europe-west1"}, settings)
+
+	settings, err = resolveVertexSettings(llmtypes.Config{Anthropic: &llmtypes.AnthropicConfig{
+		Vertex: &llmtypes.AnthropicVertexConfig{ProjectID: "config-project", Region: "us-east5"},
+	}}, "credentials-project")
+	require.NoError(t, err)
+	assert.Equal(t, vertexSettings{ProjectID: "config-project", Region: "us-east5"}, settings)
+}
+
+func TestNewAnthropicThreadVertexRewritesMessagesRequests(t *testing.T) {
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"vertex-access-token","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer metadata.Close()
+
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("CLOUDSDK_CONFIG", t.TempDir())
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(metadata.URL, "http://"))
+	t.Setenv("ANTHROPIC_BASE_URL", "")
+	t.Setenv("ANTHROPIC_VERTEX_PROJECT_ID", "")
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+	t.Setenv("CLOUD_ML_REGION", "")
+
+	var requestPath, authorization string
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestPath = r.URL.Path
+		authorization = r.Header.Get("Authorization")
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5-20250929","content":[{"type":"text","text":"hi"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	thread, err := NewAnthropicThread(llmtypes.Config{
+		Model: "claude-sonnet-4-5@20250929",
+		Anthropic: &llmtypes.AnthropicConfig{
+			Platform: "vertex",
+			BaseURL:  server.URL,
+			Vertex:   &llmtypes.AnthropicVertexConfig{ProjectID: "my-project", Region: "us-east5"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, anthropic.ModelClaudeSonnet4_5_20250929, thread.Config.Model)
+
+	_, err = thread.client.Messages.New(t.Context(), anthropic.MessageNewParams{
+		Model:     thread.Config.Model,
+		MaxTokens: 16,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("hello"))},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/v1/projects/my-project/locations/us-east5/publishers/anthropic/models/claude-sonnet-4-5@20250929:rawPredict", requestPath)
+	assert.Equal(t, "Bearer vertex-access-token", authorization)
+	assert.Equal(t, vertex.DefaultVersion, body["anthropic_version"])
+	assert.NotContains(t, body, "model")
+}
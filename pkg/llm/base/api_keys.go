@@ -0,0 +1,31 @@
+package base
+
+import (
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// RecordAPIKeyUsage attributes the usage added since before to the pooled
+// API key name in the conversation metadata. Providers call it after each
+// exchange; it does nothing when the exchange did not use a key pool.
+func (t *Thread) RecordAPIKeyUsage(name string, before llmtypes.Usage) {
+	if name == "" {
+		return
+	}
+	t.Mu.Lock()
+	defer t.Mu.Unlock()
+	if t.Usage == nil {
+		return
+	}
+	after := *t.Usage
+	t.Metadata = conversations.AddAPIKeyUsage(t.Metadata, name, llmtypes.Usage{
+		InputTokens:              after.InputTokens - before.InputTokens,
+		OutputTokens:             after.OutputTokens - before.OutputTokens,
+		CacheCreationInputTokens: after.CacheCreationInputTokens - before.CacheCreationInputTokens,
+		CacheReadInputTokens:     after.CacheReadInputTokens - before.CacheReadInputTokens,
+		InputCost:                after.InputCost - before.InputCost,
+		OutputCost:               after.OutputCost - before.OutputCost,
+		CacheCreationCost:        after.CacheCreationCost - before.CacheCreationCost,
+		CacheReadCost:            after.CacheReadCost - before.CacheReadCost,
+	})
+}
@@ -0,0 +1,30 @@
+package base
+
+import (
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAPIKeyUsage(t *testing.T) {
+	thread := NewThread(llmtypes.Config{}, "conv")
+	thread.Usage.InputTokens = 100
+
+	before := thread.GetUsage()
+	thread.Usage.InputTokens += 40
+	thread.Usage.OutputTokens += 5
+	thread.RecordAPIKeyUsage("team-a", before)
+
+	before = thread.GetUsage()
+	thread.Usage.InputTokens += 10
+	thread.RecordAPIKeyUsage("", before)
+
+	keys := conversations.APIKeyUsageFromMetadata(thread.GetMetadata())
+	require.Len(t, keys, 1)
+	assert.Equal(t, 1, keys["team-a"].Requests)
+	assert.Equal(t, 40, keys["team-a"].Usage.InputTokens, "only the exchange's usage is attributed")
+	assert.Equal(t, 5, keys["team-a"].Usage.OutputTokens)
+}
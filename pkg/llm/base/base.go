@@ -7,12 +7,21 @@ import (
 	"context"
 	"maps"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/jingkaihe/kodelet/pkg/conversations"
 	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/quota"
+	"github.com/jingkaihe/kodelet/pkg/telemetry"
+	"github.com/jingkaihe/kodelet/pkg/todos"
+	"github.com/jingkaihe/kodelet/pkg/toolapproval"
+	"github.com/jingkaihe/kodelet/pkg/toolconcurrency"
 	"github.com/jingkaihe/kodelet/pkg/tools/renderers"
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
@@ -45,9 +54,26 @@ type Thread struct {
 	ToolResults      map[string]tooltypes.StructuredToolResult // Maps tool_call_id to structured result
 	RendererRegistry *renderers.RendererRegistry               // CLI renderer registry for structured tool results
 	LoadConversation LoadConversationFunc                      // Provider-specific callback for loading conversations
+	PruneToolResults PruneToolResultsFunc                      // Provider-specific tool result eviction; nil when unsupported
+	CurrentMessages  func() ([]llmtypes.Message, error)        // Provider-specific messages of the context, archived before compaction; nil when unsupported
+	ServerCompaction bool                                      // Whether the provider's compactor uses server-side compaction
 
 	Mu             sync.Mutex // Mutex for thread-safe operations on usage and tool results
 	ConversationMu sync.Mutex // Mutex for conversation-related operations
+
+	todoTracker  todos.Tracker            // Tool-using turns of the current run, for todo enforcement
+	quotaTracker *quota.Tracker           // Subscription rate limit windows; nil when the provider does not report them
+	toolLimiter  *toolconcurrency.Limiter // Per-class limits on parallel tool calls, created on first use
+	limiterOnce  sync.Once
+	approvalGate *toolapproval.Gate // Risky tool calls awaiting user approval; nil when approval mode is off
+	approvalOnce sync.Once
+	reduction    *pendingReduction
+	journal      conversationJournal // Write-ahead journal of the running SendMessage
+
+	contextSnapshot *conversations.ContextSnapshot // Working directory state recorded on saves, guarded by Mu
+
+	maxTurnsReached atomic.Bool // Whether the last SendMessage stopped at MessageOpt.MaxTurns
+	runStats        runStats    // What the last SendMessage did, guarded by Mu
 }
 
 // NewThread creates a new Thread with initialized fields.
@@ -165,6 +191,18 @@ func (t *Thread) EnablePersistence(ctx context.Context, enabled bool) {
 	}
 }
 
+// TrackRun records that the conversation is running until the returned
+// function is called, so steering submitted from elsewhere can tell that it
+// will be consumed. Runs are recorded through the conversation store, and not
+// at all for messages that are not saved.
+func (t *Thread) TrackRun(ctx context.Context, opt llmtypes.MessageOpt) func() {
+	tracker, ok := t.Store.(conversations.ConversationRunTracker)
+	if !ok || opt.NoSaveConversation {
+		return func() {}
+	}
+	return tracker.TrackRun(ctx, t.ConversationID)
+}
+
 // PrepareUtilityMode configures a thread for internal utility calls such as summary generation.
 // Utility mode disables persistence and extensions to avoid side effects.
 func (t *Thread) PrepareUtilityMode(ctx context.Context) {
@@ -206,6 +244,52 @@ func (t *Thread) GetUsage() llmtypes.Usage {
 	return *t.Usage
 }
 
+// GetResourceUsage returns the resources consumed by tool subprocesses, or nil
+// when the thread's state does not track them.
+func (t *Thread) GetResourceUsage() *tooltypes.ResourceUsage {
+	reporter, ok := t.State.(tooltypes.ResourceUsageReporter)
+	if !ok {
+		return nil
+	}
+	usage := reporter.ResourceUsage()
+	return &usage
+}
+
+// ContextSnapshot returns the snapshot of the working directory recorded
+// with the conversation. Capturing it runs git and hashes uncommitted files,
+// so it is taken on the first save and again only when refresh is set, which
+// providers do for the save that ends a run.
+func (t *Thread) ContextSnapshot(ctx context.Context, refresh bool) (conversations.ContextSnapshot, bool) {
+	if t.State == nil {
+		return conversations.ContextSnapshot{}, false
+	}
+	t.Mu.Lock()
+	cached := t.contextSnapshot
+	t.Mu.Unlock()
+	if cached != nil && !refresh {
+		return *cached, true
+	}
+
+	snapshot := conversations.NewContextSnapshot(ctx, t.State.DiscoverContexts(), t.State.WorkingDirectory())
+	t.Mu.Lock()
+	t.contextSnapshot = &snapshot
+	t.Mu.Unlock()
+	return snapshot, true
+}
+
+// UsageLogContext tags the usage logged for the thread with the experiment
+// arm the conversation was routed to, if any.
+func (t *Thread) UsageLogContext(ctx context.Context) context.Context {
+	assignment, ok := conversations.ExperimentFromMetadata(t.GetMetadata())
+	if !ok && t.Config.ExperimentAssignment != nil {
+		assignment, ok = *t.Config.ExperimentAssignment, true
+	}
+	if !ok {
+		return ctx
+	}
+	return logger.WithLogger(ctx, logger.G(ctx).WithField("experiment", assignment.Name).WithField("experiment_arm", assignment.Arm))
+}
+
 // AggregateSubagentUsage aggregates usage from a subagent into this thread's usage.
 // This aggregates token counts and costs but NOT context window metrics
 // (CurrentContextWindow and MaxContextWindow remain unchanged to avoid premature auto-compact).
@@ -296,13 +380,19 @@ func (t *Thread) CompactRatioOrDefault(optionRatio float64) float64 {
 	return llmtypes.DefaultCompactRatio
 }
 
-// TryAutoCompact triggers context compaction when auto-compact conditions are met.
-// compactFn should perform provider-specific compaction logic.
+// TryAutoCompact reduces the context when auto-compact conditions are met.
+// compactFn should perform provider-specific compaction logic. Unless
+// compact_strategy is "compact", evicting old tool results through
+// PruneToolResults is chosen instead when it is estimated to be cheaper and
+// to free enough context. Each decision is logged, and how much context each
+// path left is recorded in the conversation metadata to refine later
+// estimates.
 func (t *Thread) TryAutoCompact(
 	ctx context.Context,
 	compactRatio float64,
 	compactFn func(context.Context) error,
 ) {
+	t.measurePendingReduction()
 	if compactFn == nil {
 		return
 	}
@@ -316,13 +406,103 @@ func (t *Thread) TryAutoCompact(
 	if usage.MaxContextWindow > 0 {
 		utilization = float64(usage.CurrentContextWindow) / float64(usage.MaxContextWindow)
 	}
-	logger.G(ctx).WithField("context_utilization", utilization).Info("triggering auto-compact")
+	log := logger.G(ctx).WithField("context_utilization", utilization)
+
+	path := compactPath(t.ServerCompaction)
+	history := CompactionHistoryFromMetadata(t.GetMetadata())
+	if t.PruneToolResults != nil && autoReductionEnabled(t.Config) {
+		decision := chooseReduction(reductionInputs{
+			ContextTokens:    usage.CurrentContextWindow,
+			MaxContextTokens: usage.MaxContextWindow,
+			CompactRatio:     compactRatio,
+			PrunableTokens:   t.PruneToolResults(PruneKeepRecentToolResults, false),
+			ReinjectTokens:   t.Config.CompactReinjectTokens,
+			CompactPath:      path,
+			CanPrune:         true,
+			History:          history,
+		})
+		path = decision.Path
+		fields := logrus.Fields{"strategy": path, "context_tokens": usage.CurrentContextWindow}
+		for _, candidate := range decision.Candidates {
+			fields[candidate.Path+"_estimated_cost"] = int(candidate.Cost)
+			fields[candidate.Path+"_estimated_remaining_tokens"] = candidate.RemainingTokens
+			if candidate.Reason != "" {
+				fields[candidate.Path+"_skipped"] = candidate.Reason
+			}
+		}
+		log.WithFields(fields).Info("chose context reduction strategy")
+	}
 
-	if err := compactFn(ctx); err != nil {
+	if path == ReductionPrune {
+		freed := t.PruneToolResults(PruneKeepRecentToolResults, true)
+		t.Mu.Lock()
+		t.Usage.CurrentContextWindow = max(t.Usage.CurrentContextWindow-freed, 0)
+		t.Mu.Unlock()
+		log.WithField("freed_tokens", freed).Info("evicted old tool results")
+		t.recordReduction(history, path, usage.CurrentContextWindow, false)
+		return
+	}
+
+	log.Info("triggering auto-compact")
+	var compacted []llmtypes.Message
+	if t.CurrentMessages != nil {
+		var err error
+		if compacted, err = t.CurrentMessages(); err != nil {
+			log.WithError(err).Warn("failed to read messages to archive before compaction")
+		}
+	}
+	err := compactFn(ctx)
+	if err != nil {
 		logger.G(ctx).WithError(err).Error("failed to auto-compact context")
 	} else {
 		logger.G(ctx).Info("auto-compact completed successfully")
+		t.archiveCompactedMessages(compacted)
+	}
+	t.recordReduction(history, path, usage.CurrentContextWindow, err != nil)
+}
+
+// archiveCompactedMessages keeps the text of messages removed by compaction in
+// the conversation metadata, where history_search can still find them.
+func (t *Thread) archiveCompactedMessages(messages []llmtypes.Message) {
+	if len(messages) == 0 {
+		return
+	}
+	transcript := conversations.AppendCompactedTranscript(t.GetMetadata(), messages, time.Now())
+	t.SetMetadataValue(convtypes.CompactedTranscriptMetadataKey, transcript)
+}
+
+// recordReduction counts a reduction attempt and, when it succeeded, starts
+// measuring how much context it left.
+func (t *Thread) recordReduction(history CompactionHistory, path string, contextTokens int, failed bool) {
+	history.recordRun(path, failed)
+	t.countReduction(path)
+	telemetry.RecordCompaction(path, failed)
+	t.SetMetadataValue(CompactionHistoryMetadataKey, history)
+	if failed || contextTokens <= 0 {
+		return
+	}
+	t.reduction = &pendingReduction{
+		path:          path,
+		contextTokens: contextTokens,
+		requestTokens: requestTokens(t.GetUsage()),
+	}
+}
+
+// measurePendingReduction records the share of context a reduction left once
+// the following request has reported the real context size.
+func (t *Thread) measurePendingReduction() {
+	pending := t.reduction
+	if pending == nil {
+		return
+	}
+	usage := t.GetUsage()
+	if requestTokens(usage) == pending.requestTokens {
+		return
 	}
+	t.reduction = nil
+	history := CompactionHistoryFromMetadata(t.GetMetadata())
+	history.recordRemaining(pending.path, float64(usage.CurrentContextWindow)/float64(pending.contextTokens))
+	t.SetMetadataValue(CompactionHistoryMetadataKey, history)
 }
 
 // EstimateContextWindowFromMessage estimates the context window size based on message content.
@@ -5,6 +5,7 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/jingkaihe/kodelet/pkg/conversations"
 	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
@@ -69,6 +70,38 @@ func TestSetState(t *testing.T) {
 	assert.Equal(t, state, bt.State)
 }
 
+// contextCountingState counts how often contexts are discovered.
+type contextCountingState struct {
+	mockState
+	discoveries int
+}
+
+func (s *contextCountingState) DiscoverContexts() map[string]string {
+	s.discoveries++
+	return map[string]string{"/repo/AGENTS.md": "rules"}
+}
+
+func TestContextSnapshotIsReusedUntilRefreshed(t *testing.T) {
+	bt := NewThread(llmtypes.Config{}, "")
+	_, ok := bt.ContextSnapshot(context.Background(), false)
+	assert.False(t, ok)
+
+	state := &contextCountingState{}
+	bt.SetState(state)
+
+	first, ok := bt.ContextSnapshot(context.Background(), false)
+	require.True(t, ok)
+	assert.Contains(t, first.Contexts, "/repo/AGENTS.md")
+	second, ok := bt.ContextSnapshot(context.Background(), false)
+	require.True(t, ok)
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, state.discoveries)
+
+	_, ok = bt.ContextSnapshot(context.Background(), true)
+	require.True(t, ok)
+	assert.Equal(t, 2, state.discoveries)
+}
+
 func TestGetState(t *testing.T) {
 	bt := NewThread(llmtypes.Config{}, "")
 
@@ -519,6 +552,28 @@ func TestTryAutoCompact(t *testing.T) {
 	assert.Equal(t, 2, called)
 }
 
+func TestTryAutoCompact_ArchivesCompactedMessages(t *testing.T) {
+	bt := NewThread(llmtypes.Config{}, "")
+	bt.Usage.CurrentContextWindow = 90
+	bt.Usage.MaxContextWindow = 100
+	messages := []llmtypes.Message{
+		{Role: "user", Content: "use postgres for the queue"},
+		{Role: "assistant", Content: ""},
+	}
+	bt.CurrentMessages = func() ([]llmtypes.Message, error) { return messages, nil }
+
+	bt.TryAutoCompact(context.Background(), 0.8, func(context.Context) error {
+		return errors.New("compact failed")
+	})
+	assert.Empty(t, conversations.CompactedTranscript(bt.GetMetadata()))
+
+	bt.TryAutoCompact(context.Background(), 0.8, func(context.Context) error { return nil })
+	transcript := conversations.CompactedTranscript(bt.GetMetadata())
+	require.Len(t, transcript, 1)
+	assert.Equal(t, "user", transcript[0].Role)
+	assert.Equal(t, "use postgres for the queue", transcript[0].Content)
+}
+
 func TestCompactRatioOrDefault(t *testing.T) {
 	bt := NewThread(llmtypes.Config{CompactRatio: 0.65}, "")
 
@@ -809,6 +864,14 @@ func (m *mockConversationStore) Query(ctx context.Context, options convtypes.Que
 	return convtypes.QueryResult{}, nil
 }
 
+func (m *mockConversationStore) Fork(_ context.Context, _ string) (convtypes.ConversationRecord, error) {
+	return convtypes.ConversationRecord{}, nil
+}
+
+func (m *mockConversationStore) Search(_ context.Context, _ string, options convtypes.QueryOptions) (convtypes.SearchResult, error) {
+	return convtypes.SearchResult{QueryOptions: options}, nil
+}
+
 func (m *mockConversationStore) Close() error {
 	if m.closeFunc != nil {
 		return m.closeFunc()
@@ -1251,3 +1314,32 @@ func TestEstimateContextWindowFromMessageNilUsage(t *testing.T) {
 	})
 	assert.Nil(t, bt.Usage)
 }
+
+// runTrackingStore records the runs tracked through it.
+type runTrackingStore struct {
+	mockConversationStore
+	running map[string]bool
+}
+
+func (s *runTrackingStore) TrackRun(_ context.Context, id string) func() {
+	s.running[id] = true
+	return func() { delete(s.running, id) }
+}
+
+func TestTrackRunUsesConversationStore(t *testing.T) {
+	ctx := context.Background()
+	store := &runTrackingStore{running: map[string]bool{}}
+	bt := NewThread(llmtypes.Config{}, "conv-run")
+	bt.Store = store
+
+	release := bt.TrackRun(ctx, llmtypes.MessageOpt{})
+	assert.True(t, store.running["conv-run"])
+	release()
+	assert.Empty(t, store.running)
+
+	bt.TrackRun(ctx, llmtypes.MessageOpt{NoSaveConversation: true})
+	assert.Empty(t, store.running)
+
+	bt.Store = &mockConversationStore{}
+	require.NotPanics(t, func() { bt.TrackRun(ctx, llmtypes.MessageOpt{})() })
+}
@@ -0,0 +1,77 @@
+package base
+
+import (
+	"context"
+
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// Budget enforces MessageOpt.MaxCostUSD and MaxTotalTokens for one
+// SendMessage call. Only usage added after the call starts counts, so a
+// resumed conversation starts with a full budget.
+type Budget struct {
+	thread         *Thread
+	start          llmtypes.Usage
+	maxCostUSD     float64
+	maxTotalTokens int
+}
+
+// StartBudget snapshots the thread's usage at the start of a SendMessage call
+// and resets the counts returned by RunStats.
+func (t *Thread) StartBudget(opt llmtypes.MessageOpt) *Budget {
+	t.maxTurnsReached.Store(false)
+	t.resetRunStats()
+	return &Budget{
+		thread:         t,
+		start:          t.GetUsage(),
+		maxCostUSD:     max(opt.MaxCostUSD, 0),
+		maxTotalTokens: max(opt.MaxTotalTokens, 0),
+	}
+}
+
+// Check returns a BudgetExceededError once the usage since StartBudget
+// reaches either limit. Providers call it before each exchange after the
+// first, so a run that finishes within its last turn is not reported.
+func (b *Budget) Check(ctx context.Context, turns int) error {
+	if b == nil || turns == 0 || (b.maxCostUSD == 0 && b.maxTotalTokens == 0) {
+		return nil
+	}
+
+	usage := b.thread.GetUsage()
+	err := &llmtypes.BudgetExceededError{
+		CostUSD:        usage.TotalCost() - b.start.TotalCost(),
+		MaxCostUSD:     b.maxCostUSD,
+		TotalTokens:    usage.TotalTokens() - b.start.TotalTokens(),
+		MaxTotalTokens: b.maxTotalTokens,
+		Turns:          turns,
+	}
+	switch {
+	case b.maxCostUSD > 0 && err.CostUSD >= b.maxCostUSD:
+		err.Limit = "cost"
+	case b.maxTotalTokens > 0 && err.TotalTokens >= b.maxTotalTokens:
+		err.Limit = "tokens"
+	default:
+		return nil
+	}
+
+	logger.G(ctx).WithField("cost_usd", err.CostUSD).
+		WithField("max_cost_usd", err.MaxCostUSD).
+		WithField("total_tokens", err.TotalTokens).
+		WithField("max_total_tokens", err.MaxTotalTokens).
+		WithField("turns", turns).
+		Warn("run budget exceeded, stopping interaction")
+	return err
+}
+
+// MarkMaxTurnsReached records that the current SendMessage call stopped at
+// MessageOpt.MaxTurns while the agent was still using tools.
+func (t *Thread) MarkMaxTurnsReached() {
+	t.maxTurnsReached.Store(true)
+}
+
+// MaxTurnsReached reports whether the last SendMessage call stopped at
+// MessageOpt.MaxTurns rather than because the agent finished.
+func (t *Thread) MaxTurnsReached() bool {
+	return t.maxTurnsReached.Load()
+}
@@ -0,0 +1,78 @@
+package base
+
+import (
+	"context"
+	"testing"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgetCheck(t *testing.T) {
+	ctx := context.Background()
+	thread := NewThread(llmtypes.Config{}, "test")
+	thread.Usage = &llmtypes.Usage{InputTokens: 5000, InputCost: 1}
+
+	budget := thread.StartBudget(llmtypes.MessageOpt{MaxCostUSD: 0.5, MaxTotalTokens: 1000})
+	require.NoError(t, budget.Check(ctx, 1), "usage before the call does not count")
+
+	thread.Usage.InputTokens += 600
+	thread.Usage.OutputTokens += 300
+	thread.Usage.OutputCost += 0.2
+	require.NoError(t, budget.Check(ctx, 1))
+
+	thread.Usage.OutputTokens += 100
+	err := budget.Check(ctx, 2)
+	var budgetErr *llmtypes.BudgetExceededError
+	require.ErrorAs(t, err, &budgetErr)
+	assert.Equal(t, "tokens", budgetErr.Limit)
+	assert.Equal(t, 1000, budgetErr.TotalTokens)
+	assert.Equal(t, 2, budgetErr.Turns)
+	assert.Equal(t, "token budget exceeded after 2 turns: used 1000 of 1000 tokens", err.Error())
+
+	thread.Usage.OutputCost += 0.3
+	require.ErrorAs(t, budget.Check(ctx, 3), &budgetErr)
+	assert.Equal(t, "cost", budgetErr.Limit)
+	assert.InDelta(t, 0.5, budgetErr.CostUSD, 1e-9)
+
+	assert.NoError(t, budget.Check(ctx, 0), "the first exchange always runs")
+	assert.NoError(t, thread.StartBudget(llmtypes.MessageOpt{}).Check(ctx, 5))
+	assert.NoError(t, (*Budget)(nil).Check(ctx, 5))
+}
+
+func TestMaxTurnsReached(t *testing.T) {
+	thread := NewThread(llmtypes.Config{}, "test")
+	assert.False(t, thread.MaxTurnsReached())
+
+	thread.MarkMaxTurnsReached()
+	assert.True(t, thread.MaxTurnsReached())
+
+	thread.StartBudget(llmtypes.MessageOpt{})
+	assert.False(t, thread.MaxTurnsReached(), "each SendMessage call starts afresh")
+}
+
+func TestRunStatsCountTheLastCall(t *testing.T) {
+	thread := NewThread(llmtypes.Config{}, "test")
+	thread.Usage = &llmtypes.Usage{InputTokens: 5000, InputCost: 1}
+	thread.countTurn()
+	thread.recordReduction(CompactionHistory{}, ReductionPrune, 0, false)
+
+	thread.StartBudget(llmtypes.MessageOpt{})
+	assert.Equal(t, RunStats{}, thread.RunStats(), "a new call starts from zero")
+
+	thread.Usage.InputTokens += 300
+	thread.Usage.InputCost += 0.5
+	thread.countTurn()
+	thread.countTurn()
+	thread.recordReduction(CompactionHistory{}, ReductionPrune, 0, false)
+	thread.recordReduction(CompactionHistory{}, ReductionServer, 0, true)
+	stats := thread.RunStats()
+	assert.Equal(t, 2, stats.Turns)
+	assert.Equal(t, map[string]int{ReductionPrune: 1, ReductionServer: 1}, stats.Reductions)
+	assert.Equal(t, 300, stats.Usage.InputTokens, "usage from before the call does not count")
+	assert.InDelta(t, 0.5, stats.Usage.TotalCost(), 1e-9)
+
+	stats.Reductions[ReductionPrune] = 5
+	assert.Equal(t, 1, thread.RunStats().Reductions[ReductionPrune], "callers get a copy")
+}
@@ -3,7 +3,10 @@ package base
 import (
 	"context"
 
+	"github.com/jingkaihe/kodelet/pkg/extensions"
 	"github.com/jingkaihe/kodelet/pkg/llm/prompts"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	"github.com/pkg/errors"
 )
 
@@ -21,3 +24,49 @@ func CompactContextWithSummary(
 
 	return swapContext(ctx, summary)
 }
+
+// CompactContextWithSummary compacts the thread like the package-level helper,
+// then re-injects the files most recently accessed by the file tools so the
+// model does not have to re-read what it was working on. Extensions
+// subscribed to context.compacted are notified once the history is replaced.
+func (t *Thread) CompactContextWithSummary(
+	ctx context.Context,
+	runUtilityPrompt func(ctx context.Context, prompt string, useWeakModel bool) (string, error),
+	swapContext func(ctx context.Context, summary string) error,
+) error {
+	compacted := t.MessagesBeforeCompaction(ctx)
+	return CompactContextWithSummary(ctx, runUtilityPrompt, func(ctx context.Context, summary string) error {
+		if err := swapContext(ctx, t.appendRecentFiles(summary)); err != nil {
+			return err
+		}
+		t.TriggerContextCompacted(ctx, summary, compacted)
+		return nil
+	})
+}
+
+// MessagesBeforeCompaction returns the messages about to be replaced by
+// compaction, for TriggerContextCompacted. It returns nil without reading the
+// history when no extension subscribes to context.compacted.
+func (t *Thread) MessagesBeforeCompaction(ctx context.Context) []llmtypes.Message {
+	runtime, _ := t.Config.Extensions.(*extensions.Runtime)
+	if t.CurrentMessages == nil || !runtime.HasEventHandlers(extensions.EventContextCompacted) {
+		return nil
+	}
+	messages, err := t.CurrentMessages()
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to read messages before compaction")
+		return nil
+	}
+	return messages
+}
+
+// TriggerContextCompacted notifies extension handlers that compaction replaced
+// messages with summary. Providers that compact server-side pass an empty
+// summary because the compacted history is opaque.
+func (t *Thread) TriggerContextCompacted(ctx context.Context, summary string, messages []llmtypes.Message) {
+	runtime, _ := t.Config.Extensions.(*extensions.Runtime)
+	if runtime == nil {
+		return
+	}
+	runtime.DispatchContextCompacted(ctx, buildExtensionCallContext(t, t.State), summary, messages)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -68,3 +69,14 @@ func TestCompactContextWithSummary(t *testing.T) {
 		assert.Equal(t, "summary text", gotSummary)
 	})
 }
+
+func TestMessagesBeforeCompactionSkipsReadWithoutSubscribers(t *testing.T) {
+	thread := NewThread(llmtypes.Config{}, "conv")
+	thread.CurrentMessages = func() ([]llmtypes.Message, error) {
+		t.Fatal("the history must not be read when no extension subscribes to context.compacted")
+		return nil, nil
+	}
+
+	assert.Nil(t, thread.MessagesBeforeCompaction(context.Background()))
+	thread.TriggerContextCompacted(context.Background(), "summary", nil)
+}
@@ -0,0 +1,261 @@
+package base
+
+import (
+	"encoding/json"
+	"math"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// Context reduction paths auto-compaction chooses between.
+const (
+	// ReductionSummary compacts the context into an LLM-written summary.
+	ReductionSummary = "summary"
+	// ReductionServer compacts the context with the provider's server-side
+	// compaction endpoint.
+	ReductionServer = "server"
+	// ReductionPrune evicts the content of old tool results.
+	ReductionPrune = "prune"
+)
+
+// CompactionHistoryMetadataKey stores how well each reduction path worked in
+// the conversation's metadata.
+const CompactionHistoryMetadataKey = "compaction_history"
+
+// PrunedToolResultText replaces the content of evicted tool results.
+const PrunedToolResultText = "[Tool result removed to free context. Run the tool again if you need this output.]"
+
+// PruneKeepRecentToolResults is the number of most recent tool results that
+// pruning never evicts.
+const PruneKeepRecentToolResults = 8
+
+// EstimatedNonTextTokens is the rough token cost of an image or document part
+// of a tool result.
+const EstimatedNonTextTokens = 1600
+
+// EstimateTextTokens roughly estimates the tokens in text at ~4 characters
+// per token.
+func EstimateTextTokens(text string) int {
+	return len(text) / 4
+}
+
+// PrunableCount is the number of the total tool results, oldest first, that
+// pruning may evict.
+func PrunableCount(total, keepRecent int) int {
+	return max(total-keepRecent, 0)
+}
+
+// PruneToolResultsFunc estimates the tokens held by the tool results older
+// than the keepRecent most recent ones, evicting them when apply is set.
+type PruneToolResultsFunc func(keepRecent int, apply bool) int
+
+// Relative token prices used to compare reduction paths, in input-token
+// equivalents. They follow the usual shape of provider pricing rather than a
+// specific model.
+const (
+	costWeightInput      = 1.0
+	costWeightOutput     = 5.0
+	costWeightCacheWrite = 1.25
+	costWeightCacheRead  = 0.1
+	// costHorizonTurns is how many later turns re-read the reduced context.
+	costHorizonTurns = 10
+)
+
+const (
+	// summaryOutputTokens is the typical length of a compaction summary.
+	summaryOutputTokens = 2000
+	// serverRemainingRatio is the share of context assumed to remain after
+	// server-side compaction before any history is available.
+	serverRemainingRatio = 0.15
+	// pruneTargetFactor is how far under compact_ratio pruning must bring the
+	// context, so the next turns do not immediately trigger another reduction.
+	pruneTargetFactor = 0.75
+	// minRunsForFailureSkip is the number of attempts before a path that
+	// mostly fails is skipped.
+	minRunsForFailureSkip = 2
+)
+
+// ReductionStats records how a reduction path performed in a conversation.
+type ReductionStats struct {
+	Runs     int `json:"runs"`
+	Failures int `json:"failures"`
+	// Measured counts the runs whose effect was measured on the next request.
+	Measured int `json:"measured"`
+	// AvgRemaining is the average share of the context left after reduction,
+	// measured on the next request.
+	AvgRemaining float64 `json:"avg_remaining"`
+}
+
+// CompactionHistory maps a reduction path to its stats.
+type CompactionHistory map[string]ReductionStats
+
+// CompactionHistoryFromMetadata decodes the compaction history stored in
+// conversation metadata.
+func CompactionHistoryFromMetadata(metadata map[string]any) CompactionHistory {
+	history := CompactionHistory{}
+	raw, ok := metadata[CompactionHistoryMetadataKey]
+	if !ok || raw == nil {
+		return history
+	}
+	if value, ok := raw.(CompactionHistory); ok {
+		for path, stats := range value {
+			history[path] = stats
+		}
+		return history
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return history
+	}
+	_ = json.Unmarshal(data, &history)
+	return history
+}
+
+func (h CompactionHistory) recordRun(path string, failed bool) {
+	stats := h[path]
+	stats.Runs++
+	if failed {
+		stats.Failures++
+	}
+	h[path] = stats
+}
+
+func (h CompactionHistory) recordRemaining(path string, remaining float64) {
+	stats := h[path]
+	stats.AvgRemaining = (stats.AvgRemaining*float64(stats.Measured) + remaining) / float64(stats.Measured+1)
+	stats.Measured++
+	h[path] = stats
+}
+
+// unreliable reports whether a path failed in most of its attempts.
+func (h CompactionHistory) unreliable(path string) bool {
+	stats := h[path]
+	return stats.Runs >= minRunsForFailureSkip && stats.Failures*2 > stats.Runs
+}
+
+// reductionInputs describes the context when auto-compaction triggers.
+type reductionInputs struct {
+	ContextTokens    int
+	MaxContextTokens int
+	CompactRatio     float64
+	PrunableTokens   int
+	ReinjectTokens   int
+	// CompactPath is the path the provider's compactor takes, summary or
+	// server.
+	CompactPath string
+	CanPrune    bool
+	History     CompactionHistory
+}
+
+// reductionCandidate is one reduction path with its estimated outcome.
+type reductionCandidate struct {
+	Path            string
+	RemainingTokens int
+	Cost            float64
+	Eligible        bool
+	Reason          string
+}
+
+// reductionDecision is the chosen path and the candidates considered.
+type reductionDecision struct {
+	Path       string
+	Candidates []reductionCandidate
+}
+
+// chooseReduction picks the cheapest reduction path that frees enough
+// context. Costs count the reduction itself plus the next costHorizonTurns
+// turns re-reading what remains. Compaction is the fallback when no path is
+// eligible.
+func chooseReduction(in reductionInputs) reductionDecision {
+	compact := compactCandidate(in)
+	decision := reductionDecision{Path: compact.Path, Candidates: []reductionCandidate{compact}}
+	if !in.CanPrune {
+		return decision
+	}
+
+	prune := pruneCandidate(in)
+	decision.Candidates = append(decision.Candidates, prune)
+	if prune.Eligible && (!compact.Eligible || prune.Cost < compact.Cost) {
+		decision.Path = ReductionPrune
+	}
+	return decision
+}
+
+func compactCandidate(in reductionInputs) reductionCandidate {
+	candidate := reductionCandidate{Path: in.CompactPath, Eligible: !in.History.unreliable(in.CompactPath)}
+	context := float64(in.ContextTokens)
+
+	var remaining, output float64
+	if in.CompactPath == ReductionServer {
+		remaining = context * serverRemainingRatio
+		output = remaining
+	} else {
+		remaining = float64(summaryOutputTokens + in.ReinjectTokens)
+		output = summaryOutputTokens
+	}
+	if stats := in.History[in.CompactPath]; stats.Measured > 0 {
+		remaining = context * stats.AvgRemaining
+	}
+	candidate.RemainingTokens = int(math.Round(remaining))
+	candidate.Cost = context*costWeightInput + output*costWeightOutput + followUpCost(remaining)
+	if !candidate.Eligible {
+		candidate.Reason = "failed in most previous attempts"
+	}
+	return candidate
+}
+
+func pruneCandidate(in reductionInputs) reductionCandidate {
+	candidate := reductionCandidate{Path: ReductionPrune}
+	context := float64(in.ContextTokens)
+	remaining := context - float64(in.PrunableTokens)
+	if stats := in.History[ReductionPrune]; stats.Measured > 0 {
+		remaining = max(remaining, context*stats.AvgRemaining)
+	}
+	candidate.RemainingTokens = int(math.Round(remaining))
+	candidate.Cost = followUpCost(remaining)
+
+	target := float64(in.MaxContextTokens) * in.CompactRatio * pruneTargetFactor
+	switch {
+	case in.PrunableTokens == 0:
+		candidate.Reason = "no old tool results to evict"
+	case remaining > target:
+		candidate.Reason = "would not free enough context"
+	case in.History.unreliable(ReductionPrune):
+		candidate.Reason = "failed in most previous attempts"
+	default:
+		candidate.Eligible = true
+	}
+	return candidate
+}
+
+// followUpCost is the cost of writing the reduced context to the cache on
+// the next request and reading it back over the following turns.
+func followUpCost(remaining float64) float64 {
+	return remaining*costWeightCacheWrite + remaining*costWeightCacheRead*costHorizonTurns
+}
+
+// compactPath is the path the provider's compactor takes for config.
+func compactPath(serverCompaction bool) string {
+	if serverCompaction {
+		return ReductionServer
+	}
+	return ReductionSummary
+}
+
+// pendingReduction is a reduction whose effect is measured on the next
+// request's context size.
+type pendingReduction struct {
+	path          string
+	contextTokens int
+	// requestTokens is the thread's total token count after the reduction;
+	// it changes once the next request completes.
+	requestTokens int
+}
+
+func requestTokens(usage llmtypes.Usage) int {
+	return usage.InputTokens + usage.OutputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens
+}
+
+func autoReductionEnabled(config llmtypes.Config) bool {
+	return config.CompactStrategy != llmtypes.CompactStrategyCompact
+}
@@ -0,0 +1,133 @@
+package base
+
+import (
+	"context"
+	"testing"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func reductionTestInputs(prunable int) reductionInputs {
+	return reductionInputs{
+		ContextTokens:    170000,
+		MaxContextTokens: 200000,
+		CompactRatio:     0.8,
+		PrunableTokens:   prunable,
+		CompactPath:      ReductionSummary,
+		CanPrune:         true,
+		History:          CompactionHistory{},
+	}
+}
+
+func TestChooseReduction(t *testing.T) {
+	t.Run("prunes when cheaper and enough context is freed", func(t *testing.T) {
+		decision := chooseReduction(reductionTestInputs(100000))
+		assert.Equal(t, ReductionPrune, decision.Path)
+		require.Len(t, decision.Candidates, 2)
+		assert.Equal(t, 70000, decision.Candidates[1].RemainingTokens)
+	})
+
+	t.Run("compacts when pruning frees too little", func(t *testing.T) {
+		decision := chooseReduction(reductionTestInputs(20000))
+		assert.Equal(t, ReductionSummary, decision.Path)
+		assert.Equal(t, "would not free enough context", decision.Candidates[1].Reason)
+	})
+
+	t.Run("compacts when pruning is more expensive", func(t *testing.T) {
+		decision := chooseReduction(reductionTestInputs(60000))
+		assert.Equal(t, ReductionSummary, decision.Path)
+		assert.True(t, decision.Candidates[1].Eligible)
+	})
+
+	t.Run("prunes when compaction keeps failing", func(t *testing.T) {
+		in := reductionTestInputs(60000)
+		in.History = CompactionHistory{ReductionSummary: {Runs: 3, Failures: 2}}
+		decision := chooseReduction(in)
+		assert.Equal(t, ReductionPrune, decision.Path)
+		assert.False(t, decision.Candidates[0].Eligible)
+	})
+
+	t.Run("uses measured history for pruning", func(t *testing.T) {
+		in := reductionTestInputs(100000)
+		in.History = CompactionHistory{ReductionPrune: {Runs: 1, Measured: 1, AvgRemaining: 0.9}}
+		decision := chooseReduction(in)
+		assert.Equal(t, ReductionSummary, decision.Path)
+		assert.Equal(t, 153000, decision.Candidates[1].RemainingTokens)
+	})
+
+	t.Run("compacts when pruning is unsupported", func(t *testing.T) {
+		in := reductionTestInputs(100000)
+		in.CanPrune = false
+		in.CompactPath = ReductionServer
+		decision := chooseReduction(in)
+		assert.Equal(t, ReductionServer, decision.Path)
+		assert.Len(t, decision.Candidates, 1)
+	})
+}
+
+func TestCompactionHistoryFromMetadata(t *testing.T) {
+	history := CompactionHistoryFromMetadata(map[string]any{
+		CompactionHistoryMetadataKey: map[string]any{
+			"prune": map[string]any{"runs": 2, "failures": 1, "measured": 1, "avg_remaining": 0.4},
+		},
+	})
+	assert.Equal(t, ReductionStats{Runs: 2, Failures: 1, Measured: 1, AvgRemaining: 0.4}, history[ReductionPrune])
+
+	assert.Empty(t, CompactionHistoryFromMetadata(nil))
+}
+
+func TestTryAutoCompact_Prune(t *testing.T) {
+	bt := NewThread(llmtypes.Config{}, "")
+	bt.Usage.CurrentContextWindow = 170000
+	bt.Usage.MaxContextWindow = 200000
+
+	var applied bool
+	bt.PruneToolResults = func(keepRecent int, apply bool) int {
+		assert.Equal(t, PruneKeepRecentToolResults, keepRecent)
+		applied = applied || apply
+		return 100000
+	}
+	compacted := false
+	compactFn := func(context.Context) error {
+		compacted = true
+		return nil
+	}
+
+	bt.TryAutoCompact(context.Background(), 0.8, compactFn)
+	assert.False(t, compacted)
+	assert.True(t, applied)
+	assert.Equal(t, 70000, bt.Usage.CurrentContextWindow)
+	history := CompactionHistoryFromMetadata(bt.GetMetadata())
+	assert.Equal(t, 1, history[ReductionPrune].Runs)
+	assert.Equal(t, 0, history[ReductionPrune].Measured)
+
+	// The next request reports the real context size.
+	bt.Usage.InputTokens = 1000
+	bt.Usage.CurrentContextWindow = 68000
+	bt.TryAutoCompact(context.Background(), 0.8, compactFn)
+	assert.False(t, compacted)
+	history = CompactionHistoryFromMetadata(bt.GetMetadata())
+	assert.Equal(t, 1, history[ReductionPrune].Measured)
+	assert.InDelta(t, 0.4, history[ReductionPrune].AvgRemaining, 0.001)
+}
+
+func TestTryAutoCompact_CompactStrategy(t *testing.T) {
+	bt := NewThread(llmtypes.Config{CompactStrategy: llmtypes.CompactStrategyCompact}, "")
+	bt.Usage.CurrentContextWindow = 170000
+	bt.Usage.MaxContextWindow = 200000
+	bt.PruneToolResults = func(int, bool) int {
+		t.Fatal("pruning should not be considered")
+		return 0
+	}
+
+	compacted := false
+	bt.TryAutoCompact(context.Background(), 0.8, func(context.Context) error {
+		compacted = true
+		return nil
+	})
+	assert.True(t, compacted)
+	history := CompactionHistoryFromMetadata(bt.GetMetadata())
+	assert.Equal(t, 1, history[ReductionSummary].Runs)
+}
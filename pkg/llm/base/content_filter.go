@@ -0,0 +1,31 @@
+package base
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jingkaihe/kodelet/pkg/llm/prompts"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// ReportContentFilter logs a response refused or filtered by the provider's
+// content policy and passes it to handler when it is a
+// ContentFilterMessageHandler.
+func ReportContentFilter(ctx context.Context, handler llmtypes.MessageHandler, event llmtypes.ContentFilterEvent) {
+	logger.G(ctx).
+		WithField("provider", event.Provider).
+		WithField("reason", event.Reason).
+		WithField("message", event.Message).
+		Warn("response blocked by the provider content policy")
+
+	if contentFilterHandler, ok := handler.(llmtypes.ContentFilterMessageHandler); ok {
+		contentFilterHandler.HandleContentFilter(event)
+	}
+}
+
+// RephraseForContentPolicy wraps a refused utility prompt in an instruction
+// to handle sensitive material neutrally.
+func RephraseForContentPolicy(prompt string) string {
+	return prompts.ContentFilterRephrasePrompt + "\n\n" + strings.TrimSpace(prompt)
+}
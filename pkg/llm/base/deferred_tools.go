@@ -0,0 +1,203 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/audit"
+	"github.com/jingkaihe/kodelet/pkg/extensions"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/toolapproval"
+	"github.com/jingkaihe/kodelet/pkg/tools"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// Choices offered when several queued calls are reviewed at once.
+const (
+	deferredApplyAll   = "Apply all"
+	deferredReviewEach = "Review each"
+	deferredDiscardAll = "Discard all"
+)
+
+// maxDeferredOutput caps how much of the output of each applied call is
+// reported back to the model.
+const maxDeferredOutput = 4000
+
+// deferToolCall queues toolName for review at the end of the turn when the
+// thread defers the calls on its risk list. It returns the placeholder result
+// the model receives instead of the tool's output.
+func deferToolCall(thread llmtypes.Thread, toolName, input, toolCallID string) (string, bool) {
+	gate := approvalGate(thread)
+	request, needed := gate.Check(toolName, input)
+	if !needed || !gate.Defers(request) {
+		return "", false
+	}
+	position := gate.Enqueue(toolapproval.DeferredCall{Request: request, Input: input, ToolCallID: toolCallID})
+	return fmt.Sprintf("Queued as change %d for the user to review when you finish your turn; it has not run yet. "+
+		"Continue as if it succeeded, but files and command output will not reflect it until the user applies it. "+
+		"Do not repeat the call. You will be told which queued changes were applied.", position), true
+}
+
+// ReviewDeferredTools has the user review the tool calls queued during the
+// turn, runs the approved ones in the order they were queued, and reports the
+// outcome to the model as a user message. Providers call it when the model
+// stops calling tools; it returns true when calls were reviewed, and the
+// caller should continue the loop so the model can react to the outcome.
+func ReviewDeferredTools(ctx context.Context, thread llmtypes.Thread, handler llmtypes.MessageHandler) bool {
+	calls := approvalGate(thread).TakeDeferred()
+	if len(calls) == 0 {
+		return false
+	}
+
+	approved, refusal := reviewDeferredCalls(ctx, calls)
+	state := threadState(thread)
+
+	var report strings.Builder
+	report.WriteString("The user reviewed the changes you queued during your turn. Only the applied changes took effect:\n")
+	applied := 0
+	for i, call := range calls {
+		fmt.Fprintf(&report, "\n%d. %s %s: ", i+1, call.Tool, call.Summary)
+		if !approved[i] {
+			reason := fmt.Sprintf("not applied because %s", refusal)
+			recordToolCallAudit(ctx, thread, toolCallAudit{
+				started:        time.Now(),
+				toolName:       call.Tool,
+				toolCallID:     call.ToolCallID,
+				input:          call.Input,
+				approval:       audit.ApprovalDenied,
+				approvalReason: reason,
+			}, tooltypes.BaseToolResult{Error: reason}, tooltypes.StructuredToolResult{ToolName: call.Tool, Error: reason, Timestamp: time.Now()})
+			report.WriteString(reason + ".")
+			continue
+		}
+
+		result := runDeferredCall(ctx, thread, state, handler, call)
+		if result.IsError() {
+			fmt.Fprintf(&report, "failed.\n%s", truncateDeferredOutput(result.GetError()))
+			continue
+		}
+		applied++
+		report.WriteString("applied.")
+		if output := strings.TrimSpace(result.GetResult()); output != "" {
+			fmt.Fprintf(&report, "\n%s", truncateDeferredOutput(output))
+		}
+	}
+	report.WriteString("\n\nDo not retry changes the user did not apply unless they ask you to.")
+
+	logger.G(ctx).
+		WithField("queued", len(calls)).
+		WithField("applied", applied).
+		Info("reviewed deferred tool calls")
+	handler.HandleText(fmt.Sprintf("\n📋 Applied %d of %d queued tool calls\n", applied, len(calls)))
+	thread.AddUserMessage(ctx, report.String())
+	return true
+}
+
+// DiscardDeferredTools drops the calls still queued when a run stops before
+// the model finished its turn, for example at the turn limit or on
+// cancellation. Providers call it once their agent loop ends.
+func DiscardDeferredTools(ctx context.Context, thread llmtypes.Thread, handler llmtypes.MessageHandler) {
+	calls := approvalGate(thread).TakeDeferred()
+	if len(calls) == 0 {
+		return
+	}
+	logger.G(ctx).WithField("queued", len(calls)).Warn("run stopped before queued tool calls were reviewed; discarding them")
+	handler.HandleText(fmt.Sprintf("\n⚠️ Discarded %d queued tool calls: the run stopped before they were reviewed\n", len(calls)))
+}
+
+// reviewDeferredCalls asks the user which queued calls to apply. Several calls
+// are offered as a batch first, with the choice to review them one by one.
+// It returns, for each call, whether it was approved, and why the others
+// were not.
+func reviewDeferredCalls(ctx context.Context, calls []toolapproval.DeferredCall) ([]bool, string) {
+	approved := make([]bool, len(calls))
+	broker, ok := extensions.UIConfirmBrokerFromContext(ctx)
+	if !ok {
+		return approved, "there was no interactive terminal to review it"
+	}
+
+	if selector, ok := extensions.UISelectBrokerFromContext(ctx); ok && len(calls) > 1 {
+		var list strings.Builder
+		for i, call := range calls {
+			fmt.Fprintf(&list, "%d. %s %s\n", i+1, call.Tool, call.Summary)
+		}
+		response, err := selector.Select(ctx, extensions.UISelectRequest{
+			ID:      extensions.NewUIInputRequestID(),
+			Title:   fmt.Sprintf("Apply %d queued tool calls?", len(calls)),
+			Message: strings.TrimSpace(list.String()),
+			Options: []string{deferredApplyAll, deferredReviewEach, deferredDiscardAll},
+		})
+		switch {
+		case err != nil:
+			return approved, fmt.Sprintf("asking for review failed: %s", err)
+		case response.Status == extensions.UIInputStatusUnavailable:
+			return approved, "interactive input is unavailable"
+		case response.Status != extensions.UIInputStatusSubmitted || response.Value == deferredDiscardAll:
+			return approved, "the user discarded it"
+		case response.Value == deferredApplyAll:
+			for i := range approved {
+				approved[i] = true
+			}
+			return approved, ""
+		case response.Value != deferredReviewEach:
+			return approved, "the user discarded it"
+		}
+	}
+
+	for i, call := range calls {
+		response, err := broker.Confirm(ctx, extensions.UIConfirmRequest{
+			ID:                extensions.NewUIInputRequestID(),
+			Title:             fmt.Sprintf("Apply queued %s call (%d of %d)?", call.Tool, i+1, len(calls)),
+			Message:           call.Summary,
+			ConfirmButtonText: "Apply",
+			CancelButtonText:  "Discard",
+		})
+		switch {
+		case err != nil:
+			return approved, fmt.Sprintf("asking for review failed: %s", err)
+		case response.Status == extensions.UIInputStatusUnavailable:
+			return approved, "interactive input is unavailable"
+		}
+		approved[i] = response.Status == extensions.UIInputStatusSubmitted && response.Confirmed
+	}
+	return approved, "the user discarded it"
+}
+
+// runDeferredCall runs an approved call with the input it was queued with.
+// Extension tool.call handlers already saw the call when it was queued.
+func runDeferredCall(
+	ctx context.Context,
+	thread llmtypes.Thread,
+	state tooltypes.State,
+	handler llmtypes.MessageHandler,
+	call toolapproval.DeferredCall,
+) tooltypes.ToolResult {
+	audited := toolCallAudit{
+		started:    time.Now(),
+		toolName:   call.Tool,
+		toolCallID: call.ToolCallID,
+		input:      call.Input,
+		approval:   audit.ApprovalConfirmed,
+	}
+
+	var result tooltypes.ToolResult
+	if release, err := acquireToolSlot(ctx, thread, call.Tool); err != nil {
+		result = tooltypes.BaseToolResult{Error: err.Error()}
+	} else {
+		runCtx := toolRunContext(ctx, thread, state, handler, call.ToolCallID)
+		result = tools.RunToolWithUpdates(runCtx, state, call.Tool, call.Input, nil)
+		release()
+	}
+	recordToolCallAudit(ctx, thread, audited, result, result.StructuredData())
+	return result
+}
+
+func truncateDeferredOutput(output string) string {
+	if len(output) <= maxDeferredOutput {
+		return output
+	}
+	return output[:maxDeferredOutput] + "\n... (output truncated)"
+}
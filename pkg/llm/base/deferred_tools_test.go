@@ -0,0 +1,122 @@
+package base
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/extensions"
+	"github.com/jingkaihe/kodelet/pkg/toolapproval"
+	"github.com/jingkaihe/kodelet/pkg/tools/renderers"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubReviewBroker struct {
+	selection string
+	confirms  []bool
+	selects   int
+}
+
+func (b *stubReviewBroker) Input(context.Context, extensions.UIInputRequest) (extensions.UIInputResponse, error) {
+	return extensions.UIInputResponse{Status: extensions.UIInputStatusUnavailable}, nil
+}
+
+func (b *stubReviewBroker) Select(context.Context, extensions.UISelectRequest) (extensions.UIInputResponse, error) {
+	b.selects++
+	return extensions.UIInputResponse{Status: extensions.UIInputStatusSubmitted, Value: b.selection}, nil
+}
+
+func (b *stubReviewBroker) Confirm(context.Context, extensions.UIConfirmRequest) (extensions.UIInputResponse, error) {
+	confirmed := len(b.confirms) > 0 && b.confirms[0]
+	if len(b.confirms) > 0 {
+		b.confirms = b.confirms[1:]
+	}
+	if !confirmed {
+		return extensions.UIInputResponse{Status: extensions.UIInputStatusDismissed}, nil
+	}
+	return extensions.UIInputResponse{Status: extensions.UIInputStatusSubmitted, Confirmed: true}, nil
+}
+
+func newDeferringThread(state tooltypes.State) *approvalThreadStub {
+	return &approvalThreadStub{
+		threadStub: threadStub{conversationID: "conv-id", state: state},
+		gate: toolapproval.NewGate(&llmtypes.ToolApprovalConfig{
+			Enabled: true,
+			Mode:    toolapproval.ModeDefer,
+			Risks:   []string{"view_image"},
+		}),
+	}
+}
+
+func TestExecuteToolDefersRiskyCalls(t *testing.T) {
+	state := &toolState{tools: []tooltypes.Tool{multimodalTool{}}}
+	thread := newDeferringThread(state)
+	registry := renderers.NewRendererRegistry()
+	handler := &toolUpdateHandler{}
+
+	execution := ExecuteTool(context.Background(), thread, state, registry, "view_image", `{"path": "a.png"}`, "call-1")
+	assert.False(t, execution.Result.IsError())
+	assert.Contains(t, execution.Result.GetResult(), "Queued as change 1")
+	assert.Contains(t, execution.Result.GetResult(), "has not run yet")
+
+	assert.True(t, ReviewDeferredTools(context.Background(), thread, handler))
+	require.Len(t, thread.userMessages, 1)
+	assert.Contains(t, thread.userMessages[0], "1. view_image a.png: not applied because there was no interactive terminal to review it.")
+	assert.False(t, ReviewDeferredTools(context.Background(), thread, handler), "the queue is emptied by a review")
+}
+
+func TestReviewDeferredTools(t *testing.T) {
+	state := &toolState{tools: []tooltypes.Tool{multimodalTool{}}}
+	registry := renderers.NewRendererRegistry()
+	handler := &toolUpdateHandler{}
+
+	queue := func(ctx context.Context, thread *approvalThreadStub) {
+		ExecuteTool(ctx, thread, state, registry, "view_image", `{"path": "a.png"}`, "call-1")
+		ExecuteTool(ctx, thread, state, registry, "view_image", `{"path": "b.png"}`, "call-2")
+	}
+
+	t.Run("review each", func(t *testing.T) {
+		thread := newDeferringThread(state)
+		broker := &stubReviewBroker{selection: deferredReviewEach, confirms: []bool{true, false}}
+		ctx := extensions.ContextWithUIInputBroker(context.Background(), broker)
+		queue(ctx, thread)
+
+		require.True(t, ReviewDeferredTools(ctx, thread, handler))
+		assert.Equal(t, 1, broker.selects)
+		require.Len(t, thread.userMessages, 1)
+		assert.Contains(t, thread.userMessages[0], "1. view_image a.png: applied.\nimage available")
+		assert.Contains(t, thread.userMessages[0], "2. view_image b.png: not applied because the user discarded it.")
+	})
+
+	t.Run("apply all", func(t *testing.T) {
+		thread := newDeferringThread(state)
+		broker := &stubReviewBroker{selection: deferredApplyAll}
+		ctx := extensions.ContextWithUIInputBroker(context.Background(), broker)
+		queue(ctx, thread)
+
+		require.True(t, ReviewDeferredTools(ctx, thread, handler))
+		assert.Contains(t, thread.userMessages[0], "1. view_image a.png: applied.")
+		assert.Contains(t, thread.userMessages[0], "2. view_image b.png: applied.")
+	})
+
+	t.Run("discard all", func(t *testing.T) {
+		thread := newDeferringThread(state)
+		broker := &stubReviewBroker{selection: deferredDiscardAll}
+		ctx := extensions.ContextWithUIInputBroker(context.Background(), broker)
+		queue(ctx, thread)
+
+		require.True(t, ReviewDeferredTools(ctx, thread, handler))
+		assert.NotContains(t, thread.userMessages[0], "applied.")
+	})
+
+	t.Run("discarded when the run stops first", func(t *testing.T) {
+		thread := newDeferringThread(state)
+		queue(context.Background(), thread)
+
+		DiscardDeferredTools(context.Background(), thread, handler)
+		assert.False(t, ReviewDeferredTools(context.Background(), thread, handler))
+		assert.Empty(t, thread.userMessages)
+	})
+}
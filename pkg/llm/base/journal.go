@@ -0,0 +1,208 @@
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// journalTextInterval is how long streamed assistant text is collected
+// before it is written to the journal, so that a crash loses at most this
+// much of a response while the journal is not written for every token.
+const journalTextInterval = 250 * time.Millisecond
+
+// InterruptedToolCallOutput is the result recovered for a tool call that was
+// still running when its conversation was interrupted.
+const InterruptedToolCallOutput = "The tool call was interrupted before it finished, so its outcome is unknown. Check its effects before running it again."
+
+// conversationJournal writes the changes made to a conversation during a
+// SendMessage to the write-ahead journal of its store. Its methods are safe
+// to call from the goroutines that run tools in parallel.
+type conversationJournal struct {
+	mu       sync.Mutex
+	store    conversations.ConversationJournal
+	provider string
+
+	text      strings.Builder // streamed text not written yet
+	textIndex int
+	textSince time.Time
+}
+
+// StartJournal starts journaling the changes of a SendMessage as they happen.
+// Nothing is journaled when the conversation is not persisted, the message
+// is not saved, or the store keeps no journal.
+func (t *Thread) StartJournal(provider string, opt llmtypes.MessageOpt) {
+	t.journal.mu.Lock()
+	defer t.journal.mu.Unlock()
+
+	t.journal.store = nil
+	t.journal.provider = provider
+	t.journal.text.Reset()
+	if !t.Persisted || opt.NoSaveConversation {
+		return
+	}
+	if store, ok := t.Store.(conversations.ConversationJournal); ok {
+		t.journal.store = store
+	}
+}
+
+// StopJournal stops journaling. Text streamed since the last write is
+// dropped, as the response it belongs to did not finish.
+func (t *Thread) StopJournal() {
+	t.journal.mu.Lock()
+	defer t.journal.mu.Unlock()
+	t.journal.store = nil
+	t.journal.text.Reset()
+}
+
+// JournalMessage journals message, added to the history at index.
+func (t *Thread) JournalMessage(ctx context.Context, index int, message any) {
+	t.journal.mu.Lock()
+	defer t.journal.mu.Unlock()
+	if t.journal.store == nil {
+		return
+	}
+
+	raw, err := json.Marshal(message)
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to marshal message for the conversation journal")
+		return
+	}
+	if t.journal.textIndex == index {
+		// The finished message replaces the text streamed for it
+		t.journal.text.Reset()
+	}
+	t.appendJournal(ctx, convtypes.JournalEvent{Kind: convtypes.JournalMessage, Index: index, Message: raw})
+}
+
+// JournalAssistantText journals text the assistant streamed for the message
+// that is to be added to the history at index. Text is written at most every
+// journalTextInterval.
+func (t *Thread) JournalAssistantText(ctx context.Context, index int, text string) {
+	t.journal.mu.Lock()
+	defer t.journal.mu.Unlock()
+	if t.journal.store == nil || text == "" {
+		return
+	}
+
+	if t.journal.textIndex != index {
+		t.journal.text.Reset()
+		t.journal.textIndex = index
+	}
+	if t.journal.text.Len() == 0 {
+		t.journal.textSince = time.Now()
+	}
+	t.journal.text.WriteString(text)
+	if time.Since(t.journal.textSince) < journalTextInterval {
+		return
+	}
+	t.appendJournal(ctx, convtypes.JournalEvent{Kind: convtypes.JournalAssistantText, Index: index, Text: t.journal.text.String()})
+	t.journal.text.Reset()
+}
+
+// JournalToolResult journals the result of the tool call toolCallID as soon
+// as the tool finishes, before the results of its batch of tool calls are
+// added to the history.
+func (t *Thread) JournalToolResult(ctx context.Context, toolCallID string, result tooltypes.ToolResult, structured tooltypes.StructuredToolResult) {
+	t.journal.mu.Lock()
+	defer t.journal.mu.Unlock()
+	if t.journal.store == nil || result == nil {
+		return
+	}
+
+	t.appendJournal(ctx, convtypes.JournalEvent{
+		Kind:       convtypes.JournalToolResult,
+		ToolCallID: toolCallID,
+		Text:       result.AssistantFacing(),
+		IsError:    result.IsError(),
+		ToolResult: &structured,
+	})
+}
+
+// JournalPosition returns how far the journal of the conversation has been
+// written. A save reads it before it starts and passes it to ClearJournal, so
+// that events written by tools still running during the save are kept.
+func (t *Thread) JournalPosition(ctx context.Context) int64 {
+	store, ok := t.Store.(conversations.ConversationJournal)
+	if !ok {
+		return 0
+	}
+	position, err := store.JournalPosition(ctx, t.ConversationID)
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to read the conversation journal position")
+		return 0
+	}
+	return position
+}
+
+// ClearJournal removes the events of the journal of the conversation up to
+// position, once the conversation is saved.
+func (t *Thread) ClearJournal(ctx context.Context, position int64) {
+	store, ok := t.Store.(conversations.ConversationJournal)
+	if !ok || position <= 0 {
+		return
+	}
+	if err := store.ClearJournal(ctx, t.ConversationID, position); err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to clear the conversation journal")
+	}
+}
+
+// ReplayJournal recovers into record what the journal of the conversation
+// holds beyond it, such as the turn that was in flight when kodelet was
+// killed. The journal is kept until the recovered conversation is saved.
+func (t *Thread) ReplayJournal(ctx context.Context, record *convtypes.ConversationRecord) convtypes.JournalReplay {
+	store, ok := t.Store.(conversations.ConversationJournal)
+	if !ok {
+		return convtypes.JournalReplay{}
+	}
+	events, err := store.LoadJournal(ctx, t.ConversationID)
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to load the conversation journal")
+		return convtypes.JournalReplay{}
+	}
+	replay, err := conversations.ReplayJournal(record, events)
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to replay the conversation journal")
+		return convtypes.JournalReplay{}
+	}
+	if replay.Recovered() {
+		logger.G(ctx).
+			WithField("conversation_id", t.ConversationID).
+			WithField("messages", replay.Messages).
+			Info("recovered conversation changes from its journal")
+	}
+	return replay
+}
+
+// RecoveredToolOutput returns the output of the tool call toolCallID for
+// completing the turn that was in flight when the journal was last written.
+// Providers complete it by adding the text of a response that was still
+// streaming, or the results of the tool calls that end the history. A call
+// the journal holds no result for did not finish, and is reported as
+// interrupted so the model checks its effects rather than assume either way.
+func RecoveredToolOutput(replay convtypes.JournalReplay, toolCallID string) convtypes.JournalToolOutput {
+	if output, ok := replay.ToolOutputs[toolCallID]; ok {
+		return output
+	}
+	return convtypes.JournalToolOutput{Text: InterruptedToolCallOutput, IsError: true}
+}
+
+// appendJournal writes event; the caller holds t.journal.mu. It is written
+// even when ctx is cancelled, as an interrupted turn is what the journal is
+// for. A failed write is logged and the conversation carries on, as it is
+// still saved at the end of the exchange.
+func (t *Thread) appendJournal(ctx context.Context, event convtypes.JournalEvent) {
+	event.Provider = t.journal.provider
+	event.CreatedAt = time.Now()
+	if err := t.journal.store.AppendJournal(context.WithoutCancel(ctx), t.ConversationID, event); err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to write the conversation journal")
+	}
+}
@@ -0,0 +1,127 @@
+package base
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// journalingStore keeps conversation journals in memory.
+type journalingStore struct {
+	mockConversationStore
+	mu       sync.Mutex
+	journals map[string][]convtypes.JournalEvent
+}
+
+func (s *journalingStore) AppendJournal(_ context.Context, id string, event convtypes.JournalEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.journals == nil {
+		s.journals = make(map[string][]convtypes.JournalEvent)
+	}
+	s.journals[id] = append(s.journals[id], event)
+	return nil
+}
+
+func (s *journalingStore) LoadJournal(_ context.Context, id string) ([]convtypes.JournalEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]convtypes.JournalEvent(nil), s.journals[id]...), nil
+}
+
+func (s *journalingStore) JournalPosition(_ context.Context, id string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.journals[id])), nil
+}
+
+// ClearJournal treats position as a count of events, as nothing was cleared
+// before.
+func (s *journalingStore) ClearJournal(_ context.Context, id string, position int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.journals[id] = s.journals[id][position:]
+	return nil
+}
+
+func TestThreadJournal(t *testing.T) {
+	ctx := context.Background()
+	store := &journalingStore{}
+	thread := NewThread(llmtypes.Config{}, "conv-journal")
+	thread.Store = store
+	thread.Persisted = true
+
+	thread.StartJournal("anthropic", llmtypes.MessageOpt{})
+	thread.JournalMessage(ctx, 0, map[string]string{"role": "user", "content": "hi"})
+
+	// Streamed text is collected until journalTextInterval has passed
+	thread.JournalAssistantText(ctx, 1, "Hello")
+	assert.Len(t, store.journals["conv-journal"], 1)
+	thread.journal.textSince = time.Now().Add(-journalTextInterval)
+	thread.JournalAssistantText(ctx, 1, " world")
+	require.Len(t, store.journals["conv-journal"], 2)
+	assert.Equal(t, "Hello world", store.journals["conv-journal"][1].Text)
+
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Go(func() {
+			callID := fmt.Sprintf("call-%d", i)
+			result := tooltypes.BaseToolResult{Result: "ok"}
+			thread.JournalToolResult(ctx, callID, result, result.StructuredData())
+		})
+	}
+	wg.Wait()
+
+	record := convtypes.ConversationRecord{ID: "conv-journal", Provider: "anthropic"}
+	replay := thread.ReplayJournal(ctx, &record)
+	assert.Equal(t, 1, replay.Messages)
+	assert.Equal(t, "Hello world", replay.AssistantText)
+	assert.Len(t, replay.ToolOutputs, 5)
+	for _, event := range store.journals["conv-journal"] {
+		assert.Equal(t, "anthropic", event.Provider)
+	}
+
+	// Events journaled after the position was read survive the clear
+	position := thread.JournalPosition(ctx)
+	thread.JournalToolResult(ctx, "call-late", tooltypes.BaseToolResult{Result: "late"}, tooltypes.StructuredToolResult{})
+	thread.ClearJournal(ctx, position)
+	require.Len(t, store.journals["conv-journal"], 1)
+	assert.Equal(t, "call-late", store.journals["conv-journal"][0].ToolCallID)
+	store.journals["conv-journal"] = nil
+
+	thread.StopJournal()
+	thread.JournalMessage(ctx, 1, map[string]string{"role": "assistant"})
+	assert.Empty(t, store.journals["conv-journal"])
+}
+
+func TestThreadJournalDisabled(t *testing.T) {
+	ctx := context.Background()
+	store := &journalingStore{}
+	thread := NewThread(llmtypes.Config{}, "conv-journal")
+	thread.Store = store
+
+	// Not persisted
+	thread.StartJournal("anthropic", llmtypes.MessageOpt{})
+	thread.JournalMessage(ctx, 0, "hi")
+
+	// Not saved
+	thread.Persisted = true
+	thread.StartJournal("anthropic", llmtypes.MessageOpt{NoSaveConversation: true})
+	thread.JournalMessage(ctx, 0, "hi")
+	assert.Empty(t, store.journals)
+
+	// A store without a journal
+	thread.Store = &mockConversationStore{}
+	thread.StartJournal("anthropic", llmtypes.MessageOpt{})
+	thread.JournalMessage(ctx, 0, "hi")
+	assert.False(t, thread.ReplayJournal(ctx, &convtypes.ConversationRecord{}).Recovered())
+}
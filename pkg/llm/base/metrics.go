@@ -0,0 +1,19 @@
+package base
+
+import (
+	"github.com/jingkaihe/kodelet/pkg/telemetry"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// RecordExchangeMetrics exports the usage added since before as Prometheus
+// metrics of model. Providers call it after each exchange.
+func (t *Thread) RecordExchangeMetrics(model string, before llmtypes.Usage) {
+	after := t.GetUsage()
+	telemetry.RecordLLMUsage(t.Config.Provider, model, telemetry.LLMUsage{
+		InputTokens:      after.InputTokens - before.InputTokens,
+		OutputTokens:     after.OutputTokens - before.OutputTokens,
+		CacheWriteTokens: after.CacheCreationInputTokens - before.CacheCreationInputTokens,
+		CacheReadTokens:  after.CacheReadInputTokens - before.CacheReadInputTokens,
+		CostUSD:          after.TotalCost() - before.TotalCost(),
+	})
+}
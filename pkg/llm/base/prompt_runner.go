@@ -7,12 +7,17 @@ import (
 
 	"github.com/jingkaihe/kodelet/pkg/conversations"
 	"github.com/jingkaihe/kodelet/pkg/llm/prompts"
+	"github.com/jingkaihe/kodelet/pkg/logger"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
 	"github.com/pkg/errors"
 )
 
-const shortSummaryFallbackMaxLength = 100
+const (
+	shortSummaryFallbackMaxLength = 100
+	titleMaxLength                = 80
+	titleFallbackMaxLength        = 60
+)
 
 // UtilityThread is a thread that supports utility-mode preparation.
 type UtilityThread interface {
@@ -60,6 +65,9 @@ func RunPreparedPromptTyped[T llmtypes.Thread](
 	if err != nil {
 		return "", err
 	}
+	if event := handler.ContentFilter(); event != nil {
+		return "", &llmtypes.ContentFilteredError{Event: *event}
+	}
 
 	return handler.CollectedText(), nil
 }
@@ -179,6 +187,108 @@ func truncateSummaryFallback(text string) string {
 	return trimmed[:shortSummaryFallbackMaxLength-3] + "..."
 }
 
+// ConversationTitle derives the title of a conversation from its first
+// exchange. The weak model writes it when useLLM is set; otherwise, or when
+// generation fails, the first line of the first user message is used.
+func ConversationTitle(
+	ctx context.Context,
+	messages []conversations.StreamableMessage,
+	toolResults map[string]tooltypes.StructuredToolResult,
+	useLLM bool,
+	runUtilityPrompt func(ctx context.Context, prompt string, useWeakModel bool) (string, error),
+) string {
+	title := FirstUserMessageTitle(messages)
+	if !useLLM || title == "" {
+		return title
+	}
+
+	generated, err := GenerateTitle(ctx, RenderMarkdownForSummary(messages, toolResults), runUtilityPrompt)
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to generate conversation title")
+		return title
+	}
+	return generated
+}
+
+// RecordConversationTitle gives the conversation its title at the first
+// exchange. The title is added to metadata, which is about to be saved, and
+// to the thread metadata so it is not derived again.
+func (t *Thread) RecordConversationTitle(
+	ctx context.Context,
+	metadata map[string]any,
+	messages []conversations.StreamableMessage,
+	toolResults map[string]tooltypes.StructuredToolResult,
+	runUtilityPrompt func(ctx context.Context, prompt string, useWeakModel bool) (string, error),
+) {
+	if conversations.TitleFromMetadata(metadata) != "" {
+		return
+	}
+
+	title := ConversationTitle(ctx, messages, toolResults, t.Config.ConversationSummaryMode.UsesLLM(), runUtilityPrompt)
+	if title == "" {
+		return
+	}
+	metadata[conversations.TitleMetadataKey] = title
+	t.SetMetadataValue(conversations.TitleMetadataKey, title)
+}
+
+// GenerateTitle runs the title prompt using the utility prompt runner.
+func GenerateTitle(
+	ctx context.Context,
+	markdown string,
+	runUtilityPrompt func(ctx context.Context, prompt string, useWeakModel bool) (string, error),
+) (string, error) {
+	prompt := strings.TrimSpace(prompts.TitlePrompt) + "\n\nConversation to title:\n\n" + strings.TrimSpace(markdown)
+	title, err := runUtilityPrompt(ctx, prompt, true)
+	if err != nil {
+		return "", err
+	}
+
+	normalized := normalizeTitle(title)
+	if normalized == "" {
+		return "", errors.New("generated empty title")
+	}
+	return normalized, nil
+}
+
+func normalizeTitle(title string) string {
+	title = strings.TrimSpace(title)
+	if i := strings.IndexAny(title, "\r\n"); i >= 0 {
+		title = title[:i]
+	}
+	title = strings.TrimPrefix(title, "<title>")
+	title = strings.TrimSuffix(title, "</title>")
+	title = strings.Trim(title, "\"'` ")
+	title = strings.TrimRight(title, ".!:; ")
+	return truncateRunes(title, titleMaxLength)
+}
+
+// FirstUserMessageTitle builds a title from the first line of the first user
+// text message.
+func FirstUserMessageTitle(messages []conversations.StreamableMessage) string {
+	for _, msg := range messages {
+		if msg.Role != "user" {
+			continue
+		}
+
+		for line := range strings.SplitSeq(firstUserMessageText(msg), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				return truncateRunes(line, titleFallbackMaxLength)
+			}
+		}
+	}
+
+	return ""
+}
+
+func truncateRunes(text string, maxLength int) string {
+	runes := []rune(text)
+	if len(runes) <= maxLength {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:maxLength-3])) + "..."
+}
+
 // BuildShortSummaryPrompt wraps rendered conversation markdown in the short-summary instruction.
 func BuildShortSummaryPrompt(markdown string) string {
 	trimmed := strings.TrimSpace(markdown)
@@ -197,7 +307,9 @@ func RenderMarkdownForSummary(
 }
 
 // RunUtilityPrompt creates a helper thread, seeds provider-specific history,
-// switches it to utility mode, and sends a prompt.
+// switches it to utility mode, and sends a prompt. A prompt refused by the provider's
+// content policy is retried once with a rephrased instruction unless the
+// content_filter.rephrase config is false.
 func RunUtilityPrompt[T UtilityThread](
 	ctx context.Context,
 	createThread func() (T, error),
@@ -205,17 +317,31 @@ func RunUtilityPrompt[T UtilityThread](
 	prompt string,
 	useWeakModel bool,
 ) (string, error) {
-	return RunPreparedPromptTyped(
-		ctx,
-		createThread,
-		func(thread T) error {
-			if seedThread != nil {
-				seedThread(thread)
-			}
-			thread.PrepareUtilityMode(ctx)
-			return nil
-		},
-		prompt,
-		UtilityPromptOptions(useWeakModel),
-	)
+	rephrase := false
+	run := func(prompt string) (string, error) {
+		return RunPreparedPromptTyped(
+			ctx,
+			createThread,
+			func(thread T) error {
+				if seedThread != nil {
+					seedThread(thread)
+				}
+				thread.PrepareUtilityMode(ctx)
+				rephrase = thread.GetConfig().ContentFilterRephrase()
+				return nil
+			},
+			prompt,
+			UtilityPromptOptions(useWeakModel),
+		)
+	}
+
+	output, err := run(prompt)
+	var filtered *llmtypes.ContentFilteredError
+	if err == nil || !rephrase || !errors.As(err, &filtered) {
+		return output, err
+	}
+
+	logger.G(ctx).WithField("reason", filtered.Event.Reason).
+		Warn("utility prompt blocked by the provider content policy, retrying with a rephrased instruction")
+	return run(RephraseForContentPolicy(prompt))
 }
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/llm/prompts"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
 	"github.com/pkg/errors"
@@ -21,6 +22,8 @@ type promptRunnerThread struct {
 	sentPrompt     string
 	sentOpt        llmtypes.MessageOpt
 	sendErr        error
+	refusals       int
+	sendCount      int
 	prepareCalled  bool
 	seedCalled     bool
 	persisted      bool
@@ -37,9 +40,18 @@ func (t *promptRunnerThread) AddUserMessage(context.Context, string, ...string)
 func (t *promptRunnerThread) SendMessage(_ context.Context, prompt string, handler llmtypes.MessageHandler, opt llmtypes.MessageOpt) (string, error) {
 	t.sentPrompt = prompt
 	t.sentOpt = opt
+	t.sendCount++
 	if t.sendErr != nil {
 		return "", t.sendErr
 	}
+	if t.refusals > 0 {
+		t.refusals--
+		handler.(llmtypes.ContentFilterMessageHandler).HandleContentFilter(llmtypes.ContentFilterEvent{
+			Provider: "test",
+			Reason:   llmtypes.ContentFilterReasonRefusal,
+		})
+		return "", nil
+	}
 	handler.HandleText("collected output")
 	return "ignored final output", nil
 }
@@ -164,6 +176,61 @@ func TestRunUtilityPromptSeedsAndPreparesThread(t *testing.T) {
 	assert.False(t, thread.sentOpt.UseWeakModel)
 }
 
+func TestRunUtilityPromptRephrasesAfterContentFilter(t *testing.T) {
+	t.Run("retries once with a rephrased prompt", func(t *testing.T) {
+		thread := newPromptRunnerThread()
+		thread.refusals = 1
+		output, err := RunUtilityPrompt(
+			context.Background(),
+			func() (*promptRunnerThread, error) { return thread, nil },
+			nil,
+			"summarize this",
+			true,
+		)
+
+		require.NoError(t, err)
+		assert.Equal(t, "collected output\n", output)
+		assert.Equal(t, 2, thread.sendCount)
+		assert.True(t, strings.HasPrefix(thread.sentPrompt, prompts.ContentFilterRephrasePrompt))
+		assert.True(t, strings.HasSuffix(thread.sentPrompt, "summarize this"))
+	})
+
+	t.Run("returns the error when the rephrased prompt is refused", func(t *testing.T) {
+		thread := newPromptRunnerThread()
+		thread.refusals = 2
+		_, err := RunUtilityPrompt(
+			context.Background(),
+			func() (*promptRunnerThread, error) { return thread, nil },
+			nil,
+			"summarize this",
+			true,
+		)
+
+		var filtered *llmtypes.ContentFilteredError
+		require.ErrorAs(t, err, &filtered)
+		assert.Equal(t, llmtypes.ContentFilterReasonRefusal, filtered.Event.Reason)
+		assert.Equal(t, 2, thread.sendCount)
+	})
+
+	t.Run("does not retry when rephrasing is disabled", func(t *testing.T) {
+		disabled := false
+		thread := newPromptRunnerThread()
+		thread.config.ContentFilter = &llmtypes.ContentFilterConfig{Rephrase: &disabled}
+		thread.refusals = 1
+		_, err := RunUtilityPrompt(
+			context.Background(),
+			func() (*promptRunnerThread, error) { return thread, nil },
+			nil,
+			"summarize this",
+			true,
+		)
+
+		var filtered *llmtypes.ContentFilteredError
+		require.ErrorAs(t, err, &filtered)
+		assert.Equal(t, 1, thread.sendCount)
+	})
+}
+
 func TestGenerateShortSummary(t *testing.T) {
 	ctx := context.Background()
 
@@ -260,6 +327,52 @@ func TestFirstUserMessageFallback(t *testing.T) {
 	})
 }
 
+func TestConversationTitle(t *testing.T) {
+	ctx := context.Background()
+	messages := []conversations.StreamableMessage{
+		{Kind: "text", Role: "user", Content: "\n  Fix the flaky login test\nIt fails on CI about once a day."},
+		{Kind: "text", Role: "assistant", Content: "Looking into it."},
+	}
+
+	t.Run("generated by the weak model", func(t *testing.T) {
+		title := ConversationTitle(ctx, messages, nil, true,
+			func(_ context.Context, prompt string, useWeakModel bool) (string, error) {
+				assert.Contains(t, prompt, "Conversation to title:")
+				assert.Contains(t, prompt, "Fix the flaky login test")
+				assert.True(t, useWeakModel)
+				return "<title>\"Fix Flaky Login Test.\"</title>\n", nil
+			},
+		)
+		assert.Equal(t, "Fix Flaky Login Test", title)
+	})
+
+	t.Run("first line of the first user message without the LLM", func(t *testing.T) {
+		title := ConversationTitle(ctx, messages, nil, false,
+			func(context.Context, string, bool) (string, error) {
+				t.Fatal("the utility prompt should not run")
+				return "", nil
+			},
+		)
+		assert.Equal(t, "Fix the flaky login test", title)
+	})
+
+	t.Run("falls back when generation fails", func(t *testing.T) {
+		title := ConversationTitle(ctx, messages, nil, true,
+			func(context.Context, string, bool) (string, error) {
+				return "", errors.New("generation failed")
+			},
+		)
+		assert.Equal(t, "Fix the flaky login test", title)
+	})
+
+	t.Run("truncates a long first line", func(t *testing.T) {
+		long := []conversations.StreamableMessage{{Kind: "text", Role: "user", Content: strings.Repeat("word ", 30)}}
+		title := FirstUserMessageTitle(long)
+		assert.Len(t, []rune(title), titleFallbackMaxLength)
+		assert.True(t, strings.HasSuffix(title, "..."))
+	})
+}
+
 func TestRenderMarkdownForSummaryExcludesThinking(t *testing.T) {
 	messages := []conversations.StreamableMessage{
 		{Kind: "text", Role: "user", Content: "Summarize this"},
@@ -0,0 +1,111 @@
+package base
+
+import (
+	"context"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/quota"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+)
+
+// EnableQuotaTracking starts tracking the rate limit windows reported by the
+// provider. Providers call it for subscription-authenticated clients.
+func (t *Thread) EnableQuotaTracking() {
+	t.quotaTracker = quota.NewTracker()
+}
+
+// QuotaTracker returns the thread's rate limit window tracker, or nil when
+// quota tracking is off.
+func (t *Thread) QuotaTracker() *quota.Tracker {
+	return t.quotaTracker
+}
+
+// ApplyQuotaPolicy warns about rate limit windows that are filling up and
+// applies quota.action once one crosses the action threshold. Providers call
+// it before every exchange; it reports whether the exchange should use the
+// weak model, and fails when pausing would exceed quota.max_pause.
+func ApplyQuotaPolicy(ctx context.Context, thread llmtypes.Thread) (bool, error) {
+	tracked, ok := thread.(interface{ QuotaTracker() *quota.Tracker })
+	if !ok || tracked.QuotaTracker() == nil {
+		return false, nil
+	}
+	tracker := tracked.QuotaTracker()
+	config := thread.GetConfig()
+	policy := quotaPolicy(config)
+
+	decision := tracker.Check(policy)
+	for _, notice := range decision.Notices {
+		entry := logger.G(ctx).
+			WithField("window", notice.Window.Name).
+			WithField("utilization", notice.Window.Utilization)
+		if !notice.Window.ResetsAt.IsZero() {
+			entry = entry.WithField("resets_at", notice.Window.ResetsAt.Format(time.RFC3339))
+		}
+		if notice.Level == quota.LevelAction {
+			entry.WithField("action", policy.Action).Warn("subscription quota is nearly exhausted")
+		} else {
+			entry.Warn("subscription quota is running low")
+		}
+	}
+
+	limiting := decision.Limiting
+	if limiting == nil {
+		return false, nil
+	}
+	switch policy.Action {
+	case quota.ActionWeakModel:
+		return config.WeakModel != "", nil
+	case quota.ActionPause:
+		return false, pauseForQuota(ctx, tracker, *limiting, policy.MaxPause)
+	}
+	return false, nil
+}
+
+func pauseForQuota(ctx context.Context, tracker *quota.Tracker, window quota.Window, maxPause time.Duration) error {
+	wait := time.Until(window.ResetsAt)
+	if window.ResetsAt.IsZero() || wait > maxPause {
+		return errors.Errorf("subscription quota for the %s window is %.0f%% used and does not reset within quota.max_pause (%s)",
+			window.Name, window.Utilization*100, maxPause)
+	}
+
+	logger.G(ctx).
+		WithField("window", window.Name).
+		WithField("wait", wait.Round(time.Second).String()).
+		Warn("pausing until the subscription quota window resets")
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+	}
+	tracker.Forget(window.Name)
+	return nil
+}
+
+func quotaPolicy(config llmtypes.Config) quota.Policy {
+	policy := quota.Policy{
+		WarnThreshold:   quota.DefaultWarnThreshold,
+		ActionThreshold: quota.DefaultActionThreshold,
+		Action:          quota.ActionWarn,
+		MaxPause:        quota.DefaultMaxPause,
+	}
+	if config.Quota == nil {
+		return policy
+	}
+	if config.Quota.WarnThreshold > 0 {
+		policy.WarnThreshold = config.Quota.WarnThreshold
+	}
+	if config.Quota.ActionThreshold > 0 {
+		policy.ActionThreshold = config.Quota.ActionThreshold
+	}
+	if config.Quota.Action != "" {
+		policy.Action = quota.Action(config.Quota.Action)
+	}
+	if config.Quota.MaxPause > 0 {
+		policy.MaxPause = config.Quota.MaxPause
+	}
+	return policy
+}
@@ -0,0 +1,80 @@
+package base
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/quota"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type quotaThreadStub struct {
+	threadStub
+	tracker *quota.Tracker
+}
+
+func (t *quotaThreadStub) QuotaTracker() *quota.Tracker { return t.tracker }
+
+func newQuotaThreadStub(config llmtypes.Config, utilization float64, reset time.Time) *quotaThreadStub {
+	header := http.Header{}
+	header.Set("Anthropic-Ratelimit-Unified-5h-Status", "allowed")
+	header.Set("Anthropic-Ratelimit-Unified-5h-Utilization", strconv.FormatFloat(utilization, 'f', -1, 64))
+	header.Set("Anthropic-Ratelimit-Unified-5h-Reset", strconv.FormatInt(reset.Unix(), 10))
+	tracker := quota.NewTracker()
+	tracker.Observe(header)
+	return &quotaThreadStub{threadStub: threadStub{config: config}, tracker: tracker}
+}
+
+func TestApplyQuotaPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("untracked threads are left alone", func(t *testing.T) {
+		useWeakModel, err := ApplyQuotaPolicy(ctx, &threadStub{})
+		require.NoError(t, err)
+		assert.False(t, useWeakModel)
+	})
+
+	t.Run("warn keeps the main model", func(t *testing.T) {
+		thread := newQuotaThreadStub(llmtypes.Config{WeakModel: "weak"}, 0.99, time.Now().Add(time.Hour))
+		useWeakModel, err := ApplyQuotaPolicy(ctx, thread)
+		require.NoError(t, err)
+		assert.False(t, useWeakModel)
+	})
+
+	t.Run("weak_model downgrades past the action threshold", func(t *testing.T) {
+		config := llmtypes.Config{WeakModel: "weak", Quota: &llmtypes.QuotaConfig{Action: "weak_model", ActionThreshold: 0.9}}
+
+		thread := newQuotaThreadStub(config, 0.85, time.Now().Add(time.Hour))
+		useWeakModel, err := ApplyQuotaPolicy(ctx, thread)
+		require.NoError(t, err)
+		assert.False(t, useWeakModel)
+
+		thread = newQuotaThreadStub(config, 0.92, time.Now().Add(time.Hour))
+		useWeakModel, err = ApplyQuotaPolicy(ctx, thread)
+		require.NoError(t, err)
+		assert.True(t, useWeakModel)
+	})
+
+	t.Run("pause waits for a reset within max_pause", func(t *testing.T) {
+		config := llmtypes.Config{Quota: &llmtypes.QuotaConfig{Action: "pause", MaxPause: time.Minute}}
+		thread := newQuotaThreadStub(config, 1, time.Now().Add(time.Second))
+
+		_, err := ApplyQuotaPolicy(ctx, thread)
+		require.NoError(t, err)
+		assert.Empty(t, thread.tracker.Windows())
+	})
+
+	t.Run("pause stops when the reset is too far away", func(t *testing.T) {
+		config := llmtypes.Config{Quota: &llmtypes.QuotaConfig{Action: "pause", MaxPause: time.Minute}}
+		thread := newQuotaThreadStub(config, 1, time.Now().Add(time.Hour))
+
+		_, err := ApplyQuotaPolicy(ctx, thread)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "does not reset within quota.max_pause (1m0s)")
+	})
+}
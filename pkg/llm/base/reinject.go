@@ -0,0 +1,60 @@
+package base
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// maxReinjectedFiles caps how many recently accessed files are re-injected after compaction.
+const maxReinjectedFiles = 10
+
+// RecentFilesReinjection renders the current contents of the files most
+// recently accessed through the file tools, up to budgetTokens. It returns an
+// empty string when the state does not track file access or nothing fits.
+func RecentFilesReinjection(state tooltypes.State, budgetTokens int) string {
+	reporter, ok := state.(tooltypes.FileAccessReporter)
+	if !ok || budgetTokens <= 0 {
+		return ""
+	}
+
+	remaining := budgetTokens * 4 // ~4 chars/token, matching the context window estimate
+	var files strings.Builder
+	count := 0
+	for _, path := range reporter.RecentlyAccessedFiles() {
+		if count == maxReinjectedFiles {
+			break
+		}
+		content, err := os.ReadFile(path)
+		if err != nil || bytes.IndexByte(content, 0) >= 0 {
+			continue
+		}
+		entry := fmt.Sprintf("<file path=%q>\n%s\n</file>\n", path, strings.TrimRight(string(content), "\n"))
+		if len(entry) > remaining {
+			continue
+		}
+		files.WriteString(entry)
+		remaining -= len(entry)
+		count++
+	}
+	if count == 0 {
+		return ""
+	}
+
+	return "<recently_accessed_files>\n" +
+		"These files were being read or edited before the conversation was compacted. Their current contents are included so you do not need to read them again.\n" +
+		files.String() +
+		"</recently_accessed_files>"
+}
+
+// appendRecentFiles appends the re-injected files to a compaction summary.
+func (t *Thread) appendRecentFiles(summary string) string {
+	reinjection := RecentFilesReinjection(t.State, t.Config.CompactReinjectTokens)
+	if reinjection == "" {
+		return summary
+	}
+	return summary + "\n\n" + reinjection
+}
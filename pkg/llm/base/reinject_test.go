@@ -0,0 +1,80 @@
+package base
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fileAccessState struct {
+	tooltypes.State
+	files []string
+}
+
+func (s *fileAccessState) RecentlyAccessedFiles() []string {
+	return s.files
+}
+
+func TestRecentFilesReinjection(t *testing.T) {
+	dir := t.TempDir()
+	recent := filepath.Join(dir, "recent.go")
+	older := filepath.Join(dir, "older.go")
+	binary := filepath.Join(dir, "image.bin")
+	large := filepath.Join(dir, "large.txt")
+	require.NoError(t, os.WriteFile(recent, []byte("package recent\n"), 0o644))
+	require.NoError(t, os.WriteFile(older, []byte("package older\n"), 0o644))
+	require.NoError(t, os.WriteFile(binary, []byte{0x89, 0x00, 0x01}, 0o644))
+	require.NoError(t, os.WriteFile(large, []byte(strings.Repeat("x", 4000)), 0o644))
+
+	state := &fileAccessState{files: []string{recent, binary, large, filepath.Join(dir, "deleted.go"), older}}
+	out := RecentFilesReinjection(state, 100)
+
+	require.NotEmpty(t, out)
+	assert.Contains(t, out, "<recently_accessed_files>")
+	assert.Contains(t, out, "package recent")
+	assert.Contains(t, out, "package older")
+	assert.NotContains(t, out, "image.bin")
+	assert.NotContains(t, out, "large.txt")
+	assert.NotContains(t, out, "deleted.go")
+	assert.Less(t, strings.Index(out, "recent.go"), strings.Index(out, "older.go"))
+}
+
+func TestRecentFilesReinjectionDisabled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.go")
+	require.NoError(t, os.WriteFile(path, []byte("package file\n"), 0o644))
+
+	assert.Empty(t, RecentFilesReinjection(&fileAccessState{files: []string{path}}, 0))
+	assert.Empty(t, RecentFilesReinjection(nil, 100))
+	assert.Empty(t, RecentFilesReinjection(&fileAccessState{}, 100))
+}
+
+func TestThreadCompactContextWithSummaryReinjectsFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+
+	thread := NewThread(llmtypes.Config{CompactReinjectTokens: 1000}, "conv")
+	thread.State = &fileAccessState{files: []string{path}}
+
+	var gotSummary string
+	err := thread.CompactContextWithSummary(
+		context.Background(),
+		func(context.Context, string, bool) (string, error) {
+			return "summary text", nil
+		},
+		func(_ context.Context, summary string) error {
+			gotSummary = summary
+			return nil
+		},
+	)
+
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(gotSummary, "summary text\n\n<recently_accessed_files>"))
+	assert.Contains(t, gotSummary, "package main")
+}
@@ -0,0 +1,73 @@
+package base
+
+import (
+	"maps"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// RunStats counts what one SendMessage call did, without any of its content.
+type RunStats struct {
+	// Turns is the number of model exchanges.
+	Turns int
+	// Reductions maps a context reduction path to how often it ran.
+	Reductions map[string]int
+	// Usage is the token usage and cost added by the call, excluding what a
+	// resumed conversation had used before.
+	Usage llmtypes.Usage
+}
+
+// runStats is what the current SendMessage call did so far.
+type runStats struct {
+	turns      int
+	reductions map[string]int
+	startUsage llmtypes.Usage
+}
+
+// resetRunStats starts counting a new SendMessage call.
+func (t *Thread) resetRunStats() {
+	usage := t.GetUsage()
+	t.Mu.Lock()
+	defer t.Mu.Unlock()
+	t.runStats = runStats{startUsage: usage}
+}
+
+// countTurn records a model exchange of the current SendMessage call.
+func (t *Thread) countTurn() {
+	t.Mu.Lock()
+	defer t.Mu.Unlock()
+	t.runStats.turns++
+}
+
+// countReduction records a context reduction attempt of the current
+// SendMessage call.
+func (t *Thread) countReduction(path string) {
+	t.Mu.Lock()
+	defer t.Mu.Unlock()
+	if t.runStats.reductions == nil {
+		t.runStats.reductions = map[string]int{}
+	}
+	t.runStats.reductions[path]++
+}
+
+// RunStats returns what the last SendMessage call did.
+func (t *Thread) RunStats() RunStats {
+	usage := t.GetUsage()
+	t.Mu.Lock()
+	defer t.Mu.Unlock()
+	start := t.runStats.startUsage
+	return RunStats{
+		Turns:      t.runStats.turns,
+		Reductions: maps.Clone(t.runStats.reductions),
+		Usage: llmtypes.Usage{
+			InputTokens:              usage.InputTokens - start.InputTokens,
+			OutputTokens:             usage.OutputTokens - start.OutputTokens,
+			CacheCreationInputTokens: usage.CacheCreationInputTokens - start.CacheCreationInputTokens,
+			CacheReadInputTokens:     usage.CacheReadInputTokens - start.CacheReadInputTokens,
+			InputCost:                usage.InputCost - start.InputCost,
+			OutputCost:               usage.OutputCost - start.OutputCost,
+			CacheCreationCost:        usage.CacheCreationCost - start.CacheCreationCost,
+			CacheReadCost:            usage.CacheReadCost - start.CacheReadCost,
+		},
+	}
+}
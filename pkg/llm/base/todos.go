@@ -0,0 +1,82 @@
+package base
+
+import (
+	"context"
+
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/todos"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// TodoTracker returns the tracker enforcing the todo discipline for the
+// thread's current run.
+func (t *Thread) TodoTracker() *todos.Tracker {
+	return &t.todoTracker
+}
+
+// HandleTodoDiscipline records an exchange that used tools and appends a todo
+// reminder user-context message when the todo list is overdue. Providers call
+// it after every such exchange; it does nothing unless todos.enforcement is
+// enabled.
+func HandleTodoDiscipline(ctx context.Context, thread llmtypes.Thread, availableTools []tooltypes.Tool) {
+	policy := todoPolicy(thread.GetConfig())
+	tracker := threadTodoTracker(thread)
+	if !policy.Enabled() || tracker == nil || !hasTool(availableTools, todos.ToolName) {
+		return
+	}
+
+	reminder, ok := tracker.RecordTurn(policy, thread.GetMetadata())
+	if !ok {
+		return
+	}
+	logger.G(ctx).
+		WithField("enforcement", policy.Enforcement).
+		Info("todo list is overdue; injecting reminder")
+	thread.AddUserMessage(ctx, reminder)
+}
+
+// todoRefusal reports why a tool call is refused under strict todo
+// enforcement.
+func todoRefusal(thread llmtypes.Thread, toolName string) (string, bool) {
+	if thread == nil {
+		return "", false
+	}
+	tracker := threadTodoTracker(thread)
+	if tracker == nil {
+		return "", false
+	}
+	return tracker.Refusal(todoPolicy(thread.GetConfig()), toolName, thread.GetMetadata())
+}
+
+func resetTodoTracking(thread llmtypes.Thread) {
+	if tracker := threadTodoTracker(thread); tracker != nil {
+		tracker.Reset(thread.GetMetadata())
+	}
+}
+
+func threadTodoTracker(thread llmtypes.Thread) *todos.Tracker {
+	tracked, ok := thread.(interface{ TodoTracker() *todos.Tracker })
+	if !ok {
+		return nil
+	}
+	return tracked.TodoTracker()
+}
+
+func todoPolicy(config llmtypes.Config) todos.Policy {
+	if config.Todos == nil {
+		return todos.Policy{Enforcement: todos.EnforcementOff}
+	}
+	policy := todos.Policy{
+		Enforcement:         todos.Enforcement(config.Todos.Enforcement),
+		ComplexityThreshold: config.Todos.ComplexityThreshold,
+		MaxStaleTurns:       config.Todos.MaxStaleTurns,
+	}
+	if policy.ComplexityThreshold <= 0 {
+		policy.ComplexityThreshold = llmtypes.DefaultTodoComplexityThreshold
+	}
+	if policy.MaxStaleTurns <= 0 {
+		policy.MaxStaleTurns = llmtypes.DefaultTodoMaxStaleTurns
+	}
+	return policy
+}
@@ -0,0 +1,75 @@
+package base
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/todos"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type todoThreadStub struct {
+	threadStub
+	tracker todos.Tracker
+}
+
+func (t *todoThreadStub) TodoTracker() *todos.Tracker { return &t.tracker }
+
+func TestHandleTodoDiscipline(t *testing.T) {
+	availableTools := []tooltypes.Tool{namedTool("bash"), namedTool(todos.ToolName)}
+
+	t.Run("reminds once the task is complex and the list is stale", func(t *testing.T) {
+		thread := &todoThreadStub{threadStub: threadStub{
+			metadata: map[string]any{},
+			config:   llmtypes.Config{Todos: &llmtypes.TodosConfig{Enforcement: "remind", ComplexityThreshold: 2, MaxStaleTurns: 2}},
+		}}
+		DispatchAgentStart(context.Background(), thread)
+
+		HandleTodoDiscipline(context.Background(), thread, availableTools)
+		assert.Empty(t, thread.userMessages)
+		HandleTodoDiscipline(context.Background(), thread, availableTools)
+		require.Len(t, thread.userMessages, 1)
+		assert.True(t, todos.IsReminderText(thread.userMessages[0]))
+
+		_, refused := todoRefusal(thread, "bash")
+		assert.False(t, refused, "remind mode never refuses tools")
+	})
+
+	t.Run("strict mode refuses tools until the list is updated", func(t *testing.T) {
+		thread := &todoThreadStub{threadStub: threadStub{
+			metadata: map[string]any{},
+			config:   llmtypes.Config{Todos: &llmtypes.TodosConfig{Enforcement: "strict", ComplexityThreshold: 1, MaxStaleTurns: 1}},
+		}}
+		DispatchAgentStart(context.Background(), thread)
+		HandleTodoDiscipline(context.Background(), thread, availableTools)
+		require.Len(t, thread.userMessages, 1)
+
+		reason, refused := todoRefusal(thread, "bash")
+		assert.True(t, refused)
+		assert.Contains(t, reason, "todo list is overdue")
+
+		list, err := todos.New([]todos.Item{{Content: "a", Status: todos.StatusInProgress}}, time.Now())
+		require.NoError(t, err)
+		thread.SetMetadataValue(todos.MetadataKey, list)
+		_, refused = todoRefusal(thread, "bash")
+		assert.False(t, refused)
+	})
+
+	t.Run("does nothing when disabled or the todo tool is unavailable", func(t *testing.T) {
+		thread := &todoThreadStub{threadStub: threadStub{metadata: map[string]any{}}}
+		for range 10 {
+			HandleTodoDiscipline(context.Background(), thread, availableTools)
+		}
+		assert.Empty(t, thread.userMessages)
+
+		thread.config = llmtypes.Config{Todos: &llmtypes.TodosConfig{Enforcement: "remind", ComplexityThreshold: 1, MaxStaleTurns: 1}}
+		for range 10 {
+			HandleTodoDiscipline(context.Background(), thread, []tooltypes.Tool{namedTool("bash")})
+		}
+		assert.Empty(t, thread.userMessages)
+	})
+}
@@ -0,0 +1,68 @@
+package base
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jingkaihe/kodelet/pkg/extensions"
+	"github.com/jingkaihe/kodelet/pkg/toolapproval"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// ToolApprovalGate returns the gate that pauses risky tool calls of the
+// thread until the user confirms them, or nil when approval mode is off.
+func (t *Thread) ToolApprovalGate() *toolapproval.Gate {
+	t.approvalOnce.Do(func() {
+		t.approvalGate = toolapproval.NewGate(t.Config.ToolApproval)
+	})
+	return t.approvalGate
+}
+
+// approvalRefusal asks the user to confirm toolName when the call matches the
+// thread's risk list. It returns the message reported to the model when the
+// call must not run, and whether the user was asked at all.
+func approvalRefusal(ctx context.Context, thread llmtypes.Thread, toolName, input string) (refusal string, asked bool) {
+	gate := approvalGate(thread)
+	request, needed := gate.Check(toolName, input)
+	if !needed {
+		return "", false
+	}
+
+	broker, ok := extensions.UIConfirmBrokerFromContext(ctx)
+	if !ok {
+		return fmt.Sprintf("%s requires approval, but there is no interactive terminal to ask. The call was not run.", toolName), true
+	}
+	message := request.Summary
+	if request.Domain != "" {
+		message += fmt.Sprintf("\n%s has not been approved in this run yet.", request.Domain)
+	}
+	response, err := broker.Confirm(ctx, extensions.UIConfirmRequest{
+		ID:                extensions.NewUIInputRequestID(),
+		Title:             fmt.Sprintf("Allow %s?", toolName),
+		Message:           message,
+		ConfirmButtonText: "Yes",
+		CancelButtonText:  "No",
+	})
+	switch {
+	case err != nil:
+		return fmt.Sprintf("Failed to ask for approval of %s: %s. The call was not run.", toolName, err), true
+	case response.Status == extensions.UIInputStatusUnavailable:
+		return fmt.Sprintf("%s requires approval, but interactive input is unavailable. The call was not run.", toolName), true
+	case response.Status != extensions.UIInputStatusSubmitted || !response.Confirmed:
+		return fmt.Sprintf("The user declined this %s call, so it was not run. Do not retry it; ask the user how to proceed.", toolName), true
+	}
+	gate.Approve(request)
+	return "", true
+}
+
+// approvalGate returns the approval gate of thread, or nil when the thread
+// has none or approval mode is off.
+func approvalGate(thread llmtypes.Thread) *toolapproval.Gate {
+	gated, ok := thread.(interface {
+		ToolApprovalGate() *toolapproval.Gate
+	})
+	if !ok {
+		return nil
+	}
+	return gated.ToolApprovalGate()
+}
@@ -0,0 +1,90 @@
+package base
+
+import (
+	"context"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/audit"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// toolCallAudit collects what the audit log records about one tool call.
+type toolCallAudit struct {
+	started        time.Time
+	toolName       string
+	toolCallID     string
+	input          string
+	approval       string
+	approvalReason string
+}
+
+// toolCallAuditLog returns the log the thread's tool calls are recorded to,
+// or nil when tool call auditing is off. Auditing is on when the config
+// enables it or when a parent kodelet run passed its log down.
+func toolCallAuditLog(ctx context.Context, thread llmtypes.Thread) *audit.ToolCallLog {
+	maxOutputBytes := 0
+	if thread != nil {
+		if config := thread.GetConfig().Audit; config != nil {
+			maxOutputBytes = config.MaxOutputBytes
+			if config.ToolCalls {
+				if _, err := audit.EnableToolCallLog(config.Dir); err != nil {
+					logger.G(ctx).WithError(err).Warn("failed to enable tool call audit log")
+				}
+			}
+		}
+	}
+
+	path := audit.ToolCallLogPath()
+	if path == "" {
+		return nil
+	}
+	return audit.NewToolCallLogWithPath(path, maxOutputBytes)
+}
+
+func recordToolCallAudit(
+	ctx context.Context,
+	thread llmtypes.Thread,
+	call toolCallAudit,
+	result tooltypes.ToolResult,
+	structuredResult tooltypes.StructuredToolResult,
+) {
+	log := toolCallAuditLog(ctx, thread)
+	if log == nil {
+		return
+	}
+
+	entry := audit.ToolCallEntry{
+		Timestamp:      call.started,
+		Subagent:       audit.IsSubagent(),
+		ToolCallID:     call.toolCallID,
+		Tool:           call.toolName,
+		Input:          []byte(call.input),
+		Output:         result.GetResult(),
+		DurationMS:     time.Since(call.started).Milliseconds(),
+		Error:          result.GetError(),
+		Approval:       call.approval,
+		ApprovalReason: call.approvalReason,
+	}
+	if thread != nil {
+		entry.ConversationID = thread.GetConversationID()
+	}
+
+	switch {
+	case call.approval == audit.ApprovalBlocked:
+		entry.Status = audit.ToolCallBlocked
+	case result.IsError():
+		entry.Status = audit.ToolCallError
+	default:
+		entry.Status = audit.ToolCallSuccess
+	}
+
+	var bashMetadata tooltypes.BashMetadata
+	if tooltypes.ExtractMetadata(structuredResult.Metadata, &bashMetadata) {
+		exitCode := bashMetadata.ExitCode
+		entry.ExitCode = &exitCode
+	}
+
+	audit.RecordToolCall(ctx, log, entry)
+}
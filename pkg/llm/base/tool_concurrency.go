@@ -0,0 +1,29 @@
+package base
+
+import (
+	"context"
+
+	"github.com/jingkaihe/kodelet/pkg/toolconcurrency"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// ToolLimiter returns the limiter that keeps parallel tool calls of the
+// thread within their concurrency classes.
+func (t *Thread) ToolLimiter() *toolconcurrency.Limiter {
+	t.limiterOnce.Do(func() {
+		t.toolLimiter = toolconcurrency.NewLimiter(t.Config.ToolConcurrency)
+	})
+	return t.toolLimiter
+}
+
+// acquireToolSlot waits until toolName may run alongside the thread's other
+// in-flight tool calls. Threads without a limiter run tools unrestricted.
+func acquireToolSlot(ctx context.Context, thread llmtypes.Thread, toolName string) (func(), error) {
+	limited, ok := thread.(interface {
+		ToolLimiter() *toolconcurrency.Limiter
+	})
+	if !ok || limited.ToolLimiter() == nil {
+		return func() {}, nil
+	}
+	return limited.ToolLimiter().Acquire(ctx, toolName)
+}
@@ -2,13 +2,18 @@ package base
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"time"
 
+	"github.com/jingkaihe/kodelet/pkg/audit"
 	"github.com/jingkaihe/kodelet/pkg/extensions"
 	"github.com/jingkaihe/kodelet/pkg/tools"
 	"github.com/jingkaihe/kodelet/pkg/tools/renderers"
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/pkg/errors"
 )
 
 // ToolExecution holds the normalized result of one tool execution cycle.
@@ -65,6 +70,7 @@ func executeTool(
 	effectiveInput := toolInput
 	blocked := false
 	reason := ""
+	call := toolCallAudit{started: time.Now(), toolName: toolName, toolCallID: toolCallID, approval: audit.ApprovalAllowed}
 
 	callContext := buildExtensionCallContext(thread, state)
 	runtime := extensionRuntime(thread)
@@ -75,23 +81,28 @@ func executeTool(
 		effectiveInput = decision.Input
 	}
 
+	call.input = effectiveInput
+
 	var result tooltypes.ToolResult
 	if blocked {
 		result = tooltypes.NewBlockedToolResult(toolName, reason)
+		call.approval, call.approvalReason = audit.ApprovalBlocked, reason
+	} else if refusal, refused := todoRefusal(thread, toolName); refused {
+		result = tooltypes.BaseToolResult{Error: refusal}
+		call.approval, call.approvalReason = audit.ApprovalRefused, refusal
+	} else if placeholder, deferred := deferToolCall(thread, toolName, effectiveInput, toolCallID); deferred {
+		result = tooltypes.BaseToolResult{Result: placeholder}
+		call.approval = audit.ApprovalDeferred
+	} else if refusal, asked := approvalRefusal(ctx, thread, toolName, effectiveInput); refusal != "" {
+		result = tooltypes.BaseToolResult{Error: refusal}
+		call.approval, call.approvalReason = audit.ApprovalDenied, refusal
+	} else if release, err := acquireToolSlot(ctx, thread, toolName); err != nil {
+		result = tooltypes.BaseToolResult{Error: err.Error()}
 	} else {
-		if thread != nil {
-			workingDir := ""
-			if state != nil {
-				workingDir = state.WorkingDirectory()
-			}
-			toolContext := tools.ToolContextFromThreadState(thread.GetConfig(), thread.GetConversationID(), workingDir, thread)
-			if toolContext.RecipeName == "" {
-				if metadataRecipeName, ok := thread.GetMetadata()["recipe_name"].(string); ok {
-					toolContext.RecipeName = metadataRecipeName
-				}
-			}
-			ctx = tools.ContextWithToolContext(ctx, toolContext)
+		if asked {
+			call.approval = audit.ApprovalConfirmed
 		}
+		ctx = toolRunContext(ctx, thread, state, handler, toolCallID)
 
 		var updateMu sync.Mutex
 		acceptUpdates := true
@@ -127,6 +138,7 @@ func executeTool(
 		}
 
 		result = tools.RunToolWithUpdates(ctx, state, toolName, effectiveInput, onUpdate)
+		release()
 		if onUpdate != nil {
 			updateMu.Lock()
 			acceptUpdates = false
@@ -139,7 +151,7 @@ func executeTool(
 		var modified bool
 		structuredResult, modified = runtime.DispatchToolResult(ctx, callContext, toolName, effectiveInput, toolCallID, structuredResult)
 		if modified {
-			result = StructuredResultToolResult{Result: structuredResult, RendererRegistry: rendererRegistry}
+			result = StructuredResultToolResult{Result: structuredResult, RendererRegistry: rendererRegistry, Images: imageContentParts(result)}
 		}
 	}
 
@@ -148,6 +160,8 @@ func executeTool(
 	}
 
 	renderedOutput := rendererRegistry.Render(structuredResult)
+	recordToolCallAudit(ctx, thread, call, result, structuredResult)
+	journalToolResult(ctx, thread, toolCallID, result, structuredResult)
 
 	return ToolExecution{
 		Input:            effectiveInput,
@@ -157,11 +171,78 @@ func executeTool(
 	}
 }
 
+// journalToolResult writes the result of a finished tool call to the
+// conversation journal of thread, if it keeps one.
+func journalToolResult(ctx context.Context, thread llmtypes.Thread, toolCallID string, result tooltypes.ToolResult, structuredResult tooltypes.StructuredToolResult) {
+	journaled, ok := thread.(interface {
+		JournalToolResult(ctx context.Context, toolCallID string, result tooltypes.ToolResult, structured tooltypes.StructuredToolResult)
+	})
+	if !ok {
+		return
+	}
+	journaled.JournalToolResult(ctx, toolCallID, result, structuredResult)
+}
+
+// toolRunContext attaches what a tool needs while it runs: the thread's tool
+// context, and the callbacks through which it reports stalls or asks the user
+// to approve exceeding change limits or to review edits.
+func toolRunContext(
+	ctx context.Context,
+	thread llmtypes.Thread,
+	state tooltypes.State,
+	handler llmtypes.MessageHandler,
+	toolCallID string,
+) context.Context {
+	if thread != nil {
+		workingDir := ""
+		if state != nil {
+			workingDir = state.WorkingDirectory()
+		}
+		toolContext := tools.ToolContextFromThreadState(thread.GetConfig(), thread.GetConversationID(), workingDir, thread)
+		if toolContext.RecipeName == "" {
+			if metadataRecipeName, ok := thread.GetMetadata()[convtypes.RecipeNameMetadataKey].(string); ok {
+				toolContext.RecipeName = metadataRecipeName
+			}
+		}
+		ctx = tools.ContextWithToolContext(ctx, toolContext)
+	}
+	if broker, ok := extensions.UIConfirmBrokerFromContext(ctx); ok {
+		ctx = tools.ContextWithChangeApprover(ctx, changeApproverFromBroker(broker))
+	}
+	ctx = tools.ContextWithToolStallNotifier(ctx, toolStallNotifier(ctx, handler, toolCallID))
+	if thread != nil && thread.GetConfig().ReviewEdits {
+		if broker, ok := extensions.UIInputBrokerFromContext(ctx); ok {
+			if reviewer, ok := broker.(tools.EditReviewBroker); ok {
+				ctx = tools.ContextWithEditReviewer(ctx, reviewer.ReviewEdit)
+			}
+		}
+	}
+	return ctx
+}
+
 // StructuredResultToolResult adapts a structured result back to ToolResult so
 // post-tool extension mutations affect both rendering and provider LLM input.
 type StructuredResultToolResult struct {
 	Result           tooltypes.StructuredToolResult
 	RendererRegistry *renderers.RendererRegistry
+	// Images are the image parts of the original result, which tool.result
+	// handlers cannot see and keep passing to the model.
+	Images []tooltypes.ToolResultContentPart
+}
+
+// imageContentParts returns the image parts of a multimodal tool result.
+func imageContentParts(result tooltypes.ToolResult) []tooltypes.ToolResultContentPart {
+	rich, ok := result.(tooltypes.MultiModalToolResult)
+	if !ok {
+		return nil
+	}
+	var images []tooltypes.ToolResultContentPart
+	for _, part := range rich.ContentParts() {
+		if part.Type == tooltypes.ToolResultContentPartTypeImage {
+			images = append(images, part)
+		}
+	}
+	return images
 }
 
 func (r StructuredResultToolResult) AssistantFacing() string {
@@ -189,17 +270,26 @@ func (r StructuredResultToolResult) StructuredData() tooltypes.StructuredToolRes
 }
 
 func (r StructuredResultToolResult) ContentParts() []tooltypes.ToolResultContentPart {
-	return []tooltypes.ToolResultContentPart{{
+	parts := []tooltypes.ToolResultContentPart{{
 		Type: tooltypes.ToolResultContentPartTypeText,
 		Text: r.GetResult(),
 	}}
+	return append(parts, r.Images...)
 }
 
 func (r StructuredResultToolResult) String() string {
 	return r.GetResult()
 }
 
-func buildExtensionCallContext(thread llmtypes.Thread, state tooltypes.State) extensions.ExtensionCallContext {
+// extensionCallContextSource is the part of a thread that identifies it to
+// extensions. Provider threads and the shared base Thread both provide it.
+type extensionCallContextSource interface {
+	GetConfig() llmtypes.Config
+	GetConversationID() string
+	GetMetadata() map[string]any
+}
+
+func buildExtensionCallContext(thread extensionCallContextSource, state tooltypes.State) extensions.ExtensionCallContext {
 	if thread == nil {
 		return extensions.ExtensionCallContext{InvokedBy: "main"}
 	}
@@ -212,7 +302,7 @@ func buildExtensionCallContext(thread llmtypes.Thread, state tooltypes.State) ex
 
 	recipeName := config.RecipeName
 	if recipeName == "" {
-		if metadataRecipeName, ok := thread.GetMetadata()["recipe_name"].(string); ok {
+		if metadataRecipeName, ok := thread.GetMetadata()[convtypes.RecipeNameMetadataKey].(string); ok {
 			recipeName = metadataRecipeName
 		}
 	}
@@ -235,3 +325,60 @@ func extensionRuntime(thread llmtypes.Thread) *extensions.Runtime {
 	runtime, _ := thread.GetConfig().Extensions.(*extensions.Runtime)
 	return runtime
 }
+
+// changeApproverFromBroker asks the active user interface to approve exceeding
+// the run's change limits.
+func changeApproverFromBroker(broker extensions.UIConfirmBroker) tools.ChangeApprover {
+	return func(ctx context.Context, summary string) (bool, error) {
+		response, err := broker.Confirm(ctx, extensions.UIConfirmRequest{
+			ID:                extensions.NewUIInputRequestID(),
+			Title:             "Change limit exceeded",
+			Message:           summary + ". Allow the agent to continue modifying files?",
+			ConfirmButtonText: "Continue",
+			CancelButtonText:  "Stop",
+		})
+		if err != nil {
+			return false, err
+		}
+		if response.Status == extensions.UIInputStatusUnavailable {
+			return false, errors.New("interactive approval is unavailable; re-run with --allow-exceed-limits")
+		}
+		return response.Status == extensions.UIInputStatusSubmitted && response.Confirmed, nil
+	}
+}
+
+// toolStallNotifier tells the handler about a stalled tool call and, when the
+// active user interface can ask, offers to cancel just that call.
+func toolStallNotifier(ctx context.Context, handler llmtypes.MessageHandler, toolCallID string) tools.ToolStallNotifier {
+	stallHandler, _ := handler.(llmtypes.ToolStallMessageHandler)
+	broker, canAsk := extensions.UIConfirmBrokerFromContext(ctx)
+	if _, terminal := broker.(*extensions.TerminalUIInputBroker); terminal {
+		// A terminal prompt blocks on stdin and cannot be withdrawn once the
+		// call finishes on its own.
+		canAsk = false
+	}
+	if stallHandler == nil && !canAsk {
+		return nil
+	}
+
+	return func(callCtx context.Context, stall tools.ToolStall) {
+		if stallHandler != nil {
+			stallHandler.HandleToolStall(toolCallID, stall.ToolName, stall.Idle)
+		}
+		if !canAsk {
+			return
+		}
+		go func() {
+			response, err := broker.Confirm(callCtx, extensions.UIConfirmRequest{
+				ID:                extensions.NewUIInputRequestID(),
+				Title:             "Tool call stalled",
+				Message:           fmt.Sprintf("%s has reported no progress for %s. Cancel this tool call? The agent continues with an error for it.", stall.ToolName, stall.Idle.Round(time.Second)),
+				ConfirmButtonText: "Cancel call",
+				CancelButtonText:  "Keep waiting",
+			})
+			if err == nil && response.Status == extensions.UIInputStatusSubmitted && response.Confirmed {
+				stall.Cancel()
+			}
+		}()
+	}
+}
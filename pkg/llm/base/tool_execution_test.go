@@ -2,11 +2,18 @@ package base
 
 import (
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/invopop/jsonschema"
+	"github.com/jingkaihe/kodelet/pkg/audit"
 	"github.com/jingkaihe/kodelet/pkg/extensions"
+	"github.com/jingkaihe/kodelet/pkg/toolapproval"
+	"github.com/jingkaihe/kodelet/pkg/toolconcurrency"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel/attribute"
@@ -151,6 +158,49 @@ func TestExecuteToolPreservesMultimodalResultWhenExtensionRuntimeIsIdle(t *testi
 	}}, multimodalResult.ContentParts())
 }
 
+func TestStructuredResultToolResultKeepsImages(t *testing.T) {
+	images := imageContentParts(multimodalToolResult{})
+	require.Len(t, images, 1)
+	assert.Nil(t, imageContentParts(tooltypes.BaseToolResult{Result: "text"}))
+
+	result := StructuredResultToolResult{
+		Result:           tooltypes.StructuredToolResult{ToolName: "view_image", Success: true},
+		RendererRegistry: renderers.NewRendererRegistry(),
+		Images:           images,
+	}
+	parts := result.ContentParts()
+	require.Len(t, parts, 2)
+	assert.Equal(t, tooltypes.ToolResultContentPartTypeText, parts[0].Type)
+	assert.Equal(t, images[0], parts[1], "images survive a tool.result modification")
+}
+
+type limitedThreadStub struct {
+	threadStub
+	limiter *toolconcurrency.Limiter
+}
+
+func (t *limitedThreadStub) ToolLimiter() *toolconcurrency.Limiter { return t.limiter }
+
+func TestExecuteToolWaitsForConcurrencySlot(t *testing.T) {
+	state := &toolState{tools: []tooltypes.Tool{multimodalTool{}}}
+	thread := &limitedThreadStub{
+		threadStub: threadStub{conversationID: "conv-id", state: state},
+		limiter:    toolconcurrency.NewLimiter(&llmtypes.ToolConcurrencyConfig{Classes: map[string]string{"view_image": toolconcurrency.ClassExclusive}}),
+	}
+	release, err := thread.limiter.Acquire(context.Background(), "view_image")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	execution := ExecuteTool(ctx, thread, state, renderers.NewRendererRegistry(), "view_image", "{}", "call-id")
+	assert.True(t, execution.Result.IsError())
+	assert.Contains(t, execution.Result.GetError(), "waiting to run view_image exclusively")
+
+	release()
+	execution = ExecuteTool(context.Background(), thread, state, renderers.NewRendererRegistry(), "view_image", "{}", "call-id")
+	assert.False(t, execution.Result.IsError())
+}
+
 func TestExecuteToolWithHandlerForwardsUpdatesAndRejectsLateCallbacks(t *testing.T) {
 	tool := &lateUpdateTool{}
 	state := &toolState{tools: []tooltypes.Tool{tool}}
@@ -174,3 +224,111 @@ func TestExecuteToolWithHandlerForwardsUpdatesAndRejectsLateCallbacks(t *testing
 	tool.callback(tooltypes.BaseToolResult{Result: "too late"})
 	assert.Equal(t, []string{"running"}, handler.updates)
 }
+
+func TestExecuteToolRecordsToolCallAudit(t *testing.T) {
+	t.Setenv(audit.ToolCallLogEnv, "")
+	dir := t.TempDir()
+	tool := &lateUpdateTool{}
+	state := &toolState{tools: []tooltypes.Tool{tool}}
+	thread := &threadStub{
+		config:         llmtypes.Config{Audit: &llmtypes.AuditConfig{ToolCalls: true, Dir: dir, MaxOutputBytes: 5}},
+		conversationID: "conv-id",
+		state:          state,
+	}
+
+	ExecuteTool(context.Background(), thread, state, renderers.NewRendererRegistry(), tool.Name(), `{"step": 1}`, "call-1")
+	ExecuteTool(context.Background(), thread, state, renderers.NewRendererRegistry(), "unknown_tool", "not json", "call-2")
+
+	path := audit.ToolCallLogPath()
+	require.Equal(t, dir, filepath.Dir(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var first, second audit.ToolCallEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+
+	assert.Equal(t, strings.TrimSuffix(filepath.Base(path), ".jsonl"), first.RunID)
+	assert.Equal(t, "conv-id", first.ConversationID)
+	assert.Equal(t, "call-1", first.ToolCallID)
+	assert.Equal(t, "late_update", first.Tool)
+	assert.JSONEq(t, `{"step": 1}`, string(first.Input))
+	assert.Equal(t, "compl", first.Output)
+	assert.Equal(t, 8, first.OutputBytes)
+	assert.True(t, first.OutputTruncated)
+	assert.Equal(t, audit.ToolCallSuccess, first.Status)
+	assert.Equal(t, audit.ApprovalAllowed, first.Approval)
+
+	assert.JSONEq(t, `"not json"`, string(second.Input))
+	assert.Equal(t, audit.ToolCallError, second.Status)
+	assert.Contains(t, second.Error, "failed to find tool")
+}
+
+type stubConfirmBroker struct {
+	response extensions.UIInputResponse
+	request  extensions.UIConfirmRequest
+}
+
+func (b *stubConfirmBroker) Confirm(_ context.Context, request extensions.UIConfirmRequest) (extensions.UIInputResponse, error) {
+	b.request = request
+	return b.response, nil
+}
+
+func TestChangeApproverFromBroker(t *testing.T) {
+	broker := &stubConfirmBroker{response: extensions.UIInputResponse{Status: extensions.UIInputStatusSubmitted, Confirmed: true}}
+	approved, err := changeApproverFromBroker(broker)(context.Background(), "3 files changed (limit 2)")
+	require.NoError(t, err)
+	assert.True(t, approved)
+	assert.Contains(t, broker.request.Message, "3 files changed (limit 2)")
+
+	broker.response = extensions.UIInputResponse{Status: extensions.UIInputStatusDismissed}
+	approved, err = changeApproverFromBroker(broker)(context.Background(), "summary")
+	require.NoError(t, err)
+	assert.False(t, approved)
+
+	broker.response = extensions.UIInputResponse{Status: extensions.UIInputStatusUnavailable}
+	_, err = changeApproverFromBroker(broker)(context.Background(), "summary")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--allow-exceed-limits")
+}
+
+func (b *stubConfirmBroker) Input(context.Context, extensions.UIInputRequest) (extensions.UIInputResponse, error) {
+	return extensions.UIInputResponse{Status: extensions.UIInputStatusUnavailable}, nil
+}
+
+type approvalThreadStub struct {
+	threadStub
+	gate *toolapproval.Gate
+}
+
+func (t *approvalThreadStub) ToolApprovalGate() *toolapproval.Gate { return t.gate }
+
+func TestExecuteToolAsksForApproval(t *testing.T) {
+	state := &toolState{tools: []tooltypes.Tool{multimodalTool{}}}
+	thread := &approvalThreadStub{
+		threadStub: threadStub{conversationID: "conv-id", state: state},
+		gate:       toolapproval.NewGate(&llmtypes.ToolApprovalConfig{Enabled: true, Risks: []string{"view_image"}}),
+	}
+	registry := renderers.NewRendererRegistry()
+
+	execution := ExecuteTool(context.Background(), thread, state, registry, "view_image", `{"path": "a.png"}`, "call-1")
+	assert.True(t, execution.Result.IsError())
+	assert.Contains(t, execution.Result.GetError(), "no interactive terminal")
+
+	broker := &stubConfirmBroker{response: extensions.UIInputResponse{Status: extensions.UIInputStatusDismissed}}
+	ctx := extensions.ContextWithUIInputBroker(context.Background(), broker)
+	execution = ExecuteTool(ctx, thread, state, registry, "view_image", `{"path": "a.png"}`, "call-2")
+	assert.True(t, execution.Result.IsError())
+	assert.Contains(t, execution.Result.GetError(), "The user declined this view_image call")
+	assert.Equal(t, "Allow view_image?", broker.request.Title)
+	assert.Equal(t, "a.png", broker.request.Message)
+
+	broker.response = extensions.UIInputResponse{Status: extensions.UIInputStatusSubmitted, Confirmed: true}
+	execution = ExecuteTool(ctx, thread, state, registry, "view_image", `{"path": "a.png"}`, "call-3")
+	assert.False(t, execution.Result.IsError())
+
+	execution = ExecuteTool(context.Background(), &threadStub{conversationID: "conv-id", state: state}, state, registry, "view_image", `{}`, "call-4")
+	assert.False(t, execution.Result.IsError(), "threads without approval mode run tools directly")
+}
@@ -28,15 +28,21 @@ func ProcessUserMessage(
 	return message, nil
 }
 
-// DispatchAgentStart notifies extension handlers when an agent loop starts.
+// DispatchAgentStart resets per-run todo tracking and notifies extension
+// handlers when an agent loop starts.
 func DispatchAgentStart(ctx context.Context, thread llmtypes.Thread) {
+	resetTodoTracking(thread)
 	if runtime := extensionRuntime(thread); runtime != nil {
 		runtime.DispatchAgentStart(ctx, buildExtensionCallContext(thread, threadState(thread)))
 	}
 }
 
-// DispatchTurnStart notifies extension handlers before a model turn starts.
+// DispatchTurnStart counts a model turn and notifies extension handlers
+// before it starts.
 func DispatchTurnStart(ctx context.Context, thread llmtypes.Thread, turnNumber int) {
+	if counter, ok := thread.(interface{ countTurn() }); ok {
+		counter.countTurn()
+	}
 	if runtime := extensionRuntime(thread); runtime != nil {
 		runtime.DispatchTurnStart(ctx, buildExtensionCallContext(thread, threadState(thread)), turnNumber)
 	}
@@ -0,0 +1,297 @@
+// Package chaos injects simulated provider failures into LLM API requests.
+// It is enabled only when KODELET_CHAOS is set, so retry, fallback and
+// compaction handling can be exercised in integration tests and staging
+// without waiting for a real outage.
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+// EnvVar is the environment variable holding the fault specification.
+const EnvVar = "KODELET_CHAOS"
+
+// Fault is a kind of simulated provider failure.
+type Fault string
+
+const (
+	// FaultRateLimit answers with a 429 rate limit error.
+	FaultRateLimit Fault = "rate_limit"
+	// FaultServerError answers with a 500 server error.
+	FaultServerError Fault = "server_error"
+	// FaultTimeout fails the request with a network timeout.
+	FaultTimeout Fault = "timeout"
+	// FaultMalformedStream answers 200 with a stream event that is not valid JSON.
+	FaultMalformedStream Fault = "malformed_stream"
+	// FaultContextLength answers with the provider's context length exceeded error.
+	FaultContextLength Fault = "context_length"
+)
+
+var knownFaults = []Fault{FaultRateLimit, FaultServerError, FaultTimeout, FaultMalformedStream, FaultContextLength}
+
+// Rule injects one fault into a share of the requests sent to a provider.
+type Rule struct {
+	// Provider limits the rule to "anthropic" or "openai". Empty matches
+	// every provider.
+	Provider string
+	Fault    Fault
+	// Probability is the chance, from 0 to 1, that a request fails.
+	Probability float64
+	// Limit caps how many times the rule fires. Zero means no limit.
+	Limit int
+
+	injected int
+}
+
+// Config is a parsed KODELET_CHAOS specification. It is safe for concurrent
+// use, and rule limits are shared by every client using the same Config.
+type Config struct {
+	mu    sync.Mutex
+	rules []*Rule
+	rng   *rand.Rand
+}
+
+// Parse reads a comma separated list of rules. Each rule is
+// [provider/]fault[:probability[:limit]], for example
+// "rate_limit:0.3,anthropic/context_length:1:1". A "seed:N" entry makes the
+// random choices repeatable.
+func Parse(spec string) (*Config, error) {
+	seed := rand.Uint64()
+	var rules []*Rule
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if parts[0] == "seed" {
+			if len(parts) != 2 {
+				return nil, errors.Errorf("invalid chaos seed %q", entry)
+			}
+			value, err := strconv.ParseUint(parts[1], 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid chaos seed %q", entry)
+			}
+			seed = value
+			continue
+		}
+		if len(parts) > 3 {
+			return nil, errors.Errorf("invalid chaos rule %q: expected [provider/]fault[:probability[:limit]]", entry)
+		}
+
+		rule := &Rule{Probability: 1}
+		name := parts[0]
+		if provider, fault, ok := strings.Cut(name, "/"); ok {
+			rule.Provider = strings.ToLower(strings.TrimSpace(provider))
+			name = fault
+		}
+		rule.Fault = Fault(strings.ToLower(strings.TrimSpace(name)))
+		if !slices.Contains(knownFaults, rule.Fault) {
+			return nil, errors.Errorf("unknown chaos fault %q in %q", rule.Fault, entry)
+		}
+		if len(parts) > 1 {
+			probability, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil || probability < 0 || probability > 1 {
+				return nil, errors.Errorf("invalid chaos probability in %q: must be between 0 and 1", entry)
+			}
+			rule.Probability = probability
+		}
+		if len(parts) > 2 {
+			limit, err := strconv.Atoi(parts[2])
+			if err != nil || limit < 0 {
+				return nil, errors.Errorf("invalid chaos limit in %q: must be a non-negative integer", entry)
+			}
+			rule.Limit = limit
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		return nil, errors.Errorf("chaos specification %q has no rules", spec)
+	}
+	return &Config{rules: rules, rng: rand.New(rand.NewPCG(seed, seed))}, nil
+}
+
+var (
+	envOnce   sync.Once
+	envConfig *Config
+)
+
+// FromEnv returns the Config parsed from KODELET_CHAOS, or nil when the
+// variable is unset or invalid. It is parsed once per process so rule limits
+// apply across threads and subagents.
+func FromEnv() *Config {
+	envOnce.Do(func() {
+		spec := strings.TrimSpace(os.Getenv(EnvVar))
+		if spec == "" {
+			return
+		}
+		config, err := Parse(spec)
+		if err != nil {
+			logger.G(context.Background()).WithError(err).Error("ignoring invalid " + EnvVar)
+			return
+		}
+		logger.G(context.Background()).WithField("spec", spec).Warn("chaos mode enabled: LLM provider failures will be simulated")
+		envConfig = config
+	})
+	return envConfig
+}
+
+// Enabled reports whether any rule applies to provider.
+func (c *Config) Enabled(provider string) bool {
+	if c == nil {
+		return false
+	}
+	for _, rule := range c.rules {
+		if rule.Provider == "" || rule.Provider == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// pick returns the fault to inject into the next request to provider.
+func (c *Config) pick(provider string) (Fault, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rule := range c.rules {
+		if rule.Provider != "" && rule.Provider != provider {
+			continue
+		}
+		if rule.Limit > 0 && rule.injected >= rule.Limit {
+			continue
+		}
+		if c.rng.Float64() >= rule.Probability {
+			continue
+		}
+		rule.injected++
+		return rule.Fault, true
+	}
+	return "", false
+}
+
+// Do sends req through next unless a rule injects a fault, in which case the
+// simulated failure is returned without contacting the provider.
+func (c *Config) Do(provider string, req *http.Request, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	fault, ok := c.pick(provider)
+	if !ok {
+		return next(req)
+	}
+	logger.G(req.Context()).WithField("provider", provider).
+		WithField("fault", string(fault)).
+		WithField("url", req.URL.Path).
+		Warn("chaos: injecting simulated provider failure")
+
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	switch fault {
+	case FaultTimeout:
+		return nil, timeoutError{}
+	case FaultMalformedStream:
+		body := "event: " + malformedEventName(provider) + "\ndata: {\"type\": \"malformed\n\n"
+		return fakeResponse(req, fault, http.StatusOK, "text/event-stream", body), nil
+	case FaultRateLimit:
+		resp := fakeResponse(req, fault, http.StatusTooManyRequests, "application/json",
+			errorBody(provider, "rate_limit_error", "rate_limit_exceeded", "Rate limit reached (simulated by KODELET_CHAOS)"))
+		resp.Header.Set("Retry-After-Ms", "100")
+		return resp, nil
+	case FaultServerError:
+		return fakeResponse(req, fault, http.StatusInternalServerError, "application/json",
+			errorBody(provider, "api_error", "server_error", "Internal server error (simulated by KODELET_CHAOS)")), nil
+	default:
+		message := "prompt is too long: 1000001 tokens > 1000000 maximum (simulated by KODELET_CHAOS)"
+		if provider != "anthropic" {
+			message = "Your input exceeds the context window of this model (simulated by KODELET_CHAOS)"
+		}
+		return fakeResponse(req, fault, http.StatusBadRequest, "application/json",
+			errorBody(provider, "invalid_request_error", "context_length_exceeded", message)), nil
+	}
+}
+
+// Middleware returns a request middleware injecting the configured faults
+// for provider, or nil when chaos mode is off for it.
+func Middleware(provider string) func(*http.Request, func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	config := FromEnv()
+	if !config.Enabled(provider) {
+		return nil
+	}
+	return func(req *http.Request, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+		return config.Do(provider, req, next)
+	}
+}
+
+// HTTPDoer is the client interface used by SDKs that take an HTTP client
+// rather than a middleware.
+type HTTPDoer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// WrapDoer returns doer with the configured faults for provider injected, or
+// doer unchanged when chaos mode is off for it.
+func WrapDoer(provider string, doer HTTPDoer) HTTPDoer {
+	config := FromEnv()
+	if doer == nil || !config.Enabled(provider) {
+		return doer
+	}
+	return &chaosDoer{config: config, provider: provider, base: doer}
+}
+
+type chaosDoer struct {
+	config   *Config
+	provider string
+	base     HTTPDoer
+}
+
+func (d *chaosDoer) Do(req *http.Request) (*http.Response, error) {
+	return d.config.Do(d.provider, req, d.base.Do)
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "chaos: simulated request timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func malformedEventName(provider string) string {
+	if provider == "anthropic" {
+		return "message_start"
+	}
+	return "response.created"
+}
+
+// errorBody renders an error in the shape the provider's SDK parses.
+func errorBody(provider, errorType, code, message string) string {
+	if provider == "anthropic" {
+		return fmt.Sprintf(`{"type":"error","error":{"type":%q,"message":%q}}`, errorType, message)
+	}
+	return fmt.Sprintf(`{"error":{"message":%q,"type":%q,"code":%q}}`, message, errorType, code)
+}
+
+func fakeResponse(req *http.Request, fault Fault, status int, contentType, body string) *http.Response {
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	header.Set("X-Kodelet-Chaos", string(fault))
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,134 @@
+package chaos
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	config, err := Parse("rate_limit:0.5, anthropic/context_length:1:2,timeout,seed:7")
+	require.NoError(t, err)
+	require.Len(t, config.rules, 3)
+	assert.Equal(t, Rule{Fault: FaultRateLimit, Probability: 0.5}, *config.rules[0])
+	assert.Equal(t, Rule{Provider: "anthropic", Fault: FaultContextLength, Probability: 1, Limit: 2}, *config.rules[1])
+	assert.Equal(t, Rule{Fault: FaultTimeout, Probability: 1}, *config.rules[2])
+
+	assert.True(t, config.Enabled("openai"))
+	only, err := Parse("anthropic/server_error")
+	require.NoError(t, err)
+	assert.True(t, only.Enabled("anthropic"))
+	assert.False(t, only.Enabled("openai"))
+	assert.False(t, (*Config)(nil).Enabled("openai"))
+
+	for _, spec := range []string{"", "seed:1", "explode", "rate_limit:2", "rate_limit:0.5:-1", "rate_limit:1:1:1", "seed:x"} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestDoRespectsLimitAndProbability(t *testing.T) {
+	config, err := Parse("openai/server_error:1:2,anthropic/rate_limit:0")
+	require.NoError(t, err)
+
+	calls := 0
+	next := func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	}
+	req := httptest.NewRequest(http.MethodPost, "https://api.openai.com/v1/responses", nil)
+
+	var statuses []int
+	for range 3 {
+		resp, err := config.Do("openai", req, next)
+		require.NoError(t, err)
+		statuses = append(statuses, resp.StatusCode)
+	}
+	assert.Equal(t, []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK}, statuses)
+	assert.Equal(t, 1, calls)
+
+	resp, err := config.Do("anthropic", req, next)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, calls)
+}
+
+func TestDoSimulatesFaults(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "https://api.anthropic.com/v1/messages", nil)
+	next := func(*http.Request) (*http.Response, error) {
+		t.Fatal("the provider must not be contacted")
+		return nil, nil
+	}
+	do := func(spec, provider string) (*http.Response, string, error) {
+		config, err := Parse(spec)
+		require.NoError(t, err)
+		resp, err := config.Do(provider, req, next)
+		if err != nil {
+			return nil, "", err
+		}
+		body, readErr := io.ReadAll(resp.Body)
+		require.NoError(t, readErr)
+		return resp, string(body), nil
+	}
+
+	resp, body, err := do("rate_limit", "anthropic")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "rate_limit", resp.Header.Get("X-Kodelet-Chaos"))
+	assert.JSONEq(t, `{"type":"error","error":{"type":"rate_limit_error","message":"Rate limit reached (simulated by KODELET_CHAOS)"}}`, body)
+
+	resp, body, err = do("context_length", "openai")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(t, body, `"code":"context_length_exceeded"`)
+
+	resp, body, err = do("malformed_stream", "openai")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Contains(t, body, "event: response.created\n")
+
+	_, _, err = do("timeout", "openai")
+	var netErr net.Error
+	require.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout())
+}
+
+func TestMiddlewareExercisesSDKRetries(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-haiku-4-5","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`)
+	}))
+	defer server.Close()
+
+	config, err := Parse("anthropic/rate_limit:1:1")
+	require.NoError(t, err)
+	client := anthropic.NewClient(
+		option.WithoutEnvironmentDefaults(),
+		option.WithAPIKey("test"),
+		option.WithBaseURL(server.URL),
+		option.WithMaxRetries(1),
+		option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+			return config.Do("anthropic", req, next)
+		}),
+	)
+
+	message, err := client.Messages.New(context.Background(), anthropic.MessageNewParams{
+		Model:     anthropic.ModelClaudeHaiku4_5_20251001,
+		MaxTokens: 1,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("hi"))},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "msg_1", message.ID)
+	assert.Equal(t, 1, requests)
+}
@@ -5,11 +5,16 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
+	"github.com/jingkaihe/kodelet/pkg/quota"
+	"github.com/jingkaihe/kodelet/pkg/secrets"
+	"github.com/jingkaihe/kodelet/pkg/todos"
+	"github.com/jingkaihe/kodelet/pkg/toolapproval"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 )
 
@@ -76,8 +81,60 @@ func HasConfiguredProfile(profileName string) bool {
 	return exists
 }
 
+// GetConfigFromViperForModel loads the configuration of profileName, or of the
+// active profile when it is empty, with explicitly changed Cobra flags applied, and
+// switches it to model when set. The provider is inferred from model again.
+// Configured experiments are not applied, so the run uses exactly the model
+// asked for, as comparisons such as `kodelet bench` need.
+func GetConfigFromViperForModel(profileName, model string, cmd *cobra.Command) (llmtypes.Config, error) {
+	config, err := loadConfigFromViper(profileName, cmd, false, true)
+	if err != nil {
+		return config, err
+	}
+	if model = strings.TrimSpace(model); model != "" {
+		config.Model = resolveModelAlias(model, config.Aliases)
+		config.Provider = ""
+		if err := ResolveProvider(&config); err != nil {
+			return config, err
+		}
+	}
+	return config, nil
+}
+
+// SwitchModel points config at model, resolving aliases, and infers the
+// provider again when the model belongs to a different vendor. A provider
+// configured with a custom endpoint is kept, since it may serve any model.
+func SwitchModel(config *llmtypes.Config, model string) error {
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return nil
+	}
+	model = resolveModelAlias(model, config.Aliases)
+	provider := strings.ToLower(strings.TrimSpace(config.Provider))
+	if modelProvider, ok := ProviderForModel(model); ok && modelProvider != provider && !usesCustomEndpoint(*config, provider) {
+		provider = ""
+	}
+
+	switched := *config
+	switched.Model = model
+	switched.Provider = provider
+	if err := ResolveProvider(&switched); err != nil {
+		return err
+	}
+	*config = switched
+	return nil
+}
+
 func getConfigFromViperWithProfileAndCmd(profileName string, cmd *cobra.Command, ignoreActiveProfile bool, ignoredFlags ...string) (llmtypes.Config, error) {
+	return loadConfigFromViper(profileName, cmd, ignoreActiveProfile, false, ignoredFlags...)
+}
+
+func loadConfigFromViper(profileName string, cmd *cobra.Command, ignoreActiveProfile, skipExperiment bool, ignoredFlags ...string) (llmtypes.Config, error) {
 	settings := cloneSettings(viper.AllSettings())
+	// AllSettings splits keys on dots and so drops model IDs such as gpt-4.1.
+	if modelFeatures := viper.Get("model_features"); modelFeatures != nil {
+		settings["model_features"] = modelFeatures
+	}
 	if ignoreActiveProfile {
 		delete(settings, "profile")
 	}
@@ -98,13 +155,26 @@ func getConfigFromViperWithProfileAndCmd(profileName string, cmd *cobra.Command,
 
 	// Apply active profile to viper if set
 	if activeProfile != "" && config.Profiles != nil {
-		if profile, exists := config.Profiles[activeProfile]; exists {
-			applyProfileToSettings(settings, profile)
+		if _, exists := config.Profiles[activeProfile]; exists {
+			chain, err := llmtypes.ProfileChain(config.Profiles, activeProfile)
+			if err != nil {
+				return config, errors.Wrap(err, "failed to apply configuration profile")
+			}
+			for _, profile := range chain {
+				applyProfileToSettings(settings, profile)
+			}
 		} else if profileName != "" {
 			return config, errors.Errorf("failed to apply configuration profile: profile '%s' not found", profileName)
 		}
 	}
 
+	// Apply the selected agent over the profile; explicit flags still win
+	if agentName := selectedAgent(settings, cmd); agentName != "" {
+		if err := applyAgentToSettings(settings, config, agentName); err != nil {
+			return config, err
+		}
+	}
+
 	// Apply explicitly changed CLI flags to viper (highest priority)
 	if cmd != nil {
 		applyExplicitFlagsToSettings(cmd, settings, ignoredFlags...)
@@ -123,12 +193,22 @@ func getConfigFromViperWithProfileAndCmd(profileName string, cmd *cobra.Command,
 		config.Profile = activeProfile
 	}
 
+	if !skipExperiment {
+		if err := assignExperiment(&config, cmd, experimentRoll()); err != nil {
+			return config, err
+		}
+	}
+
 	config.Aliases = withDefaultModelAliases(config.Aliases)
 
 	// Resolve model aliases
 	config.Model = resolveModelAlias(config.Model, config.Aliases)
 	config.WeakModel = resolveModelAlias(config.WeakModel, config.Aliases)
 
+	if err := ResolveProvider(&config); err != nil {
+		return config, err
+	}
+
 	return config, nil
 }
 
@@ -176,9 +256,63 @@ var explicitFlagKeyOverrides = map[string]string{
 	"conversation-summary-mode": "conversation_summary_mode",
 }
 
+// selectedAgent returns the agent named by the --agent flag or the agent
+// setting, if any.
+func selectedAgent(settings map[string]any, cmd *cobra.Command) string {
+	if cmd != nil {
+		if flag := cmd.Flags().Lookup("agent"); flag != nil && flag.Changed {
+			return strings.TrimSpace(flag.Value.String())
+		}
+	}
+	name, _ := settings["agent"].(string)
+	return strings.TrimSpace(name)
+}
+
+// applyAgentToSettings applies the profile and settings of the named agent
+// to a local settings map.
+func applyAgentToSettings(settings map[string]any, config llmtypes.Config, name string) error {
+	agent, ok := config.Agents[name]
+	if !ok {
+		return errors.Errorf("agent '%s' not found", name)
+	}
+	if agent.Profile != "" {
+		chain, err := llmtypes.ProfileChain(config.Profiles, agent.Profile)
+		if err != nil {
+			return errors.Wrapf(err, "failed to apply the profile of agent '%s'", name)
+		}
+		for _, profile := range chain {
+			applyProfileToSettings(settings, profile)
+		}
+	}
+	mergeSettings(settings, agent.Settings())
+	settings["agent"] = name
+	return nil
+}
+
 // applyProfileToSettings applies profile settings to a local settings map.
 func applyProfileToSettings(settings map[string]any, profile llmtypes.ProfileConfig) {
-	mergeSettings(settings, map[string]any(profile))
+	mergeSettings(settings, profile.Settings())
+}
+
+// modelFeaturesFromSettings decodes model_features, which maps model IDs to
+// their features.
+func modelFeaturesFromSettings(raw any) (llmtypes.CustomFeatures, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	var features llmtypes.CustomFeatures
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           &features,
+		WeaklyTypedInput: true,
+		ErrorUnused:      true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create model_features decoder")
+	}
+	if err := decoder.Decode(raw); err != nil {
+		return nil, errors.Wrap(err, "invalid model_features")
+	}
+	return features, nil
 }
 
 func loadConfigFromSettings(settings map[string]any) (llmtypes.Config, error) {
@@ -189,7 +323,7 @@ func loadConfigFromSettings(settings map[string]any) (llmtypes.Config, error) {
 	}
 
 	// Use viper's automatic unmarshaling with mapstructure tags
-	if err := v.Unmarshal(&config); err != nil {
+	if err := v.Unmarshal(&config, viper.DecodeHook(configDecodeHook())); err != nil {
 		return config, errors.Wrap(err, "failed to unmarshal configuration")
 	}
 	if config.OpenAI != nil {
@@ -206,6 +340,92 @@ func loadConfigFromSettings(settings map[string]any) (llmtypes.Config, error) {
 	if config.Bash.Timeout < llmtypes.MinBashTimeout {
 		return config, errors.Errorf("bash.timeout must be at least %s", llmtypes.MinBashTimeout)
 	}
+	if config.Todos != nil {
+		switch todos.Enforcement(config.Todos.Enforcement) {
+		case "", todos.EnforcementOff, todos.EnforcementRemind, todos.EnforcementStrict:
+		default:
+			return config, errors.Errorf("todos.enforcement must be one of off, remind, or strict, got %q", config.Todos.Enforcement)
+		}
+	}
+
+	if config.ToolApproval != nil {
+		if err := toolapproval.ValidateMode(config.ToolApproval.Mode); err != nil {
+			return config, errors.Wrap(err, "invalid tool_approval.mode")
+		}
+		for _, risk := range config.ToolApproval.Risks {
+			if err := toolapproval.ValidateRisk(risk); err != nil {
+				return config, errors.Wrap(err, "invalid tool_approval.risks")
+			}
+		}
+	}
+
+	if err := secrets.ValidateEnv(config.ToolEnvMap()); err != nil {
+		return config, errors.Wrap(err, "invalid tool_env")
+	}
+
+	if config.ToolConcurrency != nil {
+		for class, limit := range config.ToolConcurrency.Limits {
+			if limit < 0 {
+				return config, errors.Errorf("tool_concurrency.limits.%s must not be negative, got %d", class, limit)
+			}
+		}
+		for tool, class := range config.ToolConcurrency.Classes {
+			if strings.TrimSpace(class) == "" {
+				return config, errors.Errorf("tool_concurrency.classes.%s must name a class", tool)
+			}
+		}
+		if config.ToolConcurrency.MaxConcurrent < 0 {
+			return config, errors.Errorf("tool_concurrency.max_concurrent must not be negative, got %d", config.ToolConcurrency.MaxConcurrent)
+		}
+	}
+
+	if config.ToolLimits != nil {
+		if config.ToolLimits.Timeout < 0 {
+			return config, errors.New("tool_limits.timeout must not be negative")
+		}
+		for tool, limit := range config.ToolLimits.Tools {
+			if limit.Timeout < 0 {
+				return config, errors.Errorf("tool_limits.tools.%s.timeout must not be negative", tool)
+			}
+		}
+	}
+
+	if config.ToolCache != nil {
+		if config.ToolCache.TTL < 0 {
+			return config, errors.New("tool_cache.ttl must not be negative")
+		}
+		if config.ToolCache.MaxBytes < 0 {
+			return config, errors.Errorf("tool_cache.max_bytes must not be negative, got %d", config.ToolCache.MaxBytes)
+		}
+	}
+
+	if config.Quota != nil {
+		switch quota.Action(config.Quota.Action) {
+		case "", quota.ActionWarn, quota.ActionWeakModel, quota.ActionPause:
+		default:
+			return config, errors.Errorf("quota.action must be one of warn, weak_model, or pause, got %q", config.Quota.Action)
+		}
+		if config.Quota.WarnThreshold < 0 || config.Quota.WarnThreshold > 1 {
+			return config, errors.Errorf("quota.warn_threshold must be between 0 and 1, got %g", config.Quota.WarnThreshold)
+		}
+		if config.Quota.ActionThreshold < 0 || config.Quota.ActionThreshold > 1 {
+			return config, errors.Errorf("quota.action_threshold must be between 0 and 1, got %g", config.Quota.ActionThreshold)
+		}
+		if config.Quota.MaxPause < 0 {
+			return config, errors.New("quota.max_pause must not be negative")
+		}
+	}
+
+	if config.OpenAI != nil {
+		if err := validateAPIKeys("openai.api_keys", config.OpenAI.APIKeys); err != nil {
+			return config, err
+		}
+	}
+	if config.Anthropic != nil {
+		if err := validateAPIKeys("anthropic.api_keys", config.Anthropic.APIKeys); err != nil {
+			return config, err
+		}
+	}
 
 	// Set default anthropic_api_access if empty
 	if config.AnthropicAPIAccess == "" {
@@ -225,6 +445,29 @@ func loadConfigFromSettings(settings map[string]any) (llmtypes.Config, error) {
 	if err := validateCompactRatio(config.CompactRatio); err != nil {
 		return config, err
 	}
+	if _, ok := settings["compact_reinject_tokens"]; !ok {
+		config.CompactReinjectTokens = llmtypes.DefaultCompactReinjectTokens
+	}
+	if config.CompactReinjectTokens < 0 {
+		return config, errors.New("compact_reinject_tokens must not be negative")
+	}
+	modelFeatures, err := modelFeaturesFromSettings(settings["model_features"])
+	if err != nil {
+		return config, err
+	}
+	config.ModelFeatures = modelFeatures
+	for model, features := range config.ModelFeatures {
+		if features.MaxOutputTokens < 0 {
+			return config, errors.Errorf("model_features.%s.max_output_tokens must not be negative, got %d", model, features.MaxOutputTokens)
+		}
+	}
+	switch config.CompactStrategy {
+	case "":
+		config.CompactStrategy = llmtypes.CompactStrategyAuto
+	case llmtypes.CompactStrategyAuto, llmtypes.CompactStrategyCompact:
+	default:
+		return config, errors.Errorf("compact_strategy must be one of auto or compact, got %q", config.CompactStrategy)
+	}
 
 	// Apply retry defaults if not set
 	if config.Retry.Attempts == 0 {
@@ -243,6 +486,26 @@ func validateConversationSummaryMode(mode llmtypes.ConversationSummaryMode) erro
 	}
 }
 
+func validateAPIKeys(field string, keys []llmtypes.APIKeyConfig) error {
+	names := make(map[string]bool, len(keys))
+	for i, key := range keys {
+		if strings.TrimSpace(key.EnvVar) == "" {
+			return errors.Errorf("%s[%d].env_var is required", field, i)
+		}
+		if key.Weight < 0 {
+			return errors.Errorf("%s[%d].weight must not be negative", field, i)
+		}
+		if key.RequestsPerMinute < 0 {
+			return errors.Errorf("%s[%d].requests_per_minute must not be negative", field, i)
+		}
+		if names[key.DisplayName()] {
+			return errors.Errorf("%s has more than one key named %q", field, key.DisplayName())
+		}
+		names[key.DisplayName()] = true
+	}
+	return nil
+}
+
 func validateCompactRatio(ratio float64) error {
 	if ratio <= 0.0 || ratio > 1.0 {
 		return fmt.Errorf("compact_ratio must be greater than 0.0 and less than or equal to 1.0")
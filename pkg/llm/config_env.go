@@ -0,0 +1,46 @@
+package llm
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/jingkaihe/kodelet/pkg/extensions"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix is the prefix of the environment variables that configure Kodelet.
+const EnvPrefix = "KODELET"
+
+// EnvVarName returns the environment variable that sets the dotted config
+// key, e.g. KODELET_OPENAI_BASE_URL for openai.base_url.
+func EnvVarName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// ConfigKeys returns the dotted key of every setting loaded into
+// llmtypes.Config and the extension runtime configuration. Maps and lists
+// are single keys whose environment value is JSON.
+func ConfigKeys() []string {
+	keys := llmtypes.SettingKeys(reflect.TypeOf(llmtypes.Config{}), "")
+	return append(keys, llmtypes.SettingKeys(reflect.TypeOf(extensions.Config{}), "extensions.")...)
+}
+
+// BindConfigEnv makes v aware of every config key so that settings given
+// only through KODELET_* environment variables, including nested ones, are
+// part of v.AllSettings. v must already use EnvPrefix and replace "." with
+// "_" in environment keys.
+func BindConfigEnv(v *viper.Viper) {
+	for _, key := range ConfigKeys() {
+		_ = v.BindEnv(key)
+	}
+}
+
+func configDecodeHook() mapstructure.DecodeHookFunc {
+	return mapstructure.ComposeDecodeHookFunc(
+		llmtypes.JSONStringHook,
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+	)
+}
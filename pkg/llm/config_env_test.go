@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEnvViper() *viper.Viper {
+	v := viper.New()
+	v.SetEnvPrefix(EnvPrefix)
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	BindConfigEnv(v)
+	return v
+}
+
+func TestConfigKeysMapToDistinctEnvVars(t *testing.T) {
+	keys := ConfigKeys()
+	for _, key := range []string{
+		"model",
+		"openai.base_url",
+		"openai.models.reasoning",
+		"anthropic.api_keys",
+		"retry.attempts",
+		"bash.timeout",
+		"tool_concurrency.limits",
+		"airgap.allowed_hosts",
+		"extensions.max_event_timeout",
+	} {
+		assert.Contains(t, keys, key)
+	}
+	assert.NotContains(t, keys, "extensions", "runtime-only fields are skipped")
+
+	seen := make(map[string]string, len(keys))
+	for _, key := range keys {
+		name := EnvVarName(key)
+		if other, ok := seen[name]; ok {
+			t.Errorf("%s and %s both map to %s", other, key, name)
+		}
+		seen[name] = key
+	}
+	assert.Equal(t, "KODELET_OPENAI_MODELS_NON_REASONING", EnvVarName("openai.models.non_reasoning"))
+}
+
+func TestBindConfigEnvLoadsEnvOnlyConfig(t *testing.T) {
+	t.Setenv("KODELET_PROVIDER", "openai")
+	t.Setenv("KODELET_MODEL", "gpt-test")
+	t.Setenv("KODELET_MAX_TOKENS", "4096")
+	t.Setenv("KODELET_ALLOWED_TOOLS", "bash,file_read")
+	t.Setenv("KODELET_RETRY_ATTEMPTS", "7")
+	t.Setenv("KODELET_BASH_TIMEOUT", "5m")
+	t.Setenv("KODELET_OPENAI_BASE_URL", "http://llm.internal/v1")
+	t.Setenv("KODELET_OPENAI_ENABLE_SEARCH", "false")
+	t.Setenv("KODELET_OPENAI_MODELS_REASONING", `["o-internal"]`)
+	t.Setenv("KODELET_OPENAI_API_KEYS", `[{"name":"team-a","env_var":"TEAM_A_KEY","weight":2}]`)
+	t.Setenv("KODELET_TOOL_CONCURRENCY_LIMITS", `{"read":8}`)
+	t.Setenv("KODELET_AIRGAP_ALLOWED_HOSTS", "llm.corp,registry.corp")
+	t.Setenv("KODELET_QUOTA_MAX_PAUSE", "10m")
+	t.Setenv("KODELET_PROFILES", `{"fast":{"model":"gpt-fast"}}`)
+
+	config, err := loadConfigFromSettings(newEnvViper().AllSettings())
+	require.NoError(t, err)
+
+	assert.Equal(t, "openai", config.Provider)
+	assert.Equal(t, "gpt-test", config.Model)
+	assert.Equal(t, 4096, config.MaxTokens)
+	assert.Equal(t, []string{"bash", "file_read"}, config.AllowedTools)
+	assert.Equal(t, 7, config.Retry.Attempts)
+	require.NotNil(t, config.Bash)
+	assert.Equal(t, 5*time.Minute, config.Bash.Timeout)
+	require.NotNil(t, config.OpenAI)
+	assert.Equal(t, "http://llm.internal/v1", config.OpenAI.BaseURL)
+	require.NotNil(t, config.OpenAI.EnableSearch)
+	assert.False(t, *config.OpenAI.EnableSearch)
+	require.NotNil(t, config.OpenAI.Models)
+	assert.Equal(t, []string{"o-internal"}, config.OpenAI.Models.Reasoning)
+	assert.Equal(t, []llmtypes.APIKeyConfig{{Name: "team-a", EnvVar: "TEAM_A_KEY", Weight: 2}}, config.OpenAI.APIKeys)
+	require.NotNil(t, config.ToolConcurrency)
+	assert.Equal(t, map[string]int{"read": 8}, config.ToolConcurrency.Limits)
+	require.NotNil(t, config.Airgap)
+	assert.Equal(t, []string{"llm.corp", "registry.corp"}, config.Airgap.AllowedHosts)
+	require.NotNil(t, config.Quota)
+	assert.Equal(t, 10*time.Minute, config.Quota.MaxPause)
+	assert.Equal(t, "gpt-fast", config.Profiles["fast"]["model"])
+}
+
+func TestBindConfigEnvRejectsInvalidJSON(t *testing.T) {
+	t.Setenv("KODELET_TOOL_CONCURRENCY_LIMITS", `{"read":`)
+
+	_, err := loadConfigFromSettings(newEnvViper().AllSettings())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid JSON value")
+}
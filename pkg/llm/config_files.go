@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ConfigFileExtensions are the config file formats Kodelet reads, in order of
+// preference when more than one exists in the same directory.
+var ConfigFileExtensions = []string{".yaml", ".yml", ".json"}
+
+const (
+	globalConfigName = "config"
+	repoConfigName   = "kodelet-config"
+)
+
+// FindConfigFile returns the path of the first dir/name.<ext> file that
+// exists, trying ConfigFileExtensions in order, or "" when there is none.
+func FindConfigFile(dir, name string) string {
+	for _, ext := range ConfigFileExtensions {
+		path := filepath.Join(dir, name+ext)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// GlobalConfigFile returns the global config file in ~/.kodelet, which is
+// config.yaml when no config file exists yet.
+func GlobalConfigFile() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(homeDir, ".kodelet")
+	if path := FindConfigFile(dir, globalConfigName); path != "" {
+		return path, nil
+	}
+	return filepath.Join(dir, globalConfigName+".yaml"), nil
+}
+
+// RepoConfigFile returns the repo-level config file in the current
+// directory, which is kodelet-config.yaml when no config file exists yet.
+func RepoConfigFile() string {
+	path := FindConfigFile(".", repoConfigName)
+	if path == "" {
+		path = repoConfigName + ".yaml"
+	}
+	return "." + string(filepath.Separator) + path
+}
@@ -75,6 +75,173 @@ func TestGetConfigFromViper_InvalidCompactRatio(t *testing.T) {
 	}
 }
 
+func TestGetConfigFromViper_CompactStrategy(t *testing.T) {
+	viper.Reset()
+	config, err := GetConfigFromViper()
+	require.NoError(t, err)
+	assert.Equal(t, llmtypes.CompactStrategyAuto, config.CompactStrategy)
+
+	viper.Set("compact_strategy", "compact")
+	config, err = GetConfigFromViper()
+	require.NoError(t, err)
+	assert.Equal(t, llmtypes.CompactStrategyCompact, config.CompactStrategy)
+
+	viper.Set("compact_strategy", "prune")
+	_, err = GetConfigFromViper()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "compact_strategy must be one of auto or compact")
+}
+
+func TestGetConfigFromViper_APIKeys(t *testing.T) {
+	viper.Reset()
+	viper.Set("openai.api_keys", []map[string]any{
+		{"name": "team-a", "env_var": "TEAM_A_KEY", "weight": 3, "requests_per_minute": 60},
+		{"env_var": "TEAM_B_KEY"},
+	})
+	config, err := GetConfigFromViper()
+	require.NoError(t, err)
+	require.NotNil(t, config.OpenAI)
+	require.Len(t, config.OpenAI.APIKeys, 2)
+	assert.Equal(t, llmtypes.APIKeyConfig{Name: "team-a", EnvVar: "TEAM_A_KEY", Weight: 3, RequestsPerMinute: 60}, config.OpenAI.APIKeys[0])
+	assert.Equal(t, "TEAM_B_KEY", config.OpenAI.APIKeys[1].DisplayName())
+
+	viper.Set("anthropic.api_keys", []map[string]any{{"name": "dup", "env_var": "A"}, {"name": "dup", "env_var": "B"}})
+	_, err = GetConfigFromViper()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `anthropic.api_keys has more than one key named "dup"`)
+
+	viper.Set("anthropic.api_keys", []map[string]any{{"name": "missing"}})
+	_, err = GetConfigFromViper()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "anthropic.api_keys[0].env_var is required")
+	viper.Reset()
+}
+
+func TestGetConfigFromViper_ToolConcurrency(t *testing.T) {
+	viper.Reset()
+	viper.Set("tool_concurrency", map[string]any{
+		"limits":  map[string]any{"read": 8},
+		"classes": map[string]any{"deploy": "exclusive"},
+	})
+	config, err := GetConfigFromViper()
+	require.NoError(t, err)
+	require.NotNil(t, config.ToolConcurrency)
+	assert.Equal(t, map[string]int{"read": 8}, config.ToolConcurrency.Limits)
+	assert.Equal(t, map[string]string{"deploy": "exclusive"}, config.ToolConcurrency.Classes)
+
+	viper.Set("tool_concurrency.limits", map[string]any{"read": -1})
+	_, err = GetConfigFromViper()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tool_concurrency.limits.read must not be negative")
+
+	viper.Set("tool_concurrency.limits", map[string]any{"read": 8})
+	viper.Set("tool_concurrency.max_concurrent", -1)
+	_, err = GetConfigFromViper()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tool_concurrency.max_concurrent must not be negative")
+	viper.Reset()
+}
+
+func TestGetConfigFromViper_ToolLimits(t *testing.T) {
+	viper.Reset()
+	viper.Set("tool_limits", map[string]any{
+		"timeout":          "2m",
+		"max_output_bytes": 50000,
+		"tools": map[string]any{
+			"grep_tool": map[string]any{"max_output_bytes": 20000},
+			"web_crawl": map[string]any{"timeout": "5m"},
+			"bash":      map[string]any{"stall_after": "-1s"},
+		},
+	})
+	config, err := GetConfigFromViper()
+	require.NoError(t, err)
+	require.NotNil(t, config.ToolLimits)
+	assert.Equal(t, llmtypes.ToolLimit{Timeout: 2 * time.Minute, MaxOutputBytes: 20000}, config.ToolLimit("grep_tool"))
+	assert.Equal(t, llmtypes.ToolLimit{Timeout: 5 * time.Minute, MaxOutputBytes: 50000}, config.ToolLimit("web_crawl"))
+	assert.Equal(t, llmtypes.ToolLimit{Timeout: 2 * time.Minute, MaxOutputBytes: 50000}, config.ToolLimit("file_read"))
+	assert.Equal(t, llmtypes.DefaultToolStallAfter, config.ToolLimit("file_read").StallThreshold())
+	assert.Zero(t, config.ToolLimit("bash").StallThreshold(), "a negative stall_after turns the watchdog off")
+
+	viper.Set("tool_limits.timeout", "-1s")
+	_, err = GetConfigFromViper()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tool_limits.timeout must not be negative")
+	viper.Reset()
+}
+
+func TestGetConfigFromViper_ToolCache(t *testing.T) {
+	viper.Reset()
+	viper.Set("tool_cache", map[string]any{
+		"enabled": true,
+		"ttl":     "30m",
+		"tools":   []string{"grep_tool", "mcp_search_docs"},
+	})
+	config, err := GetConfigFromViper()
+	require.NoError(t, err)
+	require.NotNil(t, config.ToolCache)
+	assert.True(t, config.ToolCache.Enabled)
+	assert.Equal(t, 30*time.Minute, config.ToolCache.CacheTTL())
+	assert.Equal(t, llmtypes.DefaultToolCacheMaxBytes, config.ToolCache.CacheMaxBytes())
+	assert.True(t, config.ToolCache.Caches("mcp_search_docs"))
+	assert.False(t, config.ToolCache.Caches("web_fetch"), "tools replaces the default list")
+
+	viper.Set("tool_cache.max_bytes", -1)
+	_, err = GetConfigFromViper()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tool_cache.max_bytes must not be negative")
+	viper.Reset()
+}
+
+func TestGetConfigFromViper_ToolApproval(t *testing.T) {
+	viper.Reset()
+	viper.Set("tool_approval", map[string]any{
+		"enabled": true,
+		"risks":   []string{"bash:write", "web_fetch:new_domain"},
+	})
+	config, err := GetConfigFromViper()
+	require.NoError(t, err)
+	require.NotNil(t, config.ToolApproval)
+	assert.True(t, config.ToolApproval.Enabled)
+	assert.Equal(t, []string{"bash:write", "web_fetch:new_domain"}, config.ToolApproval.Risks)
+
+	viper.Set("tool_approval.risks", []string{"file_edit:write"})
+	_, err = GetConfigFromViper()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid tool_approval.risks")
+
+	viper.Set("tool_approval.risks", []string{"bash:write"})
+	viper.Set("tool_approval.mode", "defer")
+	config, err = GetConfigFromViper()
+	require.NoError(t, err)
+	assert.Equal(t, "defer", config.ToolApproval.Mode)
+
+	viper.Set("tool_approval.mode", "batch")
+	_, err = GetConfigFromViper()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid tool_approval.mode")
+	viper.Reset()
+}
+
+func TestGetConfigFromViper_ToolEnv(t *testing.T) {
+	viper.Reset()
+	viper.Set("tool_env", []map[string]any{
+		{"name": "GITHUB_TOKEN", "value": "secret://1password/Engineering/GitHub/token"},
+		{"name": "REGION", "value": "eu-west-1"},
+	})
+	config, err := GetConfigFromViper()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"GITHUB_TOKEN": "secret://1password/Engineering/GitHub/token",
+		"REGION":       "eu-west-1",
+	}, config.ToolEnvMap())
+
+	viper.Set("tool_env", []map[string]any{{"name": "TOKEN", "value": "secret://keychain/token"}})
+	_, err = GetConfigFromViper()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid tool_env")
+	viper.Reset()
+}
+
 func TestGetConfigFromViper_BashTimeout(t *testing.T) {
 	viper.Reset()
 	viper.Set("bash.timeout", "5m")
@@ -483,6 +650,128 @@ func TestGetConfigFromViperWithProfile_ProfileNotFound(t *testing.T) {
 	assert.Contains(t, err.Error(), "profile 'missing' not found")
 }
 
+func TestGetConfigFromViperWithProfile_AppliesInheritedProfiles(t *testing.T) {
+	originalConfig := viper.AllSettings()
+	defer func() {
+		viper.Reset()
+		for key, value := range originalConfig {
+			viper.Set(key, value)
+		}
+	}()
+
+	viper.Reset()
+	viper.Set("provider", "anthropic")
+	viper.Set("model", "base-model")
+	viper.Set("profiles", map[string]any{
+		"ops": map[string]any{
+			"model":         "ops-model",
+			"allowed_tools": []string{"bash", "file_read"},
+			"todos": map[string]any{
+				"enforcement": "remind",
+			},
+		},
+		"prod-ops": map[string]any{
+			"extends":    "ops",
+			"weak_model": "prod-weak-model",
+			"todos": map[string]any{
+				"max_stale_turns": 3,
+			},
+		},
+	})
+
+	config, err := GetConfigFromViperWithProfile("prod-ops")
+	require.NoError(t, err)
+	assert.Equal(t, "prod-ops", config.Profile)
+	assert.Equal(t, "ops-model", config.Model)
+	assert.Equal(t, "prod-weak-model", config.WeakModel)
+	assert.Equal(t, []string{"bash", "file_read"}, config.AllowedTools)
+	require.NotNil(t, config.Todos)
+	assert.Equal(t, "remind", config.Todos.Enforcement)
+	assert.Equal(t, 3, config.Todos.MaxStaleTurns)
+}
+
+func TestGetConfigFromViperWithProfile_InvalidInheritance(t *testing.T) {
+	originalConfig := viper.AllSettings()
+	defer func() {
+		viper.Reset()
+		for key, value := range originalConfig {
+			viper.Set(key, value)
+		}
+	}()
+
+	viper.Reset()
+	viper.Set("profiles", map[string]any{
+		"a":      map[string]any{"extends": "b"},
+		"b":      map[string]any{"extends": "a"},
+		"orphan": map[string]any{"extends": "missing"},
+	})
+
+	_, err := GetConfigFromViperWithProfile("a")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "profile inheritance cycle: a -> b -> a")
+
+	_, err = GetConfigFromViperWithProfile("orphan")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "profile 'orphan' extends unknown profile 'missing'")
+}
+
+func TestGetConfigFromViperWithCmd_AppliesAgent(t *testing.T) {
+	originalConfig := viper.AllSettings()
+	defer func() {
+		viper.Reset()
+		for key, value := range originalConfig {
+			viper.Set(key, value)
+		}
+	}()
+
+	viper.Reset()
+	viper.Set("provider", "anthropic")
+	viper.Set("model", "base-model")
+	viper.Set("allowed_tools", []string{"bash", "file_read", "file_edit"})
+	viper.Set("profiles", map[string]any{
+		"fast": map[string]any{"weak_model": "fast-weak-model", "max_tokens": 4096},
+	})
+	viper.Set("agents", map[string]any{
+		"reviewer": map[string]any{
+			"description":      "Reviews diffs",
+			"profile":          "fast",
+			"model":            "review-model",
+			"allowed_tools":    []string{"file_read", "grep_tool"},
+			"tool_mode":        "full",
+			"allowed_commands": []string{"git diff *"},
+			"instructions":     "Do not edit files.",
+		},
+	})
+
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().String("agent", "", "Agent")
+	cmd.Flags().Int("max-tokens", 0, "Max tokens")
+	require.NoError(t, cmd.Flags().Set("agent", "reviewer"))
+	require.NoError(t, cmd.Flags().Set("max-tokens", "2048"))
+
+	config, err := GetConfigFromViperWithCmd(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, "reviewer", config.Agent)
+	assert.Equal(t, "review-model", config.Model)
+	assert.Equal(t, "fast-weak-model", config.WeakModel, "the agent's profile is applied")
+	assert.Equal(t, 2048, config.MaxTokens, "explicit flags win over the agent")
+	assert.Equal(t, []string{"file_read", "grep_tool"}, config.AllowedTools)
+	assert.Equal(t, llmtypes.ToolModeFull, config.ToolMode, "the agent's tool mode keeps its tools as written")
+	assert.Equal(t, []string{"git diff *"}, config.AllowedCommands)
+	assert.Empty(t, config.Profile, "the agent's profile does not become the active profile")
+
+	config, err = GetConfigFromViperWithCmd(nil)
+	require.NoError(t, err)
+	assert.Empty(t, config.Agent)
+	assert.Equal(t, "base-model", config.Model)
+	assert.Contains(t, config.Agents, "reviewer")
+
+	require.NoError(t, cmd.Flags().Set("agent", "missing"))
+	_, err = GetConfigFromViperWithCmd(cmd)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "agent 'missing' not found")
+}
+
 func TestGetConfigFromViperWithAliases(t *testing.T) {
 	// Save original viper state
 	originalConfig := viper.AllSettings()
@@ -954,3 +1243,83 @@ func TestGetConfigFromViperOpenAIFullConfig(t *testing.T) {
 	assert.Equal(t, 0.00006, o1Pricing.Output)
 	assert.Equal(t, 32768, o1Pricing.ContextWindow)
 }
+
+func TestGetConfigFromViper_ModelFeatures(t *testing.T) {
+	viper.Reset()
+	viper.Set("model_features", map[string]any{
+		"gpt-4.1":   map[string]any{"max_output_tokens": 16000},
+		"local-llm": map[string]any{"supports_tools": false, "supports_vision": false},
+	})
+	config, err := GetConfigFromViper()
+	require.NoError(t, err)
+	features := llmtypes.ResolveModelFeatures(config, nil, "gpt-4.1")
+	assert.Equal(t, 16000, features.MaxOutputTokens)
+	features = llmtypes.ResolveModelFeatures(config, nil, "local-llm")
+	assert.False(t, features.Tools())
+	assert.False(t, features.Vision())
+	assert.True(t, features.ParallelToolCalls())
+
+	viper.Set("model_features", map[string]any{
+		"local-llm": map[string]any{"max_output_tokens": -1},
+	})
+	_, err = GetConfigFromViper()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "model_features.local-llm.max_output_tokens must not be negative")
+	viper.Reset()
+}
+
+func TestGetConfigFromViperForModel(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("provider", "anthropic")
+	viper.Set("model", "claude-sonnet-4-6")
+	viper.Set("max_tokens", 4096)
+	viper.Set("experiment", map[string]any{"name": "try-gpt", "percentage": 100, "model": "gpt-5.5"})
+	viper.Set("profiles", map[string]any{
+		"work": map[string]any{"max_tokens": 16000},
+	})
+
+	config, err := GetConfigFromViperForModel("", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "claude-sonnet-4-6", config.Model, "experiments are not applied")
+	assert.Nil(t, config.ExperimentAssignment)
+
+	config, err = GetConfigFromViperForModel("work", "gpt-5.5", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-5.5", config.Model)
+	assert.Equal(t, "openai", config.Provider, "the provider follows the model")
+	assert.Equal(t, 16000, config.MaxTokens)
+	assert.Equal(t, "work", config.Profile)
+
+	_, err = GetConfigFromViperForModel("missing", "", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "profile 'missing' not found")
+}
+
+func TestSwitchModel(t *testing.T) {
+	config := llmtypes.Config{
+		Provider: "openai",
+		Model:    "gpt-5.5",
+		Aliases:  map[string]string{"sonnet": "claude-sonnet-4-6"},
+	}
+	require.NoError(t, SwitchModel(&config, " sonnet "))
+	assert.Equal(t, "claude-sonnet-4-6", config.Model)
+	assert.Equal(t, "anthropic", config.Provider, "the provider follows the model")
+
+	config = llmtypes.Config{
+		Provider: "openai",
+		Model:    "gpt-5.5",
+		OpenAI:   &llmtypes.OpenAIConfig{BaseURL: "http://localhost:4000/v1"},
+	}
+	require.NoError(t, SwitchModel(&config, "claude-sonnet-4-6"))
+	assert.Equal(t, "claude-sonnet-4-6", config.Model)
+	assert.Equal(t, "openai", config.Provider, "a custom endpoint keeps its provider")
+
+	config = llmtypes.Config{Provider: "openai", Model: "gpt-5.5"}
+	err := SwitchModel(&config, "gemini-2.5-pro")
+	require.Error(t, err)
+	assert.Equal(t, "gpt-5.5", config.Model, "the config is unchanged on error")
+
+	require.NoError(t, SwitchModel(&config, ""))
+	assert.Equal(t, "gpt-5.5", config.Model)
+}
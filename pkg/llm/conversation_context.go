@@ -0,0 +1,36 @@
+package llm
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/pkg/errors"
+)
+
+// LoadConversationContext loads a saved conversation and renders its summary
+// and recent messages as a note to inject into another conversation. The
+// saved conversation is only read.
+func LoadConversationContext(ctx context.Context, conversationID string, opts conversations.ConversationContextOptions) (conversations.ConversationContext, error) {
+	conversationID = strings.TrimSpace(conversationID)
+	if conversationID == "" {
+		return conversations.ConversationContext{}, errors.New("conversation ID is required")
+	}
+
+	store, err := conversations.GetConversationStore(ctx)
+	if err != nil {
+		return conversations.ConversationContext{}, errors.Wrap(err, "failed to open conversation store")
+	}
+	defer store.Close()
+
+	record, err := store.Load(ctx, conversationID)
+	if err != nil {
+		return conversations.ConversationContext{}, errors.Wrapf(err, "failed to load conversation %s", conversationID)
+	}
+	messages, err := ExtractConversationEntries(record.Provider, record.RawMessages, record.Metadata, record.ToolResults)
+	if err != nil {
+		return conversations.ConversationContext{}, errors.Wrapf(err, "failed to read messages of conversation %s", conversationID)
+	}
+
+	return conversations.RenderConversationContext(record, messages, opts)
+}
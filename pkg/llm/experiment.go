@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"math/rand/v2"
+	"strings"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// experimentOverrideFlags opt a run out of the configured experiment, since
+// the user asked for a specific model.
+var experimentOverrideFlags = []string{"provider", "model", "weak-model"}
+
+// assignExperiment routes the run to an arm of the configured experiment and
+// applies the treatment arm's model. roll is a uniform value in [0, 100).
+func assignExperiment(config *llmtypes.Config, cmd *cobra.Command, roll float64) error {
+	experiment := config.Experiment
+	if experiment == nil || (strings.TrimSpace(experiment.Name) == "" && experiment.Percentage == 0) {
+		return nil
+	}
+	if err := validateExperiment(experiment); err != nil {
+		return err
+	}
+	if cmd != nil {
+		for _, flag := range experimentOverrideFlags {
+			if cmd.Flags().Changed(flag) {
+				return nil
+			}
+		}
+	}
+
+	assignment := &llmtypes.ExperimentAssignment{
+		Name: strings.TrimSpace(experiment.Name),
+		Arm:  llmtypes.ExperimentArmControl,
+	}
+	if roll < experiment.Percentage {
+		assignment.Arm = llmtypes.ExperimentArmTreatment
+		if model := strings.TrimSpace(experiment.Model); model != "" {
+			config.Model = model
+			config.Provider = strings.TrimSpace(experiment.Provider)
+		} else if provider := strings.TrimSpace(experiment.Provider); provider != "" {
+			config.Provider = provider
+		}
+		if weakModel := strings.TrimSpace(experiment.WeakModel); weakModel != "" {
+			config.WeakModel = weakModel
+		}
+	}
+	config.ExperimentAssignment = assignment
+	return nil
+}
+
+func validateExperiment(experiment *llmtypes.ExperimentConfig) error {
+	if strings.TrimSpace(experiment.Name) == "" {
+		return errors.New("experiment.name is required")
+	}
+	if experiment.Percentage < 0 || experiment.Percentage > 100 {
+		return errors.Errorf("experiment.percentage must be between 0 and 100, got %g", experiment.Percentage)
+	}
+	if strings.TrimSpace(experiment.Model) == "" && strings.TrimSpace(experiment.Provider) == "" && strings.TrimSpace(experiment.WeakModel) == "" {
+		return errors.New("experiment must set the treatment arm's provider, model or weak_model")
+	}
+	return nil
+}
+
+func experimentRoll() float64 {
+	return rand.Float64() * 100
+}
@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"testing"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssignExperiment(t *testing.T) {
+	newConfig := func() *llmtypes.Config {
+		return &llmtypes.Config{
+			Provider:  "anthropic",
+			Model:     "claude-sonnet-4-6",
+			WeakModel: "claude-haiku-4-5",
+			Experiment: &llmtypes.ExperimentConfig{
+				Name:       "gpt-trial",
+				Percentage: 25,
+				Provider:   "openai",
+				Model:      "gpt-5.5",
+				WeakModel:  "gpt-5.4-mini",
+			},
+		}
+	}
+
+	t.Run("control arm keeps the configured model", func(t *testing.T) {
+		config := newConfig()
+		require.NoError(t, assignExperiment(config, nil, 25))
+
+		assert.Equal(t, "claude-sonnet-4-6", config.Model)
+		assert.Equal(t, "anthropic", config.Provider)
+		assert.Equal(t, &llmtypes.ExperimentAssignment{Name: "gpt-trial", Arm: llmtypes.ExperimentArmControl}, config.ExperimentAssignment)
+	})
+
+	t.Run("treatment arm switches model and provider", func(t *testing.T) {
+		config := newConfig()
+		require.NoError(t, assignExperiment(config, nil, 24.9))
+
+		assert.Equal(t, "gpt-5.5", config.Model)
+		assert.Equal(t, "openai", config.Provider)
+		assert.Equal(t, "gpt-5.4-mini", config.WeakModel)
+		assert.Equal(t, llmtypes.ExperimentArmTreatment, config.ExperimentAssignment.Arm)
+	})
+
+	t.Run("explicit model flag opts out", func(t *testing.T) {
+		config := newConfig()
+		cmd := &cobra.Command{Use: "test"}
+		cmd.Flags().String("model", "", "Model")
+		require.NoError(t, cmd.Flags().Set("model", "claude-opus-4-6"))
+
+		require.NoError(t, assignExperiment(config, cmd, 0))
+
+		assert.Equal(t, "claude-sonnet-4-6", config.Model)
+		assert.Nil(t, config.ExperimentAssignment)
+	})
+
+	t.Run("no experiment", func(t *testing.T) {
+		config := &llmtypes.Config{Model: "claude-sonnet-4-6"}
+		require.NoError(t, assignExperiment(config, nil, 0))
+		assert.Nil(t, config.ExperimentAssignment)
+	})
+}
+
+func TestValidateExperiment(t *testing.T) {
+	tests := []struct {
+		name       string
+		experiment llmtypes.ExperimentConfig
+		wantErr    string
+	}{
+		{name: "missing name", experiment: llmtypes.ExperimentConfig{Percentage: 10, Model: "gpt-5.5"}, wantErr: "experiment.name is required"},
+		{name: "percentage out of range", experiment: llmtypes.ExperimentConfig{Name: "trial", Percentage: 120, Model: "gpt-5.5"}, wantErr: "between 0 and 100"},
+		{name: "no treatment", experiment: llmtypes.ExperimentConfig{Name: "trial", Percentage: 10}, wantErr: "treatment arm"},
+		{name: "valid", experiment: llmtypes.ExperimentConfig{Name: "trial", Percentage: 10, WeakModel: "gpt-5.4-mini"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateExperiment(&tt.experiment)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
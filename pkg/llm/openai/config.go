@@ -10,6 +10,7 @@ import (
 	"github.com/jingkaihe/kodelet/pkg/llm/openai/copilotdefaults"
 	codexpreset "github.com/jingkaihe/kodelet/pkg/llm/openai/preset/codex"
 	openaipreset "github.com/jingkaihe/kodelet/pkg/llm/openai/preset/openai"
+	"github.com/jingkaihe/kodelet/pkg/pricing"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 )
 
@@ -155,6 +156,26 @@ func loadCustomConfiguration(config llmtypes.Config) (*llmtypes.CustomModels, ll
 	return models, pricing
 }
 
+// PricingForModel returns the per-token pricing of model on the configured
+// platform, where openai.pricing takes precedence. It returns false for
+// models without a known price.
+func PricingForModel(config llmtypes.Config, model string) (llmtypes.ModelPricing, bool) {
+	_, pricing := loadCustomConfiguration(config)
+	modelPricing, ok := pricing[model]
+	return modelPricing, ok
+}
+
+// platformFeatures returns the model features known for a platform. Copilot
+// serves the OpenAI models under their OpenAI names.
+func platformFeatures(platformName string) llmtypes.CustomFeatures {
+	switch normalizePlatformName(platformName) {
+	case "openai", "copilot":
+		return openaipreset.Features
+	default:
+		return nil
+	}
+}
+
 func loadPlatformDefaultsForConfig(platformName string, config llmtypes.Config) (*llmtypes.CustomModels, llmtypes.CustomPricing) {
 	return loadPlatformDefaultsForServiceTier(platformName, normalizeServiceTier(config))
 }
@@ -202,12 +223,14 @@ func loadOpenAIPlatformDefaultsForServiceTier(serviceTier llmtypes.OpenAIService
 		NonReasoning: openaipreset.Models.NonReasoning,
 	}
 
+	platformPricing := pricing.ApplyPlatformPricing(openaipreset.PricingForServiceTier(serviceTier), "openai", serviceTier)
 	pricing := make(llmtypes.CustomPricing)
-	for model, openaiPricing := range openaipreset.PricingForServiceTier(serviceTier) {
+	for model, openaiPricing := range platformPricing {
 		pricing[model] = llmtypes.ModelPricing{
 			Input:                      openaiPricing.Input,
 			CachedInput:                openaiPricing.CachedInput,
 			CacheWriteInput:            openaiPricing.CacheWriteInput,
+			CacheWrite1hInput:          openaiPricing.CacheWrite1hInput,
 			Output:                     openaiPricing.Output,
 			LongContextInput:           openaiPricing.LongContextInput,
 			LongContextCachedInput:     openaiPricing.LongContextCachedInput,
@@ -231,12 +254,14 @@ func loadCodexPlatformDefaultsForServiceTier(serviceTier llmtypes.OpenAIServiceT
 		NonReasoning: codexpreset.Models.NonReasoning,
 	}
 
+	platformPricing := pricing.ApplyPlatformPricing(codexpreset.PricingForServiceTier(serviceTier), "codex", serviceTier)
 	pricing := make(llmtypes.CustomPricing)
-	for model, codexPricing := range codexpreset.PricingForServiceTier(serviceTier) {
+	for model, codexPricing := range platformPricing {
 		pricing[model] = llmtypes.ModelPricing{
 			Input:                      codexPricing.Input,
 			CachedInput:                codexPricing.CachedInput,
 			CacheWriteInput:            codexPricing.CacheWriteInput,
+			CacheWrite1hInput:          codexPricing.CacheWrite1hInput,
 			Output:                     codexPricing.Output,
 			LongContextInput:           codexPricing.LongContextInput,
 			LongContextCachedInput:     codexPricing.LongContextCachedInput,
@@ -284,6 +309,14 @@ func GetAPIKeyEnvVar(config llmtypes.Config) string {
 	return getPlatformAPIKeyEnvVar(resolvePlatformName(config))
 }
 
+// apiKeyPool returns the pooled API keys configured in openai.api_keys.
+func apiKeyPool(config llmtypes.Config) []llmtypes.APIKeyConfig {
+	if config.OpenAI == nil {
+		return nil
+	}
+	return config.OpenAI.APIKeys
+}
+
 // GetConfiguredBaseURL returns only explicit base URL overrides from environment or config.
 func GetConfiguredBaseURL(config llmtypes.Config) string {
 	if baseURL := os.Getenv("OPENAI_API_BASE"); baseURL != "" {
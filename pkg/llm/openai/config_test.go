@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/jingkaihe/kodelet/pkg/auth"
+	"github.com/jingkaihe/kodelet/pkg/pricing"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -353,6 +354,28 @@ func TestLoadOpenAIPlatformDefaultsUsesConfiguredServiceTierPricing(t *testing.T
 	assert.Equal(t, standardPricing["gpt-4.1"], priorityPricing["gpt-4.1"])
 }
 
+func TestLoadPlatformDefaultsAppliesPricingManifest(t *testing.T) {
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	pricing.Reset()
+	t.Cleanup(pricing.Reset)
+
+	path, err := pricing.DefaultManifestPath()
+	require.NoError(t, err)
+	require.NoError(t, pricing.SaveManifest(path, []byte(`{"version": 1, "models": [
+		{"provider": "openai", "model": "gpt-5.4", "periods": [{"effective_from": "2020-01-01T00:00:00Z", "input": 0.000001, "output": 0.000002}]},
+		{"provider": "openai", "model": "gpt-5.4", "service_tier": "priority", "periods": [{"effective_from": "2020-01-01T00:00:00Z", "input": 0.000003, "output": 0.000004}]},
+		{"provider": "codex", "model": "gpt-5.4", "periods": [{"effective_from": "2099-01-01T00:00:00Z", "input": 0.000009}]}]}`)))
+
+	_, standardPricing := loadOpenAIPlatformDefaults()
+	assert.Equal(t, 0.000001, standardPricing["gpt-5.4"].Input)
+
+	_, fastPricing := loadOpenAIPlatformDefaultsForServiceTier(llmtypes.OpenAIServiceTierFast)
+	assert.Equal(t, 0.000003, fastPricing["gpt-5.4"].Input)
+
+	_, codexPricing := loadCodexPlatformDefaults()
+	assert.NotEqual(t, 0.000009, codexPricing["gpt-5.4"].Input, "future prices are not applied yet")
+}
+
 func TestGetPlatformBaseURL(t *testing.T) {
 	tests := []struct {
 		platform string
@@ -6,6 +6,7 @@ import (
 	"context"
 	"encoding/json"
 
+	"github.com/jingkaihe/kodelet/pkg/conversations"
 	"github.com/jingkaihe/kodelet/pkg/llm/openai/responses"
 	"github.com/jingkaihe/kodelet/pkg/logger"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
@@ -38,12 +39,23 @@ func shouldUseResponsesAPI(config llmtypes.Config) bool {
 	return resolveAPIMode(config) == llmtypes.OpenAIAPIModeResponses
 }
 
+// ResolveAPIMode returns the API that threads created from config use.
+func ResolveAPIMode(config llmtypes.Config) llmtypes.OpenAIAPIMode {
+	return resolveAPIMode(config)
+}
+
 // ExtractResponsesMessages extracts messages from Responses API conversation data.
 // This is a wrapper around the responses package's ExtractMessages function.
 func ExtractResponsesMessages(rawMessages []byte, toolResults map[string]tooltypes.StructuredToolResult) ([]llmtypes.Message, error) {
 	return responses.ExtractMessages(rawMessages, toolResults)
 }
 
+// RedactResponsesToolResults redacts tool results in Responses API conversation data.
+// This is a wrapper around the responses package's RedactToolResults function.
+func RedactResponsesToolResults(rawMessages []byte, toolNames conversations.ToolRedactionSet) ([]byte, []string, error) {
+	return responses.RedactToolResults(rawMessages, toolNames)
+}
+
 // StreamResponsesMessages parses raw Responses API messages into streamable format.
 // This is a wrapper around the responses package's StreamMessages function.
 func StreamResponsesMessages(rawMessages []byte, toolResults map[string]tooltypes.StructuredToolResult) ([]responses.StreamableMessage, error) {
@@ -18,8 +18,11 @@ import (
 	"github.com/jingkaihe/kodelet/pkg/conversations"
 	"github.com/jingkaihe/kodelet/pkg/goals"
 	"github.com/jingkaihe/kodelet/pkg/llm/base"
+	"github.com/jingkaihe/kodelet/pkg/llm/chaos"
 	openaipreset "github.com/jingkaihe/kodelet/pkg/llm/openai/preset/openai"
+	"github.com/jingkaihe/kodelet/pkg/llm/ratelimit"
 	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/quota"
 	"github.com/jingkaihe/kodelet/pkg/steer"
 	"github.com/jingkaihe/kodelet/pkg/sysprompt"
 	"github.com/jingkaihe/kodelet/pkg/telemetry"
@@ -91,6 +94,12 @@ func (t *Thread) getPricing(model string) (llmtypes.ModelPricing, bool) {
 	return llmtypes.ModelPricing{}, false
 }
 
+// modelFeatures returns what model accepts, from the platform preset and the
+// model_features configuration.
+func (t *Thread) modelFeatures(model string) llmtypes.ModelFeatures {
+	return llmtypes.ResolveModelFeatures(t.Config, platformFeatures(resolvePlatformForLoading(t.Config)), model)
+}
+
 // Thread implements the Thread interface using OpenAI's API.
 // It embeds base.Thread to inherit common functionality.
 type Thread struct {
@@ -179,16 +188,16 @@ func NewOpenAIThread(config llmtypes.Config) (*Thread, error) {
 		}
 	} else {
 		// Use OpenAI API key
-		apiKeyEnvVar := GetAPIKeyEnvVar(config)
-		log.WithField("api_key_env_var", apiKeyEnvVar).Debug("using OpenAI API key")
-
-		// Validate API key early
-		if os.Getenv(apiKeyEnvVar) == "" {
-			return nil, errors.Errorf("%s environment variable is required", apiKeyEnvVar)
+		var err error
+		clientConfig, err = newAPIKeyClientConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		if keys := apiKeyPool(config); len(keys) > 0 {
+			log.WithField("api_keys", len(keys)).Debug("using OpenAI API key pool")
+		} else {
+			log.WithField("api_key_env_var", GetAPIKeyEnvVar(config)).Debug("using OpenAI API key")
 		}
-
-		apiKey := os.Getenv(apiKeyEnvVar)
-		clientConfig = openai.DefaultConfig(apiKey)
 		useCopilot = false
 	}
 
@@ -196,16 +205,22 @@ func NewOpenAIThread(config llmtypes.Config) (*Thread, error) {
 		clientConfig.BaseURL = resolvedBaseURL
 	}
 
-	client := openai.NewClientWithConfig(clientConfig)
-
-	// Load custom models and pricing if available
-	customModels, customPricing := loadCustomConfiguration(config)
-
 	conversationID := convtypes.GenerateID()
 
 	// Create the base thread with shared functionality
 	baseThread := base.NewThread(config, conversationID)
 
+	// Copilot responses report how full the rate limit windows are
+	if useCopilot {
+		baseThread.EnableQuotaTracking()
+	}
+
+	clientConfig.HTTPClient = wrapHTTPClient(config, clientConfig.HTTPClient, baseThread.QuotaTracker())
+	client := openai.NewClientWithConfig(clientConfig)
+
+	// Load custom models and pricing if available
+	customModels, customPricing := loadCustomConfiguration(config)
+
 	thread := &Thread{
 		Thread:          baseThread,
 		client:          client,
@@ -217,12 +232,20 @@ func NewOpenAIThread(config llmtypes.Config) (*Thread, error) {
 
 	// Set the LoadConversation callback for provider-specific loading
 	baseThread.LoadConversation = thread.loadConversation
+	baseThread.PruneToolResults = thread.pruneToolResults
+	baseThread.CurrentMessages = thread.GetMessages
 
 	return thread, nil
 }
 
 // AddUserMessage adds a user message with optional images to the thread
 func (t *Thread) AddUserMessage(ctx context.Context, message string, imagePaths ...string) {
+	if t.hasSystemMessage() {
+		// Before the system message is added the indexes of the history are
+		// not final, so SendMessage journals the first message itself
+		defer t.journalMessagesFrom(ctx, len(t.messages))
+	}
+
 	if goals.IsContextText(message) {
 		if imageParts := t.userImageParts(ctx, imagePaths); len(imageParts) > 0 {
 			t.messages = append(t.messages, openai.ChatCompletionMessage{
@@ -246,6 +269,21 @@ func (t *Thread) AddUserMessage(ctx context.Context, message string, imagePaths
 	})
 }
 
+func (t *Thread) hasSystemMessage() bool {
+	return len(t.messages) > 0 && t.messages[0].Role == openai.ChatMessageRoleSystem
+}
+
+// journalMessagesFrom journals the messages added to the history from index
+// from on.
+func (t *Thread) journalMessagesFrom(ctx context.Context, from int) {
+	if t.Thread == nil {
+		return
+	}
+	for i := from; i < len(t.messages); i++ {
+		t.JournalMessage(ctx, i, t.messages[i])
+	}
+}
+
 func (t *Thread) userImageParts(ctx context.Context, imagePaths []string) []openai.ChatMessagePart {
 	contentParts := []openai.ChatMessagePart{}
 
@@ -292,17 +330,15 @@ func (t *Thread) SendMessage(
 		copy(originalMessages, t.messages)
 	}
 
+	defer t.TrackRun(ctx, opt)()
+	t.StartJournal(t.Provider(), opt)
+	defer t.StopJournal()
+
 	message, err = base.ProcessUserMessage(ctx, t, message)
 	if err != nil {
 		return "", err
 	}
 
-	if len(opt.Images) > 0 {
-		t.AddUserMessage(ctx, message, opt.Images...)
-	} else {
-		t.AddUserMessage(ctx, message)
-	}
-
 	// Determine which model to use
 	model := t.Config.Model
 	maxTokens := t.Config.MaxTokens
@@ -313,8 +349,19 @@ func (t *Thread) SendMessage(
 		}
 	}
 
+	if len(opt.Images) > 0 && !t.modelFeatures(model).Vision() {
+		logger.G(ctx).WithField("model", model).Warn("model does not support image input, dropping attached images")
+		opt.Images = nil
+	}
+
+	if len(opt.Images) > 0 {
+		t.AddUserMessage(ctx, message, opt.Images...)
+	} else {
+		t.AddUserMessage(ctx, message)
+	}
+
 	// Add initial system message if it doesn't exist
-	if len(t.messages) == 0 || t.messages[0].Role != openai.ChatMessageRoleSystem {
+	if !t.hasSystemMessage() {
 		systemMessage := openai.ChatCompletionMessage{
 			Role:    openai.ChatMessageRoleSystem,
 			Content: "", // Will be set in OUTER loop
@@ -322,10 +369,13 @@ func (t *Thread) SendMessage(
 
 		// Insert system message at the beginning
 		t.messages = append([]openai.ChatCompletionMessage{systemMessage}, t.messages...)
+		t.journalMessagesFrom(ctx, 0)
 	}
 
 	turnCount := 0
 	maxTurns := max(opt.MaxTurns, 0)
+	budget := t.StartBudget(opt)
+	var budgetErr error
 	base.DispatchAgentStart(ctx, t)
 
 OUTER:
@@ -343,6 +393,10 @@ OUTER:
 					WithField("turn_count", turnCount).
 					WithField("max_turns", maxTurns).
 					Warn("reached maximum turn limit, stopping interaction")
+				t.MarkMaxTurnsReached()
+				break OUTER
+			}
+			if budgetErr = budget.Check(ctx, turnCount); budgetErr != nil {
 				break OUTER
 			}
 
@@ -353,7 +407,18 @@ OUTER:
 			if t.State != nil {
 				contexts = t.State.DiscoverContexts()
 			}
-			systemPrompt := base.ProcessSystemPrompt(ctx, t, sysprompt.SystemPrompt(model, t.Config, contexts))
+			turnModel, turnMaxTokens := model, maxTokens
+			useWeakModel, err := base.ApplyQuotaPolicy(ctx, t)
+			if err != nil {
+				return "", err
+			}
+			if useWeakModel {
+				turnModel = t.Config.WeakModel
+				if t.Config.WeakModelMaxTokens > 0 {
+					turnMaxTokens = t.Config.WeakModelMaxTokens
+				}
+			}
+			systemPrompt := base.ProcessSystemPrompt(ctx, t, sysprompt.SystemPrompt(turnModel, t.Config, contexts))
 
 			// Update system message content
 			if len(t.messages) > 0 && t.messages[0].Role == openai.ChatMessageRoleSystem {
@@ -366,7 +431,7 @@ OUTER:
 			exchangeOpt := opt.WithTurnInitiator(turnCount)
 
 			var exchangeOutput string
-			exchangeOutput, toolsUsed, err := t.processMessageExchange(ctx, handler, model, maxTokens, exchangeOpt)
+			exchangeOutput, toolsUsed, err := t.processMessageExchange(ctx, handler, turnModel, turnMaxTokens, exchangeOpt)
 			if err != nil {
 				if errors.Is(err, context.Canceled) {
 					logger.G(ctx).Info("Request to OpenAI cancelled, stopping kodelet.llm.openai")
@@ -386,9 +451,15 @@ OUTER:
 			finalOutput = exchangeOutput
 
 			base.TriggerTurnEnd(ctx, t, finalOutput, turnCount)
+			if toolsUsed {
+				base.HandleTodoDiscipline(ctx, t, t.tools(opt))
+			}
 
 			// If no tools were used, check for queued continuations before stopping
 			if !toolsUsed {
+				if base.ReviewDeferredTools(ctx, t, handler) {
+					continue OUTER
+				}
 				if base.HandleAgentStopFollowUps(ctx, t, handler) {
 					continue OUTER
 				}
@@ -404,6 +475,8 @@ OUTER:
 		}
 	}
 
+	base.DiscardDeferredTools(ctx, t, handler)
+
 	if opt.NoSaveConversation {
 		t.messages = originalMessages
 	}
@@ -415,7 +488,7 @@ OUTER:
 	}
 
 	handler.HandleDone()
-	return finalOutput, nil
+	return finalOutput, budgetErr
 }
 
 // isToolResultMessage checks if a message is a tool result message
@@ -434,6 +507,9 @@ func (t *Thread) processMessageExchange(
 ) (string, bool, error) {
 	var finalOutput string
 
+	features := t.modelFeatures(model)
+	maxTokens = features.ClampMaxTokens(maxTokens)
+
 	// Prepare completion parameters
 	requestParams := openai.ChatCompletionRequest{
 		Model:     model,
@@ -446,18 +522,21 @@ func (t *Thread) processMessageExchange(
 	}
 
 	if t.isReasoningModelDynamic(model) {
-		if t.reasoningEffort != "none" {
-			requestParams.ReasoningEffort = openAIReasoningEffortForChatRequest(t.reasoningEffort)
+		if reasoningEffort := opt.ResolvedReasoningEffort(t.reasoningEffort); reasoningEffort != "none" {
+			requestParams.ReasoningEffort = openAIReasoningEffortForChatRequest(reasoningEffort)
 		}
 		requestParams.MaxTokens = 0
 	}
 
-	// Add tool definitions if tool use is enabled
-	if !opt.NoToolUse {
+	// Add tool definitions if tool use is enabled and the model accepts them
+	if !opt.NoToolUse && features.Tools() {
 		availableTools := t.tools(opt)
 		if len(availableTools) > 0 {
 			requestParams.Tools = tools.ToOpenAITools(availableTools)
 			requestParams.ToolChoice = "auto"
+			if !features.ParallelToolCalls() {
+				requestParams.ParallelToolCalls = false
+			}
 		}
 	}
 
@@ -480,7 +559,8 @@ func (t *Thread) processMessageExchange(
 	streamHandler, isStreamingHandler := handler.(llmtypes.StreamingMessageHandler)
 
 	// Make the API request with retry logic (use streaming if handler supports it)
-	response, err := t.createChatCompletionWithRetry(ctx, requestParams, streamHandler, isStreamingHandler, extraHeaders)
+	requestCtx, keySelection := auth.WithAPIKeySelection(ctx)
+	response, err := t.createChatCompletionWithRetry(requestCtx, requestParams, streamHandler, isStreamingHandler, extraHeaders)
 	if err != nil {
 		return "", false, errors.Wrap(err, "error sending message to OpenAI")
 	}
@@ -492,7 +572,10 @@ func (t *Thread) processMessageExchange(
 	)
 
 	// Update usage tracking
+	usageBefore := t.GetUsage()
 	t.updateUsage(response.Usage, model)
+	t.RecordAPIKeyUsage(keySelection.Name(), usageBefore)
+	t.RecordExchangeMetrics(model, usageBefore)
 	if usageHandler, ok := handler.(llmtypes.UsageMessageHandler); ok {
 		usageHandler.HandleUsage(t.GetUsage())
 	}
@@ -502,9 +585,20 @@ func (t *Thread) processMessageExchange(
 		return "", false, errors.New("no response choices returned from OpenAI")
 	}
 
-	// Add the assistant response to history
+	// Add the assistant response to history. A filtered response may be
+	// empty, which the API would reject on the next request.
 	assistantMessage := response.Choices[0].Message
-	t.messages = append(t.messages, assistantMessage)
+	if contentFilter, filtered := openAIChatContentFilter(response.Choices[0]); filtered {
+		if assistantMessage.Content != "" || assistantMessage.Refusal != "" || len(assistantMessage.ToolCalls) > 0 {
+			t.messages = append(t.messages, assistantMessage)
+			t.journalMessagesFrom(ctx, len(t.messages)-1)
+		}
+		contentFilter.Provider = t.Provider()
+		base.ReportContentFilter(ctx, handler, contentFilter)
+	} else {
+		t.messages = append(t.messages, assistantMessage)
+		t.journalMessagesFrom(ctx, len(t.messages)-1)
+	}
 
 	// Extract text content (skip if streaming handler already processed it)
 	content := assistantMessage.Content
@@ -528,7 +622,7 @@ func (t *Thread) processMessageExchange(
 	if len(toolCalls) == 0 {
 		// Log structured LLM usage when no tool calls are made
 		if !opt.DisableUsageLog {
-			usage.LogLLMUsage(ctx, t.GetUsage(), model, apiStartTime, response.Usage.CompletionTokens)
+			usage.LogLLMUsageWithResources(t.UsageLogContext(ctx), t.GetUsage(), t.GetResourceUsage(), model, apiStartTime, response.Usage.CompletionTokens)
 		}
 		return finalOutput, false, nil
 	}
@@ -582,11 +676,13 @@ func (t *Thread) processMessageExchange(
 			followupImageParts = append(followupImageParts, openAIChatFollowupImageParts(rich.ContentParts())...)
 		}
 	}
+	journalFrom := len(t.messages)
 	t.messages = append(t.messages, openAIChatToolResultMessages(toolResultMessages, followupImageParts)...)
+	t.journalMessagesFrom(ctx, journalFrom)
 
 	// Log structured LLM usage after all content processing is complete
 	if !opt.DisableUsageLog {
-		usage.LogLLMUsage(ctx, t.GetUsage(), model, apiStartTime, response.Usage.CompletionTokens)
+		usage.LogLLMUsageWithResources(t.UsageLogContext(ctx), t.GetUsage(), t.GetResourceUsage(), model, apiStartTime, response.Usage.CompletionTokens)
 	}
 
 	if t.Persisted && t.Store != nil && !opt.NoSaveConversation {
@@ -595,6 +691,24 @@ func (t *Thread) processMessageExchange(
 	return finalOutput, true, nil
 }
 
+// openAIChatContentFilter reports whether choice was stopped by the content
+// filter or is a refusal.
+func openAIChatContentFilter(choice openai.ChatCompletionChoice) (llmtypes.ContentFilterEvent, bool) {
+	switch {
+	case choice.FinishReason == openai.FinishReasonContentFilter:
+		return llmtypes.ContentFilterEvent{
+			Reason:  llmtypes.ContentFilterReasonContentFilter,
+			Message: choice.Message.Refusal,
+		}, true
+	case choice.Message.Refusal != "":
+		return llmtypes.ContentFilterEvent{
+			Reason:  llmtypes.ContentFilterReasonRefusal,
+			Message: choice.Message.Refusal,
+		}, true
+	}
+	return llmtypes.ContentFilterEvent{}, false
+}
+
 func openAIChatFollowupImageParts(parts []tooltypes.ToolResultContentPart) []openai.ChatMessagePart {
 	content := make([]openai.ChatMessagePart, 0, len(parts))
 	for _, part := range parts {
@@ -658,6 +772,7 @@ func (t *Thread) processPendingSteer(ctx context.Context, requestParams *openai.
 
 			userMessage := t.pendingSteerChatMessage(ctx, steerMsg)
 			t.messages = append(t.messages, userMessage)
+			t.journalMessagesFrom(ctx, len(t.messages)-1)
 			requestParams.Messages = append(requestParams.Messages, userMessage)
 			if userHandler, ok := handler.(llmtypes.UserMessageHandler); ok {
 				userHandler.HandleUserMessage(steerMsg.Content, steerMsg.Images)
@@ -737,13 +852,12 @@ func (t *Thread) createChatCompletionWithRetry(
 
 	err := retry.Do(
 		func() error {
-			var apiErr error
 			if attemptHandler, ok := streamHandler.(llmtypes.StreamingAttemptMessageHandler); ok {
 				attemptHandler.HandleStreamingAttemptStart()
 			}
-			client := t.client
-			if len(extraHeaders) > 0 {
-				client = t.chatClientWithHeaders(extraHeaders)
+			client, apiErr := t.chatClientWithHeaders(extraHeaders)
+			if apiErr != nil {
+				return retry.Unrecoverable(apiErr)
 			}
 			if isStreamingHandler {
 				response, apiErr = t.createStreamingChatCompletionWithClient(ctx, requestParams, streamHandler, client)
@@ -762,6 +876,7 @@ func (t *Thread) createChatCompletionWithRetry(
 		retry.MaxDelay(maxDelay),
 		retry.Context(ctx),
 		retry.OnRetry(func(n uint, err error) {
+			telemetry.RecordLLMRetry("openai")
 			logger.G(ctx).WithError(err).WithField("attempt", n+1).WithField("max_attempts", retryConfig.Attempts).Warn("retrying OpenAI API call")
 		}),
 	)
@@ -794,6 +909,7 @@ func (t *Thread) createStreamingChatCompletionWithClient(
 	// Accumulators for the full response
 	var contentBuilder strings.Builder
 	var reasoningBuilder strings.Builder
+	var refusalBuilder strings.Builder
 	var toolCalls []openai.ToolCall
 	var usage openai.Usage
 	var responseID string
@@ -844,9 +960,14 @@ func (t *Thread) createStreamingChatCompletionWithClient(
 					textStarted = true
 				}
 				handler.HandleTextDelta(delta.Content)
+				t.JournalAssistantText(ctx, len(t.messages), delta.Content)
 				contentBuilder.WriteString(delta.Content)
 			}
 
+			if delta.Refusal != "" {
+				refusalBuilder.WriteString(delta.Refusal)
+			}
+
 			// Handle reasoning content delta (for o1/o3 models)
 			if delta.ReasoningContent != "" {
 				if !reasoningStarted {
@@ -914,6 +1035,7 @@ func (t *Thread) createStreamingChatCompletionWithClient(
 					Role:             openai.ChatMessageRoleAssistant,
 					Content:          contentBuilder.String(),
 					ReasoningContent: reasoningBuilder.String(),
+					Refusal:          refusalBuilder.String(),
 					ToolCalls:        toolCalls,
 				},
 				FinishReason: finishReason,
@@ -1007,7 +1129,7 @@ func (t *Thread) SwapContext(_ context.Context, summary string) error {
 
 // CompactContext performs comprehensive context compacting by creating a detailed summary
 func (t *Thread) CompactContext(ctx context.Context) error {
-	return base.CompactContextWithSummary(ctx, t.runUtilityPrompt, t.SwapContext)
+	return t.CompactContextWithSummary(ctx, t.runUtilityPrompt, t.SwapContext)
 }
 
 // ShortSummary generates a concise summary of the conversation using a faster model.
@@ -1167,22 +1289,21 @@ func (t *Thread) getExtraHeaders(opt llmtypes.MessageOpt) map[string]string {
 	return headers
 }
 
-func (t *Thread) chatClientWithHeaders(extraHeaders map[string]string) *openai.Client {
+func (t *Thread) chatClientWithHeaders(extraHeaders map[string]string) (*openai.Client, error) {
 	if len(extraHeaders) == 0 {
-		return t.client
+		return t.client, nil
 	}
 
-	clientConfig := t.buildClientConfig()
-	if clientConfig.HTTPClient == nil {
-		return t.client
+	clientConfig, err := t.buildClientConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build the client for prompt cache headers")
 	}
-
 	clientConfig.HTTPClient = &headerInjectingHTTPClient{
 		base:    clientConfig.HTTPClient,
 		headers: extraHeaders,
 	}
 
-	return openai.NewClientWithConfig(clientConfig)
+	return openai.NewClientWithConfig(clientConfig), nil
 }
 
 type headerInjectingHTTPClient struct {
@@ -1201,22 +1322,70 @@ func (h *headerInjectingHTTPClient) Do(req *http.Request) (*http.Response, error
 	return h.base.Do(clonedReq)
 }
 
-func (t *Thread) buildClientConfig() openai.ClientConfig {
+func (t *Thread) buildClientConfig() (openai.ClientConfig, error) {
 	if t.useCopilot {
 		clientConfig := openai.DefaultConfig("")
-		clientConfig.HTTPClient = auth.HTTPClientWithAuthorizer(auth.CopilotAuthorizer())
+		clientConfig.HTTPClient = wrapHTTPClient(t.Config, auth.HTTPClientWithAuthorizer(auth.CopilotAuthorizer()), t.QuotaTracker())
 		clientConfig.BaseURL = resolveClientBaseURL(t.Config, true)
-		return clientConfig
+		return clientConfig, nil
 	}
 
-	apiKeyEnvVar := GetAPIKeyEnvVar(t.Config)
-	apiKey := os.Getenv(apiKeyEnvVar)
-	clientConfig := openai.DefaultConfig(apiKey)
+	clientConfig, err := newAPIKeyClientConfig(t.Config)
+	if err != nil {
+		return openai.ClientConfig{}, err
+	}
 	if resolvedBaseURL := resolveClientBaseURL(t.Config, false); resolvedBaseURL != "" {
 		clientConfig.BaseURL = resolvedBaseURL
 	}
+	clientConfig.HTTPClient = wrapHTTPClient(t.Config, clientConfig.HTTPClient, t.QuotaTracker())
+
+	return clientConfig, nil
+}
 
-	return clientConfig
+// wrapHTTPClient paces the requests of doer with the shared rate limiter,
+// injects chaos mode faults into them and, when tracker is set, records the
+// rate limit windows their responses report.
+func wrapHTTPClient(config llmtypes.Config, doer openai.HTTPDoer, tracker *quota.Tracker) openai.HTTPDoer {
+	doer = chaos.WrapDoer("openai", doer)
+	if tracker != nil {
+		doer = &quotaObservingHTTPClient{base: doer, tracker: tracker}
+	}
+	return ratelimit.WrapDoer(ratelimit.Shared("openai", config.RateLimit), doer)
+}
+
+type quotaObservingHTTPClient struct {
+	base    openai.HTTPDoer
+	tracker *quota.Tracker
+}
+
+func (q *quotaObservingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := q.base.Do(req)
+	if resp != nil {
+		q.tracker.Observe(resp.Header)
+	}
+	return resp, err
+}
+
+// newAPIKeyClientConfig returns the client configuration for API key
+// authentication: the shared key pool when openai.api_keys is set, otherwise
+// the key in the environment variable from GetAPIKeyEnvVar.
+func newAPIKeyClientConfig(config llmtypes.Config) (openai.ClientConfig, error) {
+	if keys := apiKeyPool(config); len(keys) > 0 {
+		pool, err := auth.SharedKeyPool("openai", keys)
+		if err != nil {
+			return openai.ClientConfig{}, errors.Wrap(err, "failed to set up the OpenAI API key pool")
+		}
+		clientConfig := openai.DefaultConfig("") // Auth is injected at request time.
+		clientConfig.HTTPClient = auth.HTTPClientWithAuthorizer(auth.OpenAIKeyPoolAuthorizer(pool))
+		return clientConfig, nil
+	}
+
+	apiKeyEnvVar := GetAPIKeyEnvVar(config)
+	apiKey := os.Getenv(apiKeyEnvVar)
+	if apiKey == "" {
+		return openai.ClientConfig{}, errors.Errorf("%s environment variable is required", apiKeyEnvVar)
+	}
+	return openai.DefaultConfig(apiKey), nil
 }
 
 func (t *Thread) getPromptCacheHeaders(opt llmtypes.MessageOpt) map[string]string {
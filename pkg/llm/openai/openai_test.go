@@ -17,6 +17,7 @@ import (
 	"github.com/invopop/jsonschema"
 	"github.com/jingkaihe/kodelet/pkg/goals"
 	"github.com/jingkaihe/kodelet/pkg/llm/base"
+	"github.com/jingkaihe/kodelet/pkg/quota"
 	"github.com/jingkaihe/kodelet/pkg/steer"
 	"github.com/jingkaihe/kodelet/pkg/tools"
 	"github.com/jingkaihe/kodelet/pkg/types/llm"
@@ -185,6 +186,40 @@ func TestOpenAIChatToolResultMessages_AppendsFollowupAfterAllToolResults(t *test
 	assert.Equal(t, openai.ImageURLDetailHigh, messages[2].MultiContent[0].ImageURL.Detail)
 }
 
+func TestOpenAIChatContentFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		choice   openai.ChatCompletionChoice
+		expected llm.ContentFilterEvent
+		filtered bool
+	}{
+		{
+			name:   "completed response",
+			choice: openai.ChatCompletionChoice{FinishReason: openai.FinishReasonStop, Message: openai.ChatCompletionMessage{Content: "hi"}},
+		},
+		{
+			name:     "content filter finish reason",
+			choice:   openai.ChatCompletionChoice{FinishReason: openai.FinishReasonContentFilter},
+			expected: llm.ContentFilterEvent{Reason: llm.ContentFilterReasonContentFilter},
+			filtered: true,
+		},
+		{
+			name:     "refusal",
+			choice:   openai.ChatCompletionChoice{FinishReason: openai.FinishReasonStop, Message: openai.ChatCompletionMessage{Refusal: "I can't help with that."}},
+			expected: llm.ContentFilterEvent{Reason: llm.ContentFilterReasonRefusal, Message: "I can't help with that."},
+			filtered: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, filtered := openAIChatContentFilter(tt.choice)
+			assert.Equal(t, tt.filtered, filtered)
+			assert.Equal(t, tt.expected, event)
+		})
+	}
+}
+
 func TestExtractMessagesWithMultipleToolResults(t *testing.T) {
 	// Test with multiple tool calls and results
 	messagesWithMultipleToolsJSON := `[
@@ -1289,15 +1324,27 @@ func TestOpenAIProcessImageAndClientHelpers(t *testing.T) {
 	t.Setenv("OPENAI_API_KEY", "test-key")
 	cfg := llm.Config{OpenAI: &llm.OpenAIConfig{BaseURL: "https://openai.example/v1"}}
 	thread = &Thread{Thread: base.NewThread(cfg, "conv-helper")}
-	clientConfig := thread.buildClientConfig()
+	clientConfig, err := thread.buildClientConfig()
+	require.NoError(t, err)
 	assert.Equal(t, "https://openai.example/v1", clientConfig.BaseURL)
 	require.NotNil(t, clientConfig.HTTPClient)
-	assert.Same(t, thread.client, thread.chatClientWithHeaders(nil))
-	assert.Same(t, thread.client, thread.chatClientWithHeaders(map[string]string{}))
+	client, err := thread.chatClientWithHeaders(nil)
+	require.NoError(t, err)
+	assert.Same(t, thread.client, client)
+	client, err = thread.chatClientWithHeaders(map[string]string{})
+	require.NoError(t, err)
+	assert.Same(t, thread.client, client)
 
 	thread.client = openai.NewClientWithConfig(clientConfig)
-	withHeaders := thread.chatClientWithHeaders(map[string]string{"X-Session-Affinity": "conv-helper"})
+	withHeaders, err := thread.chatClientWithHeaders(map[string]string{"X-Session-Affinity": "conv-helper"})
+	require.NoError(t, err)
 	assert.NotSame(t, thread.client, withHeaders)
+
+	// The headers are not silently dropped when the client cannot be built
+	t.Setenv("OPENAI_API_KEY", "")
+	_, err = thread.chatClientWithHeaders(map[string]string{"X-Session-Affinity": "conv-helper"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "OPENAI_API_KEY")
 }
 
 func TestOpenAIProcessMessageExchangeTextResponse(t *testing.T) {
@@ -1716,3 +1763,83 @@ func toolNamesForOpenAITest(tools []tooltypes.Tool) []string {
 	}
 	return names
 }
+
+func TestPruneToolResults(t *testing.T) {
+	thread := &Thread{
+		Thread: base.NewThread(llm.Config{}, "conv-test"),
+		messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleUser, Content: "hello"},
+			{Role: openai.ChatMessageRoleTool, ToolCallID: "call-1", Content: strings.Repeat("a", 400)},
+			{Role: openai.ChatMessageRoleTool, ToolCallID: "call-2", MultiContent: []openai.ChatMessagePart{
+				{Type: openai.ChatMessagePartTypeText, Text: strings.Repeat("b", 40)},
+				{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{URL: "data:image/png;base64,AAAA"}},
+			}},
+			{Role: openai.ChatMessageRoleTool, ToolCallID: "call-3", Content: "recent"},
+		},
+	}
+
+	assert.Equal(t, 100+10+base.EstimatedNonTextTokens, thread.pruneToolResults(1, true))
+	assert.Equal(t, base.PrunedToolResultText, thread.messages[1].Content)
+	assert.Equal(t, base.PrunedToolResultText, thread.messages[2].Content)
+	assert.Nil(t, thread.messages[2].MultiContent)
+	assert.Equal(t, "call-2", thread.messages[2].ToolCallID)
+	assert.Equal(t, "recent", thread.messages[3].Content)
+	assert.Zero(t, thread.pruneToolResults(1, true))
+}
+
+func TestOpenAIProcessMessageExchangeAppliesModelFeatures(t *testing.T) {
+	var capturedRequest openai.ChatCompletionRequest
+	client := openai.NewClientWithConfig(openAIHTTPClientConfig(func(req *http.Request) (*http.Response, error) {
+		capturedRequest = decodeOpenAIChatRequest(t, req)
+		return jsonOpenAIResponse(http.StatusOK, `{
+			"id":"chatcmpl-test",
+			"model":"local-llm",
+			"choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":7,"completion_tokens":3,"total_tokens":10}
+		}`), nil
+	}))
+	supported := false
+	config := llm.Config{
+		Model: "local-llm",
+		ModelFeatures: llm.CustomFeatures{
+			"local-llm": {SupportsTools: &supported, MaxOutputTokens: 32},
+		},
+	}
+	thread := newTestOpenAIExchangeThread(client, config)
+	thread.State = tools.NewBasicState(context.Background(), tools.WithLLMConfig(llm.Config{AllowedTools: []string{"file_read"}}))
+	thread.messages = []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "hi"}}
+
+	_, _, err := thread.processMessageExchange(context.Background(), &captureOpenAIMessageHandler{}, "local-llm", 4096, llm.MessageOpt{DisableUsageLog: true})
+	require.NoError(t, err)
+	assert.Equal(t, 32, capturedRequest.MaxTokens)
+	assert.Empty(t, capturedRequest.Tools)
+	assert.Nil(t, capturedRequest.ToolChoice)
+
+	parallel := false
+	thread.Config.ModelFeatures = llm.CustomFeatures{"local-llm": {SupportsParallelToolCalls: &parallel}}
+	_, _, err = thread.processMessageExchange(context.Background(), &captureOpenAIMessageHandler{}, "local-llm", 4096, llm.MessageOpt{DisableUsageLog: true})
+	require.NoError(t, err)
+	assert.Equal(t, 4096, capturedRequest.MaxTokens)
+	assert.NotEmpty(t, capturedRequest.Tools)
+	assert.Equal(t, false, capturedRequest.ParallelToolCalls)
+}
+
+func TestWrapHTTPClientRecordsQuotaWindows(t *testing.T) {
+	tracker := quota.NewTracker()
+	doer := wrapHTTPClient(llm.Config{}, roundTripHTTPDoer(func(*http.Request) (*http.Response, error) {
+		header := make(http.Header)
+		header.Set("X-Ratelimit-Limit-Requests", "100")
+		header.Set("X-Ratelimit-Remaining-Requests", "10")
+		header.Set("X-Ratelimit-Reset-Requests", "6m0s")
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok")), Header: header}, nil
+	}), tracker)
+
+	resp, err := doer.Do(httptest.NewRequest(http.MethodGet, "https://example.com", nil))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	windows := tracker.Windows()
+	require.Len(t, windows, 1)
+	assert.Equal(t, "requests", windows[0].Name)
+	assert.InDelta(t, 0.9, windows[0].Utilization, 0.001)
+}
@@ -118,11 +118,14 @@ func (t *Thread) SaveConversation(ctx context.Context, summarize bool) error {
 	if !t.Persisted || t.Store == nil {
 		return nil
 	}
+	journalPosition := t.JournalPosition(ctx)
 
 	// Clean up orphaned messages before saving
 	messagesToSave := cleanedOpenAIMessages(t.messages)
 	metadata := t.GetMetadata()
-	summary := base.FirstUserMessageFallback(conversations.ApplyDisplayToStreamableMessages(conversationsFromOpenAI(streamMessagesForSummary(messagesToSave, t.GetStructuredToolResults())), metadata))
+	toolResults := t.GetStructuredToolResults()
+	displayMessages := conversations.ApplyDisplayToStreamableMessages(conversationsFromOpenAI(streamMessagesForSummary(messagesToSave, toolResults)), metadata)
+	summary := base.FirstUserMessageFallback(displayMessages)
 
 	// Generate a new summary if requested and enabled; otherwise keep the first user message.
 	if summarize {
@@ -136,6 +139,9 @@ func (t *Thread) SaveConversation(ctx context.Context, summarize bool) error {
 		}
 	}
 	t.summary = summary
+	if summarize {
+		t.RecordConversationTitle(ctx, metadata, displayMessages, toolResults, t.runUtilityPrompt)
+	}
 
 	// Serialize the thread state
 	messagesJSON, err := json.Marshal(messagesToSave)
@@ -164,6 +170,10 @@ func (t *Thread) SaveConversation(ctx context.Context, summarize bool) error {
 	if err != nil {
 		return errors.Wrap(err, "failed to persist conversation config snapshot")
 	}
+	metadata = conversations.AddExperiment(metadata, t.Config.ExperimentAssignment)
+	if snapshot, ok := t.ContextSnapshot(ctx, summarize); ok {
+		metadata = conversations.AddContextSnapshot(metadata, snapshot)
+	}
 
 	// Build the conversation record
 	record := convtypes.ConversationRecord{
@@ -180,7 +190,11 @@ func (t *Thread) SaveConversation(ctx context.Context, summarize bool) error {
 	}
 
 	// Save to the store
-	return t.Store.Save(ctx, record)
+	if err := t.Store.Save(ctx, record); err != nil {
+		return err
+	}
+	t.ClearJournal(ctx, journalPosition)
+	return nil
 }
 
 func streamMessagesForSummary(messages []openai.ChatCompletionMessage, toolResults map[string]tooltypes.StructuredToolResult) []StreamableMessage {
@@ -205,10 +219,12 @@ func (t *Thread) loadConversation(ctx context.Context) {
 		return
 	}
 
-	// Try to load the conversation
+	// Try to load the conversation. A conversation killed before its first
+	// save may only exist in its journal.
 	record, err := t.Store.Load(ctx, t.ConversationID)
 	if err != nil {
-		return
+		record = convtypes.NewConversationRecord(t.ConversationID)
+		record.Provider = "openai"
 	}
 
 	// Check if this is an OpenAI model conversation
@@ -216,13 +232,18 @@ func (t *Thread) loadConversation(ctx context.Context) {
 		return
 	}
 
+	replay := t.ReplayJournal(ctx, &record)
+	if err != nil && replay.Messages == 0 {
+		return
+	}
+
 	// Deserialize the messages
 	var messages []openai.ChatCompletionMessage
 	if err := json.Unmarshal(record.RawMessages, &messages); err != nil {
 		return
 	}
 
-	t.messages = cleanedOpenAIMessages(messages)
+	t.messages = cleanedOpenAIMessages(recoverJournaledTurn(messages, replay))
 	t.Usage = &record.Usage
 	t.summary = record.Summary
 	t.SetMetadata(record.Metadata)
@@ -230,15 +251,65 @@ func (t *Thread) loadConversation(ctx context.Context) {
 	t.SetStructuredToolResults(record.ToolResults)
 }
 
+// recoverJournaledTurn adds the text replay recovered as an assistant message,
+// or tool messages for the calls of the last assistant message left
+// unanswered.
+func recoverJournaledTurn(messages []openai.ChatCompletionMessage, replay convtypes.JournalReplay) []openai.ChatCompletionMessage {
+	if !replay.Recovered() {
+		return messages
+	}
+	if replay.AssistantText != "" {
+		return append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: replay.AssistantText})
+	}
+
+	// Tool results follow the assistant message whose calls they answer
+	start := len(messages)
+	answered := make(map[string]bool)
+	for start > 0 && messages[start-1].Role == openai.ChatMessageRoleTool {
+		start--
+		answered[messages[start].ToolCallID] = true
+	}
+	if start == 0 || messages[start-1].Role != openai.ChatMessageRoleAssistant {
+		return messages
+	}
+	for _, toolCall := range messages[start-1].ToolCalls {
+		if answered[toolCall.ID] {
+			continue
+		}
+		output := base.RecoveredToolOutput(replay, toolCall.ID)
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:       openai.ChatMessageRoleTool,
+			Content:    output.Text,
+			ToolCallID: toolCall.ID,
+		})
+	}
+	return messages
+}
+
 // StreamableMessage contains parsed message data for streaming
 type StreamableMessage struct {
 	Kind       string // "text", "tool-use", "tool-result", "thinking"
 	Role       string // "user", "assistant", "system"
 	Content    string // Text content
 	RawItem    json.RawMessage
-	ToolName   string // For tool use/result
-	ToolCallID string // For matching tool results
-	Input      string // For tool use (JSON string)
+	ToolName   string              // For tool use/result
+	ToolCallID string              // For matching tool results
+	Input      string              // For tool use (JSON string)
+	Reasoning  *llmtypes.Reasoning // For thinking
+}
+
+// ReasoningFromMessage converts the reasoning_content of a Chat Completions
+// message into the provider-neutral transcript form. Chat Completions
+// reasoning is plain text with no opaque payload.
+func ReasoningFromMessage(msg openai.ChatCompletionMessage) (llmtypes.Reasoning, bool) {
+	if msg.ReasoningContent == "" {
+		return llmtypes.Reasoning{}, false
+	}
+	return llmtypes.Reasoning{
+		Provider: "openai",
+		Format:   llmtypes.ReasoningFormatThinking,
+		Text:     msg.ReasoningContent,
+	}, true
 }
 
 // StreamMessages parses raw messages into streamable format for conversation streaming
@@ -276,11 +347,12 @@ func StreamMessages(rawMessages json.RawMessage, toolResults map[string]tooltype
 			continue
 		}
 
-		if msg.ReasoningContent != "" {
+		if reasoning, ok := ReasoningFromMessage(msg); ok && reasoning.DisplayText() != "" {
 			streamable = append(streamable, StreamableMessage{
-				Kind:    "thinking",
-				Role:    msg.Role,
-				Content: strings.TrimLeft(msg.ReasoningContent, "\n"),
+				Kind:      "thinking",
+				Role:      msg.Role,
+				Content:   reasoning.DisplayText(),
+				Reasoning: &reasoning,
 			})
 		}
 
@@ -447,10 +519,10 @@ func ExtractMessages(data []byte, toolResults map[string]tooltypes.StructuredToo
 			continue
 		}
 
-		if msg.ReasoningContent != "" {
+		if reasoning, ok := ReasoningFromMessage(msg); ok && reasoning.TranscriptText() != "" {
 			result = append(result, llmtypes.Message{
 				Role:    "assistant",
-				Content: fmt.Sprintf("💭 Thinking: %s", strings.TrimLeft(msg.ReasoningContent, "\n")),
+				Content: reasoning.TranscriptText(),
 			})
 		}
 
@@ -486,3 +558,45 @@ func ExtractMessages(data []byte, toolResults map[string]tooltypes.StructuredToo
 
 	return result, nil
 }
+
+// RedactToolResults replaces the results of the given tools with a
+// placeholder, keeping each tool message so tool calls stay paired. It
+// returns the rewritten messages and the IDs of the redacted tool calls.
+func RedactToolResults(rawMessages json.RawMessage, toolNames conversations.ToolRedactionSet) (json.RawMessage, []string, error) {
+	var messages []openai.ChatCompletionMessage
+	if err := json.Unmarshal(rawMessages, &messages); err != nil {
+		return nil, nil, errors.Wrap(err, "error unmarshaling messages")
+	}
+
+	redactedTools := make(map[string]string)
+	for _, msg := range messages {
+		for _, toolCall := range msg.ToolCalls {
+			if toolNames.Contains(toolCall.Function.Name) {
+				redactedTools[toolCall.ID] = toolCall.Function.Name
+			}
+		}
+	}
+
+	var redactedIDs []string
+	for i := range messages {
+		if messages[i].Role != openai.ChatMessageRoleTool {
+			continue
+		}
+		toolName, ok := redactedTools[messages[i].ToolCallID]
+		if !ok {
+			continue
+		}
+		messages[i].Content = conversations.RedactedToolResultPlaceholder(toolName)
+		messages[i].MultiContent = nil
+		redactedIDs = append(redactedIDs, messages[i].ToolCallID)
+	}
+	if len(redactedIDs) == 0 {
+		return rawMessages, nil, nil
+	}
+
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to marshal redacted messages")
+	}
+	return data, redactedIDs, nil
+}
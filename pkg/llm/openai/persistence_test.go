@@ -64,6 +64,14 @@ func (m *MockConversationStore) Query(_ context.Context, _ conversations.QueryOp
 	return conversations.QueryResult{}, nil
 }
 
+func (m *MockConversationStore) Fork(_ context.Context, _ string) (conversations.ConversationRecord, error) {
+	return conversations.ConversationRecord{}, nil
+}
+
+func (m *MockConversationStore) Search(_ context.Context, _ string, _ conversations.QueryOptions) (conversations.SearchResult, error) {
+	return conversations.SearchResult{}, nil
+}
+
 func (m *MockConversationStore) Close() error {
 	return nil
 }
@@ -885,6 +893,8 @@ func TestStreamMessages_WithReasoningContent(t *testing.T) {
 	assert.Equal(t, "thinking", streamableMessages[1].Kind)
 	assert.Equal(t, "assistant", streamableMessages[1].Role)
 	assert.Equal(t, "first reason about the problem", streamableMessages[1].Content)
+	require.NotNil(t, streamableMessages[1].Reasoning)
+	assert.Equal(t, "thinking", streamableMessages[1].Reasoning.Format)
 
 	assert.Equal(t, "text", streamableMessages[2].Kind)
 	assert.Equal(t, "assistant", streamableMessages[2].Role)
@@ -970,3 +980,28 @@ func TestStreamMessages_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestRecoverJournaledTurn(t *testing.T) {
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem},
+		{Role: openai.ChatMessageRoleUser, Content: "deploy it"},
+		{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{{ID: "call-1"}, {ID: "call-2"}, {ID: "call-3"}}},
+		{Role: openai.ChatMessageRoleTool, ToolCallID: "call-1", Content: "built"},
+	}
+	replay := conversations.JournalReplay{ToolOutputs: map[string]conversations.JournalToolOutput{
+		"call-1": {Text: "built"},
+		"call-2": {Text: "deployed"},
+	}}
+
+	recovered := recoverJournaledTurn(messages, replay)
+	require.Len(t, recovered, 6)
+	assert.Equal(t, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleTool, ToolCallID: "call-2", Content: "deployed"}, recovered[4])
+	assert.Equal(t, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleTool, ToolCallID: "call-3", Content: base.InterruptedToolCallOutput}, recovered[5])
+
+	recovered = recoverJournaledTurn(messages[:2], conversations.JournalReplay{Messages: 1, AssistantText: "Deploying now"})
+	require.Len(t, recovered, 3)
+	assert.Equal(t, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "Deploying now"}, recovered[2])
+
+	// Nothing is added without a journal to recover from
+	assert.Len(t, recoverJournaledTurn(messages, conversations.JournalReplay{}), 4)
+}
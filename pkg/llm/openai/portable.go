@@ -0,0 +1,101 @@
+package openai
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sashabaranov/go-openai"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// ToPortableMessages converts stored Chat Completions messages into the
+// provider-neutral form. The system prompt and reasoning_content are
+// dropped, and images become a placeholder.
+func ToPortableMessages(rawMessages []byte) ([]llmtypes.PortableMessage, error) {
+	var messages []openai.ChatCompletionMessage
+	if err := json.Unmarshal(rawMessages, &messages); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal openai messages")
+	}
+
+	var portable []llmtypes.PortableMessage
+	for _, msg := range messages {
+		switch msg.Role {
+		case openai.ChatMessageRoleUser, openai.ChatMessageRoleAssistant:
+			content := openAIMessageText(msg)
+			var toolCalls []llmtypes.PortableToolCall
+			for _, call := range msg.ToolCalls {
+				toolCalls = append(toolCalls, llmtypes.PortableToolCall{
+					ID:    call.ID,
+					Name:  call.Function.Name,
+					Input: call.Function.Arguments,
+				})
+			}
+			if content == "" && len(toolCalls) == 0 {
+				continue
+			}
+			portable = append(portable, llmtypes.PortableMessage{Role: msg.Role, Content: content, ToolCalls: toolCalls})
+		case openai.ChatMessageRoleTool:
+			portable = append(portable, llmtypes.PortableMessage{
+				Role:       "tool",
+				Content:    openAIMessageText(msg),
+				ToolCallID: msg.ToolCallID,
+			})
+		}
+	}
+	return portable, nil
+}
+
+func openAIMessageText(msg openai.ChatCompletionMessage) string {
+	if len(msg.MultiContent) == 0 {
+		return msg.Content
+	}
+	var parts []string
+	for _, part := range msg.MultiContent {
+		switch part.Type {
+		case openai.ChatMessagePartTypeText:
+			if part.Text != "" {
+				parts = append(parts, part.Text)
+			}
+		case openai.ChatMessagePartTypeImageURL:
+			parts = append(parts, llmtypes.PortableImagePlaceholder)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// FromPortableMessages converts provider-neutral messages into stored Chat
+// Completions messages. The system prompt is added by the thread when the
+// conversation is resumed.
+func FromPortableMessages(portable []llmtypes.PortableMessage) (json.RawMessage, error) {
+	messages := make([]openai.ChatCompletionMessage, 0, len(portable))
+	for _, msg := range portable {
+		switch msg.Role {
+		case "user":
+			messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: msg.Content})
+		case "tool":
+			messages = append(messages, openai.ChatCompletionMessage{
+				Role:       openai.ChatMessageRoleTool,
+				Content:    msg.Content,
+				ToolCallID: msg.ToolCallID,
+			})
+		case "assistant":
+			message := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: msg.Content}
+			for _, call := range msg.ToolCalls {
+				message.ToolCalls = append(message.ToolCalls, openai.ToolCall{
+					ID:       call.ID,
+					Type:     openai.ToolTypeFunction,
+					Function: openai.FunctionCall{Name: call.Name, Arguments: call.Input},
+				})
+			}
+			messages = append(messages, message)
+		}
+	}
+
+	raw, err := json.Marshal(messages)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal openai messages")
+	}
+	return raw, nil
+}
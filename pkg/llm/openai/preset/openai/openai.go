@@ -403,3 +403,41 @@ const BaseURL = "https://api.openai.com/v1"
 
 // APIKeyEnvVar is the environment variable name for the OpenAI API key
 const APIKeyEnvVar = "OPENAI_API_KEY"
+
+// unsupported marks a feature a model rejects.
+var unsupported = new(bool)
+
+// Features defines the known request limits of OpenAI models. Models absent
+// from this map accept tools, images and parallel tool calls without an
+// output limit of their own.
+var Features = llm.CustomFeatures{
+	"o1-mini": llm.ModelFeatures{
+		SupportsTools:   unsupported,
+		SupportsVision:  unsupported,
+		MaxOutputTokens: 65_536,
+	},
+	"o3-mini": llm.ModelFeatures{
+		SupportsVision:  unsupported,
+		MaxOutputTokens: 100_000,
+	},
+	"o1":                         llm.ModelFeatures{MaxOutputTokens: 100_000},
+	"o1-pro":                     llm.ModelFeatures{MaxOutputTokens: 100_000},
+	"o3":                         llm.ModelFeatures{MaxOutputTokens: 100_000},
+	"o3-pro":                     llm.ModelFeatures{MaxOutputTokens: 100_000},
+	"o4-mini":                    llm.ModelFeatures{MaxOutputTokens: 100_000},
+	"gpt-5":                      llm.ModelFeatures{MaxOutputTokens: 128_000},
+	"gpt-5-mini":                 llm.ModelFeatures{MaxOutputTokens: 128_000},
+	"gpt-5-nano":                 llm.ModelFeatures{MaxOutputTokens: 128_000},
+	"gpt-5-chat-latest":          llm.ModelFeatures{MaxOutputTokens: 16_384},
+	"gpt-4.1":                    llm.ModelFeatures{MaxOutputTokens: 32_768},
+	"gpt-4.1-mini":               llm.ModelFeatures{MaxOutputTokens: 32_768},
+	"gpt-4.1-nano":               llm.ModelFeatures{MaxOutputTokens: 32_768},
+	"gpt-4.5-preview":            llm.ModelFeatures{MaxOutputTokens: 16_384},
+	"gpt-4o":                     llm.ModelFeatures{MaxOutputTokens: 16_384},
+	"gpt-4o-2024-05-13":          llm.ModelFeatures{MaxOutputTokens: 4_096},
+	"gpt-4o-mini":                llm.ModelFeatures{MaxOutputTokens: 16_384},
+	"gpt-4o-search-preview":      llm.ModelFeatures{SupportsTools: unsupported, MaxOutputTokens: 16_384},
+	"gpt-4o-mini-search-preview": llm.ModelFeatures{SupportsTools: unsupported, MaxOutputTokens: 16_384},
+	"o3-deep-research":           llm.ModelFeatures{MaxOutputTokens: 100_000},
+	"o4-mini-deep-research":      llm.ModelFeatures{MaxOutputTokens: 100_000},
+}
@@ -0,0 +1,40 @@
+package openai
+
+import (
+	"github.com/jingkaihe/kodelet/pkg/llm/base"
+	"github.com/sashabaranov/go-openai"
+)
+
+// pruneToolResults estimates the tokens held by all but the keepRecent most
+// recent tool messages and, when apply is set, replaces their content with a
+// placeholder. Tool call and result pairing is preserved.
+func (t *Thread) pruneToolResults(keepRecent int, apply bool) int {
+	var results []int
+	for i, msg := range t.messages {
+		if msg.Role == openai.ChatMessageRoleTool {
+			results = append(results, i)
+		}
+	}
+
+	tokens := 0
+	for _, i := range results[:base.PrunableCount(len(results), keepRecent)] {
+		msg := t.messages[i]
+		if msg.Content == base.PrunedToolResultText && len(msg.MultiContent) == 0 {
+			continue
+		}
+		tokens += base.EstimateTextTokens(msg.Content)
+		for _, part := range msg.MultiContent {
+			if part.Type == openai.ChatMessagePartTypeText {
+				tokens += base.EstimateTextTokens(part.Text)
+			} else {
+				tokens += base.EstimatedNonTextTokens
+			}
+		}
+		if apply {
+			msg.Content = base.PrunedToolResultText
+			msg.MultiContent = nil
+			t.messages[i] = msg
+		}
+	}
+	return tokens
+}
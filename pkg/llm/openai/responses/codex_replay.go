@@ -0,0 +1,147 @@
+package responses
+
+import (
+	"context"
+	"slices"
+
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/telemetry"
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/openai/openai-go/v3/responses"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Codex runs with store=false, so every request replays the whole history
+// instead of pointing at server-side state. Two things keep that affordable:
+// reasoning is requested with its encrypted content and replayed alongside
+// the function calls it produced, so the model keeps its chain of thought
+// between tool calls, and prompt_cache_key lets the backend serve the
+// replayed prefix from cache. codexReplayStats measures how much of the
+// replayed history was not served from cache.
+
+// codexReplayStats accumulates the history Codex requests resend.
+type codexReplayStats struct {
+	// Requests is the number of requests that replayed earlier history.
+	Requests int
+	// ReplayedTokens estimates the history tokens those requests resent:
+	// the previous request's input plus its output.
+	ReplayedTokens int
+	// CachedTokens is the part of the resent input served from the prompt
+	// cache.
+	CachedTokens int
+	// UncachedTokens is the replayed history billed as regular input, the
+	// overhead of replaying instead of continuing server-side state.
+	UncachedTokens int
+
+	lastContextTokens int
+}
+
+// record adds one completed response and returns the history tokens it
+// replayed and how many of those missed the cache.
+func (s *codexReplayStats) record(usage responses.ResponseUsage) (replayed, uncached int) {
+	replayed = s.lastContextTokens
+	s.lastContextTokens = int(usage.InputTokens + usage.OutputTokens)
+	if replayed == 0 {
+		return 0, 0
+	}
+
+	cached := min(int(usage.InputTokensDetails.CachedTokens), replayed)
+	uncached = replayed - cached
+	s.Requests++
+	s.ReplayedTokens += replayed
+	s.CachedTokens += cached
+	s.UncachedTokens += uncached
+	return replayed, uncached
+}
+
+// recordCodexReplay measures the history resent by a completed Codex
+// response. Continued WebSocket responses only send new items, so they do not
+// count as replays.
+func (t *Thread) recordCodexReplay(ctx context.Context, usage responses.ResponseUsage, incremental bool) {
+	if !t.isCodex {
+		return
+	}
+	if incremental {
+		t.codexReplay.lastContextTokens = int(usage.InputTokens + usage.OutputTokens)
+		return
+	}
+
+	replayed, uncached := t.codexReplay.record(usage)
+	if replayed == 0 {
+		return
+	}
+	telemetry.AddEvent(ctx, "codex_history_replayed",
+		attribute.Int("replayed_tokens", replayed),
+		attribute.Int("uncached_tokens", uncached),
+	)
+	logger.G(ctx).WithField("replayed_tokens", replayed).
+		WithField("cached_tokens", replayed-uncached).
+		WithField("uncached_tokens", uncached).
+		Debug("codex request replayed conversation history")
+}
+
+// logCodexReplaySummary reports the replay overhead of a SendMessage call.
+func (t *Thread) logCodexReplaySummary(ctx context.Context, before codexReplayStats) {
+	if !t.isCodex || t.codexReplay.Requests == before.Requests {
+		return
+	}
+	replayed := t.codexReplay.ReplayedTokens - before.ReplayedTokens
+	uncached := t.codexReplay.UncachedTokens - before.UncachedTokens
+	cacheHitPercent := 0
+	if replayed > 0 {
+		cacheHitPercent = (replayed - uncached) * 100 / replayed
+	}
+	logger.G(ctx).WithField("requests", t.codexReplay.Requests-before.Requests).
+		WithField("replayed_tokens", replayed).
+		WithField("uncached_tokens", uncached).
+		WithField("cache_hit_percent", cacheHitPercent).
+		Info("codex history replay overhead")
+}
+
+// requestEncryptedReasoning asks the backend to return reasoning as
+// encrypted content, which a store=false request can replay.
+func requestEncryptedReasoning(params *responses.ResponseNewParams) {
+	if params.Reasoning.Effort == "" || slices.Contains(params.Include, responses.ResponseIncludableReasoningEncryptedContent) {
+		return
+	}
+	params.Include = append(slices.Clone(params.Include), responses.ResponseIncludableReasoningEncryptedContent)
+}
+
+// addEncryptedReasoning records a completed reasoning item carrying
+// encrypted content so it is replayed on the next request. The summary text
+// was already stored while streaming, so the encrypted content is attached to
+// that item when it is the latest one.
+func (t *Thread) addEncryptedReasoning(reasoning responses.ResponseReasoningItem) (responses.ResponseInputItemUnionParam, bool) {
+	if reasoning.EncryptedContent == "" {
+		return responses.ResponseInputItemUnionParam{}, false
+	}
+
+	if last := len(t.storedItems) - 1; last >= 0 && t.storedItems[last].Type == "reasoning" && t.storedItems[last].EncryptedContent == "" {
+		t.storedItems[last].CallID = reasoning.ID
+		t.storedItems[last].EncryptedContent = reasoning.EncryptedContent
+	} else {
+		t.storedItems = append(t.storedItems, StoredInputItem{
+			Type:             "reasoning",
+			Role:             "assistant",
+			CallID:           reasoning.ID,
+			EncryptedContent: reasoning.EncryptedContent,
+		})
+	}
+
+	inputItem := encryptedReasoningInputItem(reasoning.ID, reasoning.EncryptedContent)
+	t.inputItems = append(t.inputItems, inputItem)
+	return inputItem, true
+}
+
+// encryptedReasoningInputItem builds the replayed form of a reasoning item.
+// The summary is left empty so the replayed prefix stays byte-identical
+// between requests regardless of how the summary was streamed.
+func encryptedReasoningInputItem(id, encryptedContent string) responses.ResponseInputItemUnionParam {
+	return responses.ResponseInputItemUnionParam{
+		OfReasoning: &responses.ResponseReasoningItemParam{
+			ID:               id,
+			Summary:          []responses.ResponseReasoningItemSummaryParam{},
+			EncryptedContent: param.NewOpt(encryptedContent),
+		},
+	}
+}
@@ -0,0 +1,139 @@
+package responses
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/llm/base"
+	"github.com/jingkaihe/kodelet/pkg/tools"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/openai/openai-go/v3/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessStreamReplaysEncryptedReasoningBeforeFunctionCall(t *testing.T) {
+	events := []map[string]any{
+		{"type": "response.reasoning_summary_text.delta", "delta": "Check the file"},
+		{"type": "response.reasoning_summary_text.done"},
+		{
+			"type": "response.output_item.done",
+			"item": map[string]any{
+				"type":              "reasoning",
+				"id":                "rs_1",
+				"summary":           []any{map[string]any{"type": "summary_text", "text": "Check the file"}},
+				"encrypted_content": "gAAAA-encrypted",
+			},
+		},
+		{
+			"type": "response.output_item.done",
+			"item": map[string]any{
+				"type":      "function_call",
+				"call_id":   "call_1",
+				"name":      "ok_tool",
+				"arguments": `{}`,
+			},
+		},
+		{
+			"type": "response.completed",
+			"response": map[string]any{
+				"id":     "resp_1",
+				"status": "completed",
+				"usage":  map[string]any{"input_tokens": 1, "output_tokens": 1},
+			},
+		},
+	}
+
+	thread := &Thread{
+		Thread:      base.NewThread(llmtypes.Config{Provider: "openai", Model: "gpt-5.5"}, "test"),
+		storedItems: make([]StoredInputItem, 0),
+		inputItems:  make([]responses.ResponseInputItemUnionParam, 0),
+	}
+	thread.SetState(tools.NewBasicState(context.Background(), tools.WithExtensionTools([]tooltypes.Tool{responsesTestTool{name: "ok_tool"}})))
+
+	streamResult, err := thread.processStream(context.Background(), responseStreamFromMaps(t, events), &captureStreamHandler{}, "gpt-5.5", llmtypes.MessageOpt{})
+	require.NoError(t, err)
+
+	require.Len(t, thread.storedItems, 3)
+	assert.Equal(t, "reasoning", thread.storedItems[0].Type)
+	assert.Equal(t, "Check the file", thread.storedItems[0].Content)
+	assert.Equal(t, "rs_1", thread.storedItems[0].CallID)
+	assert.Equal(t, "gAAAA-encrypted", thread.storedItems[0].EncryptedContent)
+
+	require.Len(t, thread.inputItems, 3)
+	require.NotNil(t, thread.inputItems[0].OfReasoning)
+	assert.Equal(t, "gAAAA-encrypted", thread.inputItems[0].OfReasoning.EncryptedContent.Value)
+	require.NotNil(t, thread.inputItems[1].OfFunctionCall)
+	require.Len(t, streamResult.serverKnownItems, 2)
+	require.NotNil(t, streamResult.serverKnownItems[0].OfReasoning)
+
+	replayed := fromStoredItems(thread.storedItems)
+	require.Len(t, replayed, 3)
+	raw, err := json.Marshal(replayed[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"reasoning","id":"rs_1","summary":[],"encrypted_content":"gAAAA-encrypted"}`, string(raw))
+}
+
+func TestFromStoredItemsSkipsReasoningWithoutEncryptedContent(t *testing.T) {
+	items := fromStoredItems([]StoredInputItem{
+		{Type: "reasoning", Role: "assistant", Content: "display only"},
+		{Type: "message", Role: "user", Content: "hi"},
+	})
+	require.Len(t, items, 1)
+	assert.NotNil(t, items[0].OfMessage)
+}
+
+func TestApplyCodexRestrictionsRequestsEncryptedReasoning(t *testing.T) {
+	thread := &Thread{
+		Thread:  base.NewThread(llmtypes.Config{Provider: "openai", Model: "gpt-5.5"}, "test"),
+		isCodex: true,
+	}
+	params := responses.ResponseNewParams{Reasoning: shared.ReasoningParam{Effort: shared.ReasoningEffortMedium}}
+
+	thread.applyCodexRestrictions(&params)
+	thread.applyCodexRestrictions(&params)
+	assert.Equal(t, []responses.ResponseIncludable{responses.ResponseIncludableReasoningEncryptedContent}, params.Include)
+
+	params = responses.ResponseNewParams{}
+	thread.applyCodexRestrictions(&params)
+	assert.Empty(t, params.Include)
+}
+
+func TestCodexReplayStatsMeasuresUncachedHistory(t *testing.T) {
+	usage := func(input, output, cached int64) responses.ResponseUsage {
+		return responses.ResponseUsage{
+			InputTokens:        input,
+			OutputTokens:       output,
+			InputTokensDetails: responses.ResponseUsageInputTokensDetails{CachedTokens: cached},
+		}
+	}
+
+	thread := &Thread{isCodex: true}
+	ctx := context.Background()
+
+	thread.recordCodexReplay(ctx, usage(1000, 200, 0), false)
+	assert.Equal(t, 0, thread.codexReplay.Requests)
+
+	thread.recordCodexReplay(ctx, usage(1500, 100, 1024), false)
+	assert.Equal(t, 1, thread.codexReplay.Requests)
+	assert.Equal(t, 1200, thread.codexReplay.ReplayedTokens)
+	assert.Equal(t, 1024, thread.codexReplay.CachedTokens)
+	assert.Equal(t, 176, thread.codexReplay.UncachedTokens)
+
+	// A continued WebSocket response sends only new items.
+	thread.recordCodexReplay(ctx, usage(1700, 100, 1536), true)
+	assert.Equal(t, 1, thread.codexReplay.Requests)
+
+	thread.recordCodexReplay(ctx, usage(2000, 50, 2000), false)
+	assert.Equal(t, 2, thread.codexReplay.Requests)
+	assert.Equal(t, 3000, thread.codexReplay.ReplayedTokens)
+	assert.Equal(t, 176, thread.codexReplay.UncachedTokens)
+
+	nonCodex := &Thread{}
+	nonCodex.recordCodexReplay(ctx, usage(1000, 200, 0), false)
+	nonCodex.recordCodexReplay(ctx, usage(1500, 100, 0), false)
+	assert.Equal(t, 0, nonCodex.codexReplay.Requests)
+}
@@ -0,0 +1,105 @@
+package responses
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// ToPortableMessages converts stored Responses API items into the
+// provider-neutral form. Reasoning, web search calls and server-side
+// compaction items are dropped, because their content is encrypted or only
+// meaningful to OpenAI. Consecutive assistant items are merged into one
+// message.
+func ToPortableMessages(rawMessages []byte) ([]llmtypes.PortableMessage, error) {
+	var items []StoredInputItem
+	if err := json.Unmarshal(rawMessages, &items); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal responses items")
+	}
+
+	var portable []llmtypes.PortableMessage
+	assistant := func() *llmtypes.PortableMessage {
+		if n := len(portable); n > 0 && portable[n-1].Role == "assistant" {
+			return &portable[n-1]
+		}
+		portable = append(portable, llmtypes.PortableMessage{Role: "assistant"})
+		return &portable[len(portable)-1]
+	}
+
+	for _, item := range items {
+		switch item.Type {
+		case "message":
+			role := strings.ToLower(strings.TrimSpace(item.Role))
+			if item.Content == "" {
+				continue
+			}
+			switch role {
+			case "user":
+				portable = append(portable, llmtypes.PortableMessage{Role: "user", Content: item.Content})
+			case "assistant":
+				msg := assistant()
+				if len(msg.ToolCalls) > 0 {
+					portable = append(portable, llmtypes.PortableMessage{Role: "assistant", Content: item.Content})
+					continue
+				}
+				if msg.Content != "" {
+					msg.Content += "\n\n"
+				}
+				msg.Content += item.Content
+			}
+		case "function_call":
+			msg := assistant()
+			msg.ToolCalls = append(msg.ToolCalls, llmtypes.PortableToolCall{
+				ID:    item.CallID,
+				Name:  item.Name,
+				Input: item.Arguments,
+			})
+		case "function_call_output":
+			output := item.Output
+			if output == "" && len(item.RawOutput) > 0 {
+				output = llmtypes.PortableImagePlaceholder
+			}
+			portable = append(portable, llmtypes.PortableMessage{Role: "tool", Content: output, ToolCallID: item.CallID})
+		}
+	}
+
+	// Drop assistant placeholders that received neither text nor tool calls.
+	kept := portable[:0]
+	for _, msg := range portable {
+		if msg.Role == "assistant" && msg.Content == "" && len(msg.ToolCalls) == 0 {
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	return kept, nil
+}
+
+// FromPortableMessages converts provider-neutral messages into stored
+// Responses API items.
+func FromPortableMessages(portable []llmtypes.PortableMessage) (json.RawMessage, error) {
+	items := make([]StoredInputItem, 0, len(portable))
+	for _, msg := range portable {
+		switch msg.Role {
+		case "user":
+			items = append(items, StoredInputItem{Type: "message", Role: "user", Content: msg.Content})
+		case "tool":
+			items = append(items, StoredInputItem{Type: "function_call_output", CallID: msg.ToolCallID, Output: msg.Content})
+		case "assistant":
+			if msg.Content != "" {
+				items = append(items, StoredInputItem{Type: "message", Role: "assistant", Content: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				items = append(items, StoredInputItem{Type: "function_call", CallID: call.ID, Name: call.Name, Arguments: call.Input})
+			}
+		}
+	}
+
+	raw, err := json.Marshal(items)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal responses items")
+	}
+	return raw, nil
+}
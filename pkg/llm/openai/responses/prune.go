@@ -0,0 +1,79 @@
+package responses
+
+import (
+	"encoding/json"
+
+	"github.com/jingkaihe/kodelet/pkg/llm/base"
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// pruneToolResults estimates the tokens held by all but the keepRecent most
+// recent function call outputs and, when apply is set, replaces their output
+// with a placeholder in both the input and the stored items.
+func (t *Thread) pruneToolResults(keepRecent int, apply bool) int {
+	var results []int
+	for i, item := range t.storedItems {
+		if item.Type == "function_call_output" {
+			results = append(results, i)
+		}
+	}
+
+	tokens := 0
+	pruned := make(map[string]bool)
+	for _, i := range results[:base.PrunableCount(len(results), keepRecent)] {
+		item := t.storedItems[i]
+		if item.Output == base.PrunedToolResultText && len(item.RawOutput) == 0 {
+			continue
+		}
+		tokens += storedOutputTokens(item)
+		if apply {
+			item.Output = base.PrunedToolResultText
+			item.RawOutput = nil
+			item.RawItem = nil
+			t.storedItems[i] = item
+			pruned[item.CallID] = true
+		}
+	}
+	if len(pruned) == 0 {
+		return tokens
+	}
+
+	for i, item := range t.inputItems {
+		output := item.OfFunctionCallOutput
+		if output == nil || !pruned[output.CallID] {
+			continue
+		}
+		replaced := *output
+		replaced.Output = responses.ResponseInputItemFunctionCallOutputOutputUnionParam{
+			OfString: param.NewOpt(base.PrunedToolResultText),
+		}
+		t.inputItems[i] = responses.ResponseInputItemUnionParam{OfFunctionCallOutput: &replaced}
+	}
+	// Earlier input changed, so the next request cannot continue the previous
+	// response.
+	t.webSocketContinuation.reset()
+	return tokens
+}
+
+func storedOutputTokens(item StoredInputItem) int {
+	if len(item.RawOutput) == 0 {
+		return base.EstimateTextTokens(item.Output)
+	}
+	var outputItems []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(item.RawOutput, &outputItems); err != nil {
+		return base.EstimateTextTokens(string(item.RawOutput))
+	}
+	tokens := 0
+	for _, part := range outputItems {
+		if part.Type == "input_text" {
+			tokens += base.EstimateTextTokens(part.Text)
+		} else {
+			tokens += base.EstimatedNonTextTokens
+		}
+	}
+	return tokens
+}
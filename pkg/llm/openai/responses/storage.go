@@ -42,11 +42,13 @@ type StoredInputItem struct {
 	// Optional structured raw output for multimodal function call outputs.
 	RawOutput json.RawMessage `json:"raw_output,omitempty"`
 
-	// Compaction fields (when Type == "compaction")
+	// Compaction fields (when Type == "compaction"), also set on reasoning
+	// items returned with encrypted content.
 	EncryptedContent string `json:"encrypted_content,omitempty"`
 
 	// Reasoning fields (when Type == "reasoning")
 	// Reasoning string is stored in Content field with Role == "assistant"
+	// and the reasoning item ID in CallID when EncryptedContent is set.
 
 	// RawItem stores the original Responses API item payload when available.
 	// This lets us preserve compact output variants without lossy field mapping.
@@ -54,11 +56,20 @@ type StoredInputItem struct {
 }
 
 // fromStoredItems converts storage format back to SDK input items for API calls.
-// Reasoning items are skipped as they're only for display, not sent to the API.
+// Reasoning items are sent only when they carry encrypted content.
 func fromStoredItems(items []StoredInputItem) []responses.ResponseInputItemUnionParam {
 	result := make([]responses.ResponseInputItemUnionParam, 0, len(items))
 
 	for _, item := range items {
+		if item.Type == "reasoning" && len(item.RawItem) == 0 {
+			// Streamed reasoning is replayed only when it carries encrypted
+			// content; the summary text is for display.
+			if item.EncryptedContent != "" {
+				result = append(result, encryptedReasoningInputItem(item.CallID, item.EncryptedContent))
+			}
+			continue
+		}
+
 		if item.Type == "message" && len(item.RawItem) > 0 {
 			if inputItem, ok := messageInputItemFromRawItem(item.RawItem); ok {
 				result = append(result, inputItem)
@@ -81,10 +92,6 @@ func fromStoredItems(items []StoredInputItem) []responses.ResponseInputItemUnion
 		}
 
 		switch item.Type {
-		case "reasoning":
-			// Reasoning is for display only, skip for API calls
-			continue
-
 		case "message":
 			role := responses.EasyInputMessageRole(item.Role)
 			result = append(result, responses.ResponseInputItemUnionParam{
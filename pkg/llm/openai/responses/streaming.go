@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/avast/retry-go/v4"
+	"github.com/jingkaihe/kodelet/pkg/auth"
 	"github.com/jingkaihe/kodelet/pkg/llm/base"
 	"github.com/jingkaihe/kodelet/pkg/logger"
 	"github.com/jingkaihe/kodelet/pkg/telemetry"
@@ -70,6 +71,7 @@ func (t *Thread) processStream(
 			Content: t.pendingReasoning.String(),
 		})
 		t.pendingReasoning.Reset()
+		t.journalStoredItems(ctx)
 	}
 
 	result := func() processStreamResult {
@@ -133,6 +135,7 @@ func (t *Thread) processStream(
 				if isStreaming {
 					streamHandler.HandleTextDelta(event.Delta)
 				}
+				t.JournalAssistantText(ctx, len(t.storedItems), event.Delta)
 			}
 
 		case "response.reasoning_text.delta", "response.reasoning_summary_text.delta":
@@ -187,6 +190,15 @@ func (t *Thread) processStream(
 			item := event.Item
 
 			switch item.Type {
+			case "reasoning":
+				// Summary deltas were stored as they streamed; keep the encrypted
+				// reasoning so store=false requests can replay it.
+				flushPendingReasoning()
+				if inputItem, ok := t.addEncryptedReasoning(item.AsReasoning()); ok {
+					serverKnownItems = append(serverKnownItems, inputItem)
+				}
+				t.journalStoredItems(ctx)
+
 			case "web_search_call":
 				if isStreaming && thinkingStarted {
 					streamHandler.HandleThinkingBlockEnd()
@@ -234,6 +246,7 @@ func (t *Thread) processStream(
 					storedItem.Content = strings.Join(details.queries, ", ")
 				}
 				t.storedItems = append(t.storedItems, storedItem)
+				t.journalStoredItems(ctx)
 				if inputItems := fromStoredItems([]StoredInputItem{storedItem}); len(inputItems) > 0 {
 					t.inputItems = append(t.inputItems, inputItems[0])
 					serverKnownItems = append(serverKnownItems, inputItems[0])
@@ -287,6 +300,7 @@ func (t *Thread) processStream(
 					Name:      funcCall.Name,
 					Arguments: funcCall.Arguments,
 				})
+				t.journalStoredItems(ctx)
 
 				// Execute the tool
 				result := t.executeToolCall(ctx, funcCall.CallID, funcCall.Name, funcCall.Arguments, handler)
@@ -310,6 +324,7 @@ func (t *Thread) processStream(
 					Output:    storedOutput,
 					RawOutput: rawOutput,
 				})
+				t.journalStoredItems(ctx)
 
 				handler.HandleToolResult(funcCall.CallID, funcCall.Name, result)
 
@@ -318,13 +333,23 @@ func (t *Thread) processStream(
 				msg := item.AsMessage()
 				if msg.Role == "assistant" {
 					// Extract text content
-					var textContent string
+					var textContent, refusal string
 					for _, content := range msg.Content {
-						if content.Type == "output_text" {
+						switch content.Type {
+						case "output_text":
 							textPart := content.AsOutputText()
 							textContent += textPart.Text
+						case "refusal":
+							refusal += content.AsRefusal().Refusal
 						}
 					}
+					if refusal != "" {
+						base.ReportContentFilter(ctx, handler, llmtypes.ContentFilterEvent{
+							Provider: t.Provider(),
+							Reason:   llmtypes.ContentFilterReasonRefusal,
+							Message:  refusal,
+						})
+					}
 					if textContent != "" {
 						if isStreaming && !contentBlockEnded && currentText.Len() > 0 {
 							streamHandler.HandleContentBlockEnd()
@@ -349,6 +374,7 @@ func (t *Thread) processStream(
 							RawItem: rawItem,
 						}
 						t.storedItems = append(t.storedItems, storedItem)
+						t.journalStoredItems(ctx)
 						if inputItems := fromStoredItems([]StoredInputItem{storedItem}); len(inputItems) > 0 {
 							t.inputItems = append(t.inputItems, inputItems[0])
 							serverKnownItems = append(serverKnownItems, inputItems[0])
@@ -376,13 +402,25 @@ func (t *Thread) processStream(
 		case "response.incomplete":
 			// Response ended but is incomplete (e.g. max_output_tokens/content_filter)
 			finalResponse = &event.Response
-			responseIncompleteReason = event.Response.IncompleteDetails.Reason
+			reason := event.Response.IncompleteDetails.Reason
 			telemetry.AddEvent(ctx, "response_incomplete",
 				attribute.String("response_id", event.Response.ID),
-				attribute.String("reason", responseIncompleteReason),
+				attribute.String("reason", reason),
 			)
 
 			finalizeContentBlocks()
+			if reason == llmtypes.ContentFilterReasonContentFilter {
+				// The turn ends with whatever was produced before the filter.
+				flushPendingReasoning()
+				responseCompleted = true
+				responseID = event.Response.ID
+				base.ReportContentFilter(ctx, handler, llmtypes.ContentFilterEvent{
+					Provider: t.Provider(),
+					Reason:   llmtypes.ContentFilterReasonContentFilter,
+				})
+			} else {
+				responseIncompleteReason = reason
+			}
 
 		case "response.failed", "error":
 			// Handle errors
@@ -416,13 +454,17 @@ func (t *Thread) processStream(
 
 	// Update usage from final response
 	if finalResponse != nil {
+		usageBefore := t.GetUsage()
 		t.updateUsage(finalResponse.Usage, model, llmtypes.OpenAIServiceTier(finalResponse.ServiceTier))
+		t.recordCodexReplay(ctx, finalResponse.Usage, finalResponse.PreviousResponseID != "")
+		t.RecordAPIKeyUsage(auth.APIKeySelectionFromContext(ctx).Name(), usageBefore)
+		t.RecordExchangeMetrics(model, usageBefore)
 		if usageHandler, ok := handler.(llmtypes.UsageMessageHandler); ok {
 			usageHandler.HandleUsage(t.GetUsage())
 		}
 
 		if !opt.DisableUsageLog {
-			usage.LogLLMUsage(ctx, t.GetUsage(), model, apiStartTime, int(finalResponse.Usage.OutputTokens))
+			usage.LogLLMUsageWithResources(t.UsageLogContext(ctx), t.GetUsage(), t.GetResourceUsage(), model, apiStartTime, int(finalResponse.Usage.OutputTokens))
 		}
 	}
 
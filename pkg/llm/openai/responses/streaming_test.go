@@ -121,6 +121,10 @@ func (h *captureStreamHandler) HandleContentBlockEnd() {
 	h.events = append(h.events, "content_block_end")
 }
 
+func (h *captureStreamHandler) HandleContentFilter(event llmtypes.ContentFilterEvent) {
+	h.events = append(h.events, "content_filter:"+event.Reason+":"+event.Message)
+}
+
 type fakeMultiModalToolResult struct {
 	tooltypes.BaseToolResult
 	parts []tooltypes.ToolResultContentPart
@@ -813,6 +817,69 @@ func TestProcessStreamReturnsRetryableErrorOnIncompleteResponse(t *testing.T) {
 	assert.True(t, retry.IsRecoverable(err))
 }
 
+func TestProcessStreamEndsTurnOnContentFilter(t *testing.T) {
+	t.Run("incomplete response", func(t *testing.T) {
+		stream := responseStreamFromMaps(t, []map[string]any{
+			{"type": "response.output_text.delta", "delta": "Partial"},
+			{
+				"type": "response.incomplete",
+				"response": map[string]any{
+					"id":     "resp_filtered",
+					"status": "incomplete",
+					"incomplete_details": map[string]any{
+						"reason": "content_filter",
+					},
+				},
+			},
+		})
+		thread := &Thread{Thread: base.NewThread(llmtypes.Config{Provider: "openai", Model: "gpt-5.5"}, "test")}
+		handler := &captureStreamHandler{}
+
+		streamResult, err := thread.processStream(context.Background(), stream, handler, "gpt-5.5", llmtypes.MessageOpt{DisableUsageLog: true})
+		require.NoError(t, err)
+		assert.True(t, streamResult.responseCompleted)
+		assert.Equal(t, "resp_filtered", streamResult.responseID)
+		assert.Equal(t, []string{
+			"text_delta:Partial",
+			"content_block_end",
+			"content_filter:content_filter:",
+		}, handler.events)
+	})
+
+	t.Run("refusal content", func(t *testing.T) {
+		stream := responseStreamFromMaps(t, []map[string]any{
+			{
+				"type": "response.output_item.done",
+				"item": map[string]any{
+					"id":     "msg_1",
+					"type":   "message",
+					"role":   "assistant",
+					"status": "completed",
+					"content": []map[string]any{{
+						"type":    "refusal",
+						"refusal": "I can't help with that.",
+					}},
+				},
+			},
+			{
+				"type": "response.completed",
+				"response": map[string]any{
+					"id":     "resp_refused",
+					"status": "completed",
+				},
+			},
+		})
+		thread := &Thread{Thread: base.NewThread(llmtypes.Config{Provider: "openai", Model: "gpt-5.5"}, "test")}
+		handler := &captureStreamHandler{}
+
+		streamResult, err := thread.processStream(context.Background(), stream, handler, "gpt-5.5", llmtypes.MessageOpt{DisableUsageLog: true})
+		require.NoError(t, err)
+		assert.True(t, streamResult.responseCompleted)
+		assert.Equal(t, []string{"content_filter:refusal:I can't help with that."}, handler.events)
+		assert.Empty(t, thread.storedItems)
+	})
+}
+
 func TestProcessStreamEndsCommittedMessageBeforeRetryableFailure(t *testing.T) {
 	stream := responseStreamFromMaps(t, []map[string]any{
 		{"type": "response.output_text.delta", "delta": "Committed"},
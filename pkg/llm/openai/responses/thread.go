@@ -20,10 +20,13 @@ import (
 	"github.com/jingkaihe/kodelet/pkg/conversations"
 	"github.com/jingkaihe/kodelet/pkg/goals"
 	"github.com/jingkaihe/kodelet/pkg/llm/base"
+	"github.com/jingkaihe/kodelet/pkg/llm/chaos"
 	"github.com/jingkaihe/kodelet/pkg/llm/openai/copilotdefaults"
 	codexpreset "github.com/jingkaihe/kodelet/pkg/llm/openai/preset/codex"
 	openaipreset "github.com/jingkaihe/kodelet/pkg/llm/openai/preset/openai"
+	"github.com/jingkaihe/kodelet/pkg/llm/ratelimit"
 	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/pricing"
 	"github.com/jingkaihe/kodelet/pkg/steer"
 	"github.com/jingkaihe/kodelet/pkg/sysprompt"
 	"github.com/jingkaihe/kodelet/pkg/telemetry"
@@ -68,6 +71,10 @@ type Thread struct {
 	// This mirrors how Anthropic stores thinking blocks inline with messages
 	storedItems []StoredInputItem
 
+	// journaledItems is the number of storedItems written to the
+	// conversation journal
+	journaledItems int
+
 	// pendingReasoning accumulates reasoning content during streaming
 	// It's stored here (not locally in processStream) to persist across API calls
 	pendingReasoning strings.Builder
@@ -94,6 +101,11 @@ type Thread struct {
 	authorizer            auth.HTTPAuthorizer
 	webSocket             responsesWebSocketStreamer
 	webSocketContinuation responsesWebSocketContinuation
+	// codexReplay measures the history Codex requests resend.
+	codexReplay codexReplayStats
+	// rateLimiter paces requests sent over the WebSocket transport, which
+	// bypasses the HTTP middleware; nil when rate_limit is not configured.
+	rateLimiter *ratelimit.Limiter
 
 	processMessageExchangeFunc func(
 		ctx context.Context,
@@ -136,6 +148,32 @@ func NewThread(config llmtypes.Config) (*Thread, error) {
 	if err != nil {
 		return nil, err
 	}
+	rateLimiter := ratelimit.Shared("openai", config.RateLimit)
+	if middleware := ratelimit.Middleware(rateLimiter); middleware != nil {
+		opts = append(opts, option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+			return middleware(req, next)
+		}))
+	}
+	// Codex and Copilot responses report how full the rate limit windows are
+	if authInfo.useCodex || authInfo.useCopilot {
+		baseThread.EnableQuotaTracking()
+		tracker := baseThread.QuotaTracker()
+		opts = append(opts, option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+			resp, err := next(req)
+			if resp != nil {
+				tracker.Observe(resp.Header)
+			}
+			return resp, err
+		}))
+	}
+	// Simulated failures are injected into HTTP requests only, so chaos mode
+	// also turns off the WebSocket transport.
+	chaosMiddleware := chaos.Middleware("openai")
+	if chaosMiddleware != nil {
+		opts = append(opts, option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+			return chaosMiddleware(req, next)
+		}))
+	}
 
 	// Create the OpenAI client
 	client := openai.NewClient(opts...)
@@ -158,8 +196,9 @@ func NewThread(config llmtypes.Config) (*Thread, error) {
 		customPricing:   customPricing,
 		isCodex:         authInfo.useCodex,
 		useCopilot:      authInfo.useCopilot,
-		useWebSocket:    shouldUseResponsesWebSocket(config),
+		useWebSocket:    shouldUseResponsesWebSocket(config) && chaosMiddleware == nil,
 		authorizer:      authInfo.authorizer,
+		rateLimiter:     rateLimiter,
 	}
 	if thread.useWebSocket && supportsResponsesWebSocket(config) {
 		thread.webSocket = newResponsesWebSocketTransport(authInfo.baseURL)
@@ -170,11 +209,14 @@ func NewThread(config llmtypes.Config) (*Thread, error) {
 	thread.compactFunc = thread.client.Responses.Compact
 	thread.compactRawFunc = thread.compactRawJSON
 	thread.compactWithSummaryFunc = func(ctx context.Context) error {
-		return base.CompactContextWithSummary(ctx, thread.runUtilityPrompt, thread.SwapContext)
+		return thread.CompactContextWithSummary(ctx, thread.runUtilityPrompt, thread.SwapContext)
 	}
 
 	// Set the LoadConversation callback for provider-specific loading
 	baseThread.LoadConversation = thread.loadConversation
+	baseThread.PruneToolResults = thread.pruneToolResults
+	baseThread.CurrentMessages = thread.GetMessages
+	baseThread.ServerCompaction = supportsNativeResponsesCompact(config)
 
 	log.Debug("OpenAI Responses API thread created successfully")
 	return thread, nil
@@ -207,6 +249,7 @@ func (t *Thread) AddUserMessage(ctx context.Context, message string, imagePaths
 			},
 		}
 		t.addInputItem(inputItem, message)
+		t.journalStoredItems(ctx)
 		return
 	}
 
@@ -241,6 +284,20 @@ func (t *Thread) AddUserMessage(ctx context.Context, message string, imagePaths
 	}
 
 	t.addInputItem(inputItem, message)
+	t.journalStoredItems(ctx)
+}
+
+// journalStoredItems journals the items added to storedItems since it was
+// last called.
+func (t *Thread) journalStoredItems(ctx context.Context) {
+	if t.Thread == nil {
+		return
+	}
+	// Compaction replaces the history with a shorter one
+	t.journaledItems = min(t.journaledItems, len(t.storedItems))
+	for ; t.journaledItems < len(t.storedItems); t.journaledItems++ {
+		t.JournalMessage(ctx, t.journaledItems, t.storedItems[t.journaledItems])
+	}
 }
 
 func userImageInputItem(ctx context.Context, imagePaths []string) (responses.ResponseInputItemUnionParam, bool) {
@@ -316,17 +373,16 @@ func (t *Thread) SendMessage(
 		copy(originalInputItems, t.inputItems)
 	}
 
+	defer t.TrackRun(ctx, opt)()
+	t.StartJournal(t.Provider(), opt)
+	defer t.StopJournal()
+	t.journaledItems = len(t.storedItems)
+
 	message, err = base.ProcessUserMessage(ctx, t, message)
 	if err != nil {
 		return "", err
 	}
 
-	if len(opt.Images) > 0 {
-		t.AddUserMessage(ctx, message, opt.Images...)
-	} else {
-		t.AddUserMessage(ctx, message)
-	}
-
 	// Determine which model to use
 	model := t.Config.Model
 	maxTokens := t.Config.MaxTokens
@@ -337,8 +393,23 @@ func (t *Thread) SendMessage(
 		}
 	}
 
+	if len(opt.Images) > 0 && !t.modelFeatures(model).Vision() {
+		logger.G(ctx).WithField("model", model).Warn("model does not support image input, dropping attached images")
+		opt.Images = nil
+	}
+
+	if len(opt.Images) > 0 {
+		t.AddUserMessage(ctx, message, opt.Images...)
+	} else {
+		t.AddUserMessage(ctx, message)
+	}
+
 	turnCount := 0
 	maxTurns := max(opt.MaxTurns, 0)
+	budget := t.StartBudget(opt)
+	var budgetErr error
+	replayBefore := t.codexReplay
+	defer t.logCodexReplaySummary(ctx, replayBefore)
 	base.DispatchAgentStart(ctx, t)
 
 OUTER:
@@ -353,6 +424,10 @@ OUTER:
 				logger.G(ctx).WithField("turn_count", turnCount).
 					WithField("max_turns", maxTurns).
 					Warn("reached maximum turn limit, stopping interaction")
+				t.MarkMaxTurnsReached()
+				break OUTER
+			}
+			if budgetErr = budget.Check(ctx, turnCount); budgetErr != nil {
 				break OUTER
 			}
 
@@ -364,19 +439,30 @@ OUTER:
 				contexts = t.State.DiscoverContexts()
 			}
 
-			systemPrompt := base.ProcessSystemPrompt(ctx, t, sysprompt.SystemPrompt(model, t.Config, contexts))
+			turnModel, turnMaxTokens := model, maxTokens
+			useWeakModel, err := base.ApplyQuotaPolicy(ctx, t)
+			if err != nil {
+				return "", err
+			}
+			if useWeakModel {
+				turnModel = t.Config.WeakModel
+				if t.Config.WeakModelMaxTokens > 0 {
+					turnMaxTokens = t.Config.WeakModelMaxTokens
+				}
+			}
+			systemPrompt := base.ProcessSystemPrompt(ctx, t, sysprompt.SystemPrompt(turnModel, t.Config, contexts))
 
 			// Check if auto-compact should be triggered
 			t.TryAutoCompact(ctx, t.CompactRatioOrDefault(opt.CompactRatio), t.CompactContext)
 
 			exchangeOpt := opt.WithTurnInitiator(turnCount)
 
-			logger.G(ctx).WithField("model", model).Debug("starting message exchange")
+			logger.G(ctx).WithField("model", turnModel).Debug("starting message exchange")
 			processExchange := t.processMessageExchangeFunc
 			if processExchange == nil {
 				processExchange = t.processMessageExchange
 			}
-			exchangeOutput, toolsUsed, responseCompleted, err := processExchange(ctx, handler, model, maxTokens, systemPrompt, exchangeOpt)
+			exchangeOutput, toolsUsed, responseCompleted, err := processExchange(ctx, handler, turnModel, turnMaxTokens, systemPrompt, exchangeOpt)
 			if err != nil {
 				if errors.Is(err, context.Canceled) {
 					logger.G(ctx).Info("Request cancelled, stopping kodelet.llm.openai.responses")
@@ -396,9 +482,15 @@ OUTER:
 			finalOutput = exchangeOutput
 
 			base.TriggerTurnEnd(ctx, t, finalOutput, turnCount)
+			if toolsUsed {
+				base.HandleTodoDiscipline(ctx, t, base.AvailableToolsForThread(t, t.State, opt.NoToolUse))
+			}
 
 			// If no tools were used, check for queued continuations before stopping
 			if !toolsUsed {
+				if base.ReviewDeferredTools(ctx, t, handler) {
+					continue OUTER
+				}
 				if base.HandleAgentStopFollowUps(ctx, t, handler) {
 					continue OUTER
 				}
@@ -414,6 +506,8 @@ OUTER:
 		}
 	}
 
+	base.DiscardDeferredTools(ctx, t, handler)
+
 	if opt.NoSaveConversation {
 		t.inputItems = originalInputItems
 	}
@@ -426,7 +520,7 @@ OUTER:
 
 	handler.HandleDone()
 
-	return finalOutput, nil
+	return finalOutput, budgetErr
 }
 
 // applyCodexRestrictions modifies request parameters for Codex API compatibility.
@@ -442,6 +536,7 @@ func (t *Thread) applyCodexRestrictions(params *responses.ResponseNewParams) {
 	// server-side stored conversation state.
 	params.Store = param.NewOpt(false)
 	params.MaxOutputTokens = param.Opt[int64]{}
+	requestEncryptedReasoning(params)
 
 	if t.State != nil {
 		contexts := t.State.DiscoverContexts()
@@ -493,8 +588,11 @@ func (t *Thread) processMessageExchange(
 		return "", false, false, errors.Wrap(err, "failed to process pending steer")
 	}
 
-	// Build tools
-	tools := buildToolsForThread(t, t.State, opt.NoToolUse)
+	features := t.modelFeatures(model)
+	maxTokens = features.ClampMaxTokens(maxTokens)
+
+	// Build tools, leaving them out for models that reject tool definitions
+	tools := buildToolsForThread(t, t.State, opt.NoToolUse || !features.Tools())
 	log.WithField("tool_count", len(tools)).Debug("built tools for request")
 
 	// Keep a complete local input history for persistence, HTTP prompt caching, and
@@ -511,6 +609,9 @@ func (t *Thread) processMessageExchange(
 			OfToolChoiceMode: param.NewOpt(responses.ToolChoiceOptionsAuto),
 		},
 	}
+	if len(tools) > 0 && !features.ParallelToolCalls() {
+		params.ParallelToolCalls = param.NewOpt(false)
+	}
 	if sendTextVerbosity {
 		params.Text = responses.ResponseTextConfigParam{
 			Verbosity: responses.ResponseTextConfigVerbosity(textVerbosity),
@@ -529,7 +630,7 @@ func (t *Thread) processMessageExchange(
 
 	// Add reasoning configuration for reasoning models (o-series, gpt-5, etc.)
 	if t.isReasoningModelDynamic(model) && t.reasoningEffort != "" {
-		reasoningEffort := t.reasoningEffort
+		reasoningEffort := shared.ReasoningEffort(opt.ResolvedReasoningEffort(string(t.reasoningEffort)))
 		if opt.UseWeakModel {
 			reasoningEffort = shared.ReasoningEffortMedium
 		}
@@ -576,6 +677,12 @@ func (t *Thread) processMessageExchange(
 	if useWebSocket {
 		transportName = "websocket"
 		newResponsesStream = func(ctx context.Context, params responses.ResponseNewParams) (*responsesStreamAttempt, error) {
+			if t.rateLimiter != nil {
+				body, _ := json.Marshal(params)
+				if err := t.rateLimiter.Wait(ctx, ratelimit.EstimateTokens(int64(len(body)))); err != nil {
+					return nil, err
+				}
+			}
 			stream, generation, err := t.webSocket.Stream(
 				ctx,
 				func(connectionGeneration uint64) responses.ResponseNewParams {
@@ -609,6 +716,7 @@ func (t *Thread) processMessageExchange(
 		}
 	}
 
+	ctx, _ = auth.WithAPIKeySelection(ctx)
 	return t.processMessageExchangeWithStreamRetries(ctx, handler, model, params, tools, newResponsesStream, closeResponsesStream, processStream, opt, saveConversation, transportName)
 }
 
@@ -713,6 +821,7 @@ func (t *Thread) processMessageExchangeWithStreamRetries(
 		retry.MaxDelay(time.Duration(retryConfig.MaxDelay)*time.Millisecond),
 		retry.Context(ctx),
 		retry.OnRetry(func(n uint, err error) {
+			telemetry.RecordLLMRetry("openai")
 			log.WithError(err).
 				WithField("attempt", n+1).
 				WithField("max_attempts", retryConfig.Attempts).
@@ -920,6 +1029,7 @@ func (t *Thread) processPendingSteer(ctx context.Context, handler llmtypes.Messa
 			Content: steerMsg.Content,
 			RawItem: rawItem,
 		})
+		t.journalStoredItems(ctx)
 
 		if userHandler, ok := handler.(llmtypes.UserMessageHandler); ok {
 			userHandler.HandleUserMessage(steerMsg.Content, steerMsg.Images)
@@ -1046,7 +1156,7 @@ func (t *Thread) CompactContext(ctx context.Context) error {
 	compactWithSummary := t.compactWithSummaryFunc
 	if compactWithSummary == nil {
 		compactWithSummary = func(ctx context.Context) error {
-			return base.CompactContextWithSummary(ctx, t.runUtilityPrompt, t.SwapContext)
+			return t.CompactContextWithSummary(ctx, t.runUtilityPrompt, t.SwapContext)
 		}
 	}
 	if !supportsNativeResponsesCompact(t.Config) {
@@ -1168,9 +1278,19 @@ func (t *Thread) CompactContext(ctx context.Context) error {
 		return nil
 	}
 
+	compacted := t.MessagesBeforeCompaction(ctx)
+
 	// Replace input items with compacted output
 	t.inputItems = newInputItems
 	t.storedItems = newStoredItems
+	if reinjection := base.RecentFilesReinjection(t.State, t.Config.CompactReinjectTokens); reinjection != "" {
+		t.addInputItem(responses.ResponseInputItemUnionParam{
+			OfMessage: &responses.EasyInputMessageParam{
+				Role:    responses.EasyInputMessageRoleUser,
+				Content: responses.EasyInputMessageContentUnionParam{OfString: param.NewOpt(reinjection)},
+			},
+		}, reinjection)
+	}
 
 	t.ResetContextStateLocked()
 	pricing := t.getPricing(t.Config.Model)
@@ -1200,6 +1320,7 @@ func (t *Thread) CompactContext(ctx context.Context) error {
 		t.Usage.CurrentContextWindow = max(estimatedContext/4, 1)
 	}
 
+	t.TriggerContextCompacted(ctx, "", compacted)
 	return nil
 }
 
@@ -1372,6 +1493,7 @@ func (t *Thread) SaveConversation(ctx context.Context, summarize bool) error {
 	if !t.Persisted || t.Store == nil {
 		return nil
 	}
+	journalPosition := t.JournalPosition(ctx)
 
 	// Clean up orphaned messages before saving
 	t.cleanupOrphanedItems()
@@ -1381,7 +1503,8 @@ func (t *Thread) SaveConversation(ctx context.Context, summarize bool) error {
 		return errors.Wrap(err, "failed to parse conversation for summary")
 	}
 	metadata := t.GetMetadata()
-	summary := base.FirstUserMessageFallback(conversations.ApplyDisplayToStreamableMessages(conversationsFromResponses(messages), metadata))
+	displayMessages := conversations.ApplyDisplayToStreamableMessages(conversationsFromResponses(messages), metadata)
+	summary := base.FirstUserMessageFallback(displayMessages)
 
 	// Generate a new summary if requested and enabled; otherwise keep the first user message.
 	if summarize {
@@ -1395,6 +1518,9 @@ func (t *Thread) SaveConversation(ctx context.Context, summarize bool) error {
 		}
 	}
 	t.summary = summary
+	if summarize {
+		t.RecordConversationTitle(ctx, metadata, displayMessages, toolResults, t.runUtilityPrompt)
+	}
 
 	// Serialize stored items directly (already built inline during streaming)
 	inputItemsJSON, err := json.Marshal(t.storedItems)
@@ -1424,6 +1550,10 @@ func (t *Thread) SaveConversation(ctx context.Context, summarize bool) error {
 	if err != nil {
 		return errors.Wrap(err, "failed to persist conversation config snapshot")
 	}
+	metadata = conversations.AddExperiment(metadata, t.Config.ExperimentAssignment)
+	if snapshot, ok := t.ContextSnapshot(ctx, summarize); ok {
+		metadata = conversations.AddContextSnapshot(metadata, snapshot)
+	}
 
 	record := convtypes.ConversationRecord{
 		ID:          t.ConversationID,
@@ -1438,7 +1568,11 @@ func (t *Thread) SaveConversation(ctx context.Context, summarize bool) error {
 		ToolResults: toolResults,
 	}
 
-	return t.Store.Save(ctx, record)
+	if err := t.Store.Save(ctx, record); err != nil {
+		return err
+	}
+	t.ClearJournal(ctx, journalPosition)
+	return nil
 }
 
 // loadConversation loads a conversation from the store.
@@ -1448,9 +1582,11 @@ func (t *Thread) loadConversation(ctx context.Context) {
 		return
 	}
 
+	// A conversation killed before its first save may only exist in its
+	// journal
 	record, err := t.Store.Load(ctx, t.ConversationID)
 	if err != nil {
-		return
+		record = convtypes.NewConversationRecord(t.ConversationID)
 	}
 
 	if record.Provider != "" {
@@ -1464,11 +1600,17 @@ func (t *Thread) loadConversation(ctx context.Context) {
 		}
 	}
 
+	replay := t.ReplayJournal(ctx, &record)
+	if err != nil && replay.Messages == 0 {
+		return
+	}
+
 	// Deserialize from storage format
 	var storedItems []StoredInputItem
 	if err := json.Unmarshal(record.RawMessages, &storedItems); err != nil {
 		return
 	}
+	storedItems = recoverJournaledTurn(storedItems, replay)
 
 	// Store the loaded items directly and convert to SDK format for API calls
 	t.storedItems = storedItems
@@ -1480,6 +1622,24 @@ func (t *Thread) loadConversation(ctx context.Context) {
 	t.SetStructuredToolResults(record.ToolResults)
 }
 
+// recoverJournaledTurn adds the text replay recovered as an assistant message
+// item, or the output of a trailing function call.
+func recoverJournaledTurn(items []StoredInputItem, replay convtypes.JournalReplay) []StoredInputItem {
+	if !replay.Recovered() {
+		return items
+	}
+	if replay.AssistantText != "" {
+		return append(items, StoredInputItem{Type: "message", Role: "assistant", Content: replay.AssistantText})
+	}
+	if len(items) == 0 || items[len(items)-1].Type != "function_call" {
+		return items
+	}
+
+	callID := items[len(items)-1].CallID
+	output := base.RecoveredToolOutput(replay, callID)
+	return append(items, StoredInputItem{Type: "function_call_output", CallID: callID, Output: output.Text})
+}
+
 // cleanupOrphanedItems removes incomplete tool call sequences from the end.
 func (t *Thread) cleanupOrphanedItems() {
 	// Remove trailing tool calls without results
@@ -1570,6 +1730,11 @@ func normalizeServiceTier(config llmtypes.Config) llmtypes.OpenAIServiceTier {
 }
 
 func shouldUseResponsesWebSocket(config llmtypes.Config) bool {
+	// A websocket connection keeps the key it was opened with, so pooled keys
+	// need a request per exchange to rotate.
+	if config.OpenAI != nil && len(config.OpenAI.APIKeys) > 0 {
+		return false
+	}
 	if config.OpenAI != nil && config.OpenAI.WebSocketMode != nil {
 		return *config.OpenAI.WebSocketMode
 	}
@@ -1753,14 +1918,24 @@ func buildCodexAuthOptions(config llmtypes.Config, log *logrus.Entry) ([]option.
 
 // buildAPIKeyAuthOptions returns client options for standard API key authentication.
 func buildAPIKeyAuthOptions(config llmtypes.Config, log *logrus.Entry) ([]option.RequestOption, auth.HTTPAuthorizer, error) {
-	apiKeyEnvVar := getAPIKeyEnvVar(config)
-	authorizer, err := auth.OpenAIAPIKeyAuthorizerFromEnv(apiKeyEnvVar)
-	if err != nil {
-		return nil, nil, err
+	var authorizer auth.HTTPAuthorizer
+	if config.OpenAI != nil && len(config.OpenAI.APIKeys) > 0 {
+		pool, err := auth.SharedKeyPool("openai", config.OpenAI.APIKeys)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to set up the OpenAI API key pool")
+		}
+		authorizer = auth.OpenAIKeyPoolAuthorizer(pool)
+		log.WithField("api_keys", len(config.OpenAI.APIKeys)).Debug("using OpenAI API key pool for Responses API")
+	} else {
+		apiKeyEnvVar := getAPIKeyEnvVar(config)
+		var err error
+		authorizer, err = auth.OpenAIAPIKeyAuthorizerFromEnv(apiKeyEnvVar)
+		if err != nil {
+			return nil, nil, err
+		}
+		log.WithField("api_key_env_var", apiKeyEnvVar).Debug("using OpenAI API key for Responses API")
 	}
 
-	log.WithField("api_key_env_var", apiKeyEnvVar).Debug("using OpenAI API key for Responses API")
-
 	opts := auth.OpenAIRequestOptionsWithAuthorizer(authorizer)
 	if baseURL := getBaseURL(config); baseURL != "" {
 		opts = append(opts, option.WithBaseURL(baseURL))
@@ -1805,9 +1980,9 @@ func loadPlatformDefaultsForConfig(platformName string, config llmtypes.Config)
 func loadPlatformDefaultsForServiceTier(platformName string, serviceTier llmtypes.OpenAIServiceTier) (map[string]string, map[string]llmtypes.ModelPricing) {
 	switch normalizePlatformName(platformName) {
 	case "openai":
-		return loadPlatformDefaultsFromConfig(openaipreset.Models, openaipreset.PricingForServiceTier(serviceTier))
+		return loadPlatformDefaultsFromConfig(openaipreset.Models, pricing.ApplyPlatformPricing(openaipreset.PricingForServiceTier(serviceTier), "openai", serviceTier))
 	case "codex":
-		return loadPlatformDefaultsFromConfig(codexpreset.Models, codexpreset.PricingForServiceTier(serviceTier))
+		return loadPlatformDefaultsFromConfig(codexpreset.Models, pricing.ApplyPlatformPricing(codexpreset.PricingForServiceTier(serviceTier), "codex", serviceTier))
 	case "copilot":
 		models, pricing, err := copilotdefaults.LoadPlatformDefaults(context.Background())
 		if err == nil {
@@ -1856,6 +2031,17 @@ func (t *Thread) getPricing(model string) llmtypes.ModelPricing {
 	}
 }
 
+// modelFeatures returns what model accepts, from the platform preset and the
+// model_features configuration.
+func (t *Thread) modelFeatures(model string) llmtypes.ModelFeatures {
+	var presets llmtypes.CustomFeatures
+	switch normalizePlatformName(resolvePlatformForLoading(t.Config)) {
+	case "openai", "copilot":
+		presets = openaipreset.Features
+	}
+	return llmtypes.ResolveModelFeatures(t.Config, presets, model)
+}
+
 // getPricingForServiceTier selects built-in pricing using the processing tier
 // reported by the API. Explicit pricing from configuration remains authoritative.
 func (t *Thread) getPricingForServiceTier(model string, serviceTier llmtypes.OpenAIServiceTier) llmtypes.ModelPricing {
@@ -1898,9 +2084,35 @@ type StreamableMessage struct {
 	Role       string // "user", "assistant", "system"
 	Content    string // Text content
 	RawItem    json.RawMessage
-	ToolName   string // For tool use/result
-	ToolCallID string // For matching tool results
-	Input      string // For tool use (JSON string)
+	ToolName   string              // For tool use/result
+	ToolCallID string              // For matching tool results
+	Input      string              // For tool use (JSON string)
+	Reasoning  *llmtypes.Reasoning // For thinking
+}
+
+// ReasoningFromItem converts a stored reasoning item into the
+// provider-neutral transcript form. The text is the reasoning summary, and
+// the encrypted content, when present, is kept as the opaque payload in the
+// form it is replayed to the API.
+func ReasoningFromItem(item StoredInputItem) (llmtypes.Reasoning, bool) {
+	if item.Type != "reasoning" {
+		return llmtypes.Reasoning{}, false
+	}
+	reasoning := llmtypes.Reasoning{
+		Provider: "openai",
+		Format:   llmtypes.ReasoningFormatSummary,
+		Text:     item.Content,
+	}
+	if reasoning.DisplayText() == "" {
+		reasoning.Format = llmtypes.ReasoningFormatRedacted
+	}
+	switch {
+	case len(item.RawItem) > 0:
+		reasoning.Opaque = item.RawItem
+	case item.EncryptedContent != "":
+		reasoning.Opaque, _ = json.Marshal(encryptedReasoningInputItem(item.CallID, item.EncryptedContent))
+	}
+	return reasoning, true
 }
 
 const compactedHistoryNotice = "Context compacted"
@@ -1945,11 +2157,15 @@ func StreamMessages(rawMessages json.RawMessage, toolResults map[string]tooltype
 	for _, item := range displayItems {
 		switch item.Type {
 		case "reasoning":
-			// Add thinking message
+			reasoning, _ := ReasoningFromItem(item)
+			if reasoning.DisplayText() == "" {
+				continue
+			}
 			streamable = append(streamable, StreamableMessage{
-				Kind:    "thinking",
-				Role:    "assistant",
-				Content: item.Content,
+				Kind:      "thinking",
+				Role:      "assistant",
+				Content:   reasoning.DisplayText(),
+				Reasoning: &reasoning,
 			})
 
 		case "message":
@@ -2053,10 +2269,13 @@ func ExtractMessages(data []byte, toolResults map[string]tooltypes.StructuredToo
 	for _, item := range displayItems {
 		switch item.Type {
 		case "reasoning":
-			// Add thinking message
+			reasoning, _ := ReasoningFromItem(item)
+			if reasoning.TranscriptText() == "" {
+				continue
+			}
 			result = append(result, llmtypes.Message{
 				Role:    "assistant",
-				Content: fmt.Sprintf("💭 Thinking:\n%s", item.Content),
+				Content: reasoning.TranscriptText(),
 			})
 
 		case "message":
@@ -2108,6 +2327,47 @@ func ExtractMessages(data []byte, toolResults map[string]tooltypes.StructuredToo
 	return result, nil
 }
 
+// RedactToolResults replaces the outputs of the given tools with a
+// placeholder, keeping each function_call_output item so calls stay paired.
+// It returns the rewritten items and the IDs of the redacted calls.
+func RedactToolResults(data []byte, toolNames conversations.ToolRedactionSet) ([]byte, []string, error) {
+	var items []StoredInputItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, nil, errors.Wrap(err, "error unmarshaling input items")
+	}
+
+	redactedTools := make(map[string]string)
+	for _, item := range items {
+		if item.Type == "function_call" && toolNames.Contains(item.Name) {
+			redactedTools[item.CallID] = item.Name
+		}
+	}
+
+	var redactedIDs []string
+	for i := range items {
+		if items[i].Type != "function_call_output" {
+			continue
+		}
+		toolName, ok := redactedTools[items[i].CallID]
+		if !ok {
+			continue
+		}
+		items[i].Output = conversations.RedactedToolResultPlaceholder(toolName)
+		items[i].RawOutput = nil
+		items[i].RawItem = nil
+		redactedIDs = append(redactedIDs, items[i].CallID)
+	}
+	if len(redactedIDs) == 0 {
+		return data, nil, nil
+	}
+
+	redacted, err := json.Marshal(items)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to marshal redacted input items")
+	}
+	return redacted, redactedIDs, nil
+}
+
 func webSearchStoredInput(item StoredInputItem) string {
 	details := webSearchDetailsFromStoredItem(item)
 	payload := map[string]any{
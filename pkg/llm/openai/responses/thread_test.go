@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -301,6 +302,29 @@ func TestNewThreadCanDisableWebSocketMode(t *testing.T) {
 	assert.Nil(t, thread.webSocket)
 }
 
+func TestNewThreadUsesHTTPStreamingWithKeyPool(t *testing.T) {
+	t.Setenv("POOL_KEY_A", "key-a")
+	t.Setenv("POOL_KEY_B", "key-b")
+
+	config := llmtypes.Config{
+		Provider: "openai",
+		Model:    "gpt-4.1",
+		OpenAI: &llmtypes.OpenAIConfig{
+			Platform: "openai",
+			APIMode:  llmtypes.OpenAIAPIModeResponses,
+			APIKeys: []llmtypes.APIKeyConfig{
+				{Name: "a", EnvVar: "POOL_KEY_A", Weight: 2},
+				{Name: "b", EnvVar: "POOL_KEY_B"},
+			},
+		},
+	}
+
+	thread, err := NewThread(config)
+	require.NoError(t, err)
+	assert.False(t, thread.useWebSocket, "keys rotate per request, which a websocket connection cannot do")
+	assert.Nil(t, thread.webSocket)
+}
+
 func TestSupportsResponsesWebSocket(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -1652,6 +1676,27 @@ func TestCleanupOrphanedItems_RemovesTrailingFunctionCallFromStorage(t *testing.
 	assert.Equal(t, "message", thread.storedItems[0].Type)
 }
 
+func TestRecoverJournaledTurn(t *testing.T) {
+	items := []StoredInputItem{
+		{Type: "message", Role: "user", Content: "list files"},
+		{Type: "function_call", CallID: "call-1", Name: "bash", Arguments: `{"command":"ls"}`},
+	}
+
+	recovered := recoverJournaledTurn(items, convtypes.JournalReplay{ToolOutputs: map[string]convtypes.JournalToolOutput{"call-1": {Text: "README.md"}}})
+	require.Len(t, recovered, 3)
+	assert.Equal(t, StoredInputItem{Type: "function_call_output", CallID: "call-1", Output: "README.md"}, recovered[2])
+
+	recovered = recoverJournaledTurn(items, convtypes.JournalReplay{Messages: 1})
+	require.Len(t, recovered, 3)
+	assert.Equal(t, base.InterruptedToolCallOutput, recovered[2].Output)
+
+	recovered = recoverJournaledTurn(items[:1], convtypes.JournalReplay{Messages: 1, AssistantText: "Listing"})
+	require.Len(t, recovered, 2)
+	assert.Equal(t, StoredInputItem{Type: "message", Role: "assistant", Content: "Listing"}, recovered[1])
+
+	assert.Len(t, recoverJournaledTurn(items, convtypes.JournalReplay{}), 2)
+}
+
 func TestLoadCustomConfiguration(t *testing.T) {
 	config := llmtypes.Config{
 		OpenAI: &llmtypes.OpenAIConfig{
@@ -2002,6 +2047,14 @@ func (*mockResponsesConversationStore) Query(_ context.Context, _ convtypes.Quer
 	return convtypes.QueryResult{}, nil
 }
 
+func (*mockResponsesConversationStore) Fork(_ context.Context, _ string) (convtypes.ConversationRecord, error) {
+	return convtypes.ConversationRecord{}, nil
+}
+
+func (*mockResponsesConversationStore) Search(_ context.Context, _ string, _ convtypes.QueryOptions) (convtypes.SearchResult, error) {
+	return convtypes.SearchResult{}, nil
+}
+
 func (*mockResponsesConversationStore) Close() error {
 	return nil
 }
@@ -3089,3 +3142,49 @@ func TestRecordUsesResponsesAPI_MetadataDetection(t *testing.T) {
 	assert.False(t, recordUsesResponsesAPI(map[string]any{"api_mode": "chat_completions"}))
 	assert.False(t, recordUsesResponsesAPI(nil))
 }
+
+func TestPruneToolResults(t *testing.T) {
+	stored := []StoredInputItem{
+		{Type: "message", Role: "user", Content: "hi"},
+		{Type: "function_call", CallID: "call-1", Name: "bash", Arguments: `{}`},
+		{Type: "function_call_output", CallID: "call-1", Output: strings.Repeat("a", 400)},
+		{Type: "function_call", CallID: "call-2", Name: "view_image", Arguments: `{}`},
+		{Type: "function_call_output", CallID: "call-2", RawOutput: json.RawMessage(`[{"type":"input_text","text":"` + strings.Repeat("b", 40) + `"},{"type":"input_image","image_url":"data:image/png;base64,AAAA"}]`)},
+		{Type: "function_call", CallID: "call-3", Name: "bash", Arguments: `{}`},
+		{Type: "function_call_output", CallID: "call-3", Output: "recent"},
+	}
+	thread := &Thread{
+		Thread:      base.NewThread(llmtypes.Config{}, "conv-test"),
+		storedItems: stored,
+		inputItems:  fromStoredItems(stored),
+	}
+
+	assert.Equal(t, 100+10+base.EstimatedNonTextTokens, thread.pruneToolResults(1, true))
+	assert.Equal(t, base.PrunedToolResultText, thread.storedItems[2].Output)
+	assert.Nil(t, thread.storedItems[4].RawOutput)
+	assert.Equal(t, "recent", thread.storedItems[6].Output)
+
+	for i, callID := range []string{"call-1", "call-2"} {
+		output := thread.inputItems[2+2*i].OfFunctionCallOutput
+		require.NotNil(t, output)
+		assert.Equal(t, callID, output.CallID)
+		assert.Equal(t, base.PrunedToolResultText, output.Output.OfString.Value)
+	}
+	assert.Equal(t, "recent", thread.inputItems[6].OfFunctionCallOutput.Output.OfString.Value)
+	assert.Zero(t, thread.pruneToolResults(1, true))
+}
+
+func TestReasoningFromItem(t *testing.T) {
+	reasoning, ok := ReasoningFromItem(StoredInputItem{Type: "reasoning", Content: "Plan the fix", CallID: "rs_1", EncryptedContent: "gAAAA-encrypted"})
+	require.True(t, ok)
+	assert.Equal(t, llmtypes.ReasoningFormatSummary, reasoning.Format)
+	assert.Equal(t, "💭 Thinking (summary): Plan the fix", reasoning.TranscriptText())
+	assert.JSONEq(t, `{"type":"reasoning","id":"rs_1","summary":[],"encrypted_content":"gAAAA-encrypted"}`, string(reasoning.Opaque))
+
+	reasoning, ok = ReasoningFromItem(StoredInputItem{Type: "reasoning", EncryptedContent: "gAAAA-encrypted"})
+	require.True(t, ok)
+	assert.Equal(t, llmtypes.ReasoningFormatRedacted, reasoning.Format)
+
+	_, ok = ReasoningFromItem(StoredInputItem{Type: "message", Content: "hi"})
+	assert.False(t, ok)
+}
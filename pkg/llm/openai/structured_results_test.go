@@ -216,6 +216,14 @@ func (m *mockConversationStore) Query(_ context.Context, options conversations.Q
 	}, nil
 }
 
+func (m *mockConversationStore) Fork(_ context.Context, _ string) (conversations.ConversationRecord, error) {
+	return conversations.ConversationRecord{}, nil
+}
+
+func (m *mockConversationStore) Search(_ context.Context, _ string, options conversations.QueryOptions) (conversations.SearchResult, error) {
+	return conversations.SearchResult{QueryOptions: options}, nil
+}
+
 // Mock state for testing
 type mockState struct{}
 
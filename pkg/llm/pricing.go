@@ -0,0 +1,36 @@
+package llm
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/jingkaihe/kodelet/pkg/llm/anthropic"
+	"github.com/jingkaihe/kodelet/pkg/llm/openai"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// ModelPricing returns the per-token pricing of config.Model on the provider
+// it resolves to, as threads of that configuration are charged. It returns
+// false when the model has no known price, such as a custom model without
+// openai.pricing.
+func ModelPricing(config llmtypes.Config) (llmtypes.ModelPricing, bool, error) {
+	if !config.ModelAliasesResolved {
+		config.Model = resolveModelAlias(config.Model, config.Aliases)
+	}
+	if config.Provider == "" {
+		if err := ResolveProvider(&config); err != nil {
+			return llmtypes.ModelPricing{}, false, err
+		}
+	}
+
+	switch strings.ToLower(config.Provider) {
+	case providerAnthropic:
+		return anthropic.PricingForModel(config.Model), true, nil
+	case providerOpenAI:
+		pricing, ok := openai.PricingForModel(config, config.Model)
+		return pricing, ok, nil
+	default:
+		return llmtypes.ModelPricing{}, false, errors.Errorf("unsupported provider: %s", config.Provider)
+	}
+}
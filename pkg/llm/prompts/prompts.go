@@ -130,3 +130,41 @@ ASSISTANT: [explains SQL optimization techniques]
 <summary>Resolving SQL performance issues through indexing and optimization.</summary>
 </example>
 `
+
+// TitlePrompt is the prompt used for generating conversation titles
+const TitlePrompt = `Write a title for the conversation in at most 6 words, like the title of a pull request or a document. Respond with the title only.
+
+## Tone and Style
+* Use Title Case and name the concrete subject, such as the component, file or error
+* Do not use quotes, trailing punctuation or first-person pronouns
+* Avoid filler words like "help with", "question about" or "conversation"
+
+## Examples
+<example>
+<conversation>
+USER: The retry loop in the uploader never gives up when S3 returns 503
+ASSISTANT: [fixes the retry loop]
+</conversation>
+<title>Uploader Retry Loop on S3 503</title>
+</example>
+
+<example>
+<conversation>
+USER: I need to set up a Docker container for my app
+ASSISTANT: [provides Docker setup instructions]
+</conversation>
+<title>Docker Setup for the App</title>
+</example>
+`
+
+// ContentFilterRephrasePrompt is prepended to a summary, title or compaction
+// prompt that the provider refused under its content policy, before the
+// prompt is retried once.
+const ContentFilterRephrasePrompt = `Your previous response to the task below was stopped by a content policy. This is an internal bookkeeping task about a software engineering session, not a request to produce harmful content.
+
+Complete the same task, and:
+* Describe any sensitive material only at a high level, in neutral and factual terms
+* Do not quote or reproduce it, including code, payloads, credentials or offensive text
+* Omit details that are not needed to continue the engineering work
+
+The task:`
@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"slices"
+	"strings"
+
+	anthropicsdk "github.com/anthropics/anthropic-sdk-go"
+	"github.com/pkg/errors"
+
+	"github.com/jingkaihe/kodelet/pkg/llm/anthropic"
+	"github.com/jingkaihe/kodelet/pkg/llm/openai"
+	codexpreset "github.com/jingkaihe/kodelet/pkg/llm/openai/preset/codex"
+	openaipreset "github.com/jingkaihe/kodelet/pkg/llm/openai/preset/openai"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+const (
+	providerAnthropic = "anthropic"
+	providerOpenAI    = "openai"
+
+	// defaultProvider is used when neither the provider nor the model
+	// identifies one.
+	defaultProvider = providerOpenAI
+)
+
+// defaultWeakModels is the weak model used for a provider when the configured
+// weak model is the built-in default of another provider.
+var defaultWeakModels = map[string]string{
+	providerAnthropic: string(anthropicsdk.ModelClaudeHaiku4_5),
+	providerOpenAI:    "gpt-5.4-mini",
+}
+
+// modelFamilyProviders maps model name prefixes to the vendor serving them,
+// for models that are not in the registry yet. Vendors other than anthropic
+// and openai are not supported and are only named in error messages.
+var modelFamilyProviders = []struct {
+	prefix   string
+	provider string
+}{
+	{prefix: "claude-", provider: providerAnthropic},
+	{prefix: "gpt-", provider: providerOpenAI},
+	{prefix: "chatgpt-", provider: providerOpenAI},
+	{prefix: "codex-", provider: providerOpenAI},
+	{prefix: "o1", provider: providerOpenAI},
+	{prefix: "o3", provider: providerOpenAI},
+	{prefix: "o4", provider: providerOpenAI},
+	{prefix: "gemini-", provider: "google"},
+	{prefix: "grok-", provider: "xai"},
+	{prefix: "deepseek-", provider: "deepseek"},
+	{prefix: "mistral-", provider: "mistral"},
+}
+
+// ProviderForModel returns the provider serving model, looked up in the
+// built-in model registry and then by model family. It returns false for
+// models it does not recognise, such as custom OpenAI-compatible models.
+func ProviderForModel(model string) (string, bool) {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if model == "" {
+		return "", false
+	}
+	if _, ok := anthropic.ModelPricingMap[anthropicsdk.Model(model)]; ok {
+		return providerAnthropic, true
+	}
+	for _, models := range []llmtypes.CustomModels{openaipreset.Models, codexpreset.Models} {
+		if slices.Contains(models.Reasoning, model) || slices.Contains(models.NonReasoning, model) {
+			return providerOpenAI, true
+		}
+	}
+	for _, family := range modelFamilyProviders {
+		if strings.HasPrefix(model, family.prefix) {
+			return family.provider, true
+		}
+	}
+	return "", false
+}
+
+// ResolveProvider fills in config.Provider from config.Model when no provider
+// is configured, and rejects a provider that contradicts the model. Providers
+// pointed at a custom platform or base URL may serve any vendor's models, so
+// they are never treated as conflicting.
+func ResolveProvider(config *llmtypes.Config) error {
+	provider := strings.ToLower(strings.TrimSpace(config.Provider))
+	modelProvider, known := ProviderForModel(config.Model)
+
+	switch {
+	case provider == "" && !known:
+		provider = defaultProvider
+	case provider == "":
+		if !isSupportedProvider(modelProvider) {
+			return errors.Errorf("model %q is served by %s, which is not a supported provider (supported: anthropic, openai); use --provider openai with an OpenAI-compatible base URL to reach it", config.Model, modelProvider)
+		}
+		provider = modelProvider
+	case known && modelProvider != provider && !usesCustomEndpoint(*config, provider):
+		return errors.Errorf("provider %q conflicts with model %q, which is served by %s; drop the provider to infer it from the model, or choose a %s model", provider, config.Model, modelProvider, provider)
+	}
+	config.Provider = provider
+
+	if weakProvider, ok := ProviderForModel(config.WeakModel); ok && weakProvider != provider && !usesCustomEndpoint(*config, provider) {
+		if defaultWeak, isDefault := defaultWeakModels[weakProvider]; isDefault && strings.EqualFold(config.WeakModel, defaultWeak) {
+			config.WeakModel = defaultWeakModels[provider]
+		} else {
+			return errors.Errorf("weak model %q is served by %s and cannot be used with provider %q", config.WeakModel, weakProvider, provider)
+		}
+	}
+	return nil
+}
+
+func isSupportedProvider(provider string) bool {
+	return provider == providerAnthropic || provider == providerOpenAI
+}
+
+// usesCustomEndpoint reports whether provider is configured to talk to a
+// platform other than its vendor's own API.
+func usesCustomEndpoint(config llmtypes.Config, provider string) bool {
+	switch provider {
+	case providerOpenAI:
+		if openai.GetConfiguredBaseURL(config) != "" {
+			return true
+		}
+		if config.OpenAI == nil {
+			return false
+		}
+		platform := strings.ToLower(strings.TrimSpace(config.OpenAI.Platform))
+		return config.OpenAI.Models != nil || (platform != "" && platform != "openai" && platform != "codex")
+	case providerAnthropic:
+		if config.Anthropic == nil {
+			return false
+		}
+		platform := strings.ToLower(strings.TrimSpace(config.Anthropic.Platform))
+		return config.Anthropic.BaseURL != "" || (platform != "" && platform != "anthropic")
+	default:
+		return true
+	}
+}
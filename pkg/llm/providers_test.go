@@ -0,0 +1,112 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+func TestProviderForModel(t *testing.T) {
+	tests := []struct {
+		model    string
+		provider string
+		known    bool
+	}{
+		{model: "claude-sonnet-4-6", provider: "anthropic", known: true},
+		{model: "claude-future-9", provider: "anthropic", known: true},
+		{model: "gpt-5.4", provider: "openai", known: true},
+		{model: "o3-mini", provider: "openai", known: true},
+		{model: "gemini-2.5-pro", provider: "google", known: true},
+		{model: "my-local-model", known: false},
+		{model: "", known: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			provider, known := ProviderForModel(tt.model)
+			assert.Equal(t, tt.known, known)
+			assert.Equal(t, tt.provider, provider)
+		})
+	}
+}
+
+func TestResolveProvider(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        llmtypes.Config
+		wantProvider  string
+		wantWeakModel string
+		wantErr       string
+	}{
+		{
+			name:         "infers anthropic from model",
+			config:       llmtypes.Config{Model: "claude-opus-4-6"},
+			wantProvider: "anthropic",
+		},
+		{
+			name:         "infers openai from model",
+			config:       llmtypes.Config{Model: "gpt-5.4"},
+			wantProvider: "openai",
+		},
+		{
+			name:         "defaults unknown models to openai",
+			config:       llmtypes.Config{Model: "my-local-model"},
+			wantProvider: "openai",
+		},
+		{
+			name:         "keeps matching explicit provider",
+			config:       llmtypes.Config{Provider: "Anthropic", Model: "claude-sonnet-4-6"},
+			wantProvider: "anthropic",
+		},
+		{
+			name:    "rejects conflicting provider",
+			config:  llmtypes.Config{Provider: "openai", Model: "claude-sonnet-4-6"},
+			wantErr: `provider "openai" conflicts with model "claude-sonnet-4-6"`,
+		},
+		{
+			name:    "rejects unsupported vendor",
+			config:  llmtypes.Config{Model: "gemini-2.5-pro"},
+			wantErr: "not a supported provider",
+		},
+		{
+			name: "allows any model on a custom openai endpoint",
+			config: llmtypes.Config{
+				Provider: "openai",
+				Model:    "claude-sonnet-4-6",
+				OpenAI:   &llmtypes.OpenAIConfig{BaseURL: "http://localhost:4000/v1"},
+			},
+			wantProvider: "openai",
+		},
+		{
+			name:          "swaps the default weak model of another provider",
+			config:        llmtypes.Config{Model: "claude-sonnet-4-6", WeakModel: "gpt-5.4-mini"},
+			wantProvider:  "anthropic",
+			wantWeakModel: "claude-haiku-4-5",
+		},
+		{
+			name:    "rejects a custom weak model of another provider",
+			config:  llmtypes.Config{Model: "claude-sonnet-4-6", WeakModel: "gpt-5.4"},
+			wantErr: `weak model "gpt-5.4" is served by openai`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			err := ResolveProvider(&config)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantProvider, config.Provider)
+			if tt.wantWeakModel != "" {
+				assert.Equal(t, tt.wantWeakModel, config.WeakModel)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+// Package ratelimit paces LLM API requests with token buckets shared by every
+// kodelet process on the machine. Requests of a process queue in order for
+// the requests and tokens budgets, and a 429 response pauses every request to
+// the provider with a jittered backoff, so parallel subagents do not retry in
+// lockstep.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/db"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+)
+
+const (
+	// window is the period the per-minute budgets refill over.
+	window = time.Minute
+	// bytesPerToken is the rough size of a token used to estimate the tokens
+	// of a request from its body.
+	bytesPerToken = 4
+	// baseBackoff is the pause after the first 429 response that does not say
+	// when to retry. It doubles with every further 429 up to maxBackoff.
+	baseBackoff = time.Second
+	maxBackoff  = time.Minute
+	// maxJitter is the largest share of a backoff added at random.
+	maxJitter = 0.5
+)
+
+// Limiter paces the requests to one provider. It is safe for concurrent use;
+// a nil Limiter lets every request through.
+type Limiter struct {
+	provider string
+
+	mu          sync.Mutex
+	requests    bucket
+	tokens      bucket
+	updated     time.Time
+	pausedUntil time.Time
+	failures    int
+	queue       []chan struct{}
+	// store shares the budgets with other processes. Without it the budgets
+	// are local to the limiter.
+	store *store
+
+	now    func() time.Time
+	jitter func() float64
+}
+
+// bucket is a token bucket holding up to limit units that refills at limit
+// units per window. A zero limit disables the bucket.
+type bucket struct {
+	limit     float64
+	available float64
+}
+
+// New returns a limiter for provider enforcing config, or nil when config
+// sets no limit.
+func New(provider string, config *llmtypes.RateLimitConfig) *Limiter {
+	if config == nil || (config.RequestsPerMinute <= 0 && config.TokensPerMinute <= 0) {
+		return nil
+	}
+	l := &Limiter{
+		provider: provider,
+		requests: newBucket(config.RequestsPerMinute),
+		tokens:   newBucket(config.TokensPerMinute),
+		now:      time.Now,
+		jitter:   rand.Float64,
+	}
+	l.updated = l.now()
+	return l
+}
+
+func newBucket(limit int) bucket {
+	limit = max(limit, 0)
+	return bucket{limit: float64(limit), available: float64(limit)}
+}
+
+var (
+	sharedLimitersMu sync.Mutex
+	sharedLimiters   = map[string]*Limiter{}
+)
+
+// Shared returns the limiter for provider and config, so that parallel
+// threads and subagents draw on the same budgets. Its budgets are kept in
+// Kodelet's shared database and so also shared with other kodelet processes;
+// when the database cannot be used they are shared within the process only.
+// It returns nil when config sets no limit.
+func Shared(provider string, config *llmtypes.RateLimitConfig) *Limiter {
+	if config == nil {
+		return nil
+	}
+	id := provider + fmt.Sprintf("%+v", *config)
+
+	sharedLimitersMu.Lock()
+	defer sharedLimitersMu.Unlock()
+	if limiter, ok := sharedLimiters[id]; ok {
+		return limiter
+	}
+	limiter := New(provider, config)
+	if limiter == nil {
+		return nil
+	}
+	ctx := context.Background()
+	dbPath, err := db.DefaultDBPath()
+	if err == nil {
+		limiter.store, err = openStore(ctx, dbPath, id)
+	}
+	if err != nil {
+		logger.G(ctx).WithError(err).WithField("provider", provider).
+			Debug("provider rate limit is not shared with other processes")
+	}
+	sharedLimiters[id] = limiter
+	return limiter
+}
+
+// update runs fn on the budgets under l.mu, loading them from and storing
+// them to the shared store when there is one. When the store fails, fn runs
+// on the budgets last seen by this limiter.
+func (l *Limiter) update(ctx context.Context, fn func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.store != nil {
+		err := l.store.update(ctx, l, fn)
+		if err == nil {
+			return
+		}
+		logger.G(ctx).WithError(err).WithField("provider", l.provider).
+			Warn("failed to share the provider rate limit with other processes")
+	}
+	fn()
+}
+
+// EstimateTokens estimates the tokens of a request body of size bytes.
+func EstimateTokens(size int64) int {
+	if size <= 0 {
+		return 0
+	}
+	return int((size + bytesPerToken - 1) / bytesPerToken)
+}
+
+// Wait blocks until a request of tokens estimated tokens fits the budgets.
+// Waiting requests are served in the order they arrived. A request larger
+// than the whole tokens budget waits for a full bucket rather than forever.
+func (l *Limiter) Wait(ctx context.Context, tokens int) error {
+	if l == nil {
+		return nil
+	}
+	turn := l.enqueue()
+	defer l.dequeue(turn)
+
+	select {
+	case <-turn:
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "cancelled while queued for the provider rate limit")
+	}
+
+	logged := false
+	for {
+		var wait time.Duration
+		l.update(ctx, func() { wait = l.reserve(tokens) })
+		if wait <= 0 {
+			return nil
+		}
+		if !logged {
+			logger.G(ctx).WithField("provider", l.provider).
+				WithField("wait", wait.Round(time.Millisecond).String()).
+				Debug("waiting for the provider rate limit")
+			logged = true
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Wrap(ctx.Err(), "cancelled while waiting for the provider rate limit")
+		case <-timer.C:
+		}
+	}
+}
+
+// enqueue adds a waiter to the queue. Its channel is closed once it is the
+// first in line.
+func (l *Limiter) enqueue() chan struct{} {
+	turn := make(chan struct{})
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.queue = append(l.queue, turn)
+	if len(l.queue) == 1 {
+		close(turn)
+	}
+	return turn
+}
+
+// dequeue removes a waiter from the queue and lets the next one in line go.
+func (l *Limiter) dequeue(turn chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, queued := range l.queue {
+		if queued != turn {
+			continue
+		}
+		l.queue = append(l.queue[:i], l.queue[i+1:]...)
+		if i == 0 && len(l.queue) > 0 {
+			close(l.queue[0])
+		}
+		return
+	}
+}
+
+// reserve takes a request of tokens from the budgets, or returns how long
+// until it would fit. The caller must hold l.mu.
+func (l *Limiter) reserve(tokens int) time.Duration {
+	now := l.now()
+	elapsed := now.Sub(l.updated)
+	l.updated = now
+	l.requests.refill(elapsed)
+	l.tokens.refill(elapsed)
+
+	if wait := l.pausedUntil.Sub(now); wait > 0 {
+		return wait
+	}
+	cost := float64(tokens)
+	if l.tokens.limit > 0 {
+		cost = min(cost, l.tokens.limit)
+	}
+	if wait := max(l.requests.wait(1), l.tokens.wait(cost)); wait > 0 {
+		return wait
+	}
+	l.requests.take(1)
+	l.tokens.take(cost)
+	return 0
+}
+
+func (b *bucket) refill(elapsed time.Duration) {
+	if b.limit == 0 || elapsed <= 0 {
+		return
+	}
+	b.available = min(b.limit, b.available+b.limit*elapsed.Seconds()/window.Seconds())
+}
+
+func (b *bucket) wait(n float64) time.Duration {
+	if b.limit == 0 || b.available >= n {
+		return 0
+	}
+	wait := time.Duration((n - b.available) / b.limit * float64(window))
+	return max(wait, time.Millisecond)
+}
+
+func (b *bucket) take(n float64) {
+	if b.limit > 0 {
+		b.available -= n
+	}
+}
+
+// Backoff pauses every request to the provider after a 429 response. The
+// pause is retryAfter when the provider said when to retry, otherwise an
+// exponential backoff, plus up to half as much again at random.
+func (l *Limiter) Backoff(ctx context.Context, retryAfter time.Duration) {
+	if l == nil {
+		return
+	}
+	var delay time.Duration
+	l.update(ctx, func() {
+		l.failures++
+		delay = retryAfter
+		if delay <= 0 {
+			delay = min(baseBackoff<<min(l.failures-1, 16), maxBackoff)
+		}
+		delay += time.Duration(l.jitter() * maxJitter * float64(delay))
+		if until := l.now().Add(delay); until.After(l.pausedUntil) {
+			l.pausedUntil = until
+		}
+	})
+	logger.G(ctx).WithField("provider", l.provider).
+		WithField("pause", delay.Round(time.Millisecond).String()).
+		Warn("provider rate limit reached, pausing requests")
+}
+
+// succeeded resets the exponential backoff after a request gets through.
+func (l *Limiter) succeeded(ctx context.Context) {
+	l.update(ctx, func() { l.failures = 0 })
+}
+
+// Do waits for the budgets, sends req through next, and backs off when the
+// provider answers 429.
+func (l *Limiter) Do(req *http.Request, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	if l == nil {
+		return next(req)
+	}
+	if err := l.Wait(req.Context(), EstimateTokens(req.ContentLength)); err != nil {
+		return nil, err
+	}
+	resp, err := next(req)
+	if err != nil {
+		return resp, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		l.Backoff(req.Context(), retryAfter(resp.Header, l.now()))
+	case resp.StatusCode < http.StatusBadRequest:
+		l.succeeded(req.Context())
+	}
+	return resp, nil
+}
+
+// retryAfter reads how long the provider asked to wait from the
+// retry-after-ms or retry-after header, or returns 0.
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	if value := header.Get("Retry-After-Ms"); value != "" {
+		if ms, err := strconv.ParseFloat(value, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return max(time.Duration(seconds*float64(time.Second)), 0)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
+
+// Middleware returns a request middleware pacing requests with l, or nil
+// when l is nil.
+func Middleware(l *Limiter) func(*http.Request, func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	if l == nil {
+		return nil
+	}
+	return l.Do
+}
+
+// HTTPDoer is the client interface used by SDKs that take an HTTP client
+// rather than a middleware.
+type HTTPDoer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// WrapDoer returns doer with its requests paced by l, or doer unchanged when
+// l is nil.
+func WrapDoer(l *Limiter, doer HTTPDoer) HTTPDoer {
+	if l == nil || doer == nil {
+		return doer
+	}
+	return &limitedDoer{limiter: l, base: doer}
+}
+
+type limitedDoer struct {
+	limiter *Limiter
+	base    HTTPDoer
+}
+
+func (d *limitedDoer) Do(req *http.Request) (*http.Response, error) {
+	return d.limiter.Do(req, d.base.Do)
+}
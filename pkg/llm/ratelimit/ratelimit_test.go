@@ -0,0 +1,229 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/jingkaihe/kodelet/pkg/db"
+	"github.com/jingkaihe/kodelet/pkg/db/migrations"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a manually advanced clock for limiters under test.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestLimiter(config llmtypes.RateLimitConfig) (*Limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := New("anthropic", &config)
+	l.now = clock.Now
+	l.updated = clock.Now()
+	l.jitter = func() float64 { return 0 }
+	return l, clock
+}
+
+func TestNewAndSharedWithoutLimits(t *testing.T) {
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	assert.Nil(t, New("openai", nil))
+	assert.Nil(t, New("openai", &llmtypes.RateLimitConfig{}))
+	assert.Nil(t, Shared("openai", nil))
+
+	config := &llmtypes.RateLimitConfig{RequestsPerMinute: 60}
+	shared := Shared("openai", config)
+	require.NotNil(t, shared)
+	assert.Same(t, shared, Shared("openai", &llmtypes.RateLimitConfig{RequestsPerMinute: 60}))
+	assert.NotSame(t, shared, Shared("anthropic", config))
+
+	var nilLimiter *Limiter
+	assert.NoError(t, nilLimiter.Wait(context.Background(), 100))
+	assert.Nil(t, Middleware(nil))
+}
+
+func TestReserveRefillsBudgets(t *testing.T) {
+	l, clock := newTestLimiter(llmtypes.RateLimitConfig{RequestsPerMinute: 2, TokensPerMinute: 1000})
+
+	assert.Zero(t, l.reserve(100))
+	assert.Zero(t, l.reserve(100))
+	assert.Equal(t, 30*time.Second, l.reserve(100), "the third request waits for one request to refill")
+
+	clock.Advance(30 * time.Second)
+	assert.Zero(t, l.reserve(900))
+
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, 18*time.Second, l.reserve(900), "900 tokens need 300 more than the 600 refilled")
+
+	clock.Advance(18 * time.Second)
+	assert.Equal(t, 6*time.Second, l.reserve(5000), "a request larger than the whole budget waits for a full bucket")
+	clock.Advance(6 * time.Second)
+	assert.Zero(t, l.reserve(5000))
+}
+
+func TestBackoffPausesEveryRequest(t *testing.T) {
+	l, clock := newTestLimiter(llmtypes.RateLimitConfig{RequestsPerMinute: 600})
+	ctx := context.Background()
+
+	l.Backoff(ctx, 0)
+	assert.Equal(t, time.Second, l.reserve(0))
+	l.Backoff(ctx, 0)
+	assert.Equal(t, 2*time.Second, l.reserve(0), "backoff without retry-after doubles")
+
+	l.jitter = func() float64 { return 1 }
+	l.Backoff(ctx, 4*time.Second)
+	assert.Equal(t, 6*time.Second, l.reserve(0), "jitter adds up to half the delay")
+
+	clock.Advance(6 * time.Second)
+	assert.Zero(t, l.reserve(0))
+	l.succeeded(ctx)
+	l.jitter = func() float64 { return 0 }
+	l.Backoff(ctx, 0)
+	assert.Equal(t, time.Second, l.reserve(0), "a request that got through resets the backoff")
+}
+
+func TestStoreSharesBudgetsBetweenProcesses(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "storage.db")
+	database, err := db.Open(ctx, dbPath)
+	require.NoError(t, err)
+	require.NoError(t, db.NewMigrationRunner(database).Run(ctx, migrations.All()))
+	require.NoError(t, database.Close())
+
+	config := llmtypes.RateLimitConfig{RequestsPerMinute: 2}
+	first, clock := newTestLimiter(config)
+	second, _ := newTestLimiter(config)
+	second.now = clock.Now
+	for _, l := range []*Limiter{first, second} {
+		l.store, err = openStore(ctx, dbPath, "anthropic-test")
+		require.NoError(t, err)
+		t.Cleanup(func() { l.store.db.Close() })
+	}
+
+	require.NoError(t, first.Wait(ctx, 0))
+	require.NoError(t, second.Wait(ctx, 0))
+	var wait time.Duration
+	first.update(ctx, func() { wait = first.reserve(0) })
+	assert.Equal(t, 30*time.Second, wait, "requests of another process use up the budget")
+
+	clock.Advance(30 * time.Second)
+	second.Backoff(ctx, 10*time.Second)
+	first.update(ctx, func() { wait = first.reserve(0) })
+	assert.Equal(t, 10*time.Second, wait, "a 429 in another process pauses requests")
+}
+
+func TestWaitServesRequestsInOrder(t *testing.T) {
+	l, clock := newTestLimiter(llmtypes.RateLimitConfig{RequestsPerMinute: 1})
+	require.NoError(t, l.Wait(context.Background(), 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := l.Wait(ctx, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "waiting for the provider rate limit")
+
+	first := l.enqueue()
+	second := l.enqueue()
+	select {
+	case <-first:
+	default:
+		t.Fatal("the first waiter in line goes first")
+	}
+	select {
+	case <-second:
+		t.Fatal("the second waiter must wait for the first")
+	default:
+	}
+	l.dequeue(first)
+	<-second
+	l.dequeue(second)
+	assert.Empty(t, l.queue)
+
+	clock.Advance(time.Minute)
+	require.NoError(t, l.Wait(context.Background(), 0))
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	header := http.Header{}
+	assert.Zero(t, retryAfter(header, now))
+
+	header.Set("Retry-After", "3")
+	assert.Equal(t, 3*time.Second, retryAfter(header, now))
+	header.Set("Retry-After-Ms", "250")
+	assert.Equal(t, 250*time.Millisecond, retryAfter(header, now))
+
+	header = http.Header{}
+	header.Set("Retry-After", now.Add(5*time.Second).Format(http.TimeFormat))
+	assert.Equal(t, 5*time.Second, retryAfter(header, now))
+}
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Zero(t, EstimateTokens(-1))
+	assert.Equal(t, 1, EstimateTokens(3))
+	assert.Equal(t, 250, EstimateTokens(1000))
+}
+
+func TestMiddlewareBacksOffOnRateLimitResponses(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			w.Header().Set("Retry-After-Ms", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = io.WriteString(w, `{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-haiku-4-5","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`)
+	}))
+	defer server.Close()
+
+	l := New("anthropic", &llmtypes.RateLimitConfig{TokensPerMinute: 100000})
+	var estimated []int
+	middleware := Middleware(l)
+	client := anthropic.NewClient(
+		option.WithoutEnvironmentDefaults(),
+		option.WithAPIKey("test"),
+		option.WithBaseURL(server.URL),
+		option.WithMaxRetries(1),
+		option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+			estimated = append(estimated, EstimateTokens(req.ContentLength))
+			return middleware(req, next)
+		}),
+	)
+
+	message, err := client.Messages.New(context.Background(), anthropic.MessageNewParams{
+		Model:     anthropic.ModelClaudeHaiku4_5_20251001,
+		MaxTokens: 1,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock(strings.Repeat("hi ", 100)))},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "msg_1", message.ID)
+	assert.Equal(t, 2, requests, "the SDK retry goes through the limiter again")
+	require.Len(t, estimated, 2)
+	assert.Greater(t, estimated[0], 75, "tokens are estimated from the request body")
+	assert.Zero(t, l.failures, "the successful retry resets the backoff")
+}
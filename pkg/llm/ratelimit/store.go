@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/db"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// store keeps the budgets of a limiter in Kodelet's shared SQLite database,
+// so that every kodelet process on the machine, including subagents started
+// with kodelet run, draws on the same budgets and honours the same pauses.
+type store struct {
+	db *sqlx.DB
+	id string
+}
+
+// storedState is a row of the rate_limits table.
+type storedState struct {
+	Requests    float64 `db:"requests"`
+	Tokens      float64 `db:"tokens"`
+	UpdatedAt   int64   `db:"updated_at"`
+	PausedUntil int64   `db:"paused_until"`
+	Failures    int     `db:"failures"`
+}
+
+// openStore opens the budgets stored under id in the database at dbPath.
+// Database migrations must be applied before the store is used.
+func openStore(ctx context.Context, dbPath, id string) (*store, error) {
+	database, err := db.Open(ctx, dbPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open rate limit store")
+	}
+	if _, err := database.ExecContext(ctx, `SELECT 1 FROM rate_limits LIMIT 1`); err != nil {
+		database.Close()
+		return nil, errors.Wrap(err, "rate limit store is not migrated")
+	}
+	return &store{db: database, id: id}, nil
+}
+
+// update loads the stored budgets into l, runs fn and stores the result, all
+// in one write transaction. The caller must hold l.mu.
+func (s *store) update(ctx context.Context, l *Limiter, fn func()) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to begin rate limit transaction")
+	}
+	defer tx.Rollback()
+
+	// Writing first takes the database write lock up front, so concurrent
+	// processes queue on the busy timeout rather than failing to upgrade a
+	// read lock.
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO rate_limits (id, requests, tokens, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(id) DO NOTHING
+	`, s.id, l.requests.limit, l.tokens.limit, l.now().UnixNano()); err != nil {
+		return errors.Wrap(err, "failed to initialise rate limit budgets")
+	}
+
+	var state storedState
+	if err := tx.GetContext(ctx, &state, `
+		SELECT requests, tokens, updated_at, paused_until, failures
+		FROM rate_limits WHERE id = ?
+	`, s.id); err != nil {
+		return errors.Wrap(err, "failed to load rate limit budgets")
+	}
+	l.requests.available = state.Requests
+	l.tokens.available = state.Tokens
+	l.updated = time.Unix(0, state.UpdatedAt)
+	l.pausedUntil = time.Time{}
+	if state.PausedUntil > 0 {
+		l.pausedUntil = time.Unix(0, state.PausedUntil)
+	}
+	l.failures = state.Failures
+
+	fn()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE rate_limits
+		SET requests = ?, tokens = ?, updated_at = ?, paused_until = ?, failures = ?
+		WHERE id = ?
+	`, l.requests.available, l.tokens.available, unixNano(l.updated), unixNano(l.pausedUntil), l.failures, s.id); err != nil {
+		return errors.Wrap(err, "failed to store rate limit budgets")
+	}
+	return errors.Wrap(tx.Commit(), "failed to commit rate limit budgets")
+}
+
+// unixNano returns t in nanoseconds since the Unix epoch, or 0 for the zero
+// time.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
@@ -0,0 +1,54 @@
+package llm
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/llm/anthropic"
+	"github.com/jingkaihe/kodelet/pkg/llm/openai"
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+)
+
+// RedactToolResults replaces the outputs of the named tools in a conversation
+// record with placeholders and drops their structured results. Tool calls
+// and their inputs are kept, so every call still has a paired result and the
+// conversation can be resumed. It returns the number of redacted results.
+func RedactToolResults(record *convtypes.ConversationRecord, toolNames []string) (int, error) {
+	names := conversations.NewToolRedactionSet(toolNames)
+	if len(names) == 0 {
+		return 0, errors.New("at least one tool name is required")
+	}
+
+	var (
+		rawMessages []byte
+		redactedIDs []string
+		err         error
+	)
+	switch record.Provider {
+	case "anthropic":
+		rawMessages, redactedIDs, err = anthropic.RedactToolResults(record.RawMessages, names)
+	case "openai":
+		if openai.RecordUsesResponsesMode(record.Metadata, record.RawMessages) {
+			rawMessages, redactedIDs, err = openai.RedactResponsesToolResults(record.RawMessages, names)
+		} else {
+			rawMessages, redactedIDs, err = openai.RedactToolResults(record.RawMessages, names)
+		}
+	default:
+		return 0, errors.Errorf("unsupported provider: %s", record.Provider)
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to redact tool results")
+	}
+	if len(redactedIDs) == 0 {
+		return 0, nil
+	}
+
+	record.RawMessages = rawMessages
+	for _, id := range redactedIDs {
+		delete(record.ToolResults, id)
+	}
+	record.UpdatedAt = time.Now()
+	return len(redactedIDs), nil
+}
@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+func TestRedactToolResults(t *testing.T) {
+	anthropicMessages, err := json.Marshal([]anthropic.MessageParam{
+		anthropic.NewUserMessage(anthropic.NewTextBlock("read the docs")),
+		anthropic.NewAssistantMessage(
+			anthropic.NewToolUseBlock("toolu_fetch", map[string]any{"url": "https://example.com"}, "web_fetch"),
+			anthropic.NewToolUseBlock("toolu_bash", map[string]any{"command": "ls"}, "bash"),
+		),
+		anthropic.NewUserMessage(
+			anthropic.NewToolResultBlock("toolu_fetch", "secret page contents", false),
+			anthropic.NewToolResultBlock("toolu_bash", "main.go", false),
+		),
+		anthropic.NewAssistantMessage(anthropic.NewTextBlock("done")),
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		provider    string
+		metadata    map[string]any
+		rawMessages string
+	}{
+		{
+			name:        "anthropic",
+			provider:    "anthropic",
+			rawMessages: string(anthropicMessages),
+		},
+		{
+			name:     "openai chat completions",
+			provider: "openai",
+			rawMessages: `[
+				{"role": "user", "content": "read the docs"},
+				{"role": "assistant", "tool_calls": [
+					{"id": "toolu_fetch", "type": "function", "function": {"name": "web_fetch", "arguments": "{}"}},
+					{"id": "toolu_bash", "type": "function", "function": {"name": "bash", "arguments": "{}"}}]},
+				{"role": "tool", "tool_call_id": "toolu_fetch", "content": "secret page contents"},
+				{"role": "tool", "tool_call_id": "toolu_bash", "content": "main.go"},
+				{"role": "assistant", "content": "done"}]`,
+		},
+		{
+			name:     "openai responses",
+			provider: "openai",
+			metadata: map[string]any{"api_mode": "responses"},
+			rawMessages: `[
+				{"type": "message", "role": "user", "content": "read the docs"},
+				{"type": "function_call", "call_id": "toolu_fetch", "name": "web_fetch", "arguments": "{}"},
+				{"type": "function_call_output", "call_id": "toolu_fetch", "output": "secret page contents", "raw_item": {"type": "function_call_output", "output": "secret page contents"}},
+				{"type": "function_call", "call_id": "toolu_bash", "name": "bash", "arguments": "{}"},
+				{"type": "function_call_output", "call_id": "toolu_bash", "output": "main.go"},
+				{"type": "message", "role": "assistant", "content": "done"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := convtypes.ConversationRecord{
+				ID:          "conv-1",
+				Provider:    tt.provider,
+				Metadata:    tt.metadata,
+				RawMessages: json.RawMessage(tt.rawMessages),
+				ToolResults: map[string]tooltypes.StructuredToolResult{
+					"toolu_fetch": {ToolName: "web_fetch", Success: true},
+					"toolu_bash":  {ToolName: "bash", Success: true},
+				},
+			}
+
+			redacted, err := RedactToolResults(&record, []string{"web_fetch", " browser "})
+			require.NoError(t, err)
+			assert.Equal(t, 1, redacted)
+
+			raw := string(record.RawMessages)
+			assert.NotContains(t, raw, "secret page contents")
+			assert.Contains(t, raw, "[redacted: web_fetch output removed]")
+			assert.Contains(t, raw, "main.go")
+			assert.NotContains(t, record.ToolResults, "toolu_fetch")
+			assert.Contains(t, record.ToolResults, "toolu_bash")
+
+			messages, err := ExtractMessages(record.Provider, record.RawMessages, record.Metadata, record.ToolResults)
+			require.NoError(t, err)
+			assert.NotEmpty(t, messages)
+
+			redacted, err = RedactToolResults(&record, []string{"grep_tool"})
+			require.NoError(t, err)
+			assert.Zero(t, redacted)
+		})
+	}
+
+	_, err = RedactToolResults(&convtypes.ConversationRecord{Provider: "anthropic"}, []string{" "})
+	assert.ErrorContains(t, err, "at least one tool name is required")
+}
@@ -13,13 +13,19 @@ import (
 
 // NewConversationStreamer creates a fully configured conversation streamer
 // with all provider message parsers pre-registered
-func NewConversationStreamer(ctx context.Context) (streamer *conversations.ConversationStreamer, closer func() error, err error) {
+func NewConversationStreamer(ctx context.Context) (*conversations.ConversationStreamer, func() error, error) {
 	service, err := conversations.GetDefaultConversationService(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	streamer = conversations.NewConversationStreamer(service)
+	return NewConversationStreamerForService(service), service.Close, nil
+}
+
+// NewConversationStreamerForService creates a conversation streamer reading
+// from service, with all provider message parsers pre-registered
+func NewConversationStreamerForService(service conversations.ConversationServiceInterface) *conversations.ConversationStreamer {
+	streamer := conversations.NewConversationStreamer(service)
 
 	streamer.RegisterMessageParser("anthropic", func(rawMessages json.RawMessage, metadata map[string]any, toolResults map[string]tooltypes.StructuredToolResult) ([]conversations.StreamableMessage, error) {
 		msgs, err := anthropic.StreamMessages(rawMessages, toolResults)
@@ -45,7 +51,7 @@ func NewConversationStreamer(ctx context.Context) (streamer *conversations.Conve
 		return conversations.ApplyDisplayToStreamableMessages(convertOpenAIStreamableMessages(msgs), metadata), nil
 	})
 
-	return streamer, service.Close, nil
+	return streamer
 }
 
 func convertAnthropicStreamableMessages(msgs []anthropic.StreamableMessage) []conversations.StreamableMessage {
@@ -58,6 +64,7 @@ func convertAnthropicStreamableMessages(msgs []anthropic.StreamableMessage) []co
 			ToolName:   msg.ToolName,
 			ToolCallID: msg.ToolCallID,
 			Input:      msg.Input,
+			Reasoning:  msg.Reasoning,
 		}
 	}
 	return result
@@ -74,6 +81,7 @@ func convertOpenAIStreamableMessages(msgs []openai.StreamableMessage) []conversa
 			ToolName:   msg.ToolName,
 			ToolCallID: msg.ToolCallID,
 			Input:      msg.Input,
+			Reasoning:  msg.Reasoning,
 		}
 	}
 	return result
@@ -90,6 +98,7 @@ func convertResponsesStreamableMessages(msgs []responses.StreamableMessage) []co
 			ToolName:   msg.ToolName,
 			ToolCallID: msg.ToolCallID,
 			Input:      msg.Input,
+			Reasoning:  msg.Reasoning,
 		}
 	}
 	return result
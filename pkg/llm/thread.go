@@ -11,6 +11,7 @@ import (
 	"github.com/jingkaihe/kodelet/pkg/conversations"
 	"github.com/pkg/errors"
 
+	"github.com/jingkaihe/kodelet/pkg/airgap"
 	"github.com/jingkaihe/kodelet/pkg/llm/anthropic"
 	"github.com/jingkaihe/kodelet/pkg/llm/openai"
 	"github.com/jingkaihe/kodelet/pkg/logger"
@@ -57,9 +58,19 @@ func NewThread(config llmtypes.Config) (llmtypes.Thread, error) {
 		config.Model = resolveModelAlias(config.Model, config.Aliases)
 		config.WeakModel = resolveModelAlias(config.WeakModel, config.Aliases)
 	}
+	// Conflicts are rejected when the config is loaded; here the provider is
+	// only inferred, so restored conversations keep their persisted provider.
+	if config.Provider == "" {
+		if err := ResolveProvider(&config); err != nil {
+			return nil, err
+		}
+	}
 	if err := llmtypes.NormalizeReasoningConfig(&config); err != nil {
 		return nil, err
 	}
+	if err := airgap.Enforce(&config); err != nil {
+		return nil, err
+	}
 
 	// Create thread based on provider
 	switch strings.ToLower(config.Provider) {
@@ -491,3 +491,25 @@ func TestNewThreadDoesNotRemapSnapshotModels(t *testing.T) {
 	assert.Equal(t, "gpt-5.6", thread.GetConfig().Model, "built-in aliases must not remap snapshot models")
 	assert.Equal(t, "persisted-weak", thread.GetConfig().WeakModel, "live aliases must not remap snapshot weak models")
 }
+
+func TestNewThreadRejectsExternalEndpointInAirgapMode(t *testing.T) {
+	t.Setenv("OPENAI_API_BASE", "")
+	t.Setenv("OPENAI_API_KEY", "test-key")
+
+	_, err := NewThread(llmtypes.Config{
+		Provider: "openai",
+		Model:    "gpt-4.1",
+		Airgap:   &llmtypes.AirgapConfig{Enabled: true},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "airgap: model endpoint")
+
+	thread, err := NewThread(llmtypes.Config{
+		Provider: "openai",
+		Model:    "gpt-4.1",
+		OpenAI:   &llmtypes.OpenAIConfig{BaseURL: "http://localhost:8000/v1"},
+		Airgap:   &llmtypes.AirgapConfig{Enabled: true},
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, thread)
+}
@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"encoding/json"
+	"maps"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/llm/anthropic"
+	"github.com/jingkaihe/kodelet/pkg/llm/openai"
+	"github.com/jingkaihe/kodelet/pkg/llm/openai/responses"
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// Stored message formats of a conversation.
+const (
+	messageFormatAnthropic       = "anthropic"
+	messageFormatOpenAIChat      = "openai"
+	messageFormatOpenAIResponses = "openai-responses"
+)
+
+// TranslatedFromMetadataKey records the message format a conversation was
+// translated from when it was moved to another provider.
+const TranslatedFromMetadataKey = "translated_from"
+
+// TranslateConversation rewrites the messages of record into the format of
+// the thread config creates, so the conversation can be resumed with a
+// different provider or OpenAI API than it was saved with. Text, tool calls
+// and tool results are kept; reasoning, images and provider-side state such
+// as OpenAI server-side compaction are dropped. The config snapshot of the
+// record is replaced with one for config. It reports whether record changed.
+func TranslateConversation(record *convtypes.ConversationRecord, config llmtypes.Config) (bool, error) {
+	from := recordMessageFormat(record)
+	to, err := configMessageFormat(config)
+	if err != nil {
+		return false, err
+	}
+	if from == to {
+		return false, nil
+	}
+
+	var portable []llmtypes.PortableMessage
+	switch from {
+	case messageFormatAnthropic:
+		portable, err = anthropic.ToPortableMessages(record.RawMessages)
+	case messageFormatOpenAIChat:
+		portable, err = openai.ToPortableMessages(record.RawMessages)
+	case messageFormatOpenAIResponses:
+		portable, err = responses.ToPortableMessages(record.RawMessages)
+	default:
+		return false, errors.Errorf("cannot translate conversation saved by unsupported provider %q", record.Provider)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var rawMessages json.RawMessage
+	switch to {
+	case messageFormatAnthropic:
+		rawMessages, err = anthropic.FromPortableMessages(portable)
+	case messageFormatOpenAIChat:
+		rawMessages, err = openai.FromPortableMessages(portable)
+	case messageFormatOpenAIResponses:
+		rawMessages, err = responses.FromPortableMessages(portable)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	metadata := maps.Clone(record.Metadata)
+	if metadata == nil {
+		metadata = make(map[string]any)
+	}
+	for _, key := range []string{"platform", "service_tier"} {
+		delete(metadata, key)
+	}
+	snapshotConfig := config
+	if !snapshotConfig.ModelAliasesResolved {
+		snapshotConfig.Model = resolveModelAlias(config.Model, config.Aliases)
+		snapshotConfig.WeakModel = resolveModelAlias(config.WeakModel, config.Aliases)
+	}
+	metadata["model"] = snapshotConfig.Model
+	if to == messageFormatAnthropic {
+		delete(metadata, "api_mode")
+	} else {
+		mode := openai.ResolveAPIMode(config)
+		metadata["api_mode"] = string(mode)
+		openAIConfig := llmtypes.OpenAIConfig{}
+		if config.OpenAI != nil {
+			openAIConfig = *config.OpenAI
+		}
+		openAIConfig.APIMode = mode
+		snapshotConfig.OpenAI = &openAIConfig
+	}
+	metadata[TranslatedFromMetadataKey] = from
+	metadata, err = conversations.AddConfigSnapshot(metadata, snapshotConfig)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to snapshot the configuration of the translated conversation")
+	}
+
+	record.RawMessages = rawMessages
+	record.Provider = strings.ToLower(config.Provider)
+	record.Metadata = metadata
+	return true, nil
+}
+
+func recordMessageFormat(record *convtypes.ConversationRecord) string {
+	switch strings.ToLower(strings.TrimSpace(record.Provider)) {
+	case "anthropic":
+		return messageFormatAnthropic
+	case "openai-responses":
+		return messageFormatOpenAIResponses
+	case "openai":
+		if openai.RecordUsesResponsesMode(record.Metadata, record.RawMessages) {
+			return messageFormatOpenAIResponses
+		}
+		return messageFormatOpenAIChat
+	}
+	return record.Provider
+}
+
+func configMessageFormat(config llmtypes.Config) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(config.Provider)) {
+	case "anthropic":
+		return messageFormatAnthropic, nil
+	case "openai":
+		if openai.ResolveAPIMode(config) == llmtypes.OpenAIAPIModeResponses {
+			return messageFormatOpenAIResponses, nil
+		}
+		return messageFormatOpenAIChat, nil
+	}
+	return "", errors.Errorf("unsupported provider: %s", config.Provider)
+}
@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/llm/anthropic"
+	"github.com/jingkaihe/kodelet/pkg/llm/openai"
+	"github.com/jingkaihe/kodelet/pkg/llm/openai/responses"
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+func translationFixture() []llmtypes.PortableMessage {
+	return []llmtypes.PortableMessage{
+		{Role: "user", Content: "list the files"},
+		{Role: "assistant", Content: "Listing them.", ToolCalls: []llmtypes.PortableToolCall{
+			{ID: "call_1", Name: "bash", Input: `{"command":"ls"}`},
+		}},
+		{Role: "tool", Content: "main.go", ToolCallID: "call_1"},
+		{Role: "assistant", Content: "There is one file."},
+	}
+}
+
+func TestPortableMessagesRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		from func([]llmtypes.PortableMessage) (json.RawMessage, error)
+		to   func([]byte) ([]llmtypes.PortableMessage, error)
+	}{
+		{name: "anthropic", from: anthropic.FromPortableMessages, to: anthropic.ToPortableMessages},
+		{name: "openai chat completions", from: openai.FromPortableMessages, to: openai.ToPortableMessages},
+		{name: "openai responses", from: responses.FromPortableMessages, to: responses.ToPortableMessages},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := tt.from(translationFixture())
+			require.NoError(t, err)
+			portable, err := tt.to(raw)
+			require.NoError(t, err)
+			assert.Equal(t, translationFixture(), portable)
+		})
+	}
+}
+
+func TestTranslateConversation(t *testing.T) {
+	rawMessages, err := anthropic.FromPortableMessages(translationFixture())
+	require.NoError(t, err)
+
+	t.Run("anthropic to openai responses", func(t *testing.T) {
+		record := &convtypes.ConversationRecord{
+			ID:          "conv-1",
+			Provider:    "anthropic",
+			RawMessages: rawMessages,
+			Metadata:    map[string]any{"model": "claude-sonnet-4-6", "platform": "bedrock"},
+		}
+		config := llmtypes.Config{
+			Provider:        "openai",
+			Model:           "gpt-5",
+			ReasoningEffort: "medium",
+			OpenAI:          &llmtypes.OpenAIConfig{APIMode: llmtypes.OpenAIAPIModeResponses},
+		}
+
+		translated, err := TranslateConversation(record, config)
+		require.NoError(t, err)
+		assert.True(t, translated)
+		assert.Equal(t, "openai", record.Provider)
+		assert.Equal(t, "gpt-5", record.Metadata["model"])
+		assert.Equal(t, "responses", record.Metadata["api_mode"])
+		assert.Equal(t, "anthropic", record.Metadata[TranslatedFromMetadataKey])
+		assert.NotContains(t, record.Metadata, "platform")
+		assert.True(t, openai.RecordUsesResponsesMode(record.Metadata, record.RawMessages))
+
+		snapshot, ok, err := conversations.ConfigSnapshotFromMetadata(record.Metadata)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "openai", snapshot.Provider)
+		require.NotNil(t, snapshot.OpenAI)
+		assert.Equal(t, llmtypes.OpenAIAPIModeResponses, snapshot.OpenAI.APIMode)
+
+		portable, err := responses.ToPortableMessages(record.RawMessages)
+		require.NoError(t, err)
+		assert.Equal(t, translationFixture(), portable)
+	})
+
+	t.Run("same format is left untouched", func(t *testing.T) {
+		record := &convtypes.ConversationRecord{Provider: "anthropic", RawMessages: rawMessages}
+
+		translated, err := TranslateConversation(record, llmtypes.Config{Provider: "anthropic", Model: "claude-sonnet-4-6"})
+		require.NoError(t, err)
+		assert.False(t, translated)
+		assert.JSONEq(t, string(rawMessages), string(record.RawMessages))
+	})
+
+	t.Run("unsupported target provider", func(t *testing.T) {
+		record := &convtypes.ConversationRecord{Provider: "anthropic", RawMessages: rawMessages}
+
+		_, err := TranslateConversation(record, llmtypes.Config{Provider: "mistral"})
+		require.ErrorContains(t, err, "unsupported provider")
+	})
+}
+
+func TestTranslateConversationDropsReasoningAndImages(t *testing.T) {
+	raw := []byte(`[
+		{"role":"user","content":[{"type":"text","text":"what is in this picture?"},{"type":"image","source":{"type":"base64","media_type":"image/png","data":"aGVsbG8="}}]},
+		{"role":"assistant","content":[{"type":"thinking","thinking":"looking closely","signature":"sig"},{"type":"text","text":"A cat."}]}
+	]`)
+	record := &convtypes.ConversationRecord{Provider: "anthropic", RawMessages: raw}
+
+	translated, err := TranslateConversation(record, llmtypes.Config{
+		Provider: "openai",
+		Model:    "gpt-4.1",
+		OpenAI:   &llmtypes.OpenAIConfig{APIMode: llmtypes.OpenAIAPIModeChatCompletions},
+	})
+	require.NoError(t, err)
+	require.True(t, translated)
+
+	portable, err := openai.ToPortableMessages(record.RawMessages)
+	require.NoError(t, err)
+	assert.Equal(t, []llmtypes.PortableMessage{
+		{Role: "user", Content: "what is in this picture?\n\n" + llmtypes.PortableImagePlaceholder},
+		{Role: "assistant", Content: "A cat."},
+	}, portable)
+	assert.NotContains(t, string(record.RawMessages), "looking closely")
+}
@@ -0,0 +1,128 @@
+// Package markdown splits streamed assistant markdown into complete blocks,
+// so that text which may end mid code fence can be rendered incrementally.
+package markdown
+
+import (
+	"os"
+	"strings"
+)
+
+// IsTerminal reports whether file is attached to a terminal.
+func IsTerminal(file *os.File) bool {
+	if file == nil {
+		return false
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+// Stream buffers streamed markdown and renders it one complete block at a
+// time. Text is held back while a code fence is open so that partially
+// streamed code blocks are never rendered as prose.
+type Stream struct {
+	render  func(string) string
+	pending string
+}
+
+// NewStream creates a stream that renders completed blocks with render.
+func NewStream(render func(string) string) *Stream {
+	return &Stream{render: render}
+}
+
+// Write appends delta and returns the rendered output of any blocks it
+// completed, or an empty string if no block is complete yet.
+func (s *Stream) Write(delta string) string {
+	s.pending += delta
+	cut := completeBlocksEnd(s.pending)
+	if cut == 0 {
+		return ""
+	}
+	block := s.pending[:cut]
+	s.pending = s.pending[cut:]
+	return s.renderBlock(block)
+}
+
+// Flush renders whatever text is still buffered, closing any open fence.
+func (s *Stream) Flush() string {
+	block := s.pending
+	s.pending = ""
+	return s.renderBlock(block)
+}
+
+func (s *Stream) renderBlock(block string) string {
+	if strings.TrimSpace(block) == "" {
+		return ""
+	}
+	return s.render(block)
+}
+
+// completeBlocksEnd returns the offset just past the last complete markdown
+// block in text: a blank line outside a code fence, or a closing fence line.
+// Only newline-terminated lines are considered, so a partially streamed fence
+// marker is never mistaken for text.
+func completeBlocksEnd(text string) int {
+	end := 0
+	offset := 0
+	var fence string
+	for {
+		newline := strings.IndexByte(text[offset:], '\n')
+		if newline < 0 {
+			return end
+		}
+		line := text[offset : offset+newline]
+		offset += newline + 1
+
+		marker, info := fenceMarker(line)
+		switch {
+		case fence != "":
+			if marker != "" && marker[0] == fence[0] && len(marker) >= len(fence) && info == "" {
+				fence = ""
+				end = offset
+			}
+		case marker != "":
+			fence = marker
+		case strings.TrimSpace(line) == "":
+			end = offset
+		}
+	}
+}
+
+// fenceMarker returns the fence run and info string if line is a code fence.
+func fenceMarker(line string) (string, string) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || trimmed == "" || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return "", ""
+	}
+	n := 0
+	for n < len(trimmed) && trimmed[n] == trimmed[0] {
+		n++
+	}
+	if n < 3 {
+		return "", ""
+	}
+	info := strings.TrimSpace(trimmed[n:])
+	if trimmed[0] == '`' && strings.Contains(info, "`") {
+		return "", ""
+	}
+	return trimmed[:n], info
+}
+
+// TrimPartialFence drops a trailing unterminated line that may be the start of
+// a code fence still being streamed, so renderers do not flicker between prose
+// and code while the fence marker arrives.
+func TrimPartialFence(text string) string {
+	start := strings.LastIndexByte(text, '\n') + 1
+	last := strings.TrimLeft(text[start:], " ")
+	if last == "" || (last[0] != '`' && last[0] != '~') {
+		return text
+	}
+	if strings.Trim(last, string(last[0])) != "" {
+		if marker, _ := fenceMarker(last); marker == "" {
+			return text
+		}
+	}
+	return text[:start]
+}
@@ -0,0 +1,52 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamRendersCompleteBlocks(t *testing.T) {
+	var rendered []string
+	stream := NewStream(func(block string) string {
+		rendered = append(rendered, block)
+		return "<" + block + ">"
+	})
+
+	assert.Empty(t, stream.Write("Hello "))
+	assert.Empty(t, stream.Write("world\n"))
+	assert.Equal(t, "<Hello world\n\n>", stream.Write("\nNext"))
+	assert.Equal(t, "<Next>", stream.Flush())
+	assert.Empty(t, stream.Flush())
+	assert.Equal(t, []string{"Hello world\n\n", "Next"}, rendered)
+}
+
+func TestStreamHoldsOpenCodeFence(t *testing.T) {
+	stream := NewStream(func(block string) string { return block })
+
+	assert.Empty(t, stream.Write("```go\nfunc a() {}\n\n"))
+	assert.Empty(t, stream.Write("func b() {}\n``"))
+	assert.Equal(t, "```go\nfunc a() {}\n\nfunc b() {}\n```\n", stream.Write("`\nafter"))
+	assert.Equal(t, "after", stream.Flush())
+}
+
+func TestStreamFlushesUnclosedFence(t *testing.T) {
+	stream := NewStream(func(block string) string { return block })
+
+	assert.Empty(t, stream.Write("~~~\ncode\n"))
+	assert.Equal(t, "~~~\ncode\n", stream.Flush())
+}
+
+func TestCompleteBlocksEndIgnoresShorterClosingFence(t *testing.T) {
+	text := "````\n```\n\n````\n"
+	assert.Equal(t, len(text), completeBlocksEnd(text))
+	assert.Equal(t, 0, completeBlocksEnd("````\n```\n\n"))
+}
+
+func TestTrimPartialFence(t *testing.T) {
+	assert.Equal(t, "text\n", TrimPartialFence("text\n``"))
+	assert.Equal(t, "text\n", TrimPartialFence("text\n```py"))
+	assert.Equal(t, "text\n`code` here", TrimPartialFence("text\n`code` here"))
+	assert.Equal(t, "plain", TrimPartialFence("plain"))
+	assert.Equal(t, "```go\ncode\n", TrimPartialFence("```go\ncode\n"))
+}
@@ -0,0 +1,285 @@
+// Package metrics records anonymized, content-free metrics of agent runs in
+// repositories that opted in, so teams can analyze how effective the agent is
+// for them. Records hold counts and outcomes only, never prompts, output,
+// paths or commands, and are kept in the repository's .kodelet/metrics
+// directory without ever leaving the machine.
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/osutil"
+	"github.com/pkg/errors"
+)
+
+const recordVersion = 1
+
+// Dir is where the opt-in and the records are stored, relative to the
+// repository root.
+var Dir = filepath.Join(".kodelet", "metrics")
+
+const (
+	optInFile   = "opt-in.json"
+	recordsFile = "runs.jsonl"
+)
+
+// Verification outcomes recorded for a run.
+const (
+	VerificationPassed = "passed"
+	VerificationFailed = "failed"
+)
+
+// OptIn is the record that a repository opted in to run metrics.
+type OptIn struct {
+	EnabledAt time.Time `json:"enabled_at"`
+}
+
+// Record is the anonymized metrics of one run. It identifies neither the
+// conversation nor the person, and its date has no time of day.
+type Record struct {
+	Version int    `json:"v"`
+	ID      string `json:"id"`
+	// Date is the UTC day the run started on, formatted as 2006-01-02.
+	Date         string         `json:"date"`
+	Provider     string         `json:"provider"`
+	Model        string         `json:"model"`
+	Status       string         `json:"status"`
+	ExitCode     int            `json:"exit_code"`
+	DurationMS   int64          `json:"duration_ms"`
+	Turns        int            `json:"turns"`
+	ToolCalls    map[string]int `json:"tool_calls"`
+	ToolErrors   int            `json:"tool_errors"`
+	FilesChanged int            `json:"files_changed"`
+	Commands     int            `json:"commands"`
+	// CommandFailures counts the commands that exited non-zero.
+	CommandFailures int `json:"command_failures"`
+	// Verification is passed or failed when the run had a --verify command.
+	Verification string `json:"verification,omitempty"`
+	// Compactions maps a context reduction path to how often it ran.
+	Compactions map[string]int `json:"compactions,omitempty"`
+	// StopReason is why an unsuccessful run stopped, such as max_turns.
+	StopReason       string  `json:"stop_reason,omitempty"`
+	PullRequest      bool    `json:"pull_request,omitempty"`
+	InputTokens      int     `json:"input_tokens"`
+	OutputTokens     int     `json:"output_tokens"`
+	CacheWriteTokens int     `json:"cache_write_tokens"`
+	CacheReadTokens  int     `json:"cache_read_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// RepoRoot returns the root of the git repository containing dir, or dir
+// itself outside a repository.
+func RepoRoot(dir string) string {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		return dir
+	}
+	output, err := exec.Command(gitPath, "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return dir
+	}
+	root := strings.TrimSpace(string(output))
+	if root == "" {
+		return dir
+	}
+	return osutil.CanonicalizePath(filepath.Clean(root))
+}
+
+// Enable opts the repository at root in. The metrics directory ignores
+// itself so records never end up in commits.
+func Enable(root string, now time.Time) error {
+	dir := filepath.Join(root, Dir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return errors.Wrap(err, "failed to create metrics directory")
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*\n"), 0o644); err != nil {
+		return errors.Wrap(err, "failed to write metrics .gitignore")
+	}
+	if optIn, err := Status(root); err != nil || optIn != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(OptIn{EnabledAt: now.UTC()}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode metrics opt-in")
+	}
+	return errors.Wrap(os.WriteFile(filepath.Join(dir, optInFile), append(data, '\n'), 0o644), "failed to write metrics opt-in")
+}
+
+// Disable opts the repository at root out. Recorded metrics are kept unless
+// purge is set.
+func Disable(root string, purge bool) error {
+	dir := filepath.Join(root, Dir)
+	if purge {
+		return errors.Wrap(os.RemoveAll(dir), "failed to remove metrics directory")
+	}
+	if err := os.Remove(filepath.Join(dir, optInFile)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to remove metrics opt-in")
+	}
+	return nil
+}
+
+// Status returns the opt-in of the repository at root, or nil when it has not
+// opted in.
+func Status(root string) (*OptIn, error) {
+	data, err := os.ReadFile(filepath.Join(root, Dir, optInFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to read metrics opt-in")
+	}
+	var optIn OptIn
+	if err := json.Unmarshal(data, &optIn); err != nil {
+		return nil, errors.Wrap(err, "failed to decode metrics opt-in")
+	}
+	return &optIn, nil
+}
+
+// Append adds record to the records of the repository at root, filling in
+// its version and ID.
+func Append(root string, record Record) error {
+	record.Version = recordVersion
+	if record.ID == "" {
+		record.ID = newID()
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode run metrics")
+	}
+
+	file, err := os.OpenFile(filepath.Join(root, Dir, recordsFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return errors.Wrap(err, "failed to open run metrics")
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		_ = file.Close()
+		return errors.Wrap(err, "failed to write run metrics")
+	}
+	return errors.Wrap(file.Close(), "failed to close run metrics")
+}
+
+// RecordRun appends record for a run in dir when its repository opted in.
+// Failures are logged and never fail the run.
+func RecordRun(ctx context.Context, dir string, record Record) {
+	root := RepoRoot(dir)
+	optIn, err := Status(root)
+	if err == nil && optIn != nil {
+		err = Append(root, record)
+	}
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to record run metrics")
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Read returns the records of the repository at root from runs on or after
+// since, in the order they were recorded. Malformed lines are skipped.
+func Read(root string, since time.Time) ([]Record, error) {
+	file, err := os.Open(filepath.Join(root, Dir, recordsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to open run metrics")
+	}
+	defer file.Close()
+
+	sinceDate := ""
+	if !since.IsZero() {
+		sinceDate = since.UTC().Format(time.DateOnly)
+	}
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			continue
+		}
+		if record.Date >= sinceDate {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read run metrics")
+	}
+	return records, nil
+}
+
+// csvHeader lists the CSV columns written by WriteCSV.
+var csvHeader = []string{
+	"id", "date", "provider", "model", "status", "exit_code", "duration_ms",
+	"turns", "tool_calls", "tool_errors", "files_changed", "commands",
+	"command_failures", "verification", "compactions", "stop_reason",
+	"pull_request", "input_tokens", "output_tokens", "cache_write_tokens",
+	"cache_read_tokens", "cost_usd",
+}
+
+// WriteCSV writes records as CSV with a header row. Tool calls and
+// compactions are written as name=count pairs separated by semicolons.
+func WriteCSV(w io.Writer, records []Record) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return errors.Wrap(err, "failed to write metrics CSV")
+	}
+	for _, r := range records {
+		row := []string{
+			r.ID, r.Date, r.Provider, r.Model, r.Status,
+			strconv.Itoa(r.ExitCode),
+			strconv.FormatInt(r.DurationMS, 10),
+			strconv.Itoa(r.Turns),
+			formatCounts(r.ToolCalls),
+			strconv.Itoa(r.ToolErrors),
+			strconv.Itoa(r.FilesChanged),
+			strconv.Itoa(r.Commands),
+			strconv.Itoa(r.CommandFailures),
+			r.Verification,
+			formatCounts(r.Compactions),
+			r.StopReason,
+			strconv.FormatBool(r.PullRequest),
+			strconv.Itoa(r.InputTokens),
+			strconv.Itoa(r.OutputTokens),
+			strconv.Itoa(r.CacheWriteTokens),
+			strconv.Itoa(r.CacheReadTokens),
+			strconv.FormatFloat(r.CostUSD, 'f', 6, 64),
+		}
+		if err := writer.Write(row); err != nil {
+			return errors.Wrap(err, "failed to write metrics CSV")
+		}
+	}
+	writer.Flush()
+	return errors.Wrap(writer.Error(), "failed to write metrics CSV")
+}
+
+func formatCounts(counts map[string]int) string {
+	pairs := make([]string, 0, len(counts))
+	for _, name := range slices.Sorted(maps.Keys(counts)) {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", name, counts[name]))
+	}
+	return strings.Join(pairs, ";")
+}
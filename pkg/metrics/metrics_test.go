@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptInLifecycle(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	optIn, err := Status(root)
+	require.NoError(t, err)
+	assert.Nil(t, optIn)
+
+	require.NoError(t, Enable(root, now))
+	require.NoError(t, Enable(root, now.Add(time.Hour)), "enabling again keeps the first opt-in")
+	optIn, err = Status(root)
+	require.NoError(t, err)
+	require.NotNil(t, optIn)
+	assert.Equal(t, now, optIn.EnabledAt)
+	gitignore, err := os.ReadFile(filepath.Join(root, Dir, ".gitignore"))
+	require.NoError(t, err)
+	assert.Equal(t, "*\n", string(gitignore))
+
+	require.NoError(t, Append(root, Record{Date: "2026-03-01"}))
+	require.NoError(t, Disable(root, false))
+	optIn, err = Status(root)
+	require.NoError(t, err)
+	assert.Nil(t, optIn)
+	records, err := Read(root, time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, records, 1, "opting out keeps the records")
+
+	require.NoError(t, Disable(root, true))
+	assert.NoDirExists(t, filepath.Join(root, Dir))
+}
+
+func TestRecordRunOnlyWhenOptedIn(t *testing.T) {
+	root := t.TempDir()
+	RecordRun(context.Background(), root, Record{Date: "2026-03-01"})
+	assert.NoDirExists(t, filepath.Join(root, Dir))
+
+	require.NoError(t, Enable(root, time.Now()))
+	RecordRun(context.Background(), root, Record{Date: "2026-03-01", Turns: 3})
+	RecordRun(context.Background(), root, Record{Date: "2026-03-02", Turns: 5})
+
+	records, err := Read(root, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, recordVersion, records[0].Version)
+	assert.Len(t, records[0].ID, 16)
+	assert.NotEqual(t, records[0].ID, records[1].ID)
+	assert.Equal(t, 3, records[0].Turns)
+
+	records, err = Read(root, time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, records, 1, "since matches whole days")
+	assert.Equal(t, 5, records[0].Turns)
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteCSV(&buf, []Record{{
+		ID:           "abc",
+		Date:         "2026-03-01",
+		Provider:     "anthropic",
+		Model:        "claude-sonnet-4-6",
+		Status:       "success",
+		DurationMS:   1200,
+		Turns:        4,
+		ToolCalls:    map[string]int{"file_edit": 2, "bash": 3},
+		Verification: VerificationPassed,
+		Compactions:  map[string]int{"prune": 1},
+		CostUSD:      0.25,
+	}}))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	assert.Equal(t, "id,date,provider,model,status,exit_code,duration_ms,turns,tool_calls,tool_errors,files_changed,commands,command_failures,verification,compactions,stop_reason,pull_request,input_tokens,output_tokens,cache_write_tokens,cache_read_tokens,cost_usd", string(lines[0]))
+	assert.Equal(t, "abc,2026-03-01,anthropic,claude-sonnet-4-6,success,0,1200,4,bash=3;file_edit=2,0,0,0,0,passed,prune=1,,false,0,0,0,0,0.250000", string(lines[1]))
+}
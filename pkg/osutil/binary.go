@@ -0,0 +1,130 @@
+package osutil
+
+import (
+	"bytes"
+	"math"
+	"unicode/utf8"
+)
+
+// BinarySniffBytes is how much of a file or output DetectBinary inspects.
+const BinarySniffBytes = 8192
+
+// binaryEntropyThreshold is the Shannon entropy, in bits per byte, above
+// which content is treated as compressed or encrypted data. Text, including
+// base64 and minified code, stays well below it.
+const binaryEntropyThreshold = 7.5
+
+// binaryControlRatio is the share of control characters and invalid UTF-8
+// above which content is treated as binary.
+const binaryControlRatio = 0.1
+
+// textControlChars are the control characters found in text and terminal
+// output.
+var textControlChars = []byte("\n\r\t\f\b\x1b")
+
+// binaryEntropyMinBytes is the smallest sample the entropy check applies to;
+// short samples cannot reach a meaningful entropy.
+const binaryEntropyMinBytes = 1024
+
+var binarySignatures = []struct {
+	offset int
+	magic  []byte
+	kind   string
+	// check rules out text that happens to start with a short magic.
+	check func(data []byte) bool
+}{
+	{0, []byte("\x7fELF"), "ELF", nil},
+	{0, []byte{0xfe, 0xed, 0xfa, 0xce}, "Mach-O", nil},
+	{0, []byte{0xfe, 0xed, 0xfa, 0xcf}, "Mach-O", nil},
+	{0, []byte{0xce, 0xfa, 0xed, 0xfe}, "Mach-O", nil},
+	{0, []byte{0xcf, 0xfa, 0xed, 0xfe}, "Mach-O", nil},
+	{0, []byte{0xca, 0xfe, 0xba, 0xbe}, "Mach-O universal or Java class", nil},
+	{0, []byte("MZ"), "PE", looksLikePE},
+	{0, []byte("\x00asm"), "WebAssembly", nil},
+	{0, []byte("\x89PNG\r\n\x1a\n"), "PNG", nil},
+	{0, []byte{0xff, 0xd8, 0xff}, "JPEG", nil},
+	{0, []byte("GIF87a"), "GIF", nil},
+	{0, []byte("GIF89a"), "GIF", nil},
+	{8, []byte("WEBP"), "WebP", func(data []byte) bool { return bytes.HasPrefix(data, []byte("RIFF")) }},
+	{0, []byte("%PDF-"), "PDF", nil},
+	{0, []byte("PK\x03\x04"), "ZIP", nil},
+	{0, []byte{0x1f, 0x8b}, "gzip", nil},
+	{0, []byte("BZh"), "bzip2", func(data []byte) bool { return len(data) > 3 && data[3] >= '1' && data[3] <= '9' }},
+	{0, []byte("\xfd7zXZ\x00"), "xz", nil},
+	{0, []byte{0x28, 0xb5, 0x2f, 0xfd}, "zstd", nil},
+	{0, []byte("7z\xbc\xaf\x27\x1c"), "7z", nil},
+	{257, []byte("ustar"), "tar", nil},
+	{0, []byte("SQLite format 3\x00"), "SQLite", nil},
+}
+
+// DetectBinary reports whether data looks like binary rather than text, and
+// names its type: a known file format such as "ELF" or "PNG", "high-entropy
+// data" for compressed or encrypted content, or "binary data". Only the
+// first BinarySniffBytes of data are inspected. Terminal escape sequences
+// count as text.
+func DetectBinary(data []byte) (string, bool) {
+	sample := data[:min(len(data), BinarySniffBytes)]
+	if len(sample) == 0 {
+		return "", false
+	}
+	for _, sig := range binarySignatures {
+		if len(sample) >= sig.offset+len(sig.magic) && bytes.Equal(sample[sig.offset:sig.offset+len(sig.magic)], sig.magic) {
+			if sig.check != nil && !sig.check(data) {
+				continue
+			}
+			return sig.kind, true
+		}
+	}
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return "binary data", true
+	}
+	if len(sample) >= binaryEntropyMinBytes && byteEntropy(sample) > binaryEntropyThreshold {
+		return "high-entropy data", true
+	}
+
+	suspicious, total := 0, 0
+	for i := 0; i < len(sample); {
+		r, size := utf8.DecodeRune(sample[i:])
+		if r == utf8.RuneError && size == 1 {
+			// A rune cut off at the end of the sample is not evidence.
+			if len(sample)-i < utf8.UTFMax && len(sample) < len(data) {
+				break
+			}
+			suspicious++
+		} else if r == 0x7f || (r < 0x20 && !bytes.ContainsRune(textControlChars, r)) {
+			suspicious++
+		}
+		total++
+		i += size
+	}
+	if total > 0 && float64(suspicious)/float64(total) > binaryControlRatio {
+		return "binary data", true
+	}
+	return "", false
+}
+
+// looksLikePE checks the PE header a DOS stub points at, since plain text
+// can start with "MZ".
+func looksLikePE(data []byte) bool {
+	if len(data) < 0x40 {
+		return false
+	}
+	offset := int(data[0x3c]) | int(data[0x3d])<<8 | int(data[0x3e])<<16 | int(data[0x3f])<<24
+	return offset >= 0x40 && offset+4 <= len(data) && bytes.Equal(data[offset:offset+4], []byte("PE\x00\x00"))
+}
+
+func byteEntropy(data []byte) float64 {
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / float64(len(data))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
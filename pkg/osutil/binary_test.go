@@ -47,3 +47,52 @@ func TestIsBinaryFile(t *testing.T) {
 func TestIsBinaryFileReturnsFalseForUnreadablePath(t *testing.T) {
 	assert.False(t, IsBinaryFile(filepath.Join(t.TempDir(), "missing.bin")))
 }
+
+func TestDetectBinary(t *testing.T) {
+	random := make([]byte, 4096)
+	seed := uint32(1)
+	for i := range random {
+		seed = seed*1664525 + 1013904223
+		random[i] = byte(seed >> 24)
+	}
+	random[0] = 'x'
+	for i, b := range random {
+		if b == 0 {
+			random[i] = 1
+		}
+	}
+
+	pe := make([]byte, 0x90)
+	copy(pe, "MZ")
+	pe[0x3c] = 0x80
+	copy(pe[0x80:], "PE\x00\x00")
+
+	tests := []struct {
+		name     string
+		content  []byte
+		kind     string
+		isBinary bool
+	}{
+		{name: "empty", content: nil},
+		{name: "text", content: []byte("package main\n\nfunc main() {}\n")},
+		{name: "utf-8 text", content: []byte("こんにちは世界\nПривет мир\n")},
+		{name: "terminal colors", content: []byte("\x1b[32mok\x1b[0m  pkg/tools\t0.1s\n")},
+		{name: "text starting with MZ", content: []byte("MZ is not always an executable\n")},
+		{name: "text starting with BZh", content: []byte("BZhello world\n")},
+		{name: "elf", content: []byte("\x7fELF\x02\x01\x01\x00"), kind: "ELF", isBinary: true},
+		{name: "png", content: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), kind: "PNG", isBinary: true},
+		{name: "pe", content: pe, kind: "PE", isBinary: true},
+		{name: "gzip", content: []byte{0x1f, 0x8b, 0x08, 0x00}, kind: "gzip", isBinary: true},
+		{name: "null bytes", content: []byte("data\x00\x00more"), kind: "binary data", isBinary: true},
+		{name: "control characters", content: []byte("\x01\x02\x03\x04abc\x05\x06"), kind: "binary data", isBinary: true},
+		{name: "high entropy", content: random, kind: "high-entropy data", isBinary: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, isBinary := DetectBinary(tt.content)
+			assert.Equal(t, tt.isBinary, isBinary)
+			assert.Equal(t, tt.kind, kind)
+		})
+	}
+}
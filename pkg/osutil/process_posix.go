@@ -3,7 +3,9 @@
 package osutil
 
 import (
+	"os"
 	"os/exec"
+	"runtime"
 	"syscall"
 	"time"
 )
@@ -51,3 +53,29 @@ func SetProcessGroupKill(cmd *exec.Cmd) {
 		return syscall.Kill(pgid, syscall.SIGKILL)
 	}
 }
+
+// PeakMemoryBytes returns the maximum resident set size of an exited process
+// and the descendants it waited for, or 0 when it is unavailable.
+func PeakMemoryBytes(state *os.ProcessState) int64 {
+	if state == nil {
+		return 0
+	}
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || rusage == nil {
+		return 0
+	}
+	// ru_maxrss is reported in bytes on macOS and kilobytes elsewhere.
+	if runtime.GOOS == "darwin" {
+		return int64(rusage.Maxrss)
+	}
+	return int64(rusage.Maxrss) * 1024
+}
+
+// ProcessAlive reports whether a process with pid exists.
+func ProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -33,3 +33,29 @@ func SetProcessGroupKill(cmd *exec.Cmd) {
 		return cmd.Process.Signal(os.Kill)
 	}
 }
+
+// PeakMemoryBytes returns 0 on Windows where resident set size is not reported
+// for exited processes.
+func PeakMemoryBytes(_ *os.ProcessState) int64 {
+	return 0
+}
+
+// ProcessAlive reports whether a process with pid exists.
+func ProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	handle, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(handle)
+	var code uint32
+	if err := syscall.GetExitCodeProcess(handle, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
+
+// stillActive is the exit code Windows reports for a running process.
+const stillActive = 259
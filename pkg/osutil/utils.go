@@ -96,6 +96,23 @@ func IsGHCLIAuthenticated() bool {
 	return cmd.Run() == nil
 }
 
+// IsGLabCLIInstalled checks if GitLab CLI (glab) is installed
+func IsGLabCLIInstalled() bool {
+	_, err := exec.LookPath("glab")
+	return err == nil
+}
+
+// IsGLabCLIAuthenticated checks if GitLab CLI (glab) is authenticated with
+// host, or with its default instance when host is empty
+func IsGLabCLIAuthenticated(host string) bool {
+	args := []string{"auth", "status"}
+	if host != "" {
+		args = append(args, "--hostname", host)
+	}
+	cmd := exec.Command("glab", args...)
+	return cmd.Run() == nil
+}
+
 // ValidateGHCLI validates that GitHub CLI is installed and authenticated
 func ValidateGHCLI() error {
 	if !IsGHCLIInstalled() {
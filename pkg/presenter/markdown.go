@@ -0,0 +1,56 @@
+package presenter
+
+import (
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"golang.org/x/term"
+)
+
+const defaultMarkdownWidth = 100
+
+// MarkdownRenderer renders markdown into ANSI-styled terminal text.
+type MarkdownRenderer struct {
+	renderer *glamour.TermRenderer
+}
+
+// NewMarkdownRenderer creates a renderer that word-wraps at width columns. A
+// non-positive width uses the width of stdout, or a default when stdout is not
+// a terminal.
+func NewMarkdownRenderer(width int) (*MarkdownRenderer, error) {
+	if width <= 0 {
+		width = terminalWidth()
+	}
+	renderer, err := glamour.NewTermRenderer(
+		glamour.WithAutoStyle(),
+		glamour.WithWordWrap(width),
+		glamour.WithPreservedNewLines(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &MarkdownRenderer{renderer: renderer}, nil
+}
+
+// Render renders text, falling back to the raw text if rendering fails.
+func (r *MarkdownRenderer) Render(text string) (rendered string) {
+	defer func() {
+		if recover() != nil {
+			rendered = text
+		}
+	}()
+
+	out, err := r.renderer.Render(text)
+	if err != nil {
+		return text
+	}
+	return strings.Trim(out, "\n")
+}
+
+func terminalWidth() int {
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+		return width
+	}
+	return defaultMarkdownWidth
+}
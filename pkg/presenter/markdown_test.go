@@ -0,0 +1,21 @@
+package presenter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdownRendererRendersMarkdown(t *testing.T) {
+	renderer, err := NewMarkdownRenderer(80)
+	require.NoError(t, err)
+
+	out := renderer.Render("# Title\n\n- one\n- two\n\n```go\nfunc main() {}\n```\n")
+	assert.Contains(t, out, "Title")
+	assert.Contains(t, out, "• one")
+	assert.NotContains(t, out, "```")
+	assert.Contains(t, out, "func main() {}")
+	assert.False(t, strings.HasPrefix(out, "\n"))
+}
@@ -8,9 +8,12 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
+	"github.com/jingkaihe/kodelet/pkg/todos"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
 )
 
 // UsageStats represents token usage and cost information
@@ -27,6 +30,23 @@ type UsageStats struct {
 	MaxContextWindow     int
 }
 
+// ResourceStats represents the resources consumed by tool subprocesses
+type ResourceStats struct {
+	Processes       int
+	WallTime        time.Duration
+	UserCPUTime     time.Duration
+	SystemCPUTime   time.Duration
+	PeakMemoryBytes int64
+}
+
+// TodoStats represents the progress of the agent's todo list
+type TodoStats struct {
+	Completed  int
+	InProgress int
+	Pending    int
+	Cancelled  int
+}
+
 // Presenter defines the interface for consistent CLI output
 type Presenter interface {
 	Error(err error, context string)
@@ -36,6 +56,8 @@ type Presenter interface {
 	Section(title string)
 	Prompt(question string, options ...string) string
 	Stats(usage *UsageStats)
+	Resources(stats *ResourceStats)
+	Todos(stats *TodoStats)
 	Separator()
 	SetQuiet(quiet bool)
 	IsQuiet() bool
@@ -210,6 +232,37 @@ func (p *TerminalPresenter) Stats(usage *UsageStats) {
 		usage.InputCost, usage.OutputCost, usage.CacheWriteCost, usage.CacheReadCost, totalCost)
 }
 
+// Resources displays tool subprocess resource usage in a consistent format
+func (p *TerminalPresenter) Resources(stats *ResourceStats) {
+	if p.quiet || stats == nil || stats.Processes == 0 {
+		return
+	}
+
+	statsColor := color.New(color.FgCyan, color.Bold)
+	statsColor.Fprintf(p.output, "[Tool Resources] Processes: %d | Wall time: %s | CPU time: %s (user %s, sys %s) | Peak memory: %.1f MB\n",
+		stats.Processes, formatStatDuration(stats.WallTime), formatStatDuration(stats.UserCPUTime+stats.SystemCPUTime),
+		formatStatDuration(stats.UserCPUTime), formatStatDuration(stats.SystemCPUTime), float64(stats.PeakMemoryBytes)/(1024*1024))
+}
+
+// Todos displays todo list completion in a consistent format
+func (p *TerminalPresenter) Todos(stats *TodoStats) {
+	if p.quiet || stats == nil {
+		return
+	}
+	total := stats.Completed + stats.InProgress + stats.Pending
+	if total == 0 {
+		return
+	}
+
+	statsColor := color.New(color.FgCyan, color.Bold)
+	statsColor.Fprintf(p.output, "[Todos] Completed: %d/%d (%d%%) | In progress: %d | Pending: %d | Cancelled: %d\n",
+		stats.Completed, total, stats.Completed*100/total, stats.InProgress, stats.Pending, stats.Cancelled)
+}
+
+func formatStatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
 // Separator displays a visual separator
 func (p *TerminalPresenter) Separator() {
 	if p.quiet {
@@ -250,6 +303,37 @@ func ConvertUsageStats(stats *llmtypes.Usage) *UsageStats {
 	}
 }
 
+// ConvertResourceStats converts tooltypes.ResourceUsage to presenter.ResourceStats
+func ConvertResourceStats(usage *tooltypes.ResourceUsage) *ResourceStats {
+	if usage == nil {
+		return nil
+	}
+
+	return &ResourceStats{
+		Processes:       usage.Processes,
+		WallTime:        usage.WallTime,
+		UserCPUTime:     usage.UserCPUTime,
+		SystemCPUTime:   usage.SystemCPUTime,
+		PeakMemoryBytes: usage.PeakMemoryBytes,
+	}
+}
+
+// ConvertTodoStats converts the todo list in conversation metadata to presenter.TodoStats
+func ConvertTodoStats(metadata map[string]any) *TodoStats {
+	list, ok := todos.FromMetadata(metadata)
+	if !ok {
+		return nil
+	}
+
+	progress := list.Progress()
+	return &TodoStats{
+		Completed:  progress.Completed,
+		InProgress: progress.InProgress,
+		Pending:    progress.Pending,
+		Cancelled:  progress.Cancelled,
+	}
+}
+
 // Global presenter instance for convenience
 var defaultPresenter = New()
 
@@ -288,6 +372,16 @@ func Stats(usage *UsageStats) {
 	defaultPresenter.Stats(usage)
 }
 
+// Resources displays tool subprocess resource usage using the default presenter instance.
+func Resources(stats *ResourceStats) {
+	defaultPresenter.Resources(stats)
+}
+
+// Todos displays todo list completion using the default presenter instance.
+func Todos(stats *TodoStats) {
+	defaultPresenter.Todos(stats)
+}
+
 // Separator displays a visual separator using the default presenter instance.
 func Separator() {
 	defaultPresenter.Separator()
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/fatih/color"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
@@ -214,6 +215,29 @@ func TestStats(t *testing.T) {
 	assert.NotContains(t, result, "[Context Window]")
 }
 
+func TestResources(t *testing.T) {
+	var output bytes.Buffer
+	presenter := NewWithOptions(&output, nil, ColorNever)
+
+	presenter.Resources(&ResourceStats{
+		Processes:       2,
+		WallTime:        1500 * time.Millisecond,
+		UserCPUTime:     time.Second,
+		SystemCPUTime:   200 * time.Millisecond,
+		PeakMemoryBytes: 10 * 1024 * 1024,
+	})
+
+	result := output.String()
+	assert.Contains(t, result, "[Tool Resources] Processes: 2")
+	assert.Contains(t, result, "Wall time: 1.5s")
+	assert.Contains(t, result, "CPU time: 1.2s (user 1s, sys 200ms)")
+	assert.Contains(t, result, "Peak memory: 10.0 MB")
+
+	output.Reset()
+	presenter.Resources(&ResourceStats{})
+	assert.Empty(t, output.String())
+}
+
 func TestStatsWithContextWindow(t *testing.T) {
 	var output bytes.Buffer
 	presenter := NewWithOptions(&output, nil, ColorNever)
@@ -0,0 +1,261 @@
+// Package pricing manages the signed pricing manifest that updates the
+// built-in model prices between releases.
+//
+// Each release publishes a manifest listing model prices together with the
+// dates they take effect. `kodelet models refresh-pricing` downloads the
+// manifest, verifies its ed25519 signature and stores it under the Kodelet
+// base directory, where the providers pick it up when accounting costs.
+package pricing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+// ManifestVersion is the manifest format understood by this build.
+const ManifestVersion = 1
+
+// DefaultManifestURL is where releases publish the pricing manifest. The
+// detached signature is published next to it with a ".sig" suffix.
+const DefaultManifestURL = "https://github.com/jingkaihe/kodelet/releases/latest/download/pricing.json"
+
+// PublicKey is the base64-encoded ed25519 key that release manifests are
+// signed with. It is set at build time with -ldflags.
+var PublicKey = ""
+
+// Manifest lists model prices with the dates they are effective.
+type Manifest struct {
+	Version     int           `json:"version"`
+	PublishedAt time.Time     `json:"published_at"`
+	Models      []ModelPrices `json:"models"`
+}
+
+// ModelPrices holds the price history of one model on one platform.
+type ModelPrices struct {
+	// Provider is the platform serving the model: anthropic, openai or codex.
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	// ServiceTier limits the prices to an OpenAI service tier such as
+	// priority. Empty means the standard tier.
+	ServiceTier string        `json:"service_tier,omitempty"`
+	Periods     []PricePeriod `json:"periods"`
+}
+
+// PricePeriod is the pricing of a model from EffectiveFrom until
+// EffectiveUntil, or indefinitely when EffectiveUntil is unset. Long-context
+// surcharges use the long_context_* fields of llmtypes.ModelPricing.
+type PricePeriod struct {
+	EffectiveFrom  time.Time  `json:"effective_from"`
+	EffectiveUntil *time.Time `json:"effective_until,omitempty"`
+	llmtypes.ModelPricing
+}
+
+// ParseManifest decodes and validates a manifest.
+func ParseManifest(data []byte) (*Manifest, error) {
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, errors.Wrap(err, "failed to decode pricing manifest")
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// Validate checks that the manifest can be used by this build.
+func (m *Manifest) Validate() error {
+	if m.Version != ManifestVersion {
+		return errors.Errorf("unsupported pricing manifest version %d (expected %d)", m.Version, ManifestVersion)
+	}
+	for _, model := range m.Models {
+		if strings.TrimSpace(model.Provider) == "" || strings.TrimSpace(model.Model) == "" {
+			return errors.New("pricing manifest entry is missing a provider or model")
+		}
+		if len(model.Periods) == 0 {
+			return errors.Errorf("pricing manifest entry %s/%s has no price periods", model.Provider, model.Model)
+		}
+		for _, period := range model.Periods {
+			if period.EffectiveUntil != nil && !period.EffectiveUntil.After(period.EffectiveFrom) {
+				return errors.Errorf("pricing manifest entry %s/%s has a period ending before it starts", model.Provider, model.Model)
+			}
+			if period.Input < 0 || period.CachedInput < 0 || period.CacheWriteInput < 0 || period.Output < 0 {
+				return errors.Errorf("pricing manifest entry %s/%s has a negative price", model.Provider, model.Model)
+			}
+		}
+	}
+	return nil
+}
+
+// Lookup returns the price of model on provider for serviceTier at time at.
+func (m *Manifest) Lookup(provider, model, serviceTier string, at time.Time) (llmtypes.ModelPricing, bool) {
+	if m == nil {
+		return llmtypes.ModelPricing{}, false
+	}
+	for _, entry := range m.Models {
+		if !strings.EqualFold(entry.Provider, provider) || !strings.EqualFold(entry.Model, model) || !strings.EqualFold(entry.ServiceTier, serviceTier) {
+			continue
+		}
+		if period, ok := entry.periodAt(at); ok {
+			return period.ModelPricing, true
+		}
+	}
+	return llmtypes.ModelPricing{}, false
+}
+
+// Overlay returns a copy of prices with the manifest prices for provider and
+// serviceTier that are effective at time at applied on top.
+func (m *Manifest) Overlay(prices llmtypes.CustomPricing, provider, serviceTier string, at time.Time) llmtypes.CustomPricing {
+	result := make(llmtypes.CustomPricing, len(prices))
+	for model, price := range prices {
+		result[model] = price
+	}
+	if m == nil {
+		return result
+	}
+	for _, entry := range m.Models {
+		if !strings.EqualFold(entry.Provider, provider) || !strings.EqualFold(entry.ServiceTier, serviceTier) {
+			continue
+		}
+		if period, ok := entry.periodAt(at); ok {
+			result[entry.Model] = period.ModelPricing
+		}
+	}
+	return result
+}
+
+func (p ModelPrices) periodAt(at time.Time) (PricePeriod, bool) {
+	for _, period := range p.Periods {
+		if at.Before(period.EffectiveFrom) {
+			continue
+		}
+		if period.EffectiveUntil != nil && !at.Before(*period.EffectiveUntil) {
+			continue
+		}
+		return period, true
+	}
+	return PricePeriod{}, false
+}
+
+// Sign returns the base64-encoded detached signature of a manifest.
+func Sign(data []byte, privateKey ed25519.PrivateKey) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, data)))
+}
+
+// Verify checks the base64-encoded detached signature of a manifest against
+// the base64-encoded ed25519 public key.
+func Verify(data, signature []byte, publicKey string) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return errors.New("invalid pricing manifest public key")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return errors.Wrap(err, "failed to decode pricing manifest signature")
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), data, sig) {
+		return errors.New("pricing manifest signature does not match")
+	}
+	return nil
+}
+
+// DefaultManifestPath returns where the refreshed manifest is stored,
+// honouring KODELET_BASE_PATH.
+func DefaultManifestPath() (string, error) {
+	if basePath := strings.TrimSpace(os.Getenv("KODELET_BASE_PATH")); basePath != "" {
+		return filepath.Join(basePath, "pricing", "manifest.json"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get home directory")
+	}
+	return filepath.Join(homeDir, ".kodelet", "pricing", "manifest.json"), nil
+}
+
+// LoadManifest reads the manifest at path. It returns nil without an error
+// when no manifest has been stored.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to read pricing manifest")
+	}
+	return ParseManifest(data)
+}
+
+// SaveManifest stores the raw manifest bytes at path.
+func SaveManifest(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrap(err, "failed to create pricing directory")
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return errors.Wrap(err, "failed to write pricing manifest")
+	}
+	return errors.Wrap(os.Rename(tmpPath, path), "failed to store pricing manifest")
+}
+
+var (
+	currentMu     sync.Mutex
+	current       *Manifest
+	currentLoaded bool
+)
+
+// Current returns the stored manifest, loading it on first use. It returns
+// nil when no valid manifest is stored, in which case the built-in prices
+// apply.
+func Current() *Manifest {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	if currentLoaded {
+		return current
+	}
+	currentLoaded = true
+
+	path, err := DefaultManifestPath()
+	if err == nil {
+		current, err = LoadManifest(path)
+	}
+	if err != nil {
+		logger.G(context.Background()).WithError(err).Warn("ignoring stored pricing manifest")
+		current = nil
+	}
+	return current
+}
+
+// Reset forgets the loaded manifest so that the next Current call reads it
+// again.
+func Reset() {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = nil
+	currentLoaded = false
+}
+
+// ApplyPlatformPricing overlays the stored manifest prices that are effective
+// now onto the built-in prices of the openai or codex platform. Priority
+// prices apply to the fast and priority service tiers.
+func ApplyPlatformPricing(prices llmtypes.CustomPricing, platform string, serviceTier llmtypes.OpenAIServiceTier) llmtypes.CustomPricing {
+	manifest := Current()
+	now := time.Now()
+
+	result := manifest.Overlay(prices, platform, "", now)
+	tier, ok := llmtypes.ParseOpenAIServiceTier(string(serviceTier))
+	if ok && (tier == llmtypes.OpenAIServiceTierFast || tier == llmtypes.OpenAIServiceTierPriority) {
+		result = manifest.Overlay(result, platform, string(llmtypes.OpenAIServiceTierPriority), now)
+	}
+	return result
+}
@@ -0,0 +1,146 @@
+package pricing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+func date(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.DateOnly, value)
+	require.NoError(t, err)
+	return parsed
+}
+
+func testManifest(t *testing.T) *Manifest {
+	t.Helper()
+	cutover := date(t, "2026-11-01")
+	return &Manifest{
+		Version:     ManifestVersion,
+		PublishedAt: date(t, "2026-10-01"),
+		Models: []ModelPrices{
+			{
+				Provider: "anthropic",
+				Model:    "claude-sonnet-4-6",
+				Periods: []PricePeriod{
+					{EffectiveFrom: date(t, "2026-01-01"), EffectiveUntil: &cutover, ModelPricing: llmtypes.ModelPricing{Input: 3, Output: 15}},
+					{EffectiveFrom: cutover, ModelPricing: llmtypes.ModelPricing{Input: 2, Output: 10}},
+				},
+			},
+			{
+				Provider: "openai",
+				Model:    "gpt-5.4",
+				Periods:  []PricePeriod{{EffectiveFrom: date(t, "2026-01-01"), ModelPricing: llmtypes.ModelPricing{Input: 1}}},
+			},
+			{
+				Provider:    "openai",
+				Model:       "gpt-5.4",
+				ServiceTier: "priority",
+				Periods:     []PricePeriod{{EffectiveFrom: date(t, "2026-01-01"), ModelPricing: llmtypes.ModelPricing{Input: 2}}},
+			},
+		},
+	}
+}
+
+func TestManifestLookupUsesEffectivePeriod(t *testing.T) {
+	manifest := testManifest(t)
+
+	pricing, ok := manifest.Lookup("anthropic", "claude-sonnet-4-6", "", date(t, "2026-10-15"))
+	require.True(t, ok)
+	assert.Equal(t, 3.0, pricing.Input)
+
+	pricing, ok = manifest.Lookup("anthropic", "claude-sonnet-4-6", "", date(t, "2026-11-01"))
+	require.True(t, ok)
+	assert.Equal(t, 2.0, pricing.Input)
+
+	_, ok = manifest.Lookup("anthropic", "claude-sonnet-4-6", "", date(t, "2025-12-31"))
+	assert.False(t, ok, "prices before the first period are not known")
+
+	_, ok = manifest.Lookup("openai", "claude-sonnet-4-6", "", date(t, "2026-10-15"))
+	assert.False(t, ok)
+
+	var nilManifest *Manifest
+	_, ok = nilManifest.Lookup("anthropic", "claude-sonnet-4-6", "", date(t, "2026-10-15"))
+	assert.False(t, ok)
+}
+
+func TestManifestOverlayCopiesAndAppliesTier(t *testing.T) {
+	manifest := testManifest(t)
+	builtIn := llmtypes.CustomPricing{
+		"gpt-5.4":      {Input: 9},
+		"gpt-5.4-mini": {Input: 0.5},
+	}
+	at := date(t, "2026-10-15")
+
+	standard := manifest.Overlay(builtIn, "openai", "", at)
+	assert.Equal(t, 1.0, standard["gpt-5.4"].Input)
+	assert.Equal(t, 0.5, standard["gpt-5.4-mini"].Input)
+	assert.Equal(t, 9.0, builtIn["gpt-5.4"].Input, "overlay must not modify the built-in prices")
+
+	priority := manifest.Overlay(standard, "openai", "priority", at)
+	assert.Equal(t, 2.0, priority["gpt-5.4"].Input)
+
+	assert.Equal(t, 9.0, manifest.Overlay(builtIn, "codex", "", at)["gpt-5.4"].Input)
+}
+
+func TestParseManifestValidates(t *testing.T) {
+	_, err := ParseManifest([]byte(`{"version": 2, "models": []}`))
+	assert.ErrorContains(t, err, "unsupported pricing manifest version")
+
+	_, err = ParseManifest([]byte(`{"version": 1, "models": [{"provider": "openai", "model": "gpt-5.4", "periods": []}]}`))
+	assert.ErrorContains(t, err, "no price periods")
+
+	_, err = ParseManifest([]byte(`{"version": 1, "models": [{"provider": "openai", "model": "gpt-5.4", "periods": [
+		{"effective_from": "2026-02-01T00:00:00Z", "effective_until": "2026-01-01T00:00:00Z", "input": 1}]}]}`))
+	assert.ErrorContains(t, err, "ending before it starts")
+
+	manifest, err := ParseManifest([]byte(`{"version": 1, "published_at": "2026-10-01T00:00:00Z", "models": [
+		{"provider": "openai", "model": "gpt-5.4", "periods": [{"effective_from": "2026-01-01T00:00:00Z", "input": 0.000002, "long_context_threshold": 272000}]}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, 0.000002, manifest.Models[0].Periods[0].Input)
+	assert.Equal(t, 272000, manifest.Models[0].Periods[0].LongContextThreshold)
+}
+
+func TestSignAndVerify(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	encodedKey := base64.StdEncoding.EncodeToString(publicKey)
+	data := []byte(`{"version": 1}`)
+
+	signature := Sign(data, privateKey)
+	require.NoError(t, Verify(data, signature, encodedKey))
+
+	assert.ErrorContains(t, Verify([]byte(`{"version": 2}`), signature, encodedKey), "signature does not match")
+	assert.ErrorContains(t, Verify(data, signature, "not-a-key"), "invalid pricing manifest public key")
+}
+
+func TestCurrentLoadsStoredManifest(t *testing.T) {
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	Reset()
+	t.Cleanup(Reset)
+
+	assert.Nil(t, Current())
+
+	path, err := DefaultManifestPath()
+	require.NoError(t, err)
+	require.NoError(t, SaveManifest(path, []byte(`{"version": 1, "models": []}`)))
+	assert.Nil(t, Current(), "the loaded manifest is cached until Reset")
+
+	Reset()
+	require.NotNil(t, Current())
+
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o644))
+	Reset()
+	assert.Nil(t, Current(), "an invalid stored manifest is ignored")
+	assert.Equal(t, filepath.Join(os.Getenv("KODELET_BASE_PATH"), "pricing", "manifest.json"), path)
+}
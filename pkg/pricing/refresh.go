@@ -0,0 +1,99 @@
+package pricing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// maxManifestSize bounds the manifest and signature downloads.
+const maxManifestSize = 4 << 20
+
+// RefreshOptions configures RefreshManifest. Zero values use the release
+// manifest URL, the build's public key, the default manifest path and
+// http.DefaultClient.
+type RefreshOptions struct {
+	URL       string
+	PublicKey string
+	Path      string
+	Client    *http.Client
+}
+
+// RefreshManifest downloads the manifest and its detached signature, verifies
+// them and stores the manifest. A manifest published before the stored one is
+// rejected so that an outdated manifest cannot be replayed.
+func RefreshManifest(ctx context.Context, opts RefreshOptions) (*Manifest, error) {
+	if opts.URL == "" {
+		opts.URL = DefaultManifestURL
+	}
+	if opts.PublicKey == "" {
+		opts.PublicKey = PublicKey
+	}
+	if strings.TrimSpace(opts.PublicKey) == "" {
+		return nil, errors.New("no pricing manifest public key is configured; set pricing.public_key or use a release build")
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.Path == "" {
+		path, err := DefaultManifestPath()
+		if err != nil {
+			return nil, err
+		}
+		opts.Path = path
+	}
+
+	data, err := download(ctx, opts.Client, opts.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to download pricing manifest")
+	}
+	signature, err := download(ctx, opts.Client, opts.URL+".sig")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to download pricing manifest signature")
+	}
+	if err := Verify(data, signature, opts.PublicKey); err != nil {
+		return nil, err
+	}
+
+	manifest, err := ParseManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	if existing, err := LoadManifest(opts.Path); err == nil && existing != nil && manifest.PublishedAt.Before(existing.PublishedAt) {
+		return nil, errors.Errorf("downloaded pricing manifest (published %s) is older than the stored one (published %s)",
+			manifest.PublishedAt.Format("2006-01-02"), existing.PublishedAt.Format("2006-01-02"))
+	}
+
+	if err := SaveManifest(opts.Path, data); err != nil {
+		return nil, err
+	}
+	Reset()
+	return manifest, nil
+}
+
+func download(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response")
+	}
+	if len(data) > maxManifestSize {
+		return nil, errors.Errorf("response from %s exceeds %d bytes", url, maxManifestSize)
+	}
+	return data, nil
+}
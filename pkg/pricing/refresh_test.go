@@ -0,0 +1,97 @@
+package pricing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type manifestServer struct {
+	manifest  []byte
+	signature []byte
+}
+
+func (s *manifestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/pricing.json":
+		_, _ = w.Write(s.manifest)
+	case "/pricing.json.sig":
+		_, _ = w.Write(s.signature)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newRefreshFixture(t *testing.T) (*manifestServer, ed25519.PrivateKey, RefreshOptions) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	server := &manifestServer{}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	Reset()
+	t.Cleanup(Reset)
+
+	return server, privateKey, RefreshOptions{
+		URL:       httpServer.URL + "/pricing.json",
+		PublicKey: base64.StdEncoding.EncodeToString(publicKey),
+		Path:      filepath.Join(t.TempDir(), "manifest.json"),
+		Client:    httpServer.Client(),
+	}
+}
+
+func TestRefreshManifestStoresVerifiedManifest(t *testing.T) {
+	server, privateKey, opts := newRefreshFixture(t)
+	server.manifest = []byte(`{"version": 1, "published_at": "2026-10-01T00:00:00Z", "models": [
+		{"provider": "openai", "model": "gpt-5.4", "periods": [{"effective_from": "2026-01-01T00:00:00Z", "input": 0.000002}]}]}`)
+	server.signature = Sign(server.manifest, privateKey)
+
+	manifest, err := RefreshManifest(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Len(t, manifest.Models, 1)
+
+	stored, err := LoadManifest(opts.Path)
+	require.NoError(t, err)
+	assert.Equal(t, manifest, stored)
+}
+
+func TestRefreshManifestRejectsBadSignature(t *testing.T) {
+	server, privateKey, opts := newRefreshFixture(t)
+	server.manifest = []byte(`{"version": 1, "models": []}`)
+	server.signature = Sign([]byte(`{"version": 1, "models": [], "tampered": true}`), privateKey)
+
+	_, err := RefreshManifest(context.Background(), opts)
+	assert.ErrorContains(t, err, "signature does not match")
+
+	stored, err := LoadManifest(opts.Path)
+	require.NoError(t, err)
+	assert.Nil(t, stored, "an unverified manifest must not be stored")
+}
+
+func TestRefreshManifestRejectsOlderManifest(t *testing.T) {
+	server, privateKey, opts := newRefreshFixture(t)
+	require.NoError(t, SaveManifest(opts.Path, []byte(`{"version": 1, "published_at": "2026-10-01T00:00:00Z", "models": []}`)))
+	server.manifest = []byte(`{"version": 1, "published_at": "2026-09-01T00:00:00Z", "models": []}`)
+	server.signature = Sign(server.manifest, privateKey)
+
+	_, err := RefreshManifest(context.Background(), opts)
+	assert.ErrorContains(t, err, "older than the stored one")
+}
+
+func TestRefreshManifestRequiresPublicKey(t *testing.T) {
+	_, _, opts := newRefreshFixture(t)
+	opts.PublicKey = ""
+
+	_, err := RefreshManifest(context.Background(), opts)
+	assert.ErrorContains(t, err, "no pricing manifest public key")
+}
@@ -0,0 +1,98 @@
+// Package promptcache records the prompt caches created by `kodelet cache
+// prime` so usage output can show how long they stay warm.
+package promptcache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Record is a prompt cache primed for one model and working directory.
+type Record struct {
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	WorkingDirectory string    `json:"working_directory"`
+	Tokens           int       `json:"tokens"`
+	TTL              string    `json:"ttl"`
+	PrimedAt         time.Time `json:"primed_at"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+// Remaining returns how long the cache stays warm without further requests.
+// Every exchange that reads the cache extends it by its TTL again.
+func (r Record) Remaining(now time.Time) time.Duration {
+	return max(r.ExpiresAt.Sub(now), 0)
+}
+
+// DefaultPath returns the file primed caches are recorded in,
+// ~/.kodelet/cache/primed.json or under KODELET_BASE_PATH.
+func DefaultPath() (string, error) {
+	if basePath := strings.TrimSpace(os.Getenv("KODELET_BASE_PATH")); basePath != "" {
+		return filepath.Join(basePath, "cache", "primed.json"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get home directory")
+	}
+	return filepath.Join(homeDir, ".kodelet", "cache", "primed.json"), nil
+}
+
+// Load reads the records in path. A missing file has no records.
+func Load(path string) ([]Record, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read primed prompt caches")
+	}
+	var records []Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", path)
+	}
+	return records, nil
+}
+
+// Save adds record to path, replacing the record of the same model and
+// working directory and dropping records that have expired.
+func Save(path string, record Record) error {
+	records, err := Load(path)
+	if err != nil {
+		return err
+	}
+	kept := slices.DeleteFunc(Active(records, record.PrimedAt), func(r Record) bool {
+		return r.Provider == record.Provider && r.Model == record.Model && r.WorkingDirectory == record.WorkingDirectory
+	})
+	kept = append(kept, record)
+
+	data, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode primed prompt caches")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrap(err, "failed to create prompt cache directory")
+	}
+	return errors.Wrap(os.WriteFile(path, data, 0o644), "failed to write primed prompt caches")
+}
+
+// Active returns the records that have not expired at now, soonest to
+// expire first.
+func Active(records []Record, now time.Time) []Record {
+	var active []Record
+	for _, record := range records {
+		if record.ExpiresAt.After(now) {
+			active = append(active, record)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].ExpiresAt.Before(active[j].ExpiresAt)
+	})
+	return active
+}
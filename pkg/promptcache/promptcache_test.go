@@ -0,0 +1,45 @@
+package promptcache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveReplacesAndExpiresRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "primed.json")
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	records, err := Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	require.NoError(t, Save(path, Record{Provider: "anthropic", Model: "old", WorkingDirectory: "/repo", PrimedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)}))
+	require.NoError(t, Save(path, Record{Provider: "anthropic", Model: "claude", WorkingDirectory: "/repo", Tokens: 10, PrimedAt: now, ExpiresAt: now.Add(time.Hour)}))
+	require.NoError(t, Save(path, Record{Provider: "anthropic", Model: "claude", WorkingDirectory: "/other", Tokens: 20, PrimedAt: now, ExpiresAt: now.Add(5 * time.Minute)}))
+	require.NoError(t, Save(path, Record{Provider: "anthropic", Model: "claude", WorkingDirectory: "/repo", Tokens: 30, PrimedAt: now, ExpiresAt: now.Add(time.Hour)}))
+
+	records, err = Load(path)
+	require.NoError(t, err)
+	require.Len(t, records, 2, "expired records are dropped and a re-primed cache replaces its record")
+
+	active := Active(records, now)
+	require.Len(t, active, 2)
+	assert.Equal(t, "/other", active[0].WorkingDirectory, "soonest to expire first")
+	assert.Equal(t, 30, active[1].Tokens)
+	assert.Equal(t, time.Hour, active[1].Remaining(now))
+	assert.Zero(t, active[0].Remaining(now.Add(time.Hour)))
+
+	assert.Len(t, Active(records, now.Add(30*time.Minute)), 1)
+}
+
+func TestDefaultPathHonoursBasePath(t *testing.T) {
+	t.Setenv("KODELET_BASE_PATH", "/tmp/kodelet-base")
+
+	path, err := DefaultPath()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/tmp/kodelet-base", "cache", "primed.json"), path)
+}
@@ -0,0 +1,265 @@
+// Package quota tracks the rate limit windows reported by subscription
+// authenticated providers so a run can warn, downgrade or pause before it
+// hits a hard 429.
+package quota
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Action is what a run does once a quota window crosses the action threshold.
+type Action string
+
+const (
+	// ActionWarn only warns about the quota.
+	ActionWarn Action = "warn"
+	// ActionWeakModel switches the rest of the run to the weak model.
+	ActionWeakModel Action = "weak_model"
+	// ActionPause waits for the window to reset before the next request.
+	ActionPause Action = "pause"
+)
+
+// Default quota policy thresholds.
+const (
+	DefaultWarnThreshold   = 0.8
+	DefaultActionThreshold = 0.95
+	DefaultMaxPause        = 30 * time.Minute
+)
+
+// StatusRejected is the window status reported once requests are refused.
+const StatusRejected = "rejected"
+
+// Window is the usage of one rate limit window.
+type Window struct {
+	// Name identifies the window, e.g. "5h", "7d" or "requests".
+	Name   string
+	Status string
+	// Utilization is the used share of the window, from 0 to 1.
+	Utilization float64
+	// ResetsAt is when the window resets; zero when not reported.
+	ResetsAt time.Time
+}
+
+// Exhausted reports whether the window refuses further requests.
+func (w Window) Exhausted() bool {
+	return w.Status == StatusRejected || w.Utilization >= 1
+}
+
+// anthropicUnifiedWindows are the windows of the unified subscription rate
+// limit headers.
+var anthropicUnifiedWindows = []string{"5h", "7d"}
+
+// genericWindows are the OpenAI-style x-ratelimit-*-<name> header suffixes
+// used by GitHub Copilot and other OpenAI-compatible gateways.
+var genericWindows = []string{"requests", "tokens"}
+
+// ParseHeaders extracts the rate limit windows from a response's headers. It
+// understands Anthropic's unified subscription headers and the OpenAI-style
+// x-ratelimit-* headers.
+func ParseHeaders(header http.Header) []Window {
+	var windows []Window
+	for _, name := range anthropicUnifiedWindows {
+		prefix := "Anthropic-Ratelimit-Unified-" + name + "-"
+		status := header.Get(prefix + "Status")
+		utilization := header.Get(prefix + "Utilization")
+		if status == "" && utilization == "" {
+			continue
+		}
+		window := Window{Name: name, Status: status}
+		window.Utilization, _ = strconv.ParseFloat(utilization, 64)
+		if ts, err := strconv.ParseInt(header.Get(prefix+"Reset"), 10, 64); err == nil {
+			window.ResetsAt = time.Unix(ts, 0)
+		}
+		windows = append(windows, window)
+	}
+
+	for _, name := range genericWindows {
+		if window, ok := parseGenericWindow(name, header.Get("X-Ratelimit-Limit-"+name), header.Get("X-Ratelimit-Remaining-"+name), header.Get("X-Ratelimit-Reset-"+name)); ok {
+			windows = append(windows, window)
+		}
+	}
+	if window, ok := parseGenericWindow("requests", header.Get("X-Ratelimit-Limit"), header.Get("X-Ratelimit-Remaining"), header.Get("X-Ratelimit-Reset")); ok && !hasWindow(windows, "requests") {
+		windows = append(windows, window)
+	}
+	return windows
+}
+
+func parseGenericWindow(name, limit, remaining, reset string) (Window, bool) {
+	limitValue, err := strconv.ParseFloat(limit, 64)
+	if err != nil || limitValue <= 0 {
+		return Window{}, false
+	}
+	remainingValue, err := strconv.ParseFloat(remaining, 64)
+	if err != nil {
+		return Window{}, false
+	}
+	window := Window{
+		Name:        name,
+		Utilization: min(max(1-remainingValue/limitValue, 0), 1),
+		ResetsAt:    parseReset(reset, time.Now()),
+	}
+	if remainingValue <= 0 {
+		window.Status = StatusRejected
+	}
+	return window, true
+}
+
+// parseReset accepts a Unix timestamp, a Go duration ("6m0s") or a number of
+// seconds until the reset.
+func parseReset(value string, now time.Time) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		// Values this large are timestamps rather than offsets.
+		if seconds > 1e9 {
+			return time.Unix(int64(seconds), 0)
+		}
+		return now.Add(time.Duration(seconds * float64(time.Second)))
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(d)
+	}
+	return time.Time{}
+}
+
+func hasWindow(windows []Window, name string) bool {
+	for _, window := range windows {
+		if window.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy configures when to warn about and act on quota usage.
+type Policy struct {
+	// WarnThreshold is the utilization at which the user is warned.
+	WarnThreshold float64
+	// ActionThreshold is the utilization at which Action is taken.
+	ActionThreshold float64
+	Action          Action
+	// MaxPause is the longest ActionPause waits for a window to reset.
+	MaxPause time.Duration
+}
+
+// Level is how close a window is to its limit under a policy.
+type Level int
+
+const (
+	LevelOK Level = iota
+	// LevelWarn means the window crossed the warn threshold.
+	LevelWarn
+	// LevelAction means the window crossed the action threshold.
+	LevelAction
+)
+
+// Notice reports a window that newly crossed a threshold.
+type Notice struct {
+	Window Window
+	Level  Level
+}
+
+// Decision is the outcome of checking the tracked windows against a policy.
+type Decision struct {
+	// Notices lists the windows that crossed a threshold since the last
+	// check, so each crossing is reported once.
+	Notices []Notice
+	// Limiting is the most used window at or above the action threshold.
+	Limiting *Window
+}
+
+// Tracker keeps the latest usage of each rate limit window seen during a
+// thread's lifetime. It is safe for concurrent use.
+type Tracker struct {
+	mu       sync.Mutex
+	windows  map[string]Window
+	reported map[string]Level
+	now      func() time.Time
+}
+
+// NewTracker creates an empty tracker.
+func NewTracker() *Tracker {
+	return &Tracker{
+		windows:  make(map[string]Window),
+		reported: make(map[string]Level),
+		now:      time.Now,
+	}
+}
+
+// Observe records the windows reported in a response's headers.
+func (t *Tracker) Observe(header http.Header) {
+	windows := ParseHeaders(header)
+	if len(windows) == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, window := range windows {
+		t.windows[window.Name] = window
+	}
+}
+
+// Windows returns the tracked windows that have not reset yet, by name.
+func (t *Tracker) Windows() []Window {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.expireLocked()
+	windows := make([]Window, 0, len(t.windows))
+	for _, window := range t.windows {
+		windows = append(windows, window)
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Name < windows[j].Name })
+	return windows
+}
+
+// Check compares the tracked windows with policy.
+func (t *Tracker) Check(policy Policy) Decision {
+	var decision Decision
+	for _, window := range t.Windows() {
+		level := LevelOK
+		switch {
+		case window.Exhausted() || window.Utilization >= policy.ActionThreshold:
+			level = LevelAction
+		case window.Utilization >= policy.WarnThreshold:
+			level = LevelWarn
+		}
+
+		t.mu.Lock()
+		if level > t.reported[window.Name] {
+			decision.Notices = append(decision.Notices, Notice{Window: window, Level: level})
+		}
+		t.reported[window.Name] = level
+		t.mu.Unlock()
+
+		if level == LevelAction && (decision.Limiting == nil || window.Utilization > decision.Limiting.Utilization) {
+			limiting := window
+			decision.Limiting = &limiting
+		}
+	}
+	return decision
+}
+
+// Forget drops a window, e.g. after waiting for it to reset.
+func (t *Tracker) Forget(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.windows, name)
+	delete(t.reported, name)
+}
+
+func (t *Tracker) expireLocked() {
+	now := t.now()
+	for name, window := range t.windows {
+		if !window.ResetsAt.IsZero() && !now.Before(window.ResetsAt) {
+			delete(t.windows, name)
+			delete(t.reported, name)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package quota
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHeaders(t *testing.T) {
+	t.Run("anthropic unified windows", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("Anthropic-Ratelimit-Unified-5h-Status", "allowed_warning")
+		header.Set("Anthropic-Ratelimit-Unified-5h-Utilization", "0.82")
+		header.Set("Anthropic-Ratelimit-Unified-5h-Reset", "1760000000")
+		header.Set("Anthropic-Ratelimit-Unified-7d-Status", "allowed")
+		header.Set("Anthropic-Ratelimit-Unified-7d-Utilization", "0.1")
+
+		windows := ParseHeaders(header)
+
+		require.Len(t, windows, 2)
+		assert.Equal(t, Window{Name: "5h", Status: "allowed_warning", Utilization: 0.82, ResetsAt: time.Unix(1760000000, 0)}, windows[0])
+		assert.Equal(t, "7d", windows[1].Name)
+		assert.True(t, windows[1].ResetsAt.IsZero())
+	})
+
+	t.Run("openai-style windows", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("X-Ratelimit-Limit-Requests", "100")
+		header.Set("X-Ratelimit-Remaining-Requests", "0")
+		header.Set("X-Ratelimit-Reset-Requests", "6m0s")
+		header.Set("X-Ratelimit-Limit-Tokens", "1000")
+		header.Set("X-Ratelimit-Remaining-Tokens", "250")
+
+		windows := ParseHeaders(header)
+
+		require.Len(t, windows, 2)
+		assert.Equal(t, "requests", windows[0].Name)
+		assert.True(t, windows[0].Exhausted())
+		assert.WithinDuration(t, time.Now().Add(6*time.Minute), windows[0].ResetsAt, time.Minute)
+		assert.Equal(t, "tokens", windows[1].Name)
+		assert.InDelta(t, 0.75, windows[1].Utilization, 1e-9)
+	})
+
+	t.Run("no rate limit headers", func(t *testing.T) {
+		assert.Empty(t, ParseHeaders(http.Header{"Content-Type": {"application/json"}}))
+	})
+}
+
+func TestTrackerCheck(t *testing.T) {
+	policy := Policy{WarnThreshold: 0.8, ActionThreshold: 0.95, Action: ActionWarn}
+	observe := func(tracker *Tracker, utilization float64, reset time.Time) {
+		header := http.Header{}
+		header.Set("Anthropic-Ratelimit-Unified-5h-Status", "allowed")
+		header.Set("Anthropic-Ratelimit-Unified-5h-Utilization", strconv.FormatFloat(utilization, 'f', -1, 64))
+		header.Set("Anthropic-Ratelimit-Unified-5h-Reset", strconv.FormatInt(reset.Unix(), 10))
+		tracker.Observe(header)
+	}
+	reset := time.Now().Add(time.Hour)
+
+	tracker := NewTracker()
+	observe(tracker, 0.5, reset)
+	assert.Equal(t, Decision{}, tracker.Check(policy))
+
+	observe(tracker, 0.85, reset)
+	decision := tracker.Check(policy)
+	require.Len(t, decision.Notices, 1)
+	assert.Equal(t, LevelWarn, decision.Notices[0].Level)
+	assert.Nil(t, decision.Limiting)
+	assert.Empty(t, tracker.Check(policy).Notices, "each crossing is reported once")
+
+	observe(tracker, 0.97, reset)
+	decision = tracker.Check(policy)
+	require.Len(t, decision.Notices, 1)
+	assert.Equal(t, LevelAction, decision.Notices[0].Level)
+	require.NotNil(t, decision.Limiting)
+	assert.Equal(t, "5h", decision.Limiting.Name)
+	assert.NotNil(t, tracker.Check(policy).Limiting, "the limiting window stays until it resets")
+
+	tracker.now = func() time.Time { return reset.Add(time.Second) }
+	assert.Empty(t, tracker.Windows(), "windows are dropped once they reset")
+	assert.Equal(t, Decision{}, tracker.Check(policy))
+}
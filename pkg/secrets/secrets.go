@@ -0,0 +1,267 @@
+// Package secrets resolves secret:// references against external secret
+// managers. Resolved values are handed to tool subprocesses through their
+// environment only; they are never part of the model context or of stored
+// conversations.
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os/exec"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/audit"
+	"github.com/pkg/errors"
+)
+
+// Scheme prefixes values that reference a secret instead of holding one.
+const Scheme = "secret://"
+
+// Providers supported in references.
+const (
+	// ProviderVault reads a field of a HashiCorp Vault KV secret with the
+	// vault CLI: secret://vault/<path>#<field>.
+	ProviderVault = "vault"
+	// ProviderAWS reads an AWS Secrets Manager secret with the aws CLI:
+	// secret://aws/<secret-id>, or secret://aws/<secret-id>#<key> for a key
+	// of a JSON secret.
+	ProviderAWS = "aws"
+	// Provider1Password reads a field with the 1Password CLI:
+	// secret://1password/<vault>/<item>/<field>.
+	Provider1Password = "1password"
+)
+
+// defaultVaultField is the Vault field read when a reference names none.
+const defaultVaultField = "value"
+
+// minRedactedLength keeps very short values, which would match ordinary
+// output, from being redacted.
+const minRedactedLength = 4
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Reference is a parsed secret:// URI.
+type Reference struct {
+	URI      string
+	Provider string
+	Path     string
+	Field    string
+}
+
+// IsReference reports whether value is a secret:// URI.
+func IsReference(value string) bool {
+	return strings.HasPrefix(strings.TrimSpace(value), Scheme)
+}
+
+// ParseReference parses a secret:// URI.
+func ParseReference(uri string) (Reference, error) {
+	uri = strings.TrimSpace(uri)
+	rest, ok := strings.CutPrefix(uri, Scheme)
+	if !ok {
+		return Reference{}, errors.Errorf("secret reference %q must start with %s", uri, Scheme)
+	}
+	provider, location, _ := strings.Cut(rest, "/")
+	path, field, _ := strings.Cut(location, "#")
+	ref := Reference{URI: uri, Provider: strings.ToLower(provider), Path: strings.Trim(path, "/"), Field: field}
+	if ref.Path == "" {
+		return Reference{}, errors.Errorf("secret reference %q must name a secret after the provider", uri)
+	}
+	switch ref.Provider {
+	case ProviderVault:
+		if ref.Field == "" {
+			ref.Field = defaultVaultField
+		}
+	case ProviderAWS:
+	case Provider1Password, "op":
+		ref.Provider = Provider1Password
+		if ref.Field != "" || strings.Count(ref.Path, "/") < 2 {
+			return Reference{}, errors.Errorf("secret reference %q must have the form %s1password/<vault>/<item>/<field>", uri, Scheme)
+		}
+	default:
+		return Reference{}, errors.Errorf("secret reference %q: unknown provider %q, expected vault, aws or 1password", uri, provider)
+	}
+	return ref, nil
+}
+
+// ValidateEnv checks that every name is a valid environment variable name and
+// every secret:// value parses.
+func ValidateEnv(env map[string]string) error {
+	for _, name := range sortedNames(env) {
+		if !envNamePattern.MatchString(name) {
+			return errors.Errorf("invalid environment variable name %q", name)
+		}
+		if IsReference(env[name]) {
+			if _, err := ParseReference(env[name]); err != nil {
+				return errors.Wrapf(err, "invalid value of %s", name)
+			}
+		}
+	}
+	return nil
+}
+
+// runFunc runs a secret manager CLI and returns its standard output.
+type runFunc func(ctx context.Context, name string, args ...string) (string, error)
+
+// Resolver resolves references and remembers the resolved values for the
+// life of the process, so that each secret is fetched at most once and can be
+// redacted from tool output. It is safe for concurrent use.
+type Resolver struct {
+	run runFunc
+
+	mu     sync.Mutex
+	values map[string]string
+	// names maps resolved values to the variable they were injected as,
+	// for redaction.
+	names map[string]string
+}
+
+// NewResolver returns a resolver that calls the vault, aws and op CLIs.
+func NewResolver() *Resolver {
+	return newResolver(runCommand)
+}
+
+func newResolver(run runFunc) *Resolver {
+	return &Resolver{run: run, values: make(map[string]string), names: make(map[string]string)}
+}
+
+// Env returns env as NAME=value entries sorted by name, with secret://
+// values resolved just in time. Every secret handed out is recorded in the
+// secrets audit log on behalf of source.
+func (r *Resolver) Env(ctx context.Context, env map[string]string, source audit.SecretSource) ([]string, error) {
+	entries := make([]string, 0, len(env))
+	for _, name := range sortedNames(env) {
+		value := env[name]
+		if IsReference(value) {
+			ref, err := ParseReference(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid value of %s", name)
+			}
+			value, err = r.resolve(ctx, name, ref, source)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to resolve secret for %s", name)
+			}
+		}
+		entries = append(entries, name+"="+value)
+	}
+	return entries, nil
+}
+
+func (r *Resolver) resolve(ctx context.Context, name string, ref Reference, source audit.SecretSource) (string, error) {
+	entry := audit.SecretAccessEntry{
+		ConversationID: source.ConversationID,
+		Tool:           source.Tool,
+		Name:           name,
+		Provider:       ref.Provider,
+		Reference:      ref.URI,
+	}
+
+	r.mu.Lock()
+	value, cached := r.values[ref.URI]
+	r.mu.Unlock()
+	if !cached {
+		var err error
+		value, err = r.fetch(ctx, ref)
+		if err != nil {
+			entry.Error = err.Error()
+			audit.RecordSecretAccess(ctx, entry)
+			return "", err
+		}
+	}
+
+	r.mu.Lock()
+	r.values[ref.URI] = value
+	if len(value) >= minRedactedLength {
+		r.names[value] = name
+	}
+	r.mu.Unlock()
+
+	entry.Cached = cached
+	audit.RecordSecretAccess(ctx, entry)
+	return value, nil
+}
+
+func (r *Resolver) fetch(ctx context.Context, ref Reference) (string, error) {
+	switch ref.Provider {
+	case ProviderVault:
+		return r.run(ctx, "vault", "kv", "get", "-field="+ref.Field, ref.Path)
+	case ProviderAWS:
+		value, err := r.run(ctx, "aws", "secretsmanager", "get-secret-value", "--secret-id", ref.Path, "--query", "SecretString", "--output", "text")
+		if err != nil || ref.Field == "" {
+			return value, err
+		}
+		var fields map[string]any
+		if err := json.Unmarshal([]byte(value), &fields); err != nil {
+			return "", errors.Errorf("secret %s is not a JSON object, so key %q cannot be read", ref.Path, ref.Field)
+		}
+		field, ok := fields[ref.Field]
+		if !ok {
+			return "", errors.Errorf("secret %s has no key %q", ref.Path, ref.Field)
+		}
+		if s, ok := field.(string); ok {
+			return s, nil
+		}
+		encoded, _ := json.Marshal(field)
+		return string(encoded), nil
+	case Provider1Password:
+		return r.run(ctx, "op", "read", "op://"+ref.Path)
+	}
+	return "", errors.Errorf("unknown secret provider %q", ref.Provider)
+}
+
+// Redact replaces every value the resolver has handed out in text with a
+// placeholder naming its variable.
+func (r *Resolver) Redact(text string) string {
+	if r == nil || text == "" {
+		return text
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.names) == 0 {
+		return text
+	}
+	values := make([]string, 0, len(r.names))
+	for value := range r.names {
+		values = append(values, value)
+	}
+	// Longer values first, so a value containing another is replaced whole.
+	slices.SortFunc(values, func(a, b string) int { return len(b) - len(a) })
+	for _, value := range values {
+		text = strings.ReplaceAll(text, value, "[redacted: "+r.names[value]+"]")
+	}
+	return text
+}
+
+// commandTimeout bounds each call of a secret manager CLI.
+const commandTimeout = 30 * time.Second
+
+func runCommand(ctx context.Context, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", errors.Errorf("%s failed: %s", name, message)
+		}
+		return "", errors.Wrapf(err, "%s failed", name)
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+func sortedNames(env map[string]string) []string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
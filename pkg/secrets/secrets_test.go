@@ -0,0 +1,99 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jingkaihe/kodelet/pkg/audit"
+)
+
+func TestParseReference(t *testing.T) {
+	ref, err := ParseReference("secret://vault/secret/ci/deploy#token")
+	require.NoError(t, err)
+	assert.Equal(t, Reference{URI: "secret://vault/secret/ci/deploy#token", Provider: ProviderVault, Path: "secret/ci/deploy", Field: "token"}, ref)
+
+	ref, err = ParseReference("secret://vault/secret/ci/deploy")
+	require.NoError(t, err)
+	assert.Equal(t, "value", ref.Field)
+
+	ref, err = ParseReference("secret://op/Engineering/GitHub/token")
+	require.NoError(t, err)
+	assert.Equal(t, Provider1Password, ref.Provider)
+
+	for _, invalid := range []string{
+		"vault/secret/ci",
+		"secret://vault/",
+		"secret://gcp/project/secret",
+		"secret://1password/Engineering/GitHub",
+	} {
+		_, err := ParseReference(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestValidateEnv(t *testing.T) {
+	require.NoError(t, ValidateEnv(map[string]string{"GITHUB_TOKEN": "secret://aws/ci/github", "REGION": "eu-west-1"}))
+	assert.Error(t, ValidateEnv(map[string]string{"BAD-NAME": "x"}))
+	assert.Error(t, ValidateEnv(map[string]string{"TOKEN": "secret://nope/x"}))
+}
+
+func TestResolverEnvResolvesAuditsAndRedacts(t *testing.T) {
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	var calls []string
+	resolver := newResolver(func(_ context.Context, name string, args ...string) (string, error) {
+		calls = append(calls, name+" "+strings.Join(args, " "))
+		switch name {
+		case "vault":
+			return "s3cr3t-vault", nil
+		case "aws":
+			return `{"password":"s3cr3t-aws","port":5432}`, nil
+		}
+		return "", errors.New("op: not signed in")
+	})
+	env := map[string]string{
+		"DEPLOY_TOKEN": "secret://vault/ci/deploy#token",
+		"DB_PASSWORD":  "secret://aws/prod/db#password",
+		"REGION":       "eu-west-1",
+	}
+	source := audit.SecretSource{Tool: "bash", ConversationID: "conv-1"}
+
+	entries, err := resolver.Env(context.Background(), env, source)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"DB_PASSWORD=s3cr3t-aws", "DEPLOY_TOKEN=s3cr3t-vault", "REGION=eu-west-1"}, entries)
+	assert.Equal(t, []string{
+		"aws secretsmanager get-secret-value --secret-id prod/db --query SecretString --output text",
+		"vault kv get -field=token ci/deploy",
+	}, calls)
+
+	_, err = resolver.Env(context.Background(), env, source)
+	require.NoError(t, err)
+	assert.Len(t, calls, 2, "secrets are fetched once")
+
+	assert.Equal(t, "token=[redacted: DEPLOY_TOKEN] region=eu-west-1", resolver.Redact("token=s3cr3t-vault region=eu-west-1"))
+
+	_, err = resolver.Env(context.Background(), map[string]string{"GH": "secret://1password/Eng/GitHub/token"}, source)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to resolve secret for GH")
+
+	path, err := audit.DefaultSecretAccessLogPath()
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "s3cr3t", "values are never logged")
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 5)
+	var last audit.SecretAccessEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[4]), &last))
+	assert.Equal(t, "GH", last.Name)
+	assert.Equal(t, "conv-1", last.ConversationID)
+	assert.Equal(t, "op: not signed in", last.Error)
+	assert.Equal(t, filepath.Join(os.Getenv("KODELET_BASE_PATH"), "audit", "secrets.jsonl"), path)
+}
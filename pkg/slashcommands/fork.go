@@ -0,0 +1,23 @@
+package slashcommands
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ForkCommandName is the built-in slash command that continues the chat in a
+// copy of the current conversation.
+const ForkCommandName = "fork"
+
+// ParseForkCommand parses the built-in fork command. handled is false for any
+// other command.
+func ParseForkCommand(command, args string) (handled bool, err error) {
+	if strings.TrimSpace(command) != ForkCommandName {
+		return false, nil
+	}
+	if strings.TrimSpace(args) != "" {
+		return true, errors.New("usage: /fork")
+	}
+	return true, nil
+}
@@ -160,6 +160,28 @@ func BuiltIns() []Command {
 			Hint:        "objective",
 			Placeholder: "/goal <objective>",
 		},
+		{
+			Name:        ThinkCommandName,
+			Description: "Send a message with higher reasoning effort for this exchange only",
+			Hint:        "harder message",
+			Placeholder: "/think harder <message>",
+		},
+		{
+			Name:        UndoCommandName,
+			Description: "Revert the files changed in the previous turn",
+			Placeholder: "/undo",
+		},
+		{
+			Name:        ForkCommandName,
+			Description: "Continue in a copy of this conversation, leaving the original untouched",
+			Placeholder: "/fork",
+		},
+		{
+			Name:        UseConversationCommandName,
+			Description: "Bring the summary of another conversation into this one as context",
+			Hint:        "conversation-id [--messages N] [message]",
+			Placeholder: "/use-conversation <conversation-id> [--messages N] [message]",
+		},
 	}
 }
 
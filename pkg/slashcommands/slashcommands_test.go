@@ -81,13 +81,93 @@ func TestBuildCommandPlaceholder(t *testing.T) {
 func TestBuiltIns(t *testing.T) {
 	commands := BuiltIns()
 
-	require.Len(t, commands, 1)
+	require.Len(t, commands, 5)
 	assert.Equal(t, Command{
 		Name:        "goal",
 		Description: "Set the active goal for this thread",
 		Hint:        "objective",
 		Placeholder: "/goal <objective>",
 	}, commands[0])
+	assert.Equal(t, "think", commands[1].Name)
+	assert.Equal(t, "/think harder <message>", commands[1].Placeholder)
+	assert.Equal(t, "undo", commands[2].Name)
+	assert.Equal(t, "fork", commands[3].Name)
+	assert.Equal(t, "use-conversation", commands[4].Name)
+}
+
+func TestParseThinkCommand(t *testing.T) {
+	think, handled, err := ParseThinkCommand("think", "  harder   why does the cache miss? ")
+	require.NoError(t, err)
+	assert.True(t, handled)
+	assert.Equal(t, "why does the cache miss?", think.Message)
+
+	_, handled, err = ParseThinkCommand("think", "harder")
+	assert.True(t, handled)
+	assert.EqualError(t, err, "usage: /think harder <message>")
+
+	_, handled, err = ParseThinkCommand("think", "softer please")
+	assert.True(t, handled)
+	assert.Error(t, err)
+
+	_, handled, err = ParseThinkCommand("goal", "harder ship it")
+	assert.False(t, handled)
+	assert.NoError(t, err)
+}
+
+func TestParseUndoCommand(t *testing.T) {
+	handled, err := ParseUndoCommand("undo", " ")
+	require.NoError(t, err)
+	assert.True(t, handled)
+
+	handled, err = ParseUndoCommand("undo", "everything")
+	assert.True(t, handled)
+	assert.EqualError(t, err, "usage: /undo")
+
+	handled, err = ParseUndoCommand("think", "")
+	assert.False(t, handled)
+	assert.NoError(t, err)
+}
+
+func TestParseForkCommand(t *testing.T) {
+	handled, err := ParseForkCommand("fork", "")
+	require.NoError(t, err)
+	assert.True(t, handled)
+
+	handled, err = ParseForkCommand("fork", "now")
+	assert.True(t, handled)
+	assert.EqualError(t, err, "usage: /fork")
+
+	handled, err = ParseForkCommand("undo", "")
+	assert.False(t, handled)
+	assert.NoError(t, err)
+}
+
+func TestParseUseConversationCommand(t *testing.T) {
+	parsed, handled, err := ParseUseConversationCommand("use-conversation", " conv-1 ")
+	require.NoError(t, err)
+	assert.True(t, handled)
+	assert.Equal(t, UseConversationCommand{ConversationID: "conv-1"}, parsed)
+
+	parsed, handled, err = ParseUseConversationCommand("use-conversation", "conv-1 --messages 4  continue the design")
+	require.NoError(t, err)
+	assert.True(t, handled)
+	assert.Equal(t, UseConversationCommand{ConversationID: "conv-1", Messages: 4, Message: "continue the design"}, parsed)
+
+	parsed, _, err = ParseUseConversationCommand("use-conversation", "conv-1 continue the design")
+	require.NoError(t, err)
+	assert.Equal(t, "continue the design", parsed.Message)
+	assert.Zero(t, parsed.Messages)
+
+	_, handled, err = ParseUseConversationCommand("use-conversation", "")
+	assert.True(t, handled)
+	assert.EqualError(t, err, "usage: /use-conversation <conversation-id> [--messages N] [message]")
+
+	_, _, err = ParseUseConversationCommand("use-conversation", "conv-1 --messages many")
+	assert.Error(t, err)
+
+	_, handled, err = ParseUseConversationCommand("fork", "conv-1")
+	assert.False(t, handled)
+	assert.NoError(t, err)
 }
 
 func TestListAndRecipeCommands(t *testing.T) {
@@ -0,0 +1,35 @@
+package slashcommands
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ThinkCommandName is the built-in slash command that raises the
+	// reasoning effort for a single message.
+	ThinkCommandName = "think"
+	thinkHarderArg   = "harder"
+)
+
+// ThinkCommand is a parsed `/think harder <message>` invocation.
+type ThinkCommand struct {
+	// Message is the text sent to the model with the raised reasoning effort.
+	Message string
+}
+
+// ParseThinkCommand parses the built-in think command. handled is false for
+// any other command.
+func ParseThinkCommand(command, args string) (ThinkCommand, bool, error) {
+	if strings.TrimSpace(command) != ThinkCommandName {
+		return ThinkCommand{}, false, nil
+	}
+
+	mode, message, _ := strings.Cut(strings.TrimSpace(args), " ")
+	message = strings.TrimSpace(message)
+	if mode != thinkHarderArg || message == "" {
+		return ThinkCommand{}, true, errors.New("usage: /think harder <message>")
+	}
+	return ThinkCommand{Message: message}, true, nil
+}
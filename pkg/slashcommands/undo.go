@@ -0,0 +1,23 @@
+package slashcommands
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// UndoCommandName is the built-in slash command that reverts the file changes
+// of the previous turn.
+const UndoCommandName = "undo"
+
+// ParseUndoCommand parses the built-in undo command. handled is false for any
+// other command.
+func ParseUndoCommand(command, args string) (handled bool, err error) {
+	if strings.TrimSpace(command) != UndoCommandName {
+		return false, nil
+	}
+	if strings.TrimSpace(args) != "" {
+		return true, errors.New("usage: /undo")
+	}
+	return true, nil
+}
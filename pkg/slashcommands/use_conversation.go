@@ -0,0 +1,66 @@
+package slashcommands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// UseConversationCommandName is the built-in slash command that injects
+	// the context of another conversation into the current one.
+	UseConversationCommandName = "use-conversation"
+	useConversationMessagesArg = "--messages"
+	useConversationUsage       = "usage: /use-conversation <conversation-id> [--messages N] [message]"
+	// defaultUseConversationMessage is sent after the context when the
+	// command has no message of its own.
+	defaultUseConversationMessage = "Read the context from the other conversation above. Briefly confirm what you took from it, then wait for my next instruction."
+)
+
+// UseConversationCommand is a parsed
+// `/use-conversation <id> [--messages N] [message]` invocation.
+type UseConversationCommand struct {
+	ConversationID string
+	// Messages is how many recent messages of the conversation are included
+	// alongside its summary.
+	Messages int
+	// Message is the text sent to the model after the context, if any.
+	Message string
+}
+
+// ParseUseConversationCommand parses the built-in use-conversation command.
+// handled is false for any other command.
+func ParseUseConversationCommand(command, args string) (UseConversationCommand, bool, error) {
+	if strings.TrimSpace(command) != UseConversationCommandName {
+		return UseConversationCommand{}, false, nil
+	}
+
+	id, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	if id == "" || strings.HasPrefix(id, "-") {
+		return UseConversationCommand{}, true, errors.New(useConversationUsage)
+	}
+	parsed := UseConversationCommand{ConversationID: id}
+
+	rest = strings.TrimSpace(rest)
+	if flag, value, _ := strings.Cut(rest, " "); flag == useConversationMessagesArg {
+		count, message, _ := strings.Cut(strings.TrimSpace(value), " ")
+		messages, err := strconv.Atoi(count)
+		if err != nil || messages < 0 {
+			return UseConversationCommand{}, true, errors.New(useConversationUsage)
+		}
+		parsed.Messages = messages
+		rest = strings.TrimSpace(message)
+	}
+	parsed.Message = rest
+	return parsed, true, nil
+}
+
+// MessageOrDefault returns the message to send after the context, asking the
+// model to acknowledge it when the command has no message of its own.
+func (c UseConversationCommand) MessageOrDefault() string {
+	if c.Message == "" {
+		return defaultUseConversationMessage
+	}
+	return c.Message
+}
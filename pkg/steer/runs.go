@@ -0,0 +1,162 @@
+package steer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/osutil"
+	"github.com/pkg/errors"
+)
+
+const (
+	// runHeartbeatInterval is how often an active run refreshes its record.
+	runHeartbeatInterval = 15 * time.Second
+	// staleRunAfter is how long a run may go without a heartbeat before it is
+	// treated as finished, e.g. after the process was killed.
+	staleRunAfter = 4 * runHeartbeatInterval
+)
+
+// TrackRun records that the current process is running conversationID until
+// the returned function is called, so steering submitted from elsewhere can
+// tell whether it will be consumed. Failures are logged and never block the run.
+func TrackRun(ctx context.Context, conversationID string, opts ...StoreOption) func() {
+	if strings.TrimSpace(conversationID) == "" {
+		return func() {}
+	}
+
+	store, err := NewSteerStore(ctx, opts...)
+	if err != nil {
+		logger.G(ctx).WithError(err).Debug("failed to open steer store to track conversation run")
+		return func() {}
+	}
+	release := store.TrackRun(ctx, conversationID)
+	return func() {
+		release()
+		_ = store.Close()
+	}
+}
+
+// TrackRun is like the package-level TrackRun, but records the run in the
+// store's database, which stays open after the returned function is called.
+func (s *Store) TrackRun(ctx context.Context, conversationID string) func() {
+	conversationID = strings.TrimSpace(conversationID)
+	if conversationID == "" {
+		return func() {}
+	}
+
+	// A nested run in the same conversation (or a live run in another process)
+	// already holds the record; leave it in place so it is not cleared early.
+	if active, err := s.IsRunActive(ctx, conversationID); err != nil || active {
+		if err != nil {
+			logger.G(ctx).WithError(err).Debug("failed to check existing conversation run")
+		}
+		return func() {}
+	}
+
+	hostname, _ := os.Hostname()
+	pid := os.Getpid()
+	now := time.Now().UTC()
+	owner := fmt.Sprintf("%s:%d:%d", hostname, pid, now.UnixNano())
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO conversation_runs (conversation_id, owner, hostname, pid, started_at, heartbeat_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, conversationID, owner, hostname, pid, now, now); err != nil {
+		logger.G(ctx).WithError(err).Debug("failed to record conversation run")
+		return func() {}
+	}
+
+	heartbeatCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(runHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-heartbeatCtx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.db.ExecContext(heartbeatCtx, `
+					UPDATE conversation_runs SET heartbeat_at = ? WHERE owner = ?
+				`, time.Now().UTC(), owner); err != nil && heartbeatCtx.Err() == nil {
+					logger.G(ctx).WithError(err).Debug("failed to refresh conversation run heartbeat")
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+		if _, err := s.db.ExecContext(context.WithoutCancel(ctx), `
+			DELETE FROM conversation_runs WHERE owner = ?
+		`, owner); err != nil {
+			logger.G(ctx).WithError(err).Debug("failed to clear conversation run")
+		}
+	}
+}
+
+// IsRunActive reports whether a live run will consume steering queued for
+// conversationID. Records left behind by crashed processes or runs that stopped
+// heartbeating are treated as stale and removed.
+func (s *Store) IsRunActive(ctx context.Context, conversationID string) (bool, error) {
+	var run struct {
+		Owner       string    `db:"owner"`
+		Hostname    string    `db:"hostname"`
+		PID         int       `db:"pid"`
+		HeartbeatAt time.Time `db:"heartbeat_at"`
+	}
+	err := s.db.GetContext(ctx, &run, `
+		SELECT owner, hostname, pid, heartbeat_at
+		FROM conversation_runs
+		WHERE conversation_id = ?
+	`, conversationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "failed to query conversation run")
+	}
+
+	if !runIsStale(run.Hostname, run.PID, run.HeartbeatAt, time.Now()) {
+		return true, nil
+	}
+
+	logger.G(ctx).
+		WithField("conversation_id", conversationID).
+		WithField("pid", run.PID).
+		Debug("removing stale conversation run")
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM conversation_runs WHERE owner = ?
+	`, run.Owner); err != nil {
+		return false, errors.Wrap(err, "failed to remove stale conversation run")
+	}
+	return false, nil
+}
+
+func runIsStale(hostname string, pid int, heartbeatAt, now time.Time) bool {
+	if now.Sub(heartbeatAt) > staleRunAfter {
+		return true
+	}
+	currentHost, _ := os.Hostname()
+	return hostname == currentHost && !osutil.ProcessAlive(pid)
+}
+
+// FollowUpPrompt combines queued steering messages into the prompt and images
+// for a follow-up run of an idle conversation.
+func FollowUpPrompt(messages []Message) (string, []string) {
+	parts := make([]string, 0, len(messages))
+	var images []string
+	for _, message := range messages {
+		if content := strings.TrimSpace(message.Content); content != "" {
+			parts = append(parts, content)
+		}
+		images = append(images, message.Images...)
+	}
+	return strings.Join(parts, "\n\n"), images
+}
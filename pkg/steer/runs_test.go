@@ -0,0 +1,81 @@
+package steer
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackRunMarksConversationActiveUntilReleased(t *testing.T) {
+	ctx := context.Background()
+	store, dbPath := newTestStore(t)
+
+	active, err := store.IsRunActive(ctx, "conv-1")
+	require.NoError(t, err)
+	assert.False(t, active)
+
+	release := TrackRun(ctx, "conv-1", WithDBPath(dbPath))
+	active, err = store.IsRunActive(ctx, "conv-1")
+	require.NoError(t, err)
+	assert.True(t, active)
+
+	// A nested run must not clear the outer run's record when it finishes.
+	TrackRun(ctx, "conv-1", WithDBPath(dbPath))()
+	active, err = store.IsRunActive(ctx, "conv-1")
+	require.NoError(t, err)
+	assert.True(t, active)
+
+	release()
+	active, err = store.IsRunActive(ctx, "conv-1")
+	require.NoError(t, err)
+	assert.False(t, active)
+}
+
+func TestIsRunActiveRemovesStaleRuns(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStore(t)
+	hostname, _ := os.Hostname()
+	now := time.Now().UTC()
+
+	insertRun := func(conversationID string, pid int, heartbeatAt time.Time) {
+		t.Helper()
+		_, err := store.db.ExecContext(ctx, `
+			INSERT INTO conversation_runs (conversation_id, owner, hostname, pid, started_at, heartbeat_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, conversationID, conversationID+"-owner", hostname, pid, heartbeatAt, heartbeatAt)
+		require.NoError(t, err)
+	}
+
+	insertRun("expired", os.Getpid(), now.Add(-2*staleRunAfter))
+	insertRun("dead-process", 1<<22+7, now)
+	insertRun("live", os.Getpid(), now)
+
+	for _, conversationID := range []string{"expired", "dead-process"} {
+		active, err := store.IsRunActive(ctx, conversationID)
+		require.NoError(t, err)
+		assert.False(t, active, conversationID)
+
+		var count int
+		require.NoError(t, store.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM conversation_runs WHERE conversation_id = ?`, conversationID))
+		assert.Zero(t, count, conversationID)
+	}
+
+	active, err := store.IsRunActive(ctx, "live")
+	require.NoError(t, err)
+	assert.True(t, active)
+}
+
+func TestFollowUpPrompt(t *testing.T) {
+	prompt, images := FollowUpPrompt([]Message{
+		{Content: "first", Images: []string{"/tmp/a.png"}},
+		{Content: "  "},
+		{Content: "second", Images: []string{"https://example.com/b.png"}},
+	})
+
+	assert.Equal(t, "first\n\nsecond", prompt)
+	assert.Equal(t, []string{"/tmp/a.png", "https://example.com/b.png"}, images)
+}
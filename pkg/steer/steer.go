@@ -84,6 +84,12 @@ func NewSteerStore(ctx context.Context, opts ...StoreOption) (*Store, error) {
 	return &Store{db: database}, nil
 }
 
+// NewSteerStoreWithDB creates a store on a shared database opened elsewhere,
+// such as the conversation store's. Migrations must already be applied.
+func NewSteerStoreWithDB(database *sqlx.DB) *Store {
+	return &Store{db: database}
+}
+
 // Close releases the store's database connection.
 func (s *Store) Close() error {
 	if s == nil || s.db == nil {
@@ -187,6 +193,59 @@ func (s *Store) Consume(ctx context.Context, conversationID string) ([]Message,
 	return messages, nil
 }
 
+// Requeue puts consumed messages back at the front of a conversation's queue,
+// ahead of any steering queued since they were consumed.
+func (s *Store) Requeue(ctx context.Context, conversationID string, messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to begin steering requeue transaction")
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryxContext(ctx, `
+		DELETE FROM steering_messages
+		WHERE conversation_id = ?
+		RETURNING id, content, images_json, created_at
+	`, conversationID)
+	if err != nil {
+		return errors.Wrap(err, "failed to read queued steering messages")
+	}
+	queued, scanErr := scanMessages(rows)
+	closeErr := rows.Close()
+	if scanErr != nil {
+		return scanErr
+	}
+	if closeErr != nil {
+		return errors.Wrap(closeErr, "failed to close queued steering rows")
+	}
+
+	for _, message := range append(append([]Message(nil), messages...), queued...) {
+		images := message.Images
+		if images == nil {
+			images = []string{}
+		}
+		imagesJSON, err := json.Marshal(images)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal steering images")
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO steering_messages (conversation_id, content, images_json, created_at)
+			VALUES (?, ?, ?, ?)
+		`, conversationID, message.Content, string(imagesJSON), message.Timestamp.UTC()); err != nil {
+			return errors.Wrap(err, "failed to requeue steering message")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "failed to commit requeued steering messages")
+	}
+	return nil
+}
+
 // HasPending reports whether a conversation has queued steering messages.
 func (s *Store) HasPending(ctx context.Context, conversationID string) (bool, error) {
 	var pending bool
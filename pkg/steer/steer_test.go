@@ -68,6 +68,28 @@ func TestEnqueuePeekAndConsume(t *testing.T) {
 	assert.False(t, hasPending)
 }
 
+func TestRequeuePutsMessagesAheadOfNewSteering(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStore(t)
+	conversationID := "test-requeue"
+
+	_, err := store.Enqueue(ctx, conversationID, "First message", []string{"data:image/png;base64,aGVsbG8="})
+	require.NoError(t, err)
+	consumed, err := store.Consume(ctx, conversationID)
+	require.NoError(t, err)
+	_, err = store.Enqueue(ctx, conversationID, "Later message", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Requeue(ctx, conversationID, consumed))
+
+	pending, err := store.Peek(ctx, conversationID)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, "First message", pending[0].Content)
+	assert.Equal(t, []string{"data:image/png;base64,aGVsbG8="}, pending[0].Images)
+	assert.Equal(t, "Later message", pending[1].Content)
+}
+
 func TestEnqueuePersistsNormalizedImages(t *testing.T) {
 	ctx := context.Background()
 	store, _ := newTestStore(t)
@@ -28,6 +28,22 @@ type PromptContext struct {
 	ActiveContextFile   string
 	Args                map[string]string
 	EnableFSSearchTools bool
+
+	// Language is the display name of the language the agent responds in;
+	// empty means English.
+	Language string
+
+	// Agents are the named subagents the main agent can hand work to.
+	Agents []AgentEntry
+	// AgentName and AgentInstructions describe the agent this run acts as.
+	AgentName         string
+	AgentInstructions string
+}
+
+// AgentEntry is a named subagent listed in the system prompt.
+type AgentEntry struct {
+	Name        string
+	Description string
 }
 
 type contextEntry struct {
@@ -1,6 +1,13 @@
 package sysprompt
 
-import llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jingkaihe/kodelet/pkg/i18n"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
 
 // RenderRuntimeSections renders system-info and loaded-contexts sections.
 func RenderRuntimeSections(ctx *PromptContext, renderer *Renderer) []string {
@@ -29,6 +36,20 @@ func BuildRuntimeContext(llmConfig llmtypes.Config, contexts map[string]string)
 	promptCtx.ActiveContextFile = resolveActiveContextFile(promptCtx.WorkingDirectory, contexts, patterns)
 	promptCtx.Args = llmConfig.SyspromptArgs
 	promptCtx.EnableFSSearchTools = llmConfig.EnableFSSearchTools
+	if !i18n.IsEnglish(llmConfig.Language) {
+		promptCtx.Language = i18n.DisplayName(llmConfig.Language)
+	}
+	if agent, ok := llmConfig.Agents[llmConfig.Agent]; ok && llmConfig.Agent != "" {
+		promptCtx.AgentName = llmConfig.Agent
+		promptCtx.AgentInstructions = strings.TrimSpace(agent.Instructions)
+	} else {
+		for _, name := range slices.Sorted(maps.Keys(llmConfig.Agents)) {
+			promptCtx.Agents = append(promptCtx.Agents, AgentEntry{
+				Name:        name,
+				Description: strings.TrimSpace(llmConfig.Agents[name].Description),
+			})
+		}
+	}
 
 	return promptCtx
 }
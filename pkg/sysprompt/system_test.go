@@ -55,6 +55,41 @@ func TestSystemPrompt_UsesConfiguredContextPatterns(t *testing.T) {
 	assert.NotContains(t, prompt, "If the current working directory contains a `AGENTS.md` file")
 }
 
+func TestSystemPrompt_Language(t *testing.T) {
+	prompt := SystemPrompt("claude-sonnet-4-6", llm.Config{Language: "ja-JP"}, map[string]string{})
+	assert.Contains(t, prompt, "# Response Language")
+	assert.Contains(t, prompt, "Always respond to the user in Japanese (日本語)")
+
+	codexPrompt := SystemPrompt("gpt-5.3-codex", llm.Config{Provider: "openai", Language: "ja"}, map[string]string{})
+	assert.Contains(t, codexPrompt, "Always respond to the user in Japanese (日本語)")
+
+	for _, language := range []string{"", "en", "en-US"} {
+		prompt := SystemPrompt("claude-sonnet-4-6", llm.Config{Language: language}, map[string]string{})
+		assert.NotContains(t, prompt, "# Response Language", language)
+	}
+}
+
+func TestSystemPrompt_Agents(t *testing.T) {
+	agents := map[string]llm.AgentConfig{
+		"tester":   {Description: "Runs the test suite"},
+		"reviewer": {Description: "Reviews diffs", Instructions: "Do not edit files."},
+	}
+
+	prompt := SystemPrompt("claude-sonnet-4-6", llm.Config{Agents: agents}, map[string]string{})
+	assert.Contains(t, prompt, "# Subagents")
+	assert.Contains(t, prompt, "kodelet run --agent <name>")
+	assert.Contains(t, prompt, "* `reviewer`: Reviews diffs\n* `tester`: Runs the test suite")
+	assert.NotContains(t, prompt, "# Agent Instructions")
+
+	prompt = SystemPrompt("claude-sonnet-4-6", llm.Config{Agent: "reviewer", Agents: agents}, map[string]string{})
+	assert.Contains(t, prompt, "You are running as the `reviewer` agent")
+	assert.Contains(t, prompt, "Do not edit files.")
+	assert.NotContains(t, prompt, "# Subagents", "agents do not dispatch to each other")
+
+	prompt = SystemPrompt("claude-sonnet-4-6", llm.Config{}, map[string]string{})
+	assert.NotContains(t, prompt, "# Subagents")
+}
+
 func TestSystemPrompt_CustomTemplate(t *testing.T) {
 	t.Run("uses custom template with built-in include", func(t *testing.T) {
 		tmpDir := t.TempDir()
@@ -0,0 +1,137 @@
+package telemetry
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsPath is the path Prometheus metrics are served on.
+const MetricsPath = "/metrics"
+
+// LLMUsage is the token usage and cost of one model exchange.
+type LLMUsage struct {
+	InputTokens      int
+	OutputTokens     int
+	CacheWriteTokens int
+	CacheReadTokens  int
+	CostUSD          float64
+}
+
+var (
+	registry = prometheus.NewRegistry()
+
+	llmRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kodelet_llm_requests_total",
+		Help: "Model exchanges completed, by provider and model.",
+	}, []string{"provider", "model"})
+	llmTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kodelet_llm_tokens_total",
+		Help: "Tokens used by model exchanges, by provider, model and type (input, output, cache_write or cache_read).",
+	}, []string{"provider", "model", "type"})
+	llmCost = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kodelet_llm_cost_usd_total",
+		Help: "Estimated cost of model exchanges in US dollars, by provider and model.",
+	}, []string{"provider", "model"})
+	llmRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kodelet_llm_retries_total",
+		Help: "Model requests retried after a failed attempt, by provider.",
+	}, []string{"provider"})
+	toolDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kodelet_tool_duration_seconds",
+		Help:    "Wall time of executed tool calls, by tool and status (success or error).",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"tool", "status"})
+	compactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kodelet_compactions_total",
+		Help: "Context reductions, by path (prune, summary or server) and status (success or failed).",
+	}, []string{"path", "status"})
+)
+
+func init() {
+	registry.MustRegister(
+		llmRequests, llmTokens, llmCost, llmRetries, toolDuration, compactions,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// RecordLLMUsage counts a completed model exchange and what it used.
+func RecordLLMUsage(provider, model string, usage LLMUsage) {
+	llmRequests.WithLabelValues(provider, model).Inc()
+	for tokenType, tokens := range map[string]int{
+		"input":       usage.InputTokens,
+		"output":      usage.OutputTokens,
+		"cache_write": usage.CacheWriteTokens,
+		"cache_read":  usage.CacheReadTokens,
+	} {
+		if tokens > 0 {
+			llmTokens.WithLabelValues(provider, model, tokenType).Add(float64(tokens))
+		}
+	}
+	if usage.CostUSD > 0 {
+		llmCost.WithLabelValues(provider, model).Add(usage.CostUSD)
+	}
+}
+
+// RecordLLMRetry counts a model request retried after a failed attempt.
+func RecordLLMRetry(provider string) {
+	llmRetries.WithLabelValues(provider).Inc()
+}
+
+// RecordToolCall records the wall time of an executed tool call.
+func RecordToolCall(tool string, duration time.Duration, failed bool) {
+	toolDuration.WithLabelValues(tool, statusLabel(failed, "error")).Observe(duration.Seconds())
+}
+
+// RecordCompaction counts a context reduction attempt.
+func RecordCompaction(path string, failed bool) {
+	compactions.WithLabelValues(path, statusLabel(failed, "failed")).Inc()
+}
+
+func statusLabel(failed bool, failure string) string {
+	if failed {
+		return failure
+	}
+	return "success"
+}
+
+// RetryCountMiddleware counts the retries an SDK makes on its own, which it
+// numbers in the X-Stainless-Retry-Count header of each attempt.
+func RetryCountMiddleware(provider string) func(*http.Request, func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	return func(req *http.Request, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+		if attempt, err := strconv.Atoi(req.Header.Get("X-Stainless-Retry-Count")); err == nil && attempt > 0 {
+			RecordLLMRetry(provider)
+		}
+		return next(req)
+	}
+}
+
+// MetricsHandler serves the metrics of the process in the Prometheus text
+// format.
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ServeMetrics serves MetricsHandler on MetricsPath at addr until the returned
+// shutdown function is called.
+func ServeMetrics(addr string) (shutdown func(context.Context) error, err error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "failed to listen for metrics on %s", addr)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, MetricsHandler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	return server.Shutdown, nil
+}
@@ -0,0 +1,63 @@
+package telemetry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordMetrics(t *testing.T) {
+	RecordLLMUsage("anthropic", "claude-test", LLMUsage{InputTokens: 100, OutputTokens: 20, CacheReadTokens: 50, CostUSD: 0.25})
+	RecordLLMUsage("anthropic", "claude-test", LLMUsage{InputTokens: 10, CostUSD: 0.05})
+	assert.Equal(t, 2.0, testutil.ToFloat64(llmRequests.WithLabelValues("anthropic", "claude-test")))
+	assert.Equal(t, 110.0, testutil.ToFloat64(llmTokens.WithLabelValues("anthropic", "claude-test", "input")))
+	assert.Equal(t, 50.0, testutil.ToFloat64(llmTokens.WithLabelValues("anthropic", "claude-test", "cache_read")))
+	assert.InDelta(t, 0.3, testutil.ToFloat64(llmCost.WithLabelValues("anthropic", "claude-test")), 1e-9)
+
+	RecordToolCall("grep_tool", 30*time.Millisecond, false)
+	RecordToolCall("grep_tool", time.Second, true)
+	assert.Equal(t, 2, testutil.CollectAndCount(toolDuration), "one series per tool and status")
+
+	RecordCompaction("summary", true)
+	assert.Equal(t, 1.0, testutil.ToFloat64(compactions.WithLabelValues("summary", "failed")))
+}
+
+func TestRetryCountMiddleware(t *testing.T) {
+	before := testutil.ToFloat64(llmRetries.WithLabelValues("test-provider"))
+	middleware := RetryCountMiddleware("test-provider")
+	next := func(*http.Request) (*http.Response, error) { return &http.Response{StatusCode: http.StatusOK}, nil }
+
+	for _, attempt := range []string{"", "0", "1", "2"} {
+		req := httptest.NewRequest("POST", "https://api.example.com/v1/messages", nil)
+		if attempt != "" {
+			req.Header.Set("X-Stainless-Retry-Count", attempt)
+		}
+		_, err := middleware(req, next)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, before+2, testutil.ToFloat64(llmRetries.WithLabelValues("test-provider")))
+}
+
+func TestServeMetrics(t *testing.T) {
+	RecordLLMRetry("served-provider")
+
+	shutdown, err := ServeMetrics("127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, shutdown(context.Background())) })
+
+	w := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(w, httptest.NewRequest("GET", MetricsPath, nil))
+	body, err := io.ReadAll(w.Result().Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `kodelet_llm_retries_total{provider="served-provider"} 1`)
+
+	_, err = ServeMetrics("127.0.0.1:-1")
+	assert.Error(t, err)
+}
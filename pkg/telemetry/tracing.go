@@ -1,4 +1,5 @@
-// Package telemetry provides OpenTelemetry tracing for Kodelet
+// Package telemetry provides OpenTelemetry tracing and Prometheus metrics
+// for Kodelet
 package telemetry
 
 import (
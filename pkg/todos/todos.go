@@ -0,0 +1,308 @@
+// Package todos contains the persisted todo list an agent keeps for complex
+// tasks and the turn tracking that enforces keeping it current.
+package todos
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MetadataKey stores the todo list in conversation metadata.
+	MetadataKey = "todos"
+	// ToolName is the tool the agent maintains its todo list with.
+	ToolName = "todo_write"
+	// ReminderStartMarker starts a hidden todo-reminder block.
+	ReminderStartMarker = "<todo_reminder>"
+	// ReminderEndMarker ends a hidden todo-reminder block.
+	ReminderEndMarker = "</todo_reminder>"
+)
+
+// Status is the state of a todo item.
+type Status string
+
+const (
+	StatusPending    Status = "pending"
+	StatusInProgress Status = "in_progress"
+	StatusCompleted  Status = "completed"
+	StatusCancelled  Status = "cancelled"
+)
+
+// Item is one entry of the todo list.
+type Item struct {
+	Content string `json:"content"`
+	Status  Status `json:"status"`
+}
+
+// List is persisted in conversation metadata.
+type List struct {
+	Version   int       `json:"version"`
+	Items     []Item    `json:"items"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Progress summarizes how far a todo list has come.
+type Progress struct {
+	Completed  int
+	InProgress int
+	Pending    int
+	Cancelled  int
+}
+
+// New validates items and returns them as a list updated at now.
+func New(items []Item, now time.Time) (List, error) {
+	normalized := make([]Item, 0, len(items))
+	inProgress := 0
+	for i, item := range items {
+		item.Content = strings.TrimSpace(item.Content)
+		item.Status = Status(strings.TrimSpace(string(item.Status)))
+		if item.Content == "" {
+			return List{}, errors.Errorf("todo %d has no content", i+1)
+		}
+		if !IsValidStatus(item.Status) {
+			return List{}, errors.Errorf(`todo %d has status %q; use "pending", "in_progress", "completed", or "cancelled"`, i+1, item.Status)
+		}
+		if item.Status == StatusInProgress {
+			inProgress++
+		}
+		normalized = append(normalized, item)
+	}
+	if inProgress > 1 {
+		return List{}, errors.New("only one todo can be in_progress at a time")
+	}
+
+	if now.IsZero() {
+		now = time.Now()
+	}
+	return List{Version: 1, Items: normalized, UpdatedAt: now.UTC()}, nil
+}
+
+// FromMetadata reads and validates the todo list from conversation metadata.
+func FromMetadata(metadata map[string]any) (List, bool) {
+	raw, ok := metadata[MetadataKey]
+	if !ok || raw == nil {
+		return List{}, false
+	}
+
+	var list List
+	switch value := raw.(type) {
+	case List:
+		list = value
+	case *List:
+		if value == nil {
+			return List{}, false
+		}
+		list = *value
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return List{}, false
+		}
+		if err := json.Unmarshal(data, &list); err != nil {
+			return List{}, false
+		}
+	}
+	if len(list.Items) == 0 {
+		return List{}, false
+	}
+	return list, true
+}
+
+// Progress counts the list's items by status.
+func (l List) Progress() Progress {
+	var progress Progress
+	for _, item := range l.Items {
+		switch item.Status {
+		case StatusCompleted:
+			progress.Completed++
+		case StatusInProgress:
+			progress.InProgress++
+		case StatusCancelled:
+			progress.Cancelled++
+		default:
+			progress.Pending++
+		}
+	}
+	return progress
+}
+
+// Done reports whether no item is left to work on.
+func (l List) Done() bool {
+	progress := l.Progress()
+	return progress.InProgress == 0 && progress.Pending == 0
+}
+
+// Total is the number of items that count towards completion; cancelled
+// items do not.
+func (p Progress) Total() int {
+	return p.Completed + p.InProgress + p.Pending
+}
+
+// Percent is the share of counted items that are completed.
+func (p Progress) Percent() int {
+	if p.Total() == 0 {
+		return 100
+	}
+	return p.Completed * 100 / p.Total()
+}
+
+// String renders the progress as "3/5 completed (60%)".
+func (p Progress) String() string {
+	return fmt.Sprintf("%d/%d completed (%d%%)", p.Completed, p.Total(), p.Percent())
+}
+
+// Render renders the list as a markdown checklist.
+func Render(list List) string {
+	var b strings.Builder
+	for _, item := range list.Items {
+		marker := "[ ]"
+		switch item.Status {
+		case StatusCompleted:
+			marker = "[x]"
+		case StatusInProgress:
+			marker = "[~]"
+		case StatusCancelled:
+			marker = "[-]"
+		}
+		fmt.Fprintf(&b, "- %s %s\n", marker, item.Content)
+	}
+	return b.String()
+}
+
+// IsValidStatus reports whether status is a known todo status.
+func IsValidStatus(status Status) bool {
+	switch status {
+	case StatusPending, StatusInProgress, StatusCompleted, StatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsReminderText reports whether text is a todo-reminder block.
+func IsReminderText(text string) bool {
+	text = strings.TrimSpace(text)
+	return strings.HasPrefix(text, ReminderStartMarker) && strings.HasSuffix(text, ReminderEndMarker)
+}
+
+// RenderReminder renders the hidden user-context message asking the agent to
+// create or update its todo list.
+func RenderReminder(list List, hasList bool, staleTurns int, strict bool) string {
+	var b strings.Builder
+	b.WriteString(ReminderStartMarker + "\n")
+	if hasList {
+		fmt.Fprintf(&b, "Your todo list has not been updated for %d turns. Call %s now with the full list, marking finished items completed and the item you are working on in_progress.\n\nCurrent todo list (%s):\n%s", staleTurns, ToolName, list.Progress(), Render(list))
+	} else {
+		fmt.Fprintf(&b, "This task has taken %d turns without a todo list. Call %s now with every remaining subtask so none are forgotten, and keep it current as you work.\n", staleTurns, ToolName)
+	}
+	if strict {
+		fmt.Fprintf(&b, "\nOther tool calls are refused until %s is called.\n", ToolName)
+	}
+	b.WriteString(ReminderEndMarker)
+	return b.String()
+}
+
+// Enforcement selects how strictly the todo list is enforced.
+type Enforcement string
+
+const (
+	// EnforcementOff disables todo enforcement.
+	EnforcementOff Enforcement = "off"
+	// EnforcementRemind injects reminders when the list is overdue.
+	EnforcementRemind Enforcement = "remind"
+	// EnforcementStrict also refuses other tool calls once a reminder has
+	// been ignored.
+	EnforcementStrict Enforcement = "strict"
+)
+
+// Policy configures when a todo list is required.
+type Policy struct {
+	Enforcement Enforcement
+	// ComplexityThreshold is the number of tool-using turns after which a
+	// task is complex enough to require a todo list.
+	ComplexityThreshold int
+	// MaxStaleTurns is the number of tool-using turns the list may go
+	// without an update.
+	MaxStaleTurns int
+}
+
+// Enabled reports whether the policy enforces anything.
+func (p Policy) Enabled() bool {
+	return p.Enforcement == EnforcementRemind || p.Enforcement == EnforcementStrict
+}
+
+// Tracker follows the tool-using turns of one run and decides when the todo
+// list must be created or refreshed.
+type Tracker struct {
+	mu         sync.Mutex
+	toolTurns  int
+	staleTurns int
+	remindedAt int
+	overdue    bool
+	lastUpdate time.Time
+}
+
+// Reset starts tracking a new run from the conversation's current list.
+func (t *Tracker) Reset(metadata map[string]any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	list, _ := FromMetadata(metadata)
+	t.toolTurns, t.staleTurns, t.remindedAt = 0, 0, 0
+	t.overdue = false
+	t.lastUpdate = list.UpdatedAt
+}
+
+// RecordTurn records a completed tool-using turn and returns the reminder to
+// inject when the list is overdue. Reminders repeat every MaxStaleTurns turns
+// until the list is updated.
+func (t *Tracker) RecordTurn(policy Policy, metadata map[string]any) (string, bool) {
+	if !policy.Enabled() {
+		return "", false
+	}
+	list, hasList := FromMetadata(metadata)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.toolTurns++
+	if hasList && !list.UpdatedAt.Equal(t.lastUpdate) {
+		t.lastUpdate = list.UpdatedAt
+		t.staleTurns = 0
+		t.remindedAt = 0
+		t.overdue = false
+		return "", false
+	}
+	t.staleTurns++
+
+	t.overdue = t.toolTurns >= policy.ComplexityThreshold &&
+		t.staleTurns >= policy.MaxStaleTurns &&
+		!(hasList && list.Done())
+	if !t.overdue || (t.remindedAt > 0 && t.staleTurns-t.remindedAt < policy.MaxStaleTurns) {
+		return "", false
+	}
+	t.remindedAt = t.staleTurns
+	return RenderReminder(list, hasList, t.staleTurns, policy.Enforcement == EnforcementStrict), true
+}
+
+// Refusal returns why a tool call is refused under strict enforcement: the
+// list is overdue, a reminder was given, and the list has not been updated
+// since.
+func (t *Tracker) Refusal(policy Policy, toolName string, metadata map[string]any) (string, bool) {
+	if policy.Enforcement != EnforcementStrict || toolName == ToolName {
+		return "", false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.overdue || t.remindedAt == 0 {
+		return "", false
+	}
+	if list, ok := FromMetadata(metadata); ok && !list.UpdatedAt.Equal(t.lastUpdate) {
+		return "", false
+	}
+	return fmt.Sprintf("todo list is overdue; call %s to update it before using %s", ToolName, toolName), true
+}
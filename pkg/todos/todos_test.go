@@ -0,0 +1,132 @@
+package todos
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewValidatesItems(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	list, err := New([]Item{
+		{Content: " write parser ", Status: StatusCompleted},
+		{Content: "wire command", Status: StatusInProgress},
+		{Content: "docs", Status: StatusPending},
+	}, now)
+	require.NoError(t, err)
+	assert.Equal(t, "write parser", list.Items[0].Content)
+	assert.Equal(t, now, list.UpdatedAt)
+
+	_, err = New([]Item{{Content: "", Status: StatusPending}}, now)
+	assert.ErrorContains(t, err, "todo 1 has no content")
+
+	_, err = New([]Item{{Content: "a", Status: "done"}}, now)
+	assert.ErrorContains(t, err, `todo 1 has status "done"`)
+
+	_, err = New([]Item{{Content: "a", Status: StatusInProgress}, {Content: "b", Status: StatusInProgress}}, now)
+	assert.ErrorContains(t, err, "only one todo can be in_progress")
+}
+
+func TestFromMetadataAndProgress(t *testing.T) {
+	list, err := New([]Item{
+		{Content: "a", Status: StatusCompleted},
+		{Content: "b", Status: StatusCompleted},
+		{Content: "c", Status: StatusInProgress},
+		{Content: "d", Status: StatusCancelled},
+	}, time.Now())
+	require.NoError(t, err)
+
+	data, err := json.Marshal(map[string]any{MetadataKey: list})
+	require.NoError(t, err)
+	var metadata map[string]any
+	require.NoError(t, json.Unmarshal(data, &metadata))
+
+	loaded, ok := FromMetadata(metadata)
+	require.True(t, ok)
+	assert.Len(t, loaded.Items, 4)
+
+	progress := loaded.Progress()
+	assert.Equal(t, 3, progress.Total(), "cancelled items do not count")
+	assert.Equal(t, "2/3 completed (66%)", progress.String())
+	assert.False(t, loaded.Done())
+	assert.Equal(t, "- [x] a\n- [x] b\n- [~] c\n- [-] d\n", Render(loaded))
+
+	_, ok = FromMetadata(map[string]any{MetadataKey: List{}})
+	assert.False(t, ok, "an empty list is no list")
+}
+
+func TestTrackerRemindsWhenListIsOverdue(t *testing.T) {
+	policy := Policy{Enforcement: EnforcementRemind, ComplexityThreshold: 3, MaxStaleTurns: 2}
+	tracker := &Tracker{}
+	metadata := map[string]any{}
+	tracker.Reset(metadata)
+
+	for turn := 1; turn < 3; turn++ {
+		_, ok := tracker.RecordTurn(policy, metadata)
+		assert.False(t, ok, "turn %d is below the complexity threshold", turn)
+	}
+	reminder, ok := tracker.RecordTurn(policy, metadata)
+	require.True(t, ok)
+	assert.True(t, IsReminderText(reminder))
+	assert.Contains(t, reminder, "without a todo list")
+
+	_, ok = tracker.RecordTurn(policy, metadata)
+	assert.False(t, ok, "reminders are spaced by MaxStaleTurns")
+	_, ok = tracker.RecordTurn(policy, metadata)
+	assert.True(t, ok)
+
+	list, err := New([]Item{{Content: "a", Status: StatusInProgress}, {Content: "b", Status: StatusPending}}, time.Now())
+	require.NoError(t, err)
+	metadata[MetadataKey] = list
+	_, ok = tracker.RecordTurn(policy, metadata)
+	assert.False(t, ok, "updating the list resets the stale count")
+	_, ok = tracker.RecordTurn(policy, metadata)
+	assert.False(t, ok)
+	reminder, ok = tracker.RecordTurn(policy, metadata)
+	require.True(t, ok)
+	assert.Contains(t, reminder, "has not been updated for 2 turns")
+	assert.Contains(t, reminder, "0/2 completed (0%)")
+
+	done, err := New([]Item{{Content: "a", Status: StatusCompleted}, {Content: "b", Status: StatusCancelled}}, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	metadata[MetadataKey] = done
+	for range 4 {
+		_, ok = tracker.RecordTurn(policy, metadata)
+		assert.False(t, ok, "a finished list needs no reminders")
+	}
+
+	_, ok = (&Tracker{}).RecordTurn(Policy{Enforcement: EnforcementOff, ComplexityThreshold: 1, MaxStaleTurns: 1}, map[string]any{})
+	assert.False(t, ok)
+}
+
+func TestTrackerRefusesToolsUnderStrictEnforcement(t *testing.T) {
+	policy := Policy{Enforcement: EnforcementStrict, ComplexityThreshold: 1, MaxStaleTurns: 1}
+	tracker := &Tracker{}
+	metadata := map[string]any{}
+	tracker.Reset(metadata)
+
+	_, refused := tracker.Refusal(policy, "bash", metadata)
+	assert.False(t, refused, "nothing is refused before a reminder")
+
+	reminder, ok := tracker.RecordTurn(policy, metadata)
+	require.True(t, ok)
+	assert.Contains(t, reminder, "Other tool calls are refused")
+
+	reason, refused := tracker.Refusal(policy, "bash", metadata)
+	assert.True(t, refused)
+	assert.Contains(t, reason, "call todo_write")
+	_, refused = tracker.Refusal(policy, ToolName, metadata)
+	assert.False(t, refused, "the todo tool itself is always allowed")
+
+	list, err := New([]Item{{Content: "a", Status: StatusInProgress}}, time.Now())
+	require.NoError(t, err)
+	metadata[MetadataKey] = list
+	_, refused = tracker.Refusal(policy, "bash", metadata)
+	assert.False(t, refused, "an update within the turn lifts the refusal")
+
+	_, refused = tracker.Refusal(Policy{Enforcement: EnforcementRemind, ComplexityThreshold: 1, MaxStaleTurns: 1}, "bash", map[string]any{})
+	assert.False(t, refused)
+}
@@ -0,0 +1,354 @@
+// Package toolapproval decides which tool calls need the user's confirmation
+// before they run in approval mode, remembers the web domains the user has
+// already approved during a run, and queues the calls deferred for review at
+// the end of a turn.
+package toolapproval
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+)
+
+// Qualifiers narrow a risk to some of the calls of a tool. A risk without a
+// qualifier matches every call of the tool.
+const (
+	// QualifierWrite matches bash commands that are not known to be read-only.
+	QualifierWrite = "write"
+	// QualifierNewDomain matches calls to a URL whose host the user has not
+	// approved yet in this run.
+	QualifierNewDomain = "new_domain"
+)
+
+// Modes decide when the user confirms the calls on the risk list.
+const (
+	// ModeAsk confirms each call before it runs.
+	ModeAsk = "ask"
+	// ModeDefer queues each call, answers the model with a placeholder, and
+	// has the user review the queued calls as a batch at the end of the turn.
+	// Calls matching new_domain risks are still confirmed one at a time, as
+	// the model needs what they fetch.
+	ModeDefer = "defer"
+)
+
+// DefaultRisks is the risk list used when approval mode is enabled without
+// one.
+var DefaultRisks = []string{
+	"bash:write",
+	"file_write",
+	"file_edit",
+	"apply_patch",
+	"web_fetch:new_domain",
+	"web_crawl:new_domain",
+}
+
+// readOnlyCommands are commands that only read, provided their output is not
+// redirected to a file. Commands that run other commands, such as env or
+// xargs, are left out.
+var readOnlyCommands = []string{
+	"basename", "cat", "cut", "date", "df", "diff", "dirname", "du", "echo",
+	"fd", "file", "grep", "head", "ls", "printf", "pwd", "readlink",
+	"realpath", "rg", "stat", "tail", "test", "tr", "tree", "true", "type",
+	"uname", "uniq", "wc", "which", "whoami",
+}
+
+// writingFlags are the flags that make a read-only command write files or
+// run other commands.
+var writingFlags = map[string][]string{
+	"fd":   {"-x", "--exec", "-X", "--exec-batch"},
+	"git":  {"--output", "-O", "--open-files-in-pager"},
+	"rg":   {"--pre"},
+	"tree": {"-o"},
+}
+
+// readOnlyGitCommands are git subcommands that do not change the repository.
+var readOnlyGitCommands = []string{
+	"blame", "diff", "grep", "log", "ls-files", "rev-parse", "show", "status",
+}
+
+// Request describes a tool call that needs the user's confirmation.
+type Request struct {
+	// Tool is the name of the tool.
+	Tool string
+	// Summary is a one-line description of the call for the prompt.
+	Summary string
+	// Domain is the host the call reaches for new_domain risks.
+	Domain string
+}
+
+// DeferredCall is a tool call queued for review at the end of the turn.
+type DeferredCall struct {
+	Request
+	// Input is the tool input the call runs with once approved.
+	Input string
+	// ToolCallID identifies the call in the conversation.
+	ToolCallID string
+}
+
+type risk struct {
+	tool      string
+	qualifier string
+}
+
+// Gate matches tool calls against the risk list and records the domains the
+// user approved. It is safe for concurrent use.
+type Gate struct {
+	risks []risk
+	mode  string
+
+	mu       sync.Mutex
+	domains  map[string]bool
+	deferred []DeferredCall
+}
+
+// NewGate returns a gate for config, or nil when approval mode is disabled.
+func NewGate(config *llmtypes.ToolApprovalConfig) *Gate {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	entries := config.Risks
+	if len(entries) == 0 {
+		entries = DefaultRisks
+	}
+	g := &Gate{mode: config.Mode, domains: make(map[string]bool)}
+	if g.mode == "" {
+		g.mode = ModeAsk
+	}
+	for _, entry := range entries {
+		tool, qualifier, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		g.risks = append(g.risks, risk{tool: tool, qualifier: qualifier})
+	}
+	return g
+}
+
+// ValidateRisk reports whether entry is a valid risk list entry.
+func ValidateRisk(entry string) error {
+	tool, qualifier, _ := strings.Cut(strings.TrimSpace(entry), ":")
+	if tool == "" {
+		return errors.Errorf("risk %q must name a tool", entry)
+	}
+	switch qualifier {
+	case "", QualifierNewDomain:
+	case QualifierWrite:
+		if tool != "bash" {
+			return errors.Errorf("risk %q: the write qualifier only applies to bash", entry)
+		}
+	default:
+		return errors.Errorf("risk %q: unknown qualifier %q, expected write or new_domain", entry, qualifier)
+	}
+	return nil
+}
+
+// ValidateMode reports whether mode is a valid approval mode. The empty
+// mode means ModeAsk.
+func ValidateMode(mode string) error {
+	switch mode {
+	case "", ModeAsk, ModeDefer:
+		return nil
+	default:
+		return errors.Errorf("unknown mode %q, expected %s or %s", mode, ModeAsk, ModeDefer)
+	}
+}
+
+// Check returns the request to show the user when the call of toolName with
+// input matches the risk list.
+func (g *Gate) Check(toolName, input string) (Request, bool) {
+	if g == nil {
+		return Request{}, false
+	}
+	for _, r := range g.risks {
+		if r.tool != toolName {
+			continue
+		}
+		switch r.qualifier {
+		case "":
+			return Request{Tool: toolName, Summary: summarize(input)}, true
+		case QualifierWrite:
+			command := stringField(input, "command")
+			if !IsReadOnlyCommand(command) {
+				return Request{Tool: toolName, Summary: command}, true
+			}
+		case QualifierNewDomain:
+			rawURL := stringField(input, "url")
+			domain := hostOf(rawURL)
+			if !g.approvedDomain(domain) {
+				return Request{Tool: toolName, Summary: rawURL, Domain: domain}, true
+			}
+		}
+	}
+	return Request{}, false
+}
+
+// Approve records that the user approved request, so later calls to the same
+// domain do not ask again.
+func (g *Gate) Approve(request Request) {
+	if g == nil || request.Domain == "" {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.domains[request.Domain] = true
+}
+
+// Defers reports whether the call behind request is queued for review at the
+// end of the turn rather than confirmed before it runs.
+func (g *Gate) Defers(request Request) bool {
+	return g != nil && g.mode == ModeDefer && request.Domain == ""
+}
+
+// Enqueue queues call for review and returns how many calls are queued.
+func (g *Gate) Enqueue(call DeferredCall) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.deferred = append(g.deferred, call)
+	return len(g.deferred)
+}
+
+// TakeDeferred returns the queued calls in the order they were queued and
+// empties the queue.
+func (g *Gate) TakeDeferred() []DeferredCall {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	calls := g.deferred
+	g.deferred = nil
+	return calls
+}
+
+func (g *Gate) approvedDomain(domain string) bool {
+	if domain == "" {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.domains[domain]
+}
+
+// IsReadOnlyCommand reports whether every command of a bash command line is
+// known to only read. Redirections to files, command and process
+// substitution, and unknown commands count as writes.
+func IsReadOnlyCommand(command string) bool {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return false
+	}
+	for _, substitution := range []string{"$(", "`", "<(", ">("} {
+		if strings.Contains(command, substitution) {
+			return false
+		}
+	}
+	for _, segment := range splitCommands(command) {
+		if !readOnlySegment(segment) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitCommands splits a command line into its simple commands at ;, |, &
+// and newlines, so &&, || and background jobs separate commands too.
+func splitCommands(command string) []string {
+	var segments []string
+	start := 0
+	for i := 0; i < len(command); i++ {
+		switch command[i] {
+		case ';', '|', '\n':
+		case '&':
+			// >&, <& and &> are redirections rather than separators.
+			if i > 0 && (command[i-1] == '>' || command[i-1] == '<') || i+1 < len(command) && command[i+1] == '>' {
+				continue
+			}
+		default:
+			continue
+		}
+		segments = append(segments, command[start:i])
+		start = i + 1
+	}
+	return append(segments, command[start:])
+}
+
+func readOnlySegment(segment string) bool {
+	fields := strings.Fields(segment)
+	for len(fields) > 0 && strings.Contains(fields[0], "=") {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return true
+	}
+	for i, field := range fields {
+		// 2>&1 and >&2 only duplicate file descriptors.
+		if !strings.Contains(field, ">") || strings.Contains(field, ">&") {
+			continue
+		}
+		target := strings.TrimLeft(field, "0123456789&>")
+		if target == "" && i+1 < len(fields) {
+			target = fields[i+1]
+		}
+		if target != "/dev/null" {
+			return false
+		}
+	}
+
+	name := fields[0]
+	if flags := writingFlags[name]; slices.ContainsFunc(fields[1:], func(arg string) bool {
+		flag, _, _ := strings.Cut(arg, "=")
+		return slices.Contains(flags, flag)
+	}) {
+		return false
+	}
+
+	switch {
+	case name == "git":
+		return len(fields) > 1 && slices.Contains(readOnlyGitCommands, fields[1])
+	case name == "find":
+		return !slices.ContainsFunc(fields[1:], func(arg string) bool {
+			return arg == "-delete" || strings.HasPrefix(arg, "-exec") || strings.HasPrefix(arg, "-ok") || strings.HasPrefix(arg, "-fprint")
+		})
+	case name == "sed":
+		return !slices.ContainsFunc(fields[1:], func(arg string) bool {
+			return strings.HasPrefix(arg, "-i") || arg == "--in-place"
+		})
+	default:
+		return slices.Contains(readOnlyCommands, name)
+	}
+}
+
+func stringField(input, field string) string {
+	var params map[string]any
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return ""
+	}
+	value, _ := params[field].(string)
+	return value
+}
+
+func hostOf(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// summarize describes a call of a tool without a qualifier by its most
+// telling parameter.
+func summarize(input string) string {
+	for _, field := range []string{"file_path", "path", "command", "url"} {
+		if value := stringField(input, field); value != "" {
+			return value
+		}
+	}
+	const maxSummary = 200
+	if len(input) > maxSummary {
+		return fmt.Sprintf("%s...", input[:maxSummary])
+	}
+	return input
+}
@@ -0,0 +1,119 @@
+package toolapproval
+
+import (
+	"testing"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsReadOnlyCommand(t *testing.T) {
+	for command, want := range map[string]bool{
+		"ls -la":                             true,
+		"git status && git diff HEAD~1":      true,
+		"grep -rn foo . | head -20":          true,
+		"cat go.mod 2>&1 | wc -l":            true,
+		"find . -name '*.go' 2>/dev/null":    true,
+		"GOFLAGS=-mod=mod git log --oneline": true,
+		"sed -n 1,10p main.go":               true,
+		"rm -rf build":                       false,
+		"echo hello > out.txt":               false,
+		"echo hello >> out.txt":              false,
+		"cat a 1> b":                         false,
+		"git commit -m 'x'":                  false,
+		"git":                                false,
+		"ls; go build ./...":                 false,
+		"find . -name '*.tmp' -delete":       false,
+		"find . -exec rm {} +":               false,
+		"sed -i 's/a/b/' main.go":            false,
+		"echo $(rm -rf /tmp/x)":              false,
+		"":                                   false,
+		"make test":                          false,
+		"ls & rm -rf build":                  false,
+		"ls\nrm -rf build":                   false,
+		"cat go.mod &> /dev/null":            true,
+		"diff <(ls a) <(ls b)":               false,
+		"tee >(cat) < go.mod":                false,
+		"env rm -rf build":                   false,
+		"git diff --output=patch.diff":       false,
+		"git log --output patch.diff":        false,
+		"rg --pre ./run.sh foo":              false,
+		"fd -e go -x rm":                     false,
+	} {
+		assert.Equal(t, want, IsReadOnlyCommand(command), command)
+	}
+}
+
+func TestGateChecksRisks(t *testing.T) {
+	assert.Nil(t, NewGate(nil))
+	assert.Nil(t, NewGate(&llmtypes.ToolApprovalConfig{Risks: []string{"bash"}}), "approval mode is off")
+
+	gate := NewGate(&llmtypes.ToolApprovalConfig{Enabled: true})
+	require.NotNil(t, gate)
+
+	request, needed := gate.Check("file_edit", `{"file_path": "main.go", "old_text": "a", "new_text": "b"}`)
+	assert.True(t, needed)
+	assert.Equal(t, Request{Tool: "file_edit", Summary: "main.go"}, request)
+
+	_, needed = gate.Check("bash", `{"command": "git status"}`)
+	assert.False(t, needed, "read-only commands run without approval")
+	request, needed = gate.Check("bash", `{"command": "rm -rf build"}`)
+	assert.True(t, needed)
+	assert.Equal(t, "rm -rf build", request.Summary)
+
+	_, needed = gate.Check("file_read", `{"file_path": "main.go"}`)
+	assert.False(t, needed)
+
+	request, needed = gate.Check("web_fetch", `{"url": "https://Docs.Example.com/guide"}`)
+	require.True(t, needed)
+	assert.Equal(t, "docs.example.com", request.Domain)
+	gate.Approve(request)
+	_, needed = gate.Check("web_fetch", `{"url": "https://docs.example.com/api"}`)
+	assert.False(t, needed, "approved domains do not ask again")
+	_, needed = gate.Check("web_crawl", `{"url": "https://docs.example.com/"}`)
+	assert.False(t, needed)
+	_, needed = gate.Check("web_fetch", `{"url": "https://other.example.com/"}`)
+	assert.True(t, needed)
+}
+
+func TestValidateRisk(t *testing.T) {
+	for _, risk := range DefaultRisks {
+		assert.NoError(t, ValidateRisk(risk), risk)
+	}
+	assert.NoError(t, ValidateRisk("my_extension_tool"))
+	assert.Error(t, ValidateRisk(""))
+	assert.Error(t, ValidateRisk("file_edit:write"))
+	assert.Error(t, ValidateRisk("bash:delete"))
+}
+
+func TestGateDefersCalls(t *testing.T) {
+	ask := NewGate(&llmtypes.ToolApprovalConfig{Enabled: true})
+	request, _ := ask.Check("file_edit", `{"file_path": "main.go"}`)
+	assert.False(t, ask.Defers(request), "ask mode confirms each call")
+
+	gate := NewGate(&llmtypes.ToolApprovalConfig{Enabled: true, Mode: ModeDefer})
+	edit, _ := gate.Check("file_edit", `{"file_path": "main.go"}`)
+	assert.True(t, gate.Defers(edit))
+	fetch, _ := gate.Check("web_fetch", `{"url": "https://example.com/"}`)
+	assert.False(t, gate.Defers(fetch), "new domains are still confirmed one at a time")
+
+	assert.Equal(t, 1, gate.Enqueue(DeferredCall{Request: edit, ToolCallID: "call-1"}))
+	assert.Equal(t, 2, gate.Enqueue(DeferredCall{Request: Request{Tool: "bash", Summary: "make"}, ToolCallID: "call-2"}))
+	calls := gate.TakeDeferred()
+	require.Len(t, calls, 2)
+	assert.Equal(t, "call-1", calls[0].ToolCallID)
+	assert.Equal(t, "make", calls[1].Summary)
+	assert.Empty(t, gate.TakeDeferred())
+
+	var off *Gate
+	assert.False(t, off.Defers(edit))
+	assert.Empty(t, off.TakeDeferred())
+}
+
+func TestValidateMode(t *testing.T) {
+	assert.NoError(t, ValidateMode(""))
+	assert.NoError(t, ValidateMode(ModeAsk))
+	assert.NoError(t, ValidateMode(ModeDefer))
+	assert.Error(t, ValidateMode("batch"))
+}
@@ -0,0 +1,111 @@
+package toolcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// revisionTimeout bounds the git commands WorkspaceRevision runs.
+const revisionTimeout = 5 * time.Second
+
+// WorkspaceRevision returns the root of the git working tree holding dir and
+// a revision identifying its contents: its HEAD commit plus the contents of
+// the files changed since, tracked or not. Ignored files are not part of the
+// revision. It returns empty strings outside a git repository, where results
+// that depend on the workspace cannot be cached safely.
+func WorkspaceRevision(ctx context.Context, dir string) (root, revision string) {
+	ctx, cancel := context.WithTimeout(ctx, revisionTimeout)
+	defer cancel()
+
+	root, err := gitOutput(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", ""
+	}
+	root = strings.TrimSpace(root)
+	head, err := gitOutput(ctx, root, "rev-parse", "HEAD")
+	if err != nil {
+		return "", ""
+	}
+	head = strings.TrimSpace(head)
+
+	tracked, err := gitOutput(ctx, root, "diff", "--name-only", "--no-renames", "HEAD", "--")
+	if err != nil {
+		return "", ""
+	}
+	untracked, err := gitOutput(ctx, root, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return "", ""
+	}
+	var changed []string
+	for _, line := range strings.Split(tracked+"\n"+untracked, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			changed = append(changed, line)
+		}
+	}
+	if len(changed) == 0 {
+		return root, head
+	}
+	slices.Sort(changed)
+
+	hash := sha256.New()
+	for _, path := range slices.Compact(changed) {
+		hash.Write([]byte(path))
+		hash.Write([]byte{0})
+		hashFileInto(hash, filepath.Join(root, path))
+		hash.Write([]byte{0})
+	}
+	return root, head + "+" + hex.EncodeToString(hash.Sum(nil))
+}
+
+// CoveredByRevision reports whether the contents of path are part of the
+// revision of the working tree at root: path is inside root and not ignored
+// by git. Results of calls reading other paths cannot be cached safely.
+func CoveredByRevision(ctx context.Context, root, path string) bool {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return false
+	}
+	if rel == "." {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, revisionTimeout)
+	defer cancel()
+	// check-ignore exits 1 when the path is not ignored.
+	cmd := exec.CommandContext(ctx, "git", "check-ignore", "-q", "--", rel)
+	cmd.Dir = root
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == 1
+}
+
+// hashFileInto writes the contents of path to w, or nothing when the file
+// has been deleted.
+func hashFileInto(w io.Writer, path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	_, _ = io.Copy(w, file)
+}
+
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	return string(output), err
+}
@@ -0,0 +1,255 @@
+// Package toolcache stores the results of expensive read-only tool calls, such
+// as grep over a monorepo or web_fetch of the same page, so that repeated
+// calls, for example by subagents, are answered without running the tool
+// again. Entries are keyed by tool name, canonicalized input and the revision
+// of the workspace they were produced in.
+package toolcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/pkg/errors"
+)
+
+// entryExt is the extension of the files entries are stored in.
+const entryExt = ".json"
+
+// Entry is a cached tool result.
+type Entry struct {
+	ToolName        string                         `json:"tool_name"`
+	AssistantFacing string                         `json:"assistant_facing"`
+	Error           string                         `json:"error,omitempty"`
+	Structured      tooltypes.StructuredToolResult `json:"structured"`
+	CreatedAt       time.Time                      `json:"created_at"`
+	ExpiresAt       time.Time                      `json:"expires_at"`
+}
+
+// NewEntry captures result for storage until now plus ttl.
+func NewEntry(toolName string, result tooltypes.ToolResult, now time.Time, ttl time.Duration) Entry {
+	return Entry{
+		ToolName:        toolName,
+		AssistantFacing: result.AssistantFacing(),
+		Error:           result.GetError(),
+		Structured:      result.StructuredData(),
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(ttl),
+	}
+}
+
+// Result returns the entry as a tool result.
+func (e Entry) Result() tooltypes.ToolResult {
+	return cachedResult{entry: e}
+}
+
+// cachedResult replays a cached entry as a tool result.
+type cachedResult struct {
+	entry Entry
+}
+
+// AssistantFacing returns the text the model saw when the entry was stored.
+func (r cachedResult) AssistantFacing() string { return r.entry.AssistantFacing }
+
+// IsError reports whether the cached call failed.
+func (r cachedResult) IsError() bool { return r.entry.Error != "" }
+
+// GetError returns the error of the cached call.
+func (r cachedResult) GetError() string { return r.entry.Error }
+
+// GetResult returns the text the model saw when the entry was stored.
+func (r cachedResult) GetResult() string { return r.entry.AssistantFacing }
+
+// StructuredData returns the structured result of the cached call.
+func (r cachedResult) StructuredData() tooltypes.StructuredToolResult { return r.entry.Structured }
+
+// Key returns the cache key of a call of toolName with parameters, a JSON
+// object, in scope. Parameters are canonicalized, so calls that differ only in
+// key order or whitespace share a key. scope identifies what the result
+// depends on besides the input, such as the working directory and its
+// revision.
+func Key(toolName, parameters, scope string) (string, error) {
+	canonical := []byte(parameters)
+	var input any
+	decoder := json.NewDecoder(strings.NewReader(parameters))
+	decoder.UseNumber()
+	if err := decoder.Decode(&input); err == nil {
+		if canonical, err = json.Marshal(input); err != nil {
+			return "", errors.Wrap(err, "failed to canonicalize tool input")
+		}
+	}
+
+	hash := sha256.New()
+	for _, part := range [][]byte{[]byte(toolName), canonical, []byte(scope)} {
+		hash.Write(part)
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// DefaultDir returns the directory entries are stored in,
+// ~/.kodelet/cache/tools or under KODELET_BASE_PATH.
+func DefaultDir() (string, error) {
+	if basePath := strings.TrimSpace(os.Getenv("KODELET_BASE_PATH")); basePath != "" {
+		return filepath.Join(basePath, "cache", "tools"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get home directory")
+	}
+	return filepath.Join(homeDir, ".kodelet", "cache", "tools"), nil
+}
+
+// Store keeps entries as one file each in a directory, evicting the oldest
+// ones once they take more than maxBytes.
+type Store struct {
+	dir      string
+	maxBytes int64
+	mu       sync.Mutex
+}
+
+// NewStore returns a store in dir. maxBytes of 0 or less leaves its size
+// unbounded.
+func NewStore(dir string, maxBytes int64) *Store {
+	return &Store{dir: dir, maxBytes: maxBytes}
+}
+
+// Get returns the entry stored under key unless it has expired at now.
+// Expired and unreadable entries are removed.
+func (s *Store) Get(key string, now time.Time) (Entry, bool) {
+	path := s.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Entry{}, false
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil || !entry.ExpiresAt.After(now) {
+		_ = os.Remove(path)
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+// Put stores entry under key and evicts entries to stay within the size
+// limit. Entries larger than the limit on their own are not stored.
+func (s *Store) Put(key string, entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode tool cache entry")
+	}
+	if s.maxBytes > 0 && int64(len(data)) > s.maxBytes {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return errors.Wrap(err, "failed to create tool cache directory")
+	}
+	// Write then rename, so concurrent readers never see a partial entry.
+	tmp, err := os.CreateTemp(s.dir, key+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to write tool cache entry")
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.Wrap(err, "failed to write tool cache entry")
+	}
+	if err := os.Rename(tmp.Name(), s.path(key)); err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.Wrap(err, "failed to write tool cache entry")
+	}
+	return s.evict()
+}
+
+// evict removes the least recently written entries until the rest fit in
+// maxBytes. Expired entries are removed when they are next looked up.
+func (s *Store) evict() error {
+	if s.maxBytes <= 0 {
+		return nil
+	}
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to read tool cache directory")
+	}
+
+	var stored []os.FileInfo
+	var total int64
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || filepath.Ext(dirEntry.Name()) != entryExt {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		stored = append(stored, info)
+		total += info.Size()
+	}
+	if total <= s.maxBytes {
+		return nil
+	}
+
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].ModTime().Before(stored[j].ModTime())
+	})
+	for _, info := range stored {
+		if total <= s.maxBytes {
+			break
+		}
+		if err := os.Remove(filepath.Join(s.dir, info.Name())); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "failed to evict tool cache entry")
+		}
+		total -= info.Size()
+	}
+	return nil
+}
+
+func (s *Store) path(key string) string {
+	return filepath.Join(s.dir, key+entryExt)
+}
+
+// Clear removes every entry in dir and returns how many were removed and
+// how many bytes they took. A missing directory has nothing to clear.
+func Clear(dir string) (int, int64, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to read tool cache directory")
+	}
+
+	var removed int
+	var size int64
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() || (filepath.Ext(name) != entryExt && !strings.HasSuffix(name, ".tmp")) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return removed, size, errors.Wrapf(err, "failed to remove %s", name)
+		}
+		if filepath.Ext(name) == entryExt {
+			removed++
+			size += info.Size()
+		}
+	}
+	return removed, size, nil
+}
@@ -0,0 +1,91 @@
+package toolcache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyCanonicalizesInput(t *testing.T) {
+	a, err := Key("grep_tool", `{"pattern": "foo", "path": "."}`, "/repo@abc")
+	require.NoError(t, err)
+	b, err := Key("grep_tool", `{"path":".","pattern":"foo"}`, "/repo@abc")
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+
+	for _, other := range [][3]string{
+		{"glob_tool", `{"pattern": "foo", "path": "."}`, "/repo@abc"},
+		{"grep_tool", `{"pattern": "bar", "path": "."}`, "/repo@abc"},
+		{"grep_tool", `{"pattern": "foo", "path": "."}`, "/repo@def"},
+	} {
+		key, err := Key(other[0], other[1], other[2])
+		require.NoError(t, err)
+		assert.NotEqual(t, a, key, other)
+	}
+}
+
+func TestStoreGetPutAndExpiry(t *testing.T) {
+	store := NewStore(t.TempDir(), 0)
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	result := tooltypes.BaseToolResult{Result: "match"}
+
+	_, ok := store.Get("key", now)
+	assert.False(t, ok)
+
+	require.NoError(t, store.Put("key", NewEntry("grep_tool", result, now, time.Hour)))
+	entry, ok := store.Get("key", now.Add(30*time.Minute))
+	require.True(t, ok)
+	cached := entry.Result()
+	assert.Equal(t, result.AssistantFacing(), cached.AssistantFacing())
+	assert.False(t, cached.IsError())
+
+	_, ok = store.Get("key", now.Add(2*time.Hour))
+	assert.False(t, ok, "expired entries are not returned")
+	_, ok = store.Get("key", now)
+	assert.False(t, ok, "expired entries are removed")
+}
+
+func TestStoreEvictsOldestEntries(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	entry := NewEntry("web_fetch", tooltypes.BaseToolResult{Result: "page"}, now, time.Hour)
+	size := entrySize(t, entry)
+
+	store := NewStore(dir, 2*size)
+	require.NoError(t, store.Put("first", entry))
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "first.json"), now.Add(-time.Minute), now.Add(-time.Minute)))
+	require.NoError(t, store.Put("second", entry))
+	require.NoError(t, store.Put("third", entry))
+
+	_, ok := store.Get("first", now)
+	assert.False(t, ok, "the oldest entry is evicted")
+	_, ok = store.Get("third", now)
+	assert.True(t, ok)
+
+	removed, bytes, err := Clear(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	assert.Equal(t, 2*size, bytes)
+	_, ok = store.Get("third", now)
+	assert.False(t, ok)
+}
+
+func TestClearMissingDirectory(t *testing.T) {
+	removed, _, err := Clear(filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+}
+
+func entrySize(t *testing.T, entry Entry) int64 {
+	t.Helper()
+	probe := NewStore(t.TempDir(), 0)
+	require.NoError(t, probe.Put("probe", entry))
+	info, err := os.Stat(probe.path("probe"))
+	require.NoError(t, err)
+	return info.Size()
+}
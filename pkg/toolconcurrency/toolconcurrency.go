@@ -0,0 +1,148 @@
+// Package toolconcurrency limits how many tool calls of each class run at
+// once when an assistant turn requests several tools in parallel, so that
+// mutating commands do not race on the working tree while read-only tools
+// still run side by side.
+package toolconcurrency
+
+import (
+	"context"
+	"strings"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/semaphore"
+)
+
+// Built-in concurrency classes.
+const (
+	// ClassExclusive runs alone: it waits for every other tool call to
+	// finish and blocks new ones until it is done.
+	ClassExclusive = "exclusive"
+	// ClassMutating covers tools that change the working tree.
+	ClassMutating = "mutating"
+	// ClassRead covers read-only file system tools.
+	ClassRead = "read"
+	// ClassParallel is unlimited. Tools without a class belong to it.
+	ClassParallel = "parallel"
+)
+
+// DefaultLimits is the number of calls of each class that may run at once.
+var DefaultLimits = map[string]int{
+	ClassMutating: 1,
+	ClassRead:     4,
+	ClassParallel: 0,
+}
+
+// defaultClasses assigns the built-in tools to their classes.
+var defaultClasses = map[string]string{
+	"bash":        ClassMutating,
+	"file_write":  ClassMutating,
+	"file_edit":   ClassMutating,
+	"apply_patch": ClassMutating,
+	"file_read":   ClassRead,
+	"grep_tool":   ClassRead,
+	"glob_tool":   ClassRead,
+	"view_image":  ClassRead,
+}
+
+// exclusiveWeight is the weight of the gate every tool call passes through.
+// Ordinary calls take one unit and exclusive calls take all of it.
+const exclusiveWeight = 1 << 20
+
+// Limiter enforces the concurrency classes of tool calls. It is safe for
+// concurrent use.
+type Limiter struct {
+	classes map[string]string
+	limits  map[string]int
+	gate    *semaphore.Weighted
+	sems    map[string]*semaphore.Weighted
+	// total caps the calls running at once across classes, when set.
+	total *semaphore.Weighted
+}
+
+// NewLimiter returns a limiter using the built-in classes and limits with
+// the overrides in config applied. config may be nil.
+func NewLimiter(config *llmtypes.ToolConcurrencyConfig) *Limiter {
+	l := &Limiter{
+		classes: make(map[string]string, len(defaultClasses)),
+		limits:  make(map[string]int, len(DefaultLimits)),
+		gate:    semaphore.NewWeighted(exclusiveWeight),
+		sems:    make(map[string]*semaphore.Weighted),
+	}
+	for tool, class := range defaultClasses {
+		l.classes[tool] = class
+	}
+	for class, limit := range DefaultLimits {
+		l.limits[class] = limit
+	}
+	if config != nil {
+		for tool, class := range config.Classes {
+			l.classes[tool] = class
+		}
+		for class, limit := range config.Limits {
+			l.limits[class] = limit
+		}
+		if config.MaxConcurrent > 0 {
+			l.total = semaphore.NewWeighted(int64(config.MaxConcurrent))
+		}
+	}
+	for class, limit := range l.limits {
+		if limit > 0 && class != ClassExclusive {
+			l.sems[class] = semaphore.NewWeighted(int64(limit))
+		}
+	}
+	return l
+}
+
+// Class returns the concurrency class of toolName. Tools whose name starts
+// with "browser" are exclusive unless configured otherwise, since they drive
+// a single shared browser session.
+func (l *Limiter) Class(toolName string) string {
+	if class, ok := l.classes[toolName]; ok && class != "" {
+		return class
+	}
+	if strings.HasPrefix(toolName, "browser") {
+		return ClassExclusive
+	}
+	return ClassParallel
+}
+
+// Acquire blocks until toolName may run and returns the function that
+// releases its slot. It fails when ctx is done first.
+func (l *Limiter) Acquire(ctx context.Context, toolName string) (func(), error) {
+	class := l.Class(toolName)
+	if class == ClassExclusive {
+		if err := l.gate.Acquire(ctx, exclusiveWeight); err != nil {
+			return nil, errors.Wrapf(err, "waiting to run %s exclusively", toolName)
+		}
+		return func() { l.gate.Release(exclusiveWeight) }, nil
+	}
+
+	if err := l.gate.Acquire(ctx, 1); err != nil {
+		return nil, errors.Wrapf(err, "waiting to run %s", toolName)
+	}
+	if l.total != nil {
+		if err := l.total.Acquire(ctx, 1); err != nil {
+			l.gate.Release(1)
+			return nil, errors.Wrapf(err, "waiting for a free slot to run %s", toolName)
+		}
+	}
+	releaseShared := func() {
+		if l.total != nil {
+			l.total.Release(1)
+		}
+		l.gate.Release(1)
+	}
+	sem, ok := l.sems[class]
+	if !ok {
+		return releaseShared, nil
+	}
+	if err := sem.Acquire(ctx, 1); err != nil {
+		releaseShared()
+		return nil, errors.Wrapf(err, "waiting for a free %s slot to run %s", class, toolName)
+	}
+	return func() {
+		sem.Release(1)
+		releaseShared()
+	}, nil
+}
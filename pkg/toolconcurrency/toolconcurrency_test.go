@@ -0,0 +1,117 @@
+package toolconcurrency
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// maxConcurrent runs n calls of toolName through l and reports the highest
+// number that were running at the same time.
+func maxConcurrent(t *testing.T, l *Limiter, toolName string, n int) int64 {
+	t.Helper()
+	var running, peak atomic.Int64
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := l.Acquire(context.Background(), toolName)
+			require.NoError(t, err)
+			defer release()
+			now := running.Add(1)
+			for {
+				old := peak.Load()
+				if now <= old || peak.CompareAndSwap(old, now) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+	wg.Wait()
+	return peak.Load()
+}
+
+func TestLimiterClass(t *testing.T) {
+	l := NewLimiter(&llmtypes.ToolConcurrencyConfig{Classes: map[string]string{"deploy": ClassExclusive, "grep_tool": ClassParallel}})
+
+	assert.Equal(t, ClassMutating, l.Class("bash"))
+	assert.Equal(t, ClassRead, l.Class("file_read"))
+	assert.Equal(t, ClassParallel, l.Class("grep_tool"))
+	assert.Equal(t, ClassExclusive, l.Class("browser_navigate"))
+	assert.Equal(t, ClassExclusive, l.Class("deploy"))
+	assert.Equal(t, ClassParallel, l.Class("web_fetch"))
+}
+
+func TestLimiterEnforcesClassLimits(t *testing.T) {
+	l := NewLimiter(nil)
+	assert.Equal(t, int64(1), maxConcurrent(t, l, "bash", 4))
+	assert.Equal(t, int64(4), maxConcurrent(t, l, "file_read", 8))
+	assert.Equal(t, int64(6), maxConcurrent(t, l, "web_fetch", 6))
+
+	l = NewLimiter(&llmtypes.ToolConcurrencyConfig{Limits: map[string]int{ClassRead: 2}})
+	assert.Equal(t, int64(2), maxConcurrent(t, l, "file_read", 6))
+}
+
+func TestLimiterEnforcesMaxConcurrent(t *testing.T) {
+	l := NewLimiter(&llmtypes.ToolConcurrencyConfig{MaxConcurrent: 3})
+	assert.Equal(t, int64(3), maxConcurrent(t, l, "web_fetch", 6))
+	assert.Equal(t, int64(3), maxConcurrent(t, l, "file_read", 6))
+	assert.Equal(t, int64(1), maxConcurrent(t, l, "bash", 3))
+}
+
+func TestLimiterExclusiveRunsAlone(t *testing.T) {
+	l := NewLimiter(nil)
+	release, err := l.Acquire(context.Background(), "file_read")
+	require.NoError(t, err)
+
+	acquired := make(chan func())
+	go func() {
+		releaseExclusive, err := l.Acquire(context.Background(), "browser_click")
+		assert.NoError(t, err)
+		acquired <- releaseExclusive
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("exclusive tool started while another tool was running")
+	case <-time.After(20 * time.Millisecond):
+	}
+	release()
+	releaseExclusive := <-acquired
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = l.Acquire(ctx, "web_fetch")
+	require.Error(t, err, "other tools wait for the exclusive tool")
+	releaseExclusive()
+
+	release, err = l.Acquire(context.Background(), "web_fetch")
+	require.NoError(t, err)
+	release()
+}
+
+func TestLimiterAcquireHonoursCancellation(t *testing.T) {
+	l := NewLimiter(nil)
+	release, err := l.Acquire(context.Background(), "bash")
+	require.NoError(t, err)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = l.Acquire(ctx, "file_edit")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "waiting for a free mutating slot to run file_edit")
+
+	other, err := l.Acquire(context.Background(), "file_read")
+	require.NoError(t, err, "a cancelled wait must not leak its slot in the shared gate")
+	other()
+}
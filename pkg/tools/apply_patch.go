@@ -43,6 +43,7 @@ var applyPatchDescription string
 
 type applyPatchToolResult struct {
 	changes []tooltypes.ApplyPatchChange
+	reviews []*editReviewOutcome
 	err     string
 }
 
@@ -65,9 +66,24 @@ func (r *applyPatchToolResult) GetResult() string {
 		b.WriteString("\n")
 		b.WriteString(rendered)
 	}
+	if feedback := r.reviewFeedback(); feedback != "" {
+		b.WriteString("\n\n")
+		b.WriteString(feedback)
+	}
 	return strings.TrimSuffix(b.String(), "\n")
 }
 
+// reviewFeedback describes the hunks rejected in review, file by file.
+func (r *applyPatchToolResult) reviewFeedback() string {
+	var parts []string
+	for _, review := range r.reviews {
+		if feedback := review.feedback(); feedback != "" {
+			parts = append(parts, feedback)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
 func (r *applyPatchToolResult) GetError() string {
 	return r.err
 }
@@ -164,7 +180,7 @@ func (t *ApplyPatchTool) ValidateInput(state tooltypes.State, parameters string)
 }
 
 // Execute applies the patch to disk.
-func (t *ApplyPatchTool) Execute(_ context.Context, state tooltypes.State, parameters string) tooltypes.ToolResult {
+func (t *ApplyPatchTool) Execute(ctx context.Context, state tooltypes.State, parameters string) tooltypes.ToolResult {
 	parsed, err := parseAndResolvePatchInput(parameters, state.WorkingDirectory())
 	if err != nil {
 		return &applyPatchToolResult{err: err.Error()}
@@ -174,130 +190,210 @@ func (t *ApplyPatchTool) Execute(_ context.Context, state tooltypes.State, param
 		return &applyPatchToolResult{err: "No files were modified."}
 	}
 
-	result := &applyPatchToolResult{}
+	paths := patchHunkPaths(parsed.hunks)
+	unlock := lockPaths(state, paths...)
+	defer unlock()
 
+	// Every hunk is reviewed before the patch counts against the change
+	// limits, so rejected changes do not use them up.
+	result := &applyPatchToolResult{}
+	plans := make([]plannedHunk, 0, len(parsed.hunks))
+	pending := make(map[string]*string)
 	for _, hunk := range parsed.hunks {
-		switch hunk.kind {
-		case patchHunkAdd:
-			unlock := lockPaths(state, hunk.path)
-			err = applyAddHunk(hunk, result)
-			unlock()
-		case patchHunkDelete:
-			unlock := lockPaths(state, hunk.path)
-			err = applyDeleteHunk(hunk, result)
-			unlock()
-		case patchHunkUpdate:
-			paths := []string{hunk.path}
-			if hunk.movePath != "" {
-				paths = append(paths, hunk.movePath)
-			}
-			unlock := lockPaths(state, paths...)
-			err = applyUpdateHunk(hunk, result)
-			unlock()
+		plan, review, err := planHunk(ctx, hunk, pending)
+		if review != nil {
+			result.reviews = append(result.reviews, review)
 		}
-
 		if err != nil {
 			result.err = err.Error()
 			return result
 		}
+		plans = append(plans, plan)
 	}
 
-	return result
-}
-
-func applyAddHunk(hunk parsedHunk, result *applyPatchToolResult) error {
-	if parent := filepath.Dir(hunk.path); parent != "" && parent != "." {
-		if err := os.MkdirAll(parent, 0o755); err != nil {
-			return errors.Wrapf(err, "failed to create parent directories for %s", hunk.path)
+	release := func() {}
+	if changes := plannedPatchChanges(plans); len(changes) > 0 {
+		var err error
+		release, err = reserveFileChanges(ctx, state, changes...)
+		if err != nil {
+			return &applyPatchToolResult{err: err.Error()}
 		}
+		checkpointFiles(ctx, state, t.Name(), paths...)
 	}
 
-	oldContent := ""
-	if info, err := os.Stat(hunk.path); err == nil {
-		if info.IsDir() {
-			return errors.Errorf("failed to add file %s: already exists and is a directory", hunk.path)
+	for _, plan := range plans {
+		if err := writePlannedHunk(plan, result); err != nil {
+			if len(result.changes) == 0 {
+				release()
+			}
+			result.err = err.Error()
+			return result
 		}
-		if bytes, readErr := os.ReadFile(hunk.path); readErr == nil {
-			oldContent = string(bytes)
+		if plan.hunk.kind != patchHunkDelete {
+			recordFileAccess(state, plan.hunk.path, plan.hunk.movePath)
 		}
-	} else if !os.IsNotExist(err) {
-		return errors.Wrapf(err, "failed to stat %s", hunk.path)
 	}
 
-	if err := os.WriteFile(hunk.path, []byte(hunk.contents), 0o644); err != nil {
-		return errors.Wrapf(err, "failed to write file %s", hunk.path)
+	if len(result.changes) == 0 && len(result.reviews) > 0 {
+		result.err = result.reviewFeedback()
 	}
-
-	result.changes = append(result.changes, tooltypes.ApplyPatchChange{
-		Path:        hunk.path,
-		Operation:   tooltypes.ApplyPatchOperationAdd,
-		OldContent:  oldContent,
-		NewContent:  hunk.contents,
-		UnifiedDiff: applyPatchUnifiedDiff(hunk.path, hunk.path, oldContent, hunk.contents),
-	})
-
-	return nil
+	return result
 }
 
-func applyDeleteHunk(hunk parsedHunk, result *applyPatchToolResult) error {
-	oldContent, err := os.ReadFile(hunk.path)
+// plannedHunk is a hunk whose change has been reviewed and is ready to write.
+type plannedHunk struct {
+	hunk       parsedHunk
+	oldContent string
+	newContent string
+	// skip is set when the review rejected every change, so nothing is written.
+	skip bool
+}
+
+// planHunk works out the content a hunk writes and reviews it. pending holds
+// what earlier hunks of the patch leave in each file, nil once deleted, so
+// several hunks can change the same file.
+func planHunk(ctx context.Context, hunk parsedHunk, pending map[string]*string) (plannedHunk, *editReviewOutcome, error) {
+	plan := plannedHunk{hunk: hunk}
+	switch hunk.kind {
+	case patchHunkAdd:
+		if content, ok := pending[hunk.path]; ok {
+			if content != nil {
+				plan.oldContent = *content
+			}
+		} else if info, err := os.Stat(hunk.path); err == nil {
+			if info.IsDir() {
+				return plan, nil, errors.Errorf("failed to add file %s: already exists and is a directory", hunk.path)
+			}
+			if bytes, readErr := os.ReadFile(hunk.path); readErr == nil {
+				plan.oldContent = string(bytes)
+			}
+		} else if !os.IsNotExist(err) {
+			return plan, nil, errors.Wrapf(err, "failed to stat %s", hunk.path)
+		}
+		plan.newContent = hunk.contents
+	case patchHunkDelete:
+		oldContent, err := readPatchTarget(hunk.path, pending)
+		if err != nil {
+			return plan, nil, errors.Wrapf(err, "failed to read %s", hunk.path)
+		}
+		plan.oldContent = oldContent
+	case patchHunkUpdate:
+		oldContent, err := readPatchTarget(hunk.path, pending)
+		if err != nil {
+			return plan, nil, errors.Wrapf(err, "failed to read file to update %s", hunk.path)
+		}
+		newContent, err := deriveUpdatedContent(hunk.path, oldContent, hunk.chunks)
+		if err != nil {
+			return plan, nil, err
+		}
+		plan.oldContent, plan.newContent = oldContent, newContent
+	}
+
+	// Removing every line of a file is a single hunk, so a review either
+	// accepts the deletion or rejects it.
+	newContent, review, err := reviewFileChange(ctx, "apply_patch", hunk.path, plan.oldContent, plan.newContent)
 	if err != nil {
-		return errors.Wrapf(err, "failed to read %s", hunk.path)
+		return plan, nil, err
 	}
-	if err := os.Remove(hunk.path); err != nil {
-		return errors.Wrapf(err, "failed to delete file %s", hunk.path)
+	plan.newContent = newContent
+	isMove := hunk.kind == patchHunkUpdate && hunk.movePath != "" && hunk.movePath != hunk.path
+	plan.skip = review.allRejected() && !isMove
+	if plan.skip {
+		return plan, review, nil
 	}
 
-	result.changes = append(result.changes, tooltypes.ApplyPatchChange{
-		Path:        hunk.path,
-		Operation:   tooltypes.ApplyPatchOperationDelete,
-		OldContent:  string(oldContent),
-		UnifiedDiff: applyPatchUnifiedDiff(hunk.path, hunk.path, string(oldContent), ""),
-	})
-
-	return nil
+	switch {
+	case hunk.kind == patchHunkDelete:
+		pending[hunk.path] = nil
+	case isMove:
+		pending[hunk.path] = nil
+		pending[hunk.movePath] = &plan.newContent
+	default:
+		pending[hunk.path] = &plan.newContent
+	}
+	return plan, review, nil
 }
 
-func applyUpdateHunk(hunk parsedHunk, result *applyPatchToolResult) error {
-	oldContent, newContent, err := deriveUpdatedContent(hunk.path, hunk.chunks)
+// readPatchTarget returns path's content as earlier hunks of the patch left
+// it, or as it is on disk.
+func readPatchTarget(path string, pending map[string]*string) (string, error) {
+	if content, ok := pending[path]; ok {
+		if content == nil {
+			return "", errors.Wrap(os.ErrNotExist, "deleted earlier in the patch")
+		}
+		return *content, nil
+	}
+	bytes, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		return "", err
 	}
+	return string(bytes), nil
+}
 
-	targetPath := hunk.path
-	movePath := ""
-	isMove := hunk.movePath != "" && hunk.movePath != hunk.path
-	if isMove {
-		movePath = hunk.movePath
-		targetPath = movePath
-		if parent := filepath.Dir(movePath); parent != "" && parent != "." {
-			if mkErr := os.MkdirAll(parent, 0o755); mkErr != nil {
-				return errors.Wrapf(mkErr, "failed to create parent directories for %s", movePath)
+func writePlannedHunk(plan plannedHunk, result *applyPatchToolResult) error {
+	if plan.skip {
+		return nil
+	}
+	hunk := plan.hunk
+	switch hunk.kind {
+	case patchHunkAdd:
+		if parent := filepath.Dir(hunk.path); parent != "" && parent != "." {
+			if err := os.MkdirAll(parent, 0o755); err != nil {
+				return errors.Wrapf(err, "failed to create parent directories for %s", hunk.path)
 			}
 		}
-		if writeErr := os.WriteFile(movePath, []byte(newContent), 0o644); writeErr != nil {
-			return errors.Wrapf(writeErr, "failed to write file %s", movePath)
-		}
-		if rmErr := os.Remove(hunk.path); rmErr != nil {
-			return errors.Wrapf(rmErr, "failed to remove original %s", hunk.path)
-		}
-	} else {
-		if writeErr := os.WriteFile(hunk.path, []byte(newContent), 0o644); writeErr != nil {
-			return errors.Wrapf(writeErr, "failed to write file %s", hunk.path)
-		}
+		if err := os.WriteFile(hunk.path, []byte(plan.newContent), 0o644); err != nil {
+			return errors.Wrapf(err, "failed to write file %s", hunk.path)
+		}
+		result.changes = append(result.changes, tooltypes.ApplyPatchChange{
+			Path:        hunk.path,
+			Operation:   tooltypes.ApplyPatchOperationAdd,
+			OldContent:  plan.oldContent,
+			NewContent:  plan.newContent,
+			UnifiedDiff: applyPatchUnifiedDiff(hunk.path, hunk.path, plan.oldContent, plan.newContent),
+			InverseDiff: applyPatchUnifiedDiff(hunk.path, hunk.path, plan.newContent, plan.oldContent),
+		})
+	case patchHunkDelete:
+		if err := os.Remove(hunk.path); err != nil {
+			return errors.Wrapf(err, "failed to delete file %s", hunk.path)
+		}
+		result.changes = append(result.changes, tooltypes.ApplyPatchChange{
+			Path:        hunk.path,
+			Operation:   tooltypes.ApplyPatchOperationDelete,
+			OldContent:  plan.oldContent,
+			UnifiedDiff: applyPatchUnifiedDiff(hunk.path, hunk.path, plan.oldContent, ""),
+			InverseDiff: applyPatchUnifiedDiff(hunk.path, hunk.path, "", plan.oldContent),
+		})
+	case patchHunkUpdate:
+		targetPath := hunk.path
+		movePath := ""
+		if hunk.movePath != "" && hunk.movePath != hunk.path {
+			movePath = hunk.movePath
+			targetPath = movePath
+			if parent := filepath.Dir(movePath); parent != "" && parent != "." {
+				if err := os.MkdirAll(parent, 0o755); err != nil {
+					return errors.Wrapf(err, "failed to create parent directories for %s", movePath)
+				}
+			}
+			if err := os.WriteFile(movePath, []byte(plan.newContent), 0o644); err != nil {
+				return errors.Wrapf(err, "failed to write file %s", movePath)
+			}
+			if err := os.Remove(hunk.path); err != nil {
+				return errors.Wrapf(err, "failed to remove original %s", hunk.path)
+			}
+		} else if err := os.WriteFile(hunk.path, []byte(plan.newContent), 0o644); err != nil {
+			return errors.Wrapf(err, "failed to write file %s", hunk.path)
+		}
+		result.changes = append(result.changes, tooltypes.ApplyPatchChange{
+			Path:        hunk.path,
+			Operation:   tooltypes.ApplyPatchOperationUpdate,
+			OldContent:  plan.oldContent,
+			NewContent:  plan.newContent,
+			UnifiedDiff: applyPatchUnifiedDiff(hunk.path, targetPath, plan.oldContent, plan.newContent),
+			InverseDiff: applyPatchUnifiedDiff(targetPath, hunk.path, plan.newContent, plan.oldContent),
+			MovePath:    movePath,
+		})
 	}
-
-	diff := applyPatchUnifiedDiff(hunk.path, targetPath, oldContent, newContent)
-
-	result.changes = append(result.changes, tooltypes.ApplyPatchChange{
-		Path:        hunk.path,
-		Operation:   tooltypes.ApplyPatchOperationUpdate,
-		OldContent:  oldContent,
-		NewContent:  newContent,
-		UnifiedDiff: diff,
-		MovePath:    movePath,
-	})
-
 	return nil
 }
 
@@ -596,30 +692,24 @@ done:
 	return chunk, parsed + start, nil
 }
 
-func deriveUpdatedContent(path string, chunks []updateFileChunk) (oldContent string, newContent string, err error) {
-	bytes, readErr := os.ReadFile(path)
-	if readErr != nil {
-		return "", "", errors.Wrapf(readErr, "failed to read file to update %s", path)
-	}
-	oldContent = string(bytes)
-
+// deriveUpdatedContent applies the chunks of an update hunk to path's
+// oldContent.
+func deriveUpdatedContent(path, oldContent string, chunks []updateFileChunk) (string, error) {
 	originalLines := strings.Split(oldContent, "\n")
 	if len(originalLines) > 0 && originalLines[len(originalLines)-1] == "" {
 		originalLines = originalLines[:len(originalLines)-1]
 	}
 
-	replacements, replErr := computeReplacements(originalLines, path, chunks)
-	if replErr != nil {
-		return "", "", replErr
+	replacements, err := computeReplacements(originalLines, path, chunks)
+	if err != nil {
+		return "", err
 	}
 
 	updatedLines := applyReplacements(originalLines, replacements)
 	if len(updatedLines) == 0 || updatedLines[len(updatedLines)-1] != "" {
 		updatedLines = append(updatedLines, "")
 	}
-	newContent = strings.Join(updatedLines, "\n")
-
-	return oldContent, newContent, nil
+	return strings.Join(updatedLines, "\n"), nil
 }
 
 type replacement struct {
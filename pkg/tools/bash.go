@@ -21,6 +21,7 @@ import (
 	"github.com/gobwas/glob"
 	"github.com/invopop/jsonschema"
 	"github.com/jingkaihe/kodelet/pkg/binaries"
+	"github.com/jingkaihe/kodelet/pkg/devcontainer"
 	"github.com/jingkaihe/kodelet/pkg/osutil"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
@@ -37,11 +38,16 @@ var (
 		"view",
 		"less",
 		"more",
-		"cd",
 	}
 
-	descriptionTemplate = `Run a bash command in a persistent shell session.
+	// statelessBannedCommands are additionally banned when every call runs in
+	// a fresh shell, where their effect would be lost.
+	statelessBannedCommands = []string{"cd"}
 
+	descriptionTemplate = `{{if .Stateless}}Run a bash command in a fresh shell.{{else}}Run a bash command in a persistent shell session.{{end}}
+{{if .DevContainer}}
+Commands run inside the project's dev container {{.DevContainer}}. The workspace {{.WorkspaceDir}} is mounted at {{.WorkspaceFolder}}; paths outside it do not exist in the container, and commands run from outside it start in {{.WorkspaceFolder}}.
+{{end}}
 # Restrictions
 {{if .AllowedCommands}}
 Allowed command patterns:
@@ -58,21 +64,29 @@ Banned commands:
 - command: required single-line bash command
 - description: required, 5-10 words
 - timeout: required, {{.MinTimeoutSeconds}}-{{.MaxTimeoutSeconds}}
+{{if not .Stateless}}- reset: optional, true to restart the session with a clean environment in the default working directory
+{{end}}- raw: optional, true to return output that looks binary; binary output is otherwise replaced by its type and size
 
 # Rules
-- Use parallel tool calling for independent commands.
-- Do not run interactive commands.
+{{if .Stateless}}- Use parallel tool calling for independent commands.
+{{else}}- The working directory and exported environment variables persist between calls in this conversation.
+- Calls run one at a time in the session; do not start long-running foreground servers.
+{{end}}- Do not run interactive commands.
 - For multiple commands, use ';' or '&&' on one line.
-- Avoid direct cd; use absolute paths or subshell: (cd /path && cmd).
-{{if .EnableFSSearchTools}}- Prefer grep_tool/glob_tool over grep/find in bash.
+{{if .Stateless}}- Avoid direct cd; use absolute paths or subshell: (cd /path && cmd).
+{{end}}{{if .EnableFSSearchTools}}- Prefer grep_tool/glob_tool over grep/find in bash.
 {{else}}- For filesystem search activities, use fd and rg via this tool only.
 {{end}}- Do not use heredoc; use file_write or apply_patch instead.
 
 Examples:
-- (cd /repo && mise run test)
+{{if .Stateless}}- (cd /repo && mise run test){{else}}- cd /repo && mise run test{{end}}
 `
 )
 
+// ConversationIDEnv tells commands run by the bash tool which conversation
+// started them, so a nested `kodelet run --brief-from` can be briefed from it.
+const ConversationIDEnv = "KODELET_CONVERSATION_ID"
+
 const (
 	bashApproxBytesPerToken = 4
 	bashMinTimeoutSeconds   = int(llmtypes.MinBashTimeout / time.Second)
@@ -85,6 +99,14 @@ type BashTool struct {
 	compiledGlobs       []glob.Glob
 	enableFSSearchTools bool
 	maxTimeout          time.Duration
+	// stateless runs every call in a fresh shell instead of the
+	// conversation's persistent session.
+	stateless bool
+	// devContainer, when set, runs every call with docker exec in the
+	// repository's dev container.
+	devContainer *devcontainer.Container
+	// env holds the tool_env variables set for every command.
+	env map[string]string
 }
 
 var _ tooltypes.StreamingTool = (*BashTool)(nil)
@@ -112,6 +134,14 @@ func NewBashToolWithTimeout(allowedCommands []string, enableFSSearchTools bool,
 	}
 }
 
+// NewStatelessBashTool creates a BashTool that runs every command in a fresh
+// shell, as configured by bash.stateless.
+func NewStatelessBashTool(allowedCommands []string, enableFSSearchTools bool, maxTimeout time.Duration) *BashTool {
+	tool := NewBashToolWithTimeout(allowedCommands, enableFSSearchTools, maxTimeout)
+	tool.stateless = true
+	return tool
+}
+
 // MatchesCommand checks if a command matches any of the compiled glob patterns
 func (b *BashTool) MatchesCommand(command string) bool {
 	for _, c := range b.allowedCommands {
@@ -198,7 +228,7 @@ func (b *BashTool) ValidateInput(_ tooltypes.State, parameters string) error {
 		firstWord := splitted[0]
 
 		// DENY FIRST: Check if command is banned - if yes, deny it regardless of allowed commands
-		if slices.Contains(BannedCommands, firstWord) {
+		if slices.Contains(b.bannedCommands(), firstWord) {
 			return errors.New("command is banned: " + firstWord)
 		}
 
@@ -246,15 +276,25 @@ func (b *BashTool) Description() string {
 		AllowedCommands     []string
 		BannedCommands      []string
 		EnableFSSearchTools bool
+		Stateless           bool
+		DevContainer        string
+		WorkspaceDir        string
+		WorkspaceFolder     string
 		MinTimeoutSeconds   int
 		MaxTimeoutSeconds   int
 	}{
 		AllowedCommands:     b.allowedCommands,
-		BannedCommands:      BannedCommands,
+		BannedCommands:      b.bannedCommands(),
 		EnableFSSearchTools: b.enableFSSearchTools,
+		Stateless:           b.stateless,
 		MinTimeoutSeconds:   bashMinTimeoutSeconds,
 		MaxTimeoutSeconds:   b.maxTimeoutSeconds(),
 	}
+	if b.devContainer != nil {
+		data.DevContainer = b.devContainer.Name
+		data.WorkspaceDir = b.devContainer.WorkspaceDir()
+		data.WorkspaceFolder = b.devContainer.WorkspaceFolder()
+	}
 
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
@@ -265,6 +305,13 @@ func (b *BashTool) Description() string {
 	return buf.String()
 }
 
+func (b *BashTool) bannedCommands() []string {
+	if b.stateless {
+		return slices.Concat(BannedCommands, statelessBannedCommands)
+	}
+	return BannedCommands
+}
+
 func (b *BashTool) maxTimeoutSeconds() int {
 	maxTimeout := b.maxTimeout
 	if maxTimeout == 0 {
@@ -285,11 +332,14 @@ type BashToolResult struct {
 	exitCode           int
 	executionTime      time.Duration
 	workingDir         string
+	container          string
+	containerDir       string
 	outputTruncated    bool
 	outputTotalLines   int
 	outputTotalBytes   int64
 	fullOutputPath     string
 	fullOutputComplete bool
+	binary             *tooltypes.BinaryContent
 }
 
 // GetResult returns the command output
@@ -339,6 +389,9 @@ func (r *BashToolResult) StructuredData() tooltypes.StructuredToolResult {
 		ExitCode:      r.exitCode,
 		ExecutionTime: r.executionTime,
 		WorkingDir:    r.workingDir,
+		Container:     r.container,
+		ContainerDir:  r.containerDir,
+		Binary:        r.binary,
 	}
 	if r.outputTruncated {
 		metadata.Truncation = &tooltypes.BashOutputTruncation{
@@ -392,62 +445,80 @@ func (b *BashTool) execute(
 			error:      err.Error(),
 		}
 	}
-	return b.executeForeground(ctx, input, state.WorkingDirectory(), onUpdate)
+	checkpointDir := state.WorkingDirectory()
+	if strings.TrimSpace(checkpointDir) == "" {
+		checkpointDir, _ = os.Getwd()
+	}
+	defer checkpointCommand(ctx, state, b.Name(), checkpointDir)()
+
+	if !b.stateless {
+		if key, store, ok := sessionKeyFromContext(ctx); ok {
+			return b.executeInSession(ctx, key, store, input, state, onUpdate)
+		}
+	}
+	return b.executeForeground(ctx, input, state, onUpdate)
 }
 
 func (b *BashTool) executeForeground(
 	ctx context.Context,
 	input *BashInput,
-	cwd string,
+	state tooltypes.State,
 	onUpdate tooltypes.ToolUpdateCallback,
 ) tooltypes.ToolResult {
 	startTime := time.Now()
 	ctx, cancel := context.WithTimeout(ctx, time.Duration(input.Timeout)*time.Second)
 	defer cancel()
 
-	workingDir := cwd
+	workingDir := state.WorkingDirectory()
 	if strings.TrimSpace(workingDir) == "" {
 		workingDir, _ = os.Getwd()
 	}
 
-	cmd := exec.CommandContext(ctx, "bash", "-c", input.Command)
-	cmd.Dir = workingDir
-	if env, err := bashEnvWithPreferredBinDirs(); err == nil {
-		cmd.Env = env
-	}
-	osutil.SetProcessGroup(cmd)
-	osutil.SetProcessGroupKill(cmd)
-
-	output := newBashOutputAccumulator(approxBytesForTokens(bashMaxOutputTokens))
-	var completedExecutionTime atomic.Int64
-	currentResult := func() tooltypes.ToolResult {
-		executionTime := time.Since(startTime)
-		if completed := completedExecutionTime.Load(); completed > 0 {
-			executionTime = time.Duration(completed)
+	toolEnv, err := b.toolEnv(ctx)
+	if err != nil {
+		return &BashToolResult{
+			command:    input.Command,
+			workingDir: workingDir,
+			error:      err.Error(),
 		}
-		return newBashToolResult(input.Command, workingDir, executionTime, output.snapshot(), false)
 	}
 
-	var emitter *bashUpdateEmitter
-	if onUpdate != nil {
-		emitter = newBashUpdateEmitter(onUpdate, currentResult, currentResult())
-		output.onWrite = emitter.markDirty
+	var cmd *exec.Cmd
+	if b.devContainer != nil {
+		cmd = b.devContainer.Command(ctx, workingDir, input.Command, envNames(toolEnv)...)
+	} else {
+		cmd = exec.CommandContext(ctx, "bash", "-c", input.Command)
+	}
+	cmd.Dir = workingDir
+	env, err := bashEnvWithPreferredBinDirs()
+	if err != nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(withConversationIDEnv(env, ToolContextFromContext(ctx).ConversationID), toolEnv...)
+	osutil.SetProcessGroup(cmd)
+	if b.devContainer == nil {
+		// The dev container command kills its process group in the container
+		osutil.SetProcessGroupKill(cmd)
 	}
 
-	cmd.Stdout = output
-	cmd.Stderr = output
-	err := cmd.Start()
+	capture := newBashOutputCapture(input.Command, workingDir, startTime, input.Raw, onUpdate)
+	cmd.Stdout = capture.output
+	cmd.Stderr = capture.output
+	err = cmd.Start()
 	if err == nil {
 		err = cmd.Wait()
 	}
 	timedOut := ctx.Err() == context.DeadlineExceeded
 	executionTime := time.Since(startTime)
-	completedExecutionTime.Store(int64(executionTime))
-	finalSnapshot := output.finish()
-	if emitter != nil {
-		emitter.stopAndFlush()
+	recordProcessUsage(state, cmd.ProcessState, executionTime)
+	result := capture.finish(executionTime)
+	if b.devContainer != nil {
+		result.container = b.devContainer.Name
+		result.containerDir, _ = b.devContainer.ContainerPath(workingDir)
+		if result.containerDir == "" {
+			result.containerDir = b.devContainer.WorkspaceFolder()
+		}
 	}
-	result := newBashToolResult(input.Command, workingDir, executionTime, finalSnapshot, true)
 
 	if err != nil {
 		if timedOut {
@@ -466,12 +537,141 @@ func (b *BashTool) executeForeground(
 	return result
 }
 
+// executeInSession runs the command in the conversation's persistent shell,
+// restoring the session from the conversation record when no shell is
+// running, and persists the resulting state back into the record.
+func (b *BashTool) executeInSession(
+	ctx context.Context,
+	conversationID string,
+	store MetadataStore,
+	input *BashInput,
+	state tooltypes.State,
+	onUpdate tooltypes.ToolUpdateCallback,
+) tooltypes.ToolResult {
+	startTime := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(input.Timeout)*time.Second)
+	defer cancel()
+
+	defaultWorkingDir := state.WorkingDirectory()
+	if strings.TrimSpace(defaultWorkingDir) == "" {
+		defaultWorkingDir, _ = os.Getwd()
+	}
+
+	var sessionState bashSessionState
+	if input.Reset {
+		bashSessions.close(conversationID)
+		if store != nil {
+			store.SetMetadataValue(bashSessionMetadataKey, nil)
+		}
+	} else if store != nil {
+		sessionState, _ = bashSessionStateFromMetadata(store.GetMetadata())
+	}
+
+	toolEnv, err := b.toolEnv(ctx)
+	if err != nil {
+		return &BashToolResult{
+			command:    input.Command,
+			workingDir: defaultWorkingDir,
+			error:      err.Error(),
+		}
+	}
+	session, err := bashSessions.get(conversationID, defaultWorkingDir, sessionState, toolEnv)
+	if err != nil {
+		if errors.Is(err, errBashSessionUnsupported) {
+			return b.executeForeground(ctx, input, state, onUpdate)
+		}
+		return &BashToolResult{
+			command:    input.Command,
+			workingDir: defaultWorkingDir,
+			error:      err.Error(),
+		}
+	}
+
+	workingDir := session.lastState().WorkingDir
+	capture := newBashOutputCapture(input.Command, workingDir, startTime, input.Raw, onUpdate)
+	session.setUsageRecorder(func(usage tooltypes.ResourceUsage) { recordResourceUsage(state, usage) })
+	run, err := session.run(ctx, input.Command, capture.output)
+	executionTime := time.Since(startTime)
+	result := capture.finish(executionTime)
+
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			result.error = "Command timed out after " + strconv.Itoa(input.Timeout) + " seconds; the shell session was restarted from its last saved state"
+			return result
+		}
+		result.error = err.Error()
+		return result
+	}
+
+	recordResourceUsage(state, tooltypes.ResourceUsage{
+		Processes:     1 + run.backgroundProcesses,
+		WallTime:      executionTime,
+		UserCPUTime:   run.userCPUTime,
+		SystemCPUTime: run.systemCPUTime,
+	})
+	if store != nil {
+		store.SetMetadataValue(bashSessionMetadataKey, session.lastState().persistable())
+	}
+
+	result.exitCode = run.exitCode
+	if run.sessionExited {
+		result.error = fmt.Sprintf("Command exited the shell session with status %d; the next command starts a new session from the last saved state", run.exitCode)
+		return result
+	}
+	if run.exitCode != 0 {
+		result.error = fmt.Sprintf("Command exited with status %d", run.exitCode)
+	}
+	return result
+}
+
+// bashOutputCapture collects a command's output and streams result snapshots
+// to onUpdate while the command runs.
+type bashOutputCapture struct {
+	command                string
+	workingDir             string
+	output                 *bashOutputAccumulator
+	emitter                *bashUpdateEmitter
+	completedExecutionTime atomic.Int64
+}
+
+func newBashOutputCapture(command, workingDir string, startTime time.Time, raw bool, onUpdate tooltypes.ToolUpdateCallback) *bashOutputCapture {
+	c := &bashOutputCapture{
+		command:    command,
+		workingDir: workingDir,
+		output:     newBashOutputAccumulator(approxBytesForTokens(bashMaxOutputTokens)),
+	}
+	c.output.withholdBinary = !raw
+	currentResult := func() tooltypes.ToolResult {
+		executionTime := time.Since(startTime)
+		if completed := c.completedExecutionTime.Load(); completed > 0 {
+			executionTime = time.Duration(completed)
+		}
+		return newBashToolResult(c.command, c.workingDir, executionTime, c.output.snapshot(), false)
+	}
+	if onUpdate != nil {
+		c.emitter = newBashUpdateEmitter(onUpdate, currentResult, currentResult())
+		c.output.onWrite = c.emitter.markDirty
+	}
+	return c
+}
+
+// finish stops streaming and returns the result for the complete output.
+func (c *bashOutputCapture) finish(executionTime time.Duration) *BashToolResult {
+	c.completedExecutionTime.Store(int64(executionTime))
+	finalSnapshot := c.output.finish()
+	if c.emitter != nil {
+		c.emitter.stopAndFlush()
+	}
+	return newBashToolResult(c.command, c.workingDir, executionTime, finalSnapshot, true)
+}
+
 type bashOutputSnapshot struct {
 	output         string
 	truncated      bool
 	totalLines     int
 	totalBytes     int64
 	fullOutputPath string
+	binary         *tooltypes.BinaryContent
 }
 
 type bashOutputAccumulator struct {
@@ -490,6 +690,7 @@ type bashOutputAccumulator struct {
 	spillFile      *os.File
 	fullOutputPath string
 	closed         bool
+	withholdBinary bool
 	onWrite        func()
 }
 
@@ -613,7 +814,11 @@ func (a *bashOutputAccumulator) finish() bashOutputSnapshot {
 		}
 	}
 	a.closed = true
-	return a.snapshotLocked()
+	snapshot := a.snapshotLocked()
+	if snapshot.binary != nil {
+		a.discardSpillLocked()
+	}
+	return snapshot
 }
 
 func (a *bashOutputAccumulator) snapshotLocked() bashOutputSnapshot {
@@ -622,6 +827,18 @@ func (a *bashOutputAccumulator) snapshotLocked() bashOutputSnapshot {
 		totalLines++
 	}
 
+	if a.withholdBinary {
+		if kind, binary := osutil.DetectBinary(a.prefix); binary {
+			content := &tooltypes.BinaryContent{Size: a.totalBytes, Type: kind}
+			return bashOutputSnapshot{
+				output:     content.Notice() + "; rerun with raw=true if the raw bytes are genuinely needed",
+				totalLines: totalLines,
+				totalBytes: a.totalBytes,
+				binary:     content,
+			}
+		}
+	}
+
 	snapshot := bashOutputSnapshot{
 		totalLines:     totalLines,
 		totalBytes:     a.totalBytes,
@@ -678,7 +895,7 @@ func trimUTF8ContinuationPrefix(content []byte) []byte {
 func newBashToolResult(command, workingDir string, executionTime time.Duration, snapshot bashOutputSnapshot, fullOutputComplete bool) *BashToolResult {
 	return &BashToolResult{
 		command:            command,
-		combinedOutput:     snapshot.output,
+		combinedOutput:     bashSecrets.Redact(snapshot.output),
 		executionTime:      executionTime,
 		workingDir:         workingDir,
 		outputTruncated:    snapshot.truncated,
@@ -686,6 +903,7 @@ func newBashToolResult(command, workingDir string, executionTime time.Duration,
 		outputTotalBytes:   snapshot.totalBytes,
 		fullOutputPath:     snapshot.fullOutputPath,
 		fullOutputComplete: fullOutputComplete,
+		binary:             snapshot.binary,
 	}
 }
 
@@ -772,6 +990,21 @@ func bashEnvWithPreferredBinDirs() ([]string, error) {
 	return binaries.EnvWithPreferredBinDirs(os.Environ())
 }
 
+// withConversationIDEnv sets ConversationIDEnv to the conversation running
+// the command, replacing any value inherited from a parent kodelet.
+func withConversationIDEnv(env []string, conversationID string) []string {
+	if conversationID == "" {
+		return env
+	}
+	result := make([]string, 0, len(env)+1)
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, ConversationIDEnv+"=") {
+			result = append(result, kv)
+		}
+	}
+	return append(result, ConversationIDEnv+"="+conversationID)
+}
+
 func truncateBashOutputForModel(content string) string {
 	maxBytes := approxBytesForTokens(bashMaxOutputTokens)
 	if len(content) <= maxBytes {
@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jingkaihe/kodelet/pkg/audit"
+	"github.com/jingkaihe/kodelet/pkg/secrets"
+)
+
+// bashSecrets resolves the secret:// values of tool_env for every bash tool
+// in the process, so each secret is fetched once and redacted from the output
+// of all commands.
+var bashSecrets = secrets.NewResolver()
+
+// toolEnv returns the tool_env variables as NAME=value entries, resolving
+// secrets on behalf of the conversation running the command.
+func (b *BashTool) toolEnv(ctx context.Context) ([]string, error) {
+	if len(b.env) == 0 {
+		return nil, nil
+	}
+	return bashSecrets.Env(ctx, b.env, audit.SecretSource{
+		Tool:           "bash",
+		ConversationID: ToolContextFromContext(ctx).ConversationID,
+	})
+}
+
+func envNames(env []string) []string {
+	names := make([]string, 0, len(env))
+	for _, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		names = append(names, name)
+	}
+	return names
+}
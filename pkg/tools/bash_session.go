@@ -0,0 +1,294 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// bashSessionMetadataKey stores the persisted bash session state in
+	// conversation metadata.
+	bashSessionMetadataKey  = "bash_session"
+	bashSessionStateVersion = 1
+
+	// bashSessionIdleTimeout is how long an unused session shell is kept
+	// running. Reaped sessions are restarted from their persisted state.
+	bashSessionIdleTimeout = 30 * time.Minute
+	// bashSessionMaxPersistedValueBytes bounds the size of each environment
+	// variable copied into the conversation record.
+	bashSessionMaxPersistedValueBytes = 4096
+)
+
+var (
+	errBashSessionUnsupported = errors.New("persistent bash sessions are not supported on this platform")
+
+	// bashSessionIgnoredEnv lists variables maintained by bash itself, which
+	// are never captured as session state.
+	bashSessionIgnoredEnv = []string{"_", "PWD", "OLDPWD", "SHLVL"}
+
+	// bashSessionSensitiveEnv matches variable names that are kept in the
+	// running session but never persisted into the conversation record.
+	bashSessionSensitiveEnv = regexp.MustCompile(`(?i)(TOKEN|SECRET|PASSWORD|PASSWD|CREDENTIAL|PRIVATE|API_?KEY|ACCESS_?KEY|AUTH)`)
+
+	bashSessionTimesPattern = regexp.MustCompile(`(\d+)m([\d.]+)s\s+(\d+)m([\d.]+)s`)
+
+	bashSessions = newBashSessionManager()
+)
+
+// bashSessionState is the restorable state of a conversation's shell: its
+// working directory and the environment changes made by earlier commands.
+type bashSessionState struct {
+	Version    int               `json:"version"`
+	WorkingDir string            `json:"workingDir,omitempty"`
+	Env        map[string]string `json:"env,omitempty"`
+	Unset      []string          `json:"unset,omitempty"`
+}
+
+// bashSessionStateFromMetadata decodes the persisted session state, which is
+// a struct in memory and a generic map once loaded from the store.
+func bashSessionStateFromMetadata(metadata map[string]any) (bashSessionState, bool) {
+	raw, ok := metadata[bashSessionMetadataKey]
+	if !ok || raw == nil {
+		return bashSessionState{}, false
+	}
+	if state, ok := raw.(bashSessionState); ok {
+		return state, true
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return bashSessionState{}, false
+	}
+	var state bashSessionState
+	if err := json.Unmarshal(data, &state); err != nil || state.Version != bashSessionStateVersion {
+		return bashSessionState{}, false
+	}
+	return state, true
+}
+
+// persistable returns a copy of the state that is safe to store in the
+// conversation record: sensitive and oversized variables are dropped.
+func (s bashSessionState) persistable() bashSessionState {
+	persisted := bashSessionState{
+		Version:    bashSessionStateVersion,
+		WorkingDir: s.WorkingDir,
+		Unset:      slices.Clone(s.Unset),
+	}
+	for name, value := range s.Env {
+		if bashSessionSensitiveEnv.MatchString(name) || len(value) > bashSessionMaxPersistedValueBytes {
+			continue
+		}
+		if persisted.Env == nil {
+			persisted.Env = make(map[string]string)
+		}
+		persisted.Env[name] = value
+	}
+	return persisted
+}
+
+// environ applies the state's environment changes to base.
+func (s bashSessionState) environ(base []string) []string {
+	env := make([]string, 0, len(base)+len(s.Env))
+	for _, entry := range base {
+		name, _, _ := strings.Cut(entry, "=")
+		if _, overridden := s.Env[name]; overridden || slices.Contains(s.Unset, name) {
+			continue
+		}
+		env = append(env, entry)
+	}
+	names := make([]string, 0, len(s.Env))
+	for name := range s.Env {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		env = append(env, name+"="+s.Env[name])
+	}
+	return env
+}
+
+// parseBashSessionState builds the state from the NUL-separated working
+// directory and `env -0` output captured after a command, relative to the
+// environment the session shell was started with.
+func parseBashSessionState(data []byte, base []string) (bashSessionState, error) {
+	fields := bytes.Split(data, []byte{0})
+	if len(fields) == 0 || len(fields[0]) == 0 {
+		return bashSessionState{}, errors.New("bash session state is empty")
+	}
+
+	state := bashSessionState{
+		Version:    bashSessionStateVersion,
+		WorkingDir: string(fields[0]),
+	}
+	current := make(map[string]string, len(fields))
+	for _, field := range fields[1:] {
+		name, value, ok := strings.Cut(string(field), "=")
+		if !ok || name == "" || slices.Contains(bashSessionIgnoredEnv, name) {
+			continue
+		}
+		current[name] = value
+	}
+
+	baseValues := make(map[string]string, len(base))
+	for _, entry := range base {
+		name, value, _ := strings.Cut(entry, "=")
+		if slices.Contains(bashSessionIgnoredEnv, name) {
+			continue
+		}
+		baseValues[name] = value
+		if _, ok := current[name]; !ok {
+			state.Unset = append(state.Unset, name)
+		}
+	}
+	for name, value := range current {
+		if baseValue, ok := baseValues[name]; ok && baseValue == value {
+			continue
+		}
+		if state.Env == nil {
+			state.Env = make(map[string]string)
+		}
+		state.Env[name] = value
+	}
+	slices.Sort(state.Unset)
+	return state, nil
+}
+
+// parseBashTimes returns the cumulative user and system CPU time of the
+// shell's children from the output of the `times` builtin.
+func parseBashTimes(data []byte) (time.Duration, time.Duration) {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) < 2 {
+		return 0, 0
+	}
+	match := bashSessionTimesPattern.FindStringSubmatch(lines[1])
+	if match == nil {
+		return 0, 0
+	}
+	return parseBashDuration(match[1], match[2]), parseBashDuration(match[3], match[4])
+}
+
+func parseBashDuration(minutes, seconds string) time.Duration {
+	m, _ := strconv.Atoi(minutes)
+	s, _ := strconv.ParseFloat(seconds, 64)
+	return time.Duration(m)*time.Minute + time.Duration(s*float64(time.Second))
+}
+
+// bashSessionBaseEnv is the environment a session shell starts from before
+// persisted changes are applied. Pagers and colors are disabled because the
+// session's output is a terminal.
+func bashSessionBaseEnv(conversationID string) []string {
+	env, err := bashEnvWithPreferredBinDirs()
+	if err != nil {
+		env = os.Environ()
+	}
+	return append(withConversationIDEnv(env, conversationID), "TERM=dumb", "PAGER=cat", "GIT_PAGER=cat")
+}
+
+// bashRunResult describes a command run in a session.
+type bashRunResult struct {
+	exitCode      int
+	workingDir    string
+	userCPUTime   time.Duration
+	systemCPUTime time.Duration
+	// backgroundProcesses is the number of jobs the command left running in
+	// the background.
+	backgroundProcesses int
+	// sessionExited is set when the command ended the shell, for example
+	// with exit.
+	sessionExited bool
+}
+
+// bashSessionManager keeps one session shell per conversation.
+type bashSessionManager struct {
+	mu       sync.Mutex
+	sessions map[string]*bashSession
+	reaping  bool
+}
+
+func newBashSessionManager() *bashSessionManager {
+	return &bashSessionManager{sessions: make(map[string]*bashSession)}
+}
+
+// get returns the running session for key, starting one from state when
+// there is none or the previous shell has exited. A new shell also gets env,
+// which is not part of the state persisted with the conversation.
+func (m *bashSessionManager) get(key, defaultWorkingDir string, state bashSessionState, env []string) (*bashSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session, ok := m.sessions[key]; ok {
+		if session.alive() {
+			return session, nil
+		}
+		state = session.lastState()
+		delete(m.sessions, key)
+	}
+
+	workingDir := state.WorkingDir
+	if info, err := os.Stat(workingDir); workingDir == "" || err != nil || !info.IsDir() {
+		workingDir = defaultWorkingDir
+	}
+	session, err := startBashSession(key, workingDir, state, env)
+	if err != nil {
+		return nil, err
+	}
+	m.sessions[key] = session
+	if !m.reaping {
+		m.reaping = true
+		go m.reapIdle()
+	}
+	return session, nil
+}
+
+// close stops the session for key, if any.
+func (m *bashSessionManager) close(key string) {
+	m.mu.Lock()
+	session, ok := m.sessions[key]
+	delete(m.sessions, key)
+	m.mu.Unlock()
+	if ok {
+		session.close()
+	}
+}
+
+func (m *bashSessionManager) reapIdle() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.mu.Lock()
+		for key, session := range m.sessions {
+			if !session.alive() || session.idleFor() > bashSessionIdleTimeout {
+				delete(m.sessions, key)
+				go session.close()
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+// CloseBashSession stops the persistent bash session of a conversation. Its
+// state stays in the conversation record, so a later command restores it.
+func CloseBashSession(conversationID string) {
+	bashSessions.close(conversationID)
+}
+
+// sessionKeyFromContext returns the conversation whose session a bash call
+// runs in, or false when the call has no conversation and must run in a
+// fresh shell.
+func sessionKeyFromContext(ctx context.Context) (string, MetadataStore, bool) {
+	toolContext := toolContextFromContext(ctx)
+	if toolContext.ConversationID == "" {
+		return "", nil, false
+	}
+	return toolContext.ConversationID, toolContext.MetadataStore, true
+}
@@ -0,0 +1,393 @@
+//go:build unix
+
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/pkg/errors"
+	"golang.org/x/term"
+
+	"github.com/jingkaihe/kodelet/pkg/osutil"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// bashSessionExitGrace is how long output of a command that ended the shell
+// is still collected after the shell exits.
+const bashSessionExitGrace = 200 * time.Millisecond
+
+// bashSession is a long-lived bash process whose output is a PTY. Commands are
+// fed to it one at a time over stdin, each followed by a control script that
+// captures the shell's state and prints a completion marker.
+type bashSession struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	ptmx    *os.File
+	dir     string
+	marker  []byte
+	nonce   string
+	baseEnv []string
+
+	// sem serializes commands; it is acquired with the caller's context so a
+	// queued call still honours its timeout.
+	sem chan struct{}
+
+	mu        sync.Mutex
+	state     bashSessionState
+	output    io.Writer
+	pending   []byte
+	statusCh  chan int
+	lastUsed  time.Time
+	userCPU   time.Duration
+	systemCPU time.Duration
+	// jobs holds the background jobs already counted as tool processes.
+	jobs map[string]struct{}
+	// recordUsage receives the CPU time of background jobs reaped after the
+	// last command, when the session is closed.
+	recordUsage func(tooltypes.ResourceUsage)
+	exitCode    int
+	exited      chan struct{}
+	readerDone  chan struct{}
+	closeOnce   sync.Once
+}
+
+func startBashSession(conversationID, workingDir string, state bashSessionState, env []string) (*bashSession, error) {
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open pty")
+	}
+	// Raw mode keeps the terminal from echoing input or rewriting newlines.
+	if _, err := term.MakeRaw(int(tty.Fd())); err != nil {
+		ptmx.Close()
+		tty.Close()
+		return nil, errors.Wrap(err, "failed to configure pty")
+	}
+	_ = pty.Setsize(ptmx, &pty.Winsize{Rows: 50, Cols: 200})
+
+	dir, err := os.MkdirTemp("", "kodelet-bash-session-*")
+	if err != nil {
+		ptmx.Close()
+		tty.Close()
+		return nil, errors.Wrap(err, "failed to create bash session directory")
+	}
+	nonceBytes := make([]byte, 8)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		ptmx.Close()
+		tty.Close()
+		os.RemoveAll(dir)
+		return nil, errors.Wrap(err, "failed to generate bash session marker")
+	}
+	nonce := hex.EncodeToString(nonceBytes)
+
+	baseEnv := append(bashSessionBaseEnv(conversationID), env...)
+	cmd := exec.Command("bash", "--noprofile", "--norc")
+	cmd.Dir = workingDir
+	cmd.Env = state.environ(baseEnv)
+	cmd.Stdout = tty
+	cmd.Stderr = tty
+	osutil.SetProcessGroup(cmd)
+	stdin, err := cmd.StdinPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	tty.Close()
+	if err != nil {
+		ptmx.Close()
+		os.RemoveAll(dir)
+		return nil, errors.Wrap(err, "failed to start bash session")
+	}
+
+	state.Version = bashSessionStateVersion
+	state.WorkingDir = workingDir
+	s := &bashSession{
+		cmd:        cmd,
+		stdin:      stdin,
+		ptmx:       ptmx,
+		dir:        dir,
+		marker:     []byte("\x1e__KODELET_DONE_" + nonce + "__"),
+		nonce:      nonce,
+		baseEnv:    baseEnv,
+		sem:        make(chan struct{}, 1),
+		state:      state,
+		statusCh:   make(chan int, 1),
+		lastUsed:   time.Now(),
+		jobs:       make(map[string]struct{}),
+		exited:     make(chan struct{}),
+		readerDone: make(chan struct{}),
+	}
+	go s.readOutput()
+	go s.wait()
+	return s, nil
+}
+
+func (s *bashSession) wait() {
+	_ = s.cmd.Wait()
+	s.mu.Lock()
+	s.exitCode = s.cmd.ProcessState.ExitCode()
+	s.mu.Unlock()
+	close(s.exited)
+}
+
+func (s *bashSession) readOutput() {
+	defer close(s.readerDone)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := s.ptmx.Read(buf)
+		if n > 0 {
+			s.consume(buf[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// consume forwards output to the running command and detects completion
+// markers. A partial marker at the end of a chunk is held back until the
+// next read.
+func (s *bashSession) consume(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = append(s.pending, data...)
+	for {
+		idx := bytes.Index(s.pending, s.marker)
+		if idx < 0 {
+			keep := partialMarkerSuffix(s.pending, s.marker)
+			s.writeLocked(s.pending[:len(s.pending)-keep])
+			s.pending = append(s.pending[:0:0], s.pending[len(s.pending)-keep:]...)
+			return
+		}
+
+		s.writeLocked(s.pending[:idx])
+		rest := s.pending[idx+len(s.marker):]
+		end := bytes.IndexByte(rest, '\x1e')
+		if end < 0 {
+			s.pending = append(s.pending[:0:0], s.pending[idx:]...)
+			return
+		}
+		status, err := strconv.Atoi(string(rest[:end]))
+		if err != nil {
+			status = 1
+		}
+		s.pending = append(s.pending[:0:0], rest[end+1:]...)
+		// Output after the marker belongs to background jobs and is dropped.
+		s.output = nil
+		select {
+		case s.statusCh <- status:
+		default:
+		}
+	}
+}
+
+func (s *bashSession) writeLocked(p []byte) {
+	if len(p) > 0 && s.output != nil {
+		_, _ = s.output.Write(p)
+	}
+}
+
+// partialMarkerSuffix returns the length of the longest suffix of data that
+// is a prefix of marker.
+func partialMarkerSuffix(data, marker []byte) int {
+	maxLen := min(len(data), len(marker)-1)
+	for n := maxLen; n > 0; n-- {
+		if bytes.HasSuffix(data, marker[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// run executes command in the session, writing its combined output to w.
+// When ctx ends first the session is killed; the manager restarts it from
+// its last state on the next call.
+func (s *bashSession) run(ctx context.Context, command string, w io.Writer) (bashRunResult, error) {
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		return bashRunResult{}, ctx.Err()
+	}
+	defer func() { <-s.sem }()
+
+	s.touch()
+	defer s.touch()
+	if !s.alive() {
+		return bashRunResult{}, errors.New("bash session has exited")
+	}
+
+	commandPath := filepath.Join(s.dir, "command.sh")
+	timesPath := filepath.Join(s.dir, "times")
+	jobsPath := filepath.Join(s.dir, "jobs")
+	statePath := filepath.Join(s.dir, "state")
+	if err := os.WriteFile(commandPath, []byte(command+"\n"), 0o600); err != nil {
+		return bashRunResult{}, errors.Wrap(err, "failed to write bash session command")
+	}
+
+	s.mu.Lock()
+	s.output = w
+	s.pending = nil
+	select {
+	case <-s.statusCh:
+	default:
+	}
+	workingDir := s.state.WorkingDir
+	s.mu.Unlock()
+
+	script := fmt.Sprintf(`builtin source %s </dev/null
+__kodelet_status=$?
+builtin times >%s 2>/dev/null
+builtin jobs -pr >%s 2>/dev/null
+{ builtin printf '%%s\0' "$PWD"; command env -0; } >%s 2>/dev/null
+builtin printf '\036__KODELET_DONE_%s__%%d\036' "$__kodelet_status"
+`, shellQuote(commandPath), shellQuote(timesPath), shellQuote(jobsPath), shellQuote(statePath), s.nonce)
+	if _, err := io.WriteString(s.stdin, script); err != nil {
+		return bashRunResult{}, errors.Wrap(err, "failed to send command to bash session")
+	}
+
+	select {
+	case status := <-s.statusCh:
+		return s.finishRun(status, statePath, timesPath, jobsPath), nil
+	case <-s.exited:
+		select {
+		case <-s.readerDone:
+		case <-time.After(bashSessionExitGrace):
+		}
+		s.mu.Lock()
+		s.output = nil
+		exitCode := s.exitCode
+		s.mu.Unlock()
+		s.close()
+		return bashRunResult{exitCode: exitCode, workingDir: workingDir, sessionExited: true}, nil
+	case <-ctx.Done():
+		s.close()
+		return bashRunResult{workingDir: workingDir}, ctx.Err()
+	}
+}
+
+// finishRun records the state the control script captured after a command.
+func (s *bashSession) finishRun(status int, statePath, timesPath, jobsPath string) bashRunResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if data, err := os.ReadFile(statePath); err == nil {
+		if state, err := parseBashSessionState(data, s.baseEnv); err == nil {
+			s.state = state
+		}
+	}
+	result := bashRunResult{exitCode: status, workingDir: s.state.WorkingDir}
+	if data, err := os.ReadFile(timesPath); err == nil {
+		userCPU, systemCPU := parseBashTimes(data)
+		result.userCPUTime = max(userCPU-s.userCPU, 0)
+		result.systemCPUTime = max(systemCPU-s.systemCPU, 0)
+		s.userCPU, s.systemCPU = userCPU, systemCPU
+	}
+	if data, err := os.ReadFile(jobsPath); err == nil {
+		for _, pid := range strings.Fields(string(data)) {
+			if _, seen := s.jobs[pid]; !seen {
+				s.jobs[pid] = struct{}{}
+				result.backgroundProcesses++
+			}
+		}
+	}
+	return result
+}
+
+// setUsageRecorder sets where the CPU time of background jobs that finish
+// after the last command is recorded when the session closes.
+func (s *bashSession) setUsageRecorder(record func(tooltypes.ResourceUsage)) {
+	s.mu.Lock()
+	s.recordUsage = record
+	s.mu.Unlock()
+}
+
+// recordRemainingUsage records the CPU time the shell used since the last
+// command finished, which includes background jobs it reaped in the meantime.
+func (s *bashSession) recordRemainingUsage() {
+	s.mu.Lock()
+	record := s.recordUsage
+	processState := s.cmd.ProcessState
+	userCPU, systemCPU := s.userCPU, s.systemCPU
+	s.mu.Unlock()
+	if record == nil || processState == nil {
+		return
+	}
+	usage := tooltypes.ResourceUsage{
+		UserCPUTime:     max(processState.UserTime()-userCPU, 0),
+		SystemCPUTime:   max(processState.SystemTime()-systemCPU, 0),
+		PeakMemoryBytes: osutil.PeakMemoryBytes(processState),
+	}
+	if usage.CPUTime() > 0 {
+		record(usage)
+	}
+}
+
+func (s *bashSession) alive() bool {
+	select {
+	case <-s.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+func (s *bashSession) lastState() bashSessionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+func (s *bashSession) touch() {
+	s.mu.Lock()
+	s.lastUsed = time.Now()
+	s.mu.Unlock()
+}
+
+// idleFor reports how long the session has been unused; a session running a
+// command is never idle.
+func (s *bashSession) idleFor() time.Duration {
+	if len(s.sem) > 0 {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Since(s.lastUsed)
+}
+
+// close terminates the shell and everything it started, giving processes
+// the same grace period as a timed out stateless command.
+func (s *bashSession) close() {
+	s.closeOnce.Do(func() {
+		pgid := -s.cmd.Process.Pid
+		if s.alive() {
+			_ = syscall.Kill(pgid, syscall.SIGTERM)
+			select {
+			case <-s.exited:
+			case <-time.After(osutil.GracefulShutdownDelay):
+			}
+		}
+		_ = syscall.Kill(pgid, syscall.SIGKILL)
+		_ = s.stdin.Close()
+		<-s.exited
+		s.recordRemainingUsage()
+		_ = s.ptmx.Close()
+		_ = os.RemoveAll(s.dir)
+	})
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
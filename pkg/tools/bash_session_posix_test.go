@@ -0,0 +1,191 @@
+//go:build unix
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+func newBashSessionTest(t *testing.T) (context.Context, *testMetadataStore, tooltypes.State) {
+	t.Helper()
+	conversationID := "bash-session-" + t.Name()
+	store := &testMetadataStore{}
+	ctx := ContextWithToolContext(context.Background(), ToolContext{ConversationID: conversationID, MetadataStore: store})
+	t.Cleanup(func() { CloseBashSession(conversationID) })
+	return ctx, store, NewBasicState(context.Background(), WithWorkingDirectory(t.TempDir()))
+}
+
+func runBashInSession(t *testing.T, ctx context.Context, state tooltypes.State, input BashInput) tooltypes.ToolResult {
+	t.Helper()
+	if input.Description == "" {
+		input.Description = "test"
+	}
+	if input.Timeout == 0 {
+		input.Timeout = 10
+	}
+	params, err := json.Marshal(input)
+	require.NoError(t, err)
+	return NewBashTool(nil, false).Execute(ctx, state, string(params))
+}
+
+func TestBashToolSessionPersistsDirectoryAndEnvironment(t *testing.T) {
+	ctx, store, state := newBashSessionTest(t)
+	subdir := filepath.Join(state.WorkingDirectory(), "sub")
+	require.NoError(t, os.Mkdir(subdir, 0o755))
+
+	result := runBashInSession(t, ctx, state, BashInput{Command: "cd sub && export GREETING=hello && export API_TOKEN=secret"})
+	require.False(t, result.IsError(), result.GetError())
+
+	result = runBashInSession(t, ctx, state, BashInput{Command: `echo "$PWD $GREETING $API_TOKEN"`})
+	require.False(t, result.IsError(), result.GetError())
+	assert.Equal(t, subdir+" hello secret\n", result.GetResult())
+	metadata := result.StructuredData().Metadata.(*tooltypes.BashMetadata)
+	assert.Equal(t, subdir, metadata.WorkingDir)
+
+	persisted, ok := bashSessionStateFromMetadata(store.GetMetadata())
+	require.True(t, ok)
+	assert.Equal(t, subdir, persisted.WorkingDir)
+	assert.Equal(t, "hello", persisted.Env["GREETING"])
+	assert.NotContains(t, persisted.Env, "API_TOKEN")
+
+	result = runBashInSession(t, ctx, state, BashInput{Command: "false"})
+	assert.Equal(t, "Command exited with status 1", result.GetError())
+}
+
+func TestBashToolInjectsToolEnvSecrets(t *testing.T) {
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "vault"), []byte("#!/bin/sh\necho vault-s3cr3t-$4\n"), 0o755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	ctx, store, state := newBashSessionTest(t)
+	env := map[string]string{"DEPLOY_TOKEN": "secret://vault/ci/" + t.Name() + "#token", "REGION": "eu-west-1"}
+	params := `{"command":"echo \"$DEPLOY_TOKEN $REGION\"","description":"test","timeout":10}`
+
+	session := NewBashTool(nil, false)
+	session.env = env
+	result := session.Execute(ctx, state, params)
+	require.False(t, result.IsError(), result.GetError())
+	assert.Equal(t, "[redacted: DEPLOY_TOKEN] eu-west-1\n", result.GetResult())
+	persisted, _ := bashSessionStateFromMetadata(store.GetMetadata())
+	assert.NotContains(t, persisted.Env, "DEPLOY_TOKEN", "tool_env is not persisted with the conversation")
+
+	stateless := NewStatelessBashTool(nil, false, 0)
+	stateless.env = env
+	result = stateless.Execute(ctx, state, params)
+	require.False(t, result.IsError(), result.GetError())
+	assert.Equal(t, "[redacted: DEPLOY_TOKEN] eu-west-1\n", result.GetResult())
+
+	stateless.env = map[string]string{"DEPLOY_TOKEN": "secret://aws/missing-" + t.Name()}
+	t.Setenv("PATH", binDir)
+	result = stateless.Execute(ctx, state, params)
+	assert.Contains(t, result.GetError(), "failed to resolve secret for DEPLOY_TOKEN")
+}
+
+func TestBashToolSessionExportsConversationID(t *testing.T) {
+	ctx, store, state := newBashSessionTest(t)
+
+	result := runBashInSession(t, ctx, state, BashInput{Command: "echo $" + ConversationIDEnv})
+	require.False(t, result.IsError(), result.GetError())
+	assert.Equal(t, toolContextFromContext(ctx).ConversationID+"\n", result.GetResult())
+
+	persisted, ok := bashSessionStateFromMetadata(store.GetMetadata())
+	require.True(t, ok)
+	assert.NotContains(t, persisted.Env, ConversationIDEnv)
+}
+
+func TestBashToolSessionRestoresFromConversationRecord(t *testing.T) {
+	ctx, _, state := newBashSessionTest(t)
+	subdir := filepath.Join(state.WorkingDirectory(), "sub")
+	require.NoError(t, os.Mkdir(subdir, 0o755))
+
+	result := runBashInSession(t, ctx, state, BashInput{Command: "cd sub; export GREETING=hello; unset HOME"})
+	require.False(t, result.IsError(), result.GetError())
+
+	// A new process resuming the conversation has no running shell.
+	CloseBashSession(toolContextFromContext(ctx).ConversationID)
+
+	result = runBashInSession(t, ctx, state, BashInput{Command: `echo "$PWD $GREETING ${HOME:-unset}"`})
+	require.False(t, result.IsError(), result.GetError())
+	assert.Equal(t, subdir+" hello unset\n", result.GetResult())
+}
+
+func TestBashToolSessionReset(t *testing.T) {
+	ctx, store, state := newBashSessionTest(t)
+
+	result := runBashInSession(t, ctx, state, BashInput{Command: "export GREETING=hello && cd /"})
+	require.False(t, result.IsError(), result.GetError())
+
+	result = runBashInSession(t, ctx, state, BashInput{Command: `echo "$PWD ${GREETING:-unset}"`, Reset: true})
+	require.False(t, result.IsError(), result.GetError())
+	assert.Equal(t, state.WorkingDirectory()+" unset\n", result.GetResult())
+
+	persisted, ok := bashSessionStateFromMetadata(store.GetMetadata())
+	require.True(t, ok)
+	assert.Empty(t, persisted.Env)
+}
+
+func TestBashToolSessionTimeoutRestartsFromLastState(t *testing.T) {
+	ctx, _, state := newBashSessionTest(t)
+
+	result := runBashInSession(t, ctx, state, BashInput{Command: "export GREETING=hello"})
+	require.False(t, result.IsError(), result.GetError())
+
+	result = runBashInSession(t, ctx, state, BashInput{Command: "echo started; sleep 30", Timeout: 10})
+	assert.Contains(t, result.GetError(), "Command timed out after 10 seconds")
+	assert.Equal(t, "started\n", result.GetResult())
+
+	result = runBashInSession(t, ctx, state, BashInput{Command: `echo "$GREETING"`})
+	require.False(t, result.IsError(), result.GetError())
+	assert.Equal(t, "hello\n", result.GetResult())
+}
+
+func TestBashToolSessionExit(t *testing.T) {
+	ctx, _, state := newBashSessionTest(t)
+
+	result := runBashInSession(t, ctx, state, BashInput{Command: "export GREETING=hello"})
+	require.False(t, result.IsError(), result.GetError())
+
+	result = runBashInSession(t, ctx, state, BashInput{Command: "export GREETING=lost; echo bye; exit 3"})
+	assert.Contains(t, result.GetError(), "exited the shell session with status 3")
+	assert.Equal(t, "bye\n", result.GetResult())
+
+	result = runBashInSession(t, ctx, state, BashInput{Command: `echo "$GREETING"`})
+	require.False(t, result.IsError(), result.GetError())
+	assert.Equal(t, "hello\n", result.GetResult())
+}
+
+func TestStatelessBashToolRunsInFreshShell(t *testing.T) {
+	ctx, store, state := newBashSessionTest(t)
+	tool := NewStatelessBashTool(nil, false, 0)
+
+	params := `{"command": "export GREETING=hello", "description": "test", "timeout": 10}`
+	require.False(t, tool.Execute(ctx, state, params).IsError())
+	result := tool.Execute(ctx, state, `{"command": "echo ${GREETING:-unset}", "description": "test", "timeout": 10}`)
+	assert.Equal(t, "unset\n", result.GetResult())
+	assert.Empty(t, store.GetMetadata())
+
+	assert.ErrorContains(t, tool.ValidateInput(state, `{"command": "cd /tmp", "description": "test", "timeout": 10}`), "command is banned: cd")
+	assert.NoError(t, NewBashTool(nil, false).ValidateInput(state, `{"command": "cd /tmp", "description": "test", "timeout": 10}`))
+}
+
+func TestBashToolSessionCountsBackgroundJobs(t *testing.T) {
+	ctx, _, state := newBashSessionTest(t)
+
+	result := runBashInSession(t, ctx, state, BashInput{Command: "sleep 30 & sleep 30 &"})
+	require.False(t, result.IsError(), result.GetError())
+	result = runBashInSession(t, ctx, state, BashInput{Command: "true"})
+	require.False(t, result.IsError(), result.GetError())
+
+	// Each command counts as one process, and the two jobs left running are
+	// counted once.
+	assert.Equal(t, 4, state.(*BasicState).ResourceUsage().Processes)
+}
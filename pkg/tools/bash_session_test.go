@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBashSessionStateDiffsAgainstBase(t *testing.T) {
+	base := []string{"HOME=/home/me", "PATH=/usr/bin", "EDITOR=vi", "SHLVL=1"}
+	captured := strings.Join([]string{
+		"/repo",
+		"HOME=/home/me",
+		"PATH=/opt/bin:/usr/bin",
+		"FOO=bar=baz",
+		"SHLVL=2",
+		"_=/usr/bin/env",
+	}, "\x00") + "\x00"
+
+	state, err := parseBashSessionState([]byte(captured), base)
+	require.NoError(t, err)
+	assert.Equal(t, bashSessionState{
+		Version:    bashSessionStateVersion,
+		WorkingDir: "/repo",
+		Env:        map[string]string{"PATH": "/opt/bin:/usr/bin", "FOO": "bar=baz"},
+		Unset:      []string{"EDITOR"},
+	}, state)
+
+	assert.Equal(t, []string{"HOME=/home/me", "SHLVL=1", "FOO=bar=baz", "PATH=/opt/bin:/usr/bin"}, state.environ(base))
+
+	_, err = parseBashSessionState(nil, base)
+	assert.Error(t, err)
+}
+
+func TestBashSessionStatePersistableDropsSensitiveValues(t *testing.T) {
+	state := bashSessionState{
+		WorkingDir: "/repo",
+		Env: map[string]string{
+			"GOFLAGS":           "-mod=mod",
+			"GITHUB_TOKEN":      "ghp_secret",
+			"OPENAI_API_KEY":    "sk-secret",
+			"DB_PASSWORD":       "hunter2",
+			"AWS_ACCESS_KEY_ID": "AKIA",
+			"LARGE":             strings.Repeat("x", bashSessionMaxPersistedValueBytes+1),
+		},
+		Unset: []string{"EDITOR"},
+	}
+
+	persisted := state.persistable()
+	assert.Equal(t, bashSessionStateVersion, persisted.Version)
+	assert.Equal(t, map[string]string{"GOFLAGS": "-mod=mod"}, persisted.Env)
+	assert.Equal(t, []string{"EDITOR"}, persisted.Unset)
+	assert.Len(t, state.Env, 6, "the running session keeps every variable")
+}
+
+func TestBashSessionStateFromMetadata(t *testing.T) {
+	state := bashSessionState{Version: bashSessionStateVersion, WorkingDir: "/repo", Env: map[string]string{"FOO": "bar"}}
+
+	restored, ok := bashSessionStateFromMetadata(map[string]any{bashSessionMetadataKey: state})
+	require.True(t, ok)
+	assert.Equal(t, state, restored)
+
+	// Loaded conversation records hold the state as a generic map.
+	data, err := json.Marshal(map[string]any{bashSessionMetadataKey: state})
+	require.NoError(t, err)
+	var metadata map[string]any
+	require.NoError(t, json.Unmarshal(data, &metadata))
+	restored, ok = bashSessionStateFromMetadata(metadata)
+	require.True(t, ok)
+	assert.Equal(t, state, restored)
+
+	_, ok = bashSessionStateFromMetadata(map[string]any{bashSessionMetadataKey: map[string]any{"version": 99}})
+	assert.False(t, ok, "unknown versions are ignored")
+	_, ok = bashSessionStateFromMetadata(map[string]any{bashSessionMetadataKey: nil})
+	assert.False(t, ok)
+}
+
+func TestParseBashTimes(t *testing.T) {
+	userCPU, systemCPU := parseBashTimes([]byte("0m0.010s 0m0.005s\n1m2.500s 0m0.250s\n"))
+	assert.Equal(t, time.Minute+2500*time.Millisecond, userCPU)
+	assert.Equal(t, 250*time.Millisecond, systemCPU)
+
+	userCPU, systemCPU = parseBashTimes([]byte("garbage"))
+	assert.Zero(t, userCPU)
+	assert.Zero(t, systemCPU)
+}
+
+func TestPartialMarkerSuffix(t *testing.T) {
+	marker := []byte("\x1e__DONE__")
+	assert.Equal(t, 0, partialMarkerSuffix([]byte("output"), marker))
+	assert.Equal(t, 3, partialMarkerSuffix([]byte("output\x1e__"), marker))
+	assert.Equal(t, 1, partialMarkerSuffix([]byte("\x1e"), marker))
+}
@@ -0,0 +1,33 @@
+//go:build windows
+
+package tools
+
+import (
+	"context"
+	"io"
+	"time"
+
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// bashSession is unavailable on Windows, where the bash tool always runs
+// commands in a fresh shell.
+type bashSession struct{}
+
+func startBashSession(string, string, bashSessionState, []string) (*bashSession, error) {
+	return nil, errBashSessionUnsupported
+}
+
+func (s *bashSession) run(context.Context, string, io.Writer) (bashRunResult, error) {
+	return bashRunResult{}, errBashSessionUnsupported
+}
+
+func (s *bashSession) setUsageRecorder(func(tooltypes.ResourceUsage)) {}
+
+func (s *bashSession) alive() bool { return false }
+
+func (s *bashSession) lastState() bashSessionState { return bashSessionState{} }
+
+func (s *bashSession) idleFor() time.Duration { return 0 }
+
+func (s *bashSession) close() {}
@@ -74,7 +74,12 @@ func TestBashTool_Description_BannedCommands(t *testing.T) {
 	assert.Contains(t, desc, "- view")
 	assert.Contains(t, desc, "- less")
 	assert.Contains(t, desc, "- more")
-	assert.Contains(t, desc, "- cd")
+	assert.NotContains(t, desc, "- cd\n", "cd persists in the session shell")
+
+	desc = NewStatelessBashTool([]string{}, false, 0).Description()
+	assert.Contains(t, desc, "Run a bash command in a fresh shell.")
+	assert.Contains(t, desc, "- cd\n")
+	assert.Contains(t, desc, "Avoid direct cd")
 
 	// Should NOT contain allowed commands section
 	assert.NotContains(t, desc, "Allowed command patterns:")
@@ -171,6 +176,28 @@ func TestBashTool_Execute_Success(t *testing.T) {
 	assert.Equal(t, "hello world\n", result.GetResult())
 }
 
+func TestBashTool_Execute_WithholdsBinaryOutput(t *testing.T) {
+	tool := NewBashTool(nil, false)
+	input := BashInput{
+		Description: "Print an ELF header",
+		Command:     `printf '\177ELF\002\001\001\000\000\000'`,
+		Timeout:     10,
+	}
+	params, _ := json.Marshal(input)
+
+	result := tool.Execute(context.Background(), NewBasicState(context.TODO()), string(params))
+	assert.False(t, result.IsError())
+	assert.Contains(t, result.AssistantFacing(), "binary content withheld (10 bytes, type: ELF)")
+	metadata := result.StructuredData().Metadata.(*tooltypes.BashMetadata)
+	assert.Equal(t, &tooltypes.BinaryContent{Size: 10, Type: "ELF"}, metadata.Binary)
+
+	input.Raw = true
+	params, _ = json.Marshal(input)
+	result = tool.Execute(context.Background(), NewBasicState(context.TODO()), string(params))
+	assert.Equal(t, "\x7fELF\x02\x01\x01\x00\x00\x00", result.GetResult())
+	assert.Nil(t, result.StructuredData().Metadata.(*tooltypes.BashMetadata).Binary)
+}
+
 func TestBashTool_Execute_Timeout(t *testing.T) {
 	tool := NewBashTool(nil, false)
 	input := BashInput{
@@ -723,6 +750,7 @@ func TestBashTool_ValidateInput_AllowedCommands(t *testing.T) {
 	tests := []struct {
 		name            string
 		allowedCommands []string
+		stateless       bool
 		input           BashInput
 		expectError     bool
 		errorMsg        string
@@ -960,6 +988,7 @@ func TestBashTool_ValidateInput_AllowedCommands(t *testing.T) {
 		},
 		{
 			name:            "banned command denied even when in allowed list - cd",
+			stateless:       true,
 			allowedCommands: []string{"cd *", "echo *", "pwd"},
 			input: BashInput{
 				Description: "change directory",
@@ -1015,6 +1044,7 @@ func TestBashTool_ValidateInput_AllowedCommands(t *testing.T) {
 		},
 		{
 			name:            "banned command mixed with allowed commands - first command banned",
+			stateless:       true,
 			allowedCommands: []string{"ls *", "cd *", "pwd"},
 			input: BashInput{
 				Description: "change dir then list",
@@ -1035,6 +1065,16 @@ func TestBashTool_ValidateInput_AllowedCommands(t *testing.T) {
 			expectError: true,
 			errorMsg:    "command is banned: vim",
 		},
+		{
+			name:            "cd allowed in a persistent session",
+			allowedCommands: []string{"cd *", "ls *"},
+			input: BashInput{
+				Description: "change dir then list",
+				Command:     "cd /tmp && ls -la",
+				Timeout:     10,
+			},
+			expectError: false,
+		},
 		{
 			name:            "work around to banned command - with parenthesis", // this is OK by design
 			allowedCommands: []string{"(cd *", "ls *", "pwd"},
@@ -1050,6 +1090,9 @@ func TestBashTool_ValidateInput_AllowedCommands(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tool := NewBashTool(tt.allowedCommands, false)
+			if tt.stateless {
+				tool = NewStatelessBashTool(tt.allowedCommands, false, 0)
+			}
 			input, _ := json.Marshal(tt.input)
 			err := tool.ValidateInput(NewBasicState(context.TODO()), string(input))
 
@@ -1187,3 +1230,9 @@ func TestBashEnvWithPreferredBinDirs(t *testing.T) {
 	assert.Equal(t, binaries.GetLibexecBinDir(), parts[0])
 	assert.Equal(t, filepath.Join(homeDir, ".kodelet", "bin"), parts[1])
 }
+
+func TestWithConversationIDEnv(t *testing.T) {
+	env := withConversationIDEnv([]string{"PATH=/bin", ConversationIDEnv + "=parent"}, "child")
+	assert.Equal(t, []string{"PATH=/bin", ConversationIDEnv + "=child"}, env)
+	assert.Equal(t, []string{"PATH=/bin"}, withConversationIDEnv([]string{"PATH=/bin"}, ""))
+}
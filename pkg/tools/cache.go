@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/toolcache"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// workspaceIndependentTools are the cacheable tools whose results do not
+// depend on the working directory, so they are shared across workspaces and
+// revisions.
+var workspaceIndependentTools = map[string]bool{
+	"web_fetch": true,
+	"web_crawl": true,
+}
+
+// toolCacheCall is a call of a cacheable tool, looked up before the tool
+// runs and stored after it succeeds.
+type toolCacheCall struct {
+	store    *toolcache.Store
+	key      string
+	toolName string
+	ttl      time.Duration
+}
+
+// newToolCacheCall returns the cache call of toolName with parameters, or
+// nil when tool_cache is off, the tool is not cached, or the result depends
+// on files the workspace revision does not cover: a workspace outside git,
+// paths outside the repository or ignored by git, or a search that includes
+// ignored files.
+func newToolCacheCall(ctx context.Context, state tooltypes.State, toolName, parameters string) *toolCacheCall {
+	config, _ := state.GetLLMConfig().(llmtypes.Config)
+	if config.ToolCache == nil || !config.ToolCache.Enabled || !config.ToolCache.Caches(toolName) {
+		return nil
+	}
+
+	scope := ""
+	if !workspaceIndependentTools[toolName] {
+		workingDir := state.WorkingDirectory()
+		root, revision := toolcache.WorkspaceRevision(ctx, workingDir)
+		if revision == "" || !coveredByRevision(ctx, root, workingDir, parameters) {
+			return nil
+		}
+		scope = workingDir + "@" + revision
+	}
+
+	dir, err := toolcache.DefaultDir()
+	if err != nil {
+		logger.G(ctx).WithError(err).Debug("tool cache is unavailable")
+		return nil
+	}
+	key, err := toolcache.Key(toolName, parameters, scope)
+	if err != nil {
+		logger.G(ctx).WithError(err).Debug("failed to compute tool cache key")
+		return nil
+	}
+	return &toolCacheCall{
+		store:    toolcache.NewStore(dir, config.ToolCache.CacheMaxBytes()),
+		key:      key,
+		toolName: toolName,
+		ttl:      config.ToolCache.CacheTTL(),
+	}
+}
+
+// coveredByRevision reports whether the files a call with parameters reads
+// are all part of the revision of the repository at root. It looks at the
+// path parameters of the built-in tools, resolved against workingDir.
+func coveredByRevision(ctx context.Context, root, workingDir, parameters string) bool {
+	var input struct {
+		Path            string `json:"path"`
+		FilePath        string `json:"file_path"`
+		IgnoreGitignore bool   `json:"ignore_gitignore"`
+	}
+	if err := json.Unmarshal([]byte(parameters), &input); err != nil {
+		return false
+	}
+	if input.IgnoreGitignore {
+		return false
+	}
+	for _, path := range []string{input.Path, input.FilePath} {
+		if path == "" {
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workingDir, path)
+		}
+		if !toolcache.CoveredByRevision(ctx, root, filepath.Clean(path)) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup returns the cached result of the call, if any.
+func (c *toolCacheCall) lookup(ctx context.Context) (tooltypes.ToolResult, bool) {
+	if c == nil {
+		return nil, false
+	}
+	entry, ok := c.store.Get(c.key, time.Now())
+	if !ok {
+		return nil, false
+	}
+	logger.G(ctx).WithField("tool", c.toolName).Debug("reusing cached tool result")
+	return entry.Result(), true
+}
+
+// save caches result. Failed calls and image results are not cached.
+func (c *toolCacheCall) save(ctx context.Context, result tooltypes.ToolResult) {
+	if c == nil || result == nil || result.IsError() {
+		return
+	}
+	if _, ok := result.(tooltypes.MultiModalToolResult); ok {
+		return
+	}
+	if err := c.store.Put(c.key, toolcache.NewEntry(c.toolName, result, time.Now(), c.ttl)); err != nil {
+		logger.G(ctx).WithError(err).WithField("tool", c.toolName).Warn("failed to cache tool result")
+	}
+}
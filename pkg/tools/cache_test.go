@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func initCacheTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644))
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	return dir
+}
+
+func TestRunToolReusesCachedResults(t *testing.T) {
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	workspace := initCacheTestRepo(t)
+	tool := &testTool{name: "search_tool", result: tooltypes.BaseToolResult{Result: "main.go:1"}}
+	state := NewBasicState(context.Background(),
+		WithExtensionTools([]tooltypes.Tool{tool}),
+		WithWorkingDirectory(workspace),
+		WithLLMConfig(llmtypes.Config{ToolCache: &llmtypes.ToolCacheConfig{Enabled: true, Tools: []string{"search_tool"}}}),
+	)
+
+	first := RunTool(context.Background(), state, "search_tool", `{"pattern": "main", "path": "."}`)
+	require.True(t, tool.executed)
+	assert.Equal(t, "main.go:1", first.GetResult())
+
+	tool.executed = false
+	second := RunTool(context.Background(), state, "search_tool", `{"path":".","pattern":"main"}`)
+	assert.False(t, tool.executed, "the same input in another key order is answered from the cache")
+	assert.Equal(t, first.AssistantFacing(), second.AssistantFacing())
+
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644))
+	RunTool(context.Background(), state, "search_tool", `{"pattern": "main", "path": "."}`)
+	assert.True(t, tool.executed, "changing the workspace invalidates the result")
+}
+
+func TestRunToolSkipsCacheWhenNotApplicable(t *testing.T) {
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	workspace := initCacheTestRepo(t)
+
+	t.Run("failed calls", func(t *testing.T) {
+		tool := &testTool{name: "search_tool", result: tooltypes.BaseToolResult{Error: "boom"}}
+		state := NewBasicState(context.Background(),
+			WithExtensionTools([]tooltypes.Tool{tool}),
+			WithWorkingDirectory(workspace),
+			WithLLMConfig(llmtypes.Config{ToolCache: &llmtypes.ToolCacheConfig{Enabled: true, Tools: []string{"search_tool"}}}),
+		)
+		RunTool(context.Background(), state, "search_tool", `{}`)
+		tool.executed = false
+		RunTool(context.Background(), state, "search_tool", `{}`)
+		assert.True(t, tool.executed)
+	})
+
+	t.Run("tools not listed", func(t *testing.T) {
+		tool := &testTool{name: "other_tool"}
+		state := NewBasicState(context.Background(),
+			WithExtensionTools([]tooltypes.Tool{tool}),
+			WithWorkingDirectory(workspace),
+			WithLLMConfig(llmtypes.Config{ToolCache: &llmtypes.ToolCacheConfig{Enabled: true}}),
+		)
+		RunTool(context.Background(), state, "other_tool", `{}`)
+		tool.executed = false
+		RunTool(context.Background(), state, "other_tool", `{}`)
+		assert.True(t, tool.executed)
+	})
+
+	t.Run("files the revision does not cover", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(workspace, ".gitignore"), []byte("build/\n"), 0o644))
+		require.NoError(t, os.MkdirAll(filepath.Join(workspace, "build"), 0o755))
+		tool := &testTool{name: "search_tool"}
+		state := NewBasicState(context.Background(),
+			WithExtensionTools([]tooltypes.Tool{tool}),
+			WithWorkingDirectory(workspace),
+			WithLLMConfig(llmtypes.Config{ToolCache: &llmtypes.ToolCacheConfig{Enabled: true, Tools: []string{"search_tool"}}}),
+		)
+		for _, parameters := range []string{
+			`{"path": "` + t.TempDir() + `"}`,
+			`{"path": "../"}`,
+			`{"path": "build"}`,
+			`{"file_path": "` + filepath.Join(workspace, "build", "out.txt") + `"}`,
+			`{"path": ".", "ignore_gitignore": true}`,
+		} {
+			RunTool(context.Background(), state, "search_tool", parameters)
+			tool.executed = false
+			RunTool(context.Background(), state, "search_tool", parameters)
+			assert.True(t, tool.executed, parameters)
+		}
+
+		RunTool(context.Background(), state, "search_tool", `{"path": "main.go"}`)
+		tool.executed = false
+		RunTool(context.Background(), state, "search_tool", `{"path": "main.go"}`)
+		assert.False(t, tool.executed, "paths inside the repository are still cached")
+	})
+
+	t.Run("workspaces outside git", func(t *testing.T) {
+		tool := &testTool{name: "search_tool"}
+		state := NewBasicState(context.Background(),
+			WithExtensionTools([]tooltypes.Tool{tool}),
+			WithWorkingDirectory(t.TempDir()),
+			WithLLMConfig(llmtypes.Config{ToolCache: &llmtypes.ToolCacheConfig{Enabled: true, Tools: []string{"search_tool"}}}),
+		)
+		RunTool(context.Background(), state, "search_tool", `{}`)
+		tool.executed = false
+		RunTool(context.Background(), state, "search_tool", `{}`)
+		assert.True(t, tool.executed)
+	})
+}
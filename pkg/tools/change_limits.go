@@ -0,0 +1,237 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jingkaihe/kodelet/pkg/osutil"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/pkg/errors"
+)
+
+// ChangeApprover asks the user whether a run may continue past its configured
+// change limits. It returns true when the user approves.
+type ChangeApprover func(ctx context.Context, summary string) (bool, error)
+
+type changeApproverKey struct{}
+
+// ContextWithChangeApprover attaches a change-limit approver to the tool execution context.
+func ContextWithChangeApprover(ctx context.Context, approver ChangeApprover) context.Context {
+	if approver == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, changeApproverKey{}, approver)
+}
+
+func changeApproverFromContext(ctx context.Context) ChangeApprover {
+	approver, _ := ctx.Value(changeApproverKey{}).(ChangeApprover)
+	return approver
+}
+
+// fileChange describes a pending modification to a single file.
+type fileChange struct {
+	Path  string
+	Lines int
+}
+
+// fileChangeGuard is implemented by states that enforce run-level change limits.
+type fileChangeGuard interface {
+	ReserveFileChanges(ctx context.Context, changes []fileChange) (func(), error)
+}
+
+// changeTracker accumulates the files and lines modified during a run.
+type changeTracker struct {
+	mu       sync.Mutex
+	files    map[string]int
+	lines    int
+	approved bool
+
+	// promptMu serialises approval prompts so concurrent writes past the
+	// limit ask the user once.
+	promptMu sync.Mutex
+}
+
+func newChangeTracker() *changeTracker {
+	return &changeTracker{files: make(map[string]int)}
+}
+
+// ReserveFileChanges records the pending changes against the configured limits.
+// When the limits would be exceeded, the run asks the change approver in ctx
+// for permission; without approval the changes are rejected and not recorded.
+// The returned function releases the reservation, for when the change could
+// not be written.
+func (s *BasicState) ReserveFileChanges(ctx context.Context, changes []fileChange) (func(), error) {
+	s.mu.Lock()
+	limits := s.llmConfig.Limits
+	if s.changes == nil {
+		s.changes = newChangeTracker()
+	}
+	tracker := s.changes
+	s.mu.Unlock()
+	if limits == nil || (limits.MaxFilesChanged <= 0 && limits.MaxLinesChanged <= 0) {
+		return func() {}, nil
+	}
+	return tracker.reserve(ctx, *limits, changes)
+}
+
+// reserve records changes once they fit the limits or the user has approved
+// exceeding them. The approver is asked without holding t.mu, so other tools
+// are not blocked while the user decides.
+func (t *changeTracker) reserve(ctx context.Context, limits llmtypes.LimitsConfig, changes []fileChange) (func(), error) {
+	for {
+		t.mu.Lock()
+		summary := t.exceeded(limits, changes)
+		if summary == "" {
+			release := t.record(changes)
+			t.mu.Unlock()
+			return release, nil
+		}
+		t.mu.Unlock()
+
+		if err := t.approve(ctx, summary); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// exceeded describes the limits that changes would exceed, or returns an empty
+// string when they fit or the user has already approved exceeding them.
+// t.mu must be held.
+func (t *changeTracker) exceeded(limits llmtypes.LimitsConfig, changes []fileChange) string {
+	if t.approved {
+		return ""
+	}
+
+	files := len(t.files)
+	seen := make(map[string]struct{}, len(changes))
+	for _, change := range changes {
+		path := osutil.CanonicalizePath(change.Path)
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+		if t.files[path] == 0 {
+			files++
+		}
+	}
+	lines := t.lines
+	for _, change := range changes {
+		lines += change.Lines
+	}
+
+	var exceeded []string
+	if limits.MaxFilesChanged > 0 && files > limits.MaxFilesChanged {
+		exceeded = append(exceeded, fmt.Sprintf("%d files changed (limit %d)", files, limits.MaxFilesChanged))
+	}
+	if limits.MaxLinesChanged > 0 && lines > limits.MaxLinesChanged {
+		exceeded = append(exceeded, fmt.Sprintf("%d lines changed (limit %d)", lines, limits.MaxLinesChanged))
+	}
+	if len(exceeded) == 0 {
+		return ""
+	}
+	return "This change would bring the run to " + strings.Join(exceeded, " and ")
+}
+
+// approve asks the change approver in ctx to lift the limits for the rest of
+// the run.
+func (t *changeTracker) approve(ctx context.Context, summary string) error {
+	approver := changeApproverFromContext(ctx)
+	if approver == nil {
+		return errors.Errorf("%s. The change was not applied; stop and ask the user to approve, or re-run with --allow-exceed-limits", summary)
+	}
+
+	t.promptMu.Lock()
+	defer t.promptMu.Unlock()
+
+	t.mu.Lock()
+	approved := t.approved
+	t.mu.Unlock()
+	if approved {
+		return nil
+	}
+
+	approved, err := approver(ctx, summary)
+	if err != nil {
+		return errors.Wrap(err, "failed to request approval for exceeding change limits")
+	}
+	if !approved {
+		return errors.Errorf("%s. The user declined to exceed the limit; the change was not applied", summary)
+	}
+
+	t.mu.Lock()
+	t.approved = true
+	t.mu.Unlock()
+	return nil
+}
+
+// record adds changes to the totals and returns a function that takes them
+// back out. t.mu must be held.
+func (t *changeTracker) record(changes []fileChange) func() {
+	paths := make([]string, 0, len(changes))
+	lines := 0
+	for _, change := range changes {
+		path := osutil.CanonicalizePath(change.Path)
+		t.files[path]++
+		paths = append(paths, path)
+		lines += change.Lines
+	}
+	t.lines += lines
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			for _, path := range paths {
+				if t.files[path]--; t.files[path] <= 0 {
+					delete(t.files, path)
+				}
+			}
+			t.lines -= lines
+		})
+	}
+}
+
+// reserveFileChanges checks pending changes against the state's change limits,
+// if the state enforces any. The returned function releases the reservation
+// and must be called when the changes are not written.
+func reserveFileChanges(ctx context.Context, state tooltypes.State, changes ...fileChange) (func(), error) {
+	guard, ok := state.(fileChangeGuard)
+	if !ok {
+		return func() {}, nil
+	}
+	return guard.ReserveFileChanges(ctx, changes)
+}
+
+// changedLineCount returns the number of added and removed lines between two versions of a file.
+func changedLineCount(oldContent, newContent string) int {
+	count := 0
+	for _, line := range strings.Split(applyPatchUnifiedDiff("a", "b", oldContent, newContent), "\n") {
+		if strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "---") {
+			continue
+		}
+		if strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
+			count++
+		}
+	}
+	return count
+}
+
+// plannedPatchChanges lists the file changes of the reviewed hunks an
+// apply_patch call will write.
+func plannedPatchChanges(plans []plannedHunk) []fileChange {
+	changes := make([]fileChange, 0, len(plans))
+	for _, plan := range plans {
+		if plan.skip {
+			continue
+		}
+		changes = append(changes, fileChange{Path: plan.hunk.path, Lines: changedLineCount(plan.oldContent, plan.newContent)})
+		if plan.hunk.movePath != "" && plan.hunk.movePath != plan.hunk.path {
+			changes = append(changes, fileChange{Path: plan.hunk.movePath})
+		}
+	}
+	return changes
+}
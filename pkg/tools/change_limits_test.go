@@ -0,0 +1,241 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLimitedState(t *testing.T, limits *llmtypes.LimitsConfig) *BasicState {
+	t.Helper()
+	return NewBasicState(context.Background(), WithLLMConfig(llmtypes.Config{Limits: limits}))
+}
+
+func writeFileInput(t *testing.T, path, text string) string {
+	t.Helper()
+	payload, err := json.Marshal(FileWriteInput{FilePath: path, Text: text})
+	require.NoError(t, err)
+	return string(payload)
+}
+
+func TestChangedLineCount(t *testing.T) {
+	assert.Equal(t, 0, changedLineCount("a\nb\n", "a\nb\n"))
+	assert.Equal(t, 2, changedLineCount("", "a\nb\n"))
+	assert.Equal(t, 2, changedLineCount("a\nb\n", "a\nc\n"))
+	assert.Equal(t, 2, changedLineCount("a\nb\n", ""))
+}
+
+func TestFileWriteRejectsChangesBeyondFileLimit(t *testing.T) {
+	dir := t.TempDir()
+	state := newLimitedState(t, &llmtypes.LimitsConfig{MaxFilesChanged: 1})
+	tool := &FileWriteTool{}
+
+	first := filepath.Join(dir, "first.txt")
+	result := tool.Execute(context.Background(), state, writeFileInput(t, first, "one\n"))
+	require.False(t, result.IsError(), result.GetError())
+
+	// Rewriting an already-counted file stays within the limit.
+	result = tool.Execute(context.Background(), state, writeFileInput(t, first, "two\n"))
+	require.False(t, result.IsError(), result.GetError())
+
+	second := filepath.Join(dir, "second.txt")
+	result = tool.Execute(context.Background(), state, writeFileInput(t, second, "three\n"))
+	require.True(t, result.IsError())
+	assert.Contains(t, result.GetError(), "2 files changed (limit 1)")
+	assert.Contains(t, result.GetError(), "--allow-exceed-limits")
+	assert.NoFileExists(t, second)
+}
+
+func TestFileWriteRejectsChangesBeyondLineLimit(t *testing.T) {
+	dir := t.TempDir()
+	state := newLimitedState(t, &llmtypes.LimitsConfig{MaxLinesChanged: 2})
+
+	path := filepath.Join(dir, "file.txt")
+	result := (&FileWriteTool{}).Execute(context.Background(), state, writeFileInput(t, path, "a\nb\nc\n"))
+	require.True(t, result.IsError())
+	assert.Contains(t, result.GetError(), "3 lines changed (limit 2)")
+	assert.NoFileExists(t, path)
+}
+
+func TestChangeLimitApprovalLiftsLimitsForRun(t *testing.T) {
+	dir := t.TempDir()
+	state := newLimitedState(t, &llmtypes.LimitsConfig{MaxFilesChanged: 1})
+	calls := 0
+	ctx := ContextWithChangeApprover(context.Background(), func(_ context.Context, summary string) (bool, error) {
+		calls++
+		assert.Contains(t, summary, "2 files changed (limit 1)")
+		return true, nil
+	})
+	tool := &FileWriteTool{}
+
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		result := tool.Execute(ctx, state, writeFileInput(t, filepath.Join(dir, name), "x\n"))
+		require.False(t, result.IsError(), result.GetError())
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func TestChangeLimitDeclinedApprovalRejectsChange(t *testing.T) {
+	dir := t.TempDir()
+	state := newLimitedState(t, &llmtypes.LimitsConfig{MaxFilesChanged: 1})
+	ctx := ContextWithChangeApprover(context.Background(), func(context.Context, string) (bool, error) {
+		return false, nil
+	})
+	tool := &FileWriteTool{}
+
+	require.False(t, tool.Execute(ctx, state, writeFileInput(t, filepath.Join(dir, "a.txt"), "x\n")).IsError())
+	result := tool.Execute(ctx, state, writeFileInput(t, filepath.Join(dir, "b.txt"), "x\n"))
+	require.True(t, result.IsError())
+	assert.Contains(t, result.GetError(), "declined")
+}
+
+func TestApplyPatchRespectsChangeLimits(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.txt")
+	require.NoError(t, os.WriteFile(existing, []byte("keep\nold\n"), 0o644))
+	state := NewBasicState(context.Background(), WithLLMConfig(llmtypes.Config{
+		WorkingDirectory: dir,
+		Limits:           &llmtypes.LimitsConfig{MaxFilesChanged: 1},
+	}))
+
+	patch := "*** Begin Patch\n" +
+		"*** Update File: existing.txt\n" +
+		"@@\n" +
+		" keep\n" +
+		"-old\n" +
+		"+new\n" +
+		"*** Add File: added.txt\n" +
+		"+hello\n" +
+		"*** End Patch"
+	payload, err := json.Marshal(ApplyPatchInput{Input: patch})
+	require.NoError(t, err)
+
+	result := (&ApplyPatchTool{}).Execute(context.Background(), state, string(payload))
+	require.True(t, result.IsError())
+	assert.Contains(t, result.GetError(), "2 files changed (limit 1)")
+
+	content, err := os.ReadFile(existing)
+	require.NoError(t, err)
+	assert.Equal(t, "keep\nold\n", string(content))
+	assert.NoFileExists(t, filepath.Join(dir, "added.txt"))
+}
+
+func TestApplyPatchRejectedHunksDoNotCountTowardsLimits(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.txt")
+	require.NoError(t, os.WriteFile(existing, []byte("keep\nold\n"), 0o644))
+	state := NewBasicState(context.Background(), WithLLMConfig(llmtypes.Config{
+		WorkingDirectory: dir,
+		Limits:           &llmtypes.LimitsConfig{MaxFilesChanged: 1},
+	}))
+
+	patch := "*** Begin Patch\n" +
+		"*** Update File: existing.txt\n" +
+		"@@\n" +
+		" keep\n" +
+		"-old\n" +
+		"+new\n" +
+		"*** Add File: added.txt\n" +
+		"+hello\n" +
+		"*** End Patch"
+	payload, err := json.Marshal(ApplyPatchInput{Input: patch})
+	require.NoError(t, err)
+
+	ctx := ContextWithEditReviewer(context.Background(), func(_ context.Context, review EditReview) ([]bool, error) {
+		return []bool{filepath.Base(review.Path) == "added.txt"}, nil
+	})
+	result := (&ApplyPatchTool{}).Execute(ctx, state, string(payload))
+	require.False(t, result.IsError(), result.GetError())
+	assert.FileExists(t, filepath.Join(dir, "added.txt"))
+
+	content, err := os.ReadFile(existing)
+	require.NoError(t, err)
+	assert.Equal(t, "keep\nold\n", string(content))
+}
+
+func TestApplyPatchAppliesSeveralHunksToOneFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(path, []byte("a\nb\nc\n"), 0o644))
+	state := NewBasicState(context.Background(), WithLLMConfig(llmtypes.Config{
+		WorkingDirectory: dir,
+		Limits:           &llmtypes.LimitsConfig{MaxFilesChanged: 1},
+	}))
+
+	patch := "*** Begin Patch\n" +
+		"*** Update File: notes.txt\n" +
+		"@@\n" +
+		"-a\n" +
+		"+A\n" +
+		"*** Update File: notes.txt\n" +
+		"@@\n" +
+		"-c\n" +
+		"+C\n" +
+		"*** End Patch"
+	payload, err := json.Marshal(ApplyPatchInput{Input: patch})
+	require.NoError(t, err)
+
+	result := (&ApplyPatchTool{}).Execute(context.Background(), state, string(payload))
+	require.False(t, result.IsError(), result.GetError())
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "A\nb\nC\n", string(content))
+}
+
+func TestChangeLimitsDisabledWithoutConfig(t *testing.T) {
+	dir := t.TempDir()
+	state := newLimitedState(t, nil)
+	tool := &FileWriteTool{}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		result := tool.Execute(context.Background(), state, writeFileInput(t, filepath.Join(dir, name), "x\n"))
+		require.False(t, result.IsError(), result.GetError())
+	}
+}
+
+func TestChangeLimitFailedWriteReleasesReservation(t *testing.T) {
+	dir := t.TempDir()
+	state := newLimitedState(t, &llmtypes.LimitsConfig{MaxFilesChanged: 1})
+	tool := &FileWriteTool{}
+
+	// The parent directory does not exist, so the write fails.
+	result := tool.Execute(context.Background(), state, writeFileInput(t, filepath.Join(dir, "missing", "a.txt"), "x\n"))
+	require.True(t, result.IsError())
+
+	result = tool.Execute(context.Background(), state, writeFileInput(t, filepath.Join(dir, "b.txt"), "x\n"))
+	require.False(t, result.IsError(), result.GetError())
+}
+
+func TestChangeLimitApprovalDoesNotBlockOtherReservations(t *testing.T) {
+	tracker := newChangeTracker()
+	limits := llmtypes.LimitsConfig{MaxFilesChanged: 1}
+	prompted := make(chan struct{})
+	decide := make(chan bool)
+	ctx := ContextWithChangeApprover(context.Background(), func(context.Context, string) (bool, error) {
+		close(prompted)
+		return <-decide, nil
+	})
+
+	_, err := tracker.reserve(ctx, limits, []fileChange{{Path: "/tmp/a"}})
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := tracker.reserve(ctx, limits, []fileChange{{Path: "/tmp/b"}})
+		done <- err
+	}()
+	<-prompted
+
+	// A change to an already-counted file fits the limit and must not wait
+	// for the pending prompt.
+	_, err = tracker.reserve(ctx, limits, []fileChange{{Path: "/tmp/a", Lines: 1}})
+	require.NoError(t, err)
+
+	decide <- true
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/jingkaihe/kodelet/pkg/checkpoints"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// WithCheckpoints returns an option that snapshots files into stores before
+// the file tools modify them, such as the store of a run and the store of its
+// conversation.
+func WithCheckpoints(stores ...*checkpoints.Store) BasicStateOption {
+	return func(_ context.Context, s *BasicState) error {
+		for _, store := range stores {
+			if store != nil {
+				s.checkpoints = append(s.checkpoints, store)
+			}
+		}
+		return nil
+	}
+}
+
+// CheckpointFiles snapshots paths before tool modifies them. A failed
+// snapshot is logged rather than blocking the change.
+func (s *BasicState) CheckpointFiles(ctx context.Context, tool string, paths []string) {
+	s.mu.RLock()
+	stores := s.checkpoints
+	s.mu.RUnlock()
+	for _, store := range stores {
+		if err := store.Snapshot(tool, paths); err != nil {
+			logger.G(ctx).WithError(err).WithField("tool", tool).Warn("failed to checkpoint files before modifying them")
+		}
+	}
+}
+
+// CheckpointCommand captures the git working tree containing dir before tool
+// runs a command that may change any file in it. The returned function
+// snapshots the files the command changed and must be called once it ends.
+// Outside a git working tree, or when bash checkpoints are disabled, nothing
+// is recorded.
+func (s *BasicState) CheckpointCommand(ctx context.Context, tool, dir string) func() {
+	s.mu.RLock()
+	stores := s.checkpoints
+	enabled := s.llmConfig.BashCheckpoint()
+	s.mu.RUnlock()
+	if len(stores) == 0 || !enabled {
+		return func() {}
+	}
+
+	workspace, err := checkpoints.CaptureWorkspace(ctx, dir)
+	if err != nil {
+		logger.G(ctx).WithError(err).WithField("tool", tool).Warn("failed to checkpoint the workspace before running a command")
+		return func() {}
+	}
+	if workspace == nil {
+		return func() {}
+	}
+	return func() {
+		changes, err := workspace.Changes(ctx)
+		if err == nil {
+			for _, store := range stores {
+				if err = store.SnapshotContent(tool, changes); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			logger.G(ctx).WithError(err).WithField("tool", tool).Warn("failed to checkpoint files changed by a command")
+		}
+	}
+}
+
+// fileCheckpointer is implemented by states that snapshot files for undo.
+type fileCheckpointer interface {
+	CheckpointFiles(ctx context.Context, tool string, paths []string)
+}
+
+// checkpointFiles snapshots paths before tool modifies them, if the state
+// keeps checkpoints.
+func checkpointFiles(ctx context.Context, state tooltypes.State, tool string, paths ...string) {
+	if checkpointer, ok := state.(fileCheckpointer); ok {
+		checkpointer.CheckpointFiles(ctx, tool, paths)
+	}
+}
+
+// commandCheckpointer is implemented by states that snapshot the files
+// commands change for undo.
+type commandCheckpointer interface {
+	CheckpointCommand(ctx context.Context, tool, dir string) func()
+}
+
+// checkpointCommand captures the workspace before tool runs a command in dir,
+// if the state keeps checkpoints, and returns the function that records the
+// changed files once the command ends.
+func checkpointCommand(ctx context.Context, state tooltypes.State, tool, dir string) func() {
+	if checkpointer, ok := state.(commandCheckpointer); ok {
+		return checkpointer.CheckpointCommand(ctx, tool, dir)
+	}
+	return func() {}
+}
+
+// patchHunkPaths lists the files a patch modifies, including move targets.
+func patchHunkPaths(hunks []parsedHunk) []string {
+	paths := make([]string, 0, len(hunks))
+	for _, hunk := range hunks {
+		paths = append(paths, hunk.path)
+		if hunk.movePath != "" {
+			paths = append(paths, hunk.movePath)
+		}
+	}
+	return paths
+}
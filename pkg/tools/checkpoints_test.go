@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/checkpoints"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileWriteCheckpointsBeforeWriting(t *testing.T) {
+	dir := t.TempDir()
+	store, err := checkpoints.Open(t.TempDir(), "run-1")
+	require.NoError(t, err)
+	state := NewBasicState(context.Background(), WithCheckpoints(store))
+
+	path := filepath.Join(dir, "file.txt")
+	require.NoError(t, os.WriteFile(path, []byte("before\n"), 0o644))
+
+	result := (&FileWriteTool{}).Execute(context.Background(), state, writeFileInput(t, path, "after\n"))
+	require.False(t, result.IsError(), result.GetError())
+
+	steps := store.Steps()
+	require.Len(t, steps, 1)
+	assert.Equal(t, "file_write", steps[0].Tool)
+	require.Len(t, steps[0].Files, 1)
+	assert.True(t, steps[0].Files[0].Existed)
+
+	_, err = store.Restore(checkpoints.RestoreOptions{})
+	require.NoError(t, err)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "before\n", string(content))
+}
+
+func TestBashCheckpointsChangedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := t.TempDir()
+	path := filepath.Join(repo, "file.txt")
+	require.NoError(t, os.WriteFile(path, []byte("before\n"), 0o644))
+	gitInit := exec.Command("git", "init", "-q")
+	gitInit.Dir = repo
+	require.NoError(t, gitInit.Run())
+
+	params, err := json.Marshal(BashInput{
+		Description: "Change a file",
+		Command:     "echo after > file.txt && echo new > created.txt",
+		Timeout:     10,
+	})
+	require.NoError(t, err)
+
+	disabled := false
+	for _, tc := range []struct {
+		name   string
+		config llmtypes.Config
+		steps  int
+	}{
+		{name: "disabled", config: llmtypes.Config{Bash: &llmtypes.BashConfig{Checkpoint: &disabled}}, steps: 0},
+		{name: "enabled", steps: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(path, []byte("before\n"), 0o644))
+			require.NoError(t, os.RemoveAll(filepath.Join(repo, "created.txt")))
+			store, err := checkpoints.Open(t.TempDir(), "conversation-1")
+			require.NoError(t, err)
+			state := NewBasicState(context.Background(), WithCheckpoints(store), WithWorkingDirectory(repo), WithLLMConfig(tc.config))
+
+			result := (&BashTool{stateless: true}).Execute(context.Background(), state, string(params))
+			require.False(t, result.IsError(), result.GetError())
+			require.Len(t, store.Steps(), tc.steps)
+			if tc.steps == 0 {
+				return
+			}
+			assert.Equal(t, "bash", store.Steps()[0].Tool)
+
+			_, err = store.Restore(checkpoints.RestoreOptions{})
+			require.NoError(t, err)
+			content, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, "before\n", string(content))
+			assert.NoFileExists(t, filepath.Join(repo, "created.txt"))
+		})
+	}
+}
+
+func TestPatchHunkPaths(t *testing.T) {
+	hunks := []parsedHunk{
+		{path: "/repo/a.go"},
+		{path: "/repo/b.go", movePath: "/repo/c.go"},
+	}
+	assert.Equal(t, []string{"/repo/a.go", "/repo/b.go", "/repo/c.go"}, patchHunkPaths(hunks))
+}
@@ -0,0 +1,26 @@
+package tools
+
+import (
+	"context"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/devcontainer"
+)
+
+// WithDevContainer returns an option that runs the bash tool inside
+// container instead of on the host.
+func WithDevContainer(container *devcontainer.Container) BasicStateOption {
+	return func(_ context.Context, s *BasicState) error {
+		s.devContainer = container
+		return nil
+	}
+}
+
+// NewDevContainerBashTool creates a BashTool that runs every command with
+// docker exec in container. Each call starts a fresh shell, because the
+// persistent session runs on the host.
+func NewDevContainerBashTool(container *devcontainer.Container, allowedCommands []string, enableFSSearchTools bool, maxTimeout time.Duration) *BashTool {
+	tool := NewStatelessBashTool(allowedCommands, enableFSSearchTools, maxTimeout)
+	tool.devContainer = container
+	return tool
+}
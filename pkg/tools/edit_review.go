@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jingkaihe/kodelet/pkg/diffview"
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// editReviewContextLines is the number of unchanged lines shown around each
+// hunk of a reviewed change.
+const editReviewContextLines = 3
+
+// EditHunk is one contiguous change to a file with its surrounding context.
+type EditHunk struct {
+	Header string
+	Lines  []diffview.Line
+}
+
+// EditReview is a change to a file proposed by a file tool, split into hunks
+// the user accepts or rejects one by one.
+type EditReview struct {
+	ToolName string
+	Path     string
+	Hunks    []EditHunk
+}
+
+// EditReviewer shows a proposed change to the user and returns whether each
+// hunk was accepted, in hunk order.
+type EditReviewer func(ctx context.Context, review EditReview) ([]bool, error)
+
+// EditReviewBroker is implemented by user interfaces that can review file
+// changes hunk by hunk.
+type EditReviewBroker interface {
+	ReviewEdit(ctx context.Context, review EditReview) ([]bool, error)
+}
+
+type editReviewerKey struct{}
+
+// ContextWithEditReviewer attaches an edit reviewer to the tool execution
+// context. File tools then write only the hunks the reviewer accepts.
+func ContextWithEditReviewer(ctx context.Context, reviewer EditReviewer) context.Context {
+	if reviewer == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, editReviewerKey{}, reviewer)
+}
+
+func editReviewerFromContext(ctx context.Context) EditReviewer {
+	reviewer, _ := ctx.Value(editReviewerKey{}).(EditReviewer)
+	return reviewer
+}
+
+// editReviewOutcome records the hunks of a reviewed change the user rejected.
+type editReviewOutcome struct {
+	path     string
+	total    int
+	rejected []EditHunk
+}
+
+// reviewFileChange asks the edit reviewer in ctx, if there is one, to review
+// the change from oldContent to newContent. It returns newContent with the
+// rejected hunks reverted. The outcome is nil when nothing was reviewed.
+func reviewFileChange(ctx context.Context, toolName, path, oldContent, newContent string) (string, *editReviewOutcome, error) {
+	reviewer := editReviewerFromContext(ctx)
+	if reviewer == nil || oldContent == newContent {
+		return newContent, nil, nil
+	}
+
+	oldLines := splitLinesKeepEnds(oldContent)
+	newLines := splitLinesKeepEnds(newContent)
+	groups := difflib.NewMatcher(oldLines, newLines).GetGroupedOpCodes(editReviewContextLines)
+	hunks := make([]EditHunk, len(groups))
+	for i, group := range groups {
+		hunks[i] = newEditHunk(group, oldLines, newLines)
+	}
+
+	accepted, err := reviewer(ctx, EditReview{ToolName: toolName, Path: path, Hunks: hunks})
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "failed to review the change to %s", path)
+	}
+
+	outcome := &editReviewOutcome{path: path, total: len(hunks)}
+	var b strings.Builder
+	next := 0
+	for i, group := range groups {
+		if i >= len(accepted) || !accepted[i] {
+			outcome.rejected = append(outcome.rejected, hunks[i])
+			continue
+		}
+		for _, code := range group {
+			if code.Tag == 'e' {
+				continue
+			}
+			b.WriteString(strings.Join(oldLines[next:code.I1], ""))
+			b.WriteString(strings.Join(newLines[code.J1:code.J2], ""))
+			next = code.I2
+		}
+	}
+	b.WriteString(strings.Join(oldLines[next:], ""))
+	return b.String(), outcome, nil
+}
+
+func newEditHunk(group []difflib.OpCode, oldLines, newLines []string) EditHunk {
+	first, last := group[0], group[len(group)-1]
+	hunk := EditHunk{
+		Header: fmt.Sprintf("@@ -%s +%s @@", formatHunkRange(first.I1, last.I2), formatHunkRange(first.J1, last.J2)),
+	}
+	for _, code := range group {
+		if code.Tag == 'e' {
+			for i := code.I1; i < code.I2; i++ {
+				hunk.Lines = append(hunk.Lines, diffview.Line{
+					Kind:    diffview.LineContext,
+					OldLine: i + 1,
+					NewLine: code.J1 + i - code.I1 + 1,
+					Content: trimLineEnding(oldLines[i]),
+				})
+			}
+			continue
+		}
+		for i := code.I1; i < code.I2; i++ {
+			hunk.Lines = append(hunk.Lines, diffview.Line{Kind: diffview.LineRemoved, OldLine: i + 1, Content: trimLineEnding(oldLines[i])})
+		}
+		for j := code.J1; j < code.J2; j++ {
+			hunk.Lines = append(hunk.Lines, diffview.Line{Kind: diffview.LineAdded, NewLine: j + 1, Content: trimLineEnding(newLines[j])})
+		}
+	}
+	return hunk
+}
+
+// formatHunkRange formats a line range the way unified diff headers do.
+func formatHunkRange(start, stop int) string {
+	length := stop - start
+	switch length {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, length)
+	}
+}
+
+// UnifiedText renders the hunk as a unified diff hunk.
+func (h EditHunk) UnifiedText() string {
+	var b strings.Builder
+	b.WriteString(h.Header)
+	for _, line := range h.Lines {
+		b.WriteString("\n")
+		switch line.Kind {
+		case diffview.LineAdded:
+			b.WriteString("+")
+		case diffview.LineRemoved:
+			b.WriteString("-")
+		default:
+			b.WriteString(" ")
+		}
+		b.WriteString(line.Content)
+	}
+	return b.String()
+}
+
+func (o *editReviewOutcome) allRejected() bool {
+	return o != nil && o.total > 0 && len(o.rejected) == o.total
+}
+
+// feedback tells the model which hunks the user rejected, or returns an empty
+// string when every hunk was accepted.
+func (o *editReviewOutcome) feedback() string {
+	if o == nil || len(o.rejected) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	if o.allRejected() {
+		fmt.Fprintf(&b, "The user reviewed the change to %s and rejected it; the file was not modified.", o.path)
+	} else {
+		fmt.Fprintf(&b, "The user reviewed the change to %s and rejected %d of %d hunks. Only the accepted hunks were written; re-read the file before editing it again.", o.path, len(o.rejected), o.total)
+	}
+	b.WriteString("\n")
+	for _, hunk := range o.rejected {
+		fmt.Fprintf(&b, "\n<rejected_hunk>\n%s\n</rejected_hunk>\n", hunk.UnifiedText())
+	}
+	b.WriteString("\nDo not reapply the rejected hunks unchanged. If the change is still needed, ask the user how to proceed or propose a different one.")
+	return b.String()
+}
+
+func splitLinesKeepEnds(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func trimLineEnding(line string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+}
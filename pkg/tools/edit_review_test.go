@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/diffview"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reviewerWith returns a context whose edit reviewer answers with decisions
+// and records the reviews it was shown.
+func reviewerWith(decisions []bool, reviews *[]EditReview) context.Context {
+	return ContextWithEditReviewer(context.Background(), func(_ context.Context, review EditReview) ([]bool, error) {
+		*reviews = append(*reviews, review)
+		return decisions, nil
+	})
+}
+
+func numberedLines(count int) string {
+	var b strings.Builder
+	for i := 1; i <= count; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	return b.String()
+}
+
+func TestReviewFileChangeAppliesOnlyAcceptedHunks(t *testing.T) {
+	oldContent := numberedLines(20)
+	newContent := strings.Replace(oldContent, "line 1\n", "line ONE\n", 1)
+	newContent = strings.Replace(newContent, "line 20\n", "line TWENTY\nline extra\n", 1)
+
+	var reviews []EditReview
+	content, outcome, err := reviewFileChange(reviewerWith([]bool{true, false}, &reviews), "file_edit", "f.txt", oldContent, newContent)
+	require.NoError(t, err)
+
+	require.Len(t, reviews, 1)
+	require.Len(t, reviews[0].Hunks, 2)
+	assert.Equal(t, "file_edit", reviews[0].ToolName)
+	assert.Equal(t, "@@ -1,4 +1,4 @@", reviews[0].Hunks[0].Header)
+	assert.Equal(t, diffview.Line{Kind: diffview.LineRemoved, OldLine: 1, Content: "line 1"}, reviews[0].Hunks[0].Lines[0])
+	assert.Equal(t, diffview.Line{Kind: diffview.LineAdded, NewLine: 1, Content: "line ONE"}, reviews[0].Hunks[0].Lines[1])
+
+	assert.Equal(t, strings.Replace(oldContent, "line 1\n", "line ONE\n", 1), content)
+	assert.False(t, outcome.allRejected())
+	feedback := outcome.feedback()
+	assert.Contains(t, feedback, "rejected 1 of 2 hunks")
+	assert.Contains(t, feedback, "<rejected_hunk>\n@@ -17,4 +17,5 @@\n line 17\n line 18\n line 19\n-line 20\n+line TWENTY\n+line extra\n</rejected_hunk>")
+}
+
+func TestReviewFileChangeWithoutReviewer(t *testing.T) {
+	content, outcome, err := reviewFileChange(context.Background(), "file_write", "f.txt", "a\n", "b\n")
+	require.NoError(t, err)
+	assert.Equal(t, "b\n", content)
+	assert.Nil(t, outcome)
+	assert.Empty(t, outcome.feedback())
+}
+
+func TestFileEditRejectedReviewLeavesFileUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n\nfunc main() {}\n"), 0o644))
+	payload, err := json.Marshal(FileEditInput{FilePath: path, OldText: "func main() {}", NewText: "func main() { run() }"})
+	require.NoError(t, err)
+
+	var reviews []EditReview
+	state := NewBasicState(context.Background(), WithLLMConfig(llmtypes.Config{}))
+	result := (&FileEditTool{}).Execute(reviewerWith([]bool{false}, &reviews), state, string(payload))
+
+	require.True(t, result.IsError())
+	assert.Contains(t, result.GetError(), "rejected it; the file was not modified")
+	assert.Contains(t, result.GetError(), "+func main() { run() }")
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n\nfunc main() {}\n", string(content))
+}
+
+func TestFileWritePartiallyAcceptedReview(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	oldContent := numberedLines(20)
+	require.NoError(t, os.WriteFile(path, []byte(oldContent), 0o644))
+	newContent := strings.Replace(oldContent, "line 1\n", "first\n", 1)
+	newContent = strings.Replace(newContent, "line 20\n", "last\n", 1)
+
+	var reviews []EditReview
+	state := NewBasicState(context.Background(), WithLLMConfig(llmtypes.Config{}))
+	result := (&FileWriteTool{}).Execute(reviewerWith([]bool{false, true}, &reviews), state, writeFileInput(t, path, newContent))
+
+	require.False(t, result.IsError(), result.GetError())
+	assert.Contains(t, result.AssistantFacing(), "rejected 1 of 2 hunks")
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Replace(oldContent, "line 20\n", "last\n", 1), string(content))
+}
+
+func TestApplyPatchReviewSkipsRejectedFiles(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.txt")
+	require.NoError(t, os.WriteFile(existing, []byte("keep\nold\n"), 0o644))
+	state := NewBasicState(context.Background(), WithLLMConfig(llmtypes.Config{WorkingDirectory: dir}))
+
+	patch := "*** Begin Patch\n" +
+		"*** Update File: existing.txt\n" +
+		"@@\n" +
+		" keep\n" +
+		"-old\n" +
+		"+new\n" +
+		"*** Add File: added.txt\n" +
+		"+hello\n" +
+		"*** End Patch"
+	payload, err := json.Marshal(ApplyPatchInput{Input: patch})
+	require.NoError(t, err)
+
+	ctx := ContextWithEditReviewer(context.Background(), func(_ context.Context, review EditReview) ([]bool, error) {
+		return []bool{filepath.Base(review.Path) == "added.txt"}, nil
+	})
+	result := (&ApplyPatchTool{}).Execute(ctx, state, string(payload))
+
+	require.False(t, result.IsError(), result.GetError())
+	assert.Contains(t, result.AssistantFacing(), "change to "+existing+" and rejected it")
+	content, err := os.ReadFile(existing)
+	require.NoError(t, err)
+	assert.Equal(t, "keep\nold\n", string(content))
+	assert.FileExists(t, filepath.Join(dir, "added.txt"))
+
+	ctx = ContextWithEditReviewer(context.Background(), func(context.Context, EditReview) ([]bool, error) {
+		return []bool{false}, nil
+	})
+	require.NoError(t, os.Remove(filepath.Join(dir, "added.txt")))
+	result = (&ApplyPatchTool{}).Execute(ctx, state, string(payload))
+	require.True(t, result.IsError(), "a patch with every change rejected fails")
+	assert.Contains(t, result.GetError(), "<rejected_hunk>")
+	assert.NoFileExists(t, filepath.Join(dir, "added.txt"))
+}
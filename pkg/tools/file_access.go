@@ -0,0 +1,55 @@
+package tools
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/osutil"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// fileAccessRecorder is implemented by states that remember which files the
+// file tools touched, so they can be re-injected after compaction.
+type fileAccessRecorder interface {
+	RecordFileAccess(path string)
+}
+
+// RecordFileAccess marks path as accessed now.
+func (s *BasicState) RecordFileAccess(path string) {
+	s.fileAccessMu.Lock()
+	defer s.fileAccessMu.Unlock()
+	if s.fileLastAccess == nil {
+		s.fileLastAccess = make(map[string]time.Time)
+	}
+	s.fileLastAccess[osutil.CanonicalizePath(path)] = time.Now()
+}
+
+// RecentlyAccessedFiles returns the files touched by the file tools, most recent first.
+func (s *BasicState) RecentlyAccessedFiles() []string {
+	s.fileAccessMu.Lock()
+	defer s.fileAccessMu.Unlock()
+	paths := make([]string, 0, len(s.fileLastAccess))
+	for path := range s.fileLastAccess {
+		paths = append(paths, path)
+	}
+	sort.SliceStable(paths, func(i, j int) bool {
+		ti, tj := s.fileLastAccess[paths[i]], s.fileLastAccess[paths[j]]
+		if ti.Equal(tj) {
+			return paths[i] < paths[j]
+		}
+		return ti.After(tj)
+	})
+	return paths
+}
+
+func recordFileAccess(state tooltypes.State, paths ...string) {
+	recorder, ok := state.(fileAccessRecorder)
+	if !ok {
+		return
+	}
+	for _, path := range paths {
+		if path != "" {
+			recorder.RecordFileAccess(path)
+		}
+	}
+}
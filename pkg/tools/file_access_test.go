@@ -0,0 +1,34 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/osutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileToolsRecordFileAccess(t *testing.T) {
+	dir := t.TempDir()
+	read := filepath.Join(dir, "read.txt")
+	written := filepath.Join(dir, "written.txt")
+	require.NoError(t, os.WriteFile(read, []byte("hello\n"), 0o644))
+	state := NewBasicState(context.Background())
+
+	payload, err := json.Marshal(FileReadInput{FilePath: read})
+	require.NoError(t, err)
+	result := (&FileReadTool{}).Execute(context.Background(), state, string(payload))
+	require.False(t, result.IsError(), result.GetError())
+
+	result = (&FileWriteTool{}).Execute(context.Background(), state, writeFileInput(t, written, "x\n"))
+	require.False(t, result.IsError(), result.GetError())
+
+	assert.Equal(t, []string{osutil.CanonicalizePath(written), osutil.CanonicalizePath(read)}, state.RecentlyAccessedFiles())
+
+	state.RecordFileAccess(read)
+	assert.Equal(t, osutil.CanonicalizePath(read), state.RecentlyAccessedFiles()[0])
+}
@@ -27,6 +27,7 @@ type FileEditToolResult struct {
 	replaceAll    bool
 	replacedCount int
 	edits         []EditInfo
+	review        *editReviewOutcome
 	err           string
 }
 
@@ -66,7 +67,9 @@ func (r *FileEditToolResult) AssistantFacing() string {
 	}
 
 	var result string
-	if r.replaceAll && r.replacedCount > 1 {
+	if feedback := r.review.feedback(); feedback != "" {
+		result = fmt.Sprintf("File %s has been partially edited.\n\n%s", r.filename, feedback)
+	} else if r.replaceAll && r.replacedCount > 1 {
 		result = fmt.Sprintf("File %s has been edited successfully. Replaced %d occurrences", r.filename, r.replacedCount)
 		if len(r.edits) > 0 {
 			result += "\n\nSample edited code blocks:"
@@ -145,6 +148,8 @@ func (r *FileEditToolResult) StructuredData() tooltypes.StructuredToolResult {
 		Edits:         edits,
 		ReplaceAll:    r.replaceAll,
 		ReplacedCount: r.replacedCount,
+		UnifiedDiff:   applyPatchUnifiedDiff(r.filename, r.filename, r.oldContent, r.newContent),
+		InverseDiff:   applyPatchUnifiedDiff(r.filename, r.filename, r.newContent, r.oldContent),
 	}
 
 	if r.IsError() {
@@ -403,7 +408,7 @@ func FormatEditedBlock(originalContent, oldText, newText string) string {
 }
 
 // Execute performs the file edit operation
-func (t *FileEditTool) Execute(_ context.Context, state tooltypes.State, parameters string) tooltypes.ToolResult {
+func (t *FileEditTool) Execute(ctx context.Context, state tooltypes.State, parameters string) tooltypes.ToolResult {
 	var input FileEditInput
 	if err := json.Unmarshal([]byte(parameters), &input); err != nil {
 		return &FileEditToolResult{
@@ -474,13 +479,46 @@ func (t *FileEditTool) Execute(_ context.Context, state tooltypes.State, paramet
 		}
 	}
 
+	proposedContent := content
+	content, review, err := reviewFileChange(ctx, t.Name(), input.FilePath, originalContent, proposedContent)
+	if err != nil {
+		return &FileEditToolResult{
+			filename: input.FilePath,
+			err:      err.Error(),
+		}
+	}
+	if review.allRejected() {
+		return &FileEditToolResult{
+			filename: input.FilePath,
+			err:      review.feedback(),
+		}
+	}
+	if content != proposedContent {
+		// Only part of the edit was applied, so describe it as a change to the
+		// whole file.
+		oldText, newText = originalContent, content
+		startLine, endLine = 1, strings.Count(originalContent, "\n")+1
+		edits = []EditInfo{{StartLine: startLine, EndLine: endLine, OldContent: oldText, NewContent: newText}}
+	}
+
+	release, err := reserveFileChanges(ctx, state, fileChange{Path: input.FilePath, Lines: changedLineCount(originalContent, content)})
+	if err != nil {
+		return &FileEditToolResult{
+			filename: input.FilePath,
+			err:      err.Error(),
+		}
+	}
+	checkpointFiles(ctx, state, t.Name(), input.FilePath)
+
 	err = os.WriteFile(input.FilePath, []byte(content), 0o644)
 	if err != nil {
+		release()
 		return &FileEditToolResult{
 			filename: input.FilePath,
 			err:      fmt.Sprintf("failed to write the file: %s", err),
 		}
 	}
+	recordFileAccess(state, input.FilePath)
 
 	return &FileEditToolResult{
 		filename:      input.FilePath,
@@ -493,5 +531,6 @@ func (t *FileEditTool) Execute(_ context.Context, state tooltypes.State, paramet
 		replaceAll:    replaceAll,
 		replacedCount: replacedCount,
 		edits:         edits,
+		review:        review,
 	}
 }
@@ -681,6 +681,31 @@ func TestFileEditTool_StructuredDataReplaceAll(t *testing.T) {
 	assert.Len(t, meta.Edits, 2)
 }
 
+func TestFileEditTool_StructuredDataDiffs(t *testing.T) {
+	tempFile, err := os.CreateTemp("", "file_edit_diff_test")
+	require.NoError(t, err)
+	defer os.Remove(tempFile.Name())
+	original := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\n"
+	require.NoError(t, os.WriteFile(tempFile.Name(), []byte(original), 0o644))
+
+	params, _ := json.Marshal(FileEditInput{FilePath: tempFile.Name(), OldText: "seven", NewText: "SEVEN"})
+	result := (&FileEditTool{}).Execute(context.Background(), NewBasicState(context.TODO()), string(params))
+	require.False(t, result.IsError())
+
+	meta, ok := result.StructuredData().Metadata.(*tooltypes.FileEditMetadata)
+	require.True(t, ok)
+	assert.Equal(t, "--- "+tempFile.Name()+"\n+++ "+tempFile.Name()+"\n"+
+		"@@ -4,5 +4,5 @@\n four\n five\n six\n-seven\n+SEVEN\n eight\n", meta.UnifiedDiff)
+	assert.Equal(t, "--- "+tempFile.Name()+"\n+++ "+tempFile.Name()+"\n"+
+		"@@ -4,5 +4,5 @@\n four\n five\n six\n-SEVEN\n+seven\n eight\n", meta.InverseDiff)
+
+	failed := (&FileEditToolResult{filename: tempFile.Name(), err: "old text not found"}).StructuredData()
+	failedMeta, ok := failed.Metadata.(*tooltypes.FileEditMetadata)
+	require.True(t, ok)
+	assert.Empty(t, failedMeta.UnifiedDiff)
+	assert.Empty(t, failedMeta.InverseDiff)
+}
+
 func TestFindAllOccurrences(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -0,0 +1,398 @@
+package tools
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+const (
+	// OutlineLineThreshold is the size, in lines, above which file_read returns
+	// an outline instead of the content when no range is requested.
+	OutlineLineThreshold = 500
+	// MaxOutlineFileBytes is the largest file that is outlined.
+	MaxOutlineFileBytes = 5 << 20
+	// MaxOutlineEntries bounds the number of entries in an outline.
+	MaxOutlineEntries = 400
+	// maxOutlineTextLength bounds the length of a single outline entry.
+	maxOutlineTextLength = 160
+)
+
+// File read modes.
+const (
+	FileReadModeAuto    = "auto"
+	FileReadModeFull    = "full"
+	FileReadModeOutline = "outline"
+)
+
+// outlineEntry is one declaration or section in a file outline.
+type outlineEntry struct {
+	StartLine int
+	EndLine   int
+	Depth     int
+	Text      string
+}
+
+// outlineFile returns the outline of content, or false when the language has
+// no outliner.
+func outlineFile(language string, content string) ([]outlineEntry, bool) {
+	switch language {
+	case "go":
+		if entries, ok := outlineGo(content); ok {
+			return entries, true
+		}
+		return outlineByPatterns(content, goPatterns), true
+	case "markdown":
+		return outlineMarkdown(content), true
+	}
+	patterns, ok := outlinePatterns[language]
+	if !ok {
+		return nil, false
+	}
+	return outlineByPatterns(content, patterns), true
+}
+
+// renderOutline formats entries for the model, with a hint on how to read a
+// section.
+func renderOutline(entries []outlineEntry, totalLines int, language string) []string {
+	lines := make([]string, 0, len(entries)+2)
+	if language == "" {
+		language = "text"
+	}
+	lines = append(lines, fmt.Sprintf("[Outline of a %d-line %s file. Line ranges are shown for each declaration or section.]", totalLines, language))
+
+	width := len(fmt.Sprintf("%d-%d", totalLines, totalLines))
+	shown := entries
+	if len(shown) > MaxOutlineEntries {
+		shown = shown[:MaxOutlineEntries]
+	}
+	for _, entry := range shown {
+		lineRange := fmt.Sprintf("%d-%d", entry.StartLine, entry.EndLine)
+		lines = append(lines, fmt.Sprintf("%*s  %s%s", width, lineRange, strings.Repeat("  ", entry.Depth), entry.Text))
+	}
+	if len(entries) > len(shown) {
+		lines = append(lines, fmt.Sprintf("... [%d more entries not shown]", len(entries)-len(shown)))
+	}
+
+	example := shown[0]
+	lines = append(lines, fmt.Sprintf("[Read a section with offset and line_limit, e.g. offset=%d line_limit=%d. Use mode=\"full\" to read the file from the start.]",
+		example.StartLine, example.EndLine-example.StartLine+1))
+	return lines
+}
+
+func outlineGo(content string) ([]outlineEntry, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, false
+	}
+	line := func(pos token.Pos) int { return fset.Position(pos).Line }
+	source := func(from, to token.Pos) string {
+		return collapseOutlineText(content[fset.Position(from).Offset:fset.Position(to).Offset])
+	}
+
+	var entries []outlineEntry
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			entries = append(entries, outlineEntry{
+				StartLine: line(decl.Pos()),
+				EndLine:   line(decl.End()),
+				Text:      source(decl.Pos(), decl.Type.End()),
+			})
+		case *ast.GenDecl:
+			switch decl.Tok {
+			case token.IMPORT:
+				entries = append(entries, outlineEntry{
+					StartLine: line(decl.Pos()),
+					EndLine:   line(decl.End()),
+					Text:      fmt.Sprintf("import (%d)", len(decl.Specs)),
+				})
+			case token.TYPE:
+				for _, spec := range decl.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					start, end := typeSpec.Pos(), typeSpec.End()
+					if !decl.Lparen.IsValid() {
+						start, end = decl.Pos(), decl.End()
+					}
+					entries = append(entries, outlineEntry{
+						StartLine: line(start),
+						EndLine:   line(end),
+						Text:      "type " + goTypeSummary(typeSpec, source),
+					})
+					if iface, ok := typeSpec.Type.(*ast.InterfaceType); ok {
+						for _, method := range iface.Methods.List {
+							if _, ok := method.Type.(*ast.FuncType); !ok || len(method.Names) == 0 {
+								continue
+							}
+							entries = append(entries, outlineEntry{
+								StartLine: line(method.Pos()),
+								EndLine:   line(method.End()),
+								Depth:     1,
+								Text:      source(method.Pos(), method.End()),
+							})
+						}
+					}
+				}
+			case token.CONST, token.VAR:
+				var names []string
+				for _, spec := range decl.Specs {
+					for _, name := range spec.(*ast.ValueSpec).Names {
+						names = append(names, name.Name)
+					}
+				}
+				if len(names) > 6 {
+					names = append(names[:6], "...")
+				}
+				entries = append(entries, outlineEntry{
+					StartLine: line(decl.Pos()),
+					EndLine:   line(decl.End()),
+					Text:      decl.Tok.String() + " " + strings.Join(names, ", "),
+				})
+			}
+		}
+	}
+	return entries, true
+}
+
+func goTypeSummary(spec *ast.TypeSpec, source func(from, to token.Pos) string) string {
+	name := spec.Name.Name
+	if spec.TypeParams != nil {
+		name = source(spec.Name.Pos(), spec.TypeParams.End())
+	}
+	if spec.Assign.IsValid() {
+		return name + " = " + source(spec.Type.Pos(), spec.Type.End())
+	}
+	switch spec.Type.(type) {
+	case *ast.StructType:
+		return name + " struct"
+	case *ast.InterfaceType:
+		return name + " interface"
+	}
+	return name + " " + source(spec.Type.Pos(), spec.Type.End())
+}
+
+// outlineMarkdown lists the headings of a markdown document, ignoring fenced
+// code blocks.
+func outlineMarkdown(content string) []outlineEntry {
+	lines := strings.Split(content, "\n")
+	type heading struct {
+		line  int
+		level int
+		text  string
+	}
+	var headings []heading
+	minLevel := 6
+	inFence := false
+	for i, text := range lines {
+		trimmed := strings.TrimSpace(text)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || !strings.HasPrefix(text, "#") {
+			continue
+		}
+		level := len(text) - len(strings.TrimLeft(text, "#"))
+		if level > 6 || (len(text) > level && text[level] != ' ') {
+			continue
+		}
+		headings = append(headings, heading{line: i + 1, level: level, text: collapseOutlineText(text)})
+		minLevel = min(minLevel, level)
+	}
+
+	totalLines := countOutlineLines(content)
+	entries := make([]outlineEntry, 0, len(headings))
+	for i, h := range headings {
+		end := totalLines
+		for _, next := range headings[i+1:] {
+			if next.level <= h.level {
+				end = next.line - 1
+				break
+			}
+		}
+		entries = append(entries, outlineEntry{StartLine: h.line, EndLine: end, Depth: h.level - minLevel, Text: h.text})
+	}
+	return entries
+}
+
+// outlinePattern matches a line that starts a declaration.
+type outlinePattern = *regexp.Regexp
+
+var goPatterns = []outlinePattern{
+	regexp.MustCompile(`^func\s`),
+	regexp.MustCompile(`^type\s+\w+`),
+}
+
+var (
+	pythonPatterns = []outlinePattern{
+		regexp.MustCompile(`^\s*(async\s+)?def\s+\w+`),
+		regexp.MustCompile(`^\s*class\s+\w+`),
+	}
+	scriptPatterns = []outlinePattern{
+		regexp.MustCompile(`^\s*(export\s+)?(default\s+)?(async\s+)?function\*?\s*\w+`),
+		regexp.MustCompile(`^\s*(export\s+)?(default\s+)?(abstract\s+)?class\s+\w+`),
+		regexp.MustCompile(`^\s*(export\s+)?(declare\s+)?(interface|type|enum|namespace)\s+\w+`),
+		regexp.MustCompile(`^\s*(export\s+)?(const|let|var)\s+\w+\s*(:[^=]+)?=\s*(async\s+)?(\([^)]*\)|\w+)\s*(:[^=]+)?=>`),
+		regexp.MustCompile(`^\s+((public|private|protected|static|readonly|async|get|set|override)\s+)*#?\w+\s*(<[^>]*>)?\([^)]*\)\s*(:[^{]+)?\{\s*$`),
+	}
+	rustPatterns = []outlinePattern{
+		regexp.MustCompile(`^\s*(pub(\([^)]*\))?\s+)?((async|const|unsafe|extern\s+"[^"]*")\s+)*fn\s+\w+`),
+		regexp.MustCompile(`^\s*(pub(\([^)]*\))?\s+)?(struct|enum|trait|union|mod|type)\s+\w+`),
+		regexp.MustCompile(`^\s*(unsafe\s+)?impl\b`),
+		regexp.MustCompile(`^\s*macro_rules!\s*\w+`),
+	}
+	jvmPatterns = []outlinePattern{
+		regexp.MustCompile(`^\s*((public|private|protected|internal|static|final|abstract|sealed|open|data|inner|override|suspend|inline|partial)\s+)*(class|interface|enum|record|object|trait|fun|def)\s+\w+`),
+		regexp.MustCompile(`^\s+((public|private|protected|static|final|abstract|synchronized|native)\s+)+[\w<>\[\],.?\s]+\s+\w+\s*\(`),
+	}
+	cPatterns = []outlinePattern{
+		regexp.MustCompile(`^(struct|union|enum|class|namespace|typedef\s+struct)\s+\w+`),
+		regexp.MustCompile(`^[A-Za-z_][\w:<>,\s\*&]*[\s\*&]+~?[A-Za-z_][\w:]*\s*\([^;]*$`),
+	}
+	rubyPatterns = []outlinePattern{
+		regexp.MustCompile(`^\s*(class|module)\s+\w+`),
+		regexp.MustCompile(`^\s*def\s+[\w.?!=]+`),
+	}
+	phpPatterns = []outlinePattern{
+		regexp.MustCompile(`^\s*((abstract|final|readonly)\s+)*(class|interface|trait|enum)\s+\w+`),
+		regexp.MustCompile(`^\s*((public|private|protected|static|abstract|final)\s+)*function\s+&?\w+`),
+	}
+	swiftPatterns = []outlinePattern{
+		regexp.MustCompile(`^\s*((public|private|fileprivate|internal|open|final|static|override|mutating)\s+)*(class|struct|enum|protocol|extension|actor|func)\s+\w+`),
+	}
+	shellPatterns = []outlinePattern{
+		regexp.MustCompile(`^\s*(function\s+)?[\w.:-]+\s*\(\)\s*\{?`),
+		regexp.MustCompile(`^\s*function\s+[\w.:-]+`),
+	}
+)
+
+// outlinePatterns maps languages from osutil.DetectLanguageFromPath to the
+// patterns of their declarations.
+var outlinePatterns = map[string][]outlinePattern{
+	"python":     pythonPatterns,
+	"javascript": scriptPatterns,
+	"typescript": scriptPatterns,
+	"rust":       rustPatterns,
+	"java":       jvmPatterns,
+	"kotlin":     jvmPatterns,
+	"scala":      jvmPatterns,
+	"c":          cPatterns,
+	"cpp":        cPatterns,
+	"ruby":       rubyPatterns,
+	"php":        phpPatterns,
+	"swift":      swiftPatterns,
+	"bash":       shellPatterns,
+	"shell":      shellPatterns,
+}
+
+// outlineControlKeywords start lines that look like method declarations but
+// are control flow.
+var outlineControlKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true,
+	"return": true, "else": true, "do": true, "try": true, "new": true,
+}
+
+// outlineByPatterns outlines content with line patterns. A declaration ends
+// before the next non-blank line indented no deeper than it, or on that line
+// when it closes a block.
+func outlineByPatterns(content string, patterns []outlinePattern) []outlineEntry {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	var entries []outlineEntry
+	var indents []int
+	for i, text := range lines {
+		if !matchesOutlinePattern(text, patterns) {
+			continue
+		}
+		indent := outlineIndent(text)
+		entries = append(entries, outlineEntry{
+			StartLine: i + 1,
+			EndLine:   outlineBlockEnd(lines, i, indent),
+			Text:      collapseOutlineText(strings.TrimSuffix(strings.TrimSpace(text), "{")),
+		})
+		indents = append(indents, indent)
+	}
+
+	// Nest each entry under the closest preceding entry that encloses it.
+	for i := range entries {
+		for j := i - 1; j >= 0; j-- {
+			if indents[j] < indents[i] && entries[j].EndLine >= entries[i].StartLine {
+				entries[i].Depth = entries[j].Depth + 1
+				break
+			}
+		}
+	}
+	return entries
+}
+
+func matchesOutlinePattern(text string, patterns []outlinePattern) bool {
+	fields := strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == '\t' || r == '(' })
+	if len(fields) > 0 && outlineControlKeywords[fields[0]] {
+		return false
+	}
+	for _, pattern := range patterns {
+		if pattern.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+func outlineBlockEnd(lines []string, start, indent int) int {
+	lastContent := start
+	for i := start + 1; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" {
+			continue
+		}
+		if outlineIndent(lines[i]) > indent || strings.HasPrefix(trimmed, ")") || strings.HasPrefix(trimmed, "]") {
+			lastContent = i
+			continue
+		}
+		if strings.HasPrefix(trimmed, "}") || trimmed == "end" || strings.HasPrefix(trimmed, "end ") {
+			return i + 1
+		}
+		break
+	}
+	return lastContent + 1
+}
+
+func outlineIndent(text string) int {
+	indent := 0
+	for _, r := range text {
+		switch r {
+		case ' ':
+			indent++
+		case '\t':
+			indent += 4
+		default:
+			return indent
+		}
+	}
+	return indent
+}
+
+func collapseOutlineText(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > maxOutlineTextLength {
+		text = truncateOutlineText(text, maxOutlineTextLength) + "..."
+	}
+	return text
+}
+
+func truncateOutlineText(text string, limit int) string {
+	for limit > 0 && (text[limit]&0xC0) == 0x80 {
+		limit--
+	}
+	return text[:limit]
+}
+
+func countOutlineLines(content string) int {
+	if content == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(content, "\n"), "\n") + 1
+}
@@ -0,0 +1,215 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+func TestOutlineGo(t *testing.T) {
+	content := `package sample
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	A = 1
+	B = 2
+)
+
+// Server serves requests.
+type Server struct {
+	name string
+}
+
+type Handler interface {
+	Handle(ctx context.Context) error
+}
+
+type Set[T comparable] map[T]struct{}
+
+func (s *Server) Start(ctx context.Context,
+	name string) error {
+	fmt.Println(name)
+	return nil
+}
+`
+
+	entries, ok := outlineFile("go", content)
+
+	require.True(t, ok)
+	assert.Equal(t, []outlineEntry{
+		{StartLine: 3, EndLine: 6, Text: "import (2)"},
+		{StartLine: 8, EndLine: 11, Text: "const A, B"},
+		{StartLine: 14, EndLine: 16, Text: "type Server struct"},
+		{StartLine: 18, EndLine: 20, Text: "type Handler interface"},
+		{StartLine: 19, EndLine: 19, Depth: 1, Text: "Handle(ctx context.Context) error"},
+		{StartLine: 22, EndLine: 22, Text: "type Set[T comparable] map[T]struct{}"},
+		{StartLine: 24, EndLine: 28, Text: "func (s *Server) Start(ctx context.Context, name string) error"},
+	}, entries)
+}
+
+func TestOutlinePython(t *testing.T) {
+	content := `import os
+
+
+class Greeter:
+    def __init__(self, name):
+        self.name = name
+
+    def greet(self):
+        return f"hi {self.name}"
+
+
+async def main():
+    print(Greeter("x").greet())
+
+if __name__ == "__main__":
+    main()
+`
+
+	entries, ok := outlineFile("python", content)
+
+	require.True(t, ok)
+	assert.Equal(t, []outlineEntry{
+		{StartLine: 4, EndLine: 9, Text: "class Greeter:"},
+		{StartLine: 5, EndLine: 6, Depth: 1, Text: "def __init__(self, name):"},
+		{StartLine: 8, EndLine: 9, Depth: 1, Text: "def greet(self):"},
+		{StartLine: 12, EndLine: 13, Text: "async def main():"},
+	}, entries)
+}
+
+func TestOutlineTypeScript(t *testing.T) {
+	content := `export interface Options {
+  name: string;
+}
+
+export class Client {
+  constructor(private options: Options) {
+    if (options.name) {
+      console.log(options.name);
+    }
+  }
+
+  async fetch(path: string): Promise<string> {
+    return path;
+  }
+}
+
+export const handler = async (event: Event) => {
+  return event;
+};
+`
+
+	entries, ok := outlineFile("typescript", content)
+
+	require.True(t, ok)
+	assert.Equal(t, []outlineEntry{
+		{StartLine: 1, EndLine: 3, Text: "export interface Options"},
+		{StartLine: 5, EndLine: 15, Text: "export class Client"},
+		{StartLine: 6, EndLine: 10, Depth: 1, Text: "constructor(private options: Options)"},
+		{StartLine: 12, EndLine: 14, Depth: 1, Text: "async fetch(path: string): Promise<string>"},
+		{StartLine: 17, EndLine: 19, Text: "export const handler = async (event: Event) =>"},
+	}, entries)
+}
+
+func TestOutlineMarkdown(t *testing.T) {
+	content := "# Title\nintro\n## Install\nsteps\n```sh\n# not a heading\n```\n## Usage\ntext\n# Appendix\nend\n"
+
+	entries, ok := outlineFile("markdown", content)
+
+	require.True(t, ok)
+	assert.Equal(t, []outlineEntry{
+		{StartLine: 1, EndLine: 9, Text: "# Title"},
+		{StartLine: 3, EndLine: 7, Depth: 1, Text: "## Install"},
+		{StartLine: 8, EndLine: 9, Depth: 1, Text: "## Usage"},
+		{StartLine: 10, EndLine: 11, Text: "# Appendix"},
+	}, entries)
+}
+
+func TestOutlineUnsupportedLanguage(t *testing.T) {
+	_, ok := outlineFile("yaml", "key: value\n")
+	assert.False(t, ok)
+}
+
+func largeGoFile(functions int) string {
+	var b strings.Builder
+	b.WriteString("package big\n")
+	for i := range functions {
+		fmt.Fprintf(&b, "\nfunc F%d() int {\n", i)
+		for j := range 8 {
+			fmt.Fprintf(&b, "\t_ = %d\n", j)
+		}
+		fmt.Fprintf(&b, "\treturn %d\n}\n", i)
+	}
+	return b.String()
+}
+
+func TestFileReadTool_Outline(t *testing.T) {
+	dir := t.TempDir()
+	large := filepath.Join(dir, "big.go")
+	require.NoError(t, os.WriteFile(large, []byte(largeGoFile(60)), 0o644))
+	small := filepath.Join(dir, "small.go")
+	require.NoError(t, os.WriteFile(small, []byte(largeGoFile(2)), 0o644))
+	data := filepath.Join(dir, "data.yaml")
+	require.NoError(t, os.WriteFile(data, []byte(strings.Repeat("key: value\n", 600)), 0o644))
+
+	tool := &FileReadTool{}
+	execute := func(input FileReadInput) *FileReadToolResult {
+		params, err := json.Marshal(input)
+		require.NoError(t, err)
+		return tool.Execute(context.Background(), NewBasicState(context.TODO()), string(params)).(*FileReadToolResult)
+	}
+
+	t.Run("large source file returns an outline", func(t *testing.T) {
+		result := execute(FileReadInput{FilePath: large})
+
+		require.False(t, result.IsError())
+		output := result.AssistantFacing()
+		assert.Contains(t, output, "[Outline of a 721-line go file.")
+		assert.Contains(t, output, "   3-13  func F0() int")
+		assert.Contains(t, output, "711-721  func F59() int")
+		assert.Contains(t, output, "offset=3 line_limit=11")
+
+		var meta tooltypes.FileReadMetadata
+		require.True(t, tooltypes.ExtractMetadata(result.StructuredData().Metadata, &meta))
+		assert.True(t, meta.Outline)
+		assert.Equal(t, 721, meta.TotalLines)
+		assert.False(t, meta.Truncated)
+	})
+
+	t.Run("full mode and ranges return content", func(t *testing.T) {
+		result := execute(FileReadInput{FilePath: large, Mode: FileReadModeFull})
+		assert.Contains(t, result.AssistantFacing(), "1: package big")
+
+		result = execute(FileReadInput{FilePath: large, Offset: 3, LineLimit: 11})
+		assert.Contains(t, result.AssistantFacing(), " 3: func F0() int {")
+		assert.False(t, result.outline)
+	})
+
+	t.Run("small files and unsupported languages return content", func(t *testing.T) {
+		assert.False(t, execute(FileReadInput{FilePath: small}).outline)
+		assert.False(t, execute(FileReadInput{FilePath: data}).outline)
+	})
+
+	t.Run("outline mode", func(t *testing.T) {
+		result := execute(FileReadInput{FilePath: small, Mode: FileReadModeOutline})
+		assert.True(t, result.outline)
+		assert.Contains(t, result.AssistantFacing(), "func F1() int")
+
+		result = execute(FileReadInput{FilePath: data, Mode: FileReadModeOutline})
+		assert.True(t, result.IsError())
+		assert.Contains(t, result.GetError(), "No outline is available")
+	})
+}
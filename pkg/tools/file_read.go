@@ -34,11 +34,20 @@ type FileReadToolResult struct {
 	lineLimit        int
 	remainingLines   int
 	truncationReason string
+	outline          bool
+	totalLines       int
+	binary           *tooltypes.BinaryContent
 	err              string
 }
 
-// GetResult returns the file content
+// GetResult returns the file content, or its outline
 func (r *FileReadToolResult) GetResult() string {
+	if r.binary != nil {
+		return r.binary.Notice() + "; pass raw=true to read it anyway"
+	}
+	if r.outline {
+		return strings.Join(r.lines, "\n")
+	}
 	return osutil.ContentWithLineNumber(r.lines, r.offset)
 }
 
@@ -56,7 +65,7 @@ func (r *FileReadToolResult) IsError() bool {
 func (r *FileReadToolResult) AssistantFacing() string {
 	var content string
 	if !r.IsError() {
-		content = osutil.ContentWithLineNumber(r.lines, r.offset)
+		content = r.GetResult()
 	}
 	return tooltypes.StringifyToolResult(content, r.GetError())
 }
@@ -70,7 +79,7 @@ func (r *FileReadToolResult) StructuredData() tooltypes.StructuredToolResult {
 	}
 
 	// Check if content was truncated (either by bytes or line limit)
-	truncated := len(r.lines) > 0 && (strings.Contains(r.lines[len(r.lines)-1], "truncated") || strings.Contains(r.lines[len(r.lines)-1], "lines remaining"))
+	truncated := !r.outline && len(r.lines) > 0 && (strings.Contains(r.lines[len(r.lines)-1], "truncated") || strings.Contains(r.lines[len(r.lines)-1], "lines remaining"))
 
 	// Detect language from file extension
 	language := osutil.DetectLanguageFromPath(r.filename)
@@ -84,6 +93,9 @@ func (r *FileReadToolResult) StructuredData() tooltypes.StructuredToolResult {
 		Language:       language,
 		Truncated:      truncated,
 		RemainingLines: r.remainingLines,
+		Outline:        r.outline,
+		TotalLines:     r.totalLines,
+		Binary:         r.binary,
 	}
 
 	if r.IsError() {
@@ -113,13 +125,19 @@ func (r *FileReadTool) Name() string {
 func (r *FileReadTool) Description() string {
 	return `Reads a file and returns its contents with line numbers.
 
-This tool takes three parameters:
+This tool takes five parameters:
 - file_path: The absolute path of the file to read
 - offset: The 1-indexed line number to start reading from (default: 1, minimum: 1)
 - line_limit: The maximum number of lines to read from the offset (default: 2000, minimum: 1, maximum: 2000)
+- mode: auto, full or outline (default: auto)
+- raw: return the content even when the file looks binary (default: false)
 
 For most files, omit offset and line_limit to read the entire file. Use these parameters only for large files when you need specific sections.
 
+Source and markdown files longer than ` + fmt.Sprint(OutlineLineThreshold) + ` lines read without offset or line_limit return an outline instead of the content: the declarations or section headings with their line ranges. Read the sections you need with offset and line_limit, or pass mode="full" to read the file from the start. Pass mode="outline" to get the outline of any supported file.
+
+Binary files such as executables, images, archives and compressed or encrypted data are not returned; the result names the detected type and size instead. Only pass raw=true when the raw bytes are genuinely needed.
+
 The result will include line numbers padded appropriately, followed by the content of each line.
 If there are more lines beyond the line limit, a truncation message will be shown with the exact count of remaining lines.
 
@@ -166,6 +184,12 @@ func (r *FileReadTool) ValidateInput(_ tooltypes.State, parameters string) error
 		return fmt.Errorf("line_limit cannot exceed %d", MaxLineLimit)
 	}
 
+	switch input.Mode {
+	case "", FileReadModeAuto, FileReadModeFull, FileReadModeOutline:
+	default:
+		return fmt.Errorf("mode must be one of %s, %s or %s", FileReadModeAuto, FileReadModeFull, FileReadModeOutline)
+	}
+
 	return nil
 }
 
@@ -186,6 +210,7 @@ func (r *FileReadTool) TracingKVs(parameters string) ([]attribute.KeyValue, erro
 		attribute.String("file_path", input.FilePath),
 		attribute.Int("offset", input.Offset),
 		attribute.Int("line_limit", input.LineLimit),
+		attribute.String("mode", input.Mode),
 	}, nil
 }
 
@@ -200,6 +225,12 @@ func (r *FileReadTool) Execute(_ context.Context, state tooltypes.State, paramet
 		}
 	}
 
+	if input.Mode == FileReadModeOutline || (input.Mode != FileReadModeFull && input.Offset <= 1 && input.LineLimit == 0) {
+		if result := readFileOutline(state, input); result != nil {
+			return result
+		}
+	}
+
 	// Set default line limit if not provided
 	if input.LineLimit == 0 {
 		input.LineLimit = MaxLineLimit
@@ -214,8 +245,25 @@ func (r *FileReadTool) Execute(_ context.Context, state tooltypes.State, paramet
 		}
 	}
 	defer file.Close()
+	recordFileAccess(state, input.FilePath)
+
+	reader := bufio.NewReaderSize(file, osutil.BinarySniffBytes)
+	if !input.Raw {
+		head, _ := reader.Peek(osutil.BinarySniffBytes)
+		if kind, binary := osutil.DetectBinary(head); binary {
+			size := int64(len(head))
+			if info, err := file.Stat(); err == nil {
+				size = info.Size()
+			}
+			return &FileReadToolResult{
+				filename:  input.FilePath,
+				lineLimit: input.LineLimit,
+				binary:    &tooltypes.BinaryContent{Size: size, Type: kind},
+			}
+		}
+	}
 
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(reader)
 
 	if input.Offset == 0 {
 		input.Offset = 1
@@ -310,3 +358,48 @@ func (r *FileReadTool) Execute(_ context.Context, state tooltypes.State, paramet
 		truncationReason: truncationReason,
 	}
 }
+
+// readFileOutline returns the outline of the file, or nil when the content
+// should be read instead: in auto mode for small files and files without an
+// outline.
+func readFileOutline(state tooltypes.State, input *FileReadInput) *FileReadToolResult {
+	explicit := input.Mode == FileReadModeOutline
+	info, err := os.Stat(input.FilePath)
+	if err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+	if info.Size() > MaxOutlineFileBytes {
+		if explicit {
+			return &FileReadToolResult{filename: input.FilePath, err: fmt.Sprintf("File is too large to outline (over %d bytes); read it with offset and line_limit", MaxOutlineFileBytes)}
+		}
+		return nil
+	}
+
+	data, err := os.ReadFile(input.FilePath)
+	if err != nil {
+		return nil
+	}
+	content := string(data)
+	totalLines := countOutlineLines(content)
+	if !explicit && totalLines <= OutlineLineThreshold {
+		return nil
+	}
+
+	language := osutil.DetectLanguageFromPath(input.FilePath)
+	entries, ok := outlineFile(language, content)
+	if !ok || len(entries) == 0 {
+		if explicit {
+			return &FileReadToolResult{filename: input.FilePath, err: "No outline is available for this file; read it with offset and line_limit"}
+		}
+		return nil
+	}
+
+	recordFileAccess(state, input.FilePath)
+	return &FileReadToolResult{
+		filename:   input.FilePath,
+		lines:      renderOutline(entries, totalLines, language),
+		offset:     1,
+		outline:    true,
+		totalLines: totalLines,
+	}
+}
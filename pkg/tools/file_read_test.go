@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -108,6 +109,15 @@ func TestFileReadTool_ValidateInput(t *testing.T) {
 			expectError: true,
 			errorMsg:    fmt.Sprintf("line_limit cannot exceed %d", MaxLineLimit),
 		},
+		{
+			name: "invalid mode",
+			input: FileReadInput{
+				FilePath: "/tmp/test.txt",
+				Mode:     "summary",
+			},
+			expectError: true,
+			errorMsg:    "mode must be one of auto, full or outline",
+		},
 	}
 
 	for _, tt := range tests {
@@ -208,6 +218,27 @@ func TestFileReadTool_Execute(t *testing.T) {
 	})
 }
 
+func TestFileReadTool_BinaryContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app")
+	content := []byte("\x7fELF\x02\x01\x01\x00\x00\x00\nrest\n")
+	require.NoError(t, os.WriteFile(path, content, 0o644))
+	tool := &FileReadTool{}
+
+	params, _ := json.Marshal(FileReadInput{FilePath: path})
+	result := tool.Execute(context.Background(), NewBasicState(context.TODO()), string(params))
+	assert.False(t, result.IsError())
+	assert.Equal(t, "binary content withheld (16 bytes, type: ELF); pass raw=true to read it anyway", result.GetResult())
+	metadata := result.StructuredData().Metadata.(*tooltypes.FileReadMetadata)
+	assert.Equal(t, &tooltypes.BinaryContent{Size: 16, Type: "ELF"}, metadata.Binary)
+	assert.Empty(t, metadata.Lines)
+
+	params, _ = json.Marshal(FileReadInput{FilePath: path, Raw: true})
+	result = tool.Execute(context.Background(), NewBasicState(context.TODO()), string(params))
+	assert.False(t, result.IsError())
+	assert.Contains(t, result.GetResult(), "2: rest")
+	assert.Nil(t, result.StructuredData().Metadata.(*tooltypes.FileReadMetadata).Binary)
+}
+
 func TestFileReadTool_Line_Padding(t *testing.T) {
 	// Create a temporary test file with 100 lines
 	var content strings.Builder
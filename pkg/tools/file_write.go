@@ -20,6 +20,7 @@ import (
 type FileWriteToolResult struct {
 	filename string
 	text     string
+	review   *editReviewOutcome
 	err      string
 }
 
@@ -47,6 +48,9 @@ func (r *FileWriteToolResult) AssistantFacing() string {
 	var content string
 	if !r.IsError() {
 		content = r.GetResult()
+		if feedback := r.review.feedback(); feedback != "" {
+			content += "\n\n" + feedback
+		}
 	}
 	return tooltypes.StringifyToolResult(content, r.GetError())
 }
@@ -137,7 +141,7 @@ func (t *FileWriteTool) TracingKVs(parameters string) ([]attribute.KeyValue, err
 }
 
 // Execute writes the file and returns the result
-func (t *FileWriteTool) Execute(_ context.Context, state tooltypes.State, parameters string) tooltypes.ToolResult {
+func (t *FileWriteTool) Execute(ctx context.Context, state tooltypes.State, parameters string) tooltypes.ToolResult {
 	var input FileWriteInput
 	if err := json.Unmarshal([]byte(parameters), &input); err != nil {
 		return &FileWriteToolResult{
@@ -146,16 +150,43 @@ func (t *FileWriteTool) Execute(_ context.Context, state tooltypes.State, parame
 		}
 	}
 
-	err := os.WriteFile(input.FilePath, []byte(input.Text), 0o644)
+	existing, _ := os.ReadFile(input.FilePath)
+	text, review, err := reviewFileChange(ctx, t.Name(), input.FilePath, string(existing), input.Text)
+	if err != nil {
+		return &FileWriteToolResult{
+			filename: input.FilePath,
+			err:      err.Error(),
+		}
+	}
+	if review.allRejected() {
+		return &FileWriteToolResult{
+			filename: input.FilePath,
+			err:      review.feedback(),
+		}
+	}
+
+	release, err := reserveFileChanges(ctx, state, fileChange{Path: input.FilePath, Lines: changedLineCount(string(existing), text)})
+	if err != nil {
+		return &FileWriteToolResult{
+			filename: input.FilePath,
+			err:      err.Error(),
+		}
+	}
+	checkpointFiles(ctx, state, t.Name(), input.FilePath)
+
+	err = os.WriteFile(input.FilePath, []byte(text), 0o644)
 	if err != nil {
+		release()
 		return &FileWriteToolResult{
 			filename: input.FilePath,
 			err:      fmt.Sprintf("failed to write the file: %s", err.Error()),
 		}
 	}
+	recordFileAccess(state, input.FilePath)
 
 	return &FileWriteToolResult{
 		filename: input.FilePath,
-		text:     input.Text,
+		text:     text,
+		review:   review,
 	}
 }
@@ -0,0 +1,193 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/invopop/jsonschema"
+	"github.com/jingkaihe/kodelet/pkg/gitlog"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// GitLogTool lists the commits in a revision range grouped by repository area.
+type GitLogTool struct{}
+
+// GitLogInput reuses the shared git_log input schema while preserving pkg/tools schema IDs.
+type GitLogInput tooltypes.GitLogInput
+
+// GitLogToolResult represents the grouped history returned by git_log.
+type GitLogToolResult struct {
+	log gitlog.Log
+	err string
+}
+
+// Name returns the name of the tool
+func (t *GitLogTool) Name() string {
+	return "git_log"
+}
+
+// GenerateSchema generates the JSON schema for the tool's input parameters
+func (t *GitLogTool) GenerateSchema() *jsonschema.Schema {
+	return GenerateSchema[GitLogInput]()
+}
+
+// Description returns the description of the tool
+func (t *GitLogTool) Description() string {
+	return `List the commits in a git revision range, grouped by the area of the repository they touch.
+
+Use this tool to summarise what changed between two releases, for example when drafting release notes or a changelog.
+
+## Input
+- from: (optional) Exclusive start of the range: a tag, branch or commit. Defaults to the latest tag before "to", or the whole history when there are no tags.
+- to: (optional) Inclusive end of the range. Defaults to HEAD.
+- path: (optional) The absolute path of the repository. Defaults to the current working directory.
+
+## Output
+* Commits are grouped by area: the conventional commit scope when the subject has one, otherwise the directory most of the commit's files are in (for example "pkg/llm" or "docs").
+* Each commit lists its short hash, subject, author, date, and the pull requests and issues it references, with links when the origin remote is on GitHub.
+* Merge commits are skipped. At most 500 commits are returned.
+`
+}
+
+// ValidateInput validates the input parameters for the tool
+func (t *GitLogTool) ValidateInput(_ tooltypes.State, parameters string) error {
+	var input GitLogInput
+	if err := json.Unmarshal([]byte(parameters), &input); err != nil {
+		return err
+	}
+	if input.Path != "" && !filepath.IsAbs(input.Path) {
+		return errors.New("path must be an absolute path")
+	}
+	for _, rev := range []string{input.From, input.To} {
+		if strings.HasPrefix(strings.TrimSpace(rev), "-") {
+			return errors.Errorf("invalid revision %q", rev)
+		}
+	}
+	return nil
+}
+
+// TracingKVs returns tracing key-value pairs for observability
+func (t *GitLogTool) TracingKVs(parameters string) ([]attribute.KeyValue, error) {
+	var input GitLogInput
+	if err := json.Unmarshal([]byte(parameters), &input); err != nil {
+		return nil, err
+	}
+	return []attribute.KeyValue{
+		attribute.String("from", input.From),
+		attribute.String("to", input.To),
+		attribute.String("path", input.Path),
+	}, nil
+}
+
+// Execute reads and groups the commits in the requested range
+func (t *GitLogTool) Execute(ctx context.Context, state tooltypes.State, parameters string) tooltypes.ToolResult {
+	var input GitLogInput
+	if err := json.Unmarshal([]byte(parameters), &input); err != nil {
+		return &GitLogToolResult{err: err.Error()}
+	}
+
+	dir := input.Path
+	if dir == "" && state != nil {
+		dir = state.WorkingDirectory()
+	}
+	log, err := gitlog.Read(ctx, gitlog.Options{Dir: dir, From: input.From, To: input.To})
+	if err != nil {
+		return &GitLogToolResult{err: err.Error()}
+	}
+	return &GitLogToolResult{log: log}
+}
+
+// GetResult returns the commits grouped by area
+func (r *GitLogToolResult) GetResult() string {
+	var b strings.Builder
+	rangeName := r.log.To
+	if r.log.From != "" {
+		rangeName = r.log.From + ".." + r.log.To
+	}
+	fmt.Fprintf(&b, "Commits in %s: %d in %d areas\n", rangeName, r.log.Total, len(r.log.Areas))
+	if r.log.Truncated {
+		fmt.Fprintf(&b, "[Only the newest %d commits are listed. Narrow the range to see the rest.]\n", r.log.Total)
+	}
+
+	for _, area := range r.log.Areas {
+		fmt.Fprintf(&b, "\n## %s (%d)\n", area.Name, len(area.Commits))
+		for _, commit := range area.Commits {
+			hash := commit.Hash
+			if len(hash) > 7 {
+				hash = hash[:7]
+			}
+			fmt.Fprintf(&b, "- %s %s (%s, %s)", hash, commit.Subject, commit.Author, commit.Date.Format("2006-01-02"))
+			var refs []string
+			for _, number := range commit.PRs {
+				refs = append(refs, formatGitRef("PR", number, r.log.PullRequestURL(number)))
+			}
+			for _, number := range commit.Issues {
+				refs = append(refs, formatGitRef("issue", number, r.log.IssueURL(number)))
+			}
+			if len(refs) > 0 {
+				fmt.Fprintf(&b, " [%s]", strings.Join(refs, ", "))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func formatGitRef(kind string, number int, url string) string {
+	if url == "" {
+		return fmt.Sprintf("%s #%d", kind, number)
+	}
+	return fmt.Sprintf("%s #%d %s", kind, number, url)
+}
+
+// GetError returns the error message
+func (r *GitLogToolResult) GetError() string {
+	return r.err
+}
+
+// IsError returns true if the result contains an error
+func (r *GitLogToolResult) IsError() bool {
+	return r.err != ""
+}
+
+// AssistantFacing returns the string representation for the AI assistant
+func (r *GitLogToolResult) AssistantFacing() string {
+	var content string
+	if !r.IsError() {
+		content = r.GetResult()
+	}
+	return tooltypes.StringifyToolResult(content, r.GetError())
+}
+
+// StructuredData returns structured metadata about the grouped history
+func (r *GitLogToolResult) StructuredData() tooltypes.StructuredToolResult {
+	result := tooltypes.StructuredToolResult{
+		ToolName:  "git_log",
+		Success:   !r.IsError(),
+		Timestamp: time.Now(),
+	}
+	if r.IsError() {
+		result.Error = r.GetError()
+		return result
+	}
+
+	areas := make([]tooltypes.GitLogArea, 0, len(r.log.Areas))
+	for _, area := range r.log.Areas {
+		areas = append(areas, tooltypes.GitLogArea{Name: area.Name, Commits: len(area.Commits)})
+	}
+	result.Metadata = &tooltypes.GitLogMetadata{
+		From:      r.log.From,
+		To:        r.log.To,
+		RepoURL:   r.log.RepoURL,
+		Total:     r.log.Total,
+		Truncated: r.log.Truncated,
+		Areas:     areas,
+	}
+	return result
+}
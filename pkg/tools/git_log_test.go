@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gitLogTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	commit := func(message, file string) {
+		path := filepath.Join(dir, file)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(message), 0o644))
+		run("add", ".")
+		run("commit", "-m", message)
+	}
+
+	run("init", "-b", "main")
+	run("config", "user.email", "test@example.com")
+	run("config", "user.name", "Test User")
+	commit("initial", "README.md")
+	run("tag", "v1.0.0")
+	commit("Add retries to the client (#42)", "pkg/client/client.go")
+	commit("Describe retries", "docs/retries.md")
+	run("remote", "add", "origin", "https://github.com/acme/widget.git")
+	return dir
+}
+
+func TestGitLogTool_ValidateInput(t *testing.T) {
+	tool := &GitLogTool{}
+	assert.NoError(t, tool.ValidateInput(nil, `{}`))
+	assert.NoError(t, tool.ValidateInput(nil, `{"from": "v1.0.0", "to": "main", "path": "/repo"}`))
+	assert.ErrorContains(t, tool.ValidateInput(nil, `{"path": "repo"}`), "absolute")
+	assert.ErrorContains(t, tool.ValidateInput(nil, `{"from": "--output=/tmp/x"}`), "invalid revision")
+}
+
+func TestGitLogTool_Execute(t *testing.T) {
+	dir := gitLogTestRepo(t)
+	tool := &GitLogTool{}
+
+	result := tool.Execute(context.Background(), NewBasicState(context.Background(), WithWorkingDirectory(dir)), `{}`)
+	require.False(t, result.IsError(), result.GetError())
+
+	output := result.GetResult()
+	assert.Contains(t, output, "Commits in v1.0.0..HEAD: 2 in 2 areas")
+	assert.Contains(t, output, "## pkg/client (1)")
+	assert.Contains(t, output, "Add retries to the client (#42) (Test User,")
+	assert.Contains(t, output, "[PR #42 https://github.com/acme/widget/pull/42]")
+	assert.Contains(t, output, "## docs (1)")
+	assert.NotContains(t, output, "initial")
+
+	structured := result.StructuredData()
+	assert.Equal(t, "git_log", structured.ToolName)
+	var meta tooltypes.GitLogMetadata
+	require.True(t, tooltypes.ExtractMetadata(structured.Metadata, &meta))
+	assert.Equal(t, "v1.0.0", meta.From)
+	assert.Equal(t, 2, meta.Total)
+	assert.Equal(t, "https://github.com/acme/widget", meta.RepoURL)
+	assert.Len(t, meta.Areas, 2)
+}
+
+func TestGitLogTool_ExecuteUnknownRevision(t *testing.T) {
+	dir := gitLogTestRepo(t)
+	tool := &GitLogTool{}
+
+	result := tool.Execute(context.Background(), nil, `{"from": "v9.9.9", "path": "`+dir+`"}`)
+	assert.True(t, result.IsError())
+	assert.Contains(t, result.GetError(), "git log failed")
+}
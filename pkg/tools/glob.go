@@ -196,8 +196,12 @@ func (t *GlobTool) ValidateInput(_ tooltypes.State, parameters string) error {
 	return nil
 }
 
-func getFdPath() string {
-	return binaries.GetFdPath()
+func getFdPath(ctx context.Context) string {
+	path, err := binaries.EnsureFd(ctx)
+	if err != nil {
+		return ""
+	}
+	return path
 }
 
 type fileInfo struct {
@@ -243,7 +247,7 @@ func parseGlobPattern(pattern string) (dir, filePattern string, recursive bool)
 }
 
 func searchWithFd(ctx context.Context, searchPath, pattern string, ignoreGitignore bool) ([]string, error) {
-	fdPath := getFdPath()
+	fdPath := getFdPath(ctx)
 	if fdPath == "" {
 		return nil, errors.New("fd not found")
 	}
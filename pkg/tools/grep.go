@@ -305,9 +305,14 @@ func FormatSearchResults(pattern string, results []SearchResult) string {
 	return output.String()
 }
 
-// getRipgrepPath returns the path to the managed ripgrep binary
-func getRipgrepPath() string {
-	return binaries.GetRipgrepPath()
+// getRipgrepPath returns the path to the managed ripgrep binary, resolving it
+// on first use so startup does not pay for binary discovery.
+func getRipgrepPath(ctx context.Context) string {
+	path, err := binaries.EnsureRipgrep(ctx)
+	if err != nil {
+		return ""
+	}
+	return path
 }
 
 // rgJSONMatch represents a match in ripgrep's JSON output
@@ -343,7 +348,7 @@ type rgJSONSubmatchText struct {
 
 // searchPath searches for pattern using ripgrep in a file or directory
 func searchPath(ctx context.Context, searchPath, pattern, includePattern string, ignoreCase, fixedStrings bool, surroundLines int) ([]SearchResult, error) {
-	rgPath := getRipgrepPath()
+	rgPath := getRipgrepPath(ctx)
 	if rgPath == "" {
 		return nil, errors.New("ripgrep not found")
 	}
@@ -428,7 +428,7 @@ func TestGrepHiddenFilesIgnored(t *testing.T) {
 // TestGrepGitignoreRespected tests that files matching .gitignore patterns are excluded
 func TestGrepGitignoreRespected(t *testing.T) {
 	// Skip if ripgrep is not available
-	if getRipgrepPath() == "" {
+	if getRipgrepPath(context.Background()) == "" {
 		t.Skip("ripgrep not available, skipping test")
 	}
 
@@ -855,7 +855,7 @@ func TestParseRipgrepJSON(t *testing.T) {
 // TestSearchDirectoryRipgrep tests the ripgrep search function directly
 func TestSearchDirectoryRipgrep(t *testing.T) {
 	// Skip if ripgrep is not available
-	if getRipgrepPath() == "" {
+	if getRipgrepPath(context.Background()) == "" {
 		t.Skip("ripgrep not available, skipping test")
 	}
 
@@ -951,7 +951,7 @@ func TestSearchDirectoryRipgrep(t *testing.T) {
 // TestRipgrepBasicSearch tests basic search functionality with ripgrep
 func TestRipgrepBasicSearch(t *testing.T) {
 	// Skip if ripgrep is not available
-	if getRipgrepPath() == "" {
+	if getRipgrepPath(context.Background()) == "" {
 		t.Skip("ripgrep not available, skipping test")
 	}
 
@@ -983,7 +983,7 @@ func TestRipgrepBasicSearch(t *testing.T) {
 }
 
 func TestGrepIgnoreCase(t *testing.T) {
-	if getRipgrepPath() == "" {
+	if getRipgrepPath(context.Background()) == "" {
 		t.Skip("ripgrep not available, skipping test")
 	}
 
@@ -1022,7 +1022,7 @@ func TestGrepIgnoreCase(t *testing.T) {
 }
 
 func TestGrepFixedStrings(t *testing.T) {
-	if getRipgrepPath() == "" {
+	if getRipgrepPath(context.Background()) == "" {
 		t.Skip("ripgrep not available, skipping test")
 	}
 
@@ -1286,7 +1286,7 @@ func TestGrepToolResult_StructuredData_TruncationMetadata(t *testing.T) {
 
 // TestGrepLineTruncation tests that long lines are truncated in search results
 func TestGrepLineTruncation(t *testing.T) {
-	if getRipgrepPath() == "" {
+	if getRipgrepPath(context.Background()) == "" {
 		t.Skip("ripgrep not available, skipping test")
 	}
 
@@ -1320,7 +1320,7 @@ func TestGrepLineTruncation(t *testing.T) {
 
 // TestGrepOutputSizeTruncation tests that output is truncated when exceeding size limit
 func TestGrepOutputSizeTruncation(t *testing.T) {
-	if getRipgrepPath() == "" {
+	if getRipgrepPath(context.Background()) == "" {
 		t.Skip("ripgrep not available, skipping test")
 	}
 
@@ -1392,7 +1392,7 @@ func TestTruncateResultsBySize(t *testing.T) {
 // size truncation is applied even when file limit truncation has already occurred.
 // This prevents output from exceeding grepMaxOutputSize when many large files match.
 func TestSizeTruncationAfterFileLimitTruncation(t *testing.T) {
-	if getRipgrepPath() == "" {
+	if getRipgrepPath(context.Background()) == "" {
 		t.Skip("ripgrep not available, skipping test")
 	}
 
@@ -1441,7 +1441,7 @@ func TestSizeTruncationAfterFileLimitTruncation(t *testing.T) {
 }
 
 func TestGrepMatchPositions_Integration(t *testing.T) {
-	if getRipgrepPath() == "" {
+	if getRipgrepPath(context.Background()) == "" {
 		t.Skip("ripgrep not available, skipping test")
 	}
 
@@ -0,0 +1,261 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/invopop/jsonschema"
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	historySearchScopeCurrent = "current"
+	historySearchScopeRepo    = "repo"
+
+	defaultHistorySearchLimit = 20
+	maxHistorySearchLimit     = 50
+)
+
+// historySearcher runs `kodelet conversation search` with the given flags.
+type historySearcher func(ctx context.Context, query string, flags []string) ([]conversations.SearchMatch, error)
+
+// HistorySearchTool searches saved conversations, including history removed
+// from the context by compaction.
+type HistorySearchTool struct {
+	search historySearcher
+}
+
+// HistorySearchInput reuses the shared history_search input schema while preserving pkg/tools schema IDs.
+type HistorySearchInput tooltypes.HistorySearchInput
+
+// HistorySearchToolResult represents the matches of a history search.
+type HistorySearchToolResult struct {
+	query   string
+	scope   string
+	matches []tooltypes.HistorySearchMatch
+	err     string
+}
+
+// NewHistorySearchTool creates a history_search tool with production dependencies.
+func NewHistorySearchTool() *HistorySearchTool {
+	return &HistorySearchTool{search: defaultHistorySearcher}
+}
+
+// Name returns the tool name.
+func (t *HistorySearchTool) Name() string {
+	return "history_search"
+}
+
+// Description returns the tool description.
+func (t *HistorySearchTool) Description() string {
+	return `Search earlier messages of this conversation and of past conversations in the same working directory.
+
+Use this when:
+- You need a decision, constraint or answer the user gave earlier that is no longer in your context, for example after the context was compacted
+- The user refers to something "we discussed" or "like last time"
+- Before asking the user a question they may already have answered
+
+Input:
+- query: required text to search for, matched case-insensitively as a phrase. Prefer short distinctive phrases such as a library, file or function name
+- scope: "current" searches only this conversation, including history removed by compaction; "repo" (default) searches every saved conversation in the working directory
+- limit: maximum number of matches (default 20, max 50)
+
+Each match shows the conversation ID, the role of the message and a snippet around the match. Matches marked "compacted" are no longer in your context. Use read_conversation with a conversation ID to read more of a past conversation.
+
+Only saved conversations are searched, so messages from the turn in progress are not included.`
+}
+
+// GenerateSchema generates the JSON schema for the tool input.
+func (t *HistorySearchTool) GenerateSchema() *jsonschema.Schema {
+	return GenerateSchema[HistorySearchInput]()
+}
+
+// ValidateInput validates the tool input.
+func (t *HistorySearchTool) ValidateInput(_ tooltypes.State, parameters string) error {
+	input := &HistorySearchInput{}
+	if err := json.Unmarshal([]byte(parameters), input); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(input.Query) == "" {
+		return errors.New("query is required")
+	}
+	switch input.Scope {
+	case "", historySearchScopeCurrent, historySearchScopeRepo:
+	default:
+		return errors.Errorf("invalid scope %q: must be %q or %q", input.Scope, historySearchScopeCurrent, historySearchScopeRepo)
+	}
+	if input.Limit < 0 {
+		return errors.New("limit must not be negative")
+	}
+
+	return nil
+}
+
+// TracingKVs returns tracing attributes for observability.
+func (t *HistorySearchTool) TracingKVs(parameters string) ([]attribute.KeyValue, error) {
+	input := &HistorySearchInput{}
+	if err := json.Unmarshal([]byte(parameters), input); err != nil {
+		return nil, err
+	}
+
+	return []attribute.KeyValue{
+		attribute.String("query", strings.TrimSpace(input.Query)),
+		attribute.String("scope", input.Scope),
+		attribute.Int("limit", input.Limit),
+	}, nil
+}
+
+// Execute executes the history_search tool.
+func (t *HistorySearchTool) Execute(ctx context.Context, state tooltypes.State, parameters string) tooltypes.ToolResult {
+	input := &HistorySearchInput{}
+	if err := json.Unmarshal([]byte(parameters), input); err != nil {
+		return &HistorySearchToolResult{err: err.Error()}
+	}
+
+	query := strings.TrimSpace(input.Query)
+	scope := input.Scope
+	if scope == "" {
+		scope = historySearchScopeRepo
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultHistorySearchLimit
+	}
+	limit = min(limit, maxHistorySearchLimit)
+
+	currentID := ToolContextFromContext(ctx).ConversationID
+	flags := []string{"--limit", strconv.Itoa(limit)}
+	switch scope {
+	case historySearchScopeCurrent:
+		if currentID == "" {
+			return &HistorySearchToolResult{
+				query: query,
+				scope: scope,
+				err:   "This conversation is not saved, so it cannot be searched. Use scope \"repo\" to search past conversations.",
+			}
+		}
+		flags = append(flags, "--conversation", currentID)
+	default:
+		if workingDir := state.WorkingDirectory(); workingDir != "" {
+			flags = append(flags, "--cwd", workingDir)
+		}
+	}
+
+	found, err := t.search(ctx, query, flags)
+	if err != nil {
+		return &HistorySearchToolResult{
+			query: query,
+			scope: scope,
+			err:   fmt.Sprintf("Failed to search conversation history: %s", err),
+		}
+	}
+
+	matches := make([]tooltypes.HistorySearchMatch, 0, len(found))
+	for _, match := range found {
+		matches = append(matches, tooltypes.HistorySearchMatch{
+			ConversationID: match.ConversationID,
+			Role:           match.Role,
+			Snippet:        match.Snippet,
+			UpdatedAt:      match.UpdatedAt,
+			Current:        match.ConversationID == currentID,
+			Compacted:      match.Compacted,
+		})
+	}
+	return &HistorySearchToolResult{query: query, scope: scope, matches: matches}
+}
+
+// AssistantFacing returns the assistant-visible tool output.
+func (r *HistorySearchToolResult) AssistantFacing() string {
+	return tooltypes.StringifyToolResult(r.GetResult(), r.err)
+}
+
+// GetResult returns the matches as text.
+func (r *HistorySearchToolResult) GetResult() string {
+	if r.err != "" {
+		return ""
+	}
+	if len(r.matches) == 0 {
+		return fmt.Sprintf("No messages matching %q were found.", r.query)
+	}
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "Found %d messages matching %q:\n", len(r.matches), r.query)
+	for _, match := range r.matches {
+		labels := []string{match.ConversationID}
+		if match.Current {
+			labels = append(labels, "this conversation")
+		}
+		labels = append(labels, match.UpdatedAt.Format(time.DateOnly), match.Role)
+		if match.Compacted {
+			labels = append(labels, "compacted")
+		}
+		fmt.Fprintf(&output, "\n[%s]\n%s\n", strings.Join(labels, ", "), match.Snippet)
+	}
+	return output.String()
+}
+
+// GetError returns the tool error.
+func (r *HistorySearchToolResult) GetError() string {
+	return r.err
+}
+
+// IsError returns whether the result is an error.
+func (r *HistorySearchToolResult) IsError() bool {
+	return r.err != ""
+}
+
+// StructuredData returns structured metadata about the history_search result.
+func (r *HistorySearchToolResult) StructuredData() tooltypes.StructuredToolResult {
+	result := tooltypes.StructuredToolResult{
+		ToolName:  "history_search",
+		Success:   !r.IsError(),
+		Timestamp: time.Now(),
+		Metadata: &tooltypes.HistorySearchMetadata{
+			Query:   r.query,
+			Scope:   r.scope,
+			Matches: r.matches,
+		},
+	}
+
+	if r.IsError() {
+		result.Error = r.err
+	}
+
+	return result
+}
+
+func defaultHistorySearcher(ctx context.Context, query string, flags []string) ([]conversations.SearchMatch, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get executable path")
+	}
+
+	args := append([]string{"conversation", "search", "--json"}, flags...)
+	args = append(args, "--", query)
+	output, err := exec.CommandContext(ctx, exe, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, errors.Errorf("conversation search failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+
+	var parsed struct {
+		Matches []conversations.SearchMatch `json:"matches"`
+	}
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		return nil, errors.Wrap(err, "failed to parse conversation search output")
+	}
+	return parsed.Matches, nil
+}
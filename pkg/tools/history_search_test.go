@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistorySearchToolValidateInput(t *testing.T) {
+	tool := NewHistorySearchTool()
+
+	require.NoError(t, tool.ValidateInput(nil, `{"query":"job queue"}`))
+	require.NoError(t, tool.ValidateInput(nil, `{"query":"job queue","scope":"current","limit":5}`))
+
+	err := tool.ValidateInput(nil, `{"query":"  "}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "query is required")
+
+	err = tool.ValidateInput(nil, `{"query":"queue","scope":"everywhere"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid scope")
+
+	err = tool.ValidateInput(nil, `{"query":"queue","limit":-1}`)
+	require.Error(t, err)
+}
+
+func TestHistorySearchToolExecuteRepoScope(t *testing.T) {
+	updatedAt := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	tool := &HistorySearchTool{
+		search: func(_ context.Context, query string, flags []string) ([]conversations.SearchMatch, error) {
+			assert.Equal(t, "job queue", query)
+			assert.Equal(t, []string{"--limit", "50", "--cwd", "/repo"}, flags)
+			return []conversations.SearchMatch{
+				{ConversationID: "conv-current", UpdatedAt: updatedAt, Role: "user", Snippet: "use Postgres for the job queue", Compacted: true},
+				{ConversationID: "conv-old", UpdatedAt: updatedAt, Role: "assistant", Snippet: "the job queue lives in pkg/queue"},
+			}, nil
+		},
+	}
+
+	ctx := ContextWithConversationID(context.Background(), "conv-current")
+	state := NewBasicState(context.Background(), WithWorkingDirectory("/repo"))
+	result := tool.Execute(ctx, state, `{"query":" job queue ","limit":500}`)
+
+	require.False(t, result.IsError())
+	output := result.AssistantFacing()
+	assert.Contains(t, output, `Found 2 messages matching "job queue"`)
+	assert.Contains(t, output, "[conv-current, this conversation, 2026-10-16, user, compacted]\nuse Postgres for the job queue")
+	assert.Contains(t, output, "[conv-old, 2026-10-16, assistant]\nthe job queue lives in pkg/queue")
+
+	structured := result.StructuredData()
+	assert.Equal(t, "history_search", structured.ToolName)
+	var meta tooltypes.HistorySearchMetadata
+	require.True(t, tooltypes.ExtractMetadata(structured.Metadata, &meta))
+	assert.Equal(t, "job queue", meta.Query)
+	assert.Equal(t, "repo", meta.Scope)
+	require.Len(t, meta.Matches, 2)
+	assert.True(t, meta.Matches[0].Current)
+	assert.False(t, meta.Matches[1].Current)
+}
+
+func TestHistorySearchToolExecuteCurrentScope(t *testing.T) {
+	tool := &HistorySearchTool{
+		search: func(_ context.Context, _ string, flags []string) ([]conversations.SearchMatch, error) {
+			assert.Equal(t, []string{"--limit", "20", "--conversation", "conv-current"}, flags)
+			return nil, nil
+		},
+	}
+
+	ctx := ContextWithConversationID(context.Background(), "conv-current")
+	result := tool.Execute(ctx, NewBasicState(context.Background()), `{"query":"redis","scope":"current"}`)
+	require.False(t, result.IsError())
+	assert.Contains(t, result.AssistantFacing(), `No messages matching "redis" were found.`)
+
+	result = tool.Execute(context.Background(), NewBasicState(context.Background()), `{"query":"redis","scope":"current"}`)
+	require.True(t, result.IsError())
+	assert.Contains(t, result.GetError(), "not saved")
+}
+
+func TestHistorySearchToolExecuteSearchError(t *testing.T) {
+	tool := &HistorySearchTool{
+		search: func(context.Context, string, []string) ([]conversations.SearchMatch, error) {
+			return nil, errors.New("store unavailable")
+		},
+	}
+
+	result := tool.Execute(context.Background(), NewBasicState(context.Background()), `{"query":"redis"}`)
+	require.True(t, result.IsError())
+	assert.Contains(t, result.GetError(), "Failed to search conversation history: store unavailable")
+}
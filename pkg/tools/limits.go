@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// toolLimit returns the tool_limits of a call of toolName, or only the
+// default output limit when the state carries no configuration.
+func toolLimit(state tooltypes.State, toolName string) llmtypes.ToolLimit {
+	config, _ := state.GetLLMConfig().(llmtypes.Config)
+	return config.ToolLimit(toolName)
+}
+
+// runWithTimeout runs execute, giving up once timeout passes. The context
+// passed to execute is cancelled at that point, so tools that honour it stop;
+// the call is reported as timed out either way.
+func runWithTimeout(
+	ctx context.Context,
+	toolName string,
+	timeout time.Duration,
+	execute func(context.Context) tooltypes.ToolResult,
+) tooltypes.ToolResult {
+	if timeout <= 0 {
+		return execute(ctx)
+	}
+
+	limitedCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan tooltypes.ToolResult, 1)
+	go func() {
+		done <- execute(limitedCtx)
+	}()
+
+	select {
+	case result := <-done:
+		return result
+	case <-limitedCtx.Done():
+		if ctx.Err() != nil {
+			// The caller gave up, not the limit; let the tool report it.
+			return <-done
+		}
+		return tooltypes.BaseToolResult{
+			Error: fmt.Sprintf("%s timed out after %s (tool_limits timeout)", toolName, timeout),
+		}
+	}
+}
+
+// limitedToolResult replaces the model-facing text of a result whose output
+// exceeded tool_limits.max_output_bytes.
+type limitedToolResult struct {
+	tooltypes.ToolResult
+	assistantFacing string
+}
+
+// AssistantFacing returns the truncated output.
+func (r limitedToolResult) AssistantFacing() string {
+	return r.assistantFacing
+}
+
+// limitToolOutput keeps the head and tail of the model-facing text of result
+// when it is longer than maxBytes. Multimodal results are left alone, since
+// their content parts carry images rather than text.
+func limitToolOutput(result tooltypes.ToolResult, maxBytes int) tooltypes.ToolResult {
+	if result == nil || maxBytes <= 0 {
+		return result
+	}
+	if _, ok := result.(tooltypes.MultiModalToolResult); ok {
+		return result
+	}
+	text := result.AssistantFacing()
+	if len(text) <= maxBytes {
+		return result
+	}
+	return limitedToolResult{
+		ToolResult:      result,
+		assistantFacing: fmt.Sprintf("Total output bytes: %d\n\n%s", len(text), truncateMiddleByBytesEstimate(text, maxBytes, false)),
+	}
+}
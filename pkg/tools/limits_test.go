@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type slowTestTool struct {
+	*testTool
+}
+
+func (t *slowTestTool) Execute(ctx context.Context, _ tooltypes.State, _ string) tooltypes.ToolResult {
+	select {
+	case <-ctx.Done():
+		return tooltypes.BaseToolResult{Error: ctx.Err().Error()}
+	case <-time.After(5 * time.Second):
+		return tooltypes.BaseToolResult{Result: "finished"}
+	}
+}
+
+func TestRunToolAppliesTimeout(t *testing.T) {
+	tool := &slowTestTool{testTool: &testTool{name: "slow_tool"}}
+	state := NewBasicState(context.Background(),
+		WithExtensionTools([]tooltypes.Tool{tool}),
+		WithLLMConfig(llmtypes.Config{ToolLimits: &llmtypes.ToolLimitsConfig{
+			Tools: map[string]llmtypes.ToolLimit{"slow_tool": {Timeout: 20 * time.Millisecond}},
+		}}),
+	)
+
+	result := RunTool(context.Background(), state, "slow_tool", `{}`)
+
+	require.True(t, result.IsError())
+	assert.Equal(t, "slow_tool timed out after 20ms (tool_limits timeout)", result.GetError())
+}
+
+func TestRunToolTruncatesLongOutput(t *testing.T) {
+	output := "head\n" + strings.Repeat("x", 10_000) + "\ntail"
+	tool := &testTool{name: "noisy_tool", result: tooltypes.BaseToolResult{Result: output}}
+	state := NewBasicState(context.Background(),
+		WithExtensionTools([]tooltypes.Tool{tool}),
+		WithLLMConfig(llmtypes.Config{ToolLimits: &llmtypes.ToolLimitsConfig{
+			ToolLimit: llmtypes.ToolLimit{MaxOutputBytes: 1_000},
+		}}),
+	)
+
+	result := RunTool(context.Background(), state, "noisy_tool", `{}`)
+
+	facing := result.AssistantFacing()
+	assert.Less(t, len(facing), 1_100)
+	assert.True(t, strings.HasPrefix(facing, "Total output bytes: "))
+	assert.Contains(t, facing, "<result>\nhead")
+	assert.Contains(t, facing, "chars truncated")
+	assert.Contains(t, facing, "tail\n</result>")
+	assert.Equal(t, output, result.GetResult())
+}
+
+func TestLimitToolOutputLeavesShortAndMultiModalResults(t *testing.T) {
+	short := tooltypes.BaseToolResult{Result: "ok"}
+	assert.Equal(t, short, limitToolOutput(short, 100))
+
+	long := tooltypes.BaseToolResult{Result: strings.Repeat("x", 200)}
+	assert.Equal(t, long, limitToolOutput(long, -1))
+
+	image := &ViewImageToolResult{}
+	assert.Same(t, image, limitToolOutput(image, 1))
+}
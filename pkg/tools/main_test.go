@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"testing"
 
@@ -13,5 +14,15 @@ func TestMain(m *testing.M) {
 	// Ensure binaries are available before running tests
 	_, _ = binaries.EnsureRipgrep(ctx)
 	_, _ = binaries.EnsureFd(ctx)
-	os.Exit(m.Run())
+
+	// Keep audit logs written by network tools out of the user's home.
+	basePath, err := os.MkdirTemp("", "kodelet-tools-test-*")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create test base path: %v\n", err)
+		os.Exit(1)
+	}
+	os.Setenv("KODELET_BASE_PATH", basePath)
+	code := m.Run()
+	os.RemoveAll(basePath)
+	os.Exit(code)
 }
@@ -0,0 +1,350 @@
+package tools
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	toml "github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestContextName is the file name under which the generated project
+// manifest summary is reported in the discovered contexts.
+const ManifestContextName = "project-manifests (generated by kodelet)"
+
+const (
+	maxManifestDependencies = 25
+	maxManifestScripts      = 20
+	maxWorkflowCommands     = 15
+)
+
+// manifestSummarizers turn a single manifest file into summary lines. Each
+// returns nil when the file is missing or cannot be parsed.
+var manifestSummarizers = []struct {
+	title string
+	path  string
+	parse func(path string) []string
+}{
+	{title: "go.mod", path: "go.mod", parse: summarizeGoMod},
+	{title: "package.json", path: "package.json", parse: summarizePackageJSON},
+	{title: "pyproject.toml", path: "pyproject.toml", parse: summarizePyProject},
+	{title: "Cargo.toml", path: "Cargo.toml", parse: summarizeCargoToml},
+}
+
+// summarizeManifests builds a concise summary of the package manifests and CI
+// workflows in dir so the model can learn the stack without exploring it.
+func summarizeManifests(dir string) string {
+	if dir == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, summarizer := range manifestSummarizers {
+		lines := summarizer.parse(filepath.Join(dir, summarizer.path))
+		writeManifestSection(&b, summarizer.title, lines)
+	}
+
+	workflows, _ := filepath.Glob(filepath.Join(dir, ".github", "workflows", "*.y*ml"))
+	sort.Strings(workflows)
+	for _, workflow := range workflows {
+		rel, _ := filepath.Rel(dir, workflow)
+		writeManifestSection(&b, rel, summarizeWorkflow(workflow))
+	}
+
+	if b.Len() == 0 {
+		return ""
+	}
+	return "Summary of the project manifests and CI workflows in the working directory. Prefer these scripts and toolchain versions over exploratory commands.\n" + b.String()
+}
+
+func writeManifestSection(b *strings.Builder, title string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n## %s\n", title)
+	for _, line := range lines {
+		b.WriteString("- ")
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+}
+
+func summarizeGoMod(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var lines, deps []string
+	inRequire := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case inRequire && line == ")":
+			inRequire = false
+		case inRequire:
+			if dep := goModRequirement(line); dep != "" {
+				deps = append(deps, dep)
+			}
+		case line == "require (":
+			inRequire = true
+		case strings.HasPrefix(line, "require "):
+			if dep := goModRequirement(strings.TrimPrefix(line, "require ")); dep != "" {
+				deps = append(deps, dep)
+			}
+		case strings.HasPrefix(line, "module "):
+			lines = append(lines, "module: "+strings.TrimSpace(strings.TrimPrefix(line, "module ")))
+		case strings.HasPrefix(line, "go "):
+			lines = append(lines, "go version: "+strings.TrimSpace(strings.TrimPrefix(line, "go ")))
+		case strings.HasPrefix(line, "toolchain "):
+			lines = append(lines, "toolchain: "+strings.TrimSpace(strings.TrimPrefix(line, "toolchain ")))
+		}
+	}
+	if len(deps) > 0 {
+		lines = append(lines, "direct dependencies: "+joinLimited(deps, maxManifestDependencies))
+	}
+	return lines
+}
+
+func goModRequirement(line string) string {
+	if strings.Contains(line, "// indirect") {
+		return ""
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 || strings.HasPrefix(fields[0], "//") {
+		return ""
+	}
+	return fields[0] + " " + fields[1]
+}
+
+func summarizePackageJSON(path string) []string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var manifest struct {
+		Name            string            `json:"name"`
+		PackageManager  string            `json:"packageManager"`
+		Engines         map[string]string `json:"engines"`
+		Scripts         map[string]string `json:"scripts"`
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil
+	}
+
+	var lines []string
+	if manifest.Name != "" {
+		lines = append(lines, "name: "+manifest.Name)
+	}
+	if manifest.PackageManager != "" {
+		lines = append(lines, "package manager: "+manifest.PackageManager)
+	}
+	if len(manifest.Engines) > 0 {
+		lines = append(lines, "engines: "+joinLimited(formatKeyValues(manifest.Engines, " "), maxManifestDependencies))
+	}
+	for _, script := range limitStrings(sortedKeys(manifest.Scripts), maxManifestScripts) {
+		lines = append(lines, fmt.Sprintf("script %s: %s", script, manifest.Scripts[script]))
+	}
+	if len(manifest.Dependencies) > 0 {
+		lines = append(lines, "dependencies: "+joinLimited(formatKeyValues(manifest.Dependencies, "@"), maxManifestDependencies))
+	}
+	if len(manifest.DevDependencies) > 0 {
+		lines = append(lines, "dev dependencies: "+joinLimited(formatKeyValues(manifest.DevDependencies, "@"), maxManifestDependencies))
+	}
+	return lines
+}
+
+func summarizePyProject(path string) []string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var manifest struct {
+		Project struct {
+			Name           string              `toml:"name"`
+			RequiresPython string              `toml:"requires-python"`
+			Dependencies   []string            `toml:"dependencies"`
+			Scripts        map[string]string   `toml:"scripts"`
+			Optional       map[string][]string `toml:"optional-dependencies"`
+		} `toml:"project"`
+		BuildSystem struct {
+			BuildBackend string `toml:"build-backend"`
+		} `toml:"build-system"`
+		Tool map[string]any `toml:"tool"`
+	}
+	if err := toml.Unmarshal(content, &manifest); err != nil {
+		return nil
+	}
+
+	var lines []string
+	if manifest.Project.Name != "" {
+		lines = append(lines, "name: "+manifest.Project.Name)
+	}
+	if manifest.Project.RequiresPython != "" {
+		lines = append(lines, "requires-python: "+manifest.Project.RequiresPython)
+	}
+	if manifest.BuildSystem.BuildBackend != "" {
+		lines = append(lines, "build backend: "+manifest.BuildSystem.BuildBackend)
+	}
+	if len(manifest.Tool) > 0 {
+		lines = append(lines, "tool config: "+strings.Join(sortedKeys(manifest.Tool), ", "))
+	}
+	for _, script := range limitStrings(sortedKeys(manifest.Project.Scripts), maxManifestScripts) {
+		lines = append(lines, fmt.Sprintf("script %s: %s", script, manifest.Project.Scripts[script]))
+	}
+	if len(manifest.Project.Dependencies) > 0 {
+		lines = append(lines, "dependencies: "+joinLimited(manifest.Project.Dependencies, maxManifestDependencies))
+	}
+	if len(manifest.Project.Optional) > 0 {
+		lines = append(lines, "optional dependency groups: "+strings.Join(sortedKeys(manifest.Project.Optional), ", "))
+	}
+	return lines
+}
+
+func summarizeCargoToml(path string) []string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var manifest struct {
+		Package struct {
+			Name        string `toml:"name"`
+			Edition     string `toml:"edition"`
+			RustVersion string `toml:"rust-version"`
+		} `toml:"package"`
+		Workspace struct {
+			Members []string `toml:"members"`
+		} `toml:"workspace"`
+		Dependencies    map[string]any `toml:"dependencies"`
+		DevDependencies map[string]any `toml:"dev-dependencies"`
+	}
+	if err := toml.Unmarshal(content, &manifest); err != nil {
+		return nil
+	}
+
+	var lines []string
+	if manifest.Package.Name != "" {
+		lines = append(lines, "crate: "+manifest.Package.Name)
+	}
+	if manifest.Package.Edition != "" {
+		lines = append(lines, "edition: "+manifest.Package.Edition)
+	}
+	if manifest.Package.RustVersion != "" {
+		lines = append(lines, "rust-version: "+manifest.Package.RustVersion)
+	}
+	if len(manifest.Workspace.Members) > 0 {
+		lines = append(lines, "workspace members: "+joinLimited(manifest.Workspace.Members, maxManifestDependencies))
+	}
+	if len(manifest.Dependencies) > 0 {
+		lines = append(lines, "dependencies: "+joinLimited(sortedKeys(manifest.Dependencies), maxManifestDependencies))
+	}
+	if len(manifest.DevDependencies) > 0 {
+		lines = append(lines, "dev dependencies: "+joinLimited(sortedKeys(manifest.DevDependencies), maxManifestDependencies))
+	}
+	return lines
+}
+
+func summarizeWorkflow(path string) []string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var workflow struct {
+		Name string `yaml:"name"`
+		Jobs map[string]struct {
+			Steps []struct {
+				Uses string            `yaml:"uses"`
+				Run  string            `yaml:"run"`
+				With map[string]string `yaml:"with"`
+			} `yaml:"steps"`
+		} `yaml:"jobs"`
+	}
+	if err := yaml.Unmarshal(content, &workflow); err != nil {
+		return nil
+	}
+
+	var lines []string
+	if workflow.Name != "" {
+		lines = append(lines, "workflow: "+workflow.Name)
+	}
+	var toolchains, commands []string
+	for _, jobName := range sortedKeys(workflow.Jobs) {
+		for _, step := range workflow.Jobs[jobName].Steps {
+			if strings.Contains(step.Uses, "/setup-") {
+				toolchains = appendUnique(toolchains, formatSetupAction(step.Uses, step.With))
+			}
+			for _, command := range strings.Split(step.Run, "\n") {
+				if command = strings.TrimSpace(command); command != "" && !strings.HasPrefix(command, "#") {
+					commands = appendUnique(commands, command)
+				}
+			}
+		}
+	}
+	if len(toolchains) > 0 {
+		lines = append(lines, "toolchains: "+strings.Join(toolchains, ", "))
+	}
+	for _, command := range limitStrings(commands, maxWorkflowCommands) {
+		lines = append(lines, "run: "+command)
+	}
+	return lines
+}
+
+func formatSetupAction(uses string, with map[string]string) string {
+	action, _, _ := strings.Cut(uses, "@")
+	for _, key := range sortedKeys(with) {
+		if strings.HasSuffix(key, "-version") || strings.HasSuffix(key, "-version-file") || key == "toolchain" {
+			return fmt.Sprintf("%s (%s: %s)", action, key, with[key])
+		}
+	}
+	return action
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatKeyValues(values map[string]string, sep string) []string {
+	formatted := make([]string, 0, len(values))
+	for _, key := range sortedKeys(values) {
+		formatted = append(formatted, key+sep+values[key])
+	}
+	return formatted
+}
+
+func limitStrings(values []string, limit int) []string {
+	if len(values) <= limit {
+		return values
+	}
+	return values[:limit]
+}
+
+func joinLimited(values []string, limit int) string {
+	joined := strings.Join(limitStrings(values, limit), ", ")
+	if len(values) > limit {
+		joined += fmt.Sprintf(", … (%d more)", len(values)-limit)
+	}
+	return joined
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
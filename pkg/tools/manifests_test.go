@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeManifest(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestSummarizeManifests(t *testing.T) {
+	dir := t.TempDir()
+	writeManifest(t, dir, "go.mod", `module example.com/app
+
+go 1.22
+
+toolchain go1.22.3
+
+require github.com/pkg/errors v0.9.1
+
+require (
+	github.com/spf13/cobra v1.9.1
+	golang.org/x/sys v0.30.0 // indirect
+)
+`)
+	writeManifest(t, dir, "package.json", `{
+  "name": "web",
+  "packageManager": "pnpm@9.1.0",
+  "engines": {"node": ">=20"},
+  "scripts": {"test": "vitest run", "build": "vite build"},
+  "dependencies": {"react": "^18.3.0"},
+  "devDependencies": {"vitest": "^1.6.0"}
+}`)
+	writeManifest(t, dir, "pyproject.toml", `[project]
+name = "tooling"
+requires-python = ">=3.11"
+dependencies = ["requests>=2"]
+
+[build-system]
+build-backend = "hatchling.build"
+
+[tool.ruff]
+line-length = 100
+`)
+	writeManifest(t, dir, "Cargo.toml", `[package]
+name = "engine"
+edition = "2021"
+rust-version = "1.78"
+
+[dependencies]
+serde = { version = "1", features = ["derive"] }
+`)
+	writeManifest(t, dir, ".github/workflows/ci.yml", `name: CI
+on: [push]
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - uses: actions/setup-go@v5
+        with:
+          go-version: 1.22
+      - run: |
+          # comment
+          make lint
+          make test
+`)
+
+	summary := summarizeManifests(dir)
+
+	assert.Contains(t, summary, "## go.mod")
+	assert.Contains(t, summary, "- module: example.com/app")
+	assert.Contains(t, summary, "- go version: 1.22")
+	assert.Contains(t, summary, "- toolchain: go1.22.3")
+	assert.Contains(t, summary, "- direct dependencies: github.com/pkg/errors v0.9.1, github.com/spf13/cobra v1.9.1")
+	assert.NotContains(t, summary, "golang.org/x/sys")
+
+	assert.Contains(t, summary, "- package manager: pnpm@9.1.0")
+	assert.Contains(t, summary, "- engines: node >=20")
+	assert.Contains(t, summary, "- script build: vite build")
+	assert.Contains(t, summary, "- script test: vitest run")
+	assert.Contains(t, summary, "- dev dependencies: vitest@^1.6.0")
+
+	assert.Contains(t, summary, "- requires-python: >=3.11")
+	assert.Contains(t, summary, "- build backend: hatchling.build")
+	assert.Contains(t, summary, "- tool config: ruff")
+
+	assert.Contains(t, summary, "- crate: engine")
+	assert.Contains(t, summary, "- rust-version: 1.78")
+	assert.Contains(t, summary, "- dependencies: serde")
+
+	assert.Contains(t, summary, "## "+filepath.Join(".github", "workflows", "ci.yml"))
+	assert.Contains(t, summary, "- toolchains: actions/setup-go (go-version: 1.22)")
+	assert.Contains(t, summary, "- run: make lint")
+	assert.Contains(t, summary, "- run: make test")
+	assert.NotContains(t, summary, "# comment")
+}
+
+func TestSummarizeManifestsSkipsMissingAndInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	assert.Empty(t, summarizeManifests(dir))
+
+	writeManifest(t, dir, "package.json", "{not json")
+	assert.Empty(t, summarizeManifests(dir))
+}
+
+func TestJoinLimited(t *testing.T) {
+	assert.Equal(t, "a, b", joinLimited([]string{"a", "b"}, 2))
+	assert.Equal(t, "a, b, … (1 more)", joinLimited([]string{"a", "b", "c"}, 2))
+}
+
+func TestBasicState_DiscoverContextsIncludesManifestSummary(t *testing.T) {
+	dir := t.TempDir()
+	writeManifest(t, dir, "go.mod", "module example.com/app\n\ngo 1.22\n")
+	key := filepath.Join(dir, ManifestContextName)
+
+	state := NewBasicState(context.Background(), WithWorkingDirectory(dir))
+	contexts := state.DiscoverContexts()
+	require.Contains(t, contexts, key)
+	assert.Contains(t, contexts[key], "module: example.com/app")
+
+	disabled := NewBasicState(context.Background(), WithLLMConfig(llmtypes.Config{
+		WorkingDirectory: dir,
+		Context:          &llmtypes.ContextConfig{DisableManifests: true},
+	}))
+	assert.NotContains(t, disabled.DiscoverContexts(), key)
+}
@@ -75,6 +75,9 @@ func (r *BashRenderer) renderMarkdown(result tools.StructuredToolResult, include
 	if meta.WorkingDir != "" {
 		fmt.Fprintf(&output, "- **Working directory:** %s\n", inlineCode(meta.WorkingDir))
 	}
+	if meta.Container != "" {
+		fmt.Fprintf(&output, "- **Dev container:** %s at %s\n", inlineCode(meta.Container), inlineCode(meta.ContainerDir))
+	}
 	fmt.Fprintf(&output, "- **Execution time:** %s\n", inlineCode(meta.ExecutionTime.String()))
 	if result.Error != "" {
 		fmt.Fprintf(&output, "- **Error:** %s\n", inlineCode(result.Error))
@@ -104,6 +107,9 @@ func (r *BashRenderer) renderBashMetadata(meta tools.BashMetadata, output *strin
 	if meta.WorkingDir != "" {
 		fmt.Fprintf(output, "Working Directory: %s\n", meta.WorkingDir)
 	}
+	if meta.Container != "" {
+		fmt.Fprintf(output, "Dev Container: %s (%s)\n", meta.Container, meta.ContainerDir)
+	}
 
 	fmt.Fprintf(output, "Execution Time: %v\n", meta.ExecutionTime)
 
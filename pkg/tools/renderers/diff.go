@@ -0,0 +1,58 @@
+package renderers
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/jingkaihe/kodelet/pkg/diffview"
+)
+
+var (
+	diffAddedColor   = color.New(color.FgGreen)
+	diffRemovedColor = color.New(color.FgRed)
+	diffHeaderColor  = color.New(color.FgCyan)
+)
+
+// renderDiffLines joins rendered diff lines, coloring added, removed and
+// hunk header lines when colored is set.
+func renderDiffLines(lines []diffview.RenderedLine, colored bool) string {
+	if !colored {
+		return diffview.RenderedText(lines)
+	}
+
+	parts := make([]string, 0, len(lines))
+	for _, line := range lines {
+		switch line.Kind {
+		case diffview.LineAdded:
+			parts = append(parts, diffAddedColor.Sprint(line.Text))
+		case diffview.LineRemoved:
+			parts = append(parts, diffRemovedColor.Sprint(line.Text))
+		case diffview.LineHeader, diffview.LineMeta:
+			parts = append(parts, diffHeaderColor.Sprint(line.Text))
+		default:
+			parts = append(parts, line.Text)
+		}
+	}
+	return strings.TrimSuffix(strings.Join(parts, "\n"), "\n")
+}
+
+// classifyUnifiedDiff splits the text of a unified diff into lines by kind,
+// keeping the text of each line as it is.
+func classifyUnifiedDiff(diff string) []diffview.RenderedLine {
+	var lines []diffview.RenderedLine
+	for _, text := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		kind := diffview.LineContext
+		switch {
+		case strings.HasPrefix(text, "+++ "), strings.HasPrefix(text, "--- "):
+			kind = diffview.LineMeta
+		case strings.HasPrefix(text, "@@"):
+			kind = diffview.LineHeader
+		case strings.HasPrefix(text, "+"):
+			kind = diffview.LineAdded
+		case strings.HasPrefix(text, "-"):
+			kind = diffview.LineRemoved
+		}
+		lines = append(lines, diffview.RenderedLine{Kind: kind, Text: text})
+	}
+	return lines
+}
@@ -34,5 +34,8 @@ func (r *ExtensionToolRenderer) RenderCLI(result tools.StructuredToolResult) str
 		output.WriteString("\n")
 		output.WriteString(meta.Output)
 	}
+	if meta.Images > 0 {
+		fmt.Fprintf(&output, "\n[%d image(s) passed to the model]", meta.Images)
+	}
 	return output.String()
 }
@@ -26,6 +26,13 @@ func (r *FileReadRenderer) RenderCLI(result tools.StructuredToolResult) string {
 		return "Error: Invalid metadata type for file_read"
 	}
 
+	if meta.Outline {
+		return fmt.Sprintf("File Outline: %s\n%s", meta.FilePath, strings.Join(meta.Lines, "\n"))
+	}
+	if meta.Binary != nil {
+		return fmt.Sprintf("File Read: %s\n%s", meta.FilePath, meta.Binary.Notice())
+	}
+
 	buf := bytes.NewBufferString(fmt.Sprintf("File Read: %s\n", meta.FilePath))
 	fmt.Fprintf(buf, "Offset: %d\n", meta.Offset)
 	buf.WriteString(osutil.ContentWithLineNumber(meta.Lines, meta.Offset))
@@ -57,6 +64,9 @@ func (r *FileReadRenderer) RenderToolUseMarkdown(rawInput string) string {
 	if input.LineLimit > 0 {
 		fmt.Fprintf(&output, "- **Line limit:** %d\n", input.LineLimit)
 	}
+	if input.Mode != "" {
+		fmt.Fprintf(&output, "- **Mode:** %s\n", input.Mode)
+	}
 
 	return strings.TrimSpace(output.String())
 }
@@ -80,6 +90,19 @@ func (r *FileReadRenderer) renderMarkdown(result tools.StructuredToolResult, inc
 	if includePath {
 		fmt.Fprintf(&output, "- **Path:** %s\n", inlineCode(meta.FilePath))
 	}
+	if meta.Outline {
+		fmt.Fprintf(&output, "- **Outline:** %d lines\n", meta.TotalLines)
+		if meta.Language != "" {
+			fmt.Fprintf(&output, "- **Language:** %s\n", inlineCode(meta.Language))
+		}
+		output.WriteString("\n")
+		output.WriteString(fencedCodeBlock("text", strings.Join(meta.Lines, "\n")))
+		return strings.TrimSpace(output.String())
+	}
+	if meta.Binary != nil {
+		fmt.Fprintf(&output, "- **Binary:** %s, %d bytes (content withheld)\n", meta.Binary.Type, meta.Binary.Size)
+		return strings.TrimSpace(output.String())
+	}
 	if includeOffset {
 		fmt.Fprintf(&output, "- **Offset:** %d\n", meta.Offset)
 	}
@@ -180,6 +203,16 @@ type FileEditRenderer struct{}
 // RenderCLI renders file edit results in CLI format with unified diff output, showing the
 // file path, number of replacements, and the changes made to the file.
 func (r *FileEditRenderer) RenderCLI(result tools.StructuredToolResult) string {
+	return r.renderCLI(result, false)
+}
+
+// RenderColorCLI renders file edit results like RenderCLI, with added and
+// removed lines colored.
+func (r *FileEditRenderer) RenderColorCLI(result tools.StructuredToolResult) string {
+	return r.renderCLI(result, true)
+}
+
+func (r *FileEditRenderer) renderCLI(result tools.StructuredToolResult, colored bool) string {
 	if !result.Success {
 		return fmt.Sprintf("Error: %s", result.Error)
 	}
@@ -195,33 +228,43 @@ func (r *FileEditRenderer) RenderCLI(result tools.StructuredToolResult) string {
 	}
 
 	var output bytes.Buffer
-	if meta.ReplaceAll && meta.ReplacedCount > 1 {
-		fmt.Fprintf(&output, "File edited: %s (%d replacements)\n\n", meta.FilePath, meta.ReplacedCount)
+	fmt.Fprintf(&output, "%s\n\n", fileEditHeader(meta))
+
+	// The diff of the whole file carries the file's line numbers; results
+	// saved before it was recorded fall back to a diff per edit.
+	if file := diffview.FromFileEditMetadata(meta); len(file.Lines) > 0 {
+		output.WriteString(renderDiffLines(diffview.RenderFileBody(file), colored))
+		return output.String()
+	}
 
-		// Show all edits
-		for i, edit := range meta.Edits {
+	for i, edit := range meta.Edits {
+		if len(meta.Edits) > 1 {
 			fmt.Fprintf(&output, "Edit %d (lines %d-%d):\n", i+1, edit.StartLine, edit.EndLine)
-			diff := udiff.Unified(meta.FilePath, meta.FilePath, edit.OldContent, edit.NewContent)
-			output.WriteString(diff)
-			if i < len(meta.Edits)-1 {
-				output.WriteString("\n")
-			}
-		}
-	} else {
-		// Single edit or replace_all with single occurrence
-		edit := meta.Edits[0]
-		if meta.ReplaceAll {
-			fmt.Fprintf(&output, "File edited: %s (1 replacement)\n\n", meta.FilePath)
-		} else {
-			fmt.Fprintf(&output, "File edited: %s\n\n", meta.FilePath)
 		}
 		diff := udiff.Unified(meta.FilePath, meta.FilePath, edit.OldContent, edit.NewContent)
+		if colored {
+			diff = renderDiffLines(classifyUnifiedDiff(diff), true) + "\n"
+		}
 		output.WriteString(diff)
+		if i < len(meta.Edits)-1 {
+			output.WriteString("\n")
+		}
 	}
 
 	return output.String()
 }
 
+func fileEditHeader(meta tools.FileEditMetadata) string {
+	switch {
+	case meta.ReplaceAll && meta.ReplacedCount > 1:
+		return fmt.Sprintf("File edited: %s (%d replacements)", meta.FilePath, meta.ReplacedCount)
+	case meta.ReplaceAll:
+		return fmt.Sprintf("File edited: %s (1 replacement)", meta.FilePath)
+	default:
+		return fmt.Sprintf("File edited: %s", meta.FilePath)
+	}
+}
+
 // RenderMarkdown renders file edit results in markdown format.
 func (r *FileEditRenderer) RenderMarkdown(result tools.StructuredToolResult) string {
 	return r.renderMarkdown(result, true)
@@ -277,13 +320,16 @@ func (r *FileEditRenderer) renderMarkdown(result tools.StructuredToolResult, inc
 
 	var output strings.Builder
 	if includeHeader {
-		if meta.ReplaceAll && meta.ReplacedCount > 1 {
-			fmt.Fprintf(&output, "File edited: %s (%d replacements)\n", meta.FilePath, meta.ReplacedCount)
-		} else if meta.ReplaceAll {
-			fmt.Fprintf(&output, "File edited: %s (1 replacement)\n", meta.FilePath)
-		} else {
-			fmt.Fprintf(&output, "File edited: %s\n", meta.FilePath)
+		fmt.Fprintf(&output, "%s\n", fileEditHeader(meta))
+	}
+
+	if file := diffview.FromFileEditMetadata(meta); len(file.Lines) > 0 {
+		if output.Len() > 0 {
+			output.WriteString("\n")
 		}
+		fmt.Fprintf(&output, "Changes (+%d -%d):\n\n", file.Added, file.Removed)
+		output.WriteString(fencedCodeBlock("diff", diffview.RenderedText(diffview.RenderFileBody(file))))
+		return strings.TrimSpace(output.String())
 	}
 
 	for i, edit := range meta.Edits {
@@ -307,6 +353,16 @@ type ApplyPatchRenderer struct{}
 
 // RenderCLI renders apply_patch results with a summary and unified diffs.
 func (r *ApplyPatchRenderer) RenderCLI(result tools.StructuredToolResult) string {
+	return r.renderCLI(result, false)
+}
+
+// RenderColorCLI renders apply_patch results like RenderCLI, with added and
+// removed lines colored.
+func (r *ApplyPatchRenderer) RenderColorCLI(result tools.StructuredToolResult) string {
+	return r.renderCLI(result, true)
+}
+
+func (r *ApplyPatchRenderer) renderCLI(result tools.StructuredToolResult, colored bool) string {
 	var meta tools.ApplyPatchMetadata
 	if !tools.ExtractMetadata(result.Metadata, &meta) {
 		if !result.Success {
@@ -324,7 +380,7 @@ func (r *ApplyPatchRenderer) RenderCLI(result tools.StructuredToolResult) string
 	summary := diffview.FromApplyPatchMetadata(meta)
 	fmt.Fprintf(&output, " (+%d -%d):", summary.Added, summary.Removed)
 
-	rendered := diffview.RenderedText(diffview.RenderSummary(summary))
+	rendered := renderDiffLines(diffview.RenderSummary(summary), colored)
 	if strings.TrimSpace(rendered) != "" {
 		output.WriteString("\n")
 		output.WriteString(rendered)
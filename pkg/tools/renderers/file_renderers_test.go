@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/fatih/color"
 	"github.com/jingkaihe/kodelet/pkg/types/tools"
 	"github.com/stretchr/testify/assert"
 )
@@ -32,6 +33,21 @@ func TestFileReadRenderer(t *testing.T) {
 		assert.Contains(t, output, "package main", "Expected file content in output")
 	})
 
+	t.Run("Binary file read", func(t *testing.T) {
+		result := tools.StructuredToolResult{
+			ToolName:  "file_read",
+			Success:   true,
+			Timestamp: time.Now(),
+			Metadata: &tools.FileReadMetadata{
+				FilePath: "/test/app",
+				Binary:   &tools.BinaryContent{Size: 2048, Type: "ELF"},
+			},
+		}
+
+		assert.Equal(t, "File Read: /test/app\nbinary content withheld (2048 bytes, type: ELF)", renderer.RenderCLI(result))
+		assert.Contains(t, renderer.RenderMarkdown(result), "- **Binary:** ELF, 2048 bytes (content withheld)")
+	})
+
 	t.Run("Truncated file read", func(t *testing.T) {
 		result := tools.StructuredToolResult{
 			ToolName:  "file_read",
@@ -51,6 +67,31 @@ func TestFileReadRenderer(t *testing.T) {
 		assert.Contains(t, output, "[truncated]", "Expected truncation indicator in output")
 	})
 
+	t.Run("Outline", func(t *testing.T) {
+		result := tools.StructuredToolResult{
+			ToolName:  "file_read",
+			Success:   true,
+			Timestamp: time.Now(),
+			Metadata: &tools.FileReadMetadata{
+				FilePath:   "/test/big.go",
+				Lines:      []string{"[Outline of a 900-line go file.]", "3-40  func Run() error"},
+				Offset:     1,
+				Language:   "go",
+				Outline:    true,
+				TotalLines: 900,
+			},
+		}
+
+		output := renderer.RenderCLI(result)
+		assert.Contains(t, output, "File Outline: /test/big.go")
+		assert.Contains(t, output, "3-40  func Run() error")
+		assert.NotContains(t, output, "Offset:")
+
+		markdown := renderer.RenderMarkdown(result)
+		assert.Contains(t, markdown, "- **Outline:** 900 lines")
+		assert.Contains(t, markdown, "3-40  func Run() error")
+	})
+
 	t.Run("Error handling", func(t *testing.T) {
 		result := tools.StructuredToolResult{
 			ToolName:  "file_read",
@@ -381,6 +422,42 @@ func TestFileEditRendererRenderMarkdown(t *testing.T) {
 	assert.NotContains(t, output, "- **Language:**")
 }
 
+func TestFileEditRendererUnifiedDiff(t *testing.T) {
+	renderer := &FileEditRenderer{}
+	result := tools.StructuredToolResult{
+		ToolName:  "file_edit",
+		Success:   true,
+		Timestamp: time.Now(),
+		Metadata: &tools.FileEditMetadata{
+			FilePath:    "/test/file.go",
+			Edits:       []tools.Edit{{StartLine: 41, EndLine: 41, OldContent: "old", NewContent: "new"}},
+			UnifiedDiff: "--- /test/file.go\n+++ /test/file.go\n@@ -40,3 +40,3 @@\n before\n-old\n+new\n after\n",
+		},
+	}
+
+	output := renderer.RenderCLI(result)
+	assert.Equal(t, "File edited: /test/file.go\n\n"+
+		"      │  @@ -40,3 +40,3 @@\n"+
+		"40 40 │  before\n"+
+		"41    │ -old\n"+
+		"   41 │ +new\n"+
+		"42 42 │  after", output)
+
+	markdown := renderer.RenderMarkdown(result)
+	assert.Contains(t, markdown, "Changes (+1 -1):")
+	assert.Contains(t, markdown, "```diff\n")
+	assert.NotContains(t, markdown, "Lines 41-41")
+
+	original := color.NoColor
+	color.NoColor = false
+	t.Cleanup(func() { color.NoColor = original })
+	colored := renderer.RenderColorCLI(result)
+	assert.Contains(t, colored, "\x1b[32m   41 │ +new\x1b[0m")
+	assert.Contains(t, colored, "\x1b[31m41    │ -old\x1b[0m")
+	assert.Contains(t, NewRendererRegistry().RenderColor(result), "\x1b[32m")
+	assert.NotContains(t, NewRendererRegistry().Render(result), "\x1b[")
+}
+
 func TestFileWriteRendererMarkdownVariants(t *testing.T) {
 	renderer := &FileWriteRenderer{}
 	result := tools.StructuredToolResult{
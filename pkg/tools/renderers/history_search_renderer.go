@@ -0,0 +1,99 @@
+package renderers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// HistorySearchRenderer renders history_search tool results.
+type HistorySearchRenderer struct{}
+
+// RenderCLI renders history_search results in CLI format.
+func (r *HistorySearchRenderer) RenderCLI(result tools.StructuredToolResult) string {
+	if !result.Success {
+		return fmt.Sprintf("Error: %s", result.Error)
+	}
+
+	var meta tools.HistorySearchMetadata
+	if !tools.ExtractMetadata(result.Metadata, &meta) {
+		return "Error: Invalid metadata type for history_search"
+	}
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "History search: %q (%s)\n", meta.Query, meta.Scope)
+	if len(meta.Matches) == 0 {
+		output.WriteString("No matches found")
+		return output.String()
+	}
+	for _, match := range meta.Matches {
+		fmt.Fprintf(&output, "\n%s\n  %s\n", historySearchMatchLabel(match), match.Snippet)
+	}
+	return strings.TrimRight(output.String(), "\n")
+}
+
+// RenderMarkdown renders history_search results in markdown format.
+func (r *HistorySearchRenderer) RenderMarkdown(result tools.StructuredToolResult) string {
+	return r.renderMarkdown(result, true)
+}
+
+// RenderToolUseMarkdown renders history_search invocation inputs in markdown format.
+func (r *HistorySearchRenderer) RenderToolUseMarkdown(rawInput string) string {
+	var input tools.HistorySearchInput
+	if !decodeToolInput(rawInput, &input) {
+		return ""
+	}
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "- **Query:** %s\n", inlineCode(input.Query))
+	if input.Scope != "" {
+		fmt.Fprintf(&output, "- **Scope:** %s\n", inlineCode(input.Scope))
+	}
+
+	return strings.TrimSpace(output.String())
+}
+
+// RenderMergedMarkdown renders history_search results for the merged tool-call view.
+func (r *HistorySearchRenderer) RenderMergedMarkdown(result tools.StructuredToolResult) string {
+	return r.renderMarkdown(result, false)
+}
+
+func (r *HistorySearchRenderer) renderMarkdown(result tools.StructuredToolResult, includeContext bool) string {
+	if !result.Success {
+		return renderMarkdownFromCLI(result, r.RenderCLI(result))
+	}
+
+	var meta tools.HistorySearchMetadata
+	if !tools.ExtractMetadata(result.Metadata, &meta) {
+		return renderMarkdownFromCLI(result, r.RenderCLI(result))
+	}
+
+	var output strings.Builder
+	if includeContext {
+		fmt.Fprintf(&output, "- **Query:** %s\n", inlineCode(meta.Query))
+		fmt.Fprintf(&output, "- **Scope:** %s\n\n", inlineCode(meta.Scope))
+	}
+	if len(meta.Matches) == 0 {
+		output.WriteString("_No matches found._")
+		return strings.TrimSpace(output.String())
+	}
+	for _, match := range meta.Matches {
+		fmt.Fprintf(&output, "- **%s**: %s\n", sanitizeMarkdownText(historySearchMatchLabel(match)), sanitizeMarkdownText(match.Snippet))
+	}
+
+	return strings.TrimSpace(output.String())
+}
+
+func historySearchMatchLabel(match tools.HistorySearchMatch) string {
+	labels := []string{match.ConversationID}
+	if match.Current {
+		labels = append(labels, "this conversation")
+	}
+	labels = append(labels, match.UpdatedAt.Format(time.DateOnly), match.Role)
+	if match.Compacted {
+		labels = append(labels, "compacted")
+	}
+	return strings.Join(labels, ", ")
+}
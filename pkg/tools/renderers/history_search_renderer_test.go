@@ -0,0 +1,67 @@
+package renderers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistorySearchRenderer(t *testing.T) {
+	renderer := &HistorySearchRenderer{}
+	updatedAt := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	result := tools.StructuredToolResult{
+		ToolName: "history_search",
+		Success:  true,
+		Metadata: &tools.HistorySearchMetadata{
+			Query: "job queue",
+			Scope: "repo",
+			Matches: []tools.HistorySearchMatch{
+				{ConversationID: "conv-1", Role: "user", Snippet: "use Postgres for the\njob queue", UpdatedAt: updatedAt, Current: true, Compacted: true},
+				{ConversationID: "conv-2", Role: "assistant", Snippet: "queue is in pkg/queue", UpdatedAt: updatedAt},
+			},
+		},
+	}
+
+	t.Run("cli", func(t *testing.T) {
+		assert.Equal(t, `History search: "job queue" (repo)
+
+conv-1, this conversation, 2026-10-16, user, compacted
+  use Postgres for the
+job queue
+
+conv-2, 2026-10-16, assistant
+  queue is in pkg/queue`, renderer.RenderCLI(result))
+
+		assert.Equal(t, "History search: \"redis\" (current)\nNo matches found", renderer.RenderCLI(tools.StructuredToolResult{
+			ToolName: "history_search",
+			Success:  true,
+			Metadata: &tools.HistorySearchMetadata{Query: "redis", Scope: "current"},
+		}))
+	})
+
+	t.Run("cli error and invalid metadata", func(t *testing.T) {
+		assert.Equal(t, "Error: not saved", renderer.RenderCLI(tools.StructuredToolResult{
+			ToolName: "history_search",
+			Error:    "not saved",
+		}))
+		assert.Equal(t, "Error: Invalid metadata type for history_search", renderer.RenderCLI(tools.StructuredToolResult{
+			ToolName: "history_search",
+			Success:  true,
+			Metadata: &tools.ReadConversationMetadata{},
+		}))
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		output := renderer.RenderMarkdown(result)
+		assert.Contains(t, output, "- **Query:** `job queue`")
+		assert.Contains(t, output, "- **conv-1, this conversation, 2026-10-16, user, compacted**: use Postgres for the job queue")
+
+		merged := renderer.RenderMergedMarkdown(result)
+		assert.NotContains(t, merged, "**Query:**")
+		assert.Contains(t, merged, "- **conv-2, 2026-10-16, assistant**: queue is in pkg/queue")
+
+		assert.Equal(t, "- **Query:** `job queue`\n- **Scope:** `current`", renderer.RenderToolUseMarkdown(`{"query":"job queue","scope":"current"}`))
+	})
+}
@@ -15,6 +15,12 @@ type CLIRenderer interface {
 	RenderCLI(result tools.StructuredToolResult) string
 }
 
+// ColorCLIRenderer renders structured tool results to CLI output with ANSI
+// colors, for terminals.
+type ColorCLIRenderer interface {
+	RenderColorCLI(result tools.StructuredToolResult) string
+}
+
 // RendererRegistry manages tool renderers with pattern matching support
 type RendererRegistry struct {
 	renderers map[string]CLIRenderer
@@ -40,7 +46,9 @@ func NewRendererRegistry() *RendererRegistry {
 	registry.Register("view_image", &ViewImageRenderer{})
 	registry.Register("openai_web_search", &OpenAIWebSearchRenderer{})
 	registry.Register("web_fetch", &WebFetchRenderer{})
+	registry.Register("web_crawl", &WebCrawlRenderer{})
 	registry.Register("read_conversation", &ReadConversationRenderer{})
+	registry.Register("history_search", &HistorySearchRenderer{})
 	registry.Register("skill", &SkillRenderer{})
 
 	registry.Register("extension_tool", &ExtensionToolRenderer{})
@@ -60,10 +68,7 @@ func (r *RendererRegistry) RegisterPattern(pattern string, renderer CLIRenderer)
 
 // Render finds the appropriate renderer and renders the result
 func (r *RendererRegistry) Render(result tools.StructuredToolResult) string {
-	if _, ok := result.Metadata.(*tools.ExtensionToolMetadata); ok {
-		return (&ExtensionToolRenderer{}).RenderCLI(result)
-	}
-	if _, ok := result.Metadata.(tools.ExtensionToolMetadata); ok {
+	if isExtensionToolResult(result) {
 		return (&ExtensionToolRenderer{}).RenderCLI(result)
 	}
 
@@ -76,6 +81,27 @@ func (r *RendererRegistry) Render(result tools.StructuredToolResult) string {
 	return r.renderFallback(result)
 }
 
+// RenderColor renders the result like Render, with colors for renderers that
+// support them, such as the diffs of file edits. Colors are left out when
+// output is not a terminal or NO_COLOR is set.
+func (r *RendererRegistry) RenderColor(result tools.StructuredToolResult) string {
+	if renderer, exists := r.resolveRenderer(result.ToolName); exists && !isExtensionToolResult(result) {
+		if colorRenderer, ok := renderer.(ColorCLIRenderer); ok {
+			return colorRenderer.RenderColorCLI(result)
+		}
+	}
+	return r.Render(result)
+}
+
+func isExtensionToolResult(result tools.StructuredToolResult) bool {
+	switch result.Metadata.(type) {
+	case *tools.ExtensionToolMetadata, tools.ExtensionToolMetadata:
+		return true
+	default:
+		return false
+	}
+}
+
 // RenderMarkdown finds the appropriate renderer and renders the result as markdown.
 func (r *RendererRegistry) RenderMarkdown(result tools.StructuredToolResult) string {
 	renderer, exists := r.resolveRenderer(result.ToolName)
@@ -0,0 +1,38 @@
+package renderers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// WebCrawlRenderer renders web crawl results
+type WebCrawlRenderer struct{}
+
+// RenderCLI renders web crawl results in CLI format, showing the crawl
+// directory and the saved file of every page.
+func (r *WebCrawlRenderer) RenderCLI(result tools.StructuredToolResult) string {
+	if !result.Success {
+		return result.Error
+	}
+
+	var meta tools.WebCrawlMetadata
+	if !tools.ExtractMetadata(result.Metadata, &meta) {
+		return "Error: Invalid metadata type for web_crawl"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Web Crawl: %s\nSaved to: %s\nFetched: %d, queued: %d\n", meta.URL, meta.Dir, meta.Fetched, meta.Queued)
+	for _, page := range meta.Pages {
+		switch {
+		case page.Error != "":
+			fmt.Fprintf(&b, "\n  %s [%s]", page.URL, page.Error)
+		case page.DuplicateOf != "":
+			fmt.Fprintf(&b, "\n  %s [duplicate]", page.URL)
+		default:
+			fmt.Fprintf(&b, "\n  %s -> %s", page.URL, page.File)
+		}
+	}
+	return b.String()
+}
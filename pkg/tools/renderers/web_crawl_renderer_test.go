@@ -0,0 +1,47 @@
+package renderers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebCrawlRenderer(t *testing.T) {
+	renderer := &WebCrawlRenderer{}
+
+	t.Run("Web crawl with pages", func(t *testing.T) {
+		result := tools.StructuredToolResult{
+			ToolName:  "web_crawl",
+			Success:   true,
+			Timestamp: time.Now(),
+			Metadata: &tools.WebCrawlMetadata{
+				URL:     "https://example.com/docs/",
+				Dir:     "/tmp/crawl",
+				Fetched: 3,
+				Queued:  2,
+				Pages: []tools.WebCrawlPage{
+					{URL: "https://example.com/docs/", File: "/tmp/crawl/pages/docs/index.md"},
+					{URL: "https://example.com/docs/copy", DuplicateOf: "https://example.com/docs/"},
+					{URL: "https://example.com/docs/missing", Error: "HTTP 404"},
+				},
+			},
+		}
+
+		expected := "Web Crawl: https://example.com/docs/\nSaved to: /tmp/crawl\nFetched: 3, queued: 2\n" +
+			"\n  https://example.com/docs/ -> /tmp/crawl/pages/docs/index.md" +
+			"\n  https://example.com/docs/copy [duplicate]" +
+			"\n  https://example.com/docs/missing [HTTP 404]"
+		assert.Equal(t, expected, renderer.RenderCLI(result))
+	})
+
+	t.Run("Web crawl error", func(t *testing.T) {
+		result := tools.StructuredToolResult{
+			ToolName: "web_crawl",
+			Success:  false,
+			Error:    "Failed to crawl",
+		}
+		assert.Equal(t, "Failed to crawl", renderer.RenderCLI(result))
+	})
+}
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"os"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/osutil"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// resourceUsageRecorder is implemented by states that account for the
+// resources consumed by tool subprocesses.
+type resourceUsageRecorder interface {
+	RecordResourceUsage(usage tooltypes.ResourceUsage)
+}
+
+// RecordResourceUsage adds a finished subprocess to the run's resource accounting.
+func (s *BasicState) RecordResourceUsage(usage tooltypes.ResourceUsage) {
+	s.resourcesMu.Lock()
+	defer s.resourcesMu.Unlock()
+	s.resources.Add(usage)
+}
+
+// ResourceUsage returns the resources consumed by tool subprocesses so far.
+func (s *BasicState) ResourceUsage() tooltypes.ResourceUsage {
+	s.resourcesMu.Lock()
+	defer s.resourcesMu.Unlock()
+	return s.resources
+}
+
+// recordProcessUsage records an exited subprocess against the state, if the
+// state tracks resource usage.
+func recordProcessUsage(state tooltypes.State, processState *os.ProcessState, wallTime time.Duration) {
+	if processState == nil {
+		return
+	}
+	recordResourceUsage(state, tooltypes.ResourceUsage{
+		Processes:       1,
+		WallTime:        wallTime,
+		UserCPUTime:     processState.UserTime(),
+		SystemCPUTime:   processState.SystemTime(),
+		PeakMemoryBytes: osutil.PeakMemoryBytes(processState),
+	})
+}
+
+// recordResourceUsage adds usage to state when it tracks resource usage.
+func recordResourceUsage(state tooltypes.State, usage tooltypes.ResourceUsage) {
+	if recorder, ok := state.(resourceUsageRecorder); ok {
+		recorder.RecordResourceUsage(usage)
+	}
+}
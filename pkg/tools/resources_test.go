@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBashToolRecordsResourceUsage(t *testing.T) {
+	state := NewBasicState(context.Background(), WithWorkingDirectory(t.TempDir()))
+	tool := NewBashTool(nil, false)
+
+	for _, command := range []string{"true", "exit 1"} {
+		payload, err := json.Marshal(BashInput{Description: "test", Command: command, Timeout: 10})
+		require.NoError(t, err)
+		tool.Execute(context.Background(), state, string(payload))
+	}
+
+	usage := state.ResourceUsage()
+	assert.Equal(t, 2, usage.Processes)
+	assert.Positive(t, usage.WallTime)
+	assert.GreaterOrEqual(t, usage.CPUTime(), time.Duration(0))
+}
+
+func TestResourceUsageAddKeepsPeakMemory(t *testing.T) {
+	state := NewBasicState(context.Background())
+	state.RecordResourceUsage(tooltypes.ResourceUsage{Processes: 1, WallTime: time.Second, UserCPUTime: time.Second, PeakMemoryBytes: 300})
+	state.RecordResourceUsage(tooltypes.ResourceUsage{Processes: 1, WallTime: 2 * time.Second, SystemCPUTime: time.Second, PeakMemoryBytes: 100})
+
+	assert.Equal(t, tooltypes.ResourceUsage{
+		Processes:       2,
+		WallTime:        3 * time.Second,
+		UserCPUTime:     time.Second,
+		SystemCPUTime:   time.Second,
+		PeakMemoryBytes: 300,
+	}, state.ResourceUsage())
+}
@@ -8,6 +8,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/jingkaihe/kodelet/pkg/checkpoints"
+	"github.com/jingkaihe/kodelet/pkg/devcontainer"
 	"github.com/jingkaihe/kodelet/pkg/logger"
 	"github.com/jingkaihe/kodelet/pkg/osutil"
 	"github.com/jingkaihe/kodelet/pkg/skills"
@@ -16,7 +18,11 @@ import (
 	"github.com/spf13/viper"
 )
 
-var _ tooltypes.State = &BasicState{}
+var (
+	_ tooltypes.State                 = &BasicState{}
+	_ tooltypes.ResourceUsageReporter = &BasicState{}
+	_ tooltypes.FileAccessReporter    = &BasicState{}
+)
 
 type contextInfo struct {
 	Content      string
@@ -39,6 +45,23 @@ type BasicState struct {
 	// Per-file locking for atomic file operations
 	fileLocks   map[string]*sync.Mutex
 	fileLocksMu sync.Mutex
+
+	// Run-level accounting of file modifications for change limits
+	changes *changeTracker
+
+	// Snapshots of files taken before the file and bash tools modify them
+	checkpoints []*checkpoints.Store
+
+	// Dev container the bash tool runs commands in, when set
+	devContainer *devcontainer.Container
+
+	// Run-level accounting of resources consumed by tool subprocesses
+	resources   tooltypes.ResourceUsage
+	resourcesMu sync.Mutex
+
+	// Last access time of files touched by the file tools
+	fileLastAccess map[string]time.Time
+	fileAccessMu   sync.Mutex
 }
 
 func hasExplicitAllowedTools(config llmtypes.Config) bool {
@@ -118,6 +141,18 @@ func filterOutSkill(tools []tooltypes.Tool) []tooltypes.Tool {
 	return filtered
 }
 
+// filterOutWebTools removes web_fetch and web_crawl, which cannot reach the
+// web in air-gapped mode.
+func filterOutWebTools(tools []tooltypes.Tool) []tooltypes.Tool {
+	filtered := make([]tooltypes.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if tool.Name() != "web_fetch" && tool.Name() != "web_crawl" {
+			filtered = append(filtered, tool)
+		}
+	}
+	return filtered
+}
+
 // ContextDiscovery tracks context discovery results
 type ContextDiscovery struct {
 	workingDir      string
@@ -156,6 +191,7 @@ func NewBasicState(ctx context.Context, opts ...BasicStateOption) *BasicState {
 			contextPatterns: llmtypes.DefaultContextPatterns(),
 		},
 		fileLocks: make(map[string]*sync.Mutex),
+		changes:   newChangeTracker(),
 	}
 
 	for _, opt := range opts {
@@ -194,6 +230,9 @@ func WithMainTools() BasicStateOption {
 		if !skillsEnabledForConfig(s.llmConfig) {
 			s.tools = filterOutSkill(s.tools)
 		}
+		if s.llmConfig.AirgapEnabled() {
+			s.tools = filterOutWebTools(s.tools)
+		}
 		s.configureTools()
 		return nil
 	}
@@ -453,9 +492,20 @@ func (s *BasicState) configureToolSlice(tools []tooltypes.Tool) []tooltypes.Tool
 	for i, tool := range tools {
 		switch tool.Name() {
 		case "bash":
-			tools[i] = NewBashToolWithTimeout(s.llmConfig.AllowedCommands, s.llmConfig.EnableFSSearchTools, s.llmConfig.BashTimeout())
+			var bash *BashTool
+			if s.devContainer != nil {
+				bash = NewDevContainerBashTool(s.devContainer, s.llmConfig.AllowedCommands, s.llmConfig.EnableFSSearchTools, s.llmConfig.BashTimeout())
+			} else if s.llmConfig.BashStateless() {
+				bash = NewStatelessBashTool(s.llmConfig.AllowedCommands, s.llmConfig.EnableFSSearchTools, s.llmConfig.BashTimeout())
+			} else {
+				bash = NewBashToolWithTimeout(s.llmConfig.AllowedCommands, s.llmConfig.EnableFSSearchTools, s.llmConfig.BashTimeout())
+			}
+			bash.env = s.llmConfig.ToolEnvMap()
+			tools[i] = bash
 		case "web_fetch":
 			tools[i] = NewWebFetchTool(s.llmConfig.AllowedDomainsFile)
+		case "web_crawl":
+			tools[i] = NewWebCrawlTool(s.llmConfig.AllowedDomainsFile)
 		case "view_image":
 			tools[i] = NewViewImageTool(s.llmConfig.Model, s.llmConfig.Provider)
 		}
@@ -480,6 +530,13 @@ func (s *BasicState) DiscoverContexts() map[string]string {
 		contexts[ctx.Path] = ctx.Content
 	}
 
+	// 3. Add a summary of the working directory's package manifests and CI workflows
+	if s.llmConfig.Context == nil || !s.llmConfig.Context.DisableManifests {
+		if summary := summarizeManifests(s.contextDiscovery.workingDir); summary != "" {
+			contexts[filepath.Join(s.contextDiscovery.workingDir, ManifestContextName)] = summary
+		}
+	}
+
 	return contexts
 }
 
@@ -481,6 +481,22 @@ func TestWithMainTools(t *testing.T) {
 		assert.Empty(t, state.Tools(), "NoToolsMarker should result in no tools")
 	})
 
+	t.Run("airgap removes web_fetch and web_crawl", func(t *testing.T) {
+		config := llmtypes.Config{
+			Airgap: &llmtypes.AirgapConfig{Enabled: true},
+		}
+		state := NewBasicState(ctx, WithLLMConfig(config), WithMainTools())
+
+		toolNames := make([]string, len(state.Tools()))
+		for i, tool := range state.Tools() {
+			toolNames[i] = tool.Name()
+		}
+
+		assert.Contains(t, toolNames, "bash")
+		assert.NotContains(t, toolNames, "web_fetch")
+		assert.NotContains(t, toolNames, "web_crawl")
+	})
+
 	t.Run("patch removes file_read file_write and file_edit from defaults", func(t *testing.T) {
 		config := llmtypes.Config{
 			ToolMode: llmtypes.ToolModePatch,
@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/invopop/jsonschema"
+	"github.com/jingkaihe/kodelet/pkg/todos"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// TodoWriteTool replaces the thread's todo list.
+type TodoWriteTool struct{}
+
+// TodoWriteInput reuses the shared todo_write input schema while preserving pkg/tools schema IDs.
+type TodoWriteInput tooltypes.TodoWriteInput
+
+// TodoToolResult represents a todo_write result.
+type TodoToolResult struct {
+	list    *todos.List
+	content string
+	err     string
+}
+
+func NewTodoWriteTool() *TodoWriteTool {
+	return &TodoWriteTool{}
+}
+
+func (t *TodoWriteTool) GenerateSchema() *jsonschema.Schema {
+	return GenerateSchema[TodoWriteInput]()
+}
+
+func (t *TodoWriteTool) Name() string {
+	return todos.ToolName
+}
+
+func (t *TodoWriteTool) Description() string {
+	return `Create or update the todo list for the current task.
+Use it for tasks with three or more distinct steps, or when the user gives several things to do. Skip it for trivial single-step requests.
+Always send the complete list; it replaces the previous one.
+Keep exactly one item in_progress while working, mark items completed as soon as they are done, and cancel items that are no longer needed instead of deleting them.
+Only mark an item completed when it is fully done and verified.`
+}
+
+func (t *TodoWriteTool) ValidateInput(_ tooltypes.State, parameters string) error {
+	input := &TodoWriteInput{}
+	if err := json.Unmarshal([]byte(parameters), input); err != nil {
+		return err
+	}
+	_, err := todos.New(todoItems(input.Todos), time.Now())
+	return err
+}
+
+func (t *TodoWriteTool) Execute(ctx context.Context, _ tooltypes.State, parameters string) tooltypes.ToolResult {
+	input := &TodoWriteInput{}
+	if err := json.Unmarshal([]byte(parameters), input); err != nil {
+		return &TodoToolResult{err: err.Error()}
+	}
+
+	list, err := todos.New(todoItems(input.Todos), time.Now())
+	if err != nil {
+		return &TodoToolResult{err: err.Error()}
+	}
+
+	store := toolContextFromContext(ctx).MetadataStore
+	if store == nil {
+		return &TodoToolResult{err: "todo metadata is unavailable"}
+	}
+	store.SetMetadataValue(todos.MetadataKey, list)
+
+	content := "Todo list updated: " + list.Progress().String() + "\n" + todos.Render(list)
+	return &TodoToolResult{list: &list, content: content}
+}
+
+func (t *TodoWriteTool) TracingKVs(parameters string) ([]attribute.KeyValue, error) {
+	input := &TodoWriteInput{}
+	if err := json.Unmarshal([]byte(parameters), input); err != nil {
+		return nil, err
+	}
+	return []attribute.KeyValue{
+		attribute.Int("todos", len(input.Todos)),
+	}, nil
+}
+
+func todoItems(inputs []tooltypes.TodoItemInput) []todos.Item {
+	items := make([]todos.Item, 0, len(inputs))
+	for _, input := range inputs {
+		items = append(items, todos.Item{Content: input.Content, Status: todos.Status(input.Status)})
+	}
+	return items
+}
+
+func (r *TodoToolResult) AssistantFacing() string {
+	return tooltypes.StringifyToolResult(r.content, r.err)
+}
+
+func (r *TodoToolResult) IsError() bool {
+	return r.err != ""
+}
+
+func (r *TodoToolResult) GetError() string {
+	return r.err
+}
+
+func (r *TodoToolResult) GetResult() string {
+	return r.content
+}
+
+func (r *TodoToolResult) StructuredData() tooltypes.StructuredToolResult {
+	result := tooltypes.StructuredToolResult{
+		ToolName:  todos.ToolName,
+		Success:   !r.IsError(),
+		Timestamp: time.Now(),
+	}
+	if r.IsError() {
+		result.Error = r.err
+	}
+
+	metadata := tooltypes.TodoWriteMetadata{}
+	if r.list != nil {
+		for _, item := range r.list.Items {
+			metadata.Todos = append(metadata.Todos, tooltypes.TodoItemInput{Content: item.Content, Status: string(item.Status)})
+		}
+		progress := r.list.Progress()
+		metadata.Completed = progress.Completed
+		metadata.Total = progress.Total()
+	}
+	result.Metadata = metadata
+	return result
+}
@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/todos"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTodoWriteToolStoresList(t *testing.T) {
+	store := &testMetadataStore{}
+	ctx := ContextWithToolContext(context.Background(), ToolContext{MetadataStore: store})
+	tool := NewTodoWriteTool()
+
+	result := tool.Execute(ctx, NewBasicState(context.Background()), `{"todos": [
+		{"content": "write parser", "status": "completed"},
+		{"content": "wire command", "status": "in_progress"},
+		{"content": "docs", "status": "pending"}]}`)
+
+	require.False(t, result.IsError(), result.GetError())
+	assert.Contains(t, result.GetResult(), "Todo list updated: 1/3 completed (33%)")
+	assert.Contains(t, result.GetResult(), "- [~] wire command")
+
+	list, ok := todos.FromMetadata(store.GetMetadata())
+	require.True(t, ok)
+	assert.Len(t, list.Items, 3)
+
+	metadata, ok := result.StructuredData().Metadata.(tooltypes.TodoWriteMetadata)
+	require.True(t, ok)
+	assert.Equal(t, 1, metadata.Completed)
+	assert.Equal(t, 3, metadata.Total)
+}
+
+func TestTodoWriteToolValidation(t *testing.T) {
+	tool := NewTodoWriteTool()
+	state := NewBasicState(context.Background())
+
+	assert.ErrorContains(t, tool.ValidateInput(state, `{"todos": [{"content": "a", "status": "done"}]}`), `status "done"`)
+	assert.NoError(t, tool.ValidateInput(state, `{"todos": [{"content": "a", "status": "pending"}]}`))
+
+	result := tool.Execute(context.Background(), state, `{"todos": [{"content": "a", "status": "pending"}]}`)
+	assert.Equal(t, "todo metadata is unavailable", result.GetError())
+}
@@ -7,12 +7,14 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/invopop/jsonschema"
 	"github.com/jingkaihe/kodelet/pkg/logger"
 	"github.com/jingkaihe/kodelet/pkg/telemetry"
 	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -36,11 +38,15 @@ var toolRegistry = map[string]tooltypes.Tool{
 	"file_write":        &FileWriteTool{},
 	"file_edit":         &FileEditTool{},
 	"read_conversation": NewReadConversationTool(),
+	"history_search":    NewHistorySearchTool(),
 	"grep_tool":         &GrepTool{},
 	"glob_tool":         &GlobTool{},
 	"web_fetch":         &WebFetchTool{},
+	"web_crawl":         &WebCrawlTool{},
+	"git_log":           &GitLogTool{},
 	"get_goal":          NewGetGoalTool(),
 	"update_goal":       NewUpdateGoalTool(),
+	"todo_write":        NewTodoWriteTool(),
 	"view_image":        NewViewImageTool("", ""),
 	"skill":             NewSkillTool(nil, false, false),
 }
@@ -76,11 +82,14 @@ var defaultMainTools = []string{
 	"file_write",
 	"file_edit",
 	"read_conversation",
+	"history_search",
 	"grep_tool",
 	"glob_tool",
 	"web_fetch",
+	"web_crawl",
 	"get_goal",
 	"update_goal",
+	"todo_write",
 	"view_image",
 	"skill",
 }
@@ -278,12 +287,27 @@ func RunToolWithUpdates(
 		}
 	}
 
-	var result tooltypes.ToolResult
-	if streamingTool, ok := tool.(tooltypes.StreamingTool); ok && onUpdate != nil {
-		result = streamingTool.ExecuteStreaming(ctx, state, parameters, onUpdate)
-	} else {
-		result = tool.Execute(ctx, state, parameters)
+	limit := toolLimit(state, toolName)
+	cacheCall := newToolCacheCall(ctx, state, toolName, parameters)
+	result, cached := cacheCall.lookup(ctx)
+	span.SetAttributes(attribute.Bool("tool_cache.hit", cached))
+	if !cached {
+		started := time.Now()
+		result = runWithTimeout(ctx, toolName, limit.Timeout, func(ctx context.Context) tooltypes.ToolResult {
+			return runWithWatchdog(ctx, toolName, limit.StallThreshold(), func(ctx context.Context, progress func()) tooltypes.ToolResult {
+				if streamingTool, ok := tool.(tooltypes.StreamingTool); ok && onUpdate != nil {
+					return streamingTool.ExecuteStreaming(ctx, state, parameters, func(partialResult tooltypes.ToolResult) {
+						progress()
+						onUpdate(partialResult)
+					})
+				}
+				return tool.Execute(ctx, state, parameters)
+			})
+		})
+		telemetry.RecordToolCall(toolName, time.Since(started), result.IsError())
+		cacheCall.save(ctx, result)
 	}
+	result = limitToolOutput(result, limit.MaxOutputBytes)
 
 	if result.IsError() {
 		span.SetStatus(codes.Error, result.GetError())
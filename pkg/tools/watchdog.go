@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/telemetry"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxStallCheckInterval bounds how late a stall is noticed.
+const maxStallCheckInterval = 5 * time.Second
+
+// ToolStall describes a tool call that has gone without progress for longer
+// than its tool_limits stall_after.
+type ToolStall struct {
+	ToolName string
+	// Idle is how long the call has gone without progress. Streaming tools
+	// make progress with each output update, other tools only by finishing.
+	Idle time.Duration
+	// Cancel gives up on this call alone. The agent gets an error result for
+	// it and the turn carries on.
+	Cancel func()
+}
+
+// ToolStallNotifier is told about a stalled tool call. ctx is cancelled once
+// the call returns, so prompts raised from it can be withdrawn. It must not
+// block.
+type ToolStallNotifier func(ctx context.Context, stall ToolStall)
+
+type toolStallNotifierKey struct{}
+
+// ContextWithToolStallNotifier attaches a stalled-call notifier to the tool execution context.
+func ContextWithToolStallNotifier(ctx context.Context, notifier ToolStallNotifier) context.Context {
+	if notifier == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, toolStallNotifierKey{}, notifier)
+}
+
+func toolStallNotifierFromContext(ctx context.Context) ToolStallNotifier {
+	notifier, _ := ctx.Value(toolStallNotifierKey{}).(ToolStallNotifier)
+	return notifier
+}
+
+// runWithWatchdog runs execute and reports the call as stalled each time it
+// goes stallAfter without calling progress. A stalled call is logged,
+// recorded on the tool span and passed to the context's ToolStallNotifier,
+// whose Cancel returns an error result right away and cancels the context
+// passed to execute.
+func runWithWatchdog(
+	ctx context.Context,
+	toolName string,
+	stallAfter time.Duration,
+	execute func(ctx context.Context, progress func()) tooltypes.ToolResult,
+) tooltypes.ToolResult {
+	if stallAfter <= 0 {
+		return execute(ctx, func() {})
+	}
+
+	watchedCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var lastProgress atomic.Int64
+	lastProgress.Store(time.Now().UnixNano())
+	progress := func() {
+		lastProgress.Store(time.Now().UnixNano())
+	}
+
+	done := make(chan tooltypes.ToolResult, 1)
+	go func() {
+		done <- execute(watchedCtx, progress)
+	}()
+
+	cancelled := make(chan time.Duration, 1)
+	var cancelOnce sync.Once
+	cancelCall := func(idle time.Duration) func() {
+		return func() {
+			cancelOnce.Do(func() {
+				cancelled <- idle
+				cancel()
+			})
+		}
+	}
+
+	ticker := time.NewTicker(min(stallAfter/4, maxStallCheckInterval))
+	defer ticker.Stop()
+
+	var reported int64
+	for {
+		select {
+		case result := <-done:
+			select {
+			case idle := <-cancelled:
+				// The tool returned because the user cancelled it.
+				return stallCancelledResult(ctx, toolName, idle)
+			default:
+				return result
+			}
+		case idle := <-cancelled:
+			return stallCancelledResult(ctx, toolName, idle)
+		case <-ticker.C:
+			last := lastProgress.Load()
+			idle := time.Since(time.Unix(0, last))
+			if idle < stallAfter || last == reported {
+				continue
+			}
+			// Report each quiet stretch once; new output starts another.
+			reported = last
+			logger.G(ctx).
+				WithField("tool", toolName).
+				WithField("idle", idle.Round(time.Second).String()).
+				Warn("tool call has stalled")
+			telemetry.AddEvent(ctx, "tool.stalled",
+				attribute.String("tool.name", toolName),
+				attribute.String("tool.idle", idle.String()),
+			)
+			if notify := toolStallNotifierFromContext(ctx); notify != nil {
+				notify(watchedCtx, ToolStall{ToolName: toolName, Idle: idle, Cancel: cancelCall(idle)})
+			}
+		}
+	}
+}
+
+// stallCancelledResult records a stalled call the user cancelled and reports
+// it to the agent.
+func stallCancelledResult(ctx context.Context, toolName string, idle time.Duration) tooltypes.ToolResult {
+	telemetry.AddEvent(ctx, "tool.stall_cancelled",
+		attribute.String("tool.name", toolName),
+		attribute.String("tool.idle", idle.String()),
+	)
+	return tooltypes.BaseToolResult{
+		Error: fmt.Sprintf("%s was cancelled by the user after %s without progress", toolName, idle.Round(time.Second)),
+	}
+}
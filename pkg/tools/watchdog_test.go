@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunToolCancelsStalledCall(t *testing.T) {
+	tool := &slowTestTool{testTool: &testTool{name: "hung_tool"}}
+	state := NewBasicState(context.Background(),
+		WithExtensionTools([]tooltypes.Tool{tool}),
+		WithLLMConfig(llmtypes.Config{ToolLimits: &llmtypes.ToolLimitsConfig{
+			ToolLimit: llmtypes.ToolLimit{StallAfter: 40 * time.Millisecond},
+		}}),
+	)
+
+	var stalls []ToolStall
+	ctx := ContextWithToolStallNotifier(context.Background(), func(_ context.Context, stall ToolStall) {
+		stalls = append(stalls, stall)
+		stall.Cancel()
+	})
+
+	started := time.Now()
+	result := RunTool(ctx, state, "hung_tool", `{}`)
+
+	assert.Less(t, time.Since(started), 2*time.Second)
+	require.Len(t, stalls, 1)
+	assert.Equal(t, "hung_tool", stalls[0].ToolName)
+	assert.GreaterOrEqual(t, stalls[0].Idle, 40*time.Millisecond)
+	require.True(t, result.IsError())
+	assert.Contains(t, result.GetError(), "hung_tool was cancelled by the user after")
+}
+
+func TestRunWithWatchdogReportsEachQuietStretchOnce(t *testing.T) {
+	var stalls atomic.Int32
+	ctx := ContextWithToolStallNotifier(context.Background(), func(context.Context, ToolStall) {
+		stalls.Add(1)
+	})
+
+	result := runWithWatchdog(ctx, "chatty_tool", 40*time.Millisecond, func(_ context.Context, progress func()) tooltypes.ToolResult {
+		for range 6 {
+			time.Sleep(15 * time.Millisecond)
+			progress()
+		}
+		time.Sleep(150 * time.Millisecond)
+		return tooltypes.BaseToolResult{Result: "done"}
+	})
+
+	assert.Equal(t, "done", result.GetResult())
+	assert.Equal(t, int32(1), stalls.Load(), "regular output keeps the call from stalling until it goes quiet")
+}
+
+func TestRunWithWatchdogDisabled(t *testing.T) {
+	ctx := ContextWithToolStallNotifier(context.Background(), func(context.Context, ToolStall) {
+		t.Fatal("a disabled watchdog must not report stalls")
+	})
+
+	result := runWithWatchdog(ctx, "quiet_tool", 0, func(context.Context, func()) tooltypes.ToolResult {
+		time.Sleep(30 * time.Millisecond)
+		return tooltypes.BaseToolResult{Result: "done"}
+	})
+
+	assert.Equal(t, "done", result.GetResult())
+}
@@ -0,0 +1,279 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/invopop/jsonschema"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/jingkaihe/kodelet/pkg/audit"
+	"github.com/jingkaihe/kodelet/pkg/osutil"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/jingkaihe/kodelet/pkg/webcrawl"
+)
+
+const (
+	maxWebCrawlPages = 100
+	maxWebCrawlDepth = 5
+	// webCrawlRequestTimeout bounds each request of a crawl.
+	webCrawlRequestTimeout = 30 * time.Second
+)
+
+// WebCrawlTool implements the web_crawl tool for saving a set of pages, such
+// as a documentation site, as Markdown files.
+type WebCrawlTool struct {
+	domainFilter *osutil.DomainFilter
+	dir          string
+	delay        time.Duration
+}
+
+// NewWebCrawlTool creates a new WebCrawlTool with optional domain filtering
+func NewWebCrawlTool(allowedDomainsFile string) *WebCrawlTool {
+	var domainFilter *osutil.DomainFilter
+	if allowedDomainsFile != "" {
+		domainFilter = osutil.NewDomainFilter(allowedDomainsFile)
+	}
+	return &WebCrawlTool{
+		domainFilter: domainFilter,
+		delay:        webcrawl.DefaultDelay,
+	}
+}
+
+// WebCrawlInput defines the input parameters for the web_crawl tool.
+type WebCrawlInput struct {
+	URL        string `json:"url" jsonschema:"description=The URL to start crawling from"`
+	PathPrefix string `json:"path_prefix,omitempty" jsonschema:"description=Only crawl pages whose path starts with this prefix. Default: the directory of url"`
+	MaxPages   int    `json:"max_pages,omitempty" jsonschema:"description=Maximum number of pages to fetch in this call (default: 20; max: 100)"`
+	MaxDepth   int    `json:"max_depth,omitempty" jsonschema:"description=Maximum number of links to follow away from url (default: 2; max: 5)"`
+	Refresh    bool   `json:"refresh,omitempty" jsonschema:"description=Discard the pages saved by an earlier crawl of url and start over"`
+}
+
+// WebCrawlToolResult represents the result of a crawl
+type WebCrawlToolResult struct {
+	url    string
+	result *webcrawl.Result
+	err    string
+}
+
+// Name returns the name of the tool.
+func (t *WebCrawlTool) Name() string {
+	return "web_crawl"
+}
+
+// GenerateSchema generates the JSON schema for the tool's input parameters.
+func (t *WebCrawlTool) GenerateSchema() *jsonschema.Schema {
+	return GenerateSchema[WebCrawlInput]()
+}
+
+// Description returns the description of the tool.
+func (t *WebCrawlTool) Description() string {
+	return `Crawl a documentation site or another set of linked pages and save them as Markdown files.
+
+Use this tool instead of many web_fetch calls when you need to read several pages of the same site, for example "read these docs and implement X". Then read the saved files with file_read or search them with grep_tool.
+
+# Input
+- url: required URL to start from
+- path_prefix: optional; only pages whose path starts with it are crawled (default: the directory of url, e.g. /docs/ for https://example.com/docs/intro)
+- max_pages: optional; pages fetched by this call (default: 20, max: 100)
+- max_depth: optional; links followed away from url (default: 2, max: 5)
+- refresh: optional; discard an earlier crawl of url and start over
+
+# Behavior
+- Only follows links on the same host within path_prefix, breadth-first. Assets such as images, scripts and archives are skipped.
+- Respects robots.txt and waits at least a second between requests.
+- Pages with identical content are saved once. HTML is converted to Markdown.
+- Returns the saved file of every page and an index.md listing them.
+- Crawls are resumable: calling web_crawl again with the same url and path_prefix continues with the pages still queued, without fetching saved pages again. max_depth is kept from the first call.
+
+# Rules
+- Use HTTPS for external domains. HTTP is allowed only for localhost/internal addresses.
+- Only public pages are supported (no auth/session handling).
+`
+}
+
+// ValidateInput validates the input parameters for the tool.
+func (t *WebCrawlTool) ValidateInput(_ tooltypes.State, parameters string) error {
+	input := &WebCrawlInput{}
+	if err := json.Unmarshal([]byte(parameters), input); err != nil {
+		return err
+	}
+	if input.URL == "" {
+		return errors.New("url is required")
+	}
+	parsedURL, err := url.Parse(input.URL)
+	if err != nil {
+		return errors.Wrap(err, "invalid URL")
+	}
+	if parsedURL.Scheme != "https" && (parsedURL.Scheme != "http" || !isLocalHost(parsedURL.Hostname())) {
+		return errors.New("only HTTPS scheme is supported for external domains, HTTP is allowed for localhost/internal addresses")
+	}
+	if input.MaxPages < 0 || input.MaxPages > maxWebCrawlPages {
+		return errors.Errorf("max_pages must be between 1 and %d", maxWebCrawlPages)
+	}
+	if input.MaxDepth < 0 || input.MaxDepth > maxWebCrawlDepth {
+		return errors.Errorf("max_depth must be between 1 and %d", maxWebCrawlDepth)
+	}
+
+	if t.domainFilter != nil {
+		allowed, err := t.domainFilter.IsAllowed(input.URL)
+		if err != nil {
+			return errors.Wrap(err, "failed to validate domain")
+		}
+		if !allowed {
+			return errors.Errorf("domain %s is not in the allowed domains list", parsedURL.Hostname())
+		}
+	}
+	return nil
+}
+
+// Execute crawls the pages reachable from the input URL.
+func (t *WebCrawlTool) Execute(ctx context.Context, _ tooltypes.State, parameters string) tooltypes.ToolResult {
+	input := &WebCrawlInput{}
+	if err := json.Unmarshal([]byte(parameters), input); err != nil {
+		return &WebCrawlToolResult{url: input.URL, err: err.Error()}
+	}
+
+	maxDepth := input.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = webcrawl.DefaultMaxDepth
+	}
+	client := &http.Client{
+		Timeout: webCrawlRequestTimeout,
+		Transport: audit.NewEgressTransport(http.DefaultTransport, audit.EgressSource{
+			Tool:           "web_crawl",
+			ConversationID: ToolContextFromContext(ctx).ConversationID,
+		}),
+	}
+
+	result, err := webcrawl.Crawl(ctx, webcrawl.Options{
+		StartURL:   input.URL,
+		PathPrefix: input.PathPrefix,
+		MaxPages:   input.MaxPages,
+		MaxDepth:   maxDepth,
+		Delay:      t.delay,
+		Refresh:    input.Refresh,
+		Dir:        t.dir,
+		Client:     client,
+	})
+	if err != nil {
+		toolResult := &WebCrawlToolResult{url: input.URL, result: result, err: fmt.Sprintf("Failed to crawl %s: %s", input.URL, err)}
+		if result != nil {
+			toolResult.err += ". The pages fetched so far are saved; call web_crawl again with the same url to resume."
+		}
+		return toolResult
+	}
+	return &WebCrawlToolResult{url: input.URL, result: result}
+}
+
+// TracingKVs returns tracing key-value pairs for observability.
+func (t *WebCrawlTool) TracingKVs(parameters string) ([]attribute.KeyValue, error) {
+	input := &WebCrawlInput{}
+	if err := json.Unmarshal([]byte(parameters), input); err != nil {
+		return nil, err
+	}
+	return []attribute.KeyValue{
+		attribute.String("url", input.URL),
+		attribute.String("path_prefix", input.PathPrefix),
+		attribute.Int("max_pages", input.MaxPages),
+		attribute.Int("max_depth", input.MaxDepth),
+	}, nil
+}
+
+// GetResult returns the pages of the crawl and where they are saved
+func (r *WebCrawlToolResult) GetResult() string {
+	if r.result == nil {
+		return ""
+	}
+	res := r.result
+	saved := 0
+	for _, page := range res.Pages {
+		if page.File != "" {
+			saved++
+		}
+	}
+
+	var b strings.Builder
+	verb := "Crawled"
+	if res.Resumed {
+		verb = "Resumed the crawl of"
+	}
+	fmt.Fprintf(&b, "%s %s (path prefix %s): fetched %d pages, %d saved in total.\n", verb, res.StartURL, res.PathPrefix, res.Fetched, saved)
+	fmt.Fprintf(&b, "Pages are saved as Markdown in %s; index.md lists them. Read them with file_read or search them with grep_tool.\n\n", res.Dir)
+
+	for _, page := range res.Pages {
+		switch {
+		case page.Error != "":
+			fmt.Fprintf(&b, "- %s [error: %s]\n", page.URL, page.Error)
+		case page.DuplicateOf != "":
+			fmt.Fprintf(&b, "- %s [same as %s]\n", page.URL, page.DuplicateOf)
+		default:
+			title := page.Title
+			if title == "" {
+				title = page.URL
+			}
+			fmt.Fprintf(&b, "- %s (%s)\n  %s (%d bytes)\n", title, page.URL, page.File, page.Bytes)
+		}
+	}
+
+	if res.RobotsDisallowed > 0 {
+		fmt.Fprintf(&b, "\n%d pages were skipped because robots.txt disallows them.\n", res.RobotsDisallowed)
+	}
+	switch {
+	case res.LimitReached:
+		fmt.Fprintf(&b, "\nThe crawl holds the maximum of %d pages; %d found pages were not fetched. Crawl a narrower path_prefix to read them.\n", webcrawl.MaxTotalPages, res.Queued)
+	case res.Queued > 0:
+		fmt.Fprintf(&b, "\n%d pages are still queued. Call web_crawl again with the same url to continue.\n", res.Queued)
+	}
+	return b.String()
+}
+
+// GetError returns the error message
+func (r *WebCrawlToolResult) GetError() string {
+	return r.err
+}
+
+// IsError returns true if the result contains an error
+func (r *WebCrawlToolResult) IsError() bool {
+	return r.err != ""
+}
+
+// AssistantFacing returns the string representation for the AI assistant
+func (r *WebCrawlToolResult) AssistantFacing() string {
+	return tooltypes.StringifyToolResult(r.GetResult(), r.err)
+}
+
+// StructuredData returns structured metadata about the crawl
+func (r *WebCrawlToolResult) StructuredData() tooltypes.StructuredToolResult {
+	result := tooltypes.StructuredToolResult{
+		ToolName:  "web_crawl",
+		Success:   !r.IsError(),
+		Error:     r.err,
+		Timestamp: time.Now(),
+	}
+	meta := &tooltypes.WebCrawlMetadata{URL: r.url}
+	if r.result != nil {
+		meta.Dir = r.result.Dir
+		meta.PathPrefix = r.result.PathPrefix
+		meta.Fetched = r.result.Fetched
+		meta.Queued = r.result.Queued
+		meta.RobotsDisallowed = r.result.RobotsDisallowed
+		for _, page := range r.result.Pages {
+			meta.Pages = append(meta.Pages, tooltypes.WebCrawlPage{
+				URL:         page.URL,
+				Title:       page.Title,
+				File:        page.File,
+				DuplicateOf: page.DuplicateOf,
+				Error:       page.Error,
+			})
+		}
+	}
+	result.Metadata = meta
+	return result
+}
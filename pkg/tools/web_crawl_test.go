@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebCrawlToolValidation(t *testing.T) {
+	tool := NewWebCrawlTool("")
+
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+	}{
+		{name: "Valid input", input: `{"url": "https://example.com/docs/"}`},
+		{name: "Valid HTTP for localhost", input: `{"url": "http://localhost:8080/docs/", "max_pages": 100, "max_depth": 5}`},
+		{name: "Missing URL", input: `{}`, expectError: true},
+		{name: "HTTP for external domain", input: `{"url": "http://example.com/docs/"}`, expectError: true},
+		{name: "Too many pages", input: `{"url": "https://example.com/docs/", "max_pages": 101}`, expectError: true},
+		{name: "Too deep", input: `{"url": "https://example.com/docs/", "max_depth": 6}`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tool.ValidateInput(&BasicState{}, tt.input)
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWebCrawlToolValidationDomainFilter(t *testing.T) {
+	domainsFile := t.TempDir() + "/domains.txt"
+	require.NoError(t, os.WriteFile(domainsFile, []byte("docs.example.com\n"), 0o644))
+	tool := NewWebCrawlTool(domainsFile)
+
+	assert.NoError(t, tool.ValidateInput(&BasicState{}, `{"url": "https://docs.example.com/guide/"}`))
+	err := tool.ValidateInput(&BasicState{}, `{"url": "https://other.example.com/guide/"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not in the allowed domains list")
+}
+
+func TestWebCrawlToolExecute(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/docs/":
+			fmt.Fprint(w, `<html><head><title>Docs</title></head><body><a href="one">One</a> <a href="two">Two</a></body></html>`)
+		case "/docs/one", "/docs/two":
+			fmt.Fprintf(w, `<html><head><title>%s</title></head><body>%s</body></html>`, r.URL.Path, r.URL.Path)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tool := NewWebCrawlTool("")
+	tool.dir = t.TempDir()
+	tool.delay = 0
+
+	result := tool.Execute(context.Background(), &BasicState{}, fmt.Sprintf(`{"url": %q, "max_pages": 2}`, server.URL+"/docs/"))
+	require.False(t, result.IsError(), result.GetError())
+	output := result.GetResult()
+	assert.Contains(t, output, "fetched 2 pages, 2 saved in total")
+	assert.Contains(t, output, "1 pages are still queued. Call web_crawl again with the same url to continue.")
+
+	structured := result.StructuredData()
+	var meta tooltypes.WebCrawlMetadata
+	require.True(t, tooltypes.ExtractMetadata(structured.Metadata, &meta))
+	assert.Equal(t, 2, meta.Fetched)
+	assert.Equal(t, 1, meta.Queued)
+	require.Len(t, meta.Pages, 2)
+	assert.FileExists(t, meta.Pages[1].File)
+
+	result = tool.Execute(context.Background(), &BasicState{}, fmt.Sprintf(`{"url": %q}`, server.URL+"/docs/"))
+	require.False(t, result.IsError(), result.GetError())
+	assert.Contains(t, result.GetResult(), "Resumed the crawl of")
+	assert.Contains(t, result.GetResult(), "fetched 1 pages, 3 saved in total")
+}
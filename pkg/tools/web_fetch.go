@@ -20,6 +20,7 @@ import (
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel/attribute"
 
+	"github.com/jingkaihe/kodelet/pkg/audit"
 	"github.com/jingkaihe/kodelet/pkg/logger"
 	"github.com/jingkaihe/kodelet/pkg/osutil"
 	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
@@ -508,8 +509,13 @@ func fetchWithSameDomainRedirects(ctx context.Context, urlStr string) (string, s
 
 	originalDomain := parsedURL.Hostname()
 
-	// Create a custom HTTP client with a redirect policy
+	// Create a custom HTTP client with a redirect policy. Every hop is recorded
+	// in the egress audit log.
 	client := &http.Client{
+		Transport: audit.NewEgressTransport(http.DefaultTransport, audit.EgressSource{
+			Tool:           "web_fetch",
+			ConversationID: ToolContextFromContext(ctx).ConversationID,
+		}),
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			// Check if the redirect is to the same domain
 			if req.URL.Hostname() != originalDomain {
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/jingkaihe/kodelet/pkg/audit"
 	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -373,6 +374,36 @@ func TestFetchWithSameDomainRedirects(t *testing.T) {
 	})
 }
 
+func TestFetchWithSameDomainRedirectsRecordsEgress(t *testing.T) {
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/page", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	ctx := ContextWithConversationID(context.Background(), "conv-egress")
+	_, _, err := fetchWithSameDomainRedirects(ctx, server.URL+"/redirect?token=secret")
+	require.NoError(t, err)
+
+	log, err := audit.NewEgressLog()
+	require.NoError(t, err)
+	entries, err := log.Read(audit.EgressFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "web_fetch", entries[0].Tool)
+	assert.Equal(t, "conv-egress", entries[0].ConversationID)
+	assert.Equal(t, "127.0.0.1", entries[0].Domain)
+	assert.Equal(t, http.StatusFound, entries[0].Status)
+	assert.NotContains(t, entries[0].URL, "secret")
+	assert.Equal(t, http.StatusOK, entries[1].Status)
+	assert.Equal(t, int64(len("hello")), entries[1].BytesReceived)
+}
+
 func TestConvertHTMLToMarkdown(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,264 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jingkaihe/kodelet/pkg/diffview"
+	"github.com/jingkaihe/kodelet/pkg/extensions"
+	"github.com/jingkaihe/kodelet/pkg/i18n"
+	"github.com/jingkaihe/kodelet/pkg/tools"
+	"github.com/pkg/errors"
+)
+
+// editReviewMinSideBySideWidth is the narrowest dialog content width that
+// shows hunks side by side; narrower dialogs show them as unified diffs.
+const editReviewMinSideBySideWidth = 60
+
+// editReviewState holds a file change under review and the decision for each
+// hunk. It is shared with the waiting broker, which reads the decisions once
+// the prompt resolves.
+type editReviewState struct {
+	path     string
+	hunks    []tools.EditHunk
+	accepted []bool
+	index    int
+	offset   int
+}
+
+// ReviewEdit shows a proposed file change hunk by hunk and returns which hunks
+// the user accepted. Dismissing the review rejects every hunk.
+func (b *tuiUIBroker) ReviewEdit(ctx context.Context, review tools.EditReview) ([]bool, error) {
+	if b == nil || b.ch == nil || b.isClosed() {
+		return nil, errors.New("tui edit review is not available")
+	}
+	state := &editReviewState{
+		path:     review.Path,
+		hunks:    review.Hunks,
+		accepted: make([]bool, len(review.Hunks)),
+	}
+	for i := range state.accepted {
+		state.accepted[i] = true
+	}
+	hunkWord := "hunks"
+	if len(review.Hunks) == 1 {
+		hunkWord = "hunk"
+	}
+	prompt := uiPromptState{
+		mode:             uiPromptEditReview,
+		id:               extensions.NewUIInputRequestID(),
+		title:            "Review change to " + review.Path,
+		message:          fmt.Sprintf("%s proposes %d %s. Only accepted hunks are written.", review.ToolName, len(review.Hunks), hunkWord),
+		submitButtonText: "Apply",
+		cancelButtonText: "Reject all",
+		editReview:       state,
+		response:         make(chan extensions.UIInputResponse, 1),
+	}
+	response, err := b.prompt(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	if response.Status != extensions.UIInputStatusSubmitted {
+		return make([]bool, len(review.Hunks)), nil
+	}
+	return append([]bool{}, state.accepted...), nil
+}
+
+// updateEditReviewKey handles the keys specific to reviewing a change and
+// reports whether the key was consumed.
+func (m *model) updateEditReviewKey(key string) bool {
+	review := m.activeUIPrompt.editReview
+	if review == nil || len(review.hunks) == 0 {
+		return false
+	}
+	switch key {
+	case "left", "h", "shift+tab":
+		review.selectHunk(review.index - 1)
+	case "right", "l", "tab":
+		review.selectHunk(review.index + 1)
+	case "y", "a":
+		review.accepted[review.index] = true
+		review.selectHunk(review.index + 1)
+	case "n", "x", "r":
+		review.accepted[review.index] = false
+		review.selectHunk(review.index + 1)
+	case " ", "space":
+		review.accepted[review.index] = !review.accepted[review.index]
+	case "A":
+		for i := range review.accepted {
+			review.accepted[i] = true
+		}
+		m.submitUIPrompt()
+		return true
+	case "up", "k":
+		review.offset = max(0, review.offset-1)
+	case "down", "j":
+		review.offset++
+	case "pgup":
+		review.offset = max(0, review.offset-m.maxEditReviewRows())
+	case "pgdown":
+		review.offset += m.maxEditReviewRows()
+	default:
+		return false
+	}
+	m.refreshViewport(false)
+	return true
+}
+
+// selectHunk moves to hunk index, stopping at the first and last hunks.
+func (r *editReviewState) selectHunk(index int) {
+	index = max(0, min(index, len(r.hunks)-1))
+	if index != r.index {
+		r.index = index
+		r.offset = 0
+	}
+}
+
+// acceptedSummary reports how many hunks are accepted, such as "2/3".
+func (r *editReviewState) acceptedSummary() string {
+	count := 0
+	for _, accepted := range r.accepted {
+		if accepted {
+			count++
+		}
+	}
+	return fmt.Sprintf("%d/%d", count, len(r.hunks))
+}
+
+func (m model) editReviewDialogWidth() int {
+	return max(4, min(m.contentWidth(), 160))
+}
+
+func (m model) maxEditReviewRows() int {
+	return max(3, m.height-14)
+}
+
+// renderEditReviewLines renders the hunk strip and the selected hunk of a
+// change under review.
+func (m model) renderEditReviewLines(review *editReviewState, width int) []string {
+	if review == nil || len(review.hunks) == 0 {
+		return nil
+	}
+	hunk := review.hunks[review.index]
+
+	status := "accepted"
+	if !review.accepted[review.index] {
+		status = "rejected"
+	}
+	var strip strings.Builder
+	for i, accepted := range review.accepted {
+		mark := "✓"
+		if !accepted {
+			mark = "✗"
+		}
+		if i == review.index {
+			strip.WriteString("[" + mark + "]")
+		} else {
+			strip.WriteString(" " + mark + " ")
+		}
+	}
+	lines := []string{
+		renderPersistentStyle(uiDialogBodyStyle, fitVisible(fmt.Sprintf("Hunk %d/%d (%s)  %s", review.index+1, len(review.hunks), status, strip.String()), width)),
+		renderPersistentStyle(uiDialogMutedStyle, fitVisible(hunk.Header, width)),
+	}
+
+	var rows []string
+	if width >= editReviewMinSideBySideWidth {
+		rows = renderEditReviewSideBySide(hunk.Lines, width, review.accepted[review.index])
+	} else {
+		rows = renderEditReviewUnified(hunk.Lines, width, review.accepted[review.index])
+	}
+
+	limit := m.maxEditReviewRows()
+	offset := min(review.offset, max(0, len(rows)-limit))
+	end := min(len(rows), offset+limit)
+	if offset > 0 {
+		lines = append(lines, renderPersistentStyle(uiDialogMutedStyle, fmt.Sprintf("↑ %d more", offset)))
+	}
+	lines = append(lines, rows[offset:end]...)
+	if end < len(rows) {
+		lines = append(lines, renderPersistentStyle(uiDialogMutedStyle, fmt.Sprintf("↓ %d more", len(rows)-end)))
+	}
+	return append(lines, "", renderPersistentStyle(uiDialogMutedStyle, fitVisible(m.text(i18n.EditReviewHint), width)))
+}
+
+func renderEditReviewSideBySide(lines []diffview.Line, width int, accepted bool) []string {
+	half := (width - 3) / 2
+	oldWidth, newWidth := editReviewNumberWidths(lines)
+	rows := diffview.SideBySide(lines)
+	out := make([]string, 0, len(rows))
+	for _, row := range rows {
+		left := renderEditReviewCell(row.Left, row.Left != nil && row.Left.OldLine > 0, oldWidth, half, accepted, true)
+		right := renderEditReviewCell(row.Right, row.Right != nil && row.Right.NewLine > 0, newWidth, width-3-half, accepted, false)
+		out = append(out, left+renderPersistentStyle(uiDialogMutedStyle, " │ ")+right)
+	}
+	return out
+}
+
+func renderEditReviewCell(line *diffview.Line, numbered bool, numberWidth, width int, accepted, old bool) string {
+	if line == nil || !numbered {
+		return strings.Repeat(" ", max(0, width))
+	}
+	number := line.NewLine
+	if old {
+		number = line.OldLine
+	}
+	text := fmt.Sprintf("%*d %s%s", numberWidth, number, editReviewSign(line.Kind), editReviewContent(line.Content))
+	return renderPersistentStyle(editReviewLineStyle(line.Kind, accepted), padVisible(fitVisible(text, width), width))
+}
+
+func renderEditReviewUnified(lines []diffview.Line, width int, accepted bool) []string {
+	oldWidth, newWidth := editReviewNumberWidths(lines)
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		oldNumber, newNumber := strings.Repeat(" ", oldWidth), strings.Repeat(" ", newWidth)
+		if line.OldLine > 0 {
+			oldNumber = fmt.Sprintf("%*d", oldWidth, line.OldLine)
+		}
+		if line.NewLine > 0 {
+			newNumber = fmt.Sprintf("%*d", newWidth, line.NewLine)
+		}
+		text := fmt.Sprintf("%s %s %s%s", oldNumber, newNumber, editReviewSign(line.Kind), editReviewContent(line.Content))
+		out = append(out, renderPersistentStyle(editReviewLineStyle(line.Kind, accepted), fitVisible(text, width)))
+	}
+	return out
+}
+
+func editReviewNumberWidths(lines []diffview.Line) (int, int) {
+	maxOld, maxNew := 1, 1
+	for _, line := range lines {
+		maxOld = max(maxOld, line.OldLine)
+		maxNew = max(maxNew, line.NewLine)
+	}
+	return len(fmt.Sprint(maxOld)), len(fmt.Sprint(maxNew))
+}
+
+func editReviewSign(kind diffview.LineKind) string {
+	switch kind {
+	case diffview.LineAdded:
+		return "+"
+	case diffview.LineRemoved:
+		return "-"
+	default:
+		return " "
+	}
+}
+
+func editReviewContent(content string) string {
+	return strings.ReplaceAll(content, "\t", "    ")
+}
+
+func editReviewLineStyle(kind diffview.LineKind, accepted bool) lipgloss.Style {
+	switch {
+	case kind == diffview.LineContext:
+		return uiDialogBodyStyle
+	case !accepted:
+		return uiDialogMutedStyle
+	case kind == diffview.LineAdded:
+		return diffAddedStyle
+	default:
+		return diffRemovedStyle
+	}
+}
@@ -0,0 +1,111 @@
+package tui
+
+import (
+	"context"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	xansi "github.com/charmbracelet/x/ansi"
+	"github.com/jingkaihe/kodelet/pkg/diffview"
+	"github.com/jingkaihe/kodelet/pkg/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type editReviewResult struct {
+	accepted []bool
+	err      error
+}
+
+func testEditReview() tools.EditReview {
+	return tools.EditReview{
+		ToolName: "file_edit",
+		Path:     "main.go",
+		Hunks: []tools.EditHunk{
+			{
+				Header: "@@ -1,2 +1,2 @@",
+				Lines: []diffview.Line{
+					{Kind: diffview.LineContext, OldLine: 1, NewLine: 1, Content: "package main"},
+					{Kind: diffview.LineRemoved, OldLine: 2, Content: "func main() {}"},
+					{Kind: diffview.LineAdded, NewLine: 2, Content: "func main() { run() }"},
+				},
+			},
+			{
+				Header: "@@ -9 +9 @@",
+				Lines: []diffview.Line{
+					{Kind: diffview.LineRemoved, OldLine: 9, Content: "// old"},
+					{Kind: diffview.LineAdded, NewLine: 9, Content: "// new"},
+				},
+			},
+		},
+	}
+}
+
+func openEditReview(t *testing.T, width int) (model, <-chan editReviewResult) {
+	t.Helper()
+	m := newModel(context.Background(), Config{})
+	t.Cleanup(m.cancel)
+	m.width = width
+	m.height = 40
+	m.resize()
+	m.running = true
+	m.activeRunID = 5
+
+	broker := newTUIUIBroker(m.runCh, m.activeRunID)
+	resultCh := make(chan editReviewResult, 1)
+	go func() {
+		accepted, err := broker.ReviewEdit(context.Background(), testEditReview())
+		resultCh <- editReviewResult{accepted: accepted, err: err}
+	}()
+
+	msg, ok := receiveRunMsg(t, m.runCh).(uiPromptRequestMsg)
+	require.True(t, ok)
+	updated, _ := m.Update(msg)
+	m = updated.(model)
+	require.NotNil(t, m.activeUIPrompt)
+	require.Equal(t, uiPromptEditReview, m.activeUIPrompt.mode)
+	return m, resultCh
+}
+
+func TestTUIEditReviewRejectsSelectedHunks(t *testing.T) {
+	m, resultCh := openEditReview(t, 120)
+
+	view := xansi.Strip(m.View())
+	assert.Contains(t, view, "Review change to main.go")
+	assert.Contains(t, view, "file_edit proposes 2 hunks")
+	assert.Contains(t, view, "Hunk 1/2 (accepted)")
+	assert.Contains(t, view, "2 -func main() {}")
+	assert.Contains(t, view, "│ 2 +func main() { run() }", "wide dialogs pair old and new lines side by side")
+	assert.Contains(t, view, "Apply 2/2")
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	m = updated.(model)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	m = updated.(model)
+	view = xansi.Strip(m.View())
+	assert.Contains(t, view, "Hunk 2/2 (rejected)")
+	assert.Contains(t, view, "Apply 1/2")
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(model)
+
+	result := <-resultCh
+	require.NoError(t, result.err)
+	assert.Equal(t, []bool{true, false}, result.accepted)
+	assert.Nil(t, m.activeUIPrompt)
+}
+
+func TestTUIEditReviewDismissRejectsEverything(t *testing.T) {
+	m, resultCh := openEditReview(t, 50)
+
+	view := xansi.Strip(m.View())
+	assert.Contains(t, view, "2   -func main() {}", "narrow dialogs fall back to a unified diff")
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updated.(model)
+
+	result := <-resultCh
+	require.NoError(t, result.err)
+	assert.Equal(t, []bool{false, false}, result.accepted)
+	assert.Nil(t, m.activeUIPrompt)
+}
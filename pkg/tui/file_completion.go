@@ -0,0 +1,272 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pkg/errors"
+)
+
+const (
+	// fileIndexTTL is how long the file index is reused before typing another
+	// @ reloads it.
+	fileIndexTTL = 30 * time.Second
+	// maxIndexedFiles bounds the file index of very large trees.
+	maxIndexedFiles = 50000
+	// fileSuggestionLimit bounds the ranked matches of one query.
+	fileSuggestionLimit   = 50
+	fileSuggestionMaxRows = 8
+)
+
+// skippedWalkDirs are not indexed outside git repositories.
+var skippedWalkDirs = map[string]bool{"node_modules": true, "vendor": true, "__pycache__": true}
+
+type fileIndexMsg struct {
+	cwd   string
+	files []string
+	err   error
+}
+
+func loadFileIndex(ctx context.Context, cwd string) tea.Cmd {
+	return func() tea.Msg {
+		dir, err := resolveSlashCommandCWD(cwd)
+		if err != nil {
+			return fileIndexMsg{cwd: strings.TrimSpace(cwd), err: err}
+		}
+		files, err := listCompletionFiles(ctx, dir)
+		return fileIndexMsg{cwd: strings.TrimSpace(cwd), files: files, err: err}
+	}
+}
+
+// listCompletionFiles lists the files under dir relative to it. Inside a git
+// repository these are the tracked and untracked files that are not ignored;
+// elsewhere hidden files and dependency directories are left out.
+func listCompletionFiles(ctx context.Context, dir string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "ls-files", "--cached", "--others", "--exclude-standard", "-z")
+	cmd.Dir = dir
+	if output, err := cmd.Output(); err == nil {
+		var files []string
+		for _, file := range bytes.Split(output, []byte{0}) {
+			if len(file) == 0 {
+				continue
+			}
+			files = append(files, string(file))
+			if len(files) >= maxIndexedFiles {
+				break
+			}
+		}
+		slices.Sort(files)
+		return slices.Compact(files), nil
+	}
+
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if p == dir {
+			return nil
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			if strings.HasPrefix(name, ".") || skippedWalkDirs[name] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(name, ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return nil
+		}
+		files = append(files, filepath.ToSlash(rel))
+		if len(files) >= maxIndexedFiles {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list files")
+	}
+	return files, nil
+}
+
+// fileMentionQuery returns the partial path typed after an @ at the end of
+// the draft.
+func (m model) fileMentionQuery() (string, bool) {
+	draft := m.textarea.Value()
+	token := draft[strings.LastIndexAny(draft, " \t\r\n")+1:]
+	if !strings.HasPrefix(token, "@") {
+		return "", false
+	}
+	return strings.TrimPrefix(token, "@"), true
+}
+
+func (m model) fileSuggestionsOpen() bool {
+	if m.profilePickerOpen || m.reasoningPickerOpen || m.historySearch != nil {
+		return false
+	}
+	if m.textarea.Value() == m.fileDismissedDraft {
+		return false
+	}
+	_, ok := m.fileMentionQuery()
+	return ok && (len(m.filteredFileSuggestions()) > 0 || m.fileIndexErr != nil)
+}
+
+// filteredFileSuggestions ranks the indexed files against the query: file
+// names starting with it first, then paths starting with it, then paths
+// containing it.
+func (m model) filteredFileSuggestions() []string {
+	query, ok := m.fileMentionQuery()
+	if !ok || len(m.fileIndex) == 0 {
+		return nil
+	}
+	query = strings.ToLower(query)
+	ranked := make([][]string, 3)
+	for _, file := range m.fileIndex {
+		lower := strings.ToLower(file)
+		switch {
+		case strings.HasPrefix(path.Base(lower), query):
+			ranked[0] = append(ranked[0], file)
+		case strings.HasPrefix(lower, query):
+			ranked[1] = append(ranked[1], file)
+		case strings.Contains(lower, query):
+			ranked[2] = append(ranked[2], file)
+		}
+	}
+	suggestions := make([]string, 0, fileSuggestionLimit)
+	for _, files := range ranked {
+		if query != "" {
+			slices.SortStableFunc(files, func(a, b string) int { return len(a) - len(b) })
+		}
+		for _, file := range files {
+			if len(suggestions) == fileSuggestionLimit {
+				return suggestions
+			}
+			suggestions = append(suggestions, file)
+		}
+	}
+	return suggestions
+}
+
+// refreshFileIndex reloads the file index when an @ is typed and the index is
+// missing, stale or for another directory.
+func (m *model) refreshFileIndex(now time.Time) tea.Cmd {
+	if _, ok := m.fileMentionQuery(); !ok || m.fileIndexLoading {
+		return nil
+	}
+	cwd := strings.TrimSpace(m.slashCommandCWD())
+	if m.fileIndexCWD == cwd && now.Sub(m.fileIndexLoadedAt) < fileIndexTTL {
+		return nil
+	}
+	m.fileIndexLoading = true
+	return loadFileIndex(m.ctx, cwd)
+}
+
+func (m *model) dismissFileSuggestions() {
+	m.fileSuggestionIndex = -1
+	m.fileDismissedDraft = m.textarea.Value()
+}
+
+func (m *model) moveFileSuggestionSelection(delta int) {
+	suggestions := m.filteredFileSuggestions()
+	if len(suggestions) == 0 {
+		m.fileSuggestionIndex = -1
+		return
+	}
+	next := m.fileSuggestionIndex + delta
+	if delta > 0 && next >= len(suggestions) {
+		next = -1
+	} else if delta < 0 && m.fileSuggestionIndex < 0 {
+		next = len(suggestions) - 1
+	}
+	m.fileSuggestionIndex = next
+}
+
+// selectFileSuggestion replaces the @ mention with the selected path.
+func (m *model) selectFileSuggestion() {
+	suggestions := m.filteredFileSuggestions()
+	if len(suggestions) == 0 {
+		return
+	}
+	index := m.fileSuggestionIndex
+	if index < 0 || index >= len(suggestions) {
+		index = 0
+	}
+	draft := m.textarea.Value()
+	start := strings.LastIndexAny(draft, " \t\r\n") + 1
+	m.textarea.SetValue(draft[:start] + suggestions[index] + " ")
+	m.fileSuggestionIndex = -1
+	m.fileDismissedDraft = ""
+}
+
+func (m model) maxVisibleFileSuggestions() int {
+	availableHeight := m.height - inputHeight - 2 - m.profilePickerHeight() - m.reasoningPickerHeight() - m.historySearchHeight() - 1
+	return max(1, min(fileSuggestionMaxRows, availableHeight))
+}
+
+func (m model) fileSuggestionsHeight() int {
+	if !m.fileSuggestionsOpen() {
+		return 0
+	}
+	height := min(len(m.filteredFileSuggestions()), m.maxVisibleFileSuggestions())
+	if m.fileIndexErr != nil {
+		height++
+	}
+	return height
+}
+
+func (m model) renderFileSuggestions() string {
+	if !m.fileSuggestionsOpen() {
+		return ""
+	}
+	width := m.slashCommandSuggestionsWidth()
+	if width <= 0 {
+		return ""
+	}
+
+	suggestions := m.filteredFileSuggestions()
+	start, end := suggestionWindow(len(suggestions), m.fileSuggestionIndex, m.maxVisibleFileSuggestions())
+	lines := make([]string, 0, end-start+1)
+	for i := start; i < end; i++ {
+		line := renderFileSuggestionLine(suggestions[i], width)
+		if i == m.fileSuggestionIndex {
+			line = renderPersistentStyle(slashCommandSelectedStyle, padVisible(line, width))
+		} else {
+			line = padVisible(line, width)
+		}
+		lines = append(lines, line)
+	}
+
+	if m.fileIndexErr != nil {
+		errorText := fitVisible("file completion unavailable: "+m.fileIndexErr.Error(), max(1, width-2))
+		lines = append(lines, padVisible(" "+renderPersistentStyle(slashCommandErrorStyle, errorText)+" ", width))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderFileSuggestionLine shows the file name highlighted after its directory.
+func renderFileSuggestionLine(file string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if lipgloss.Width(file) > width {
+		return renderPersistentStyle(slashCommandNameStyle, fitVisible(file, width))
+	}
+	dir, name := path.Split(file)
+	if dir == "" {
+		return renderPersistentStyle(slashCommandNameStyle, name)
+	}
+	return renderPersistentStyle(slashCommandDescriptionStyle, dir) + renderPersistentStyle(slashCommandNameStyle, name)
+}
@@ -0,0 +1,155 @@
+package tui
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFileCompletionTestModel(t *testing.T, files ...string) model {
+	t.Helper()
+	m := newModel(context.Background(), Config{})
+	t.Cleanup(m.cancel)
+	m.width = 80
+	m.height = 24
+	m.fileIndex = files
+	m.fileIndexCWD = m.slashCommandCWD()
+	m.fileIndexLoadedAt = time.Now()
+	m.resize()
+	return m
+}
+
+func TestFileSuggestionsRankFileNamesFirst(t *testing.T) {
+	m := newFileCompletionTestModel(t,
+		"cmd/kodelet/main.go",
+		"docs/MANUAL.md",
+		"pkg/tui/model.go",
+		"pkg/tui/model_test.go",
+		"pkg/types/models.go",
+	)
+
+	m.textarea.SetValue("look at @mod")
+	assert.Equal(t, []string{"pkg/tui/model.go", "pkg/types/models.go", "pkg/tui/model_test.go"}, m.filteredFileSuggestions())
+
+	m.textarea.SetValue("look at @pkg/tui")
+	assert.Equal(t, []string{"pkg/tui/model.go", "pkg/tui/model_test.go"}, m.filteredFileSuggestions())
+
+	m.textarea.SetValue("look at @MANUAL")
+	assert.Equal(t, []string{"docs/MANUAL.md"}, m.filteredFileSuggestions())
+
+	m.textarea.SetValue("email me@example.com")
+	assert.False(t, m.fileSuggestionsOpen())
+
+	m.textarea.SetValue("@nothing-matches")
+	assert.False(t, m.fileSuggestionsOpen())
+}
+
+func TestFileSuggestionKeyboardCompletion(t *testing.T) {
+	m := newFileCompletionTestModel(t, "pkg/tui/model.go", "pkg/tui/view.go")
+	m.textarea.SetValue("compare @pkg/tui")
+	m.resize()
+	require.True(t, m.fileSuggestionsOpen())
+	assert.Equal(t, 2, m.fileSuggestionsHeight())
+	assert.Contains(t, m.View(), "view.go")
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m = updated.(model)
+	require.Nil(t, cmd)
+	updated, cmd = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m = updated.(model)
+	require.Nil(t, cmd)
+	assert.Equal(t, 1, m.fileSuggestionIndex)
+
+	updated, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(model)
+	require.Nil(t, cmd)
+	assert.Equal(t, "compare pkg/tui/model.go ", m.textarea.Value())
+	assert.False(t, m.fileSuggestionsOpen())
+
+	m.textarea.SetValue(m.textarea.Value() + "with @vi")
+	updated, cmd = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = updated.(model)
+	require.Nil(t, cmd)
+	assert.Equal(t, "compare pkg/tui/model.go with pkg/tui/view.go ", m.textarea.Value())
+}
+
+func TestFileSuggestionEscapeDismissesUntilDraftChanges(t *testing.T) {
+	m := newFileCompletionTestModel(t, "main.go")
+	m.textarea.SetValue("@")
+	require.True(t, m.fileSuggestionsOpen())
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updated.(model)
+	assert.False(t, m.fileSuggestionsOpen())
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("m")})
+	m = updated.(model)
+	assert.Equal(t, "@m", m.textarea.Value())
+	assert.True(t, m.fileSuggestionsOpen())
+}
+
+func TestFileIndexRefreshesOnDemand(t *testing.T) {
+	m := newFileCompletionTestModel(t, "main.go")
+	m.textarea.SetValue("@")
+	assert.Nil(t, m.refreshFileIndex(time.Now()), "a fresh index is reused")
+
+	cmd := m.refreshFileIndex(time.Now().Add(fileIndexTTL + time.Second))
+	require.NotNil(t, cmd)
+	assert.True(t, m.fileIndexLoading)
+	assert.Nil(t, m.refreshFileIndex(time.Now().Add(fileIndexTTL+time.Second)), "one load at a time")
+
+	updated, _ := m.Update(fileIndexMsg{cwd: m.slashCommandCWD(), files: []string{"go.mod", "main.go"}})
+	m = updated.(model)
+	assert.False(t, m.fileIndexLoading)
+	assert.Equal(t, []string{"go.mod", "main.go"}, m.fileIndex)
+
+	m.textarea.SetValue("no mention")
+	assert.Nil(t, m.refreshFileIndex(time.Now().Add(time.Hour)))
+}
+
+func TestListCompletionFilesRespectsGitignore(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	require.NoError(t, exec.Command("git", "-C", dir, "init", "-q").Run())
+	for name, content := range map[string]string{
+		".gitignore":          "build/\n*.log\n",
+		"main.go":             "package main\n",
+		"pkg/util/util.go":    "package util\n",
+		"build/output.bin":    "binary",
+		"debug.log":           "log",
+		"docs/guide/intro.md": "# Intro\n",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	files, err := listCompletionFiles(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{".gitignore", "docs/guide/intro.md", "main.go", "pkg/util/util.go"}, files)
+
+	files, err = listCompletionFiles(context.Background(), filepath.Join(dir, "pkg"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"util/util.go"}, files)
+}
+
+func TestListCompletionFilesOutsideGit(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.go", ".env", ".cache/data", "node_modules/lib/index.js", "src/app.ts"} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+
+	files, err := listCompletionFiles(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go", "src/app.ts"}, files)
+}
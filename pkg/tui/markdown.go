@@ -13,6 +13,9 @@ func (m *model) renderMarkdown(text string, width int, kind markdownKind) string
 	if text == "" {
 		return ""
 	}
+	if m.noRender {
+		return wrapText(text, width)
+	}
 
 	renderer, err := m.markdownRenderer(max(10, width), kind)
 	if err != nil {
@@ -17,6 +17,7 @@ import (
 	"github.com/jingkaihe/kodelet/pkg/conversations"
 	"github.com/jingkaihe/kodelet/pkg/extensions"
 	"github.com/jingkaihe/kodelet/pkg/fragments"
+	"github.com/jingkaihe/kodelet/pkg/i18n"
 	"github.com/jingkaihe/kodelet/pkg/messagehistory"
 	"github.com/jingkaihe/kodelet/pkg/slashcommands"
 	"github.com/pkg/errors"
@@ -76,7 +77,7 @@ func newModel(ctx context.Context, config Config) model {
 	applyTheme(theme)
 
 	ta := textarea.New()
-	ta.Placeholder = "Ask kodelet..."
+	ta.Placeholder = i18n.T(config.Language, i18n.InputPlaceholder)
 	ta.Prompt = ""
 	ta.ShowLineNumbers = false
 	applyThemeToTextarea(&ta)
@@ -142,12 +143,14 @@ func newModel(ctx context.Context, config Config) model {
 		conversationID:          conversationID,
 		conversationWasResumed:  conversationWasResumed,
 		profile:                 profile,
+		language:                config.Language,
 		profileOptions:          profileOptions,
 		profileIndex:            profileIndex,
 		reasoningEffort:         reasoningEffort,
 		reasoningEffortOptions:  reasoningEffortOptions,
 		reasoningEffortIndex:    reasoningEffortIndex,
 		reasoningEffortExplicit: config.ReasoningEffortExplicit,
+		noRender:                config.NoRender,
 		reasoningPickerIndex:    reasoningEffortIndex,
 		cwd:                     cwd,
 		requestedCWD:            requestedCWD,
@@ -157,6 +160,7 @@ func newModel(ctx context.Context, config Config) model {
 		theme:                   theme,
 		themeSelection:          themeSelection,
 		slashCommandIndex:       -1,
+		fileSuggestionIndex:     -1,
 		viewport:                vp,
 		textarea:                ta,
 		spinner:                 sp,
@@ -1,18 +1,18 @@
 package tui
 
 import (
-	"os"
-	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/jingkaihe/kodelet/pkg/llm"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	"github.com/spf13/viper"
 )
 
 func loadProfileOptions() []string {
-	globalProfiles := loadProfilesFromConfigFile(filepath.Join(userHomeDir(), ".kodelet", "config.yaml"))
-	repoProfiles := loadProfilesFromConfigFile("kodelet-config.yaml")
+	globalConfigFile, _ := llm.GlobalConfigFile()
+	globalProfiles := loadProfilesFromConfigFile(globalConfigFile)
+	repoProfiles := loadProfilesFromConfigFile(llm.RepoConfigFile())
 	options := make([]string, 0, len(globalProfiles)+len(repoProfiles)+1)
 	seen := map[string]bool{}
 
@@ -78,14 +78,6 @@ func profilesFromViper(v *viper.Viper) map[string]llmtypes.ProfileConfig {
 	return profiles
 }
 
-func userHomeDir() string {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return ""
-	}
-	return homeDir
-}
-
 func (m model) canChangeProfile() bool {
 	return strings.TrimSpace(m.conversationID) == "" && !m.running && len(m.profileOptions) > 1
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/jingkaihe/kodelet/pkg/diffview"
+	"github.com/jingkaihe/kodelet/pkg/markdown"
 )
 
 func (m *model) renderTranscript() (string, []detailRegion) {
@@ -102,7 +103,12 @@ func (m *model) renderTranscript() (string, []detailRegion) {
 						}
 					}
 				case blockText:
-					trimmed := strings.TrimSpace(block.text)
+					text := block.text
+					if m.running && i == len(m.entries)-1 && blockIdx == len(entry.blocks)-1 {
+						// Hold back a fence marker that is still streaming in.
+						text = markdown.TrimPartialFence(text)
+					}
+					trimmed := strings.TrimSpace(text)
 					if trimmed != "" {
 						m.renderAssistantBlockSeparator(&b, &line, &renderedAssistantBlock)
 						renderedMarkdown := m.renderMarkdown(trimmed, m.transcriptTextWidth(), markdownAssistant)
@@ -169,7 +175,7 @@ func (m model) renderAssistantBlockSeparator(b *strings.Builder, line *int, rend
 }
 
 func (m model) renderQueuedSteering(b *strings.Builder, line *int) {
-	if len(m.queuedSteering) == 0 && strings.TrimSpace(m.steerError) == "" {
+	if len(m.queuedSteering) == 0 && len(m.queuedMessages) == 0 && strings.TrimSpace(m.steerError) == "" {
 		return
 	}
 
@@ -181,6 +187,12 @@ func (m model) renderQueuedSteering(b *strings.Builder, line *int) {
 		b.WriteString("\n")
 		*line += lineCount(rendered)
 	}
+	for i, message := range m.queuedMessages {
+		rendered := steeringStyle.Render(fmt.Sprintf("↳ queued message %d: ", i+1) + wrapText(strings.TrimSpace(message), m.transcriptTextWidth()-20))
+		b.WriteString(rendered)
+		b.WriteString("\n")
+		*line += lineCount(rendered)
+	}
 	if trimmed := strings.TrimSpace(m.steerError); trimmed != "" {
 		rendered := steeringErrorStyle.Render("⚠ " + wrapText(trimmed, m.transcriptTextWidth()-2))
 		b.WriteString(rendered)
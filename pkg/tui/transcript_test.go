@@ -67,6 +67,44 @@ func TestRenderTranscriptAddsSpacingBetweenAssistantBlocks(t *testing.T) {
 	assert.Contains(t, content, "\n\nfinal answer")
 }
 
+func TestRenderTranscriptNoRenderShowsRawMarkdown(t *testing.T) {
+	m := newModel(context.Background(), Config{NoRender: true})
+	t.Cleanup(m.cancel)
+	m.width = 80
+	m.height = 24
+	m.resize()
+	m.entries = []chatEntry{{
+		kind:   entryAssistant,
+		blocks: []assistantBlock{{kind: blockText, text: "## Heading\n\n- item"}},
+	}}
+
+	content, _ := m.renderTranscript()
+
+	plain := xansi.Strip(content)
+	assert.Contains(t, plain, "## Heading")
+	assert.Contains(t, plain, "- item")
+}
+
+func TestRenderTranscriptHoldsBackStreamingFenceMarker(t *testing.T) {
+	m := newModel(context.Background(), Config{NoRender: true})
+	t.Cleanup(m.cancel)
+	m.width = 80
+	m.height = 24
+	m.resize()
+	m.entries = []chatEntry{{
+		kind:   entryAssistant,
+		blocks: []assistantBlock{{kind: blockText, text: "Here is the fix:\n``"}},
+	}}
+
+	m.running = true
+	content, _ := m.renderTranscript()
+	assert.NotContains(t, xansi.Strip(content), "``")
+
+	m.running = false
+	content, _ = m.renderTranscript()
+	assert.Contains(t, xansi.Strip(content), "``")
+}
+
 func TestRenderTranscriptUsesHeavyUserMessageBar(t *testing.T) {
 	m := newModel(context.Background(), Config{})
 	t.Cleanup(m.cancel)
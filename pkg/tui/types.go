@@ -26,6 +26,8 @@ type Config struct {
 	ReasoningEffortExplicit bool
 	CWD                     string
 	Theme                   string
+	NoRender                bool
+	Language                string
 	Runner                  chat.ChatRunner
 }
 
@@ -114,6 +116,7 @@ type model struct {
 	conversationID          string
 	conversationWasResumed  bool
 	profile                 string
+	language                string
 	profileOptions          []string
 	profileIndex            int
 	reasoningEffort         string
@@ -134,6 +137,14 @@ type model struct {
 	slashCommandErr      error
 	slashDismissedDraft  string
 
+	fileIndex           []string
+	fileIndexCWD        string
+	fileIndexLoadedAt   time.Time
+	fileIndexLoading    bool
+	fileIndexErr        error
+	fileSuggestionIndex int
+	fileDismissedDraft  string
+
 	messageHistoryStore    *messagehistory.Store
 	messageHistoryScopeCWD string
 	initialHistoryPending  bool
@@ -154,6 +165,7 @@ type model struct {
 	entries []chatEntry
 	usage   llmtypes.Usage
 
+	noRender                       bool
 	assistantMarkdownRenderer      *glamour.TermRenderer
 	assistantMarkdownRendererWidth int
 	thoughtMarkdownRenderer        *glamour.TermRenderer
@@ -174,6 +186,7 @@ type model struct {
 
 	detailRegions  []detailRegion
 	queuedSteering []string
+	queuedMessages []string
 	steerError     string
 	status         string
 	err            error
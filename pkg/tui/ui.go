@@ -26,6 +26,7 @@ const (
 	uiPromptInput uiPromptMode = iota
 	uiPromptConfirm
 	uiPromptSelect
+	uiPromptEditReview
 )
 
 type uiPromptOrigin int
@@ -54,6 +55,8 @@ type uiPromptState struct {
 	optionValues []string
 	selectIndex  int
 
+	editReview *editReviewState
+
 	input    textinput.Model
 	response chan extensions.UIInputResponse
 }
@@ -424,6 +427,8 @@ func (m *model) submitUIPrompt() tea.Cmd {
 		m.resolveUIPrompt(extensions.UIInputResponse{Status: extensions.UIInputStatusSubmitted, Value: value})
 	case uiPromptConfirm:
 		m.resolveUIPrompt(extensions.UIInputResponse{Status: extensions.UIInputStatusSubmitted, Confirmed: true, Value: "true"})
+	case uiPromptEditReview:
+		m.resolveUIPrompt(extensions.UIInputResponse{Status: extensions.UIInputStatusSubmitted})
 	case uiPromptSelect:
 		if len(prompt.options) == 0 {
 			return nil
@@ -520,6 +525,8 @@ func uiPromptTitle(mode uiPromptMode, title string) string {
 		return "Extension requested confirmation"
 	case uiPromptSelect:
 		return "Extension requested selection"
+	case uiPromptEditReview:
+		return "Review file change"
 	default:
 		return "Extension requested input"
 	}
@@ -535,6 +542,8 @@ func uiPromptSubmitLabel(prompt uiPromptState) string {
 		return "Confirm"
 	case uiPromptSelect:
 		return "Select"
+	case uiPromptEditReview:
+		return "Apply"
 	default:
 		return "Submit"
 	}
@@ -229,6 +229,19 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.resize()
 		m.refreshViewport(false)
 
+	case fileIndexMsg:
+		m.fileIndexLoading = false
+		if msg.cwd != strings.TrimSpace(m.slashCommandCWD()) {
+			break
+		}
+		m.fileIndex = msg.files
+		m.fileIndexCWD = msg.cwd
+		m.fileIndexLoadedAt = time.Now()
+		m.fileIndexErr = msg.err
+		m.fileSuggestionIndex = -1
+		m.resize()
+		m.refreshViewport(false)
+
 	case messageHistoryMsg:
 		if strings.TrimSpace(msg.scopeCWD) != strings.TrimSpace(m.messageHistoryScopeCWD) {
 			break
@@ -291,6 +304,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.refreshViewport(false)
 				return m, nil
 			}
+			if m.fileSuggestionsOpen() {
+				m.dismissFileSuggestions()
+				m.resize()
+				m.refreshViewport(false)
+				return m, nil
+			}
 			if m.profilePickerOpen {
 				m.closeProfilePicker()
 				m.resize()
@@ -321,6 +340,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.refreshViewport(false)
 			}
 			return m, nil
+		case "ctrl+q":
+			if m.running && !m.runCancelling {
+				m.queueMessage()
+			}
+			return m, nil
 		case "ctrl+o":
 			m.toggleAllDetails()
 			m.refreshViewport(false)
@@ -346,6 +370,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.refreshViewport(false)
 				return m, nil
 			}
+			if m.fileSuggestionsOpen() {
+				m.moveFileSuggestionSelection(-1)
+				m.refreshViewport(false)
+				return m, nil
+			}
 			if m.profilePickerOpen {
 				m.moveProfilePicker(-1)
 				m.refreshViewport(false)
@@ -357,6 +386,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 			}
 		case "down", "tab":
+			if m.fileSuggestionsOpen() {
+				if key == "tab" {
+					m.selectFileSuggestion()
+					m.resize()
+					m.refreshViewport(false)
+				} else {
+					m.moveFileSuggestionSelection(1)
+					m.refreshViewport(false)
+				}
+				return m, nil
+			}
 			if m.slashCommandSuggestionsOpen() {
 				if key == "tab" {
 					m.selectSlashCommand()
@@ -385,6 +425,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.refreshViewport(false)
 				return m, nil
 			}
+			if m.fileSuggestionsOpen() {
+				m.selectFileSuggestion()
+				m.resize()
+				m.refreshViewport(false)
+				return m, nil
+			}
 			if m.profilePickerOpen {
 				m.selectProfilePickerOption(m.profilePickerIndex)
 				m.resize()
@@ -510,12 +556,18 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else {
 			m.status = "ready"
 		}
-		m.refreshViewport(m.autoFollow)
 		if m.quitAfterRun {
+			m.refreshViewport(m.autoFollow)
 			m.quitAfterRun = false
 			m.cancel()
 			return m, tea.Quit
 		}
+		if msg.err != nil || wasCancelling {
+			m.restoreQueuedMessages()
+		} else if cmd := m.submitQueuedMessage(); cmd != nil {
+			return m, tea.Batch(waitForMsg(m.runCh), cmd)
+		}
+		m.refreshViewport(m.autoFollow)
 		return m, waitForMsg(m.runCh)
 
 	case transcriptRefreshMsg:
@@ -548,7 +600,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 
 	previousTextareaValue := m.textarea.Value()
-	previousSlashCommandHeight := m.slashCommandSuggestionsHeight()
+	previousSuggestionsHeight := m.slashCommandSuggestionsHeight() + m.fileSuggestionsHeight()
 	var cmd tea.Cmd
 	m.textarea, cmd = m.textarea.Update(msg)
 	cmds = append(cmds, cmd)
@@ -556,9 +608,14 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.slashDismissedDraft != "" && m.slashDismissedDraft != m.textarea.Value() {
 			m.slashDismissedDraft = ""
 		}
+		if m.fileDismissedDraft != "" && m.fileDismissedDraft != m.textarea.Value() {
+			m.fileDismissedDraft = ""
+		}
 		m.slashCommandIndex = -1
+		m.fileSuggestionIndex = -1
+		cmds = append(cmds, m.refreshFileIndex(time.Now()))
 	}
-	if m.slashCommandSuggestionsHeight() != previousSlashCommandHeight {
+	if m.slashCommandSuggestionsHeight()+m.fileSuggestionsHeight() != previousSuggestionsHeight {
 		m.resize()
 		m.refreshViewport(false)
 	}
@@ -573,6 +630,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *model) updateUIPromptKey(msg tea.KeyMsg) tea.Cmd {
+	if m.activeUIPrompt.mode == uiPromptEditReview && m.updateEditReviewKey(msg.String()) {
+		return nil
+	}
 	switch msg.String() {
 	case "esc", "ctrl+c", "ctrl+d":
 		m.dismissUIPrompt()
@@ -816,9 +876,10 @@ func (m *model) resize() {
 	inputOuterHeight := inputHeight + 2
 	historySearchHeight := m.historySearchHeight()
 	slashCommandHeight := m.slashCommandSuggestionsHeight()
+	fileSuggestionsHeight := m.fileSuggestionsHeight()
 	settingsPickerHeight := m.profilePickerHeight() + m.reasoningPickerHeight()
 	footerHeight := 0
-	viewportHeight := m.height - inputOuterHeight - historySearchHeight - slashCommandHeight - settingsPickerHeight - footerHeight
+	viewportHeight := m.height - inputOuterHeight - historySearchHeight - slashCommandHeight - fileSuggestionsHeight - settingsPickerHeight - footerHeight
 	if viewportHeight < 1 {
 		viewportHeight = 1
 	}
@@ -839,6 +900,12 @@ func (m *model) submit() tea.Cmd {
 	if cmd, handled := m.handleLocalSlashCommand(message); handled {
 		return cmd
 	}
+	m.textarea.Reset()
+	return m.startRun(message)
+}
+
+// startRun sends message as a new user turn.
+func (m *model) startRun(message string) tea.Cmd {
 	m.profilePickerOpen = false
 	m.reasoningPickerOpen = false
 	m.dismissSlashCommandSuggestions()
@@ -846,7 +913,6 @@ func (m *model) submit() tea.Cmd {
 		m.conversationID = convtypes.GenerateID()
 	}
 
-	m.textarea.Reset()
 	m.appendSubmittedMessageToHistory(message)
 	persistMessageHistory := m.persistSubmittedMessageCommand(message)
 	m.entries = append(m.entries, chatEntry{kind: entryUser, content: userDisplayMessage(message)})
@@ -1072,6 +1138,52 @@ func (m *model) submitSteering() {
 	m.refreshViewport(true)
 }
 
+// queueMessage holds the composer draft locally until the running turn finishes.
+// Unlike steering, queued messages are sent as ordinary user turns in order.
+func (m *model) queueMessage() {
+	message := strings.TrimSpace(m.textarea.Value())
+	if message == "" {
+		return
+	}
+	m.textarea.Reset()
+	m.queuedMessages = append(m.queuedMessages, message)
+	m.status = fmt.Sprintf("%d queued", len(m.queuedMessages))
+	m.refreshViewport(true)
+}
+
+// submitQueuedMessage starts the next queued message, if any. Queued local
+// slash commands such as /theme are handled in the TUI as they would be when
+// typed, and the next queued message is sent after them.
+func (m *model) submitQueuedMessage() tea.Cmd {
+	var cmds []tea.Cmd
+	for len(m.queuedMessages) > 0 && !m.running {
+		message := m.queuedMessages[0]
+		m.queuedMessages = m.queuedMessages[1:]
+		draft := m.textarea.Value()
+		if cmd, handled := m.handleLocalSlashCommand(message); handled {
+			m.textarea.SetValue(draft)
+			cmds = append(cmds, cmd)
+			continue
+		}
+		cmds = append(cmds, m.startRun(message))
+	}
+	return tea.Batch(cmds...)
+}
+
+// restoreQueuedMessages moves queued messages back into the composer after a
+// failed or cancelled turn so they are not sent without the user noticing.
+func (m *model) restoreQueuedMessages() {
+	if len(m.queuedMessages) == 0 {
+		return
+	}
+	parts := append([]string(nil), m.queuedMessages...)
+	if draft := strings.TrimSpace(m.textarea.Value()); draft != "" {
+		parts = append(parts, draft)
+	}
+	m.queuedMessages = nil
+	m.textarea.SetValue(strings.Join(parts, "\n\n"))
+}
+
 func (m *model) stopRun() {
 	if m.cancelRun != nil {
 		m.cancelRun()
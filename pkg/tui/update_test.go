@@ -1399,3 +1399,106 @@ func TestScrollingBackToBottomResumesStreamingAutoFollow(t *testing.T) {
 	assert.True(t, m.viewport.AtBottom())
 	assert.True(t, m.autoFollow)
 }
+
+func TestCtrlQQueuesMessageWhileRunning(t *testing.T) {
+	m := newModel(context.Background(), Config{})
+	t.Cleanup(m.cancel)
+	m.width = 100
+	m.height = 30
+	m.resize()
+	m.activeRunID = 1
+	m.running = true
+	m.textarea.SetValue(" follow up ")
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlQ})
+	m = updated.(model)
+
+	assert.Nil(t, cmd)
+	assert.Empty(t, m.textarea.Value())
+	assert.Equal(t, []string{"follow up"}, m.queuedMessages)
+	assert.Equal(t, "1 queued", m.status)
+	content, _ := m.renderTranscript()
+	assert.Contains(t, content, "queued message 1: follow up")
+}
+
+func TestDoneSubmitsNextQueuedMessage(t *testing.T) {
+	runner := &recordingRunner{conversationID: "conversation-done"}
+	m := newModel(context.Background(), Config{ConversationID: "conversation-123", Runner: runner})
+	t.Cleanup(m.cancel)
+	m.width = 100
+	m.height = 30
+	m.resize()
+	m.activeRunID = 1
+	m.running = true
+	m.queuedMessages = []string{"first", "second"}
+
+	updated, cmd := m.Update(chatDoneMsg{runID: 1, conversationID: "conversation-123"})
+	m = updated.(model)
+
+	require.NotNil(t, cmd)
+	assert.True(t, m.running)
+	assert.Equal(t, []string{"second"}, m.queuedMessages)
+	require.NotEmpty(t, m.entries)
+	assert.Equal(t, chatEntry{kind: entryUser, content: "first"}, m.entries[len(m.entries)-1])
+}
+
+func TestTabDoesNotQueueMessageWhileRunning(t *testing.T) {
+	m := newModel(context.Background(), Config{})
+	t.Cleanup(m.cancel)
+	m.width = 100
+	m.height = 30
+	m.resize()
+	m.activeRunID = 1
+	m.running = true
+	m.textarea.SetValue("follow up")
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = updated.(model)
+
+	assert.Empty(t, m.queuedMessages)
+	assert.Contains(t, m.textarea.Value(), "follow up")
+}
+
+func TestDoneHandlesQueuedLocalSlashCommand(t *testing.T) {
+	runner := &recordingRunner{conversationID: "conversation-done"}
+	m := newModel(context.Background(), Config{ConversationID: "conversation-123", Runner: runner})
+	t.Cleanup(m.cancel)
+	m.width = 100
+	m.height = 30
+	m.resize()
+	m.activeRunID = 1
+	m.running = true
+	m.queuedMessages = []string{"/theme", "next"}
+	m.textarea.SetValue("draft")
+
+	updated, cmd := m.Update(chatDoneMsg{runID: 1, conversationID: "conversation-123"})
+	m = updated.(model)
+
+	require.NotNil(t, cmd)
+	require.NotNil(t, m.activeUIPrompt)
+	assert.Equal(t, uiPromptTheme, m.activeUIPrompt.origin)
+	assert.Equal(t, "draft", m.textarea.Value())
+	assert.True(t, m.running)
+	assert.Empty(t, m.queuedMessages)
+	assert.Equal(t, chatEntry{kind: entryUser, content: "next"}, m.entries[len(m.entries)-1])
+}
+
+func TestCancelledRunRestoresQueuedMessages(t *testing.T) {
+	m := newModel(context.Background(), Config{})
+	t.Cleanup(m.cancel)
+	m.width = 100
+	m.height = 30
+	m.resize()
+	m.activeRunID = 1
+	m.running = true
+	m.runCancelling = true
+	m.queuedMessages = []string{"first", "second"}
+	m.textarea.SetValue("draft")
+
+	updated, _ := m.Update(chatDoneMsg{runID: 1, err: context.Canceled})
+	m = updated.(model)
+
+	assert.False(t, m.running)
+	assert.Empty(t, m.queuedMessages)
+	assert.Equal(t, "first\n\nsecond\n\ndraft", m.textarea.Value())
+}
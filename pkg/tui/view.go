@@ -5,6 +5,7 @@ import (
 
 	"github.com/charmbracelet/lipgloss"
 	xansi "github.com/charmbracelet/x/ansi"
+	"github.com/jingkaihe/kodelet/pkg/i18n"
 	"github.com/jingkaihe/kodelet/pkg/slashcommands"
 )
 
@@ -50,6 +51,7 @@ func (m model) View() string {
 	transcript := m.viewport.View()
 	historySearch := m.renderHistorySearch()
 	slashSuggestions := m.renderSlashCommandSuggestions()
+	fileSuggestions := m.renderFileSuggestions()
 	profilePicker := m.renderProfilePicker()
 	reasoningPicker := m.renderReasoningPicker()
 	input := m.renderInputBox()
@@ -60,6 +62,9 @@ func (m model) View() string {
 	if strings.TrimSpace(slashSuggestions) != "" {
 		parts = append(parts, slashSuggestions)
 	}
+	if strings.TrimSpace(fileSuggestions) != "" {
+		parts = append(parts, fileSuggestions)
+	}
 	if strings.TrimSpace(profilePicker) != "" {
 		parts = append(parts, profilePicker)
 	}
@@ -150,20 +155,22 @@ func (m model) renderShortcutsDialog() string {
 		shortcut    string
 		description string
 	}{
-		{shortcut: "Enter", description: "Send message"},
-		{shortcut: "Shift+Enter", description: "Insert newline"},
-		{shortcut: "Ctrl+G", description: "Edit draft in $EDITOR"},
-		{shortcut: "Ctrl+R", description: "Search previous sent messages"},
-		{shortcut: "Ctrl+T", description: "Change profile before starting"},
-		{shortcut: "Ctrl+Y", description: "Change reasoning effort before starting"},
-		{shortcut: "Ctrl+O", description: "Toggle thought/tool details"},
-		{shortcut: "PgUp/PgDown", description: "Scroll transcript"},
-		{shortcut: "Esc", description: "Cancel or dismiss"},
-		{shortcut: "Ctrl+C", description: "Cancel run or quit"},
+		{shortcut: "Enter", description: m.text(i18n.ShortcutSend)},
+		{shortcut: "Shift+Enter", description: m.text(i18n.ShortcutNewline)},
+		{shortcut: "Ctrl+Q", description: m.text(i18n.ShortcutQueue)},
+		{shortcut: "@", description: m.text(i18n.ShortcutFilePath)},
+		{shortcut: "Ctrl+G", description: m.text(i18n.ShortcutEditor)},
+		{shortcut: "Ctrl+R", description: m.text(i18n.ShortcutSearchHistory)},
+		{shortcut: "Ctrl+T", description: m.text(i18n.ShortcutProfile)},
+		{shortcut: "Ctrl+Y", description: m.text(i18n.ShortcutReasoningEffort)},
+		{shortcut: "Ctrl+O", description: m.text(i18n.ShortcutDetails)},
+		{shortcut: "PgUp/PgDown", description: m.text(i18n.ShortcutScroll)},
+		{shortcut: "Esc", description: m.text(i18n.ShortcutDismiss)},
+		{shortcut: "Ctrl+C", description: m.text(i18n.ShortcutQuit)},
 	}
 
 	lines := []string{
-		renderPersistentStyle(uiDialogTitleStyle, fitVisible("Shortcuts", contentWidth)),
+		renderPersistentStyle(uiDialogTitleStyle, fitVisible(m.text(i18n.ShortcutsTitle), contentWidth)),
 		"",
 	}
 	for _, row := range rows {
@@ -171,7 +178,7 @@ func (m model) renderShortcutsDialog() string {
 		description := renderPersistentStyle(uiDialogBodyStyle, fitVisible(row.description, descriptionWidth))
 		lines = append(lines, shortcut+"  "+description)
 	}
-	lines = append(lines, "", renderPersistentStyle(uiDialogMutedStyle, fitVisible("Press Esc, Enter, ?, or q to close.", contentWidth)))
+	lines = append(lines, "", renderPersistentStyle(uiDialogMutedStyle, fitVisible(m.text(i18n.ShortcutsClose), contentWidth)))
 
 	top := uiDialogBorderStyle.Render("╭" + strings.Repeat("─", width-2) + "╮")
 	bottom := uiDialogBorderStyle.Render("╰" + strings.Repeat("─", width-2) + "╯")
@@ -266,6 +273,9 @@ func (m model) renderUIDialog() string {
 	}
 	prompt := *m.activeUIPrompt
 	width := m.uiDialogWidth()
+	if prompt.mode == uiPromptEditReview {
+		width = m.editReviewDialogWidth()
+	}
 	if width <= 4 {
 		return ""
 	}
@@ -291,19 +301,24 @@ func (m model) renderUIDialog() string {
 		}
 		lines = append(lines, m.renderUIInputLine(prompt, contentWidth))
 		if prompt.required && strings.TrimSpace(prompt.input.Value()) == "" {
-			lines = append(lines, renderPersistentStyle(uiDialogMutedStyle, "Required"))
+			lines = append(lines, renderPersistentStyle(uiDialogMutedStyle, m.text(i18n.Required)))
 		}
 	case uiPromptConfirm:
 		if len(lines) > 1 {
 			lines = append(lines, "")
 		}
-		lines = append(lines, renderPersistentStyle(uiDialogMutedStyle, "Press Enter/Y to confirm or Esc/N to cancel."))
+		lines = append(lines, renderPersistentStyle(uiDialogMutedStyle, m.text(i18n.ConfirmHint)))
+	case uiPromptEditReview:
+		if len(lines) > 1 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, m.renderEditReviewLines(prompt.editReview, contentWidth)...)
 	case uiPromptSelect:
 		if len(lines) > 1 {
 			lines = append(lines, "")
 		}
 		if len(prompt.options) == 0 {
-			lines = append(lines, renderPersistentStyle(uiDialogMutedStyle, "No options available."))
+			lines = append(lines, renderPersistentStyle(uiDialogMutedStyle, m.text(i18n.NoOptions)))
 		} else {
 			lines = append(lines, m.renderUISelectLines(prompt, contentWidth)...)
 		}
@@ -380,6 +395,9 @@ func (m model) renderUIDialogActions(prompt uiPromptState, width int) string {
 		submit = "[Y] " + uiPromptSubmitLabel(prompt)
 		cancel = "[N] " + uiPromptCancelLabel(prompt)
 	}
+	if prompt.mode == uiPromptEditReview && prompt.editReview != nil {
+		submit += " " + prompt.editReview.acceptedSummary()
+	}
 	line := renderPersistentStyle(uiDialogCancelStyle, cancel) + "  " + renderPersistentStyle(uiDialogButtonStyle, submit)
 	if lipgloss.Width(line) > width {
 		return fitVisible(cancel+"  "+submit, width)
@@ -465,33 +483,33 @@ type visibleSlashCommandSuggestion struct {
 }
 
 func visibleSlashCommandSuggestions(commands []slashcommands.Command, selectedIndex, limit int) []visibleSlashCommandSuggestion {
-	if limit <= 0 || len(commands) == 0 {
-		return nil
-	}
-	if len(commands) <= limit {
-		visible := make([]visibleSlashCommandSuggestion, 0, len(commands))
-		for i, command := range commands {
-			visible = append(visible, visibleSlashCommandSuggestion{command: command, index: i})
-		}
-		return visible
+	start, end := suggestionWindow(len(commands), selectedIndex, limit)
+	visible := make([]visibleSlashCommandSuggestion, 0, end-start)
+	for i := start; i < end; i++ {
+		visible = append(visible, visibleSlashCommandSuggestion{command: commands[i], index: i})
 	}
+	return visible
+}
 
-	start := 0
+// suggestionWindow returns the range of at most limit suggestions to show so
+// that the selected one is visible.
+func suggestionWindow(count, selectedIndex, limit int) (start, end int) {
+	if limit <= 0 || count == 0 {
+		return 0, 0
+	}
+	if count <= limit {
+		return 0, count
+	}
 	if selectedIndex >= 0 {
 		start = selectedIndex - limit + 1
 		if start < 0 {
 			start = 0
 		}
-		if start+limit > len(commands) {
-			start = len(commands) - limit
+		if start+limit > count {
+			start = count - limit
 		}
 	}
-
-	visible := make([]visibleSlashCommandSuggestion, 0, limit)
-	for i := start; i < min(len(commands), start+limit); i++ {
-		visible = append(visible, visibleSlashCommandSuggestion{command: commands[i], index: i})
-	}
-	return visible
+	return start, min(count, start+limit)
 }
 
 func renderSlashCommandSuggestionLine(command slashcommands.Command, width int) string {
@@ -1135,8 +1153,8 @@ func (m model) flowingWaterFrame() string {
 }
 
 func (m model) workingStatusText() string {
-	if len(tuiWorkingMessages) == 0 {
-		return "Working…"
+	if len(tuiWorkingMessages) == 0 || !i18n.IsEnglish(m.language) {
+		return m.text(i18n.Working)
 	}
 	const framesPerWorkingMessage = 36
 	frame := m.workingFrame
@@ -1147,6 +1165,11 @@ func (m model) workingStatusText() string {
 	return tuiWorkingMessages[messageIndex]
 }
 
+// text returns a fixed UI string in the configured language.
+func (m model) text(msg i18n.Message) string {
+	return i18n.T(m.language, msg)
+}
+
 func (m model) inputContentWidth() int {
 	outerWidth := max(1, m.inputOuterWidth())
 	paddingWidth := 2
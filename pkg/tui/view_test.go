@@ -134,6 +134,21 @@ func TestShortcutsDialogRendersWithThemeColors(t *testing.T) {
 	}
 }
 
+func TestShortcutsDialogUsesConfiguredLanguage(t *testing.T) {
+	m := newModel(context.Background(), Config{Language: "ja"})
+	t.Cleanup(m.cancel)
+	m.width = 96
+	m.height = 24
+	m.resize()
+	m.openShortcutsDialog()
+
+	view := xansi.Strip(m.View())
+	assert.Contains(t, view, "ショートカット")
+	assert.Contains(t, view, "$EDITOR で下書きを編集")
+	assert.Equal(t, "kodelet に質問...", m.textarea.Placeholder)
+	assert.Equal(t, "作業中…", m.workingStatusText())
+}
+
 func TestNotificationSeverityUsesThemeColors(t *testing.T) {
 	withANSI256ColorProfile(t)
 
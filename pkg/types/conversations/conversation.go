@@ -7,6 +7,7 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"strings"
 	"time"
 
@@ -14,17 +15,29 @@ import (
 	"github.com/jingkaihe/kodelet/pkg/types/tools"
 )
 
+// CompactedTranscriptMetadataKey stores the messages that compaction removed
+// from a conversation's context, so they can still be searched.
+const CompactedTranscriptMetadataKey = "compacted_transcript"
+
+// RecipeNameMetadataKey stores the name of the recipe a conversation was
+// started with.
+const RecipeNameMetadataKey = "recipe_name"
+
 // QueryOptions provides filtering and sorting options for conversation queries
 type QueryOptions struct {
-	StartDate  *time.Time // Filter by start date
-	EndDate    *time.Time // Filter by end date
-	SearchTerm string     // Text to search for in messages
-	Provider   string     // Filter by LLM provider (e.g., "anthropic", "openai")
-	CWD        string     // Filter by canonical working directory
-	Limit      int        // Maximum number of results
-	Offset     int        // Offset for pagination
-	SortBy     string     // Field to sort by
-	SortOrder  string     // "asc" or "desc"
+	StartDate     *time.Time // Filter by start date
+	EndDate       *time.Time // Filter by end date
+	SearchTerm    string     // Text to search for in messages
+	ContentSearch string     // Text to search for anywhere in the stored messages and metadata
+	Provider      string     // Filter by LLM provider (e.g., "anthropic", "openai")
+	CWD           string     // Filter by canonical working directory
+	CWDSubdirs    bool       // Also match conversations started in subdirectories of CWD
+	Model         string     // Filter by the model recorded in conversation metadata
+	Profile       string     // Filter by the profile recorded in conversation metadata
+	Limit         int        // Maximum number of results
+	Offset        int        // Offset for pagination
+	SortBy        string     // Field to sort by
+	SortOrder     string     // "asc" or "desc"
 }
 
 // ConversationRecord represents a persisted conversation with its messages and metadata
@@ -63,6 +76,43 @@ type QueryResult struct {
 	QueryOptions
 }
 
+// SearchHit is a conversation that matched a full-text search
+type SearchHit struct {
+	ConversationSummary
+	Snippet string `json:"snippet"` // Matching text from the summary or messages
+}
+
+// SearchResult represents the result of a full-text conversation search
+type SearchResult struct {
+	Hits  []SearchHit `json:"hits"`
+	Total int         `json:"total"` // Represents the total number of matching conversations without pagination
+	QueryOptions
+}
+
+// PruneOptions selects the conversations a retention pass removes. Limits
+// left at zero are not applied. Conversations with an active run are kept.
+type PruneOptions struct {
+	Before   time.Time // Prune conversations last updated before this time
+	MaxCount int       // Keep at most this many of the most recently updated conversations
+	MaxBytes int64     // Keep the most recently updated conversations within this much stored data
+	DryRun   bool      // Report what would be pruned without deleting it
+}
+
+// PrunedConversation is a conversation removed, or selected for removal, by a retention pass
+type PrunedConversation struct {
+	ID        string    `json:"id"`
+	Summary   string    `json:"summary,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Bytes     int64     `json:"bytes"` // Size of the stored messages, tool results and metadata
+}
+
+// PruneResult represents the result of a retention pass
+type PruneResult struct {
+	Conversations []PrunedConversation `json:"conversations"`
+	Bytes         int64                `json:"bytes"`   // Stored data of the pruned conversations
+	DryRun        bool                 `json:"dry_run"` // Whether the conversations were left in place
+}
+
 // NewConversationRecord creates a new conversation record with a unique ID
 func NewConversationRecord(id string) ConversationRecord {
 	now := time.Now()
@@ -82,6 +132,23 @@ func NewConversationRecord(id string) ConversationRecord {
 	}
 }
 
+// Fork returns a copy of the record under a new ID. Messages, tool results,
+// metadata and the working directory are kept so the copy can be continued
+// independently; token and cost usage start from zero, except the context
+// window figures, which describe the copied messages.
+func (cr *ConversationRecord) Fork() ConversationRecord {
+	forked := NewConversationRecord("")
+	forked.RawMessages = append(json.RawMessage(nil), cr.RawMessages...)
+	forked.Provider = cr.Provider
+	forked.Summary = cr.Summary
+	forked.CWD = cr.CWD
+	forked.Usage.CurrentContextWindow = cr.Usage.CurrentContextWindow
+	forked.Usage.MaxContextWindow = cr.Usage.MaxContextWindow
+	maps.Copy(forked.ToolResults, cr.ToolResults)
+	maps.Copy(forked.Metadata, cr.Metadata)
+	return forked
+}
+
 // ToSummary converts a ConversationRecord to a ConversationSummary
 func (cr *ConversationRecord) ToSummary() ConversationSummary {
 	// Extract first message by parsing the raw messages
@@ -124,13 +191,24 @@ func (cr *ConversationRecord) ToSummary() ConversationSummary {
 		FirstMessage: firstMessage,
 		Summary:      cr.Summary,
 		Provider:     cr.Provider,
-		Metadata:     cr.Metadata,
+		Metadata:     summaryMetadata(cr.Metadata),
 		Usage:        cr.Usage,
 		CreatedAt:    cr.CreatedAt,
 		UpdatedAt:    cr.UpdatedAt,
 	}
 }
 
+// summaryMetadata drops the archived compacted transcript, which can be
+// large and is only needed when searching the full record.
+func summaryMetadata(metadata map[string]any) map[string]any {
+	if _, ok := metadata[CompactedTranscriptMetadataKey]; !ok {
+		return metadata
+	}
+	trimmed := maps.Clone(metadata)
+	delete(trimmed, CompactedTranscriptMetadataKey)
+	return trimmed
+}
+
 func parseConversationDisplayMetadata(metadata map[string]any) map[string]string {
 	if len(metadata) == 0 {
 		return nil
@@ -232,3 +310,9 @@ func (cs ConversationSummary) GetUsage() llmtypes.Usage {
 func (cs ConversationSummary) GetProvider() string {
 	return cs.Provider
 }
+
+// GetModel returns the model the conversation was last run with, if recorded
+func (cs ConversationSummary) GetModel() string {
+	model, _ := cs.Metadata["model"].(string)
+	return model
+}
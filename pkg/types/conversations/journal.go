@@ -0,0 +1,66 @@
+package conversations
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+// JournalEventKind is the kind of a conversation journal event
+type JournalEventKind string
+
+const (
+	// JournalMessage is a message added to the conversation history.
+	JournalMessage JournalEventKind = "message"
+	// JournalAssistantText is text the assistant streamed for a message it
+	// has not finished.
+	JournalAssistantText JournalEventKind = "assistant_text"
+	// JournalToolResult is the result of a tool call that finished.
+	JournalToolResult JournalEventKind = "tool_result"
+)
+
+// JournalEvent is an entry of the write-ahead journal of a conversation: a
+// change to the conversation since it was last saved, written as it happens
+// so that a crash loses as little of the conversation as possible.
+type JournalEvent struct {
+	Kind     JournalEventKind `json:"kind"`
+	Provider string           `json:"provider"`
+	// Index is the position in the history of the message a message or
+	// assistant text event adds to.
+	Index int `json:"index"`
+	// Message is the raw provider message of a message event.
+	Message json.RawMessage `json:"message,omitempty"`
+	// Text is the streamed text of an assistant text event, or the output a
+	// tool result event returned to the model.
+	Text       string                      `json:"text,omitempty"`
+	ToolCallID string                      `json:"toolCallId,omitempty"`
+	IsError    bool                        `json:"isError,omitempty"`
+	ToolResult *tools.StructuredToolResult `json:"toolResult,omitempty"`
+	CreatedAt  time.Time                   `json:"createdAt"`
+}
+
+// JournalToolOutput is the output a journaled tool call returned to the model
+type JournalToolOutput struct {
+	Text    string
+	IsError bool
+}
+
+// JournalReplay is what replaying a conversation journal recovered beyond
+// the saved record.
+type JournalReplay struct {
+	// Messages is the number of messages added to the record.
+	Messages int
+	// AssistantText is the text of an assistant message that was still
+	// streaming, to be added to the history after the recovered messages.
+	AssistantText string
+	// ToolOutputs holds, by tool call ID, the outputs of the tool calls that
+	// finished, so that the results of an interrupted batch of tool calls
+	// can be added to the history.
+	ToolOutputs map[string]JournalToolOutput
+}
+
+// Recovered reports whether the journal held anything the record did not.
+func (r JournalReplay) Recovered() bool {
+	return r.Messages > 0 || r.AssistantText != "" || len(r.ToolOutputs) > 0
+}
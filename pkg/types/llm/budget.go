@@ -0,0 +1,24 @@
+package llm
+
+import "fmt"
+
+// BudgetExceededError is returned by SendMessage when a run reaches its
+// MessageOpt.MaxCostUSD or MaxTotalTokens limit. The conversation is saved
+// before it is returned, so the run can be resumed with a larger budget.
+type BudgetExceededError struct {
+	// Limit is "cost" or "tokens".
+	Limit          string
+	CostUSD        float64
+	MaxCostUSD     float64
+	TotalTokens    int
+	MaxTotalTokens int
+	// Turns is the number of model turns completed before stopping.
+	Turns int
+}
+
+func (e *BudgetExceededError) Error() string {
+	if e.Limit == "cost" {
+		return fmt.Sprintf("cost budget exceeded after %d turns: spent $%.4f of $%.4f", e.Turns, e.CostUSD, e.MaxCostUSD)
+	}
+	return fmt.Sprintf("token budget exceeded after %d turns: used %d of %d tokens", e.Turns, e.TotalTokens, e.MaxTotalTokens)
+}
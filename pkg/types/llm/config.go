@@ -24,6 +24,24 @@ const (
 	// DefaultBashTimeout is the default maximum timeout for bash tool calls.
 	DefaultBashTimeout = 120 * time.Second
 
+	// DefaultToolMaxOutputBytes caps the output of a tool call sent to the
+	// model when tool_limits.max_output_bytes is unset. It sits above the
+	// limits of the built-in tools, so it mostly applies to MCP and
+	// extension tools.
+	DefaultToolMaxOutputBytes = 200_000
+
+	// DefaultToolStallAfter is how long a tool call may go without progress
+	// before it is reported as stalled.
+	DefaultToolStallAfter = 2 * time.Minute
+
+	// DefaultToolCacheTTL is how long a cached tool result is reused when
+	// tool_cache.ttl is unset.
+	DefaultToolCacheTTL = time.Hour
+
+	// DefaultToolCacheMaxBytes caps the tool result cache on disk when
+	// tool_cache.max_bytes is unset.
+	DefaultToolCacheMaxBytes int64 = 100 << 20
+
 	// AnthropicAPIAccessAuto uses subscription auth if available, then falls back to API key
 	AnthropicAPIAccessAuto AnthropicAPIAccess = "auto"
 	// AnthropicAPIAccessSubscription forces use of subscription-based OAuth auth only
@@ -43,6 +61,13 @@ const (
 
 	// DefaultCompactRatio is the default context window utilization threshold for automatic compaction.
 	DefaultCompactRatio = 0.8
+	// DefaultCompactReinjectTokens is the default token budget for re-injecting recently accessed files after compaction.
+	DefaultCompactReinjectTokens = 20000
+
+	// CompactStrategyAuto chooses between compaction and evicting old tool results by estimated cost.
+	CompactStrategyAuto = "auto"
+	// CompactStrategyCompact always compacts the context with the provider's compactor.
+	CompactStrategyCompact = "compact"
 )
 
 // IsPatchMode reports whether the tool mode should use apply_patch-only workflows.
@@ -73,16 +98,22 @@ type Config struct {
 	AnthropicAPIAccess      AnthropicAPIAccess `mapstructure:"anthropic_api_access" json:"anthropic_api_access" yaml:"anthropic_api_access"`   // AnthropicAPIAccess controls how to authenticate with Anthropic API
 	AnthropicAccount        string             `mapstructure:"anthropic_account" json:"anthropic_account" yaml:"anthropic_account"`            // AnthropicAccount specifies which Anthropic subscription account to use
 	Aliases                 map[string]string  `mapstructure:"aliases" json:"aliases,omitempty" yaml:"aliases,omitempty"`                      // Aliases maps short model names to full model names
+	ModelFeatures           CustomFeatures     `mapstructure:"model_features" json:"model_features,omitempty" yaml:"model_features,omitempty"` // ModelFeatures overrides the tool, vision and output token support of models
 	ModelAliasesResolved    bool               `mapstructure:"-" json:"-" yaml:"-"`                                                            // ModelAliasesResolved prevents effective model names from being resolved as aliases again
 	Retry                   RetryConfig        `mapstructure:"retry" json:"retry" yaml:"retry"`                                                // Retry configuration for API calls
 	Sysprompt               string             `mapstructure:"sysprompt" json:"sysprompt,omitempty" yaml:"sysprompt,omitempty"`                // Sysprompt is the path to a custom system prompt template file
 	SyspromptArgs           map[string]string  `mapstructure:"sysprompt_args" json:"sysprompt_args,omitempty" yaml:"sysprompt_args,omitempty"` // SyspromptArgs are custom template arguments for system prompt rendering
 	Bash                    *BashConfig        `mapstructure:"bash" json:"bash,omitempty" yaml:"bash,omitempty"`                               // Bash contains bash tool configuration
+	Language                string             `mapstructure:"language" json:"language,omitempty" yaml:"language,omitempty"`                   // Language is the language the agent responds in and the chat UI is shown in (e.g. "ja"); empty means English
 
 	// Profile system configuration
 	Profile  string                   `mapstructure:"profile" json:"profile,omitempty" yaml:"profile,omitempty"`    // Active profile name
 	Profiles map[string]ProfileConfig `mapstructure:"profiles" json:"profiles,omitempty" yaml:"profiles,omitempty"` // Named configuration profiles
 
+	// Named subagent configuration
+	Agent  string                 `mapstructure:"agent" json:"agent,omitempty" yaml:"agent,omitempty"`    // Agent is the named agent this run acts as
+	Agents map[string]AgentConfig `mapstructure:"agents" json:"agents,omitempty" yaml:"agents,omitempty"` // Agents are named subagents the main agent can hand work to
+
 	// Provider-specific configurations
 	OpenAI    *OpenAIConfig    `mapstructure:"openai" json:"openai,omitempty" yaml:"openai,omitempty"`          // OpenAI-specific configuration including compatible providers
 	Anthropic *AnthropicConfig `mapstructure:"anthropic" json:"anthropic,omitempty" yaml:"anthropic,omitempty"` // Anthropic-specific configuration including compatible providers
@@ -93,35 +124,88 @@ type Config struct {
 	// Context configuration
 	Context *ContextConfig `mapstructure:"context" json:"context,omitempty" yaml:"context,omitempty"` // Context configuration for context file discovery
 
+	// Safety limits configuration
+	Limits *LimitsConfig `mapstructure:"limits" json:"limits,omitempty" yaml:"limits,omitempty"` // Limits caps how much a single run may modify before requiring approval
+
+	// Edit review configuration
+	ReviewEdits bool `mapstructure:"review_edits" json:"review_edits,omitempty" yaml:"review_edits,omitempty"` // ReviewEdits asks the user to accept or reject each hunk of a file change in chat before it is written
+
+	// Tool approval configuration
+	ToolApproval *ToolApprovalConfig `mapstructure:"tool_approval" json:"tool_approval,omitempty" yaml:"tool_approval,omitempty"` // ToolApproval asks the user to confirm risky tool calls before they run
+
+	// Tool environment configuration
+	ToolEnv []ToolEnvVar `mapstructure:"tool_env" json:"tool_env,omitempty" yaml:"tool_env,omitempty"` // ToolEnv sets environment variables, optionally resolved from secret managers, for bash commands
+
+	// Audit configuration
+	Audit *AuditConfig `mapstructure:"audit" json:"audit,omitempty" yaml:"audit,omitempty"` // Audit records tool invocations for compliance review
+
+	// Planning discipline configuration
+	Todos *TodosConfig `mapstructure:"todos" json:"todos,omitempty" yaml:"todos,omitempty"` // Todos enforces keeping a todo list for complex tasks
+
+	// Content filter configuration
+	ContentFilter *ContentFilterConfig `mapstructure:"content_filter" json:"content_filter,omitempty" yaml:"content_filter,omitempty"` // ContentFilter controls how utility prompts refused by the provider are retried
+
+	// Parallel tool execution configuration
+	ToolConcurrency *ToolConcurrencyConfig `mapstructure:"tool_concurrency" json:"tool_concurrency,omitempty" yaml:"tool_concurrency,omitempty"` // ToolConcurrency limits how many calls of each tool class run at once
+
+	// Tool execution limits
+	ToolLimits *ToolLimitsConfig `mapstructure:"tool_limits" json:"tool_limits,omitempty" yaml:"tool_limits,omitempty"` // ToolLimits caps the wall time and model-facing output of tool calls
+
+	// Tool result cache configuration
+	ToolCache *ToolCacheConfig `mapstructure:"tool_cache" json:"tool_cache,omitempty" yaml:"tool_cache,omitempty"` // ToolCache reuses the results of repeated read-only tool calls
+
+	// Subscription quota configuration
+	Quota *QuotaConfig `mapstructure:"quota" json:"quota,omitempty" yaml:"quota,omitempty"` // Quota controls how runs react to subscription rate limit windows filling up
+
+	// Provider rate limit configuration
+	RateLimit *RateLimitConfig `mapstructure:"rate_limit" json:"rate_limit,omitempty" yaml:"rate_limit,omitempty"` // RateLimit paces requests to the provider across every kodelet process
+
+	// Air-gapped deployment configuration
+	Airgap *AirgapConfig `mapstructure:"airgap" json:"airgap,omitempty" yaml:"airgap,omitempty"` // Airgap restricts Kodelet to endpoints inside an isolated network
+
+	// Experiment configuration
+	Experiment           *ExperimentConfig     `mapstructure:"experiment" json:"experiment,omitempty" yaml:"experiment,omitempty"` // Experiment routes a share of new runs to an alternate model
+	ExperimentAssignment *ExperimentAssignment `mapstructure:"-" json:"-" yaml:"-"`                                                // ExperimentAssignment is the experiment arm a new run was routed to
+
 	// Runtime feature toggle configuration
 	Extensions              any                     `mapstructure:"-" json:"-" yaml:"-"`                                                                         // Extensions is the active extension runtime for lifecycle events
 	EnableFSSearchTools     bool                    `mapstructure:"enable_fs_search_tools" json:"enable_fs_search_tools" yaml:"enable_fs_search_tools"`          // EnableFSSearchTools enables glob_tool and grep_tool and updates prompt/tool guidance accordingly
 	ConversationSummaryMode ConversationSummaryMode `mapstructure:"conversation_summary_mode" json:"conversation_summary_mode" yaml:"conversation_summary_mode"` // ConversationSummaryMode controls whether persisted conversation summaries come from the LLM or first user message
 	RecipeName              string                  `mapstructure:"recipe_name" json:"recipe_name" yaml:"recipe_name"`                                           // RecipeName is the active recipe/fragment name for extension context metadata
 	CompactRatio            float64                 `mapstructure:"compact_ratio" json:"compact_ratio" yaml:"compact_ratio"`                                     // CompactRatio is the context utilization threshold for automatic compaction (>0.0-1.0)
+	CompactReinjectTokens   int                     `mapstructure:"compact_reinject_tokens" json:"compact_reinject_tokens" yaml:"compact_reinject_tokens"`       // CompactReinjectTokens is the token budget for re-injecting recently accessed files after compaction (0 disables)
+	CompactStrategy         string                  `mapstructure:"compact_strategy" json:"compact_strategy" yaml:"compact_strategy"`                            // CompactStrategy is "auto" to choose between compaction and tool result eviction, or "compact" to always compact
 }
 
 // BashConfig holds configuration for the bash tool.
 type BashConfig struct {
-	Timeout time.Duration `mapstructure:"timeout" json:"timeout" yaml:"timeout"` // Timeout is the maximum allowed timeout for a bash tool call
+	Timeout   time.Duration `mapstructure:"timeout" json:"timeout" yaml:"timeout"`       // Timeout is the maximum allowed timeout for a bash tool call
+	Stateless bool          `mapstructure:"stateless" json:"stateless" yaml:"stateless"` // Stateless runs every bash call in a fresh shell instead of the conversation's persistent session
+	// Checkpoint snapshots the files a bash call changes in a git working
+	// tree so undo and rollback can revert them. Defaults to true.
+	Checkpoint *bool `mapstructure:"checkpoint" json:"checkpoint,omitempty" yaml:"checkpoint,omitempty"`
 }
 
 // MarshalJSON renders durations as config-friendly strings instead of nanoseconds.
 func (c BashConfig) MarshalJSON() ([]byte, error) {
 	type bashConfig struct {
-		Timeout string `json:"timeout"`
+		Timeout    string `json:"timeout"`
+		Stateless  bool   `json:"stateless,omitempty"`
+		Checkpoint *bool  `json:"checkpoint,omitempty"`
 	}
 
-	return json.Marshal(bashConfig{Timeout: c.Timeout.String()})
+	return json.Marshal(bashConfig{Timeout: c.Timeout.String(), Stateless: c.Stateless, Checkpoint: c.Checkpoint})
 }
 
 // MarshalYAML renders durations as config-friendly strings instead of nanoseconds.
 func (c BashConfig) MarshalYAML() (any, error) {
 	type bashConfig struct {
-		Timeout string `yaml:"timeout"`
+		Timeout    string `yaml:"timeout"`
+		Stateless  bool   `yaml:"stateless,omitempty"`
+		Checkpoint *bool  `yaml:"checkpoint,omitempty"`
 	}
 
-	return bashConfig{Timeout: c.Timeout.String()}, nil
+	return bashConfig{Timeout: c.Timeout.String(), Stateless: c.Stateless, Checkpoint: c.Checkpoint}, nil
 }
 
 // BashTimeout returns the configured bash tool timeout, or the default if unset.
@@ -132,6 +216,70 @@ func (c Config) BashTimeout() time.Duration {
 	return c.Bash.Timeout
 }
 
+// ToolEnvMap returns ToolEnv keyed by variable name. Later entries win.
+func (c Config) ToolEnvMap() map[string]string {
+	if len(c.ToolEnv) == 0 {
+		return nil
+	}
+	env := make(map[string]string, len(c.ToolEnv))
+	for _, v := range c.ToolEnv {
+		env[v.Name] = v.Value
+	}
+	return env
+}
+
+// ContentFilterRephrase reports whether a utility prompt refused by the
+// provider's content policy is retried once with a rephrased instruction.
+func (c Config) ContentFilterRephrase() bool {
+	return c.ContentFilter == nil || c.ContentFilter.Rephrase == nil || *c.ContentFilter.Rephrase
+}
+
+// AirgapEnabled reports whether Kodelet is restricted to internal endpoints.
+func (c Config) AirgapEnabled() bool {
+	return c.Airgap != nil && c.Airgap.Enabled
+}
+
+// BashStateless reports whether bash tool calls run in a fresh shell instead
+// of the conversation's persistent session.
+func (c Config) BashStateless() bool {
+	return c.Bash != nil && c.Bash.Stateless
+}
+
+// ToolLimit returns the limits of a call of toolName: its entry in
+// tool_limits.tools, with unset fields taken from the tool_limits defaults.
+// MaxOutputBytes falls back to DefaultToolMaxOutputBytes; a negative value
+// turns the output limit off.
+func (c Config) ToolLimit(toolName string) ToolLimit {
+	limit := ToolLimit{}
+	if c.ToolLimits != nil {
+		limit = c.ToolLimits.ToolLimit
+		for name, override := range c.ToolLimits.Tools {
+			if !strings.EqualFold(name, toolName) {
+				continue
+			}
+			if override.Timeout != 0 {
+				limit.Timeout = override.Timeout
+			}
+			if override.MaxOutputBytes != 0 {
+				limit.MaxOutputBytes = override.MaxOutputBytes
+			}
+			if override.StallAfter != 0 {
+				limit.StallAfter = override.StallAfter
+			}
+		}
+	}
+	if limit.MaxOutputBytes == 0 {
+		limit.MaxOutputBytes = DefaultToolMaxOutputBytes
+	}
+	return limit
+}
+
+// BashCheckpoint reports whether the files bash calls change in a git working
+// tree are snapshotted for undo.
+func (c Config) BashCheckpoint() bool {
+	return c.Bash == nil || c.Bash.Checkpoint == nil || *c.Bash.Checkpoint
+}
+
 // OpenAIAPIMode defines which OpenAI-compatible API surface to use.
 type OpenAIAPIMode string
 
@@ -265,15 +413,47 @@ type OpenAIConfig struct {
 	EnableSearch  *bool                   `mapstructure:"enable_search" json:"enable_search,omitempty" yaml:"enable_search,omitempty"`    // Enable native OpenAI Responses web_search tool when supported (defaults to true)
 	WebSocketMode *bool                   `mapstructure:"websocket_mode" json:"websocket_mode,omitempty" yaml:"websocket_mode,omitempty"` // Use Responses API WebSocket transport when supported (defaults to true)
 	ManualCache   bool                    `mapstructure:"manual_cache" json:"manual_cache" yaml:"manual_cache"`                           // Enables manual cache affinity headers for Chat Completions when prompt caching is requested
+	APIKeys       []APIKeyConfig          `mapstructure:"api_keys" json:"api_keys,omitempty" yaml:"api_keys,omitempty"`                   // Pool of API keys rotated per request by weight (overrides api_key_env_var)
 	Models        *CustomModels           `mapstructure:"models" json:"models,omitempty" yaml:"models,omitempty"`                         // Custom model configuration
 	Pricing       map[string]ModelPricing `mapstructure:"pricing" json:"pricing,omitempty" yaml:"pricing,omitempty"`                      // Custom pricing configuration
 }
 
 // AnthropicConfig holds Anthropic-specific configuration including compatible platforms.
 type AnthropicConfig struct {
-	Platform         string `mapstructure:"platform" json:"platform" yaml:"platform"`                                                // Canonical platform name for Anthropic-compatible APIs (e.g., anthropic, copilot)
-	BaseURL          string `mapstructure:"base_url" json:"base_url" yaml:"base_url"`                                                // Custom API base URL (overrides platform defaults)
-	AdaptiveThinking bool   `mapstructure:"adaptive_thinking" json:"adaptive_thinking,omitempty" yaml:"adaptive_thinking,omitempty"` // Forces Anthropic adaptive-thinking request plumbing for the configured custom model ID when true
+	Platform         string                 `mapstructure:"platform" json:"platform" yaml:"platform"`                                                // Canonical platform name for Anthropic-compatible APIs (e.g., anthropic, copilot, vertex)
+	BaseURL          string                 `mapstructure:"base_url" json:"base_url" yaml:"base_url"`                                                // Custom API base URL (overrides platform defaults)
+	AdaptiveThinking bool                   `mapstructure:"adaptive_thinking" json:"adaptive_thinking,omitempty" yaml:"adaptive_thinking,omitempty"` // Forces Anthropic adaptive-thinking request plumbing for the configured custom model ID when true
+	APIKeys          []APIKeyConfig         `mapstructure:"api_keys" json:"api_keys,omitempty" yaml:"api_keys,omitempty"`                            // Pool of API keys rotated per request by weight (overrides ANTHROPIC_API_KEY)
+	Vertex           *AnthropicVertexConfig `mapstructure:"vertex" json:"vertex,omitempty" yaml:"vertex,omitempty"`                                  // Google Cloud project and region Claude is served from when platform is vertex
+}
+
+// AnthropicVertexConfig locates Claude models hosted on Google Cloud Vertex AI.
+type AnthropicVertexConfig struct {
+	ProjectID string `mapstructure:"project_id" json:"project_id,omitempty" yaml:"project_id,omitempty"` // Google Cloud project (defaults to ANTHROPIC_VERTEX_PROJECT_ID, GOOGLE_CLOUD_PROJECT or the credentials' project)
+	Region    string `mapstructure:"region" json:"region,omitempty" yaml:"region,omitempty"`             // Vertex AI region such as us-east5, or global (defaults to CLOUD_ML_REGION, then global)
+}
+
+// APIKeyConfig is one API key in a provider's key pool. Requests are spread
+// across the pool by weight, skipping keys that have used up their budget.
+type APIKeyConfig struct {
+	// Name identifies the key in logs and usage reports. Defaults to EnvVar.
+	Name string `mapstructure:"name" json:"name,omitempty" yaml:"name,omitempty"`
+	// EnvVar is the environment variable holding the key.
+	EnvVar string `mapstructure:"env_var" json:"env_var" yaml:"env_var"`
+	// Weight is the key's share of requests relative to the other keys.
+	// Defaults to 1.
+	Weight int `mapstructure:"weight" json:"weight,omitempty" yaml:"weight,omitempty"`
+	// RequestsPerMinute caps the requests sent with the key in any one
+	// minute. Zero means no cap.
+	RequestsPerMinute int `mapstructure:"requests_per_minute" json:"requests_per_minute,omitempty" yaml:"requests_per_minute,omitempty"`
+}
+
+// DisplayName returns the name the key is reported under.
+func (k APIKeyConfig) DisplayName() string {
+	if k.Name != "" {
+		return k.Name
+	}
+	return k.EnvVar
 }
 
 // CustomModels holds model categorization for custom configurations
@@ -290,6 +470,8 @@ type ModelPricing struct {
 	CachedInput float64 `mapstructure:"cached_input" json:"cached_input" yaml:"cached_input"`
 	// Cache write input token cost per token.
 	CacheWriteInput float64 `mapstructure:"cache_write_input" json:"cache_write_input" yaml:"cache_write_input"`
+	// One-hour cache write input token cost per token (Anthropic).
+	CacheWrite1hInput float64 `mapstructure:"cache_write_1h_input" json:"cache_write_1h_input,omitempty" yaml:"cache_write_1h_input,omitempty"`
 	// Output token cost per token.
 	Output float64 `mapstructure:"output" json:"output" yaml:"output"`
 	// Long-context input token cost per token.
@@ -335,6 +517,91 @@ type CustomPricing map[string]ModelPricing
 // ProfileConfig holds the configuration values for a named profile
 type ProfileConfig map[string]any
 
+// ProfileExtendsKey names the profile a profile inherits its settings from.
+const ProfileExtendsKey = "extends"
+
+// Extends returns the name of the profile this profile inherits from, if any.
+func (p ProfileConfig) Extends() string {
+	parent, _ := p[ProfileExtendsKey].(string)
+	parent = strings.TrimSpace(parent)
+	if parent == "default" {
+		return ""
+	}
+	return parent
+}
+
+// Settings returns the profile's own settings without the inheritance key.
+func (p ProfileConfig) Settings() map[string]any {
+	settings := make(map[string]any, len(p))
+	for key, value := range p {
+		if key != ProfileExtendsKey {
+			settings[key] = value
+		}
+	}
+	return settings
+}
+
+// ProfileChain returns the profiles name inherits from followed by the named
+// profile itself, so that applying them in order lets each profile override
+// its ancestors.
+func ProfileChain(profiles map[string]ProfileConfig, name string) ([]ProfileConfig, error) {
+	var chain []ProfileConfig
+	visited := make(map[string]bool)
+	path := []string{}
+	for current := name; current != ""; {
+		path = append(path, current)
+		if visited[current] {
+			return nil, errors.Errorf("profile inheritance cycle: %s", strings.Join(path, " -> "))
+		}
+		visited[current] = true
+
+		profile, ok := profiles[current]
+		if !ok {
+			if current == name {
+				return nil, errors.Errorf("profile '%s' not found", name)
+			}
+			return nil, errors.Errorf("profile '%s' extends unknown profile '%s'", path[len(path)-2], current)
+		}
+		chain = append(chain, profile)
+		current = profile.Extends()
+	}
+
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain, nil
+}
+
+// AgentConfig defines a named subagent: what it is for and the model, tools
+// and commands it runs with. Unset fields keep the configuration of the run.
+type AgentConfig struct {
+	Description     string   `mapstructure:"description" json:"description" yaml:"description"`                                    // Description tells the main agent when to hand work to this agent
+	Profile         string   `mapstructure:"profile" json:"profile,omitempty" yaml:"profile,omitempty"`                            // Profile is applied before the agent's own settings
+	Model           string   `mapstructure:"model" json:"model,omitempty" yaml:"model,omitempty"`                                  // Model overrides the main model
+	AllowedTools    []string `mapstructure:"allowed_tools" json:"allowed_tools,omitempty" yaml:"allowed_tools,omitempty"`          // AllowedTools restricts the tools the agent may use
+	ToolMode        ToolMode `mapstructure:"tool_mode" json:"tool_mode,omitempty" yaml:"tool_mode,omitempty"`                      // ToolMode overrides the file-interaction mode, so full keeps AllowedTools as written
+	AllowedCommands []string `mapstructure:"allowed_commands" json:"allowed_commands,omitempty" yaml:"allowed_commands,omitempty"` // AllowedCommands restricts the commands its bash tool may run
+	Instructions    string   `mapstructure:"instructions" json:"instructions,omitempty" yaml:"instructions,omitempty"`             // Instructions are added to the agent's system prompt
+}
+
+// Settings returns the configuration settings the agent overrides.
+func (a AgentConfig) Settings() map[string]any {
+	settings := map[string]any{}
+	if a.Model != "" {
+		settings["model"] = a.Model
+	}
+	if len(a.AllowedTools) > 0 {
+		settings["allowed_tools"] = a.AllowedTools
+	}
+	if a.ToolMode != "" {
+		settings["tool_mode"] = string(a.ToolMode)
+	}
+	if len(a.AllowedCommands) > 0 {
+		settings["allowed_commands"] = a.AllowedCommands
+	}
+	return settings
+}
+
 // RetryConfig holds the retry configuration for API calls
 // Note: Anthropic only uses Attempts (relies on SDK retry), OpenAI uses all fields
 type RetryConfig struct {
@@ -370,9 +637,375 @@ type ContextConfig struct {
 	// Patterns is a list of filenames to search for in each directory.
 	// Default is ["AGENTS.md"]. Files are searched in order; first match wins per directory.
 	Patterns []string `mapstructure:"patterns" json:"patterns" yaml:"patterns"`
+	// DisableManifests turns off the generated summary of package manifests
+	// (go.mod, package.json, pyproject.toml, Cargo.toml) and CI workflows.
+	DisableManifests bool `mapstructure:"disable_manifests" json:"disable_manifests,omitempty" yaml:"disable_manifests,omitempty"`
 }
 
 // DefaultContextPatterns returns the default context file patterns.
 func DefaultContextPatterns() []string {
 	return []string{"AGENTS.md"}
 }
+
+// LimitsConfig holds run-level safety limits enforced by the file tools.
+// A zero value for any limit means the limit is disabled.
+type LimitsConfig struct {
+	// MaxFilesChanged caps the number of distinct files a run may modify.
+	MaxFilesChanged int `mapstructure:"max_files_changed" json:"max_files_changed" yaml:"max_files_changed"`
+	// MaxLinesChanged caps the total number of added and removed lines across a run.
+	MaxLinesChanged int `mapstructure:"max_lines_changed" json:"max_lines_changed" yaml:"max_lines_changed"`
+}
+
+// ToolApprovalConfig configures the interactive approval of risky tool calls.
+type ToolApprovalConfig struct {
+	// Enabled has the user confirm each tool call matching Risks, as set by Mode.
+	Enabled bool `mapstructure:"enabled" json:"enabled" yaml:"enabled"`
+	// Risks lists the calls that need approval. An entry is a tool name,
+	// matching every call, "bash:write" for bash commands that are not known
+	// to be read-only, or "<tool>:new_domain" for calls to a URL on a domain
+	// not approved yet in the run. Defaults to file changes, bash writes and
+	// web_fetch and web_crawl to new domains.
+	Risks []string `mapstructure:"risks" json:"risks,omitempty" yaml:"risks,omitempty"`
+	// Mode is "ask" to confirm each call before it runs, or "defer" to queue
+	// the calls of a turn and review them together when the agent finishes.
+	// Defaults to "ask".
+	Mode string `mapstructure:"mode" json:"mode,omitempty" yaml:"mode,omitempty"`
+}
+
+// ToolEnvVar is an environment variable set for the commands run by the bash
+// tool.
+type ToolEnvVar struct {
+	Name string `mapstructure:"name" json:"name" yaml:"name"`
+	// Value is the literal value, or a secret:// reference resolved when a
+	// command first needs it.
+	Value string `mapstructure:"value" json:"value" yaml:"value"`
+}
+
+// AuditConfig configures the per-run tool call audit log.
+type AuditConfig struct {
+	// ToolCalls records every tool invocation, including those of subagents
+	// started during the run, to <dir>/<run-id>.jsonl.
+	ToolCalls bool `mapstructure:"tool_calls" json:"tool_calls" yaml:"tool_calls"`
+	// Dir is the directory run files are written to. Defaults to ~/.kodelet/audit.
+	Dir string `mapstructure:"dir" json:"dir,omitempty" yaml:"dir,omitempty"`
+	// MaxOutputBytes caps how much of each tool output is recorded. Defaults to 4096.
+	MaxOutputBytes int `mapstructure:"max_output_bytes" json:"max_output_bytes,omitempty" yaml:"max_output_bytes,omitempty"`
+}
+
+// ToolConcurrencyConfig overrides the concurrency classes used when tool
+// calls from one assistant turn run in parallel.
+type ToolConcurrencyConfig struct {
+	// Limits maps a class name to the number of its calls that may run at
+	// once. 0 means unlimited. The "exclusive" class always runs alone.
+	Limits map[string]int `mapstructure:"limits" json:"limits,omitempty" yaml:"limits,omitempty"`
+	// Classes maps a tool name to its class, overriding the built-in
+	// assignment. Extension tools are unlimited unless listed here.
+	Classes map[string]string `mapstructure:"classes" json:"classes,omitempty" yaml:"classes,omitempty"`
+	// MaxConcurrent caps the tool calls that run at once across every class.
+	// 0 means unlimited.
+	MaxConcurrent int `mapstructure:"max_concurrent" json:"max_concurrent,omitempty" yaml:"max_concurrent,omitempty"`
+}
+
+// ToolLimit caps one tool call.
+type ToolLimit struct {
+	// Timeout is the longest a call may run before it is cancelled and
+	// reported as an error. 0 means no limit beyond the tool's own.
+	Timeout time.Duration `mapstructure:"timeout" json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// MaxOutputBytes caps the output sent to the model. Longer output keeps
+	// its head and tail with a truncation marker in between. Defaults to
+	// DefaultToolMaxOutputBytes; a negative value turns the cap off.
+	MaxOutputBytes int `mapstructure:"max_output_bytes" json:"max_output_bytes,omitempty" yaml:"max_output_bytes,omitempty"`
+	// StallAfter is how long a call may go without progress before the user
+	// is warned and offered to cancel it. Defaults to DefaultToolStallAfter;
+	// a negative value turns the watchdog off.
+	StallAfter time.Duration `mapstructure:"stall_after" json:"stall_after,omitempty" yaml:"stall_after,omitempty"`
+}
+
+// StallThreshold returns how long a call may go without progress before it is
+// reported as stalled, or 0 when the watchdog is off.
+func (l ToolLimit) StallThreshold() time.Duration {
+	switch {
+	case l.StallAfter < 0:
+		return 0
+	case l.StallAfter == 0:
+		return DefaultToolStallAfter
+	default:
+		return l.StallAfter
+	}
+}
+
+// durationString renders a non-zero duration for config output. Negative
+// values are kept, since they switch a limit off.
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// MarshalJSON renders durations as config-friendly strings instead of nanoseconds.
+func (l ToolLimit) MarshalJSON() ([]byte, error) {
+	type toolLimit struct {
+		Timeout        string `json:"timeout,omitempty"`
+		MaxOutputBytes int    `json:"max_output_bytes,omitempty"`
+		StallAfter     string `json:"stall_after,omitempty"`
+	}
+
+	limit := toolLimit{MaxOutputBytes: l.MaxOutputBytes, StallAfter: durationString(l.StallAfter)}
+	if l.Timeout > 0 {
+		limit.Timeout = l.Timeout.String()
+	}
+	return json.Marshal(limit)
+}
+
+// MarshalYAML renders durations as config-friendly strings instead of nanoseconds.
+func (l ToolLimit) MarshalYAML() (any, error) {
+	type toolLimit struct {
+		Timeout        string `yaml:"timeout,omitempty"`
+		MaxOutputBytes int    `yaml:"max_output_bytes,omitempty"`
+		StallAfter     string `yaml:"stall_after,omitempty"`
+	}
+
+	limit := toolLimit{MaxOutputBytes: l.MaxOutputBytes, StallAfter: durationString(l.StallAfter)}
+	if l.Timeout > 0 {
+		limit.Timeout = l.Timeout.String()
+	}
+	return limit, nil
+}
+
+// ToolLimitsConfig caps the tool calls of every tool, applied centrally when
+// a tool runs on top of the tool's own limits.
+type ToolLimitsConfig struct {
+	// ToolLimit holds the limits of tools without an entry in Tools.
+	ToolLimit `mapstructure:",squash" yaml:",inline"`
+	// Tools maps a tool name to its limits. Unset fields fall back to the
+	// defaults above.
+	Tools map[string]ToolLimit `mapstructure:"tools" json:"tools,omitempty" yaml:"tools,omitempty"`
+}
+
+// MarshalJSON renders durations as config-friendly strings instead of nanoseconds.
+func (c ToolLimitsConfig) MarshalJSON() ([]byte, error) {
+	type toolLimitsConfig struct {
+		Timeout        string               `json:"timeout,omitempty"`
+		MaxOutputBytes int                  `json:"max_output_bytes,omitempty"`
+		StallAfter     string               `json:"stall_after,omitempty"`
+		Tools          map[string]ToolLimit `json:"tools,omitempty"`
+	}
+
+	config := toolLimitsConfig{MaxOutputBytes: c.MaxOutputBytes, StallAfter: durationString(c.StallAfter), Tools: c.Tools}
+	if c.Timeout > 0 {
+		config.Timeout = c.Timeout.String()
+	}
+	return json.Marshal(config)
+}
+
+// MarshalYAML renders durations as config-friendly strings instead of nanoseconds.
+func (c ToolLimitsConfig) MarshalYAML() (any, error) {
+	type toolLimitsConfig struct {
+		Timeout        string               `yaml:"timeout,omitempty"`
+		MaxOutputBytes int                  `yaml:"max_output_bytes,omitempty"`
+		StallAfter     string               `yaml:"stall_after,omitempty"`
+		Tools          map[string]ToolLimit `yaml:"tools,omitempty"`
+	}
+
+	config := toolLimitsConfig{MaxOutputBytes: c.MaxOutputBytes, StallAfter: durationString(c.StallAfter), Tools: c.Tools}
+	if c.Timeout > 0 {
+		config.Timeout = c.Timeout.String()
+	}
+	return config, nil
+}
+
+// ToolCacheConfig enables the cache of tool results shared by every run on
+// the machine. Results are keyed by tool name, input and, for tools reading
+// the workspace, the git revision of the working directory.
+type ToolCacheConfig struct {
+	// Enabled turns the cache on. It is off by default.
+	Enabled bool `mapstructure:"enabled" json:"enabled" yaml:"enabled"`
+	// TTL is how long a result is reused. Defaults to DefaultToolCacheTTL.
+	TTL time.Duration `mapstructure:"ttl" json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	// MaxBytes caps the size of the cache on disk; the oldest results are
+	// evicted first. Defaults to DefaultToolCacheMaxBytes.
+	MaxBytes int64 `mapstructure:"max_bytes" json:"max_bytes,omitempty" yaml:"max_bytes,omitempty"`
+	// Tools lists the tools whose results are cached. Defaults to
+	// DefaultToolCacheTools. Only list tools without side effects.
+	Tools []string `mapstructure:"tools" json:"tools,omitempty" yaml:"tools,omitempty"`
+}
+
+// MarshalJSON renders durations as config-friendly strings instead of nanoseconds.
+func (c ToolCacheConfig) MarshalJSON() ([]byte, error) {
+	type toolCacheConfig struct {
+		Enabled  bool     `json:"enabled"`
+		TTL      string   `json:"ttl,omitempty"`
+		MaxBytes int64    `json:"max_bytes,omitempty"`
+		Tools    []string `json:"tools,omitempty"`
+	}
+
+	return json.Marshal(toolCacheConfig{Enabled: c.Enabled, TTL: durationString(c.TTL), MaxBytes: c.MaxBytes, Tools: c.Tools})
+}
+
+// MarshalYAML renders durations as config-friendly strings instead of nanoseconds.
+func (c ToolCacheConfig) MarshalYAML() (any, error) {
+	type toolCacheConfig struct {
+		Enabled  bool     `yaml:"enabled"`
+		TTL      string   `yaml:"ttl,omitempty"`
+		MaxBytes int64    `yaml:"max_bytes,omitempty"`
+		Tools    []string `yaml:"tools,omitempty"`
+	}
+
+	return toolCacheConfig{Enabled: c.Enabled, TTL: durationString(c.TTL), MaxBytes: c.MaxBytes, Tools: c.Tools}, nil
+}
+
+// DefaultToolCacheTools are the tools cached when tool_cache.tools is unset:
+// the read-only searches and fetches that are expensive to repeat.
+var DefaultToolCacheTools = []string{"grep_tool", "glob_tool", "web_fetch", "web_crawl"}
+
+// CacheTTL returns how long a result is reused.
+func (c ToolCacheConfig) CacheTTL() time.Duration {
+	if c.TTL > 0 {
+		return c.TTL
+	}
+	return DefaultToolCacheTTL
+}
+
+// CacheMaxBytes returns the size cap of the cache on disk.
+func (c ToolCacheConfig) CacheMaxBytes() int64 {
+	if c.MaxBytes > 0 {
+		return c.MaxBytes
+	}
+	return DefaultToolCacheMaxBytes
+}
+
+// Caches reports whether the results of toolName are cached.
+func (c ToolCacheConfig) Caches(toolName string) bool {
+	tools := c.Tools
+	if len(tools) == 0 {
+		tools = DefaultToolCacheTools
+	}
+	for _, name := range tools {
+		if strings.EqualFold(name, toolName) {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimitConfig paces the requests sent to a provider. Every kodelet
+// process on the machine using the same provider shares one budget, so
+// parallel subagents queue for it instead of running into 429 responses. A zero value for either
+// limit means the limit is disabled.
+type RateLimitConfig struct {
+	// RequestsPerMinute caps the requests sent to the provider per minute.
+	RequestsPerMinute int `mapstructure:"requests_per_minute" json:"requests_per_minute" yaml:"requests_per_minute"`
+	// TokensPerMinute caps the input tokens sent to the provider per minute,
+	// estimated from the size of each request.
+	TokensPerMinute int `mapstructure:"tokens_per_minute" json:"tokens_per_minute" yaml:"tokens_per_minute"`
+}
+
+// QuotaConfig configures how a run reacts as the rate limit windows of an
+// Anthropic subscription or GitHub Copilot fill up.
+type QuotaConfig struct {
+	// WarnThreshold is the window utilization (0-1) at which the user is
+	// warned. Defaults to 0.8.
+	WarnThreshold float64 `mapstructure:"warn_threshold" json:"warn_threshold" yaml:"warn_threshold"`
+	// ActionThreshold is the window utilization (0-1) at which Action is
+	// taken. Defaults to 0.95.
+	ActionThreshold float64 `mapstructure:"action_threshold" json:"action_threshold" yaml:"action_threshold"`
+	// Action is "warn" (default), "weak_model" to finish the run on the weak
+	// model, or "pause" to wait for the window to reset.
+	Action string `mapstructure:"action" json:"action" yaml:"action"`
+	// MaxPause is the longest "pause" waits for a reset before stopping the
+	// run. Defaults to 30m.
+	MaxPause time.Duration `mapstructure:"max_pause" json:"max_pause" yaml:"max_pause"`
+}
+
+// MarshalJSON renders durations as config-friendly strings instead of nanoseconds.
+func (c QuotaConfig) MarshalJSON() ([]byte, error) {
+	type quotaConfig struct {
+		WarnThreshold   float64 `json:"warn_threshold"`
+		ActionThreshold float64 `json:"action_threshold"`
+		Action          string  `json:"action"`
+		MaxPause        string  `json:"max_pause"`
+	}
+
+	return json.Marshal(quotaConfig{c.WarnThreshold, c.ActionThreshold, c.Action, c.MaxPause.String()})
+}
+
+// MarshalYAML renders durations as config-friendly strings instead of nanoseconds.
+func (c QuotaConfig) MarshalYAML() (any, error) {
+	type quotaConfig struct {
+		WarnThreshold   float64 `yaml:"warn_threshold"`
+		ActionThreshold float64 `yaml:"action_threshold"`
+		Action          string  `yaml:"action"`
+		MaxPause        string  `yaml:"max_pause"`
+	}
+
+	return quotaConfig{c.WarnThreshold, c.ActionThreshold, c.Action, c.MaxPause.String()}, nil
+}
+
+// AirgapConfig restricts Kodelet to endpoints inside an isolated network.
+type AirgapConfig struct {
+	// Enabled refuses external model endpoints and turns off web access,
+	// tool binary downloads and external telemetry.
+	Enabled bool `mapstructure:"enabled" json:"enabled" yaml:"enabled"`
+	// CABundle is a PEM file of the CAs trusted for HTTPS, replacing the
+	// system roots.
+	CABundle string `mapstructure:"ca_bundle" json:"ca_bundle,omitempty" yaml:"ca_bundle,omitempty"`
+	// AllowedHosts lists hosts counted as internal in addition to loopback,
+	// private addresses and single-label or .internal, .local and .lan names.
+	AllowedHosts []string `mapstructure:"allowed_hosts" json:"allowed_hosts,omitempty" yaml:"allowed_hosts,omitempty"`
+}
+
+// ExperimentConfig routes a percentage of new runs to an alternate model so
+// the arms can be compared with `kodelet usage report --group-by experiment`.
+type ExperimentConfig struct {
+	// Name identifies the experiment in conversation metadata and reports.
+	Name string `mapstructure:"name" json:"name" yaml:"name"`
+	// Percentage of new runs routed to the treatment arm, from 0 to 100.
+	Percentage float64 `mapstructure:"percentage" json:"percentage" yaml:"percentage"`
+	// Provider, Model and WeakModel configure the treatment arm. Empty values
+	// keep the configured ones.
+	Provider  string `mapstructure:"provider" json:"provider,omitempty" yaml:"provider,omitempty"`
+	Model     string `mapstructure:"model" json:"model,omitempty" yaml:"model,omitempty"`
+	WeakModel string `mapstructure:"weak_model" json:"weak_model,omitempty" yaml:"weak_model,omitempty"`
+}
+
+// Experiment arms.
+const (
+	ExperimentArmControl   = "control"
+	ExperimentArmTreatment = "treatment"
+)
+
+// ExperimentAssignment records which arm of an experiment a run was routed to.
+type ExperimentAssignment struct {
+	Name string `json:"name"`
+	Arm  string `json:"arm"`
+}
+
+// ContentFilterConfig configures the handling of responses refused or
+// filtered by the provider's content policy.
+type ContentFilterConfig struct {
+	// Rephrase retries a refused summary, title or compaction prompt once
+	// with an instruction to describe sensitive material neutrally. Defaults
+	// to true.
+	Rephrase *bool `mapstructure:"rephrase" json:"rephrase,omitempty" yaml:"rephrase,omitempty"`
+}
+
+// Default todo enforcement thresholds.
+const (
+	DefaultTodoComplexityThreshold = 5
+	DefaultTodoMaxStaleTurns       = 3
+)
+
+// TodosConfig configures the structured todo discipline for complex tasks.
+type TodosConfig struct {
+	// Enforcement is "off" (default), "remind" to inject reminders when the
+	// todo list is overdue, or "strict" to also refuse other tool calls until
+	// the list is updated.
+	Enforcement string `mapstructure:"enforcement" json:"enforcement" yaml:"enforcement"`
+	// ComplexityThreshold is the number of tool-using turns after which a
+	// task requires a todo list.
+	ComplexityThreshold int `mapstructure:"complexity_threshold" json:"complexity_threshold" yaml:"complexity_threshold"`
+	// MaxStaleTurns is the number of tool-using turns the todo list may go
+	// without an update.
+	MaxStaleTurns int `mapstructure:"max_stale_turns" json:"max_stale_turns" yaml:"max_stale_turns"`
+}
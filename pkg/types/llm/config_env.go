@@ -0,0 +1,62 @@
+package llm
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SettingKeys returns the dotted key of every setting in the config struct
+// type t, each prefixed with prefix. Nested config structs from the same
+// package are expanded down to their fields; maps and lists are single keys.
+func SettingKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + name
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() == reflect.Struct && fieldType.PkgPath() == t.PkgPath() {
+			keys = append(keys, SettingKeys(fieldType, key+".")...)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// JSONStringHook is a mapstructure decode hook that decodes JSON object and
+// array strings into maps, structs and lists, so those settings can be given
+// as environment variables. Other strings are left to the remaining hooks,
+// which keeps comma separated lists working.
+func JSONStringHook(from reflect.Type, to reflect.Type, data any) (any, error) {
+	value, ok := data.(string)
+	if !ok || from.Kind() != reflect.String {
+		return data, nil
+	}
+	switch to.Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice:
+	default:
+		return data, nil
+	}
+	trimmed := strings.TrimSpace(value)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return data, nil
+	}
+	var decoded any
+	if err := json.Unmarshal([]byte(trimmed), &decoded); err != nil {
+		return nil, errors.Wrap(err, "invalid JSON value")
+	}
+	return decoded, nil
+}
@@ -179,6 +179,8 @@ func (s *ConversationConfigSnapshot) Apply(config Config) (Config, error) {
 	config.AllowedReasoningEfforts = nil
 	config.ConversationSummaryMode = s.ConversationSummaryMode
 	config.CompactRatio = s.CompactRatio
+	// A resumed conversation keeps the model it was routed to when created.
+	config.ExperimentAssignment = nil
 
 	switch strings.ToLower(strings.TrimSpace(s.Provider)) {
 	case "openai":
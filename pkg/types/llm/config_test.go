@@ -172,3 +172,32 @@ func TestModelPricingForPromptTokensAllowsPartialLongContextRates(t *testing.T)
 	assert.Equal(t, 1.25, longContext.CacheWriteInput)
 	assert.Equal(t, 4.0, longContext.Output)
 }
+
+func TestProfileChain(t *testing.T) {
+	profiles := map[string]ProfileConfig{
+		"base":  {"model": "base-model"},
+		"ops":   {"extends": "base", "model": "ops-model"},
+		"prod":  {"extends": "ops", "weak_model": "prod-weak"},
+		"solo":  {"extends": "default", "model": "solo-model"},
+		"loop":  {"extends": "loop"},
+		"stray": {"extends": "missing"},
+	}
+
+	chain, err := ProfileChain(profiles, "prod")
+	require.NoError(t, err)
+	assert.Equal(t, []ProfileConfig{profiles["base"], profiles["ops"], profiles["prod"]}, chain)
+	assert.Equal(t, map[string]any{"weak_model": "prod-weak"}, profiles["prod"].Settings())
+
+	chain, err = ProfileChain(profiles, "solo")
+	require.NoError(t, err)
+	assert.Equal(t, []ProfileConfig{profiles["solo"]}, chain)
+
+	_, err = ProfileChain(profiles, "loop")
+	assert.EqualError(t, err, "profile inheritance cycle: loop -> loop")
+
+	_, err = ProfileChain(profiles, "stray")
+	assert.EqualError(t, err, "profile 'stray' extends unknown profile 'missing'")
+
+	_, err = ProfileChain(profiles, "absent")
+	assert.EqualError(t, err, "profile 'absent' not found")
+}
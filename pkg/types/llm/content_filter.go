@@ -0,0 +1,43 @@
+package llm
+
+import "fmt"
+
+// Content filter reasons reported by providers.
+const (
+	// ContentFilterReasonRefusal is an Anthropic stop_reason of "refusal" or an
+	// OpenAI Responses refusal content part.
+	ContentFilterReasonRefusal = "refusal"
+	// ContentFilterReasonContentFilter is an OpenAI finish_reason or
+	// incomplete reason of "content_filter".
+	ContentFilterReasonContentFilter = "content_filter"
+)
+
+// ContentFilterEvent describes a response the provider refused or stopped
+// under its content policy.
+type ContentFilterEvent struct {
+	Provider string `json:"provider"`
+	// Reason is ContentFilterReasonRefusal or ContentFilterReasonContentFilter.
+	Reason string `json:"reason"`
+	// Message is the refusal text returned by the provider, if any.
+	Message string `json:"message,omitempty"`
+}
+
+// ContentFilterMessageHandler can be implemented by message handlers that
+// want to know when the provider refused or filtered a response. The turn
+// ends normally with whatever text was produced before the refusal.
+type ContentFilterMessageHandler interface {
+	HandleContentFilter(event ContentFilterEvent)
+}
+
+// ContentFilteredError is returned by utility prompts, such as summaries and
+// compaction, whose response was refused or filtered by the provider.
+type ContentFilteredError struct {
+	Event ContentFilterEvent
+}
+
+func (e *ContentFilteredError) Error() string {
+	if e.Event.Message != "" {
+		return fmt.Sprintf("%s response blocked by content policy (%s): %s", e.Event.Provider, e.Event.Reason, e.Event.Message)
+	}
+	return fmt.Sprintf("%s response blocked by content policy (%s)", e.Event.Provider, e.Event.Reason)
+}
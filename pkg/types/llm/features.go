@@ -0,0 +1,79 @@
+package llm
+
+import "strings"
+
+// ModelFeatures describe what a model accepts, so requests can leave out
+// parameters the provider would reject with a 400 error. Unset flags are
+// assumed to be supported, and a zero MaxOutputTokens means no known limit.
+type ModelFeatures struct {
+	SupportsTools             *bool `mapstructure:"supports_tools" json:"supports_tools,omitempty" yaml:"supports_tools,omitempty"`                                           // SupportsTools is false for models that reject tool definitions
+	SupportsVision            *bool `mapstructure:"supports_vision" json:"supports_vision,omitempty" yaml:"supports_vision,omitempty"`                                        // SupportsVision is false for models that reject image input
+	SupportsParallelToolCalls *bool `mapstructure:"supports_parallel_tool_calls" json:"supports_parallel_tool_calls,omitempty" yaml:"supports_parallel_tool_calls,omitempty"` // SupportsParallelToolCalls is false for models that must call one tool per turn
+	MaxOutputTokens           int   `mapstructure:"max_output_tokens" json:"max_output_tokens,omitempty" yaml:"max_output_tokens,omitempty"`                                  // MaxOutputTokens caps max_tokens sent for the model
+}
+
+// CustomFeatures maps model IDs to their features.
+type CustomFeatures map[string]ModelFeatures
+
+// Tools reports whether the model accepts tool definitions.
+func (f ModelFeatures) Tools() bool {
+	return f.SupportsTools == nil || *f.SupportsTools
+}
+
+// Vision reports whether the model accepts image input.
+func (f ModelFeatures) Vision() bool {
+	return f.SupportsVision == nil || *f.SupportsVision
+}
+
+// ParallelToolCalls reports whether the model may call several tools in one turn.
+func (f ModelFeatures) ParallelToolCalls() bool {
+	return f.SupportsParallelToolCalls == nil || *f.SupportsParallelToolCalls
+}
+
+// ClampMaxTokens lowers maxTokens to the model's output limit.
+func (f ModelFeatures) ClampMaxTokens(maxTokens int) int {
+	if f.MaxOutputTokens > 0 && maxTokens > f.MaxOutputTokens {
+		return f.MaxOutputTokens
+	}
+	return maxTokens
+}
+
+// Merge returns f with the flags set in override replacing its own.
+func (f ModelFeatures) Merge(override ModelFeatures) ModelFeatures {
+	if override.SupportsTools != nil {
+		f.SupportsTools = override.SupportsTools
+	}
+	if override.SupportsVision != nil {
+		f.SupportsVision = override.SupportsVision
+	}
+	if override.SupportsParallelToolCalls != nil {
+		f.SupportsParallelToolCalls = override.SupportsParallelToolCalls
+	}
+	if override.MaxOutputTokens > 0 {
+		f.MaxOutputTokens = override.MaxOutputTokens
+	}
+	return f
+}
+
+// Lookup returns the features of model. Model IDs are matched case-insensitively.
+func (c CustomFeatures) Lookup(model string) (ModelFeatures, bool) {
+	if features, ok := c[model]; ok {
+		return features, true
+	}
+	for id, features := range c {
+		if strings.EqualFold(id, model) {
+			return features, true
+		}
+	}
+	return ModelFeatures{}, false
+}
+
+// ResolveModelFeatures returns the features of model: the provider preset
+// overridden by the model_features configuration.
+func ResolveModelFeatures(config Config, presets CustomFeatures, model string) ModelFeatures {
+	features, _ := presets.Lookup(model)
+	if override, ok := config.ModelFeatures.Lookup(model); ok {
+		features = features.Merge(override)
+	}
+	return features
+}
@@ -0,0 +1,45 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelFeaturesDefaults(t *testing.T) {
+	var features ModelFeatures
+	assert.True(t, features.Tools())
+	assert.True(t, features.Vision())
+	assert.True(t, features.ParallelToolCalls())
+	assert.Equal(t, 200_000, features.ClampMaxTokens(200_000))
+}
+
+func TestModelFeaturesClampMaxTokens(t *testing.T) {
+	features := ModelFeatures{MaxOutputTokens: 16_384}
+	assert.Equal(t, 16_384, features.ClampMaxTokens(32_000))
+	assert.Equal(t, 8_192, features.ClampMaxTokens(8_192))
+	assert.Equal(t, 0, features.ClampMaxTokens(0))
+}
+
+func TestResolveModelFeatures(t *testing.T) {
+	unsupported := false
+	supported := true
+	presets := CustomFeatures{
+		"o1-mini": {SupportsTools: &unsupported, SupportsVision: &unsupported, MaxOutputTokens: 65_536},
+	}
+
+	features := ResolveModelFeatures(Config{}, presets, "O1-Mini")
+	assert.False(t, features.Tools())
+	assert.False(t, features.Vision())
+	assert.Equal(t, 65_536, features.MaxOutputTokens)
+
+	config := Config{ModelFeatures: CustomFeatures{
+		"o1-mini": {SupportsTools: &supported, MaxOutputTokens: 32_000},
+	}}
+	features = ResolveModelFeatures(config, presets, "o1-mini")
+	assert.True(t, features.Tools(), "configuration overrides the preset")
+	assert.False(t, features.Vision(), "unset overrides keep the preset")
+	assert.Equal(t, 32_000, features.MaxOutputTokens)
+
+	assert.Equal(t, ModelFeatures{}, ResolveModelFeatures(config, presets, "gpt-4o"))
+}
@@ -11,9 +11,12 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/jingkaihe/kodelet/pkg/markdown"
 	"github.com/jingkaihe/kodelet/pkg/tools/renderers"
 	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/pkg/errors"
 )
 
 // consoleMu protects console output from concurrent writes during parallel tool execution
@@ -54,6 +57,13 @@ type ToolUpdateMessageHandler interface {
 	HandleToolUpdate(toolCallID string, toolName string, result tooltypes.ToolResult)
 }
 
+// ToolStallMessageHandler can be implemented by message handlers that want to
+// tell the user when a running tool call has gone idle for longer than its
+// tool_limits stall_after. It may be called more than once per call.
+type ToolStallMessageHandler interface {
+	HandleToolStall(toolCallID string, toolName string, idle time.Duration)
+}
+
 // UserMessageHandler can render user-authored messages that are injected during
 // an active turn, such as queued steering messages.
 type UserMessageHandler interface {
@@ -97,6 +107,9 @@ const (
 	EventTypeToolUse    = "tool_use"
 	EventTypeToolUpdate = "tool_update"
 	EventTypeToolResult = "tool_result"
+	// EventTypeContentFilter reports a response refused or filtered by the
+	// provider's content policy.
+	EventTypeContentFilter = "content_filter"
 
 	// Streaming event types
 	EventTypeTextDelta        = "text_delta"
@@ -106,14 +119,61 @@ const (
 	EventTypeContentBlockEnd  = "content_block_end"
 )
 
+// OutputProfile selects how much of a run a console handler prints
+type OutputProfile string
+
+const (
+	// OutputProfileQuiet prints nothing but the final answer
+	OutputProfileQuiet OutputProfile = "quiet"
+	// OutputProfileNormal prints assistant text, thinking and tool calls
+	OutputProfileNormal OutputProfile = "normal"
+	// OutputProfileVerbose adds tool timings and per-response token usage
+	OutputProfileVerbose OutputProfile = "verbose"
+)
+
+// ParseOutputProfile parses an output profile name; empty means normal
+func ParseOutputProfile(value string) (OutputProfile, error) {
+	switch profile := OutputProfile(strings.ToLower(strings.TrimSpace(value))); profile {
+	case "":
+		return OutputProfileNormal, nil
+	case OutputProfileQuiet, OutputProfileNormal, OutputProfileVerbose:
+		return profile, nil
+	default:
+		return "", errors.Errorf("invalid output profile %q: must be quiet, normal or verbose", value)
+	}
+}
+
+// NewConsoleMessageHandler creates a console handler configured for profile
+func NewConsoleMessageHandler(profile OutputProfile) *ConsoleMessageHandler {
+	return &ConsoleMessageHandler{
+		Silent:  profile == OutputProfileQuiet,
+		Verbose: profile == OutputProfileVerbose,
+	}
+}
+
 // ConsoleMessageHandler prints messages to the console
 type ConsoleMessageHandler struct {
 	Silent bool
+	// Verbose also prints how long each tool took and the tokens used by
+	// each model response.
+	Verbose bool
+	// RenderMarkdown renders assistant text as terminal markdown when set;
+	// streamed text is then printed one complete block at a time.
+	RenderMarkdown func(string) string
+
+	stream *markdown.Stream
+
+	mu         sync.Mutex
+	toolStarts map[string]time.Time
+	lastUsage  Usage
 }
 
 // HandleText prints the text to the console unless Silent is true
 func (h *ConsoleMessageHandler) HandleText(text string) {
 	if !h.Silent {
+		if h.RenderMarkdown != nil {
+			text = h.RenderMarkdown(text)
+		}
 		consoleMu.Lock()
 		fmt.Println(text)
 		fmt.Println()
@@ -122,7 +182,15 @@ func (h *ConsoleMessageHandler) HandleText(text string) {
 }
 
 // HandleToolUse prints tool invocation details to the console unless Silent is true
-func (h *ConsoleMessageHandler) HandleToolUse(_ string, toolName string, input string) {
+func (h *ConsoleMessageHandler) HandleToolUse(toolCallID string, toolName string, input string) {
+	if h.Verbose {
+		h.mu.Lock()
+		if h.toolStarts == nil {
+			h.toolStarts = map[string]time.Time{}
+		}
+		h.toolStarts[toolCallID] = time.Now()
+		h.mu.Unlock()
+	}
 	if !h.Silent {
 		consoleMu.Lock()
 		fmt.Printf("🔧 Using tool: %s\n  %s\n\n", toolName, formatJSONInput(input))
@@ -131,16 +199,53 @@ func (h *ConsoleMessageHandler) HandleToolUse(_ string, toolName string, input s
 }
 
 // HandleToolResult prints tool execution results to the console unless Silent is true
-func (h *ConsoleMessageHandler) HandleToolResult(_, _ string, result tooltypes.ToolResult) {
+func (h *ConsoleMessageHandler) HandleToolResult(toolCallID, _ string, result tooltypes.ToolResult) {
 	if !h.Silent {
 		registry := renderers.NewRendererRegistry()
-		rendered := registry.Render(result.StructuredData())
+		render := registry.Render
+		if h.RenderMarkdown != nil {
+			// Output is a terminal, so diffs are colored as well.
+			render = registry.RenderColor
+		}
+		rendered := render(result.StructuredData())
+		header := "🔄 Tool result:"
+		if h.Verbose {
+			h.mu.Lock()
+			if started, ok := h.toolStarts[toolCallID]; ok {
+				header = fmt.Sprintf("🔄 Tool result (%s):", time.Since(started).Round(time.Millisecond))
+				delete(h.toolStarts, toolCallID)
+			}
+			h.mu.Unlock()
+		}
 		consoleMu.Lock()
-		fmt.Printf("🔄 Tool result:\n%s\n\n", rendered)
+		fmt.Printf("%s\n%s\n\n", header, rendered)
 		consoleMu.Unlock()
 	}
 }
 
+// HandleUsage prints the tokens used since the previous usage snapshot when
+// Verbose is true
+func (h *ConsoleMessageHandler) HandleUsage(usage Usage) {
+	if !h.Verbose || h.Silent {
+		return
+	}
+	h.mu.Lock()
+	last := h.lastUsage
+	h.lastUsage = usage
+	h.mu.Unlock()
+
+	input := usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens -
+		last.InputTokens - last.CacheCreationInputTokens - last.CacheReadInputTokens
+	output := usage.OutputTokens - last.OutputTokens
+	line := fmt.Sprintf("📊 Tokens: +%d input, +%d output, $%.4f total", input, output, usage.TotalCost())
+	if usage.MaxContextWindow > 0 {
+		line += fmt.Sprintf(", context %d/%d", usage.CurrentContextWindow, usage.MaxContextWindow)
+	}
+	consoleMu.Lock()
+	fmt.Printf("%s\n\n", line)
+	consoleMu.Unlock()
+}
+
 // HandleThinking prints thinking content to the console unless Silent is true
 func (h *ConsoleMessageHandler) HandleThinking(thinking string) {
 	if !h.Silent {
@@ -151,18 +256,64 @@ func (h *ConsoleMessageHandler) HandleThinking(thinking string) {
 	}
 }
 
-// HandleDone is called when message processing is complete
+// HandleContentFilter prints a notice that the provider refused or filtered
+// the response unless Silent is true
+func (h *ConsoleMessageHandler) HandleContentFilter(event ContentFilterEvent) {
+	if h.Silent {
+		return
+	}
+	line := fmt.Sprintf("⚠️  Response blocked by the %s content policy (%s)", event.Provider, event.Reason)
+	if event.Message != "" {
+		line += ": " + event.Message
+	}
+	consoleMu.Lock()
+	fmt.Printf("%s\n\n", line)
+	consoleMu.Unlock()
+}
+
+// HandleToolStall prints a warning about a tool call that has gone without
+// progress unless Silent is true
+func (h *ConsoleMessageHandler) HandleToolStall(_, toolName string, idle time.Duration) {
+	if h.Silent {
+		return
+	}
+	consoleMu.Lock()
+	fmt.Printf("⏳ %s has reported no progress for %s\n\n", toolName, idle.Round(time.Second))
+	consoleMu.Unlock()
+}
+
+// HandleDone prints any markdown still buffered from the stream
 func (h *ConsoleMessageHandler) HandleDone() {
-	// No action needed for console handler
+	if !h.Silent && h.stream != nil {
+		h.printRendered(h.stream.Flush())
+	}
 }
 
 // HandleTextDelta prints streamed text chunks to the console unless Silent is true
 func (h *ConsoleMessageHandler) HandleTextDelta(delta string) {
-	if !h.Silent {
-		consoleMu.Lock()
-		fmt.Print(delta)
-		consoleMu.Unlock()
+	if h.Silent {
+		return
+	}
+	if h.RenderMarkdown != nil {
+		if h.stream == nil {
+			h.stream = markdown.NewStream(h.RenderMarkdown)
+		}
+		h.printRendered(h.stream.Write(delta))
+		return
 	}
+	consoleMu.Lock()
+	fmt.Print(delta)
+	consoleMu.Unlock()
+}
+
+func (h *ConsoleMessageHandler) printRendered(rendered string) {
+	if rendered == "" {
+		return
+	}
+	consoleMu.Lock()
+	fmt.Println(rendered)
+	fmt.Println()
+	consoleMu.Unlock()
 }
 
 // HandleThinkingStart prints the thinking prefix to the console unless Silent is true
@@ -192,20 +343,27 @@ func (h *ConsoleMessageHandler) HandleThinkingBlockEnd() {
 	}
 }
 
-// HandleContentBlockEnd prints a newline when a content block ends unless Silent is true
+// HandleContentBlockEnd prints a newline when a content block ends unless Silent is true.
+// Buffered markdown is rendered instead when markdown rendering is enabled.
 func (h *ConsoleMessageHandler) HandleContentBlockEnd() {
-	if !h.Silent {
-		consoleMu.Lock()
-		fmt.Println()
-		consoleMu.Unlock()
+	if h.Silent {
+		return
 	}
+	if h.stream != nil {
+		h.printRendered(h.stream.Flush())
+		return
+	}
+	consoleMu.Lock()
+	fmt.Println()
+	consoleMu.Unlock()
 }
 
 // StringCollectorHandler collects text responses into a string
 type StringCollectorHandler struct {
-	Silent bool
-	text   strings.Builder
-	mu     sync.Mutex
+	Silent        bool
+	text          strings.Builder
+	contentFilter *ContentFilterEvent
+	mu            sync.Mutex
 }
 
 // HandleText collects the text in a string builder and optionally prints to console
@@ -265,6 +423,21 @@ func (h *StringCollectorHandler) CollectedText() string {
 	return h.text.String()
 }
 
+// HandleContentFilter records that the provider refused or filtered the response
+func (h *StringCollectorHandler) HandleContentFilter(event ContentFilterEvent) {
+	h.mu.Lock()
+	h.contentFilter = &event
+	h.mu.Unlock()
+}
+
+// ContentFilter returns the content filter event of the response, or nil
+// when the response was not refused or filtered
+func (h *StringCollectorHandler) ContentFilter() *ContentFilterEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.contentFilter
+}
+
 // HandleTextDelta collects streamed text chunks and optionally prints to console
 func (h *StringCollectorHandler) HandleTextDelta(delta string) {
 	h.mu.Lock()
@@ -335,6 +508,7 @@ type DeltaEntry struct {
 	Input          string                          `json:"input,omitempty"`
 	Result         string                          `json:"result,omitempty"`
 	ToolResult     *tooltypes.StructuredToolResult `json:"tool_result,omitempty"`
+	Reason         string                          `json:"reason,omitempty"`
 	ConversationID string                          `json:"conversation_id"`
 	Role           string                          `json:"role"`
 }
@@ -449,6 +623,18 @@ func (h *HeadlessStreamHandler) HandleText(text string) {
 	})
 }
 
+// HandleContentFilter outputs an event for a response refused or filtered by
+// the provider's content policy.
+func (h *HeadlessStreamHandler) HandleContentFilter(event ContentFilterEvent) {
+	h.output(DeltaEntry{
+		Kind:           "content-filter",
+		Content:        event.Message,
+		Reason:         event.Reason,
+		ConversationID: h.conversationID,
+		Role:           "assistant",
+	})
+}
+
 // HandleUserMessage outputs user-authored messages before subsequent assistant
 // and tool events.
 func (h *HeadlessStreamHandler) HandleUserMessage(content string, images []string) {
@@ -523,6 +709,19 @@ func (h *HeadlessStreamHandler) HandleToolUse(toolCallID, toolName, input string
 	})
 }
 
+// HandleToolStall outputs an event for a tool call that has gone without
+// progress for longer than its stall_after.
+func (h *HeadlessStreamHandler) HandleToolStall(toolCallID, toolName string, idle time.Duration) {
+	h.output(DeltaEntry{
+		Kind:           "tool-stall",
+		ToolName:       toolName,
+		ToolCallID:     toolCallID,
+		Content:        fmt.Sprintf("no progress for %s", idle.Round(time.Second)),
+		ConversationID: h.conversationID,
+		Role:           "assistant",
+	})
+}
+
 // HandleToolUpdate outputs transient accumulated tool result snapshots.
 func (h *HeadlessStreamHandler) HandleToolUpdate(toolCallID, toolName string, result tooltypes.ToolResult) {
 	structuredResult := result.StructuredData()
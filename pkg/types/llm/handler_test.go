@@ -74,6 +74,57 @@ func TestConsoleMessageHandlerOutput(t *testing.T) {
 	assert.Empty(t, silentOutput)
 }
 
+func TestConsoleMessageHandlerOutputProfiles(t *testing.T) {
+	assert.True(t, NewConsoleMessageHandler(OutputProfileQuiet).Silent)
+	assert.False(t, NewConsoleMessageHandler(OutputProfileNormal).Silent)
+	assert.False(t, NewConsoleMessageHandler(OutputProfileNormal).Verbose)
+	assert.True(t, NewConsoleMessageHandler(OutputProfileVerbose).Verbose)
+
+	profile, err := ParseOutputProfile(" Verbose ")
+	require.NoError(t, err)
+	assert.Equal(t, OutputProfileVerbose, profile)
+	profile, err = ParseOutputProfile("")
+	require.NoError(t, err)
+	assert.Equal(t, OutputProfileNormal, profile)
+	_, err = ParseOutputProfile("loud")
+	require.Error(t, err)
+
+	verbose := NewConsoleMessageHandler(OutputProfileVerbose)
+	output := captureStdout(func() {
+		verbose.HandleToolUse("call-1", "bash", `{"command":"pwd"}`)
+		verbose.HandleToolResult("call-1", "bash", tooltypes.BaseToolResult{Result: "ok"})
+		verbose.HandleUsage(Usage{InputTokens: 100, CacheReadInputTokens: 50, OutputTokens: 20, InputCost: 0.01})
+		verbose.HandleUsage(Usage{InputTokens: 130, CacheReadInputTokens: 50, OutputTokens: 25, InputCost: 0.02, CurrentContextWindow: 205, MaxContextWindow: 1000})
+	})
+	assert.Regexp(t, `🔄 Tool result \(\d+(\.\d+)?[mµn]?s\):`, output)
+	assert.Contains(t, output, "📊 Tokens: +150 input, +20 output, $0.0100 total")
+	assert.Contains(t, output, "📊 Tokens: +30 input, +5 output, $0.0200 total, context 205/1000")
+
+	normalOutput := captureStdout(func() {
+		NewConsoleMessageHandler(OutputProfileNormal).HandleUsage(Usage{InputTokens: 100})
+	})
+	assert.Empty(t, normalOutput)
+}
+
+func TestConsoleMessageHandlerRendersStreamedMarkdown(t *testing.T) {
+	var rendered []string
+	handler := &ConsoleMessageHandler{RenderMarkdown: func(block string) string {
+		rendered = append(rendered, block)
+		return "rendered: " + strings.ReplaceAll(block, "```", "~~~")
+	}}
+
+	output := captureStdout(func() {
+		handler.HandleTextDelta("- item\n\n```go\nfunc main() {}\n")
+		handler.HandleTextDelta("``")
+		handler.HandleTextDelta("`\n")
+		handler.HandleContentBlockEnd()
+	})
+
+	assert.Equal(t, []string{"- item\n\n", "```go\nfunc main() {}\n```\n"}, rendered)
+	assert.Contains(t, output, "rendered: - item")
+	assert.NotContains(t, output, "```")
+}
+
 func TestStringCollectorHandler_HandleThinkingTrimsLeadingNewlines(t *testing.T) {
 	handler := &StringCollectorHandler{Silent: true}
 
@@ -218,6 +269,32 @@ func TestHeadlessStreamHandler_ContentEnd(t *testing.T) {
 	assert.Equal(t, "assistant", entry.Role)
 }
 
+func TestHeadlessStreamHandler_ContentFilter(t *testing.T) {
+	handler := NewHeadlessStreamHandler("conv-filter")
+
+	output := captureStdout(func() {
+		handler.HandleContentFilter(ContentFilterEvent{Provider: "anthropic", Reason: ContentFilterReasonRefusal, Message: "I can't help with that."})
+	})
+
+	var entry DeltaEntry
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(output)), &entry))
+	assert.Equal(t, "content-filter", entry.Kind)
+	assert.Equal(t, "refusal", entry.Reason)
+	assert.Equal(t, "I can't help with that.", entry.Content)
+	assert.Equal(t, "conv-filter", entry.ConversationID)
+	assert.Equal(t, "assistant", entry.Role)
+}
+
+func TestStringCollectorHandlerRecordsContentFilter(t *testing.T) {
+	handler := &StringCollectorHandler{}
+	assert.Nil(t, handler.ContentFilter())
+
+	handler.HandleContentFilter(ContentFilterEvent{Provider: "openai", Reason: ContentFilterReasonContentFilter})
+
+	require.NotNil(t, handler.ContentFilter())
+	assert.Equal(t, ContentFilterReasonContentFilter, handler.ContentFilter().Reason)
+}
+
 func TestHeadlessStreamHandler_UserMessage(t *testing.T) {
 	handler := NewHeadlessStreamHandler("conv-user")
 
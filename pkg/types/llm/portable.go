@@ -0,0 +1,28 @@
+package llm
+
+// PortableMessage is a provider-neutral form of a stored conversation
+// message, used to continue a conversation with a different provider than
+// the one it was saved with. It keeps text, tool calls and tool results;
+// reasoning, images and other provider-specific content are not carried
+// over.
+type PortableMessage struct {
+	// Role is "user", "assistant", or "tool" for a tool result.
+	Role    string `json:"role"`
+	Content string `json:"content,omitempty"`
+	// ToolCalls are the tool calls made by an assistant message.
+	ToolCalls []PortableToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID is the call a tool message answers.
+	ToolCallID string `json:"tool_call_id,omitempty"`
+}
+
+// PortableToolCall is a tool call made by the assistant.
+type PortableToolCall struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Input is the JSON encoded tool input.
+	Input string `json:"input"`
+}
+
+// PortableImagePlaceholder replaces images, which are not carried over
+// between providers.
+const PortableImagePlaceholder = "[image omitted when the conversation was moved to another provider]"
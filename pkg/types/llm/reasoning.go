@@ -1,6 +1,7 @@
 package llm
 
 import (
+	"encoding/json"
 	"slices"
 	"strings"
 
@@ -115,3 +116,81 @@ func ReasoningEffortOptions(config Config) []string {
 	}
 	return append([]string(nil), options...)
 }
+
+// EscalateReasoningEffort returns the next effort above the configured one
+// among the efforts the config allows, or the configured effort when it is
+// already the highest.
+func EscalateReasoningEffort(config Config) string {
+	effort, err := NormalizeReasoningEffort(config.ReasoningEffort)
+	if err != nil || effort == "" {
+		effort = DefaultReasoningEffort
+	}
+
+	current := slices.Index(validReasoningEfforts, effort)
+	next := effort
+	nextRank := len(validReasoningEfforts)
+	for _, option := range ReasoningEffortOptions(config) {
+		rank := slices.Index(validReasoningEfforts, option)
+		if rank > current && rank < nextRank {
+			next, nextRank = option, rank
+		}
+	}
+	return next
+}
+
+// ReasoningEffortIncrease returns how many levels effort is above base, or 0
+// when it is not higher or either value is unknown.
+func ReasoningEffortIncrease(base, effort string) int {
+	baseRank := slices.Index(validReasoningEfforts, strings.ToLower(strings.TrimSpace(base)))
+	rank := slices.Index(validReasoningEfforts, strings.ToLower(strings.TrimSpace(effort)))
+	if baseRank < 0 || rank <= baseRank {
+		return 0
+	}
+	return rank - baseRank
+}
+
+// Reasoning formats recorded in transcripts.
+const (
+	// ReasoningFormatThinking is the full reasoning text, such as an
+	// Anthropic thinking block or Chat Completions reasoning_content.
+	ReasoningFormatThinking = "thinking"
+	// ReasoningFormatSummary is a summary of reasoning the provider keeps
+	// hidden, such as an OpenAI Responses reasoning summary.
+	ReasoningFormatSummary = "summary"
+	// ReasoningFormatRedacted is reasoning with no readable text, such as an
+	// Anthropic redacted thinking block or encrypted-only OpenAI reasoning.
+	ReasoningFormatRedacted = "redacted"
+)
+
+// Reasoning is the provider-neutral transcript form of the reasoning a model
+// produced before answering. Providers convert their own artifacts into it so
+// that display, export and search treat reasoning the same way, while Opaque
+// keeps the provider payload needed to replay the reasoning on resume.
+type Reasoning struct {
+	Provider string `json:"provider"`
+	Format   string `json:"format"`
+	Text     string `json:"text,omitempty"`
+	// Opaque is the provider's original reasoning payload, such as an
+	// Anthropic thinking signature or OpenAI encrypted content. It is kept
+	// verbatim and never interpreted outside the provider.
+	Opaque json.RawMessage `json:"opaque,omitempty"`
+}
+
+// DisplayText returns the readable reasoning text, or "" when the reasoning
+// has none.
+func (r Reasoning) DisplayText() string {
+	return strings.TrimSpace(r.Text)
+}
+
+// TranscriptText returns the reasoning as a plain transcript line in the
+// form the console prints it, or "" when it has no readable text.
+func (r Reasoning) TranscriptText() string {
+	text := r.DisplayText()
+	if text == "" {
+		return ""
+	}
+	if r.Format == ReasoningFormatSummary {
+		return "💭 Thinking (summary): " + text
+	}
+	return "💭 Thinking: " + text
+}
@@ -51,6 +51,36 @@ func TestReasoningEffortOptionsUsesProviderDefaultsWithoutPolicy(t *testing.T) {
 	assert.Equal(t, []string{"low", "high"}, ReasoningEffortOptions(restricted))
 }
 
+func TestEscalateReasoningEffort(t *testing.T) {
+	assert.Equal(t, "high", EscalateReasoningEffort(Config{Provider: "openai", ReasoningEffort: "medium"}))
+	assert.Equal(t, "high", EscalateReasoningEffort(Config{Provider: "openai"}), "an unset effort is medium")
+	assert.Equal(t, "low", EscalateReasoningEffort(Config{Provider: "anthropic", ReasoningEffort: "none"}), "anthropic has no minimal effort")
+	assert.Equal(t, "max", EscalateReasoningEffort(Config{Provider: "anthropic", ReasoningEffort: "max"}))
+	assert.Equal(t, "xhigh", EscalateReasoningEffort(Config{
+		Provider:                "openai",
+		ReasoningEffort:         "low",
+		AllowedReasoningEfforts: []string{"low", "xhigh"},
+	}), "the policy bounds escalation")
+	assert.Equal(t, "high", EscalateReasoningEffort(Config{
+		Provider:                "openai",
+		ReasoningEffort:         "high",
+		AllowedReasoningEfforts: []string{"low", "high"},
+	}))
+}
+
+func TestReasoningEffortIncrease(t *testing.T) {
+	assert.Equal(t, 1, ReasoningEffortIncrease("medium", "high"))
+	assert.Equal(t, 3, ReasoningEffortIncrease("medium", " MAX "))
+	assert.Zero(t, ReasoningEffortIncrease("high", "low"))
+	assert.Zero(t, ReasoningEffortIncrease("", "high"))
+}
+
+func TestMessageOptResolvedReasoningEffort(t *testing.T) {
+	assert.Equal(t, "medium", MessageOpt{}.ResolvedReasoningEffort("medium"))
+	assert.Equal(t, "high", MessageOpt{ReasoningEffort: " High "}.ResolvedReasoningEffort("medium"))
+	assert.Equal(t, "medium", MessageOpt{ReasoningEffort: "high", UseWeakModel: true}.ResolvedReasoningEffort("medium"))
+}
+
 func TestConversationConfigSnapshotApplyPreservesLivePolicy(t *testing.T) {
 	config := Config{
 		Profile:                 "work",
@@ -158,3 +188,9 @@ func TestConversationConfigSnapshotPreservesUnsetOpenAITextVerbosity(t *testing.
 	require.NoError(t, err)
 	assert.NotContains(t, string(rawSnapshot), "text_verbosity")
 }
+
+func TestReasoningTranscriptText(t *testing.T) {
+	assert.Equal(t, "💭 Thinking: step one", Reasoning{Format: ReasoningFormatThinking, Text: "\nstep one\n"}.TranscriptText())
+	assert.Equal(t, "💭 Thinking (summary): plan", Reasoning{Format: ReasoningFormatSummary, Text: "plan"}.TranscriptText())
+	assert.Empty(t, Reasoning{Format: ReasoningFormatRedacted, Opaque: json.RawMessage(`{}`)}.TranscriptText())
+}
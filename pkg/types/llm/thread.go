@@ -3,6 +3,7 @@ package llm
 import (
 	"context"
 	"strings"
+	"time"
 
 	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
 )
@@ -36,11 +37,31 @@ type MessageOpt struct {
 	// MaxTurns limits the number of turns within a single SendMessage call
 	// A value of 0 means no limit, and negative values are treated as 0
 	MaxTurns int
+	// MaxCostUSD stops the agent loop with a BudgetExceededError once this
+	// SendMessage call, including aggregated subagent usage, has cost at least
+	// this much. A value of 0 means no limit.
+	MaxCostUSD float64
+	// MaxTotalTokens stops the agent loop with a BudgetExceededError once this
+	// SendMessage call has used at least this many tokens. A value of 0 means
+	// no limit.
+	MaxTotalTokens int
 	// CompactRatio is the ratio of context window at which to trigger auto-compact (>0.0-1.0).
 	// A zero value uses the thread/configured default; it does not disable auto-compaction.
 	CompactRatio float64
 	// DisableUsageLog disables LLM usage logging for this message
 	DisableUsageLog bool
+	// ReasoningEffort overrides the configured reasoning effort for this message only.
+	// Empty uses the configured effort. It does not apply to weak model requests.
+	ReasoningEffort string
+}
+
+// ResolvedReasoningEffort returns the reasoning effort to use for this message,
+// preferring the per-message override over the configured effort.
+func (o MessageOpt) ResolvedReasoningEffort(configured string) string {
+	if effort := strings.ToLower(strings.TrimSpace(o.ReasoningEffort)); effort != "" && !o.UseWeakModel {
+		return effort
+	}
+	return configured
 }
 
 // ResolvedInitiator returns the normalized initiator, defaulting to user.
@@ -108,3 +129,20 @@ type Thread interface {
 	// GetMetadata returns provider-neutral conversation metadata.
 	GetMetadata() map[string]any
 }
+
+// CachePrimeResult reports the prompt cache created by priming.
+type CachePrimeResult struct {
+	Model string
+	// CacheWriteTokens were written to the cache; CacheReadTokens were
+	// already cached and had their expiry extended.
+	CacheWriteTokens int
+	CacheReadTokens  int
+	Cost             float64
+	TTL              time.Duration
+}
+
+// CachePrimer is implemented by threads whose provider can create a prompt
+// cache for the system prompt and tools ahead of the first exchange.
+type CachePrimer interface {
+	PrimePromptCache(ctx context.Context, ttl time.Duration) (CachePrimeResult, error)
+}
@@ -5,6 +5,8 @@ type BashInput struct {
 	Description string `json:"description" jsonschema:"description=A description of the command to run"`
 	Command     string `json:"command" jsonschema:"description=The bash command to run"`
 	Timeout     int    `json:"timeout" jsonschema:"description=Timeout in seconds"`
+	Reset       bool   `json:"reset,omitempty" jsonschema:"description=Restart the shell session with a clean environment and the default working directory before running the command"`
+	Raw         bool   `json:"raw,omitempty" jsonschema:"description=Return the output even when it looks binary. Only set this when the raw bytes are genuinely needed"`
 }
 
 // FileReadInput defines the input parameters for the file_read tool.
@@ -12,6 +14,8 @@ type FileReadInput struct {
 	FilePath  string `json:"file_path" jsonschema:"description=The absolute path of the file to read"`
 	Offset    int    `json:"offset" jsonschema:"description=The 1-indexed line number to start reading from. Default: 1"`
 	LineLimit int    `json:"line_limit" jsonschema:"description=The maximum number of lines to read from the offset. Default: 2000. Max: 2000"`
+	Mode      string `json:"mode,omitempty" jsonschema:"description=auto returns an outline for large source files read without a range and the content otherwise. full always returns the content. outline always returns the outline. Default: auto,enum=auto,enum=full,enum=outline"`
+	Raw       bool   `json:"raw,omitempty" jsonschema:"description=Return the content even when the file looks binary. Only set this when the raw bytes are genuinely needed"`
 }
 
 // FileWriteInput defines the input parameters for the file_write tool.
@@ -51,12 +55,26 @@ type GlobInput struct {
 	IgnoreGitignore bool   `json:"ignore_gitignore,omitempty" jsonschema:"description=If true, do not respect .gitignore rules (default: false, meaning .gitignore is respected)"`
 }
 
+// GitLogInput defines the input parameters for the git_log tool.
+type GitLogInput struct {
+	From string `json:"from,omitempty" jsonschema:"description=Exclusive start of the range (tag, branch or commit). Default: the latest tag before to"`
+	To   string `json:"to,omitempty" jsonschema:"description=Inclusive end of the range. Default: HEAD"`
+	Path string `json:"path,omitempty" jsonschema:"description=The absolute path of the repository. Defaults to the current working directory"`
+}
+
 // ReadConversationInput defines the input parameters for the read_conversation tool.
 type ReadConversationInput struct {
 	ConversationID string `json:"conversation_id" jsonschema:"description=The ID of the saved conversation to read"`
 	Goal           string `json:"goal" jsonschema:"description=What information to extract from the conversation"`
 }
 
+// HistorySearchInput defines the input parameters for the history_search tool.
+type HistorySearchInput struct {
+	Query string `json:"query" jsonschema:"description=Text to search for. It is matched case-insensitively as a phrase"`
+	Scope string `json:"scope,omitempty" jsonschema:"description=current searches this conversation including history removed by compaction. repo searches every saved conversation in the working directory (default: repo),enum=current,enum=repo"`
+	Limit int    `json:"limit,omitempty" jsonschema:"description=Maximum number of matches to return (default: 20; max: 50)"`
+}
+
 // GetGoalInput defines the input parameters for the get_goal tool.
 type GetGoalInput struct{}
 
@@ -65,3 +83,14 @@ type UpdateGoalInput struct {
 	Status string `json:"status" jsonschema:"description=Required goal status to set,enum=active,enum=paused,enum=complete,enum=blocked,enum=cleared"`
 	Reason string `json:"reason,omitempty" jsonschema:"description=Optional concise evidence for why the goal is complete or blocked"`
 }
+
+// TodoWriteInput defines the input parameters for the todo_write tool.
+type TodoWriteInput struct {
+	Todos []TodoItemInput `json:"todos" jsonschema:"description=The complete todo list; it replaces the previous list"`
+}
+
+// TodoItemInput is one todo_write list entry.
+type TodoItemInput struct {
+	Content string `json:"content" jsonschema:"description=What needs to be done"`
+	Status  string `json:"status" jsonschema:"description=Item status,enum=pending,enum=in_progress,enum=completed,enum=cancelled"`
+}
@@ -0,0 +1,39 @@
+package tools
+
+import "time"
+
+// ResourceUsage aggregates the resources consumed by tool subprocesses during a run.
+type ResourceUsage struct {
+	Processes       int           `json:"processes"`
+	WallTime        time.Duration `json:"wallTime"`
+	UserCPUTime     time.Duration `json:"userCpuTime"`
+	SystemCPUTime   time.Duration `json:"systemCpuTime"`
+	PeakMemoryBytes int64         `json:"peakMemoryBytes"`
+}
+
+// CPUTime returns the combined user and system CPU time.
+func (u ResourceUsage) CPUTime() time.Duration {
+	return u.UserCPUTime + u.SystemCPUTime
+}
+
+// Add accumulates other into u. Times are summed and peak memory keeps the maximum.
+func (u *ResourceUsage) Add(other ResourceUsage) {
+	u.Processes += other.Processes
+	u.WallTime += other.WallTime
+	u.UserCPUTime += other.UserCPUTime
+	u.SystemCPUTime += other.SystemCPUTime
+	u.PeakMemoryBytes = max(u.PeakMemoryBytes, other.PeakMemoryBytes)
+}
+
+// ResourceUsageReporter is implemented by states that track the resources
+// consumed by tool subprocesses.
+type ResourceUsageReporter interface {
+	ResourceUsage() ResourceUsage
+}
+
+// FileAccessReporter is implemented by states that track the files accessed
+// by the file tools.
+type FileAccessReporter interface {
+	// RecentlyAccessedFiles returns absolute file paths, most recently accessed first.
+	RecentlyAccessedFiles() []string
+}
@@ -2,6 +2,7 @@ package tools
 
 import (
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"time"
 
@@ -66,9 +67,13 @@ var metadataTypeRegistry = map[string]reflect.Type{
 	"view_image":        reflect.TypeOf(ViewImageMetadata{}),
 	"openai_web_search": reflect.TypeOf(OpenAIWebSearchMetadata{}),
 	"web_fetch":         reflect.TypeOf(WebFetchMetadata{}),
+	"web_crawl":         reflect.TypeOf(WebCrawlMetadata{}),
 	"read_conversation": reflect.TypeOf(ReadConversationMetadata{}),
+	"history_search":    reflect.TypeOf(HistorySearchMetadata{}),
+	"git_log":           reflect.TypeOf(GitLogMetadata{}),
 	"get_goal":          reflect.TypeOf(GetGoalMetadata{}),
 	"update_goal":       reflect.TypeOf(UpdateGoalMetadata{}),
+	"todo_write":        reflect.TypeOf(TodoWriteMetadata{}),
 	"skill":             reflect.TypeOf(SkillMetadata{}),
 	"blocked":           reflect.TypeOf(BlockedMetadata{}),
 }
@@ -124,6 +129,25 @@ type FileReadMetadata struct {
 	Language       string   `json:"language,omitempty"`
 	Truncated      bool     `json:"truncated"`
 	RemainingLines int      `json:"remainingLines,omitempty"`
+	// Outline is set when Lines holds an outline of the file rather than its
+	// content.
+	Outline    bool `json:"outline,omitempty"`
+	TotalLines int  `json:"totalLines,omitempty"`
+	// Binary is set when the content was withheld because the file looks
+	// binary.
+	Binary *BinaryContent `json:"binary,omitempty"`
+}
+
+// BinaryContent describes tool output withheld from the model because it
+// looks binary.
+type BinaryContent struct {
+	Size int64  `json:"size"`
+	Type string `json:"type"`
+}
+
+// Notice returns the text shown in place of the withheld content.
+func (b BinaryContent) Notice() string {
+	return fmt.Sprintf("binary content withheld (%d bytes, type: %s)", b.Size, b.Type)
 }
 
 // ToolType returns the tool type identifier for file read operations
@@ -147,6 +171,10 @@ type FileEditMetadata struct {
 	Language      string `json:"language,omitempty"`
 	ReplaceAll    bool   `json:"replaceAll,omitempty"`
 	ReplacedCount int    `json:"replacedCount,omitempty"`
+	// UnifiedDiff is the change to the whole file, with its line numbers.
+	UnifiedDiff string `json:"unifiedDiff,omitempty"`
+	// InverseDiff reverts the change when applied to the edited file.
+	InverseDiff string `json:"inverseDiff,omitempty"`
 }
 
 // Edit represents a single text replacement in a file
@@ -172,6 +200,8 @@ type ApplyPatchChange struct {
 	OldContent  string `json:"oldContent,omitempty"`
 	NewContent  string `json:"newContent,omitempty"`
 	UnifiedDiff string `json:"unifiedDiff,omitempty"`
+	// InverseDiff reverts the change when applied to the changed file.
+	InverseDiff string `json:"inverseDiff,omitempty"`
 	MovePath    string `json:"movePath,omitempty"`
 }
 
@@ -254,8 +284,11 @@ type BashMetadata struct {
 	Output         string                `json:"output"`
 	ExecutionTime  time.Duration         `json:"executionTime"`
 	WorkingDir     string                `json:"workingDir,omitempty"`
+	Container      string                `json:"container,omitempty"`    // Dev container the command ran in
+	ContainerDir   string                `json:"containerDir,omitempty"` // WorkingDir as seen inside the container
 	Truncation     *BashOutputTruncation `json:"truncation,omitempty"`
 	FullOutputPath string                `json:"fullOutputPath,omitempty"`
+	Binary         *BinaryContent        `json:"binary,omitempty"`
 }
 
 // BashOutputTruncation describes output omitted from the model-facing snapshot.
@@ -271,11 +304,13 @@ func (m BashMetadata) ToolType() string { return "bash" }
 
 // ExtensionToolMetadata contains metadata about an extension tool execution.
 type ExtensionToolMetadata struct {
-	ExtensionID   string         `json:"extensionId"`
-	ToolName      string         `json:"toolName"`
-	Output        string         `json:"output"`
-	Data          map[string]any `json:"data,omitempty"`
-	ExecutionTime time.Duration  `json:"executionTime"`
+	ExtensionID string         `json:"extensionId"`
+	ToolName    string         `json:"toolName"`
+	Output      string         `json:"output"`
+	Data        map[string]any `json:"data,omitempty"`
+	// Images is the number of images passed back to the model.
+	Images        int           `json:"images,omitempty"`
+	ExecutionTime time.Duration `json:"executionTime"`
 }
 
 // ToolType returns the tool type identifier for extension tool execution.
@@ -314,6 +349,29 @@ type WebFetchMetadata struct {
 // ToolType returns the tool type identifier for web fetch operations
 func (m WebFetchMetadata) ToolType() string { return "web_fetch" }
 
+// WebCrawlMetadata contains metadata about a web_crawl operation.
+type WebCrawlMetadata struct {
+	URL              string         `json:"url"`
+	PathPrefix       string         `json:"pathPrefix,omitempty"`
+	Dir              string         `json:"dir,omitempty"`
+	Fetched          int            `json:"fetched"`
+	Queued           int            `json:"queued"`
+	RobotsDisallowed int            `json:"robotsDisallowed,omitempty"`
+	Pages            []WebCrawlPage `json:"pages,omitempty"`
+}
+
+// WebCrawlPage is a page found by a web_crawl operation.
+type WebCrawlPage struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	File        string `json:"file,omitempty"`
+	DuplicateOf string `json:"duplicateOf,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ToolType returns the tool type identifier for web_crawl operations.
+func (m WebCrawlMetadata) ToolType() string { return "web_crawl" }
+
 // OpenAIWebSearchMetadata contains metadata about a native OpenAI web search operation.
 type OpenAIWebSearchMetadata struct {
 	CallID  string   `json:"callId"`
@@ -339,6 +397,49 @@ type ReadConversationMetadata struct {
 // ToolType returns the tool type identifier for read_conversation operations.
 func (m ReadConversationMetadata) ToolType() string { return "read_conversation" }
 
+// HistorySearchMetadata contains metadata about a history_search operation.
+type HistorySearchMetadata struct {
+	Query   string               `json:"query"`
+	Scope   string               `json:"scope"`
+	Matches []HistorySearchMatch `json:"matches,omitempty"`
+}
+
+// HistorySearchMatch is a message of a saved conversation that matched a
+// history_search query.
+type HistorySearchMatch struct {
+	ConversationID string    `json:"conversationID"`
+	Role           string    `json:"role"`
+	Snippet        string    `json:"snippet"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+	// Current marks matches in the conversation that ran the search.
+	Current bool `json:"current,omitempty"`
+	// Compacted marks matches in history that compaction removed from the
+	// conversation's context.
+	Compacted bool `json:"compacted,omitempty"`
+}
+
+// ToolType returns the tool type identifier for history_search operations.
+func (m HistorySearchMetadata) ToolType() string { return "history_search" }
+
+// GitLogMetadata contains metadata about a git_log operation.
+type GitLogMetadata struct {
+	From      string       `json:"from,omitempty"`
+	To        string       `json:"to"`
+	RepoURL   string       `json:"repoURL,omitempty"`
+	Total     int          `json:"total"`
+	Truncated bool         `json:"truncated,omitempty"`
+	Areas     []GitLogArea `json:"areas"`
+}
+
+// GitLogArea is the number of commits git_log found in one area.
+type GitLogArea struct {
+	Name    string `json:"name"`
+	Commits int    `json:"commits"`
+}
+
+// ToolType returns the tool type identifier for git_log operations.
+func (m GitLogMetadata) ToolType() string { return "git_log" }
+
 // GetGoalMetadata contains metadata about a get_goal operation.
 type GetGoalMetadata struct {
 	Objective string    `json:"objective,omitempty"`
@@ -364,6 +465,16 @@ type UpdateGoalMetadata struct {
 // ToolType returns the tool type identifier for update_goal operations.
 func (m UpdateGoalMetadata) ToolType() string { return "update_goal" }
 
+// TodoWriteMetadata contains metadata about a todo_write operation.
+type TodoWriteMetadata struct {
+	Todos     []TodoItemInput `json:"todos"`
+	Completed int             `json:"completed"`
+	Total     int             `json:"total"`
+}
+
+// ToolType returns the tool type identifier for todo_write operations.
+func (m TodoWriteMetadata) ToolType() string { return "todo_write" }
+
 // ExtractMetadata is a helper that handles both pointer and value type assertions
 // This is necessary because JSON unmarshaling creates value types, while
 // direct creation uses pointer types
@@ -89,7 +89,7 @@ func TestMetadataTypeRegistry_Completeness(t *testing.T) {
 		"grep_tool", "glob_tool", "bash",
 		"view_image",
 		"openai_web_search",
-		"web_fetch", "read_conversation", "get_goal", "update_goal", "extension_tool",
+		"web_fetch", "web_crawl", "read_conversation", "history_search", "git_log", "get_goal", "update_goal", "todo_write", "extension_tool",
 		"skill", "blocked",
 	}
 
@@ -117,10 +117,14 @@ func TestAllMetadataToolTypes(t *testing.T) {
 		{"ExtensionToolMetadata", ExtensionToolMetadata{}, "extension_tool"},
 		{"ViewImageMetadata", ViewImageMetadata{}, "view_image"},
 		{"WebFetchMetadata", WebFetchMetadata{}, "web_fetch"},
+		{"WebCrawlMetadata", WebCrawlMetadata{}, "web_crawl"},
 		{"OpenAIWebSearchMetadata", OpenAIWebSearchMetadata{}, "openai_web_search"},
 		{"ReadConversationMetadata", ReadConversationMetadata{}, "read_conversation"},
+		{"HistorySearchMetadata", HistorySearchMetadata{}, "history_search"},
+		{"GitLogMetadata", GitLogMetadata{}, "git_log"},
 		{"GetGoalMetadata", GetGoalMetadata{}, "get_goal"},
 		{"UpdateGoalMetadata", UpdateGoalMetadata{}, "update_goal"},
+		{"TodoWriteMetadata", TodoWriteMetadata{}, "todo_write"},
 		{"SkillMetadata", SkillMetadata{}, "skill"},
 		{"BlockedMetadata", BlockedMetadata{}, "blocked"},
 	}
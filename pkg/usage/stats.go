@@ -7,12 +7,14 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jingkaihe/kodelet/pkg/logger"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
 )
 
 // ConversationSummary provides access to conversation metadata and usage statistics
@@ -23,6 +25,7 @@ type ConversationSummary interface {
 	GetMessageCount() int
 	GetUsage() llmtypes.Usage
 	GetProvider() string
+	GetModel() string
 }
 
 // DailyUsage represents usage statistics for a single day
@@ -357,8 +360,200 @@ func CalculateDailyProviderBreakdownStats(summaries []ConversationSummary, start
 	}
 }
 
+// ConversationOutcome summarizes how far a conversation got with its goal
+// and todo list.
+type ConversationOutcome struct {
+	HasGoal        bool
+	GoalCompleted  bool
+	Todos          int
+	TodosCompleted int
+}
+
+// GroupedConversation is a conversation with the report group it belongs to.
+type GroupedConversation struct {
+	Summary ConversationSummary
+	Group   string
+	Outcome ConversationOutcome
+}
+
+// GroupStats represents the usage and outcomes of the conversations in one
+// report group.
+type GroupStats struct {
+	Group          string
+	Usage          llmtypes.Usage
+	Conversations  int
+	Messages       int
+	Goals          int
+	GoalsCompleted int
+	Todos          int
+	TodosCompleted int
+}
+
+// AverageCost returns the mean cost per conversation.
+func (g GroupStats) AverageCost() float64 {
+	if g.Conversations == 0 {
+		return 0
+	}
+	return g.Usage.TotalCost() / float64(g.Conversations)
+}
+
+// CalculateGroupStats aggregates usage and outcomes per group, sorted by
+// group name.
+func CalculateGroupStats(conversations []GroupedConversation) []GroupStats {
+	groups := make(map[string]*GroupStats)
+	for _, conversation := range conversations {
+		stats, ok := groups[conversation.Group]
+		if !ok {
+			stats = &GroupStats{Group: conversation.Group}
+			groups[conversation.Group] = stats
+		}
+
+		usage := conversation.Summary.GetUsage()
+		stats.Usage.InputTokens += usage.InputTokens
+		stats.Usage.OutputTokens += usage.OutputTokens
+		stats.Usage.CacheCreationInputTokens += usage.CacheCreationInputTokens
+		stats.Usage.CacheReadInputTokens += usage.CacheReadInputTokens
+		stats.Usage.InputCost += usage.InputCost
+		stats.Usage.OutputCost += usage.OutputCost
+		stats.Usage.CacheCreationCost += usage.CacheCreationCost
+		stats.Usage.CacheReadCost += usage.CacheReadCost
+		stats.Conversations++
+		stats.Messages += conversation.Summary.GetMessageCount()
+
+		outcome := conversation.Outcome
+		if outcome.HasGoal {
+			stats.Goals++
+			if outcome.GoalCompleted {
+				stats.GoalsCompleted++
+			}
+		}
+		stats.Todos += outcome.Todos
+		stats.TodosCompleted += outcome.TodosCompleted
+	}
+
+	result := make([]GroupStats, 0, len(groups))
+	for _, stats := range groups {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Group < result[j].Group
+	})
+	return result
+}
+
+// Cost report groupings.
+const (
+	CostGroupByDay          = "day"
+	CostGroupByModel        = "model"
+	CostGroupByConversation = "conversation"
+)
+
+// CostReportRow is the usage of one row of a cost report. Day rows set Date,
+// model rows set Provider and Model, and conversation rows set all of them
+// and ConversationID.
+type CostReportRow struct {
+	Date           time.Time
+	Provider       string
+	Model          string
+	ConversationID string
+	Conversations  int
+	Usage          llmtypes.Usage
+}
+
+// CostReport is the usage of conversations grouped for cost attribution.
+type CostReport struct {
+	GroupBy            string
+	Rows               []CostReportRow
+	Total              llmtypes.Usage
+	TotalConversations int
+}
+
+// CalculateCostReport groups the usage of conversations updated within the
+// time range by day, by provider and model, or by conversation. Day and
+// conversation rows are sorted newest first and model rows by cost, highest
+// first.
+func CalculateCostReport(summaries []ConversationSummary, groupBy string, startTime, endTime time.Time) *CostReport {
+	report := &CostReport{GroupBy: groupBy}
+	rows := make(map[string]*CostReportRow)
+	var keys []string
+
+	for _, summary := range summaries {
+		// Use UpdatedAt as the date for this conversation's usage
+		date := summary.GetUpdatedAt().Truncate(24 * time.Hour)
+		if !startTime.IsZero() && date.Before(startTime) {
+			continue
+		}
+		if !endTime.IsZero() && date.After(endTime) {
+			continue
+		}
+
+		var key string
+		row := CostReportRow{}
+		switch groupBy {
+		case CostGroupByModel:
+			row.Provider = summary.GetProvider()
+			row.Model = summary.GetModel()
+			key = row.Provider + "\x00" + row.Model
+		case CostGroupByConversation:
+			row.Date = date
+			row.Provider = summary.GetProvider()
+			row.Model = summary.GetModel()
+			row.ConversationID = summary.GetID()
+			key = row.ConversationID
+		default:
+			row.Date = date
+			key = date.Format("2006-01-02")
+		}
+
+		existing, ok := rows[key]
+		if !ok {
+			existing = &row
+			rows[key] = existing
+			keys = append(keys, key)
+		}
+		usage := summary.GetUsage()
+		addUsage(&existing.Usage, usage)
+		existing.Conversations++
+		addUsage(&report.Total, usage)
+		report.TotalConversations++
+	}
+
+	report.Rows = make([]CostReportRow, 0, len(keys))
+	for _, key := range keys {
+		report.Rows = append(report.Rows, *rows[key])
+	}
+	sort.SliceStable(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+		if groupBy == CostGroupByModel {
+			if a.Usage.TotalCost() != b.Usage.TotalCost() {
+				return a.Usage.TotalCost() > b.Usage.TotalCost()
+			}
+			return a.Provider+"/"+a.Model < b.Provider+"/"+b.Model
+		}
+		return a.Date.After(b.Date)
+	})
+	return report
+}
+
+func addUsage(total *llmtypes.Usage, usage llmtypes.Usage) {
+	total.InputTokens += usage.InputTokens
+	total.OutputTokens += usage.OutputTokens
+	total.CacheCreationInputTokens += usage.CacheCreationInputTokens
+	total.CacheReadInputTokens += usage.CacheReadInputTokens
+	total.InputCost += usage.InputCost
+	total.OutputCost += usage.OutputCost
+	total.CacheCreationCost += usage.CacheCreationCost
+	total.CacheReadCost += usage.CacheReadCost
+}
+
 // LogLLMUsage logs detailed LLM usage statistics including tokens, costs, and performance metrics
 func LogLLMUsage(ctx context.Context, usage llmtypes.Usage, model string, startTime time.Time, requestOutputTokens int) {
+	LogLLMUsageWithResources(ctx, usage, nil, model, startTime, requestOutputTokens)
+}
+
+// LogLLMUsageWithResources logs LLM usage statistics together with the resources
+// consumed by tool subprocesses so far in the run.
+func LogLLMUsageWithResources(ctx context.Context, usage llmtypes.Usage, resources *tooltypes.ResourceUsage, model string, startTime time.Time, requestOutputTokens int) {
 	fields := map[string]any{
 		"model":              model,
 		"input_tokens":       usage.InputTokens,
@@ -379,5 +574,12 @@ func LogLLMUsage(ctx context.Context, usage llmtypes.Usage, model string, startT
 		fields["output_tokens/s"] = roundToThreeDecimalPlaces(tokensPerSecond)
 	}
 
+	if resources != nil && resources.Processes > 0 {
+		fields["tool_processes"] = resources.Processes
+		fields["tool_wall_time_s"] = roundToThreeDecimalPlaces(resources.WallTime.Seconds())
+		fields["tool_cpu_time_s"] = roundToThreeDecimalPlaces(resources.CPUTime().Seconds())
+		fields["tool_peak_memory_mb"] = roundToThreeDecimalPlaces(float64(resources.PeakMemoryBytes) / (1024 * 1024))
+	}
+
 	logger.G(ctx).WithFields(fields).Info("Turn completed")
 }
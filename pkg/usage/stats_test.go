@@ -15,6 +15,7 @@ import (
 
 	"github.com/jingkaihe/kodelet/pkg/logger"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
 )
 
 type testSetup struct {
@@ -59,6 +60,7 @@ type testConversationSummary struct {
 	messageCount int
 	usage        llmtypes.Usage
 	provider     string
+	model        string
 }
 
 func (s testConversationSummary) GetID() string            { return s.id }
@@ -67,6 +69,7 @@ func (s testConversationSummary) GetUpdatedAt() time.Time  { return s.updatedAt
 func (s testConversationSummary) GetMessageCount() int     { return s.messageCount }
 func (s testConversationSummary) GetUsage() llmtypes.Usage { return s.usage }
 func (s testConversationSummary) GetProvider() string      { return s.provider }
+func (s testConversationSummary) GetModel() string         { return s.model }
 func testUsage(input, output, cacheWrite, cacheRead int) llmtypes.Usage {
 	return llmtypes.Usage{
 		InputTokens:              input,
@@ -161,6 +164,31 @@ func TestCalculateProviderBreakdownStatsAggregatesByProvider(t *testing.T) {
 	assert.NotContains(t, stats.ProviderStats, "ignored")
 }
 
+func TestCalculateGroupStatsAggregatesUsageAndOutcomes(t *testing.T) {
+	conversations := []GroupedConversation{
+		{Summary: testConversationSummary{messageCount: 4, usage: testUsage(100, 10, 0, 0)}, Group: "trial/treatment", Outcome: ConversationOutcome{HasGoal: true, GoalCompleted: true, Todos: 3, TodosCompleted: 3}},
+		{Summary: testConversationSummary{messageCount: 2, usage: testUsage(300, 30, 0, 0)}, Group: "trial/treatment", Outcome: ConversationOutcome{HasGoal: true, Todos: 2, TodosCompleted: 1}},
+		{Summary: testConversationSummary{messageCount: 6, usage: testUsage(200, 20, 0, 0)}, Group: "trial/control"},
+	}
+
+	stats := CalculateGroupStats(conversations)
+
+	require.Len(t, stats, 2)
+	assert.Equal(t, "trial/control", stats[0].Group)
+	assert.Equal(t, 1, stats[0].Conversations)
+	assert.Equal(t, 0, stats[0].Goals)
+	assert.Equal(t, "trial/treatment", stats[1].Group)
+	assert.Equal(t, 2, stats[1].Conversations)
+	assert.Equal(t, 6, stats[1].Messages)
+	assert.Equal(t, 400, stats[1].Usage.InputTokens)
+	assert.Equal(t, 2, stats[1].Goals)
+	assert.Equal(t, 1, stats[1].GoalsCompleted)
+	assert.Equal(t, 5, stats[1].Todos)
+	assert.Equal(t, 4, stats[1].TodosCompleted)
+	assert.InDelta(t, 0.22, stats[1].AverageCost(), 0.0001)
+	assert.Zero(t, GroupStats{}.AverageCost())
+}
+
 func TestCalculateDailyProviderBreakdownStatsAggregatesAndSorts(t *testing.T) {
 	base := time.Date(2026, 5, 20, 12, 0, 0, 0, time.UTC)
 	summaries := []ConversationSummary{
@@ -187,6 +215,42 @@ func TestCalculateDailyProviderBreakdownStatsAggregatesAndSorts(t *testing.T) {
 	assert.Equal(t, 600, stats.Total.InputTokens)
 }
 
+func TestCalculateCostReportGroupings(t *testing.T) {
+	base := time.Date(2026, 5, 20, 12, 0, 0, 0, time.UTC)
+	summaries := []ConversationSummary{
+		testConversationSummary{id: "a", updatedAt: base, provider: "anthropic", model: "claude-sonnet", usage: testUsage(100, 10, 1, 2)},
+		testConversationSummary{id: "b", updatedAt: base.Add(2 * time.Hour), provider: "openai", model: "gpt-5", usage: testUsage(500, 50, 0, 0)},
+		testConversationSummary{id: "c", updatedAt: base.AddDate(0, 0, 1), provider: "anthropic", model: "claude-sonnet", usage: testUsage(300, 30, 3, 4)},
+		testConversationSummary{id: "old", updatedAt: base.AddDate(0, 0, -5), provider: "openai", model: "gpt-5", usage: testUsage(900, 90, 0, 0)},
+	}
+	since := base.AddDate(0, 0, -1).Truncate(24 * time.Hour)
+
+	byModel := CalculateCostReport(summaries, CostGroupByModel, since, time.Time{})
+	require.Len(t, byModel.Rows, 2)
+	assert.Equal(t, "openai", byModel.Rows[0].Provider, "most expensive model first")
+	assert.Equal(t, "gpt-5", byModel.Rows[0].Model)
+	assert.Equal(t, 1, byModel.Rows[0].Conversations)
+	assert.Equal(t, "claude-sonnet", byModel.Rows[1].Model)
+	assert.Equal(t, 2, byModel.Rows[1].Conversations)
+	assert.Equal(t, 400, byModel.Rows[1].Usage.InputTokens)
+	assert.Equal(t, 4, byModel.Rows[1].Usage.CacheCreationInputTokens)
+	assert.Equal(t, 6, byModel.Rows[1].Usage.CacheReadInputTokens)
+	assert.Equal(t, 900, byModel.Total.InputTokens)
+	assert.Equal(t, 3, byModel.TotalConversations)
+
+	byDay := CalculateCostReport(summaries, CostGroupByDay, since, time.Time{})
+	require.Len(t, byDay.Rows, 2)
+	assert.Equal(t, base.AddDate(0, 0, 1).Truncate(24*time.Hour), byDay.Rows[0].Date)
+	assert.Equal(t, 600, byDay.Rows[1].Usage.InputTokens)
+	assert.Empty(t, byDay.Rows[1].Model)
+
+	byConversation := CalculateCostReport(summaries, CostGroupByConversation, since, time.Time{})
+	require.Len(t, byConversation.Rows, 3)
+	assert.Equal(t, "c", byConversation.Rows[0].ConversationID)
+	assert.Equal(t, []string{"a", "b"}, []string{byConversation.Rows[1].ConversationID, byConversation.Rows[2].ConversationID}, "same-day rows keep their order")
+	assert.Equal(t, "gpt-5", byConversation.Rows[2].Model)
+}
+
 func TestFormatNumberAndCost(t *testing.T) {
 	assert.Equal(t, "999", FormatNumber(999))
 	assert.Equal(t, "1,000", FormatNumber(1000))
@@ -362,6 +426,34 @@ func TestLogLLMUsage_AllFieldsPresent(t *testing.T) {
 	assert.Equal(t, 0.185, logEntry["context_window_usage_ratio"]) // 185/1000
 }
 
+func TestLogLLMUsageWithResources(t *testing.T) {
+	ts := setupTestLogger(logrus.InfoLevel)
+
+	resources := &tooltypes.ResourceUsage{
+		Processes:       3,
+		WallTime:        1500 * time.Millisecond,
+		UserCPUTime:     time.Second,
+		SystemCPUTime:   250 * time.Millisecond,
+		PeakMemoryBytes: 64 * 1024 * 1024,
+	}
+	LogLLMUsageWithResources(ts.ctx, llmtypes.Usage{}, resources, "test-model", time.Now().Add(-1*time.Second), 50)
+
+	logEntry := ts.parseLogEntry(t)
+	assert.Equal(t, float64(3), logEntry["tool_processes"])
+	assert.Equal(t, 1.5, logEntry["tool_wall_time_s"])
+	assert.Equal(t, 1.25, logEntry["tool_cpu_time_s"])
+	assert.Equal(t, float64(64), logEntry["tool_peak_memory_mb"])
+}
+
+func TestLogLLMUsageWithoutToolProcessesOmitsResources(t *testing.T) {
+	ts := setupTestLogger(logrus.InfoLevel)
+
+	LogLLMUsageWithResources(ts.ctx, llmtypes.Usage{}, &tooltypes.ResourceUsage{}, "test-model", time.Now().Add(-1*time.Second), 50)
+
+	logEntry := ts.parseLogEntry(t)
+	assert.NotContains(t, logEntry, "tool_processes")
+}
+
 func TestLogLLMUsage_LargeNumbers(t *testing.T) {
 	ts := setupTestLogger(logrus.InfoLevel)
 
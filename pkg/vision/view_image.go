@@ -132,6 +132,42 @@ func MakeViewImageResult(path string, detail string, model string, provider stri
 		return nil, errors.Wrapf(err, "unable to process image at `%s`: unsupported image `%s`", cleanPath, guessedMime)
 	}
 
+	result, err := makeResult(fileBytes, img, format, normalizedDetail)
+	if err != nil {
+		return nil, err
+	}
+	result.Path = cleanPath
+	result.Assistant = fmt.Sprintf("Viewed image %s (%dx%d, %s)", cleanPath, result.Width, result.Height, result.MimeType)
+	return result, nil
+}
+
+// MakeImageResult validates and preprocesses image bytes produced by a tool,
+// such as a screenshot, the same way view_image processes local files. Detail
+// falls back to the default resized behavior when model does not support
+// original detail.
+func MakeImageResult(data []byte, detail string, model string) (*Result, error) {
+	if len(data) > maxImageFileSize {
+		return nil, errors.Errorf("image too large: %d bytes (max: %d bytes)", len(data), maxImageFileSize)
+	}
+	if strings.TrimSpace(detail) != "original" || !SupportsViewImageOriginalDetail(model) {
+		detail = ""
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decode image")
+	}
+	result, err := makeResult(data, img, format, strings.TrimSpace(detail))
+	if err != nil {
+		return nil, err
+	}
+	result.Assistant = fmt.Sprintf("Image (%dx%d, %s)", result.Width, result.Height, result.MimeType)
+	return result, nil
+}
+
+// makeResult downscales a decoded image to the view_image limits for detail
+// and encodes it as a data URL.
+func makeResult(data []byte, img image.Image, format string, detail string) (*Result, error) {
 	bounds := img.Bounds()
 	originalWidth := bounds.Dx()
 	originalHeight := bounds.Dy()
@@ -143,10 +179,10 @@ func MakeViewImageResult(path string, detail string, model string, provider stri
 		return nil, err
 	}
 
-	outputBytes := fileBytes
+	outputBytes := data
 	outputWidth := originalWidth
 	outputHeight := originalHeight
-	useOriginal := normalizedDetail == "original"
+	useOriginal := detail == "original"
 
 	if useOriginal {
 		outputWidth, outputHeight = viewImageOutputDimensionsForLimits(
@@ -170,17 +206,13 @@ func MakeViewImageResult(path string, detail string, model string, provider stri
 	}
 
 	encoded := base64.StdEncoding.EncodeToString(outputBytes)
-	dataURL := dataURLFromBase64Payload(mimeType, encoded)
-	result := &Result{
-		Path:     cleanPath,
-		ImageURL: dataURL,
+	return &Result{
+		ImageURL: dataURLFromBase64Payload(mimeType, encoded),
 		MimeType: mimeType,
 		Width:    outputWidth,
 		Height:   outputHeight,
-		Detail:   normalizedDetail,
-	}
-	result.Assistant = fmt.Sprintf("Viewed image %s (%dx%d, %s)", cleanPath, result.Width, result.Height, mimeType)
-	return result, nil
+		Detail:   detail,
+	}, nil
 }
 
 // MetadataFromResult converts a processed image result into structured metadata.
@@ -0,0 +1,60 @@
+package webcrawl
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// htmlDocument is what a crawl needs from an HTML page.
+type htmlDocument struct {
+	title string
+	base  string
+	links []string
+}
+
+// parseHTML collects the title, base URL and link targets of an HTML page.
+func parseHTML(body []byte) htmlDocument {
+	var doc htmlDocument
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	inTitle := false
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			doc.title = strings.Join(strings.Fields(doc.title), " ")
+			return doc
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "title":
+				inTitle = doc.title == ""
+			case "base":
+				if href := attribute(token, "href"); href != "" && doc.base == "" {
+					doc.base = href
+				}
+			case "a":
+				if href := attribute(token, "href"); href != "" && !strings.HasPrefix(href, "#") {
+					doc.links = append(doc.links, href)
+				}
+			}
+		case html.EndTagToken:
+			if tokenizer.Token().Data == "title" {
+				inTitle = false
+			}
+		case html.TextToken:
+			if inTitle {
+				doc.title += string(tokenizer.Text())
+			}
+		}
+	}
+}
+
+func attribute(token html.Token, name string) string {
+	for _, attr := range token.Attr {
+		if attr.Key == name {
+			return strings.TrimSpace(attr.Val)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,135 @@
+package webcrawl
+
+import (
+	"bufio"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// robots is the part of a robots.txt that applies to the crawler.
+type robots struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+type robotsRule struct {
+	allow   bool
+	length  int
+	pattern *regexp.Regexp
+}
+
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// parseRobots parses a robots.txt and keeps the rules of the most specific
+// group naming userAgent, or of the "*" group when none does.
+func parseRobots(content, userAgent string) robots {
+	var groups []*robotsGroup
+	var current *robotsGroup
+	inRules := false
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if current == nil || inRules {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+				inRules = false
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if current == nil {
+				continue
+			}
+			inRules = true
+			if value == "" {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{
+				allow:   key == "allow",
+				length:  len(value),
+				pattern: robotsPattern(value),
+			})
+		case "crawl-delay":
+			if current == nil {
+				continue
+			}
+			inRules = true
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	userAgent = strings.ToLower(userAgent)
+	var matched robots
+	bestLength := -1
+	for _, group := range groups {
+		for _, agent := range group.agents {
+			length := -1
+			switch {
+			case agent == "*":
+				length = 0
+			case agent != "" && strings.Contains(userAgent, agent):
+				length = len(agent)
+			}
+			if length < 0 || length < bestLength {
+				continue
+			}
+			if length > bestLength {
+				matched = robots{}
+				bestLength = length
+			}
+			matched.rules = append(matched.rules, group.rules...)
+			matched.crawlDelay = max(matched.crawlDelay, group.crawlDelay)
+			break
+		}
+	}
+	return matched
+}
+
+// robotsPattern compiles a robots.txt path pattern, in which * matches any
+// characters and a trailing $ anchors the end of the path.
+func robotsPattern(value string) *regexp.Regexp {
+	anchored := strings.HasSuffix(value, "$")
+	value = strings.TrimSuffix(value, "$")
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(value), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// allowed reports whether path may be crawled. The longest matching rule
+// wins, and allow wins a tie.
+func (r robots) allowed(path string) bool {
+	allow := true
+	bestLength := -1
+	for _, rule := range r.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if rule.length > bestLength || (rule.length == bestLength && rule.allow) {
+			allow = rule.allow
+			bestLength = rule.length
+		}
+	}
+	return allow
+}
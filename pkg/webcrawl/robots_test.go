@@ -0,0 +1,39 @@
+package webcrawl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRobots(t *testing.T) {
+	content := `# comment
+User-agent: *
+Disallow: /private/
+Crawl-delay: 2
+
+User-agent: googlebot
+User-agent: kodelet
+Disallow: /docs/internal
+Allow: /docs/internal/public
+Disallow: /*.json$
+Crawl-delay: 0.5
+`
+
+	rules := parseRobots(content, UserAgent)
+	assert.Equal(t, 500*time.Millisecond, rules.crawlDelay, "the most specific group applies")
+	assert.True(t, rules.allowed("/private/page"), "rules of the * group do not apply")
+	assert.False(t, rules.allowed("/docs/internal/guide"))
+	assert.True(t, rules.allowed("/docs/internal/public/guide"), "the longest match wins")
+	assert.False(t, rules.allowed("/docs/api.json"))
+	assert.True(t, rules.allowed("/docs/api.json?raw=1"), "$ anchors the end")
+
+	rules = parseRobots(content, "other-bot")
+	assert.Equal(t, 2*time.Second, rules.crawlDelay)
+	assert.False(t, rules.allowed("/private/page"))
+	assert.True(t, rules.allowed("/docs/internal/guide"))
+
+	rules = parseRobots("User-agent: *\nDisallow:\n", UserAgent)
+	assert.True(t, rules.allowed("/anything"), "an empty disallow allows everything")
+}
@@ -0,0 +1,637 @@
+// Package webcrawl fetches a bounded set of pages below a URL, converts them
+// to Markdown and keeps them on disk, so that documentation can be read and
+// searched with file tools instead of being fetched one page at a time.
+// Crawls honour robots.txt, pace their requests and resume where an earlier
+// crawl of the same URL stopped.
+package webcrawl
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultMaxPages is how many pages a crawl fetches when Options.MaxPages
+	// is not set.
+	DefaultMaxPages = 20
+	// DefaultMaxDepth is how many links away from the start URL a crawl goes
+	// by default.
+	DefaultMaxDepth = 2
+	// DefaultDelay is the minimum time between two requests of a crawl.
+	DefaultDelay = time.Second
+	// MaxTotalPages bounds the pages a crawl keeps across resumes.
+	MaxTotalPages = 500
+	// UserAgent identifies crawl requests and selects their robots.txt rules.
+	UserAgent = "kodelet-web-crawl"
+
+	manifestFile  = "manifest.json"
+	indexFile     = "index.md"
+	pagesDir      = "pages"
+	maxPageBytes  = 5 << 20
+	maxCrawlDelay = 30 * time.Second
+	maxRedirects  = 10
+)
+
+// skippedExtensions are links to assets rather than pages.
+var skippedExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".ico": true, ".webp": true,
+	".css": true, ".js": true, ".map": true, ".woff": true, ".woff2": true, ".ttf": true,
+	".pdf": true, ".zip": true, ".gz": true, ".tar": true, ".tgz": true,
+	".mp3": true, ".mp4": true, ".webm": true, ".mov": true,
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Options configures a crawl.
+type Options struct {
+	// StartURL is the first page of the crawl.
+	StartURL string
+	// PathPrefix limits the crawl to paths starting with it. It defaults to
+	// the directory of StartURL.
+	PathPrefix string
+	// MaxPages is how many pages this call fetches.
+	MaxPages int
+	// MaxDepth is how many links away from StartURL the crawl goes. It is
+	// kept from the first call when a crawl resumes.
+	MaxDepth int
+	// Delay is the minimum time between two requests. A longer robots.txt
+	// Crawl-delay takes precedence.
+	Delay time.Duration
+	// Refresh discards an earlier crawl of StartURL and starts over.
+	Refresh bool
+	// Dir is the directory crawls are stored in.
+	Dir string
+	// Client sends the requests. Redirects are only followed to the same host.
+	Client *http.Client
+}
+
+// Page is a page of a crawl.
+type Page struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+	Title string `json:"title,omitempty"`
+	// File is the Markdown file the page is saved in. It is relative to the
+	// crawl directory in the manifest and absolute in a Result.
+	File        string `json:"file,omitempty"`
+	Bytes       int    `json:"bytes,omitempty"`
+	Hash        string `json:"hash,omitempty"`
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+type queuedPage struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+}
+
+// manifest is the state of a crawl, saved after every page so that a crawl
+// can resume.
+type manifest struct {
+	StartURL         string       `json:"start_url"`
+	PathPrefix       string       `json:"path_prefix"`
+	MaxDepth         int          `json:"max_depth"`
+	Pages            []Page       `json:"pages"`
+	Queue            []queuedPage `json:"queue"`
+	RobotsDisallowed int          `json:"robots_disallowed,omitempty"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+}
+
+// Result is the state of a crawl after a call.
+type Result struct {
+	Dir        string
+	StartURL   string
+	PathPrefix string
+	// Pages are every page of the crawl in the order they were fetched.
+	Pages []Page
+	// Fetched is how many pages this call fetched.
+	Fetched int
+	// Resumed is set when the call continued an earlier crawl.
+	Resumed bool
+	// Queued is how many pages were found but not fetched yet.
+	Queued int
+	// RobotsDisallowed counts the pages robots.txt did not allow.
+	RobotsDisallowed int
+	// LimitReached is set when the crawl holds MaxTotalPages pages.
+	LimitReached bool
+}
+
+// DefaultDir returns the directory crawls are stored in under the Kodelet
+// base directory, honouring KODELET_BASE_PATH.
+func DefaultDir() (string, error) {
+	if basePath := strings.TrimSpace(os.Getenv("KODELET_BASE_PATH")); basePath != "" {
+		return filepath.Join(basePath, "web-archives", "crawls"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get home directory")
+	}
+	return filepath.Join(homeDir, ".kodelet", "web-archives", "crawls"), nil
+}
+
+type crawler struct {
+	opts     Options
+	start    *url.URL
+	prefix   string
+	dir      string
+	client   *http.Client
+	manifest *manifest
+	seen     map[string]bool
+	hashes   map[string]string
+	files    map[string]bool
+	robots   robots
+	sleep    func(context.Context, time.Duration) error
+	last     time.Time
+}
+
+// Crawl fetches up to opts.MaxPages pages breadth-first from opts.StartURL,
+// following links within the same host and path prefix, and saves each page
+// as Markdown. The state is saved after every page: a later call with the
+// same start URL and prefix continues with the pages still queued, and a
+// crawl interrupted by ctx keeps what it fetched.
+func Crawl(ctx context.Context, opts Options) (*Result, error) {
+	c, err := newCrawler(opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.run(ctx)
+}
+
+func newCrawler(opts Options) (*crawler, error) {
+	start, err := normalizeURL(opts.StartURL)
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.TrimSpace(opts.PathPrefix)
+	if prefix == "" {
+		prefix = start.Path[:strings.LastIndex(start.Path, "/")+1]
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	if !strings.HasPrefix(start.Path, prefix) && start.Path+"/" != prefix {
+		return nil, errors.Errorf("url %s is outside path_prefix %s", start, prefix)
+	}
+	if opts.MaxPages <= 0 {
+		opts.MaxPages = DefaultMaxPages
+	}
+	if opts.MaxDepth < 0 {
+		opts.MaxDepth = 0
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.Dir == "" {
+		if opts.Dir, err = DefaultDir(); err != nil {
+			return nil, err
+		}
+	}
+
+	client := *opts.Client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if req.URL.Host != start.Host {
+			return errors.Errorf("redirect to different host not allowed: %s -> %s", start.Host, req.URL.Host)
+		}
+		if len(via) >= maxRedirects {
+			return errors.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(start.String() + "\n" + prefix))
+	return &crawler{
+		opts:   opts,
+		start:  start,
+		prefix: prefix,
+		dir:    filepath.Join(opts.Dir, sanitizeName(start.Host), hex.EncodeToString(sum[:6])),
+		client: &client,
+		seen:   map[string]bool{},
+		hashes: map[string]string{},
+		files:  map[string]bool{},
+		sleep:  sleepContext,
+	}, nil
+}
+
+func (c *crawler) run(ctx context.Context) (*Result, error) {
+	if c.opts.Refresh {
+		if err := os.RemoveAll(c.dir); err != nil {
+			return nil, errors.Wrap(err, "failed to remove earlier crawl")
+		}
+	}
+	resumed, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "failed to create crawl directory")
+	}
+
+	if c.robots, err = c.fetchRobots(ctx); err != nil {
+		return nil, err
+	}
+	delay := max(c.opts.Delay, min(c.robots.crawlDelay, maxCrawlDelay))
+
+	fetched := 0
+	var crawlErr error
+	for len(c.manifest.Queue) > 0 && fetched < c.opts.MaxPages && len(c.manifest.Pages) < MaxTotalPages {
+		next := c.manifest.Queue[0]
+		c.manifest.Queue = c.manifest.Queue[1:]
+
+		u, err := url.Parse(next.URL)
+		if err != nil {
+			continue
+		}
+		if !c.robots.allowed(u.RequestURI()) {
+			c.manifest.RobotsDisallowed++
+			continue
+		}
+
+		if !c.last.IsZero() {
+			if err := c.sleep(ctx, delay-time.Since(c.last)); err != nil {
+				c.manifest.Queue = append([]queuedPage{next}, c.manifest.Queue...)
+				crawlErr = err
+				break
+			}
+		}
+		c.last = time.Now()
+
+		page, err := c.fetchPage(ctx, next)
+		if err != nil && ctx.Err() != nil {
+			c.manifest.Queue = append([]queuedPage{next}, c.manifest.Queue...)
+			crawlErr = ctx.Err()
+			break
+		}
+		c.manifest.Pages = append(c.manifest.Pages, page)
+		fetched++
+		if err := c.save(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.save(); err != nil {
+		return nil, err
+	}
+	result := c.result(fetched, resumed)
+	if crawlErr != nil {
+		return result, errors.Wrap(crawlErr, "crawl interrupted")
+	}
+	return result, nil
+}
+
+// load reads the manifest of an earlier crawl, or starts a new one.
+func (c *crawler) load() (bool, error) {
+	data, err := os.ReadFile(filepath.Join(c.dir, manifestFile))
+	if err != nil && !os.IsNotExist(err) {
+		return false, errors.Wrap(err, "failed to read crawl manifest")
+	}
+	if err == nil {
+		var m manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return false, errors.Wrap(err, "failed to decode crawl manifest")
+		}
+		c.manifest = &m
+		for _, page := range m.Pages {
+			c.seen[page.URL] = true
+			if page.Hash != "" && page.DuplicateOf == "" {
+				c.hashes[page.Hash] = page.URL
+			}
+			if page.File != "" {
+				c.files[page.File] = true
+			}
+		}
+		for _, queued := range m.Queue {
+			c.seen[queued.URL] = true
+		}
+		return true, nil
+	}
+
+	c.manifest = &manifest{
+		StartURL:   c.start.String(),
+		PathPrefix: c.prefix,
+		MaxDepth:   c.opts.MaxDepth,
+		Queue:      []queuedPage{{URL: c.start.String()}},
+	}
+	c.seen[c.start.String()] = true
+	return false, nil
+}
+
+// fetchRobots reads the robots.txt of the host. A missing robots.txt allows
+// everything; one that cannot be read stops the crawl.
+func (c *crawler) fetchRobots(ctx context.Context) (robots, error) {
+	robotsURL := url.URL{Scheme: c.start.Scheme, Host: c.start.Host, Path: "/robots.txt"}
+	resp, err := c.get(ctx, robotsURL.String())
+	if err != nil {
+		return robots{}, errors.Wrap(err, "failed to fetch robots.txt")
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes))
+		if err != nil {
+			return robots{}, errors.Wrap(err, "failed to read robots.txt")
+		}
+		return parseRobots(string(body), UserAgent), nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return robots{}, nil
+	default:
+		return robots{}, errors.Errorf("failed to fetch robots.txt: HTTP %d", resp.StatusCode)
+	}
+}
+
+func (c *crawler) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept", "text/html, text/markdown;q=0.9, text/plain;q=0.8")
+	return c.client.Do(req)
+}
+
+// fetchPage fetches a queued page, saves it and queues the links it has.
+// Failures are recorded on the page; the error is only returned so the
+// caller can tell an interrupted crawl apart.
+func (c *crawler) fetchPage(ctx context.Context, queued queuedPage) (Page, error) {
+	page := Page{URL: queued.URL, Depth: queued.Depth}
+	fail := func(err error) (Page, error) {
+		page.Error = err.Error()
+		return page, err
+	}
+
+	resp, err := c.get(ctx, queued.URL)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fail(errors.Errorf("HTTP %d", resp.StatusCode))
+	}
+
+	pageURL := resp.Request.URL
+	if final, err := normalizeURL(pageURL.String()); err == nil && final.String() != queued.URL {
+		if !c.inScope(final) {
+			return fail(errors.Errorf("redirected outside the crawl to %s", final))
+		}
+		if c.seen[final.String()] {
+			page.DuplicateOf = final.String()
+			return page, nil
+		}
+		c.seen[final.String()] = true
+		pageURL = final
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes+1))
+	if err != nil {
+		return fail(err)
+	}
+	if len(body) > maxPageBytes {
+		return fail(errors.Errorf("page is larger than %d bytes", maxPageBytes))
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	var content string
+	switch {
+	case contentType == "text/html" || contentType == "application/xhtml+xml":
+		doc := parseHTML(body)
+		page.Title = doc.title
+		content, err = md.NewConverter(pageURL.Host, true, nil).ConvertString(string(body))
+		if err != nil {
+			return fail(errors.Wrap(err, "failed to convert page to Markdown"))
+		}
+		if queued.Depth < c.manifest.MaxDepth {
+			c.queueLinks(pageURL, doc, queued.Depth+1)
+		}
+	case contentType == "text/markdown" || contentType == "text/plain" || isMarkdownPath(pageURL.Path):
+		content = string(body)
+		page.Title = markdownTitle(content)
+	default:
+		return fail(errors.Errorf("unsupported content type %q", contentType))
+	}
+
+	sum := sha256.Sum256([]byte(content))
+	page.Hash = hex.EncodeToString(sum[:])
+	if original, ok := c.hashes[page.Hash]; ok {
+		page.DuplicateOf = original
+		return page, nil
+	}
+	c.hashes[page.Hash] = page.URL
+
+	page.File = c.fileFor(pageURL)
+	file := filepath.Join(c.dir, page.File)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return fail(errors.Wrap(err, "failed to create page directory"))
+	}
+	header := fmt.Sprintf("<!-- Source: %s -->\n\n", page.URL)
+	if err := os.WriteFile(file, []byte(header+content), 0o644); err != nil {
+		return fail(errors.Wrap(err, "failed to save page"))
+	}
+	page.Bytes = len(content)
+	return page, nil
+}
+
+// queueLinks queues the unseen links of a page that are within the crawl.
+func (c *crawler) queueLinks(pageURL *url.URL, doc htmlDocument, depth int) {
+	base := pageURL
+	if doc.base != "" {
+		if resolved, err := pageURL.Parse(doc.base); err == nil {
+			base = resolved
+		}
+	}
+	for _, href := range doc.links {
+		resolved, err := base.Parse(href)
+		if err != nil {
+			continue
+		}
+		link, err := normalizeURL(resolved.String())
+		if err != nil || !c.inScope(link) || c.seen[link.String()] {
+			continue
+		}
+		c.seen[link.String()] = true
+		if !c.robots.allowed(link.RequestURI()) {
+			c.manifest.RobotsDisallowed++
+			continue
+		}
+		c.manifest.Queue = append(c.manifest.Queue, queuedPage{URL: link.String(), Depth: depth})
+	}
+}
+
+func (c *crawler) inScope(u *url.URL) bool {
+	if u.Scheme != c.start.Scheme || u.Host != c.start.Host {
+		return false
+	}
+	if !strings.HasPrefix(u.Path, c.prefix) && u.Path+"/" != c.prefix {
+		return false
+	}
+	return !skippedExtensions[strings.ToLower(path.Ext(u.Path))]
+}
+
+// fileFor returns the file a page is saved in, mirroring its path below the
+// pages directory.
+func (c *crawler) fileFor(u *url.URL) string {
+	var segments []string
+	for _, segment := range strings.Split(strings.Trim(u.Path, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segment = unescaped
+		}
+		segment = sanitizeName(segment)
+		if segment == "." || segment == ".." {
+			segment = "_"
+		}
+		segments = append(segments, segment)
+	}
+	name := "index"
+	if len(segments) > 0 && !strings.HasSuffix(u.Path, "/") {
+		name = segments[len(segments)-1]
+		segments = segments[:len(segments)-1]
+		for _, ext := range []string{".html", ".htm", ".md", ".markdown"} {
+			name = strings.TrimSuffix(name, ext)
+		}
+	}
+	if u.RawQuery != "" {
+		sum := sha256.Sum256([]byte(u.RawQuery))
+		name += "_" + hex.EncodeToString(sum[:4])
+	}
+
+	dir := path.Join(append([]string{pagesDir}, segments...)...)
+	file := path.Join(dir, name+".md")
+	for i := 2; c.files[file]; i++ {
+		file = path.Join(dir, fmt.Sprintf("%s_%d.md", name, i))
+	}
+	c.files[file] = true
+	return filepath.FromSlash(file)
+}
+
+// save writes the manifest and the index of the crawl.
+func (c *crawler) save() error {
+	c.manifest.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(c.manifest, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode crawl manifest")
+	}
+	if err := writeFileAtomic(filepath.Join(c.dir, manifestFile), append(data, '\n')); err != nil {
+		return errors.Wrap(err, "failed to write crawl manifest")
+	}
+
+	var index bytes.Buffer
+	fmt.Fprintf(&index, "# Crawl of %s\n\nPath prefix: %s\n\n", c.manifest.StartURL, c.manifest.PathPrefix)
+	for _, page := range c.manifest.Pages {
+		if page.File == "" {
+			continue
+		}
+		title := page.Title
+		if title == "" {
+			title = page.URL
+		}
+		fmt.Fprintf(&index, "- [%s](%s): %s\n", title, page.URL, filepath.ToSlash(page.File))
+	}
+	return errors.Wrap(writeFileAtomic(filepath.Join(c.dir, indexFile), index.Bytes()), "failed to write crawl index")
+}
+
+func (c *crawler) result(fetched int, resumed bool) *Result {
+	result := &Result{
+		Dir:              c.dir,
+		StartURL:         c.manifest.StartURL,
+		PathPrefix:       c.manifest.PathPrefix,
+		Fetched:          fetched,
+		Resumed:          resumed,
+		Queued:           len(c.manifest.Queue),
+		RobotsDisallowed: c.manifest.RobotsDisallowed,
+		LimitReached:     len(c.manifest.Pages) >= MaxTotalPages,
+	}
+	for _, page := range c.manifest.Pages {
+		if page.File != "" {
+			page.File = filepath.Join(c.dir, page.File)
+		}
+		result.Pages = append(result.Pages, page)
+	}
+	return result
+}
+
+// normalizeURL parses rawURL for deduplication: the scheme and host are
+// lowercased, default ports and fragments dropped, and an empty path is /.
+func normalizeURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid URL")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "https" && port == "443") || (u.Scheme == "http" && port == "80") {
+		u.Host = u.Hostname()
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.User = nil
+	if u.Path == "" {
+		u.Path = "/"
+		u.RawPath = ""
+	}
+	return u, nil
+}
+
+func isMarkdownPath(p string) bool {
+	ext := strings.ToLower(path.Ext(p))
+	return ext == ".md" || ext == ".markdown"
+}
+
+func markdownTitle(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if title, ok := strings.CutPrefix(strings.TrimSpace(line), "# "); ok {
+			return strings.TrimSpace(title)
+		}
+	}
+	return ""
+}
+
+func sanitizeName(name string) string {
+	name = unsafeNameChars.ReplaceAllString(name, "_")
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+func writeFileAtomic(file string, data []byte) error {
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
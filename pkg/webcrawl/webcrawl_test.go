@@ -0,0 +1,196 @@
+package webcrawl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type docsServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []string
+}
+
+func newDocsServer(t *testing.T) *docsServer {
+	t.Helper()
+	s := &docsServer{}
+	pages := map[string]string{
+		"/docs/":               `<html><head><title>Docs Home</title></head><body><h1>Home</h1><a href="guide">Guide</a> <a href="/docs/api.html#top">API</a> <a href="/blog/">Blog</a> <a href="https://example.com/docs/">External</a> <a href="logo.png">Logo</a> <a href="private/secret">Secret</a></body></html>`,
+		"/docs/guide":          `<html><head><title>Guide</title></head><body><p>Read the <a href="/docs/api.html">API</a> and <a href="deep/one">more</a>.</p><a href="copy">Copy</a></body></html>`,
+		"/docs/api.html":       `<html><head><title>API</title></head><body><p>Endpoints</p><a href="/docs/missing">Missing</a></body></html>`,
+		"/docs/copy":           `<html><head><title>Guide</title></head><body><p>Read the <a href="/docs/api.html">API</a> and <a href="deep/one">more</a>.</p><a href="copy">Copy</a></body></html>`,
+		"/docs/deep/one":       `<html><body><p>Deep</p><a href="two">Two</a></body></html>`,
+		"/docs/deep/two":       `<html><body><p>Too deep</p></body></html>`,
+		"/docs/private/secret": `<html><body>secret</body></html>`,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.URL.Path)
+		s.mu.Unlock()
+		assert.Equal(t, UserAgent, r.Header.Get("User-Agent"))
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nDisallow: /docs/private/\n")
+			return
+		}
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *docsServer) requested(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, requested := range s.requests {
+		if requested == path {
+			count++
+		}
+	}
+	return count
+}
+
+func pageURLs(pages []Page) []string {
+	urls := make([]string, 0, len(pages))
+	for _, page := range pages {
+		urls = append(urls, page.URL)
+	}
+	return urls
+}
+
+func TestCrawlFollowsLinksWithinScope(t *testing.T) {
+	server := newDocsServer(t)
+	dir := t.TempDir()
+
+	result, err := Crawl(context.Background(), Options{
+		StartURL: server.URL + "/docs/",
+		MaxPages: 20,
+		MaxDepth: 2,
+		Dir:      dir,
+	})
+	require.NoError(t, err)
+
+	base := server.URL + "/docs/"
+	assert.Equal(t, []string{base, base + "guide", base + "api.html", base + "deep/one", base + "copy", base + "missing"}, pageURLs(result.Pages))
+	assert.Equal(t, 6, result.Fetched)
+	assert.Zero(t, result.Queued, "pages beyond max depth are not queued")
+	assert.Equal(t, 1, result.RobotsDisallowed)
+	assert.False(t, result.Resumed)
+	assert.Equal(t, 1, server.requested("/docs/api.html"), "links are deduplicated")
+	assert.Zero(t, server.requested("/blog/"), "links outside the path prefix are not followed")
+	assert.Zero(t, server.requested("/docs/private/secret"), "robots.txt is respected")
+	assert.Zero(t, server.requested("/docs/deep/two"))
+
+	home := result.Pages[0]
+	assert.Equal(t, "Docs Home", home.Title)
+	assert.Equal(t, filepath.Join(result.Dir, "pages", "docs", "index.md"), home.File)
+	content, err := os.ReadFile(home.File)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "# Home")
+	assert.Contains(t, string(content), "Source: "+base)
+
+	assert.Equal(t, filepath.Join(result.Dir, "pages", "docs", "api.md"), result.Pages[2].File)
+	assert.Equal(t, base+"guide", result.Pages[4].DuplicateOf, "identical content is kept once")
+	assert.Empty(t, result.Pages[4].File)
+	assert.Equal(t, "HTTP 404", result.Pages[5].Error)
+
+	index, err := os.ReadFile(filepath.Join(result.Dir, "index.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(index), "- [Guide]("+base+"guide): pages/docs/guide.md")
+}
+
+func TestCrawlResumesFromManifest(t *testing.T) {
+	server := newDocsServer(t)
+	dir := t.TempDir()
+	opts := Options{StartURL: server.URL + "/docs/", MaxPages: 2, MaxDepth: 2, Dir: dir}
+
+	first, err := Crawl(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, 2, first.Fetched)
+	assert.Equal(t, 3, first.Queued)
+
+	opts.MaxPages = 20
+	opts.MaxDepth = 0
+	second, err := Crawl(context.Background(), opts)
+	require.NoError(t, err)
+	assert.True(t, second.Resumed)
+	assert.Equal(t, first.Dir, second.Dir)
+	assert.Equal(t, 4, second.Fetched)
+	assert.Len(t, second.Pages, 6, "the depth of the first call is kept")
+	assert.Equal(t, 1, server.requested("/docs/"), "fetched pages are not fetched again")
+
+	opts.Refresh = true
+	third, err := Crawl(context.Background(), opts)
+	require.NoError(t, err)
+	assert.False(t, third.Resumed)
+	assert.Equal(t, []string{server.URL + "/docs/"}, pageURLs(third.Pages))
+}
+
+func TestCrawlPacesRequestsAndStopsOnCancel(t *testing.T) {
+	server := newDocsServer(t)
+	c, err := newCrawler(Options{StartURL: server.URL + "/docs/guide", MaxDepth: 2, Delay: time.Minute, Dir: t.TempDir()})
+	require.NoError(t, err)
+	assert.Equal(t, "/docs/", c.prefix, "the prefix defaults to the directory of the start URL")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var waits []time.Duration
+	c.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		if len(waits) == 2 {
+			cancel()
+			return ctx.Err()
+		}
+		return nil
+	}
+
+	result, err := c.run(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "crawl interrupted")
+	require.Len(t, waits, 2)
+	assert.Greater(t, waits[0], 59*time.Second, "requests wait for the delay")
+	assert.Len(t, result.Pages, 2)
+	assert.Equal(t, 3, result.Queued, "the interrupted page stays queued")
+
+	manifest, err := os.ReadFile(filepath.Join(result.Dir, "manifest.json"))
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(string(manifest), `"queue"`))
+}
+
+func TestNewCrawlerRejectsStartOutsidePrefix(t *testing.T) {
+	_, err := newCrawler(Options{StartURL: "https://example.com/blog/post", PathPrefix: "/docs/", Dir: t.TempDir()})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "outside path_prefix")
+
+	_, err = newCrawler(Options{StartURL: "ftp://example.com/docs/", Dir: t.TempDir()})
+	require.Error(t, err)
+}
+
+func TestNormalizeURL(t *testing.T) {
+	for raw, want := range map[string]string{
+		"HTTPS://Example.COM":             "https://example.com/",
+		"https://example.com:443/a#frag":  "https://example.com/a",
+		"http://localhost:80/a?b=1":       "http://localhost/a?b=1",
+		"https://user:pw@example.com/doc": "https://example.com/doc",
+	} {
+		u, err := normalizeURL(raw)
+		require.NoError(t, err)
+		assert.Equal(t, want, u.String(), raw)
+	}
+}
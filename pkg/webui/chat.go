@@ -154,16 +154,19 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/x-ndjson")
-	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("X-Accel-Buffering", "no")
-
 	conversationID := strings.TrimSpace(req.ConversationID)
 	if conversationID == "" {
 		conversationID = convtypes.GenerateID()
 		req.ConversationID = conversationID
 	}
+	if !s.authorizeDriver(w, r, conversationID) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
 
 	ctx, cancel := context.WithCancel(s.chatExecutionContext(requestCtx))
 	run := newActiveChatRun(cancel)
@@ -218,6 +221,13 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// discardEventSink drops events for runs that have no requesting client.
+type discardEventSink struct{}
+
+func (discardEventSink) Send(ChatEvent) error {
+	return nil
+}
+
 type broadcastingEventSink struct {
 	primary        ChatEventSink
 	broadcast      func(string, ChatEvent)
@@ -0,0 +1,477 @@
+package webui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/sashabaranov/go-openai"
+
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	convtypes "github.com/jingkaihe/kodelet/pkg/types/conversations"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+)
+
+const (
+	// chatCompletionsModel is the model name clients use for the default
+	// profile. "kodelet/<profile>" selects another profile.
+	chatCompletionsModel = "kodelet"
+	// chatCompletionsConversationHeader carries the conversation a chat
+	// completion ran in. Clients send it back to continue that conversation.
+	chatCompletionsConversationHeader = "X-Kodelet-Conversation-Id"
+	// maxChatCompletionsToolInput caps the tool input shown in the content.
+	maxChatCompletionsToolInput = 200
+)
+
+// chatCompletionsError is the error body of the OpenAI API.
+type chatCompletionsError struct {
+	Error chatCompletionsErrorDetail `json:"error"`
+}
+
+type chatCompletionsErrorDetail struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"`
+}
+
+// chatCompletionsModelInfo is a model of the OpenAI models list.
+type chatCompletionsModelInfo struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// handleListModels handles GET /v1/models, listing a model for each profile
+// chat completions can run with.
+func (s *Server) handleListModels(w http.ResponseWriter, _ *http.Request) {
+	var models []chatCompletionsModelInfo
+	for _, profile := range getWebUIProfileOptions() {
+		models = append(models, chatCompletionsModelInfo{
+			ID:      chatCompletionsModelName(profile.Name),
+			Object:  "model",
+			OwnedBy: "kodelet",
+		})
+	}
+
+	s.writeJSONResponse(w, struct {
+		Object string                     `json:"object"`
+		Data   []chatCompletionsModelInfo `json:"data"`
+	}{Object: "list", Data: models})
+}
+
+// handleChatCompletions handles POST /v1/chat/completions. It runs the agent
+// loop, tools included, on the request as a web UI chat turn and returns the
+// reply in the OpenAI chat completions format, streamed as server-sent events
+// when stream is set. Tool calls are reported as assistant content.
+//
+// Each request starts a new conversation whose first message carries the
+// earlier messages of the request. Send the X-Kodelet-Conversation-Id header
+// of a response back to continue that conversation with only the last user
+// message instead.
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	requestCtx := r.Context()
+
+	var req openai.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeChatCompletionsError(w, http.StatusBadRequest, "invalid chat completion request: "+err.Error(), "invalid_request_error", "")
+		return
+	}
+
+	profile, ok := chatCompletionsProfile(req.Model)
+	if !ok {
+		writeChatCompletionsError(w, http.StatusNotFound, fmt.Sprintf("model %q does not exist; use %q or %q", req.Model, chatCompletionsModel, chatCompletionsModel+"/<profile>"), "invalid_request_error", "model_not_found")
+		return
+	}
+
+	conversationID := strings.TrimSpace(r.Header.Get(chatCompletionsConversationHeader))
+	continued := conversationID != ""
+	if !continued {
+		conversationID = convtypes.GenerateID()
+	}
+
+	if continued {
+		if _, _, err := s.collaborationHub().Authorize(conversationID, ""); err != nil {
+			writeChatCompletionsError(w, http.StatusConflict, err.Error(), "invalid_request_error", "conversation_driven")
+			return
+		}
+	}
+
+	chatReq, err := chatCompletionsChatRequest(req.Messages, continued)
+	if err != nil {
+		writeChatCompletionsError(w, http.StatusBadRequest, err.Error(), "invalid_request_error", "")
+		return
+	}
+	chatReq.ConversationID = conversationID
+	chatReq.Profile = profile
+	chatReq.ReasoningEffort = req.ReasoningEffort
+
+	sink := &chatCompletionsSink{
+		id:           "chatcmpl-" + conversationID,
+		model:        chatCompletionsModelName(profile),
+		created:      time.Now().Unix(),
+		includeUsage: req.StreamOptions != nil && req.StreamOptions.IncludeUsage,
+	}
+	if req.Stream {
+		events, err := newSSEWriter(w)
+		if err != nil {
+			writeChatCompletionsError(w, http.StatusInternalServerError, "streaming is not supported", "server_error", "")
+			return
+		}
+		sink.events = events
+	}
+
+	ctx, cancel := context.WithCancel(s.chatExecutionContext(requestCtx))
+	run := newActiveChatRun(cancel)
+	if !s.registerActiveChat(conversationID, run) {
+		cancel()
+		writeChatCompletionsError(w, http.StatusConflict, "conversation already has an active run", "invalid_request_error", "conversation_busy")
+		return
+	}
+	defer s.unregisterActiveChat(conversationID, run)
+	defer s.closeChatSubscribers(conversationID)
+	defer cancel()
+
+	w.Header().Set(chatCompletionsConversationHeader, conversationID)
+	if sink.events != nil {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		if err := sink.start(); err != nil {
+			return
+		}
+	}
+
+	broadcastingSink := &broadcastingEventSink{
+		primary:        sink,
+		broadcast:      s.broadcastChatEvent,
+		conversationID: conversationID,
+	}
+	run.uiInput = newWebUIInputBroker(conversationID, broadcastingSink)
+
+	_, runErr := s.chatRunner.Run(ctx, chatReq, broadcastingSink)
+	if runErr != nil {
+		if stdErrors.Is(runErr, io.ErrClosedPipe) || stdErrors.Is(runErr, context.Canceled) {
+			logger.G(requestCtx).WithError(runErr).Debug("chat completion stream disconnected")
+			return
+		}
+
+		logger.G(ctx).WithError(runErr).Error("chat completion failed")
+		s.broadcastChatEvent(conversationID, ChatEvent{Kind: "error", ConversationID: conversationID, Role: "assistant", Error: runErr.Error()})
+		if sink.events != nil {
+			_ = sink.fail(runErr)
+			return
+		}
+		writeChatCompletionsError(w, http.StatusInternalServerError, runErr.Error(), "server_error", "")
+		return
+	}
+
+	s.broadcastChatEvent(conversationID, ChatEvent{Kind: "done", ConversationID: conversationID, Role: "assistant"})
+	if sink.events != nil {
+		_ = sink.finish()
+		return
+	}
+	s.writeJSONResponse(w, sink.response())
+}
+
+// chatCompletionsProfile returns the profile selected by model, which is
+// "kodelet" for the default profile or "kodelet/<profile>".
+func chatCompletionsProfile(model string) (string, bool) {
+	model = strings.TrimSpace(model)
+	if model == "" || model == chatCompletionsModel {
+		return "", true
+	}
+	profile, found := strings.CutPrefix(model, chatCompletionsModel+"/")
+	if !found || strings.TrimSpace(profile) == "" {
+		return "", false
+	}
+	for _, option := range getWebUIProfileOptions() {
+		if option.Name == profile {
+			if profile == "default" {
+				return "", true
+			}
+			return profile, true
+		}
+	}
+	return "", false
+}
+
+func chatCompletionsModelName(profile string) string {
+	if profile == "" || profile == "default" {
+		return chatCompletionsModel
+	}
+	return chatCompletionsModel + "/" + profile
+}
+
+// chatCompletionsChatRequest turns the messages of a chat completion request
+// into a chat turn. The last message must come from the user. Unless the
+// conversation is continued, where kodelet already has the history, the
+// earlier messages are passed along as a transcript before it.
+func chatCompletionsChatRequest(messages []openai.ChatCompletionMessage, continued bool) (ChatRequest, error) {
+	if len(messages) == 0 {
+		return ChatRequest{}, errors.New("messages must not be empty")
+	}
+	last := messages[len(messages)-1]
+	if last.Role != openai.ChatMessageRoleUser {
+		return ChatRequest{}, errors.Errorf("the last message must have the user role, got %q", last.Role)
+	}
+
+	text, images := chatCompletionsMessageContent(last)
+	if !continued && len(messages) > 1 {
+		var b strings.Builder
+		b.WriteString("The conversation so far, as sent by the client:\n\n<conversation_history>\n")
+		for _, message := range messages[:len(messages)-1] {
+			content, earlierImages := chatCompletionsMessageContent(message)
+			for range earlierImages {
+				content = strings.TrimSpace(content + "\n[image]")
+			}
+			if content == "" {
+				continue
+			}
+			fmt.Fprintf(&b, "### %s\n%s\n\n", chatCompletionsRoleHeading(message.Role), content)
+		}
+		b.WriteString("</conversation_history>\n\n")
+		text = b.String() + text
+	}
+	if strings.TrimSpace(text) == "" && len(images) == 0 {
+		return ChatRequest{}, errors.New("the last user message has no content")
+	}
+
+	if len(images) == 0 {
+		return ChatRequest{Message: text}, nil
+	}
+	content := []ChatContentBlock{{Type: "text", Text: text}}
+	for _, image := range images {
+		content = append(content, ChatContentBlock{Type: "image", ImageURL: &ChatImageURLSource{URL: image}})
+	}
+	return ChatRequest{Content: content}, nil
+}
+
+// chatCompletionsMessageContent returns the text and image URLs of message.
+func chatCompletionsMessageContent(message openai.ChatCompletionMessage) (string, []string) {
+	if len(message.MultiContent) == 0 {
+		return strings.TrimSpace(message.Content), nil
+	}
+	var texts, images []string
+	for _, part := range message.MultiContent {
+		switch part.Type {
+		case openai.ChatMessagePartTypeText:
+			if text := strings.TrimSpace(part.Text); text != "" {
+				texts = append(texts, text)
+			}
+		case openai.ChatMessagePartTypeImageURL:
+			if part.ImageURL != nil && strings.TrimSpace(part.ImageURL.URL) != "" {
+				images = append(images, strings.TrimSpace(part.ImageURL.URL))
+			}
+		}
+	}
+	return strings.Join(texts, "\n\n"), images
+}
+
+func chatCompletionsRoleHeading(role string) string {
+	switch role {
+	case openai.ChatMessageRoleSystem, openai.ChatMessageRoleDeveloper:
+		return "System"
+	case openai.ChatMessageRoleAssistant:
+		return "Assistant"
+	case openai.ChatMessageRoleTool, openai.ChatMessageRoleFunction:
+		return "Tool result"
+	default:
+		return "User"
+	}
+}
+
+// chatCompletionsSink turns the events of a chat turn into a chat completion.
+// With events set, each piece of content is streamed as a chunk; otherwise it
+// is collected for the response.
+type chatCompletionsSink struct {
+	id           string
+	model        string
+	created      int64
+	includeUsage bool
+	events       *sseWriter
+
+	mu        sync.Mutex
+	content   strings.Builder
+	reasoning strings.Builder
+	usage     *llmtypes.Usage
+}
+
+// Send implements ChatEventSink.
+func (s *chatCompletionsSink) Send(event ChatEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch event.Kind {
+	case "text-delta":
+		return s.writeContent(event.Delta, "")
+	case "text":
+		if text, ok := event.Content.(string); ok {
+			return s.writeContent(s.separated(text), "")
+		}
+	case "thinking-delta":
+		return s.writeContent("", event.Delta)
+	case "thinking":
+		if text, ok := event.Content.(string); ok {
+			return s.writeContent("", text)
+		}
+	case "tool-use":
+		return s.writeContent(s.separated(fmt.Sprintf("> `%s` %s", event.ToolName, compactToolInput(event.Input))), "")
+	case "tool-result":
+		if event.ToolResult != nil && !event.ToolResult.Success {
+			return s.writeContent(s.separated(fmt.Sprintf("> `%s` failed: %s", event.ToolName, truncateToolActivity(event.ToolResult.Error))), "")
+		}
+	case "ui-notification":
+		if event.UINotify != nil {
+			return s.writeContent(s.separated(fmt.Sprintf("> %s: %s", event.UINotify.Title, event.UINotify.Message)), "")
+		}
+	case "usage":
+		s.usage = event.Usage
+	}
+	return nil
+}
+
+// separated puts text in its own paragraph after any earlier content.
+func (s *chatCompletionsSink) separated(text string) string {
+	if s.content.Len() == 0 {
+		return text + "\n\n"
+	}
+	if strings.HasSuffix(s.content.String(), "\n\n") {
+		return text + "\n\n"
+	}
+	return "\n\n" + text + "\n\n"
+}
+
+func (s *chatCompletionsSink) writeContent(content, reasoning string) error {
+	if content == "" && reasoning == "" {
+		return nil
+	}
+	s.content.WriteString(content)
+	s.reasoning.WriteString(reasoning)
+	if s.events == nil {
+		return nil
+	}
+	return s.chunk(openai.ChatCompletionStreamChoiceDelta{Content: content, ReasoningContent: reasoning}, "", nil)
+}
+
+func (s *chatCompletionsSink) chunk(delta openai.ChatCompletionStreamChoiceDelta, finishReason openai.FinishReason, usage *openai.Usage) error {
+	return s.events.Data(openai.ChatCompletionStreamResponse{
+		ID:      s.id,
+		Object:  "chat.completion.chunk",
+		Created: s.created,
+		Model:   s.model,
+		Choices: []openai.ChatCompletionStreamChoice{{Delta: delta, FinishReason: finishReason}},
+		Usage:   usage,
+	})
+}
+
+// start sends the first chunk, which carries the assistant role.
+func (s *chatCompletionsSink) start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.chunk(openai.ChatCompletionStreamChoiceDelta{Role: openai.ChatMessageRoleAssistant}, "", nil)
+}
+
+// finish sends the final chunk and ends the stream.
+func (s *chatCompletionsSink) finish() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var usage *openai.Usage
+	if s.includeUsage {
+		usage = s.openAIUsage()
+	}
+	if err := s.chunk(openai.ChatCompletionStreamChoiceDelta{}, openai.FinishReasonStop, usage); err != nil {
+		return err
+	}
+	return s.events.Done()
+}
+
+// fail reports err on the stream and ends it.
+func (s *chatCompletionsSink) fail(err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if writeErr := s.events.Data(chatCompletionsError{Error: chatCompletionsErrorDetail{Message: err.Error(), Type: "server_error"}}); writeErr != nil {
+		return writeErr
+	}
+	return s.events.Done()
+}
+
+// response returns the collected chat completion.
+func (s *chatCompletionsSink) response() openai.ChatCompletionResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	response := openai.ChatCompletionResponse{
+		ID:      s.id,
+		Object:  "chat.completion",
+		Created: s.created,
+		Model:   s.model,
+		Choices: []openai.ChatCompletionChoice{{
+			Message: openai.ChatCompletionMessage{
+				Role:             openai.ChatMessageRoleAssistant,
+				Content:          strings.TrimRight(s.content.String(), "\n"),
+				ReasoningContent: s.reasoning.String(),
+			},
+			FinishReason: openai.FinishReasonStop,
+		}},
+	}
+	if usage := s.openAIUsage(); usage != nil {
+		response.Usage = *usage
+	}
+	return response
+}
+
+func (s *chatCompletionsSink) openAIUsage() *openai.Usage {
+	if s.usage == nil {
+		return nil
+	}
+	prompt := s.usage.InputTokens + s.usage.CacheCreationInputTokens + s.usage.CacheReadInputTokens
+	return &openai.Usage{
+		PromptTokens:        prompt,
+		CompletionTokens:    s.usage.OutputTokens,
+		TotalTokens:         prompt + s.usage.OutputTokens,
+		PromptTokensDetails: &openai.PromptTokensDetails{CachedTokens: s.usage.CacheReadInputTokens},
+	}
+}
+
+// compactToolInput renders the JSON input of a tool call on one line.
+func compactToolInput(input string) string {
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, []byte(input)); err != nil {
+		return truncateToolActivity(input)
+	}
+	return truncateToolActivity(compacted.String())
+}
+
+func truncateToolActivity(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) <= maxChatCompletionsToolInput {
+		return text
+	}
+	cut := maxChatCompletionsToolInput
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "…"
+}
+
+func writeChatCompletionsError(w http.ResponseWriter, statusCode int, message, errorType, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(chatCompletionsError{Error: chatCompletionsErrorDetail{Message: message, Type: errorType, Code: code}}); err != nil {
+		logger.G(context.TODO()).WithError(err).Error("failed to encode chat completion error")
+	}
+}
@@ -0,0 +1,169 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+)
+
+func newChatCompletionsTestServer(runFunc func(context.Context, ChatRequest, ChatEventSink) (string, error)) *Server {
+	return &Server{
+		conversationService: &mockConversationService{},
+		runCtx:              context.Background(),
+		activeChats:         make(map[string]*activeChatRun),
+		chatRunner:          &mockChatRunner{runFunc: runFunc},
+		router:              mux.NewRouter(),
+	}
+}
+
+func runChatCompletionsTestTurn(_ context.Context, req ChatRequest, sink ChatEventSink) (string, error) {
+	events := []ChatEvent{
+		{Kind: "conversation", ConversationID: req.ConversationID},
+		{Kind: "thinking-delta", Delta: "Checking the files"},
+		{Kind: "tool-use", ToolName: "bash", ToolCallID: "call_1", Input: "{\n  \"command\": \"ls\"\n}"},
+		{Kind: "tool-result", ToolName: "bash", ToolCallID: "call_1", ToolResult: &tooltypes.StructuredToolResult{ToolName: "bash", Success: false, Error: "exit status 2"}},
+		{Kind: "text-delta", Delta: "The directory "},
+		{Kind: "text-delta", Delta: "is empty."},
+		{Kind: "usage", Usage: &llmtypes.Usage{InputTokens: 10, CacheReadInputTokens: 5, OutputTokens: 7}},
+	}
+	for _, event := range events {
+		if err := sink.Send(event); err != nil {
+			return "", err
+		}
+	}
+	return req.ConversationID, nil
+}
+
+func TestHandleChatCompletions(t *testing.T) {
+	var captured ChatRequest
+	server := newChatCompletionsTestServer(func(ctx context.Context, req ChatRequest, sink ChatEventSink) (string, error) {
+		captured = req
+		return runChatCompletionsTestTurn(ctx, req, sink)
+	})
+
+	body := `{"model":"kodelet","messages":[{"role":"system","content":"Be terse."},{"role":"user","content":"What is in the directory?"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	server.handleChatCompletions(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	conversationID := w.Header().Get(chatCompletionsConversationHeader)
+	require.NotEmpty(t, conversationID)
+	assert.Equal(t, conversationID, captured.ConversationID)
+	assert.Contains(t, captured.Message, "<conversation_history>\n### System\nBe terse.")
+	assert.True(t, strings.HasSuffix(captured.Message, "</conversation_history>\n\nWhat is in the directory?"))
+
+	var response openai.ChatCompletionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "chatcmpl-"+conversationID, response.ID)
+	assert.Equal(t, "chat.completion", response.Object)
+	assert.Equal(t, "kodelet", response.Model)
+	require.Len(t, response.Choices, 1)
+	assert.Equal(t, openai.FinishReasonStop, response.Choices[0].FinishReason)
+	assert.Equal(t, "> `bash` {\"command\":\"ls\"}\n\n> `bash` failed: exit status 2\n\nThe directory is empty.", response.Choices[0].Message.Content)
+	assert.Equal(t, "Checking the files", response.Choices[0].Message.ReasoningContent)
+	assert.Equal(t, 15, response.Usage.PromptTokens)
+	assert.Equal(t, 7, response.Usage.CompletionTokens)
+	assert.Equal(t, 22, response.Usage.TotalTokens)
+}
+
+func TestHandleChatCompletionsStream(t *testing.T) {
+	var captured ChatRequest
+	server := newChatCompletionsTestServer(func(ctx context.Context, req ChatRequest, sink ChatEventSink) (string, error) {
+		captured = req
+		return runChatCompletionsTestTurn(ctx, req, sink)
+	})
+
+	body := `{"model":"kodelet","stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":"earlier"},{"role":"assistant","content":"reply"},{"role":"user","content":[{"type":"text","text":"What is in the directory?"}]}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set(chatCompletionsConversationHeader, "conv-continued")
+	w := httptest.NewRecorder()
+	server.handleChatCompletions(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, "conv-continued", w.Header().Get(chatCompletionsConversationHeader))
+	assert.Equal(t, "conv-continued", captured.ConversationID)
+	assert.Equal(t, "What is in the directory?", captured.Message, "a continued conversation only gets the last message")
+
+	events := strings.Split(strings.TrimSpace(w.Body.String()), "\n\n")
+	require.Greater(t, len(events), 2)
+	assert.Equal(t, "data: [DONE]", events[len(events)-1])
+
+	var chunks []openai.ChatCompletionStreamResponse
+	for _, event := range events[:len(events)-1] {
+		payload, found := strings.CutPrefix(event, "data: ")
+		require.True(t, found, event)
+		var chunk openai.ChatCompletionStreamResponse
+		require.NoError(t, json.Unmarshal([]byte(payload), &chunk))
+		assert.Equal(t, "chat.completion.chunk", chunk.Object)
+		chunks = append(chunks, chunk)
+	}
+
+	assert.Equal(t, openai.ChatMessageRoleAssistant, chunks[0].Choices[0].Delta.Role)
+	var content strings.Builder
+	for _, chunk := range chunks {
+		content.WriteString(chunk.Choices[0].Delta.Content)
+	}
+	assert.Contains(t, content.String(), "The directory is empty.")
+
+	last := chunks[len(chunks)-1]
+	assert.Equal(t, openai.FinishReasonStop, last.Choices[0].FinishReason)
+	require.NotNil(t, last.Usage)
+	assert.Equal(t, 22, last.Usage.TotalTokens)
+}
+
+func TestHandleChatCompletionsRejectsInvalidRequests(t *testing.T) {
+	server := newChatCompletionsTestServer(func(context.Context, ChatRequest, ChatEventSink) (string, error) {
+		t.Fatal("the chat runner must not be called")
+		return "", nil
+	})
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		error  string
+	}{
+		{name: "unknown model", body: `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`, status: http.StatusNotFound, error: `model "gpt-4o" does not exist`},
+		{name: "no messages", body: `{"model":"kodelet","messages":[]}`, status: http.StatusBadRequest, error: "messages must not be empty"},
+		{name: "last message from assistant", body: `{"messages":[{"role":"assistant","content":"hi"}]}`, status: http.StatusBadRequest, error: `the last message must have the user role, got "assistant"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			server.handleChatCompletions(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			var response chatCompletionsError
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Contains(t, response.Error.Message, tt.error)
+		})
+	}
+}
+
+func TestChatCompletionsChatRequestWithImages(t *testing.T) {
+	req, err := chatCompletionsChatRequest([]openai.ChatCompletionMessage{{
+		Role: openai.ChatMessageRoleUser,
+		MultiContent: []openai.ChatMessagePart{
+			{Type: openai.ChatMessagePartTypeText, Text: "What is this?"},
+			{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{URL: "https://example.com/a.png"}},
+		},
+	}}, false)
+	require.NoError(t, err)
+	require.Len(t, req.Content, 2)
+	assert.Equal(t, ChatContentBlock{Type: "text", Text: "What is this?"}, req.Content[0])
+	assert.Equal(t, "https://example.com/a.png", req.Content[1].ImageURL.URL)
+}
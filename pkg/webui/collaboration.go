@@ -0,0 +1,546 @@
+package webui
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	chat "github.com/jingkaihe/kodelet/pkg/chat"
+)
+
+// collaborationClientHeader carries the secret the server issued to a
+// browser tab when it joined a conversation. Every client authenticates with
+// the same server token, so the secret is what tells the driver apart from
+// the viewers. Collaborators only ever see the public ID derived from it.
+const collaborationClientHeader = "X-Kodelet-Client-Secret"
+
+var (
+	// collaborationPresenceTTL is how long a client is listed as watching a
+	// conversation after its last heartbeat. A driver that stops sending
+	// heartbeats for this long gives up control.
+	collaborationPresenceTTL = 30 * time.Second
+	// collaborationTakeoverTimeout is how long the driver has to answer a
+	// takeover request before it is granted, so an unattended session can
+	// always be taken over.
+	collaborationTakeoverTimeout = 20 * time.Second
+)
+
+var (
+	errTakeoverPending            = errors.New("another takeover request is pending")
+	errUnknownCollaborationClient = errors.New("unknown collaboration client; rejoin the conversation")
+)
+
+type (
+	collaborationState    = chat.CollaborationEvent
+	collaborationClient   = chat.CollaborationClient
+	collaborationTakeover = chat.CollaborationTakeover
+)
+
+// collaborationDriverError rejects a request from a client that is not the
+// driver of the conversation.
+type collaborationDriverError struct {
+	Driver collaborationClient
+}
+
+func (e *collaborationDriverError) Error() string {
+	name := e.Driver.Name
+	if name == "" {
+		name = "another client"
+	}
+	return fmt.Sprintf("the conversation is driven by %s; request a takeover to take control", name)
+}
+
+type collaborationSession struct {
+	driverID string
+	clients  map[string]*collaborationClient
+	takeover *collaborationTakeover
+}
+
+// collaborationHub tracks the viewers and the driver of each conversation
+// open in the web UI. Stale clients and unanswered takeovers are resolved
+// whenever a session is accessed, so no background work is needed.
+type collaborationHub struct {
+	mu       sync.Mutex
+	sessions map[string]*collaborationSession
+	now      func() time.Time
+	// key signs the client secrets the hub issues, so it can recognise them
+	// without remembering every client.
+	key []byte
+}
+
+func newCollaborationHub() *collaborationHub {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(errors.Wrap(err, "failed to generate collaboration key"))
+	}
+	return &collaborationHub{
+		sessions: make(map[string]*collaborationSession),
+		now:      time.Now,
+		key:      key,
+	}
+}
+
+// Issue returns a new client secret and the public ID derived from it.
+func (h *collaborationHub) Issue() (secret, id string) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		panic(errors.Wrap(err, "failed to generate collaboration client secret"))
+	}
+	secret = hex.EncodeToString(nonce) + "." + h.sign(nonce)
+	id, _ = h.Identify(secret)
+	return secret, id
+}
+
+// Identify returns the public ID of a secret the hub issued. The ID is a
+// hash of the secret, so broadcasting it does not let another client act as
+// its owner.
+func (h *collaborationHub) Identify(secret string) (string, bool) {
+	encodedNonce, signature, ok := strings.Cut(secret, ".")
+	nonce, err := hex.DecodeString(encodedNonce)
+	if !ok || err != nil || !hmac.Equal([]byte(signature), []byte(h.sign(nonce))) {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return "client-" + hex.EncodeToString(sum[:6]), true
+}
+
+func (h *collaborationHub) sign(nonce []byte) string {
+	mac := hmac.New(sha256.New, h.key)
+	mac.Write(nonce)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// session returns the session of conversationID after expiring stale clients
+// and granting an unanswered takeover. Callers hold h.mu.
+func (h *collaborationHub) session(conversationID string) *collaborationSession {
+	session := h.sessions[conversationID]
+	if session == nil {
+		session = &collaborationSession{clients: make(map[string]*collaborationClient)}
+		h.sessions[conversationID] = session
+	}
+
+	now := h.now()
+	for id, client := range session.clients {
+		if now.Sub(client.LastSeen) > collaborationPresenceTTL {
+			delete(session.clients, id)
+		}
+	}
+	if session.takeover != nil && session.clients[session.takeover.ClientID] == nil {
+		session.takeover = nil
+	}
+	if session.driverID != "" && session.clients[session.driverID] == nil {
+		session.driverID = ""
+	}
+	if session.takeover != nil && (session.driverID == "" || !now.Before(session.takeover.GrantedAt)) {
+		session.driverID = session.takeover.ClientID
+		session.takeover = nil
+	}
+	return session
+}
+
+// touch records a heartbeat of clientID. Callers hold h.mu.
+func (h *collaborationHub) touch(session *collaborationSession, clientID, name string) {
+	client := session.clients[clientID]
+	if client == nil {
+		client = &collaborationClient{ID: clientID}
+		session.clients[clientID] = client
+	}
+	if name = strings.TrimSpace(name); name != "" {
+		client.Name = name
+	}
+	client.LastSeen = h.now()
+}
+
+// state snapshots a session. Callers hold h.mu.
+func (h *collaborationHub) state(conversationID string, session *collaborationSession) collaborationState {
+	state := collaborationState{ConversationID: conversationID, Viewers: []collaborationClient{}}
+	for id, client := range session.clients {
+		if id == session.driverID {
+			driver := *client
+			state.Driver = &driver
+			continue
+		}
+		state.Viewers = append(state.Viewers, *client)
+	}
+	slices.SortFunc(state.Viewers, func(a, b collaborationClient) int { return strings.Compare(a.ID, b.ID) })
+	if session.takeover != nil {
+		takeover := *session.takeover
+		state.Takeover = &takeover
+	}
+	return state
+}
+
+// cleanup forgets a session nobody watches any more. Callers hold h.mu.
+func (h *collaborationHub) cleanup(conversationID string, session *collaborationSession) {
+	if len(session.clients) == 0 {
+		delete(h.sessions, conversationID)
+	}
+}
+
+// State returns who watches and drives conversationID.
+func (h *collaborationHub) State(conversationID string) collaborationState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session := h.session(conversationID)
+	defer h.cleanup(conversationID, session)
+	return h.state(conversationID, session)
+}
+
+// Heartbeat records that clientID is watching conversationID.
+func (h *collaborationHub) Heartbeat(conversationID, clientID, name string) collaborationState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session := h.session(conversationID)
+	h.touch(session, clientID, name)
+	return h.state(conversationID, session)
+}
+
+// Leave removes clientID from conversationID. A leaving driver hands control
+// to a pending takeover requester.
+func (h *collaborationHub) Leave(conversationID, clientID string) collaborationState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session := h.session(conversationID)
+	delete(session.clients, clientID)
+	session = h.session(conversationID)
+	defer h.cleanup(conversationID, session)
+	return h.state(conversationID, session)
+}
+
+// Authorize checks that clientID may drive conversationID. A client claims a
+// conversation nobody drives; requests without a client ID, such as API
+// calls, are allowed only while nobody drives it.
+func (h *collaborationHub) Authorize(conversationID, clientID string) (collaborationState, bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session := h.session(conversationID)
+	defer h.cleanup(conversationID, session)
+	if session.driverID != "" && session.driverID != clientID {
+		return collaborationState{}, false, &collaborationDriverError{Driver: *session.clients[session.driverID]}
+	}
+	if clientID == "" {
+		return h.state(conversationID, session), false, nil
+	}
+
+	claimed := session.driverID == ""
+	h.touch(session, clientID, "")
+	session.driverID = clientID
+	return h.state(conversationID, session), claimed, nil
+}
+
+// RequestTakeover asks the driver to hand conversationID to clientID. The
+// request is granted at once when nobody drives the conversation.
+func (h *collaborationHub) RequestTakeover(conversationID, clientID, name string) (collaborationState, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session := h.session(conversationID)
+	h.touch(session, clientID, name)
+	switch {
+	case session.driverID == "" || session.driverID == clientID:
+		session.driverID = clientID
+		if session.takeover != nil && session.takeover.ClientID == clientID {
+			session.takeover = nil
+		}
+	case session.takeover != nil && session.takeover.ClientID != clientID:
+		return h.state(conversationID, session), errTakeoverPending
+	case session.takeover == nil:
+		now := h.now()
+		session.takeover = &collaborationTakeover{
+			ClientID:    clientID,
+			Name:        session.clients[clientID].Name,
+			RequestedAt: now,
+			GrantedAt:   now.Add(collaborationTakeoverTimeout),
+		}
+	}
+	return h.state(conversationID, session), nil
+}
+
+// RespondTakeover lets the driver accept or deny the pending takeover.
+func (h *collaborationHub) RespondTakeover(conversationID, clientID string, accept bool) (collaborationState, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session := h.session(conversationID)
+	if session.driverID != clientID {
+		return h.state(conversationID, session), errors.New("only the driver can answer a takeover request")
+	}
+	h.touch(session, clientID, "")
+	if session.takeover == nil {
+		return h.state(conversationID, session), errors.New("no takeover request is pending")
+	}
+	if accept {
+		session.driverID = session.takeover.ClientID
+	}
+	session.takeover = nil
+	return h.state(conversationID, session), nil
+}
+
+// Release gives up control of conversationID, handing it to a pending
+// takeover requester if there is one.
+func (h *collaborationHub) Release(conversationID, clientID string) (collaborationState, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session := h.session(conversationID)
+	if session.driverID != clientID {
+		return h.state(conversationID, session), errors.New("only the driver can release control")
+	}
+	h.touch(session, clientID, "")
+	session.driverID = ""
+	if session.takeover != nil {
+		session.driverID = session.takeover.ClientID
+		session.takeover = nil
+	}
+	return h.state(conversationID, session), nil
+}
+
+func (s *Server) collaborationHub() *collaborationHub {
+	s.collaborationOnce.Do(func() {
+		if s.collaboration == nil {
+			s.collaboration = newCollaborationHub()
+		}
+	})
+	return s.collaboration
+}
+
+// collaborationClientSecret returns the client secret a request carries.
+// Event streams, which cannot always set headers, may pass it as
+// client_secret.
+func collaborationClientSecret(r *http.Request) string {
+	if secret := strings.TrimSpace(r.Header.Get(collaborationClientHeader)); secret != "" {
+		return secret
+	}
+	return strings.TrimSpace(r.URL.Query().Get("client_secret"))
+}
+
+// collaborationClientID returns the public ID of the client a request comes
+// from, or "" when it carries no secret. It reports false when the secret was
+// not issued by this server.
+func (s *Server) collaborationClientID(r *http.Request) (string, bool) {
+	secret := collaborationClientSecret(r)
+	if secret == "" {
+		return "", true
+	}
+	return s.collaborationHub().Identify(secret)
+}
+
+// broadcastCollaboration tells the stream subscribers of a conversation who
+// watches and drives it.
+func (s *Server) broadcastCollaboration(state collaborationState) {
+	s.broadcastChatEvent(state.ConversationID, ChatEvent{
+		Kind:           "collaboration",
+		ConversationID: state.ConversationID,
+		Collaboration:  &state,
+	})
+}
+
+// authorizeDriver rejects a request that would act on conversationID from a
+// client that does not drive it, and reports a new driver to the viewers.
+func (s *Server) authorizeDriver(w http.ResponseWriter, r *http.Request, conversationID string) bool {
+	clientID, ok := s.collaborationClientID(r)
+	if !ok {
+		s.writeErrorResponse(w, http.StatusUnauthorized, errUnknownCollaborationClient.Error(), nil)
+		return false
+	}
+	state, claimed, err := s.collaborationHub().Authorize(conversationID, clientID)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusConflict, err.Error(), nil)
+		return false
+	}
+	if claimed {
+		s.broadcastCollaboration(state)
+	}
+	return true
+}
+
+type collaborationPresenceRequest struct {
+	Name string `json:"name,omitempty"`
+}
+
+type collaborationTakeoverResponseRequest struct {
+	Accept bool `json:"accept"`
+}
+
+// collaborationPresenceResponse answers a heartbeat with the state of the
+// conversation and the credentials of the client, which are issued on the
+// first heartbeat and whenever the client's secret is not recognised.
+type collaborationPresenceResponse struct {
+	collaborationState
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+// collaborationRequestTarget returns the conversation and client of a
+// collaboration request, writing an error when either is missing.
+func (s *Server) collaborationRequestTarget(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	conversationID := strings.TrimSpace(mux.Vars(r)["id"])
+	if conversationID == "" {
+		s.writeErrorResponse(w, http.StatusBadRequest, "conversation ID is required", nil)
+		return "", "", false
+	}
+	clientID, ok := s.collaborationClientID(r)
+	switch {
+	case !ok:
+		s.writeErrorResponse(w, http.StatusUnauthorized, errUnknownCollaborationClient.Error(), nil)
+		return "", "", false
+	case clientID == "":
+		s.writeErrorResponse(w, http.StatusBadRequest, collaborationClientHeader+" header is required", nil)
+		return "", "", false
+	}
+	return conversationID, clientID, true
+}
+
+func decodeOptionalJSON(r *http.Request, v any) error {
+	if r.Body == nil || r.ContentLength == 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// handleGetCollaboration handles GET /api/conversations/{id}/collaboration
+func (s *Server) handleGetCollaboration(w http.ResponseWriter, r *http.Request) {
+	conversationID := strings.TrimSpace(mux.Vars(r)["id"])
+	if conversationID == "" {
+		s.writeErrorResponse(w, http.StatusBadRequest, "conversation ID is required", nil)
+		return
+	}
+	s.writeJSONResponse(w, s.collaborationHub().State(conversationID))
+}
+
+// handleCollaborationHeartbeat handles POST /api/conversations/{id}/collaboration/presence.
+// Clients send it periodically while the conversation is open. A client
+// without a secret the server recognises joins with a new one.
+func (s *Server) handleCollaborationHeartbeat(w http.ResponseWriter, r *http.Request) {
+	conversationID := strings.TrimSpace(mux.Vars(r)["id"])
+	if conversationID == "" {
+		s.writeErrorResponse(w, http.StatusBadRequest, "conversation ID is required", nil)
+		return
+	}
+	hub := s.collaborationHub()
+	secret := collaborationClientSecret(r)
+	clientID, ok := hub.Identify(secret)
+	if !ok {
+		secret, clientID = hub.Issue()
+	}
+	var req collaborationPresenceRequest
+	if err := decodeOptionalJSON(r, &req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "invalid presence request", err)
+		return
+	}
+
+	before := hub.State(conversationID)
+	state := hub.Heartbeat(conversationID, clientID, req.Name)
+	if collaborationMembershipChanged(before, state) {
+		s.broadcastCollaboration(state)
+	}
+	s.writeJSONResponse(w, collaborationPresenceResponse{
+		collaborationState: state,
+		ClientID:           clientID,
+		ClientSecret:       secret,
+	})
+}
+
+// handleCollaborationLeave handles DELETE /api/conversations/{id}/collaboration/presence
+func (s *Server) handleCollaborationLeave(w http.ResponseWriter, r *http.Request) {
+	conversationID, clientID, ok := s.collaborationRequestTarget(w, r)
+	if !ok {
+		return
+	}
+	state := s.collaborationHub().Leave(conversationID, clientID)
+	s.broadcastCollaboration(state)
+	s.writeJSONResponse(w, state)
+}
+
+// handleCollaborationTakeover handles POST /api/conversations/{id}/collaboration/takeover
+func (s *Server) handleCollaborationTakeover(w http.ResponseWriter, r *http.Request) {
+	conversationID, clientID, ok := s.collaborationRequestTarget(w, r)
+	if !ok {
+		return
+	}
+	var req collaborationPresenceRequest
+	if err := decodeOptionalJSON(r, &req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "invalid takeover request", err)
+		return
+	}
+
+	state, err := s.collaborationHub().RequestTakeover(conversationID, clientID, req.Name)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusConflict, err.Error(), nil)
+		return
+	}
+	s.broadcastCollaboration(state)
+	s.writeJSONResponse(w, state)
+}
+
+// handleCollaborationTakeoverResponse handles POST /api/conversations/{id}/collaboration/takeover/respond
+func (s *Server) handleCollaborationTakeoverResponse(w http.ResponseWriter, r *http.Request) {
+	conversationID, clientID, ok := s.collaborationRequestTarget(w, r)
+	if !ok {
+		return
+	}
+	var req collaborationTakeoverResponseRequest
+	if err := decodeOptionalJSON(r, &req); err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "invalid takeover response", err)
+		return
+	}
+
+	state, err := s.collaborationHub().RespondTakeover(conversationID, clientID, req.Accept)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusConflict, err.Error(), nil)
+		return
+	}
+	s.broadcastCollaboration(state)
+	s.writeJSONResponse(w, state)
+}
+
+// handleCollaborationRelease handles POST /api/conversations/{id}/collaboration/release
+func (s *Server) handleCollaborationRelease(w http.ResponseWriter, r *http.Request) {
+	conversationID, clientID, ok := s.collaborationRequestTarget(w, r)
+	if !ok {
+		return
+	}
+	state, err := s.collaborationHub().Release(conversationID, clientID)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusConflict, err.Error(), nil)
+		return
+	}
+	s.broadcastCollaboration(state)
+	s.writeJSONResponse(w, state)
+}
+
+// collaborationMembershipChanged reports whether a heartbeat changed who
+// watches or drives a conversation, which viewers need to hear about.
+func collaborationMembershipChanged(before, after collaborationState) bool {
+	ids := func(state collaborationState) []string {
+		var ids []string
+		if state.Driver != nil {
+			ids = append(ids, "driver:"+state.Driver.ID+":"+state.Driver.Name)
+		}
+		for _, viewer := range state.Viewers {
+			ids = append(ids, viewer.ID+":"+viewer.Name)
+		}
+		if state.Takeover != nil {
+			ids = append(ids, "takeover:"+state.Takeover.ClientID)
+		}
+		return ids
+	}
+	return !slices.Equal(ids(before), ids(after))
+}
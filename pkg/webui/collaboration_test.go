@@ -0,0 +1,231 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCollaborationHub(now *time.Time) *collaborationHub {
+	hub := newCollaborationHub()
+	hub.now = func() time.Time { return *now }
+	return hub
+}
+
+func TestCollaborationHubFirstClientDrives(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	hub := newTestCollaborationHub(&now)
+
+	hub.Heartbeat("conv", "alice", "Alice")
+	hub.Heartbeat("conv", "bob", "Bob")
+
+	state, claimed, err := hub.Authorize("conv", "alice")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	require.NotNil(t, state.Driver)
+	assert.Equal(t, "Alice", state.Driver.Name)
+	assert.Equal(t, []string{"bob"}, viewerIDs(state))
+
+	_, _, err = hub.Authorize("conv", "bob")
+	var driverErr *collaborationDriverError
+	require.ErrorAs(t, err, &driverErr)
+	assert.Contains(t, err.Error(), "driven by Alice")
+
+	_, _, err = hub.Authorize("conv", "")
+	require.Error(t, err, "API requests must not act while a client drives")
+
+	_, claimed, err = hub.Authorize("conv", "alice")
+	require.NoError(t, err)
+	assert.False(t, claimed)
+}
+
+func TestCollaborationHubTakeoverHandshake(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	hub := newTestCollaborationHub(&now)
+	_, _, err := hub.Authorize("conv", "alice")
+	require.NoError(t, err)
+
+	state, err := hub.RequestTakeover("conv", "bob", "Bob")
+	require.NoError(t, err)
+	require.NotNil(t, state.Takeover)
+	assert.Equal(t, "bob", state.Takeover.ClientID)
+	assert.Equal(t, "alice", state.Driver.ID)
+
+	_, err = hub.RequestTakeover("conv", "carol", "Carol")
+	require.ErrorIs(t, err, errTakeoverPending)
+
+	_, err = hub.RespondTakeover("conv", "bob", true)
+	require.Error(t, err, "only the driver answers takeovers")
+
+	state, err = hub.RespondTakeover("conv", "alice", false)
+	require.NoError(t, err)
+	assert.Nil(t, state.Takeover)
+	assert.Equal(t, "alice", state.Driver.ID)
+
+	_, err = hub.RequestTakeover("conv", "bob", "")
+	require.NoError(t, err)
+	state, err = hub.RespondTakeover("conv", "alice", true)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", state.Driver.ID)
+	assert.Equal(t, "Bob", state.Driver.Name)
+	assert.ElementsMatch(t, []string{"alice", "carol"}, viewerIDs(state))
+}
+
+func TestCollaborationHubGrantsUnansweredTakeover(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	hub := newTestCollaborationHub(&now)
+	_, _, err := hub.Authorize("conv", "alice")
+	require.NoError(t, err)
+	_, err = hub.RequestTakeover("conv", "bob", "Bob")
+	require.NoError(t, err)
+
+	now = now.Add(collaborationTakeoverTimeout / 2)
+	hub.Heartbeat("conv", "alice", "")
+	state := hub.Heartbeat("conv", "bob", "")
+	assert.Equal(t, "alice", state.Driver.ID)
+
+	now = now.Add(collaborationTakeoverTimeout)
+	state = hub.Heartbeat("conv", "bob", "")
+	assert.Equal(t, "bob", state.Driver.ID)
+	assert.Nil(t, state.Takeover)
+}
+
+func TestCollaborationHubExpiresAbsentDriver(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	hub := newTestCollaborationHub(&now)
+	_, _, err := hub.Authorize("conv", "alice")
+	require.NoError(t, err)
+
+	now = now.Add(collaborationPresenceTTL / 2)
+	hub.Heartbeat("conv", "bob", "")
+	now = now.Add(collaborationPresenceTTL)
+
+	state, claimed, err := hub.Authorize("conv", "bob")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	assert.Equal(t, "bob", state.Driver.ID)
+	assert.Empty(t, state.Viewers)
+}
+
+func TestCollaborationHubLeaveAndRelease(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	hub := newTestCollaborationHub(&now)
+	_, _, err := hub.Authorize("conv", "alice")
+	require.NoError(t, err)
+	_, err = hub.RequestTakeover("conv", "bob", "")
+	require.NoError(t, err)
+
+	state := hub.Leave("conv", "alice")
+	assert.Equal(t, "bob", state.Driver.ID, "a leaving driver hands over to the requester")
+
+	state, err = hub.Release("conv", "bob")
+	require.NoError(t, err)
+	assert.Nil(t, state.Driver)
+
+	hub.Leave("conv", "bob")
+	assert.Empty(t, hub.sessions)
+}
+
+func TestServerRejectsRequestsFromNonDrivers(t *testing.T) {
+	server := &Server{
+		conversationService: &mockConversationService{},
+		router:              mux.NewRouter(),
+		activeChats:         make(map[string]*activeChatRun),
+	}
+	server.activeChats["conv-123"] = newActiveChatRun(func() {})
+	driverSecret, driverID := server.collaborationHub().Issue()
+	viewerSecret, viewerID := server.collaborationHub().Issue()
+	_, _, err := server.collaborationHub().Authorize("conv-123", driverID)
+	require.NoError(t, err)
+
+	stop := func(secret string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/conversations/conv-123/stop", nil)
+		req.Header.Set(collaborationClientHeader, secret)
+		req = mux.SetURLVars(req, map[string]string{"id": "conv-123"})
+		w := httptest.NewRecorder()
+		server.handleStopConversation(w, req)
+		return w
+	}
+
+	w := stop(viewerSecret)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.False(t, server.activeChats["conv-123"].stopRequested)
+
+	w = stop(driverID)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "the broadcast ID of the driver is not a credential")
+	assert.False(t, server.activeChats["conv-123"].stopRequested)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/conversations/conv-123/collaboration/takeover", nil)
+	req.Header.Set(collaborationClientHeader, viewerSecret)
+	req = mux.SetURLVars(req, map[string]string{"id": "conv-123"})
+	w = httptest.NewRecorder()
+	server.handleCollaborationTakeover(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/conversations/conv-123/collaboration/takeover/respond", strings.NewReader(`{"accept":true}`))
+	req.Header.Set(collaborationClientHeader, driverSecret)
+	req = mux.SetURLVars(req, map[string]string{"id": "conv-123"})
+	w = httptest.NewRecorder()
+	server.handleCollaborationTakeoverResponse(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var state collaborationState
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+	assert.Equal(t, viewerID, state.Driver.ID)
+
+	w = stop(viewerSecret)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, server.activeChats["conv-123"].stopRequested)
+}
+
+func TestServerCollaborationIssuesClientSecrets(t *testing.T) {
+	server := &Server{router: mux.NewRouter()}
+	heartbeat := func(secret string) (*httptest.ResponseRecorder, collaborationPresenceResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/api/conversations/conv-123/collaboration/presence", nil)
+		if secret != "" {
+			req.Header.Set(collaborationClientHeader, secret)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": "conv-123"})
+		w := httptest.NewRecorder()
+		server.handleCollaborationHeartbeat(w, req)
+		var response collaborationPresenceResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w, response
+	}
+
+	w, joined := heartbeat("")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotEmpty(t, joined.ClientSecret)
+	assert.Equal(t, []string{joined.ClientID}, viewerIDs(joined.collaborationState))
+	broadcast, err := json.Marshal(server.collaborationHub().State("conv-123"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(broadcast), joined.ClientSecret, "the secret is not part of the broadcast state")
+
+	_, again := heartbeat(joined.ClientSecret)
+	assert.Equal(t, joined.ClientID, again.ClientID)
+	assert.Equal(t, joined.ClientSecret, again.ClientSecret)
+
+	_, forged := heartbeat(joined.ClientID)
+	assert.NotEqual(t, joined.ClientID, forged.ClientID, "an unrecognised secret joins as a new client")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/conversations/conv-123/collaboration/release", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "conv-123"})
+	w = httptest.NewRecorder()
+	server.handleCollaborationRelease(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func viewerIDs(state collaborationState) []string {
+	ids := []string{}
+	for _, viewer := range state.Viewers {
+		ids = append(ids, viewer.ID)
+	}
+	return ids
+}
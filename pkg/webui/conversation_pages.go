@@ -0,0 +1,138 @@
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jingkaihe/kodelet/pkg/tools/renderers"
+	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultMessagePageLimit is the page size used when only ?after= is given.
+	defaultMessagePageLimit = 100
+	// maxMessagePageLimit caps ?limit= so a single page stays cheap to render.
+	maxMessagePageLimit = 500
+	// deferredToolResultBytes is the encoded size above which a tool result is
+	// sent as a server-rendered summary and fetched in full on demand.
+	deferredToolResultBytes = 16 * 1024
+	// deferredToolResultPreviewBytes caps the rendered text of a deferred result.
+	deferredToolResultPreviewBytes = 8 * 1024
+)
+
+// messagePage selects a window of a conversation's messages. Messages are
+// numbered from 1 by their seq, and a page holds the messages after After.
+type messagePage struct {
+	After int
+	Limit int
+}
+
+// parseMessagePage reads ?after=seq&limit=n. It returns nil when neither is
+// set, in which case the whole conversation is returned.
+func parseMessagePage(query url.Values) (*messagePage, error) {
+	afterStr, limitStr := query.Get("after"), query.Get("limit")
+	if afterStr == "" && limitStr == "" {
+		return nil, nil
+	}
+
+	page := &messagePage{Limit: defaultMessagePageLimit}
+	if afterStr != "" {
+		after, err := strconv.Atoi(afterStr)
+		if err != nil || after < 0 {
+			return nil, errors.Errorf("invalid after %q: must be a non-negative message seq", afterStr)
+		}
+		page.After = after
+	}
+	if limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return nil, errors.Errorf("invalid limit %q: must be a positive integer", limitStr)
+		}
+		page.Limit = min(limit, maxMessagePageLimit)
+	}
+	return page, nil
+}
+
+// apply numbers messages and returns the ones in the page, and whether more
+// messages follow it.
+func (p *messagePage) apply(messages []WebMessage) ([]WebMessage, bool) {
+	for i := range messages {
+		messages[i].Seq = i + 1
+	}
+	if p == nil {
+		return messages, false
+	}
+
+	start := min(p.After, len(messages))
+	end := min(start+p.Limit, len(messages))
+	return messages[start:end], end < len(messages)
+}
+
+// WebDeferredToolResult stands in for a tool result too large to inline in a
+// conversation response. The web UI shows Rendered and fetches the full
+// result from /api/conversations/{id}/tools/{toolCallId} when it is needed.
+type WebDeferredToolResult struct {
+	ToolName  string    `json:"toolName"`
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Deferred  bool      `json:"deferred"`
+	Size      int       `json:"size"`
+	Rendered  string    `json:"rendered,omitempty"`
+}
+
+// pageToolResults returns the results of the tool calls in messages, with any
+// result larger than deferredToolResultBytes replaced by a rendered summary.
+func pageToolResults(messages []WebMessage, results map[string]tooltypes.StructuredToolResult) map[string]any {
+	if len(results) == 0 {
+		return nil
+	}
+
+	var registry *renderers.RendererRegistry
+	paged := make(map[string]any)
+	for _, message := range messages {
+		for _, toolCall := range message.ToolCalls {
+			result, ok := results[toolCall.ID]
+			if !ok {
+				continue
+			}
+			encoded, err := json.Marshal(result)
+			if err != nil || len(encoded) <= deferredToolResultBytes {
+				paged[toolCall.ID] = result
+				continue
+			}
+			if registry == nil {
+				registry = renderers.NewRendererRegistry()
+			}
+			paged[toolCall.ID] = WebDeferredToolResult{
+				ToolName:  result.ToolName,
+				Success:   result.Success,
+				Error:     result.Error,
+				Timestamp: result.Timestamp,
+				Deferred:  true,
+				Size:      len(encoded),
+				Rendered:  truncateRendered(registry.Render(result), deferredToolResultPreviewBytes),
+			}
+		}
+	}
+	if len(paged) == 0 {
+		return nil
+	}
+	return paged
+}
+
+func truncateRendered(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + fmt.Sprintf("\n… (%d more bytes)", len(text)-cut)
+}
@@ -0,0 +1,123 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/types/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMessagePage(t *testing.T) {
+	page, err := parseMessagePage(url.Values{})
+	require.NoError(t, err)
+	assert.Nil(t, page)
+
+	page, err = parseMessagePage(url.Values{"after": {"20"}})
+	require.NoError(t, err)
+	assert.Equal(t, &messagePage{After: 20, Limit: defaultMessagePageLimit}, page)
+
+	page, err = parseMessagePage(url.Values{"limit": {"10000"}})
+	require.NoError(t, err)
+	assert.Equal(t, &messagePage{Limit: maxMessagePageLimit}, page)
+
+	for _, query := range []url.Values{{"after": {"-1"}}, {"after": {"x"}}, {"limit": {"0"}}} {
+		_, err := parseMessagePage(query)
+		assert.Error(t, err, query.Encode())
+	}
+}
+
+func TestServer_handleGetConversationPaginatesMessages(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	t.Setenv("USERPROFILE", homeDir)
+
+	var rawMessages []map[string]any
+	toolResults := map[string]tools.StructuredToolResult{}
+	for i := 1; i <= 3; i++ {
+		callID := fmt.Sprintf("call-%d", i)
+		output := "ok"
+		if i == 3 {
+			output = strings.Repeat("x", deferredToolResultBytes)
+		}
+		rawMessages = append(rawMessages,
+			map[string]any{"role": "user", "content": []any{map[string]any{"type": "text", "text": fmt.Sprintf("question %d", i)}}},
+			map[string]any{"role": "assistant", "content": []any{map[string]any{"type": "tool_use", "id": callID, "name": "bash", "input": map[string]any{"command": "echo"}}}},
+		)
+		toolResults[callID] = tools.StructuredToolResult{
+			ToolName: "bash",
+			Success:  true,
+			Metadata: &tools.BashMetadata{Command: "echo", Output: output},
+		}
+	}
+	raw, err := json.Marshal(rawMessages)
+	require.NoError(t, err)
+
+	server := &Server{
+		conversationService: &mockConversationService{
+			getFunc: func(_ context.Context, id string) (*conversations.GetConversationResponse, error) {
+				return &conversations.GetConversationResponse{ID: id, Provider: "anthropic", RawMessages: raw, ToolResults: toolResults}, nil
+			},
+		},
+		router: mux.NewRouter(),
+	}
+	get := func(query string) (*httptest.ResponseRecorder, map[string]any) {
+		req := httptest.NewRequest(http.MethodGet, "/api/conversations/conv-1"+query, nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "conv-1"})
+		w := httptest.NewRecorder()
+		server.handleGetConversation(w, req)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w, body
+	}
+	seqs := func(body map[string]any) []float64 {
+		var result []float64
+		for _, message := range body["messages"].([]any) {
+			result = append(result, message.(map[string]any)["seq"].(float64))
+		}
+		return result
+	}
+
+	w, body := get("?limit=4")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []float64{1, 2, 3, 4}, seqs(body))
+	assert.Equal(t, true, body["hasMore"])
+	assert.Equal(t, float64(6), body["messageCount"])
+	assert.ElementsMatch(t, []string{"call-1", "call-2"}, keys(body["toolResults"].(map[string]any)))
+
+	_, body = get("?after=4&limit=4")
+	assert.Equal(t, []float64{5, 6}, seqs(body))
+	assert.Nil(t, body["hasMore"])
+	results := body["toolResults"].(map[string]any)
+	require.Len(t, results, 1)
+	deferred := results["call-3"].(map[string]any)
+	assert.Equal(t, true, deferred["deferred"])
+	assert.Equal(t, "bash", deferred["toolName"])
+	assert.Nil(t, deferred["metadata"])
+	assert.Contains(t, deferred["rendered"], "more bytes)")
+	assert.LessOrEqual(t, len(deferred["rendered"].(string)), deferredToolResultPreviewBytes+32)
+
+	_, body = get("")
+	assert.Equal(t, []float64{1, 2, 3, 4, 5, 6}, seqs(body))
+	assert.Len(t, body["toolResults"], 3)
+
+	w, _ = get("?after=-1")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func keys(m map[string]any) []string {
+	result := make([]string, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	return result
+}
@@ -2,6 +2,7 @@ package webui
 
 import (
 	"context"
+	"sort"
 	"strings"
 	"sync"
 
@@ -64,3 +65,37 @@ func webExtensionRuntimeKey(cwd string) string {
 	}
 	return osutil.CanonicalizePath(cwd)
 }
+
+// webExtensionStatus is the state of an extension process of the runtime
+// serving CWD.
+type webExtensionStatus struct {
+	extensions.ProcessStatus
+	CWD string `json:"cwd,omitempty"`
+}
+
+// Statuses returns the state of the extension processes of every runtime.
+// Runtimes start with the first chat in their working directory.
+func (m *webExtensionRuntimeManager) Statuses() []webExtensionStatus {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	keys := make([]string, 0, len(m.runtimes))
+	for key := range m.runtimes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	runtimes := make([]*extensions.Runtime, 0, len(keys))
+	for _, key := range keys {
+		runtimes = append(runtimes, m.runtimes[key])
+	}
+	m.mu.Unlock()
+
+	var statuses []webExtensionStatus
+	for i, runtime := range runtimes {
+		for _, status := range runtime.Statuses() {
+			statuses = append(statuses, webExtensionStatus{ProcessStatus: status, CWD: keys[i]})
+		}
+	}
+	return statuses
+}
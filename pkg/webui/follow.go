@@ -0,0 +1,224 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/llm"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+	"github.com/jingkaihe/kodelet/pkg/steer"
+)
+
+var (
+	// conversationFollowInterval is how often a followed conversation is
+	// checked for newly saved messages.
+	conversationFollowInterval = time.Second
+	// conversationFollowRunCheckInterval is how often the run of a followed
+	// conversation is checked to still be active.
+	conversationFollowRunCheckInterval = 5 * time.Second
+	// conversationFollowKeepAlive is how often a comment is sent on an
+	// otherwise quiet stream so proxies keep the connection open.
+	conversationFollowKeepAlive = 15 * time.Second
+)
+
+// sseWriter writes server-sent events to an HTTP response.
+type sseWriter struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newSSEWriter(w http.ResponseWriter) (*sseWriter, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("streaming is not supported by this response writer")
+	}
+
+	return &sseWriter{
+		w:       w,
+		flusher: flusher,
+	}, nil
+}
+
+// Event sends data as a JSON encoded event named event.
+func (s *sseWriter) Event(event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal event")
+	}
+	return s.write(fmt.Sprintf("event: %s\ndata: %s\n\n", event, payload))
+}
+
+// Data sends data as a JSON encoded event without a name, as the OpenAI API
+// streams its chunks.
+func (s *sseWriter) Data(data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal event")
+	}
+	return s.write(fmt.Sprintf("data: %s\n\n", payload))
+}
+
+// Done sends the "[DONE]" message that ends an OpenAI API stream.
+func (s *sseWriter) Done() error {
+	return s.write("data: [DONE]\n\n")
+}
+
+// Comment sends a comment line, which clients ignore.
+func (s *sseWriter) Comment(text string) error {
+	return s.write(fmt.Sprintf(": %s\n\n", text))
+}
+
+func (s *sseWriter) write(message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.w.Write([]byte(message)); err != nil {
+		return errors.Wrap(err, "failed to write event")
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+type conversationFollowIdleEvent struct {
+	ConversationID string `json:"conversation_id"`
+}
+
+// handleFollowConversation handles GET /api/conversations/{id}/follow. It
+// streams the messages and tool results saved to a conversation as
+// server-sent "entry" events while any process, such as `kodelet run`, is
+// running it, then sends an "idle" event and ends the stream. Pass
+// history=true to receive the saved history first.
+func (s *Server) handleFollowConversation(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	conversationID := strings.TrimSpace(mux.Vars(r)["id"])
+	if conversationID == "" {
+		s.writeErrorResponse(w, http.StatusBadRequest, "conversation ID is required", nil)
+		return
+	}
+
+	includeHistory := false
+	if raw := strings.TrimSpace(r.URL.Query().Get("history")); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			s.writeErrorResponse(w, http.StatusBadRequest, "invalid history parameter", err)
+			return
+		}
+		includeHistory = parsed
+	}
+
+	if _, err := s.conversationService.GetConversation(ctx, conversationID); err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "failed to get conversation", err)
+		return
+	}
+
+	events, err := newSSEWriter(w)
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusInternalServerError, "failed to initialize conversation stream", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	events.flusher.Flush()
+
+	runDone := make(chan struct{})
+	go s.watchConversationRun(ctx, conversationID, runDone)
+
+	// The keep-alive writer must stop before the response is finished.
+	var keepAlive sync.WaitGroup
+	keepAlive.Add(1)
+	defer func() {
+		cancel()
+		keepAlive.Wait()
+	}()
+	go func() {
+		defer keepAlive.Done()
+		ticker := time.NewTicker(conversationFollowKeepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := events.Comment("keep-alive"); err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	streamer := llm.NewConversationStreamerForService(s.conversationService)
+	err = streamer.StreamLiveUpdates(ctx, conversationID, conversations.StreamOpts{
+		Interval:       conversationFollowInterval,
+		IncludeHistory: includeHistory,
+		Done:           runDone,
+		Output: func(entry conversations.StreamEntry) error {
+			return events.Event("entry", entry)
+		},
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.G(ctx).WithError(err).WithField("conversation_id", conversationID).Warn("failed to follow conversation")
+		}
+		return
+	}
+
+	_ = events.Event("idle", conversationFollowIdleEvent{ConversationID: conversationID})
+}
+
+// watchConversationRun closes done once no run of conversationID is active,
+// whether it was started by this server or by another kodelet process.
+func (s *Server) watchConversationRun(ctx context.Context, conversationID string, done chan<- struct{}) {
+	defer close(done)
+
+	steerStore, err := steer.NewSteerStore(ctx)
+	if err != nil {
+		logger.G(ctx).WithError(err).Warn("failed to open steer store to watch conversation run")
+	}
+	if steerStore != nil {
+		defer steerStore.Close()
+	}
+
+	isActive := func() bool {
+		if s.hasActiveChatRun(conversationID) {
+			return true
+		}
+		if steerStore == nil {
+			return false
+		}
+		active, err := steerStore.IsRunActive(ctx, conversationID)
+		if err != nil {
+			// Keep following; a transient store error should not end the stream.
+			logger.G(ctx).WithError(err).Debug("failed to check whether the followed conversation is running")
+			return true
+		}
+		return active
+	}
+
+	ticker := time.NewTicker(conversationFollowRunCheckInterval)
+	defer ticker.Stop()
+	for isActive() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
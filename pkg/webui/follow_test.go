@@ -0,0 +1,161 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/db"
+	"github.com/jingkaihe/kodelet/pkg/db/migrations"
+	"github.com/jingkaihe/kodelet/pkg/steer"
+)
+
+func setFollowIntervals(t *testing.T, interval time.Duration) {
+	t.Helper()
+	originalInterval := conversationFollowInterval
+	originalRunCheck := conversationFollowRunCheckInterval
+	conversationFollowInterval = interval
+	conversationFollowRunCheckInterval = interval
+	t.Cleanup(func() {
+		conversationFollowInterval = originalInterval
+		conversationFollowRunCheckInterval = originalRunCheck
+	})
+}
+
+type followEvent struct {
+	name string
+	data string
+}
+
+func parseFollowEvents(t *testing.T, body string) []followEvent {
+	t.Helper()
+	var events []followEvent
+	for _, block := range strings.Split(body, "\n\n") {
+		var event followEvent
+		for _, line := range strings.Split(block, "\n") {
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				event.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+		if event.name != "" {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func followRequest(conversationID, query string) *http.Request {
+	req := httptest.NewRequest("GET", "/api/conversations/"+conversationID+"/follow"+query, nil)
+	return mux.SetURLVars(req, map[string]string{"id": conversationID})
+}
+
+func TestServer_handleFollowConversationIdleSendsHistory(t *testing.T) {
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	require.NoError(t, db.RunMigrations(context.Background(), migrations.All()))
+	setFollowIntervals(t, 10*time.Millisecond)
+
+	mockService := &mockConversationService{
+		getFunc: func(_ context.Context, id string) (*conversations.GetConversationResponse, error) {
+			return &conversations.GetConversationResponse{
+				ID:          id,
+				Provider:    "anthropic",
+				RawMessages: json.RawMessage(`[{"role":"user","content":[{"type":"text","text":"hello"}]}]`),
+			}, nil
+		},
+	}
+	server := &Server{conversationService: mockService, router: mux.NewRouter()}
+	w := httptest.NewRecorder()
+
+	server.handleFollowConversation(w, followRequest("conv-idle", "?history=true"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	events := parseFollowEvents(t, w.Body.String())
+	require.Len(t, events, 2)
+	assert.Equal(t, "entry", events[0].name)
+	var entry conversations.StreamEntry
+	require.NoError(t, json.Unmarshal([]byte(events[0].data), &entry))
+	assert.Equal(t, "hello", entry.Content)
+	assert.Equal(t, "conv-idle", entry.ConversationID)
+	assert.Equal(t, "idle", events[1].name)
+	assert.JSONEq(t, `{"conversation_id":"conv-idle"}`, events[1].data)
+}
+
+func TestServer_handleFollowConversationStreamsActiveRun(t *testing.T) {
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	require.NoError(t, db.RunMigrations(context.Background(), migrations.All()))
+	setFollowIntervals(t, 10*time.Millisecond)
+
+	conversationID := "conv-active"
+	var mu sync.Mutex
+	rawMessages := `[{"role":"user","content":[{"type":"text","text":"list files"}]}]`
+	updatedAt := time.Now()
+	mockService := &mockConversationService{
+		getFunc: func(_ context.Context, id string) (*conversations.GetConversationResponse, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return &conversations.GetConversationResponse{
+				ID:          id,
+				Provider:    "anthropic",
+				RawMessages: json.RawMessage(rawMessages),
+				UpdatedAt:   updatedAt,
+			}, nil
+		},
+	}
+
+	stopRun := steer.TrackRun(context.Background(), conversationID)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		rawMessages = `[{"role":"user","content":[{"type":"text","text":"list files"}]},{"role":"assistant","content":[{"type":"text","text":"main.go"}]}]`
+		updatedAt = updatedAt.Add(time.Second)
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		stopRun()
+	}()
+
+	server := &Server{conversationService: mockService, router: mux.NewRouter()}
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.handleFollowConversation(w, followRequest(conversationID, ""))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("follow stream did not end after the run stopped")
+	}
+
+	events := parseFollowEvents(t, w.Body.String())
+	require.Len(t, events, 2)
+	assert.Equal(t, "entry", events[0].name)
+	var entry conversations.StreamEntry
+	require.NoError(t, json.Unmarshal([]byte(events[0].data), &entry))
+	assert.Equal(t, "assistant", entry.Role)
+	assert.Equal(t, "main.go", entry.Content)
+	assert.Equal(t, "idle", events[1].name)
+}
+
+func TestServer_handleFollowConversationRejectsInvalidHistory(t *testing.T) {
+	server := &Server{conversationService: &mockConversationService{}, router: mux.NewRouter()}
+	w := httptest.NewRecorder()
+
+	server.handleFollowConversation(w, followRequest("conv-1", "?history=maybe"))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
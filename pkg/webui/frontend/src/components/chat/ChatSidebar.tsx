@@ -1,7 +1,7 @@
 import React from "react";
 import { ChevronRight, PanelLeftClose, SquarePen } from "lucide-react";
 import type { Conversation } from "../../types";
-import { cn, truncateText } from "../../utils";
+import { cn, getConversationTitle, truncateText } from "../../utils";
 
 const DEFAULT_VISIBLE_CONVERSATIONS_PER_GROUP = 10;
 const VISIBLE_CONVERSATIONS_STEP = 10;
@@ -20,6 +20,7 @@ interface ChatSidebarProps {
 
 const previewConversation = (conversation: Conversation): string => {
 	return (
+		getConversationTitle(conversation) ||
 		conversation.summary ||
 		conversation.preview ||
 		conversation.firstMessage ||
@@ -17,6 +17,7 @@ import {
 import type { ChatRenderToolCall, ToolResult } from '../../types';
 import { cn, formatDuration } from '../../utils';
 import ToolRenderer from '../ToolRenderer';
+import DeferredToolResult from './DeferredToolResult';
 import {
   formatTaskRunElapsed,
   getTaskRunSnapshot,
@@ -582,7 +583,9 @@ const ChatToolActivity: React.FC<ChatToolActivityProps> = ({ tools }) => {
             </summary>
 
             <div className="activity-detail-content space-y-2">
-              {toolCall.result ? (
+              {toolCall.result?.deferred ? (
+                <DeferredToolResult toolCall={toolCall} toolResult={toolCall.result} />
+              ) : toolCall.result ? (
                 <ToolRenderer
                   isPartial={toolCall.inProgress}
                   toolInput={toolCall.input}
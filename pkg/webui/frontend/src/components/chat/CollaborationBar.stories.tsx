@@ -0,0 +1,46 @@
+import type { Meta, StoryObj } from "@storybook/react-vite";
+import CollaborationBar from "./CollaborationBar";
+
+const meta = {
+	title: "Chat/CollaborationBar",
+	component: CollaborationBar,
+	parameters: {
+		layout: "padded",
+	},
+	args: {
+		clientId: "client-self",
+		onRequestTakeover: () => {},
+		onRespondToTakeover: () => {},
+		onRelease: () => {},
+		state: {
+			conversation_id: "conv-123",
+			driver: { id: "client-other", name: "Ada", last_seen: "" },
+			viewers: [
+				{ id: "client-self", last_seen: "" },
+				{ id: "client-third", name: "Grace", last_seen: "" },
+			],
+		},
+	},
+} satisfies Meta<typeof CollaborationBar>;
+
+export default meta;
+
+type Story = StoryObj<typeof meta>;
+
+export const Watching: Story = {};
+
+export const TakeoverRequested: Story = {
+	args: {
+		state: {
+			conversation_id: "conv-123",
+			driver: { id: "client-self", last_seen: "" },
+			viewers: [{ id: "client-other", name: "Ada", last_seen: "" }],
+			takeover: {
+				client_id: "client-other",
+				name: "Ada",
+				requested_at: "",
+				granted_at: "",
+			},
+		},
+	},
+};
@@ -0,0 +1,57 @@
+import { fireEvent, render, screen } from '@testing-library/react';
+import { describe, expect, it, vi } from 'vitest';
+import CollaborationBar from './CollaborationBar';
+import type { CollaborationState } from '../../types';
+
+const handlers = () => ({
+  onRequestTakeover: vi.fn(),
+  onRespondToTakeover: vi.fn(),
+  onRelease: vi.fn(),
+});
+
+describe('CollaborationBar', () => {
+  it('renders nothing while the client watches alone', () => {
+    const state: CollaborationState = {
+      conversation_id: 'conv-1',
+      driver: { id: 'me', last_seen: '' },
+      viewers: [],
+    };
+
+    const { container } = render(<CollaborationBar clientId="me" state={state} {...handlers()} />);
+
+    expect(container).toBeEmptyDOMElement();
+  });
+
+  it('lets a viewer request control from the driver', () => {
+    const props = handlers();
+    const state: CollaborationState = {
+      conversation_id: 'conv-1',
+      driver: { id: 'other', name: 'Ada', last_seen: '' },
+      viewers: [{ id: 'me', last_seen: '' }],
+    };
+
+    render(<CollaborationBar clientId="me" state={state} {...props} />);
+
+    expect(screen.getByTestId('collaboration-bar')).toHaveTextContent('Ada is driving this conversation');
+    fireEvent.click(screen.getByRole('button', { name: 'Request control' }));
+    expect(props.onRequestTakeover).toHaveBeenCalled();
+  });
+
+  it('asks the driver to answer a takeover request', () => {
+    const props = handlers();
+    const state: CollaborationState = {
+      conversation_id: 'conv-1',
+      driver: { id: 'me', last_seen: '' },
+      viewers: [{ id: 'other', name: 'Ada', last_seen: '' }],
+      takeover: { client_id: 'other', name: 'Ada', requested_at: '', granted_at: '' },
+    };
+
+    render(<CollaborationBar clientId="me" state={state} {...props} />);
+
+    expect(screen.getByText('Ada asks for control')).toBeInTheDocument();
+    fireEvent.click(screen.getByRole('button', { name: 'Keep control' }));
+    expect(props.onRespondToTakeover).toHaveBeenCalledWith(false);
+    fireEvent.click(screen.getByRole('button', { name: 'Hand over' }));
+    expect(props.onRespondToTakeover).toHaveBeenCalledWith(true);
+  });
+});
@@ -0,0 +1,114 @@
+import type { CollaborationClient, CollaborationState } from '../../types';
+
+interface CollaborationBarProps {
+  clientId: string;
+  state: CollaborationState | null;
+  busy?: boolean;
+  onRequestTakeover: () => void;
+  onRespondToTakeover: (accept: boolean) => void;
+  onRelease: () => void;
+}
+
+const clientLabel = (client: { id?: string; client_id?: string; name?: string }, clientId: string): string => {
+  const id = client.id ?? client.client_id ?? '';
+  if (id === clientId) {
+    return 'You';
+  }
+  return client.name?.trim() || `Viewer ${id.slice(0, 6)}`;
+};
+
+const viewerSummary = (viewers: CollaborationClient[], clientId: string): string => {
+  const others = viewers.filter((viewer) => viewer.id !== clientId);
+  if (others.length === 0) {
+    return '';
+  }
+  if (others.length <= 2) {
+    return others.map((viewer) => clientLabel(viewer, clientId)).join(', ');
+  }
+  return `${others.length} viewers`;
+};
+
+// CollaborationBar shows who drives a conversation watched from several
+// browser tabs and lets viewers ask for control.
+const CollaborationBar = ({
+  clientId,
+  state,
+  busy = false,
+  onRequestTakeover,
+  onRespondToTakeover,
+  onRelease,
+}: CollaborationBarProps) => {
+  if (!state) {
+    return null;
+  }
+
+  const others = state.viewers.filter((viewer) => viewer.id !== clientId);
+  const isDriver = state.driver?.id === clientId;
+  const takeover = state.takeover;
+  if (others.length === 0 && (!state.driver || isDriver) && !takeover) {
+    return null;
+  }
+
+  const viewers = viewerSummary(state.viewers, clientId);
+  const driverText = state.driver
+    ? isDriver
+      ? 'You are driving this conversation'
+      : `${clientLabel(state.driver, clientId)} is driving this conversation`
+    : 'Nobody is driving this conversation';
+
+  return (
+    <section aria-label="Collaboration" className="collaboration-bar" data-testid="collaboration-bar">
+      <div className="collaboration-bar-inner">
+        <p className="collaboration-bar-copy">
+          {driverText}
+          {viewers ? <span className="collaboration-bar-viewers"> · Watching: {viewers}</span> : null}
+        </p>
+        <div className="collaboration-bar-actions">
+          {isDriver && takeover ? (
+            <>
+              <span className="collaboration-bar-request">
+                {clientLabel(takeover, clientId)} asks for control
+              </span>
+              <button
+                className="collaboration-bar-button is-primary"
+                disabled={busy}
+                type="button"
+                onClick={() => onRespondToTakeover(true)}
+              >
+                Hand over
+              </button>
+              <button
+                className="collaboration-bar-button"
+                disabled={busy}
+                type="button"
+                onClick={() => onRespondToTakeover(false)}
+              >
+                Keep control
+              </button>
+            </>
+          ) : null}
+          {isDriver && !takeover && others.length > 0 ? (
+            <button className="collaboration-bar-button" disabled={busy} type="button" onClick={onRelease}>
+              Release control
+            </button>
+          ) : null}
+          {!isDriver && takeover?.client_id === clientId ? (
+            <span className="collaboration-bar-request">Waiting for the driver to answer…</span>
+          ) : null}
+          {!isDriver && !takeover ? (
+            <button
+              className="collaboration-bar-button is-primary"
+              disabled={busy}
+              type="button"
+              onClick={onRequestTakeover}
+            >
+              {state.driver ? 'Request control' : 'Take control'}
+            </button>
+          ) : null}
+        </div>
+      </div>
+    </section>
+  );
+};
+
+export default CollaborationBar;
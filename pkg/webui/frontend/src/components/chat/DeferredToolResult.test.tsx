@@ -0,0 +1,59 @@
+import { fireEvent, render, screen, waitFor } from '@testing-library/react';
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import DeferredToolResult from './DeferredToolResult';
+
+const mockGetToolResult = vi.fn();
+
+vi.mock('../../services/api', () => ({
+  default: {
+    getToolResult: (...args: unknown[]) => mockGetToolResult(...args),
+  },
+}));
+
+describe('DeferredToolResult', () => {
+  beforeEach(() => {
+    mockGetToolResult.mockReset();
+  });
+
+  it('shows the rendered summary and loads the full result on demand', async () => {
+    mockGetToolResult.mockResolvedValueOnce({
+      toolName: 'bash',
+      success: true,
+      metadata: {
+        command: 'cat big.log',
+        exitCode: 0,
+        output: 'full output line',
+        executionTime: 1000000,
+      },
+    });
+
+    render(
+      <DeferredToolResult
+        toolCall={{ callId: 'call-1', name: 'bash', input: '{"command":"cat big.log"}', conversationId: 'conv-1' }}
+        toolResult={{ toolName: 'bash', success: true, deferred: true, size: 40960, rendered: 'Command: cat big.log' }}
+      />
+    );
+
+    expect(screen.getByText('Command: cat big.log')).toBeInTheDocument();
+    fireEvent.click(screen.getByRole('button', { name: /Load full result/ }));
+
+    await waitFor(() => expect(screen.getByText('full output line')).toBeInTheDocument());
+    expect(mockGetToolResult).toHaveBeenCalledWith('conv-1', 'call-1');
+  });
+
+  it('reports a failed load and keeps the summary', async () => {
+    mockGetToolResult.mockRejectedValueOnce(new Error('tool result not found'));
+
+    render(
+      <DeferredToolResult
+        toolCall={{ callId: 'call-1', name: 'bash', input: '{}', conversationId: 'conv-1' }}
+        toolResult={{ toolName: 'bash', success: true, deferred: true, rendered: 'summary text' }}
+      />
+    );
+
+    fireEvent.click(screen.getByRole('button', { name: 'Load full result' }));
+
+    await waitFor(() => expect(screen.getByText('tool result not found')).toBeInTheDocument());
+    expect(screen.getByText('summary text')).toBeInTheDocument();
+  });
+});
@@ -0,0 +1,56 @@
+import React, { useState } from 'react';
+import apiService from '../../services/api';
+import type { ChatRenderToolCall, ToolResult } from '../../types';
+import { formatFileSize } from '../../utils';
+import ToolRenderer from '../ToolRenderer';
+import { ReferenceCodeBlock, ReferenceToolNote } from '../tool-renderers/reference';
+
+interface DeferredToolResultProps {
+  toolCall: ChatRenderToolCall;
+  toolResult: ToolResult;
+}
+
+// DeferredToolResult shows the server-rendered text of a tool result that was
+// too large to include in the conversation response, and fetches the full
+// structured result only when asked.
+const DeferredToolResult: React.FC<DeferredToolResultProps> = ({ toolCall, toolResult }) => {
+  const [fullResult, setFullResult] = useState<ToolResult | null>(null);
+  const [loading, setLoading] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+
+  if (fullResult) {
+    return <ToolRenderer toolInput={toolCall.input} toolResult={fullResult} />;
+  }
+
+  const loadFullResult = async () => {
+    if (!toolCall.conversationId) {
+      return;
+    }
+    setLoading(true);
+    setError(null);
+    try {
+      setFullResult(await apiService.getToolResult(toolCall.conversationId, toolCall.callId));
+    } catch (loadError) {
+      setError(loadError instanceof Error ? loadError.message : 'Failed to load tool result');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <div className="quiet-tool-detail">
+      {!toolResult.success && toolResult.error ? <ReferenceToolNote text={toolResult.error} /> : null}
+      {toolResult.rendered ? <ReferenceCodeBlock content={toolResult.rendered} /> : null}
+      {toolCall.conversationId ? (
+        <button className="tool-action-link" disabled={loading} onClick={() => void loadFullResult()}>
+          {loading
+            ? 'Loading full result…'
+            : `Load full result${toolResult.size ? ` (${formatFileSize(toolResult.size)})` : ''}`}
+        </button>
+      ) : null}
+      <ReferenceToolNote text={error} />
+    </div>
+  );
+};
+
+export default DeferredToolResult;
@@ -64,4 +64,19 @@ describe('FileEditRenderer', () => {
     expect(screen.getByText('/empty.txt')).toBeInTheDocument();
     expect(container.querySelector('.diff-block')).not.toBeInTheDocument();
   });
+
+  it('renders the whole-file diff with file line numbers when present', () => {
+    const toolResult = createToolResult({
+      filePath: '/src/main.js',
+      edits: [{ startLine: 41, endLine: 41, oldContent: 'old', newContent: 'new' }],
+      unifiedDiff: '--- /src/main.js\n+++ /src/main.js\n@@ -40,3 +40,3 @@\n before\n-old\n+new\n after\n',
+    });
+
+    const { container } = render(<FileEditRenderer toolResult={toolResult} />);
+
+    expect(screen.queryByText('Lines 41-41')).not.toBeInTheDocument();
+    expect(container.querySelectorAll('.diff-block')).toHaveLength(1);
+    expect(container.querySelector('.diff-line-added .diff-line-number:nth-child(2)')).toHaveTextContent('41');
+    expect(container.querySelector('.diff-line-header')).toHaveTextContent('@@ -40,3 +40,3 @@');
+  });
 });
@@ -1,6 +1,7 @@
 import React from 'react';
 import { ToolResult } from '../../types';
 import {
+  parseUnifiedDiff,
   ReferenceDiffBlock,
 } from './reference';
 
@@ -12,6 +13,8 @@ interface FileEditMetadata {
   replacedCount?: number;
   actualReplaced?: number;
   occurrence?: number;
+  unifiedDiff?: string;
+  inverseDiff?: string;
 }
 
 interface FileEdit {
@@ -63,6 +66,9 @@ const FileEditRenderer: React.FC<FileEditRendererProps> = ({ toolResult }) => {
   };
 
   const replacementText = `${replacedCount} replacement${replacedCount !== 1 ? 's' : ''}`;
+  // The diff of the whole file carries the file's line numbers; results saved
+  // before it was recorded fall back to a diff per edit.
+  const fileDiffLines = meta.unifiedDiff ? parseUnifiedDiff(meta.unifiedDiff) : [];
 
   return (
     <div className="quiet-tool-detail">
@@ -72,23 +78,27 @@ const FileEditRenderer: React.FC<FileEditRendererProps> = ({ toolResult }) => {
       </div>
       <div className="quiet-tool-path">{meta.filePath}</div>
 
-      <div className="space-y-3">
-        {edits.map((edit, index) => {
-          const diffLines =
-            edit.oldContent || edit.newContent
-              ? createUnifiedDiff(edit.oldContent || '', edit.newContent || '')
-              : [];
+      {fileDiffLines.length > 0 ? (
+        <ReferenceDiffBlock lines={fileDiffLines} />
+      ) : (
+        <div className="space-y-3">
+          {edits.map((edit, index) => {
+            const diffLines =
+              edit.oldContent || edit.newContent
+                ? createUnifiedDiff(edit.oldContent || '', edit.newContent || '')
+                : [];
 
-          return (
-            <div key={index} className="space-y-2">
-              <div className="quiet-tool-section-title">
-                Lines {edit.startLine}-{edit.endLine}
+            return (
+              <div key={index} className="space-y-2">
+                <div className="quiet-tool-section-title">
+                  Lines {edit.startLine}-{edit.endLine}
+                </div>
+                <ReferenceDiffBlock lines={diffLines} />
               </div>
-              <ReferenceDiffBlock lines={diffLines} />
-            </div>
-          );
-        })}
-      </div>
+            );
+          })}
+        </div>
+      )}
     </div>
   );
 };
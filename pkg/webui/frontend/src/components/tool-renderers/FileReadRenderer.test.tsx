@@ -74,4 +74,18 @@ describe('FileReadRenderer', () => {
     expect(screen.getByText('0 lines')).toBeInTheDocument();
     expect(container.querySelector('.tool-code-block')).toBeInTheDocument();
   });
+
+  it('shows a notice instead of code for binary files', () => {
+    const toolResult = createToolResult({
+      filePath: '/bin/tool',
+      lines: [],
+      binary: { size: 2048, type: 'ELF' },
+    });
+
+    const { container } = render(<FileReadRenderer toolResult={toolResult} />);
+
+    expect(screen.getByText('/bin/tool')).toBeInTheDocument();
+    expect(screen.getByText('Content withheld (2048 bytes, type: ELF).')).toBeInTheDocument();
+    expect(container.querySelector('.tool-code-block')).not.toBeInTheDocument();
+  });
 });
@@ -21,6 +21,36 @@ const FileReadRenderer: React.FC<FileReadRendererProps> = ({ toolResult }) => {
   const lineLimit = meta.lineLimit;
   const remainingLines = meta.remainingLines || 0;
 
+  if (meta.outline) {
+    return (
+      <div className="quiet-tool-detail">
+        <div className="quiet-tool-line">
+          <span className="quiet-tool-emphasis">Outline</span>
+          {meta.totalLines ? (
+            <span className="quiet-tool-muted">{meta.totalLines} lines</span>
+          ) : null}
+        </div>
+        <div className="quiet-tool-path">{meta.filePath}</div>
+        <ReferenceCodeBlock content={lines.join('\n')} />
+      </div>
+    );
+  }
+
+  if (meta.binary) {
+    return (
+      <div className="quiet-tool-detail">
+        <div className="quiet-tool-line">
+          <span className="quiet-tool-emphasis">Binary</span>
+          <span className="quiet-tool-muted">{meta.binary.type}</span>
+        </div>
+        <div className="quiet-tool-path">{meta.filePath}</div>
+        <ReferenceToolNote
+          text={`Content withheld (${meta.binary.size} bytes, type: ${meta.binary.type}).`}
+        />
+      </div>
+    );
+  }
+
   let lastNonEmptyIndex = lines.length - 1;
   const isTruncationMessage = (line: string) =>
     line.includes('lines remaining') || line.includes('truncated due to');
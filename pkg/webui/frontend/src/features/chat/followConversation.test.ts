@@ -0,0 +1,71 @@
+import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
+import { FOLLOW_REFRESH_DELAY_MS, followConversation } from './followConversation';
+
+class FakeEventSource {
+  static instances: FakeEventSource[] = [];
+  listeners: Record<string, Array<() => void>> = {};
+  onerror: (() => void) | null = null;
+  closed = false;
+  url: string;
+
+  constructor(url: string) {
+    this.url = url;
+    FakeEventSource.instances.push(this);
+  }
+
+  addEventListener(name: string, listener: () => void) {
+    this.listeners[name] = [...(this.listeners[name] || []), listener];
+  }
+
+  emit(name: string) {
+    (this.listeners[name] || []).forEach((listener) => listener());
+  }
+
+  close() {
+    this.closed = true;
+  }
+}
+
+describe('followConversation', () => {
+  beforeEach(() => {
+    vi.useFakeTimers();
+    FakeEventSource.instances = [];
+    vi.stubGlobal('EventSource', FakeEventSource);
+  });
+
+  afterEach(() => {
+    vi.useRealTimers();
+    vi.unstubAllGlobals();
+  });
+
+  it('coalesces entries into one refresh and stops when the run is idle', () => {
+    const onUpdate = vi.fn();
+    const onIdle = vi.fn();
+
+    followConversation('conv 1', { onUpdate, onIdle });
+    const source = FakeEventSource.instances[0];
+    expect(source.url).toBe('/api/conversations/conv%201/follow');
+
+    source.emit('entry');
+    source.emit('entry');
+    vi.advanceTimersByTime(FOLLOW_REFRESH_DELAY_MS);
+    expect(onUpdate).toHaveBeenCalledTimes(1);
+
+    source.emit('idle');
+    expect(onIdle).toHaveBeenCalledTimes(1);
+    expect(source.closed).toBe(true);
+  });
+
+  it('closes the stream and drops a pending refresh when stopped', () => {
+    const onUpdate = vi.fn();
+
+    const stop = followConversation('conv-1', { onUpdate });
+    const source = FakeEventSource.instances[0];
+    source.emit('entry');
+    stop();
+    vi.advanceTimersByTime(FOLLOW_REFRESH_DELAY_MS);
+
+    expect(onUpdate).not.toHaveBeenCalled();
+    expect(source.closed).toBe(true);
+  });
+});
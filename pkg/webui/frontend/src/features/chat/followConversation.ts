@@ -0,0 +1,51 @@
+// Entries saved close together are coalesced into a single refresh.
+export const FOLLOW_REFRESH_DELAY_MS = 250;
+
+interface FollowConversationOptions {
+  // Called after new messages or tool results were saved.
+  onUpdate: () => void;
+  // Called once no run of the conversation is active any more.
+  onIdle?: () => void;
+}
+
+// followConversation watches a conversation that is run outside this page,
+// for example by `kodelet run`, over the server-sent events of
+// /api/conversations/{id}/follow. It returns a function that stops following.
+export const followConversation = (
+  id: string,
+  { onUpdate, onIdle }: FollowConversationOptions
+): (() => void) => {
+  if (typeof EventSource === 'undefined') {
+    return () => {};
+  }
+
+  const source = new EventSource(`/api/conversations/${encodeURIComponent(id)}/follow`);
+  let refreshTimer: ReturnType<typeof setTimeout> | null = null;
+
+  source.addEventListener('entry', () => {
+    if (refreshTimer) {
+      return;
+    }
+    refreshTimer = setTimeout(() => {
+      refreshTimer = null;
+      onUpdate();
+    }, FOLLOW_REFRESH_DELAY_MS);
+  });
+  source.addEventListener('idle', () => {
+    source.close();
+    onIdle?.();
+  });
+  // The server ends the stream after the idle event; close instead of letting
+  // EventSource reconnect.
+  source.onerror = () => {
+    source.close();
+  };
+
+  return () => {
+    source.close();
+    if (refreshTimer) {
+      clearTimeout(refreshTimer);
+      refreshTimer = null;
+    }
+  };
+};
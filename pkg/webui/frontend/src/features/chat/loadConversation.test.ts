@@ -0,0 +1,61 @@
+import { beforeEach, describe, expect, it, vi } from 'vitest';
+import type { Conversation, Message } from '../../types';
+import { loadConversation } from './loadConversation';
+
+const mockGetConversation = vi.fn();
+
+vi.mock('../../services/api', () => ({
+  default: {
+    getConversation: (...args: unknown[]) => mockGetConversation(...args),
+  },
+}));
+
+const page = (seqs: number[], hasMore: boolean, toolResults: Conversation['toolResults'] = {}): Conversation => ({
+  id: 'conv-1',
+  createdAt: '2026-03-08T00:00:00Z',
+  updatedAt: '2026-03-08T00:00:00Z',
+  messageCount: 5,
+  hasMore,
+  messages: seqs.map((seq): Message => ({ seq, role: seq % 2 === 1 ? 'user' : 'assistant', content: `message ${seq}` })),
+  toolResults,
+});
+
+describe('loadConversation', () => {
+  beforeEach(() => {
+    mockGetConversation.mockReset();
+  });
+
+  it('loads every page and reports progress after each one', async () => {
+    mockGetConversation
+      .mockResolvedValueOnce(page([1, 2], true, { a: { toolName: 'bash', success: true } }))
+      .mockResolvedValueOnce(page([3, 4], true, { b: { toolName: 'bash', success: true, deferred: true } }))
+      .mockResolvedValueOnce(page([5], false));
+    const onPage = vi.fn();
+
+    const conversation = await loadConversation('conv-1', { pageSize: 2, onPage });
+
+    expect(mockGetConversation.mock.calls.map((call) => call[1])).toEqual([
+      { after: 0, limit: 2 },
+      { after: 2, limit: 2 },
+      { after: 4, limit: 2 },
+    ]);
+    expect(onPage).toHaveBeenCalledTimes(3);
+    expect(onPage.mock.calls[0][0].messages).toHaveLength(2);
+    expect(conversation.messages?.map((message) => message.seq)).toEqual([1, 2, 3, 4, 5]);
+    expect(Object.keys(conversation.toolResults || {})).toEqual(['a', 'b']);
+    expect(conversation.hasMore).toBe(false);
+  });
+
+  it('stops when aborted between pages', async () => {
+    const controller = new AbortController();
+    mockGetConversation.mockImplementationOnce(async () => {
+      controller.abort();
+      return page([1, 2], true);
+    });
+
+    await expect(loadConversation('conv-1', { signal: controller.signal })).rejects.toMatchObject({
+      name: 'AbortError',
+    });
+    expect(mockGetConversation).toHaveBeenCalledTimes(1);
+  });
+});
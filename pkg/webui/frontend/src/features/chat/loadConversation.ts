@@ -0,0 +1,51 @@
+import apiService from '../../services/api';
+import type { Conversation } from '../../types';
+
+export const CONVERSATION_PAGE_SIZE = 100;
+
+interface LoadConversationOptions {
+  signal?: AbortSignal;
+  pageSize?: number;
+  // Called with the messages loaded so far after every page.
+  onPage?: (conversation: Conversation) => void;
+}
+
+const nextPageTick = (): Promise<void> =>
+  new Promise((resolve) => {
+    setTimeout(resolve, 0);
+  });
+
+// loadConversation fetches a conversation one page of messages at a time so
+// long conversations render progressively instead of in a single large
+// response. It resolves with every page merged into one conversation.
+export const loadConversation = async (
+  id: string,
+  { signal, pageSize = CONVERSATION_PAGE_SIZE, onPage }: LoadConversationOptions = {}
+): Promise<Conversation> => {
+  let conversation = await apiService.getConversation(id, { after: 0, limit: pageSize }, signal);
+  onPage?.(conversation);
+
+  while (conversation.hasMore) {
+    await nextPageTick();
+    if (signal?.aborted) {
+      throw new DOMException('Conversation load aborted', 'AbortError');
+    }
+
+    const loadedMessages = conversation.messages || [];
+    const after = loadedMessages[loadedMessages.length - 1]?.seq ?? loadedMessages.length;
+    const page = await apiService.getConversation(id, { after, limit: pageSize }, signal);
+    if ((page.messages || []).length === 0) {
+      conversation = { ...page, messages: loadedMessages, toolResults: conversation.toolResults, hasMore: false };
+      break;
+    }
+
+    conversation = {
+      ...page,
+      messages: [...loadedMessages, ...(page.messages || [])],
+      toolResults: { ...conversation.toolResults, ...page.toolResults },
+    };
+    onPage?.(conversation);
+  }
+
+  return conversation;
+};
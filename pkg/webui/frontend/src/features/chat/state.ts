@@ -227,12 +227,16 @@ export const conversationToChatMessages = (
     if (toolCalls.length > 0) {
       blocks.push({
         type: 'tools',
-        tools: toolCalls.map((toolCall) => ({
-          callId: toolCall.id,
-          name: toolCall.function?.name || 'unknown',
-          input: toolCall.function?.arguments || '{}',
-          result: conversation.toolResults?.[toolCall.id],
-        })),
+        tools: toolCalls.map((toolCall) => {
+          const result = conversation.toolResults?.[toolCall.id];
+          return {
+            callId: toolCall.id,
+            name: toolCall.function?.name || 'unknown',
+            input: toolCall.function?.arguments || '{}',
+            result,
+            ...(result?.deferred ? { conversationId: conversation.id } : {}),
+          };
+        }),
       });
     }
 
@@ -271,6 +275,7 @@ export const applyChatStreamEvent = (
   switch (event.kind) {
     case 'conversation':
     case 'usage':
+    case 'collaboration':
     case 'done':
     case 'error':
       return nextMessages;
@@ -28,6 +28,9 @@ const mockStopConversation = vi.fn();
 const mockDeleteConversation = vi.fn();
 const mockForkConversation = vi.fn();
 const mockRespondToUIInput = vi.fn();
+const mockHeartbeatCollaboration = vi.fn();
+const mockLeaveCollaboration = vi.fn();
+const mockRequestTakeover = vi.fn();
 let routeParams: { id?: string } = {};
 
 const flushAsyncUpdates = async () => {
@@ -80,6 +83,13 @@ vi.mock("../services/api", () => ({
 		deleteConversation: (...args: unknown[]) => mockDeleteConversation(...args),
 		forkConversation: (...args: unknown[]) => mockForkConversation(...args),
 		respondToUIInput: (...args: unknown[]) => mockRespondToUIInput(...args),
+		clientId: "client-self",
+		heartbeatCollaboration: (...args: unknown[]) =>
+			mockHeartbeatCollaboration(...args),
+		leaveCollaboration: (...args: unknown[]) => mockLeaveCollaboration(...args),
+		requestTakeover: (...args: unknown[]) => mockRequestTakeover(...args),
+		respondToTakeover: vi.fn(),
+		releaseControl: vi.fn(),
 	},
 }));
 
@@ -180,6 +190,11 @@ describe("ChatPage", () => {
 			conversation_id: "conv-copy-123",
 		});
 		mockRespondToUIInput.mockResolvedValue({ success: true });
+		mockHeartbeatCollaboration.mockImplementation(async (id: string) => ({
+			conversation_id: id,
+			viewers: [],
+		}));
+		mockLeaveCollaboration.mockResolvedValue(undefined);
 		mockGetCWDHints.mockResolvedValue({
 			hints: [{ path: "/workspace/default" }],
 		});
@@ -1163,6 +1178,60 @@ describe("ChatPage", () => {
 		);
 	});
 
+	it("keeps the composer read-only while another tab drives the conversation", async () => {
+		routeParams = { id: "conv-123" };
+		mockGetConversation.mockResolvedValue({
+			id: "conv-123",
+			createdAt: "2024-01-01T00:00:00Z",
+			updatedAt: "2024-01-01T00:00:00Z",
+			messageCount: 0,
+			messages: [],
+			toolResults: {},
+		});
+		mockHeartbeatCollaboration.mockResolvedValue({
+			conversation_id: "conv-123",
+			driver: { id: "client-other", name: "Ada", last_seen: "" },
+			viewers: [{ id: "client-self", last_seen: "" }],
+		});
+		mockRequestTakeover.mockResolvedValue({
+			conversation_id: "conv-123",
+			driver: { id: "client-other", name: "Ada", last_seen: "" },
+			viewers: [{ id: "client-self", last_seen: "" }],
+			takeover: {
+				client_id: "client-self",
+				requested_at: "",
+				granted_at: "",
+			},
+		});
+
+		render(<ChatPage />);
+
+		await waitFor(() =>
+			expect(mockHeartbeatCollaboration).toHaveBeenCalledWith("conv-123"),
+		);
+		await waitFor(() =>
+			expect(screen.getByTestId("collaboration-bar")).toHaveTextContent(
+				"Ada is driving this conversation",
+			),
+		);
+		expect(
+			screen.getByPlaceholderText(
+				"Another tab is driving this conversation; request control to take part…",
+			),
+		).toBeDisabled();
+
+		fireEvent.click(screen.getByRole("button", { name: "Request control" }));
+
+		await waitFor(() =>
+			expect(mockRequestTakeover).toHaveBeenCalledWith("conv-123"),
+		);
+		await waitFor(() =>
+			expect(
+				screen.getByText("Waiting for the driver to answer…"),
+			).toBeInTheDocument(),
+		);
+	});
+
 	it("re-subscribes to an active conversation stream when reopening a conversation", async () => {
 		routeParams = { id: "conv-123" };
 		mockGetConversation.mockResolvedValue({
@@ -18,6 +18,7 @@ import {
 import { useNavigate, useParams } from "react-router-dom";
 import ChatComposer from "../components/chat/ChatComposer";
 import ChatSidebar from "../components/chat/ChatSidebar";
+import CollaborationBar from "../components/chat/CollaborationBar";
 import ChatTranscript from "../components/chat/ChatTranscript";
 import NewChatContextDialog from "../components/chat/NewChatContextDialog";
 import PendingSteerList from "../components/chat/PendingSteerList";
@@ -26,11 +27,14 @@ import {
 	applyChatStreamEvent,
 	conversationToChatMessages,
 } from "../features/chat/state";
+import { followConversation } from "../features/chat/followConversation";
+import { loadConversation } from "../features/chat/loadConversation";
 import apiService from "../services/api";
 import type {
 	CWDHint,
 	ChatSettings,
 	ChatStreamEvent,
+	CollaborationState,
 	ContentBlock,
 	Conversation,
 	GitDiffResponse,
@@ -46,6 +50,7 @@ import {
 	formatCompactRelativeTime,
 	formatContextWindow,
 	formatCost,
+	getConversationTitle,
 	showToast,
 	truncateMiddle,
 } from "../utils";
@@ -145,6 +150,9 @@ const MAX_IMAGE_ATTACHMENTS = 10;
 const MAX_IMAGE_BYTES = 5 * 1024 * 1024;
 const SIDEBAR_CONVERSATION_LIMIT = 100;
 const RECENT_WORKSPACE_LIMIT = 5;
+// COLLABORATION_HEARTBEAT_INTERVAL_MS keeps this tab listed as a viewer well
+// within the 30 second presence window of the server.
+const COLLABORATION_HEARTBEAT_INTERVAL_MS = 10_000;
 const AUTO_SCROLL_BOTTOM_THRESHOLD = 80;
 const SUPPORTED_IMAGE_TYPES = new Set([
 	"image/png",
@@ -468,6 +476,9 @@ const ChatPage: React.FC = () => {
 		useState<UIRequestDialogState | null>(null);
 	const [uiInputSubmitting, setUIInputSubmitting] = useState(false);
 	const [statusTick, setStatusTick] = useState(0);
+	const [collaboration, setCollaboration] =
+		useState<CollaborationState | null>(null);
+	const [collaborationBusy, setCollaborationBusy] = useState(false);
 	const loadedConversationId = conversation?.id ?? null;
 	const transcriptEndRef = useRef<HTMLDivElement | null>(null);
 	const shouldAutoScrollRef = useRef(true);
@@ -972,16 +983,24 @@ const ChatPage: React.FC = () => {
 
 		setConversationLoading(true);
 		setConversationError(null);
+		setMessages([]);
 
-		void apiService
-			.getConversation(conversationId)
-			.then((data) => {
+		// Messages are rendered page by page; conversationLoading stays set
+		// until the last page so the resume stream starts on the full history.
+		const controller = new AbortController();
+		void loadConversation(conversationId, {
+			signal: controller.signal,
+			onPage: (data) => {
 				const normalizedConversation = normalizeConversation(data);
 				setActiveConversationId(normalizedConversation.id);
 				setConversation(normalizedConversation);
 				setMessages(conversationToChatMessages(normalizedConversation));
-			})
+			},
+		})
 			.catch((error: unknown) => {
+				if (controller.signal.aborted) {
+					return;
+				}
 				const message =
 					error instanceof Error
 						? error.message
@@ -989,8 +1008,14 @@ const ChatPage: React.FC = () => {
 				setConversationError(message);
 			})
 			.finally(() => {
-				setConversationLoading(false);
+				if (!controller.signal.aborted) {
+					setConversationLoading(false);
+				}
 			});
+
+		return () => {
+			controller.abort();
+		};
 	}, [conversationId]);
 
 	useEffect(() => {
@@ -1036,6 +1061,13 @@ const ChatPage: React.FC = () => {
 						return;
 					}
 
+					if (event.kind === "collaboration") {
+						if (event.collaboration) {
+							setCollaboration(event.collaboration);
+						}
+						return;
+					}
+
 					sawEvent = true;
 					if (event.kind === "conversation" && event.conversation_id) {
 						setActiveConversationId(event.conversation_id);
@@ -1125,6 +1157,90 @@ const ChatPage: React.FC = () => {
 		};
 	}, [clearRunningConversation, conversationId, conversationLoading, loadedConversationId, markConversationRunning]);
 
+	// Other browser tabs see this one as a viewer of the open conversation for
+	// as long as it keeps sending heartbeats.
+	useEffect(() => {
+		setCollaboration(null);
+		if (!conversationId) {
+			return;
+		}
+
+		let cancelled = false;
+		const heartbeat = () => {
+			void apiService
+				.heartbeatCollaboration(conversationId)
+				.then((state) => {
+					if (!cancelled) {
+						setCollaboration(state);
+					}
+				})
+				.catch((error) => {
+					console.error("Failed to send collaboration heartbeat", error);
+				});
+		};
+
+		heartbeat();
+		const interval = window.setInterval(
+			heartbeat,
+			COLLABORATION_HEARTBEAT_INTERVAL_MS,
+		);
+		return () => {
+			cancelled = true;
+			window.clearInterval(interval);
+			void apiService.leaveCollaboration(conversationId).catch(() => {});
+		};
+	}, [conversationId]);
+
+	// Conversations run outside this page, for example by `kodelet run`, are
+	// reloaded as the run saves new messages.
+	useEffect(() => {
+		if (
+			!conversationId ||
+			conversationLoading ||
+			loadedConversationId !== conversationId ||
+			currentConversationIsStreaming
+		) {
+			return;
+		}
+
+		let refreshController: AbortController | null = null;
+		const stopFollowing = followConversation(conversationId, {
+			onUpdate: () => {
+				refreshController?.abort();
+				const controller = new AbortController();
+				refreshController = controller;
+
+				void loadConversation(conversationId, { signal: controller.signal })
+					.then((data) => {
+						if (
+							controller.signal.aborted ||
+							viewedConversationIdRef.current !== conversationId
+						) {
+							return;
+						}
+						const normalizedConversation = normalizeConversation(data);
+						setConversation(normalizedConversation);
+						setMessages(conversationToChatMessages(normalizedConversation));
+					})
+					.catch((error: unknown) => {
+						if (!controller.signal.aborted) {
+							console.error("Failed to refresh followed conversation", error);
+						}
+					});
+			},
+		});
+
+		return () => {
+			stopFollowing();
+			refreshController?.abort();
+		};
+	}, [
+		conversationId,
+		conversationLoading,
+		currentConversationIsStreaming,
+		loadedConversationId,
+	]);
+
 	const handleTranscriptScroll = (event: React.UIEvent<HTMLDivElement>) => {
 		shouldAutoScrollRef.current = isScrolledNearBottom(event.currentTarget);
 	};
@@ -1455,25 +1571,39 @@ const ChatPage: React.FC = () => {
 
 			try {
 				const queuedContent = buildUserContent(prompt, attachmentsForSubmit);
-				await apiService.steerConversation(
+				const response = await apiService.steerConversation(
 					targetConversationId,
 					prompt,
 					queuedContent,
 				);
-				setConversation((currentConversation) =>
-					currentConversation?.id === targetConversationId
-						? {
-								...currentConversation,
-								pendingSteer: [
-									...(currentConversation.pendingSteer || []),
-									{ role: "user", content: queuedContent },
-								],
-							}
-						: currentConversation,
-				);
+				if (!response.resumed) {
+					setConversation((currentConversation) =>
+						currentConversation?.id === targetConversationId
+							? {
+									...currentConversation,
+									pendingSteer: [
+										...(currentConversation.pendingSteer || []),
+										{ role: "user", content: queuedContent },
+									],
+								}
+							: currentConversation,
+					);
+				}
 				setDraft("");
 				setAttachments([]);
-				showToast("Steering queued for the active conversation", "success");
+				if (response.resumed) {
+					showToast(
+						"Conversation was idle — started a follow-up run with your steering",
+						"success",
+					);
+				} else if (response.idle) {
+					showToast(
+						"Conversation idle — steering will be used on next resume",
+						"info",
+					);
+				} else {
+					showToast("Steering queued for the active conversation", "success");
+				}
 			} catch (error) {
 				const message =
 					error instanceof Error
@@ -1630,6 +1760,13 @@ const ChatPage: React.FC = () => {
 							return;
 						}
 
+						if (event.kind === "collaboration") {
+							if (shouldUpdateCurrentView && event.collaboration) {
+								setCollaboration(event.collaboration);
+							}
+							return;
+						}
+
 						if (event.kind === "usage" && event.usage) {
 							if (shouldUpdateCurrentView) {
 								setConversation((currentConversation) =>
@@ -1694,7 +1831,7 @@ const ChatPage: React.FC = () => {
 					finishedOnStartedConversation)
 			) {
 				const latestConversation = normalizeConversation(
-					await apiService.getConversation(streamedConversationId),
+					await loadConversation(streamedConversationId),
 				);
 				setConversation(latestConversation);
 				setMessages(conversationToChatMessages(latestConversation));
@@ -1796,6 +1933,25 @@ const ChatPage: React.FC = () => {
 		}
 	};
 
+	const runCollaborationAction = (
+		action: (id: string) => Promise<CollaborationState>,
+	) => {
+		if (!conversationId || collaborationBusy) {
+			return;
+		}
+
+		setCollaborationBusy(true);
+		void action(conversationId)
+			.then((state) => setCollaboration(state))
+			.catch((error) => {
+				showToast(
+					error instanceof Error ? error.message : "Collaboration request failed",
+					"error",
+				);
+			})
+			.finally(() => setCollaborationBusy(false));
+	};
+
 	const handleStop = () => {
 		const conversationToStop = activeRunningConversationId;
 		if (!conversationToStop) {
@@ -1915,11 +2071,15 @@ const ChatPage: React.FC = () => {
 	};
 
 	const heading = useMemo(() => {
+		const title = getConversationTitle(conversation);
+		if (title) {
+			return title;
+		}
 		if (conversation?.summary) {
 			return conversation.summary;
 		}
 		return getGreeting();
-	}, [conversation?.summary]);
+	}, [conversation]);
 
 	const currentProfileLabel = useMemo(() => {
 		if (conversationId) {
@@ -2158,6 +2318,11 @@ const ChatPage: React.FC = () => {
 	);
 
 	const hasActiveConversationTarget = Boolean(activeRunningConversationId);
+	const drivenByAnotherClient = Boolean(
+		conversationId &&
+			collaboration?.driver &&
+			collaboration.driver.id !== apiService.clientId,
+	);
 	const canSteerActiveConversation = hasActiveConversationTarget;
 	const isSteeringMode =
 		currentConversationIsStreaming && canSteerActiveConversation;
@@ -2165,17 +2330,21 @@ const ChatPage: React.FC = () => {
 		? draft.trim().length > 0
 		: draft.trim().length > 0 || attachments.length > 0;
 	const canStopActiveConversation =
-		currentConversationIsStreaming && Boolean(activeRunningConversationId);
+		currentConversationIsStreaming &&
+		Boolean(activeRunningConversationId) &&
+		!drivenByAnotherClient;
 	const canStartNewChat = !currentConversationIsStarting;
-	const composerPlaceholder = currentConversationIsStreaming
-		? !activeRunningConversationId
-			? "Waiting for conversation to start…"
-			: canSteerActiveConversation
-				? "Steer the active conversation…"
-				: "Add your guidance here..."
-		: activeSlashCommand
-			? getSlashCommandPlaceholder(activeSlashCommand)
-			: "Ask kodelet anything...";
+	const composerPlaceholder = drivenByAnotherClient
+		? "Another tab is driving this conversation; request control to take part…"
+		: currentConversationIsStreaming
+			? !activeRunningConversationId
+				? "Waiting for conversation to start…"
+				: canSteerActiveConversation
+					? "Steer the active conversation…"
+					: "Add your guidance here..."
+			: activeSlashCommand
+				? getSlashCommandPlaceholder(activeSlashCommand)
+				: "Ask kodelet anything...";
 	const composerSlashUsageHint =
 		!currentConversationIsStreaming && !steering && activeSlashCommand
 			? getSlashCommandPlaceholder(activeSlashCommand)
@@ -2487,7 +2656,7 @@ const ChatPage: React.FC = () => {
 						data-testid="chat-transcript-scroll"
 						onScroll={handleTranscriptScroll}
 					>
-						{conversationLoading ? (
+						{conversationLoading && messages.length === 0 ? (
 							<div className="flex min-h-full items-center justify-center px-6 py-12">
 								<div className="surface-panel rounded-2xl px-6 py-5 text-sm text-kodelet-dark/70">
 									Loading conversation…
@@ -2509,6 +2678,14 @@ const ChatPage: React.FC = () => {
 									isStreaming={currentConversationIsStreaming}
 									messages={messages}
 								/>
+								{conversationLoading ? (
+									<p
+										className="px-4 py-3 text-center text-xs text-kodelet-dark/60"
+										data-testid="transcript-loading-more"
+									>
+										Loading more messages…
+									</p>
+								) : null}
 								{composerMetaText ? (
 									<div className="transcript-meta-strip-shell">
 										<div className="mx-auto w-full max-w-5xl px-4 md:px-8">
@@ -2528,10 +2705,30 @@ const ChatPage: React.FC = () => {
 						)}
 					</div>
 
+					{conversationId ? (
+						<CollaborationBar
+							busy={collaborationBusy}
+							clientId={apiService.clientId}
+							state={collaboration}
+							onRelease={() =>
+								runCollaborationAction((id) => apiService.releaseControl(id))
+							}
+							onRequestTakeover={() =>
+								runCollaborationAction((id) => apiService.requestTakeover(id))
+							}
+							onRespondToTakeover={(accept) =>
+								runCollaborationAction((id) =>
+									apiService.respondToTakeover(id, accept),
+								)
+							}
+						/>
+					) : null}
+
 					<ChatComposer
 						addImageDisabled={
 							(currentConversationIsStreaming && !canSteerActiveConversation) ||
-							steering
+							steering ||
+							drivenByAnotherClient
 						}
 						attachments={attachments}
 						canStop={canStopActiveConversation}
@@ -2552,10 +2749,11 @@ const ChatPage: React.FC = () => {
 						submitActionLabel={submitActionLabel}
 						submitDisabled={
 							steering ||
+							drivenByAnotherClient ||
 							!canSubmit ||
 							(currentConversationIsStreaming && !canSteerActiveConversation)
 						}
-						textareaDisabled={steering}
+						textareaDisabled={steering || drivenByAnotherClient}
 						onAttachImages={appendAttachments}
 						onContextOpen={() => {
 							setNewChatProfileDraft(currentProfileLabel);
@@ -220,6 +220,20 @@ describe("ApiService", () => {
 			);
 			expect(result).toEqual(mockConversation);
 		});
+
+		it("requests a page of messages", async () => {
+			mockFetch.mockResolvedValueOnce({
+				ok: true,
+				json: async () => ({ id: "123", messages: [], messageCount: 250, hasMore: true }),
+			});
+
+			await apiService.getConversation("123", { after: 100, limit: 100 });
+
+			expect(mockFetch).toHaveBeenCalledWith(
+				"/api/conversations/123?after=100&limit=100",
+				expect.any(Object),
+			);
+		});
 	});
 
 	describe("getChatSettings", () => {
@@ -375,6 +389,75 @@ describe("ApiService", () => {
 		});
 	});
 
+	describe("collaboration", () => {
+		it("joins with the credentials the server issues", async () => {
+			const state = {
+				conversation_id: "conv-123",
+				driver: { id: "client-ada", name: "Ada", last_seen: "" },
+				viewers: [],
+			};
+			mockFetch.mockResolvedValueOnce({
+				ok: true,
+				status: 200,
+				json: async () => ({
+					...state,
+					client_id: "client-ada",
+					client_secret: "secret-ada",
+				}),
+			});
+
+			const result = await apiService.heartbeatCollaboration("conv-123", "Ada");
+
+			expect(mockFetch).toHaveBeenCalledWith(
+				"/api/conversations/conv-123/collaboration/presence",
+				expect.objectContaining({
+					method: "POST",
+					body: JSON.stringify({ name: "Ada" }),
+				}),
+			);
+			expect(result).toEqual(state);
+			expect(apiService.clientId).toBe("client-ada");
+			expect(
+				JSON.parse(
+					window.sessionStorage.getItem("kodelet.collaboration.client") ?? "{}",
+				),
+			).toEqual({ id: "client-ada", secret: "secret-ada" });
+
+			mockFetch.mockResolvedValueOnce({
+				ok: true,
+				json: async () => ({ data: "test" }),
+			});
+			await apiService.getConversations();
+
+			expect(mockFetch).toHaveBeenLastCalledWith(
+				"/api/conversations",
+				expect.objectContaining({
+					headers: expect.objectContaining({
+						"X-Kodelet-Client-Secret": "secret-ada",
+					}),
+				}),
+			);
+		});
+
+		it("answers takeover requests", async () => {
+			mockFetch.mockResolvedValueOnce({
+				ok: true,
+				status: 200,
+				json: async () => ({ conversation_id: "conv-123", viewers: [] }),
+			});
+
+			await apiService.respondToTakeover("conv-123", true);
+
+			expect(mockFetch).toHaveBeenCalledWith(
+				"/api/conversations/conv-123/collaboration/takeover/respond",
+				expect.objectContaining({
+					method: "POST",
+					body: JSON.stringify({ accept: true }),
+				}),
+			);
+		});
+	});
+
 	describe("steerConversation", () => {
 		it("queues steering for an existing conversation", async () => {
 			mockFetch.mockResolvedValueOnce({
@@ -458,7 +541,7 @@ describe("ApiService", () => {
 
 			mockFetch.mockResolvedValueOnce({
 				ok: true,
-				json: async () => mockToolResult,
+				json: async () => ({ toolCallId: "tool-123", result: mockToolResult }),
 			});
 
 			const result = await apiService.getToolResult("conv-123", "tool-123");
@@ -7,6 +7,9 @@ import {
 	ChatRequest,
 	ContentBlock,
 	ChatStreamEvent,
+	CollaborationCredentials,
+	CollaborationPresence,
+	CollaborationState,
 	Conversation,
 	ConversationListResponse,
 	SearchFilters,
@@ -20,19 +23,65 @@ import {
 	UIInputResponseResult,
 } from "../types";
 
+const collaborationClientHeader = "X-Kodelet-Client-Secret";
+const collaborationClientStorageKey = "kodelet.collaboration.client";
+
+// loadCollaborationClient restores the credentials the server issued to this
+// browser tab. They are kept in sessionStorage so a reload keeps control of
+// the conversation while other tabs join as clients of their own.
+const loadCollaborationClient = (): CollaborationCredentials => {
+	try {
+		const stored = window.sessionStorage.getItem(collaborationClientStorageKey);
+		if (stored) {
+			const parsed = JSON.parse(stored) as Partial<CollaborationCredentials>;
+			if (parsed.id && parsed.secret) {
+				return { id: parsed.id, secret: parsed.secret };
+			}
+		}
+	} catch {
+		// sessionStorage can be unavailable, e.g. in sandboxed iframes.
+	}
+	return { id: "", secret: "" };
+};
+
+const storeCollaborationClient = (client: CollaborationCredentials): void => {
+	try {
+		window.sessionStorage.setItem(
+			collaborationClientStorageKey,
+			JSON.stringify(client),
+		);
+	} catch {
+		// Fall back to credentials that last as long as the page.
+	}
+};
+
 class ApiService {
 	private baseUrl = "";
 
+	private collaborationClient = loadCollaborationClient();
+
+	// clientId is the public ID collaborators see for this tab. It is known
+	// once the first presence heartbeat has issued the tab's credentials.
+	get clientId(): string {
+		return this.collaborationClient.id;
+	}
+
+	private collaborationHeaders(): Record<string, string> {
+		const { secret } = this.collaborationClient;
+		return secret ? { [collaborationClientHeader]: secret } : {};
+	}
+
 	private async request<T>(
 		endpoint: string,
 		options: RequestInit = {},
 	): Promise<T> {
 		const response = await fetch(`${this.baseUrl}${endpoint}`, {
+			...options,
 			headers: {
 				"Content-Type": "application/json",
+				...this.collaborationHeaders(),
 				...options.headers,
 			},
-			...options,
 		});
 
 		if (!response.ok) {
@@ -107,8 +156,20 @@ class ApiService {
 		return response;
 	}
 
-	async getConversation(id: string): Promise<Conversation> {
-		return this.request<Conversation>(`/api/conversations/${id}`);
+	async getConversation(
+		id: string,
+		page?: { after?: number; limit?: number },
+		signal?: AbortSignal,
+	): Promise<Conversation> {
+		const params = new URLSearchParams();
+		if (page?.after !== undefined) params.append("after", page.after.toString());
+		if (page?.limit !== undefined) params.append("limit", page.limit.toString());
+
+		const queryString = params.toString();
+		return this.request<Conversation>(
+			`/api/conversations/${id}${queryString ? `?${queryString}` : ""}`,
+			signal ? { signal } : {},
+		);
 	}
 
 	async getChatSettings(profile?: string): Promise<ChatSettings> {
@@ -243,9 +304,72 @@ class ApiService {
 		conversationId: string,
 		toolCallId: string,
 	): Promise<ToolResult> {
-		return this.request(
+		const response = await this.request<{ toolCallId: string; result: ToolResult }>(
 			`/api/conversations/${conversationId}/tools/${toolCallId}`,
 		);
+		return response.result;
+	}
+
+	async heartbeatCollaboration(
+		id: string,
+		name?: string,
+	): Promise<CollaborationState> {
+		const presence = await this.request<CollaborationPresence>(
+			`/api/conversations/${id}/collaboration/presence`,
+			{
+				method: "POST",
+				body: JSON.stringify(name ? { name } : {}),
+			},
+		);
+		const { client_id: clientId, client_secret: clientSecret, ...state } =
+			presence;
+		if (clientId && clientSecret) {
+			this.collaborationClient = { id: clientId, secret: clientSecret };
+			storeCollaborationClient(this.collaborationClient);
+		}
+		return state;
+	}
+
+	async leaveCollaboration(id: string): Promise<void> {
+		await this.request(`/api/conversations/${id}/collaboration/presence`, {
+			method: "DELETE",
+			keepalive: true,
+		});
+	}
+
+	async requestTakeover(
+		id: string,
+		name?: string,
+	): Promise<CollaborationState> {
+		return this.request<CollaborationState>(
+			`/api/conversations/${id}/collaboration/takeover`,
+			{
+				method: "POST",
+				body: JSON.stringify(name ? { name } : {}),
+			},
+		);
+	}
+
+	async respondToTakeover(
+		id: string,
+		accept: boolean,
+	): Promise<CollaborationState> {
+		return this.request<CollaborationState>(
+			`/api/conversations/${id}/collaboration/takeover/respond`,
+			{
+				method: "POST",
+				body: JSON.stringify({ accept }),
+			},
+		);
+	}
+
+	async releaseControl(id: string): Promise<CollaborationState> {
+		return this.request<CollaborationState>(
+			`/api/conversations/${id}/collaboration/release`,
+			{
+				method: "POST",
+			},
+		);
 	}
 
 	async streamChat(
@@ -259,6 +383,7 @@ class ApiService {
 			method: "POST",
 			headers: {
 				"Content-Type": "application/json",
+				...this.collaborationHeaders(),
 			},
 			body: JSON.stringify(request),
 			signal: options.signal,
@@ -320,6 +445,7 @@ class ApiService {
 			`/api/conversations/${conversationId}/stream`,
 			{
 				method: "GET",
+				headers: this.collaborationHeaders(),
 				signal: options.signal,
 			},
 		);
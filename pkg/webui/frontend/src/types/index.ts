@@ -1,6 +1,7 @@
 // Core types for the Kodelet Web UI
 
 export interface Message {
+	seq?: number; // 1-based position in the conversation, set when loaded from the API
 	role: "user" | "assistant";
 	content: string | ContentBlock[];
 	toolCalls?: ToolCall[];
@@ -35,6 +36,9 @@ export interface ToolResult {
 	metadataType?: string;
 	success: boolean;
 	error?: string;
+	deferred?: boolean; // Too large to inline; fetch the full result on demand
+	size?: number;
+	rendered?: string; // Server-rendered text of a deferred result
 	metadata?:
 		| FileMetadata
 		| ApplyPatchMetadata
@@ -76,6 +80,7 @@ export interface Conversation {
 	createdAt: string;
 	updatedAt: string;
 	messageCount: number;
+	hasMore?: boolean; // More messages follow this page
 	summary?: string;
 	provider?: string;
 	cwd?: string;
@@ -149,6 +154,8 @@ export interface SteerConversationResponse {
 	success: boolean;
 	conversation_id: string;
 	queued: boolean;
+	idle?: boolean;
+	resumed?: boolean;
 }
 
 export interface StopConversationResponse {
@@ -285,6 +292,7 @@ export interface ChatStreamEvent {
 		| "tool-use"
 		| "tool-update"
 		| "tool-result"
+		| "collaboration"
 		| "done"
 		| "error";
 	conversation_id?: string;
@@ -300,9 +308,44 @@ export interface ChatStreamEvent {
 	ui_confirm?: UIConfirmRequestEvent;
 	ui_select?: UISelectRequestEvent;
 	ui_notify?: UINotifyEvent;
+	collaboration?: CollaborationState;
 	error?: string;
 }
 
+export interface CollaborationClient {
+	id: string;
+	name?: string;
+	last_seen: string;
+}
+
+export interface CollaborationTakeover {
+	client_id: string;
+	name?: string;
+	requested_at: string;
+	granted_at: string;
+}
+
+export interface CollaborationState {
+	conversation_id: string;
+	driver?: CollaborationClient;
+	viewers: CollaborationClient[];
+	takeover?: CollaborationTakeover;
+}
+
+// CollaborationCredentials identify this browser tab to the server. Only the
+// public id is shown to collaborators; the secret authenticates the tab.
+export interface CollaborationCredentials {
+	id: string;
+	secret: string;
+}
+
+// CollaborationPresence answers a presence heartbeat with the tab's
+// credentials, which the server issues when the tab joins.
+export interface CollaborationPresence extends CollaborationState {
+	client_id: string;
+	client_secret: string;
+}
+
 export interface UIInputRequestEvent {
 	id: string;
 	conversationId?: string;
@@ -368,6 +411,7 @@ export interface ChatRenderToolCall {
 	name: string;
 	input: string;
 	result?: ToolResult;
+	conversationId?: string;
 	inProgress?: boolean;
 }
 
@@ -388,6 +432,13 @@ export interface FileMetadata {
 	totalLines?: number;
 	remainingLines?: number;
 	truncated?: boolean;
+	outline?: boolean;
+	binary?: BinaryContent;
+}
+
+export interface BinaryContent {
+	size: number;
+	type: string;
 }
 
 export interface ApplyPatchMetadata {
@@ -400,6 +451,7 @@ export interface ApplyPatchChange {
 	oldContent?: string;
 	newContent?: string;
 	unifiedDiff?: string;
+	inverseDiff?: string;
 	movePath?: string;
 }
 
@@ -409,6 +461,8 @@ export interface BashMetadata {
 	exitCode?: number;
 	executionTime?: number;
 	workingDir?: string;
+	container?: string; // Dev container the command ran in
+	containerDir?: string;
 	truncation?: {
 		truncated: boolean;
 		totalLines: number;
@@ -416,6 +470,7 @@ export interface BashMetadata {
 		maxBytes: number;
 	};
 	fullOutputPath?: string;
+	binary?: BinaryContent;
 }
 
 export interface GrepMetadata {
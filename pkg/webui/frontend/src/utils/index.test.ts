@@ -3,6 +3,7 @@ import {
   formatDate,
   formatCompactRelativeTime,
   formatCost,
+  getConversationTitle,
   copyToClipboard,
   showToast,
   escapeHtml,
@@ -255,6 +256,18 @@ describe('showToast', () => {
   });
 });
 
+describe('getConversationTitle', () => {
+  it('returns the title stored in metadata', () => {
+    expect(getConversationTitle({ metadata: { title: ' Fix Flaky Login Test ' } })).toBe('Fix Flaky Login Test');
+  });
+
+  it('returns empty string without a title', () => {
+    expect(getConversationTitle({ metadata: { title: 42 } })).toBe('');
+    expect(getConversationTitle({})).toBe('');
+    expect(getConversationTitle(null)).toBe('');
+  });
+});
+
 describe('escapeHtml', () => {
   it('escapes HTML special characters', () => {
     expect(escapeHtml('<div>Test & "quotes"</div>')).toBe('&lt;div&gt;Test &amp; "quotes"&lt;/div&gt;');
@@ -1,7 +1,7 @@
 // Utility functions for Kodelet Web UI
 
 import { format, formatDistanceToNow } from 'date-fns';
-import { Usage } from '../types';
+import { Conversation, Usage } from '../types';
 
 const formatCompactNumber = (value: number): string => {
   return new Intl.NumberFormat('en-US', {
@@ -11,6 +11,14 @@ const formatCompactNumber = (value: number): string => {
 };
 
 // Date formatting utility
+// Title generated at the first exchange, stored in conversation metadata.
+export const getConversationTitle = (
+  conversation: Pick<Conversation, 'metadata'> | null | undefined
+): string => {
+  const title = conversation?.metadata?.title;
+  return typeof title === 'string' ? title.trim() : '';
+};
+
 export const formatDate = (dateString: string | null | undefined): string => {
   if (!dateString) return 'N/A';
 
@@ -0,0 +1,139 @@
+package webui
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/extensions"
+	"github.com/jingkaihe/kodelet/pkg/llm"
+	"github.com/jingkaihe/kodelet/pkg/logger"
+)
+
+// Probe endpoints. They are served without authentication so infrastructure
+// probes need no token.
+const (
+	healthzPath = "/healthz"
+	readyzPath  = "/readyz"
+)
+
+// Readiness check results.
+const (
+	checkOK   = "ok"
+	checkFail = "fail"
+)
+
+// storeCheckTimeout bounds the conversation store query of a readiness check.
+const storeCheckTimeout = 5 * time.Second
+
+// readinessCheck is the result of one readiness check.
+type readinessCheck struct {
+	Status     string               `json:"status"`
+	Error      string               `json:"error,omitempty"`
+	Extensions []webExtensionStatus `json:"extensions,omitempty"`
+	Depth      *int                 `json:"depth,omitempty"`
+}
+
+type readinessResponse struct {
+	Status string                    `json:"status"`
+	Checks map[string]readinessCheck `json:"checks,omitempty"`
+}
+
+func isProbePath(path string) bool {
+	return path == healthzPath || path == readyzPath
+}
+
+// handleHealthz handles GET /healthz. It succeeds while the server is
+// serving requests.
+func (s *Server) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSONResponse(w, readinessResponse{Status: checkOK})
+}
+
+// handleReadyz handles GET /readyz. It answers 503 unless the conversation
+// store is reachable, the default profile's provider credentials load, and no
+// extension is disabled. The individual checks are only included for
+// authenticated requests, since their errors may describe the configuration.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	checks := s.readinessChecks(r.Context())
+
+	response := readinessResponse{Status: checkOK}
+	for name, check := range checks {
+		if check.Status != checkOK {
+			response.Status = checkFail
+			logger.G(r.Context()).WithField("check", name).WithField("error", check.Error).Debug("readiness check failed")
+		}
+	}
+	if s.readinessDetailsVisible(r) {
+		response.Checks = checks
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if response.Status != checkOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	s.writeJSONResponse(w, response)
+}
+
+func (s *Server) readinessDetailsVisible(r *http.Request) bool {
+	if s.config == nil {
+		return true
+	}
+	authToken := strings.TrimSpace(s.config.AuthToken)
+	return authToken == "" || requestHasAuthToken(r, authToken)
+}
+
+func (s *Server) readinessChecks(ctx context.Context) map[string]readinessCheck {
+	return map[string]readinessCheck{
+		"store":      s.checkStore(ctx),
+		"provider":   checkProviderCredentials(),
+		"extensions": s.checkExtensions(),
+		"queue":      s.checkQueue(),
+	}
+}
+
+func (s *Server) checkStore(ctx context.Context) readinessCheck {
+	ctx, cancel := context.WithTimeout(ctx, storeCheckTimeout)
+	defer cancel()
+	if _, err := s.conversationService.ListConversations(ctx, &conversations.ListConversationsRequest{Limit: 1}); err != nil {
+		return readinessCheck{Status: checkFail, Error: err.Error()}
+	}
+	return readinessCheck{Status: checkOK}
+}
+
+// checkProviderCredentials builds the LLM client a new chat would use, which
+// fails when the provider's credentials are missing or cannot be loaded. It
+// does not send a request to the provider.
+func checkProviderCredentials() readinessCheck {
+	config, err := llm.GetConfigFromViper()
+	if err != nil {
+		return readinessCheck{Status: checkFail, Error: err.Error()}
+	}
+	thread, err := llm.NewThread(config)
+	if err != nil {
+		return readinessCheck{Status: checkFail, Error: err.Error()}
+	}
+	_ = llm.CloseThread(thread)
+	return readinessCheck{Status: checkOK}
+}
+
+func (s *Server) checkExtensions() readinessCheck {
+	check := readinessCheck{Status: checkOK, Extensions: s.extensionRuntimes.Statuses()}
+	for _, status := range check.Extensions {
+		if status.State == extensions.ProcessDisabled {
+			check.Status = checkFail
+			check.Error = "extension " + status.ExtensionID + " is disabled after repeated failures"
+			break
+		}
+	}
+	return check
+}
+
+// checkQueue reports how many chat runs are in progress.
+func (s *Server) checkQueue() readinessCheck {
+	s.activeChatsMu.Lock()
+	depth := len(s.activeChats)
+	s.activeChatsMu.Unlock()
+	return readinessCheck{Status: checkOK, Depth: &depth}
+}
@@ -0,0 +1,103 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jingkaihe/kodelet/pkg/conversations"
+	"github.com/jingkaihe/kodelet/pkg/db"
+	"github.com/jingkaihe/kodelet/pkg/db/migrations"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHealthTestServer(t *testing.T) *Server {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	t.Setenv("KODELET_CONVERSATION_STORE_TYPE", "sqlite")
+	require.NoError(t, db.RunMigrations(context.Background(), migrations.All()))
+	server, err := NewServer(context.Background(), &ServerConfig{
+		Host:         "127.0.0.1",
+		Port:         1,
+		CWD:          t.TempDir(),
+		CompactRatio: 0.8,
+		AuthToken:    "token",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, server.Stop()) })
+	return server
+}
+
+func getReadyz(t *testing.T, server *Server, authenticated bool) (int, readinessResponse) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	if authenticated {
+		req.Header.Set("Authorization", "Bearer token")
+	}
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	var response readinessResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return w.Code, response
+}
+
+func TestServerHealthzSkipsAuth(t *testing.T) {
+	server := newHealthTestServer(t)
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestServerReadyz(t *testing.T) {
+	server := newHealthTestServer(t)
+
+	t.Run("ready", func(t *testing.T) {
+		t.Setenv("OPENAI_API_KEY", "sk-test")
+		code, response := getReadyz(t, server, true)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, checkOK, response.Status)
+		for _, name := range []string{"store", "provider", "extensions", "queue"} {
+			assert.Equal(t, checkOK, response.Checks[name].Status, name)
+		}
+		require.NotNil(t, response.Checks["queue"].Depth)
+		assert.Equal(t, 0, *response.Checks["queue"].Depth)
+	})
+
+	t.Run("missing provider credentials", func(t *testing.T) {
+		t.Setenv("OPENAI_API_KEY", "")
+		code, response := getReadyz(t, server, true)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, checkFail, response.Checks["provider"].Status)
+		assert.Contains(t, response.Checks["provider"].Error, "OPENAI_API_KEY")
+	})
+
+	t.Run("unreachable store", func(t *testing.T) {
+		t.Setenv("OPENAI_API_KEY", "sk-test")
+		original := server.conversationService
+		t.Cleanup(func() { server.conversationService = original })
+		server.conversationService = &mockConversationService{
+			listFunc: func(context.Context, *conversations.ListConversationsRequest) (*conversations.ListConversationsResponse, error) {
+				return nil, errors.New("database is locked")
+			},
+		}
+
+		code, response := getReadyz(t, server, true)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "database is locked", response.Checks["store"].Error)
+
+		code, response = getReadyz(t, server, false)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, checkFail, response.Status)
+		assert.Empty(t, response.Checks, "checks are only shown to authenticated requests")
+	})
+}
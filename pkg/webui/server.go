@@ -31,11 +31,16 @@ import (
 	"github.com/jingkaihe/kodelet/pkg/extensions"
 	"github.com/jingkaihe/kodelet/pkg/fragments"
 	"github.com/jingkaihe/kodelet/pkg/goals"
+	"github.com/jingkaihe/kodelet/pkg/llm"
+	kodeletanthropic "github.com/jingkaihe/kodelet/pkg/llm/anthropic"
+	kodeletopenai "github.com/jingkaihe/kodelet/pkg/llm/openai"
 	openairesponses "github.com/jingkaihe/kodelet/pkg/llm/openai/responses"
 	"github.com/jingkaihe/kodelet/pkg/logger"
 	"github.com/jingkaihe/kodelet/pkg/presenter"
 	"github.com/jingkaihe/kodelet/pkg/slashcommands"
 	"github.com/jingkaihe/kodelet/pkg/steer"
+	"github.com/jingkaihe/kodelet/pkg/telemetry"
+	"github.com/jingkaihe/kodelet/pkg/todos"
 	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
 	tooltypes "github.com/jingkaihe/kodelet/pkg/types/tools"
 	"github.com/pkg/errors"
@@ -64,6 +69,8 @@ type Server struct {
 	activeChatsMu       sync.Mutex
 	chatSubscribers     map[string]map[*subscriberEventSink]struct{}
 	chatSubscribersMu   sync.Mutex
+	collaboration       *collaborationHub
+	collaborationOnce   sync.Once
 }
 
 type activeChatRun struct {
@@ -103,6 +110,9 @@ type ServerConfig struct {
 	CompactRatio float64
 	AuthToken    string
 	CORSOrigins  []string
+	// AutoResumeSteer starts a follow-up run when steering is submitted to an
+	// idle conversation instead of leaving it queued until the next resume.
+	AutoResumeSteer bool
 }
 
 // Validate validates the server configuration
@@ -188,6 +198,7 @@ func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
 		extensionRuntimes: extensionRuntimes,
 		activeChats:       make(map[string]*activeChatRun),
 		chatSubscribers:   make(map[string]map[*subscriberEventSink]struct{}),
+		collaboration:     newCollaborationHub(),
 	}
 	if runner, ok := s.chatRunner.(*webUIChatRunner); ok {
 		runner.server = s
@@ -211,15 +222,34 @@ func (s *Server) setupRoutes() {
 	api.HandleFunc("/conversations", s.handleListConversations).Methods("GET")
 	api.HandleFunc("/conversations/{id}", s.handleGetConversation).Methods("GET")
 	api.HandleFunc("/conversations/{id}/stream", s.handleStreamConversation).Methods("GET")
+	api.HandleFunc("/conversations/{id}/follow", s.handleFollowConversation).Methods("GET")
 	api.HandleFunc("/conversations/{id}/fork", s.handleForkConversation).Methods("POST")
 	api.HandleFunc("/conversations/{id}/steer", s.handleGetPendingSteer).Methods("GET")
 	api.HandleFunc("/conversations/{id}/steer", s.handleSteerConversation).Methods("POST")
 	api.HandleFunc("/conversations/{id}/stop", s.handleStopConversation).Methods("POST")
 	api.HandleFunc("/conversations/{id}/ui-input/{requestId}", s.handleRespondUIInput).Methods("POST")
+	api.HandleFunc("/conversations/{id}/collaboration", s.handleGetCollaboration).Methods("GET")
+	api.HandleFunc("/conversations/{id}/collaboration/presence", s.handleCollaborationHeartbeat).Methods("POST")
+	api.HandleFunc("/conversations/{id}/collaboration/presence", s.handleCollaborationLeave).Methods("DELETE")
+	api.HandleFunc("/conversations/{id}/collaboration/takeover", s.handleCollaborationTakeover).Methods("POST")
+	api.HandleFunc("/conversations/{id}/collaboration/takeover/respond", s.handleCollaborationTakeoverResponse).Methods("POST")
+	api.HandleFunc("/conversations/{id}/collaboration/release", s.handleCollaborationRelease).Methods("POST")
 	api.HandleFunc("/conversations/{id}/tools/{toolCallId}", s.handleGetToolResult).Methods("GET")
 	api.HandleFunc("/conversations/{id}", s.handleDeleteConversation).Methods("DELETE")
 	api.HandleFunc("/chat", s.handleChat).Methods("POST")
 
+	// OpenAI-compatible API
+	v1 := s.router.PathPrefix("/v1").Subrouter()
+	v1.HandleFunc("/models", s.handleListModels).Methods("GET")
+	v1.HandleFunc("/chat/completions", s.handleChatCompletions).Methods("POST")
+
+	// Liveness and readiness probes
+	s.router.HandleFunc(healthzPath, s.handleHealthz).Methods("GET")
+	s.router.HandleFunc(readyzPath, s.handleReadyz).Methods("GET")
+
+	// Prometheus metrics of the threads and tool calls of this server
+	s.router.Handle(telemetry.MetricsPath, telemetry.MetricsHandler()).Methods("GET")
+
 	// Static assets from the React build
 	s.router.PathPrefix("/assets/").Handler(s.staticFileHandler())
 
@@ -300,7 +330,8 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 		w.Header().Set("Access-Control-Allow-Origin", origin)
 		w.Header().Set("Vary", "Origin")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+chatCompletionsConversationHeader+", "+collaborationClientHeader)
+		w.Header().Set("Access-Control-Expose-Headers", chatCompletionsConversationHeader)
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -481,7 +512,7 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodOptions {
+		if r.Method == http.MethodOptions || isProbePath(r.URL.Path) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -602,6 +633,10 @@ func (s *Server) writeAuthError(w http.ResponseWriter, r *http.Request, statusCo
 		s.writeErrorResponse(w, statusCode, message, nil)
 		return
 	}
+	if strings.HasPrefix(r.URL.Path, "/v1/") {
+		writeChatCompletionsError(w, statusCode, message, "invalid_request_error", "invalid_api_key")
+		return
+	}
 
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(statusCode)
@@ -934,6 +969,7 @@ type WebConversationResponse struct {
 	PendingSteer          []WebMessage `json:"pendingSteer,omitempty"`
 	ToolResults           any          `json:"toolResults,omitempty"`
 	MessageCount          int          `json:"messageCount"`
+	HasMore               bool         `json:"hasMore,omitempty"`
 }
 
 // ChatProfileOption represents a selectable profile in the web UI.
@@ -978,6 +1014,7 @@ const (
 
 // WebMessage represents a message with structured tool calls for the web UI
 type WebMessage struct {
+	Seq           int           `json:"seq,omitempty"`
 	Role          string        `json:"role"`
 	Content       any           `json:"content"`
 	ToolCalls     []WebToolCall `json:"toolCalls,omitempty"`
@@ -1131,15 +1168,12 @@ func resolveConversationReasoningEffort(response *conversations.GetConversationR
 }
 
 func getGlobalProfiles() map[string]llmtypes.ProfileConfig {
-	v := viper.New()
-	v.SetConfigName("config")
-	v.SetConfigType("yaml")
-
-	homeDir, err := os.UserHomeDir()
+	configFile, err := llm.GlobalConfigFile()
 	if err != nil {
 		return nil
 	}
-	v.AddConfigPath(filepath.Join(homeDir, ".kodelet"))
+	v := viper.New()
+	v.SetConfigFile(configFile)
 
 	if err := v.ReadInConfig(); err != nil {
 		return nil
@@ -1150,9 +1184,7 @@ func getGlobalProfiles() map[string]llmtypes.ProfileConfig {
 
 func getRepoProfiles() map[string]llmtypes.ProfileConfig {
 	v := viper.New()
-	v.SetConfigName("kodelet-config")
-	v.SetConfigType("yaml")
-	v.AddConfigPath(".")
+	v.SetConfigFile(llm.RepoConfigFile())
 
 	if err := v.ReadInConfig(); err != nil {
 		return nil
@@ -1500,6 +1532,12 @@ func (s *Server) handleGetConversation(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	page, err := parseMessagePage(r.URL.Query())
+	if err != nil {
+		s.writeErrorResponse(w, http.StatusBadRequest, "invalid message page", err)
+		return
+	}
+
 	// Get conversation
 	response, err := s.conversationService.GetConversation(ctx, id)
 	if err != nil {
@@ -1522,9 +1560,15 @@ func (s *Server) handleGetConversation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pendingSteer, err := pendingSteerWebMessages(ctx, id)
-	if err != nil {
-		logger.G(ctx).WithError(err).WithField("conversation_id", id).Warn("failed to read pending steering messages")
+	messageCount := len(webMessages)
+	webMessages, hasMore := page.apply(webMessages)
+
+	var pendingSteer []WebMessage
+	if !hasMore {
+		pendingSteer, err = pendingSteerWebMessages(ctx, id)
+		if err != nil {
+			logger.G(ctx).WithError(err).WithField("conversation_id", id).Warn("failed to read pending steering messages")
+		}
 	}
 
 	// Convert to web response format
@@ -1545,8 +1589,9 @@ func (s *Server) handleGetConversation(w http.ResponseWriter, r *http.Request) {
 		Usage:                 response.Usage,
 		Messages:              webMessages,
 		PendingSteer:          pendingSteer,
-		ToolResults:           response.ToolResults,
-		MessageCount:          len(webMessages),
+		ToolResults:           pageToolResults(webMessages, response.ToolResults),
+		MessageCount:          messageCount,
+		HasMore:               hasMore,
 	}
 
 	s.writeJSONResponse(w, webResponse)
@@ -1806,11 +1851,9 @@ func (s *Server) extractAnthropicContent(rawMessage json.RawMessage) (any, strin
 			}
 		}
 		// Handle thinking blocks
-		if thinkingBlock := contentBlock.OfThinking; thinkingBlock != nil {
-			thinkingText = thinkingBlock.Thinking
-			if strings.TrimSpace(thinkingBlock.Thinking) != "" {
-				thinkingTexts = append(thinkingTexts, thinkingBlock.Thinking)
-			}
+		if reasoning, ok := kodeletanthropic.ReasoningFromBlock(contentBlock); ok && reasoning.DisplayText() != "" {
+			thinkingText = reasoning.DisplayText()
+			thinkingTexts = append(thinkingTexts, thinkingText)
 		}
 	}
 
@@ -1883,7 +1926,10 @@ func (s *Server) extractOpenAIContent(rawMessage json.RawMessage) (any, string,
 		return "", "", errors.Wrap(err, "failed to deserialize OpenAI message")
 	}
 
-	thinkingText := strings.TrimLeft(openaiMessage.ReasoningContent, "\n")
+	var thinkingText string
+	if reasoning, ok := kodeletopenai.ReasoningFromMessage(openaiMessage); ok {
+		thinkingText = reasoning.DisplayText()
+	}
 
 	// OpenAI messages have simple string content or multimodal content
 	if openaiMessage.Content != "" {
@@ -2011,6 +2057,10 @@ type steerConversationResponse struct {
 	Success        bool   `json:"success"`
 	ConversationID string `json:"conversation_id"`
 	Queued         bool   `json:"queued"`
+	// Idle is true when no run is active to consume the steering.
+	Idle bool `json:"idle"`
+	// Resumed is true when a follow-up run was started for an idle conversation.
+	Resumed bool `json:"resumed"`
 }
 
 // handleSteerConversation handles POST /api/conversations/{id}/steer
@@ -2052,6 +2102,9 @@ func (s *Server) handleSteerConversation(w http.ResponseWriter, r *http.Request)
 		)
 		return
 	}
+	if !s.authorizeDriver(w, r, conversationID) {
+		return
+	}
 
 	steerStore, err := steer.NewSteerStore(ctx)
 	if err != nil {
@@ -2066,11 +2119,98 @@ func (s *Server) handleSteerConversation(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	s.writeJSONResponse(w, steerConversationResponse{
+	response := steerConversationResponse{
 		Success:        true,
 		ConversationID: conversationID,
 		Queued:         queued,
-	})
+	}
+	if !s.hasActiveChatRun(conversationID) {
+		active, err := steerStore.IsRunActive(ctx, conversationID)
+		if err != nil {
+			logger.G(ctx).WithError(err).Warn("failed to check whether the steered conversation is running")
+		}
+		response.Idle = err == nil && !active
+	}
+	if response.Idle && s.config != nil && s.config.AutoResumeSteer {
+		response.Resumed, err = s.startSteerFollowUp(ctx, steerStore, conversationID)
+		if err != nil {
+			s.writeErrorResponse(w, http.StatusInternalServerError, "failed to resume idle conversation", err)
+			return
+		}
+	}
+
+	s.writeJSONResponse(w, response)
+}
+
+// startSteerFollowUp starts a background run of an idle conversation with its
+// queued steering as the prompt. Events are broadcast to stream subscribers.
+func (s *Server) startSteerFollowUp(requestCtx context.Context, steerStore *steer.Store, conversationID string) (bool, error) {
+	ctx, cancel := context.WithCancel(s.chatExecutionContext(requestCtx))
+	run := newActiveChatRun(cancel)
+	if !s.registerActiveChat(conversationID, run) {
+		cancel()
+		return false, nil
+	}
+
+	messages, err := steerStore.Consume(requestCtx, conversationID)
+	if err != nil || len(messages) == 0 {
+		s.unregisterActiveChat(conversationID, run)
+		cancel()
+		return false, err
+	}
+	prompt, images := steer.FollowUpPrompt(messages)
+
+	// The request's store is closed when the handler returns, so the run gets
+	// its own to put the steering back in the queue if it fails.
+	requeueStore, err := steer.NewSteerStore(requestCtx)
+	if err != nil {
+		_ = steerStore.Requeue(requestCtx, conversationID, messages)
+		s.unregisterActiveChat(conversationID, run)
+		cancel()
+		return false, err
+	}
+
+	sink := &broadcastingEventSink{
+		primary:        discardEventSink{},
+		broadcast:      s.broadcastChatEvent,
+		conversationID: conversationID,
+	}
+	run.uiInput = newWebUIInputBroker(conversationID, sink)
+
+	go func() {
+		defer s.unregisterActiveChat(conversationID, run)
+		defer s.closeChatSubscribers(conversationID)
+		defer cancel()
+		defer requeueStore.Close()
+
+		req := ChatRequest{
+			Message:        prompt,
+			Content:        chat.ContentBlocksForUserInput(prompt, images),
+			ConversationID: conversationID,
+		}
+		if _, err := s.chatRunner.Run(ctx, req, sink); err != nil {
+			logger.G(ctx).WithError(err).Error("steering follow-up run failed")
+			message := err.Error()
+			if requeueErr := requeueStore.Requeue(context.WithoutCancel(ctx), conversationID, messages); requeueErr != nil {
+				logger.G(ctx).WithError(requeueErr).Error("failed to requeue steering after follow-up run failed")
+			} else {
+				message += "; the steering was queued again"
+			}
+			s.broadcastChatEvent(conversationID, ChatEvent{
+				Kind:           "error",
+				ConversationID: conversationID,
+				Role:           "assistant",
+				Error:          message,
+			})
+			return
+		}
+		s.broadcastChatEvent(conversationID, ChatEvent{
+			Kind:           "done",
+			ConversationID: conversationID,
+			Role:           "assistant",
+		})
+	}()
+	return true, nil
 }
 
 // handleStopConversation handles POST /api/conversations/{id}/stop
@@ -2081,6 +2221,10 @@ func (s *Server) handleStopConversation(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !s.authorizeDriver(w, r, conversationID) {
+		return
+	}
+
 	_, stopped := s.cancelActiveChat(conversationID)
 	s.writeJSONResponse(w, stopConversationResponse{
 		Success:        true,
@@ -2121,6 +2265,10 @@ func (s *Server) handleRespondUIInput(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.authorizeDriver(w, r, conversationID) {
+		return
+	}
+
 	response := extensions.UIInputResponse{Status: status, Value: req.Value}
 	if strings.EqualFold(strings.TrimSpace(req.Value), "true") {
 		response.Confirmed = true
@@ -2288,6 +2436,9 @@ func applyWebContentDisplay(content any, metadata map[string]any, consumedDispla
 
 	switch value := content.(type) {
 	case string:
+		if todos.IsReminderText(value) {
+			return ""
+		}
 		if goals.IsContextText(value) {
 			if display, ok := consumeWebContentDisplay(metadata, consumedDisplays, value); ok {
 				return []WebContentBlock{webContentBlockForDisplay(display)}
@@ -2303,6 +2454,12 @@ func applyWebContentDisplay(content any, metadata map[string]any, consumedDispla
 			if block.Type != "text" || strings.TrimSpace(block.Text) == "" {
 				continue
 			}
+			if todos.IsReminderText(block.Text) {
+				blocks := make([]WebContentBlock, len(value))
+				copy(blocks, value)
+				blocks[index] = WebContentBlock{Type: "text"}
+				return blocks
+			}
 			if goals.IsContextText(block.Text) {
 				blocks := make([]WebContentBlock, len(value))
 				copy(blocks, value)
@@ -312,6 +312,32 @@ func TestNewServerInitializesRoutesAndNormalizesConfig(t *testing.T) {
 	assert.Contains(t, w.Body.String(), "<html")
 }
 
+func TestServerServesMetricsBehindAuth(t *testing.T) {
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	t.Setenv("KODELET_CONVERSATION_STORE_TYPE", "sqlite")
+	config := &ServerConfig{
+		Host:         "127.0.0.1",
+		Port:         1,
+		CWD:          t.TempDir(),
+		CompactRatio: 0.8,
+		AuthToken:    "token",
+	}
+	server, err := NewServer(context.Background(), config)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, server.Stop()) })
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "go_goroutines")
+}
+
 func TestNewAuthTokenGeneratesUsableToken(t *testing.T) {
 	token, err := NewAuthToken()
 
@@ -1911,6 +1937,109 @@ func TestServer_handleSteerConversation(t *testing.T) {
 	assert.Empty(t, pending[0].Images)
 }
 
+func TestServer_handleSteerConversationReportsIdleConversation(t *testing.T) {
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	require.NoError(t, db.RunMigrations(context.Background(), migrations.All()))
+
+	server := &Server{
+		conversationService: &mockConversationService{},
+		router:              mux.NewRouter(),
+		config:              &ServerConfig{},
+	}
+
+	req := httptest.NewRequest("POST", "/api/conversations/conv-idle/steer", strings.NewReader(`{"message":"one more thing"}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "conv-idle"})
+	w := httptest.NewRecorder()
+	server.handleSteerConversation(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var response steerConversationResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(t, response.Idle)
+	assert.False(t, response.Resumed)
+
+	steerStore, err := steer.NewSteerStore(context.Background())
+	require.NoError(t, err)
+	defer steerStore.Close()
+	pending, err := steerStore.Peek(context.Background(), "conv-idle")
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+}
+
+func TestServer_handleSteerConversationResumesIdleConversation(t *testing.T) {
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	require.NoError(t, db.RunMigrations(context.Background(), migrations.All()))
+
+	requests := make(chan ChatRequest, 1)
+	server := &Server{
+		conversationService: &mockConversationService{},
+		router:              mux.NewRouter(),
+		config:              &ServerConfig{AutoResumeSteer: true},
+		activeChats:         make(map[string]*activeChatRun),
+		chatSubscribers:     make(map[string]map[*subscriberEventSink]struct{}),
+		chatRunner: &mockChatRunner{runFunc: func(_ context.Context, req ChatRequest, _ ChatEventSink) (string, error) {
+			requests <- req
+			return req.ConversationID, nil
+		}},
+	}
+
+	req := httptest.NewRequest("POST", "/api/conversations/conv-idle/steer", strings.NewReader(`{"message":"one more thing"}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "conv-idle"})
+	w := httptest.NewRecorder()
+	server.handleSteerConversation(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var response steerConversationResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(t, response.Idle)
+	assert.True(t, response.Resumed)
+
+	select {
+	case chatReq := <-requests:
+		assert.Equal(t, "conv-idle", chatReq.ConversationID)
+		assert.Equal(t, "one more thing", chatReq.Message)
+	case <-time.After(5 * time.Second):
+		t.Fatal("follow-up run was not started")
+	}
+
+	steerStore, err := steer.NewSteerStore(context.Background())
+	require.NoError(t, err)
+	defer steerStore.Close()
+	pending, err := steerStore.Peek(context.Background(), "conv-idle")
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestServer_handleSteerConversationRequeuesSteeringWhenFollowUpFails(t *testing.T) {
+	t.Setenv("KODELET_BASE_PATH", t.TempDir())
+	require.NoError(t, db.RunMigrations(context.Background(), migrations.All()))
+
+	server := &Server{
+		conversationService: &mockConversationService{},
+		router:              mux.NewRouter(),
+		config:              &ServerConfig{AutoResumeSteer: true},
+		activeChats:         make(map[string]*activeChatRun),
+		chatSubscribers:     make(map[string]map[*subscriberEventSink]struct{}),
+		chatRunner: &mockChatRunner{runFunc: func(context.Context, ChatRequest, ChatEventSink) (string, error) {
+			return "", errors.New("provider unavailable")
+		}},
+	}
+
+	req := httptest.NewRequest("POST", "/api/conversations/conv-idle/steer", strings.NewReader(`{"message":"one more thing"}`))
+	req = mux.SetURLVars(req, map[string]string{"id": "conv-idle"})
+	w := httptest.NewRecorder()
+	server.handleSteerConversation(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	steerStore, err := steer.NewSteerStore(context.Background())
+	require.NoError(t, err)
+	defer steerStore.Close()
+	require.Eventually(t, func() bool {
+		pending, err := steerStore.Peek(context.Background(), "conv-idle")
+		return err == nil && len(pending) == 1 && pending[0].Content == "one more thing"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
 func TestServer_handleSteerConversationWithImageContent(t *testing.T) {
 	homeDir := t.TempDir()
 	t.Setenv("KODELET_BASE_PATH", homeDir)
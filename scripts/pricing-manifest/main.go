@@ -0,0 +1,145 @@
+// Command pricing-manifest writes the signed pricing manifest published with
+// each release. The manifest holds the built-in prices of this build, so
+// older binaries running `kodelet models refresh-pricing` pick them up.
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jingkaihe/kodelet/pkg/llm/anthropic"
+	codexpreset "github.com/jingkaihe/kodelet/pkg/llm/openai/preset/codex"
+	openaipreset "github.com/jingkaihe/kodelet/pkg/llm/openai/preset/openai"
+	"github.com/jingkaihe/kodelet/pkg/pricing"
+	llmtypes "github.com/jingkaihe/kodelet/pkg/types/llm"
+	"github.com/pkg/errors"
+)
+
+// privateKeyEnv holds the base64-encoded ed25519 private key, or its seed,
+// that the manifest is signed with.
+const privateKeyEnv = "KODELET_PRICING_PRIVATE_KEY"
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	outDir := flag.String("out", ".build/pricing", "directory to write pricing.json and pricing.json.sig to")
+	flag.Parse()
+
+	privateKey, err := loadPrivateKey(os.Getenv(privateKeyEnv))
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(buildManifest(time.Now().UTC()), "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode pricing manifest")
+	}
+	if _, err := pricing.ParseManifest(data); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		return errors.Wrap(err, "failed to create output directory")
+	}
+	manifestPath := filepath.Join(*outDir, "pricing.json")
+	if err := os.WriteFile(manifestPath, data, 0o644); err != nil {
+		return errors.Wrap(err, "failed to write pricing manifest")
+	}
+	if err := os.WriteFile(manifestPath+".sig", pricing.Sign(data, privateKey), 0o644); err != nil {
+		return errors.Wrap(err, "failed to write pricing manifest signature")
+	}
+	fmt.Println(manifestPath)
+	return nil
+}
+
+func loadPrivateKey(encoded string) (ed25519.PrivateKey, error) {
+	if strings.TrimSpace(encoded) == "" {
+		return nil, errors.Errorf("%s is not set", privateKeyEnv)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode %s", privateKeyEnv)
+	}
+	switch len(key) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(key), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(key), nil
+	default:
+		return nil, errors.Errorf("%s must be an ed25519 private key or seed", privateKeyEnv)
+	}
+}
+
+// buildManifest lists the built-in Anthropic, OpenAI and Codex prices. They
+// carry no start date, so each applies until a later manifest replaces it.
+func buildManifest(publishedAt time.Time) pricing.Manifest {
+	manifest := pricing.Manifest{Version: pricing.ManifestVersion, PublishedAt: publishedAt}
+	for model, prices := range anthropic.ModelPricingMap {
+		manifest.Models = append(manifest.Models, modelPrices("anthropic", string(model), "", fromAnthropicPricing(prices)))
+	}
+	for _, platform := range []struct {
+		name     string
+		standard llmtypes.CustomPricing
+		priority llmtypes.CustomPricing
+	}{
+		{name: "openai", standard: openaipreset.Pricing, priority: openaipreset.PriorityPricing},
+		{name: "codex", standard: codexpreset.Pricing, priority: codexpreset.PriorityPricing},
+	} {
+		for model, prices := range platform.standard {
+			manifest.Models = append(manifest.Models, modelPrices(platform.name, model, "", prices))
+		}
+		for model, prices := range platform.priority {
+			manifest.Models = append(manifest.Models, modelPrices(platform.name, model, string(llmtypes.OpenAIServiceTierPriority), prices))
+		}
+	}
+
+	sort.Slice(manifest.Models, func(i, j int) bool {
+		a, b := manifest.Models[i], manifest.Models[j]
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		if a.Model != b.Model {
+			return a.Model < b.Model
+		}
+		return a.ServiceTier < b.ServiceTier
+	})
+	return manifest
+}
+
+func modelPrices(provider, model, serviceTier string, prices llmtypes.ModelPricing) pricing.ModelPrices {
+	return pricing.ModelPrices{
+		Provider:    provider,
+		Model:       model,
+		ServiceTier: serviceTier,
+		Periods:     []pricing.PricePeriod{{ModelPricing: prices}},
+	}
+}
+
+func fromAnthropicPricing(p anthropic.ModelPricing) llmtypes.ModelPricing {
+	return llmtypes.ModelPricing{
+		Input:                      p.Input,
+		Output:                     p.Output,
+		CachedInput:                p.PromptCachingRead,
+		CacheWriteInput:            p.PromptCachingWrite5m,
+		CacheWrite1hInput:          p.PromptCachingWrite1h,
+		ContextWindow:              p.ContextWindow,
+		LongContextThreshold:       p.LongContextThreshold,
+		LongContextInput:           p.LongContextInput,
+		LongContextOutput:          p.LongContextOutput,
+		LongContextCachedInput:     p.LongContextPromptCachingRead,
+		LongContextCacheWriteInput: p.LongContextPromptCachingWrite5m,
+	}
+}
@@ -15,4 +15,5 @@ trap cleanup EXIT
 "$SCRIPT_DIR/extract-release-notes.sh" > "$RELEASE_NOTES_FILE"
 
 cd "$REPO_ROOT"
+go run ./scripts/pricing-manifest -out .build/pricing
 goreleaser release --clean --release-notes "$RELEASE_NOTES_FILE"
@@ -201,6 +201,25 @@ test("preserves explicit zero timeout and merges event timeout options", async (
   );
 });
 
+test("marks an event durable when any handler asks for it", async () => {
+  const extension = defineExtension((ext) => {
+    ext.on("turn.end", async () => undefined);
+    ext.on("turn.end", { durable: true }, async () => undefined);
+    ext.on("session.start", async () => undefined);
+  });
+
+  const harness = await createTestHarness(extension);
+  const init = harness.initialize({ extension: { id: "audit", cwd: process.cwd() } });
+
+  assert.deepEqual(
+    init.subscriptions.sort((a, b) => a.event.localeCompare(b.event)),
+    [
+      { event: "session.start", priority: 0 },
+      { event: "turn.end", priority: 0, durable: true },
+    ],
+  );
+});
+
 test("agent.init can patch the system prompt and tool list", async () => {
   const extension = defineExtension((ext) => {
     ext.on("agent.init", () => ({
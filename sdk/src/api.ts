@@ -39,6 +39,7 @@ interface RegisteredEventHandler {
   event: EventName;
   priority: number;
   timeoutInSec?: number;
+  durable: boolean;
   order: number;
   handler: EventHandler<EventName>;
 }
@@ -102,6 +103,7 @@ export class ExtensionHost implements ExtensionAPI {
       event,
       priority: options.priority ?? 0,
       timeoutInSec: options.timeoutInSec,
+      durable: options.durable ?? false,
       order: this.order++,
       handler: handler as EventHandler<EventName>,
     });
@@ -216,22 +218,28 @@ export class ExtensionHost implements ExtensionAPI {
   }
 
   private subscriptions(): InitializeResult["subscriptions"] {
-    const byEvent = new Map<string, { priority: number; timeoutInSec?: number }>();
+    const byEvent = new Map<string, { priority: number; timeoutInSec?: number; durable: boolean }>();
     for (const handler of this.handlers) {
       const previous = byEvent.get(handler.event);
       if (previous === undefined) {
-        byEvent.set(handler.event, { priority: handler.priority, timeoutInSec: handler.timeoutInSec });
+        byEvent.set(handler.event, {
+          priority: handler.priority,
+          timeoutInSec: handler.timeoutInSec,
+          durable: handler.durable,
+        });
         continue;
       }
       byEvent.set(handler.event, {
         priority: Math.max(previous.priority, handler.priority),
         timeoutInSec: mergeTimeoutInSec(previous.timeoutInSec, handler.timeoutInSec),
+        durable: previous.durable || handler.durable,
       });
     }
     return [...byEvent.entries()].map(([event, options]) => ({
       event,
       priority: options.priority,
       ...optionalTimeout(options.timeoutInSec),
+      ...(options.durable ? { durable: true } : {}),
     }));
   }
 }
@@ -0,0 +1,100 @@
+import { AsyncLocalStorage } from "node:async_hooks";
+import { appendFile, mkdir } from "node:fs/promises";
+import os from "node:os";
+import path from "node:path";
+import type { FetchLike } from "@modelcontextprotocol/sdk/shared/transport.js";
+
+// EgressEntry mirrors the JSONL entries written by Kodelet's Go egress audit log.
+export interface EgressEntry {
+  v: number;
+  ts: string;
+  conversation_id?: string;
+  tool: string;
+  method: string;
+  domain: string;
+  url: string;
+  status?: number;
+  bytes_sent: number;
+  bytes_received: number;
+  error?: string;
+}
+
+const egressLogVersion = 1;
+const egressConversation = new AsyncLocalStorage<string | undefined>();
+
+export function egressLogPath(): string {
+  const basePath = process.env.KODELET_BASE_PATH?.trim();
+  if (basePath) {
+    return path.join(basePath, "audit", "egress.jsonl");
+  }
+  return path.join(os.homedir(), ".kodelet", "audit", "egress.jsonl");
+}
+
+// withEgressConversation attributes requests made while fn runs to conversationId.
+export async function withEgressConversation<Result>(conversationId: string | undefined, fn: () => Promise<Result>): Promise<Result> {
+  return await egressConversation.run(conversationId, fn);
+}
+
+// auditedMCPFetch records every request sent to an MCP HTTP server in the egress audit log.
+export function auditedMCPFetch(serverName: string, baseFetch: FetchLike = fetch): FetchLike {
+  return async (url, init) => {
+    const requestUrl = new URL(url);
+    const entry: EgressEntry = {
+      v: egressLogVersion,
+      ts: new Date().toISOString(),
+      conversation_id: egressConversation.getStore() || undefined,
+      tool: `mcp:${serverName}`,
+      method: (init?.method ?? "GET").toUpperCase(),
+      domain: requestUrl.hostname,
+      url: redactURL(requestUrl),
+      bytes_sent: bodySize(init?.body),
+      bytes_received: 0,
+    };
+    try {
+      const response = await baseFetch(url, init);
+      entry.status = response.status;
+      entry.bytes_received = Number(response.headers.get("content-length")) || 0;
+      await recordEgress(entry);
+      return response;
+    } catch (error) {
+      entry.error = error instanceof Error ? error.message : String(error);
+      await recordEgress(entry);
+      throw error;
+    }
+  };
+}
+
+export async function recordEgress(entry: EgressEntry, logPath = egressLogPath()): Promise<void> {
+  try {
+    await mkdir(path.dirname(logPath), { recursive: true, mode: 0o700 });
+    // A single append keeps lines intact when several processes write the log.
+    await appendFile(logPath, `${JSON.stringify(entry)}\n`, { encoding: "utf8", mode: 0o600 });
+  } catch (error) {
+    console.error(`failed to record egress audit entry: ${error instanceof Error ? error.message : String(error)}`);
+  }
+}
+
+function redactURL(url: URL): string {
+  const redacted = new URL(url.href);
+  redacted.username = "";
+  redacted.password = "";
+  redacted.search = "";
+  redacted.hash = "";
+  return redacted.href;
+}
+
+function bodySize(body: unknown): number {
+  if (typeof body === "string") {
+    return Buffer.byteLength(body);
+  }
+  if (body instanceof ArrayBuffer) {
+    return body.byteLength;
+  }
+  if (ArrayBuffer.isView(body)) {
+    return body.byteLength;
+  }
+  if (body instanceof URLSearchParams) {
+    return Buffer.byteLength(body.toString());
+  }
+  return 0;
+}
@@ -28,7 +28,9 @@ export interface MCPServerConfig {
   envs?: Record<string, string>;
   url?: string;
   headers?: Record<string, string>;
+  bearer_token?: string;
   oauth?: MCPOAuthConfig;
+  health_check_interval?: string | number;
   tool_white_list?: string[];
 }
 
@@ -2,11 +2,12 @@ import { Client } from "@modelcontextprotocol/sdk/client/index.js";
 import { UnauthorizedError } from "@modelcontextprotocol/sdk/client/auth.js";
 import { SSEClientTransport } from "@modelcontextprotocol/sdk/client/sse.js";
 import { StdioClientTransport } from "@modelcontextprotocol/sdk/client/stdio.js";
-import { StreamableHTTPClientTransport } from "@modelcontextprotocol/sdk/client/streamableHttp.js";
+import { StreamableHTTPClientTransport, StreamableHTTPError } from "@modelcontextprotocol/sdk/client/streamableHttp.js";
 import type { FetchLike } from "@modelcontextprotocol/sdk/shared/transport.js";
-import type { Tool } from "@modelcontextprotocol/sdk/types.js";
+import { ErrorCode, McpError, type Tool } from "@modelcontextprotocol/sdk/types.js";
 
-import type { ExtensionAPI } from "../../types.js";
+import type { ExtensionAPI, ToolResultImage } from "../../types.js";
+import { auditedMCPFetch, withEgressConversation } from "./audit.js";
 import type { MCPConfig, MCPOAuthGlobalConfig, MCPServerConfig } from "./config.js";
 import { KodeletMCPOAuthProvider } from "./oauth.js";
 
@@ -14,6 +15,9 @@ type MCPTransport = StdioClientTransport | StreamableHTTPClientTransport | SSECl
 
 export const mcpToolTimeoutInSec = 600;
 export const mcpToolRequestTimeoutMs = mcpToolTimeoutInSec * 1000;
+export const mcpHealthCheckTimeoutMs = 10_000;
+export const mcpReconnectAttempts = 3;
+export const mcpReconnectBackoffMs = 500;
 
 interface TransportBundle {
   transport: MCPTransport;
@@ -22,35 +26,60 @@ interface TransportBundle {
 
 interface ConnectedServer {
   name: string;
+  config: MCPServerConfig;
+  globalOAuth?: MCPOAuthGlobalConfig;
   client: Client;
   transport: MCPTransport;
   whiteList: string[];
   oauthProvider?: KodeletMCPOAuthProvider;
   authorization?: Promise<void>;
   authorizationGeneration: number;
+  // connected is cleared when the transport closes, for example because a
+  // stdio server exited, so the next call reconnects first.
+  connected: boolean;
+  reconnection?: Promise<void>;
+  healthCheck?: NodeJS.Timeout;
+  healthCheckRunning?: boolean;
+  closed?: boolean;
 }
 
+// registerMCP starts every configured server concurrently, so startup waits
+// for the slowest server rather than for all of them in turn. Tools are then
+// registered in server name order to keep the tool list stable.
 export async function registerMCP(ext: ExtensionAPI, config: MCPConfig): Promise<void> {
-  const servers = config.mcpServers ?? {};
+  const servers = Object.entries(config.mcpServers ?? {}).sort(([a], [b]) => a.localeCompare(b));
   const connectedServers: ConnectedServer[] = [];
-
-  for (const [serverName, serverConfig] of Object.entries(servers).sort(([a], [b]) => a.localeCompare(b))) {
+  const started = await Promise.all(servers.map(async ([serverName, serverConfig]) => {
     try {
-      const connected = await connectServer(serverName, serverConfig, config.oauth);
-      connectedServers.push(connected);
-      await registerServerTools(ext, connected);
+      const server = await connectServer(serverName, serverConfig, config.oauth);
+      connectedServers.push(server);
+      return { server, tools: await listServerTools(server) };
     } catch (error) {
-      process.stderr.write(
-        `${JSON.stringify({ level: "warn", extension: "mcp", message: "failed to initialize MCP server", server: serverName, error: errorMessage(error) })}\n`,
-      );
+      logMCP("warn", "failed to initialize MCP server", serverName, error);
+      return undefined;
     }
+  }));
+
+  for (const entry of started) {
+    if (!entry) {
+      continue;
+    }
+    registerServerTools(ext, entry.server, entry.tools);
+    startHealthCheck(entry.server);
   }
 
   ext.on("session.end", { timeoutInSec: 10 }, async () => {
-    await Promise.allSettled(connectedServers.map((server) => closeConnectedServer(server)));
+    await Promise.allSettled(connectedServers.map((server) => shutdownServer(server)));
   });
 }
 
+async function shutdownServer(server: ConnectedServer): Promise<void> {
+  server.closed = true;
+  clearInterval(server.healthCheck);
+  await Promise.allSettled([server.reconnection]);
+  await closeConnectedServer(server);
+}
+
 async function closeConnectedServer(server: ConnectedServer): Promise<void> {
   if (server.transport instanceof StreamableHTTPClientTransport) {
     await Promise.allSettled([server.transport.terminateSession()]);
@@ -58,6 +87,12 @@ async function closeConnectedServer(server: ConnectedServer): Promise<void> {
   await Promise.allSettled([server.client.close(), server.oauthProvider?.close()]);
 }
 
+function logMCP(level: "info" | "warn", message: string, serverName: string, error?: unknown): void {
+  process.stderr.write(
+    `${JSON.stringify({ level, extension: "mcp", message, server: serverName, ...(error === undefined ? {} : { error: errorMessage(error) }) })}\n`,
+  );
+}
+
 async function connectServer(serverName: string, config: MCPServerConfig, globalOAuth: MCPOAuthGlobalConfig | undefined): Promise<ConnectedServer> {
   const initial = buildTransport(serverName, config, globalOAuth);
   let activeTransport = initial.transport;
@@ -84,16 +119,137 @@ async function connectServer(serverName: string, config: MCPServerConfig, global
     activeTransport = retry.transport;
   }
 
-  return {
+  const server: ConnectedServer = {
     name: serverName,
+    config,
+    globalOAuth,
     client,
     transport: activeTransport,
     whiteList: config.tool_white_list ?? [],
     oauthProvider: initial.oauthProvider,
     authorizationGeneration: 0,
+    connected: true,
+  };
+  watchConnection(server);
+  return server;
+}
+
+function watchConnection(server: ConnectedServer): void {
+  const client = server.client;
+  client.onclose = () => {
+    if (server.client === client) {
+      server.connected = false;
+    }
   };
 }
 
+// reconnectServer replaces the connection of a server whose transport closed
+// or whose session expired. The server keeps its registered tools, so calls
+// made while it reconnects wait for the new connection.
+async function reconnectServer(server: ConnectedServer): Promise<void> {
+  server.reconnection ??= (async () => {
+    server.connected = false;
+    await closeConnectedServer(server);
+
+    let lastError: unknown;
+    for (let attempt = 0; attempt < mcpReconnectAttempts; attempt++) {
+      if (server.closed) {
+        throw new Error(`MCP server ${JSON.stringify(server.name)} is shut down`);
+      }
+      if (attempt > 0) {
+        await sleep(mcpReconnectBackoffMs * 2 ** (attempt - 1));
+      }
+      try {
+        const next = await connectServer(server.name, server.config, server.globalOAuth);
+        if (server.closed) {
+          await closeConnectedServer(next);
+          throw new Error(`MCP server ${JSON.stringify(server.name)} is shut down`);
+        }
+        server.client = next.client;
+        server.transport = next.transport;
+        server.oauthProvider = next.oauthProvider;
+        server.connected = true;
+        watchConnection(server);
+        logMCP("info", "reconnected MCP server", server.name);
+        return;
+      } catch (error) {
+        lastError = error;
+      }
+    }
+    throw new Error(`failed to reconnect MCP server ${JSON.stringify(server.name)}: ${errorMessage(lastError)}`);
+  })().finally(() => {
+    server.reconnection = undefined;
+  });
+  return await server.reconnection;
+}
+
+// withConnection runs a request on a live connection. A server that has
+// disconnected is reconnected before the request. A request rejected because
+// the Streamable HTTP session expired never reached the server, so it is sent
+// again on a new session; a request cut off by a dropped connection may have
+// run, so it fails after the connection is restored.
+async function withConnection<Result>(server: ConnectedServer, request: () => Promise<Result>): Promise<Result> {
+  if (!server.connected || server.reconnection) {
+    await reconnectServer(server);
+  }
+  try {
+    return await request();
+  } catch (error) {
+    if (isSessionExpiredError(error)) {
+      await reconnectServer(server);
+      return await request();
+    }
+    if (!isConnectionError(server, error)) {
+      throw error;
+    }
+    await reconnectServer(server);
+    throw new Error(
+      `lost the connection to MCP server ${JSON.stringify(server.name)} during the call; it has reconnected, but the call may not have completed: ${errorMessage(error)}`,
+    );
+  }
+}
+
+function startHealthCheck(server: ConnectedServer): void {
+  const intervalMs = parseIntervalMs(server.config.health_check_interval);
+  if (intervalMs <= 0) {
+    return;
+  }
+  server.healthCheck = setInterval(() => {
+    void checkServerHealth(server);
+  }, intervalMs);
+  server.healthCheck.unref();
+}
+
+// checkServerHealth pings the server and reconnects it when the ping fails.
+async function checkServerHealth(server: ConnectedServer): Promise<boolean> {
+  if (server.closed || server.healthCheckRunning) {
+    return server.connected;
+  }
+  server.healthCheckRunning = true;
+  try {
+    if (server.connected && !server.reconnection) {
+      try {
+        await server.client.ping({ timeout: mcpHealthCheckTimeoutMs });
+        return true;
+      } catch (error) {
+        if (server.closed) {
+          return false;
+        }
+        logMCP("warn", "MCP server health check failed", server.name, error);
+      }
+    }
+    try {
+      await reconnectServer(server);
+      return true;
+    } catch (error) {
+      logMCP("warn", "failed to reconnect MCP server", server.name, error);
+      return false;
+    }
+  } finally {
+    server.healthCheckRunning = false;
+  }
+}
+
 function buildTransport(
   serverName: string,
   config: MCPServerConfig,
@@ -124,7 +280,7 @@ function buildTransport(
       const url = new URL(config.url);
       return { transport: new SSEClientTransport(url, {
         authProvider: provider,
-        fetch: scopedMCPFetch(url, resolveConfigValues(config.headers)),
+        fetch: scopedMCPFetch(url, remoteHeaders(config), auditedMCPFetch(serverName)),
       }), oauthProvider: provider };
     }
     case "http": {
@@ -135,12 +291,23 @@ function buildTransport(
       const url = new URL(config.url);
       return { transport: new StreamableHTTPClientTransport(url, {
         authProvider: provider,
-        fetch: scopedMCPFetch(url, resolveConfigValues(config.headers)),
+        fetch: scopedMCPFetch(url, remoteHeaders(config), auditedMCPFetch(serverName)),
       }), oauthProvider: provider };
     }
   }
 }
 
+// remoteHeaders returns the configured headers of a remote server, with
+// bearer_token sent as the Authorization header unless one is configured.
+export function remoteHeaders(config: MCPServerConfig): Record<string, string> | undefined {
+  const headers = resolveConfigValues(config.headers);
+  const token = config.bearer_token === undefined ? "" : expandEnvValue(config.bearer_token).trim();
+  if (!token || Object.keys(headers ?? {}).some((name) => name.toLowerCase() === "authorization")) {
+    return headers;
+  }
+  return { ...headers, Authorization: `Bearer ${token}` };
+}
+
 export function scopedMCPFetch(
   serverUrl: URL,
   configuredHeaders: Record<string, string> | undefined,
@@ -193,6 +360,40 @@ function isUnauthorizedError(error: unknown): boolean {
   return error instanceof UnauthorizedError || (error instanceof Error && error.name === "UnauthorizedError");
 }
 
+// isSessionExpiredError reports whether a Streamable HTTP server no longer
+// knows the session, which the MCP specification signals with 404.
+function isSessionExpiredError(error: unknown): boolean {
+  return error instanceof StreamableHTTPError && error.code === 404;
+}
+
+// isConnectionError reports whether a request failed because the connection
+// to the server was lost, as opposed to an error answered by the server.
+function isConnectionError(server: ConnectedServer, error: unknown): boolean {
+  if (error instanceof McpError) {
+    return error.code === ErrorCode.ConnectionClosed;
+  }
+  // fetch rejects with a TypeError when the server cannot be reached.
+  return !server.connected || error instanceof TypeError;
+}
+
+function parseIntervalMs(value: string | number | undefined): number {
+  if (typeof value === "number") {
+    return Number.isFinite(value) && value > 0 ? value : 0;
+  }
+  const match = String(value ?? "").trim().match(/^(\d+(?:\.\d+)?)(ms|s|m|h)?$/i);
+  if (!match) {
+    return 0;
+  }
+  const amount = Number.parseFloat(match[1] ?? "0");
+  const unit = (match[2] ?? "ms").toLowerCase();
+  const multiplier = unit === "h" ? 3_600_000 : unit === "m" ? 60_000 : unit === "s" ? 1000 : 1;
+  return Math.floor(amount * multiplier);
+}
+
+async function sleep(ms: number): Promise<void> {
+  await new Promise((resolve) => setTimeout(resolve, ms));
+}
+
 function normalizeServerType(config: MCPServerConfig): "stdio" | "sse" | "http" {
   const raw = String(config.type ?? config.server_type ?? "").trim().toLowerCase();
   if (raw === "") {
@@ -232,9 +433,13 @@ function expandEnvValue(value: string): string {
   return value.replace(/\$\{([^}]+)\}|\$([A-Za-z_][A-Za-z0-9_]*)/g, (_match, braced: string | undefined, bare: string | undefined) => process.env[braced ?? bare ?? ""] ?? "");
 }
 
-async function registerServerTools(ext: ExtensionAPI, server: ConnectedServer): Promise<void> {
-  const result = await requestWithAuthorization(server, () => server.client.listTools());
-  for (const tool of result.tools) {
+async function listServerTools(server: ConnectedServer): Promise<Tool[]> {
+  const result = await withConnection(server, () => requestWithAuthorization(server, () => server.client.listTools()));
+  return result.tools;
+}
+
+function registerServerTools(ext: ExtensionAPI, server: ConnectedServer, tools: Tool[]): void {
+  for (const tool of tools) {
     if (!toolWhiteListed(tool, server.whiteList)) {
       continue;
     }
@@ -244,9 +449,9 @@ async function registerServerTools(ext: ExtensionAPI, server: ConnectedServer):
       description: tool.description?.trim() || tool.title?.trim() || tool.name,
       inputSchema: tool.inputSchema,
       timeoutInSec: mcpToolTimeoutInSec,
-      async execute(input) {
+      async execute(input, ctx) {
         const start = Date.now();
-        const result = await callServerTool(server, tool.name, input as Record<string, unknown>);
+        const result = await withEgressConversation(ctx.conversationId, () => callServerTool(server, tool.name, input as Record<string, unknown>));
         if ("toolResult" in result) {
           const content = stringifyUnknown(result.toolResult);
           return {
@@ -264,9 +469,11 @@ async function registerServerTools(ext: ExtensionAPI, server: ConnectedServer):
             data: mcpData(server.name, tool.name, input as Record<string, unknown>, contentText, contentBlocks, Date.now() - start),
           };
         }
+        const images = imageBlocks(result.content);
         return {
           content: contentText,
           data: mcpData(server.name, tool.name, input as Record<string, unknown>, contentText, contentBlocks, Date.now() - start),
+          ...(images.length > 0 ? { images } : {}),
         };
       },
     });
@@ -274,7 +481,7 @@ async function registerServerTools(ext: ExtensionAPI, server: ConnectedServer):
 }
 
 async function callServerTool(server: ConnectedServer, toolName: string, input: Record<string, unknown>) {
-  return await requestWithAuthorization(server, () => requestServerTool(server, toolName, input));
+  return await withConnection(server, () => requestWithAuthorization(server, () => requestServerTool(server, toolName, input)));
 }
 
 async function requestWithAuthorization<Result>(server: ConnectedServer, request: () => Promise<Result>): Promise<Result> {
@@ -357,6 +564,16 @@ function normalizeContentBlock(block: unknown): Record<string, string> {
   return { type: "unknown", text: stringifyUnknown(block) };
 }
 
+function imageBlocks(blocks: unknown[]): ToolResultImage[] {
+  const images: ToolResultImage[] = [];
+  for (const block of blocks) {
+    if (isRecord(block) && block.type === "image" && typeof block.data === "string" && typeof block.mimeType === "string") {
+      images.push({ data: `data:${block.mimeType};base64,${block.data}` });
+    }
+  }
+  return images;
+}
+
 function mcpData(
   serverName: string,
   toolName: string,
@@ -97,6 +97,7 @@ export type {
   ToolInputSchema,
   ToolListPatch,
   ToolRegistration,
+  ToolResultImage,
   ToolUpdateRequest,
   ToolResultDetails,
   ToolResultEvent,
@@ -6,9 +6,10 @@ import path from "node:path";
 import test from "node:test";
 
 import mcpExtension from "./extensions/mcp/index.js";
+import { auditedMCPFetch, egressLogPath, withEgressConversation, type EgressEntry } from "./extensions/mcp/audit.js";
 import { loadMCPConfig } from "./extensions/mcp/config.js";
 import { KodeletMCPOAuthProvider } from "./extensions/mcp/oauth.js";
-import { mcpToolRequestTimeoutMs, mcpToolTimeoutInSec, scopedMCPFetch } from "./extensions/mcp/register.js";
+import { mcpToolRequestTimeoutMs, mcpToolTimeoutInSec, remoteHeaders, scopedMCPFetch } from "./extensions/mcp/register.js";
 import { createExtensionHost } from "./api.js";
 
 test("keeps the MCP request timeout aligned with the extension tool timeout", () => {
@@ -358,6 +359,124 @@ test("MCP remote HTTP headers expand environment variables", async () => {
   }
 });
 
+test("MCP remote HTTP servers reconnect when the session expires", async () => {
+  const root = await mkdtemp(path.join(os.tmpdir(), "kodelet-mcp-http-reconnect-"));
+  const oldHome = process.env.HOME;
+  const oldUserProfile = process.env.USERPROFILE;
+  const oldWorkspaceCWD = process.env.KODELET_EXTENSION_WORKSPACE_CWD;
+  const oldToken = process.env.MCP_TEST_BEARER_TOKEN;
+  const receivedAuthHeaders: string[] = [];
+  const expiredSessions = new Set<string>();
+  let sessions = 0;
+
+  const server = http.createServer((req, res) => {
+    if (req.method === "DELETE") {
+      res.writeHead(200).end();
+      return;
+    }
+    if (req.method !== "POST") {
+      res.writeHead(405).end();
+      return;
+    }
+    receivedAuthHeaders.push(req.headers.authorization ?? "");
+
+    let body = "";
+    req.setEncoding("utf8");
+    req.on("data", (chunk) => {
+      body += chunk;
+    });
+    req.on("end", () => {
+      const message = JSON.parse(body) as { id?: string | number; method?: string; params?: Record<string, unknown> };
+      const sessionID = req.headers["mcp-session-id"]?.toString() ?? "";
+      if (message.method !== "initialize" && expiredSessions.has(sessionID)) {
+        res.writeHead(404, { "content-type": "application/json" }).end(JSON.stringify({ jsonrpc: "2.0", error: { code: -32001, message: "Session not found" }, id: null }));
+        return;
+      }
+      if (message.id === undefined) {
+        res.writeHead(202).end();
+        return;
+      }
+
+      const headers: Record<string, string> = { "content-type": "application/json" };
+      let result: Record<string, unknown> = {};
+      if (message.method === "initialize") {
+        sessions++;
+        headers["mcp-session-id"] = `session-${sessions}`;
+        result = { protocolVersion: message.params?.protocolVersion ?? "2024-11-05", capabilities: { tools: {} }, serverInfo: { name: "fake-http", version: "1.0.0" } };
+      } else if (message.method === "tools/list") {
+        result = { tools: [{ name: "whoami", description: "Report the session", inputSchema: { type: "object" } }] };
+      } else if (message.method === "tools/call") {
+        result = { content: [{ type: "text", text: sessionID }] };
+      }
+      res.writeHead(200, headers).end(JSON.stringify({ jsonrpc: "2.0", id: message.id, result }));
+    });
+  });
+
+  try {
+    const home = path.join(root, "home");
+    const workspace = path.join(root, "workspace");
+    await mkdir(path.join(home, ".kodelet"), { recursive: true });
+    await mkdir(workspace, { recursive: true });
+
+    process.env.HOME = home;
+    delete process.env.USERPROFILE;
+    process.env.KODELET_EXTENSION_WORKSPACE_CWD = workspace;
+    process.env.MCP_TEST_BEARER_TOKEN = "bearer-token";
+
+    await new Promise<void>((resolve, reject) => {
+      server.once("error", reject);
+      server.listen(0, "127.0.0.1", () => {
+        server.off("error", reject);
+        resolve();
+      });
+    });
+    const address = server.address();
+    assert(address && typeof address === "object");
+
+    await writeFile(
+      path.join(workspace, "mcp.json"),
+      JSON.stringify({
+        oauth: { interactive: "never" },
+        mcpServers: {
+          remote: {
+            type: "http",
+            url: `http://127.0.0.1:${address.port}/mcp`,
+            bearer_token: "${MCP_TEST_BEARER_TOKEN}",
+          },
+        },
+      }),
+      "utf8",
+    );
+
+    const host = await createExtensionHost(mcpExtension);
+    const init = host.initialize({
+      protocolVersion: "2024-11-05",
+      extension: { id: "mcp", cwd: workspace, dataDir: path.join(root, "data") },
+    });
+    assert.deepEqual(init.tools.map((tool) => tool.name), ["mcp__remote_whoami"]);
+
+    const first = await host.executeTool({ name: "mcp__remote_whoami", input: {}, context: { cwd: workspace } });
+    assert.equal(first.content, "session-1");
+
+    expiredSessions.add("session-1");
+    const second = await host.executeTool({ name: "mcp__remote_whoami", input: {}, context: { cwd: workspace } });
+    assert.equal(second.content, "session-2");
+    assert(receivedAuthHeaders.length > 0);
+    assert(receivedAuthHeaders.every((header) => header === "Bearer bearer-token"));
+
+    await host.handleEvent({ id: "session-end", event: "session.end", context: { cwd: workspace } });
+  } finally {
+    await new Promise<void>((resolve, reject) => {
+      server.close((error) => error ? reject(error) : resolve());
+    }).catch(() => undefined);
+    restoreEnv("HOME", oldHome);
+    restoreEnv("USERPROFILE", oldUserProfile);
+    restoreEnv("KODELET_EXTENSION_WORKSPACE_CWD", oldWorkspaceCWD);
+    restoreEnv("MCP_TEST_BEARER_TOKEN", oldToken);
+    await rm(root, { recursive: true, force: true });
+  }
+});
+
 test("MCP configured headers are scoped and do not override OAuth", async () => {
   const requests: Array<{ url: string; headers: Record<string, string> }> = [];
   const baseFetch = async (url: string | URL, init?: RequestInit): Promise<Response> => {
@@ -388,6 +507,57 @@ test("MCP configured headers are scoped and do not override OAuth", async () =>
   assert.equal(requests[3]?.headers["x-mcp-secret"], undefined);
 });
 
+test("MCP bearer_token is sent unless an Authorization header is configured", () => {
+  const oldToken = process.env.MCP_TEST_BEARER_TOKEN;
+  try {
+    process.env.MCP_TEST_BEARER_TOKEN = "from-env";
+    assert.deepEqual(remoteHeaders({ url: "https://example.com/mcp", bearer_token: "${MCP_TEST_BEARER_TOKEN}", headers: { "X-Team": "core" } }), {
+      "X-Team": "core",
+      Authorization: "Bearer from-env",
+    });
+    assert.deepEqual(remoteHeaders({ url: "https://example.com/mcp", bearer_token: "ignored", headers: { authorization: "Basic abc" } }), {
+      authorization: "Basic abc",
+    });
+    assert.equal(remoteHeaders({ url: "https://example.com/mcp" }), undefined);
+  } finally {
+    restoreEnv("MCP_TEST_BEARER_TOKEN", oldToken);
+  }
+});
+
+test("MCP fetches are recorded in the egress audit log", async () => {
+  const root = await mkdtemp(path.join(os.tmpdir(), "kodelet-mcp-audit-"));
+  const oldBasePath = process.env.KODELET_BASE_PATH;
+  try {
+    process.env.KODELET_BASE_PATH = root;
+    const baseFetch = async (): Promise<Response> => new Response("{}", { status: 200, headers: { "content-length": "2" } });
+    const auditedFetch = auditedMCPFetch("remote", baseFetch);
+
+    await withEgressConversation("conv-mcp", () => auditedFetch("https://mcp.example/rpc?token=secret", {
+      method: "POST",
+      body: JSON.stringify({ jsonrpc: "2.0", id: 1, method: "tools/call" }),
+    }));
+    await assert.rejects(auditedMCPFetch("remote", async () => {
+      throw new Error("connection refused");
+    })("https://mcp.example/rpc"));
+
+    const entries = (await readFile(egressLogPath(), "utf8")).trim().split("\n").map((line) => JSON.parse(line) as EgressEntry);
+    assert.equal(entries.length, 2);
+    assert.equal(entries[0]?.tool, "mcp:remote");
+    assert.equal(entries[0]?.conversation_id, "conv-mcp");
+    assert.equal(entries[0]?.method, "POST");
+    assert.equal(entries[0]?.domain, "mcp.example");
+    assert.equal(entries[0]?.url, "https://mcp.example/rpc");
+    assert.equal(entries[0]?.status, 200);
+    assert.equal(entries[0]?.bytes_received, 2);
+    assert.ok((entries[0]?.bytes_sent ?? 0) > 0);
+    assert.equal(entries[1]?.conversation_id, undefined);
+    assert.equal(entries[1]?.error, "connection refused");
+  } finally {
+    restoreEnv("KODELET_BASE_PATH", oldBasePath);
+    await rm(root, { recursive: true, force: true });
+  }
+});
+
 test("MCP discovery and tool calls complete OAuth challenges and retry", async () => {
   const root = await mkdtemp(path.join(os.tmpdir(), "kodelet-mcp-tool-oauth-"));
   const oldHome = process.env.HOME;
@@ -11,10 +11,19 @@ export interface ExtensionMetadata {
   version?: string;
 }
 
+export interface ToolResultImage {
+  /** Base64-encoded image bytes, or a data URL such as data:image/png;base64,... */
+  data: string;
+  /** Pass "original" to skip downscaling on models that support full-resolution images. */
+  detail?: "original";
+}
+
 export interface ToolExecutionResult {
   content: string;
   data?: Record<string, unknown>;
   error?: string;
+  /** Images passed back to the model along with content, at most 10 per call. */
+  images?: ToolResultImage[];
 }
 
 export interface ToolUpdateRequest {
@@ -213,6 +222,7 @@ export type EventName =
   | "tool.update"
   | "tool.result"
   | "turn.end"
+  | "context.compacted"
   | "agent.end"
   | "session.end"
   | (string & {});
@@ -254,6 +264,13 @@ export interface TurnEndEventPayload {
   turnNumber?: number;
 }
 
+export interface ContextCompactedEventPayload {
+  /** Summary that replaced the history; empty when the provider compacts server-side. */
+  summary: string;
+  /** Messages the summary replaced. */
+  messages?: unknown[];
+}
+
 export interface AgentEndEventPayload {
   messages?: unknown[];
 }
@@ -269,6 +286,7 @@ export interface EventPayloadMap {
   "agent.start": EmptyEventPayload;
   "turn.start": TurnStartEventPayload;
   "turn.end": TurnEndEventPayload;
+  "context.compacted": ContextCompactedEventPayload;
   "agent.end": AgentEndEventPayload;
   "session.end": EmptyEventPayload;
 }
@@ -293,6 +311,7 @@ export type ToolCallEvent = ExtensionEvent<"tool.call">;
 export type ToolUpdateEvent = ExtensionEvent<"tool.update">;
 export type ToolResultEvent = ExtensionEvent<"tool.result">;
 export type TurnEndEvent = ExtensionEvent<"turn.end">;
+export type ContextCompactedEvent = ExtensionEvent<"context.compacted">;
 export type AgentEndEvent = ExtensionEvent<"agent.end">;
 export type SessionEndEvent = ExtensionEvent<"session.end">;
 
@@ -325,6 +344,8 @@ export interface EventResult {
 export interface EventSubscriptionOptions {
   priority?: number;
   timeoutInSec?: number;
+  /** Queue observational events in Kodelet's database and retry failed deliveries in order. */
+  durable?: boolean;
 }
 
 export type EventHandler<Name extends EventName = EventName> = (
@@ -377,6 +398,7 @@ export interface InitializeResult {
     event: string;
     priority?: number;
     timeoutInSec?: number;
+    durable?: boolean;
   }>;
 }
 